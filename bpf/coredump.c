@@ -0,0 +1,65 @@
+#include "vmlinux.h"
+
+#include <bpf/bpf_core_read.h>
+#include <bpf/bpf_helpers.h>
+#include <bpf/bpf_tracing.h>
+
+#include "bpf_common.h"
+#include "bpf_ratelimit.h"
+
+char __license[] SEC("license") = "Dual MIT/GPL";
+
+BPF_RATELIMIT(rate, 1, 10);
+
+struct coredump_event {
+	u64 stack[PERF_MAX_STACK_DEPTH];
+	s64 stack_size;
+	char comm[COMPAT_TASK_COMM_LEN];
+	u32 pid;
+	u32 tid;
+	s32 signo;
+	u32 uid;
+};
+
+struct {
+	__uint(type, BPF_MAP_TYPE_PERCPU_ARRAY);
+	__uint(key_size, sizeof(u32));
+	__uint(value_size, sizeof(struct coredump_event));
+	__uint(max_entries, 1);
+} coredump_event_buf SEC(".maps");
+
+struct {
+	__uint(type, BPF_MAP_TYPE_PERF_EVENT_ARRAY);
+	__uint(key_size, sizeof(int));
+	__uint(value_size, sizeof(u32));
+} coredump_events SEC(".maps");
+
+/* do_coredump, or vfs_coredump on newer kernels, the symbol is picked in go. */
+SEC("kprobe/do_coredump")
+int kprobe_coredump(struct pt_regs *ctx)
+{
+	kernel_siginfo_t *siginfo = (void *)PT_REGS_PARM1(ctx);
+	struct coredump_event *event;
+	u64 pid_tgid;
+	u32 key = 0;
+
+	if (bpf_ratelimited(&rate))
+		return 0;
+
+	event = bpf_map_lookup_elem(&coredump_event_buf, &key);
+	if (!event)
+		return 0;
+
+	pid_tgid	  = bpf_get_current_pid_tgid();
+	event->pid	  = pid_tgid >> 32;
+	event->tid	  = (u32)pid_tgid;
+	event->uid	  = (u32)bpf_get_current_uid_gid();
+	event->signo	  = BPF_CORE_READ(siginfo, si_signo);
+	event->stack_size = bpf_get_stack(ctx, event->stack, sizeof(event->stack),
+					  COMPAT_BPF_F_USER_STACK);
+	bpf_get_current_comm(event->comm, sizeof(event->comm));
+
+	bpf_perf_event_output(ctx, &coredump_events, COMPAT_BPF_F_CURRENT_CPU,
+			      event, sizeof(*event));
+	return 0;
+}
@@ -0,0 +1,140 @@
+#include "vmlinux.h"
+
+#include <bpf/bpf_core_read.h>
+#include <bpf/bpf_helpers.h>
+#include <bpf/bpf_tracing.h>
+
+#include "bpf_common.h"
+
+char __license[] SEC("license") = "Dual MIT/GPL";
+
+#define FS_ENFORCE_PATH_LEN 256
+
+#define FS_ENFORCE_OP_WRITE 1
+#define FS_ENFORCE_OP_EXEC  2
+
+#define FMODE_WRITE 0x2
+#define EPERM	    1
+
+/* set by the agent, only report the matching operations when zero. */
+volatile const u8 enforce = 0;
+
+struct fs_enforce_key {
+	u32 prefixlen;
+	char path[FS_ENFORCE_PATH_LEN];
+};
+
+struct fs_enforce_event {
+	char path[FS_ENFORCE_PATH_LEN];
+	char comm[COMPAT_TASK_COMM_LEN];
+	u32 pid;
+	u32 tid;
+	u32 uid;
+	u32 op;
+	u32 denied;
+};
+
+/* path prefixes, the value is the mask of the denied operations. */
+struct {
+	__uint(type, BPF_MAP_TYPE_LPM_TRIE);
+	__uint(key_size, sizeof(struct fs_enforce_key));
+	__uint(value_size, sizeof(u32));
+	__uint(max_entries, 256);
+	__uint(map_flags, BPF_F_NO_PREALLOC);
+} fs_enforce_rules SEC(".maps");
+
+struct {
+	__uint(type, BPF_MAP_TYPE_PERCPU_ARRAY);
+	__uint(key_size, sizeof(u32));
+	__uint(value_size, sizeof(struct fs_enforce_key));
+	__uint(max_entries, 1);
+} fs_enforce_key_buf SEC(".maps");
+
+struct {
+	__uint(type, BPF_MAP_TYPE_PERCPU_ARRAY);
+	__uint(key_size, sizeof(u32));
+	__uint(value_size, sizeof(struct fs_enforce_event));
+	__uint(max_entries, 1);
+} fs_enforce_event_buf SEC(".maps");
+
+struct {
+	__uint(type, BPF_MAP_TYPE_PERF_EVENT_ARRAY);
+	__uint(key_size, sizeof(int));
+	__uint(value_size, sizeof(u32));
+} fs_enforce_events SEC(".maps");
+
+/* tasks of the host pid namespace are never checked, a container without
+ * its own pid namespace is seen as the host. */
+static __always_inline bool in_container(void)
+{
+	struct task_struct *task = (struct task_struct *)bpf_get_current_task();
+
+	return BPF_CORE_READ(task, thread_pid, level) > 0;
+}
+
+static __always_inline int fs_enforce_check(void *ctx, struct path *path, u32 op)
+{
+	struct fs_enforce_event *event;
+	struct fs_enforce_key *key;
+	u32 *denied_ops;
+	u64 pid_tgid;
+	u32 zero = 0;
+	long len;
+
+	if (!in_container())
+		return 0;
+
+	key = bpf_map_lookup_elem(&fs_enforce_key_buf, &zero);
+	if (!key)
+		return 0;
+
+	len = bpf_d_path(path, key->path, sizeof(key->path));
+	if (len <= 1)
+		return 0;
+
+	/* bpf_d_path counts the trailing nul. */
+	key->prefixlen = (len - 1) * 8;
+	denied_ops     = bpf_map_lookup_elem(&fs_enforce_rules, key);
+	if (!denied_ops || !(*denied_ops & op))
+		return 0;
+
+	event = bpf_map_lookup_elem(&fs_enforce_event_buf, &zero);
+	if (!event)
+		return enforce ? -EPERM : 0;
+
+	pid_tgid      = bpf_get_current_pid_tgid();
+	event->pid    = pid_tgid >> 32;
+	event->tid    = (u32)pid_tgid;
+	event->uid    = (u32)bpf_get_current_uid_gid();
+	event->op     = op;
+	event->denied = enforce;
+	bpf_get_current_comm(event->comm, sizeof(event->comm));
+	bpf_probe_read_kernel_str(event->path, sizeof(event->path), key->path);
+
+	bpf_perf_event_output(ctx, &fs_enforce_events, COMPAT_BPF_F_CURRENT_CPU,
+			      event, sizeof(*event));
+	return enforce ? -EPERM : 0;
+}
+
+SEC("lsm/file_open")
+int BPF_PROG(fs_enforce_file_open, struct file *file, int ret)
+{
+	/* denied by an earlier lsm already. */
+	if (ret)
+		return ret;
+
+	if (!(file->f_mode & FMODE_WRITE))
+		return 0;
+
+	return fs_enforce_check(ctx, &file->f_path, FS_ENFORCE_OP_WRITE);
+}
+
+SEC("lsm/bprm_check_security")
+int BPF_PROG(fs_enforce_bprm_check, struct linux_binprm *bprm, int ret)
+{
+	if (ret)
+		return ret;
+
+	/* bpf_d_path wants the btf pointers of the hook arguments. */
+	return fs_enforce_check(ctx, &bprm->file->f_path, FS_ENFORCE_OP_EXEC);
+}
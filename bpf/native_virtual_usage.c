@@ -0,0 +1,187 @@
+#include "vmlinux.h"
+#include <bpf/bpf_helpers.h>
+#include <bpf/bpf_tracing.h>
+#include <bpf/bpf_core_read.h>
+#include "bpf_profiler.h"
+
+char __license[] SEC("license") = "GPL";
+
+DEFINE_PROFILER_MAPS(struct profiler_event_base_t);
+
+/* anonymous mmap length by thread, between do_mmap entry and return. */
+struct {
+	__uint(type, BPF_MAP_TYPE_HASH);
+	__uint(max_entries, 10240);
+	__type(key, u64);
+	__type(value, u64);
+} mmap_len SEC(".maps");
+
+/* alloc-time event of each live mapping, keyed by start address. */
+struct {
+	__uint(type, BPF_MAP_TYPE_HASH);
+	__uint(max_entries, 1 << 20);
+	__type(key, u64);
+	__type(value, struct profiler_event_base_t);
+} addr_to_stackid SEC(".maps");
+
+SEC("kprobe/do_mmap")
+int BPF_KPROBE(trace_mmap_enter, struct file *file, unsigned long addr,
+               unsigned long len)
+{
+	u64 pid_tgid = bpf_get_current_pid_tgid();
+	u64 mem_css = 0;
+
+	if (file)
+		return 0;
+
+	if (profiler_filter_css != 0)
+		mem_css = current_task_memory_css_addr();
+	if (!profiler_should_trace(pid_tgid, mem_css))
+		return 0;
+
+	u64 l = len;
+	bpf_map_update_elem(&mmap_len, &pid_tgid, &l, COMPAT_BPF_ANY);
+	return 0;
+}
+
+SEC("kretprobe/do_mmap")
+int BPF_KRETPROBE(trace_mmap_exit, unsigned long ret)
+{
+	u64 *transfer_count_ptr;
+	u64 *sample_count_ptrs[2];
+	void *select_profiler_stack_map;
+	void *select_profiler_output;
+	u64 *select_profiler_sample_count_ptr;
+
+	u64 pid_tgid = bpf_get_current_pid_tgid();
+	u64 *len = bpf_map_lookup_elem(&mmap_len, &pid_tgid);
+	if (!len)
+		return 0;
+
+	s64 value = (s64)*len;
+	bpf_map_delete_elem(&mmap_len, &pid_tgid);
+
+	/* IS_ERR_VALUE */
+	if (ret >= (unsigned long)-4095)
+		return 0;
+
+	if (!profiler_init_state(&profiler_state_map, &transfer_count_ptr, sample_count_ptrs))
+		return 0;
+
+	SELECT_PROFILER_AB();
+
+	struct profiler_event_base_t *event = profiler_prepare_event_base(
+		&event_buf, pid_tgid, ctx, select_profiler_stack_map);
+	if (!event)
+		return 0;
+
+	event->value = value;
+
+	u64 start = ret;
+	bpf_map_update_elem(&addr_to_stackid, &start, event, COMPAT_BPF_ANY);
+
+	profiler_emit_event(ctx, select_profiler_output,
+	                    select_profiler_sample_count_ptr, event, sizeof(*event));
+
+	return 0;
+}
+
+/*
+ * Unmaps are charged back to the stack that created the mapping, so the
+ * profile shows what is still mapped. Partial unmaps not starting at a
+ * tracked address are ignored.
+ */
+SEC("tracepoint/syscalls/sys_enter_munmap")
+int trace_munmap(struct trace_event_raw_sys_enter *ctx)
+{
+	u64 *transfer_count_ptr;
+	u64 *sample_count_ptrs[2];
+	void *select_profiler_stack_map __attribute__((unused));
+	void *select_profiler_output;
+	u64 *select_profiler_sample_count_ptr;
+
+	if (!profiler_init_state(&profiler_state_map, &transfer_count_ptr, sample_count_ptrs))
+		return 0;
+
+	u64 pid_tgid = bpf_get_current_pid_tgid();
+	u64 mem_css = 0;
+	if (profiler_filter_css != 0)
+		mem_css = current_task_memory_css_addr();
+	if (!profiler_should_trace(pid_tgid, mem_css))
+		return 0;
+
+	u64 start = ctx->args[0];
+	s64 len = (s64)ctx->args[1];
+	struct profiler_event_base_t *stack_info =
+		bpf_map_lookup_elem(&addr_to_stackid, &start);
+	if (!stack_info)
+		return 0;
+
+	u32 idx = 0;
+	struct profiler_event_base_t *event = bpf_map_lookup_elem(&event_buf, &idx);
+	if (!event)
+		return 0;
+
+	__builtin_memset(event, 0, sizeof(*event));
+
+	profiler_copy_event_base(event, stack_info);
+	event->value = -(len < stack_info->value ? len : stack_info->value);
+
+	bpf_map_delete_elem(&addr_to_stackid, &start);
+
+	SELECT_PROFILER_AB();
+
+	profiler_emit_event(ctx, select_profiler_output,
+	                    select_profiler_sample_count_ptr, event, sizeof(*event));
+
+	return 0;
+}
+
+/*
+ * brk moves the heap end; the delta against mm->brk is charged to the
+ * calling stack, shrinking included since there is no allocation to map
+ * it back to.
+ */
+SEC("tracepoint/syscalls/sys_enter_brk")
+int trace_brk(struct trace_event_raw_sys_enter *ctx)
+{
+	u64 *transfer_count_ptr;
+	u64 *sample_count_ptrs[2];
+	void *select_profiler_stack_map;
+	void *select_profiler_output;
+	u64 *select_profiler_sample_count_ptr;
+
+	u64 brk = ctx->args[0];
+	/* brk(0) only queries the current break. */
+	if (brk == 0)
+		return 0;
+
+	if (!profiler_init_state(&profiler_state_map, &transfer_count_ptr, sample_count_ptrs))
+		return 0;
+
+	u64 pid_tgid = bpf_get_current_pid_tgid();
+	u64 mem_css = 0;
+	if (profiler_filter_css != 0)
+		mem_css = current_task_memory_css_addr();
+	if (!profiler_should_trace(pid_tgid, mem_css))
+		return 0;
+
+	struct task_struct *task = (struct task_struct *)bpf_get_current_task();
+	u64 cur = BPF_CORE_READ(task, mm, brk);
+	if (brk == cur)
+		return 0;
+
+	SELECT_PROFILER_AB();
+
+	struct profiler_event_base_t *event = profiler_prepare_event_base(
+		&event_buf, pid_tgid, ctx, select_profiler_stack_map);
+	if (!event)
+		return 0;
+
+	event->value = (s64)(brk - cur);
+
+	profiler_emit_event(ctx, select_profiler_output,
+	                    select_profiler_sample_count_ptr, event, sizeof(*event));
+
+	return 0;
+}
@@ -26,6 +26,10 @@ struct oom_info {
 	u64 victim_memcg_css;
 	u64 mem_limit_pages;
 	u64 mem_usage_pages;
+	/* the memcg hitting its limit, 0 of the global oom. */
+	u64 oom_memcg_css;
+	s32 victim_oom_score_adj;
+	u32 pad;
 };
 
 SEC("kprobe/oom_kill_process")
@@ -52,11 +56,16 @@ int BPF_KPROBE(oom_kill_process, struct oom_control *oc, const char *message)
 	info.trigger_memcg_css =
 	    (u64)BPF_CORE_READ(trigger_task, cgroups, subsys[memory_cgrp_id]);
 
+	info.victim_oom_score_adj =
+	    BPF_CORE_READ(victim_task, signal, oom_score_adj);
+
 	info.mem_limit_pages = BPF_CORE_READ(oc, totalpages);
 	struct mem_cgroup *memcg = BPF_CORE_READ(oc, memcg);
 	if (memcg) {
 		info.mem_usage_pages =
 		    (u64)BPF_CORE_READ(memcg, memory.usage.counter);
+		/* css is the first member of mem_cgroup. */
+		info.oom_memcg_css = (u64)memcg;
 	}
 
 	bpf_perf_event_output(ctx, &oom_perf_events, COMPAT_BPF_F_CURRENT_CPU,
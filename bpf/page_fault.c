@@ -0,0 +1,72 @@
+#include "vmlinux.h"
+
+#include <bpf/bpf_core_read.h>
+#include <bpf/bpf_helpers.h>
+#include <bpf/bpf_tracing.h>
+
+#include "bpf_cgroup.h"
+#include "bpf_common.h"
+#include "bpf_ratelimit.h"
+
+char __license[] SEC("license") = "Dual MIT/GPL";
+
+/* include/linux/mm_types.h */
+#define VM_FAULT_MAJOR 0x0004
+
+/* memory css of the container being sampled, set at load time. */
+volatile const u64 target_css = 0;
+
+BPF_RATELIMIT(rate, 1, 200);
+
+struct fault_event {
+	u64 stack[PERF_MAX_STACK_DEPTH];
+	s64 stack_size;
+	char comm[COMPAT_TASK_COMM_LEN];
+	u32 pid;
+	u32 tid;
+};
+
+struct {
+	__uint(type, BPF_MAP_TYPE_PERCPU_ARRAY);
+	__uint(key_size, sizeof(u32));
+	__uint(value_size, sizeof(struct fault_event));
+	__uint(max_entries, 1);
+} fault_event_buf SEC(".maps");
+
+struct {
+	__uint(type, BPF_MAP_TYPE_PERF_EVENT_ARRAY);
+	__uint(key_size, sizeof(int));
+	__uint(value_size, sizeof(u32));
+} page_fault_events SEC(".maps");
+
+SEC("kretprobe/handle_mm_fault")
+int kretprobe_handle_mm_fault(struct pt_regs *ctx)
+{
+	struct fault_event *event;
+	u64 pid_tgid;
+	u32 key = 0;
+
+	if (!(PT_REGS_RC(ctx) & VM_FAULT_MAJOR))
+		return 0;
+
+	if (current_task_memory_css_addr() != target_css)
+		return 0;
+
+	if (bpf_ratelimited(&rate))
+		return 0;
+
+	event = bpf_map_lookup_elem(&fault_event_buf, &key);
+	if (!event)
+		return 0;
+
+	pid_tgid	  = bpf_get_current_pid_tgid();
+	event->pid	  = pid_tgid >> 32;
+	event->tid	  = (u32)pid_tgid;
+	event->stack_size = bpf_get_stack(ctx, event->stack, sizeof(event->stack),
+					  COMPAT_BPF_F_USER_STACK);
+	bpf_get_current_comm(event->comm, sizeof(event->comm));
+
+	bpf_perf_event_output(ctx, &page_fault_events, COMPAT_BPF_F_CURRENT_CPU,
+			      event, sizeof(*event));
+	return 0;
+}
@@ -0,0 +1,158 @@
+#include "vmlinux.h"
+
+#include <bpf/bpf_core_read.h>
+#include <bpf/bpf_helpers.h>
+#include <bpf/bpf_tracing.h>
+
+#include "bpf_common.h"
+#include "bpf_net_namespace.h"
+#include "vmlinux_net.h"
+
+char __license[] SEC("license") = "Dual MIT/GPL";
+
+/* keep in sync with core/events/skb_drop.go */
+#define SKB_DROP_REASON_NONE 0xffffffff
+#define SKB_DROP_STACK_SAMPLE 16
+
+/* SKB_CONSUMED, the packets freed but not dropped seen by the kprobes, read
+ * from the kernel btf. */
+volatile const u32 consumed_reason = SKB_DROP_REASON_NONE;
+
+struct skb_drop_key {
+	u32 reason;
+	char netdev[IFNAMSIZ];
+};
+
+/* the owner of a sampled drop and where it was dropped */
+struct skb_drop_site_key {
+	u64 memcg_css;
+	u32 netns_inum;
+	u32 reason;
+	s32 stack_id;
+	u32 pad;
+};
+
+struct {
+	__uint(type, BPF_MAP_TYPE_LRU_HASH);
+	__type(key, struct skb_drop_key);
+	__type(value, u64);
+	__uint(max_entries, 4096);
+} skb_drop_counts SEC(".maps");
+
+struct {
+	__uint(type, BPF_MAP_TYPE_LRU_HASH);
+	__type(key, struct skb_drop_site_key);
+	__type(value, u64);
+	__uint(max_entries, 10240);
+} skb_drop_sites SEC(".maps");
+
+struct {
+	__uint(type, BPF_MAP_TYPE_STACK_TRACE);
+	__uint(key_size, sizeof(u32));
+	__uint(value_size, PERF_MAX_STACK_DEPTH * sizeof(u64));
+	__uint(max_entries, 1024);
+} skb_drop_stacks SEC(".maps");
+
+struct {
+	__uint(type, BPF_MAP_TYPE_PERCPU_ARRAY);
+	__type(key, u32);
+	__type(value, u64);
+	__uint(max_entries, 1);
+} skb_drop_sample SEC(".maps");
+
+/* the reason of the tracepoint since v5.17, see bpf/dropwatch.c. */
+struct trace_event_raw_kfree_skb___reason {
+	enum { SKB_DROP_REASON_UNSUPPORT = -1 } reason;
+} __attribute__((preserve_access_index));
+
+static __always_inline void map_count(void *map, void *key)
+{
+	u64 *count, one = 1;
+
+	count = bpf_map_lookup_elem(map, key);
+	if (!count) {
+		bpf_map_update_elem(map, key, &one, COMPAT_BPF_NOEXIST);
+		return;
+	}
+
+	__sync_fetch_and_add(count, 1);
+}
+
+static __always_inline u64 skb_memcg_css(struct sk_buff *skb)
+{
+	struct sock *sk = BPF_CORE_READ(skb, sk);
+
+	if (!sk || !bpf_core_field_exists(((struct sock *)0)->sk_memcg))
+		return 0;
+
+	/* the time wait and request sockets share sock_common only. */
+	u8 state = BPF_CORE_READ(sk, __sk_common.skc_state);
+	if (state == TCP_TIME_WAIT || state == TCP_NEW_SYN_RECV)
+		return 0;
+
+	return (u64)BPF_CORE_READ(sk, sk_memcg);
+}
+
+static __always_inline void skb_drop(void *ctx, struct sk_buff *skb,
+				     u32 reason)
+{
+	struct skb_drop_key key = {.reason = reason};
+	struct skb_drop_site_key site = {.reason = reason};
+	struct net_device *dev;
+	u64 *sample;
+	u32 zero = 0;
+
+	if (!skb || reason == consumed_reason)
+		return;
+
+	dev = BPF_CORE_READ(skb, dev);
+	if (dev)
+		bpf_probe_read_kernel_str(key.netdev, sizeof(key.netdev),
+					  dev->name);
+	map_count(&skb_drop_counts, &key);
+
+	/* the stacks of one drop out of SKB_DROP_STACK_SAMPLE per cpu */
+	sample = bpf_map_lookup_elem(&skb_drop_sample, &zero);
+	if (!sample || (*sample)++ % SKB_DROP_STACK_SAMPLE)
+		return;
+
+	site.memcg_css	= skb_memcg_css(skb);
+	site.netns_inum = skb_netns_inum(skb);
+	site.stack_id	= bpf_get_stackid(ctx, &skb_drop_stacks, 0);
+	map_count(&skb_drop_sites, &site);
+}
+
+SEC("tracepoint/skb/kfree_skb")
+int tracepoint_kfree_skb(struct trace_event_raw_kfree_skb *ctx)
+{
+	struct trace_event_raw_kfree_skb___reason *ctx_reason = (void *)ctx;
+	u32 reason = SKB_DROP_REASON_NONE;
+
+	if (bpf_core_field_exists(ctx_reason->reason))
+		reason = BPF_CORE_READ(ctx_reason, reason);
+
+	skb_drop(ctx, ctx->skbaddr, reason);
+	return 0;
+}
+
+/* the kprobes, when the tracepoint cannot be attached. */
+SEC("kprobe/sk_skb_reason_drop")
+int kprobe_sk_skb_reason_drop(struct pt_regs *ctx)
+{
+	skb_drop(ctx, (void *)PT_REGS_PARM2(ctx), (u32)PT_REGS_PARM3(ctx));
+	return 0;
+}
+
+SEC("kprobe/kfree_skb_reason")
+int kprobe_kfree_skb_reason(struct pt_regs *ctx)
+{
+	skb_drop(ctx, (void *)PT_REGS_PARM1(ctx), (u32)PT_REGS_PARM2(ctx));
+	return 0;
+}
+
+SEC("kprobe/kfree_skb")
+int kprobe_kfree_skb(struct pt_regs *ctx)
+{
+	skb_drop(ctx, (void *)PT_REGS_PARM1(ctx), SKB_DROP_REASON_NONE);
+	return 0;
+}
@@ -6,6 +6,9 @@
 
 #include "bpf_common.h"
 
+/* the NET_RX softirqs running this long record their stacks, 0 disables. */
+volatile const u64 net_rx_thresh = 0;
+
 enum lat_zone {
 	LAT_ZONE0 = 0, // 0 ~ 10us
 	LAT_ZONE1,     // 10us ~ 100us
@@ -18,6 +21,21 @@ struct softirq_lat {
 	u64 enable;
 	u64 timestamp;
 	u64 total_latency[LAT_ZONE_MAX];
+	u64 entry;
+	u64 total_runtime[LAT_ZONE_MAX];
+};
+
+/* the task running a slow NET_RX softirq, ksoftirqd or the one processing
+ * it on irq exit or bh enable, and its stack. */
+struct net_rx_stack_key {
+	u32 pid;
+	s32 stack_id;
+	char comm[COMPAT_TASK_COMM_LEN];
+};
+
+struct net_rx_stack_val {
+	u64 count;
+	u64 max_runtime;
 };
 
 struct {
@@ -27,6 +45,33 @@ struct {
 	__uint(max_entries, NR_SOFTIRQS_MAX);
 } softirq_percpu_lats SEC(".maps");
 
+struct {
+	__uint(type, BPF_MAP_TYPE_LRU_HASH);
+	__type(key, struct net_rx_stack_key);
+	__type(value, struct net_rx_stack_val);
+	__uint(max_entries, 1024);
+} softirq_net_rx_slow SEC(".maps");
+
+struct {
+	__uint(type, BPF_MAP_TYPE_STACK_TRACE);
+	__uint(key_size, sizeof(u32));
+	__uint(value_size, PERF_MAX_STACK_DEPTH * sizeof(u64));
+	__uint(max_entries, 1024);
+} softirq_net_rx_stacks SEC(".maps");
+
+static __always_inline void lat_zone_count(u64 *zones, u64 latency)
+{
+	if (latency < 10 * NSEC_PER_USEC) {
+		__sync_fetch_and_add(&zones[LAT_ZONE0], 1);
+	} else if (latency < 100 * NSEC_PER_USEC) {
+		__sync_fetch_and_add(&zones[LAT_ZONE1], 1);
+	} else if (latency < 1 * NSEC_PER_MSEC) {
+		__sync_fetch_and_add(&zones[LAT_ZONE2], 1);
+	} else {
+		__sync_fetch_and_add(&zones[LAT_ZONE3], 1);
+	}
+}
+
 SEC("tracepoint/irq/softirq_raise")
 int probe_softirq_raise(struct trace_event_raw_softirq *ctx)
 {
@@ -68,22 +113,63 @@ int probe_softirq_entry(struct trace_event_raw_softirq *ctx)
 	if (!lat)
 		return 0;
 
+	u64 now = bpf_ktime_get_ns();
+
+	lat->entry = now;
 	if (!lat->enable)
 		return 0;
 
-	u64 latency = bpf_ktime_get_ns() - lat->timestamp;
+	lat_zone_count(lat->total_latency, now - lat->timestamp);
+	lat->enable = 0;
 
-	if (latency < 10 * NSEC_PER_USEC) {
-		__sync_fetch_and_add(&lat->total_latency[LAT_ZONE0], 1);
-	} else if (latency < 100 * NSEC_PER_USEC) {
-		__sync_fetch_and_add(&lat->total_latency[LAT_ZONE1], 1);
-	} else if (latency < 1 * NSEC_PER_MSEC) {
-		__sync_fetch_and_add(&lat->total_latency[LAT_ZONE2], 1);
-	} else {
-		__sync_fetch_and_add(&lat->total_latency[LAT_ZONE3], 1);
+	return 0;
+}
+
+static __always_inline void net_rx_slow(void *ctx, u64 runtime)
+{
+	struct net_rx_stack_key key = {};
+	struct net_rx_stack_val *val;
+
+	key.pid	     = (u32)bpf_get_current_pid_tgid();
+	key.stack_id = bpf_get_stackid(ctx, &softirq_net_rx_stacks, 0);
+	bpf_get_current_comm(&key.comm, sizeof(key.comm));
+
+	val = bpf_map_lookup_elem(&softirq_net_rx_slow, &key);
+	if (!val) {
+		struct net_rx_stack_val init = {
+			.count	     = 1,
+			.max_runtime = runtime,
+		};
+		bpf_map_update_elem(&softirq_net_rx_slow, &key, &init,
+				    COMPAT_BPF_NOEXIST);
+		return;
 	}
 
-	lat->enable = 0;
+	__sync_fetch_and_add(&val->count, 1);
+	if (runtime > val->max_runtime)
+		val->max_runtime = runtime;
+}
+
+SEC("tracepoint/irq/softirq_exit")
+int probe_softirq_exit(struct trace_event_raw_softirq *ctx)
+{
+	struct softirq_lat *lat;
+	u32 vec = ctx->vec;
+
+	if (vec >= NR_SOFTIRQS)
+		return 0;
+
+	lat = bpf_map_lookup_elem(&softirq_percpu_lats, &vec);
+	if (!lat || !lat->entry)
+		return 0;
+
+	u64 runtime = bpf_ktime_get_ns() - lat->entry;
+
+	lat_zone_count(lat->total_runtime, runtime);
+	lat->entry = 0;
+
+	if (vec == NET_RX_SOFTIRQ && net_rx_thresh && runtime >= net_rx_thresh)
+		net_rx_slow(ctx, runtime);
 
 	return 0;
 }
@@ -0,0 +1,293 @@
+#include "vmlinux.h"
+
+#include <bpf/bpf_core_read.h>
+#include <bpf/bpf_endian.h>
+#include <bpf/bpf_helpers.h>
+#include <bpf/bpf_tracing.h>
+
+#include "bpf_common.h"
+#include "bpf_net_namespace.h"
+#include "bpf_ratelimit.h"
+#include "vmlinux_net.h"
+
+char __license[] SEC("license") = "Dual MIT/GPL";
+
+/* the retransmits and resets of a flow within flow_window_ns saving an
+ * event, 0 disables the events. */
+volatile const u64 flow_threshold = 0;
+volatile const u64 flow_window_ns = 10 * 1000000000ULL;
+
+BPF_RATELIMIT(rate, 1, 100);
+
+/* keep in sync with core/events/tcp_retrans.go */
+enum {
+	TCP_RETRANS_TYPE_RETRANSMIT = 0,
+	TCP_RETRANS_TYPE_RESET,
+};
+
+/* the local end is saddr and sport, the ports in host order. */
+struct tcp_flow_key {
+	u8 saddr[16];
+	u8 daddr[16];
+	u32 netns_inum;
+	u16 sport;
+	u16 dport;
+	u16 family;
+	u16 pad;
+};
+
+struct tcp_flow_value {
+	u64 window_start;
+	u64 retrans;
+	u64 resets;
+};
+
+/* memcg css of the socket, 0 if none, and its net namespace */
+struct tcp_retrans_key {
+	u64 memcg_css;
+	u32 netns_inum;
+	u32 pad;
+};
+
+struct tcp_retrans_count {
+	u64 retrans;
+	u64 resets;
+};
+
+struct tcp_retrans_event {
+	u64 stack[PERF_MAX_STACK_DEPTH];
+	s64 stack_size;
+	u64 memcg_css;
+	/* of the flow within the window */
+	u64 retrans;
+	u64 resets;
+	u32 pid;
+	u32 netns_inum;
+	u8 saddr[16];
+	u8 daddr[16];
+	u16 sport;
+	u16 dport;
+	u16 family;
+	u8 state;
+	u8 type;
+	char comm[COMPAT_TASK_COMM_LEN];
+};
+
+struct {
+	__uint(type, BPF_MAP_TYPE_LRU_HASH);
+	__type(key, struct tcp_retrans_key);
+	__type(value, struct tcp_retrans_count);
+	__uint(max_entries, 10240);
+} tcp_retrans_counts SEC(".maps");
+
+struct {
+	__uint(type, BPF_MAP_TYPE_LRU_HASH);
+	__type(key, struct tcp_flow_key);
+	__type(value, struct tcp_flow_value);
+	__uint(max_entries, 65536);
+} tcp_retrans_flows SEC(".maps");
+
+struct {
+	__uint(type, BPF_MAP_TYPE_PERCPU_ARRAY);
+	__uint(key_size, sizeof(u32));
+	__uint(value_size, sizeof(struct tcp_retrans_event));
+	__uint(max_entries, 1);
+} tcp_retrans_event_buf SEC(".maps");
+
+struct {
+	__uint(type, BPF_MAP_TYPE_PERF_EVENT_ARRAY);
+	__uint(key_size, sizeof(int));
+	__uint(value_size, sizeof(u32));
+} tcp_retrans_events SEC(".maps");
+
+/* the time wait and request sockets share sock_common only. */
+static __always_inline bool sk_is_fullsock(struct sock *sk)
+{
+	u8 state = BPF_CORE_READ(sk, __sk_common.skc_state);
+
+	return state != TCP_TIME_WAIT && state != TCP_NEW_SYN_RECV;
+}
+
+static __always_inline u64 sk_memcg_css_addr(struct sock *sk)
+{
+	if (!bpf_core_field_exists(((struct sock *)0)->sk_memcg))
+		return 0;
+
+	if (!sk_is_fullsock(sk))
+		return 0;
+
+	return (u64)BPF_CORE_READ(sk, sk_memcg);
+}
+
+static __always_inline void sk_flow_key(struct sock *sk,
+					struct tcp_flow_key *key)
+{
+	key->family	= BPF_CORE_READ(sk, __sk_common.skc_family);
+	key->sport	= BPF_CORE_READ(sk, __sk_common.skc_num);
+	key->dport	= bpf_ntohs(BPF_CORE_READ(sk, __sk_common.skc_dport));
+	key->netns_inum = BPF_CORE_READ(sk, __sk_common.skc_net.net, ns.inum);
+
+	if (key->family == AF_INET6) {
+		BPF_CORE_READ_INTO(&key->saddr, sk,
+				   __sk_common.skc_v6_rcv_saddr);
+		BPF_CORE_READ_INTO(&key->daddr, sk, __sk_common.skc_v6_daddr);
+	} else {
+		u32 saddr = BPF_CORE_READ(sk, __sk_common.skc_rcv_saddr);
+		u32 daddr = BPF_CORE_READ(sk, __sk_common.skc_daddr);
+
+		__builtin_memcpy(key->saddr, &saddr, sizeof(saddr));
+		__builtin_memcpy(key->daddr, &daddr, sizeof(daddr));
+	}
+}
+
+/* the reset answers the ipv4 packet, the local end is its destination. */
+static __always_inline void skb_flow_key(struct sk_buff *skb,
+					 struct tcp_flow_key *key)
+{
+	struct iphdr ip_hdr;
+	struct tcphdr tcp_hdr;
+
+	bpf_probe_read_kernel(&ip_hdr, sizeof(ip_hdr), skb_network_header(skb));
+	bpf_probe_read_kernel(&tcp_hdr, sizeof(tcp_hdr),
+			      skb_transport_header(skb));
+
+	key->family	= AF_INET;
+	key->sport	= bpf_ntohs(tcp_hdr.dest);
+	key->dport	= bpf_ntohs(tcp_hdr.source);
+	key->netns_inum = skb_netns_inum(skb);
+	__builtin_memcpy(key->saddr, &ip_hdr.daddr, sizeof(ip_hdr.daddr));
+	__builtin_memcpy(key->daddr, &ip_hdr.saddr, sizeof(ip_hdr.saddr));
+}
+
+static __always_inline void submit_event(void *ctx, struct tcp_flow_key *key,
+					 struct tcp_flow_value *flow, u64 css,
+					 u8 state, u8 type)
+{
+	struct tcp_retrans_event *event;
+	u32 zero = 0;
+
+	if (bpf_ratelimited(&rate))
+		return;
+
+	event = bpf_map_lookup_elem(&tcp_retrans_event_buf, &zero);
+	if (!event)
+		return;
+
+	event->memcg_css  = css;
+	event->retrans	  = flow->retrans;
+	event->resets	  = flow->resets;
+	event->pid	  = bpf_get_current_pid_tgid() >> 32;
+	event->netns_inum = key->netns_inum;
+	event->sport	  = key->sport;
+	event->dport	  = key->dport;
+	event->family	  = key->family;
+	event->state	  = state;
+	event->type	  = type;
+	__builtin_memcpy(event->saddr, key->saddr, sizeof(key->saddr));
+	__builtin_memcpy(event->daddr, key->daddr, sizeof(key->daddr));
+	bpf_get_current_comm(event->comm, sizeof(event->comm));
+	event->stack_size =
+	    bpf_get_stack(ctx, event->stack, sizeof(event->stack), 0);
+
+	bpf_perf_event_output(ctx, &tcp_retrans_events,
+			      COMPAT_BPF_F_CURRENT_CPU, event, sizeof(*event));
+}
+
+static __always_inline void tcp_retrans_account(void *ctx,
+						struct tcp_flow_key *key,
+						u64 css, u8 state, u8 type)
+{
+	struct tcp_retrans_key count_key = {
+		.memcg_css  = css,
+		.netns_inum = key->netns_inum,
+	};
+	struct tcp_retrans_count *count, count_init = {};
+	struct tcp_flow_value *flow, flow_init = {};
+	u64 now = bpf_ktime_get_ns();
+
+	if (type == TCP_RETRANS_TYPE_RESET)
+		count_init.resets = 1;
+	else
+		count_init.retrans = 1;
+
+	count = bpf_map_lookup_elem(&tcp_retrans_counts, &count_key);
+	if (!count) {
+		bpf_map_update_elem(&tcp_retrans_counts, &count_key,
+				    &count_init, COMPAT_BPF_NOEXIST);
+	} else if (type == TCP_RETRANS_TYPE_RESET) {
+		__sync_fetch_and_add(&count->resets, 1);
+	} else {
+		__sync_fetch_and_add(&count->retrans, 1);
+	}
+
+	if (!flow_threshold)
+		return;
+
+	flow = bpf_map_lookup_elem(&tcp_retrans_flows, key);
+	if (!flow || now - flow->window_start > flow_window_ns) {
+		flow_init.window_start = now;
+		bpf_map_update_elem(&tcp_retrans_flows, key, &flow_init,
+				    COMPAT_BPF_ANY);
+		flow = bpf_map_lookup_elem(&tcp_retrans_flows, key);
+		if (!flow)
+			return;
+	}
+
+	if (type == TCP_RETRANS_TYPE_RESET)
+		__sync_fetch_and_add(&flow->resets, 1);
+	else
+		__sync_fetch_and_add(&flow->retrans, 1);
+
+	/* once per flow and window */
+	if (flow->retrans + flow->resets != flow_threshold)
+		return;
+
+	submit_event(ctx, key, flow, css, state, type);
+}
+
+SEC("kprobe/tcp_retransmit_skb")
+int kprobe_tcp_retransmit_skb(struct pt_regs *ctx)
+{
+	struct sock *sk		= (void *)PT_REGS_PARM1(ctx);
+	struct tcp_flow_key key = {};
+
+	sk_flow_key(sk, &key);
+	tcp_retrans_account(ctx, &key, sk_memcg_css_addr(sk),
+			    BPF_CORE_READ(sk, __sk_common.skc_state),
+			    TCP_RETRANS_TYPE_RETRANSMIT);
+	return 0;
+}
+
+SEC("kprobe/tcp_send_active_reset")
+int kprobe_tcp_send_active_reset(struct pt_regs *ctx)
+{
+	struct sock *sk		= (void *)PT_REGS_PARM1(ctx);
+	struct tcp_flow_key key = {};
+
+	sk_flow_key(sk, &key);
+	tcp_retrans_account(ctx, &key, sk_memcg_css_addr(sk),
+			    BPF_CORE_READ(sk, __sk_common.skc_state),
+			    TCP_RETRANS_TYPE_RESET);
+	return 0;
+}
+
+/* sk is NULL for the packets of no socket, and may be a listen, time wait
+ * or request socket. */
+SEC("kprobe/tcp_v4_send_reset")
+int kprobe_tcp_v4_send_reset(struct pt_regs *ctx)
+{
+	struct sock *sk		= (void *)PT_REGS_PARM1(ctx);
+	struct sk_buff *skb	= (void *)PT_REGS_PARM2(ctx);
+	struct tcp_flow_key key = {};
+	u8 state		= TCP_CLOSE;
+	u64 css			= 0;
+
+	skb_flow_key(skb, &key);
+	if (sk) {
+		state = BPF_CORE_READ(sk, __sk_common.skc_state);
+		css   = sk_memcg_css_addr(sk);
+	}
+
+	tcp_retrans_account(ctx, &key, css, state, TCP_RETRANS_TYPE_RESET);
+	return 0;
+}
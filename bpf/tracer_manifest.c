@@ -0,0 +1,93 @@
+#include "vmlinux.h"
+
+#include <bpf/bpf_core_read.h>
+#include <bpf/bpf_helpers.h>
+#include <bpf/bpf_tracing.h>
+
+#include "bpf_common.h"
+
+char __license[] SEC("license") = "Dual MIT/GPL";
+
+/* keep in sync with core/metrics/tracer_manifest.go */
+#define MANIFEST_MAX_FILTERS 4
+
+enum {
+	MANIFEST_OP_EQ = 0,
+	MANIFEST_OP_NE,
+	MANIFEST_OP_GT,
+	MANIFEST_OP_GE,
+	MANIFEST_OP_LT,
+	MANIFEST_OP_LE,
+};
+
+/* filters on the tracepoint record, resolved from its format file. */
+volatile const u32 filter_count				   = 0;
+volatile const u32 filter_offset[MANIFEST_MAX_FILTERS] = {};
+volatile const u64 filter_mask[MANIFEST_MAX_FILTERS]   = {};
+volatile const u32 filter_op[MANIFEST_MAX_FILTERS]     = {};
+volatile const u64 filter_value[MANIFEST_MAX_FILTERS]  = {};
+
+/* cpu css address -> event count */
+struct {
+	__uint(type, BPF_MAP_TYPE_LRU_HASH);
+	__type(key, u64);
+	__type(value, u64);
+	__uint(max_entries, 10240);
+} manifest_counts SEC(".maps");
+
+static __always_inline bool filter_match(void *ctx, int i)
+{
+	u64 val = 0;
+
+	bpf_probe_read_kernel(&val, sizeof(val),
+			      (void *)((unsigned long)ctx + filter_offset[i]));
+	val &= filter_mask[i];
+
+	switch (filter_op[i]) {
+	case MANIFEST_OP_EQ:
+		return val == filter_value[i];
+	case MANIFEST_OP_NE:
+		return val != filter_value[i];
+	case MANIFEST_OP_GT:
+		return val > filter_value[i];
+	case MANIFEST_OP_GE:
+		return val >= filter_value[i];
+	case MANIFEST_OP_LT:
+		return val < filter_value[i];
+	case MANIFEST_OP_LE:
+		return val <= filter_value[i];
+	}
+	return false;
+}
+
+/* attached to the manifest tracepoint with AttachWithOptions */
+SEC("tracepoint/manifest/probe")
+int tracepoint_manifest_probe(void *ctx)
+{
+	struct task_struct *task;
+	u64 css, *valp;
+	int i;
+
+#pragma unroll
+	for (i = 0; i < MANIFEST_MAX_FILTERS; i++) {
+		if (i >= filter_count)
+			break;
+		if (!filter_match(ctx, i))
+			return 0;
+	}
+
+	task = (struct task_struct *)bpf_get_current_task();
+	css  = (u64)BPF_CORE_READ(task, cgroups, subsys[cpu_cgrp_id]);
+
+	valp = bpf_map_lookup_elem(&manifest_counts, &css);
+	if (!valp) {
+		u64 one = 1;
+
+		bpf_map_update_elem(&manifest_counts, &css, &one,
+				    COMPAT_BPF_NOEXIST);
+		return 0;
+	}
+
+	__sync_fetch_and_add(valp, 1);
+	return 0;
+}
@@ -59,6 +59,8 @@ func buildCommand(seed version.Seed) *cli.App {
 		return configureRuntime(opts)
 	}
 
+	app.Commands = []*cli.Command{cohortReportCommand(opts)}
+
 	app.Action = func(ctx *cli.Context) error {
 		if ctx.NArg() > 0 {
 			return fmt.Errorf("unexpected positional arguments: %v", ctx.Args().Slice())
@@ -0,0 +1,180 @@
+// Copyright 2026 The HuaTuo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"huatuo-bamai/cmd/huatuo-apiserver/config"
+	"huatuo-bamai/internal/cohort"
+	"huatuo-bamai/internal/log"
+	"huatuo-bamai/internal/storage/driver"
+	"huatuo-bamai/internal/strutil"
+
+	"github.com/urfave/cli/v2"
+)
+
+const (
+	cliFlagWindow = "window"
+	cliFlagEnd    = "end"
+	cliFlagFormat = "format"
+	cliFlagOutput = "output"
+
+	cohortReportFormatJSON     = "json"
+	cohortReportFormatMarkdown = "markdown"
+)
+
+func cohortReportCommand(opts *Options) *cli.Command {
+	flags := []cli.Flag{
+		&cli.DurationFlag{
+			Name:  cliFlagWindow,
+			Value: 24 * time.Hour,
+			Usage: "compare the nodes over this window",
+		},
+		&cli.TimestampFlag{
+			Name:   cliFlagEnd,
+			Layout: time.RFC3339,
+			Usage:  "end of the window in RFC 3339, defaults to now",
+		},
+		&cli.StringFlag{
+			Name:  cliFlagFormat,
+			Value: cohortReportFormatJSON,
+			Usage: "report format, json or markdown",
+		},
+		&cli.StringFlag{
+			Name:    cliFlagOutput,
+			Aliases: []string{"o"},
+			Usage:   "output file, defaults to stdout",
+		},
+	}
+	for _, c := range []string{"a", "b"} {
+		flags = append(flags,
+			&cli.StringFlag{
+				Name:  c + "-name",
+				Value: c,
+				Usage: "name of cohort " + c,
+			},
+			&cli.StringFlag{
+				Name:  c + "-selector",
+				Usage: "cohort " + c + " is the hosts of the series matching this prometheus selector within the window",
+			},
+			&cli.StringSliceFlag{
+				Name:  c + "-hosts",
+				Usage: "hostnames of cohort " + c + ", added to those of the selector",
+			},
+		)
+	}
+
+	return &cli.Command{
+		Name:  "cohort-report",
+		Usage: "compare the metrics and events of two cohorts of nodes, e.g. kernel A and kernel B",
+		Flags: flags,
+		Action: func(ctx *cli.Context) error {
+			spec, err := cohortReportSpec(ctx, &opts.Config.CohortReport)
+			if err != nil {
+				return err
+			}
+
+			format := ctx.String(cliFlagFormat)
+			if format != cohortReportFormatJSON && format != cohortReportFormatMarkdown {
+				return fmt.Errorf("unknown report format %q", format)
+			}
+
+			es := &opts.Config.ElasticSearch
+			events, err := cohort.NewEvents(ctx.Context, &driver.Config{
+				Driver:      "elasticsearch",
+				ESAddresses: strutil.SplitCommaList(es.Address),
+				ESUsername:  es.Username,
+				ESPassword:  es.Password,
+				ESIndex:     es.Index,
+			})
+			if err != nil {
+				return fmt.Errorf("open event storage: %w", err)
+			}
+			defer func() {
+				if err := events.Close(ctx.Context); err != nil {
+					log.WithError(err).Warn("close event storage")
+				}
+			}()
+
+			prometheus := cohort.NewPrometheus(opts.Config.CohortReport.PrometheusAddress,
+				time.Duration(opts.Config.CohortReport.TimeoutSeconds)*time.Second)
+			report, err := cohort.Generate(ctx.Context, spec, prometheus, events)
+			if err != nil {
+				return fmt.Errorf("cohort report: %w", err)
+			}
+
+			var w io.Writer = os.Stdout
+			if output := ctx.String(cliFlagOutput); output != "" {
+				f, err := os.Create(output)
+				if err != nil {
+					return err
+				}
+				defer f.Close()
+				w = f
+			}
+
+			if format == cohortReportFormatMarkdown {
+				return report.WriteMarkdown(w)
+			}
+			encoder := json.NewEncoder(w)
+			encoder.SetIndent("", "  ")
+			return encoder.Encode(report)
+		},
+	}
+}
+
+func cohortReportSpec(ctx *cli.Context, cfg *config.CohortReportConfig) (*cohort.Spec, error) {
+	spec := &cohort.Spec{
+		End:         time.Now(),
+		Window:      ctx.Duration(cliFlagWindow),
+		Tracers:     cfg.Tracers,
+		Alpha:       cfg.Significance,
+		Concurrency: cfg.Concurrency,
+	}
+	if end := ctx.Timestamp(cliFlagEnd); end != nil {
+		spec.End = *end
+	}
+
+	for _, m := range cfg.Metrics {
+		spec.Metrics = append(spec.Metrics, cohort.Metric{Name: m.Name, Query: m.Query})
+	}
+	if len(spec.Metrics) == 0 {
+		spec.Metrics = cohort.DefaultMetrics
+	}
+	if len(spec.Tracers) == 0 {
+		spec.Tracers = cohort.DefaultTracers
+	}
+
+	for _, c := range []struct {
+		prefix string
+		cohort *cohort.Cohort
+	}{{"a", &spec.A}, {"b", &spec.B}} {
+		*c.cohort = cohort.Cohort{
+			Name:     ctx.String(c.prefix + "-name"),
+			Selector: ctx.String(c.prefix + "-selector"),
+			Hosts:    ctx.StringSlice(c.prefix + "-hosts"),
+		}
+		if c.cohort.Selector == "" && len(c.cohort.Hosts) == 0 {
+			return nil, fmt.Errorf("cohort %s needs --%s-selector or --%s-hosts", c.prefix, c.prefix, c.prefix)
+		}
+	}
+
+	return spec, nil
+}
@@ -0,0 +1,76 @@
+// Copyright 2026 The HuaTuo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"flag"
+	"reflect"
+	"testing"
+	"time"
+
+	"huatuo-bamai/cmd/huatuo-apiserver/config"
+	"huatuo-bamai/internal/cohort"
+
+	"github.com/urfave/cli/v2"
+)
+
+func cohortReportContext(t *testing.T, args ...string) *cli.Context {
+	t.Helper()
+
+	cmd := cohortReportCommand(&Options{})
+	flags := flag.NewFlagSet("test", flag.ContinueOnError)
+	for _, cliFlag := range cmd.Flags {
+		if err := cliFlag.Apply(flags); err != nil {
+			t.Fatalf("apply flag: %v", err)
+		}
+	}
+	if err := flags.Parse(args); err != nil {
+		t.Fatalf("parse flags: %v", err)
+	}
+	return cli.NewContext(cli.NewApp(), flags, nil)
+}
+
+func TestCohortReportSpec(t *testing.T) {
+	ctx := cohortReportContext(t,
+		"--a-name", "5.10",
+		"--a-selector", `huatuo_bamai_kernel_patch_boot_kernel_mismatch{running="5.10"}`,
+		"--b-hosts", "node-1", "--b-hosts", "node-2",
+		"--window", "6h",
+		"--end", "2026-05-01T00:00:00Z",
+	)
+	cfg := &config.CohortReportConfig{Significance: 0.01, Concurrency: 4}
+
+	spec, err := cohortReportSpec(ctx, cfg)
+	if err != nil {
+		t.Fatalf("cohortReportSpec() error = %v", err)
+	}
+	if spec.A.Name != "5.10" || spec.A.Selector == "" || spec.B.Name != "b" ||
+		!reflect.DeepEqual(spec.B.Hosts, []string{"node-1", "node-2"}) {
+		t.Errorf("cohorts = %+v, %+v", spec.A, spec.B)
+	}
+	if spec.Window != 6*time.Hour || !spec.End.Equal(time.Date(2026, 5, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("window = %v ending %v", spec.Window, spec.End)
+	}
+	if spec.Alpha != 0.01 || spec.Concurrency != 4 {
+		t.Errorf("alpha = %g, concurrency = %d", spec.Alpha, spec.Concurrency)
+	}
+	if !reflect.DeepEqual(spec.Metrics, cohort.DefaultMetrics) || !reflect.DeepEqual(spec.Tracers, cohort.DefaultTracers) {
+		t.Errorf("metrics = %v, tracers = %v, want the defaults", spec.Metrics, spec.Tracers)
+	}
+
+	if _, err := cohortReportSpec(cohortReportContext(t, "--a-hosts", "node-1"), cfg); err == nil {
+		t.Error("cohortReportSpec() without cohort b error = nil")
+	}
+}
@@ -49,6 +49,7 @@ type APIServerConfig struct {
 	ShutdownTimeoutSeconds   int    `default:"60"`
 	MaxHeaderBytes           int    `default:"1048576"`
 	MaxBodyBytes             int64  `default:"4194304"`
+	SlowRequestMilliseconds  int    `default:"1000"`
 	RateLimit                int    `default:"200"`
 	RateBurst                int    `default:"200"`
 }
@@ -80,6 +81,9 @@ type AgentConfig struct {
 	StatusRetryBackoffMillis  int `default:"100"`
 	StatusPollIntervalSeconds int `default:"5"`
 	MaxConsecutivePollErrors  int `default:"3"`
+	// SigningKeyFile holds the key signing the task requests, shared with
+	// the agents requiring them signed.
+	SigningKeyFile string
 }
 
 type ElasticSearchConfig struct {
@@ -89,6 +93,21 @@ type ElasticSearchConfig struct {
 	Index    string `default:"huatuo_bamai"`
 }
 
+// CohortReportConfig configures the cohort-report command, comparing the
+// metrics and events of two cohorts of nodes.
+type CohortReportConfig struct {
+	PrometheusAddress string  `default:"http://127.0.0.1:9090"`
+	TimeoutSeconds    int     `default:"30"`
+	Concurrency       int     `default:"8"`
+	Significance      float64 `default:"0.05"`
+	// Metrics and Tracers default to the built-in set when empty.
+	Metrics []struct {
+		Name  string
+		Query string
+	}
+	Tracers []string
+}
+
 // Validate rejects profiling settings that cannot produce a valid job.
 func (c ProfilingConfig) Validate() error {
 	if c.AggregationInterval <= 0 {
@@ -140,6 +159,8 @@ type Config struct {
 	ElasticSearch ElasticSearchConfig
 
 	Profiling ProfilingConfig
+
+	CohortReport CohortReportConfig
 }
 
 func (c *Config) Validate() error {
@@ -189,6 +210,9 @@ func (c *Config) Validate() error {
 	if err := c.ElasticSearch.Validate(); err != nil {
 		return fmt.Errorf("validating Elasticsearch config: %w", err)
 	}
+	if err := c.CohortReport.Validate(); err != nil {
+		return fmt.Errorf("validating cohort report config: %w", err)
+	}
 	return nil
 }
 
@@ -227,6 +251,7 @@ func (c *APIServerConfig) Validate() error {
 		{name: "shutdown timeout", value: int64(c.ShutdownTimeoutSeconds)},
 		{name: "max header bytes", value: int64(c.MaxHeaderBytes)},
 		{name: "max body bytes", value: c.MaxBodyBytes},
+		{name: "slow request threshold", value: int64(c.SlowRequestMilliseconds)},
 		{name: "rate limit", value: int64(c.RateLimit)},
 		{name: "rate burst", value: int64(c.RateBurst)},
 	}
@@ -276,6 +301,25 @@ func (c ElasticSearchConfig) Validate() error {
 	return nil
 }
 
+func (c *CohortReportConfig) Validate() error {
+	parsed, err := url.Parse(c.PrometheusAddress)
+	if err != nil || parsed.Host == "" || (parsed.Scheme != "http" && parsed.Scheme != "https") {
+		return fmt.Errorf("invalid prometheus address %q", c.PrometheusAddress)
+	}
+	if c.TimeoutSeconds <= 0 || c.Concurrency <= 0 {
+		return errors.New("timeout and concurrency must be greater than zero")
+	}
+	if c.Significance <= 0 || c.Significance >= 1 {
+		return fmt.Errorf("significance %g must be between 0 and 1", c.Significance)
+	}
+	for i, m := range c.Metrics {
+		if strings.TrimSpace(m.Name) == "" || strings.TrimSpace(m.Query) == "" {
+			return fmt.Errorf("metric %d: name and query are required", i)
+		}
+	}
+	return nil
+}
+
 func isHTTPMethod(value string) bool {
 	switch strings.ToUpper(value) {
 	case "GET", "HEAD", "POST", "PUT", "PATCH", "DELETE", "OPTIONS":
@@ -155,6 +155,47 @@ Index = "profiles"
 	}
 }
 
+func TestLoadFileCohortReportConfig(t *testing.T) {
+	configFile := filepath.Join(t.TempDir(), "apiserver.conf")
+	contents := []byte(`
+[[Auth.users]]
+ID = "test-token"
+IsAdmin = true
+
+[CohortReport]
+Tracers = ["softlockup"]
+
+[[CohortReport.Metrics]]
+Name = "load1"
+Query = "avg_over_time(huatuo_bamai_loadavg_load1[$window])"
+`)
+	if err := os.WriteFile(configFile, contents, 0o600); err != nil {
+		t.Fatalf("os.WriteFile() error = %v", err)
+	}
+
+	cfg, err := LoadFile(configFile)
+	if err != nil {
+		t.Fatalf("LoadFile() error = %v", err)
+	}
+	report := cfg.CohortReport
+	if report.PrometheusAddress != "http://127.0.0.1:9090" || report.Significance != 0.05 {
+		t.Errorf("CohortReport = %+v, want the default address and significance", report)
+	}
+	if len(report.Metrics) != 1 || report.Metrics[0].Name != "load1" || len(report.Tracers) != 1 {
+		t.Errorf("CohortReport metrics = %+v, tracers = %v", report.Metrics, report.Tracers)
+	}
+
+	report.Significance = 1
+	if err := report.Validate(); err == nil {
+		t.Error("Validate() with significance 1 error = nil")
+	}
+	report.Significance = 0.05
+	report.PrometheusAddress = "127.0.0.1:9090"
+	if err := report.Validate(); err == nil {
+		t.Error("Validate() without a scheme error = nil")
+	}
+}
+
 func TestAgentConfigValidate(t *testing.T) {
 	valid := AgentConfig{
 		Port:                      19704,
@@ -273,6 +314,7 @@ func TestConfigValidateRejectsDuplicateUsers(t *testing.T) {
 			ShutdownTimeoutSeconds:   60,
 			MaxHeaderBytes:           1024,
 			MaxBodyBytes:             1024,
+			SlowRequestMilliseconds:  1000,
 			RateLimit:                10,
 			RateBurst:                10,
 		},
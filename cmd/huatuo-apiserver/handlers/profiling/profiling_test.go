@@ -95,8 +95,11 @@ func TestCapabilities(t *testing.T) {
 		t.Errorf("MemoryLanguages len = %d, want 4 (c++, c, go, java)", len(resp.MemoryLanguages))
 	}
 
-	if len(resp.MemoryModes) != 5 {
-		t.Errorf("MemoryModes len = %d, want 5", len(resp.MemoryModes))
+	if len(resp.MemoryModes) != 6 {
+		t.Errorf("MemoryModes len = %d, want 6", len(resp.MemoryModes))
+	}
+	if _, ok := resp.MemoryModes["NATIVE_VIRTUAL_USAGE"]; !ok {
+		t.Errorf("MemoryModes missing NATIVE_VIRTUAL_USAGE")
 	}
 	if _, ok := resp.MemoryModes["NATIVE_PHYSICAL_ALLOC"]; !ok {
 		t.Errorf("MemoryModes missing NATIVE_PHYSICAL_ALLOC")
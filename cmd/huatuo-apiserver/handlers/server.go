@@ -31,24 +31,25 @@ import (
 
 // ServerOptions groups the dependencies required to start the API server.
 type ServerOptions struct {
-	Addr                string
-	PromReg             *prometheus.Registry
-	TraceJobManager     trace.JobManager
-	ProfilingJobManager profiling.JobManager
-	ProfileService      profiling.ProfileQueryService
-	ProfilingConfig     profiling.Config
-	AuthUsers           []server.UserConfig
-	EnablePProf         bool
-	VersionInfo         *version.Info
-	RateLimit           rate.Limit
-	RateBurst           int
-	ReadHeaderTimeout   time.Duration
-	ReadTimeout         time.Duration
-	WriteTimeout        time.Duration
-	IdleTimeout         time.Duration
-	MaxHeaderBytes      int
-	MaxBodyBytes        int64
-	Ready               func(context.Context) error
+	Addr                 string
+	PromReg              *prometheus.Registry
+	TraceJobManager      trace.JobManager
+	ProfilingJobManager  profiling.JobManager
+	ProfileService       profiling.ProfileQueryService
+	ProfilingConfig      profiling.Config
+	AuthUsers            []server.UserConfig
+	EnablePProf          bool
+	VersionInfo          *version.Info
+	RateLimit            rate.Limit
+	RateBurst            int
+	ReadHeaderTimeout    time.Duration
+	ReadTimeout          time.Duration
+	WriteTimeout         time.Duration
+	IdleTimeout          time.Duration
+	MaxHeaderBytes       int
+	MaxBodyBytes         int64
+	SlowRequestThreshold time.Duration
+	Ready                func(context.Context) error
 }
 
 // RunningServer exposes the lifecycle of the API listener.
@@ -80,15 +81,16 @@ func Start(opts *ServerOptions) (RunningServer, error) {
 		AdminPaths: []string{
 			"/v1/profiles/flamegraph/**",
 		},
-		PromReg:           opts.PromReg,
-		VersionInfo:       opts.VersionInfo,
-		ReadHeaderTimeout: opts.ReadHeaderTimeout,
-		ReadTimeout:       opts.ReadTimeout,
-		WriteTimeout:      opts.WriteTimeout,
-		IdleTimeout:       opts.IdleTimeout,
-		MaxHeaderBytes:    opts.MaxHeaderBytes,
-		MaxBodyBytes:      opts.MaxBodyBytes,
-		Ready:             opts.Ready,
+		PromReg:              opts.PromReg,
+		VersionInfo:          opts.VersionInfo,
+		ReadHeaderTimeout:    opts.ReadHeaderTimeout,
+		ReadTimeout:          opts.ReadTimeout,
+		WriteTimeout:         opts.WriteTimeout,
+		IdleTimeout:          opts.IdleTimeout,
+		MaxHeaderBytes:       opts.MaxHeaderBytes,
+		MaxBodyBytes:         opts.MaxBodyBytes,
+		SlowRequestThreshold: opts.SlowRequestThreshold,
+		Ready:                opts.Ready,
 	})
 
 	// Register trace routes
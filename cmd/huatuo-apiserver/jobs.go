@@ -15,20 +15,34 @@
 package main
 
 import (
+	"bytes"
 	"context"
 	"fmt"
+	"os"
 	"time"
 
 	"huatuo-bamai/internal/job"
 )
 
 func setupJobManagers(ctx context.Context, d *Daemon) (func(context.Context) error, error) {
+	var signingKey []byte
+	if path := d.opts.Config.Agent.SigningKeyFile; path != "" {
+		key, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("read agent signing key: %w", err)
+		}
+		if signingKey = bytes.TrimSpace(key); len(signingKey) == 0 {
+			return nil, fmt.Errorf("agent signing key %s is empty", path)
+		}
+	}
+
 	nodeAgent := job.NewHTTPNodeAgent(job.HTTPNodeAgentConfig{
 		Port:                d.opts.Config.Agent.Port,
 		RequestTimeout:      time.Duration(d.opts.Config.Agent.RequestTimeoutSeconds) * time.Second,
 		StatusRetryAttempts: d.opts.Config.Agent.StatusRetryAttempts,
 		StatusRetryBackoff:  time.Duration(d.opts.Config.Agent.StatusRetryBackoffMillis) * time.Millisecond,
 		Observe:             d.agentObserver,
+		SigningKey:          signingKey,
 	})
 	profilingPolicy := job.TypePolicy{
 		Group:          "profiling",
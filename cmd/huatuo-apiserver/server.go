@@ -41,17 +41,18 @@ func startHandlers(_ context.Context, d *Daemon) (func(context.Context) error, e
 			MaxProfilerProcs:    d.opts.Config.Profiling.MaxProfilerProcs,
 			FlameGraphBaseURL:   d.opts.Config.Profiling.FlameGraphBaseURL,
 		},
-		AuthUsers:         authUsers(d.opts.Config.Auth.Users),
-		EnablePProf:       d.opts.EnablePProf,
-		VersionInfo:       &d.opts.VersionInfo,
-		RateLimit:         rate.Limit(d.opts.Config.APIServer.RateLimit),
-		RateBurst:         d.opts.Config.APIServer.RateBurst,
-		ReadHeaderTimeout: time.Duration(d.opts.Config.APIServer.ReadHeaderTimeoutSeconds) * time.Second,
-		ReadTimeout:       time.Duration(d.opts.Config.APIServer.ReadTimeoutSeconds) * time.Second,
-		WriteTimeout:      time.Duration(d.opts.Config.APIServer.WriteTimeoutSeconds) * time.Second,
-		IdleTimeout:       time.Duration(d.opts.Config.APIServer.IdleTimeoutSeconds) * time.Second,
-		MaxHeaderBytes:    d.opts.Config.APIServer.MaxHeaderBytes,
-		MaxBodyBytes:      d.opts.Config.APIServer.MaxBodyBytes,
+		AuthUsers:            authUsers(d.opts.Config.Auth.Users),
+		EnablePProf:          d.opts.EnablePProf,
+		VersionInfo:          &d.opts.VersionInfo,
+		RateLimit:            rate.Limit(d.opts.Config.APIServer.RateLimit),
+		RateBurst:            d.opts.Config.APIServer.RateBurst,
+		ReadHeaderTimeout:    time.Duration(d.opts.Config.APIServer.ReadHeaderTimeoutSeconds) * time.Second,
+		ReadTimeout:          time.Duration(d.opts.Config.APIServer.ReadTimeoutSeconds) * time.Second,
+		WriteTimeout:         time.Duration(d.opts.Config.APIServer.WriteTimeoutSeconds) * time.Second,
+		IdleTimeout:          time.Duration(d.opts.Config.APIServer.IdleTimeoutSeconds) * time.Second,
+		MaxHeaderBytes:       d.opts.Config.APIServer.MaxHeaderBytes,
+		MaxBodyBytes:         d.opts.Config.APIServer.MaxBodyBytes,
+		SlowRequestThreshold: time.Duration(d.opts.Config.APIServer.SlowRequestMilliseconds) * time.Millisecond,
 		Ready: func(ctx context.Context) error {
 			return errors.Join(d.jobManager.Ready(ctx), d.profileService.Ready(ctx))
 		},
@@ -0,0 +1,46 @@
+// Copyright 2026 The HuaTuo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"huatuo-bamai/cmd/huatuo-bamai/config"
+	"huatuo-bamai/internal/affinity"
+)
+
+// setupAffinity pins the agent threads before the BPF readers start, the
+// threads created later inherit the mask.
+func setupAffinity(_ *Daemon) (func(context.Context) error, error) {
+	cfg := config.Get().RuntimeAffinity
+
+	cpus, err := affinity.CPUs(affinity.Config{CPUs: cfg.CPUs, NUMANode: cfg.NUMANode})
+	if err != nil {
+		return nil, fmt.Errorf("housekeeping cpus: %w", err)
+	}
+	if cpus == nil {
+		return nil, nil
+	}
+
+	if err := affinity.Start(cpus); err != nil {
+		return nil, err
+	}
+
+	return func(context.Context) error {
+		affinity.Stop()
+		return nil
+	}, nil
+}
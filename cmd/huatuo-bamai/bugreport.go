@@ -0,0 +1,127 @@
+// Copyright 2026 The HuaTuo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"time"
+
+	"huatuo-bamai/cmd/huatuo-bamai/config"
+	"huatuo-bamai/cmd/huatuo-bamai/handlers"
+	"huatuo-bamai/internal/bugreport"
+
+	"github.com/urfave/cli/v2"
+)
+
+const (
+	cliFlagOutput = "output"
+
+	bugreportAgentTimeout = 2 * time.Minute
+)
+
+func bugreportCommand(opts *Options) *cli.Command {
+	return &cli.Command{
+		Name:  "bugreport",
+		Usage: "collect logs, config, tracer states, recent events and host environment into a tarball",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:    cliFlagOutput,
+				Aliases: []string{"o"},
+				Usage:   "output file, defaults to huatuo-bugreport-<hostname>-<time>.tar.gz",
+			},
+		},
+		Action: func(ctx *cli.Context) error {
+			name := handlers.BugreportName(time.Now())
+			output := ctx.String(cliFlagOutput)
+			if output == "" {
+				output = name + ".tar.gz"
+			}
+
+			f, err := os.Create(output)
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+
+			// the running agent knows the tracer states, recent events and
+			// bpf load failures, fall back to what can be read offline.
+			err = fetchAgentBugreport(ctx.Context, f)
+			if err == nil {
+				fmt.Printf("bugreport written to %s\n", output)
+				return nil
+			}
+			fmt.Printf("agent not reachable, collecting offline bugreport: %v\n", err)
+
+			if err := f.Truncate(0); err != nil {
+				return err
+			}
+			if _, err := f.Seek(0, io.SeekStart); err != nil {
+				return err
+			}
+
+			if err := bugreport.Write(ctx.Context, f, name, handlers.BugreportSources(nil, &opts.VersionInfo)); err != nil {
+				return fmt.Errorf("write bugreport: %w", err)
+			}
+
+			fmt.Printf("bugreport written to %s\n", output)
+			return nil
+		},
+	}
+}
+
+// agentURL is the URL of the path on the API server of the running agent.
+func agentURL(path string) (string, error) {
+	host, port, err := net.SplitHostPort(config.Get().APIServer.TCPAddr)
+	if err != nil {
+		return "", err
+	}
+	if host == "" {
+		host = "127.0.0.1"
+	}
+	return fmt.Sprintf("http://%s%s", net.JoinHostPort(host, port), path), nil
+}
+
+func fetchAgentBugreport(ctx context.Context, w io.Writer) error {
+	url, err := agentURL("/bugreport")
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, bugreportAgentTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, http.NoBody)
+	if err != nil {
+		return err
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET %s: %s", url, resp.Status)
+	}
+
+	_, err = io.Copy(w, resp.Body)
+	return err
+}
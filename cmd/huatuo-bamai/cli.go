@@ -43,6 +43,7 @@ const (
 	cliFlagLogDebug       = "log-debug"
 	cliFlagDryRun         = "dry-run"
 	cliFlagProcfsPrefix   = "procfs-prefix"
+	cliFlagTakeover       = "takeover"
 )
 
 // Options holds all CLI-derived configuration. Populated by FromContext
@@ -61,6 +62,7 @@ type Options struct {
 	LogDebug       bool
 	DryRun         bool
 	ProcfsPrefix   string
+	Takeover       bool
 	VersionInfo    version.Info
 }
 
@@ -76,9 +78,14 @@ func buildCommand(seed version.Seed) *cli.App {
 		if err := opts.FromContext(ctx); err != nil {
 			return err
 		}
+		if ctx.Args().First() == configCommandName {
+			return nil
+		}
 		return configureRuntime(opts)
 	}
 
+	app.Commands = []*cli.Command{bugreportCommand(opts), configCommand(), diffConfigCommand()}
+
 	app.Action = func(ctx *cli.Context) error {
 		if ctx.NArg() > 0 {
 			return fmt.Errorf("unexpected positional arguments: %v", ctx.Args().Slice())
@@ -113,9 +120,8 @@ func (o *Options) AddFlags(app *cli.App) {
 			Usage: "tools bin dir",
 		},
 		&cli.StringFlag{
-			Name:     cliFlagRegion,
-			Required: true,
-			Usage:    "the host and containers are in this region",
+			Name:  cliFlagRegion,
+			Usage: "the host and containers are in this region, required unless resolved by Host.RegionSources",
 		},
 		&cli.BoolFlag{
 			Name:  cliFlagDisableKubelet,
@@ -148,6 +154,10 @@ func (o *Options) AddFlags(app *cli.App) {
 			Name:  cliFlagProcfsPrefix,
 			Usage: "procfs prefix for default mountpoint e.g. /proc /sys and /dev",
 		},
+		&cli.BoolFlag{
+			Name:  cliFlagTakeover,
+			Usage: "terminate the running instance and take its state dir, storage and port over, e.g. to upgrade",
+		},
 	}
 }
 
@@ -162,6 +172,7 @@ func (o *Options) FromContext(ctx *cli.Context) error {
 	o.LogDebug = ctx.Bool(cliFlagLogDebug)
 	o.DryRun = ctx.Bool(cliFlagDryRun)
 	o.ProcfsPrefix = ctx.String(cliFlagProcfsPrefix)
+	o.Takeover = ctx.Bool(cliFlagTakeover)
 
 	var err error
 	if o.ConfigDir, err = resolveOptionDir(ctx, cliFlagConfigDir); err != nil {
@@ -15,6 +15,8 @@
 package config
 
 import (
+	"reflect"
+
 	"huatuo-bamai/core/autotracing"
 	"huatuo-bamai/core/events"
 	collector "huatuo-bamai/core/metrics"
@@ -35,27 +37,243 @@ type BamaiConfig struct {
 	}
 
 	RuntimeCgroup struct {
-		LimitInitCPU float64 `default:"0.5"`
-		LimitCPU     float64 `default:"2.0"`
-		LimitMem     int64   `default:"2048"`
+		LimitInitCPU float64 `default:"0.5" min:"0"`
+		LimitCPU     float64 `default:"2.0" min:"0"`
+		LimitMem     int64   `default:"2048" min:"0"`
+	}
+
+	// RuntimeAffinity pins the agent threads to the housekeeping CPUs, a
+	// cpu list such as "0-1,48-49", or to all the CPUs of NUMANode. Both
+	// unset leaves the affinity alone.
+	RuntimeAffinity struct {
+		CPUs     string
+		NUMANode int `default:"-1" min:"-1"`
+	}
+
+	// Instance runs one agent per StateDir, --takeover replaces the
+	// running one within TakeoverTimeout seconds.
+	Instance struct {
+		StateDir        string `default:"/var/run/huatuo-bamai"`
+		TakeoverTimeout int    `default:"30" min:"1"`
 	}
 
 	Storage struct {
 		ES struct {
-			Address            string `default:"http://127.0.0.1:9200"`
-			Username, Password string
-			Index              string `default:"huatuo_bamai"`
+			Address  string `default:"http://127.0.0.1:9200"`
+			Username string
+			Password string `secret:"true"`
+			Index    string `default:"huatuo_bamai"`
+			// Routes send some tracers to their own daily indices,
+			// Retention is in days.
+			Routes []struct {
+				Name      string
+				Tracers   []string
+				Retention int `min:"0"`
+			} `toml:"Routes,omitempty"`
+			// QueueSize is the documents queued in memory. The events
+			// beyond it, or failing after the retries, spill to
+			// SpillPath, empty drops them. SpillMaxSize is in MB.
+			QueueSize    int `default:"10000"`
+			SpillPath    string
+			SpillMaxSize int `default:"1024" min:"0"`
+			// IndexPattern writes the events not routed to time-suffixed
+			// indices, e.g. "huatuo_bamai-%Y.%m.%d". ILMPolicy is
+			// installed at startup with an index template, it deletes
+			// the indices after Retention days. RolloverSize in GB or
+			// RolloverAge in days roll the Index alias over instead.
+			IndexPattern string
+			ILMPolicy    string
+			Retention    int `min:"0"`
+			RolloverSize int `min:"0"`
+			RolloverAge  int `min:"0"`
+		}
+
+		// ClickHouse stores the events in Table, empty Address disables
+		// it. FlushInterval is in seconds, Retention in days.
+		ClickHouse struct {
+			Address            string
+			Username           string
+			Password           string `secret:"true"`
+			Database           string `default:"default"`
+			Table              string `default:"huatuo_events"`
+			CAFile             string
+			InsecureSkipVerify bool
+			BatchSize          int `default:"1000"`
+			FlushInterval      int `default:"1"`
+			Retention          int `min:"0"`
+		}
+
+		// Loki pushes the events as log lines, empty Address disables
+		// it. TenantID is the X-Scope-OrgID, FlushInterval is in seconds.
+		Loki struct {
+			Address            string
+			Username           string
+			Password           string `secret:"true"`
+			TenantID           string
+			CAFile             string
+			InsecureSkipVerify bool
+			BatchSize          int `default:"1000"`
+			FlushInterval      int `default:"1"`
 		}
 
 		LocalFile struct {
 			Path         string `default:"huatuo-local"`
-			RotationSize int    `default:"100"`
-			MaxRotation  int    `default:"10"`
+			RotationSize int    `default:"100" min:"0"`
+			MaxRotation  int    `default:"10" min:"0"`
+			MaxAgeDays   int    `min:"0"`
+			Compression  string `enum:",gzip,zstd"`
+			SyncInterval int    `min:"0"`
+		}
+
+		// SQLite stores the events in a local database, queried with
+		// GET /v1/events, empty Path disables it. MaxAge is in days,
+		// zero keeps the events forever.
+		SQLite struct {
+			Path   string
+			MaxAge int `default:"7" min:"0"`
+		}
+
+		// DeadLetter keeps the events a backend fails to store, after
+		// its retries, in Path and writes them again every
+		// RedriveInterval seconds, empty Path drops them. MaxSize is in
+		// MB per backend, zero is unbounded.
+		DeadLetter struct {
+			Path            string
+			MaxSize         int `default:"1024" min:"0"`
+			RedriveInterval int `default:"60" min:"1"`
+		}
+
+		// Routing sends the events of the Tracers, names or globs, to
+		// the Backends only, the first matching rule wins.
+		Routing []struct {
+			Tracers  []string
+			Backends []string `enum:"elasticsearch,clickhouse,loki,localfile,sqlite"`
+		} `toml:"Routing,omitempty"`
+
+		// Enrichment rules run exprlite expressions on the documents of a
+		// tracer before they are stored.
+		Enrichment []struct {
+			Tracer string
+			Drop   string
+			Fields []struct {
+				Name string
+				Expr string
+			}
+		} `toml:"Enrichment,omitempty"`
+
+		// Sampling samples the events of the high volume tracers before
+		// they are stored, Rate is in events per second.
+		Sampling []struct {
+			Tracers     []string
+			Every       int     `min:"0"`
+			Probability float64 `min:"0" max:"1"`
+			Rate        float64 `min:"0"`
+			Burst       int     `min:"0"`
+			Always      string
+		} `toml:"Sampling,omitempty"`
+
+		// ContextCapture snapshots host and cgroup files into the
+		// documents of the triggered tracers.
+		ContextCapture struct {
+			Tracers      []string
+			Files        []string
+			CgroupFiles  []string
+			Rate         float64 `default:"1"`
+			Burst        int     `default:"5" min:"1"`
+			MaxFileBytes int     `default:"16384" min:"1"`
+		}
+
+		// Backpressure samples the events and pauses the event tracers
+		// while the tracing stores lag, backlogs are in documents and
+		// Interval in seconds.
+		Backpressure struct {
+			Tracers       []string
+			SampleBacklog int64 `default:"20000" min:"0"`
+			SampleRate    int   `default:"10" min:"1"`
+			PauseBacklog  int64 `default:"100000" min:"0"`
+			ResumeBacklog int64 `default:"5000" min:"0"`
+			Interval      int   `default:"5" min:"1"`
+		}
+
+		// Correlation groups the events of a node or a container into
+		// incidents, Window and MaxDuration are in seconds.
+		Correlation struct {
+			Tracers     []string
+			Window      int `min:"0"`
+			MaxDuration int `default:"300" min:"0"`
+			MinEvents   int `default:"2" min:"1"`
+		}
+
+		// Audit is the sqlite database of the tracer start/stop and
+		// config changes, empty Path disables it.
+		Audit struct {
+			Path string `default:"huatuo-audit.db"`
+		}
+
+		// State is the sqlite database the tracers checkpoint their
+		// state in, empty Path disables it. MaxAge is in seconds.
+		State struct {
+			Path   string `default:"huatuo-state.db"`
+			MaxAge int    `default:"86400" min:"0"`
+		}
+	}
+
+	// OTLP exports to an OpenTelemetry collector over gRPC, empty
+	// Endpoint disables it. Timeout, FlushInterval and Interval are in
+	// seconds.
+	OTLP struct {
+		Endpoint           string
+		Headers            map[string]string `toml:"Headers,omitempty" secret:"true"`
+		Insecure           bool
+		CAFile             string
+		CertFile           string
+		KeyFile            string
+		InsecureSkipVerify bool
+		Timeout            int `default:"10"`
+
+		// Logs exports the events as log records.
+		Logs struct {
+			Enable        bool `default:"true"`
+			BatchSize     int  `default:"512"`
+			FlushInterval int  `default:"1"`
+		}
+
+		// Metrics pushes the metrics of /metrics every Interval.
+		Metrics struct {
+			Enable   bool
+			Interval int `default:"60"`
 		}
 	}
 
+	// RemoteWrite pushes the metrics of /metrics to a Prometheus remote
+	// write endpoint, for the nodes which cannot be scraped, empty URL
+	// disables it. Timeout and Interval are in seconds.
+	RemoteWrite struct {
+		URL                string
+		Username           string
+		Password           string            `secret:"true"`
+		BearerToken        string            `secret:"true"`
+		Headers            map[string]string `toml:"Headers,omitempty" secret:"true"`
+		ExternalLabels     map[string]string `toml:"ExternalLabels,omitempty"`
+		CAFile             string
+		CertFile           string
+		KeyFile            string
+		InsecureSkipVerify bool
+		Timeout            int `default:"10"`
+		Interval           int `default:"30"`
+		BatchSize          int `default:"2000"`
+	}
+
 	Task struct {
-		MaxRunningTask int `default:"10"`
+		MaxRunningTask int `default:"10" min:"1"`
+		// SigningKeyFile holds the key shared with the central server,
+		// the /tasks requests must then be signed with it, within
+		// SignatureMaxAge seconds.
+		SigningKeyFile  string
+		SignatureMaxAge int `default:"300" min:"1"`
+		// Allowlist restricts the tasks to these tracer binaries, every
+		// one of bin/ if empty.
+		Allowlist []string
 	}
 
 	EventsWatch struct {
@@ -63,11 +281,101 @@ type BamaiConfig struct {
 		KeepAliveInterval int `default:"30"`
 	}
 
+	// EventTemplates render the events into human readable summaries,
+	// by Go text/template per tracer, "*" for the others.
+	EventTemplates []struct {
+		Tracer   string
+		Template string
+	} `toml:"EventTemplates,omitempty"`
+
 	Pod struct {
-		KubeletReadOnlyPort   uint32 `default:"10255"`
-		KubeletAuthorizedPort uint32 `default:"10250"`
+		KubeletReadOnlyPort   uint32 `default:"10255" max:"65535"`
+		KubeletAuthorizedPort uint32 `default:"10250" max:"65535"`
 		KubeletClientCertPath string
-		DockerAPIVersion      string `default:"1.24"`
+		// KubeletTokenPath authenticates to the authorized port by the
+		// bearer token in the file, e.g. of the service account.
+		// KubeletCAPath verifies the serving certificate of kubelet for
+		// KubeletServerName, the hostname if empty.
+		KubeletTokenPath  string
+		KubeletCAPath     string
+		KubeletServerName string
+		DockerAPIVersion  string `default:"1.24"`
+
+		// ContainerRuntime forces the runtime of the kubelet containers,
+		// detected from the pods if empty. CRIOSocket is the cri-o socket.
+		ContainerRuntime string `enum:",docker,containerd,cri-o"`
+		CRIOSocket       string `default:"/var/run/crio/crio.sock"`
+		// CgroupDriver forces the cgroup driver of kubelet, read from
+		// its configz or config files, then the cgroups, if empty.
+		CgroupDriver string `enum:",systemd,cgroupfs"`
+
+		// Mode "kubelet" syncs the containers from kubelet only,
+		// "standalone" resolves them from the CRI runtime, Docker and
+		// the cgroups, empty is standalone while kubelet is unreachable.
+		Mode       string `enum:",kubelet,standalone"`
+		Standalone struct {
+			CRIEndpoint string
+			Namespace   string
+		}
+
+		// Systemd resolves the units of Slices matching Units as
+		// containers, empty Units disables it.
+		Systemd struct {
+			Slices    []string
+			Units     []string
+			Namespace string
+		}
+
+		// CgroupPaths resolve the child cgroups of Root matching Pattern
+		// as containers, e.g. the yarn or mesos ones.
+		CgroupPaths []struct {
+			Root      string
+			Pattern   string
+			Namespace string
+		} `toml:"CgroupPaths,omitempty"`
+
+		// Docker resolves the running containers of the Docker Engine
+		// at Host, those of kubelet aside, as containers.
+		Docker struct {
+			Enable    bool
+			Host      string
+			Namespace string
+		}
+
+		// NamespaceWhitelist and PodNameBlacklist are the globs of the
+		// pod namespaces tracked and of the pod names not tracked, every
+		// namespace is tracked if NamespaceWhitelist is empty.
+		NamespaceWhitelist []string
+		PodNameBlacklist   []string
+
+		// Metadata attaches the pod labels and annotations, e.g. app or
+		// team, to the container metrics and the tracer documents.
+		Metadata struct {
+			Labels      []string
+			Annotations []string
+		}
+	}
+
+	// Host resolves the hostname, region and kubernetes node name which
+	// label the metrics and events.
+	Host struct {
+		HostnameSources []string
+		RegionSources   []string
+		NodeNameEnv     string `default:"NODE_NAME"`
+		Cloud           string
+		RefreshInterval int `default:"300" min:"0"`
+	}
+
+	// Quota limits the events and metric series of each kubernetes
+	// namespace, zero is unlimited.
+	Quota struct {
+		EventsPerMinute int `min:"0"`
+		MetricSeries    int `min:"0"`
+		Namespaces      []struct {
+			Name            string
+			EventsPerMinute int `min:"0"`
+			MetricSeries    int `min:"0"`
+		} `toml:"Namespaces,omitempty"`
 	}
 
 	AutoTracing     autotracing.Config
@@ -94,6 +402,12 @@ func Load(path string) error {
 	return nil
 }
 
+// Schema returns the JSON Schema of the huatuo-bamai config file, with
+// the defaults and the bounds checked by Load.
+func Schema() (*internalconfig.JSONSchema, error) {
+	return internalconfig.Schema(&BamaiConfig{}, "huatuo-bamai.conf")
+}
+
 // Get returns the bamai configuration.
 func Get() *BamaiConfig {
 	return cfg
@@ -108,6 +422,22 @@ func Set(key string, val any) error {
 	return nil
 }
 
+// TracerConfig returns the config section of the tracer or the collector,
+// e.g. "EventTracing.Softirq" for softirq_tracing, found by the tracer tag
+// of the sections.
+func TracerConfig(c *BamaiConfig, tracer string) (section string, value any, ok bool) {
+	root := reflect.ValueOf(c).Elem()
+	for _, module := range []string{"AutoTracing", "EventTracing", "MetricCollector"} {
+		v := root.FieldByName(module)
+		for i := 0; i < v.NumField(); i++ {
+			if v.Type().Field(i).Tag.Get("tracer") == tracer {
+				return module + "." + v.Type().Field(i).Name, v.Field(i).Interface(), true
+			}
+		}
+	}
+	return "", nil, false
+}
+
 // Sync writes the config back to the current config file.
 func Sync() error {
 	return internalconfig.Sync(configFile, cfg)
@@ -164,3 +164,71 @@ ExcludedOnContainer = "writeback"
 		t.Errorf("synced config should persist MetricCollector.Vmstat.IncludedOnContainer, got %s", string(raw))
 	}
 }
+
+func TestLoadShippedConfig(t *testing.T) {
+	if err := Load("../../../huatuo-bamai.conf"); err != nil {
+		t.Fatalf("Load huatuo-bamai.conf returned error: %v", err)
+	}
+}
+
+func TestTracerConfig(t *testing.T) {
+	c := &BamaiConfig{}
+	c.EventTracing.Softirq.DisabledThreshold = 20000000
+	c.MetricCollector.Vmstat.IncludedOnHost = "allocstall"
+
+	section, value, ok := TracerConfig(c, "softirq_tracing")
+	if !ok || section != "EventTracing.Softirq" || value != c.EventTracing.Softirq {
+		t.Errorf("TracerConfig(softirq_tracing) = %s, %+v, %v", section, value, ok)
+	}
+	if section, _, ok := TracerConfig(c, "memory_vmstat"); !ok || section != "MetricCollector.Vmstat" {
+		t.Errorf("TracerConfig(memory_vmstat) = %s, %v", section, ok)
+	}
+	if section, _, ok := TracerConfig(c, "cpuidle"); !ok || section != "AutoTracing.CPUIdle" {
+		t.Errorf("TracerConfig(cpuidle) = %s, %v", section, ok)
+	}
+	if _, _, ok := TracerConfig(c, "hungtask"); ok {
+		t.Error("TracerConfig(hungtask) found a section")
+	}
+}
+
+func TestLoadOutOfBounds(t *testing.T) {
+	path := writeConfigFile(t, t.TempDir(), "huatuo-bamai.conf", `
+[Storage.LocalFile]
+Compression = "lz4"
+
+[[Storage.Routing]]
+Tracers = ["oom"]
+Backends = ["kafka"]
+
+[Pod]
+KubeletReadOnlyPort = 70000
+`)
+
+	err := Load(path)
+	if err == nil {
+		t.Fatal("Load returned no error")
+	}
+	for _, field := range []string{"Storage.LocalFile.Compression", "Storage.Routing[0].Backends[0]", "Pod.KubeletReadOnlyPort"} {
+		if !strings.Contains(err.Error(), field) {
+			t.Errorf("Load error %q should name %s", err, field)
+		}
+	}
+}
+
+func TestSchema(t *testing.T) {
+	schema, err := Schema()
+	if err != nil {
+		t.Fatalf("Schema returned error: %v", err)
+	}
+
+	localFile := schema.Properties["Storage"].Properties["LocalFile"]
+	if localFile == nil || len(localFile.Properties["Compression"].Enum) != 3 {
+		t.Errorf("unexpected Storage.LocalFile schema: %+v", localFile)
+	}
+	if def := localFile.Properties["RotationSize"].Default; def != int64(100) {
+		t.Errorf("unexpected Storage.LocalFile.RotationSize default: %v", def)
+	}
+	if schema.Properties["EventTracing"] == nil || schema.Properties["MetricCollector"] == nil {
+		t.Error("schema should include the core module configs")
+	}
+}
@@ -0,0 +1,157 @@
+// Copyright 2026 The HuaTuo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"sort"
+	"time"
+
+	"huatuo-bamai/cmd/huatuo-bamai/config"
+	"huatuo-bamai/cmd/huatuo-bamai/handlers"
+	internalconfig "huatuo-bamai/internal/config"
+	"huatuo-bamai/internal/server/response"
+
+	"github.com/urfave/cli/v2"
+)
+
+const diffConfigAgentTimeout = 30 * time.Second
+
+func diffConfigCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "diff-config",
+		Usage: "compare the state of the running agent with the config file, to spot the changes not applied",
+		Action: func(ctx *cli.Context) error {
+			state, err := fetchAgentState(ctx.Context)
+			if err != nil {
+				return fmt.Errorf("fetch agent state: %w", err)
+			}
+
+			drifts, err := configDrifts(state, config.Get())
+			if err != nil {
+				return err
+			}
+			if len(drifts) == 0 {
+				fmt.Println("the running agent matches the config file")
+				return nil
+			}
+
+			for _, drift := range drifts {
+				fmt.Println(drift)
+			}
+			return cli.Exit(fmt.Sprintf("%d differences between the running agent and the config file", len(drifts)), 1)
+		},
+	}
+}
+
+func fetchAgentState(ctx context.Context) (*handlers.State, error) {
+	url, err := agentURL("/api/state")
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, diffConfigAgentTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, http.NoBody)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("GET %s: %s", url, resp.Status)
+	}
+
+	state := &handlers.State{}
+	dec := json.NewDecoder(resp.Body)
+	// the configs of the tracers are untyped, keep their numbers exact.
+	dec.UseNumber()
+	if err := dec.Decode(&response.Response{Data: state}); err != nil {
+		return nil, fmt.Errorf("decode %s: %w", url, err)
+	}
+	return state, nil
+}
+
+// configDrifts lists what differs between the running agent and the config
+// file: the running config, the config each tracer started with and the
+// tracers blacklisted. The credentials are masked on both sides, their
+// changes are not seen.
+func configDrifts(state *handlers.State, file *config.BamaiConfig) ([]string, error) {
+	var drifts []string
+
+	diffs, err := internalconfig.Diff(state.Config, internalconfig.Mask(file))
+	if err != nil {
+		return nil, fmt.Errorf("diff config: %w", err)
+	}
+	for _, d := range diffs {
+		drifts = append(drifts, fmt.Sprintf("%s: running %s, file %s", d.Path, driftValue(d.A), driftValue(d.B)))
+	}
+
+	names := make([]string, 0, len(state.Tracers))
+	for name := range state.Tracers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		tracer := state.Tracers[name]
+		blacklisted := slices.Contains(file.BlackList, name)
+		switch {
+		case tracer.Status == "disabled" && !blacklisted:
+			drifts = append(drifts, fmt.Sprintf("tracer %s: disabled, not blacklisted in the file", name))
+		case tracer.LifecycleSnapshot != nil && tracer.IsRunning && blacklisted:
+			drifts = append(drifts, fmt.Sprintf("tracer %s: running, blacklisted in the file", name))
+		}
+
+		if tracer.LifecycleSnapshot == nil || tracer.Config == nil {
+			continue
+		}
+		section, value, ok := config.TracerConfig(file, name)
+		if !ok {
+			continue
+		}
+		diffs, err := internalconfig.Diff(tracer.Config, value)
+		if err != nil {
+			return nil, fmt.Errorf("diff config of tracer %s: %w", name, err)
+		}
+		for _, d := range diffs {
+			drifts = append(drifts, fmt.Sprintf("tracer %s: %s.%s: started with %s, file %s",
+				name, section, d.Path, driftValue(d.A), driftValue(d.B)))
+		}
+	}
+
+	return drifts, nil
+}
+
+func driftValue(v any) string {
+	if v == nil {
+		return "unset"
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(data)
+}
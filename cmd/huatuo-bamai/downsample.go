@@ -0,0 +1,66 @@
+// Copyright 2026 The HuaTuo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"huatuo-bamai/cmd/huatuo-bamai/config"
+	"huatuo-bamai/pkg/metric"
+)
+
+// downsampleDir is the directory of the aggregates in the local file
+// storage, apart from the files of the tracers.
+const downsampleDir = "downsampled-metrics"
+
+// setupDownsample keeps the aggregates of the selected metrics in the local
+// file storage, for the nodes without Prometheus.
+func setupDownsample(d *Daemon) (func(context.Context) error, error) {
+	cfg := config.Get()
+	downsample := cfg.MetricCollector.Downsample
+	if len(downsample.Metrics) == 0 {
+		return nil, nil
+	}
+	if cfg.Storage.LocalFile.Path == "" {
+		return nil, fmt.Errorf("MetricCollector.Downsample needs Storage.LocalFile.Path")
+	}
+
+	downsampler, err := metric.NewDownsampler(&metric.DownsampleConfig{
+		Dir:       filepath.Join(cfg.Storage.LocalFile.Path, downsampleDir),
+		Metrics:   downsample.Metrics,
+		Interval:  time.Duration(downsample.Interval) * time.Second,
+		Retention: time.Duration(downsample.RetentionDays) * 24 * time.Hour,
+	}, d.metrics)
+	if err != nil {
+		return nil, err
+	}
+	d.downsampler = downsampler
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		downsampler.Run(ctx)
+	}()
+
+	return func(context.Context) error {
+		cancel()
+		<-done
+		return nil
+	}, nil
+}
@@ -0,0 +1,47 @@
+// Copyright 2026 The HuaTuo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package handlers
+
+import (
+	"huatuo-bamai/internal/bpf"
+	"huatuo-bamai/internal/server"
+	"huatuo-bamai/internal/server/response"
+)
+
+type BpfHandler struct {
+	Handlers []server.Handle
+}
+
+type BpfLoadFailuresReq struct {
+	Limit int `form:"limit" binding:"omitempty,min=1"`
+}
+
+func NewBpfHandler() *BpfHandler {
+	h := &BpfHandler{}
+	h.Handlers = []server.Handle{
+		{Typ: server.HttpGet, Uri: "/load_failures", Handle: h.loadFailures},
+	}
+	return h
+}
+
+func (h *BpfHandler) loadFailures(ctx *server.Context) error {
+	req := &BpfLoadFailuresReq{}
+	if err := ctx.ShouldBindQuery(req); err != nil {
+		return response.ErrInvalidRequest.WithMessage(err.Error())
+	}
+
+	response.Success(ctx, bpf.LoadFailures(req.Limit))
+	return nil
+}
@@ -0,0 +1,114 @@
+// Copyright 2026 The HuaTuo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"huatuo-bamai/cmd/huatuo-bamai/config"
+	"huatuo-bamai/internal/bpf"
+	"huatuo-bamai/internal/bugreport"
+	internalconfig "huatuo-bamai/internal/config"
+	"huatuo-bamai/internal/log"
+	"huatuo-bamai/internal/server"
+	"huatuo-bamai/internal/version"
+	"huatuo-bamai/pkg/tracing"
+
+	"github.com/pelletier/go-toml"
+)
+
+// bugreportLogTail is how much of the agent log goes into a bundle.
+const bugreportLogTail = 4 << 20
+
+type BugreportHandler struct {
+	tracingManager *tracing.Manager
+	versionInfo    *version.Info
+	Handlers       []server.Handle
+}
+
+func NewBugreportHandler(manager *tracing.Manager, versionInfo *version.Info) *BugreportHandler {
+	h := &BugreportHandler{tracingManager: manager, versionInfo: versionInfo}
+	h.Handlers = []server.Handle{
+		{Typ: server.HttpGet, Uri: "/bugreport", Handle: h.bugreport},
+	}
+	return h
+}
+
+func (h *BugreportHandler) bugreport(ctx *server.Context) error {
+	name := BugreportName(time.Now())
+
+	ctx.Header("Content-Type", "application/gzip")
+	ctx.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", name+".tar.gz"))
+
+	// headers are out, failures can only be logged from here on.
+	if err := bugreport.Write(ctx.Request().Context(), ctx.Writer(), name,
+		BugreportSources(h.tracingManager, h.versionInfo)); err != nil {
+		log.Warnf("write bugreport: %v", err)
+	}
+	return nil
+}
+
+// BugreportName is the directory name inside, and the base name of, a bundle.
+func BugreportName(now time.Time) string {
+	hostname, _ := os.Hostname()
+	return fmt.Sprintf("huatuo-bugreport-%s-%s", hostname, now.Format("20060102-150405"))
+}
+
+// BugreportSources lists the files of a bundle. Without a tracing manager,
+// i.e. when the agent is not running, the runtime state is left out.
+func BugreportSources(manager *tracing.Manager, versionInfo *version.Info) []bugreport.Source {
+	sources := []bugreport.Source{
+		{Name: "version.json", Collect: func(context.Context) ([]byte, error) {
+			return json.MarshalIndent(versionInfo, "", "  ")
+		}},
+		{Name: "environment.txt", Collect: bugreport.Environment},
+		{Name: "huatuo-bamai.conf", Collect: func(context.Context) ([]byte, error) {
+			return effectiveConfig()
+		}},
+		{Name: "huatuo-bamai.log", Collect: func(context.Context) ([]byte, error) {
+			logFile := config.Get().Log.File
+			if logFile == "" {
+				return nil, errors.New("logging to stdout, collect the container or journal logs")
+			}
+			return bugreport.TailFile(logFile, bugreportLogTail)
+		}},
+	}
+
+	if manager == nil {
+		return sources
+	}
+
+	return append(sources,
+		bugreport.Source{Name: "tracers.json", Collect: func(context.Context) ([]byte, error) {
+			return json.MarshalIndent(manager.Snapshots(), "", "  ")
+		}},
+		bugreport.Source{Name: "events.json", Collect: func(context.Context) ([]byte, error) {
+			return json.MarshalIndent(tracing.RecentDocuments(), "", "  ")
+		}},
+		bugreport.Source{Name: "bpf_load_failures.json", Collect: func(context.Context) ([]byte, error) {
+			return json.MarshalIndent(bpf.LoadFailures(0), "", "  ")
+		}},
+	)
+}
+
+// effectiveConfig is the running config with credentials masked.
+func effectiveConfig() ([]byte, error) {
+	return toml.Marshal(*internalconfig.Mask(config.Get()))
+}
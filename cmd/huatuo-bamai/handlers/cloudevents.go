@@ -42,9 +42,11 @@ func DocumentToWatchEvent(doc *tracing.Document) pkgtypes.WatchEvent {
 			ContainerHostNamespace: doc.ContainerHostNamespace,
 			ContainerType:          doc.ContainerType,
 			ContainerQos:           doc.ContainerQoS,
+			ContainerPodMetadata:   doc.ContainerPodMetadata,
 			TracerName:             doc.TracerName,
 			TracerID:               doc.TracerID,
 			TracerRunType:          doc.TracerRunType,
+			Summary:                tracing.RenderDocument(doc),
 		},
 	}
 }
@@ -31,6 +31,7 @@ func newTestDocument() *tracing.Document {
 		Region:        "cn",
 		UploadedTime:  time.Unix(1_700_000_000, 0).UTC(),
 		TracerName:    "cpu",
+		TracerTime:    "2023-11-14 22:13:20",
 		TracerRunType: "auto",
 	}
 }
@@ -62,6 +63,7 @@ func TestDocumentToWatchEvent_Data(t *testing.T) {
 		ObservedTimestamp: doc.TracerTime,
 		TracerName:        doc.TracerName,
 		TracerRunType:     doc.TracerRunType,
+		Summary:           "cpu on node-1 at 2023-11-14 22:13:20",
 	}
 	require.Equal(t, want, ev.Data)
 }
@@ -1,4 +1,4 @@
-// Copyright 2025 The HuaTuo Authors
+// Copyright 2025, 2026 The HuaTuo Authors
 //
 // Licensed under the Apache License, Version 2.0 (the "License");
 // you may not use this file except in compliance with the License.
@@ -18,11 +18,14 @@ import (
 	"math"
 	"net/http"
 	"reflect"
+	"sort"
+	"strings"
 
 	"huatuo-bamai/cmd/huatuo-bamai/config"
 	"huatuo-bamai/internal/log"
 	"huatuo-bamai/internal/server"
 	"huatuo-bamai/internal/server/response"
+	"huatuo-bamai/pkg/tracing"
 )
 
 type ConfigHandler struct {
@@ -47,7 +50,16 @@ func (h *ConfigHandler) update(ctx *server.Context) error {
 		return response.ErrInvalidRequest.WithMessage(err.Error())
 	}
 
-	for k, v := range req.Config {
+	// only the keys are audited, the values may be secrets.
+	keys := make([]string, 0, len(req.Config))
+	for k := range req.Config {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	auditCtx := tracing.WithActor(ctx.Request().Context(), apiActor(ctx))
+
+	for _, k := range keys {
+		v := req.Config[k]
 		if reflect.ValueOf(v).Kind() == reflect.Float64 {
 			f := v.(float64)
 			if f > math.MaxInt64 || f < math.MinInt64 {
@@ -56,11 +68,14 @@ func (h *ConfigHandler) update(ctx *server.Context) error {
 			v = int64(f)
 		}
 		if err := config.Set(k, v); err != nil {
+			tracing.Audit(auditCtx, tracing.AuditActionConfig, "", k, err)
 			return response.ErrInvalidRequest.WithMessage(err.Error())
 		}
 	}
 
-	if err := config.Sync(); err != nil {
+	err := config.Sync()
+	tracing.Audit(auditCtx, tracing.AuditActionConfig, "", strings.Join(keys, ","), err)
+	if err != nil {
 		log.Warnf("config sync error: %v", err)
 		return response.ErrInternal.WithMessage(err.Error())
 	}
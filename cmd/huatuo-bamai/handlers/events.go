@@ -16,8 +16,12 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
 	"sync/atomic"
 	"time"
 
@@ -61,6 +65,7 @@ func NewEventsHandler(maxClients, keepAliveIntervalSecs int) *EventsHandler {
 		keepAliveInterval: keepAlive,
 	}
 	h.Handlers = []server.Handle{
+		{Typ: server.HttpGet, Uri: "", Handle: h.query},
 		{Typ: server.HttpPost, Uri: "/watch", Handle: h.watch},
 	}
 	return h
@@ -82,6 +87,87 @@ func (h *EventsHandler) releasePermit() {
 	h.activeClients.Add(-1)
 }
 
+// eventQueryMaxLimit bounds the events of a query.
+const eventQueryMaxLimit = 1000
+
+// query is the GET /v1/events handler, it returns the events of the local
+// event database, newest first.
+func (h *EventsHandler) query(ctx *server.Context) error {
+	query, err := parseEventQuery(ctx.Request().URL.Query(), time.Now())
+	if err != nil {
+		return response.ErrInvalidRequest.WithMessage(err.Error())
+	}
+
+	docs, err := tracing.QueryEvents(ctx.Request().Context(), query)
+	if err != nil {
+		if errors.Is(err, tracing.ErrEventQueryDisabled) {
+			return response.ErrNotFound.WithMessage(err.Error())
+		}
+		log.WithError(err).Error("query tracing events failed")
+		return response.ErrInternal.WithMessage("query tracing events failed")
+	}
+
+	response.Success(ctx, docs)
+	return nil
+}
+
+// parseEventQuery parses the parameters of an event query. tracer is a
+// comma separated list, since and until are RFC3339 times or durations
+// before now, e.g. since=24h.
+func parseEventQuery(values url.Values, now time.Time) (*tracing.EventQuery, error) {
+	query := &tracing.EventQuery{
+		ContainerID: values.Get("container_id"),
+		Limit:       100,
+	}
+	for _, tracer := range strings.Split(values.Get("tracer"), ",") {
+		if tracer = strings.TrimSpace(tracer); tracer != "" {
+			query.Tracers = append(query.Tracers, tracer)
+		}
+	}
+
+	if err := parseTimeRange(values, now, &query.Since, &query.Until); err != nil {
+		return nil, err
+	}
+
+	for key, value := range map[string]*int{"limit": &query.Limit, "offset": &query.Offset} {
+		raw := values.Get(key)
+		if raw == "" {
+			continue
+		}
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("%s must be a non-negative integer", key)
+		}
+		*value = n
+	}
+	if query.Limit == 0 || query.Limit > eventQueryMaxLimit {
+		return nil, fmt.Errorf("limit must be between 1 and %d", eventQueryMaxLimit)
+	}
+
+	return query, nil
+}
+
+// parseTimeRange parses the since and until parameters, RFC3339 times or
+// durations before now.
+func parseTimeRange(values url.Values, now time.Time, since, until *time.Time) error {
+	for key, value := range map[string]*time.Time{"since": since, "until": until} {
+		raw := values.Get(key)
+		if raw == "" {
+			continue
+		}
+		if d, err := time.ParseDuration(raw); err == nil && d >= 0 {
+			*value = now.Add(-d)
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			return fmt.Errorf("%s must be a RFC3339 time or a duration", key)
+		}
+		*value = t
+	}
+	return nil
+}
+
 // WatchRequest is the POST body sent by a client to register an event watch.
 // All filter fields are optional regex patterns; omitting a field matches all values.
 // Additional filter fields can be added to WatchFilters without breaking existing clients.
@@ -15,9 +15,11 @@
 package handlers
 
 import (
+	"net/url"
 	"sync"
 	"sync/atomic"
 	"testing"
+	"time"
 
 	"huatuo-bamai/pkg/tracing"
 
@@ -134,3 +136,34 @@ func TestWatchFilters_Matcher_RegionFilter(t *testing.T) {
 	require.True(t, m.Match(&tracing.Document{Region: "cn-north"}))
 	require.False(t, m.Match(&tracing.Document{Region: "us-east"}))
 }
+
+// --- parseEventQuery() ---
+
+func TestParseEventQuery(t *testing.T) {
+	now := time.Date(2026, 5, 2, 0, 0, 0, 0, time.UTC)
+	values, err := url.ParseQuery("tracer=oom,+softirq&since=24h&until=2026-05-01T12:00:00Z&limit=10&container_id=c1")
+	require.NoError(t, err)
+
+	query, err := parseEventQuery(values, now)
+	require.NoError(t, err)
+	require.Equal(t, []string{"oom", "softirq"}, query.Tracers)
+	require.Equal(t, "c1", query.ContainerID)
+	require.True(t, query.Since.Equal(now.Add(-24*time.Hour)))
+	require.True(t, query.Until.Equal(time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)))
+	require.Equal(t, 10, query.Limit)
+
+	query, err = parseEventQuery(url.Values{}, now)
+	require.NoError(t, err)
+	require.Empty(t, query.Tracers)
+	require.True(t, query.Since.IsZero())
+	require.Equal(t, 100, query.Limit)
+}
+
+func TestParseEventQuery_Invalid(t *testing.T) {
+	for _, raw := range []string{"since=yesterday", "until=-1h", "limit=0", "limit=1001", "offset=-1"} {
+		values, err := url.ParseQuery(raw)
+		require.NoError(t, err)
+		_, err = parseEventQuery(values, time.Now())
+		require.Error(t, err, raw)
+	}
+}
@@ -0,0 +1,137 @@
+// Copyright 2026 The HuaTuo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"huatuo-bamai/internal/log"
+	"huatuo-bamai/internal/server"
+	"huatuo-bamai/internal/server/response"
+	"huatuo-bamai/pkg/metric"
+)
+
+// recordQueryMaxLimit bounds the aggregates of a query, a week of a series.
+const recordQueryMaxLimit = 7 * 24 * 60
+
+var errDownsampleDisabled = errors.New("metric downsampling is disabled")
+
+type MetricHandler struct {
+	downsampler *metric.Downsampler
+	Handlers    []server.Handle
+}
+
+func NewMetricHandler(downsampler *metric.Downsampler) *MetricHandler {
+	h := &MetricHandler{downsampler: downsampler}
+	h.Handlers = []server.Handle{
+		{Typ: server.HttpGet, Uri: "/aliases", Handle: h.aliases},
+		{Typ: server.HttpGet, Uri: "/records", Handle: h.records},
+		{Typ: server.HttpGet, Uri: "/records/export", Handle: h.export},
+	}
+	return h
+}
+
+// aliases reports the deprecated metric names and the series still
+// exported under them.
+func (h *MetricHandler) aliases(ctx *server.Context) error {
+	response.Success(ctx, metric.AliasReport())
+	return nil
+}
+
+// records returns the 1-minute aggregates of the downsampled metrics, the
+// oldest first.
+func (h *MetricHandler) records(ctx *server.Context) error {
+	if h.downsampler == nil {
+		return response.ErrNotFound.WithMessage(errDownsampleDisabled.Error())
+	}
+
+	query, err := parseRecordQuery(ctx.Request().URL.Query(), time.Now())
+	if err != nil {
+		return response.ErrInvalidRequest.WithMessage(err.Error())
+	}
+	if query.Limit == 0 {
+		query.Limit = recordQueryMaxLimit
+	}
+
+	records, err := h.downsampler.Query(query)
+	if err != nil {
+		log.WithError(err).Error("query metric records failed")
+		return response.ErrInternal.WithMessage("query metric records failed")
+	}
+
+	response.Success(ctx, records)
+	return nil
+}
+
+// export writes the aggregates of the downsampled metrics in the
+// OpenMetrics text format, to backfill a Prometheus with.
+func (h *MetricHandler) export(ctx *server.Context) error {
+	if h.downsampler == nil {
+		return response.ErrNotFound.WithMessage(errDownsampleDisabled.Error())
+	}
+
+	query, err := parseRecordQuery(ctx.Request().URL.Query(), time.Now())
+	if err != nil {
+		return response.ErrInvalidRequest.WithMessage(err.Error())
+	}
+
+	ctx.Header("Content-Type", "application/openmetrics-text; version=1.0.0; charset=utf-8")
+	// headers are out, failures can only be logged from here on.
+	if err := h.downsampler.Export(ctx.Writer(), query); err != nil {
+		log.Warnf("export metric records: %v", err)
+	}
+	return nil
+}
+
+// parseRecordQuery parses the parameters of a query of the aggregates. name
+// is a name or a glob, labels a comma separated list of name=value, since
+// and until are RFC3339 times or durations before now. The limit is
+// ignored by the export.
+func parseRecordQuery(values url.Values, now time.Time) (*metric.DownsampleQuery, error) {
+	query := &metric.DownsampleQuery{Name: values.Get("name")}
+
+	for _, pair := range strings.Split(values.Get("labels"), ",") {
+		if pair = strings.TrimSpace(pair); pair == "" {
+			continue
+		}
+		name, value, ok := strings.Cut(pair, "=")
+		if !ok || name == "" {
+			return nil, fmt.Errorf("labels must be name=value pairs")
+		}
+		if query.Labels == nil {
+			query.Labels = map[string]string{}
+		}
+		query.Labels[name] = value
+	}
+
+	if err := parseTimeRange(values, now, &query.Since, &query.Until); err != nil {
+		return nil, err
+	}
+
+	if raw := values.Get("limit"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 1 || n > recordQueryMaxLimit {
+			return nil, fmt.Errorf("limit must be between 1 and %d", recordQueryMaxLimit)
+		}
+		query.Limit = n
+	}
+
+	return query, nil
+}
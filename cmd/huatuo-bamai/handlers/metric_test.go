@@ -0,0 +1,94 @@
+// Copyright 2026 The HuaTuo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"huatuo-bamai/internal/server"
+	"huatuo-bamai/internal/server/response"
+	"huatuo-bamai/pkg/metric"
+
+	httpGin "github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseRecordQuery(t *testing.T) {
+	now := time.Date(2026, 5, 2, 0, 0, 0, 0, time.UTC)
+	values, err := url.ParseQuery("name=huatuo_bamai_memory_*&labels=container_host=web,+cpu=1&since=1h&limit=10")
+	require.NoError(t, err)
+
+	query, err := parseRecordQuery(values, now)
+	require.NoError(t, err)
+	require.Equal(t, "huatuo_bamai_memory_*", query.Name)
+	require.Equal(t, map[string]string{"container_host": "web", "cpu": "1"}, query.Labels)
+	require.True(t, query.Since.Equal(now.Add(-time.Hour)))
+	require.True(t, query.Until.IsZero())
+	require.Equal(t, 10, query.Limit)
+
+	for _, raw := range []string{"labels=cpu", "labels==1", "since=yesterday", "limit=0", "limit=10081"} {
+		values, err := url.ParseQuery(raw)
+		require.NoError(t, err)
+		_, err = parseRecordQuery(values, now)
+		require.Error(t, err, raw)
+	}
+}
+
+func TestMetricRecords(t *testing.T) {
+	httpGin.SetMode(httpGin.TestMode)
+
+	reg := prometheus.NewRegistry()
+	load := prometheus.NewGauge(prometheus.GaugeOpts{Name: "huatuo_bamai_load"})
+	reg.MustRegister(load)
+	downsampler, err := metric.NewDownsampler(&metric.DownsampleConfig{
+		Dir:     t.TempDir(),
+		Metrics: []string{"huatuo_bamai_load"},
+	}, reg)
+	require.NoError(t, err)
+	load.Set(3)
+	require.NoError(t, downsampler.Sample(time.Now()))
+	require.NoError(t, downsampler.Flush())
+
+	get := func(h *MetricHandler, uri string) *httptest.ResponseRecorder {
+		engine := httpGin.New()
+		root := server.NewRoot(engine, "")
+		root.GET("/records", h.records)
+		root.GET("/records/export", h.export)
+		rec := httptest.NewRecorder()
+		engine.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, uri, http.NoBody))
+		return rec
+	}
+
+	rec := get(NewMetricHandler(downsampler), "/records?name=huatuo_bamai_*&since=1h")
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	var records []*metric.Aggregate
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response.Response{Data: &records}))
+	require.Len(t, records, 1)
+	require.Equal(t, 3.0, records[0].Last)
+
+	rec = get(NewMetricHandler(downsampler), "/records/export")
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.True(t, strings.HasPrefix(rec.Body.String(), "# TYPE huatuo_bamai_load gauge\nhuatuo_bamai_load 3 "), rec.Body.String())
+
+	rec = get(NewMetricHandler(nil), "/records")
+	require.Equal(t, http.StatusNotFound, rec.Code)
+}
@@ -20,6 +20,7 @@ import (
 	"huatuo-bamai/cmd/huatuo-bamai/config"
 	"huatuo-bamai/internal/server"
 	"huatuo-bamai/internal/version"
+	"huatuo-bamai/pkg/metric"
 	"huatuo-bamai/pkg/tracing"
 
 	"github.com/prometheus/client_golang/prometheus"
@@ -30,7 +31,16 @@ type ServerOptions struct {
 	Addr           string
 	TracingManager *tracing.Manager
 	PromReg        *prometheus.Registry
+	PromGroups     map[string]prometheus.Gatherer
 	VersionInfo    *version.Info
+	// Collectors are the names of the metric collectors registered.
+	Collectors []string
+	// Downsampler keeps the aggregates of the metrics, nil if disabled.
+	Downsampler *metric.Downsampler
+	// SigningKey, when set, requires the task requests be signed by the
+	// central server within SignatureMaxAge.
+	SigningKey      []byte
+	SignatureMaxAge time.Duration
 }
 
 // Start starts the HTTP server with all handlers registered.
@@ -42,7 +52,11 @@ func Start(opts ServerOptions) {
 		RateBurst:       200,
 		EnableRetry:     true,
 		PromReg:         opts.PromReg,
+		PromGroups:      opts.PromGroups,
 		VersionInfo:     opts.VersionInfo,
+		SigningKey:      opts.SigningKey,
+		SignedPaths:     []string{"/tasks", "/tasks/**"},
+		SignatureMaxAge: opts.SignatureMaxAge,
 	})
 
 	SetTracingManager(opts.TracingManager)
@@ -51,6 +65,10 @@ func Start(opts ServerOptions) {
 	s.MustRegisterRoutes("/tracers", NewTracerHandler(opts.TracingManager).Handlers)
 	s.MustRegisterRoutes("", NewContainerHandler().Handlers)
 	s.MustRegisterRoutes("", NewConfigHandler().Handlers)
+	s.MustRegisterRoutes("/bpf", NewBpfHandler().Handlers)
+	s.MustRegisterRoutes("/metrics", NewMetricHandler(opts.Downsampler).Handlers)
+	s.MustRegisterRoutes("", NewBugreportHandler(opts.TracingManager, opts.VersionInfo).Handlers)
+	s.MustRegisterRoutes("/api", NewStateHandler(opts.TracingManager, opts.Collectors, opts.VersionInfo).Handlers)
 	evtCfg := config.Get().EventsWatch
 	s.MustRegisterRoutes("/v1/events", NewEventsHandler(evtCfg.MaxClients, evtCfg.KeepAliveInterval).Handlers)
 
@@ -0,0 +1,136 @@
+// Copyright 2026 The HuaTuo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package handlers
+
+import (
+	"time"
+
+	"huatuo-bamai/cmd/huatuo-bamai/config"
+	"huatuo-bamai/internal/bpf"
+	internalconfig "huatuo-bamai/internal/config"
+	"huatuo-bamai/internal/log"
+	"huatuo-bamai/internal/pod"
+	"huatuo-bamai/internal/server"
+	"huatuo-bamai/internal/server/response"
+	"huatuo-bamai/internal/version"
+	"huatuo-bamai/pkg/tracing"
+)
+
+// State is the runtime state of the agent, returned by GET /api/state.
+type State struct {
+	Version *version.Info `json:"version"`
+	// Config is the running config, credentials masked.
+	Config *config.BamaiConfig `json:"config"`
+	// Tracers are the tracers registered, blacklisted or not supported,
+	// with the config each one last started with.
+	Tracers    map[string]TracerState `json:"tracers"`
+	Collectors []CollectorState       `json:"collectors"`
+	BPF        []bpf.ObjectState      `json:"bpf"`
+	Stores     []tracing.StoreState   `json:"stores"`
+	Containers ContainerSummary       `json:"containers"`
+}
+
+// TracerState is the registration status of a tracer, e.g. "active" or
+// "disabled", and its lifecycle when it is managed.
+type TracerState struct {
+	Status string `json:"status"`
+	*tracing.LifecycleSnapshot
+}
+
+// CollectorState is a metric collector and its config section, the
+// collectors read their config on every scrape.
+type CollectorState struct {
+	Name    string `json:"name"`
+	Section string `json:"section,omitempty"`
+	Config  any    `json:"config,omitempty"`
+}
+
+// ContainerSummary counts the containers cached.
+type ContainerSummary struct {
+	Total    int            `json:"total"`
+	ByType   map[string]int `json:"by_type"`
+	ByQos    map[string]int `json:"by_qos"`
+	SyncedAt time.Time      `json:"synced_at"`
+}
+
+type StateHandler struct {
+	tracingManager *tracing.Manager
+	collectors     []string
+	versionInfo    *version.Info
+	Handlers       []server.Handle
+}
+
+func NewStateHandler(manager *tracing.Manager, collectors []string, versionInfo *version.Info) *StateHandler {
+	h := &StateHandler{tracingManager: manager, collectors: collectors, versionInfo: versionInfo}
+	h.Handlers = []server.Handle{
+		{Typ: server.HttpGet, Uri: "/state", Handle: h.state},
+	}
+	return h
+}
+
+func (h *StateHandler) state(ctx *server.Context) error {
+	running := config.Get()
+	state := &State{
+		Version:    h.versionInfo,
+		Config:     internalconfig.Mask(running),
+		Tracers:    map[string]TracerState{},
+		Collectors: make([]CollectorState, 0, len(h.collectors)),
+		BPF:        bpf.Objects(),
+		Stores:     tracing.StoreStates(),
+		Containers: containerSummary(),
+	}
+
+	for name, status := range tracing.EventTracingStatus() {
+		state.Tracers[name] = TracerState{Status: status}
+	}
+	if h.tracingManager != nil {
+		for name, snapshot := range h.tracingManager.Snapshots() {
+			tracer := state.Tracers[name]
+			tracer.LifecycleSnapshot = &snapshot
+			state.Tracers[name] = tracer
+		}
+	}
+
+	for _, name := range h.collectors {
+		collector := CollectorState{Name: name}
+		if section, value, ok := config.TracerConfig(running, name); ok {
+			collector.Section, collector.Config = section, value
+		}
+		state.Collectors = append(state.Collectors, collector)
+	}
+
+	response.Success(ctx, state)
+	return nil
+}
+
+func containerSummary() ContainerSummary {
+	summary := ContainerSummary{ByType: map[string]int{}, ByQos: map[string]int{}}
+
+	containers, err := pod.Containers()
+	if err != nil {
+		log.Debugf("state: containers: %v", err)
+		return summary
+	}
+
+	for _, container := range containers {
+		summary.Total++
+		summary.ByType[container.Type.String()]++
+		summary.ByQos[container.Qos.String()]++
+		if container.SyncedAt.After(summary.SyncedAt) {
+			summary.SyncedAt = container.SyncedAt
+		}
+	}
+	return summary
+}
@@ -0,0 +1,100 @@
+// Copyright 2026 The HuaTuo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"huatuo-bamai/cmd/huatuo-bamai/config"
+	internalconfig "huatuo-bamai/internal/config"
+	"huatuo-bamai/internal/server"
+	"huatuo-bamai/internal/server/response"
+	"huatuo-bamai/internal/version"
+
+	httpGin "github.com/gin-gonic/gin"
+)
+
+func TestStateHandler(t *testing.T) {
+	httpGin.SetMode(httpGin.TestMode)
+
+	if err := config.Load(writeConfig(t, `
+[Storage.ES]
+Password = "secret"
+
+[Storage.ClickHouse]
+Password = "secret"
+
+[Storage.Loki]
+Password = "secret"
+
+[OTLP]
+Headers = { Authorization = "Bearer token" }
+
+[RemoteWrite]
+URL = "http://127.0.0.1:9090/api/v1/write"
+BearerToken = "token"
+Headers = { X-Api-Key = "key" }
+
+[MetricCollector.Vmstat]
+IncludedOnHost = "allocstall"
+`)); err != nil {
+		t.Fatalf("load config: %v", err)
+	}
+
+	engine := httpGin.New()
+	h := NewStateHandler(nil, []string{"memory_vmstat", "cpu_util"}, &version.Info{Version: "v2.0.0"})
+	server.NewRoot(engine, "").GET("/state", h.state)
+
+	rec := httptest.NewRecorder()
+	engine.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/state", http.NoBody))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d, body: %s", rec.Code, http.StatusOK, rec.Body.String())
+	}
+
+	state := &State{}
+	if err := json.Unmarshal(rec.Body.Bytes(), &response.Response{Data: state}); err != nil {
+		t.Fatalf("decode state: %v", err)
+	}
+
+	if state.Version == nil || state.Version.Version != "v2.0.0" {
+		t.Errorf("Version = %+v, want v2.0.0", state.Version)
+	}
+	rw := state.Config.RemoteWrite
+	if state.Config.Storage.ES.Password != internalconfig.MaskedValue || rw.BearerToken != internalconfig.MaskedValue ||
+		rw.Headers["X-Api-Key"] != internalconfig.MaskedValue {
+		t.Errorf("credentials not masked: %+v", rw)
+	}
+	storage := state.Config.Storage
+	if storage.ClickHouse.Password != internalconfig.MaskedValue || storage.Loki.Password != internalconfig.MaskedValue ||
+		state.Config.OTLP.Headers["Authorization"] != internalconfig.MaskedValue {
+		t.Errorf("storage credentials not masked: %+v, %+v", storage, state.Config.OTLP)
+	}
+	if running := config.Get(); running.Storage.ES.Password != "secret" || running.RemoteWrite.Headers["X-Api-Key"] != "key" {
+		t.Error("masking changed the running config")
+	}
+
+	if len(state.Collectors) != 2 {
+		t.Fatalf("Collectors = %+v, want 2", state.Collectors)
+	}
+	if c := state.Collectors[0]; c.Section != "MetricCollector.Vmstat" || c.Config.(map[string]any)["IncludedOnHost"] != "allocstall" {
+		t.Errorf("Collectors[0] = %+v, want the Vmstat section", c)
+	}
+	if c := state.Collectors[1]; c.Section != "" || c.Config != nil {
+		t.Errorf("Collectors[1] = %+v, want no section", c)
+	}
+}
@@ -16,7 +16,10 @@ package handlers
 
 import (
 	"errors"
+	"fmt"
 	"net/http"
+	"slices"
+	"strings"
 	"time"
 
 	"huatuo-bamai/cmd/huatuo-bamai/config"
@@ -59,6 +62,40 @@ func handleBindError(ctx *server.Context, err error) {
 	response.ErrorWithCode(ctx, http.StatusBadRequest, 400, err.Error())
 }
 
+// errTaskNotAllowed is returned for a tracer binary out of Task.Allowlist.
+var errTaskNotAllowed = errors.New("tracer is not in the task allowlist")
+
+// taskActor returns the author of the task requests, the operator of the
+// central server for the signed ones.
+func taskActor(ctx *server.Context) string {
+	if ctx.UserID != "" {
+		return ctx.UserID
+	}
+	return apiActor(ctx)
+}
+
+// checkTaskTracer returns an error unless the tracer is a binary of
+// tracing.TaskBinDir allowed by Task.Allowlist.
+func checkTaskTracer(tracer string) error {
+	if tracer == "." || tracer == ".." || strings.ContainsAny(tracer, `/\`) {
+		return fmt.Errorf("invalid tracer name %q", tracer)
+	}
+	if allowlist := config.Get().Task.Allowlist; len(allowlist) > 0 && !slices.Contains(allowlist, tracer) {
+		return fmt.Errorf("%w: %s", errTaskNotAllowed, tracer)
+	}
+	return nil
+}
+
+// taskTracer returns the tracer binary of the task, empty if unknown.
+func taskTracer(id string) string {
+	for _, info := range tracing.ListTasks() {
+		if info.TaskID == id {
+			return info.TracerName
+		}
+	}
+	return ""
+}
+
 func (h *TaskHandler) create(ctx *server.Context) error {
 	var req NewTaskReq
 	if err := ctx.ShouldBindJSON(&req); err != nil {
@@ -66,6 +103,16 @@ func (h *TaskHandler) create(ctx *server.Context) error {
 		return nil
 	}
 
+	auditCtx := tracing.WithActor(ctx.Request().Context(), taskActor(ctx))
+	detail := fmt.Sprintf("task=%s timeout=%ds args=%q", req.RequestID, req.Timeout, req.TracerArgs)
+	if err := checkTaskTracer(req.TracerName); err != nil {
+		tracing.Audit(auditCtx, tracing.AuditActionTaskStart, req.TracerName, detail, err)
+		if errors.Is(err, errTaskNotAllowed) {
+			return response.ErrForbidden.WithMessage(err.Error())
+		}
+		return response.ErrInvalidRequest.WithMessage(err.Error())
+	}
+
 	storageDefault := tracing.TaskStorageDB
 	if req.DataType == "json" {
 		storageDefault = tracing.TaskStorageStdout
@@ -87,6 +134,8 @@ func (h *TaskHandler) create(ctx *server.Context) error {
 		req.TracerArgs,
 		config.Get().Task.MaxRunningTask,
 	)
+	detail = fmt.Sprintf("task=%s timeout=%ds args=%q", id, req.Timeout, req.TracerArgs)
+	tracing.Audit(auditCtx, tracing.AuditActionTaskStart, req.TracerName, detail, err)
 	if err != nil {
 		if errors.Is(err, tracing.ErrTaskLimitExceeded) {
 			return response.ErrInvalidRequest.WithMessage(err.Error())
@@ -127,7 +176,11 @@ func (h *TaskHandler) stop(ctx *server.Context) error {
 		return response.ErrInvalidRequest.WithMessage("missing task id")
 	}
 
-	if err := tracing.StopTask(taskID); err != nil {
+	tracer := taskTracer(taskID)
+	err := tracing.StopTask(taskID)
+	tracing.Audit(tracing.WithActor(ctx.Request().Context(), taskActor(ctx)),
+		tracing.AuditActionTaskStop, tracer, "task="+taskID, err)
+	if err != nil {
 		if errors.Is(err, tracing.ErrTaskNotFound) {
 			return response.ErrNotFound.WithMessage("task not found")
 		}
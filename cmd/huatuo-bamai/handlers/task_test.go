@@ -15,9 +15,15 @@
 package handlers
 
 import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
 
+	"huatuo-bamai/cmd/huatuo-bamai/config"
 	"huatuo-bamai/internal/server"
+
+	httpGin "github.com/gin-gonic/gin"
 )
 
 func TestTaskHandlerRegistersListRoute(t *testing.T) {
@@ -31,3 +37,38 @@ func TestTaskHandlerRegistersListRoute(t *testing.T) {
 
 	t.Fatal("NewTaskHandler() should register GET /tasks list route")
 }
+
+func TestTaskHandlerRejectsTracers(t *testing.T) {
+	httpGin.SetMode(httpGin.TestMode)
+
+	if err := config.Load(writeConfig(t, `
+[Task]
+  Allowlist = ["perf"]
+`)); err != nil {
+		t.Fatalf("load config: %v", err)
+	}
+
+	engine := httpGin.New()
+	server.NewRoot(engine, "").POST("/tasks", NewTaskHandler().create)
+
+	tests := []struct {
+		tracer string
+		want   int
+	}{
+		{tracer: "../../usr/bin/sh", want: http.StatusBadRequest},
+		{tracer: "..", want: http.StatusBadRequest},
+		{tracer: "sh", want: http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		body := `{"tracer_name":"` + tt.tracer + `","timeout":60,"data_type":"json"}`
+		req := httptest.NewRequest(http.MethodPost, "/tasks", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+
+		engine.ServeHTTP(rec, req)
+
+		if rec.Code != tt.want {
+			t.Errorf("tracer %q status = %d, want %d, body: %s", tt.tracer, rec.Code, tt.want, rec.Body.String())
+		}
+	}
+}
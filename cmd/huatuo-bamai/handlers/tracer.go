@@ -18,6 +18,8 @@ import (
 	"context"
 	"errors"
 	"net/http"
+	"strconv"
+	"time"
 
 	"huatuo-bamai/internal/log"
 	"huatuo-bamai/internal/server"
@@ -34,6 +36,8 @@ func NewTracerHandler(manager *tracing.Manager) *TracerHandler {
 	h := &TracerHandler{tracingManager: manager}
 	h.Handlers = []server.Handle{
 		{Typ: server.HttpGet, Uri: "", Handle: h.list},
+		{Typ: server.HttpGet, Uri: "/audit", Handle: h.audit},
+		{Typ: server.HttpGet, Uri: "/schemas", Handle: h.schemas},
 		{Typ: server.HttpPut, Uri: "/:name/start", Handle: h.start},
 		{Typ: server.HttpPut, Uri: "/:name/stop", Handle: h.stop},
 	}
@@ -45,13 +49,20 @@ func (h *TracerHandler) list(ctx *server.Context) error {
 	return nil
 }
 
+// schemas lists the schemas of the tracer data, the schema_name and
+// schema_version of the documents refer to.
+func (h *TracerHandler) schemas(ctx *server.Context) error {
+	response.Success(ctx, tracing.Schemas())
+	return nil
+}
+
 func (h *TracerHandler) start(ctx *server.Context) error {
 	name := ctx.Param("name")
 	if name == "" {
 		return response.ErrInvalidRequest.WithMessage("missing tracer name")
 	}
 
-	tracerCtx := context.WithoutCancel(ctx.Request().Context())
+	tracerCtx := tracing.WithActor(context.WithoutCancel(ctx.Request().Context()), apiActor(ctx))
 	if err := h.tracingManager.StartByName(tracerCtx, name); err != nil {
 		return tracerAPIError(err)
 	}
@@ -66,7 +77,8 @@ func (h *TracerHandler) stop(ctx *server.Context) error {
 		return response.ErrInvalidRequest.WithMessage("missing tracer name")
 	}
 
-	if err := h.tracingManager.StopByName(ctx.Request().Context(), name); err != nil {
+	tracerCtx := tracing.WithActor(ctx.Request().Context(), apiActor(ctx))
+	if err := h.tracingManager.StopByName(tracerCtx, name); err != nil {
 		return tracerAPIError(err)
 	}
 
@@ -74,6 +86,57 @@ func (h *TracerHandler) stop(ctx *server.Context) error {
 	return nil
 }
 
+// auditQueryMaxLimit bounds the records of an audit query.
+const auditQueryMaxLimit = 1000
+
+func (h *TracerHandler) audit(ctx *server.Context) error {
+	query := tracing.AuditQuery{
+		Tracer: ctx.Query("tracer"),
+		Action: ctx.Query("action"),
+		Actor:  ctx.Query("actor"),
+		Limit:  100,
+	}
+
+	if raw := ctx.Query("since"); raw != "" {
+		since, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			return response.ErrInvalidRequest.WithMessage("since must be a RFC3339 time")
+		}
+		query.Since = since
+	}
+	for key, value := range map[string]*int{"limit": &query.Limit, "offset": &query.Offset} {
+		raw := ctx.Query(key)
+		if raw == "" {
+			continue
+		}
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 0 {
+			return response.ErrInvalidRequest.WithMessage(key + " must be a non-negative integer")
+		}
+		*value = n
+	}
+	if query.Limit == 0 || query.Limit > auditQueryMaxLimit {
+		return response.ErrInvalidRequest.WithMessage("limit must be between 1 and 1000")
+	}
+
+	records, err := tracing.QueryAudit(ctx.Request().Context(), &query)
+	if err != nil {
+		if errors.Is(err, tracing.ErrAuditDisabled) {
+			return response.ErrNotFound.WithMessage(err.Error())
+		}
+		log.WithError(err).Error("query tracing audit failed")
+		return response.ErrInternal.WithMessage("query tracing audit failed")
+	}
+
+	response.Success(ctx, records)
+	return nil
+}
+
+// apiActor identifies the API client in the audit records.
+func apiActor(ctx *server.Context) string {
+	return "api:" + ctx.ClientIP()
+}
+
 func tracerAPIError(err error) error {
 	switch {
 	case errors.Is(err, tracing.ErrTracerNotFound):
@@ -0,0 +1,45 @@
+// Copyright 2026 The HuaTuo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"huatuo-bamai/cmd/huatuo-bamai/config"
+	"huatuo-bamai/internal/hostinfo"
+)
+
+func setupHost(d *Daemon) (func(context.Context) error, error) {
+	host := config.Get().Host
+
+	if err := hostinfo.Init(hostinfo.Options{
+		HostnameSources: host.HostnameSources,
+		RegionSources:   host.RegionSources,
+		Region:          d.opts.Region,
+		NodeNameEnv:     host.NodeNameEnv,
+		Cloud:           host.Cloud,
+		RefreshInterval: time.Duration(host.RefreshInterval) * time.Second,
+	}); err != nil {
+		return nil, fmt.Errorf("resolve host identity: %w", err)
+	}
+	hostinfo.Start()
+
+	return func(context.Context) error {
+		hostinfo.Stop()
+		return nil
+	}, nil
+}
@@ -0,0 +1,105 @@
+// Copyright 2026 The HuaTuo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"huatuo-bamai/cmd/huatuo-bamai/config"
+	"huatuo-bamai/internal/instance"
+	"huatuo-bamai/internal/log"
+	"huatuo-bamai/internal/pidfile"
+)
+
+// setupInstance locks the state dir and the storage paths, taking them over
+// from the running instance with --takeover. It runs before the pid file
+// lock, which the instances of the previous versions hold.
+func setupInstance(d *Daemon) (func(context.Context) error, error) {
+	cfg := config.Get()
+	timeout := time.Duration(cfg.Instance.TakeoverTimeout) * time.Second
+
+	inst, err := instance.Acquire(&instance.Options{
+		Dir:      cfg.Instance.StateDir,
+		Version:  d.opts.VersionInfo.Version,
+		Addr:     cfg.APIServer.TCPAddr,
+		Takeover: d.opts.Takeover,
+		Timeout:  timeout,
+		PidFile:  pidfile.Path(appName),
+	})
+	if err != nil {
+		return nil, instanceError(err)
+	}
+	if inst.Previous != nil {
+		log.Infof("took over the running instance %s", inst.Previous)
+	}
+
+	if !d.opts.DisableStorage {
+		for _, path := range storagePaths(cfg) {
+			prev, err := inst.Claim(path)
+			if err != nil {
+				inst.Release()
+				return nil, instanceError(err)
+			}
+			if prev != nil {
+				log.Infof("took %s over from %s", path, prev)
+			}
+		}
+	}
+
+	// the instance taken over may still close its listener.
+	var wait time.Duration
+	if d.opts.Takeover {
+		wait = timeout
+	}
+	if err := instance.WaitPort(cfg.APIServer.TCPAddr, wait); err != nil {
+		log.Warnf("api server address %s not available, retried later: %v", cfg.APIServer.TCPAddr, err)
+	}
+
+	return func(context.Context) error {
+		inst.Release()
+		return nil
+	}, nil
+}
+
+func instanceError(err error) error {
+	if errors.Is(err, instance.ErrLocked) {
+		return fmt.Errorf("%w, start with --%s to replace it", err, cliFlagTakeover)
+	}
+	return err
+}
+
+// storagePaths are the paths the storage backends write, two instances
+// writing one corrupt it.
+func storagePaths(cfg *config.BamaiConfig) []string {
+	var paths []string
+	for _, path := range []string{
+		cfg.Storage.LocalFile.Path,
+		cfg.Storage.SQLite.Path,
+		cfg.Storage.Audit.Path,
+		cfg.Storage.State.Path,
+		cfg.Storage.DeadLetter.Path,
+	} {
+		if path != "" {
+			paths = append(paths, path)
+		}
+	}
+	if cfg.Storage.ES.Address != "" && cfg.Storage.ES.SpillPath != "" {
+		paths = append(paths, cfg.Storage.ES.SpillPath)
+	}
+	return paths
+}
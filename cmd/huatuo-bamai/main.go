@@ -27,6 +27,7 @@ import (
 	"huatuo-bamai/internal/log"
 	"huatuo-bamai/internal/pidfile"
 	"huatuo-bamai/internal/version"
+	"huatuo-bamai/pkg/metric"
 	"huatuo-bamai/pkg/tracing"
 
 	"github.com/prometheus/client_golang/prometheus"
@@ -75,9 +76,12 @@ func mainAction(opts *Options) error {
 type Daemon struct {
 	opts *Options
 
-	cgr     cgroups.Cgroup
-	metrics *prometheus.Registry
-	tracer  *tracing.Manager
+	cgr          cgroups.Cgroup
+	metrics      *prometheus.Registry
+	metricGroups map[string]prometheus.Gatherer
+	collectors   []string
+	downsampler  *metric.Downsampler
+	tracer       *tracing.Manager
 }
 
 func NewDaemon(opts *Options) *Daemon {
@@ -122,12 +126,18 @@ func (d *Daemon) Run(ctx context.Context) error {
 		name  string
 		setup func(*Daemon) (func(context.Context) error, error)
 	}{
+		{"instance", setupInstance},
 		{"pidfile", lockPidfile},
+		{"host", setupHost},
 		{"cgroup", setupCgroup},
+		{"affinity", setupAffinity},
 		{"storage", setupStorage},
 		{"bpf", setupBPF},
 		{"pod", setupPodManager},
 		{"metrics", setupMetrics},
+		{"otlp", setupOTLP},
+		{"remotewrite", setupRemoteWrite},
+		{"downsample", setupDownsample},
 		{"toolstream", startToolstream},
 		{"tracing", startTracing},
 		{"handlers", startHandlers},
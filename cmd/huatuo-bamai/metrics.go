@@ -16,15 +16,37 @@ package main
 
 import (
 	"context"
+	"fmt"
+	"time"
 
 	"huatuo-bamai/cmd/huatuo-bamai/config"
+	collector "huatuo-bamai/core/metrics"
+	"huatuo-bamai/internal/affinity"
+	"huatuo-bamai/internal/pod"
+	"huatuo-bamai/internal/quota"
+	"huatuo-bamai/internal/storage"
 	"huatuo-bamai/pkg/metric"
 	"huatuo-bamai/pkg/metric/runtime"
 
 	"github.com/prometheus/client_golang/prometheus"
 )
 
+// defaultMetricGroup holds the collectors in no configured group.
+const defaultMetricGroup = "default"
+
 func setupMetrics(d *Daemon) (func(context.Context) error, error) {
+	if err := quota.Set(quotaConfig(config.Get())); err != nil {
+		return nil, err
+	}
+
+	aliases, err := metricAliases(config.Get())
+	if err != nil {
+		return nil, err
+	}
+	if err := metric.SetAliases(aliases); err != nil {
+		return nil, err
+	}
+
 	nc, err := metric.NewCollectorManager(config.Get().BlackList, d.opts.Region)
 	if err != nil {
 		return nil, err
@@ -32,9 +54,102 @@ func setupMetrics(d *Daemon) (func(context.Context) error, error) {
 
 	reg := prometheus.NewRegistry()
 	reg.MustRegister(nc)
-
-	runtime.RegisterCollector(reg, metric.DefaultNamespace)
+	registerAgentMetrics(reg)
 	d.metrics = reg
+	d.collectors = nc.Names()
+
+	groups, err := metricGroups(nc, config.Get())
+	if err != nil {
+		return nil, err
+	}
+	d.metricGroups = groups
 
 	return nil, nil
 }
+
+// registerAgentMetrics registers the metrics of the agent itself.
+func registerAgentMetrics(reg *prometheus.Registry) {
+	runtime.RegisterCollector(reg, metric.DefaultNamespace)
+	storage.RegisterMetrics(reg)
+	quota.RegisterMetrics(reg)
+	collector.RegisterMetrics(reg)
+	affinity.RegisterMetrics(reg)
+	pod.RegisterMetrics(reg)
+}
+
+// metricGroups partitions the collectors by the configured groups. The
+// collectors in no group and the metrics of the agent make up the default
+// group, so the groups together cover the whole /metrics.
+func metricGroups(nc *metric.CollectorManager, cfg *config.BamaiConfig) (map[string]prometheus.Gatherer, error) {
+	if len(cfg.MetricCollector.Groups) == 0 {
+		return nil, nil
+	}
+
+	groups := make(map[string]prometheus.Gatherer, len(cfg.MetricCollector.Groups)+1)
+	grouped := make(map[string]string)
+	for _, group := range cfg.MetricCollector.Groups {
+		if group.Name == "" || group.Name == defaultMetricGroup {
+			return nil, fmt.Errorf("metric group name %q is reserved", group.Name)
+		}
+		if _, ok := groups[group.Name]; ok {
+			return nil, fmt.Errorf("metric group %s is duplicated", group.Name)
+		}
+		for _, name := range group.Collectors {
+			if other, ok := grouped[name]; ok {
+				return nil, fmt.Errorf("metric collector %s is in groups %s and %s", name, other, group.Name)
+			}
+			grouped[name] = group.Name
+		}
+
+		reg := prometheus.NewRegistry()
+		reg.MustRegister(nc.Subset(group.Collectors))
+		groups[group.Name] = reg
+	}
+
+	ungrouped := make([]string, 0, len(nc.Names()))
+	for _, name := range nc.Names() {
+		if _, ok := grouped[name]; !ok {
+			ungrouped = append(ungrouped, name)
+		}
+	}
+
+	reg := prometheus.NewRegistry()
+	reg.MustRegister(nc.Subset(ungrouped))
+	registerAgentMetrics(reg)
+	groups[defaultMetricGroup] = reg
+
+	return groups, nil
+}
+
+func metricAliases(cfg *config.BamaiConfig) ([]metric.Alias, error) {
+	aliases := make([]metric.Alias, 0, len(cfg.MetricCollector.Aliases))
+	for _, a := range cfg.MetricCollector.Aliases {
+		alias := metric.Alias{Deprecated: a.Deprecated, Name: a.Name}
+		if a.Until != "" {
+			until, err := time.ParseInLocation(time.DateOnly, a.Until, time.Local)
+			if err != nil {
+				return nil, fmt.Errorf("metric alias %s: invalid Until: %w", a.Deprecated, err)
+			}
+			alias.Until = until
+		}
+		aliases = append(aliases, alias)
+	}
+	return aliases, nil
+}
+
+func quotaConfig(cfg *config.BamaiConfig) *quota.Config {
+	q := &quota.Config{
+		Default: quota.Limits{
+			EventsPerMinute: cfg.Quota.EventsPerMinute,
+			MetricSeries:    cfg.Quota.MetricSeries,
+		},
+		Namespaces: make(map[string]quota.Limits, len(cfg.Quota.Namespaces)),
+	}
+	for _, ns := range cfg.Quota.Namespaces {
+		q.Namespaces[ns.Name] = quota.Limits{
+			EventsPerMinute: ns.EventsPerMinute,
+			MetricSeries:    ns.MetricSeries,
+		}
+	}
+	return q
+}
@@ -0,0 +1,64 @@
+// Copyright 2026 The HuaTuo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"time"
+
+	"huatuo-bamai/cmd/huatuo-bamai/config"
+	"huatuo-bamai/internal/hostinfo"
+	"huatuo-bamai/internal/otlp"
+)
+
+// setupOTLP pushes the metrics to the OTLP collector, the events are
+// exported by the otlp storage backend.
+func setupOTLP(d *Daemon) (func(context.Context) error, error) {
+	cfg := config.Get().OTLP
+	if cfg.Endpoint == "" || !cfg.Metrics.Enable {
+		return nil, nil
+	}
+
+	client, err := otlp.NewClient(&otlp.Config{
+		Endpoint:           cfg.Endpoint,
+		Headers:            cfg.Headers,
+		Insecure:           cfg.Insecure,
+		CAFile:             cfg.CAFile,
+		CertFile:           cfg.CertFile,
+		KeyFile:            cfg.KeyFile,
+		InsecureSkipVerify: cfg.InsecureSkipVerify,
+		Timeout:            time.Duration(cfg.Timeout) * time.Second,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	exporter := otlp.NewMetricsExporter(client, d.metrics,
+		otlp.Resource(hostinfo.Hostname(), hostinfo.Region()),
+		time.Duration(cfg.Metrics.Interval)*time.Second)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		exporter.Run(ctx)
+	}()
+
+	return func(context.Context) error {
+		cancel()
+		<-done
+		return client.Close()
+	}, nil
+}
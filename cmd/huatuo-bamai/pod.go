@@ -24,24 +24,86 @@ import (
 )
 
 func setupPodManager(d *Daemon) (func(context.Context) error, error) {
-	if d.opts.DisableKubelet {
+	podCfg := &config.Get().Pod
+	if err := pod.SetPodMetadata(podCfg.Metadata.Labels, podCfg.Metadata.Annotations); err != nil {
+		return nil, fmt.Errorf("pod metadata: %w", err)
+	}
+	if err := pod.SetTrackFilter(podCfg.NamespaceWhitelist, podCfg.PodNameBlacklist); err != nil {
+		return nil, fmt.Errorf("pod track filter: %w", err)
+	}
+
+	if err := setupPodResolvers(); err != nil {
+		pod.ReleaseManager()
+		return nil, err
+	}
+
+	release := func(context.Context) error {
+		pod.ReleaseManager()
+		return nil
+	}
+
+	if d.opts.DisableKubelet && podCfg.Mode != pod.ModeStandalone {
 		log.Infof("kubelet pod sync disabled by --disable-kubelet")
-		return nil, nil
+		return release, nil
 	}
 
 	mgrCtx := pod.ManagerCtx{
-		PodReadOnlyPort:   config.Get().Pod.KubeletReadOnlyPort,
-		PodAuthorizedPort: config.Get().Pod.KubeletAuthorizedPort,
-		PodClientCertPath: config.Get().Pod.KubeletClientCertPath,
-		DockerAPIVersion:  config.Get().Pod.DockerAPIVersion,
+		PodReadOnlyPort:     podCfg.KubeletReadOnlyPort,
+		PodAuthorizedPort:   podCfg.KubeletAuthorizedPort,
+		PodClientCertPath:   podCfg.KubeletClientCertPath,
+		PodTokenPath:        podCfg.KubeletTokenPath,
+		PodCAPath:           podCfg.KubeletCAPath,
+		PodServerName:       podCfg.KubeletServerName,
+		DockerAPIVersion:    podCfg.DockerAPIVersion,
+		ContainerRuntime:    podCfg.ContainerRuntime,
+		CRIOSocket:          podCfg.CRIOSocket,
+		CgroupDriver:        podCfg.CgroupDriver,
+		Mode:                podCfg.Mode,
+		CRIEndpoint:         podCfg.Standalone.CRIEndpoint,
+		StandaloneNamespace: podCfg.Standalone.Namespace,
 	}
 
 	if err := pod.InitManager(&mgrCtx); err != nil {
+		pod.ReleaseManager()
 		return nil, fmt.Errorf("init podlist and sync module: %w", err)
 	}
 
-	return func(context.Context) error {
-		pod.ReleaseManager()
-		return nil
-	}, nil
+	return release, nil
+}
+
+// setupPodResolvers registers the resolvers of the workloads not managed by
+// kubelet, they are containers with or without kubelet.
+func setupPodResolvers() error {
+	podCfg := &config.Get().Pod
+
+	if len(podCfg.Systemd.Units) > 0 {
+		r, err := pod.NewSystemdResolver(podCfg.Systemd.Slices, podCfg.Systemd.Units, podCfg.Systemd.Namespace)
+		if err != nil {
+			return fmt.Errorf("pod systemd resolver: %w", err)
+		}
+		if err := pod.RegisterResolver(r); err != nil {
+			return err
+		}
+	}
+
+	for _, rule := range podCfg.CgroupPaths {
+		r, err := pod.NewCgroupPathResolver(rule.Root, rule.Pattern, rule.Namespace)
+		if err != nil {
+			return fmt.Errorf("pod cgroup path resolver: %w", err)
+		}
+		if err := pod.RegisterResolver(r); err != nil {
+			return err
+		}
+	}
+
+	if podCfg.Docker.Enable {
+		r, err := pod.NewDockerResolver(podCfg.Docker.Host, podCfg.DockerAPIVersion, podCfg.Docker.Namespace)
+		if err != nil {
+			return fmt.Errorf("pod docker resolver: %w", err)
+		}
+		if err := pod.RegisterResolver(r); err != nil {
+			return err
+		}
+	}
+	return nil
 }
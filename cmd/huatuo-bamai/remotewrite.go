@@ -0,0 +1,65 @@
+// Copyright 2026 The HuaTuo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"time"
+
+	"huatuo-bamai/cmd/huatuo-bamai/config"
+	"huatuo-bamai/pkg/metric"
+)
+
+// setupRemoteWrite pushes the metrics to the remote write endpoint, the
+// /metrics endpoint is still served.
+func setupRemoteWrite(d *Daemon) (func(context.Context) error, error) {
+	cfg := config.Get().RemoteWrite
+	if cfg.URL == "" {
+		return nil, nil
+	}
+
+	writer, err := metric.NewRemoteWriter(&metric.RemoteWriteConfig{
+		URL:                cfg.URL,
+		Username:           cfg.Username,
+		Password:           cfg.Password,
+		BearerToken:        cfg.BearerToken,
+		Headers:            cfg.Headers,
+		ExternalLabels:     cfg.ExternalLabels,
+		CAFile:             cfg.CAFile,
+		CertFile:           cfg.CertFile,
+		KeyFile:            cfg.KeyFile,
+		InsecureSkipVerify: cfg.InsecureSkipVerify,
+		Timeout:            time.Duration(cfg.Timeout) * time.Second,
+		Interval:           time.Duration(cfg.Interval) * time.Second,
+		BatchSize:          cfg.BatchSize,
+	}, d.metrics)
+	if err != nil {
+		return nil, err
+	}
+	d.metrics.MustRegister(writer)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		writer.Run(ctx)
+	}()
+
+	return func(context.Context) error {
+		cancel()
+		<-done
+		return nil
+	}, nil
+}
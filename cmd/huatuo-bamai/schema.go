@@ -0,0 +1,77 @@
+// Copyright 2026 The HuaTuo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	"huatuo-bamai/cmd/huatuo-bamai/config"
+
+	"github.com/urfave/cli/v2"
+)
+
+// configCommandName is the command working on the config files rather
+// than on the config of the agent, it runs without loading one.
+const configCommandName = "config"
+
+func configCommand() *cli.Command {
+	return &cli.Command{
+		Name:  configCommandName,
+		Usage: "inspect the huatuo-bamai config file format",
+		Subcommands: []*cli.Command{
+			{
+				Name:  "schema",
+				Usage: "print the JSON Schema of the config file, with the defaults and the bounds checked at load time",
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:    cliFlagOutput,
+						Aliases: []string{"o"},
+						Usage:   "output file, defaults to stdout",
+					},
+				},
+				Action: func(ctx *cli.Context) error {
+					output := ctx.String(cliFlagOutput)
+					if output == "" {
+						return writeConfigSchema(os.Stdout)
+					}
+
+					f, err := os.Create(output)
+					if err != nil {
+						return err
+					}
+					if err := writeConfigSchema(f); err != nil {
+						_ = f.Close()
+						return err
+					}
+					return f.Close()
+				},
+			},
+		},
+	}
+}
+
+func writeConfigSchema(w io.Writer) error {
+	schema, err := config.Schema()
+	if err != nil {
+		return fmt.Errorf("config schema: %w", err)
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(schema)
+}
@@ -17,6 +17,7 @@ package main
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"huatuo-bamai/cmd/huatuo-bamai/config"
 	"huatuo-bamai/internal/log"
@@ -37,39 +38,172 @@ func setupStorage(d *Daemon) (func(context.Context) error, error) {
 }
 
 func initStorage(storageRegion string, cfg *config.BamaiConfig) error {
-	var esStore *storage.Store[*tracing.Document]
+	if err := tracing.SetEnrichRules(enrichRules(cfg)); err != nil {
+		return err
+	}
+	if err := tracing.SetSamplingPolicies(samplingPolicies(cfg)); err != nil {
+		return err
+	}
+
+	templates := make([]tracing.RenderTemplate, 0, len(cfg.EventTemplates))
+	for _, t := range cfg.EventTemplates {
+		templates = append(templates, tracing.RenderTemplate{Tracer: t.Tracer, Template: t.Template})
+	}
+	if err := tracing.SetRenderTemplates(templates); err != nil {
+		return err
+	}
+
+	capture := cfg.Storage.ContextCapture
+	if err := tracing.SetCaptureConfig(&tracing.CaptureConfig{
+		Tracers:      capture.Tracers,
+		Files:        capture.Files,
+		CgroupFiles:  capture.CgroupFiles,
+		Rate:         capture.Rate,
+		Burst:        capture.Burst,
+		MaxFileBytes: capture.MaxFileBytes,
+	}); err != nil {
+		return err
+	}
+
+	correlation := cfg.Storage.Correlation
+	if err := tracing.SetCorrelationConfig(&tracing.CorrelationConfig{
+		Tracers:     correlation.Tracers,
+		Window:      time.Duration(correlation.Window) * time.Second,
+		MaxDuration: time.Duration(correlation.MaxDuration) * time.Second,
+		MinEvents:   correlation.MinEvents,
+	}); err != nil {
+		return err
+	}
+
+	if cfg.Storage.Audit.Path != "" {
+		auditStore, err := storage.NewFromConfig[*tracing.AuditRecord](context.Background(), &driver.Config{
+			Driver:    "sqlite",
+			SQLiteDSN: cfg.Storage.Audit.Path,
+		}, tracing.AuditCollection, tracing.AuditStoreMapper{})
+		if err != nil {
+			return fmt.Errorf("new tracing audit store (sqlite): %w", err)
+		}
+		tracing.SetAuditStore(auditStore)
+	}
 
-	tracingMetadataStores := make([]*storage.Store[*tracing.Document], 0, 2)
-	if cfg.Storage.ES.Address != "" &&
+	if cfg.Storage.State.Path != "" {
+		stateStore, err := storage.NewFromConfig[*tracing.StateRecord](context.Background(), &driver.Config{
+			Driver:    "sqlite",
+			SQLiteDSN: cfg.Storage.State.Path,
+		}, tracing.StateCollection, tracing.StateStoreMapper{})
+		if err != nil {
+			return fmt.Errorf("new tracing state store (sqlite): %w", err)
+		}
+		tracing.SetStateStore(stateStore, time.Duration(cfg.Storage.State.MaxAge)*time.Second)
+	}
+
+	esEnabled := cfg.Storage.ES.Address != "" &&
 		cfg.Storage.ES.Username != "" &&
-		cfg.Storage.ES.Password != "" {
-		store, err := storage.NewFromConfig[*tracing.Document](context.Background(), &driver.Config{
-			Driver:      "elasticsearch",
-			ESAddresses: strutil.SplitCommaList(cfg.Storage.ES.Address),
-			ESUsername:  cfg.Storage.ES.Username,
-			ESPassword:  cfg.Storage.ES.Password,
-			ESIndex:     cfg.Storage.ES.Index,
-		}, tracing.DocumentCollection, tracing.DocumentStoreMapper{})
+		cfg.Storage.ES.Password != ""
+
+	tracingMetadataStores := make([]*storage.Store[*tracing.Document], 0, 5)
+	if esEnabled {
+		esStore, err := newESStore(cfg, tracing.DocumentCollection, tracing.DocumentStoreMapper{}, cfg.Storage.ES.SpillPath, cfg.Storage.DeadLetter.Path)
 		if err != nil {
 			return fmt.Errorf("new tracing document store (elasticsearch): %w", err)
 		}
-		esStore = store
 		tracingMetadataStores = append(tracingMetadataStores, esStore)
 	}
 
 	if cfg.Storage.LocalFile.Path != "" {
-		localFileStore, err := storage.NewFromConfig[*tracing.Document](context.Background(), &driver.Config{
+		localFileStore, err := storage.NewFromConfig[*tracing.Document](context.Background(), withDeadLetter(&driver.Config{
 			Driver:                "localfile",
 			LocalFilePath:         cfg.Storage.LocalFile.Path,
 			LocalFileMaxRotation:  cfg.Storage.LocalFile.MaxRotation,
 			LocalFileRotationSize: cfg.Storage.LocalFile.RotationSize,
-		}, tracing.DocumentCollection, tracing.DocumentStoreMapper{})
+			LocalFileMaxAgeDays:   cfg.Storage.LocalFile.MaxAgeDays,
+			LocalFileCompression:  cfg.Storage.LocalFile.Compression,
+			LocalFileSyncInterval: time.Duration(cfg.Storage.LocalFile.SyncInterval) * time.Second,
+		}, cfg, cfg.Storage.DeadLetter.Path), tracing.DocumentCollection, tracing.DocumentStoreMapper{})
 		if err != nil {
 			return fmt.Errorf("new tracing document store (localfile): %w", err)
 		}
 		tracingMetadataStores = append(tracingMetadataStores, localFileStore)
 	}
 
+	if cfg.Storage.SQLite.Path != "" {
+		sqliteStore, err := storage.NewFromConfig[*tracing.Document](context.Background(), withDeadLetter(&driver.Config{
+			Driver:               "sqlite",
+			SQLiteDSN:            cfg.Storage.SQLite.Path,
+			SQLiteRetention:      time.Duration(cfg.Storage.SQLite.MaxAge) * 24 * time.Hour,
+			SQLiteRetentionField: "time",
+		}, cfg, cfg.Storage.DeadLetter.Path), tracing.DocumentCollection, tracing.DocumentStoreMapper{})
+		if err != nil {
+			return fmt.Errorf("new tracing document store (sqlite): %w", err)
+		}
+		tracingMetadataStores = append(tracingMetadataStores, sqliteStore)
+		tracing.SetEventQueryStore(sqliteStore)
+	}
+
+	if cfg.Storage.ClickHouse.Address != "" {
+		clickHouse := cfg.Storage.ClickHouse
+		clickHouseStore, err := storage.NewFromConfig[*tracing.Document](context.Background(), withDeadLetter(&driver.Config{
+			Driver:                       "clickhouse",
+			ClickHouseAddress:            clickHouse.Address,
+			ClickHouseUsername:           clickHouse.Username,
+			ClickHousePassword:           clickHouse.Password,
+			ClickHouseDatabase:           clickHouse.Database,
+			ClickHouseTable:              clickHouse.Table,
+			ClickHouseCAFile:             clickHouse.CAFile,
+			ClickHouseInsecureSkipVerify: clickHouse.InsecureSkipVerify,
+			ClickHouseBatchSize:          clickHouse.BatchSize,
+			ClickHouseFlushInterval:      time.Duration(clickHouse.FlushInterval) * time.Second,
+			ClickHouseRetention:          time.Duration(clickHouse.Retention) * 24 * time.Hour,
+		}, cfg, cfg.Storage.DeadLetter.Path), tracing.DocumentCollection, tracing.DocumentStoreMapper{})
+		if err != nil {
+			return fmt.Errorf("new tracing document store (clickhouse): %w", err)
+		}
+		tracingMetadataStores = append(tracingMetadataStores, clickHouseStore)
+	}
+
+	if cfg.Storage.Loki.Address != "" {
+		loki := cfg.Storage.Loki
+		lokiStore, err := storage.NewFromConfig[*tracing.Document](context.Background(), withDeadLetter(&driver.Config{
+			Driver:                 "loki",
+			LokiAddress:            loki.Address,
+			LokiUsername:           loki.Username,
+			LokiPassword:           loki.Password,
+			LokiTenantID:           loki.TenantID,
+			LokiCAFile:             loki.CAFile,
+			LokiInsecureSkipVerify: loki.InsecureSkipVerify,
+			LokiBatchSize:          loki.BatchSize,
+			LokiFlushInterval:      time.Duration(loki.FlushInterval) * time.Second,
+		}, cfg, cfg.Storage.DeadLetter.Path), tracing.DocumentCollection, tracing.DocumentStoreMapper{})
+		if err != nil {
+			return fmt.Errorf("new tracing document store (loki): %w", err)
+		}
+		tracingMetadataStores = append(tracingMetadataStores, lokiStore)
+	}
+
+	if otlp := cfg.OTLP; otlp.Endpoint != "" && otlp.Logs.Enable {
+		otlpStore, err := storage.NewFromConfig[*tracing.Document](context.Background(), withDeadLetter(&driver.Config{
+			Driver:                 "otlp",
+			OTLPEndpoint:           otlp.Endpoint,
+			OTLPHeaders:            otlp.Headers,
+			OTLPInsecure:           otlp.Insecure,
+			OTLPCAFile:             otlp.CAFile,
+			OTLPCertFile:           otlp.CertFile,
+			OTLPKeyFile:            otlp.KeyFile,
+			OTLPInsecureSkipVerify: otlp.InsecureSkipVerify,
+			OTLPTimeout:            time.Duration(otlp.Timeout) * time.Second,
+			OTLPBatchSize:          otlp.Logs.BatchSize,
+			OTLPFlushInterval:      time.Duration(otlp.Logs.FlushInterval) * time.Second,
+		}, cfg, cfg.Storage.DeadLetter.Path), tracing.DocumentCollection, tracing.DocumentStoreMapper{})
+		if err != nil {
+			return fmt.Errorf("new tracing document store (otlp): %w", err)
+		}
+		tracingMetadataStores = append(tracingMetadataStores, otlpStore)
+	}
+
+	if err := setStoreRoutes(cfg, tracingMetadataStores); err != nil {
+		return err
+	}
+
 	if len(tracingMetadataStores) > 0 {
 		tracing.SetTracingStore(
 			tracingMetadataStores,
@@ -78,28 +212,116 @@ func initStorage(storageRegion string, cfg *config.BamaiConfig) error {
 			},
 		)
 	}
-	if esStore != nil {
-		tracing.SetTaskStore([]*storage.Store[*tracing.Document]{esStore}, tracing.DocumentOptions{Region: storageRegion})
+	if !esEnabled {
+		return nil
 	}
 
-	if cfg.Storage.ES.Address != "" &&
-		cfg.Storage.ES.Username != "" &&
-		cfg.Storage.ES.Password != "" {
-		profileStore, err := storage.NewFromConfig[*tracing.Document](context.Background(), &driver.Config{
-			Driver:      "elasticsearch",
-			ESAddresses: strutil.SplitCommaList(cfg.Storage.ES.Address),
-			ESUsername:  cfg.Storage.ES.Username,
-			ESPassword:  cfg.Storage.ES.Password,
-			ESIndex:     cfg.Storage.ES.Index,
-		}, profiler.MetadataCollection, tracing.ProfileDocumentStoreMapper{})
-		if err != nil {
-			return fmt.Errorf("new profiling document store (elasticsearch): %w", err)
-		}
-		tracing.SetProfileStore(
-			[]*storage.Store[*tracing.Document]{profileStore},
-			tracing.DocumentOptions{Region: storageRegion},
-		)
+	// the task results have their own bulk queue, they are not stuck
+	// behind the backlog of the events.
+	taskStore, err := newESStore(cfg, tracing.DocumentCollection, tracing.DocumentStoreMapper{}, "", "")
+	if err != nil {
+		return fmt.Errorf("new task document store (elasticsearch): %w", err)
+	}
+	tracing.SetTaskStore([]*storage.Store[*tracing.Document]{taskStore}, tracing.DocumentOptions{Region: storageRegion})
+
+	profileStore, err := newESStore(cfg, profiler.MetadataCollection, tracing.ProfileDocumentStoreMapper{}, "", "")
+	if err != nil {
+		return fmt.Errorf("new profiling document store (elasticsearch): %w", err)
 	}
+	tracing.SetProfileStore(
+		[]*storage.Store[*tracing.Document]{profileStore},
+		tracing.DocumentOptions{Region: storageRegion},
+	)
 
 	return nil
 }
+
+// newESStore creates an elasticsearch store, each one has its own bulk
+// queue. Only the events spill to disk and have a dead letter, neither
+// directory is shared.
+func newESStore(cfg *config.BamaiConfig, collection string, mapper driver.Mapper[*tracing.Document], spillPath, deadLetterPath string) (*storage.Store[*tracing.Document], error) {
+	return storage.NewFromConfig[*tracing.Document](context.Background(), withDeadLetter(&driver.Config{
+		Driver:         "elasticsearch",
+		ESAddresses:    strutil.SplitCommaList(cfg.Storage.ES.Address),
+		ESUsername:     cfg.Storage.ES.Username,
+		ESPassword:     cfg.Storage.ES.Password,
+		ESIndex:        cfg.Storage.ES.Index,
+		ESRoutes:       esRoutes(cfg),
+		ESQueueSize:    cfg.Storage.ES.QueueSize,
+		ESSpillPath:    spillPath,
+		ESSpillMaxSize: int64(cfg.Storage.ES.SpillMaxSize) * 1024 * 1024,
+		ESIndexPattern: cfg.Storage.ES.IndexPattern,
+		ESILMPolicy:    cfg.Storage.ES.ILMPolicy,
+		ESRetention:    time.Duration(cfg.Storage.ES.Retention) * 24 * time.Hour,
+		ESRolloverSize: int64(cfg.Storage.ES.RolloverSize) << 30,
+		ESRolloverAge:  time.Duration(cfg.Storage.ES.RolloverAge) * 24 * time.Hour,
+	}, cfg, deadLetterPath), collection, mapper)
+}
+
+// withDeadLetter sets the dead letter of the events stores, in a directory
+// of path per backend.
+func withDeadLetter(dc *driver.Config, cfg *config.BamaiConfig, path string) *driver.Config {
+	dc.DeadLetterPath = path
+	dc.DeadLetterMaxSize = int64(cfg.Storage.DeadLetter.MaxSize) * 1024 * 1024
+	dc.DeadLetterRedriveInterval = time.Duration(cfg.Storage.DeadLetter.RedriveInterval) * time.Second
+	return dc
+}
+
+func esRoutes(cfg *config.BamaiConfig) []driver.ESRoute {
+	routes := make([]driver.ESRoute, 0, len(cfg.Storage.ES.Routes))
+	for _, r := range cfg.Storage.ES.Routes {
+		routes = append(routes, driver.ESRoute{
+			Name:      r.Name,
+			Tracers:   r.Tracers,
+			Retention: time.Duration(r.Retention) * 24 * time.Hour,
+		})
+	}
+	return routes
+}
+
+// setStoreRoutes installs the routing rules of the events, the backends
+// not enabled on this node are ignored.
+func setStoreRoutes(cfg *config.BamaiConfig, stores []*storage.Store[*tracing.Document]) error {
+	enabled := make(map[string]bool, len(stores))
+	for _, store := range stores {
+		enabled[store.Name] = true
+	}
+
+	routes := make([]tracing.StoreRoute, 0, len(cfg.Storage.Routing))
+	for _, r := range cfg.Storage.Routing {
+		for _, backend := range r.Backends {
+			if !enabled[backend] {
+				log.Warnf("storage routing of %v: backend %s is not enabled", r.Tracers, backend)
+			}
+		}
+		routes = append(routes, tracing.StoreRoute{Tracers: r.Tracers, Backends: r.Backends})
+	}
+	return tracing.SetStoreRoutes(routes)
+}
+
+func enrichRules(cfg *config.BamaiConfig) []tracing.EnrichRule {
+	rules := make([]tracing.EnrichRule, 0, len(cfg.Storage.Enrichment))
+	for _, r := range cfg.Storage.Enrichment {
+		rule := tracing.EnrichRule{Tracer: r.Tracer, Drop: r.Drop}
+		for _, f := range r.Fields {
+			rule.Fields = append(rule.Fields, tracing.EnrichField{Name: f.Name, Expr: f.Expr})
+		}
+		rules = append(rules, rule)
+	}
+	return rules
+}
+
+func samplingPolicies(cfg *config.BamaiConfig) []tracing.SamplingPolicy {
+	policies := make([]tracing.SamplingPolicy, 0, len(cfg.Storage.Sampling))
+	for _, p := range cfg.Storage.Sampling {
+		policies = append(policies, tracing.SamplingPolicy{
+			Tracers:     p.Tracers,
+			Every:       p.Every,
+			Probability: p.Probability,
+			Rate:        p.Rate,
+			Burst:       p.Burst,
+			Always:      p.Always,
+		})
+	}
+	return policies
+}
@@ -15,18 +15,22 @@
 package main
 
 import (
+	"bytes"
 	"context"
 	"fmt"
+	"os"
+	"time"
 
 	"huatuo-bamai/cmd/huatuo-bamai/config"
 	"huatuo-bamai/cmd/huatuo-bamai/handlers"
 	"huatuo-bamai/internal/bpf"
+	"huatuo-bamai/internal/log"
 	"huatuo-bamai/internal/toolstream"
 	"huatuo-bamai/pkg/tracing"
 )
 
 func setupBPF(_ *Daemon) (func(context.Context) error, error) {
-	if err := bpf.NewManager(&bpf.Option{}); err != nil {
+	if err := bpf.NewManager(&bpf.Option{OnLoadFailure: saveBpfLoadFailure}); err != nil {
 		return nil, fmt.Errorf("init bpf manager: %w", err)
 	}
 
@@ -36,6 +40,18 @@ func setupBPF(_ *Daemon) (func(context.Context) error, error) {
 	}, nil
 }
 
+// saveBpfLoadFailure stores the verifier diagnostics as an event, so that
+// load failures on remote hosts can be debugged without shell access.
+func saveBpfLoadFailure(f *bpf.LoadFailure) {
+	if err := tracing.Save(&tracing.WriteRequest{
+		TracerName: "bpf_load_failure",
+		TracerTime: f.Time,
+		TracerData: f,
+	}); err != nil {
+		log.Warnf("failed to save tracing data: %v", err)
+	}
+}
+
 func startToolstream(_ *Daemon) (func(context.Context) error, error) {
 	srv, err := toolstream.NewServerDefault()
 	if err != nil {
@@ -55,10 +71,31 @@ func startTracing(d *Daemon) (func(context.Context) error, error) {
 		return nil, fmt.Errorf("new tracing manager: %w", err)
 	}
 
+	// the snapshots of the tracers record the config they started with.
+	tracing.SetTracerConfigFunc(func(tracer string) any {
+		if _, value, ok := config.TracerConfig(config.Get(), tracer); ok {
+			return value
+		}
+		return nil
+	})
+
 	if err := mgr.Start(context.Background()); err != nil {
 		return nil, fmt.Errorf("start tracing manager: %w", err)
 	}
 
+	bp := config.Get().Storage.Backpressure
+	if err := mgr.StartBackpressure(&tracing.BackpressureConfig{
+		Tracers:       bp.Tracers,
+		SampleBacklog: bp.SampleBacklog,
+		SampleRate:    bp.SampleRate,
+		PauseBacklog:  bp.PauseBacklog,
+		ResumeBacklog: bp.ResumeBacklog,
+		Interval:      time.Duration(bp.Interval) * time.Second,
+	}); err != nil {
+		_ = mgr.Close(context.Background())
+		return nil, fmt.Errorf("start tracing backpressure: %w", err)
+	}
+
 	d.tracer = mgr
 	// Stop collectors first, then drain bulk-buffered writes before BPF teardown.
 	return func(ctx context.Context) error {
@@ -73,11 +110,29 @@ func startTracing(d *Daemon) (func(context.Context) error, error) {
 }
 
 func startHandlers(d *Daemon) (func(context.Context) error, error) {
+	taskCfg := config.Get().Task
+
+	var signingKey []byte
+	if taskCfg.SigningKeyFile != "" {
+		key, err := os.ReadFile(taskCfg.SigningKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("read task signing key: %w", err)
+		}
+		if signingKey = bytes.TrimSpace(key); len(signingKey) == 0 {
+			return nil, fmt.Errorf("task signing key %s is empty", taskCfg.SigningKeyFile)
+		}
+	}
+
 	handlers.Start(handlers.ServerOptions{
-		Addr:           config.Get().APIServer.TCPAddr,
-		TracingManager: d.tracer,
-		PromReg:        d.metrics,
-		VersionInfo:    &d.opts.VersionInfo,
+		Addr:            config.Get().APIServer.TCPAddr,
+		TracingManager:  d.tracer,
+		PromReg:         d.metrics,
+		PromGroups:      d.metricGroups,
+		VersionInfo:     &d.opts.VersionInfo,
+		Collectors:      d.collectors,
+		Downsampler:     d.downsampler,
+		SigningKey:      signingKey,
+		SignatureMaxAge: time.Duration(taskCfg.SignatureMaxAge) * time.Second,
 	})
 	return nil, nil
 }
@@ -198,7 +198,8 @@ func (a *nativeAggregator) snapshotCpuMemProfile(pctx *pcontext.ProfilerContext)
 	}
 
 	skipNegForPprof := pctx.Type == profiling.TypeMemory &&
-		pctx.MemoryMode == profiling.MemoryModePhysicalUsage
+		(pctx.MemoryMode == profiling.MemoryModePhysicalUsage ||
+			pctx.MemoryMode == profiling.MemoryModeVirtualUsage)
 
 	tree := make([]*profiler.TreeItem, 0, len(a.aggrMap))
 
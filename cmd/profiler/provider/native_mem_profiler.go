@@ -33,6 +33,7 @@ import (
 
 //go:generate $BPF_COMPILE $BPF_INCLUDE -s $BPF_DIR/native_physical_usage.c -o $BPF_DIR/native_physical_usage.o
 //go:generate $BPF_COMPILE $BPF_INCLUDE -s $BPF_DIR/native_virtual_alloc.c -o $BPF_DIR/native_virtual_alloc.o
+//go:generate $BPF_COMPILE $BPF_INCLUDE -s $BPF_DIR/native_virtual_usage.c -o $BPF_DIR/native_virtual_usage.o
 //go:generate $BPF_COMPILE $BPF_INCLUDE -s $BPF_DIR/native_physical_alloc.c -o $BPF_DIR/native_physical_alloc.o
 
 const (
@@ -60,7 +61,7 @@ func init() {
 	registry.Register(registry.ProfilerMeta{
 		Type:           profiling.TypeMemory,
 		Implementation: profiling.ImplementationNative,
-		Description:    "Native memory profiler using eBPF (virtual_alloc, physical_alloc, physical_usage modes)",
+		Description:    "Native memory profiler using eBPF (virtual_alloc, virtual_usage, physical_alloc, physical_usage modes)",
 		Impl:           impl,
 		NewAggregator:  impl.NewAggregator,
 	})
@@ -74,7 +75,7 @@ func (p *memNativeProfiler) NewAggregator(pctx *pcontext.ProfilerContext) (aggre
 		return nil, err
 	}
 
-	if mode == profiling.MemoryModePhysicalUsage {
+	if mode == profiling.MemoryModePhysicalUsage || mode == profiling.MemoryModeVirtualUsage {
 		pctx.IsOneShotAgg = true
 	}
 
@@ -165,6 +166,17 @@ func newNativeMemoryBPFLoadConfig(internalMode profiling.MemoryMode, pid int, cs
 				{ProgramName: "trace_mmap", Symbol: "do_mmap"},
 			},
 		}, nil
+	case profiling.MemoryModeVirtualUsage:
+		return &nativeMemoryBPFLoadConfig{
+			ObjectFile: "native_virtual_usage.o",
+			Constants:  constants,
+			AttachOpts: []bpf.AttachOption{
+				{ProgramName: "trace_mmap_enter", Symbol: "do_mmap"},
+				{ProgramName: "trace_mmap_exit", Symbol: "do_mmap"},
+				{ProgramName: "trace_munmap", Symbol: "syscalls/sys_enter_munmap"},
+				{ProgramName: "trace_brk", Symbol: "syscalls/sys_enter_brk"},
+			},
+		}, nil
 	case profiling.MemoryModePhysicalUsage:
 		attachOpts, err := newPhysicalUsageAttachOptions()
 		if err != nil {
@@ -240,8 +252,9 @@ func (p *memNativeProfiler) ReadDataLoop(ctx context.Context, enqueue func(any))
 	defer log.Info("data reading loop ended")
 
 	// Determine if fallback is needed based on profiling mode
-	// Retained mode (physical_usage) needs fallback, others don't
-	needsFallback := p.internalMode == profiling.MemoryModePhysicalUsage
+	// Retained modes (physical_usage, virtual_usage) need fallback, others don't
+	needsFallback := p.internalMode == profiling.MemoryModePhysicalUsage ||
+		p.internalMode == profiling.MemoryModeVirtualUsage
 
 	// Initialize ring buffer context once, reuse throughout the profiling loop
 	ringCtx, err := newRingBufferContext(p.bpf, ctx, 4096*257, needsFallback)
@@ -282,7 +295,7 @@ func (p *memNativeProfiler) ReadDataLoop(ctx context.Context, enqueue func(any))
 
 func (p *memNativeProfiler) convertValueToBytes(v int64) int64 {
 	switch p.internalMode {
-	case profiling.MemoryModeVirtualAlloc:
+	case profiling.MemoryModeVirtualAlloc, profiling.MemoryModeVirtualUsage:
 		return v
 	case profiling.MemoryModePhysicalAlloc, profiling.MemoryModePhysicalUsage:
 		return v * p.pageSize * 100 / int64(p.probability)
@@ -48,6 +48,17 @@ func TestNewBpfLoadConfigAttachOpts(t *testing.T) {
 				{ProgramName: "trace_mmap", Symbol: "do_mmap"},
 			},
 		},
+		{
+			name:       "virtual usage",
+			mode:       profiling.MemoryModeVirtualUsage,
+			wantObject: "native_virtual_usage.o",
+			wantAttach: []bpf.AttachOption{
+				{ProgramName: "trace_mmap_enter", Symbol: "do_mmap"},
+				{ProgramName: "trace_mmap_exit", Symbol: "do_mmap"},
+				{ProgramName: "trace_munmap", Symbol: "syscalls/sys_enter_munmap"},
+				{ProgramName: "trace_brk", Symbol: "syscalls/sys_enter_brk"},
+			},
+		},
 		{
 			name:            "physical usage",
 			mode:            profiling.MemoryModePhysicalUsage,
@@ -30,6 +30,7 @@ func validateNativePIDs(profileType string, pids []int) error {
 func resolveMemMode(mode profiling.MemoryMode) (profiling.MemoryMode, error) {
 	switch mode {
 	case profiling.MemoryModeVirtualAlloc,
+		profiling.MemoryModeVirtualUsage,
 		profiling.MemoryModePhysicalUsage,
 		profiling.MemoryModePhysicalAlloc:
 		return mode, nil
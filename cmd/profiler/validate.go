@@ -284,8 +284,9 @@ func validateProfilerFlagCompatibility(ctx *cli.Context, lang profiling.Language
 		return fmt.Errorf("--binary-match-path is not supported by native profilers")
 	}
 	if ctx.IsSet("physical-memory-probability") {
+		mode := profiling.MemoryMode(ctx.String("memory-mode"))
 		physicalMemory := nativeMemory &&
-			profiling.MemoryMode(ctx.String("memory-mode")) != profiling.MemoryModeVirtualAlloc
+			mode != profiling.MemoryModeVirtualAlloc && mode != profiling.MemoryModeVirtualUsage
 		if !physicalMemory {
 			return fmt.Errorf("--physical-memory-probability is supported only by native physical memory profiling")
 		}
@@ -430,7 +430,7 @@ func TestValidateMemoryMode(t *testing.T) {
 			language:  "go",
 			typ:       "memory",
 			mode:      "object_alloc",
-			wantError: "memory mode \"object_alloc\" is not supported for go; supported modes: virtual_alloc, physical_alloc, physical_usage",
+			wantError: "memory mode \"object_alloc\" is not supported for go; supported modes: virtual_alloc, virtual_usage, physical_alloc, physical_usage",
 		},
 		{
 			name:     "Python is validated before memory mode",
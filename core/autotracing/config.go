@@ -42,6 +42,17 @@ type MemBurstConfig struct {
 	DumpProcessMaxNum   int `default:"10"`
 }
 
+// MemLeakConfig holds memory leak autotracing configuration. The observation
+// window is Interval * WindowLength seconds, three hours by default.
+type MemLeakConfig struct {
+	Interval          int `default:"60"`
+	WindowLength      int `default:"180"`
+	GrowthThreshold   int `default:"64"`
+	MonotonicRatio    int `default:"90"`
+	IntervalTracing   int `default:"21600"`
+	DumpProcessMaxNum int `default:"5"`
+}
+
 // Config holds autotracing configuration.
 type Config struct {
 	CPUIdle struct {
@@ -55,21 +66,21 @@ type Config struct {
 		IntervalTracing       int64                  `default:"1800"`
 		RunTracingToolTimeout int64                  `default:"10"`
 		Filter                *ContainerFilterConfig `toml:"Filter"`
-	}
+	} `tracer:"cpuidle"`
 
 	CPUSys struct {
 		SysThreshold          int64 `default:"45"`
 		DeltaSysThreshold     int64 `default:"20"`
 		Interval              int64 `default:"10"`
 		RunTracingToolTimeout int64 `default:"10"`
-	}
+	} `tracer:"cpusys"`
 
 	Dload struct {
 		ThresholdLoad   int64 `default:"5"`
 		Interval        int64 `default:"10"`
 		IntervalTracing int64 `default:"1800"`
 		EnableDebug     bool  `default:"false"`
-	}
+	} `tracer:"dload"`
 
 	IOTracing struct {
 		RbpsThreshold         uint64 `default:"2000"`
@@ -81,7 +92,55 @@ type Config struct {
 		MaxFilesPerProcDump   int    `default:"5"`
 	}
 
-	MemoryBurst MemBurstConfig
+	MemoryBurst MemBurstConfig `tracer:"memburst"`
+
+	MemoryLeak MemLeakConfig `tracer:"memleak"`
+
+	// Eviction keeps WindowLength samples of every container, taken every
+	// Interval seconds, for the snapshot of the evicted pods.
+	Eviction struct {
+		Interval     int64 `default:"5"`
+		WindowLength int   `default:"12"`
+		MaxSockets   int   `default:"100"`
+	} `tracer:"eviction"`
+
+	// IdleDiagnostics runs the deep node checks while the node is idle,
+	// CPUThreshold is the busy percent and MemoryTestSize in MiB.
+	IdleDiagnostics struct {
+		Enable            bool  `default:"false"`
+		CPUThreshold      int64 `default:"10"`
+		IdleDuration      int64 `default:"600"`
+		Interval          int64 `default:"60"`
+		IntervalDiagnosis int64 `default:"86400"`
+		MemoryTestSize    int64 `default:"256"`
+		EnableGPU         bool  `default:"true"`
+		EnableSMART       bool  `default:"true"`
+	} `tracer:"idlediag"`
+
+	// MemoryBandwidth reports the host memory bandwidth saturation, from
+	// resctrl MBM, starving the GPUs. PeakBandwidth is the MiB/s of an L3
+	// domain and PcieThroughputThreshold the MiB/s of a GPU.
+	MemoryBandwidth struct {
+		Enable                  bool  `default:"false"`
+		Interval                int64 `default:"10"`
+		PeakBandwidth           int64
+		SaturationPercent       int64 `default:"80"`
+		GPUUtilThreshold        int64 `default:"30"`
+		PcieThroughputThreshold int64 `default:"1024"`
+		Samples                 int64 `default:"3"`
+		IntervalTracing         int64 `default:"1800"`
+	} `tracer:"membw"`
+
+	// PSI snapshots the top processes and memory.stat of the host, or of a
+	// container, when the avg10 of the some or full pressure of a resource
+	// crosses SomeThreshold or FullThreshold percent, 0 disables one.
+	PSI struct {
+		SomeThreshold     float64 `default:"40"`
+		FullThreshold     float64 `default:"10"`
+		Interval          int64   `default:"10"`
+		IntervalTracing   int64   `default:"600"`
+		DumpProcessMaxNum int     `default:"10"`
+	} `tracer:"psi"`
 
 	// IssuesList for known issue filtering
 	IssuesList [][]string
@@ -1,4 +1,4 @@
-// Copyright 2025 The HuaTuo Authors
+// Copyright 2025, 2026 The HuaTuo Authors
 //
 // Licensed under the Apache License, Version 2.0 (the "License");
 // you may not use this file except in compliance with the License.
@@ -114,7 +114,7 @@ func shouldCareThisLoadEvent(container *containerDloadInfo, threshold *dloadThre
 	intervalTracing := nowtime.Sub(container.traceTime)
 
 	if int64(intervalTracing.Seconds()) > threshold.intervalTracing {
-		if container.loaduni[0] > float64(threshold.thresh) {
+		if container.loaduni[0] > float64(threshold.of(container)) {
 			container.traceTime = nowtime
 			return true
 		}
@@ -152,7 +152,7 @@ func detectDloadContainer(threshold *dloadThreshold) (*containerDloadInfo, cadvi
 
 			if shouldCareThisLoadEvent(container, threshold) {
 				log.Infof("dload event: Threshold=%0.2f %+v, LoadAvg=%0.2f, DLoadAvg=%0.2f",
-					float64(threshold.thresh), stats, container.load[0], container.loaduni[0])
+					float64(threshold.of(container)), stats, container.load[0], container.loaduni[0])
 				return container, stats, nil
 			}
 		}
@@ -187,7 +187,7 @@ func buildAndSaveDloadContainer(thresh *dloadThreshold, container *containerDloa
 		NrIoWait:          loadstat.NrIoWait,
 		LoadAvg:           container.load[0],
 		DLoadAvg:          container.loaduni[0],
-		Threshold:         uint64(thresh.thresh),
+		Threshold:         thresh.of(container),
 		Stack:             fmt.Sprintf("%s%s", stackCgrp, stackHost),
 	}
 
@@ -356,6 +356,11 @@ type dloadThreshold struct {
 	debug           bool
 }
 
+// of returns the load threshold of the container, its pod may override it.
+func (t *dloadThreshold) of(container *containerDloadInfo) uint64 {
+	return container.container.Threshold("dload", uint64(t.thresh))
+}
+
 // Start detect work, monitor the load of containers.
 // CGROUPSTATS_CMD_GET netlink API only works with cgroup v1.
 func (c *dloadTracing) Start(ctx context.Context) error {
@@ -0,0 +1,289 @@
+// Copyright 2026 The HuaTuo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package autotracing
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+
+	"huatuo-bamai/internal/cgroups"
+	"huatuo-bamai/internal/log"
+	"huatuo-bamai/internal/packet"
+	"huatuo-bamai/internal/pod"
+	"huatuo-bamai/internal/procfs"
+	"huatuo-bamai/pkg/tracing"
+	"huatuo-bamai/pkg/types"
+)
+
+func init() {
+	tracing.RegisterEventTracing("eviction", newEviction)
+}
+
+var (
+	// evictions are queued by the pod sync, the tracer must not block it.
+	evictions            = make(chan *pod.ContainerEviction, 64)
+	evictionRegisterOnce sync.Once
+)
+
+func newEviction() (*tracing.EventTracingAttr, error) {
+	cgroup, err := cgroups.NewManager()
+	if err != nil {
+		return nil, err
+	}
+
+	evictionRegisterOnce.Do(func() {
+		pod.RegisterEvictionHandler(func(eviction *pod.ContainerEviction) {
+			select {
+			case evictions <- eviction:
+			default:
+				log.Warnf("eviction queue full, drop container %s", eviction.Container.ID)
+			}
+		})
+	})
+
+	return &tracing.EventTracingAttr{
+		TracingData: &evictionTracing{cgroupMgr: cgroup},
+		Interval:    10,
+		Flag:        tracing.FlagTracing,
+	}, nil
+}
+
+type evictionTracing struct {
+	cgroupMgr cgroups.Cgroup
+}
+
+// EvictionTracingData is the last diagnostic state of a container whose pod
+// was evicted by kubelet for resource pressure.
+type EvictionTracingData struct {
+	PodName   string `json:"pod_name"`
+	Namespace string `json:"namespace"`
+	Message   string `json:"message"`
+	// Cgroup is nil once the cgroup is removed, the window still tells.
+	Cgroup      *evictionCgroupStats `json:"cgroup,omitempty"`
+	CgroupError string               `json:"cgroup_error,omitempty"`
+	Window      []*evictionSample    `json:"window"`
+	Sockets     *evictionSockets     `json:"sockets,omitempty"`
+}
+
+type evictionCgroupStats struct {
+	CPUUsage     uint64            `json:"cpu_usage_us"`
+	CPUStat      map[string]uint64 `json:"cpu_stat"`
+	MemoryUsage  uint64            `json:"memory_usage"`
+	MemoryLimit  uint64            `json:"memory_limit"`
+	MemoryStat   map[string]uint64 `json:"memory_stat"`
+	MemoryEvents map[string]uint64 `json:"memory_events,omitempty"`
+	Pids         uint64            `json:"pids"`
+}
+
+// evictionSample is one point of the recent usage of a container.
+type evictionSample struct {
+	Time        time.Time `json:"time"`
+	CPUUsage    uint64    `json:"cpu_usage_us"`
+	MemoryUsage uint64    `json:"memory_usage"`
+	MemoryLimit uint64    `json:"memory_limit"`
+	Pids        uint64    `json:"pids"`
+}
+
+type evictionSockets struct {
+	// States counts the tcp sockets by state.
+	States map[string]int `json:"states"`
+	// Sockets lists the first MaxSockets tcp sockets.
+	Sockets []*evictionSocket `json:"sockets"`
+}
+
+type evictionSocket struct {
+	Local   string `json:"local"`
+	Remote  string `json:"remote"`
+	State   string `json:"state"`
+	TxQueue uint64 `json:"tx_queue"`
+	RxQueue uint64 `json:"rx_queue"`
+}
+
+func validateEviction() error {
+	c := &cfg.Eviction
+	if c.Interval <= 0 || c.WindowLength <= 0 {
+		return fmt.Errorf("eviction interval and window length must be positive, got %d and %d",
+			c.Interval, c.WindowLength)
+	}
+	if c.MaxSockets < 0 {
+		return fmt.Errorf("eviction max sockets must be non-negative, got %d", c.MaxSockets)
+	}
+	return nil
+}
+
+func (c *evictionTracing) Start(ctx context.Context) error {
+	if err := validateEviction(); err != nil {
+		return err
+	}
+
+	windows := newContainerSamples[*evictionSample](cfg.Eviction.WindowLength)
+
+	// sampling lists the containers, which syncs them from kubelet, so the
+	// evictions are noticed within an interval.
+	ticker := time.NewTicker(time.Duration(cfg.Eviction.Interval) * time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return types.ErrExitByCancelCtx
+		case eviction := <-evictions:
+			c.report(eviction, windows.take(eviction.Container.ID))
+		case <-ticker.C:
+			c.sample(windows)
+		}
+	}
+}
+
+func (c *evictionTracing) sample(windows *containerSamples[*evictionSample]) {
+	containers, err := pod.Containers()
+	if err != nil {
+		log.Debugf("eviction list containers: %v", err)
+		return
+	}
+
+	windows.track(containers)
+	for _, w := range windows.rings {
+		sample, err := c.readSample(w.path)
+		if err != nil {
+			log.Debugf("eviction sample [%s]: %v", w.path, err)
+			continue
+		}
+		w.add(sample)
+	}
+}
+
+func (c *evictionTracing) readSample(path string) (*evictionSample, error) {
+	cpu, err := c.cgroupMgr.CpuUsage(path)
+	if err != nil {
+		return nil, err
+	}
+	memory, err := c.cgroupMgr.MemoryUsage(path)
+	if err != nil {
+		return nil, err
+	}
+
+	sample := &evictionSample{
+		Time:        time.Now(),
+		CPUUsage:    cpu.Usage,
+		MemoryUsage: memory.Usage,
+		MemoryLimit: memory.MaxLimited,
+	}
+	if pids, err := c.cgroupMgr.PidsUsage(path); err == nil {
+		sample.Pids = pids.Current
+	}
+	return sample, nil
+}
+
+func (c *evictionTracing) readCgroupStats(path string) (*evictionCgroupStats, error) {
+	cpu, err := c.cgroupMgr.CpuUsage(path)
+	if err != nil {
+		return nil, err
+	}
+	memory, err := c.cgroupMgr.MemoryUsage(path)
+	if err != nil {
+		return nil, err
+	}
+
+	stats := &evictionCgroupStats{
+		CPUUsage:    cpu.Usage,
+		MemoryUsage: memory.Usage,
+		MemoryLimit: memory.MaxLimited,
+	}
+	// the optional files, e.g. memory.events is cgroup v2 only.
+	stats.CPUStat, _ = c.cgroupMgr.CpuStatRaw(path)
+	stats.MemoryStat, _ = c.cgroupMgr.MemoryStatRaw(path)
+	stats.MemoryEvents, _ = c.cgroupMgr.MemoryEventRaw(path)
+	if pids, err := c.cgroupMgr.PidsUsage(path); err == nil {
+		stats.Pids = pids.Current
+	}
+	return stats, nil
+}
+
+// readContainerSockets reads the tcp sockets in the net namespace of pid.
+func readContainerSockets(pid, limit int) (*evictionSockets, error) {
+	fs, err := procfs.NewFS(procfs.Path(strconv.Itoa(pid)))
+	if err != nil {
+		return nil, err
+	}
+
+	tcp, err := fs.NetTCP()
+	if err != nil {
+		return nil, err
+	}
+	// no ipv6 in the namespace.
+	tcp6, _ := fs.NetTCP6()
+
+	sockets := &evictionSockets{States: map[string]int{}}
+	for _, line := range append(tcp, tcp6...) {
+		state := packet.TCPStateName(uint8(line.St))
+		sockets.States[state]++
+
+		if len(sockets.Sockets) >= limit {
+			continue
+		}
+		sockets.Sockets = append(sockets.Sockets, &evictionSocket{
+			Local:   net.JoinHostPort(line.LocalAddr.String(), strconv.FormatUint(line.LocalPort, 10)),
+			Remote:  net.JoinHostPort(line.RemAddr.String(), strconv.FormatUint(line.RemPort, 10)),
+			State:   state,
+			TxQueue: line.TxQueue,
+			RxQueue: line.RxQueue,
+		})
+	}
+
+	return sockets, nil
+}
+
+func (c *evictionTracing) report(eviction *pod.ContainerEviction, window *sampleRing[*evictionSample]) {
+	container := eviction.Container
+
+	data := &EvictionTracingData{
+		PodName:   eviction.PodName,
+		Namespace: eviction.Namespace,
+		Message:   eviction.Message,
+	}
+
+	// the final state first, the cgroup is about to be removed.
+	stats, err := c.readCgroupStats(container.CgroupPath)
+	if err != nil {
+		data.CgroupError = err.Error()
+	} else {
+		data.Cgroup = stats
+	}
+
+	if sockets, err := readContainerSockets(container.InitPid, cfg.Eviction.MaxSockets); err == nil {
+		data.Sockets = sockets
+	} else {
+		log.Debugf("eviction read sockets of %s: %v", container, err)
+	}
+
+	if window != nil {
+		data.Window = window.ordered(nil)
+	}
+
+	if err := tracing.Save(&tracing.WriteRequest{
+		TracerName:    "eviction",
+		ContainerID:   container.ID,
+		TracerTime:    time.Now(),
+		TracerData:    data,
+		TracerRunType: tracing.TracerRunTypeAutotracing,
+	}); err != nil {
+		log.Warnf("failed to save tracing data: %v", err)
+	}
+}
@@ -0,0 +1,40 @@
+// Copyright 2026 The HuaTuo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package autotracing
+
+import (
+	"net"
+	"os"
+	"testing"
+)
+
+func TestReadContainerSockets(t *testing.T) {
+	ln, err := net.Listen("tcp4", "127.0.0.1:0")
+	if err != nil {
+		t.Skipf("listen: %v", err)
+	}
+	defer ln.Close()
+
+	sockets, err := readContainerSockets(os.Getpid(), 0)
+	if err != nil {
+		t.Fatalf("readContainerSockets() error = %v", err)
+	}
+	if sockets.States["LISTEN"] == 0 {
+		t.Errorf("states = %v, want a LISTEN socket", sockets.States)
+	}
+	if len(sockets.Sockets) != 0 {
+		t.Errorf("sockets = %d, want none with limit 0", len(sockets.Sockets))
+	}
+}
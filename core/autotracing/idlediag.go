@@ -0,0 +1,554 @@
+// Copyright 2026 The HuaTuo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package autotracing
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+
+	"huatuo-bamai/core/metrics/nvidia/nvml"
+	"huatuo-bamai/internal/log"
+	"huatuo-bamai/internal/procfs"
+	"huatuo-bamai/internal/procfs/sysfs"
+	"huatuo-bamai/pkg/tracing"
+	"huatuo-bamai/pkg/types"
+)
+
+func init() {
+	tracing.RegisterEventTracing("idlediag", newIdleDiag)
+}
+
+// Check results of the idle diagnostics.
+const (
+	idleDiagPass    = "pass"
+	idleDiagFail    = "fail"
+	idleDiagSkipped = "skipped"
+)
+
+// idleDiagChunk is the memory tested between two checks of the workload.
+const idleDiagChunk = 1 << 20
+
+func newIdleDiag() (*tracing.EventTracingAttr, error) {
+	if !cfg.IdleDiagnostics.Enable {
+		return nil, types.ErrNotSupported
+	}
+
+	return &tracing.EventTracingAttr{
+		TracingData: &idleDiagTracing{},
+		Interval:    10,
+		Flag:        tracing.FlagTracing,
+	}, nil
+}
+
+type idleDiagTracing struct{}
+
+// IdleDiagTracingData is the node health report, stored once every check
+// ran to the end.
+type IdleDiagTracingData struct {
+	StartTime string           `json:"start_time"`
+	EndTime   string           `json:"end_time"`
+	Paused    int              `json:"paused"`
+	Healthy   bool             `json:"healthy"`
+	Checks    []*idleDiagCheck `json:"checks"`
+}
+
+type idleDiagCheck struct {
+	Name     string `json:"name"`
+	Status   string `json:"status"`
+	Detail   string `json:"detail,omitempty"`
+	Duration int64  `json:"duration_ms"`
+}
+
+// idleDiagStep is a diagnostic, it returns the status and the detail of
+// the check, or the ctx error when the workload came back.
+type idleDiagStep struct {
+	name string
+	run  func(ctx context.Context) (string, string, error)
+}
+
+// idleDiagRun is a pass over all the steps, it resumes at the paused step
+// in the next idle period.
+type idleDiagRun struct {
+	steps  []idleDiagStep
+	next   int
+	start  time.Time
+	paused int
+	checks []*idleDiagCheck
+}
+
+func newIdleDiagRun() *idleDiagRun {
+	var steps []idleDiagStep
+	if cfg.IdleDiagnostics.EnableGPU {
+		steps = append(steps, idleDiagStep{name: "gpu", run: idleDiagGPU})
+	}
+	if cfg.IdleDiagnostics.EnableSMART {
+		steps = append(steps, idleDiagStep{name: "smart", run: idleDiagSMART})
+	}
+	if size := cfg.IdleDiagnostics.MemoryTestSize; size > 0 {
+		steps = append(steps, idleDiagStep{name: "memory", run: func(ctx context.Context) (string, string, error) {
+			return idleDiagMemory(ctx, size<<20)
+		}})
+	}
+	return &idleDiagRun{steps: steps}
+}
+
+// resume runs the remaining steps, it returns false when ctx is canceled.
+func (r *idleDiagRun) resume(ctx context.Context) bool {
+	if r.start.IsZero() {
+		r.start = time.Now()
+	}
+
+	for ; r.next < len(r.steps); r.next++ {
+		step := r.steps[r.next]
+
+		begin := time.Now()
+		status, detail, err := step.run(ctx)
+		if ctx.Err() != nil {
+			r.paused++
+			return false
+		}
+		if err != nil {
+			status, detail = idleDiagSkipped, err.Error()
+		}
+
+		r.checks = append(r.checks, &idleDiagCheck{
+			Name:     step.name,
+			Status:   status,
+			Detail:   detail,
+			Duration: time.Since(begin).Milliseconds(),
+		})
+	}
+	return true
+}
+
+func (r *idleDiagRun) report() *IdleDiagTracingData {
+	data := &IdleDiagTracingData{
+		StartTime: r.start.Format(time.RFC3339),
+		EndTime:   time.Now().Format(time.RFC3339),
+		Paused:    r.paused,
+		Healthy:   true,
+		Checks:    r.checks,
+	}
+	for _, check := range r.checks {
+		if check.Status == idleDiagFail {
+			data.Healthy = false
+		}
+	}
+	return data
+}
+
+func validateIdleDiag() error {
+	c := &cfg.IdleDiagnostics
+	if c.Interval <= 0 || c.IdleDuration < 0 || c.IntervalDiagnosis < 0 {
+		return fmt.Errorf("idle diagnostics interval must be positive, durations non-negative")
+	}
+	if c.CPUThreshold <= 0 || c.CPUThreshold > 100 {
+		return fmt.Errorf("idle diagnostics cpu threshold must be in (0, 100], got %d", c.CPUThreshold)
+	}
+	if c.MemoryTestSize < 0 {
+		return fmt.Errorf("idle diagnostics memory test size must be non-negative, got %d", c.MemoryTestSize)
+	}
+	return nil
+}
+
+func (c *idleDiagTracing) Start(ctx context.Context) error {
+	if err := validateIdleDiag(); err != nil {
+		return err
+	}
+
+	threshold := float64(cfg.IdleDiagnostics.CPUThreshold)
+	idleDuration := time.Duration(cfg.IdleDiagnostics.IdleDuration) * time.Second
+	intervalDiagnosis := time.Duration(cfg.IdleDiagnostics.IntervalDiagnosis) * time.Second
+
+	ticker := time.NewTicker(time.Duration(cfg.IdleDiagnostics.Interval) * time.Second)
+	defer ticker.Stop()
+
+	var (
+		prev       *idleDiagCPU
+		idleSince  time.Time
+		lastReport time.Time
+		run        = newIdleDiagRun()
+	)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return types.ErrExitByCancelCtx
+		case <-ticker.C:
+		}
+
+		cur, err := readIdleDiagCPU()
+		if err != nil {
+			log.Debugf("idlediag read cpu: %v", err)
+			continue
+		}
+		busy := cur.busyPercent(prev)
+		prev = cur
+
+		if busy < 0 || busy > threshold {
+			idleSince = time.Time{}
+			continue
+		}
+		if idleSince.IsZero() {
+			idleSince = time.Now()
+		}
+		if time.Since(idleSince) < idleDuration ||
+			(!lastReport.IsZero() && time.Since(lastReport) < intervalDiagnosis) {
+			continue
+		}
+
+		runCtx, cancel := context.WithCancel(ctx)
+		go watchIdleDiagWorkload(runCtx, cancel, threshold)
+		done := run.resume(runCtx)
+		cancel()
+
+		if ctx.Err() != nil {
+			return types.ErrExitByCancelCtx
+		}
+		if !done {
+			log.Infof("idlediag paused at %s, workload arrived", run.steps[run.next].name)
+			idleSince, prev = time.Time{}, nil
+			continue
+		}
+
+		c.save(run.report())
+		lastReport = time.Now()
+		run = newIdleDiagRun()
+	}
+}
+
+func (c *idleDiagTracing) save(data *IdleDiagTracingData) {
+	if err := tracing.Save(&tracing.WriteRequest{
+		TracerName:    "idlediag",
+		TracerTime:    time.Now(),
+		TracerData:    data,
+		TracerRunType: tracing.TracerRunTypeAutotracing,
+	}); err != nil {
+		log.Warnf("failed to save tracing data: %v", err)
+	}
+}
+
+// watchIdleDiagWorkload cancels the diagnostics as soon as the node is
+// busy again.
+func watchIdleDiagWorkload(ctx context.Context, cancel context.CancelFunc, threshold float64) {
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	prev, _ := readIdleDiagCPU()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		cur, err := readIdleDiagCPU()
+		if err != nil {
+			continue
+		}
+		if cur.busyPercent(prev) > threshold {
+			cancel()
+			return
+		}
+		prev = cur
+	}
+}
+
+// idleDiagCPU are the busy and total ticks of the node, without the ticks
+// of the agent, so the diagnostics do not pause themselves.
+type idleDiagCPU struct {
+	busy  uint64
+	total uint64
+}
+
+// busyPercent returns the busy share since prev, -1 without prev.
+func (c *idleDiagCPU) busyPercent(prev *idleDiagCPU) float64 {
+	if prev == nil || c.total <= prev.total {
+		return -1
+	}
+	busy := float64(c.busy) - float64(prev.busy)
+	return max(busy, 0) * 100 / float64(c.total-prev.total)
+}
+
+func readIdleDiagCPU() (*idleDiagCPU, error) {
+	f, err := os.Open(procfs.Path("stat"))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Scan()
+	fields := strings.Fields(scanner.Text())
+	if len(fields) < 5 || fields[0] != "cpu" {
+		return nil, fmt.Errorf("unexpected /proc/stat line %q", scanner.Text())
+	}
+
+	cpu := &idleDiagCPU{}
+	for i, field := range fields[1:] {
+		val, err := strconv.ParseUint(field, 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		cpu.total += val
+		// idle and iowait
+		if i != 3 && i != 4 {
+			cpu.busy += val
+		}
+	}
+
+	self, err := selfCPUTicks()
+	if err != nil {
+		return nil, err
+	}
+	cpu.busy -= min(self, cpu.busy)
+	return cpu, nil
+}
+
+// selfCPUTicks sums utime, stime, cutime and cstime of the agent, in the
+// USER_HZ ticks of /proc/stat.
+func selfCPUTicks() (uint64, error) {
+	raw, err := os.ReadFile("/proc/self/stat")
+	if err != nil {
+		return 0, err
+	}
+
+	// the fields after the comm, which may contain spaces.
+	end := strings.LastIndexByte(string(raw), ')')
+	fields := strings.Fields(string(raw)[end+1:])
+	if len(fields) < 15 {
+		return 0, fmt.Errorf("unexpected /proc/self/stat")
+	}
+
+	var ticks uint64
+	for _, field := range fields[11:15] {
+		val, err := strconv.ParseInt(field, 10, 64)
+		if err != nil {
+			return 0, err
+		}
+		ticks += uint64(max(val, 0))
+	}
+	return ticks, nil
+}
+
+// idleDiagGPU queries every NVIDIA GPU, uncorrected ECC errors fail it.
+func idleDiagGPU(ctx context.Context) (string, string, error) {
+	if err := nvml.Init(); err != nil {
+		return idleDiagSkipped, "no nvml library", nil
+	}
+	defer func() { _ = nvml.Shutdown() }()
+
+	devices, err := nvml.ListDevices()
+	if err != nil {
+		return "", "", err
+	}
+
+	status := idleDiagPass
+	details := make([]string, 0, len(devices))
+	for i, dev := range devices {
+		if err := ctx.Err(); err != nil {
+			return "", "", err
+		}
+
+		info, err := nvml.GetInfo(ctx, dev)
+		if err != nil {
+			return "", "", err
+		}
+		detail := fmt.Sprintf("gpu %d %s %s", i, info.Name, info.BDF)
+
+		if temperature, err := nvml.GetTemperature(ctx, dev); err == nil {
+			detail += fmt.Sprintf(" temperature %dC", temperature)
+		}
+		if ue, err := nvml.GetEccErrors(ctx, dev, nvml.MemoryErrorTypeUncorrected); err == nil {
+			detail += fmt.Sprintf(" uncorrected ecc %d", ue)
+			if ue > 0 {
+				status = idleDiagFail
+			}
+		}
+		details = append(details, detail)
+	}
+
+	if len(devices) == 0 {
+		return idleDiagSkipped, "no gpu", nil
+	}
+	return status, strings.Join(details, "; "), nil
+}
+
+// idleDiagDisks returns the whole disks of /sys/block.
+func idleDiagDisks() ([]string, error) {
+	entries, err := os.ReadDir(sysfs.Path("block"))
+	if err != nil {
+		return nil, err
+	}
+
+	var disks []string
+	for _, entry := range entries {
+		name := entry.Name()
+		switch {
+		case strings.HasPrefix(name, "loop"), strings.HasPrefix(name, "ram"),
+			strings.HasPrefix(name, "dm-"), strings.HasPrefix(name, "zram"),
+			strings.HasPrefix(name, "md"), strings.HasPrefix(name, "nbd"),
+			strings.HasPrefix(name, "sr"):
+			continue
+		}
+		disks = append(disks, name)
+	}
+	return disks, nil
+}
+
+type smartctlHealth struct {
+	SmartStatus *struct {
+		Passed bool `json:"passed"`
+	} `json:"smart_status"`
+}
+
+// idleDiagSMART runs the SMART health self-assessment of every disk.
+func idleDiagSMART(ctx context.Context) (string, string, error) {
+	if _, err := exec.LookPath("smartctl"); err != nil {
+		return idleDiagSkipped, "no smartctl", nil
+	}
+
+	disks, err := idleDiagDisks()
+	if err != nil {
+		return "", "", err
+	}
+
+	status := idleDiagPass
+	var failed, unknown []string
+	for _, disk := range disks {
+		// the exit status is a bitmask of the findings, the json tells.
+		out, _ := exec.CommandContext(ctx, "smartctl", "--health", "--json", filepath.Join("/dev", disk)).Output()
+		if err := ctx.Err(); err != nil {
+			return "", "", err
+		}
+
+		var health smartctlHealth
+		if err := json.Unmarshal(out, &health); err != nil || health.SmartStatus == nil {
+			unknown = append(unknown, disk)
+			continue
+		}
+		if !health.SmartStatus.Passed {
+			status = idleDiagFail
+			failed = append(failed, disk)
+		}
+	}
+
+	detail := fmt.Sprintf("%d disks", len(disks))
+	if len(failed) > 0 {
+		detail += ", failed " + strings.Join(failed, ",")
+	}
+	if len(unknown) > 0 {
+		detail += ", unknown " + strings.Join(unknown, ",")
+	}
+	return status, detail, nil
+}
+
+// idleDiagMemory writes and verifies patterns on free pages, the size is
+// bounded by a quarter of the available memory.
+func idleDiagMemory(ctx context.Context, size int64) (string, string, error) {
+	available, err := memAvailable()
+	if err != nil {
+		return "", "", err
+	}
+	size = min(size, available/4) &^ (idleDiagChunk - 1)
+	if size <= 0 {
+		return idleDiagSkipped, "not enough available memory", nil
+	}
+
+	mem, err := unix.Mmap(-1, 0, int(size), unix.PROT_READ|unix.PROT_WRITE, unix.MAP_PRIVATE|unix.MAP_ANONYMOUS)
+	if err != nil {
+		return "", "", fmt.Errorf("mmap %d bytes: %w", size, err)
+	}
+	defer func() { _ = unix.Munmap(mem) }()
+
+	bad, err := memoryPatternTest(ctx, mem)
+	if err != nil {
+		return "", "", err
+	}
+
+	detail := fmt.Sprintf("%d MiB tested", size>>20)
+	if bad > 0 {
+		return idleDiagFail, fmt.Sprintf("%s, %d bad words", detail, bad), nil
+	}
+	return idleDiagPass, detail, nil
+}
+
+// memoryPatternTest returns the words which did not read back the pattern
+// written, the all zero, all one, checkerboard and own address patterns.
+func memoryPatternTest(ctx context.Context, mem []byte) (int, error) {
+	words := unsafe.Slice((*uint64)(unsafe.Pointer(unsafe.SliceData(mem))), len(mem)/8)
+	chunk := idleDiagChunk / 8
+
+	patterns := []func(i int) uint64{
+		func(int) uint64 { return 0 },
+		func(int) uint64 { return ^uint64(0) },
+		func(int) uint64 { return 0x5555555555555555 },
+		func(int) uint64 { return 0xaaaaaaaaaaaaaaaa },
+		func(i int) uint64 { return uint64(uintptr(unsafe.Pointer(&words[i]))) },
+	}
+
+	bad := 0
+	for _, pattern := range patterns {
+		for start := 0; start < len(words); start += chunk {
+			if err := ctx.Err(); err != nil {
+				return 0, err
+			}
+			end := min(start+chunk, len(words))
+
+			for i := start; i < end; i++ {
+				words[i] = pattern(i)
+			}
+			for i := start; i < end; i++ {
+				if words[i] != pattern(i) {
+					bad++
+				}
+			}
+		}
+	}
+	return bad, nil
+}
+
+func memAvailable() (int64, error) {
+	f, err := os.Open(procfs.Path("meminfo"))
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) >= 2 && fields[0] == "MemAvailable:" {
+			kb, err := strconv.ParseInt(fields[1], 10, 64)
+			return kb * 1024, err
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return 0, err
+	}
+	return 0, errors.New("no MemAvailable in meminfo")
+}
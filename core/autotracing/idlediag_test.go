@@ -0,0 +1,107 @@
+// Copyright 2026 The HuaTuo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package autotracing
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"huatuo-bamai/internal/procfs"
+)
+
+func TestIdleDiagBusyPercent(t *testing.T) {
+	prev := &idleDiagCPU{busy: 100, total: 1000}
+
+	if got := (&idleDiagCPU{busy: 150, total: 1500}).busyPercent(prev); got != 10 {
+		t.Errorf("busyPercent() = %v, want 10", got)
+	}
+	if got := (&idleDiagCPU{busy: 150, total: 1500}).busyPercent(nil); got != -1 {
+		t.Errorf("busyPercent(nil) = %v, want -1", got)
+	}
+	// the agent ticks may exceed the node ones between two samples.
+	if got := (&idleDiagCPU{busy: 90, total: 1500}).busyPercent(prev); got != 0 {
+		t.Errorf("busyPercent() = %v, want 0", got)
+	}
+}
+
+func TestIdleDiagDisks(t *testing.T) {
+	root := t.TempDir()
+	procfs.RootPrefix(root)
+	t.Cleanup(func() { procfs.RootPrefix("/") })
+
+	for _, name := range []string{"sda", "nvme0n1", "loop0", "dm-0", "ram1", "zram0", "md127", "nbd0", "sr0"} {
+		if err := os.MkdirAll(filepath.Join(root, "sys/block", name), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	disks, err := idleDiagDisks()
+	if err != nil {
+		t.Fatalf("idleDiagDisks() error = %v", err)
+	}
+	if want := []string{"nvme0n1", "sda"}; !reflect.DeepEqual(disks, want) {
+		t.Errorf("idleDiagDisks() = %v, want %v", disks, want)
+	}
+}
+
+func TestMemoryPatternTest(t *testing.T) {
+	mem := make([]byte, 3*idleDiagChunk)
+	bad, err := memoryPatternTest(context.Background(), mem)
+	if err != nil || bad != 0 {
+		t.Errorf("memoryPatternTest() = %d, %v, want no bad words", bad, err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := memoryPatternTest(ctx, mem); err == nil {
+		t.Errorf("memoryPatternTest() with canceled ctx error = nil")
+	}
+}
+
+func TestIdleDiagRunResume(t *testing.T) {
+	var calls []string
+	step := func(name, status string) idleDiagStep {
+		return idleDiagStep{name: name, run: func(ctx context.Context) (string, string, error) {
+			calls = append(calls, name)
+			return status, "", ctx.Err()
+		}}
+	}
+	run := &idleDiagRun{steps: []idleDiagStep{step("gpu", idleDiagPass), step("smart", idleDiagFail)}}
+
+	// the workload arrived during the first step.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if run.resume(ctx) {
+		t.Fatalf("resume() with canceled ctx = true")
+	}
+	if run.next != 0 || len(run.checks) != 0 || run.paused != 1 {
+		t.Fatalf("paused run = %+v", run)
+	}
+
+	if !run.resume(context.Background()) {
+		t.Fatalf("resume() = false")
+	}
+	if want := []string{"gpu", "gpu", "smart"}; !reflect.DeepEqual(calls, want) {
+		t.Errorf("calls = %v, want %v", calls, want)
+	}
+
+	report := run.report()
+	if report.Healthy || len(report.Checks) != 2 || report.Paused != 1 {
+		t.Errorf("report = %+v", report)
+	}
+}
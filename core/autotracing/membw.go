@@ -0,0 +1,381 @@
+// Copyright 2026 The HuaTuo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package autotracing
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"huatuo-bamai/core/metrics/nvidia/nvml"
+	"huatuo-bamai/internal/log"
+	"huatuo-bamai/internal/procfs/sysfs"
+	"huatuo-bamai/pkg/tracing"
+	"huatuo-bamai/pkg/types"
+)
+
+func init() {
+	tracing.RegisterEventTracing("membw", newMemBandwidth)
+}
+
+// membwRootGroup is the default resctrl group, of the tasks in no group.
+const membwRootGroup = "/"
+
+// membwMaxGroups is the resctrl groups reported, the top consumers.
+const membwMaxGroups = 10
+
+func newMemBandwidth() (*tracing.EventTracingAttr, error) {
+	if !cfg.MemoryBandwidth.Enable {
+		return nil, types.ErrNotSupported
+	}
+
+	// MBM needs resctrl mounted on a CPU with memory bandwidth monitoring.
+	if _, err := os.Stat(sysfs.Path("fs/resctrl/mon_data")); err != nil {
+		log.Infof("membw: resctrl memory bandwidth monitoring not available: %v", err)
+		return nil, types.ErrNotSupported
+	}
+
+	return &tracing.EventTracingAttr{
+		TracingData: &memBandwidthTracing{},
+		Interval:    10,
+		Flag:        tracing.FlagTracing,
+	}, nil
+}
+
+type memBandwidthTracing struct{}
+
+// MemBandwidthTracingData is stored when the memory bandwidth of a domain
+// saturates while GPUs starve and copy from the host.
+type MemBandwidthTracingData struct {
+	// Bottleneck names the saturated domains and the starving GPUs.
+	Bottleneck string           `json:"bottleneck"`
+	Domains    []*membwDomain   `json:"domains"`
+	Groups     []*membwGroup    `json:"groups"`
+	GPUs       []*membwGPU      `json:"gpus"`
+	Threshold  *membwThresholds `json:"threshold"`
+}
+
+// membwDomain is the memory bandwidth of an L3 cache domain, usually a
+// socket or a die of it.
+type membwDomain struct {
+	Domain    string  `json:"domain"`
+	Bandwidth uint64  `json:"bandwidth_bytes"`
+	Percent   float64 `json:"percent"`
+	Saturated bool    `json:"saturated"`
+}
+
+// membwGroup is the memory bandwidth of a resctrl group in a domain.
+type membwGroup struct {
+	Group     string `json:"group"`
+	Domain    string `json:"domain"`
+	Bandwidth uint64 `json:"bandwidth_bytes"`
+}
+
+type membwGPU struct {
+	GPU         int    `json:"gpu"`
+	BDF         string `json:"bdf"`
+	NUMANode    string `json:"numa_node"`
+	Utilization uint32 `json:"utilization"`
+	PcieRx      uint64 `json:"pcie_rx_bytes"`
+	PcieTx      uint64 `json:"pcie_tx_bytes"`
+}
+
+type membwThresholds struct {
+	PeakBandwidth     uint64 `json:"peak_bandwidth_bytes"`
+	SaturationPercent int64  `json:"saturation_percent"`
+	GPUUtilization    int64  `json:"gpu_utilization"`
+	PcieThroughput    uint64 `json:"pcie_throughput_bytes"`
+}
+
+// membwCounters are the mbm_total_bytes by group and domain.
+type membwCounters map[string]map[string]uint64
+
+func validateMemBandwidth() error {
+	c := &cfg.MemoryBandwidth
+	if c.Interval <= 0 || c.PeakBandwidth <= 0 {
+		return fmt.Errorf("membw interval and peak bandwidth must be positive, got %d and %d",
+			c.Interval, c.PeakBandwidth)
+	}
+	if c.SaturationPercent <= 0 || c.SaturationPercent > 100 {
+		return fmt.Errorf("membw saturation percent must be in (0, 100], got %d", c.SaturationPercent)
+	}
+	if c.Samples <= 0 {
+		return fmt.Errorf("membw samples must be positive, got %d", c.Samples)
+	}
+	return nil
+}
+
+func (c *memBandwidthTracing) Start(ctx context.Context) error {
+	if err := validateMemBandwidth(); err != nil {
+		return err
+	}
+
+	if err := nvml.Init(); err != nil {
+		return fmt.Errorf("init nvml: %w", err)
+	}
+	defer func() { _ = nvml.Shutdown() }()
+
+	devices, err := nvml.ListDevices()
+	if err != nil {
+		return fmt.Errorf("list gpus: %w", err)
+	}
+
+	threshold := &membwThresholds{
+		PeakBandwidth:     uint64(cfg.MemoryBandwidth.PeakBandwidth) << 20,
+		SaturationPercent: cfg.MemoryBandwidth.SaturationPercent,
+		GPUUtilization:    cfg.MemoryBandwidth.GPUUtilThreshold,
+		PcieThroughput:    uint64(cfg.MemoryBandwidth.PcieThroughputThreshold) << 20,
+	}
+
+	root := sysfs.Path("fs/resctrl")
+	prev, err := readResctrlMBM(root)
+	if err != nil {
+		return err
+	}
+	prevTime := time.Now()
+
+	var hits int
+	var lastTracing time.Time
+
+	ticker := time.NewTicker(time.Duration(cfg.MemoryBandwidth.Interval) * time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return types.ErrExitByCancelCtx
+		case <-ticker.C:
+		}
+
+		cur, err := readResctrlMBM(root)
+		if err != nil {
+			return err
+		}
+		now := time.Now()
+		domains, groups := membwRates(prev, cur, now.Sub(prevTime))
+		prev, prevTime = cur, now
+
+		saturated := membwSaturatedDomains(domains, threshold)
+		var starved []*membwGPU
+		if len(saturated) > 0 {
+			starved = membwStarvedGPUs(ctx, devices, threshold)
+		}
+
+		// a sustained saturation, not a burst of a copy.
+		if len(saturated) == 0 || len(starved) == 0 {
+			hits = 0
+			continue
+		}
+		hits++
+		if hits < int(cfg.MemoryBandwidth.Samples) ||
+			time.Since(lastTracing) < time.Duration(cfg.MemoryBandwidth.IntervalTracing)*time.Second {
+			continue
+		}
+		hits = 0
+		lastTracing = time.Now()
+
+		data := &MemBandwidthTracingData{
+			Bottleneck: membwBottleneck(saturated, starved),
+			Domains:    domains,
+			Groups:     membwTopGroups(groups, membwMaxGroups),
+			GPUs:       starved,
+			Threshold:  threshold,
+		}
+		log.Infof("membw: %s", data.Bottleneck)
+
+		if err := tracing.Save(&tracing.WriteRequest{
+			TracerName:    "membw",
+			TracerTime:    now,
+			TracerData:    data,
+			TracerRunType: tracing.TracerRunTypeAutotracing,
+		}); err != nil {
+			log.Warnf("failed to save tracing data: %v", err)
+		}
+	}
+}
+
+// readResctrlMBM reads the MBM counters of the default group and of every
+// control group. The monitor groups are left out, the control group
+// counters include them.
+func readResctrlMBM(root string) (membwCounters, error) {
+	groups := map[string]string{membwRootGroup: root}
+
+	entries, err := os.ReadDir(root)
+	if err != nil {
+		return nil, err
+	}
+	for _, entry := range entries {
+		if !entry.IsDir() || entry.Name() == "info" || entry.Name() == "mon_groups" {
+			continue
+		}
+		groups[entry.Name()] = filepath.Join(root, entry.Name())
+	}
+
+	counters := make(membwCounters)
+	for group, dir := range groups {
+		domains, err := os.ReadDir(filepath.Join(dir, "mon_data"))
+		if err != nil {
+			// a control group without monitoring.
+			continue
+		}
+
+		for _, domain := range domains {
+			id, ok := strings.CutPrefix(domain.Name(), "mon_L3_")
+			if !ok {
+				continue
+			}
+
+			raw, err := os.ReadFile(filepath.Join(dir, "mon_data", domain.Name(), "mbm_total_bytes"))
+			if err != nil {
+				continue
+			}
+			// "Unavailable" while the RMID is not assigned.
+			value, err := strconv.ParseUint(strings.TrimSpace(string(raw)), 10, 64)
+			if err != nil {
+				continue
+			}
+
+			if counters[group] == nil {
+				counters[group] = make(map[string]uint64)
+			}
+			counters[group][id] = value
+		}
+	}
+
+	if len(counters) == 0 {
+		return nil, fmt.Errorf("no mbm_total_bytes counter in %s", root)
+	}
+	return counters, nil
+}
+
+// membwRates returns the bandwidth of every domain, the sum of its groups,
+// and of every group in a domain.
+func membwRates(prev, cur membwCounters, elapsed time.Duration) ([]*membwDomain, []*membwGroup) {
+	if elapsed <= 0 {
+		return nil, nil
+	}
+
+	byDomain := make(map[string]uint64)
+	var groups []*membwGroup
+	for group, domains := range cur {
+		for domain, value := range domains {
+			before, ok := prev[group][domain]
+			// a new group or a counter reset.
+			if !ok || value < before {
+				continue
+			}
+
+			bandwidth := uint64(float64(value-before) / elapsed.Seconds())
+			byDomain[domain] += bandwidth
+			groups = append(groups, &membwGroup{Group: group, Domain: domain, Bandwidth: bandwidth})
+		}
+	}
+
+	domains := make([]*membwDomain, 0, len(byDomain))
+	for domain, bandwidth := range byDomain {
+		domains = append(domains, &membwDomain{Domain: domain, Bandwidth: bandwidth})
+	}
+	sort.Slice(domains, func(i, j int) bool { return domains[i].Domain < domains[j].Domain })
+	return domains, groups
+}
+
+// membwSaturatedDomains marks and returns the domains past the saturation
+// percent of the peak bandwidth.
+func membwSaturatedDomains(domains []*membwDomain, threshold *membwThresholds) []*membwDomain {
+	var saturated []*membwDomain
+	for _, domain := range domains {
+		domain.Percent = float64(domain.Bandwidth) * 100 / float64(threshold.PeakBandwidth)
+		domain.Saturated = domain.Percent >= float64(threshold.SaturationPercent)
+		if domain.Saturated {
+			saturated = append(saturated, domain)
+		}
+	}
+	return saturated
+}
+
+// membwStarvedGPUs returns the GPUs which are underutilized while copying
+// from or to the host.
+func membwStarvedGPUs(ctx context.Context, devices []nvml.Device, threshold *membwThresholds) []*membwGPU {
+	var starved []*membwGPU
+	for i, dev := range devices {
+		utilization, err := nvml.GetUtilization(ctx, dev)
+		if err != nil {
+			log.Debugf("membw gpu %d utilization: %v", i, err)
+			continue
+		}
+		pcie, err := nvml.GetPcieThroughput(ctx, dev)
+		if err != nil {
+			log.Debugf("membw gpu %d pcie throughput: %v", i, err)
+			continue
+		}
+
+		gpu := &membwGPU{GPU: i, Utilization: utilization.Gpu, PcieRx: pcie.Receive, PcieTx: pcie.Transmit}
+		if !membwGPUStarved(gpu, threshold) {
+			continue
+		}
+
+		if info, err := nvml.GetInfo(ctx, dev); err == nil {
+			gpu.BDF = info.BDF
+			gpu.NUMANode = pciNUMANode(info.BDF)
+		}
+		starved = append(starved, gpu)
+	}
+	return starved
+}
+
+func membwGPUStarved(gpu *membwGPU, threshold *membwThresholds) bool {
+	return int64(gpu.Utilization) < threshold.GPUUtilization &&
+		gpu.PcieRx+gpu.PcieTx >= threshold.PcieThroughput
+}
+
+// pciNUMANode returns the NUMA node of a PCI device, the libraries report
+// the BDF with an 8 digit domain.
+func pciNUMANode(bdf string) string {
+	bdf = strings.ToLower(bdf)
+	if domain, rest, ok := strings.Cut(bdf, ":"); ok && len(domain) > 4 {
+		bdf = domain[len(domain)-4:] + ":" + rest
+	}
+
+	raw, err := os.ReadFile(sysfs.Path("bus/pci/devices", bdf, "numa_node"))
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(raw))
+}
+
+func membwTopGroups(groups []*membwGroup, limit int) []*membwGroup {
+	sort.Slice(groups, func(i, j int) bool { return groups[i].Bandwidth > groups[j].Bandwidth })
+	if len(groups) > limit {
+		groups = groups[:limit]
+	}
+	return groups
+}
+
+func membwBottleneck(saturated []*membwDomain, starved []*membwGPU) string {
+	var domains, gpus []string
+	for _, domain := range saturated {
+		domains = append(domains, fmt.Sprintf("L3 domain %s at %.0f%%", domain.Domain, domain.Percent))
+	}
+	for _, gpu := range starved {
+		gpus = append(gpus, fmt.Sprintf("gpu %d (numa %s) at %d%%", gpu.GPU, gpu.NUMANode, gpu.Utilization))
+	}
+	return fmt.Sprintf("host memory bandwidth saturated: %s, starving %s",
+		strings.Join(domains, ", "), strings.Join(gpus, ", "))
+}
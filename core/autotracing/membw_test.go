@@ -0,0 +1,112 @@
+// Copyright 2026 The HuaTuo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package autotracing
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestReadResctrlMBM(t *testing.T) {
+	root := t.TempDir()
+
+	files := map[string]string{
+		"mon_data/mon_L3_00/mbm_total_bytes":                      "1000\n",
+		"mon_data/mon_L3_01/mbm_total_bytes":                      "2000\n",
+		"train/mon_data/mon_L3_00/mbm_total_bytes":                "300\n",
+		"train/mon_data/mon_L3_01/mbm_total_bytes":                "Unavailable\n",
+		"train/mon_groups/job/mon_data/mon_L3_00/mbm_total_bytes": "100\n",
+		"info/L3_MON/num_rmids":                                   "256\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// a control group without monitoring.
+	if err := os.MkdirAll(filepath.Join(root, "nomon"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := readResctrlMBM(root)
+	if err != nil {
+		t.Fatalf("readResctrlMBM() error = %v", err)
+	}
+	want := membwCounters{
+		membwRootGroup: {"00": 1000, "01": 2000},
+		"train":        {"00": 300},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("readResctrlMBM() = %v, want %v", got, want)
+	}
+
+	if _, err := readResctrlMBM(filepath.Join(root, "nomon")); err == nil {
+		t.Error("readResctrlMBM() without counters, want error")
+	}
+}
+
+func TestMembwSaturation(t *testing.T) {
+	prev := membwCounters{
+		membwRootGroup: {"00": 1000, "01": 5000},
+		"train":        {"00": 0},
+	}
+	cur := membwCounters{
+		membwRootGroup: {"00": 3000, "01": 1000},
+		"train":        {"00": 16000},
+		"new":          {"00": 100},
+	}
+
+	domains, groups := membwRates(prev, cur, 2*time.Second)
+	// the counter reset of domain 01 and the new group are left out.
+	if len(domains) != 1 || domains[0].Domain != "00" || domains[0].Bandwidth != 9000 {
+		t.Fatalf("membwRates() domains = %+v", domains)
+	}
+	top := membwTopGroups(groups, 1)
+	if len(top) != 1 || top[0].Group != "train" || top[0].Bandwidth != 8000 {
+		t.Errorf("membwTopGroups() = %+v", top)
+	}
+
+	threshold := &membwThresholds{
+		PeakBandwidth:     10000,
+		SaturationPercent: 80,
+		GPUUtilization:    30,
+		PcieThroughput:    1000,
+	}
+	saturated := membwSaturatedDomains(domains, threshold)
+	if len(saturated) != 1 || saturated[0].Percent != 90 {
+		t.Errorf("membwSaturatedDomains() = %+v", saturated)
+	}
+
+	tests := []struct {
+		gpu  *membwGPU
+		want bool
+	}{
+		{&membwGPU{Utilization: 10, PcieRx: 800, PcieTx: 300}, true},
+		{&membwGPU{Utilization: 10, PcieRx: 100}, false},
+		{&membwGPU{Utilization: 90, PcieRx: 5000}, false},
+	}
+	for _, tt := range tests {
+		if got := membwGPUStarved(tt.gpu, threshold); got != tt.want {
+			t.Errorf("membwGPUStarved(%+v) = %v, want %v", tt.gpu, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,300 @@
+// Copyright 2026 The HuaTuo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package autotracing
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"huatuo-bamai/internal/cgroups"
+	"huatuo-bamai/internal/log"
+	"huatuo-bamai/internal/pod"
+	"huatuo-bamai/internal/procfs"
+	"huatuo-bamai/pkg/tracing"
+	"huatuo-bamai/pkg/types"
+)
+
+func init() {
+	tracing.RegisterEventTracing("memleak", newMemLeak)
+}
+
+func newMemLeak() (*tracing.EventTracingAttr, error) {
+	cgroup, err := cgroups.NewManager()
+	if err != nil {
+		return nil, err
+	}
+
+	return &tracing.EventTracingAttr{
+		TracingData: &memLeakTracing{cgroupMgr: cgroup},
+		Interval:    10,
+		Flag:        tracing.FlagTracing,
+	}, nil
+}
+
+type memLeakTracing struct {
+	cgroupMgr cgroups.Cgroup
+}
+
+// MemLeakTracingData is stored when a container's RSS keeps growing over the
+// whole observation window.
+type MemLeakTracingData struct {
+	GrowthBytesPerHour float64           `json:"growth_bytes_per_hour"`
+	WindowSeconds      int               `json:"window_seconds"`
+	StartRSS           uint64            `json:"start_rss"`
+	CurrentRSS         uint64            `json:"current_rss"`
+	Processes          []*memLeakProcess `json:"processes"`
+}
+
+type memLeakProcess struct {
+	PID   int32  `json:"pid"`
+	Comm  string `json:"comm"`
+	RSS   uint64 `json:"rss"`
+	Swap  uint64 `json:"swap"`
+	Heap  uint64 `json:"heap"`
+	Stack uint64 `json:"stack"`
+	Anon  uint64 `json:"anon"` // anonymous mappings other than heap and stack
+	File  uint64 `json:"file"`
+	Shmem uint64 `json:"shmem"`
+}
+
+func validateMemLeak(c *MemLeakConfig) error {
+	if c.Interval <= 0 {
+		return fmt.Errorf("memory leak interval must be positive, got %d", c.Interval)
+	}
+	if c.WindowLength < 2 {
+		return fmt.Errorf("memory leak window length must be at least 2, got %d", c.WindowLength)
+	}
+	if c.MonotonicRatio <= 0 || c.MonotonicRatio > 100 {
+		return fmt.Errorf("memory leak monotonic ratio must be in (0, 100], got %d", c.MonotonicRatio)
+	}
+	if c.DumpProcessMaxNum <= 0 {
+		return fmt.Errorf("memory leak dump process max num must be positive, got %d", c.DumpProcessMaxNum)
+	}
+	return nil
+}
+
+// rssGrowth fits a least squares line through samples taken interval seconds
+// apart and returns its slope in bytes per hour, together with the share of
+// steps that did not shrink.
+func rssGrowth(samples []uint64, interval int) (slope, monotonic float64) {
+	n := float64(len(samples))
+	if n < 2 {
+		return 0, 0
+	}
+
+	var sumX, sumY, sumXY, sumXX float64
+	nonDecreasing := 0
+	for i, s := range samples {
+		x, y := float64(i), float64(s)
+		sumX += x
+		sumY += y
+		sumXY += x * y
+		sumXX += x * x
+		if i > 0 && s >= samples[i-1] {
+			nonDecreasing++
+		}
+	}
+
+	perSample := (n*sumXY - sumX*sumY) / (n*sumXX - sumX*sumX)
+	return perSample * 3600 / float64(interval), float64(nonDecreasing) / (n - 1)
+}
+
+// containerRSS prefers the hierarchical counter so child cgroups are included.
+func containerRSS(raw map[string]uint64) uint64 {
+	for _, key := range []string{"total_rss", "anon", "rss"} {
+		if v, ok := raw[key]; ok {
+			return v
+		}
+	}
+	return 0
+}
+
+func (c *memLeakTracing) Start(ctx context.Context) error {
+	if err := validateMemLeak(&cfg.MemoryLeak); err != nil {
+		return err
+	}
+
+	interval := cfg.MemoryLeak.Interval
+	threshold := float64(cfg.MemoryLeak.GrowthThreshold) * 1024 * 1024
+	monotonicRatio := float64(cfg.MemoryLeak.MonotonicRatio) / 100
+	intervalTracing := time.Duration(cfg.MemoryLeak.IntervalTracing) * time.Second
+
+	histories := newContainerSamples[uint64](cfg.MemoryLeak.WindowLength)
+	// samples is reused by every container, the reports do not keep it.
+	samples := make([]uint64, 0, cfg.MemoryLeak.WindowLength)
+
+	ticker := time.NewTicker(time.Duration(interval) * time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return types.ErrExitByCancelCtx
+		case <-ticker.C:
+		}
+
+		containers, err := pod.NormalContainers()
+		if err != nil {
+			log.Debugf("memleak list containers: %v", err)
+			continue
+		}
+
+		histories.track(containers)
+		for id, h := range histories.rings {
+			raw, err := c.cgroupMgr.MemoryStatRaw(h.path)
+			if err != nil {
+				log.Debugf("memleak read memory.stat [%s]: %v", h.path, err)
+				continue
+			}
+			h.add(containerRSS(raw))
+
+			if !h.full || time.Since(h.lastReport) < intervalTracing {
+				continue
+			}
+
+			samples = h.ordered(samples[:0])
+			slope, monotonic := rssGrowth(samples, interval)
+			if slope < threshold || monotonic < monotonicRatio {
+				continue
+			}
+
+			h.lastReport = time.Now()
+			c.report(id, h.path, slope, samples)
+		}
+	}
+}
+
+func (c *memLeakTracing) report(id, path string, slope float64, samples []uint64) {
+	procs, err := c.topContainerProcesses(path, cfg.MemoryLeak.DumpProcessMaxNum)
+	if err != nil {
+		log.Debugf("memleak dump processes [%s]: %v", path, err)
+	}
+
+	if err := tracing.Save(&tracing.WriteRequest{
+		TracerName:  "memleak",
+		ContainerID: id,
+		TracerTime:  time.Now(),
+		TracerData: &MemLeakTracingData{
+			GrowthBytesPerHour: slope,
+			WindowSeconds:      len(samples) * cfg.MemoryLeak.Interval,
+			StartRSS:           samples[0],
+			CurrentRSS:         samples[len(samples)-1],
+			Processes:          procs,
+		},
+		TracerRunType: tracing.TracerRunTypeAutotracing,
+	}); err != nil {
+		log.Warnf("failed to save tracing data: %v", err)
+	}
+}
+
+func (c *memLeakTracing) topContainerProcesses(path string, topN int) ([]*memLeakProcess, error) {
+	pids, err := c.cgroupMgr.Procs(path)
+	if err != nil {
+		return nil, err
+	}
+
+	procs := make([]*memLeakProcess, 0, len(pids))
+	for _, pid := range pids {
+		p, err := readProcessSmaps(pid)
+		if err != nil {
+			continue
+		}
+		procs = append(procs, p)
+	}
+
+	sort.Slice(procs, func(i, j int) bool {
+		return procs[i].RSS > procs[j].RSS
+	})
+
+	if len(procs) > topN {
+		procs = procs[:topN]
+	}
+	return procs, nil
+}
+
+// readProcessSmaps sums Rss per mapping kind from /proc/pid/smaps, which is
+// what tells a malloc leak (heap/anon) from a growing page cache mapping.
+func readProcessSmaps(pid int32) (*memLeakProcess, error) {
+	comm, err := os.ReadFile(procfs.Path(strconv.Itoa(int(pid)), "comm"))
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := os.Open(procfs.Path(strconv.Itoa(int(pid)), "smaps"))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	p := &memLeakProcess{PID: pid, Comm: strings.TrimSpace(string(comm))}
+
+	kind := &p.Anon
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+
+		// mapping header: "addr-addr perms offset dev inode [path]"
+		if strings.Contains(fields[0], "-") && !strings.HasSuffix(fields[0], ":") {
+			kind = smapsMappingKind(p, fields)
+			continue
+		}
+
+		if len(fields) < 2 {
+			continue
+		}
+		kb, err := strconv.ParseUint(fields[1], 10, 64)
+		if err != nil {
+			continue
+		}
+
+		switch fields[0] {
+		case "Rss:":
+			p.RSS += kb * 1024
+			*kind += kb * 1024
+		case "Swap:":
+			p.Swap += kb * 1024
+		}
+	}
+
+	return p, scanner.Err()
+}
+
+func smapsMappingKind(p *memLeakProcess, header []string) *uint64 {
+	if len(header) < 6 {
+		return &p.Anon
+	}
+
+	switch name := header[5]; {
+	case name == "[heap]":
+		return &p.Heap
+	case strings.HasPrefix(name, "[stack"):
+		return &p.Stack
+	case strings.HasPrefix(name, "/dev/shm/"), strings.HasPrefix(name, "/SYSV"), name == "/dev/zero":
+		return &p.Shmem
+	case strings.HasPrefix(name, "/"):
+		return &p.File
+	default:
+		return &p.Anon
+	}
+}
@@ -0,0 +1,111 @@
+// Copyright 2026 The HuaTuo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package autotracing
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"huatuo-bamai/internal/procfs"
+)
+
+func TestRSSGrowth(t *testing.T) {
+	const mib = 1024 * 1024
+
+	cases := []struct {
+		name          string
+		samples       []uint64
+		interval      int
+		wantSlope     float64
+		wantMonotonic float64
+	}{
+		{
+			name:          "steady leak",
+			samples:       []uint64{100 * mib, 101 * mib, 102 * mib, 103 * mib},
+			interval:      60,
+			wantSlope:     60 * mib,
+			wantMonotonic: 1,
+		},
+		{
+			name:          "flat",
+			samples:       []uint64{100, 100, 100},
+			interval:      60,
+			wantSlope:     0,
+			wantMonotonic: 1,
+		},
+		{
+			name:          "sawtooth",
+			samples:       []uint64{100, 200, 100, 200, 100},
+			interval:      60,
+			wantSlope:     0,
+			wantMonotonic: 0.5,
+		},
+		{
+			name:    "single sample",
+			samples: []uint64{100},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			slope, monotonic := rssGrowth(tc.samples, tc.interval)
+			if math.Abs(slope-tc.wantSlope) > 1e-6 {
+				t.Errorf("slope = %v, want %v", slope, tc.wantSlope)
+			}
+			if monotonic != tc.wantMonotonic {
+				t.Errorf("monotonic = %v, want %v", monotonic, tc.wantMonotonic)
+			}
+		})
+	}
+}
+
+func TestReadProcessSmaps(t *testing.T) {
+	root := t.TempDir()
+	procfs.RootPrefix(root)
+	t.Cleanup(func() { procfs.RootPrefix("/") })
+
+	dir := filepath.Join(root, "proc/42")
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	smaps := `55d0c0a00000-55d0c0c00000 rw-p 00000000 00:00 0                          [heap]
+Rss:                 800 kB
+Swap:                 16 kB
+7f1e2c000000-7f1e2c100000 r-xp 00000000 08:01 1234                       /usr/lib/libc.so.6
+Rss:                 300 kB
+7f1e2d000000-7f1e2d100000 rw-p 00000000 00:00 0
+Rss:                 200 kB
+7ffc1a000000-7ffc1a021000 rw-p 00000000 00:00 0                          [stack]
+Rss:                  40 kB
+`
+	if err := os.WriteFile(filepath.Join(dir, "comm"), []byte("leaker\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "smaps"), []byte(smaps), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	p, err := readProcessSmaps(42)
+	if err != nil {
+		t.Fatalf("readProcessSmaps() error = %v", err)
+	}
+	want := memLeakProcess{PID: 42, Comm: "leaker", RSS: 1340 << 10, Swap: 16 << 10,
+		Heap: 800 << 10, Stack: 40 << 10, Anon: 200 << 10, File: 300 << 10}
+	if *p != want {
+		t.Errorf("readProcessSmaps() = %+v, want %+v", *p, want)
+	}
+}
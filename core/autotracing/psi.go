@@ -0,0 +1,311 @@
+// Copyright 2026 The HuaTuo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package autotracing
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"huatuo-bamai/internal/cgroups"
+	"huatuo-bamai/internal/cgroups/stats"
+	"huatuo-bamai/internal/log"
+	"huatuo-bamai/internal/pod"
+	"huatuo-bamai/pkg/tracing"
+	"huatuo-bamai/pkg/types"
+
+	"github.com/shirou/gopsutil/process"
+)
+
+// psiSampleDuration is the window the processes are sampled over for their
+// cpu and io rates.
+const psiSampleDuration = time.Second
+
+func init() {
+	tracing.RegisterEventTracing("psi", newPSI)
+}
+
+func newPSI() (*tracing.EventTracingAttr, error) {
+	if !stats.PSISupported() {
+		return nil, types.ErrNotSupported
+	}
+
+	cgroup, err := cgroups.NewManager()
+	if err != nil {
+		return nil, err
+	}
+
+	return &tracing.EventTracingAttr{
+		TracingData: &psiTracing{cgroupMgr: cgroup},
+		Interval:    10,
+		Flag:        tracing.FlagTracing,
+	}, nil
+}
+
+type psiTracing struct {
+	cgroupMgr cgroups.Cgroup
+}
+
+// PSITracingData is stored when the some or full pressure of a resource of
+// the host, or of a container, crosses its threshold.
+type PSITracingData struct {
+	Resource  string  `json:"resource"`
+	Kind      string  `json:"kind"`
+	Threshold float64 `json:"threshold"`
+	Avg10     float64 `json:"avg10"`
+	Avg60     float64 `json:"avg60"`
+	Avg300    float64 `json:"avg300"`
+	Total     uint64  `json:"total_us"`
+	// TopProcesses are sorted by the usage of Resource, rss for memory.
+	TopProcesses []*psiProcess     `json:"top_processes"`
+	MemoryStat   map[string]uint64 `json:"memory_stat,omitempty"`
+}
+
+type psiProcess struct {
+	PID  int32  `json:"pid"`
+	Comm string `json:"comm"`
+	// CPUPercent and the io rates are of the sample window.
+	CPUPercent       float64 `json:"cpu_percent"`
+	RSS              uint64  `json:"rss"`
+	ReadBytesPerSec  uint64  `json:"read_bytes_per_sec"`
+	WriteBytesPerSec uint64  `json:"write_bytes_per_sec"`
+
+	cpuTime               float64
+	readBytes, writeBytes uint64
+}
+
+// psiTarget is the host, with an empty id, or a container.
+type psiTarget struct {
+	id   string
+	path string
+}
+
+func validatePSI() error {
+	if cfg.PSI.Interval <= 0 {
+		return fmt.Errorf("psi interval must be positive, got %d", cfg.PSI.Interval)
+	}
+	if cfg.PSI.SomeThreshold < 0 || cfg.PSI.SomeThreshold > 100 ||
+		cfg.PSI.FullThreshold < 0 || cfg.PSI.FullThreshold > 100 {
+		return fmt.Errorf("psi thresholds must be in [0, 100], got %v and %v",
+			cfg.PSI.SomeThreshold, cfg.PSI.FullThreshold)
+	}
+	if cfg.PSI.DumpProcessMaxNum <= 0 {
+		return fmt.Errorf("psi dump process max num must be positive, got %d", cfg.PSI.DumpProcessMaxNum)
+	}
+	return nil
+}
+
+// psiExceeded returns the line of psi crossing its threshold, full first,
+// nil if none. A threshold of 0 is disabled.
+func psiExceeded(psi *stats.PSIStats, some, full float64) (kind string, line *stats.PSILine, threshold float64) {
+	if psi == nil {
+		return "", nil, 0
+	}
+	if full > 0 && psi.Full != nil && psi.Full.Avg10 >= full {
+		return "full", psi.Full, full
+	}
+	if some > 0 && psi.Some != nil && psi.Some.Avg10 >= some {
+		return "some", psi.Some, some
+	}
+	return "", nil, 0
+}
+
+func (c *psiTracing) Start(ctx context.Context) error {
+	if err := validatePSI(); err != nil {
+		return err
+	}
+
+	intervalTracing := time.Duration(cfg.PSI.IntervalTracing) * time.Second
+	lastReport := make(map[string]time.Time)
+
+	ticker := time.NewTicker(time.Duration(cfg.PSI.Interval) * time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return types.ErrExitByCancelCtx
+		case <-ticker.C:
+		}
+
+		targets := []psiTarget{{}}
+		containers, err := pod.NormalContainers()
+		if err != nil {
+			log.Debugf("psi list containers: %v", err)
+		}
+		alive := make(map[string]bool, len(containers))
+		for _, container := range containers {
+			targets = append(targets, psiTarget{id: container.ID, path: container.CgroupPath})
+			alive[container.ID] = true
+		}
+		for id := range lastReport {
+			if id != "" && !alive[id] {
+				delete(lastReport, id)
+			}
+		}
+
+		for _, target := range targets {
+			if time.Since(lastReport[target.id]) < intervalTracing {
+				continue
+			}
+			if c.check(target) {
+				lastReport[target.id] = time.Now()
+			}
+		}
+	}
+}
+
+// check saves a snapshot of the target if a resource is under pressure.
+func (c *psiTracing) check(target psiTarget) bool {
+	for _, resource := range []string{"cpu", "memory", "io"} {
+		psi, err := c.pressure(target, resource)
+		if err != nil {
+			log.Debugf("psi read %s pressure [%s]: %v", resource, target.path, err)
+			continue
+		}
+
+		kind, line, threshold := psiExceeded(psi, cfg.PSI.SomeThreshold, cfg.PSI.FullThreshold)
+		if line == nil {
+			continue
+		}
+
+		c.report(target, &PSITracingData{
+			Resource:  resource,
+			Kind:      kind,
+			Threshold: threshold,
+			Avg10:     line.Avg10,
+			Avg60:     line.Avg60,
+			Avg300:    line.Avg300,
+			Total:     line.Total,
+		})
+		return true
+	}
+	return false
+}
+
+func (c *psiTracing) pressure(target psiTarget, resource string) (*stats.PSIStats, error) {
+	if target.id == "" {
+		return stats.ReadHostPSI(resource)
+	}
+	return c.cgroupMgr.Pressure(target.path, resource)
+}
+
+func (c *psiTracing) report(target psiTarget, data *PSITracingData) {
+	pids, err := c.pids(target)
+	if err != nil {
+		log.Debugf("psi list processes [%s]: %v", target.path, err)
+	}
+	data.TopProcesses = topPSIProcesses(samplePSIProcesses(pids), data.Resource, cfg.PSI.DumpProcessMaxNum)
+
+	data.MemoryStat, err = c.cgroupMgr.MemoryStatRaw(target.path)
+	if err != nil {
+		log.Debugf("psi read memory.stat [%s]: %v", target.path, err)
+	}
+
+	if err := tracing.Save(&tracing.WriteRequest{
+		TracerName:    "psi",
+		ContainerID:   target.id,
+		TracerTime:    time.Now(),
+		TracerData:    data,
+		TracerRunType: tracing.TracerRunTypeAutotracing,
+	}); err != nil {
+		log.Warnf("failed to save tracing data: %v", err)
+	}
+}
+
+func (c *psiTracing) pids(target psiTarget) ([]int32, error) {
+	if target.id == "" {
+		return process.Pids()
+	}
+	return c.cgroupMgr.Procs(target.path)
+}
+
+// samplePSIProcesses reads the cpu time and io of the processes twice,
+// psiSampleDuration apart.
+func samplePSIProcesses(pids []int32) []*psiProcess {
+	read := func(pid int32) (*psiProcess, error) {
+		p, err := process.NewProcess(pid)
+		if err != nil {
+			return nil, err
+		}
+		times, err := p.Times()
+		if err != nil {
+			return nil, err
+		}
+
+		sample := &psiProcess{PID: pid, cpuTime: times.User + times.System}
+		// the io of the kernel threads is not readable.
+		if io, err := p.IOCounters(); err == nil {
+			sample.readBytes, sample.writeBytes = io.ReadBytes, io.WriteBytes
+		}
+		if mem, err := p.MemoryInfo(); err == nil {
+			sample.RSS = mem.RSS
+		}
+		sample.Comm, _ = p.Name()
+		return sample, nil
+	}
+
+	first := make(map[int32]*psiProcess, len(pids))
+	for _, pid := range pids {
+		if sample, err := read(pid); err == nil {
+			first[pid] = sample
+		}
+	}
+
+	start := time.Now()
+	time.Sleep(psiSampleDuration)
+	elapsed := time.Since(start).Seconds()
+
+	procs := make([]*psiProcess, 0, len(first))
+	for pid, prev := range first {
+		sample, err := read(pid)
+		if err != nil {
+			continue
+		}
+		sample.CPUPercent = max(sample.cpuTime-prev.cpuTime, 0) * 100 / elapsed
+		if sample.readBytes >= prev.readBytes {
+			sample.ReadBytesPerSec = uint64(float64(sample.readBytes-prev.readBytes) / elapsed)
+		}
+		if sample.writeBytes >= prev.writeBytes {
+			sample.WriteBytesPerSec = uint64(float64(sample.writeBytes-prev.writeBytes) / elapsed)
+		}
+		procs = append(procs, sample)
+	}
+	return procs
+}
+
+// topPSIProcesses returns the topN processes by the usage of the resource.
+func topPSIProcesses(procs []*psiProcess, resource string, topN int) []*psiProcess {
+	usage := func(p *psiProcess) float64 {
+		switch resource {
+		case "memory":
+			return float64(p.RSS)
+		case "io":
+			return float64(p.ReadBytesPerSec + p.WriteBytesPerSec)
+		default:
+			return p.CPUPercent
+		}
+	}
+
+	sort.Slice(procs, func(i, j int) bool {
+		return usage(procs[i]) > usage(procs[j])
+	})
+
+	if len(procs) > topN {
+		procs = procs[:topN]
+	}
+	return procs
+}
@@ -0,0 +1,80 @@
+// Copyright 2026 The HuaTuo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package autotracing
+
+import (
+	"testing"
+
+	"huatuo-bamai/internal/cgroups/stats"
+)
+
+func TestPSIExceeded(t *testing.T) {
+	psi := &stats.PSIStats{
+		Some: &stats.PSILine{Avg10: 45},
+		Full: &stats.PSILine{Avg10: 12},
+	}
+
+	tests := []struct {
+		name       string
+		psi        *stats.PSIStats
+		some, full float64
+		want       string
+	}{
+		{"full first", psi, 40, 10, "full"},
+		{"some", psi, 40, 20, "some"},
+		{"full disabled", psi, 40, 0, "some"},
+		{"below", psi, 50, 20, ""},
+		{"disabled", psi, 0, 0, ""},
+		{"no full line", &stats.PSIStats{Some: &stats.PSILine{Avg10: 5}}, 40, 1, ""},
+		{"cgroup without psi", nil, 40, 10, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			kind, line, threshold := psiExceeded(tt.psi, tt.some, tt.full)
+			if kind != tt.want {
+				t.Fatalf("psiExceeded() kind = %q, want %q", kind, tt.want)
+			}
+			if tt.want != "" && line.Avg10 < threshold {
+				t.Errorf("psiExceeded() avg10 %v below threshold %v", line.Avg10, threshold)
+			}
+		})
+	}
+}
+
+func TestTopPSIProcesses(t *testing.T) {
+	procs := func() []*psiProcess {
+		return []*psiProcess{
+			{PID: 1, CPUPercent: 90, RSS: 10, ReadBytesPerSec: 1},
+			{PID: 2, CPUPercent: 10, RSS: 900, WriteBytesPerSec: 5},
+			{PID: 3, CPUPercent: 50, RSS: 500, ReadBytesPerSec: 700, WriteBytesPerSec: 300},
+		}
+	}
+
+	for resource, want := range map[string][]int32{
+		"cpu":    {1, 3},
+		"memory": {2, 3},
+		"io":     {3, 2},
+	} {
+		top := topPSIProcesses(procs(), resource, 2)
+		if len(top) != len(want) {
+			t.Fatalf("%s: top = %d processes, want %d", resource, len(top), len(want))
+		}
+		for i := range want {
+			if top[i].PID != want[i] {
+				t.Errorf("%s: top[%d] = %d, want %d", resource, i, top[i].PID, want[i])
+			}
+		}
+	}
+}
@@ -0,0 +1,87 @@
+// Copyright 2026 The HuaTuo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package autotracing
+
+import (
+	"time"
+
+	"huatuo-bamai/internal/pod"
+)
+
+// sampleRing is a circular buffer of the recent samples of a container.
+type sampleRing[T any] struct {
+	samples []T
+	next    int
+	full    bool
+	alive   bool
+	path    string
+	// lastReport is the time of the last event saved from the samples.
+	lastReport time.Time
+}
+
+func (r *sampleRing[T]) add(sample T) {
+	r.samples[r.next] = sample
+	r.next = (r.next + 1) % len(r.samples)
+	if r.next == 0 {
+		r.full = true
+	}
+}
+
+// ordered appends the samples from oldest to newest to dst.
+func (r *sampleRing[T]) ordered(dst []T) []T {
+	if !r.full {
+		return append(dst, r.samples[:r.next]...)
+	}
+	return append(append(dst, r.samples[r.next:]...), r.samples[:r.next]...)
+}
+
+// containerSamples keeps a sample ring of length samples per container.
+type containerSamples[T any] struct {
+	length int
+	rings  map[string]*sampleRing[T]
+}
+
+func newContainerSamples[T any](length int) *containerSamples[T] {
+	return &containerSamples[T]{length: length, rings: make(map[string]*sampleRing[T])}
+}
+
+// track adds the rings of the new containers, updates their cgroup path and
+// drops the rings of the containers gone since the previous call.
+func (s *containerSamples[T]) track(containers map[string]*pod.Container) {
+	for _, container := range containers {
+		r, ok := s.rings[container.ID]
+		if !ok {
+			r = &sampleRing[T]{samples: make([]T, s.length)}
+			s.rings[container.ID] = r
+		}
+		r.alive = true
+		r.path = container.CgroupPath
+	}
+
+	for id, r := range s.rings {
+		if !r.alive {
+			delete(s.rings, id)
+			continue
+		}
+		r.alive = false
+	}
+}
+
+// take removes and returns the ring of a container, nil without one.
+func (s *containerSamples[T]) take(id string) *sampleRing[T] {
+	r := s.rings[id]
+	delete(s.rings, id)
+	return r
+}
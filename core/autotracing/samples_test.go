@@ -0,0 +1,71 @@
+// Copyright 2026 The HuaTuo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package autotracing
+
+import (
+	"reflect"
+	"testing"
+
+	"huatuo-bamai/internal/pod"
+)
+
+func TestSampleRingOrdered(t *testing.T) {
+	r := &sampleRing[uint64]{samples: make([]uint64, 3)}
+
+	r.add(1)
+	r.add(2)
+	if r.full || !reflect.DeepEqual(r.ordered(nil), []uint64{1, 2}) {
+		t.Fatalf("partial ring = %v, full %v", r.ordered(nil), r.full)
+	}
+
+	r.add(3)
+	r.add(4)
+	if !r.full || !reflect.DeepEqual(r.ordered(nil), []uint64{2, 3, 4}) {
+		t.Fatalf("wrapped ring = %v, full %v", r.ordered(nil), r.full)
+	}
+
+	// ordered reuses the capacity of dst.
+	dst := make([]uint64, 0, 3)
+	if got := r.ordered(dst[:0]); &got[0] != &dst[:1][0] {
+		t.Error("ordered() did not reuse dst")
+	}
+}
+
+func TestContainerSamplesTrack(t *testing.T) {
+	s := newContainerSamples[*evictionSample](2)
+
+	s.track(map[string]*pod.Container{
+		"a": {ID: "a", CgroupPath: "/kubepods/a"},
+		"b": {ID: "b", CgroupPath: "/kubepods/b"},
+	})
+	s.rings["a"].add(&evictionSample{Pids: 1})
+	if len(s.rings) != 2 || s.rings["b"].path != "/kubepods/b" {
+		t.Fatalf("rings = %v", s.rings)
+	}
+
+	// b is gone, a keeps its samples.
+	s.track(map[string]*pod.Container{"a": {ID: "a", CgroupPath: "/kubepods/a2"}})
+	a := s.rings["a"]
+	if len(s.rings) != 1 || a == nil || a.path != "/kubepods/a2" || len(a.ordered(nil)) != 1 {
+		t.Fatalf("rings after b removed = %v", s.rings)
+	}
+
+	if r := s.take("a"); r != a || len(s.rings) != 0 {
+		t.Errorf("take() = %v, rings %v", r, s.rings)
+	}
+	if r := s.take("a"); r != nil {
+		t.Errorf("take() of a taken ring = %v, want nil", r)
+	}
+}
@@ -0,0 +1,350 @@
+// Copyright 2026 The HuaTuo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package events
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"huatuo-bamai/internal/log"
+	"huatuo-bamai/internal/pod"
+	"huatuo-bamai/pkg/metric"
+	"huatuo-bamai/pkg/tracing"
+	"huatuo-bamai/pkg/types"
+
+	"github.com/cloudflare/backoff"
+	"github.com/mdlayher/netlink"
+	"golang.org/x/sys/unix"
+)
+
+const (
+	auditDenialSourceSELinux  = "selinux"
+	auditDenialSourceAppArmor = "apparmor"
+
+	// samples kept per spike event, enough to spot the offending profile.
+	auditDenialMaxSamples = 20
+)
+
+// AuditDenial is a single parsed SELinux AVC or AppArmor denial record.
+type AuditDenial struct {
+	Source      string `json:"source"`
+	Pid         int    `json:"pid"`
+	Comm        string `json:"comm"`
+	Operation   string `json:"operation,omitempty"`
+	Subject     string `json:"subject"`
+	Object      string `json:"object,omitempty"`
+	Name        string `json:"name,omitempty"`
+	Class       string `json:"class,omitempty"`
+	Permission  string `json:"permission"`
+	Permissive  bool   `json:"permissive,omitempty"`
+	ContainerID string `json:"container_id,omitempty"`
+	Container   string `json:"container,omitempty"`
+}
+
+// AuditDenialTracerData is the document stored on a denial spike.
+type AuditDenialTracerData struct {
+	Count           uint64            `json:"count"`
+	WindowSeconds   int64             `json:"window_seconds"`
+	Threshold       uint64            `json:"threshold"`
+	ContainerCounts map[string]uint64 `json:"container_counts"`
+	Samples         []*AuditDenial    `json:"samples"`
+}
+
+type auditDenialKey struct {
+	source      string
+	containerID string
+}
+
+type auditDenialTracing struct {
+	mu              sync.Mutex
+	counts          map[auditDenialKey]uint64
+	windowStart     time.Time
+	windowCount     uint64
+	windowSamples   []*AuditDenial
+	windowByCtr     map[string]uint64
+	backoff         *backoff.Backoff
+	nextAllowedTime time.Time
+}
+
+func init() {
+	tracing.RegisterEventTracing("audit_denial", newAuditDenial)
+}
+
+func newAuditDenial() (*tracing.EventTracingAttr, error) {
+	bo := backoff.NewWithoutJitter(3*time.Hour, 10*time.Minute)
+	bo.SetDecay(1 * time.Hour)
+
+	return &tracing.EventTracingAttr{
+		TracingData: &auditDenialTracing{
+			counts:      make(map[auditDenialKey]uint64),
+			windowByCtr: make(map[string]uint64),
+			backoff:     bo,
+		},
+		Interval: 10,
+		Flag:     tracing.FlagTracing | tracing.FlagMetric,
+	}, nil
+}
+
+// Start subscribes to the audit log multicast group, which mirrors records
+// to readers without taking the unicast channel away from auditd.
+func (c *auditDenialTracing) Start(ctx context.Context) error {
+	conn, err := netlink.Dial(unix.NETLINK_AUDIT, &netlink.Config{Groups: unix.AUDIT_NLGRP_READLOG})
+	if err != nil {
+		// multicast readlog needs Linux 3.16+ and CAP_AUDIT_READ.
+		if errors.Is(err, unix.EPROTONOSUPPORT) || errors.Is(err, unix.EPERM) {
+			return fmt.Errorf("audit readlog group: %w: %w", types.ErrNotSupported, err)
+		}
+		return fmt.Errorf("dial audit netlink: %w", err)
+	}
+
+	go func() {
+		<-ctx.Done()
+		conn.Close()
+	}()
+
+	c.mu.Lock()
+	c.resetWindow(time.Now())
+	c.mu.Unlock()
+
+	for {
+		msgs, err := conn.Receive()
+		if err != nil {
+			if ctx.Err() != nil {
+				return types.ErrExitByCancelCtx
+			}
+			return fmt.Errorf("receive audit netlink: %w", err)
+		}
+
+		for i := range msgs {
+			if uint16(msgs[i].Header.Type) != unix.AUDIT_AVC {
+				continue
+			}
+
+			denial := parseAuditDenial(string(msgs[i].Data))
+			if denial == nil {
+				continue
+			}
+
+			c.record(denial)
+		}
+	}
+}
+
+func (c *auditDenialTracing) record(denial *AuditDenial) {
+	if denial.Pid > 0 {
+		if container, err := pod.ContainerByPid(denial.Pid); err == nil && container != nil {
+			denial.ContainerID = container.ID
+			denial.Container = container.Name
+		}
+	}
+
+	now := time.Now()
+	window := time.Duration(cfg.AuditDenial.SpikeWindow) * time.Second
+
+	c.mu.Lock()
+	c.counts[auditDenialKey{source: denial.Source, containerID: denial.ContainerID}]++
+
+	if now.Sub(c.windowStart) > window {
+		c.resetWindow(now)
+	}
+
+	c.windowCount++
+	c.windowByCtr[denial.ContainerID]++
+	if len(c.windowSamples) < auditDenialMaxSamples {
+		c.windowSamples = append(c.windowSamples, denial)
+	}
+
+	if c.windowCount < cfg.AuditDenial.SpikeThreshold || now.Before(c.nextAllowedTime) {
+		c.mu.Unlock()
+		return
+	}
+
+	c.nextAllowedTime = now.Add(c.backoff.Duration())
+	data := &AuditDenialTracerData{
+		Count:           c.windowCount,
+		WindowSeconds:   cfg.AuditDenial.SpikeWindow,
+		Threshold:       cfg.AuditDenial.SpikeThreshold,
+		ContainerCounts: c.windowByCtr,
+		Samples:         c.windowSamples,
+	}
+	c.resetWindow(now)
+	c.mu.Unlock()
+
+	if err := tracing.Save(&tracing.WriteRequest{
+		TracerName: "audit_denial",
+		TracerTime: now,
+		TracerData: data,
+	}); err != nil {
+		log.Warnf("failed to save tracing data: %v", err)
+	}
+}
+
+// resetWindow must be called with c.mu held.
+func (c *auditDenialTracing) resetWindow(now time.Time) {
+	c.windowStart = now
+	c.windowCount = 0
+	c.windowSamples = nil
+	c.windowByCtr = make(map[string]uint64)
+}
+
+func (c *auditDenialTracing) Update() ([]*metric.Data, error) {
+	containers, err := pod.Containers()
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	keys := make([]auditDenialKey, 0, len(c.counts))
+	for k := range c.counts {
+		// drop the series once the container is gone.
+		if _, ok := containers[k.containerID]; k.containerID != "" && !ok {
+			delete(c.counts, k)
+			continue
+		}
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].source != keys[j].source {
+			return keys[i].source < keys[j].source
+		}
+		return keys[i].containerID < keys[j].containerID
+	})
+
+	data := make([]*metric.Data, 0, len(keys))
+	for _, k := range keys {
+		label := map[string]string{"source": k.source}
+		if k.containerID == "" {
+			data = append(data, metric.NewCounterData("total", float64(c.counts[k]),
+				"audit denials of host processes", label))
+			continue
+		}
+
+		data = append(data, metric.NewContainerCounterData(containers[k.containerID], "total",
+			float64(c.counts[k]), "audit denials of container processes", label))
+	}
+
+	return data, nil
+}
+
+// parseAuditDenial parses an AUDIT_AVC record body. Returns nil for records
+// that are not denials, e.g. AppArmor ALLOWED in complain mode or SELinux
+// granted audits.
+//
+// SELinux:  avc:  denied  { read } for  pid=1 comm="cat" name="x" scontext=... tcontext=... tclass=file permissive=0
+// AppArmor: apparmor="DENIED" operation="open" profile="p" name="/x" pid=1 comm="cat" requested_mask="r" denied_mask="r"
+func parseAuditDenial(record string) *AuditDenial {
+	// strip the "audit(1700000000.123:42): " prefix.
+	if i := strings.Index(record, "): "); i >= 0 && strings.HasPrefix(record, "audit(") {
+		record = record[i+3:]
+	}
+	record = strings.TrimRight(record, "\x00\n")
+
+	kv := parseAuditFields(record)
+
+	if v, ok := kv["apparmor"]; ok {
+		if v != "DENIED" {
+			return nil
+		}
+		pid, _ := strconv.Atoi(kv["pid"])
+		return &AuditDenial{
+			Source:     auditDenialSourceAppArmor,
+			Pid:        pid,
+			Comm:       kv["comm"],
+			Operation:  kv["operation"],
+			Subject:    kv["profile"],
+			Name:       kv["name"],
+			Class:      kv["class"],
+			Permission: kv["denied_mask"],
+		}
+	}
+
+	if !strings.HasPrefix(record, "avc:") {
+		return nil
+	}
+	rest := strings.TrimSpace(strings.TrimPrefix(record, "avc:"))
+	if !strings.HasPrefix(rest, "denied") {
+		return nil
+	}
+
+	var perms string
+	if l, r := strings.Index(rest, "{"), strings.Index(rest, "}"); l >= 0 && r > l {
+		perms = strings.Join(strings.Fields(rest[l+1:r]), ",")
+	}
+
+	name := kv["name"]
+	if name == "" {
+		name = kv["path"]
+	}
+
+	pid, _ := strconv.Atoi(kv["pid"])
+	return &AuditDenial{
+		Source:     auditDenialSourceSELinux,
+		Pid:        pid,
+		Comm:       kv["comm"],
+		Subject:    kv["scontext"],
+		Object:     kv["tcontext"],
+		Name:       name,
+		Class:      kv["tclass"],
+		Permission: perms,
+		Permissive: kv["permissive"] == "1",
+	}
+}
+
+// parseAuditFields splits key=value pairs, honouring double-quoted values.
+func parseAuditFields(record string) map[string]string {
+	kv := make(map[string]string)
+	for len(record) > 0 {
+		record = strings.TrimLeft(record, " ")
+		eq := strings.IndexByte(record, '=')
+		if eq <= 0 {
+			break
+		}
+
+		key := record[:eq]
+		if sp := strings.LastIndexByte(key, ' '); sp >= 0 {
+			key = key[sp+1:]
+		}
+		record = record[eq+1:]
+
+		var val string
+		if strings.HasPrefix(record, `"`) {
+			end := strings.IndexByte(record[1:], '"')
+			if end < 0 {
+				val, record = record[1:], ""
+			} else {
+				val, record = record[1:end+1], record[end+2:]
+			}
+		} else {
+			end := strings.IndexByte(record, ' ')
+			if end < 0 {
+				val, record = record, ""
+			} else {
+				val, record = record[:end], record[end:]
+			}
+		}
+
+		kv[key] = val
+	}
+
+	return kv
+}
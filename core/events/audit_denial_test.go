@@ -0,0 +1,98 @@
+// Copyright 2026 The HuaTuo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package events
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseAuditDenial(t *testing.T) {
+	tests := []struct {
+		name   string
+		record string
+		want   *AuditDenial
+	}{
+		{
+			name: "selinux_denied",
+			record: `audit(1700000000.123:42): avc:  denied  { read write } for  pid=1234 comm="nginx" ` +
+				`name="app.sock" dev="tmpfs" ino=99 scontext=system_u:system_r:container_t:s0:c1,c2 ` +
+				`tcontext=system_u:object_r:var_run_t:s0 tclass=sock_file permissive=0`,
+			want: &AuditDenial{
+				Source:     auditDenialSourceSELinux,
+				Pid:        1234,
+				Comm:       "nginx",
+				Subject:    "system_u:system_r:container_t:s0:c1,c2",
+				Object:     "system_u:object_r:var_run_t:s0",
+				Name:       "app.sock",
+				Class:      "sock_file",
+				Permission: "read,write",
+			},
+		},
+		{
+			name:   "selinux_permissive_path",
+			record: `avc:  denied  { getattr } for  pid=7 comm="ls" path="/data" scontext=a tcontext=b tclass=dir permissive=1` + "\x00",
+			want: &AuditDenial{
+				Source:     auditDenialSourceSELinux,
+				Pid:        7,
+				Comm:       "ls",
+				Subject:    "a",
+				Object:     "b",
+				Name:       "/data",
+				Class:      "dir",
+				Permission: "getattr",
+				Permissive: true,
+			},
+		},
+		{
+			name:   "selinux_granted",
+			record: `avc:  granted  { setenforce } for  pid=1 comm="setenforce" scontext=a tcontext=b tclass=security`,
+			want:   nil,
+		},
+		{
+			name: "apparmor_denied",
+			record: `audit(1700000000.456:43): apparmor="DENIED" operation="open" profile="docker-default" ` +
+				`name="/proc/sys/kernel/core_pattern" pid=4321 comm="sh" requested_mask="w" denied_mask="w" fsuid=0 ouid=0`,
+			want: &AuditDenial{
+				Source:     auditDenialSourceAppArmor,
+				Pid:        4321,
+				Comm:       "sh",
+				Operation:  "open",
+				Subject:    "docker-default",
+				Name:       "/proc/sys/kernel/core_pattern",
+				Permission: "w",
+			},
+		},
+		{
+			name:   "apparmor_complain",
+			record: `apparmor="ALLOWED" operation="open" profile="p" name="/x" pid=1 comm="cat" denied_mask="r"`,
+			want:   nil,
+		},
+		{
+			name:   "unrelated",
+			record: `audit(1700000000.000:1): op=load policy`,
+			want:   nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := parseAuditDenial(tt.record)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseAuditDenial() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -16,15 +16,23 @@ package events
 
 // Config holds event tracing configuration.
 type Config struct {
+	// OOM stores the last KernelLogLines of the kernel log with an oom
+	// kill, read KernelLogDelay milliseconds after it for the kernel to
+	// print its report. 0 lines disables it.
+	OOM struct {
+		KernelLogLines int `default:"50" min:"0"`
+		KernelLogDelay int `default:"200" min:"0"`
+	} `tracer:"oom"`
+
 	Softirq struct {
 		// 10ms
 		DisabledThreshold uint64 `default:"10000000"`
-	}
+	} `tracer:"softirq_tracing"`
 
 	MemoryReclaim struct {
 		// 900ms
 		BlockedThreshold uint64 `default:"900000000"`
-	}
+	} `tracer:"memory_reclaim_events"`
 
 	NetRxLatency struct {
 		Driver2NetRx             uint64 `default:"5"`
@@ -32,21 +40,107 @@ type Config struct {
 		Driver2Userspace         uint64 `default:"115"`
 		ExcludedHostNetnamespace bool   `default:"true"`
 		ExcludedContainerQos     []string
-	}
+	} `tracer:"net_rx_latency"`
 
 	Dropwatch struct {
 		Filter             string `default:"tcp"`
 		MaxEventsPerSecond uint64 `default:"100"`
 		ExcludeContainers  []string
-	}
+	} `tracer:"dropwatch"`
 
+	// Netdev counts the link status changes of a device within FlapWindow
+	// seconds in its events, a flapping device changes frequently.
 	Netdev struct {
 		DeviceList []string
-	}
+		FlapWindow int64 `default:"300" min:"1"`
+	} `tracer:"netdev_events"`
 
 	Ras struct {
 		MceThrBackoff int64 `default:"1800"`
-	}
+	} `tracer:"ras"`
+
+	AuditDenial struct {
+		SpikeThreshold uint64 `default:"50"`
+		SpikeWindow    int64  `default:"60"`
+	} `tracer:"audit_denial"`
+
+	KernelLog struct {
+		MaxMessagesPerSecond int   `default:"1000"`
+		RuleInterval         int64 `default:"60"`
+		BuiltinRules         bool  `default:"true"`
+		ContextLinesBefore   int   `default:"5" min:"0"`
+		ContextLinesAfter    int   `default:"30" min:"0"`
+		Rules                []struct {
+			Name     string
+			Pattern  string
+			Severity string
+		}
+	} `tracer:"kernel_log"`
+
+	NetProbe struct {
+		Targets       []string
+		Interval      int  `default:"10" min:"1"`
+		Count         int  `default:"3" min:"1"`
+		Timeout       int  `default:"1"`
+		LossThreshold uint `default:"100"`
+	} `tracer:"netprobe"`
+
+	PageFault struct {
+		Interval        int    `default:"10"`
+		RateThreshold   uint64 `default:"500"`
+		SampleDuration  int    `default:"5"`
+		IntervalTracing int    `default:"1800"`
+		ColdStartWindow int    `default:"600"`
+	} `tracer:"page_fault"`
+
+	Coredump struct {
+		StackDepth    int `default:"16"`
+		WaitTimeout   int `default:"60"`
+		UploadURL     string
+		UploadMaxSize int64 `default:"2048"`
+		UploadTimeout int   `default:"300"`
+	} `tracer:"coredump"`
+
+	Zombie struct {
+		Interval           int `default:"30"`
+		UnreapedThreshold  int `default:"10"`
+		PidsUsageThreshold int `default:"80"`
+		IntervalTracing    int `default:"1800"`
+	} `tracer:"zombie"`
+
+	DState struct {
+		Interval         int `default:"10"`
+		BlockedThreshold int `default:"120"`
+		MaxTasks         int `default:"20"`
+		IntervalTracing  int `default:"600"`
+	} `tracer:"dstate"`
+
+	// TCPRetrans saves an event when the retransmits and resets of a
+	// single flow reach FlowThreshold within FlowWindow seconds, 0
+	// disables the events. SocketDetails adds the ss(8) details of the
+	// socket of a container flow.
+	TCPRetrans struct {
+		FlowThreshold uint64 `default:"50"`
+		FlowWindow    uint64 `default:"10" min:"1"`
+		SocketDetails bool
+	} `tracer:"tcp_retrans"`
+
+	// SkbDrop saves an event with the stacks and the containers of the
+	// drops when the packets dropped per second cross StormThreshold, 0
+	// disables the events.
+	SkbDrop struct {
+		Interval        int    `default:"10" min:"1"`
+		StormThreshold  uint64 `default:"5000"`
+		MaxSites        int    `default:"10" min:"1"`
+		IntervalTracing int    `default:"600" min:"0"`
+	} `tracer:"skb_drop"`
+
+	FsEnforce struct {
+		Enable    bool
+		Mode      string `default:"audit"`
+		DenyWrite []string
+		DenyExec  []string
+	} `tracer:"fs_enforce"`
 
 	IssuesList [][]string
 }
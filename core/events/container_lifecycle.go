@@ -0,0 +1,111 @@
+// Copyright 2026 The HuaTuo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package events
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"huatuo-bamai/internal/log"
+	"huatuo-bamai/internal/pod"
+	"huatuo-bamai/pkg/tracing"
+	"huatuo-bamai/pkg/types"
+)
+
+func init() {
+	tracing.RegisterEventTracing("container_lifecycle", newContainerLifecycle)
+	tracing.RegisterSchema[ContainerLifecycleTracingData]("container_lifecycle", "container_lifecycle", 1)
+}
+
+var (
+	// lifecycles are queued by the pod sync, the tracer must not block it.
+	lifecycles            = make(chan *pod.ContainerLifecycle, 256)
+	lifecycleRegisterOnce sync.Once
+)
+
+// ContainerLifecycleTracingData is stored for every lifecycle transition of
+// a container, so the kernel events line up against its restarts.
+type ContainerLifecycleTracingData struct {
+	// Event is created, started, oom_killed or exited.
+	Event         string `json:"event" validate:"oneof=created started oom_killed exited"`
+	ContainerName string `json:"container_name"`
+	PodName       string `json:"pod_name"`
+	Namespace     string `json:"namespace"`
+	// ExitCode, Reason and Duration, how long the container ran, are of
+	// the exits, ExitCode is missing when the container is gone unseen.
+	ExitCode     *int32 `json:"exit_code,omitempty"`
+	Reason       string `json:"reason,omitempty"`
+	RestartCount int32  `json:"restart_count"`
+	Duration     int64  `json:"duration_ms,omitempty"`
+}
+
+type containerLifecycleTracing struct{}
+
+func newContainerLifecycle() (*tracing.EventTracingAttr, error) {
+	lifecycleRegisterOnce.Do(func() {
+		pod.RegisterLifecycleHandler(func(e *pod.ContainerLifecycle) {
+			select {
+			case lifecycles <- e:
+			default:
+				log.Warnf("container lifecycle queue full, drop %s of %s", e.Event, e.ContainerID)
+			}
+		})
+	})
+
+	return &tracing.EventTracingAttr{
+		TracingData: &containerLifecycleTracing{},
+		Interval:    10,
+		Flag:        tracing.FlagTracing,
+	}, nil
+}
+
+// Start saves the transitions the syncs of the containers notice, the
+// pods are synced by the tracers and the collectors listing them.
+func (c *containerLifecycleTracing) Start(ctx context.Context) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return types.ErrExitByCancelCtx
+		case e := <-lifecycles:
+			c.save(e)
+		}
+	}
+}
+
+func (c *containerLifecycleTracing) save(e *pod.ContainerLifecycle) {
+	at := e.Time
+	if at.IsZero() {
+		at = time.Now()
+	}
+
+	if err := tracing.Save(&tracing.WriteRequest{
+		TracerName:  "container_lifecycle",
+		ContainerID: e.ContainerID,
+		TracerTime:  at,
+		TracerData: &ContainerLifecycleTracingData{
+			Event:         e.Event,
+			ContainerName: e.ContainerName,
+			PodName:       e.PodName,
+			Namespace:     e.Namespace,
+			ExitCode:      e.ExitCode,
+			Reason:        e.Reason,
+			RestartCount:  e.RestartCount,
+			Duration:      e.Duration.Milliseconds(),
+		},
+	}); err != nil {
+		log.Warnf("failed to save tracing data: %v", err)
+	}
+}
@@ -0,0 +1,386 @@
+// Copyright 2026 The HuaTuo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package events
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"huatuo-bamai/internal/bpf"
+	"huatuo-bamai/internal/log"
+	"huatuo-bamai/internal/pod"
+	"huatuo-bamai/internal/procfs"
+	"huatuo-bamai/internal/symbol"
+	"huatuo-bamai/internal/utils/bytesutil"
+	"huatuo-bamai/pkg/metric"
+	"huatuo-bamai/pkg/tracing"
+
+	"golang.org/x/sys/unix"
+)
+
+//go:generate $BPF_COMPILE $BPF_INCLUDE -s $BPF_DIR/coredump.c -o $BPF_DIR/coredump.o
+
+type coredumpPerfEvent struct {
+	Stack     [symbol.KsymStackMaxDepth]uint64
+	StackSize int64
+	Comm      [bpf.TaskCommLen]byte
+	Pid       uint32
+	Tid       uint32
+	Signo     int32
+	UID       uint32
+}
+
+// CoredumpTracingData is stored for every process that dumps core.
+type CoredumpTracingData struct {
+	Pid         uint32   `json:"pid"`
+	Tid         uint32   `json:"tid"`
+	Comm        string   `json:"comm"`
+	UID         uint32   `json:"uid"`
+	Binary      string   `json:"binary"`
+	Signal      int32    `json:"signal"`
+	SignalName  string   `json:"signal_name"`
+	CorePattern string   `json:"core_pattern"`
+	CorePath    string   `json:"core_path,omitempty"`
+	CoreSize    int64    `json:"core_size,omitempty"`
+	UploadURL   string   `json:"upload_url,omitempty"`
+	UploadError string   `json:"upload_error,omitempty"`
+	Stack       []string `json:"stack"`
+}
+
+type coredumpTracing struct {
+	usym     *symbol.UsymResolver
+	client   *http.Client
+	mu       sync.Mutex
+	bySignal map[string]uint64
+}
+
+func init() {
+	tracing.RegisterEventTracing("coredump", newCoredump)
+	tracing.RegisterSchema[CoredumpTracingData]("coredump", "coredump", 1)
+}
+
+func newCoredump() (*tracing.EventTracingAttr, error) {
+	return &tracing.EventTracingAttr{
+		TracingData: &coredumpTracing{
+			usym:     symbol.NewUsymResolver(),
+			client:   &http.Client{Timeout: time.Duration(cfg.Coredump.UploadTimeout) * time.Second},
+			bySignal: make(map[string]uint64),
+		},
+		Interval: 10,
+		Flag:     tracing.FlagTracing | tracing.FlagMetric,
+	}, nil
+}
+
+func (c *coredumpTracing) Start(ctx context.Context) error {
+	b, err := bpf.LoadBpf(bpf.ThisBpfOBJ(), nil)
+	if err != nil {
+		return err
+	}
+	defer b.Close()
+
+	// newer kernels renamed do_coredump to vfs_coredump, the first arg
+	// (kernel_siginfo_t *) is unchanged.
+	coredumpSym := "do_coredump"
+	if !bpf.HasKprobeFunction(coredumpSym) {
+		coredumpSym = "vfs_coredump"
+	}
+
+	if err := b.AttachWithOptions([]bpf.AttachOption{
+		{ProgramName: "kprobe_coredump", Symbol: coredumpSym},
+	}); err != nil {
+		return err
+	}
+
+	childCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	reader, err := b.EventPipeByName(childCtx, "coredump_events", 8192)
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+
+	b.WaitDetachByBreaker(childCtx, cancel)
+
+	var wg sync.WaitGroup
+	defer wg.Wait()
+
+	for {
+		select {
+		case <-childCtx.Done():
+			return nil
+		default:
+			var event coredumpPerfEvent
+			if err := reader.ReadInto(&event); err != nil {
+				return fmt.Errorf("ReadFromPerfEvent fail: %w", err)
+			}
+
+			// everything read from /proc must be taken before the dump
+			// completes and the process goes away.
+			data, containerID, locate := c.inspect(&event)
+
+			c.mu.Lock()
+			c.bySignal[data.SignalName]++
+			c.mu.Unlock()
+
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				c.finish(childCtx, event.Pid, data, containerID, locate)
+			}()
+		}
+	}
+}
+
+// coreLocation is where the kernel is writing the core file, reached through
+// the crashed task's root as seen from the host.
+type coreLocation struct {
+	hostGlob string
+	hostBase string
+}
+
+func (c *coredumpTracing) inspect(event *coredumpPerfEvent) (*CoredumpTracingData, string, *coreLocation) {
+	data := &CoredumpTracingData{
+		Pid:        event.Pid,
+		Tid:        event.Tid,
+		Comm:       bytesutil.ToStr(event.Comm[:]),
+		UID:        event.UID,
+		Signal:     event.Signo,
+		SignalName: unix.SignalName(syscall.Signal(event.Signo)),
+	}
+
+	procDir := procfs.Path(strconv.FormatUint(uint64(event.Pid), 10))
+	if exe, err := os.Readlink(filepath.Join(procDir, "exe")); err == nil {
+		data.Binary = exe
+	}
+
+	if event.StackSize > 0 {
+		data.Stack = c.usym.UsymStackStrs(event.Pid, event.Stack[:], int(event.StackSize/8))
+		if len(data.Stack) > cfg.Coredump.StackDepth {
+			data.Stack = data.Stack[:cfg.Coredump.StackDepth]
+		}
+	}
+
+	containerID := ""
+	if container, err := pod.ContainerByPid(int(event.Pid)); err == nil && container != nil {
+		containerID = container.ID
+	}
+
+	raw, err := os.ReadFile(procfs.Path("sys/kernel/core_pattern"))
+	if err != nil {
+		log.Debugf("coredump read core_pattern: %v", err)
+		return data, containerID, nil
+	}
+	data.CorePattern = strings.TrimSpace(string(raw))
+
+	// piped to a core helper, nothing is written to a file we could see.
+	if strings.HasPrefix(data.CorePattern, "|") {
+		return data, containerID, nil
+	}
+
+	usesPid := false
+	if raw, err := os.ReadFile(procfs.Path("sys/kernel/core_uses_pid")); err == nil {
+		usesPid = strings.TrimSpace(string(raw)) != "0"
+	}
+
+	glob := coreFileGlob(data.CorePattern, event, usesPid)
+	loc := &coreLocation{hostBase: filepath.Join(procDir, "root")}
+	if !filepath.IsAbs(glob) {
+		// relative patterns are resolved against the crashing task's cwd.
+		cwd, err := os.Readlink(filepath.Join(procDir, "cwd"))
+		if err != nil {
+			return data, containerID, nil
+		}
+		glob = filepath.Join(globEscape(cwd), glob)
+	}
+	loc.hostGlob = filepath.Join(globEscape(loc.hostBase), glob)
+
+	return data, containerID, loc
+}
+
+// coreFileGlob expands core_pattern for the crashed task. Specifiers that
+// cannot be known from the host side, e.g. the namespaced pid or the
+// timestamp, become wildcards.
+func coreFileGlob(pattern string, event *coredumpPerfEvent, usesPid bool) string {
+	if pattern == "" {
+		pattern = "core"
+	}
+
+	var b strings.Builder
+	pidInPattern := false
+	for i := 0; i < len(pattern); i++ {
+		if pattern[i] != '%' || i == len(pattern)-1 {
+			b.WriteString(globEscape(pattern[i : i+1]))
+			continue
+		}
+
+		i++
+		switch pattern[i] {
+		case '%':
+			b.WriteByte('%')
+		case 'P':
+			b.WriteString(strconv.FormatUint(uint64(event.Pid), 10))
+		case 'I':
+			b.WriteString(strconv.FormatUint(uint64(event.Tid), 10))
+		case 's':
+			b.WriteString(strconv.Itoa(int(event.Signo)))
+		case 'e':
+			comm := strings.ReplaceAll(bytesutil.ToStr(event.Comm[:]), "/", "!")
+			b.WriteString(globEscape(comm))
+		case 'p':
+			pidInPattern = true
+			b.WriteByte('*')
+		default:
+			b.WriteByte('*')
+		}
+	}
+
+	if usesPid && !pidInPattern {
+		b.WriteString(".*")
+	}
+
+	return b.String()
+}
+
+func globEscape(s string) string {
+	r := strings.NewReplacer(`\`, `\\`, `*`, `\*`, `?`, `\?`, `[`, `\[`)
+	return r.Replace(s)
+}
+
+// waitCore polls for the core file until the crashed process is gone, which
+// is when the kernel has finished writing it. The file is opened while the
+// process root is still reachable through procfs.
+func waitCore(ctx context.Context, pid uint32, loc *coreLocation, since time.Time) (string, *os.File) {
+	procDir := procfs.Path(strconv.FormatUint(uint64(pid), 10))
+	timeout := time.After(time.Duration(cfg.Coredump.WaitTimeout) * time.Second)
+
+	ticker := time.NewTicker(500 * time.Millisecond)
+	defer ticker.Stop()
+
+	var (
+		path string
+		file *os.File
+	)
+	for {
+		if file == nil {
+			matches, _ := filepath.Glob(loc.hostGlob)
+			for _, m := range matches {
+				fi, err := os.Stat(m)
+				if err != nil || !fi.Mode().IsRegular() || fi.ModTime().Before(since) {
+					continue
+				}
+				if f, err := os.Open(m); err == nil {
+					path, file = strings.TrimPrefix(m, loc.hostBase), f
+					break
+				}
+			}
+		}
+
+		if _, err := os.Stat(procDir); os.IsNotExist(err) {
+			return path, file
+		}
+
+		select {
+		case <-ctx.Done():
+			return path, file
+		case <-timeout:
+			return path, file
+		case <-ticker.C:
+		}
+	}
+}
+
+func (c *coredumpTracing) finish(ctx context.Context, pid uint32, data *CoredumpTracingData, containerID string, loc *coreLocation) {
+	now := time.Now()
+
+	if loc != nil {
+		path, file := waitCore(ctx, pid, loc, now.Add(-time.Second))
+		if file != nil {
+			data.CorePath = path
+			if fi, err := file.Stat(); err == nil {
+				data.CoreSize = fi.Size()
+			}
+			if cfg.Coredump.UploadURL != "" {
+				if err := c.upload(ctx, data, file); err != nil {
+					data.UploadError = err.Error()
+				}
+			}
+			file.Close()
+		}
+	}
+
+	if err := tracing.Save(&tracing.WriteRequest{
+		TracerName:  "coredump",
+		ContainerID: containerID,
+		TracerTime:  now,
+		TracerData:  data,
+	}); err != nil {
+		log.Warnf("failed to save tracing data: %v", err)
+	}
+}
+
+// upload PUTs the core to UploadURL/<hostname>/<file name>, which fits
+// object storage buckets or gateways accepting plain uploads.
+func (c *coredumpTracing) upload(ctx context.Context, data *CoredumpTracingData, file *os.File) error {
+	if limit := cfg.Coredump.UploadMaxSize * 1024 * 1024; data.CoreSize > limit {
+		return fmt.Errorf("core size %d exceeds upload limit %d", data.CoreSize, limit)
+	}
+
+	hostname, err := os.Hostname()
+	if err != nil {
+		return err
+	}
+
+	url := strings.TrimSuffix(cfg.Coredump.UploadURL, "/") + "/" + hostname + "/" + filepath.Base(data.CorePath)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, url, io.NewSectionReader(file, 0, data.CoreSize))
+	if err != nil {
+		return err
+	}
+	req.ContentLength = data.CoreSize
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("upload %s: %s", url, resp.Status)
+	}
+
+	data.UploadURL = url
+	return nil
+}
+
+func (c *coredumpTracing) Update() ([]*metric.Data, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	data := make([]*metric.Data, 0, len(c.bySignal))
+	for signal, count := range c.bySignal {
+		data = append(data, metric.NewCounterData("total", float64(count), "core dumps by signal", map[string]string{"signal": signal}))
+	}
+
+	return data, nil
+}
@@ -0,0 +1,86 @@
+// Copyright 2026 The HuaTuo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package events
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"huatuo-bamai/internal/procfs"
+)
+
+func TestCoreFileGlob(t *testing.T) {
+	event := &coredumpPerfEvent{Pid: 4321, Tid: 4322, Signo: 11}
+	copy(event.Comm[:], "a/b*")
+
+	cases := []struct {
+		name    string
+		pattern string
+		usesPid bool
+		want    string
+	}{
+		{name: "default", pattern: "", want: "core"},
+		{name: "core uses pid", pattern: "core", usesPid: true, want: "core.*"},
+		{name: "namespaced pid", pattern: "/var/crash/core.%e.%p.%t", usesPid: true, want: `/var/crash/core.a!b\*.*.*`},
+		{name: "global ids", pattern: "/cores/%P-%I-%s-%%", want: "/cores/4321-4322-11-%"},
+		{name: "trailing percent", pattern: "core%", want: "core%"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := coreFileGlob(tc.pattern, event, tc.usesPid); got != tc.want {
+				t.Errorf("coreFileGlob(%q) = %q, want %q", tc.pattern, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestCoredumpInspect(t *testing.T) {
+	root := t.TempDir()
+	procfs.RootPrefix(root)
+	t.Cleanup(func() { procfs.RootPrefix("/") })
+
+	proc := filepath.Join(root, "proc")
+	for path, content := range map[string]string{
+		"sys/kernel/core_pattern":  "/var/crash/core.%e\n",
+		"sys/kernel/core_uses_pid": "1\n",
+	} {
+		if err := os.MkdirAll(filepath.Join(proc, filepath.Dir(path)), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(proc, path), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.MkdirAll(filepath.Join(proc, "4321"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("/usr/bin/app", filepath.Join(proc, "4321/exe")); err != nil {
+		t.Fatal(err)
+	}
+
+	event := &coredumpPerfEvent{Pid: 4321, Tid: 4321, Signo: 6}
+	copy(event.Comm[:], "app")
+
+	data, _, loc := (&coredumpTracing{}).inspect(event)
+	if data.Binary != "/usr/bin/app" || data.CorePattern != "/var/crash/core.%e" {
+		t.Errorf("inspect() = binary %q, core_pattern %q", data.Binary, data.CorePattern)
+	}
+	hostBase := filepath.Join(proc, "4321/root")
+	if loc == nil || loc.hostBase != hostBase || loc.hostGlob != hostBase+"/var/crash/core.app.*" {
+		t.Errorf("inspect() location = %+v, want under %s", loc, hostBase)
+	}
+}
@@ -21,6 +21,7 @@ import (
 	"path"
 	"strconv"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	"huatuo-bamai/internal/cgroups/subsystem"
@@ -36,8 +37,13 @@ import (
 
 type dropWatchTracing struct{}
 
+// dropwatchEventsTotal counts forwarded drop events so other tracers, e.g.
+// netprobe, can tell whether the kernel was dropping packets meanwhile.
+var dropwatchEventsTotal atomic.Uint64
+
 func init() {
 	tracing.RegisterEventTracing("dropwatch", newDropWatch)
+	tracing.RegisterSchema[*types.DropWatchTracing]("dropwatch", "dropwatch", 1)
 	toolstream.RegisterDefault[*types.DropWatchTracing]("dropwatch", handleDropwatchEvent)
 }
 
@@ -91,6 +97,8 @@ func handleDropwatchEvent(_ *toolstream.Session, ev *types.DropWatchTracing) err
 		return nil
 	}
 
+	dropwatchEventsTotal.Add(1)
+
 	if ev.ContainerID == "" {
 		ev.ContainerID = resolveContainerIDFromMeta(ev)
 	}
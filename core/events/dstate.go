@@ -0,0 +1,252 @@
+// Copyright 2026 The HuaTuo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package events
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"huatuo-bamai/internal/log"
+	"huatuo-bamai/internal/pod"
+	"huatuo-bamai/internal/procfs"
+	"huatuo-bamai/pkg/tracing"
+	"huatuo-bamai/pkg/types"
+)
+
+// DStateTracingData is stored when tasks stay in uninterruptible sleep
+// longer than the threshold. Unlike hungtask, it does not depend on the
+// hung_task detector of the kernel, disabled by some distributions.
+type DStateTracingData struct {
+	ThresholdSecs int `json:"threshold_secs"`
+	// BlockedTasks is the number of the tasks above the threshold, Tasks
+	// the longest blocked of them.
+	BlockedTasks int           `json:"blocked_tasks"`
+	Tasks        []*dstateTask `json:"tasks"`
+}
+
+type dstateTask struct {
+	Pid               int     `json:"pid"`
+	Tgid              int     `json:"tgid"`
+	Comm              string  `json:"comm"`
+	BlockedSecs       float64 `json:"blocked_secs"`
+	ContainerID       string  `json:"container_id,omitempty"`
+	ContainerHostname string  `json:"container_hostname,omitempty"`
+	Stack             string  `json:"stack"`
+}
+
+// dstateSample is a task in uninterruptible sleep seen by a scan.
+type dstateSample struct {
+	pid, tgid int
+	comm      string
+	// switches are the context switches of the task, unchanged as long
+	// as it stays blocked.
+	switches uint64
+}
+
+// dstateBlocked is a task in uninterruptible sleep since the scan it was
+// first seen in with the same context switches.
+type dstateBlocked struct {
+	dstateSample
+	since time.Time
+}
+
+type dstateTracing struct {
+	blocked    map[int]*dstateBlocked
+	lastReport time.Time
+}
+
+func init() {
+	tracing.RegisterEventTracing("dstate", newDState)
+	tracing.RegisterSchema[DStateTracingData]("dstate", "dstate", 1)
+}
+
+func newDState() (*tracing.EventTracingAttr, error) {
+	return &tracing.EventTracingAttr{
+		TracingData: &dstateTracing{
+			blocked: make(map[int]*dstateBlocked),
+		},
+		Interval: 10,
+		Flag:     tracing.FlagTracing,
+	}, nil
+}
+
+func validateDState() error {
+	if cfg.DState.Interval <= 0 {
+		return fmt.Errorf("dstate interval must be positive, got %d", cfg.DState.Interval)
+	}
+	if cfg.DState.BlockedThreshold <= 0 {
+		return fmt.Errorf("dstate blocked threshold must be positive, got %d", cfg.DState.BlockedThreshold)
+	}
+	if cfg.DState.MaxTasks <= 0 {
+		return fmt.Errorf("dstate max tasks must be positive, got %d", cfg.DState.MaxTasks)
+	}
+	return nil
+}
+
+func (c *dstateTracing) Start(ctx context.Context) error {
+	if err := validateDState(); err != nil {
+		return err
+	}
+
+	ticker := time.NewTicker(time.Duration(cfg.DState.Interval) * time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return types.ErrExitByCancelCtx
+		case <-ticker.C:
+		}
+
+		samples, err := dstateSamples()
+		if err != nil {
+			log.Debugf("dstate read /proc: %v", err)
+			continue
+		}
+
+		now := time.Now()
+		blocked := c.update(samples, now, time.Duration(cfg.DState.BlockedThreshold)*time.Second)
+		if len(blocked) == 0 ||
+			now.Sub(c.lastReport) < time.Duration(cfg.DState.IntervalTracing)*time.Second {
+			continue
+		}
+
+		c.lastReport = now
+		c.report(blocked, now)
+	}
+}
+
+// dstateSamples reads the state of every thread on the host, the context
+// switches of the ones in uninterruptible sleep.
+func dstateSamples() ([]*dstateSample, error) {
+	fs, err := procfs.NewDefaultFS()
+	if err != nil {
+		return nil, err
+	}
+
+	procs, err := fs.AllProcs()
+	if err != nil {
+		return nil, err
+	}
+
+	var samples []*dstateSample
+	for _, p := range procs {
+		threads, err := fs.AllThreads(p.PID)
+		if err != nil {
+			continue
+		}
+
+		for _, t := range threads {
+			stat, err := t.Stat()
+			if err != nil || stat.State != "D" {
+				continue
+			}
+			status, err := t.NewStatus()
+			if err != nil {
+				continue
+			}
+
+			samples = append(samples, &dstateSample{
+				pid:      t.PID,
+				tgid:     p.PID,
+				comm:     stat.Comm,
+				switches: status.TotalCtxtSwitches(),
+			})
+		}
+	}
+
+	return samples, nil
+}
+
+// update tracks the tasks of a scan and returns the ones blocked for the
+// threshold at least, the longest first. A task which switched since the
+// last scan woke up in between, it is blocked again from now.
+func (c *dstateTracing) update(samples []*dstateSample, now time.Time, threshold time.Duration) []*dstateBlocked {
+	blocked := make(map[int]*dstateBlocked, len(samples))
+	for _, s := range samples {
+		if prev, ok := c.blocked[s.pid]; ok && prev.switches == s.switches && prev.tgid == s.tgid {
+			blocked[s.pid] = prev
+			continue
+		}
+		blocked[s.pid] = &dstateBlocked{dstateSample: *s, since: now}
+	}
+	c.blocked = blocked
+
+	var hung []*dstateBlocked
+	for _, b := range blocked {
+		if now.Sub(b.since) >= threshold {
+			hung = append(hung, b)
+		}
+	}
+
+	sort.Slice(hung, func(i, j int) bool {
+		if !hung[i].since.Equal(hung[j].since) {
+			return hung[i].since.Before(hung[j].since)
+		}
+		return hung[i].pid < hung[j].pid
+	})
+	return hung
+}
+
+func (c *dstateTracing) report(blocked []*dstateBlocked, now time.Time) {
+	data := &DStateTracingData{
+		ThresholdSecs: cfg.DState.BlockedThreshold,
+		BlockedTasks:  len(blocked),
+	}
+
+	if len(blocked) > cfg.DState.MaxTasks {
+		blocked = blocked[:cfg.DState.MaxTasks]
+	}
+
+	for _, b := range blocked {
+		task := &dstateTask{
+			Pid:         b.pid,
+			Tgid:        b.tgid,
+			Comm:        b.comm,
+			BlockedSecs: now.Sub(b.since).Seconds(),
+			Stack:       dstateStack(b.tgid, b.pid),
+		}
+
+		// the kernel threads are in the root cgroup.
+		if container, err := pod.ContainerByPid(b.tgid); err == nil && container != nil {
+			task.ContainerID = container.ID
+			task.ContainerHostname = container.Hostname
+		}
+
+		data.Tasks = append(data.Tasks, task)
+	}
+
+	if err := tracing.Save(&tracing.WriteRequest{
+		TracerName: "dstate",
+		TracerTime: now,
+		TracerData: data,
+	}); err != nil {
+		log.Warnf("failed to save tracing data: %v", err)
+	}
+}
+
+// dstateStack returns the kernel stack of the task, or why it was not read.
+func dstateStack(tgid, pid int) string {
+	stack, err := os.ReadFile(procfs.Path(strconv.Itoa(tgid), "task", strconv.Itoa(pid), "stack"))
+	if err != nil {
+		return err.Error()
+	}
+	return strings.TrimSpace(string(stack))
+}
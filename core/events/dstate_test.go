@@ -0,0 +1,65 @@
+// Copyright 2026 The HuaTuo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package events
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDStateUpdate(t *testing.T) {
+	start := time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC)
+	threshold := 2 * time.Minute
+	c := &dstateTracing{blocked: make(map[int]*dstateBlocked)}
+
+	if hung := c.update([]*dstateSample{
+		{pid: 100, tgid: 100, comm: "jbd2/sda1-8", switches: 10},
+		{pid: 201, tgid: 200, comm: "mysqld", switches: 50},
+	}, start, threshold); len(hung) != 0 {
+		t.Fatalf("first scan = %d tasks, want 0", len(hung))
+	}
+
+	// 201 switched, it woke up and blocked again in between.
+	if hung := c.update([]*dstateSample{
+		{pid: 100, tgid: 100, comm: "jbd2/sda1-8", switches: 10},
+		{pid: 201, tgid: 200, comm: "mysqld", switches: 51},
+	}, start.Add(time.Minute), threshold); len(hung) != 0 {
+		t.Fatalf("second scan = %d tasks, want 0", len(hung))
+	}
+
+	now := start.Add(3 * time.Minute)
+	hung := c.update([]*dstateSample{
+		{pid: 100, tgid: 100, comm: "jbd2/sda1-8", switches: 10},
+		{pid: 201, tgid: 200, comm: "mysqld", switches: 51},
+		{pid: 300, tgid: 300, comm: "cat", switches: 1},
+	}, now, threshold)
+	if len(hung) != 2 || hung[0].pid != 100 || now.Sub(hung[0].since) != 3*time.Minute ||
+		hung[1].pid != 201 || now.Sub(hung[1].since) != 2*time.Minute {
+		t.Fatalf("hung = %+v, want pid 100 blocked 3m and 201 blocked 2m", hung)
+	}
+
+	// 100 woke up, the order is the longest blocked first.
+	now = start.Add(4 * time.Minute)
+	hung = c.update([]*dstateSample{
+		{pid: 201, tgid: 200, comm: "mysqld", switches: 51},
+		{pid: 300, tgid: 300, comm: "cat", switches: 1},
+	}, now, time.Minute)
+	if len(hung) != 2 || hung[0].pid != 201 || hung[1].pid != 300 {
+		t.Fatalf("hung = %+v, want pids 201 and 300", hung)
+	}
+	if _, ok := c.blocked[100]; ok {
+		t.Error("the woken task 100 is still tracked")
+	}
+}
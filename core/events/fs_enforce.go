@@ -0,0 +1,278 @@
+// Copyright 2026 The HuaTuo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package events
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"huatuo-bamai/internal/bpf"
+	"huatuo-bamai/internal/log"
+	"huatuo-bamai/internal/pod"
+	"huatuo-bamai/internal/utils/bytesutil"
+	"huatuo-bamai/pkg/tracing"
+	"huatuo-bamai/pkg/types"
+)
+
+//go:generate $BPF_COMPILE $BPF_INCLUDE -s $BPF_DIR/fs_enforce.c -o $BPF_DIR/fs_enforce.o
+
+const (
+	fsEnforceModeAudit   = "audit"
+	fsEnforceModeEnforce = "enforce"
+
+	// the operation mask of fs_enforce.c.
+	fsEnforceOpWrite uint32 = 1
+	fsEnforceOpExec  uint32 = 2
+
+	// FS_ENFORCE_PATH_LEN of fs_enforce.c, a path fills it with its nul.
+	fsEnforcePathLen = 256
+	fsEnforceMaxRule = 256
+
+	fsEnforceLSMPath = "/sys/kernel/security/lsm"
+)
+
+type fsEnforcePerfEvent struct {
+	Path   [fsEnforcePathLen]byte
+	Comm   [bpf.TaskCommLen]byte
+	Pid    uint32
+	Tid    uint32
+	UID    uint32
+	Op     uint32
+	Denied uint32
+}
+
+// FsEnforceTracingData is stored for every operation matching the deny-list.
+type FsEnforceTracingData struct {
+	Pid       uint32 `json:"pid"`
+	Tid       uint32 `json:"tid"`
+	Comm      string `json:"comm"`
+	UID       uint32 `json:"uid"`
+	Path      string `json:"path"`
+	Operation string `json:"operation"`
+	// Action is "denied" in enforce mode and "audited" in audit mode.
+	Action string `json:"action"`
+}
+
+type fsEnforceTracing struct {
+	enforce bool
+	rules   map[string]uint32
+}
+
+func init() {
+	tracing.RegisterEventTracing("fs_enforce", newFsEnforce)
+}
+
+func newFsEnforce() (*tracing.EventTracingAttr, error) {
+	if !cfg.FsEnforce.Enable {
+		return nil, types.ErrNotSupported
+	}
+
+	mode := cfg.FsEnforce.Mode
+	if mode != fsEnforceModeAudit && mode != fsEnforceModeEnforce {
+		return nil, fmt.Errorf("fs_enforce: invalid mode %q", mode)
+	}
+
+	rules, err := fsEnforceRules(cfg.FsEnforce.DenyWrite, cfg.FsEnforce.DenyExec)
+	if err != nil {
+		return nil, err
+	}
+	if len(rules) == 0 {
+		return nil, fmt.Errorf("fs_enforce: no DenyWrite or DenyExec rule")
+	}
+
+	if !bpfLSMEnabled() {
+		log.Warnf("fs_enforce: bpf is not in %s, boot with lsm=...,bpf", fsEnforceLSMPath)
+		return nil, types.ErrNotSupported
+	}
+
+	return &tracing.EventTracingAttr{
+		TracingData: &fsEnforceTracing{
+			enforce: mode == fsEnforceModeEnforce,
+			rules:   rules,
+		},
+		Interval: 10,
+		Flag:     tracing.FlagTracing,
+	}, nil
+}
+
+func bpfLSMEnabled() bool {
+	raw, err := os.ReadFile(fsEnforceLSMPath)
+	if err != nil {
+		return false
+	}
+
+	for _, lsm := range strings.Split(strings.TrimSpace(string(raw)), ",") {
+		if lsm == "bpf" {
+			return true
+		}
+	}
+	return false
+}
+
+// fsEnforceRules maps each path prefix to the mask of the denied operations.
+// The bpf trie only returns the longest matching prefix, so a prefix also
+// carries the operations denied on its shorter prefixes.
+func fsEnforceRules(denyWrite, denyExec []string) (map[string]uint32, error) {
+	rules := make(map[string]uint32)
+
+	add := func(prefixes []string, op uint32) error {
+		for _, prefix := range prefixes {
+			if !strings.HasPrefix(prefix, "/") {
+				return fmt.Errorf("fs_enforce: path %q is not absolute", prefix)
+			}
+			if len(prefix) >= fsEnforcePathLen {
+				return fmt.Errorf("fs_enforce: path %q is longer than %d", prefix, fsEnforcePathLen-1)
+			}
+			rules[prefix] |= op
+		}
+		return nil
+	}
+
+	if err := add(denyWrite, fsEnforceOpWrite); err != nil {
+		return nil, err
+	}
+	if err := add(denyExec, fsEnforceOpExec); err != nil {
+		return nil, err
+	}
+	if len(rules) > fsEnforceMaxRule {
+		return nil, fmt.Errorf("fs_enforce: %d rules, at most %d", len(rules), fsEnforceMaxRule)
+	}
+
+	merged := make(map[string]uint32, len(rules))
+	for prefix, ops := range rules {
+		for shorter, shorterOps := range rules {
+			if strings.HasPrefix(prefix, shorter) {
+				ops |= shorterOps
+			}
+		}
+		merged[prefix] = ops
+	}
+	return merged, nil
+}
+
+// fsEnforceMapItems encodes the rules as struct fs_enforce_key of the trie.
+func fsEnforceMapItems(rules map[string]uint32) []bpf.MapItem {
+	prefixes := make([]string, 0, len(rules))
+	for prefix := range rules {
+		prefixes = append(prefixes, prefix)
+	}
+	sort.Strings(prefixes)
+
+	items := make([]bpf.MapItem, 0, len(prefixes))
+	for _, prefix := range prefixes {
+		key := make([]byte, 4+fsEnforcePathLen)
+		binary.NativeEndian.PutUint32(key, uint32(len(prefix))*8)
+		copy(key[4:], prefix)
+
+		value := make([]byte, 4)
+		binary.NativeEndian.PutUint32(value, rules[prefix])
+		items = append(items, bpf.MapItem{Key: key, Value: value})
+	}
+	return items
+}
+
+func fsEnforceOpName(op uint32) string {
+	switch op {
+	case fsEnforceOpWrite:
+		return "write"
+	case fsEnforceOpExec:
+		return "exec"
+	default:
+		return "unknown"
+	}
+}
+
+func (c *fsEnforceTracing) Start(ctx context.Context) error {
+	var enforce uint8
+	if c.enforce {
+		enforce = 1
+	}
+
+	b, err := bpf.LoadBpf(bpf.ThisBpfOBJ(), map[string]any{"enforce": enforce})
+	if err != nil {
+		return err
+	}
+	defer b.Close()
+
+	mapID := b.MapIDByName("fs_enforce_rules")
+	if mapID == 0 {
+		return fmt.Errorf("bpf map fs_enforce_rules not found")
+	}
+	if err := b.WriteMapItems(mapID, fsEnforceMapItems(c.rules)); err != nil {
+		return fmt.Errorf("write fs_enforce rules: %w", err)
+	}
+
+	if err := b.AttachWithOptions([]bpf.AttachOption{
+		{ProgramName: "fs_enforce_file_open"},
+		{ProgramName: "fs_enforce_bprm_check"},
+	}); err != nil {
+		return err
+	}
+
+	childCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	reader, err := b.EventPipeByName(childCtx, "fs_enforce_events", 8192)
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+
+	b.WaitDetachByBreaker(childCtx, cancel)
+
+	for {
+		select {
+		case <-childCtx.Done():
+			return nil
+		default:
+			var event fsEnforcePerfEvent
+			if err := reader.ReadInto(&event); err != nil {
+				return fmt.Errorf("ReadFromPerfEvent fail: %w", err)
+			}
+
+			data := &FsEnforceTracingData{
+				Pid:       event.Pid,
+				Tid:       event.Tid,
+				Comm:      bytesutil.ToStr(event.Comm[:]),
+				UID:       event.UID,
+				Path:      bytesutil.ToStr(event.Path[:]),
+				Operation: fsEnforceOpName(event.Op),
+				Action:    "audited",
+			}
+			if event.Denied != 0 {
+				data.Action = "denied"
+			}
+
+			containerID := ""
+			if container, err := pod.ContainerByPid(int(event.Pid)); err == nil && container != nil {
+				containerID = container.ID
+			}
+
+			if err := tracing.Save(&tracing.WriteRequest{
+				TracerName:  "fs_enforce",
+				ContainerID: containerID,
+				TracerTime:  time.Now(),
+				TracerData:  data,
+			}); err != nil {
+				log.Warnf("failed to save tracing data: %v", err)
+			}
+		}
+	}
+}
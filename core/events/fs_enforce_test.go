@@ -0,0 +1,73 @@
+// Copyright 2026 The HuaTuo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package events
+
+import (
+	"encoding/binary"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestFsEnforceRules(t *testing.T) {
+	rules, err := fsEnforceRules(
+		[]string{"/proc/sys/", "/sys/kernel/"},
+		[]string{"/proc/sys/kernel/", "/usr/bin/nsenter", "/sys/kernel/"},
+	)
+	if err != nil {
+		t.Fatalf("fsEnforceRules() error = %v", err)
+	}
+
+	want := map[string]uint32{
+		"/proc/sys/": fsEnforceOpWrite,
+		// the longest prefix also denies the writes of /proc/sys/.
+		"/proc/sys/kernel/": fsEnforceOpWrite | fsEnforceOpExec,
+		"/sys/kernel/":      fsEnforceOpWrite | fsEnforceOpExec,
+		"/usr/bin/nsenter":  fsEnforceOpExec,
+	}
+	if !reflect.DeepEqual(rules, want) {
+		t.Errorf("fsEnforceRules() = %v, want %v", rules, want)
+	}
+
+	for _, invalid := range [][]string{
+		{"proc/sys"},
+		{"/" + strings.Repeat("x", fsEnforcePathLen)},
+	} {
+		if _, err := fsEnforceRules(invalid, nil); err == nil {
+			t.Errorf("fsEnforceRules(%q) error = nil", invalid)
+		}
+	}
+}
+
+func TestFsEnforceMapItems(t *testing.T) {
+	items := fsEnforceMapItems(map[string]uint32{"/proc/sys/": fsEnforceOpWrite})
+	if len(items) != 1 {
+		t.Fatalf("got %d items, want 1", len(items))
+	}
+
+	key := items[0].Key
+	if len(key) != 4+fsEnforcePathLen {
+		t.Fatalf("key size = %d, want %d", len(key), 4+fsEnforcePathLen)
+	}
+	if got := binary.NativeEndian.Uint32(key); got != uint32(len("/proc/sys/"))*8 {
+		t.Errorf("prefixlen = %d, want %d", got, len("/proc/sys/")*8)
+	}
+	if got := string(key[4 : 4+len("/proc/sys/")]); got != "/proc/sys/" {
+		t.Errorf("key path = %q", got)
+	}
+	if got := binary.NativeEndian.Uint32(items[0].Value); got != fsEnforceOpWrite {
+		t.Errorf("value = %d, want %d", got, fsEnforceOpWrite)
+	}
+}
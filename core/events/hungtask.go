@@ -55,6 +55,15 @@ type hungTaskTracing struct {
 	nextAllowedTime time.Time
 }
 
+// hungTaskState is checkpointed so that a restart neither resets the counter
+// nor dumps the backtraces of an ongoing hung task storm again at once.
+type hungTaskState struct {
+	Counter         int64     `json:"counter"`
+	NextAllowedTime time.Time `json:"next_allowed_time"`
+}
+
+const hungTaskStateKey = "limiter"
+
 func init() {
 	// OS such as Fedora-42 may disable this feature.
 	if hungTaskTimeout() < 0 {
@@ -62,6 +71,7 @@ func init() {
 	}
 
 	tracing.RegisterEventTracing("hungtask", newHungTask)
+	tracing.RegisterSchema[HungTaskTracerData]("hungtask", "hungtask", 1)
 }
 
 func newHungTask() (*tracing.EventTracingAttr, error) {
@@ -87,7 +97,38 @@ func (c *hungTaskTracing) Update() ([]*metric.Data, error) {
 	return c.data, nil
 }
 
+func (c *hungTaskTracing) restoreState(ctx context.Context) {
+	var state hungTaskState
+	ok, err := tracing.LoadState(ctx, "hungtask", hungTaskStateKey, &state)
+	if err != nil {
+		log.Infof("failed to restore hungtask state: %v", err)
+		return
+	}
+	if !ok {
+		return
+	}
+
+	if state.Counter > atomic.LoadInt64(&hungtaskCounter) {
+		atomic.StoreInt64(&hungtaskCounter, state.Counter)
+	}
+	if state.NextAllowedTime.After(c.nextAllowedTime) {
+		c.nextAllowedTime = state.NextAllowedTime
+	}
+}
+
+func (c *hungTaskTracing) saveState(ctx context.Context) {
+	if err := tracing.SaveState(ctx, "hungtask", hungTaskStateKey, &hungTaskState{
+		Counter:         atomic.LoadInt64(&hungtaskCounter),
+		NextAllowedTime: c.nextAllowedTime,
+	}); err != nil {
+		log.Infof("failed to save hungtask state: %v", err)
+	}
+}
+
 func (c *hungTaskTracing) Start(ctx context.Context) error {
+	c.restoreState(ctx)
+	defer c.saveState(ctx)
+
 	b, err := bpf.LoadBpf(bpf.ThisBpfOBJ(), nil)
 	if err != nil {
 		return err
@@ -147,6 +188,7 @@ func (c *hungTaskTracing) Start(ctx context.Context) error {
 			}); err != nil {
 				log.Warnf("failed to save tracing data: %v", err)
 			}
+			c.saveState(ctx)
 		}
 	}
 }
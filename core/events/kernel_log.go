@@ -0,0 +1,376 @@
+// Copyright 2026 The HuaTuo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package events
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"sync"
+	"time"
+
+	"huatuo-bamai/internal/log"
+	"huatuo-bamai/internal/utils/kmsgutil"
+	"huatuo-bamai/pkg/metric"
+	"huatuo-bamai/pkg/tracing"
+	"huatuo-bamai/pkg/types"
+)
+
+// KernelLogTracerData is the document stored when a record matches a rule.
+type KernelLogTracerData struct {
+	Rule     string            `json:"rule"`
+	Facility string            `json:"facility"`
+	Severity string            `json:"severity"`
+	Sequence uint64            `json:"sequence"`
+	Message  string            `json:"message"`
+	Fields   map[string]string `json:"fields,omitempty"`
+	// Suppressed is the matches of the rule within RuleInterval of the
+	// previous event, not stored.
+	Suppressed uint64 `json:"suppressed"`
+	// Context is the records around the match, the message included,
+	// "<time> <message>" by line.
+	Context []string `json:"context,omitempty"`
+}
+
+// kernelLogBuiltinRules match the kernel failures worth an event on any
+// host, after the configured rules, which override them by name.
+var kernelLogBuiltinRules = []struct {
+	name    string
+	pattern string
+}{
+	{"soft_lockup", `soft lockup - CPU#\d+ stuck`},
+	{"hard_lockup", `Watchdog detected hard LOCKUP`},
+	{"rcu_stall", `rcu.*detected (expedited )?stalls|rcu.*self-detected stall`},
+	{"hung_task", `blocked for more than \d+ seconds`},
+	{"oops", `^(Oops|BUG: unable to handle|general protection fault)|Kernel panic - not syncing`},
+	{"io_error", `I/O error, dev|Buffer I/O error|critical (medium|target) error`},
+	{"nic_reset", `NETDEV WATCHDOG: .* timed out|Detected Tx Unit Hang|[Rr]eset adapter`},
+	{"mce", `\[Hardware Error\]|Machine check events logged`},
+}
+
+// kernelLogContextTimeout is how long an event waits for its context lines
+// after the match, the records following a report come within it.
+const kernelLogContextTimeout = 2 * time.Second
+
+// kernelLogPending is an event waiting for the context lines after its
+// match.
+type kernelLogPending struct {
+	data     *KernelLogTracerData
+	time     time.Time
+	after    int
+	deadline time.Time
+}
+
+// kernelLogRule turns the records matching pattern into events.
+type kernelLogRule struct {
+	name    string
+	pattern *regexp.Regexp
+	// level is the least severe level matched.
+	level int
+
+	matches    uint64
+	suppressed uint64
+	next       time.Time
+}
+
+type kernelLogKey struct {
+	facility string
+	severity string
+}
+
+type kernelLogTracing struct {
+	mu       sync.Mutex
+	messages map[kernelLogKey]uint64
+	rules    []*kernelLogRule
+	tail     *kmsgutil.Tail
+	// recent are the lines before the context of the next match.
+	recent  []string
+	pending []*kernelLogPending
+}
+
+func init() {
+	tracing.RegisterEventTracing("kernel_log", newKernelLog)
+}
+
+func newKernelLog() (*tracing.EventTracingAttr, error) {
+	rules, err := newKernelLogRules()
+	if err != nil {
+		return nil, err
+	}
+
+	return &tracing.EventTracingAttr{
+		TracingData: &kernelLogTracing{
+			messages: make(map[kernelLogKey]uint64),
+			rules:    rules,
+		},
+		Interval: 10,
+		Flag:     tracing.FlagTracing | tracing.FlagMetric,
+	}, nil
+}
+
+func newKernelLogRules() ([]*kernelLogRule, error) {
+	rules := make([]*kernelLogRule, 0, len(cfg.KernelLog.Rules))
+	names := make(map[string]bool, len(cfg.KernelLog.Rules))
+
+	for _, r := range cfg.KernelLog.Rules {
+		if r.Name == "" || names[r.Name] {
+			return nil, fmt.Errorf("kernel log rule %q: empty or duplicated name", r.Name)
+		}
+		names[r.Name] = true
+
+		pattern, err := regexp.Compile(r.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("kernel log rule %s: %w", r.Name, err)
+		}
+
+		level := len(kmsgutil.Severities()) - 1
+		if r.Severity != "" {
+			if level, err = kmsgutil.SeverityLevel(r.Severity); err != nil {
+				return nil, fmt.Errorf("kernel log rule %s: %w", r.Name, err)
+			}
+		}
+
+		rules = append(rules, &kernelLogRule{name: r.Name, pattern: pattern, level: level})
+	}
+
+	if !cfg.KernelLog.BuiltinRules {
+		return rules, nil
+	}
+
+	level := len(kmsgutil.Severities()) - 1
+	for _, r := range kernelLogBuiltinRules {
+		if names[r.name] {
+			continue
+		}
+		rules = append(rules, &kernelLogRule{name: r.name, pattern: regexp.MustCompile(r.pattern), level: level})
+	}
+
+	return rules, nil
+}
+
+// kernelLogLine formats a record as a line of the kernel log.
+func kernelLogLine(rec *kmsgutil.Record) string {
+	return rec.Time.Format("2006-01-02 15:04:05.000000") + " " + rec.Message
+}
+
+// Start tails /dev/kmsg from the records written after it.
+func (c *kernelLogTracing) Start(ctx context.Context) error {
+	tail, err := kmsgutil.NewTail(cfg.KernelLog.MaxMessagesPerSecond)
+	if err != nil {
+		return fmt.Errorf("open kmsg: %w", err)
+	}
+
+	c.mu.Lock()
+	c.tail = tail
+	c.mu.Unlock()
+
+	go func() {
+		<-ctx.Done()
+		tail.Close()
+	}()
+
+	// the events of a report ending the log wait no longer for their
+	// context lines.
+	go func() {
+		ticker := time.NewTicker(time.Second)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case now := <-ticker.C:
+				c.save(c.flush(now))
+			}
+		}
+	}()
+
+	for {
+		rec, err := tail.Next()
+		if err != nil {
+			if ctx.Err() != nil || errors.Is(err, os.ErrClosed) {
+				return types.ErrExitByCancelCtx
+			}
+			return fmt.Errorf("read kmsg: %w", err)
+		}
+
+		c.save(c.handle(rec, time.Now()))
+	}
+}
+
+func (c *kernelLogTracing) save(events []*kernelLogPending) {
+	for _, e := range events {
+		if err := tracing.Save(&tracing.WriteRequest{
+			TracerName: "kernel_log",
+			TracerTime: e.time,
+			TracerData: e.data,
+		}); err != nil {
+			log.Warnf("failed to save tracing data: %v", err)
+		}
+	}
+}
+
+// handle adds the record to the context of the pending events, and returns
+// the events done with their context.
+func (c *kernelLogTracing) handle(rec *kmsgutil.Record, now time.Time) []*kernelLogPending {
+	ready := c.follow(rec)
+
+	data := c.record(rec, now)
+	if data == nil {
+		return ready
+	}
+
+	event := &kernelLogPending{
+		data:     data,
+		time:     rec.Time,
+		deadline: now.Add(kernelLogContextTimeout),
+	}
+	if cfg.KernelLog.ContextLinesAfter <= 0 {
+		return append(ready, event)
+	}
+
+	c.mu.Lock()
+	c.pending = append(c.pending, event)
+	c.mu.Unlock()
+	return ready
+}
+
+// follow adds the record after the match of the pending events, and returns
+// the ones with ContextLinesAfter lines.
+func (c *kernelLogTracing) follow(rec *kmsgutil.Record) []*kernelLogPending {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var ready []*kernelLogPending
+	pending := c.pending[:0]
+	for _, e := range c.pending {
+		e.data.Context = append(e.data.Context, kernelLogLine(rec))
+		if e.after++; e.after >= cfg.KernelLog.ContextLinesAfter {
+			ready = append(ready, e)
+			continue
+		}
+		pending = append(pending, e)
+	}
+	c.pending = pending
+	return ready
+}
+
+// flush returns the pending events past their deadline.
+func (c *kernelLogTracing) flush(now time.Time) []*kernelLogPending {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var ready []*kernelLogPending
+	pending := c.pending[:0]
+	for _, e := range c.pending {
+		if !now.Before(e.deadline) {
+			ready = append(ready, e)
+			continue
+		}
+		pending = append(pending, e)
+	}
+	c.pending = pending
+	return ready
+}
+
+// record counts the record and returns the event of the first rule it
+// matches, nil when it matches none or the rule is within RuleInterval.
+func (c *kernelLogTracing) record(rec *kmsgutil.Record, now time.Time) *KernelLogTracerData {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.messages[kernelLogKey{facility: rec.Facility, severity: rec.Severity}]++
+
+	line := kernelLogLine(rec)
+	defer func() {
+		if before := cfg.KernelLog.ContextLinesBefore; before > 0 {
+			c.recent = append(c.recent, line)
+			if len(c.recent) > before {
+				c.recent = c.recent[len(c.recent)-before:]
+			}
+		}
+	}()
+
+	for _, rule := range c.rules {
+		if rec.Level > rule.level || !rule.pattern.MatchString(rec.Message) {
+			continue
+		}
+
+		rule.matches++
+		if now.Before(rule.next) {
+			rule.suppressed++
+			return nil
+		}
+
+		rule.next = now.Add(time.Duration(cfg.KernelLog.RuleInterval) * time.Second)
+		data := &KernelLogTracerData{
+			Rule:       rule.name,
+			Facility:   rec.Facility,
+			Severity:   rec.Severity,
+			Sequence:   rec.Seq,
+			Message:    rec.Message,
+			Fields:     rec.Fields,
+			Suppressed: rule.suppressed,
+		}
+		if cfg.KernelLog.ContextLinesBefore > 0 || cfg.KernelLog.ContextLinesAfter > 0 {
+			data.Context = append(append(make([]string, 0, len(c.recent)+1+cfg.KernelLog.ContextLinesAfter),
+				c.recent...), line)
+		}
+		rule.suppressed = 0
+		return data
+	}
+
+	return nil
+}
+
+func (c *kernelLogTracing) Update() ([]*metric.Data, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	keys := make([]kernelLogKey, 0, len(c.messages))
+	for k := range c.messages {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].facility != keys[j].facility {
+			return keys[i].facility < keys[j].facility
+		}
+		return keys[i].severity < keys[j].severity
+	})
+
+	data := make([]*metric.Data, 0, len(keys)+len(c.rules)+2)
+	for _, k := range keys {
+		data = append(data, metric.NewCounterData("messages_total", float64(c.messages[k]),
+			"kernel log messages", map[string]string{"facility": k.facility, "severity": k.severity}))
+	}
+
+	for _, rule := range c.rules {
+		data = append(data, metric.NewCounterData("rule_matches_total", float64(rule.matches),
+			"kernel log messages matching a rule", map[string]string{"rule": rule.name}))
+	}
+
+	if c.tail != nil {
+		data = append(data,
+			metric.NewCounterData("messages_dropped_total", float64(c.tail.Dropped()),
+				"kernel log messages not read", map[string]string{"reason": "ratelimit"}),
+			metric.NewCounterData("messages_dropped_total", float64(c.tail.Lost()),
+				"kernel log messages not read", map[string]string{"reason": "overrun"}))
+	}
+
+	return data, nil
+}
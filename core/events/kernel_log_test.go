@@ -0,0 +1,203 @@
+// Copyright 2026 The HuaTuo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package events
+
+import (
+	"testing"
+	"time"
+
+	"huatuo-bamai/internal/utils/kmsgutil"
+)
+
+type kernelLogRuleConfig = struct {
+	Name     string
+	Pattern  string
+	Severity string
+}
+
+func setKernelLogRules(t *testing.T, rules ...kernelLogRuleConfig) {
+	t.Helper()
+
+	orig := cfg
+	t.Cleanup(func() { cfg = orig })
+	cfg = &Config{}
+	cfg.KernelLog.RuleInterval = 60
+	cfg.KernelLog.Rules = rules
+}
+
+func TestNewKernelLogRules(t *testing.T) {
+	tests := []struct {
+		name  string
+		rules []kernelLogRuleConfig
+	}{
+		{name: "empty name", rules: []kernelLogRuleConfig{{Pattern: "error"}}},
+		{name: "duplicated name", rules: []kernelLogRuleConfig{{Name: "a", Pattern: "x"}, {Name: "a", Pattern: "y"}}},
+		{name: "invalid pattern", rules: []kernelLogRuleConfig{{Name: "a", Pattern: "("}}},
+		{name: "unknown severity", rules: []kernelLogRuleConfig{{Name: "a", Pattern: "x", Severity: "fatal"}}},
+	}
+
+	for i := range tests {
+		t.Run(tests[i].name, func(t *testing.T) {
+			setKernelLogRules(t, tests[i].rules...)
+			if _, err := newKernelLogRules(); err == nil {
+				t.Error("newKernelLogRules() error=nil")
+			}
+		})
+	}
+}
+
+func TestKernelLogRecord(t *testing.T) {
+	setKernelLogRules(t,
+		kernelLogRuleConfig{Name: "fs_error", Pattern: `EXT4-fs error`, Severity: "err"},
+		kernelLogRuleConfig{Name: "link_down", Pattern: `NIC Link is Down`},
+	)
+	rules, err := newKernelLogRules()
+	if err != nil {
+		t.Fatalf("newKernelLogRules() error=%v", err)
+	}
+	c := &kernelLogTracing{messages: make(map[kernelLogKey]uint64), rules: rules}
+
+	fsError := &kmsgutil.Record{Facility: "kern", Severity: "err", Level: 3, Seq: 1, Message: "EXT4-fs error (device sda1)"}
+	now := time.Now()
+
+	data := c.record(fsError, now)
+	if data == nil || data.Rule != "fs_error" || data.Suppressed != 0 {
+		t.Fatalf("record()=%+v, want the fs_error event", data)
+	}
+
+	// within RuleInterval, suppressed until the next event.
+	if data := c.record(fsError, now.Add(time.Second)); data != nil {
+		t.Errorf("record() within the interval=%+v, want nil", data)
+	}
+	if data := c.record(fsError, now.Add(61*time.Second)); data == nil || data.Suppressed != 1 {
+		t.Errorf("record() after the interval=%+v, want 1 suppressed", data)
+	}
+
+	// less severe than the rule.
+	notice := &kmsgutil.Record{Facility: "kern", Severity: "notice", Level: 5, Message: "EXT4-fs error (device sda1)"}
+	if data := c.record(notice, now.Add(time.Hour)); data != nil {
+		t.Errorf("record() of a notice=%+v, want nil", data)
+	}
+
+	linkDown := &kmsgutil.Record{Facility: "kern", Severity: "info", Level: 6, Message: "ixgbe eth0: NIC Link is Down"}
+	if data := c.record(linkDown, now); data == nil || data.Rule != "link_down" {
+		t.Errorf("record()=%+v, want the link_down event", data)
+	}
+
+	if got := c.messages[kernelLogKey{facility: "kern", severity: "err"}]; got != 3 {
+		t.Errorf("messages{kern,err}=%d, want 3", got)
+	}
+	if rules[0].matches != 3 || rules[1].matches != 1 {
+		t.Errorf("rule matches=%d, %d, want 3, 1", rules[0].matches, rules[1].matches)
+	}
+
+	metrics, err := c.Update()
+	if err != nil {
+		t.Fatalf("Update() error=%v", err)
+	}
+	// 3 facility and severity pairs, 2 rules.
+	if len(metrics) != 5 {
+		t.Errorf("Update() returned %d metrics, want 5", len(metrics))
+	}
+}
+
+func TestKernelLogBuiltinRules(t *testing.T) {
+	setKernelLogRules(t, kernelLogRuleConfig{Name: "io_error", Pattern: `I/O error, dev sd`})
+	cfg.KernelLog.BuiltinRules = true
+
+	rules, err := newKernelLogRules()
+	if err != nil {
+		t.Fatalf("newKernelLogRules() error=%v", err)
+	}
+	if len(rules) != len(kernelLogBuiltinRules) {
+		t.Fatalf("%d rules, want %d, io_error overridden", len(rules), len(kernelLogBuiltinRules))
+	}
+	c := &kernelLogTracing{messages: make(map[kernelLogKey]uint64), rules: rules}
+
+	for msg, want := range map[string]string{
+		"watchdog: BUG: soft lockup - CPU#3 stuck for 22s! [kworker/3:1:123]":                  "soft_lockup",
+		"rcu: INFO: rcu_sched detected stalls on CPUs/tasks:":                                  "rcu_stall",
+		"INFO: task jbd2/sda1-8:512 blocked for more than 120 seconds.":                        "hung_task",
+		"BUG: unable to handle page fault for address: ffffffffc0a1b2c3":                       "oops",
+		"blk_update_request: I/O error, dev sdb, sector 2048 op 0x0:(READ)":                    "io_error",
+		"NETDEV WATCHDOG: eth0 (ixgbe): transmit queue 3 timed out":                            "nic_reset",
+		"mce: [Hardware Error]: Machine check events logged":                                   "mce",
+		"EXT4-fs (sda1): mounted filesystem with ordered data mode. Opts: (null). Quota mode.": "",
+	} {
+		data := c.record(&kmsgutil.Record{Level: 3, Message: msg}, time.Now())
+		if want == "" {
+			if data != nil {
+				t.Errorf("record(%q)=%+v, want nil", msg, data)
+			}
+			continue
+		}
+		if data == nil || data.Rule != want {
+			t.Errorf("record(%q)=%+v, want %s", msg, data, want)
+		}
+	}
+}
+
+func TestKernelLogContext(t *testing.T) {
+	setKernelLogRules(t, kernelLogRuleConfig{Name: "soft_lockup", Pattern: `soft lockup`})
+	cfg.KernelLog.ContextLinesBefore = 2
+	cfg.KernelLog.ContextLinesAfter = 2
+
+	rules, err := newKernelLogRules()
+	if err != nil {
+		t.Fatalf("newKernelLogRules() error=%v", err)
+	}
+	c := &kernelLogTracing{messages: make(map[kernelLogKey]uint64), rules: rules}
+
+	start := time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC)
+	rec := func(i int, msg string) *kmsgutil.Record {
+		return &kmsgutil.Record{Level: 0, Seq: uint64(i), Time: start.Add(time.Duration(i) * time.Millisecond), Message: msg}
+	}
+
+	for i, msg := range []string{"a", "b", "c"} {
+		if ready := c.handle(rec(i, msg), start); len(ready) != 0 {
+			t.Fatalf("handle(%q)=%d events, want 0", msg, len(ready))
+		}
+	}
+	if ready := c.handle(rec(3, "soft lockup - CPU#0 stuck"), start); len(ready) != 0 {
+		t.Fatalf("handle() of the match=%d events, want 0 waiting for the context", len(ready))
+	}
+	if ready := c.handle(rec(4, "Modules linked in:"), start); len(ready) != 0 {
+		t.Fatalf("handle()=%d events, want 0", len(ready))
+	}
+
+	ready := c.handle(rec(5, "Call Trace:"), start)
+	if len(ready) != 1 {
+		t.Fatalf("handle()=%d events, want 1", len(ready))
+	}
+	want := []string{"b", "c", "soft lockup - CPU#0 stuck", "Modules linked in:", "Call Trace:"}
+	if got := ready[0].data.Context; len(got) != len(want) {
+		t.Fatalf("context=%q, want %q", got, want)
+	} else {
+		for i := range want {
+			if got[i] != kernelLogLine(rec(i+1, want[i])) {
+				t.Errorf("context[%d]=%q, want %q", i, got[i], want[i])
+			}
+		}
+	}
+
+	// the report at the end of the log, saved at its deadline.
+	c.handle(rec(6, "soft lockup - CPU#1 stuck"), start.Add(time.Hour))
+	if ready := c.flush(start.Add(time.Hour + time.Second)); len(ready) != 0 {
+		t.Errorf("flush() before the deadline=%d events, want 0", len(ready))
+	}
+	if ready := c.flush(start.Add(time.Hour + kernelLogContextTimeout)); len(ready) != 1 || len(ready[0].data.Context) != 3 {
+		t.Errorf("flush() at the deadline=%+v, want 1 event with 3 lines", ready)
+	}
+}
@@ -37,18 +37,23 @@ type netdevInfo struct {
 	driver          string
 	driverVersion   string
 	firmwareVersion string
+	// changes are the times of the link status changes within FlapWindow.
+	changes []time.Time
 }
 
 type netdevTracing struct {
 	name                  string
 	linkUpdateCh          chan netlink.LinkUpdate
 	linkDoneCh            chan struct{}
+	deviceMatcher         *matcher.ListMatcher
 	mu                    sync.Mutex
 	netdevInfoStore       map[string]*netdevInfo              // [ifname]ifinfomsg::netdevInfo
 	linkStatusEventCounts map[linkstatus.Types]map[string]int // [netdevEventType][ifname]count
 }
 
-type netdevEventData struct {
+// NetdevTracingData is stored when the admin or the carrier state of a
+// device changes.
+type NetdevTracingData struct {
 	linkFlags       uint32
 	flagsChange     uint32
 	Ifname          string `json:"ifname"`
@@ -59,10 +64,21 @@ type netdevEventData struct {
 	Driver          string `json:"driver"`
 	DriverVersion   string `json:"driver_version"`
 	FirmwareVersion string `json:"firmware_version"`
+	OperState       string `json:"operstate"`
+	MTU             int    `json:"mtu"`
+	// Master is the bond or the bridge of the device.
+	Master string `json:"master,omitempty"`
+	// PrevChangeAt is the last change of the device since the start.
+	PrevChangeAt      time.Time `json:"prev_change_at,omitzero"`
+	SincePrevChangeMs int64     `json:"since_prev_change_ms,omitempty"`
+	// Flaps are the changes within FlapWindowSecs, this one included.
+	Flaps          int   `json:"flaps"`
+	FlapWindowSecs int64 `json:"flap_window_secs"`
 }
 
 func init() {
 	tracing.RegisterEventTracing("netdev_events", newNetdevTracing)
+	tracing.RegisterSchema[NetdevTracingData]("netdev_events", "netdev_events", 1)
 }
 
 func newNetdevTracing() (*tracing.EventTracingAttr, error) {
@@ -159,49 +175,85 @@ func (netdev *netdevTracing) checkAndInitLinkStatus() error {
 	if err != nil {
 		return fmt.Errorf("netdev device list: %w", err)
 	}
+	netdev.deviceMatcher = deviceMatcher
 
 	for _, link := range links {
-		ifname := link.Attrs().Name
-		if !deviceMatcher.Match(ifname) {
-			continue
+		if data := netdev.addLink(eth, link); data != nil {
+			netdev.updateAndSaveEvent(data)
 		}
+	}
 
-		drvInfo, err := eth.DriverInfo(ifname)
-		if err != nil {
-			continue
-		}
+	return nil
+}
 
-		flags := link.Attrs().RawFlags
-		netdev.setInfo(ifname, &netdevInfo{
-			flags:           flags,
-			driver:          drvInfo.Driver,
-			driverVersion:   drvInfo.Version,
-			firmwareVersion: drvInfo.FwVersion,
-		})
-
-		data := &netdevEventData{
-			linkFlags:       flags,
-			Ifname:          ifname,
-			Index:           link.Attrs().Index,
-			Mac:             link.Attrs().HardwareAddr.String(),
-			IsAtStart:       true,
-			Driver:          drvInfo.Driver,
-			DriverVersion:   drvInfo.Version,
-			FirmwareVersion: drvInfo.FwVersion,
-		}
-		netdev.updateAndSaveEvent(data)
+// addLink stores the flags and the driver of a device of the device list
+// as its baseline, nil if it is not monitored.
+func (netdev *netdevTracing) addLink(eth *ethtool.Ethtool, link netlink.Link) *NetdevTracingData {
+	ifname := link.Attrs().Name
+	if !netdev.deviceMatcher.Match(ifname) {
+		return nil
 	}
 
-	return nil
+	drvInfo, err := eth.DriverInfo(ifname)
+	if err != nil {
+		return nil
+	}
+
+	flags := link.Attrs().RawFlags
+	netdev.setInfo(ifname, &netdevInfo{
+		flags:           flags,
+		driver:          drvInfo.Driver,
+		driverVersion:   drvInfo.Version,
+		firmwareVersion: drvInfo.FwVersion,
+	})
+
+	return &NetdevTracingData{
+		linkFlags:       flags,
+		Ifname:          ifname,
+		Index:           link.Attrs().Index,
+		Mac:             link.Attrs().HardwareAddr.String(),
+		IsAtStart:       true,
+		Driver:          drvInfo.Driver,
+		DriverVersion:   drvInfo.Version,
+		FirmwareVersion: drvInfo.FwVersion,
+	}
 }
 
-func (netdev *netdevTracing) updateAndSaveEvent(data *netdevEventData) {
+// recordChange records a change of the device at now, and returns its last
+// change, zero if none, and its changes within the window, this one
+// included.
+func (info *netdevInfo) recordChange(now time.Time, window time.Duration) (time.Time, int) {
+	var prev time.Time
+	if n := len(info.changes); n > 0 {
+		prev = info.changes[n-1]
+	}
+
+	changes := info.changes[:0]
+	for _, t := range info.changes {
+		if now.Sub(t) < window {
+			changes = append(changes, t)
+		}
+	}
+	info.changes = append(changes, now)
+	return prev, len(info.changes)
+}
+
+func (netdev *netdevTracing) updateAndSaveEvent(data *NetdevTracingData) {
 	changed := linkstatus.Changed(data.linkFlags, data.flagsChange)
+	now := time.Now()
 
 	netdev.mu.Lock()
 	for _, status := range changed {
 		netdev.linkStatusEventCounts[status][data.Ifname]++
 	}
+	if info, ok := netdev.netdevInfoStore[data.Ifname]; ok && len(changed) > 0 && !data.IsAtStart {
+		window := time.Duration(cfg.Netdev.FlapWindow) * time.Second
+		data.PrevChangeAt, data.Flaps = info.recordChange(now, window)
+		data.FlapWindowSecs = cfg.Netdev.FlapWindow
+		if !data.PrevChangeAt.IsZero() {
+			data.SincePrevChangeMs = now.Sub(data.PrevChangeAt).Milliseconds()
+		}
+	}
 	netdev.mu.Unlock()
 
 	for _, status := range changed {
@@ -216,7 +268,7 @@ func (netdev *netdevTracing) updateAndSaveEvent(data *netdevEventData) {
 		log.Infof("%s %+v", data.LinkStatus, data)
 		if err := tracing.Save(&tracing.WriteRequest{
 			TracerName: netdev.name,
-			TracerTime: time.Now(),
+			TracerTime: now,
 			TracerData: data,
 		}); err != nil {
 			log.Warnf("failed to save tracing data: %v", err)
@@ -252,11 +304,27 @@ func (netdev *netdevTracing) handleEvent(ev *netlink.LinkUpdate) {
 
 	oldFlags, driverInfo, ok := netdev.loadAndSwapFlags(ifname, currFlags)
 	if !ok {
+		// a device created after the start, e.g. a vf or a bond.
+		eth, err := ethtool.NewEthtool()
+		if err != nil {
+			log.Debugf("ethtool: %v", err)
+			return
+		}
+		defer eth.Close()
+
+		netdev.addLink(eth, ev.Link)
 		return
 	}
 	change := currFlags ^ oldFlags
 
-	data := &netdevEventData{
+	master := ""
+	if index := ev.Link.Attrs().MasterIndex; index > 0 {
+		if link, err := netlink.LinkByIndex(index); err == nil {
+			master = link.Attrs().Name
+		}
+	}
+
+	data := &NetdevTracingData{
 		linkFlags:       currFlags,
 		flagsChange:     change,
 		Ifname:          ifname,
@@ -266,6 +334,9 @@ func (netdev *netdevTracing) handleEvent(ev *netlink.LinkUpdate) {
 		Driver:          driverInfo.driver,
 		DriverVersion:   driverInfo.driverVersion,
 		FirmwareVersion: driverInfo.firmwareVersion,
+		OperState:       ev.Link.Attrs().OperState.String(),
+		MTU:             ev.Link.Attrs().MTU,
+		Master:          master,
 	}
 	netdev.updateAndSaveEvent(data)
 }
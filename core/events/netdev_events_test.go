@@ -0,0 +1,46 @@
+// Copyright 2026 The HuaTuo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package events
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNetdevRecordChange(t *testing.T) {
+	window := 5 * time.Minute
+	start := time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC)
+	info := &netdevInfo{}
+
+	prev, flaps := info.recordChange(start, window)
+	if !prev.IsZero() || flaps != 1 {
+		t.Errorf("first recordChange() = %v, %d, want zero, 1", prev, flaps)
+	}
+
+	prev, flaps = info.recordChange(start.Add(10*time.Second), window)
+	if !prev.Equal(start) || flaps != 2 {
+		t.Errorf("recordChange() = %v, %d, want %v, 2", prev, flaps, start)
+	}
+
+	// the first change is out of the window, the last one is still reported.
+	now := start.Add(6 * time.Minute)
+	prev, flaps = info.recordChange(now, window)
+	if !prev.Equal(start.Add(10*time.Second)) || flaps != 1 {
+		t.Errorf("recordChange() = %v, %d, want %v, 1", prev, flaps, start.Add(10*time.Second))
+	}
+	if len(info.changes) != 1 || !info.changes[0].Equal(now) {
+		t.Errorf("changes = %v, want [%v]", info.changes, now)
+	}
+}
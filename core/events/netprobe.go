@@ -0,0 +1,323 @@
+// Copyright 2026 The HuaTuo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package events
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"sync"
+	"time"
+
+	"huatuo-bamai/internal/log"
+	"huatuo-bamai/pkg/metric"
+	"huatuo-bamai/pkg/tracing"
+	"huatuo-bamai/pkg/types"
+)
+
+const (
+	netProbeICMP = "icmp"
+	netProbeTCP  = "tcp"
+
+	icmpEchoRequest = 8
+	icmpEchoReply   = 0
+)
+
+// NetProbeTracerData is the document stored when a target starts failing.
+type NetProbeTracerData struct {
+	Target   string  `json:"target" validate:"required"`
+	Protocol string  `json:"protocol" validate:"required"`
+	Address  string  `json:"address" validate:"required"`
+	Sent     int     `json:"sent" validate:"gte=1"`
+	Received int     `json:"received" validate:"gte=0,ltefield=Sent"`
+	Loss     float64 `json:"loss" validate:"gte=0,lte=1"`
+	Error    string  `json:"error,omitempty"`
+	// dropwatch events seen while the failing round ran, non-zero hints the
+	// kernel dropped the probes rather than the remote being down.
+	KernelDrops uint64 `json:"kernel_drops"`
+}
+
+type netProbeTarget struct {
+	name     string
+	protocol string
+	address  string
+}
+
+type netProbeResult struct {
+	up       bool
+	latency  time.Duration
+	loss     float64
+	failures uint64
+}
+
+type netProbeTracing struct {
+	targets []netProbeTarget
+	mu      sync.Mutex
+	results map[string]*netProbeResult
+	seq     uint16
+}
+
+func init() {
+	tracing.RegisterEventTracing("netprobe", newNetProbe)
+	tracing.RegisterSchema[NetProbeTracerData]("netprobe", "netprobe", 1)
+}
+
+func newNetProbe() (*tracing.EventTracingAttr, error) {
+	if len(cfg.NetProbe.Targets) == 0 {
+		return nil, types.ErrNotSupported
+	}
+
+	targets := make([]netProbeTarget, 0, len(cfg.NetProbe.Targets))
+	for _, t := range cfg.NetProbe.Targets {
+		target, err := parseNetProbeTarget(t)
+		if err != nil {
+			return nil, err
+		}
+		targets = append(targets, target)
+	}
+
+	return &tracing.EventTracingAttr{
+		TracingData: &netProbeTracing{
+			targets: targets,
+			results: make(map[string]*netProbeResult, len(targets)),
+		},
+		Interval: 10,
+		Flag:     tracing.FlagTracing | tracing.FlagMetric,
+	}, nil
+}
+
+// parseNetProbeTarget accepts icmp://host and tcp://host:port.
+func parseNetProbeTarget(s string) (netProbeTarget, error) {
+	u, err := url.Parse(s)
+	if err != nil {
+		return netProbeTarget{}, fmt.Errorf("netprobe target %q: %w", s, err)
+	}
+
+	switch u.Scheme {
+	case netProbeICMP:
+		if u.Host == "" {
+			return netProbeTarget{}, fmt.Errorf("netprobe target %q: missing host", s)
+		}
+	case netProbeTCP:
+		if _, _, err := net.SplitHostPort(u.Host); err != nil {
+			return netProbeTarget{}, fmt.Errorf("netprobe target %q: %w", s, err)
+		}
+	default:
+		return netProbeTarget{}, fmt.Errorf("netprobe target %q: unsupported protocol %q", s, u.Scheme)
+	}
+
+	return netProbeTarget{name: s, protocol: u.Scheme, address: u.Host}, nil
+}
+
+func (c *netProbeTracing) Start(ctx context.Context) error {
+	interval := time.Duration(cfg.NetProbe.Interval) * time.Second
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		for _, t := range c.targets {
+			c.probe(ctx, t)
+		}
+
+		select {
+		case <-ctx.Done():
+			return types.ErrExitByCancelCtx
+		case <-ticker.C:
+		}
+	}
+}
+
+// netProbeLoss returns the loss ratio of a round, and whether the target is
+// up, losing less than threshold percent of the probes.
+func netProbeLoss(sent, received int, threshold uint) (float64, bool) {
+	loss := 1 - float64(received)/float64(sent)
+	return loss, loss*100 < float64(threshold)
+}
+
+func (c *netProbeTracing) probe(ctx context.Context, t netProbeTarget) {
+	timeout := time.Duration(cfg.NetProbe.Timeout) * time.Second
+	drops := dropwatchEventsTotal.Load()
+
+	var (
+		received int
+		rttSum   time.Duration
+		lastErr  error
+	)
+	for i := 0; i < cfg.NetProbe.Count && ctx.Err() == nil; i++ {
+		rtt, err := c.probeOnce(t, timeout)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		received++
+		rttSum += rtt
+	}
+
+	loss, up := netProbeLoss(cfg.NetProbe.Count, received, cfg.NetProbe.LossThreshold)
+
+	c.mu.Lock()
+	res, ok := c.results[t.name]
+	if !ok {
+		// start as up so a target down at startup still raises an event.
+		res = &netProbeResult{up: true}
+		c.results[t.name] = res
+	}
+	wasUp := res.up
+	res.up = up
+	res.loss = loss
+	res.latency = 0
+	if received > 0 {
+		res.latency = rttSum / time.Duration(received)
+	}
+	if !up {
+		res.failures++
+	}
+	c.mu.Unlock()
+
+	if up || !wasUp {
+		return
+	}
+
+	data := &NetProbeTracerData{
+		Target:      t.name,
+		Protocol:    t.protocol,
+		Address:     t.address,
+		Sent:        cfg.NetProbe.Count,
+		Received:    received,
+		Loss:        loss,
+		KernelDrops: dropwatchEventsTotal.Load() - drops,
+	}
+	if lastErr != nil {
+		data.Error = lastErr.Error()
+	}
+
+	if err := tracing.Save(&tracing.WriteRequest{
+		TracerName: "netprobe",
+		TracerTime: time.Now(),
+		TracerData: data,
+	}); err != nil {
+		log.Warnf("failed to save tracing data: %v", err)
+	}
+}
+
+func (c *netProbeTracing) probeOnce(t netProbeTarget, timeout time.Duration) (time.Duration, error) {
+	if t.protocol == netProbeTCP {
+		start := time.Now()
+		conn, err := net.DialTimeout("tcp", t.address, timeout)
+		if err != nil {
+			return 0, err
+		}
+		rtt := time.Since(start)
+		conn.Close()
+		return rtt, nil
+	}
+
+	c.seq++
+	return icmpEcho(t.address, c.seq, timeout)
+}
+
+// icmpEcho sends a single ICMPv4 echo request over a raw socket and waits for
+// the matching reply.
+func icmpEcho(host string, seq uint16, timeout time.Duration) (time.Duration, error) {
+	dst, err := net.ResolveIPAddr("ip4", host)
+	if err != nil {
+		return 0, err
+	}
+
+	conn, err := net.ListenPacket("ip4:icmp", "0.0.0.0")
+	if err != nil {
+		return 0, err
+	}
+	defer conn.Close()
+
+	id := uint16(os.Getpid())
+	req := make([]byte, 16)
+	req[0] = icmpEchoRequest
+	binary.BigEndian.PutUint16(req[4:], id)
+	binary.BigEndian.PutUint16(req[6:], seq)
+	copy(req[8:], "huatuo!!")
+	binary.BigEndian.PutUint16(req[2:], icmpChecksum(req))
+
+	start := time.Now()
+	if err := conn.SetDeadline(start.Add(timeout)); err != nil {
+		return 0, err
+	}
+	if _, err := conn.WriteTo(req, dst); err != nil {
+		return 0, err
+	}
+
+	buf := make([]byte, 1500)
+	for {
+		n, peer, err := conn.ReadFrom(buf)
+		if err != nil {
+			return 0, err
+		}
+
+		// every raw icmp socket sees all replies, skip those of other probes.
+		if n < 8 || buf[0] != icmpEchoReply || !peer.(*net.IPAddr).IP.Equal(dst.IP) {
+			continue
+		}
+		if binary.BigEndian.Uint16(buf[4:]) != id || binary.BigEndian.Uint16(buf[6:]) != seq {
+			continue
+		}
+
+		return time.Since(start), nil
+	}
+}
+
+func icmpChecksum(b []byte) uint16 {
+	var sum uint32
+	for i := 0; i+1 < len(b); i += 2 {
+		sum += uint32(b[i])<<8 | uint32(b[i+1])
+	}
+	if len(b)%2 == 1 {
+		sum += uint32(b[len(b)-1]) << 8
+	}
+	for sum>>16 != 0 {
+		sum = sum&0xffff + sum>>16
+	}
+
+	return ^uint16(sum)
+}
+
+func (c *netProbeTracing) Update() ([]*metric.Data, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	data := make([]*metric.Data, 0, 4*len(c.results))
+	for _, t := range c.targets {
+		res, ok := c.results[t.name]
+		if !ok {
+			continue
+		}
+
+		up := 0.0
+		if res.up {
+			up = 1
+		}
+
+		label := map[string]string{"target": t.name, "protocol": t.protocol}
+		data = append(data,
+			metric.NewGaugeData("up", up, "whether the target answered within the loss threshold", label),
+			metric.NewGaugeData("latency_seconds", res.latency.Seconds(), "average probe round trip time", label),
+			metric.NewGaugeData("loss_ratio", res.loss, "ratio of lost probes in the last round", label),
+			metric.NewCounterData("failures_total", float64(res.failures), "probe rounds over the loss threshold", label))
+	}
+
+	return data, nil
+}
@@ -0,0 +1,45 @@
+// Copyright 2026 The HuaTuo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package events
+
+import (
+	"testing"
+)
+
+func TestNetProbeLoss(t *testing.T) {
+	for _, tt := range []struct {
+		name      string
+		sent      int
+		received  int
+		threshold uint
+		loss      float64
+		up        bool
+	}{
+		{"all received", 3, 3, 100, 0, true},
+		{"all lost", 3, 0, 100, 1, false},
+		{"some lost", 4, 3, 100, 0.25, true},
+		{"loss at the threshold", 4, 2, 50, 0.5, false},
+		{"loss below the threshold", 4, 3, 50, 0.25, true},
+		{"threshold 0", 1, 1, 0, 0, false},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			loss, up := netProbeLoss(tt.sent, tt.received, tt.threshold)
+			if loss != tt.loss || up != tt.up {
+				t.Errorf("netProbeLoss(%d, %d, %d) = %v, %v, want %v, %v",
+					tt.sent, tt.received, tt.threshold, loss, up, tt.loss, tt.up)
+			}
+		})
+	}
+}
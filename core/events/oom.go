@@ -17,6 +17,7 @@ package events
 import (
 	"context"
 	"fmt"
+	"os"
 	"sync"
 	"time"
 
@@ -27,6 +28,7 @@ import (
 	"huatuo-bamai/internal/pod"
 	"huatuo-bamai/internal/utils/bytesutil"
 	"huatuo-bamai/internal/utils/kernaddr"
+	"huatuo-bamai/internal/utils/kmsgutil"
 	"huatuo-bamai/pkg/metric"
 	"huatuo-bamai/pkg/tracing"
 )
@@ -41,6 +43,13 @@ type perfEventData struct {
 	VictimPid       int32
 	TriggerMemcgCSS uint64
 	VictimMemcgCSS  uint64
+	// MemLimitPages are the pages of the oom domain, of the memcg or the
+	// host, MemUsagePages of the memcg.
+	MemLimitPages     uint64
+	MemUsagePages     uint64
+	OOMMemcgCSS       uint64
+	VictimOOMScoreAdj int32
+	Pad               uint32
 }
 
 type OOMActor struct {
@@ -50,12 +59,36 @@ type OOMActor struct {
 	Pid                 int32                    `json:"pid"`
 	Comm                string                   `json:"comm"`
 	Cgroup              *OOMCgroupMemorySnapshot `json:"cgroup,omitempty"`
+	// OOMScoreAdj is of the victim.
+	OOMScoreAdj *int32 `json:"oom_score_adj,omitempty"`
 }
 
+// OOMMemcg is the memory cgroup which hit its limit.
+type OOMMemcg struct {
+	MemoryCgroupCSSAddr string `json:"memory_cgroup_css_addr"`
+	ContainerID         string `json:"container_id,omitempty"`
+	ContainerHostname   string `json:"container_hostname,omitempty"`
+	LimitBytes          uint64 `json:"limit_bytes"`
+	UsageBytes          uint64 `json:"usage_bytes"`
+}
+
+// The constraints of the oom kills.
+const (
+	oomConstraintGlobal = "global"
+	oomConstraintMemcg  = "memcg"
+)
+
 type OOMTracingData struct {
-	Trigger        OOMActor           `json:"trigger"`
-	Victim         OOMActor           `json:"victim"`
+	Trigger OOMActor `json:"trigger"`
+	Victim  OOMActor `json:"victim"`
+	// Constraint is memcg when Memcg hit its limit, global when the host
+	// ran out of memory.
+	Constraint     string             `json:"constraint" validate:"oneof=global memcg"`
+	Memcg          *OOMMemcg          `json:"memcg,omitempty"`
 	MemorySnapshot *OOMMemorySnapshot `json:"memory_snapshot,omitempty"`
+	// KernelLog is the tail of the kernel log, the oom report of the
+	// kernel, "<time> <message>" by line.
+	KernelLog []string `json:"kernel_log,omitempty"`
 }
 
 type oomMetric struct {
@@ -75,6 +108,7 @@ var (
 
 func init() {
 	tracing.RegisterEventTracing("oom", newOOMCollector)
+	tracing.RegisterSchema[OOMTracingData]("oom", "oom", 1)
 }
 
 func newOOMCollector() (*tracing.EventTracingAttr, error) {
@@ -134,6 +168,17 @@ func (c *oomCollector) Start(ctx context.Context) error {
 
 	b.WaitDetachByBreaker(childCtx, cancel)
 
+	// the kernel prints its oom report after the event of the kprobe, the
+	// log is read once it is done, off the reader.
+	pending := make(chan oomPending, oomPendingMax)
+	if cfg.OOM.KernelLogLines > 0 {
+		go oomFlushKernelLog(childCtx, pending, time.Duration(cfg.OOM.KernelLogDelay)*time.Millisecond,
+			func(p oomPending) {
+				p.data.KernelLog = oomKernelLog()
+				oomSave(p.data, p.now)
+			})
+	}
+
 	for {
 		select {
 		case <-childCtx.Done():
@@ -149,6 +194,7 @@ func (c *oomCollector) Start(ctx context.Context) error {
 				return fmt.Errorf("failed to fetch containers: %w", err)
 			}
 
+			now := time.Now()
 			oomData := buildTracingData(data, containers, c.cgroup)
 
 			mutex.Lock()
@@ -161,15 +207,64 @@ func (c *oomCollector) Start(ctx context.Context) error {
 
 			mutex.Unlock()
 
-			if err := tracing.Save(&tracing.WriteRequest{
-				TracerName:  "oom",
-				TracerTime:  time.Now(),
-				TracerData:  oomData,
-				ContainerID: oomData.Victim.ContainerID,
-			}); err != nil {
-				log.Warnf("failed to save tracing data: %v", err)
+			if cfg.OOM.KernelLogLines <= 0 {
+				oomSave(oomData, now)
+				continue
+			}
+
+			select {
+			case pending <- oomPending{data: oomData, now: now}:
+			default:
+				// an oom storm, the flusher is behind.
+				oomSave(oomData, now)
+			}
+		}
+	}
+}
+
+// oomPendingMax bounds the oom events waiting for their kernel log, the
+// ones beyond it are saved without.
+const oomPendingMax = 64
+
+type oomPending struct {
+	data *OOMTracingData
+	now  time.Time
+}
+
+// oomFlushKernelLog passes the pending events to flush in order, each delay
+// after the oom. The events still pending when ctx is done are dropped.
+func oomFlushKernelLog(ctx context.Context, pending <-chan oomPending, delay time.Duration, flush func(oomPending)) {
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	for {
+		var p oomPending
+		select {
+		case <-ctx.Done():
+			return
+		case p = <-pending:
+		}
+
+		if wait := time.Until(p.now.Add(delay)); wait > 0 {
+			timer.Reset(wait)
+			select {
+			case <-ctx.Done():
+				return
+			case <-timer.C:
 			}
 		}
+		flush(p)
+	}
+}
+
+func oomSave(oomData *OOMTracingData, now time.Time) {
+	if err := tracing.Save(&tracing.WriteRequest{
+		TracerName:  "oom",
+		TracerTime:  now,
+		TracerData:  oomData,
+		ContainerID: oomData.Victim.ContainerID,
+	}); err != nil {
+		log.Warnf("failed to save tracing data: %v", err)
 	}
 }
 
@@ -191,7 +286,24 @@ func buildTracingData(data perfEventData, containers map[string]*pod.Container,
 			ContainerID:         victimID,
 			Pid:                 data.VictimPid,
 			Comm:                bytesutil.ToStr(data.VictimComm[:]),
+			OOMScoreAdj:         &data.VictimOOMScoreAdj,
 		},
+		Constraint: oomConstraintGlobal,
+	}
+
+	if data.OOMMemcgCSS != 0 {
+		pageSize := uint64(os.Getpagesize())
+		memcgID := cssContainers[data.OOMMemcgCSS]
+		oomData.Constraint = oomConstraintMemcg
+		oomData.Memcg = &OOMMemcg{
+			MemoryCgroupCSSAddr: kernaddr.Format(data.OOMMemcgCSS),
+			ContainerID:         memcgID,
+			LimitBytes:          data.MemLimitPages * pageSize,
+			UsageBytes:          data.MemUsagePages * pageSize,
+		}
+		if container, ok := containers[memcgID]; ok {
+			oomData.Memcg.ContainerHostname = container.Hostname
+		}
 	}
 
 	if container, ok := containers[triggerID]; ok {
@@ -220,6 +332,21 @@ func buildTracingData(data perfEventData, containers map[string]*pod.Container,
 	return oomData
 }
 
+// oomKernelLog returns the last KernelLogLines lines of the kernel log.
+func oomKernelLog() []string {
+	records, err := kmsgutil.Last(cfg.OOM.KernelLogLines)
+	if err != nil {
+		log.Warnf("failed to read the kernel log: %v", err)
+		return nil
+	}
+
+	lines := make([]string, 0, len(records))
+	for _, rec := range records {
+		lines = append(lines, kernelLogLine(rec))
+	}
+	return lines
+}
+
 func containerCounterUpdate(containerID, comm string) {
 	if val, exists := outOfMemoryCounterContainer[containerID]; exists {
 		val.count++
@@ -0,0 +1,101 @@
+// Copyright 2026 The HuaTuo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package events
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"huatuo-bamai/internal/cgroups/subsystem"
+	"huatuo-bamai/internal/pod"
+)
+
+func TestBuildTracingDataConstraint(t *testing.T) {
+	containers := map[string]*pod.Container{
+		"c1": {
+			ID:        "c1",
+			Hostname:  "web-0",
+			CgroupCss: map[string]uint64{subsystem.SubsystemMemory: 0x1000},
+		},
+	}
+
+	// the processes of the host, the limit of a parent memcg hit.
+	data := perfEventData{
+		TriggerMemcgCSS:   0x2000,
+		VictimMemcgCSS:    0x2000,
+		MemLimitPages:     256,
+		MemUsagePages:     255,
+		OOMMemcgCSS:       0x1000,
+		VictimOOMScoreAdj: -998,
+	}
+	oomData := buildTracingData(data, containers, nil)
+	if oomData.Constraint != oomConstraintMemcg || oomData.Memcg == nil {
+		t.Fatalf("constraint = %q, memcg = %+v", oomData.Constraint, oomData.Memcg)
+	}
+	pageSize := uint64(os.Getpagesize())
+	if m := oomData.Memcg; m.ContainerID != "c1" || m.ContainerHostname != "web-0" ||
+		m.LimitBytes != 256*pageSize || m.UsageBytes != 255*pageSize {
+		t.Errorf("memcg = %+v", m)
+	}
+	if adj := oomData.Victim.OOMScoreAdj; adj == nil || *adj != -998 {
+		t.Errorf("victim oom_score_adj = %v, want -998", adj)
+	}
+	if oomData.Trigger.OOMScoreAdj != nil {
+		t.Errorf("trigger oom_score_adj = %v, want nil", *oomData.Trigger.OOMScoreAdj)
+	}
+
+	data.OOMMemcgCSS = 0
+	if oomData := buildTracingData(data, containers, nil); oomData.Constraint != oomConstraintGlobal || oomData.Memcg != nil {
+		t.Errorf("constraint = %q, memcg = %+v, want global", oomData.Constraint, oomData.Memcg)
+	}
+}
+
+func TestOOMFlushKernelLog(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	pending := make(chan oomPending, 2)
+	flushed := make(chan oomPending, 2)
+	done := make(chan struct{})
+	delay := 50 * time.Millisecond
+	go func() {
+		oomFlushKernelLog(ctx, pending, delay, func(p oomPending) { flushed <- p })
+		close(done)
+	}()
+
+	// an old event is flushed at once, a new one after the delay.
+	start := time.Now()
+	pending <- oomPending{data: &OOMTracingData{}, now: start.Add(-time.Second)}
+	pending <- oomPending{data: &OOMTracingData{}, now: start}
+	<-flushed
+	<-flushed
+	if elapsed := time.Since(start); elapsed < delay {
+		t.Errorf("flushed after %v, want at least %v", elapsed, delay)
+	}
+
+	// shutdown does not wait for the pending events.
+	pending <- oomPending{data: &OOMTracingData{}, now: time.Now().Add(time.Hour)}
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("oomFlushKernelLog did not return on cancel")
+	}
+	if len(flushed) != 0 {
+		t.Errorf("flushed %d events after cancel, want 0", len(flushed))
+	}
+}
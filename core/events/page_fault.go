@@ -0,0 +1,291 @@
+// Copyright 2026 The HuaTuo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package events
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"huatuo-bamai/internal/bpf"
+	"huatuo-bamai/internal/cgroups"
+	"huatuo-bamai/internal/cgroups/subsystem"
+	"huatuo-bamai/internal/log"
+	"huatuo-bamai/internal/pod"
+	"huatuo-bamai/internal/symbol"
+	"huatuo-bamai/internal/utils/bytesutil"
+	"huatuo-bamai/pkg/metric"
+	"huatuo-bamai/pkg/tracing"
+	"huatuo-bamai/pkg/types"
+)
+
+//go:generate $BPF_COMPILE $BPF_INCLUDE -s $BPF_DIR/page_fault.c -o $BPF_DIR/page_fault.o
+
+// stacks kept per spike event.
+const pageFaultMaxStacks = 10
+
+type pageFaultPerfEvent struct {
+	Stack     [symbol.KsymStackMaxDepth]uint64
+	StackSize int64
+	Comm      [bpf.TaskCommLen]byte
+	Pid       uint32
+	Tid       uint32
+}
+
+// PageFaultTracingData is stored when a container's major fault rate spikes.
+type PageFaultTracingData struct {
+	Rate                float64           `json:"rate"`
+	Threshold           uint64            `json:"threshold"`
+	ContainerAgeSeconds int64             `json:"container_age_seconds"`
+	ColdStart           bool              `json:"cold_start"`
+	MemoryUsage         uint64            `json:"memory_usage"`
+	MemoryLimit         uint64            `json:"memory_limit"`
+	Samples             int               `json:"samples"`
+	Stacks              []*pageFaultStack `json:"stacks"`
+}
+
+type pageFaultStack struct {
+	Comm  string `json:"comm"`
+	Count int    `json:"count"`
+	Stack string `json:"stack"`
+}
+
+type pageFaultContainer struct {
+	majorFaults  uint64
+	rate         float64
+	threshold    uint64
+	lastSampled  time.Time
+	lastSampleAt time.Time
+	container    *pod.Container
+}
+
+type pageFaultTracing struct {
+	cgroupMgr  cgroups.Cgroup
+	usym       *symbol.UsymResolver
+	mu         sync.Mutex
+	containers map[string]*pageFaultContainer
+}
+
+func init() {
+	tracing.RegisterEventTracing("page_fault", newPageFault)
+}
+
+func newPageFault() (*tracing.EventTracingAttr, error) {
+	cgroup, err := cgroups.NewManager()
+	if err != nil {
+		return nil, err
+	}
+
+	return &tracing.EventTracingAttr{
+		TracingData: &pageFaultTracing{
+			cgroupMgr:  cgroup,
+			usym:       symbol.NewUsymResolver(),
+			containers: make(map[string]*pageFaultContainer),
+		},
+		Interval: 10,
+		Flag:     tracing.FlagTracing | tracing.FlagMetric,
+	}, nil
+}
+
+// Start polls the cheap memory.stat counters and only loads the bpf program
+// for a short while once a container crosses the rate threshold.
+func (c *pageFaultTracing) Start(ctx context.Context) error {
+	ticker := time.NewTicker(time.Duration(cfg.PageFault.Interval) * time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return types.ErrExitByCancelCtx
+		case <-ticker.C:
+		}
+
+		for _, pc := range c.updateRates() {
+			if err := c.sample(ctx, pc); err != nil {
+				if errors.Is(err, types.ErrExitByCancelCtx) {
+					return err
+				}
+				log.Warnf("page_fault sample %s: %v", pc.container, err)
+			}
+		}
+	}
+}
+
+// updateRates refreshes the major fault counters and returns the containers
+// that spiked and are out of their backoff.
+func (c *pageFaultTracing) updateRates() []*pageFaultContainer {
+	containers, err := pod.NormalContainers()
+	if err != nil {
+		log.Debugf("page_fault list containers: %v", err)
+		return nil
+	}
+
+	return c.updateContainers(containers, time.Now())
+}
+
+// updateContainers reads the major faults of the containers polled at now.
+func (c *pageFaultTracing) updateContainers(containers map[string]*pod.Container, now time.Time) []*pageFaultContainer {
+	backoff := time.Duration(cfg.PageFault.IntervalTracing) * time.Second
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for id := range c.containers {
+		if _, ok := containers[id]; !ok {
+			delete(c.containers, id)
+		}
+	}
+
+	var spiked []*pageFaultContainer
+	for id, container := range containers {
+		raw, err := c.cgroupMgr.MemoryStatRaw(container.CgroupPath)
+		if err != nil {
+			log.Debugf("page_fault read memory.stat %s: %v", container, err)
+			continue
+		}
+
+		// hierarchical on cgroup v1, always hierarchical on v2.
+		faults, ok := raw["total_pgmajfault"]
+		if !ok {
+			faults = raw["pgmajfault"]
+		}
+
+		pc, ok := c.containers[id]
+		if !ok {
+			c.containers[id] = &pageFaultContainer{majorFaults: faults, lastSampleAt: now, container: container}
+			continue
+		}
+
+		pc.rate = 0
+		if elapsed := now.Sub(pc.lastSampleAt).Seconds(); faults >= pc.majorFaults && elapsed > 0 {
+			pc.rate = float64(faults-pc.majorFaults) / elapsed
+		}
+		pc.majorFaults = faults
+		pc.lastSampleAt = now
+		pc.container = container
+		pc.threshold = container.Threshold("page-fault-rate", cfg.PageFault.RateThreshold)
+
+		if pc.rate >= float64(pc.threshold) && now.Sub(pc.lastSampled) >= backoff {
+			pc.lastSampled = now
+			spiked = append(spiked, pc)
+		}
+	}
+
+	return spiked
+}
+
+func (c *pageFaultTracing) sample(ctx context.Context, pc *pageFaultContainer) error {
+	container := pc.container
+	css, ok := container.CgroupCss[subsystem.SubsystemMemory]
+	if !ok {
+		return fmt.Errorf("no memory css")
+	}
+
+	b, err := bpf.LoadBpf(bpf.ThisBpfOBJ(), map[string]any{"target_css": css})
+	if err != nil {
+		return fmt.Errorf("load bpf: %w", err)
+	}
+	defer b.Close()
+
+	sampleCtx, cancel := context.WithTimeout(ctx, time.Duration(cfg.PageFault.SampleDuration)*time.Second)
+	defer cancel()
+
+	reader, err := b.AttachAndEventPipe(sampleCtx, "page_fault_events", 8192)
+	if err != nil {
+		return fmt.Errorf("attach: %w", err)
+	}
+	defer reader.Close()
+
+	stacks := make(map[string]*pageFaultStack)
+	samples := 0
+	for {
+		var data pageFaultPerfEvent
+		if err := reader.ReadInto(&data); err != nil {
+			if ctx.Err() != nil {
+				return types.ErrExitByCancelCtx
+			}
+			if sampleCtx.Err() != nil {
+				break
+			}
+			return fmt.Errorf("read perf event: %w", err)
+		}
+
+		if data.StackSize <= 0 {
+			continue
+		}
+
+		samples++
+		comm := bytesutil.ToStr(data.Comm[:])
+		frames := c.usym.UsymStackStrs(data.Pid, data.Stack[:], int(data.StackSize/8))
+		key := comm + "\n" + strings.Join(frames, "\n")
+		if s, ok := stacks[key]; ok {
+			s.Count++
+			continue
+		}
+		stacks[key] = &pageFaultStack{Comm: comm, Count: 1, Stack: strings.Join(frames, "\n")}
+	}
+
+	top := make([]*pageFaultStack, 0, len(stacks))
+	for _, s := range stacks {
+		top = append(top, s)
+	}
+	sort.Slice(top, func(i, j int) bool { return top[i].Count > top[j].Count })
+	if len(top) > pageFaultMaxStacks {
+		top = top[:pageFaultMaxStacks]
+	}
+
+	age := time.Since(container.StartedAt)
+	data := &PageFaultTracingData{
+		Rate:                pc.rate,
+		Threshold:           pc.threshold,
+		ContainerAgeSeconds: int64(age.Seconds()),
+		ColdStart:           age < time.Duration(cfg.PageFault.ColdStartWindow)*time.Second,
+		Samples:             samples,
+		Stacks:              top,
+	}
+	if usage, err := c.cgroupMgr.MemoryUsage(container.CgroupPath); err == nil {
+		data.MemoryUsage = usage.Usage
+		data.MemoryLimit = usage.MaxLimited
+	}
+
+	if err := tracing.Save(&tracing.WriteRequest{
+		TracerName:  "page_fault",
+		ContainerID: container.ID,
+		TracerTime:  time.Now(),
+		TracerData:  data,
+	}); err != nil {
+		log.Warnf("failed to save tracing data: %v", err)
+	}
+
+	return nil
+}
+
+func (c *pageFaultTracing) Update() ([]*metric.Data, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	data := make([]*metric.Data, 0, 2*len(c.containers))
+	for _, pc := range c.containers {
+		data = append(data,
+			metric.NewContainerCounterData(pc.container, "major_faults_total", float64(pc.majorFaults), "major page faults of the container", nil),
+			metric.NewContainerGaugeData(pc.container, "major_faults_rate", pc.rate, "major page faults per second over the last poll interval", nil))
+	}
+
+	return data, nil
+}
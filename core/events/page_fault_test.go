@@ -0,0 +1,114 @@
+// Copyright 2026 The HuaTuo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package events
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"huatuo-bamai/internal/cgroups"
+	"huatuo-bamai/internal/pod"
+)
+
+// pageFaultCgroup serves memory.stat from stats, a missing path fails.
+type pageFaultCgroup struct {
+	cgroups.Cgroup
+	stats map[string]map[string]uint64
+}
+
+func (c *pageFaultCgroup) MemoryStatRaw(path string) (map[string]uint64, error) {
+	raw, ok := c.stats[path]
+	if !ok {
+		return nil, errors.New("cgroup removed")
+	}
+	return raw, nil
+}
+
+func TestPageFaultUpdateContainers(t *testing.T) {
+	orig := cfg
+	t.Cleanup(func() { cfg = orig })
+	cfg = &Config{}
+	cfg.PageFault.RateThreshold = 100
+	cfg.PageFault.IntervalTracing = 60
+
+	labels := map[string]any{"HostNamespace": "default"}
+	web := &pod.Container{ID: "web", CgroupPath: "/web", Labels: labels}
+	db := &pod.Container{ID: "db", CgroupPath: "/db", Labels: labels}
+	containers := map[string]*pod.Container{"web": web, "db": db}
+
+	// cgroup v1 has the hierarchical total_pgmajfault, v2 only pgmajfault.
+	cgroup := &pageFaultCgroup{stats: map[string]map[string]uint64{
+		"/web": {"pgmajfault": 10, "total_pgmajfault": 1000},
+		"/db":  {"pgmajfault": 50},
+	}}
+	c := &pageFaultTracing{cgroupMgr: cgroup, containers: map[string]*pageFaultContainer{}}
+
+	now := time.Now()
+	if spiked := c.updateContainers(containers, now); len(spiked) != 0 {
+		t.Errorf("first poll spiked %d containers, want none", len(spiked))
+	}
+	if got := c.containers["web"].majorFaults; got != 1000 {
+		t.Errorf("web major faults = %d, want the total 1000", got)
+	}
+	if got := c.containers["db"].majorFaults; got != 50 {
+		t.Errorf("db major faults = %d, want 50", got)
+	}
+
+	// web faults 2000 in 10s, db 10.
+	cgroup.stats["/web"]["total_pgmajfault"] = 21000
+	cgroup.stats["/db"]["pgmajfault"] = 60
+	now = now.Add(10 * time.Second)
+	spiked := c.updateContainers(containers, now)
+	if len(spiked) != 1 || spiked[0].container != web {
+		t.Fatalf("second poll spiked %v, want web", spiked)
+	}
+	if pc := c.containers["web"]; pc.rate != 2000 || pc.threshold != 100 {
+		t.Errorf("web rate = %v, threshold %d, want 2000, 100", pc.rate, pc.threshold)
+	}
+	if pc := c.containers["db"]; pc.rate != 1 {
+		t.Errorf("db rate = %v, want 1", pc.rate)
+	}
+
+	// the metrics are labeled by the container of each counter.
+	data, err := c.Update()
+	if err != nil || len(data) != 4 {
+		t.Fatalf("Update() = %d metrics, %v, want 4", len(data), err)
+	}
+	for i := 0; i < len(data); i += 2 {
+		total, rate := data[i].Value, data[i+1].Value
+		if (total != 21000 || rate != 2000) && (total != 60 || rate != 1) {
+			t.Errorf("Update() = total %v, rate %v of no container", total, rate)
+		}
+	}
+
+	// still spiking within IntervalTracing, a counter reset has no rate.
+	cgroup.stats["/web"]["total_pgmajfault"] = 100000
+	cgroup.stats["/db"]["pgmajfault"] = 5
+	now = now.Add(10 * time.Second)
+	if spiked := c.updateContainers(containers, now); len(spiked) != 0 {
+		t.Errorf("poll within the backoff spiked %d containers, want none", len(spiked))
+	}
+	if pc := c.containers["db"]; pc.rate != 0 || pc.majorFaults != 5 {
+		t.Errorf("db after reset = rate %v, faults %d, want 0, 5", pc.rate, pc.majorFaults)
+	}
+
+	// a removed container is forgotten.
+	delete(containers, "db")
+	c.updateContainers(containers, now.Add(10*time.Second))
+	if _, ok := c.containers["db"]; ok {
+		t.Error("db still tracked after removal")
+	}
+}
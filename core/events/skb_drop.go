@@ -0,0 +1,393 @@
+// Copyright 2026 The HuaTuo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package events
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"huatuo-bamai/internal/bpf"
+	"huatuo-bamai/internal/log"
+	"huatuo-bamai/internal/packet"
+	"huatuo-bamai/internal/symbol"
+	"huatuo-bamai/internal/utils/bytesutil"
+	"huatuo-bamai/internal/utils/kernaddr"
+	"huatuo-bamai/internal/utils/netutil"
+	"huatuo-bamai/pkg/metric"
+	"huatuo-bamai/pkg/tracing"
+)
+
+//go:generate $BPF_COMPILE $BPF_INCLUDE -s $BPF_DIR/skb_drop.c -o $BPF_DIR/skb_drop.o
+
+const (
+	// keep in sync with bpf/skb_drop.c
+	skbDropReasonNone  = 0xffffffff
+	skbDropStackSample = 16
+
+	skbDropCountsMap = "skb_drop_counts"
+	skbDropSitesMap  = "skb_drop_sites"
+	skbDropStacksMap = "skb_drop_stacks"
+
+	// skbDropTopCounts are the reasons and netdevs listed in an event.
+	skbDropTopCounts = 10
+)
+
+// skbDropProbes are tried in order until one is attached, the kprobes when
+// the tracepoint cannot be.
+var skbDropProbes = []bpf.AttachOption{
+	{ProgramName: "tracepoint_kfree_skb", Symbol: "skb/kfree_skb"},
+	{ProgramName: "kprobe_sk_skb_reason_drop", Symbol: "sk_skb_reason_drop"},
+	{ProgramName: "kprobe_kfree_skb_reason", Symbol: "kfree_skb_reason"},
+	{ProgramName: "kprobe_kfree_skb", Symbol: "kfree_skb"},
+}
+
+type skbDropKey struct {
+	reason uint32
+	netdev string
+}
+
+// skbDropSiteKey is the owner of the sampled drops, the memory cgroup of the
+// socket and the net namespace, and their kernel stack.
+type skbDropSiteKey struct {
+	memcgCSS  uint64
+	netnsInum uint32
+	reason    uint32
+	stackID   int32
+}
+
+// SkbDropTracingData is stored when the packets dropped by the kernel per
+// second cross the storm threshold.
+type SkbDropTracingData struct {
+	DropsPerSec  float64 `json:"drops_per_sec"`
+	Threshold    uint64  `json:"threshold"`
+	Drops        uint64  `json:"drops"`
+	DurationSecs float64 `json:"duration_secs"`
+	// TopDrops are the reasons and netdevs dropping the most.
+	TopDrops []*skbDropCount `json:"top_drops"`
+	// Sites are the kernel stacks and the owners of the drops, the most
+	// first.
+	Sites []*skbDropSite `json:"sites"`
+}
+
+type skbDropCount struct {
+	Reason string `json:"reason"`
+	Netdev string `json:"netdev"`
+	Drops  uint64 `json:"drops"`
+}
+
+type skbDropSite struct {
+	Reason string `json:"reason"`
+	// Drops are estimated from the sampled ones.
+	Drops               uint64 `json:"drops"`
+	ContainerID         string `json:"container_id,omitempty"`
+	ContainerHostname   string `json:"container_hostname,omitempty"`
+	NetNamespaceInode   uint32 `json:"net_namespace_inode"`
+	MemoryCgroupCSSAddr string `json:"memory_cgroup_css_addr"`
+	Stack               string `json:"stack"`
+}
+
+type skbDropTracing struct {
+	bpf          bpf.BPF
+	reasons      map[uint32]string
+	hostNetInode uint64
+	running      atomic.Bool
+}
+
+func init() {
+	tracing.RegisterEventTracing("skb_drop", newSkbDrop)
+	tracing.RegisterSchema[SkbDropTracingData]("skb_drop", "skb_drop", 1)
+}
+
+func newSkbDrop() (*tracing.EventTracingAttr, error) {
+	return &tracing.EventTracingAttr{
+		TracingData: &skbDropTracing{},
+		Interval:    10,
+		Flag:        tracing.FlagTracing | tracing.FlagMetric,
+	}, nil
+}
+
+func (c *skbDropTracing) Start(ctx context.Context) error {
+	hostNetInode, err := netutil.NetNSInodeByPid(1)
+	if err != nil {
+		return fmt.Errorf("get host netns inode: %w", err)
+	}
+
+	// the kernels before 5.17 have no drop reasons.
+	reasons, err := packet.DropReasons()
+	if err != nil {
+		log.Infof("skb_drop: no drop reasons: %v", err)
+	}
+	consumed := uint32(skbDropReasonNone)
+	for value, name := range reasons {
+		if name == packet.DropReasonConsumed {
+			consumed = value
+		}
+	}
+
+	b, err := bpf.LoadBpf(bpf.ThisBpfOBJ(), map[string]any{"consumed_reason": consumed})
+	if err != nil {
+		return err
+	}
+	defer b.Close()
+
+	if err := attachSkbDrop(b); err != nil {
+		return err
+	}
+
+	childCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	b.WaitDetachByBreaker(childCtx, cancel)
+
+	c.bpf, c.reasons, c.hostNetInode = b, reasons, hostNetInode
+	c.running.Store(true)
+	defer c.running.Store(false)
+
+	ticker := time.NewTicker(time.Duration(cfg.SkbDrop.Interval) * time.Second)
+	defer ticker.Stop()
+
+	var (
+		lastCounts map[skbDropKey]uint64
+		lastSites  map[skbDropSiteKey]uint64
+		lastTime   time.Time
+		lastReport time.Time
+	)
+	for {
+		select {
+		case <-childCtx.Done():
+			return nil
+		case <-ticker.C:
+		}
+
+		counts, err := c.counts()
+		if err != nil {
+			log.Warnf("skb_drop dump %s: %v", skbDropCountsMap, err)
+			continue
+		}
+		sites, err := c.sites()
+		if err != nil {
+			log.Warnf("skb_drop dump %s: %v", skbDropSitesMap, err)
+			continue
+		}
+
+		now := time.Now()
+		countDeltas, siteDeltas := skbDropDeltas(counts, lastCounts), skbDropDeltas(sites, lastSites)
+		elapsed := now.Sub(lastTime)
+		first := lastCounts == nil
+		lastCounts, lastSites, lastTime = counts, sites, now
+
+		if first || cfg.SkbDrop.StormThreshold == 0 ||
+			now.Sub(lastReport) < time.Duration(cfg.SkbDrop.IntervalTracing)*time.Second {
+			continue
+		}
+
+		var drops uint64
+		for _, delta := range countDeltas {
+			drops += delta
+		}
+		rate := float64(drops) / elapsed.Seconds()
+		if rate < float64(cfg.SkbDrop.StormThreshold) {
+			continue
+		}
+
+		lastReport = now
+		c.report(&SkbDropTracingData{
+			DropsPerSec:  rate,
+			Threshold:    cfg.SkbDrop.StormThreshold,
+			Drops:        drops,
+			DurationSecs: elapsed.Seconds(),
+			TopDrops:     c.topDrops(countDeltas, skbDropTopCounts),
+		}, siteDeltas, now)
+	}
+}
+
+// attachSkbDrop attaches the first of skbDropProbes the kernel supports.
+func attachSkbDrop(b bpf.BPF) error {
+	var errs []error
+	for _, probe := range skbDropProbes {
+		err := b.AttachWithOptions([]bpf.AttachOption{probe})
+		if err == nil {
+			log.Infof("skb_drop attached to %s", probe.Symbol)
+			return nil
+		}
+		errs = append(errs, fmt.Errorf("%s: %w", probe.Symbol, err))
+	}
+	return errors.Join(errs...)
+}
+
+func (c *skbDropTracing) reasonName(reason uint32) string {
+	if reason == skbDropReasonNone {
+		return "unknown"
+	}
+	if name, ok := c.reasons[reason]; ok {
+		return name
+	}
+	return strconv.FormatUint(uint64(reason), 10)
+}
+
+func (c *skbDropTracing) counts() (map[skbDropKey]uint64, error) {
+	items, err := c.bpf.DumpMapByName(skbDropCountsMap)
+	if err != nil {
+		return nil, err
+	}
+
+	counts := make(map[skbDropKey]uint64, len(items))
+	for _, item := range items {
+		if len(item.Key) < 4+bpf.NetdevNameLen || len(item.Value) < 8 {
+			continue
+		}
+
+		key := skbDropKey{
+			reason: binary.LittleEndian.Uint32(item.Key),
+			netdev: bytesutil.ToStr(item.Key[4 : 4+bpf.NetdevNameLen]),
+		}
+		counts[key] += binary.LittleEndian.Uint64(item.Value)
+	}
+	return counts, nil
+}
+
+func (c *skbDropTracing) sites() (map[skbDropSiteKey]uint64, error) {
+	items, err := c.bpf.DumpMapByName(skbDropSitesMap)
+	if err != nil {
+		return nil, err
+	}
+
+	sites := make(map[skbDropSiteKey]uint64, len(items))
+	for _, item := range items {
+		if len(item.Key) < 20 || len(item.Value) < 8 {
+			continue
+		}
+
+		key := skbDropSiteKey{
+			memcgCSS:  binary.LittleEndian.Uint64(item.Key),
+			netnsInum: binary.LittleEndian.Uint32(item.Key[8:]),
+			reason:    binary.LittleEndian.Uint32(item.Key[12:]),
+			stackID:   int32(binary.LittleEndian.Uint32(item.Key[16:])),
+		}
+		sites[key] = binary.LittleEndian.Uint64(item.Value)
+	}
+	return sites, nil
+}
+
+// skbDropDeltas returns the counts since the last dump, without the ones
+// unchanged. The entries evicted from the lru maps restart from 0.
+func skbDropDeltas[K comparable](counts, last map[K]uint64) map[K]uint64 {
+	deltas := make(map[K]uint64, len(counts))
+	for key, count := range counts {
+		delta := count
+		if prev, ok := last[key]; ok && prev <= count {
+			delta = count - prev
+		}
+		if delta > 0 {
+			deltas[key] = delta
+		}
+	}
+	return deltas
+}
+
+// topDrops returns the topN reasons and netdevs by their drops.
+func (c *skbDropTracing) topDrops(deltas map[skbDropKey]uint64, topN int) []*skbDropCount {
+	top := make([]*skbDropCount, 0, len(deltas))
+	for key, drops := range deltas {
+		top = append(top, &skbDropCount{
+			Reason: c.reasonName(key.reason),
+			Netdev: skbDropNetdev(key.netdev),
+			Drops:  drops,
+		})
+	}
+
+	sort.Slice(top, func(i, j int) bool {
+		if top[i].Drops != top[j].Drops {
+			return top[i].Drops > top[j].Drops
+		}
+		if top[i].Reason != top[j].Reason {
+			return top[i].Reason < top[j].Reason
+		}
+		return top[i].Netdev < top[j].Netdev
+	})
+
+	if len(top) > topN {
+		top = top[:topN]
+	}
+	return top
+}
+
+// skbDropNetdev is the netdev label, "-" for the packets of no device.
+func skbDropNetdev(netdev string) string {
+	if netdev == "" {
+		return "-"
+	}
+	return netdev
+}
+
+func (c *skbDropTracing) report(data *SkbDropTracingData, siteDeltas map[skbDropSiteKey]uint64, now time.Time) {
+	keys := make([]skbDropSiteKey, 0, len(siteDeltas))
+	for key := range siteDeltas {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return siteDeltas[keys[i]] > siteDeltas[keys[j]]
+	})
+	if len(keys) > cfg.SkbDrop.MaxSites {
+		keys = keys[:cfg.SkbDrop.MaxSites]
+	}
+
+	for _, key := range keys {
+		site := &skbDropSite{
+			Reason:              c.reasonName(key.reason),
+			Drops:               siteDeltas[key] * skbDropStackSample,
+			NetNamespaceInode:   key.netnsInum,
+			MemoryCgroupCSSAddr: kernaddr.Format(key.memcgCSS),
+			Stack:               symbol.KsymStackOfMap(c.bpf, skbDropStacksMap, key.stackID),
+		}
+		if container := containerBySocket(key.memcgCSS, key.netnsInum, c.hostNetInode); container != nil {
+			site.ContainerID = container.ID
+			site.ContainerHostname = container.Hostname
+		}
+		data.Sites = append(data.Sites, site)
+	}
+
+	if err := tracing.Save(&tracing.WriteRequest{
+		TracerName: "skb_drop",
+		TracerTime: now,
+		TracerData: data,
+	}); err != nil {
+		log.Warnf("failed to save tracing data: %v", err)
+	}
+}
+
+func (c *skbDropTracing) Update() ([]*metric.Data, error) {
+	if !c.running.Load() {
+		return nil, nil
+	}
+
+	counts, err := c.counts()
+	if err != nil {
+		return nil, err
+	}
+
+	data := make([]*metric.Data, 0, len(counts))
+	for key, count := range counts {
+		data = append(data, metric.NewCounterData("total", float64(count), "packets dropped by the kernel",
+			map[string]string{"reason": c.reasonName(key.reason), "netdev": skbDropNetdev(key.netdev)}))
+	}
+	return data, nil
+}
@@ -0,0 +1,73 @@
+// Copyright 2026 The HuaTuo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package events
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSkbDropDeltas(t *testing.T) {
+	last := map[skbDropKey]uint64{
+		{reason: 2, netdev: "eth0"}: 100,
+		{reason: 3, netdev: "eth0"}: 50,
+		{reason: 5, netdev: "eth1"}: 80,
+	}
+	counts := map[skbDropKey]uint64{
+		{reason: 2, netdev: "eth0"}: 150,
+		{reason: 3, netdev: "eth0"}: 50,
+		// evicted and counted again.
+		{reason: 5, netdev: "eth1"}: 7,
+		{reason: 6}:                 3,
+	}
+
+	want := map[skbDropKey]uint64{
+		{reason: 2, netdev: "eth0"}: 50,
+		{reason: 5, netdev: "eth1"}: 7,
+		{reason: 6}:                 3,
+	}
+	if got := skbDropDeltas(counts, last); !reflect.DeepEqual(got, want) {
+		t.Errorf("deltas = %v, want %v", got, want)
+	}
+}
+
+func TestSkbDropTopDrops(t *testing.T) {
+	c := &skbDropTracing{reasons: map[uint32]string{2: "NOT_SPECIFIED", 3: "NO_SOCKET"}}
+
+	top := c.topDrops(map[skbDropKey]uint64{
+		{reason: 2, netdev: "eth0"}:               10,
+		{reason: 3, netdev: "eth0"}:               30,
+		{reason: 3}:                               30,
+		{reason: 77, netdev: "eth1"}:              5,
+		{reason: skbDropReasonNone, netdev: "lo"}: 1,
+	}, 4)
+
+	want := []*skbDropCount{
+		{Reason: "NO_SOCKET", Netdev: "-", Drops: 30},
+		{Reason: "NO_SOCKET", Netdev: "eth0", Drops: 30},
+		{Reason: "NOT_SPECIFIED", Netdev: "eth0", Drops: 10},
+		{Reason: "77", Netdev: "eth1", Drops: 5},
+	}
+	if !reflect.DeepEqual(top, want) {
+		for _, d := range top {
+			t.Logf("%+v", d)
+		}
+		t.Errorf("top drops mismatch")
+	}
+
+	if name := c.reasonName(skbDropReasonNone); name != "unknown" {
+		t.Errorf("reason of the kernels without = %s", name)
+	}
+}
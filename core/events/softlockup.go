@@ -54,6 +54,7 @@ type softLockupTracing struct {
 
 func init() {
 	tracing.RegisterEventTracing("softlockup", newSoftLockup)
+	tracing.RegisterSchema[SoftLockupTracerData]("softlockup", "softlockup", 1)
 }
 
 func newSoftLockup() (*tracing.EventTracingAttr, error) {
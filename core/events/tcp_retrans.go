@@ -0,0 +1,396 @@
+// Copyright 2026 The HuaTuo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package events
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"huatuo-bamai/internal/bpf"
+	"huatuo-bamai/internal/cgroups/subsystem"
+	"huatuo-bamai/internal/log"
+	"huatuo-bamai/internal/packet"
+	"huatuo-bamai/internal/pod"
+	"huatuo-bamai/internal/symbol"
+	"huatuo-bamai/internal/utils/bytesutil"
+	"huatuo-bamai/internal/utils/executil"
+	"huatuo-bamai/internal/utils/kernaddr"
+	"huatuo-bamai/internal/utils/netutil"
+	"huatuo-bamai/pkg/metric"
+	"huatuo-bamai/pkg/tracing"
+
+	"golang.org/x/sys/unix"
+)
+
+//go:generate $BPF_COMPILE $BPF_INCLUDE -s $BPF_DIR/tcp_retrans.c -o $BPF_DIR/tcp_retrans.o
+
+const (
+	// keep in sync with bpf/tcp_retrans.c
+	tcpRetransTypeRetransmit = 0
+	tcpRetransTypeReset      = 1
+
+	tcpRetransCountsMap = "tcp_retrans_counts"
+
+	// tcpRetransSocketDetailsMax is the ss(8) commands run at once, the
+	// events of the flows beyond are saved without the details.
+	tcpRetransSocketDetailsMax = 4
+)
+
+// tcpRetransPerfEvent mirrors struct tcp_retrans_event of the bpf program.
+type tcpRetransPerfEvent struct {
+	Stack     [symbol.KsymStackMaxDepth]uint64
+	StackSize int64
+	MemcgCSS  uint64
+	Retrans   uint64
+	Resets    uint64
+	Pid       uint32
+	NetnsInum uint32
+	Saddr     [16]byte
+	Daddr     [16]byte
+	Sport     uint16
+	Dport     uint16
+	Family    uint16
+	State     uint8
+	Type      uint8
+	Comm      [bpf.TaskCommLen]byte
+}
+
+// tcpRetransCountKey is the memory cgroup of the sockets, 0 for the resets
+// of no socket, and their net namespace.
+type tcpRetransCountKey struct {
+	memcgCSS  uint64
+	netnsInum uint32
+}
+
+type tcpRetransCount struct {
+	retrans uint64
+	resets  uint64
+}
+
+// TCPRetransTracingData is stored when the retransmits and resets of a single
+// flow reach the threshold within the window. The local end is the source.
+type TCPRetransTracingData struct {
+	// Type is what reached the threshold, retransmit or reset.
+	Type                string `json:"type"`
+	Comm                string `json:"comm"`
+	Pid                 uint32 `json:"pid"`
+	Saddr               string `json:"saddr"`
+	Sport               uint16 `json:"sport"`
+	Daddr               string `json:"daddr"`
+	Dport               uint16 `json:"dport"`
+	State               string `json:"state"`
+	Retransmits         uint64 `json:"retransmits"`
+	Resets              uint64 `json:"resets"`
+	Threshold           uint64 `json:"threshold"`
+	WindowSecs          uint64 `json:"window_secs"`
+	NetNamespaceInode   uint32 `json:"net_namespace_inode"`
+	MemoryCgroupCSSAddr string `json:"memory_cgroup_css_addr"`
+	Stack               string `json:"stack"`
+	// SocketDetails is the output of ss(8) for the flow in the net
+	// namespace of its container, see the SocketDetails option.
+	SocketDetails string `json:"socket_details,omitempty"`
+}
+
+type tcpRetransTracing struct {
+	bpf          bpf.BPF
+	hostNetInode uint64
+	running      atomic.Bool
+	// details bounds the ss(8) commands running, off the perf reader loop.
+	details chan struct{}
+}
+
+func init() {
+	tracing.RegisterEventTracing("tcp_retrans", newTCPRetrans)
+	tracing.RegisterSchema[TCPRetransTracingData]("tcp_retrans", "tcp_retrans", 1)
+}
+
+func newTCPRetrans() (*tracing.EventTracingAttr, error) {
+	return &tracing.EventTracingAttr{
+		TracingData: &tcpRetransTracing{details: make(chan struct{}, tcpRetransSocketDetailsMax)},
+		Interval:    10,
+		Flag:        tracing.FlagTracing | tracing.FlagMetric,
+	}, nil
+}
+
+func (c *tcpRetransTracing) Start(ctx context.Context) error {
+	hostNetInode, err := netutil.NetNSInodeByPid(1)
+	if err != nil {
+		return fmt.Errorf("get host netns inode: %w", err)
+	}
+	c.hostNetInode = hostNetInode
+
+	b, err := bpf.LoadBpf(bpf.ThisBpfOBJ(), map[string]any{
+		"flow_threshold": cfg.TCPRetrans.FlowThreshold,
+		"flow_window_ns": cfg.TCPRetrans.FlowWindow * uint64(time.Second),
+	})
+	if err != nil {
+		return err
+	}
+	defer b.Close()
+
+	childCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	reader, err := b.AttachAndEventPipe(childCtx, "tcp_retrans_events", 8192)
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+
+	b.WaitDetachByBreaker(childCtx, cancel)
+
+	c.bpf = b
+	c.running.Store(true)
+	defer c.running.Store(false)
+
+	for {
+		select {
+		case <-childCtx.Done():
+			return nil
+		default:
+			var data tcpRetransPerfEvent
+			if err := reader.ReadInto(&data); err != nil {
+				return fmt.Errorf("read from perf event fail: %w", err)
+			}
+
+			c.save(&data)
+		}
+	}
+}
+
+func (c *tcpRetransTracing) save(data *tcpRetransPerfEvent) {
+	tracerData := &TCPRetransTracingData{
+		Type:                "retransmit",
+		Comm:                bytesutil.ToStr(data.Comm[:]),
+		Pid:                 data.Pid,
+		Saddr:               tcpRetransAddr(data.Family, data.Saddr),
+		Sport:               data.Sport,
+		Daddr:               tcpRetransAddr(data.Family, data.Daddr),
+		Dport:               data.Dport,
+		State:               packet.TCPStateName(data.State),
+		Retransmits:         data.Retrans,
+		Resets:              data.Resets,
+		Threshold:           cfg.TCPRetrans.FlowThreshold,
+		WindowSecs:          cfg.TCPRetrans.FlowWindow,
+		NetNamespaceInode:   data.NetnsInum,
+		MemoryCgroupCSSAddr: kernaddr.Format(data.MemcgCSS),
+	}
+	if data.Type == tcpRetransTypeReset {
+		tracerData.Type = "reset"
+	}
+	if data.StackSize > 0 {
+		tracerData.Stack = strings.Join(symbol.KsymStackStrs(data.Stack[:], symbol.KsymStackMaxDepth), "\n")
+	}
+
+	container := containerBySocket(data.MemcgCSS, data.NetnsInum, c.hostNetInode)
+	if container == nil {
+		tcpRetransSave("", tracerData, time.Now())
+		return
+	}
+
+	now := time.Now()
+	if !cfg.TCPRetrans.SocketDetails || container.InitPid <= 0 {
+		tcpRetransSave(container.ID, tracerData, now)
+		return
+	}
+
+	select {
+	case c.details <- struct{}{}:
+		go func() {
+			defer func() { <-c.details }()
+
+			tracerData.SocketDetails = tcpRetransSocketDetails(container.InitPid, tracerData)
+			tcpRetransSave(container.ID, tracerData, now)
+		}()
+	default:
+		tcpRetransSave(container.ID, tracerData, now)
+	}
+}
+
+func tcpRetransSave(containerID string, tracerData *TCPRetransTracingData, now time.Time) {
+	if err := tracing.Save(&tracing.WriteRequest{
+		TracerName:  "tcp_retrans",
+		ContainerID: containerID,
+		TracerTime:  now,
+		TracerData:  tracerData,
+	}); err != nil {
+		log.Warnf("failed to save tracing data: %v", err)
+	}
+}
+
+// tcpRetransSocketDetails returns the ss(8) output of the flow in the net
+// namespace of the init pid of its container, empty on an error.
+func tcpRetransSocketDetails(pid int, data *TCPRetransTracingData) string {
+	out, err := executil.Nsenter(context.Background(), pid, nil, "ss", tcpRetransSSArgs(data)...)
+	if err != nil {
+		log.Debugf("tcp_retrans socket details of pid %d: %v", pid, err)
+		return ""
+	}
+	return strings.TrimSpace(string(out))
+}
+
+// tcpRetransSSArgs are the ss(8) arguments filtering the sockets of the
+// flow, in every state since a reset may have closed it. The ipv6 addresses
+// are bracketed, ss parses a colon as the port.
+func tcpRetransSSArgs(data *TCPRetransTracingData) []string {
+	host := func(addr string) string {
+		if strings.Contains(addr, ":") {
+			return "[" + addr + "]"
+		}
+		return addr
+	}
+
+	return []string{
+		"-tin", "state", "all",
+		"src", host(data.Saddr), "and", "sport", "=", ":" + strconv.Itoa(int(data.Sport)),
+		"and", "dst", host(data.Daddr), "and", "dport", "=", ":" + strconv.Itoa(int(data.Dport)),
+	}
+}
+
+// containerBySocket returns the container of the memory cgroup of a socket.
+// The packets of no socket have no cgroup, they are of a container of their
+// net namespace, but the host one shared by the host network pods.
+func containerBySocket(css uint64, netnsInum uint32, hostNetInode uint64) *pod.Container {
+	if css != 0 {
+		container, err := pod.ContainerByCSS(css, subsystem.SubsystemMemory)
+		if err != nil {
+			log.Debugf("css lookup %x: %v", css, err)
+			return nil
+		}
+		return container
+	}
+
+	if uint64(netnsInum) == hostNetInode {
+		return nil
+	}
+	container, err := pod.ContainerByNetInode(uint64(netnsInum))
+	if err != nil {
+		log.Debugf("netns lookup %d: %v", netnsInum, err)
+		return nil
+	}
+	return container
+}
+
+// tcpRetransAddr formats the address of the flow, the first 4 bytes of an
+// ipv4 one.
+func tcpRetransAddr(family uint16, addr [16]byte) string {
+	if family == unix.AF_INET {
+		return net.IP(addr[:4]).String()
+	}
+	return net.IP(addr[:]).String()
+}
+
+func (c *tcpRetransTracing) counts() (map[tcpRetransCountKey]tcpRetransCount, error) {
+	items, err := c.bpf.DumpMapByName(tcpRetransCountsMap)
+	if err != nil {
+		return nil, err
+	}
+
+	counts := make(map[tcpRetransCountKey]tcpRetransCount, len(items))
+	for _, item := range items {
+		if len(item.Key) < 12 || len(item.Value) < 16 {
+			continue
+		}
+
+		key := tcpRetransCountKey{
+			memcgCSS:  binary.LittleEndian.Uint64(item.Key),
+			netnsInum: binary.LittleEndian.Uint32(item.Key[8:]),
+		}
+		counts[key] = tcpRetransCount{
+			retrans: binary.LittleEndian.Uint64(item.Value),
+			resets:  binary.LittleEndian.Uint64(item.Value[8:]),
+		}
+	}
+	return counts, nil
+}
+
+// tcpRetransAggregate sums the counts per container, by the memory cgroup of
+// the sockets. The resets of no socket are of the first container by id of
+// their net namespace, shared by the containers of a pod, but the host one.
+// The rest is of the host.
+func tcpRetransAggregate(counts map[tcpRetransCountKey]tcpRetransCount, containers map[string]*pod.Container, hostNetInode uint64) (map[*pod.Container]*tcpRetransCount, *tcpRetransCount) {
+	cssContainers := pod.BuildCssContainers(containers, subsystem.SubsystemMemory)
+
+	ids := make([]string, 0, len(containers))
+	for id := range containers {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	netnsContainers := make(map[uint64]*pod.Container, len(containers))
+	for _, id := range ids {
+		container := containers[id]
+		if _, ok := netnsContainers[container.NetNamespaceInode]; !ok && container.NetNamespaceInode != hostNetInode {
+			netnsContainers[container.NetNamespaceInode] = container
+		}
+	}
+
+	host := &tcpRetransCount{}
+	perContainer := make(map[*pod.Container]*tcpRetransCount)
+	for key, count := range counts {
+		container, ok := cssContainers[key.memcgCSS]
+		if key.memcgCSS == 0 {
+			container, ok = netnsContainers[uint64(key.netnsInum)]
+		}
+
+		sum := host
+		if ok {
+			if sum, ok = perContainer[container]; !ok {
+				sum = &tcpRetransCount{}
+				perContainer[container] = sum
+			}
+		}
+		sum.retrans += count.retrans
+		sum.resets += count.resets
+	}
+
+	return perContainer, host
+}
+
+func (c *tcpRetransTracing) Update() ([]*metric.Data, error) {
+	if !c.running.Load() {
+		return nil, nil
+	}
+
+	containers, err := pod.NormalContainers()
+	if err != nil {
+		return nil, fmt.Errorf("get normal container: %w", err)
+	}
+
+	counts, err := c.counts()
+	if err != nil {
+		return nil, err
+	}
+
+	perContainer, host := tcpRetransAggregate(counts, containers, c.hostNetInode)
+
+	data := []*metric.Data{
+		metric.NewCounterData("host_retransmits_total", float64(host.retrans), "tcp retransmits outside of containers", nil),
+		metric.NewCounterData("host_resets_total", float64(host.resets), "tcp resets sent outside of containers", nil),
+	}
+	for container, count := range perContainer {
+		data = append(data,
+			metric.NewContainerCounterData(container, "retransmits_total", float64(count.retrans), "tcp retransmits of the container", nil),
+			metric.NewContainerCounterData(container, "resets_total", float64(count.resets), "tcp resets sent by the container", nil))
+	}
+	return data, nil
+}
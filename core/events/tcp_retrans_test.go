@@ -0,0 +1,95 @@
+// Copyright 2026 The HuaTuo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package events
+
+import (
+	"strings"
+	"testing"
+
+	"huatuo-bamai/internal/cgroups/subsystem"
+	"huatuo-bamai/internal/pod"
+
+	"golang.org/x/sys/unix"
+)
+
+func TestTCPRetransAddr(t *testing.T) {
+	v4 := [16]byte{10, 0, 0, 1, 0xff, 0xff}
+	if got := tcpRetransAddr(unix.AF_INET, v4); got != "10.0.0.1" {
+		t.Errorf("ipv4 = %s", got)
+	}
+
+	v6 := [16]byte{0xfe, 0x80, 15: 1}
+	if got := tcpRetransAddr(unix.AF_INET6, v6); got != "fe80::1" {
+		t.Errorf("ipv6 = %s", got)
+	}
+
+	mapped := [16]byte{10: 0xff, 11: 0xff, 12: 192, 13: 168, 14: 1, 15: 2}
+	if got := tcpRetransAddr(unix.AF_INET6, mapped); got != "192.168.1.2" {
+		t.Errorf("ipv4 mapped = %s", got)
+	}
+}
+
+func TestTCPRetransAggregate(t *testing.T) {
+	const hostNetInode = 4026531840
+
+	// a and b in a pod, c of the host network.
+	a := &pod.Container{ID: "a", NetNamespaceInode: 100, CgroupCss: map[string]uint64{subsystem.SubsystemMemory: 0x1000}}
+	b := &pod.Container{ID: "b", NetNamespaceInode: 100, CgroupCss: map[string]uint64{subsystem.SubsystemMemory: 0x2000}}
+	c := &pod.Container{ID: "c", NetNamespaceInode: hostNetInode, CgroupCss: map[string]uint64{subsystem.SubsystemMemory: 0x3000}}
+	containers := map[string]*pod.Container{"a": a, "b": b, "c": c}
+
+	perContainer, host := tcpRetransAggregate(map[tcpRetransCountKey]tcpRetransCount{
+		{memcgCSS: 0x1000, netnsInum: 100}:          {retrans: 5, resets: 1},
+		{memcgCSS: 0x2000, netnsInum: 100}:          {retrans: 7},
+		{memcgCSS: 0x3000, netnsInum: hostNetInode}: {retrans: 2, resets: 2},
+		// resets of no socket.
+		{netnsInum: 100}:          {resets: 3},
+		{netnsInum: hostNetInode}: {resets: 4},
+		// the sockets of the host, and of a container gone.
+		{memcgCSS: 0x9000, netnsInum: hostNetInode}: {retrans: 10},
+		{memcgCSS: 0x8000, netnsInum: 100}:          {retrans: 1},
+	}, containers, hostNetInode)
+
+	want := map[*pod.Container]tcpRetransCount{
+		a: {retrans: 5, resets: 4},
+		b: {retrans: 7},
+		c: {retrans: 2, resets: 2},
+	}
+	if len(perContainer) != len(want) {
+		t.Fatalf("containers = %d, want %d", len(perContainer), len(want))
+	}
+	for container, count := range want {
+		if got := perContainer[container]; got == nil || *got != count {
+			t.Errorf("%s = %+v, want %+v", container.ID, got, count)
+		}
+	}
+	if *host != (tcpRetransCount{retrans: 11, resets: 4}) {
+		t.Errorf("host = %+v", *host)
+	}
+}
+
+func TestTCPRetransSSArgs(t *testing.T) {
+	args := tcpRetransSSArgs(&TCPRetransTracingData{Saddr: "10.0.0.1", Sport: 43210, Daddr: "10.0.0.2", Dport: 80})
+	want := "-tin state all src 10.0.0.1 and sport = :43210 and dst 10.0.0.2 and dport = :80"
+	if got := strings.Join(args, " "); got != want {
+		t.Errorf("ipv4 = %q, want %q", got, want)
+	}
+
+	args = tcpRetransSSArgs(&TCPRetransTracingData{Saddr: "fe80::1", Sport: 43210, Daddr: "fe80::2", Dport: 443})
+	want = "-tin state all src [fe80::1] and sport = :43210 and dst [fe80::2] and dport = :443"
+	if got := strings.Join(args, " "); got != want {
+		t.Errorf("ipv6 = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,305 @@
+// Copyright 2026 The HuaTuo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package events
+
+import (
+	"context"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"huatuo-bamai/internal/cgroups"
+	"huatuo-bamai/internal/log"
+	"huatuo-bamai/internal/pod"
+	"huatuo-bamai/internal/procfs"
+	"huatuo-bamai/pkg/metric"
+	"huatuo-bamai/pkg/tracing"
+	"huatuo-bamai/pkg/types"
+)
+
+// parents kept per zombie event.
+const zombieMaxParents = 10
+
+// ZombieTracingData is stored when a container's init does not reap its
+// children, or zombies push the container close to its pids limit.
+type ZombieTracingData struct {
+	Zombies        int             `json:"zombies"`
+	UnreapedByInit int             `json:"unreaped_by_init"`
+	InitPid        int             `json:"init_pid"`
+	InitComm       string          `json:"init_comm"`
+	PidsCurrent    uint64          `json:"pids_current"`
+	PidsMax        uint64          `json:"pids_max,omitempty"`
+	PidsUsageRatio float64         `json:"pids_usage_ratio,omitempty"`
+	ZombieParents  []*zombieParent `json:"zombie_parents"`
+}
+
+type zombieParent struct {
+	Pid     int    `json:"pid"`
+	Comm    string `json:"comm"`
+	Zombies int    `json:"zombies"`
+}
+
+type zombieContainer struct {
+	container   *pod.Container
+	zombies     int
+	unreaped    int
+	pidsCurrent uint64
+	pidsMax     uint64
+	// zombie children of init seen in the previous scan.
+	initZombies map[int]struct{}
+	lastReport  time.Time
+	// scanned is false when the last scan could not read the processes,
+	// the counts are stale and not exported.
+	scanned bool
+}
+
+type zombieTracing struct {
+	cgroupMgr  cgroups.Cgroup
+	mu         sync.Mutex
+	containers map[string]*zombieContainer
+}
+
+type zombieProc struct {
+	state string
+	ppid  int
+	comm  string
+}
+
+func init() {
+	tracing.RegisterEventTracing("zombie", newZombie)
+	tracing.RegisterSchema[ZombieTracingData]("zombie", "zombie", 1)
+}
+
+func newZombie() (*tracing.EventTracingAttr, error) {
+	cgroup, err := cgroups.NewManager()
+	if err != nil {
+		return nil, err
+	}
+
+	return &tracing.EventTracingAttr{
+		TracingData: &zombieTracing{
+			cgroupMgr:  cgroup,
+			containers: make(map[string]*zombieContainer),
+		},
+		Interval: 10,
+		Flag:     tracing.FlagTracing | tracing.FlagMetric,
+	}, nil
+}
+
+func (c *zombieTracing) Start(ctx context.Context) error {
+	ticker := time.NewTicker(time.Duration(cfg.Zombie.Interval) * time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return types.ErrExitByCancelCtx
+		case <-ticker.C:
+		}
+
+		c.scan()
+	}
+}
+
+// allProcs snapshots the state and parent of every task on the host. Zombies
+// are no longer listed in cgroup.procs, so they are attributed to the
+// container of their still living parent.
+func allProcs() (map[int]*zombieProc, error) {
+	fs, err := procfs.NewDefaultFS()
+	if err != nil {
+		return nil, err
+	}
+
+	procs, err := fs.AllProcs()
+	if err != nil {
+		return nil, err
+	}
+
+	all := make(map[int]*zombieProc, len(procs))
+	for _, p := range procs {
+		stat, err := p.Stat()
+		if err != nil {
+			continue
+		}
+		all[p.PID] = &zombieProc{state: stat.State, ppid: stat.PPID, comm: stat.Comm}
+	}
+
+	return all, nil
+}
+
+func (c *zombieTracing) scan() {
+	containers, err := pod.NormalContainers()
+	if err != nil {
+		log.Debugf("zombie list containers: %v", err)
+		return
+	}
+
+	all, err := allProcs()
+	if err != nil {
+		log.Debugf("zombie read /proc: %v", err)
+		return
+	}
+
+	c.update(containers, all)
+}
+
+// update counts the zombies of the containers from the tasks of the host.
+func (c *zombieTracing) update(containers map[string]*pod.Container, all map[int]*zombieProc) {
+	// zombie children by parent pid.
+	zombiesByParent := make(map[int][]int)
+	for pid, p := range all {
+		if p.state == "Z" {
+			zombiesByParent[p.ppid] = append(zombiesByParent[p.ppid], pid)
+		}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for id := range c.containers {
+		if _, ok := containers[id]; !ok {
+			delete(c.containers, id)
+		}
+	}
+
+	for id, container := range containers {
+		zc, ok := c.containers[id]
+		if !ok {
+			zc = &zombieContainer{}
+			c.containers[id] = zc
+		}
+		zc.container = container
+
+		pids, err := c.cgroupMgr.Procs(container.CgroupPath)
+		if err != nil {
+			log.Debugf("zombie read cgroup.procs %s: %v", container, err)
+			zc.scanned = false
+			continue
+		}
+		zc.scanned = true
+
+		var parents []*zombieParent
+		zc.zombies = 0
+		for _, pid := range pids {
+			if zombies := len(zombiesByParent[int(pid)]); zombies > 0 {
+				zc.zombies += zombies
+				comm := ""
+				if p, ok := all[int(pid)]; ok {
+					comm = p.comm
+				}
+				parents = append(parents, &zombieParent{Pid: int(pid), Comm: comm, Zombies: zombies})
+			}
+		}
+
+		// a zombie of init that survives a whole interval is one init
+		// is not reaping, short-lived ones are just waiting for wait().
+		initZombies := make(map[int]struct{})
+		zc.unreaped = 0
+		for _, pid := range zombiesByParent[container.InitPid] {
+			initZombies[pid] = struct{}{}
+			if _, ok := zc.initZombies[pid]; ok {
+				zc.unreaped++
+			}
+		}
+		zc.initZombies = initZombies
+
+		zc.pidsCurrent, zc.pidsMax = 0, 0
+		if usage, err := c.cgroupMgr.PidsUsage(container.CgroupPath); err == nil {
+			zc.pidsCurrent = usage.Current
+			if usage.Max != math.MaxUint64 {
+				zc.pidsMax = usage.Max
+			}
+		}
+
+		if c.shouldReport(zc) {
+			zc.lastReport = time.Now()
+			c.report(zc, all, parents)
+		}
+	}
+}
+
+func (c *zombieTracing) shouldReport(zc *zombieContainer) bool {
+	if zc.zombies == 0 {
+		return false
+	}
+
+	if time.Since(zc.lastReport) < time.Duration(cfg.Zombie.IntervalTracing)*time.Second {
+		return false
+	}
+
+	unreapedThreshold := zc.container.Threshold("zombie-unreaped", uint64(cfg.Zombie.UnreapedThreshold))
+	if uint64(zc.unreaped) >= unreapedThreshold {
+		return true
+	}
+
+	pidsUsageThreshold := zc.container.Threshold("zombie-pids-usage", uint64(cfg.Zombie.PidsUsageThreshold))
+	return zc.pidsMax > 0 &&
+		zc.pidsCurrent*100 >= zc.pidsMax*pidsUsageThreshold
+}
+
+func (c *zombieTracing) report(zc *zombieContainer, all map[int]*zombieProc, parents []*zombieParent) {
+	sort.Slice(parents, func(i, j int) bool { return parents[i].Zombies > parents[j].Zombies })
+	if len(parents) > zombieMaxParents {
+		parents = parents[:zombieMaxParents]
+	}
+
+	data := &ZombieTracingData{
+		Zombies:        zc.zombies,
+		UnreapedByInit: zc.unreaped,
+		InitPid:        zc.container.InitPid,
+		PidsCurrent:    zc.pidsCurrent,
+		PidsMax:        zc.pidsMax,
+		ZombieParents:  parents,
+	}
+	if p, ok := all[zc.container.InitPid]; ok {
+		data.InitComm = p.comm
+	}
+	if zc.pidsMax > 0 {
+		data.PidsUsageRatio = float64(zc.pidsCurrent) / float64(zc.pidsMax)
+	}
+
+	if err := tracing.Save(&tracing.WriteRequest{
+		TracerName:  "zombie",
+		ContainerID: zc.container.ID,
+		TracerTime:  time.Now(),
+		TracerData:  data,
+	}); err != nil {
+		log.Warnf("failed to save tracing data: %v", err)
+	}
+}
+
+func (c *zombieTracing) Update() ([]*metric.Data, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	data := make([]*metric.Data, 0, 4*len(c.containers))
+	for _, zc := range c.containers {
+		if !zc.scanned {
+			continue
+		}
+
+		data = append(data,
+			metric.NewContainerGaugeData(zc.container, "zombies", float64(zc.zombies), "zombie processes of the container", nil),
+			metric.NewContainerGaugeData(zc.container, "unreaped_by_init", float64(zc.unreaped), "zombie children of the container init left unreaped for a whole interval", nil),
+			metric.NewContainerGaugeData(zc.container, "pids_current", float64(zc.pidsCurrent), "pids.current of the container", nil))
+		if zc.pidsMax > 0 {
+			data = append(data,
+				metric.NewContainerGaugeData(zc.container, "pids_max", float64(zc.pidsMax), "pids.max of the container", nil))
+		}
+	}
+
+	return data, nil
+}
@@ -0,0 +1,113 @@
+// Copyright 2026 The HuaTuo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package events
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"huatuo-bamai/internal/cgroups"
+	"huatuo-bamai/internal/cgroups/stats"
+	"huatuo-bamai/internal/pod"
+	"huatuo-bamai/internal/procfs"
+)
+
+// zombieCgroup serves cgroup.procs from procs, a missing path fails.
+type zombieCgroup struct {
+	cgroups.Cgroup
+	procs map[string][]int32
+}
+
+func (c *zombieCgroup) Procs(path string) ([]int32, error) {
+	pids, ok := c.procs[path]
+	if !ok {
+		return nil, errors.New("cgroup removed")
+	}
+	return pids, nil
+}
+
+func (c *zombieCgroup) PidsUsage(string) (*stats.PidsUsage, error) {
+	return &stats.PidsUsage{Current: 10, Max: 100}, nil
+}
+
+// writeProcStat writes /proc/<pid>/stat of a task in the fake procfs tree.
+func writeProcStat(t *testing.T, root string, pid, ppid int, comm, state string) {
+	dir := filepath.Join(root, "proc", fmt.Sprint(pid))
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	stat := fmt.Sprintf("%d (%s) %s %d %s\n", pid, comm, state, ppid, strings.Repeat("0 ", 48))
+	if err := os.WriteFile(filepath.Join(dir, "stat"), []byte(stat), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestZombieUpdate(t *testing.T) {
+	root := t.TempDir()
+	procfs.RootPrefix(root)
+	t.Cleanup(func() { procfs.RootPrefix("/") })
+
+	// the init of web leaves two zombies, db has none.
+	writeProcStat(t, root, 100, 1, "web", "S")
+	writeProcStat(t, root, 101, 100, "worker", "Z")
+	writeProcStat(t, root, 102, 100, "worker", "Z")
+	writeProcStat(t, root, 200, 1, "db", "S")
+
+	all, err := allProcs()
+	if err != nil {
+		t.Fatalf("allProcs() error = %v", err)
+	}
+	if len(all) != 4 || all[101].state != "Z" || all[101].ppid != 100 {
+		t.Fatalf("allProcs() = %d tasks, 101: %+v", len(all), all[101])
+	}
+
+	labels := map[string]any{"HostNamespace": "default"}
+	web := &pod.Container{ID: "web", CgroupPath: "/web", InitPid: 100, Labels: labels}
+	db := &pod.Container{ID: "db", CgroupPath: "/db", InitPid: 200, Labels: labels}
+	containers := map[string]*pod.Container{"web": web, "db": db}
+	cgroup := &zombieCgroup{procs: map[string][]int32{"/web": {100}, "/db": {200}}}
+	c := &zombieTracing{cgroupMgr: cgroup, containers: map[string]*zombieContainer{}}
+
+	c.update(containers, all)
+	if zc := c.containers["web"]; zc.zombies != 2 || zc.unreaped != 0 {
+		t.Errorf("web = %d zombies, %d unreaped, want 2, 0", zc.zombies, zc.unreaped)
+	}
+	// zombies, unreaped_by_init, pids_current and pids_max of each.
+	if data, _ := c.Update(); len(data) != 8 {
+		t.Errorf("Update() = %d metrics, want 8", len(data))
+	}
+
+	c.update(containers, all)
+	if zc := c.containers["web"]; zc.unreaped != 2 {
+		t.Errorf("web = %d unreaped, want 2", zc.unreaped)
+	}
+
+	// the processes of web cannot be read, its counts are stale.
+	delete(cgroup.procs, "/web")
+	c.update(containers, all)
+	if data, _ := c.Update(); len(data) != 4 {
+		t.Errorf("Update() with web unread = %d metrics, want 4", len(data))
+	}
+
+	cgroup.procs["/web"] = []int32{100}
+	c.update(containers, all)
+	if data, _ := c.Update(); len(data) != 8 {
+		t.Errorf("Update() with web read again = %d metrics, want 8", len(data))
+	}
+}
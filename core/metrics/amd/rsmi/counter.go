@@ -0,0 +1,77 @@
+// Copyright 2026 The HuaTuo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rsmi
+
+import "context"
+
+// XgmiCounters are the started XGMI data counters of a GPU.
+type XgmiCounters struct {
+	gpuId   uint32
+	handles []uintptr
+}
+
+// newXgmiCounters starts the data counters of the XGMI links. The counters
+// need the perf events of the amdgpu driver, usually root.
+func (l *library) newXgmiCounters(gpuId uint32) (*XgmiCounters, error) {
+	if err := checkReturnCode("rsmi_dev_counter_group_supported", rsmiDevCounterGroupSupported(gpuId, eventGroupXgmiDataOut)); err != nil {
+		return nil, err
+	}
+
+	c := &XgmiCounters{gpuId: gpuId}
+	for link := uint32(0); link < XgmiMaxLinks; link++ {
+		var handle uintptr
+		if err := checkReturnCode("rsmi_dev_counter_create", rsmiDevCounterCreate(gpuId, eventGroupXgmiDataOut+link, &handle)); err != nil {
+			c.Close()
+			return nil, err
+		}
+		c.handles = append(c.handles, handle)
+
+		if err := checkReturnCode("rsmi_counter_control", rsmiCounterControl(handle, counterCmdStart, 0)); err != nil {
+			c.Close()
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+// List returns the data sent over each link since the counters started.
+func (c *XgmiCounters) List(ctx context.Context) ([]XgmiThroughput, error) {
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	default:
+	}
+
+	links := make([]XgmiThroughput, 0, len(c.handles))
+	for i, handle := range c.handles {
+		var value counterValue
+		if err := checkReturnCode("rsmi_counter_read", rsmiCounterRead(handle, &value)); err != nil {
+			return nil, err
+		}
+		links = append(links, XgmiThroughput{
+			Link:     uint32(i),
+			Transmit: value.value * xgmiBeatBytes,
+		})
+	}
+	return links, nil
+}
+
+// Close destroys the counters.
+func (c *XgmiCounters) Close() {
+	for _, handle := range c.handles {
+		_ = rsmiDevCounterDestroy(handle)
+	}
+	c.handles = nil
+}
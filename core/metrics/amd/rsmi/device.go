@@ -0,0 +1,172 @@
+// Copyright 2026 The HuaTuo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rsmi
+
+import (
+	"context"
+	"fmt"
+)
+
+// getDriverVersion returns the version of the amdgpu kernel driver.
+func (l *library) getDriverVersion() (string, error) {
+	buf := make([]byte, versionBufferSize)
+	if err := checkReturnCode("rsmi_version_str_get", rsmiVersionStrGet(swComponentDriver, &buf[0], uint32(len(buf)))); err != nil {
+		return "", err
+	}
+	return cString(buf), nil
+}
+
+// getGpuCount returns the number of GPUs, each GCD of a multi-die package
+// is a GPU of its own.
+func (l *library) getGpuCount() (uint32, error) {
+	var count uint32
+	if err := checkReturnCode("rsmi_num_monitor_devices", rsmiNumMonitorDevices(&count)); err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+// getInfo returns the identity of the GPU, the optional fields are left
+// empty when not supported.
+func (l *library) getInfo(ctx context.Context, gpuId uint32) (Info, error) {
+	select {
+	case <-ctx.Done():
+		return Info{}, ctx.Err()
+	default:
+	}
+
+	name := make([]byte, nameBufferSize)
+	if err := checkReturnCode("rsmi_dev_name_get", rsmiDevNameGet(gpuId, &name[0], uint64(len(name)))); err != nil {
+		return Info{}, err
+	}
+
+	var bdfid uint64
+	if err := checkReturnCode("rsmi_dev_pci_id_get", rsmiDevPciIDGet(gpuId, &bdfid)); err != nil {
+		return Info{}, err
+	}
+
+	var uniqueID uint64
+	if err := checkReturnCode("rsmi_dev_unique_id_get", rsmiDevUniqueIDGet(gpuId, &uniqueID)); err != nil && !IsNotSupported(err) {
+		return Info{}, err
+	}
+
+	vbios := make([]byte, versionBufferSize)
+	if err := checkReturnCode("rsmi_dev_vbios_version_get", rsmiDevVbiosVersionGet(gpuId, &vbios[0], uint32(len(vbios)))); err != nil && !IsNotSupported(err) {
+		return Info{}, err
+	}
+
+	return Info{
+		Name:        cString(name),
+		UniqueID:    uniqueID,
+		BiosVersion: cString(vbios),
+		BDF:         formatBDF(bdfid),
+	}, nil
+}
+
+// formatBDF formats the bdfid of rsmi_dev_pci_id_get, the domain is in the
+// upper 32 bits.
+func formatBDF(bdfid uint64) string {
+	return fmt.Sprintf("%04x:%02x:%02x.%x", bdfid>>32, (bdfid>>8)&0xff, (bdfid>>3)&0x1f, bdfid&0x7)
+}
+
+// getUtilization returns the gfx and memory controller busy percents.
+func (l *library) getUtilization(ctx context.Context, gpuId uint32) (gfx, memory uint32, err error) {
+	select {
+	case <-ctx.Done():
+		return 0, 0, ctx.Err()
+	default:
+	}
+
+	if err := checkReturnCode("rsmi_dev_busy_percent_get", rsmiDevBusyPercentGet(gpuId, &gfx)); err != nil {
+		return 0, 0, err
+	}
+	if err := checkReturnCode("rsmi_dev_memory_busy_percent_get", rsmiDevMemoryBusyPercentGet(gpuId, &memory)); err != nil {
+		return 0, 0, err
+	}
+	return gfx, memory, nil
+}
+
+// getMemory returns the VRAM usage.
+func (l *library) getMemory(ctx context.Context, gpuId uint32) (Memory, error) {
+	select {
+	case <-ctx.Done():
+		return Memory{}, ctx.Err()
+	default:
+	}
+
+	var obj Memory
+	if err := checkReturnCode("rsmi_dev_memory_total_get", rsmiDevMemoryTotalGet(gpuId, memoryTypeVram, &obj.Total)); err != nil {
+		return Memory{}, err
+	}
+	if err := checkReturnCode("rsmi_dev_memory_usage_get", rsmiDevMemoryUsageGet(gpuId, memoryTypeVram, &obj.Used)); err != nil {
+		return Memory{}, err
+	}
+	return obj, nil
+}
+
+// getPower returns the average board power in microwatts.
+func (l *library) getPower(ctx context.Context, gpuId uint32) (uint64, error) {
+	select {
+	case <-ctx.Done():
+		return 0, ctx.Err()
+	default:
+	}
+
+	var uw uint64
+	if err := checkReturnCode("rsmi_dev_power_ave_get", rsmiDevPowerAveGet(gpuId, 0, &uw)); err != nil {
+		return 0, err
+	}
+	return uw, nil
+}
+
+// getTemperature returns the junction temperature in celsius.
+func (l *library) getTemperature(ctx context.Context, gpuId uint32) (float64, error) {
+	select {
+	case <-ctx.Done():
+		return 0, ctx.Err()
+	default:
+	}
+
+	var millidegrees int64
+	if err := checkReturnCode("rsmi_dev_temp_metric_get", rsmiDevTempMetricGet(gpuId, temperatureJunction, temperatureCurrent, &millidegrees)); err != nil {
+		return 0, err
+	}
+	return float64(millidegrees) / 1000, nil
+}
+
+// getEccMemoryErrors returns the ECC errors of the HBM.
+func (l *library) getEccMemoryErrors(ctx context.Context, gpuId uint32) (ErrorCount, error) {
+	select {
+	case <-ctx.Done():
+		return ErrorCount{}, ctx.Err()
+	default:
+	}
+
+	var obj ErrorCount
+	if err := checkReturnCode("rsmi_dev_ecc_count_get", rsmiDevEccCountGet(gpuId, gpuBlockUmc, &obj)); err != nil {
+		return ErrorCount{}, err
+	}
+	return obj, nil
+}
+
+// cString converts a NUL-terminated byte slice to a Go string.
+func cString(bs []byte) string {
+	for i, b := range bs {
+		if b == 0 {
+			return string(bs[:i])
+		}
+	}
+	return string(bs)
+}
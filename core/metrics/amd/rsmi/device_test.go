@@ -0,0 +1,139 @@
+// Copyright 2026 The HuaTuo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rsmi
+
+import (
+	"context"
+	"testing"
+	"unsafe"
+)
+
+// putString copies s into the C buffer of size n, NUL-terminated.
+func putString(buf *byte, n uint64, s string) {
+	dst := unsafe.Slice(buf, n)
+	dst[copy(dst[:n-1], s)] = 0
+}
+
+func TestGetInfo(t *testing.T) {
+	rsmiDevNameGet = func(_ uint32, buf *byte, n uint64) Status {
+		putString(buf, n, "AMD Instinct MI300X")
+		return Success
+	}
+	rsmiDevPciIDGet = func(_ uint32, bdfid *uint64) Status {
+		// domain 0x1, bus 0x2b, device 0x00, function 0x1.
+		*bdfid = 1<<32 | 0x2b<<8 | 0x1
+		return Success
+	}
+	rsmiDevUniqueIDGet = func(_ uint32, id *uint64) Status {
+		*id = 0xdeadbeef
+		return Success
+	}
+	rsmiDevVbiosVersionGet = func(uint32, *byte, uint32) Status { return ErrorNotSupported }
+
+	info, err := librsmi.getInfo(context.Background(), 0)
+	if err != nil {
+		t.Fatalf("getInfo: %v", err)
+	}
+	want := Info{Name: "AMD Instinct MI300X", UniqueID: 0xdeadbeef, BDF: "0001:2b:00.1"}
+	if info != want {
+		t.Errorf("getInfo = %+v, want %+v", info, want)
+	}
+
+	rsmiDevPciIDGet = func(uint32, *uint64) Status { return ErrorPermission }
+	if _, err := librsmi.getInfo(context.Background(), 0); err == nil || IsNotSupported(err) {
+		t.Errorf("getInfo on a permission error = %v, want a failure", err)
+	}
+}
+
+func TestGetReadings(t *testing.T) {
+	rsmiDevTempMetricGet = func(_ uint32, sensor, metric uint32, v *int64) Status {
+		if sensor != temperatureJunction || metric != temperatureCurrent {
+			return ErrorInvalidArgs
+		}
+		*v = 45500
+		return Success
+	}
+	rsmiDevMemoryTotalGet = func(_ uint32, _ uint32, v *uint64) Status { *v = 192 << 30; return Success }
+	rsmiDevMemoryUsageGet = func(_ uint32, _ uint32, v *uint64) Status { *v = 8 << 30; return Success }
+	rsmiDevPowerAveGet = func(uint32, uint32, *uint64) Status { return ErrorNoData }
+
+	ctx := context.Background()
+	if temp, err := librsmi.getTemperature(ctx, 0); err != nil || temp != 45.5 {
+		t.Errorf("getTemperature = %v, %v, want 45.5", temp, err)
+	}
+	if mem, err := librsmi.getMemory(ctx, 0); err != nil || mem != (Memory{Total: 192 << 30, Used: 8 << 30}) {
+		t.Errorf("getMemory = %+v, %v", mem, err)
+	}
+	if _, err := librsmi.getPower(ctx, 0); !IsNotSupported(err) {
+		t.Errorf("getPower = %v, want not supported", err)
+	}
+
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	if _, err := librsmi.getMemory(canceled, 0); err == nil {
+		t.Error("getMemory on a canceled context succeeded")
+	}
+}
+
+func TestXgmiCounters(t *testing.T) {
+	rsmiDevCounterGroupSupported = func(uint32, uint32) Status { return Success }
+	rsmiDevCounterCreate = func(_ uint32, event uint32, handle *uintptr) Status {
+		*handle = uintptr(event)
+		return Success
+	}
+	rsmiCounterControl = func(uintptr, uint32, uintptr) Status { return Success }
+	rsmiCounterRead = func(handle uintptr, v *counterValue) Status {
+		// the beats of link n are n+1.
+		v.value = uint64(handle) - uint64(eventGroupXgmiDataOut) + 1
+		return Success
+	}
+	destroyed := 0
+	rsmiDevCounterDestroy = func(uintptr) Status { destroyed++; return Success }
+
+	counters, err := librsmi.newXgmiCounters(0)
+	if err != nil {
+		t.Fatalf("newXgmiCounters: %v", err)
+	}
+	links, err := counters.List(context.Background())
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if len(links) != XgmiMaxLinks {
+		t.Fatalf("List = %d links, want %d", len(links), XgmiMaxLinks)
+	}
+	for i, link := range links {
+		if link.Link != uint32(i) || link.Transmit != uint64(i+1)*xgmiBeatBytes {
+			t.Errorf("link %d = %+v, want %d bytes", i, link, (i+1)*xgmiBeatBytes)
+		}
+	}
+
+	counters.Close()
+	if destroyed != XgmiMaxLinks {
+		t.Errorf("Close destroyed %d counters, want %d", destroyed, XgmiMaxLinks)
+	}
+}
+
+func TestSetLibraryPath(t *testing.T) {
+	l := &library{}
+	if err := l.setPath("/custom/librocm_smi64.so"); err != nil || l.dl == nil {
+		t.Fatalf("setPath before load = %v", err)
+	}
+
+	loaded := l.dl
+	l.refcount = 1
+	if err := l.setPath(DefaultLibraryPath); err == nil || l.dl != loaded {
+		t.Errorf("setPath while loaded = %v, want an error and the library kept", err)
+	}
+}
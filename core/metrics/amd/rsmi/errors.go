@@ -0,0 +1,100 @@
+// Copyright 2026 The HuaTuo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rsmi
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+)
+
+// rsmi_status_t, the names of rocm_smi.h.
+//
+//nolint:errname
+const (
+	Success           Status = 0
+	ErrorInvalidArgs  Status = 1
+	ErrorNotSupported Status = 2
+	ErrorPermission   Status = 4
+	ErrorNotFound     Status = 10
+	ErrorNoData       Status = 14
+)
+
+var statusNames = map[Status]string{
+	0:  "RSMI_STATUS_SUCCESS",
+	1:  "RSMI_STATUS_INVALID_ARGS",
+	2:  "RSMI_STATUS_NOT_SUPPORTED",
+	3:  "RSMI_STATUS_FILE_ERROR",
+	4:  "RSMI_STATUS_PERMISSION",
+	5:  "RSMI_STATUS_OUT_OF_RESOURCES",
+	6:  "RSMI_STATUS_INTERNAL_EXCEPTION",
+	7:  "RSMI_STATUS_INPUT_OUT_OF_BOUNDS",
+	8:  "RSMI_STATUS_INIT_ERROR",
+	9:  "RSMI_STATUS_NOT_YET_IMPLEMENTED",
+	10: "RSMI_STATUS_NOT_FOUND",
+	11: "RSMI_STATUS_INSUFFICIENT_SIZE",
+	12: "RSMI_STATUS_INTERRUPT",
+	13: "RSMI_STATUS_UNEXPECTED_SIZE",
+	14: "RSMI_STATUS_NO_DATA",
+	15: "RSMI_STATUS_UNEXPECTED_DATA",
+	16: "RSMI_STATUS_BUSY",
+	17: "RSMI_STATUS_REFCOUNT_OVERFLOW",
+}
+
+// String returns the name of the status. rsmi_status_string is not used,
+// it returns the message by a C string pointer.
+func (s Status) String() string {
+	if name, ok := statusNames[s]; ok {
+		return name
+	}
+	return "RSMI_STATUS_" + strconv.Itoa(int(s))
+}
+
+type Error struct {
+	symbol string
+	code   Status
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s failed: %s", e.symbol, e.code.String())
+}
+
+// IsNotSupported reports whether err represents an operation the device or
+// the driver does not support.
+func IsNotSupported(err error) bool {
+	var rsmiErr *Error
+	if !errors.As(err, &rsmiErr) {
+		return false
+	}
+
+	switch rsmiErr.code {
+	case ErrorNotSupported, ErrorNotFound, ErrorNoData:
+		return true
+	default:
+		return false
+	}
+}
+
+// checkReturnCode converts a return code to an error.
+func checkReturnCode(symbol string, code Status) error {
+	if code == Success {
+		return nil
+	}
+
+	return &Error{
+		symbol: symbol,
+		code:   code,
+	}
+}
@@ -0,0 +1,31 @@
+// Copyright 2026 The HuaTuo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rsmi
+
+// Init loads and initializes the ROCm SMI library.
+func Init() error {
+	if err := librsmi.load(); err != nil {
+		return err
+	}
+	return checkReturnCode("rsmi_init", rsmiInit(0))
+}
+
+// Shutdown shuts down the ROCm SMI library.
+func Shutdown() error {
+	if err := checkReturnCode("rsmi_shut_down", rsmiShutDown()); err != nil {
+		return err
+	}
+	return librsmi.close()
+}
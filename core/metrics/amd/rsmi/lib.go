@@ -0,0 +1,135 @@
+// Copyright 2026 The HuaTuo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rsmi
+
+import (
+	"errors"
+	"sync"
+
+	"huatuo-bamai/core/metrics/metax/dl"
+
+	"github.com/ebitengine/purego"
+)
+
+// dynamicLibrary abstracts a dynamically loaded shared library.
+type dynamicLibrary interface {
+	Open() error
+	Close() error
+	Handle() uintptr
+}
+
+// library represents the ROCm SMI shared library, loaded once and shared
+// by reference counting.
+type library struct {
+	sync.Mutex
+	refcount int32
+	dl       dynamicLibrary
+}
+
+// DefaultLibraryPath is the ROCm SMI library of the ROCm default install.
+const DefaultLibraryPath = "/opt/rocm/lib/librocm_smi64.so"
+
+// global singleton instance
+var librsmi = &library{
+	dl: newDynamicLibrary(DefaultLibraryPath),
+}
+
+func newDynamicLibrary(path string) dynamicLibrary {
+	return dl.New(path, purego.RTLD_NOW|purego.RTLD_GLOBAL)
+}
+
+// SetLibraryPath sets the path of the ROCm SMI library loaded by Init, it
+// fails while the library is loaded.
+func SetLibraryPath(path string) error {
+	return librsmi.setPath(path)
+}
+
+func (l *library) setPath(path string) error {
+	l.Lock()
+	defer l.Unlock()
+
+	if l.refcount > 0 {
+		return errors.New("rsmi library is already loaded")
+	}
+
+	l.dl = newDynamicLibrary(path)
+	return nil
+}
+
+// load opens the shared library and registers all required symbols.
+// Multiple calls are reference-counted and idempotent.
+func (l *library) load() error {
+	l.Lock()
+	defer l.Unlock()
+
+	if l.refcount > 0 {
+		l.refcount++
+		return nil
+	}
+
+	if err := l.dl.Open(); err != nil {
+		return err
+	}
+
+	l.registerRsmiLibSymbols(l.dl.Handle())
+	l.refcount++
+	return nil
+}
+
+// close decrements the reference count and unloads the library when the
+// last reference is released.
+func (l *library) close() error {
+	l.Lock()
+	defer l.Unlock()
+
+	if l.refcount == 0 {
+		return nil
+	}
+	if l.refcount > 1 {
+		l.refcount--
+		return nil
+	}
+
+	if err := l.dl.Close(); err != nil {
+		return err
+	}
+	l.refcount--
+	return nil
+}
+
+// registerRsmiLibSymbols registers all required ROCm SMI symbols from the
+// loaded shared library.
+func (l *library) registerRsmiLibSymbols(handle uintptr) {
+	purego.RegisterLibFunc(&rsmiInit, handle, "rsmi_init")
+	purego.RegisterLibFunc(&rsmiShutDown, handle, "rsmi_shut_down")
+	purego.RegisterLibFunc(&rsmiVersionStrGet, handle, "rsmi_version_str_get")
+	purego.RegisterLibFunc(&rsmiNumMonitorDevices, handle, "rsmi_num_monitor_devices")
+	purego.RegisterLibFunc(&rsmiDevNameGet, handle, "rsmi_dev_name_get")
+	purego.RegisterLibFunc(&rsmiDevUniqueIDGet, handle, "rsmi_dev_unique_id_get")
+	purego.RegisterLibFunc(&rsmiDevPciIDGet, handle, "rsmi_dev_pci_id_get")
+	purego.RegisterLibFunc(&rsmiDevVbiosVersionGet, handle, "rsmi_dev_vbios_version_get")
+	purego.RegisterLibFunc(&rsmiDevBusyPercentGet, handle, "rsmi_dev_busy_percent_get")
+	purego.RegisterLibFunc(&rsmiDevMemoryBusyPercentGet, handle, "rsmi_dev_memory_busy_percent_get")
+	purego.RegisterLibFunc(&rsmiDevMemoryTotalGet, handle, "rsmi_dev_memory_total_get")
+	purego.RegisterLibFunc(&rsmiDevMemoryUsageGet, handle, "rsmi_dev_memory_usage_get")
+	purego.RegisterLibFunc(&rsmiDevPowerAveGet, handle, "rsmi_dev_power_ave_get")
+	purego.RegisterLibFunc(&rsmiDevTempMetricGet, handle, "rsmi_dev_temp_metric_get")
+	purego.RegisterLibFunc(&rsmiDevEccCountGet, handle, "rsmi_dev_ecc_count_get")
+	purego.RegisterLibFunc(&rsmiDevCounterGroupSupported, handle, "rsmi_dev_counter_group_supported")
+	purego.RegisterLibFunc(&rsmiDevCounterCreate, handle, "rsmi_dev_counter_create")
+	purego.RegisterLibFunc(&rsmiCounterControl, handle, "rsmi_counter_control")
+	purego.RegisterLibFunc(&rsmiCounterRead, handle, "rsmi_counter_read")
+	purego.RegisterLibFunc(&rsmiDevCounterDestroy, handle, "rsmi_dev_counter_destroy")
+}
@@ -0,0 +1,113 @@
+// Copyright 2026 The HuaTuo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rsmi
+
+// Status is rsmi_status_t.
+type Status int32
+
+// the constants of rocm_smi.h used by the callers.
+const (
+	// RSMI_SW_COMP_DRIVER
+	swComponentDriver uint32 = 0
+	// RSMI_MEM_TYPE_VRAM
+	memoryTypeVram uint32 = 0
+	// RSMI_TEMP_TYPE_JUNCTION, the hotspot of the die.
+	temperatureJunction uint32 = 1
+	// RSMI_TEMP_CURRENT
+	temperatureCurrent uint32 = 0
+	// RSMI_GPU_BLOCK_UMC, the HBM memory controller.
+	gpuBlockUmc uint64 = 0x1
+	// RSMI_CNTR_CMD_START
+	counterCmdStart uint32 = 0
+)
+
+// RSMI_EVNT_GRP_XGMI_DATA_OUT and its events RSMI_EVNT_XGMI_DATA_OUT_0..5,
+// the beats sent to each XGMI neighbor.
+const (
+	eventGroupXgmiDataOut uint32 = 10
+	XgmiMaxLinks                 = 6
+	// each beat carries 32 bytes.
+	xgmiBeatBytes = 32
+)
+
+// buffer sizes, rocm_smi has no constants for them.
+const (
+	nameBufferSize    = 256
+	versionBufferSize = 256
+)
+
+// ErrorCount is rsmi_error_count_t, deferred_err is only filled by recent
+// libraries.
+type ErrorCount struct {
+	Correctable   uint64
+	Uncorrectable uint64
+	_             uint64 // deferred_err, not used yet.
+}
+
+// counterValue is rsmi_counter_value_t.
+type counterValue struct {
+	value uint64
+	_     uint64 // time_enabled, not used yet.
+	_     uint64 // time_running, not used yet.
+}
+
+// Info is the identity of a GPU.
+type Info struct {
+	Name        string
+	UniqueID    uint64
+	BiosVersion string
+	BDF         string
+}
+
+// Memory is the VRAM usage in bytes.
+type Memory struct {
+	Total uint64
+	Used  uint64
+}
+
+// XgmiThroughput is the data sent over an XGMI link in bytes.
+type XgmiThroughput struct {
+	Link     uint32
+	Transmit uint64
+}
+
+// ROCm SMI API RAW SYMBOLS
+var (
+	// Initialization symbols
+	rsmiInit          func(uint64) Status
+	rsmiShutDown      func() Status
+	rsmiVersionStrGet func(uint32, *byte, uint32) Status
+
+	// Device symbols
+	rsmiNumMonitorDevices       func(*uint32) Status
+	rsmiDevNameGet              func(uint32, *byte, uint64) Status
+	rsmiDevUniqueIDGet          func(uint32, *uint64) Status
+	rsmiDevPciIDGet             func(uint32, *uint64) Status
+	rsmiDevVbiosVersionGet      func(uint32, *byte, uint32) Status
+	rsmiDevBusyPercentGet       func(uint32, *uint32) Status
+	rsmiDevMemoryBusyPercentGet func(uint32, *uint32) Status
+	rsmiDevMemoryTotalGet       func(uint32, uint32, *uint64) Status
+	rsmiDevMemoryUsageGet       func(uint32, uint32, *uint64) Status
+	rsmiDevPowerAveGet          func(uint32, uint32, *uint64) Status
+	rsmiDevTempMetricGet        func(uint32, uint32, uint32, *int64) Status
+	rsmiDevEccCountGet          func(uint32, uint64, *ErrorCount) Status
+
+	// Counter symbols
+	rsmiDevCounterGroupSupported func(uint32, uint32) Status
+	rsmiDevCounterCreate         func(uint32, uint32, *uintptr) Status
+	rsmiCounterControl           func(uintptr, uint32, uintptr) Status
+	rsmiCounterRead              func(uintptr, *counterValue) Status
+	rsmiDevCounterDestroy        func(uintptr) Status
+)
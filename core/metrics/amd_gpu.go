@@ -0,0 +1,245 @@
+// Copyright 2026 The HuaTuo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"huatuo-bamai/core/metrics/amd/rsmi"
+	"huatuo-bamai/internal/log"
+	"huatuo-bamai/pkg/metric"
+	"huatuo-bamai/pkg/tracing"
+	"huatuo-bamai/pkg/types"
+)
+
+func init() {
+	tracing.RegisterEventTracing("amd_gpu", newAmdGpuCollector)
+}
+
+// ROCm SMI reports every GCD of a multi-die package as a GPU of its own, so
+// each GPU has the single die 0.
+const amdGpuDie = "0"
+
+type amdGpuCollector struct {
+	count uint32
+	// xgmi holds the started counters of each GPU, nil when not supported.
+	xgmi map[uint32]*rsmi.XgmiCounters
+}
+
+func newAmdGpuCollector() (*tracing.EventTracingAttr, error) {
+	path := cfg.AmdGpu.LibraryPath
+	if path == "" {
+		path = rsmi.DefaultLibraryPath
+	}
+	if err := rsmi.SetLibraryPath(path); err != nil {
+		return nil, err
+	}
+
+	// Init ROCm SMI lib, absent without ROCm.
+	if err := rsmi.Init(); err != nil {
+		log.Debugf("amd gpu init rsmi %s: %v", path, err)
+		return nil, types.ErrNotSupported
+	}
+
+	count, err := rsmi.GetGPUCount()
+	if err != nil || count == 0 {
+		_ = rsmi.Shutdown()
+		return nil, types.ErrNotSupported
+	}
+
+	xgmi := make(map[uint32]*rsmi.XgmiCounters, count)
+	for gpuId := uint32(0); gpuId < count; gpuId++ {
+		counters, err := rsmi.NewXgmiCounters(gpuId)
+		if err != nil {
+			log.Debugf("xgmi counters not available on gpu %d: %v", gpuId, err)
+			continue
+		}
+		xgmi[gpuId] = counters
+	}
+
+	return &tracing.EventTracingAttr{
+		TracingData: &amdGpuCollector{count: count, xgmi: xgmi},
+		Flag:        tracing.FlagMetric,
+	}, nil
+}
+
+func (a *amdGpuCollector) Update() ([]*metric.Data, error) {
+	ctx := context.Background()
+	var metrics []*metric.Data
+
+	// Driver version
+	operationGetDriverVersion := "get driver version"
+	driverVersion, err := rsmi.GetDriverVersion()
+	if err != nil {
+		if !rsmi.IsNotSupported(err) {
+			return nil, fmt.Errorf("failed to %s: %w", operationGetDriverVersion, err)
+		}
+		log.Debugf("operation %s not supported", operationGetDriverVersion)
+	} else {
+		metrics = append(metrics, metric.NewGaugeData("driver_info", 1, "GPU driver info.", map[string]string{
+			"version": driverVersion,
+		}))
+	}
+
+	// GPU
+	for gpuId := uint32(0); gpuId < a.count; gpuId++ {
+		gpuMetrics, err := amdCollectGpuMetrics(ctx, gpuId, a.xgmi[gpuId])
+		if err != nil {
+			return nil, fmt.Errorf("failed to collect gpu %d metrics: %w", gpuId, err)
+		}
+		metrics = append(metrics, gpuMetrics...)
+	}
+
+	return metrics, nil
+}
+
+// amdCollectGpuMetrics gathers raw GPU metrics for a single GPU.
+func amdCollectGpuMetrics(ctx context.Context, gpuId uint32, xgmi *rsmi.XgmiCounters) ([]*metric.Data, error) {
+	var metrics []*metric.Data
+	gpuLabel := strconv.Itoa(int(gpuId))
+
+	// GPU info
+	info, err := rsmi.GetGPUInfo(ctx, gpuId)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get gpu info: %w", err)
+	}
+	metrics = append(metrics, metric.NewGaugeData("info", 1, "GPU info.", map[string]string{
+		"gpu":          gpuLabel,
+		"model":        info.Name,
+		"uuid":         fmt.Sprintf("%016x", info.UniqueID),
+		"bios_version": info.BiosVersion,
+		"bdf":          info.BDF,
+	}))
+	metrics = append(metrics, metric.NewGaugeData("topology_info", 1, "GPU topology info.", gpuTopologyLabels(gpuLabel, info.BDF)))
+
+	// Board power
+	operationGetPower := "get power"
+	power, err := rsmi.GetPower(ctx, gpuId)
+	if err != nil {
+		if !rsmi.IsNotSupported(err) {
+			return nil, fmt.Errorf("failed to %s: %w", operationGetPower, err)
+		}
+		log.Debugf("operation %s not supported on gpu %d", operationGetPower, gpuId)
+	} else {
+		metrics = append(metrics, metric.NewGaugeData("board_power_watts", float64(power)/1000/1000, "GPU board power.", map[string]string{
+			"gpu": gpuLabel,
+		}))
+	}
+
+	// XGMI throughput
+	if xgmi != nil {
+		links, err := xgmi.List(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list xgmi throughputs: %w", err)
+		}
+		for _, link := range links {
+			metrics = append(metrics, metric.NewCounterData("xgmi_transmit_bytes_total", float64(link.Transmit), "GPU XGMI transmit data size.", map[string]string{
+				"gpu":  gpuLabel,
+				"xgmi": strconv.Itoa(int(link.Link)),
+			}))
+		}
+	}
+
+	// Temperature
+	operationGetTemperature := "get temperature"
+	temperature, err := rsmi.GetTemperature(ctx, gpuId)
+	if err != nil {
+		if !rsmi.IsNotSupported(err) {
+			return nil, fmt.Errorf("failed to %s: %w", operationGetTemperature, err)
+		}
+		log.Debugf("operation %s not supported on gpu %d", operationGetTemperature, gpuId)
+	} else {
+		metrics = append(metrics, metric.NewGaugeData("temperature_celsius", temperature, "GPU temperature.", map[string]string{
+			"gpu": gpuLabel,
+			"die": amdGpuDie,
+		}))
+	}
+
+	// Utilization
+	operationGetUtilization := "get utilization"
+	gfx, memory, err := rsmi.GetUtilization(ctx, gpuId)
+	if err != nil {
+		if !rsmi.IsNotSupported(err) {
+			return nil, fmt.Errorf("failed to %s: %w", operationGetUtilization, err)
+		}
+		log.Debugf("operation %s not supported on gpu %d", operationGetUtilization, gpuId)
+	} else {
+		metrics = append(
+			metrics,
+			metric.NewGaugeData("utilization_percent", float64(gfx), "GPU utilization, ranging from 0 to 100.", map[string]string{
+				"gpu": gpuLabel,
+				"die": amdGpuDie,
+				"ip":  "gfx",
+			}),
+			metric.NewGaugeData("utilization_percent", float64(memory), "GPU utilization, ranging from 0 to 100.", map[string]string{
+				"gpu": gpuLabel,
+				"die": amdGpuDie,
+				"ip":  "memory",
+			}),
+		)
+	}
+
+	// Memory
+	operationGetMemory := "get memory info"
+	vram, err := rsmi.GetMemory(ctx, gpuId)
+	if err != nil {
+		if !rsmi.IsNotSupported(err) {
+			return nil, fmt.Errorf("failed to %s: %w", operationGetMemory, err)
+		}
+		log.Debugf("operation %s not supported on gpu %d", operationGetMemory, gpuId)
+	} else {
+		metrics = append(
+			metrics,
+			metric.NewGaugeData("memory_total_bytes", float64(vram.Total), "Total vram.", map[string]string{
+				"gpu": gpuLabel,
+				"die": amdGpuDie,
+			}),
+			metric.NewGaugeData("memory_used_bytes", float64(vram.Used), "Used vram.", map[string]string{
+				"gpu": gpuLabel,
+				"die": amdGpuDie,
+			}),
+		)
+	}
+
+	// Ecc memory
+	operationGetEccMemoryErrors := "get ecc memory errors"
+	ecc, err := rsmi.GetEccMemoryErrors(ctx, gpuId)
+	if err != nil {
+		if !rsmi.IsNotSupported(err) {
+			return nil, fmt.Errorf("failed to %s: %w", operationGetEccMemoryErrors, err)
+		}
+		log.Debugf("operation %s not supported on gpu %d", operationGetEccMemoryErrors, gpuId)
+	} else {
+		metrics = append(
+			metrics,
+			metric.NewCounterData("ecc_memory_errors_total", float64(ecc.Correctable), "GPU ECC memory errors count.", map[string]string{
+				"gpu":         gpuLabel,
+				"die":         amdGpuDie,
+				"memory_type": "dram",
+				"error_type":  "ce",
+			}),
+			metric.NewCounterData("ecc_memory_errors_total", float64(ecc.Uncorrectable), "GPU ECC memory errors count.", map[string]string{
+				"gpu":         gpuLabel,
+				"die":         amdGpuDie,
+				"memory_type": "dram",
+				"error_type":  "ue",
+			}),
+		)
+	}
+
+	return metrics, nil
+}
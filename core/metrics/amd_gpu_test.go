@@ -0,0 +1,93 @@
+// Copyright 2026 The HuaTuo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"huatuo-bamai/core/metrics/amd/rsmi"
+)
+
+// fakeRsmi replaces the ROCm SMI API by fixed readings, restored at the end
+// of the test.
+func fakeRsmi(t *testing.T) {
+	getDriverVersion, getInfo, getPower := rsmi.GetDriverVersion, rsmi.GetGPUInfo, rsmi.GetPower
+	getTemperature, getUtilization := rsmi.GetTemperature, rsmi.GetUtilization
+	getMemory, getEcc := rsmi.GetMemory, rsmi.GetEccMemoryErrors
+	t.Cleanup(func() {
+		rsmi.GetDriverVersion, rsmi.GetGPUInfo, rsmi.GetPower = getDriverVersion, getInfo, getPower
+		rsmi.GetTemperature, rsmi.GetUtilization = getTemperature, getUtilization
+		rsmi.GetMemory, rsmi.GetEccMemoryErrors = getMemory, getEcc
+	})
+
+	rsmi.GetDriverVersion = func() (string, error) { return "6.8.5", nil }
+	rsmi.GetGPUInfo = func(context.Context, uint32) (rsmi.Info, error) {
+		return rsmi.Info{Name: "MI300X", UniqueID: 0xbeef, BDF: "0000:2b:00.0"}, nil
+	}
+	rsmi.GetPower = func(context.Context, uint32) (uint64, error) { return 250_000_000, nil }
+	rsmi.GetTemperature = func(context.Context, uint32) (float64, error) { return 45.5, nil }
+	rsmi.GetUtilization = func(context.Context, uint32) (uint32, uint32, error) { return 80, 30, nil }
+	rsmi.GetMemory = func(context.Context, uint32) (rsmi.Memory, error) {
+		return rsmi.Memory{Total: 192 << 30, Used: 8 << 30}, nil
+	}
+	rsmi.GetEccMemoryErrors = func(context.Context, uint32) (rsmi.ErrorCount, error) {
+		return rsmi.ErrorCount{Correctable: 3, Uncorrectable: 1}, nil
+	}
+}
+
+func TestAmdCollectGpuMetrics(t *testing.T) {
+	fakeRsmi(t)
+
+	data, err := amdCollectGpuMetrics(context.Background(), 0, nil)
+	if err != nil {
+		t.Fatalf("amdCollectGpuMetrics() error = %v", err)
+	}
+
+	// info, topology_info, board power, temperature, gfx and memory
+	// utilization, vram total and used, correctable and uncorrectable ecc.
+	want := []float64{1, 1, 250, 45.5, 80, 30, 192 << 30, 8 << 30, 3, 1}
+	if len(data) != len(want) {
+		t.Fatalf("amdCollectGpuMetrics() returned %d metrics, want %d", len(data), len(want))
+	}
+	for i, v := range want {
+		if data[i].Value != v {
+			t.Errorf("amdCollectGpuMetrics()[%d] = %v, want %v", i, data[i].Value, v)
+		}
+	}
+
+	// an unexpected error of the library fails the collection.
+	rsmi.GetTemperature = func(context.Context, uint32) (float64, error) {
+		return 0, errors.New("rsmi_dev_temp_metric_get failed")
+	}
+	if _, err := amdCollectGpuMetrics(context.Background(), 0, nil); err == nil {
+		t.Error("amdCollectGpuMetrics() with a failing temperature error = nil")
+	}
+}
+
+func TestAmdGpuUpdate(t *testing.T) {
+	fakeRsmi(t)
+
+	collector := &amdGpuCollector{count: 2, xgmi: map[uint32]*rsmi.XgmiCounters{}}
+	data, err := collector.Update()
+	if err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	// driver_info and the metrics of both GPUs.
+	if len(data) != 1+2*10 {
+		t.Errorf("Update() returned %d metrics, want 21", len(data))
+	}
+}
@@ -0,0 +1,85 @@
+// Copyright 2026 The HuaTuo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"sync"
+	"time"
+
+	"huatuo-bamai/internal/pod"
+)
+
+// cgroupCounters caches the counters of the cgroups of the containers
+// between two collections, for their increase.
+type cgroupCounters struct {
+	mutex sync.Mutex
+	last  map[string]*cgroupCounterSample
+}
+
+type cgroupCounterSample struct {
+	values []uint64
+	at     time.Time
+}
+
+// cgroupCounterDelta is the increase of the counters of a cgroup between
+// two collections, in the order they were passed to delta.
+type cgroupCounterDelta struct {
+	values   []uint64
+	interval time.Duration
+}
+
+func newCgroupCounters() *cgroupCounters {
+	return &cgroupCounters{last: make(map[string]*cgroupCounterSample)}
+}
+
+// delta caches the counters of the cgroup and returns their increase since
+// the last collection, nil on the first one. A counter going backwards
+// means the cgroup was recreated, nil too.
+func (c *cgroupCounters) delta(cgroup string, now time.Time, values ...uint64) *cgroupCounterDelta {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	prev, ok := c.last[cgroup]
+	c.last[cgroup] = &cgroupCounterSample{values: values, at: now}
+	if !ok || len(prev.values) != len(values) || !now.After(prev.at) {
+		return nil
+	}
+
+	delta := &cgroupCounterDelta{values: make([]uint64, len(values)), interval: now.Sub(prev.at)}
+	for i, v := range values {
+		if v < prev.values[i] {
+			return nil
+		}
+		delta.values[i] = v - prev.values[i]
+	}
+	return delta
+}
+
+// retain drops the cgroups of the containers gone.
+func (c *cgroupCounters) retain(containers map[string]*pod.Container) {
+	cgroups := make(map[string]struct{}, len(containers))
+	for _, container := range containers {
+		cgroups[container.CgroupPath] = struct{}{}
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	for cgroup := range c.last {
+		if _, ok := cgroups[cgroup]; !ok {
+			delete(c.last, cgroup)
+		}
+	}
+}
@@ -0,0 +1,56 @@
+// Copyright 2026 The HuaTuo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"huatuo-bamai/internal/pod"
+)
+
+func TestCgroupCounters(t *testing.T) {
+	counters := newCgroupCounters()
+	start := time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC)
+
+	if delta := counters.delta("/kubepods/a", start, 100, 10); delta != nil {
+		t.Fatalf("first delta() = %+v, want nil", delta)
+	}
+
+	delta := counters.delta("/kubepods/a", start.Add(10*time.Second), 300, 10)
+	if delta == nil || !slices.Equal(delta.values, []uint64{200, 0}) || delta.interval != 10*time.Second {
+		t.Fatalf("delta() = %+v", delta)
+	}
+
+	// a counter going backwards, the cgroup recreated.
+	if delta := counters.delta("/kubepods/a", start.Add(20*time.Second), 400, 5); delta != nil {
+		t.Errorf("delta() of a recreated cgroup = %+v, want nil", delta)
+	}
+	if delta := counters.delta("/kubepods/a", start.Add(30*time.Second), 500, 6); delta == nil ||
+		!slices.Equal(delta.values, []uint64{100, 1}) {
+		t.Errorf("delta() after the recreation = %+v", delta)
+	}
+
+	// the cgroups are apart.
+	if delta := counters.delta("/kubepods/b", start.Add(30*time.Second), 1000, 1000); delta != nil {
+		t.Errorf("first delta() of another cgroup = %+v, want nil", delta)
+	}
+
+	counters.retain(map[string]*pod.Container{"b": {CgroupPath: "/kubepods/b"}})
+	if _, ok := counters.last["/kubepods/a"]; ok || len(counters.last) != 1 {
+		t.Errorf("retain() left %v", counters.last)
+	}
+}
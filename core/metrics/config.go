@@ -16,50 +16,233 @@ package collector
 
 // Config holds metric collector configuration used by the package at runtime.
 type Config struct {
+	AmdGpu struct {
+		// LibraryPath is the ROCm SMI library, the ROCm default install
+		// when empty.
+		LibraryPath string
+	} `tracer:"amd_gpu"`
+
 	AscendNPU struct {
 		EnableDCMI bool `default:"true"`
 		EnablePCIe bool `default:"false"`
 		EnableHCCN bool `default:"false"`
+	} `tracer:"ascend_npu"`
+
+	FirmwareInventory struct {
+		Interval  int `default:"600"`
+		EnableBMC bool
+		// Golden declares the fleet firmware versions of a component,
+		// optionally narrowed to a model.
+		Golden []struct {
+			Component string
+			Model     string
+			Versions  []string
+		} `toml:"Golden,omitempty"`
+	} `tracer:"firmware_inventory"`
+
+	// GPUDirect checks the GPUDirect RDMA prerequisites of every GPU/NIC
+	// pair, PeerMemModules are the modules registering the GPU memory to
+	// the RDMA stack, any of them loaded is enough, empty for the NVIDIA
+	// ones.
+	GPUDirect struct {
+		Interval       int `default:"60"`
+		PeerMemModules []string
+	} `tracer:"gpudirect"`
+
+	// GPUSimulation replaces the GPU libraries of Vendors by simulated
+	// devices, for CI and dashboard demos on nodes without hardware.
+	// Behaviors are idle, busy, hot, ecc or failed by GPU index, repeated
+	// over the GPUs. NotSupported and Failures inject return codes into
+	// the library symbols, e.g. "nvmlDeviceGetPowerUsage". Empty Vendors
+	// simulates all of them.
+	GPUSimulation struct {
+		Enable       bool
+		Vendors      []string
+		GPUs         int `default:"2"`
+		Behaviors    []string
+		NotSupported []string
+		Failures     []struct {
+			Symbol string
+			Code   int32
+		} `toml:"Failures,omitempty"`
 	}
 
+	KernelPatch struct {
+		Interval int `default:"300"`
+	} `tracer:"kernel_patch"`
+
+	NodeMaintenance struct {
+		Interval int    `default:"300"`
+		CrashDir string `default:"/var/crash"`
+		// MaxUptimeDays is the fleet uptime policy, 0 disables it.
+		MaxUptimeDays int
+	} `tracer:"node_maintenance"`
+
+	MetaxGpu struct {
+		IdleFullInterval int `default:"60"`
+		// FaultInterval polls the faults stored as gpu_fault events, 0
+		// disables it. FaultEccThreshold is the uncorrectable errors of a
+		// die between two polls reported.
+		FaultInterval     int `default:"10"`
+		FaultEccThreshold int `default:"1"`
+		// Concurrency is the goroutines calling into SML, Timeout is the
+		// seconds budget of a collection.
+		Concurrency int `default:"4"`
+		Timeout     int `default:"5"`
+		// LibraryPath is the SML library, SearchPaths are tried in order
+		// when it is empty.
+		LibraryPath string
+		SearchPaths []string
+	} `tracer:"metax_gpu"`
+
 	NetdevStats struct {
 		EnableNetlink  bool `default:"false"`
 		DeviceExcluded string
 		DeviceIncluded string
-	}
+	} `tracer:"netdev"`
 
 	NetdevDCB struct {
 		DeviceList []string
-	}
+	} `tracer:"netdev_dcb"`
 
 	NetdevHW struct {
 		DeviceList []string
-	}
+	} `tracer:"netdev_hw"`
 
+	// Qdisc exports the classes of the devices of ClassDeviceList, and
+	// saves a netdev_qdisc event when the drops of the root qdisc of a
+	// device reach EventDropRate per second and doubled since the last
+	// collection, 0 disables the events. EventInterval is the minimum
+	// seconds between two events of a device.
 	Qdisc struct {
-		DeviceExcluded string
-		DeviceIncluded string
-	}
+		DeviceExcluded  string
+		DeviceIncluded  string
+		ClassDeviceList []string
+		EventDropRate   int `default:"100" min:"0"`
+		EventInterval   int `default:"300"`
+	} `tracer:"netdev_qdisc"`
 
 	Vmstat struct {
 		IncludedOnHost      string
 		ExcludedOnHost      string
 		IncludedOnContainer string
 		ExcludedOnContainer string
-	}
+	} `tracer:"memory_vmstat"`
 
 	MemoryEvents struct {
 		Included string
 		Excluded string
-	}
+	} `tracer:"memory_events"`
 
 	Netstat struct {
 		Included string
 		Excluded string
-	}
+	} `tracer:"netstat"`
 
 	MountPointStat struct {
 		MountPointsIncluded string
+	} `tracer:"mountpoint_perm"`
+
+	// FilesystemStat skips the mounts of the pseudo filesystems and of the
+	// containers, and walks the writable layer and the emptyDir volumes
+	// of a container every ContainerInterval seconds.
+	FilesystemStat struct {
+		FSTypesExcluded     string `default:"^(autofs|binfmt_misc|bpf|cgroup2?|configfs|debugfs|devpts|devtmpfs|fusectl|hugetlbfs|iso9660|mqueue|nsfs|overlay|proc|pstore|rpc_pipefs|securityfs|selinuxfs|squashfs|sysfs|tracefs)$"`
+		MountPointsExcluded string `default:"^/(dev|proc|sys|run/credentials/.+|run/containerd/.+|var/lib/containerd/.+|var/lib/docker/.+|var/lib/kubelet/pods/.+)($|/)"`
+		ContainerInterval   int    `default:"300" min:"10"`
+	} `tracer:"filesystem"`
+
+	// Conntrack dumps the conntrack table every DumpInterval seconds for
+	// the entries per protocol, 0 disables it, and saves a conntrack
+	// event of its TopTalkers source addresses when more than
+	// EventThreshold percent of nf_conntrack_max is used, 0 disables the
+	// events. EventInterval is the minimum seconds between two events.
+	// The tables of more than DumpMaxEntries entries are not dumped.
+	Conntrack struct {
+		DumpInterval   int `default:"60" min:"0"`
+		DumpMaxEntries int `default:"1048576" min:"1"`
+		EventThreshold int `default:"90" min:"0" max:"100"`
+		EventInterval  int `default:"600"`
+		TopTalkers     int `default:"10" min:"1"`
+	} `tracer:"conntrack"`
+
+	// Softirq records the stacks of the NET_RX softirqs running longer
+	// than NetRxThreshold nanoseconds, 0 disables it, and saves a softirq
+	// event of the TopStacks ones every EventInterval seconds at most.
+	Softirq struct {
+		NetRxThreshold uint64 `default:"2000000"`
+		EventInterval  int    `default:"300" min:"10"`
+		TopStacks      int    `default:"10" min:"1"`
+	} `tracer:"softirq"`
+
+	// Hardirq saves a hardirq event of its TopIRQs interrupts when a cpu
+	// handles EventThreshold percent or more of the device interrupts of
+	// the host, 0 disables the events, and at least EventMinRate of them
+	// per second. EventInterval is the minimum seconds between two events.
+	Hardirq struct {
+		EventThreshold int `default:"50" min:"0" max:"100"`
+		EventMinRate   int `default:"5000" min:"0"`
+		EventInterval  int `default:"600"`
+		TopIRQs        int `default:"10" min:"1"`
+	} `tracer:"hardirq"`
+
+	DNSCache struct {
+		Server         string `default:"169.254.20.10:53"`
+		UpstreamServer string
+		QueryName      string `default:"kubernetes.default.svc.cluster.local"`
+		MetricsURL     string `default:"http://169.254.20.10:9253/metrics"`
+		Timeout        int    `default:"2"`
+	} `tracer:"dns_cache"`
+
+	// CPUBurst saves a cpu_throttle event when a container is throttled in
+	// more than EventThreshold percent of its periods between two
+	// collections, 0 disables the events. EventInterval is the minimum
+	// seconds between two events of a container.
+	CPUBurst struct {
+		EventThreshold int `min:"0" max:"100"`
+		EventInterval  int `default:"300"`
+	} `tracer:"cpu_burst"`
+
+	CpuTick struct {
+		ContainerQos []string
+	} `tracer:"cpu_tick"`
+
+	TracerManifest struct {
+		Dir      string
+		Interval int `default:"10"`
+	} `tracer:"tracer_manifest"`
+
+	// Groups partition the collectors, each group is served by
+	// /metrics?group=<Name> to be scraped at its own frequency.
+	Groups []struct {
+		Name       string
+		Collectors []string
+	} `toml:"Groups,omitempty"`
+
+	// Aliases export renamed metrics under their deprecated names too,
+	// until the Until date (2006-01-02), empty exports them forever.
+	Aliases []struct {
+		Deprecated string
+		Name       string
+		Until      string
+	} `toml:"Aliases,omitempty"`
+
+	// ContainerScan sizes the worker pool shared by the per-container
+	// collectors: a worker per ContainersPerWorker containers of a scan,
+	// MaxConcurrency containers scanned at once by all of them, 0 for
+	// GOMAXPROCS.
+	ContainerScan struct {
+		ContainersPerWorker int `default:"50" min:"1"`
+		MaxConcurrency      int `min:"0"`
+	}
+
+	// Downsample keeps the 1-minute aggregates of the Metrics, names or
+	// globs, in the local file storage for RetentionDays days, sampled
+	// every Interval seconds. Empty Metrics disables it.
+	Downsample struct {
+		Metrics       []string
+		Interval      int `default:"15" min:"1"`
+		RetentionDays int `default:"7" min:"1"`
 	}
 }
 
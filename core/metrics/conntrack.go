@@ -0,0 +1,320 @@
+// Copyright 2026 The HuaTuo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"huatuo-bamai/internal/log"
+	"huatuo-bamai/internal/pod"
+	"huatuo-bamai/internal/procfs"
+	"huatuo-bamai/internal/utils/netutil"
+	"huatuo-bamai/internal/utils/parseutil"
+	"huatuo-bamai/pkg/metric"
+	"huatuo-bamai/pkg/tracing"
+	"huatuo-bamai/pkg/types"
+
+	"github.com/vishvananda/netlink"
+	"golang.org/x/sys/unix"
+)
+
+const (
+	conntrackCountPath = "sys/net/netfilter/nf_conntrack_count"
+	conntrackMaxPath   = "sys/net/netfilter/nf_conntrack_max"
+)
+
+// conntrackProtocols names the protocols of the entries, the others are
+// "other".
+var conntrackProtocols = map[uint8]string{
+	unix.IPPROTO_ICMP:    "icmp",
+	unix.IPPROTO_TCP:     "tcp",
+	unix.IPPROTO_UDP:     "udp",
+	unix.IPPROTO_GRE:     "gre",
+	unix.IPPROTO_ICMPV6:  "icmpv6",
+	unix.IPPROTO_SCTP:    "sctp",
+	unix.IPPROTO_UDPLITE: "udplite",
+}
+
+// ConntrackTalker is a source address of the original direction of the
+// entries, and the container of the address.
+type ConntrackTalker struct {
+	SrcIP             string            `json:"src_ip"`
+	Entries           uint64            `json:"entries"`
+	Protocols         map[string]uint64 `json:"protocols"`
+	ContainerID       string            `json:"container_id,omitempty"`
+	ContainerHostname string            `json:"container_hostname,omitempty"`
+}
+
+// ConntrackTracingData is stored when the entries of the conntrack table
+// reach EventThreshold percent of nf_conntrack_max.
+type ConntrackTracingData struct {
+	Entries uint64 `json:"entries"`
+	Max     uint64 `json:"max"`
+	// Utilization is the percent of nf_conntrack_max used.
+	Utilization float64 `json:"utilization" validate:"gte=0"`
+	Threshold   int     `json:"threshold"`
+	// Dumped is the entries the top talkers are counted from, 0 when the
+	// table is larger than DumpMaxEntries.
+	Dumped     uint64            `json:"dumped"`
+	TopTalkers []ConntrackTalker `json:"top_talkers"`
+}
+
+// conntrackCollector reads the netfilter conntrack table of the host net
+// namespace, where the traffic forwarded from the pods is tracked too.
+type conntrackCollector struct {
+	mutex sync.Mutex
+	// protocols are the entries per protocol of the last dump.
+	protocols map[string]uint64
+	lastDump  time.Time
+	lastEvent time.Time
+}
+
+func init() {
+	tracing.RegisterEventTracing("conntrack", newConntrack)
+	tracing.RegisterSchema[ConntrackTracingData]("conntrack", "conntrack", 1)
+}
+
+func newConntrack() (*tracing.EventTracingAttr, error) {
+	// nf_conntrack is not loaded, e.g. an eBPF dataplane.
+	if !netFileExists(procfs.Path(conntrackCountPath)) {
+		return nil, types.ErrNotSupported
+	}
+
+	return &tracing.EventTracingAttr{
+		TracingData: &conntrackCollector{},
+		Flag:        tracing.FlagMetric,
+	}, nil
+}
+
+// shouldReport tells whether the utilization is saved as an event, at most
+// one per EventInterval.
+func (c *conntrackCollector) shouldReport(utilization float64, now time.Time) bool {
+	threshold := cfg.Conntrack.EventThreshold
+	if threshold <= 0 || utilization < float64(threshold) {
+		return false
+	}
+	if now.Sub(c.lastEvent) < time.Duration(cfg.Conntrack.EventInterval)*time.Second {
+		return false
+	}
+
+	c.lastEvent = now
+	return true
+}
+
+// dump lists the entries of the table, nil when it holds more than
+// DumpMaxEntries.
+func (c *conntrackCollector) dump(entries uint64) []*netlink.ConntrackFlow {
+	if entries > uint64(cfg.Conntrack.DumpMaxEntries) {
+		log.Debugf("conntrack table of %d entries not dumped", entries)
+		return nil
+	}
+
+	flows, err := netlink.ConntrackTableList(netlink.ConntrackTable, netlink.InetFamily(unix.AF_UNSPEC))
+	if err != nil {
+		log.Infof("failed to dump conntrack table: %v", err)
+		return nil
+	}
+	return flows
+}
+
+func (c *conntrackCollector) Update() ([]*metric.Data, error) {
+	entries, err := parseutil.ReadUint(procfs.Path(conntrackCountPath))
+	if err != nil {
+		return nil, err
+	}
+	limit, err := parseutil.ReadUint(procfs.Path(conntrackMaxPath))
+	if err != nil {
+		return nil, err
+	}
+
+	utilization := 0.0
+	if limit > 0 {
+		utilization = float64(entries) * 100 / float64(limit)
+	}
+
+	data := []*metric.Data{
+		metric.NewGaugeData("entries", float64(entries), "entries of the conntrack table", nil),
+		metric.NewGaugeData("max", float64(limit), "nf_conntrack_max, the size limit of the conntrack table", nil),
+		metric.NewGaugeData("utilization", utilization, "percent of nf_conntrack_max used", nil),
+	}
+	data = append(data, conntrackStats()...)
+
+	now := time.Now()
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	report := c.shouldReport(utilization, now)
+	dumpDue := cfg.Conntrack.DumpInterval > 0 &&
+		now.Sub(c.lastDump) >= time.Duration(cfg.Conntrack.DumpInterval)*time.Second
+
+	var flows []*netlink.ConntrackFlow
+	if report || dumpDue {
+		flows = c.dump(entries)
+		c.protocols = conntrackProtocolEntries(flows)
+		c.lastDump = now
+	}
+
+	for protocol, count := range c.protocols {
+		data = append(data, metric.NewGaugeData("protocol_entries", float64(count),
+			"entries of the conntrack table per protocol", map[string]string{"protocol": protocol}))
+	}
+
+	if report {
+		c.save(entries, limit, utilization, flows, now)
+	}
+	return data, nil
+}
+
+// conntrackStats sums the insert failures and the drops of the cpus, the
+// table being full drops the new connections.
+func conntrackStats() []*metric.Data {
+	procFS, err := procfs.NewDefaultFS()
+	if err != nil {
+		return nil
+	}
+
+	stats, err := procFS.ConntrackStat()
+	if err != nil {
+		log.Debugf("failed to read conntrack stat: %v", err)
+		return nil
+	}
+
+	var insertFailed, drop, earlyDrop uint64
+	for _, stat := range stats {
+		insertFailed += stat.InsertFailed
+		drop += stat.Drop
+		earlyDrop += stat.EarlyDrop
+	}
+
+	return []*metric.Data{
+		metric.NewCounterData("insert_failed_total", float64(insertFailed), "entries failed to be inserted into the conntrack table", nil),
+		metric.NewCounterData("drop_total", float64(drop), "packets dropped as no entry could be allocated", nil),
+		metric.NewCounterData("early_drop_total", float64(earlyDrop), "entries evicted to make room for new ones in a full table", nil),
+	}
+}
+
+func conntrackProtocolName(protocol uint8) string {
+	if name, ok := conntrackProtocols[protocol]; ok {
+		return name
+	}
+	return "other"
+}
+
+// conntrackProtocolEntries counts the entries per protocol, nil for no
+// entries dumped.
+func conntrackProtocolEntries(flows []*netlink.ConntrackFlow) map[string]uint64 {
+	if len(flows) == 0 {
+		return nil
+	}
+
+	protocols := make(map[string]uint64)
+	for _, flow := range flows {
+		protocols[conntrackProtocolName(flow.Forward.Protocol)]++
+	}
+	return protocols
+}
+
+// conntrackTopTalkers returns the n source addresses of the most entries.
+// An address is of the first container by id of the pod, but the host
+// network pods sharing the address of the node.
+func conntrackTopTalkers(flows []*netlink.ConntrackFlow, containers map[string]*pod.Container, hostNetInode uint64, n int) []ConntrackTalker {
+	talkers := make(map[string]*ConntrackTalker)
+	for _, flow := range flows {
+		if flow.Forward.SrcIP == nil {
+			continue
+		}
+
+		ip := flow.Forward.SrcIP.String()
+		talker, ok := talkers[ip]
+		if !ok {
+			talker = &ConntrackTalker{SrcIP: ip, Protocols: make(map[string]uint64)}
+			talkers[ip] = talker
+		}
+		talker.Entries++
+		talker.Protocols[conntrackProtocolName(flow.Forward.Protocol)]++
+	}
+
+	top := make([]ConntrackTalker, 0, len(talkers))
+	for _, talker := range talkers {
+		top = append(top, *talker)
+	}
+	sort.Slice(top, func(i, j int) bool {
+		if top[i].Entries != top[j].Entries {
+			return top[i].Entries > top[j].Entries
+		}
+		return top[i].SrcIP < top[j].SrcIP
+	})
+	if len(top) > n {
+		top = top[:n]
+	}
+
+	ids := make([]string, 0, len(containers))
+	for id := range containers {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	ipContainers := make(map[string]*pod.Container, len(containers))
+	for _, id := range ids {
+		container := containers[id]
+		if container.IPAddress == "" || container.NetNamespaceInode == hostNetInode {
+			continue
+		}
+		if _, ok := ipContainers[container.IPAddress]; !ok {
+			ipContainers[container.IPAddress] = container
+		}
+	}
+
+	for i := range top {
+		if container, ok := ipContainers[top[i].SrcIP]; ok {
+			top[i].ContainerID = container.ID
+			top[i].ContainerHostname = container.Hostname
+		}
+	}
+	return top
+}
+
+func (c *conntrackCollector) save(entries, limit uint64, utilization float64, flows []*netlink.ConntrackFlow, now time.Time) {
+	tracerData := &ConntrackTracingData{
+		Entries:     entries,
+		Max:         limit,
+		Utilization: utilization,
+		Threshold:   cfg.Conntrack.EventThreshold,
+		Dumped:      uint64(len(flows)),
+	}
+
+	if len(flows) > 0 {
+		containers, err := pod.NormalContainers()
+		if err != nil {
+			log.Infof("failed to get normal containers: %v", err)
+		}
+		hostNetInode, err := netutil.NetNSInodeByPid(1)
+		if err != nil {
+			log.Infof("failed to get host netns inode: %v", err)
+		}
+		tracerData.TopTalkers = conntrackTopTalkers(flows, containers, hostNetInode, cfg.Conntrack.TopTalkers)
+	}
+
+	if err := tracing.Save(&tracing.WriteRequest{
+		TracerName: "conntrack",
+		TracerTime: now,
+		TracerData: tracerData,
+	}); err != nil {
+		log.Warnf("failed to save tracing data: %v", err)
+	}
+}
@@ -0,0 +1,98 @@
+// Copyright 2026 The HuaTuo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"net"
+	"reflect"
+	"testing"
+	"time"
+
+	"huatuo-bamai/internal/pod"
+
+	"github.com/vishvananda/netlink"
+	"golang.org/x/sys/unix"
+)
+
+func conntrackTestFlow(src string, protocol uint8) *netlink.ConntrackFlow {
+	flow := &netlink.ConntrackFlow{}
+	flow.Forward.SrcIP = net.ParseIP(src)
+	flow.Forward.Protocol = protocol
+	return flow
+}
+
+func TestConntrackTopTalkers(t *testing.T) {
+	flows := []*netlink.ConntrackFlow{
+		conntrackTestFlow("10.0.0.2", unix.IPPROTO_TCP),
+		conntrackTestFlow("10.0.0.2", unix.IPPROTO_TCP),
+		conntrackTestFlow("10.0.0.2", unix.IPPROTO_UDP),
+		conntrackTestFlow("192.168.1.10", unix.IPPROTO_TCP),
+		conntrackTestFlow("192.168.1.10", unix.IPPROTO_ICMP),
+		conntrackTestFlow("10.0.0.3", 253),
+		{},
+	}
+	containers := map[string]*pod.Container{
+		"b": {ID: "b", Hostname: "web", IPAddress: "10.0.0.2", NetNamespaceInode: 100},
+		"a": {ID: "a", Hostname: "web", IPAddress: "10.0.0.2", NetNamespaceInode: 100},
+		// a host network pod, the address of the node.
+		"c": {ID: "c", Hostname: "agent", IPAddress: "192.168.1.10", NetNamespaceInode: 1},
+	}
+
+	got := conntrackTopTalkers(flows, containers, 1, 2)
+	want := []ConntrackTalker{
+		{SrcIP: "10.0.0.2", Entries: 3, Protocols: map[string]uint64{"tcp": 2, "udp": 1}, ContainerID: "a", ContainerHostname: "web"},
+		{SrcIP: "192.168.1.10", Entries: 2, Protocols: map[string]uint64{"tcp": 1, "icmp": 1}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("conntrackTopTalkers() = %+v, want %+v", got, want)
+	}
+
+	protocols := conntrackProtocolEntries(flows[:6])
+	if want := map[string]uint64{"tcp": 3, "udp": 1, "icmp": 1, "other": 1}; !reflect.DeepEqual(protocols, want) {
+		t.Errorf("conntrackProtocolEntries() = %v, want %v", protocols, want)
+	}
+	if protocols := conntrackProtocolEntries(nil); protocols != nil {
+		t.Errorf("conntrackProtocolEntries(nil) = %v, want nil", protocols)
+	}
+}
+
+func TestConntrackShouldReport(t *testing.T) {
+	orig := cfg
+	t.Cleanup(func() { cfg = orig })
+	cfg = &Config{}
+	cfg.Conntrack.EventThreshold = 90
+	cfg.Conntrack.EventInterval = 600
+
+	now := time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC)
+	c := &conntrackCollector{}
+
+	if c.shouldReport(89.9, now) {
+		t.Error("shouldReport() = true below the threshold")
+	}
+	if !c.shouldReport(90, now) {
+		t.Error("shouldReport() = false at the threshold")
+	}
+	if c.shouldReport(95, now.Add(time.Minute)) {
+		t.Error("shouldReport() = true within the event interval")
+	}
+	if !c.shouldReport(95, now.Add(10*time.Minute)) {
+		t.Error("shouldReport() = false after the event interval")
+	}
+
+	cfg.Conntrack.EventThreshold = 0
+	if c.shouldReport(100, now.Add(time.Hour)) {
+		t.Error("shouldReport() = true with the events disabled")
+	}
+}
@@ -0,0 +1,141 @@
+// Copyright 2026 The HuaTuo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"runtime"
+	"sync"
+	"time"
+
+	"huatuo-bamai/internal/pod"
+	"huatuo-bamai/pkg/metric"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	containerScanDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "huatuo",
+		Name:      "container_scan_duration_seconds",
+		Help:      "Duration of the container scans of the per-container collectors.",
+		Buckets:   []float64{.01, .05, .1, .25, .5, 1, 2.5, 5, 10, 30},
+	}, []string{"collector"})
+	containerScanWorkers = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "huatuo",
+		Name:      "container_scan_workers",
+		Help:      "Workers of the last container scan of the per-container collectors.",
+	}, []string{"collector"})
+)
+
+// RegisterMetrics registers the metrics of the container scans.
+func RegisterMetrics(reg prometheus.Registerer) {
+	reg.MustRegister(containerScanDuration, containerScanWorkers)
+}
+
+// containerScanTokens bounds the containers scanned at once by all the
+// collectors together, one token per worker of the CPU budget.
+var containerScanTokens struct {
+	sync.Mutex
+	ch chan struct{}
+}
+
+// containerScanBudget returns the workers scanning containers at once,
+// across the collectors.
+func containerScanBudget() int {
+	if cfg.ContainerScan.MaxConcurrency > 0 {
+		return cfg.ContainerScan.MaxConcurrency
+	}
+	return runtime.GOMAXPROCS(0)
+}
+
+// containerScanWorkerCount returns the workers of a scan of n containers,
+// one per ContainersPerWorker containers within the budget.
+func containerScanWorkerCount(n, budget int) int {
+	perWorker := max(cfg.ContainerScan.ContainersPerWorker, 1)
+	return max(min((n+perWorker-1)/perWorker, budget), 1)
+}
+
+// acquireContainerScanToken waits for a token of the budget and returns
+// the channel to release it to, the tokens are resized on a budget change.
+func acquireContainerScanToken(budget int) chan struct{} {
+	containerScanTokens.Lock()
+	if cap(containerScanTokens.ch) != budget {
+		containerScanTokens.ch = make(chan struct{}, budget)
+	}
+	ch := containerScanTokens.ch
+	containerScanTokens.Unlock()
+
+	ch <- struct{}{}
+	return ch
+}
+
+// scanContainers returns the metrics of scan for every container, scanned
+// by the workers of the shared pool. It stops at the first error and
+// returns it with the metrics of the containers scanned until then. A nil
+// container, the host of some collectors, is scanned as any other.
+func scanContainers(collector string, containers map[string]*pod.Container,
+	scan func(*pod.Container) ([]*metric.Data, error),
+) ([]*metric.Data, error) {
+	start := time.Now()
+	budget := containerScanBudget()
+	workers := containerScanWorkerCount(len(containers), budget)
+	containerScanWorkers.WithLabelValues(collector).Set(float64(workers))
+	defer func() {
+		containerScanDuration.WithLabelValues(collector).Observe(time.Since(start).Seconds())
+	}()
+
+	var (
+		mu       sync.Mutex
+		metrics  []*metric.Data
+		firstErr error
+		wg       sync.WaitGroup
+	)
+	failed := func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return firstErr != nil
+	}
+
+	queue := make(chan *pod.Container)
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for container := range queue {
+				tokens := acquireContainerScanToken(budget)
+				data, err := scan(container)
+				<-tokens
+
+				mu.Lock()
+				if err != nil && firstErr == nil {
+					firstErr = err
+				}
+				metrics = append(metrics, data...)
+				mu.Unlock()
+			}
+		}()
+	}
+
+	for _, container := range containers {
+		if failed() {
+			break
+		}
+		queue <- container
+	}
+	close(queue)
+	wg.Wait()
+
+	return metrics, firstErr
+}
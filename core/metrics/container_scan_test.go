@@ -0,0 +1,100 @@
+// Copyright 2026 The HuaTuo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"huatuo-bamai/internal/pod"
+	"huatuo-bamai/pkg/metric"
+)
+
+func setContainerScan(t *testing.T, perWorker, maxConcurrency int) {
+	t.Helper()
+
+	saved := cfg
+	t.Cleanup(func() { cfg = saved })
+
+	c := *saved
+	c.ContainerScan.ContainersPerWorker = perWorker
+	c.ContainerScan.MaxConcurrency = maxConcurrency
+	cfg = &c
+}
+
+func scanTestContainers(n int) map[string]*pod.Container {
+	containers := make(map[string]*pod.Container, n)
+	for i := range n {
+		id := fmt.Sprint(i)
+		containers[id] = &pod.Container{ID: id}
+	}
+	return containers
+}
+
+func TestContainerScanWorkerCount(t *testing.T) {
+	setContainerScan(t, 50, 0)
+
+	for _, tt := range []struct{ n, budget, want int }{
+		{0, 8, 1},
+		{10, 8, 1},
+		{51, 8, 2},
+		{600, 8, 8},
+		{600, 32, 12},
+	} {
+		if got := containerScanWorkerCount(tt.n, tt.budget); got != tt.want {
+			t.Errorf("containerScanWorkerCount(%d, %d) = %d, want %d", tt.n, tt.budget, got, tt.want)
+		}
+	}
+}
+
+func TestScanContainers(t *testing.T) {
+	setContainerScan(t, 1, 3)
+
+	var running, peak atomic.Int32
+	metrics, err := scanContainers("test", scanTestContainers(30), func(c *pod.Container) ([]*metric.Data, error) {
+		n := running.Add(1)
+		defer running.Add(-1)
+		for p := peak.Load(); n > p && !peak.CompareAndSwap(p, n); p = peak.Load() {
+		}
+		time.Sleep(time.Millisecond)
+		return []*metric.Data{metric.NewGaugeData("test", 1, "test", map[string]string{"id": c.ID})}, nil
+	})
+	if err != nil || len(metrics) != 30 {
+		t.Fatalf("scanContainers() = %d metrics, %v, want 30", len(metrics), err)
+	}
+	if p := peak.Load(); p < 2 || p > 3 {
+		t.Errorf("containers scanned at once = %d, want 2 to 3", p)
+	}
+}
+
+func TestScanContainersError(t *testing.T) {
+	setContainerScan(t, 1, 2)
+
+	errScan := errors.New("scan failed")
+	var scanned atomic.Int32
+	_, err := scanContainers("test", scanTestContainers(100), func(c *pod.Container) ([]*metric.Data, error) {
+		scanned.Add(1)
+		return nil, errScan
+	})
+	if !errors.Is(err, errScan) {
+		t.Errorf("scanContainers() error = %v, want %v", err, errScan)
+	}
+	if n := scanned.Load(); n == 100 {
+		t.Errorf("scanned %d containers, want the scan stopped", n)
+	}
+}
@@ -0,0 +1,214 @@
+// Copyright 2026 The HuaTuo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"math"
+	"reflect"
+	"sync"
+	"time"
+
+	"huatuo-bamai/internal/cgroups"
+	"huatuo-bamai/internal/cgroups/stats"
+	"huatuo-bamai/internal/log"
+	"huatuo-bamai/internal/pod"
+	"huatuo-bamai/pkg/metric"
+	"huatuo-bamai/pkg/tracing"
+)
+
+// cpuBurstStat is the cpu.stat of a container, times in nanoseconds
+// regardless of the cgroup version.
+type cpuBurstStat struct {
+	nrPeriods     uint64
+	nrThrottled   uint64
+	throttledTime uint64
+	nrBursts      uint64
+	burstTime     uint64
+}
+
+// cpuThrottleEvent is the last cpu_throttle event of a container.
+type cpuThrottleEvent struct {
+	lastEvent time.Time
+}
+
+// CPUThrottleTracingData is stored when a container is throttled in more
+// than EventThreshold percent of its periods between two collections.
+type CPUThrottleTracingData struct {
+	// ThrottledRatio is the percent of the periods throttled.
+	ThrottledRatio float64 `json:"throttled_ratio" validate:"gte=0,lte=100"`
+	NrPeriods      uint64  `json:"nr_periods"`
+	NrThrottled    uint64  `json:"nr_throttled"`
+	ThrottledTime  int64   `json:"throttled_time_us"`
+	Interval       int64   `json:"interval_ms"`
+	// Quota, Period and Burst of cpu.max or cpu.cfs_*_us, in microseconds.
+	Quota  uint64 `json:"quota_us"`
+	Period uint64 `json:"period_us"`
+	Burst  uint64 `json:"burst_us"`
+}
+
+type cpuBurstCollector struct {
+	cgroup cgroups.Cgroup
+	mutex  sync.Mutex
+	// counters are nr_periods, nr_bursts, nr_throttled and throttled_time.
+	counters *cgroupCounters
+}
+
+func init() {
+	tracing.RegisterEventTracing("cpu_burst", newCPUBurst)
+	tracing.RegisterSchema[CPUThrottleTracingData]("cpu_burst", "cpu_throttle", 1)
+	_ = pod.RegisterContainerLifeResources("collector_cpu_burst", reflect.TypeOf(&cpuThrottleEvent{}))
+}
+
+func newCPUBurst() (*tracing.EventTracingAttr, error) {
+	cgroup, err := cgroups.NewManager()
+	if err != nil {
+		return nil, err
+	}
+
+	return &tracing.EventTracingAttr{
+		TracingData: &cpuBurstCollector{
+			cgroup:   cgroup,
+			counters: newCgroupCounters(),
+		},
+		Flag: tracing.FlagMetric,
+	}, nil
+}
+
+// readCPUBurstStat normalizes cgroup v1 (*_time, ns) and v2 (*_usec) keys,
+// for cpu_burst and cpu_stat alike.
+func readCPUBurstStat(raw map[string]uint64) cpuBurstStat {
+	stat := cpuBurstStat{
+		nrPeriods:     raw["nr_periods"],
+		nrThrottled:   raw["nr_throttled"],
+		throttledTime: raw["throttled_time"],
+		nrBursts:      raw["nr_bursts"],
+		burstTime:     raw["burst_time"],
+	}
+
+	if v, ok := raw["throttled_usec"]; ok {
+		stat.throttledTime = v * 1000
+	}
+	if v, ok := raw["burst_usec"]; ok {
+		stat.burstTime = v * 1000
+	}
+
+	return stat
+}
+
+// cpuBurstRatios returns the share of periods in the last scrape interval
+// which borrowed burst budget and which were still throttled, of the delta
+// of nr_periods, nr_bursts and nr_throttled. Throttling despite a configured
+// burst means the burst is too small for the workload.
+func cpuBurstRatios(delta *cgroupCounterDelta) (burst, throttled float64) {
+	if delta == nil || delta.values[0] == 0 {
+		return 0, 0
+	}
+
+	periods := float64(delta.values[0])
+	return float64(delta.values[1]) / periods, float64(delta.values[2]) / periods
+}
+
+// shouldReport tells whether the throttled ratio, 0 to 1, of the last
+// collection is saved as an event, at most one per EventInterval of a
+// container.
+func (e *cpuThrottleEvent) shouldReport(throttledRatio float64, now time.Time) bool {
+	threshold := cfg.CPUBurst.EventThreshold
+	if threshold <= 0 || throttledRatio*100 < float64(threshold) {
+		return false
+	}
+	if now.Sub(e.lastEvent) < time.Duration(cfg.CPUBurst.EventInterval)*time.Second {
+		return false
+	}
+
+	e.lastEvent = now
+	return true
+}
+
+func (c *cpuBurstCollector) Update() ([]*metric.Data, error) {
+	containers, err := pod.ContainersByType(pod.ContainerTypeNormal | pod.ContainerTypeSidecar)
+	if err != nil {
+		return nil, err
+	}
+	c.counters.retain(containers)
+
+	return scanContainers("cpu_burst", containers, func(container *pod.Container) ([]*metric.Data, error) {
+		quota, err := c.cgroup.CpuQuotaAndPeriod(container.CgroupPath)
+		if err != nil {
+			log.Infof("failed to get cpu quota of %s, %v", container, err)
+			return nil, nil
+		}
+
+		// neither bursts nor throttling happen without a quota.
+		if quota.Quota == math.MaxUint64 {
+			return nil, nil
+		}
+
+		raw, err := c.cgroup.CpuStatRaw(container.CgroupPath)
+		if err != nil {
+			log.Infof("failed to get cpu stat of %s, %v", container, err)
+			return nil, nil
+		}
+
+		now := time.Now()
+		stat := readCPUBurstStat(raw)
+		delta := c.counters.delta(container.CgroupPath, now,
+			stat.nrPeriods, stat.nrBursts, stat.nrThrottled, stat.throttledTime)
+		burstRatio, throttledRatio := cpuBurstRatios(delta)
+
+		if delta != nil && delta.values[0] != 0 {
+			event := container.LifeResources("collector_cpu_burst").(*cpuThrottleEvent)
+
+			c.mutex.Lock()
+			report := event.shouldReport(throttledRatio, now)
+			c.mutex.Unlock()
+
+			if report {
+				c.save(container, throttledRatio, delta, quota, now)
+			}
+		}
+
+		return []*metric.Data{
+			metric.NewContainerGaugeData(container, "burst_quota_seconds", float64(quota.Burst)/1e6, "cpu burst budget per period", nil),
+			metric.NewContainerGaugeData(container, "quota_seconds", float64(quota.Quota)/1e6, "cpu quota per period", nil),
+			metric.NewContainerCounterData(container, "periods_total", float64(stat.nrPeriods), "elapsed enforcement periods", nil),
+			metric.NewContainerCounterData(container, "bursts_total", float64(stat.nrBursts), "periods which consumed burst budget", nil),
+			metric.NewContainerCounterData(container, "burst_seconds_total", float64(stat.burstTime)/1e9, "cpu time consumed above quota from the burst budget", nil),
+			metric.NewContainerCounterData(container, "throttled_total", float64(stat.nrThrottled), "periods which were throttled", nil),
+			metric.NewContainerCounterData(container, "throttled_seconds_total", float64(stat.throttledTime)/1e9, "time spent throttled", nil),
+			metric.NewContainerGaugeData(container, "burst_periods_ratio", burstRatio, "share of periods which consumed burst budget since last scrape", nil),
+			metric.NewContainerGaugeData(container, "throttled_periods_ratio", throttledRatio, "share of periods which were throttled since last scrape", nil),
+		}, nil
+	})
+}
+
+func (c *cpuBurstCollector) save(container *pod.Container, throttledRatio float64, delta *cgroupCounterDelta, quota *stats.CpuQuota, now time.Time) {
+	if err := tracing.Save(&tracing.WriteRequest{
+		TracerName:  "cpu_burst",
+		ContainerID: container.ID,
+		TracerTime:  now,
+		TracerData: &CPUThrottleTracingData{
+			ThrottledRatio: throttledRatio * 100,
+			NrPeriods:      delta.values[0],
+			NrThrottled:    delta.values[2],
+			ThrottledTime:  int64(delta.values[3] / 1000),
+			Interval:       delta.interval.Milliseconds(),
+			Quota:          quota.Quota,
+			Period:         quota.Period,
+			Burst:          quota.Burst,
+		},
+	}); err != nil {
+		log.Warnf("failed to save tracing data: %v", err)
+	}
+}
@@ -0,0 +1,125 @@
+// Copyright 2026 The HuaTuo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"testing"
+	"time"
+)
+
+func TestReadCPUBurstStat(t *testing.T) {
+	tests := []struct {
+		name string
+		raw  map[string]uint64
+		want cpuBurstStat
+	}{
+		{
+			name: "cgroup v1",
+			raw: map[string]uint64{
+				"nr_periods": 100, "nr_throttled": 10, "throttled_time": 5000000,
+				"nr_bursts": 3, "burst_time": 2000000,
+			},
+			want: cpuBurstStat{nrPeriods: 100, nrThrottled: 10, throttledTime: 5000000, nrBursts: 3, burstTime: 2000000},
+		},
+		{
+			name: "cgroup v2",
+			raw: map[string]uint64{
+				"usage_usec": 900000, "nr_periods": 100, "nr_throttled": 10, "throttled_usec": 5000,
+				"nr_bursts": 3, "burst_usec": 2000,
+			},
+			want: cpuBurstStat{nrPeriods: 100, nrThrottled: 10, throttledTime: 5000000, nrBursts: 3, burstTime: 2000000},
+		},
+		{
+			name: "cgroup v2 without burst",
+			raw:  map[string]uint64{"nr_periods": 100, "nr_throttled": 10, "throttled_usec": 5000},
+			want: cpuBurstStat{nrPeriods: 100, nrThrottled: 10, throttledTime: 5000000},
+		},
+		{
+			name: "no quota",
+			raw:  map[string]uint64{"usage_usec": 900000},
+			want: cpuBurstStat{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := readCPUBurstStat(tt.raw); got != tt.want {
+				t.Errorf("readCPUBurstStat() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCPUThrottleEvent(t *testing.T) {
+	orig := cfg
+	t.Cleanup(func() { cfg = orig })
+	cfg = &Config{}
+	cfg.CPUBurst.EventThreshold = 50
+	cfg.CPUBurst.EventInterval = 300
+
+	start := time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC)
+	counters := newCgroupCounters()
+	delta := func(raw map[string]uint64, now time.Time) *cgroupCounterDelta {
+		s := readCPUBurstStat(raw)
+		return counters.delta("/kubepods/a", now, s.nrPeriods, s.nrBursts, s.nrThrottled, s.throttledTime)
+	}
+	var event cpuThrottleEvent
+
+	// the first collection has no delta, cgroup v2 keys.
+	if d := delta(map[string]uint64{"nr_periods": 100, "nr_throttled": 10, "throttled_usec": 5000}, start); d != nil {
+		t.Fatalf("first delta() = %+v, want nil", d)
+	}
+
+	now := start.Add(10 * time.Second)
+	d := delta(map[string]uint64{"nr_periods": 200, "nr_bursts": 20, "nr_throttled": 70, "throttled_usec": 305000}, now)
+	if d == nil {
+		t.Fatal("delta() = nil")
+	}
+	if d.values[3] != 300000000 || d.interval != 10*time.Second {
+		t.Errorf("delta = %+v", d)
+	}
+	burst, throttled := cpuBurstRatios(d)
+	if burst != 0.2 || throttled != 0.6 {
+		t.Errorf("cpuBurstRatios() = %v, %v, want 0.2, 0.6", burst, throttled)
+	}
+
+	// an event per EventInterval.
+	if !event.shouldReport(throttled, now) {
+		t.Error("shouldReport() = false above the threshold")
+	}
+	if event.shouldReport(throttled, now.Add(time.Minute)) {
+		t.Error("shouldReport() = true within the event interval")
+	}
+
+	// cgroup v1 keys, below the threshold.
+	now = now.Add(10 * time.Minute)
+	d = delta(map[string]uint64{"nr_periods": 300, "nr_bursts": 20, "nr_throttled": 80, "throttled_time": 310000000}, now)
+	if _, throttled = cpuBurstRatios(d); throttled != 0.1 || event.shouldReport(throttled, now) {
+		t.Errorf("throttled ratio = %v, want 0.1 and no event", throttled)
+	}
+
+	// the cgroup recreated.
+	d = delta(map[string]uint64{"nr_periods": 5, "nr_throttled": 5}, now.Add(time.Second))
+	if burst, throttled = cpuBurstRatios(d); d != nil || burst != 0 || throttled != 0 {
+		t.Errorf("delta of a recreated cgroup = %+v, ratios %v, %v", d, burst, throttled)
+	}
+
+	// the events disabled.
+	cfg.CPUBurst.EventThreshold = 0
+	event.lastEvent = time.Time{}
+	if event.shouldReport(1, now) {
+		t.Error("shouldReport() = true with the events disabled")
+	}
+}
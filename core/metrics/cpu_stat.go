@@ -1,4 +1,4 @@
-// Copyright 2025 The HuaTuo Authors
+// Copyright 2025, 2026 The HuaTuo Authors
 //
 // Licensed under the Apache License, Version 2.0 (the "License");
 // you may not use this file except in compliance with the License.
@@ -81,14 +81,7 @@ func (c *cpuStatCollector) updateDataCache(cpu *cpuStat, container *pod.Containe
 		deltaCpuUsage         uint64
 	)
 
-	c.mutex.Lock()
-	defer c.mutex.Unlock()
-
-	now := time.Now()
-	if now.Sub(cpu.lastUpdate).Nanoseconds() < 1000000000 {
-		return nil
-	}
-
+	// the cgroups are read out of the lock, by the workers of the scan.
 	raw, err := c.cgroup.CpuStatRaw(container.CgroupPath)
 	if err != nil {
 		return err
@@ -99,14 +92,24 @@ func (c *cpuStatCollector) updateDataCache(cpu *cpuStat, container *pod.Containe
 		return err
 	}
 
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	now := time.Now()
+	if now.Sub(cpu.lastUpdate).Nanoseconds() < 1000000000 {
+		return nil
+	}
+
+	// throttled_usec and burst_usec of cgroup v2 in nanoseconds as v1.
+	burst := readCPUBurstStat(raw)
 	stat := cpuStat{
-		nrThrottled:      raw["nr_throttled"],
-		throttledTime:    raw["throttled_time"],
+		nrThrottled:      burst.nrThrottled,
+		throttledTime:    burst.throttledTime,
 		hierarchyWaitSum: raw["hierarchy_wait_sum"],
 		innerWaitSum:     raw["inner_wait_sum"],
 		waitSum:          raw["wait_sum"],
-		nrBursts:         raw["nr_bursts"],
-		burstTime:        raw["burst_time"],
+		nrBursts:         burst.nrBursts,
+		burstTime:        burst.burstTime,
 		cpuTotal:         usage.Usage * 1000,
 		lastUpdate:       now,
 	}
@@ -163,22 +166,20 @@ func (c *cpuStatCollector) updateDataCache(cpu *cpuStat, container *pod.Containe
 }
 
 func (c *cpuStatCollector) Update() ([]*metric.Data, error) {
-	metrics := []*metric.Data{}
-
 	containers, err := pod.ContainersByType(pod.ContainerTypeNormal | pod.ContainerTypeSidecar)
 	if err != nil {
 		return nil, err
 	}
 
-	for _, container := range containers {
+	return scanContainers("cpu_stat", containers, func(container *pod.Container) ([]*metric.Data, error) {
 		containerDataCache := container.LifeResources("collector_cpu_stat").(*cpuStat)
 		if err := c.updateDataCache(containerDataCache, container); err != nil {
 			log.Infof("failed to update cpu info of %s, %v", container, err)
-			continue
+			return nil, nil
 		}
 
-		metrics = append(
-			metrics, metric.NewContainerGaugeData(container, "wait_rate", containerDataCache.waitrateHierarchy, "wait rate for the containers", nil),
+		return []*metric.Data{
+			metric.NewContainerGaugeData(container, "wait_rate", containerDataCache.waitrateHierarchy, "wait rate for the containers", nil),
 			metric.NewContainerGaugeData(container, "inner_wait_rate", containerDataCache.waitrateInner, "inner wait rate for the containers", nil),
 			metric.NewContainerGaugeData(container, "exter_wait_rate", containerDataCache.waitrateExter, "exter wait rate for the containers", nil),
 			metric.NewContainerGaugeData(container, "wait_sum_exter_wait_rate", containerDataCache.waitrateWaitSum, "exter wait rate base on wait_sum (requires kernel.sched_schedstats=1)", nil),
@@ -187,8 +188,6 @@ func (c *cpuStatCollector) Update() ([]*metric.Data, error) {
 			metric.NewContainerGaugeData(container, "throttled_time", float64(containerDataCache.throttledTime), "throttle time for the containers", nil),
 			metric.NewContainerGaugeData(container, "nr_bursts", float64(containerDataCache.nrBursts), "burst nr for the containers", nil),
 			metric.NewContainerGaugeData(container, "burst_time", float64(containerDataCache.burstTime), "burst time for the containers", nil),
-		)
-	}
-
-	return metrics, nil
+		}, nil
+	})
 }
@@ -0,0 +1,221 @@
+// Copyright 2026 The HuaTuo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"io"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+
+	"huatuo-bamai/internal/cgroups"
+	"huatuo-bamai/internal/log"
+	"huatuo-bamai/internal/pod"
+	"huatuo-bamai/internal/procfs"
+	"huatuo-bamai/internal/procfs/sysfs"
+	"huatuo-bamai/internal/utils/parseutil"
+	"huatuo-bamai/pkg/metric"
+	"huatuo-bamai/pkg/tracing"
+)
+
+type cpuTickCollector struct {
+	cgroup cgroups.Cgroup
+}
+
+func init() {
+	tracing.RegisterEventTracing("cpu_tick", newCPUTick)
+}
+
+func newCPUTick() (*tracing.EventTracingAttr, error) {
+	cgroup, err := cgroups.NewManager()
+	if err != nil {
+		return nil, err
+	}
+
+	return &tracing.EventTracingAttr{
+		TracingData: &cpuTickCollector{cgroup: cgroup},
+		Flag:        tracing.FlagMetric,
+	}, nil
+}
+
+// timerInterrupts returns the local timer interrupts by cpu id, the "LOC"
+// line on x86 and the arch_timer lines on arm64.
+func timerInterrupts(r io.Reader) (map[int]uint64, error) {
+	lines, err := readInterrupts(r)
+	if err != nil {
+		return nil, err
+	}
+
+	counts := make(map[int]uint64)
+	for _, line := range lines {
+		if line.name != "LOC" && !slices.Contains(line.desc, "arch_timer") {
+			continue
+		}
+		for cpu, v := range line.counts {
+			counts[cpu] += v
+		}
+	}
+	return counts, nil
+}
+
+func readCPUSet(path string) (map[int]bool, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	list, err := parseutil.CPUList(string(raw))
+	if err != nil {
+		return nil, err
+	}
+
+	set := make(map[int]bool, len(list))
+	for _, cpu := range list {
+		set[cpu] = true
+	}
+	return set, nil
+}
+
+func (c *cpuTickCollector) hostData() ([]*metric.Data, error) {
+	nohzFull, err := readCPUSet(sysfs.Path("devices/system/cpu/nohz_full"))
+	if err != nil {
+		return nil, err
+	}
+
+	isolated, err := readCPUSet(sysfs.Path("devices/system/cpu/isolated"))
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := os.Open(procfs.Path("interrupts"))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	counts, err := timerInterrupts(f)
+	if err != nil {
+		return nil, err
+	}
+
+	data := make([]*metric.Data, 0, len(counts)+3)
+	for cpu, count := range counts {
+		data = append(data, metric.NewCounterData("local_timer_interrupts_total", float64(count),
+			"local timer interrupts, a nohz_full cpu with a steady rate is still ticking", map[string]string{
+				"cpu":       strconv.Itoa(cpu),
+				"nohz_full": strconv.FormatBool(nohzFull[cpu]),
+				"isolated":  strconv.FormatBool(isolated[cpu]),
+			}))
+	}
+
+	data = append(data,
+		metric.NewGaugeData("nohz_full_cpus", float64(len(nohzFull)), "cpus booted with nohz_full", nil),
+		metric.NewGaugeData("isolated_cpus", float64(len(isolated)), "cpus isolated from the scheduler domains", nil))
+
+	if raw, err := os.ReadFile(sysfs.Path("devices/system/clocksource/clocksource0/current_clocksource")); err == nil {
+		data = append(data, metric.NewGaugeData("clocksource", 1, "current clocksource",
+			map[string]string{"clocksource": strings.TrimSpace(string(raw))}))
+	}
+
+	return data, nil
+}
+
+func containerQosIncluded(container *pod.Container) bool {
+	if len(cfg.CpuTick.ContainerQos) == 0 {
+		return true
+	}
+
+	for _, qos := range cfg.CpuTick.ContainerQos {
+		if strings.EqualFold(container.Qos.String(), qos) {
+			return true
+		}
+	}
+	return false
+}
+
+// containerData sums the context switches of every thread in the container.
+// Threads which exit take their counts with them, so the sums are gauges,
+// a counter would see a reset on every exit.
+func (c *cpuTickCollector) containerData(container *pod.Container) ([]*metric.Data, error) {
+	tids, err := c.cgroup.Pids(container.CgroupPath)
+	if err != nil {
+		return nil, err
+	}
+
+	var voluntary, nonvoluntary uint64
+	for _, tid := range tids {
+		proc, err := procfs.NewProc(int(tid))
+		if err != nil {
+			continue
+		}
+		status, err := proc.NewStatus()
+		if err != nil {
+			continue
+		}
+		voluntary += status.VoluntaryCtxtSwitches
+		nonvoluntary += status.NonVoluntaryCtxtSwitches
+	}
+
+	procs, err := c.cgroup.Procs(container.CgroupPath)
+	if err != nil {
+		return nil, err
+	}
+
+	var slackMax uint64
+	for _, pid := range procs {
+		slack, err := parseutil.ReadUint(procfs.Path(strconv.Itoa(int(pid)), "timerslack_ns"))
+		if err != nil {
+			continue
+		}
+		slackMax = max(slackMax, slack)
+	}
+
+	return []*metric.Data{
+		metric.NewContainerGaugeData(container, "voluntary_ctxt_switches", float64(voluntary), "voluntary context switches of the live container threads", nil),
+		metric.NewContainerGaugeData(container, "nonvoluntary_ctxt_switches", float64(nonvoluntary), "involuntary context switches of the live container threads", nil),
+		metric.NewContainerGaugeData(container, "timerslack_ns_max", float64(slackMax), "largest timer slack among the container processes", nil),
+	}, nil
+}
+
+func (c *cpuTickCollector) Update() ([]*metric.Data, error) {
+	data, err := c.hostData()
+	if err != nil {
+		return nil, err
+	}
+
+	containers, err := pod.NormalContainers()
+	if err != nil {
+		return nil, err
+	}
+
+	scanned, _ := scanContainers("cpu_tick", containers, func(container *pod.Container) ([]*metric.Data, error) {
+		if !containerQosIncluded(container) {
+			return nil, nil
+		}
+
+		containerData, err := c.containerData(container)
+		if err != nil {
+			log.Debugf("cpu_tick container %s: %v", container, err)
+			return nil, nil
+		}
+		return containerData, nil
+	})
+
+	return append(data, scanned...), nil
+}
@@ -72,23 +72,24 @@ func (c *cpuUtilCollector) updateDataCache(cache *cpuUtilStat, container *pod.Co
 		cgroupPath string
 	)
 
-	c.mutex.Lock()
-	defer c.mutex.Unlock()
-
-	now := time.Now()
-	if now.Sub(cache.lastTimestamp).Nanoseconds() < 1000000000 {
-		return nil
-	}
-
 	if container != nil {
 		cgroupPath = container.CgroupPath
 	}
 
+	// the cgroups are read out of the lock, by the workers of the scan.
 	stat, err := c.cgroup.CpuUsage(cgroupPath)
 	if err != nil {
 		return err
 	}
 
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	now := time.Now()
+	if now.Sub(cache.lastTimestamp).Nanoseconds() < 1000000000 {
+		return nil
+	}
+
 	// allow statistics 0
 	deltaTotalTime := stat.Usage - cache.lastUsage.Usage
 	deltaUsrTime := stat.User - cache.lastUsage.User
@@ -126,18 +127,16 @@ func (c *cpuUtilCollector) updateHostDataCache() ([]*metric.Data, error) {
 }
 
 func (c *cpuUtilCollector) Update() ([]*metric.Data, error) {
-	metrics := []*metric.Data{}
-
 	containers, err := pod.ContainersByType(pod.ContainerTypeNormal | pod.ContainerTypeSidecar)
 	if err != nil {
 		return nil, err
 	}
 
-	for _, container := range containers {
+	metrics, _ := scanContainers("cpu_util", containers, func(container *pod.Container) ([]*metric.Data, error) {
 		cpuQuota, err := c.cgroup.CpuQuotaAndPeriod(container.CgroupPath)
 		if err != nil {
 			log.Infof("fetch container [%s] cpu quota and period: %v", container, err)
-			continue
+			return nil, nil
 		}
 
 		var numCores float64
@@ -148,20 +147,22 @@ func (c *cpuUtilCollector) Update() ([]*metric.Data, error) {
 		}
 
 		if numCores <= 0 {
-			continue
+			return nil, nil
 		}
 
 		containerDataCache := container.LifeResources("collector_cpu_util").(*cpuUtilStat)
 		if err := c.updateDataCache(containerDataCache, container, numCores); err != nil {
 			log.Infof("failed to update cpu info of %s, %v", container, err)
-			continue
+			return nil, nil
 		}
 
-		metrics = append(metrics, metric.NewContainerGaugeData(container, "cores", numCores, "cpu core number for the containers", nil),
+		return []*metric.Data{
+			metric.NewContainerGaugeData(container, "cores", numCores, "cpu core number for the containers", nil),
 			metric.NewContainerGaugeData(container, "usr", containerDataCache.usrUtil, "cpu usr for the containers", nil),
 			metric.NewContainerGaugeData(container, "sys", containerDataCache.sysUtil, "cpu sys for the containers", nil),
-			metric.NewContainerGaugeData(container, "total", containerDataCache.totalUtil, "cpu total for the containers", nil))
-	}
+			metric.NewContainerGaugeData(container, "total", containerDataCache.totalUtil, "cpu total for the containers", nil),
+		}, nil
+	})
 
 	more, _ := c.updateHostDataCache()
 
@@ -0,0 +1,262 @@
+// Copyright 2026 The HuaTuo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	"huatuo-bamai/internal/log"
+	"huatuo-bamai/pkg/metric"
+	"huatuo-bamai/pkg/tracing"
+
+	"github.com/miekg/dns"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+)
+
+const (
+	dnsProbeTargetLocal    = "local"
+	dnsProbeTargetUpstream = "upstream"
+)
+
+// the causes of a dns_lookup event.
+const (
+	dnsFailureLocalCache = "local_cache"
+	dnsFailureUpstream   = "upstream"
+	dnsFailureUnknown    = "unknown"
+)
+
+// CoreDNS series summed from the node-local cache stats endpoint, exported
+// under our own names so dashboards do not depend on the CoreDNS version.
+var dnsCacheStatsSeries = map[string]struct {
+	name string
+	help string
+}{
+	"coredns_cache_hits_total":                   {"cache_hits_total", "node-local dns cache hits"},
+	"coredns_cache_misses_total":                 {"cache_misses_total", "node-local dns cache misses"},
+	"coredns_forward_requests_total":             {"upstream_requests_total", "requests forwarded upstream by node-local dns"},
+	"coredns_forward_healthcheck_failures_total": {"upstream_healthcheck_failures_total", "upstream health check failures seen by node-local dns"},
+	"coredns_panics_total":                       {"panics_total", "node-local dns panics"},
+}
+
+// DNSLookupTracingData is stored when the synthetic lookups start failing,
+// or fail for another cause.
+type DNSLookupTracingData struct {
+	// Cause is local_cache when only the node-local cache fails, upstream
+	// when the upstream fails, and unknown when the cache fails without an
+	// upstream probe to tell them apart.
+	Cause     string              `json:"cause" validate:"oneof=local_cache upstream unknown"`
+	QueryName string              `json:"query_name"`
+	Failures  []*DNSLookupFailure `json:"failures" validate:"min=1"`
+}
+
+type DNSLookupFailure struct {
+	Target string `json:"target"`
+	Server string `json:"server"`
+	Error  string `json:"error"`
+}
+
+type dnsProbe struct {
+	target string
+	server string
+}
+
+type dnsCacheCollector struct {
+	probes   []dnsProbe
+	resolver *dns.Client
+	client   *http.Client
+	failures map[string]uint64
+	// cause of the last dns_lookup event, empty while the lookups succeed.
+	cause string
+}
+
+func init() {
+	tracing.RegisterEventTracing("dns_cache", newDNSCache)
+	tracing.RegisterSchema[DNSLookupTracingData]("dns_cache", "dns_lookup", 1)
+}
+
+func newDNSCache() (*tracing.EventTracingAttr, error) {
+	probes := []dnsProbe{{target: dnsProbeTargetLocal, server: cfg.DNSCache.Server}}
+	if cfg.DNSCache.UpstreamServer != "" {
+		probes = append(probes, dnsProbe{target: dnsProbeTargetUpstream, server: cfg.DNSCache.UpstreamServer})
+	}
+
+	for _, p := range probes {
+		if _, _, err := net.SplitHostPort(p.server); err != nil {
+			return nil, fmt.Errorf("dns cache %s server %q: %w", p.target, p.server, err)
+		}
+	}
+
+	timeout := time.Duration(cfg.DNSCache.Timeout) * time.Second
+	return &tracing.EventTracingAttr{
+		TracingData: &dnsCacheCollector{
+			probes:   probes,
+			resolver: &dns.Client{Net: "udp", Timeout: timeout},
+			client:   &http.Client{Timeout: timeout},
+			failures: make(map[string]uint64, len(probes)),
+		},
+		Flag: tracing.FlagMetric,
+	}, nil
+}
+
+func (c *dnsCacheCollector) Update() ([]*metric.Data, error) {
+	data := []*metric.Data{}
+	var failed []*DNSLookupFailure
+
+	for _, p := range c.probes {
+		label := map[string]string{"target": p.target, "server": p.server}
+
+		// a failed lookup has no latency, the timeout would skew it.
+		latency, err := c.lookup(p.server)
+		if err != nil {
+			log.Debugf("dns probe %s %s: %v", p.target, p.server, err)
+			c.failures[p.target]++
+			failed = append(failed, &DNSLookupFailure{Target: p.target, Server: p.server, Error: err.Error()})
+			data = append(data,
+				metric.NewGaugeData("up", 0, "whether the dns server answered the synthetic lookup", label),
+				metric.NewCounterData("lookup_failures_total", float64(c.failures[p.target]), "failed synthetic lookups", label))
+			continue
+		}
+
+		data = append(data,
+			metric.NewGaugeData("up", 1, "whether the dns server answered the synthetic lookup", label),
+			metric.NewGaugeData("lookup_latency_seconds", latency.Seconds(), "synthetic lookup round trip time", label),
+			metric.NewCounterData("lookup_failures_total", float64(c.failures[p.target]), "failed synthetic lookups", label))
+	}
+
+	c.report(failed)
+
+	if cfg.DNSCache.MetricsURL == "" {
+		return data, nil
+	}
+
+	stats, err := c.scrapeStats()
+	if err != nil {
+		log.Debugf("dns cache stats %s: %v", cfg.DNSCache.MetricsURL, err)
+		return append(data, metric.NewGaugeData("stats_up", 0, "whether the node-local dns stats endpoint is reachable", nil)), nil
+	}
+
+	return append(append(data, metric.NewGaugeData("stats_up", 1, "whether the node-local dns stats endpoint is reachable", nil)), stats...), nil
+}
+
+// report saves a dns_lookup event when the lookups start failing or the
+// cause changes, a lasting failure is saved once.
+func (c *dnsCacheCollector) report(failed []*DNSLookupFailure) {
+	cause := dnsFailureCause(failed, len(c.probes) > 1)
+	if cause == c.cause {
+		return
+	}
+	c.cause = cause
+	if cause == "" {
+		return
+	}
+
+	if err := tracing.Save(&tracing.WriteRequest{
+		TracerName: "dns_cache",
+		TracerTime: time.Now(),
+		TracerData: &DNSLookupTracingData{
+			Cause:     cause,
+			QueryName: cfg.DNSCache.QueryName,
+			Failures:  failed,
+		},
+	}); err != nil {
+		log.Warnf("failed to save tracing data: %v", err)
+	}
+}
+
+// dnsFailureCause tells the node-local cache failures from the upstream ones
+// by the upstream probe, empty when no lookup failed.
+func dnsFailureCause(failed []*DNSLookupFailure, upstreamProbed bool) string {
+	if len(failed) == 0 {
+		return ""
+	}
+
+	for _, f := range failed {
+		if f.Target == dnsProbeTargetUpstream {
+			return dnsFailureUpstream
+		}
+	}
+	if upstreamProbed {
+		return dnsFailureLocalCache
+	}
+	return dnsFailureUnknown
+}
+
+// lookup treats NXDOMAIN as healthy: the server answered, only the name is
+// missing. SERVFAIL/REFUSED mean the cache or its upstream is broken.
+func (c *dnsCacheCollector) lookup(server string) (time.Duration, error) {
+	msg := new(dns.Msg)
+	msg.SetQuestion(dns.Fqdn(cfg.DNSCache.QueryName), dns.TypeA)
+
+	resp, rtt, err := c.resolver.Exchange(msg, server)
+	if err != nil {
+		return rtt, err
+	}
+
+	if resp.Rcode != dns.RcodeSuccess && resp.Rcode != dns.RcodeNameError {
+		return rtt, fmt.Errorf("rcode %s", dns.RcodeToString[resp.Rcode])
+	}
+
+	return rtt, nil
+}
+
+func (c *dnsCacheCollector) scrapeStats() ([]*metric.Data, error) {
+	resp, err := c.client.Get(cfg.DNSCache.MetricsURL)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("status %s", resp.Status)
+	}
+
+	var parser expfmt.TextParser
+	families, err := parser.TextToMetricFamilies(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("parse metrics: %w", err)
+	}
+
+	data := []*metric.Data{}
+	for name, series := range dnsCacheStatsSeries {
+		family, ok := families[name]
+		if !ok {
+			continue
+		}
+
+		data = append(data, metric.NewCounterData(series.name, sumMetricFamily(family), series.help, nil))
+	}
+
+	return data, nil
+}
+
+func sumMetricFamily(family *dto.MetricFamily) float64 {
+	var sum float64
+	for _, m := range family.GetMetric() {
+		switch {
+		case m.GetCounter() != nil:
+			sum += m.GetCounter().GetValue()
+		case m.GetGauge() != nil:
+			sum += m.GetGauge().GetValue()
+		case m.GetUntyped() != nil:
+			sum += m.GetUntyped().GetValue()
+		}
+	}
+
+	return sum
+}
@@ -0,0 +1,123 @@
+// Copyright 2026 The HuaTuo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+// startDNSServer answers every query with rcode and returns its address.
+func startDNSServer(t *testing.T, rcode int) string {
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+
+	started := make(chan struct{})
+	srv := &dns.Server{
+		PacketConn: pc,
+		Handler: dns.HandlerFunc(func(w dns.ResponseWriter, req *dns.Msg) {
+			resp := new(dns.Msg)
+			resp.SetRcode(req, rcode)
+			_ = w.WriteMsg(resp)
+		}),
+		NotifyStartedFunc: func() { close(started) },
+	}
+	go func() { _ = srv.ActivateAndServe() }()
+	t.Cleanup(func() { _ = srv.Shutdown() })
+	<-started
+
+	return pc.LocalAddr().String()
+}
+
+func TestDNSCacheUpdate(t *testing.T) {
+	orig := cfg
+	t.Cleanup(func() { cfg = orig })
+	cfg = &Config{}
+	cfg.DNSCache.QueryName = "kubernetes.default.svc.cluster.local"
+
+	c := &dnsCacheCollector{
+		probes: []dnsProbe{
+			{target: dnsProbeTargetLocal, server: startDNSServer(t, dns.RcodeServerFailure)},
+			{target: dnsProbeTargetUpstream, server: startDNSServer(t, dns.RcodeNameError)},
+		},
+		resolver: &dns.Client{Net: "udp", Timeout: time.Second},
+		failures: map[string]uint64{},
+	}
+
+	data, err := c.Update()
+	if err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	// the failed local lookup has up and failures but no latency, the
+	// NXDOMAIN of the upstream is an answer.
+	if len(data) != 5 {
+		t.Fatalf("Update() returned %d metrics, want 5", len(data))
+	}
+	if data[0].Value != 0 || data[1].Value != 1 || data[2].Value != 1 || data[4].Value != 0 {
+		t.Errorf("Update() = up %v, failures %v, upstream up %v, failures %v",
+			data[0].Value, data[1].Value, data[2].Value, data[4].Value)
+	}
+	if c.cause != dnsFailureLocalCache {
+		t.Errorf("cause = %q, want %q", c.cause, dnsFailureLocalCache)
+	}
+}
+
+func TestDNSFailureCause(t *testing.T) {
+	local := &DNSLookupFailure{Target: dnsProbeTargetLocal}
+	upstream := &DNSLookupFailure{Target: dnsProbeTargetUpstream}
+
+	tests := []struct {
+		name           string
+		failed         []*DNSLookupFailure
+		upstreamProbed bool
+		want           string
+	}{
+		{"healthy", nil, true, ""},
+		{"local cache", []*DNSLookupFailure{local}, true, dnsFailureLocalCache},
+		{"upstream", []*DNSLookupFailure{upstream}, true, dnsFailureUpstream},
+		{"both", []*DNSLookupFailure{local, upstream}, true, dnsFailureUpstream},
+		{"no upstream probe", []*DNSLookupFailure{local}, false, dnsFailureUnknown},
+	}
+	for _, tt := range tests {
+		if got := dnsFailureCause(tt.failed, tt.upstreamProbed); got != tt.want {
+			t.Errorf("%s: dnsFailureCause() = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestDNSCacheReport(t *testing.T) {
+	c := &dnsCacheCollector{probes: make([]dnsProbe, 2)}
+	local := []*DNSLookupFailure{{Target: dnsProbeTargetLocal}}
+
+	for _, step := range []struct {
+		failed []*DNSLookupFailure
+		want   string
+	}{
+		{local, dnsFailureLocalCache},
+		{local, dnsFailureLocalCache},
+		{nil, ""},
+		{[]*DNSLookupFailure{{Target: dnsProbeTargetUpstream}}, dnsFailureUpstream},
+	} {
+		c.report(step.failed)
+		if c.cause != step.want {
+			t.Errorf("cause = %q, want %q", c.cause, step.want)
+		}
+	}
+}
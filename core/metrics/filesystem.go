@@ -0,0 +1,369 @@
+// Copyright 2026 The HuaTuo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"huatuo-bamai/internal/log"
+	"huatuo-bamai/internal/matcher"
+	"huatuo-bamai/internal/pod"
+	"huatuo-bamai/internal/procfs"
+	"huatuo-bamai/pkg/metric"
+	"huatuo-bamai/pkg/tracing"
+
+	"golang.org/x/sys/unix"
+)
+
+// statfsTimeout bounds the statfs of a mount, a hung network filesystem
+// blocks it forever.
+const statfsTimeout = 5 * time.Second
+
+// emptyDirPath is in the host path of the emptyDir volumes of kubelet,
+// followed by the volume name.
+const emptyDirPath = "/volumes/kubernetes.io~empty-dir/"
+
+// diskUsage is the space and the inodes used under a directory.
+type diskUsage struct {
+	bytes  uint64
+	inodes uint64
+}
+
+// filesystemContainerUsage caches the usage of the writable layer and the
+// emptyDir volumes of a container, walked every ContainerInterval.
+type filesystemContainerUsage struct {
+	lastScan  time.Time
+	rootfs    *diskUsage
+	emptyDirs map[string]*diskUsage
+}
+
+type filesystemCollector struct {
+	mutex sync.Mutex
+	// stuck are the mounts of a statfs not returned yet.
+	stuck map[string]bool
+	// scanning is set while the containers are walked.
+	scanning bool
+}
+
+func init() {
+	tracing.RegisterEventTracing("filesystem", newFilesystem)
+	_ = pod.RegisterContainerLifeResources("collector_filesystem", reflect.TypeOf(&filesystemContainerUsage{}))
+}
+
+func newFilesystem() (*tracing.EventTracingAttr, error) {
+	return &tracing.EventTracingAttr{
+		TracingData: &filesystemCollector{
+			stuck: make(map[string]bool),
+		},
+		Flag: tracing.FlagMetric,
+	}, nil
+}
+
+// statfs returns the statfs of the mount, or an error once statfsTimeout
+// passed. The mount is skipped until a stuck statfs returns.
+func (c *filesystemCollector) statfs(mountPoint string) (*unix.Statfs_t, error) {
+	c.mutex.Lock()
+	if c.stuck[mountPoint] {
+		c.mutex.Unlock()
+		return nil, fmt.Errorf("statfs %s: still stuck", mountPoint)
+	}
+	c.stuck[mountPoint] = true
+	c.mutex.Unlock()
+
+	done := make(chan error, 1)
+	var buf unix.Statfs_t
+	go func() {
+		err := unix.Statfs(mountPoint, &buf)
+
+		c.mutex.Lock()
+		delete(c.stuck, mountPoint)
+		c.mutex.Unlock()
+		done <- err
+	}()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			return nil, err
+		}
+		return &buf, nil
+	case <-time.After(statfsTimeout):
+		return nil, fmt.Errorf("statfs %s: timeout", mountPoint)
+	}
+}
+
+func (c *filesystemCollector) Update() ([]*metric.Data, error) {
+	procFS, err := procfs.NewDefaultFS()
+	if err != nil {
+		return nil, err
+	}
+
+	mounts, err := procFS.GetMounts()
+	if err != nil {
+		return nil, err
+	}
+
+	mountFilter, err := matcher.NewValueMatcher("", cfg.FilesystemStat.MountPointsExcluded)
+	if err != nil {
+		return nil, fmt.Errorf("mount point filter: %w", err)
+	}
+	fsTypeFilter, err := matcher.NewValueMatcher("", cfg.FilesystemStat.FSTypesExcluded)
+	if err != nil {
+		return nil, fmt.Errorf("fstype filter: %w", err)
+	}
+
+	// the last mount of a mount point covers the others.
+	visible := make(map[string]*procfs.MountInfo, len(mounts))
+	for _, m := range mounts {
+		visible[m.MountPoint] = m
+	}
+
+	data := []*metric.Data{}
+	for _, m := range mounts {
+		if visible[m.MountPoint] != m || !mountFilter.Match(m.MountPoint) || !fsTypeFilter.Match(m.FSType) {
+			continue
+		}
+
+		buf, err := c.statfs(m.MountPoint)
+		if err != nil {
+			log.Debugf("failed to statfs %s: %v", m.MountPoint, err)
+			continue
+		}
+
+		bsize := float64(buf.Bsize)
+		label := map[string]string{"mountpoint": m.MountPoint, "fstype": m.FSType, "device": m.Source}
+		data = append(data,
+			metric.NewGaugeData("size_bytes", float64(buf.Blocks)*bsize, "size of the filesystem", label),
+			metric.NewGaugeData("free_bytes", float64(buf.Bfree)*bsize, "free space of the filesystem", label),
+			metric.NewGaugeData("avail_bytes", float64(buf.Bavail)*bsize, "space of the filesystem available to unprivileged users", label),
+			metric.NewGaugeData("files", float64(buf.Files), "inodes of the filesystem", label),
+			metric.NewGaugeData("files_free", float64(buf.Ffree), "free inodes of the filesystem", label))
+	}
+
+	containerData, err := c.containerUpdate(mounts)
+	if err != nil {
+		return nil, err
+	}
+	return append(data, containerData...), nil
+}
+
+// containerUpdate returns the cached usage of the containers, and walks the
+// ones due again in the background, one container at a time.
+func (c *filesystemCollector) containerUpdate(hostMounts []*procfs.MountInfo) ([]*metric.Data, error) {
+	containers, err := pod.ContainersByType(pod.ContainerTypeNormal | pod.ContainerTypeSidecar)
+	if err != nil {
+		return nil, err
+	}
+
+	interval := time.Duration(cfg.FilesystemStat.ContainerInterval) * time.Second
+	now := time.Now()
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	var data []*metric.Data
+	var due []*pod.Container
+	for _, container := range containers {
+		usage := container.LifeResources("collector_filesystem").(*filesystemContainerUsage)
+		if now.Sub(usage.lastScan) >= interval {
+			due = append(due, container)
+		}
+
+		if usage.rootfs != nil {
+			data = append(data,
+				metric.NewContainerGaugeData(container, "rootfs_usage_bytes", float64(usage.rootfs.bytes), "space used by the writable layer", nil),
+				metric.NewContainerGaugeData(container, "rootfs_inodes", float64(usage.rootfs.inodes), "inodes used by the writable layer", nil))
+		}
+		for volume, du := range usage.emptyDirs {
+			label := map[string]string{"volume": volume}
+			data = append(data,
+				metric.NewContainerGaugeData(container, "emptydir_usage_bytes", float64(du.bytes), "space used by the emptyDir volume", label),
+				metric.NewContainerGaugeData(container, "emptydir_inodes", float64(du.inodes), "inodes used by the emptyDir volume", label))
+		}
+	}
+
+	if len(due) > 0 && !c.scanning {
+		c.scanning = true
+		go c.scanContainers(due, hostMounts)
+	}
+	return data, nil
+}
+
+func (c *filesystemCollector) scanContainers(containers []*pod.Container, hostMounts []*procfs.MountInfo) {
+	defer func() {
+		c.mutex.Lock()
+		c.scanning = false
+		c.mutex.Unlock()
+	}()
+
+	procFS, err := procfs.NewDefaultFS()
+	if err != nil {
+		log.Infof("failed to open procfs: %v", err)
+		return
+	}
+
+	for _, container := range containers {
+		mounts, err := procFS.GetProcMounts(container.InitPid)
+		if err != nil {
+			log.Infof("failed to get mounts of %s, %v", container, err)
+			continue
+		}
+
+		rootfs, emptyDirs := c.containerUsage(mounts, hostMounts)
+
+		c.mutex.Lock()
+		usage := container.LifeResources("collector_filesystem").(*filesystemContainerUsage)
+		usage.lastScan, usage.rootfs, usage.emptyDirs = time.Now(), rootfs, emptyDirs
+		c.mutex.Unlock()
+	}
+}
+
+// containerUsage walks the upper directory of the overlay root of the
+// container, and its emptyDir volumes. The tmpfs volumes are read by statfs.
+func (c *filesystemCollector) containerUsage(mounts, hostMounts []*procfs.MountInfo) (*diskUsage, map[string]*diskUsage) {
+	var rootfs *diskUsage
+	emptyDirs := make(map[string]*diskUsage)
+
+	for _, m := range mounts {
+		if m.MountPoint == "/" {
+			// the writable layer of the other snapshotters is not known.
+			if upper, ok := m.SuperOptions["upperdir"]; ok && m.FSType == "overlay" {
+				du, err := walkDiskUsage(upper)
+				if err != nil {
+					log.Debugf("failed to walk %s: %v", upper, err)
+					continue
+				}
+				rootfs = du
+			}
+			continue
+		}
+
+		hostPath := hostPathOf(m, hostMounts)
+		volume := emptyDirVolume(hostPath)
+		if volume == "" {
+			continue
+		}
+
+		if m.FSType == "tmpfs" {
+			buf, err := c.statfs(hostPath)
+			if err != nil {
+				log.Debugf("failed to statfs %s: %v", hostPath, err)
+				continue
+			}
+			emptyDirs[volume] = &diskUsage{
+				bytes:  (buf.Blocks - buf.Bfree) * uint64(buf.Bsize),
+				inodes: buf.Files - buf.Ffree,
+			}
+			continue
+		}
+
+		du, err := walkDiskUsage(hostPath)
+		if err != nil {
+			log.Debugf("failed to walk %s: %v", hostPath, err)
+			continue
+		}
+		emptyDirs[volume] = du
+	}
+
+	return rootfs, emptyDirs
+}
+
+// hostPathOf returns the host path of a mount of a container, through the
+// host mount of the same device whose root contains it, "" if none.
+func hostPathOf(m *procfs.MountInfo, hostMounts []*procfs.MountInfo) string {
+	for _, h := range hostMounts {
+		if h.MajorMinorVer != m.MajorMinorVer {
+			continue
+		}
+
+		root := strings.TrimSuffix(h.Root, "/")
+		if m.Root != h.Root && !strings.HasPrefix(m.Root, root+"/") {
+			continue
+		}
+		return filepath.Join(h.MountPoint, strings.TrimPrefix(m.Root, root))
+	}
+	return ""
+}
+
+// emptyDirVolume returns the name of the emptyDir volume of the host path,
+// "" if it is not one.
+func emptyDirVolume(hostPath string) string {
+	_, volume, ok := strings.Cut(hostPath, emptyDirPath)
+	if !ok || volume == "" || strings.Contains(volume, "/") {
+		return ""
+	}
+	return volume
+}
+
+// walkDiskUsage returns the space allocated and the inodes under root, in
+// its filesystem only. The hard links are counted once.
+func walkDiskUsage(root string) (*diskUsage, error) {
+	rootInfo, err := os.Lstat(root)
+	if err != nil {
+		return nil, err
+	}
+	dev := rootInfo.Sys().(*syscall.Stat_t).Dev
+
+	du := &diskUsage{}
+	links := make(map[uint64]bool)
+	err = filepath.WalkDir(root, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			// removed while walked.
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+
+		stat := info.Sys().(*syscall.Stat_t)
+		if stat.Dev != dev {
+			if entry.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if stat.Nlink > 1 && !entry.IsDir() {
+			if links[stat.Ino] {
+				return nil
+			}
+			links[stat.Ino] = true
+		}
+
+		du.bytes += uint64(stat.Blocks) * 512
+		du.inodes++
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return du, nil
+}
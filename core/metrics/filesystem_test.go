@@ -0,0 +1,102 @@
+// Copyright 2026 The HuaTuo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"huatuo-bamai/internal/procfs"
+)
+
+func TestHostPathOf(t *testing.T) {
+	hostMounts := []*procfs.MountInfo{
+		{MajorMinorVer: "0:52", Root: "/", MountPoint: "/var/lib/kubelet/pods/uid/volumes/kubernetes.io~empty-dir/shm", FSType: "tmpfs"},
+		{MajorMinorVer: "8:1", Root: "/", MountPoint: "/", FSType: "ext4"},
+		{MajorMinorVer: "8:16", Root: "/", MountPoint: "/data", FSType: "xfs"},
+		{MajorMinorVer: "8:16", Root: "/kubelet", MountPoint: "/var/lib/kubelet", FSType: "xfs"},
+	}
+
+	tests := []struct {
+		mount *procfs.MountInfo
+		want  string
+	}{
+		{
+			mount: &procfs.MountInfo{MajorMinorVer: "8:1", Root: "/var/lib/kubelet/pods/uid/volumes/kubernetes.io~empty-dir/cache"},
+			want:  "/var/lib/kubelet/pods/uid/volumes/kubernetes.io~empty-dir/cache",
+		},
+		{
+			// the kubelet directory on its own filesystem.
+			mount: &procfs.MountInfo{MajorMinorVer: "8:16", Root: "/kubelet/pods/uid/volumes/kubernetes.io~empty-dir/logs"},
+			want:  "/data/kubelet/pods/uid/volumes/kubernetes.io~empty-dir/logs",
+		},
+		{
+			mount: &procfs.MountInfo{MajorMinorVer: "0:52", Root: "/"},
+			want:  "/var/lib/kubelet/pods/uid/volumes/kubernetes.io~empty-dir/shm",
+		},
+		{mount: &procfs.MountInfo{MajorMinorVer: "0:99", Root: "/"}, want: ""},
+	}
+
+	for _, tt := range tests {
+		if got := hostPathOf(tt.mount, hostMounts); got != tt.want {
+			t.Errorf("hostPathOf(%s %s) = %q, want %q", tt.mount.MajorMinorVer, tt.mount.Root, got, tt.want)
+		}
+	}
+}
+
+func TestEmptyDirVolume(t *testing.T) {
+	for path, want := range map[string]string{
+		"/var/lib/kubelet/pods/uid/volumes/kubernetes.io~empty-dir/cache":     "cache",
+		"/var/lib/kubelet/pods/uid/volumes/kubernetes.io~empty-dir/cache/sub": "",
+		"/var/lib/kubelet/pods/uid/volumes/kubernetes.io~configmap/config":    "",
+		"": "",
+	} {
+		if got := emptyDirVolume(path); got != want {
+			t.Errorf("emptyDirVolume(%q) = %q, want %q", path, got, want)
+		}
+	}
+}
+
+func TestWalkDiskUsage(t *testing.T) {
+	root := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(root, "a", "b"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	file := filepath.Join(root, "a", "b", "file")
+	if err := os.WriteFile(file, make([]byte, 64<<10), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	// a hard link is counted once.
+	if err := os.Link(file, filepath.Join(root, "link")); err != nil {
+		t.Fatal(err)
+	}
+
+	du, err := walkDiskUsage(root)
+	if err != nil {
+		t.Fatalf("walkDiskUsage() error = %v", err)
+	}
+	// root, a, b and file.
+	if du.inodes != 4 {
+		t.Errorf("inodes = %d, want 4", du.inodes)
+	}
+	if du.bytes < 64<<10 {
+		t.Errorf("bytes = %d, want 64KiB at least", du.bytes)
+	}
+
+	if _, err := walkDiskUsage(filepath.Join(root, "missing")); err == nil {
+		t.Error("walkDiskUsage() of a missing directory error = nil")
+	}
+}
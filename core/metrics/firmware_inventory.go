@@ -0,0 +1,362 @@
+// Copyright 2026 The HuaTuo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"huatuo-bamai/internal/log"
+	"huatuo-bamai/internal/procfs"
+	"huatuo-bamai/internal/procfs/sysfs"
+	"huatuo-bamai/pkg/metric"
+	"huatuo-bamai/pkg/tracing"
+
+	"github.com/safchain/ethtool"
+)
+
+func init() {
+	tracing.RegisterEventTracing("firmware_inventory", newFirmwareInventory)
+}
+
+const (
+	firmwareComponentNIC  = "nic"
+	firmwareComponentGPU  = "gpu"
+	firmwareComponentNVMe = "nvme"
+	firmwareComponentBIOS = "bios"
+	firmwareComponentBMC  = "bmc"
+
+	firmwareIpmiTimeout = 10 * time.Second
+)
+
+// firmwareItem is the firmware of a single device.
+type firmwareItem struct {
+	Component string `json:"component"`
+	Device    string `json:"device"`
+	Model     string `json:"model"`
+	Firmware  string `json:"firmware"`
+}
+
+func (f *firmwareItem) key() string {
+	return f.Component + "/" + f.Device
+}
+
+// FirmwareMismatchEvent is saved when a device firmware is not one of the
+// golden versions declared for its component and model.
+type FirmwareMismatchEvent struct {
+	Component string   `json:"component"`
+	Device    string   `json:"device"`
+	Model     string   `json:"model"`
+	Firmware  string   `json:"firmware"`
+	Expected  []string `json:"expected"`
+}
+
+type firmwareInventory struct {
+	items       []firmwareItem
+	refreshTime time.Time
+	// reported remembers the mismatched firmware already saved as event.
+	reported map[string]string
+}
+
+func newFirmwareInventory() (*tracing.EventTracingAttr, error) {
+	return &tracing.EventTracingAttr{
+		TracingData: &firmwareInventory{reported: map[string]string{}},
+		Flag:        tracing.FlagMetric,
+	}, nil
+}
+
+func (f *firmwareInventory) Update() ([]*metric.Data, error) {
+	// firmware only changes with a flash and a reboot or device reset.
+	interval := time.Duration(cfg.FirmwareInventory.Interval) * time.Second
+	if f.items == nil || time.Since(f.refreshTime) >= interval {
+		f.items = collectFirmware()
+		f.refreshTime = time.Now()
+	}
+
+	data := make([]*metric.Data, 0, len(f.items))
+	seen := make(map[string]bool, len(f.items))
+	for i := range f.items {
+		item := &f.items[i]
+		seen[item.key()] = true
+
+		data = append(data, metric.NewGaugeData("info", 1, "Firmware version of a device.", map[string]string{
+			"component": item.Component,
+			"device":    item.Device,
+			"model":     item.Model,
+			"firmware":  item.Firmware,
+		}))
+
+		expected, ok := firmwareGolden(item)
+		if !ok {
+			continue
+		}
+
+		mismatch := !slices.Contains(expected, item.Firmware)
+		value := 0.0
+		if mismatch {
+			value = 1
+			f.reportMismatch(item, expected)
+		} else {
+			delete(f.reported, item.key())
+		}
+
+		data = append(data, metric.NewGaugeData("mismatch", value, "Whether the firmware deviates from the golden versions, 1 means mismatch.", map[string]string{
+			"component": item.Component,
+			"device":    item.Device,
+			"model":     item.Model,
+		}))
+	}
+
+	for key := range f.reported {
+		if !seen[key] {
+			delete(f.reported, key)
+		}
+	}
+
+	return data, nil
+}
+
+func (f *firmwareInventory) reportMismatch(item *firmwareItem, expected []string) {
+	if f.reported[item.key()] == item.Firmware {
+		return
+	}
+	f.reported[item.key()] = item.Firmware
+
+	log.Warnf("firmware mismatch: %s %s (%s) runs %s, expected %v",
+		item.Component, item.Device, item.Model, item.Firmware, expected)
+
+	if err := tracing.Save(&tracing.WriteRequest{
+		TracerName: "firmware_inventory",
+		TracerTime: time.Now(),
+		TracerData: &FirmwareMismatchEvent{
+			Component: item.Component,
+			Device:    item.Device,
+			Model:     item.Model,
+			Firmware:  item.Firmware,
+			Expected:  expected,
+		},
+	}); err != nil {
+		log.Warnf("failed to save tracing data: %v", err)
+	}
+}
+
+// firmwareGolden returns the golden versions of the item. An entry naming
+// the model of the item wins over one for the whole component.
+func firmwareGolden(item *firmwareItem) ([]string, bool) {
+	var (
+		expected []string
+		found    bool
+	)
+	for _, golden := range cfg.FirmwareInventory.Golden {
+		if golden.Component != item.Component {
+			continue
+		}
+		if golden.Model == item.Model {
+			return golden.Versions, true
+		}
+		if golden.Model == "" && !found {
+			expected, found = golden.Versions, true
+		}
+	}
+	return expected, found
+}
+
+func collectFirmware() []firmwareItem {
+	var items []firmwareItem
+
+	for _, source := range []struct {
+		name    string
+		collect func() ([]firmwareItem, error)
+	}{
+		{firmwareComponentNIC, nicFirmware},
+		{firmwareComponentGPU, gpuFirmware},
+		{firmwareComponentNVMe, nvmeFirmware},
+		{firmwareComponentBIOS, biosFirmware},
+		{firmwareComponentBMC, bmcFirmware},
+	} {
+		found, err := source.collect()
+		if err != nil {
+			log.Debugf("firmware inventory %s: %v", source.name, err)
+			continue
+		}
+		items = append(items, found...)
+	}
+
+	return items
+}
+
+// nicFirmware reads the firmware of physical NICs, as ethtool -i does.
+func nicFirmware() ([]firmwareItem, error) {
+	ifaces, err := sysfs.DefaultNetClassDevices()
+	if err != nil {
+		return nil, err
+	}
+
+	eth, err := ethtool.NewEthtool()
+	if err != nil {
+		return nil, err
+	}
+	defer eth.Close()
+
+	var items []firmwareItem
+	for _, iface := range ifaces {
+		// virtual interfaces have no backing device.
+		if _, err := os.Stat(sysfs.Path("class/net", iface, "device")); err != nil {
+			continue
+		}
+
+		drv, err := eth.DriverInfo(iface)
+		if err != nil || drv.FwVersion == "" || drv.FwVersion == "N/A" {
+			continue
+		}
+
+		items = append(items, firmwareItem{
+			Component: firmwareComponentNIC,
+			Device:    iface,
+			Model:     drv.Driver,
+			Firmware:  drv.FwVersion,
+		})
+	}
+	return items, nil
+}
+
+// gpuFirmware reads the video BIOS of NVIDIA GPUs, MetaX GPUs export their
+// bios version in metax_gpu_info.
+func gpuFirmware() ([]firmwareItem, error) {
+	infos, err := filepath.Glob(procfs.Path("driver/nvidia/gpus/*/information"))
+	if err != nil {
+		return nil, err
+	}
+
+	var items []firmwareItem
+	for _, info := range infos {
+		data, err := os.ReadFile(info)
+		if err != nil {
+			continue
+		}
+
+		fields := parseColonFields(data)
+		if fields["Video BIOS"] == "" {
+			continue
+		}
+
+		items = append(items, firmwareItem{
+			Component: firmwareComponentGPU,
+			Device:    filepath.Base(filepath.Dir(info)),
+			Model:     fields["Model"],
+			Firmware:  fields["Video BIOS"],
+		})
+	}
+	return items, nil
+}
+
+func nvmeFirmware() ([]firmwareItem, error) {
+	ctrls, err := filepath.Glob(sysfs.Path("class/nvme/nvme*"))
+	if err != nil {
+		return nil, err
+	}
+
+	var items []firmwareItem
+	for _, ctrl := range ctrls {
+		firmware := readTrimmed(filepath.Join(ctrl, "firmware_rev"))
+		if firmware == "" {
+			continue
+		}
+
+		items = append(items, firmwareItem{
+			Component: firmwareComponentNVMe,
+			Device:    filepath.Base(ctrl),
+			Model:     readTrimmed(filepath.Join(ctrl, "model")),
+			Firmware:  firmware,
+		})
+	}
+	return items, nil
+}
+
+func biosFirmware() ([]firmwareItem, error) {
+	firmware := readTrimmed(sysfs.Path("class/dmi/id/bios_version"))
+	if firmware == "" {
+		return nil, nil
+	}
+
+	return []firmwareItem{{
+		Component: firmwareComponentBIOS,
+		Device:    "bios",
+		Model:     readTrimmed(sysfs.Path("class/dmi/id/bios_vendor")),
+		Firmware:  firmware,
+	}}, nil
+}
+
+// bmcFirmware runs ipmitool mc info, only when enabled as it is slow and
+// loads the ipmi driver.
+func bmcFirmware() ([]firmwareItem, error) {
+	if !cfg.FirmwareInventory.EnableBMC {
+		return nil, nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), firmwareIpmiTimeout)
+	defer cancel()
+
+	out, err := exec.CommandContext(ctx, "ipmitool", "mc", "info").Output()
+	if err != nil {
+		return nil, err
+	}
+
+	fields := parseColonFields(out)
+	if fields["Firmware Revision"] == "" {
+		return nil, nil
+	}
+
+	return []firmwareItem{{
+		Component: firmwareComponentBMC,
+		Device:    "bmc",
+		Model:     fields["Manufacturer Name"],
+		Firmware:  fields["Firmware Revision"],
+	}}, nil
+}
+
+// parseColonFields parses "Key: value" lines, keys may contain spaces.
+func parseColonFields(data []byte) map[string]string {
+	fields := map[string]string{}
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		key, value, ok := strings.Cut(scanner.Text(), ":")
+		if !ok {
+			continue
+		}
+		key = strings.TrimSpace(key)
+		if _, ok := fields[key]; ok || key == "" {
+			continue
+		}
+		fields[key] = strings.TrimSpace(value)
+	}
+	return fields
+}
+
+func readTrimmed(path string) string {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
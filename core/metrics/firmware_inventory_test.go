@@ -0,0 +1,137 @@
+// Copyright 2026 The HuaTuo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"huatuo-bamai/internal/procfs"
+)
+
+func writeTestFile(t *testing.T, path, content string) {
+	t.Helper()
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func setFirmwareRootfs(t *testing.T) string {
+	t.Helper()
+
+	root := t.TempDir()
+	procfs.RootPrefix(root)
+	t.Cleanup(func() { procfs.RootPrefix("/") })
+
+	writeTestFile(t, filepath.Join(root, "sys/class/nvme/nvme0/firmware_rev"), "GDC5302Q\n")
+	writeTestFile(t, filepath.Join(root, "sys/class/nvme/nvme0/model"), "SAMSUNG MZQL23T8HCLS\n")
+	writeTestFile(t, filepath.Join(root, "sys/class/dmi/id/bios_version"), "2.1.4\n")
+	writeTestFile(t, filepath.Join(root, "sys/class/dmi/id/bios_vendor"), "Dell Inc.\n")
+	writeTestFile(t, filepath.Join(root, "proc/driver/nvidia/gpus/0000:3b:00.0/information"),
+		"Model: \t\t NVIDIA A100-SXM4-80GB\nIRQ:   \t\t 123\nVideo BIOS: \t 92.00.45.00.06\nBus Type: \t PCIe\n")
+	return root
+}
+
+func TestCollectFirmware(t *testing.T) {
+	setFirmwareRootfs(t)
+
+	got := map[string]firmwareItem{}
+	for _, item := range collectFirmware() {
+		got[item.key()] = item
+	}
+
+	want := map[string]firmwareItem{
+		"nvme/nvme0":       {Component: "nvme", Device: "nvme0", Model: "SAMSUNG MZQL23T8HCLS", Firmware: "GDC5302Q"},
+		"bios/bios":        {Component: "bios", Device: "bios", Model: "Dell Inc.", Firmware: "2.1.4"},
+		"gpu/0000:3b:00.0": {Component: "gpu", Device: "0000:3b:00.0", Model: "NVIDIA A100-SXM4-80GB", Firmware: "92.00.45.00.06"},
+	}
+	for key, item := range want {
+		if !reflect.DeepEqual(got[key], item) {
+			t.Errorf("%s = %+v, want %+v", key, got[key], item)
+		}
+	}
+}
+
+func TestFirmwareInventoryMismatch(t *testing.T) {
+	setFirmwareRootfs(t)
+
+	orig := cfg
+	t.Cleanup(func() { cfg = orig })
+	cfg = &Config{}
+	cfg.FirmwareInventory.Interval = 600
+	cfg.FirmwareInventory.Golden = []struct {
+		Component string
+		Model     string
+		Versions  []string
+	}{
+		{Component: "nvme", Versions: []string{"GDC5902Q"}},
+		{Component: "nvme", Model: "SAMSUNG MZQL23T8HCLS", Versions: []string{"GDC5302Q"}},
+		{Component: "bios", Versions: []string{"2.2.0"}},
+	}
+
+	// the model entry wins over the component entry.
+	if got, _ := firmwareGolden(&firmwareItem{Component: "nvme", Model: "SAMSUNG MZQL23T8HCLS"}); !reflect.DeepEqual(got, []string{"GDC5302Q"}) {
+		t.Errorf("golden of known model = %v, want [GDC5302Q]", got)
+	}
+	if got, _ := firmwareGolden(&firmwareItem{Component: "nvme", Model: "other"}); !reflect.DeepEqual(got, []string{"GDC5902Q"}) {
+		t.Errorf("golden of other model = %v, want [GDC5902Q]", got)
+	}
+	if _, ok := firmwareGolden(&firmwareItem{Component: "gpu"}); ok {
+		t.Errorf("gpu has no golden versions")
+	}
+
+	f := &firmwareInventory{reported: map[string]string{}}
+	data, err := f.Update()
+	if err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+
+	// info of nvme, bios and gpu, mismatch of nvme and bios.
+	if len(data) < 5 {
+		t.Errorf("Update() returned %d metrics, want at least 5", len(data))
+	}
+	if want := map[string]string{"bios/bios": "2.1.4"}; !reflect.DeepEqual(f.reported, want) {
+		t.Errorf("reported = %v, want %v", f.reported, want)
+	}
+
+	// a fixed firmware clears the report.
+	cfg.FirmwareInventory.Golden[2].Versions = []string{"2.1.4"}
+	if _, err := f.Update(); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	if len(f.reported) != 0 {
+		t.Errorf("reported = %v, want empty", f.reported)
+	}
+}
+
+func TestParseColonFields(t *testing.T) {
+	out := []byte(`Device ID                 : 32
+Firmware Revision         : 7.10
+IPMI Version              : 2.0
+Manufacturer Name         : Dell Inc.
+Additional Device Support :
+    Sensor Device
+`)
+	fields := parseColonFields(out)
+	if fields["Firmware Revision"] != "7.10" || fields["Manufacturer Name"] != "Dell Inc." {
+		t.Errorf("parseColonFields() = %v", fields)
+	}
+}
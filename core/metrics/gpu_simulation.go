@@ -0,0 +1,75 @@
+// Copyright 2026 The HuaTuo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"fmt"
+	"slices"
+
+	"huatuo-bamai/core/metrics/gpusim"
+	"huatuo-bamai/core/metrics/metax/sml"
+	"huatuo-bamai/core/metrics/nvidia/nvml"
+	"huatuo-bamai/internal/log"
+)
+
+// the vendors of GPUSimulation.Vendors.
+const (
+	gpuVendorMetax  = "metax"
+	gpuVendorNvidia = "nvidia"
+)
+
+// gpuSimulate installs the simulated GPUs of the configuration in place of
+// the library of vendor, before the library is initialized. It returns
+// false when the vendor is not simulated.
+func gpuSimulate(vendor string) (bool, error) {
+	simCfg := &cfg.GPUSimulation
+	if !simCfg.Enable || (len(simCfg.Vendors) > 0 && !slices.Contains(simCfg.Vendors, vendor)) {
+		return false, nil
+	}
+
+	behaviors := make([]gpusim.Behavior, 0, len(simCfg.Behaviors))
+	for _, behavior := range simCfg.Behaviors {
+		behaviors = append(behaviors, gpusim.Behavior(behavior))
+	}
+	failures := make(map[string]int32, len(simCfg.Failures))
+	for _, failure := range simCfg.Failures {
+		failures[failure.Symbol] = failure.Code
+	}
+
+	sim, err := gpusim.New(&gpusim.Config{
+		GPUs:         simCfg.GPUs,
+		Behaviors:    behaviors,
+		NotSupported: simCfg.NotSupported,
+		Failures:     failures,
+	})
+	if err != nil {
+		return true, err
+	}
+
+	switch vendor {
+	case gpuVendorMetax:
+		err = sml.Simulate(sim)
+	case gpuVendorNvidia:
+		err = nvml.Simulate(sim)
+	default:
+		err = fmt.Errorf("no gpu simulation of vendor %s", vendor)
+	}
+	if err != nil {
+		return true, err
+	}
+
+	log.Infof("%s gpu library simulated with %d gpus", vendor, sim.GPUs())
+	return true, nil
+}
@@ -0,0 +1,126 @@
+// Copyright 2026 The HuaTuo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"context"
+	"testing"
+
+	"huatuo-bamai/core/metrics/metax/sml"
+	"huatuo-bamai/core/metrics/nvidia/nvml"
+)
+
+// simulateGpus sets the configuration of a simulation of the vendor.
+func simulateGpus(t *testing.T, vendor string, behaviors, notSupported []string) {
+	t.Helper()
+
+	orig := cfg
+	t.Cleanup(func() { cfg = orig })
+	cfg = &Config{}
+	cfg.GPUSimulation.Enable = true
+	cfg.GPUSimulation.Vendors = []string{vendor}
+	cfg.GPUSimulation.GPUs = 2
+	cfg.GPUSimulation.Behaviors = behaviors
+	cfg.GPUSimulation.NotSupported = notSupported
+}
+
+func TestGpuSimulateDisabled(t *testing.T) {
+	simulateGpus(t, gpuVendorNvidia, nil, nil)
+
+	if simulated, err := gpuSimulate(gpuVendorMetax); simulated || err != nil {
+		t.Errorf("gpuSimulate(metax) = %v, %v, want not simulated", simulated, err)
+	}
+
+	cfg.GPUSimulation.Behaviors = []string{"melting"}
+	if _, err := gpuSimulate(gpuVendorNvidia); err == nil {
+		t.Error("gpuSimulate() with an unknown behavior error = nil")
+	}
+}
+
+func TestGpuSimulationMetax(t *testing.T) {
+	simulateGpus(t, gpuVendorMetax, []string{"busy", "hot"}, []string{"mxSmlGetPcieInfo"})
+	cfg.MetaxGpu.Concurrency = 1
+	cfg.MetaxGpu.Timeout = 5
+
+	attr, err := newMetaxGpuCollector()
+	if err != nil {
+		t.Fatalf("newMetaxGpuCollector() error = %v", err)
+	}
+	t.Cleanup(func() { _ = sml.Shutdown() })
+
+	data, err := attr.TracingData.(*metaxGpuCollector).Update()
+	if err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	if len(data) < 20 {
+		t.Errorf("Update() returned %d metrics, want at least 20", len(data))
+	}
+
+	ctx := context.Background()
+	if _, err := sml.GetGPUPcieLinkInfo(ctx, 0); !sml.IsNotSupported(err) {
+		t.Errorf("GetGPUPcieLinkInfo() error = %v, want not supported", err)
+	}
+	// the hot GPU throttles on chip_overheated, bit 4.
+	if status, err := sml.GetDieClocksThrottleStatus(ctx, 1, 0); err != nil || status != 1<<3 {
+		t.Errorf("GetDieClocksThrottleStatus() = %#x, %v, want 0x8", status, err)
+	}
+}
+
+func TestGpuSimulationNvidia(t *testing.T) {
+	simulateGpus(t, gpuVendorNvidia, []string{"busy", "ecc"}, []string{"nvmlDeviceGetPowerUsage"})
+
+	attr, err := newNvidiaGpuCollector()
+	if err != nil {
+		t.Fatalf("newNvidiaGpuCollector() error = %v", err)
+	}
+	collector := attr.TracingData.(*nvidiaGpuCollector)
+	// the Xid watcher would outlive the test.
+	collector.xidOnce.Do(func() {})
+
+	data, err := collector.Update()
+	if err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	if len(data) < 10 {
+		t.Errorf("Update() returned %d metrics, want at least 10", len(data))
+	}
+	if len(collector.devices) != 2 {
+		t.Errorf("devices = %d, want 2", len(collector.devices))
+	}
+	if _, err := nvml.GetPower(context.Background(), collector.devices[0]); !nvml.IsNotSupported(err) {
+		t.Errorf("GetPower() error = %v, want not supported", err)
+	}
+	if err := nvml.Shutdown(); err != nil {
+		t.Fatalf("Shutdown() error = %v", err)
+	}
+
+	// an error code of the driver fails the collection.
+	cfg.GPUSimulation.Failures = append(cfg.GPUSimulation.Failures, struct {
+		Symbol string
+		Code   int32
+	}{Symbol: "nvmlSystemGetDriverVersion", Code: 999})
+
+	attr, err = newNvidiaGpuCollector()
+	if err != nil {
+		t.Fatalf("newNvidiaGpuCollector() error = %v", err)
+	}
+	t.Cleanup(func() { _ = nvml.Shutdown() })
+	collector = attr.TracingData.(*nvidiaGpuCollector)
+	collector.xidOnce.Do(func() {})
+
+	if _, err := collector.Update(); err == nil {
+		t.Error("Update() with a failing driver version error = nil")
+	}
+}
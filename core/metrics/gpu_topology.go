@@ -0,0 +1,78 @@
+// Copyright 2026 The HuaTuo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"os"
+	"strings"
+
+	"huatuo-bamai/internal/procfs/sysfs"
+)
+
+// gpuSysfsBDF returns bdf the way sysfs names PCI devices, the vendor
+// libraries report an 8 digit domain in upper case, e.g. "00000000:3B:00.0".
+func gpuSysfsBDF(bdf string) string {
+	bdf = strings.ToLower(strings.TrimSpace(bdf))
+	if domain, rest, ok := strings.Cut(bdf, ":"); ok && len(domain) > 4 && strings.Count(rest, ":") == 1 {
+		return domain[len(domain)-4:] + ":" + rest
+	}
+	return bdf
+}
+
+// gpuTopologyLabels returns the labels of the topology_info metric of a
+// GPU: the NUMA node and the PCIe root complex it is attached to, empty
+// when sysfs does not tell.
+func gpuTopologyLabels(gpu, bdf string) map[string]string {
+	device := gpuSysfsBDF(bdf)
+	root, _ := pciDeviceChain(device)
+
+	return map[string]string{
+		"gpu":       gpu,
+		"bdf":       bdf,
+		"numa_node": pciNUMANode(device),
+		"pcie_root": root,
+	}
+}
+
+// pciDeviceChain returns the root bus of a PCI device and the devices from
+// its root port down to the device itself.
+func pciDeviceChain(bdf string) (string, []string) {
+	// e.g. ../../../devices/pci0000:00/0000:00:01.0/0000:01:00.0
+	target, err := os.Readlink(sysfs.Path("bus/pci/devices", bdf))
+	if err != nil {
+		return "", []string{bdf}
+	}
+
+	elems := strings.Split(target, "/")
+	for i, elem := range elems {
+		if strings.HasPrefix(elem, "pci") {
+			return elem, elems[i+1:]
+		}
+	}
+	return "", []string{bdf}
+}
+
+func pciNUMANode(bdf string) string {
+	node, _ := readSysfsString(sysfs.Path("bus/pci/devices", bdf, "numa_node"))
+	return node
+}
+
+func readSysfsString(path string) (string, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(raw)), nil
+}
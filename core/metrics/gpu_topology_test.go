@@ -0,0 +1,78 @@
+// Copyright 2026 The HuaTuo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"huatuo-bamai/core/metrics/nvidia/nvml"
+	"huatuo-bamai/internal/procfs"
+)
+
+func TestGpuSysfsBDF(t *testing.T) {
+	for bdf, want := range map[string]string{
+		"00000000:3B:00.0": "0000:3b:00.0",
+		"0000:3b:00.0":     "0000:3b:00.0",
+		"00000001:C1:00.0": "0001:c1:00.0",
+	} {
+		if got := gpuSysfsBDF(bdf); got != want {
+			t.Errorf("gpuSysfsBDF(%q) = %q, want %q", bdf, got, want)
+		}
+	}
+}
+
+func TestGpuTopologyLabels(t *testing.T) {
+	root := t.TempDir()
+	procfs.RootPrefix(root)
+	t.Cleanup(func() { procfs.RootPrefix("/") })
+
+	device := filepath.Join(root, "sys/devices/pci0000:3a/0000:3a:00.0/0000:3b:00.0")
+	writeTestFile(t, filepath.Join(device, "numa_node"), "1\n")
+	if err := os.MkdirAll(filepath.Join(root, "sys/bus/pci/devices"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("../../../devices/pci0000:3a/0000:3a:00.0/0000:3b:00.0",
+		filepath.Join(root, "sys/bus/pci/devices/0000:3b:00.0")); err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]string{"gpu": "0", "bdf": "00000000:3B:00.0", "numa_node": "1", "pcie_root": "pci0000:3a"}
+	if got := gpuTopologyLabels("0", "00000000:3B:00.0"); !reflect.DeepEqual(got, want) {
+		t.Errorf("gpuTopologyLabels() = %v, want %v", got, want)
+	}
+
+	want = map[string]string{"gpu": "1", "bdf": "0000:5e:00.0", "numa_node": "", "pcie_root": ""}
+	if got := gpuTopologyLabels("1", "0000:5e:00.0"); !reflect.DeepEqual(got, want) {
+		t.Errorf("missing device: gpuTopologyLabels() = %v, want %v", got, want)
+	}
+}
+
+func TestNvidiaNvLinkPeers(t *testing.T) {
+	gpuByBDF := map[string]int{"0000:3b:00.0": 0, "0000:5e:00.0": 1, "0000:86:00.0": 2}
+	remotes := []nvml.NvLinkRemote{
+		{Link: 0, BDF: "00000000:86:00.0"},
+		{Link: 1, BDF: "00000000:5E:00.0"},
+		{Link: 2, BDF: "00000000:86:00.0"},
+		{Link: 3, BDF: "00000000:C1:00.0"},
+	}
+
+	peers, switchLinks := nvidiaNvLinkPeers(remotes, gpuByBDF)
+	if peers != "1,2" || switchLinks != 1 {
+		t.Errorf("nvidiaNvLinkPeers() = %q, %d, want \"1,2\", 1", peers, switchLinks)
+	}
+}
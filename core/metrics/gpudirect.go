@@ -0,0 +1,328 @@
+// Copyright 2026 The HuaTuo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"huatuo-bamai/internal/log"
+	"huatuo-bamai/internal/procfs/sysfs"
+	"huatuo-bamai/pkg/metric"
+	"huatuo-bamai/pkg/tracing"
+)
+
+func init() {
+	tracing.RegisterEventTracing("gpudirect", newGPUDirect)
+}
+
+// The PCIe path between a GPU and a NIC, named as nvidia-smi topo does.
+const (
+	// gpuDirectPathPIX crosses a single PCIe switch.
+	gpuDirectPathPIX = "pix"
+	// gpuDirectPathPXB crosses several PCIe switches.
+	gpuDirectPathPXB = "pxb"
+	// gpuDirectPathPHB crosses the host bridge of a CPU.
+	gpuDirectPathPHB = "phb"
+	// gpuDirectPathSYS crosses the interconnect between the CPUs.
+	gpuDirectPathSYS = "sys"
+)
+
+// gpuDirectPeerMemModules are the NVIDIA peer memory modules, checked
+// when GPUDirect.PeerMemModules is empty.
+var gpuDirectPeerMemModules = []string{"nvidia_peermem", "nv_peer_mem"}
+
+// The reasons a GPU/NIC pair is not ready for GPUDirect RDMA.
+const (
+	gpuDirectReasonPeerMem   = "peermem_not_loaded"
+	gpuDirectReasonACS       = "acs_redirect"
+	gpuDirectReasonIOMMU     = "iommu_translated"
+	gpuDirectReasonCrossNUMA = "cross_numa"
+)
+
+const (
+	pciVendorNVIDIA = "0x10de"
+	pciVendorMetaX  = "0x9999"
+
+	// pciExtCapACS is the ACS extended capability, the control register is
+	// at offset 6. Request and completion redirect send the peer to peer
+	// transactions up to the root complex.
+	pciExtCapStart      = 0x100
+	pciExtCapACS        = 0x000d
+	pciACSCtrlOffset    = 6
+	pciACSCtrlRedirects = 1<<2 | 1<<3
+)
+
+// gpuDirectPair is the GPUDirect RDMA state of the path between a GPU and
+// an RDMA NIC.
+type gpuDirectPair struct {
+	GPU     string   `json:"gpu"`
+	NIC     string   `json:"nic"`
+	Path    string   `json:"path"`
+	ACSPort []string `json:"acs_ports,omitempty"`
+	Reasons []string `json:"reasons,omitempty"`
+}
+
+func (p *gpuDirectPair) key() string {
+	return p.GPU + "/" + p.NIC
+}
+
+// GPUDirectDegradedEvent is saved when a GPU/NIC pair ready for GPUDirect
+// RDMA is no longer, the traffic then falls back to bounce buffers in the
+// host memory.
+type GPUDirectDegradedEvent struct {
+	*gpuDirectPair
+	PeerMemModules []string `json:"peermem_modules"`
+	IOMMU          bool     `json:"iommu"`
+}
+
+type gpuDirect struct {
+	refreshTime time.Time
+	peerMem     bool
+	iommu       bool
+	pairs       []*gpuDirectPair
+	// ready remembers the pairs ready at the previous refresh.
+	ready map[string]bool
+}
+
+func newGPUDirect() (*tracing.EventTracingAttr, error) {
+	return &tracing.EventTracingAttr{
+		TracingData: &gpuDirect{ready: map[string]bool{}},
+		Flag:        tracing.FlagMetric,
+	}, nil
+}
+
+func (g *gpuDirect) Update() ([]*metric.Data, error) {
+	interval := time.Duration(cfg.GPUDirect.Interval) * time.Second
+	if g.refreshTime.IsZero() || time.Since(g.refreshTime) >= interval {
+		if err := g.refresh(); err != nil {
+			return nil, err
+		}
+		g.refreshTime = time.Now()
+	}
+
+	// no GPU or no RDMA NIC, nothing to check.
+	if len(g.pairs) == 0 {
+		return nil, nil
+	}
+
+	data := []*metric.Data{
+		metric.NewGaugeData("peermem_loaded", boolFloat(g.peerMem), "Whether a GPU peer memory module is loaded, 1 means loaded.", nil),
+		metric.NewGaugeData("iommu_enabled", boolFloat(g.iommu), "Whether an IOMMU is enabled.", nil),
+	}
+	for _, pair := range g.pairs {
+		labels := map[string]string{"gpu": pair.GPU, "nic": pair.NIC, "path": pair.Path}
+		data = append(data,
+			metric.NewGaugeData("ready", boolFloat(len(pair.Reasons) == 0), "Whether the GPU/NIC pair is ready for GPUDirect RDMA, 1 means ready.", labels),
+			metric.NewGaugeData("acs_redirect_ports", float64(len(pair.ACSPort)), "PCIe ports between the GPU and the NIC redirecting peer to peer requests.", labels),
+		)
+	}
+	return data, nil
+}
+
+func (g *gpuDirect) refresh() error {
+	gpus, nics, err := gpuDirectDevices()
+	if err != nil {
+		return err
+	}
+
+	modules := cfg.GPUDirect.PeerMemModules
+	if len(modules) == 0 {
+		modules = gpuDirectPeerMemModules
+	}
+	g.peerMem = gpuPeerMemLoaded(modules)
+	g.iommu = iommuEnabled()
+
+	g.pairs = g.pairs[:0]
+	for _, gpu := range gpus {
+		for nic, bdf := range nics {
+			pair := gpuDirectCheck(gpu, bdf, g.peerMem)
+			pair.NIC = nic
+			g.pairs = append(g.pairs, pair)
+		}
+	}
+	slices.SortFunc(g.pairs, func(a, b *gpuDirectPair) int { return strings.Compare(a.key(), b.key()) })
+
+	ready := make(map[string]bool, len(g.pairs))
+	for _, pair := range g.pairs {
+		ready[pair.key()] = len(pair.Reasons) == 0
+		if g.ready[pair.key()] && !ready[pair.key()] {
+			g.reportDegraded(pair)
+		}
+	}
+	g.ready = ready
+	return nil
+}
+
+func (g *gpuDirect) reportDegraded(pair *gpuDirectPair) {
+	log.Warnf("gpudirect: gpu %s and nic %s not ready: %v", pair.GPU, pair.NIC, pair.Reasons)
+
+	if err := tracing.Save(&tracing.WriteRequest{
+		TracerName: "gpudirect",
+		TracerTime: time.Now(),
+		TracerData: &GPUDirectDegradedEvent{
+			gpuDirectPair:  pair,
+			PeerMemModules: cfg.GPUDirect.PeerMemModules,
+			IOMMU:          g.iommu,
+		},
+	}); err != nil {
+		log.Warnf("failed to save tracing data: %v", err)
+	}
+}
+
+// gpuDirectDevices returns the BDF of the NVIDIA and MetaX GPUs, and the
+// PCI device of every RDMA NIC by its name.
+func gpuDirectDevices() ([]string, map[string]string, error) {
+	entries, err := os.ReadDir(sysfs.Path("bus/pci/devices"))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var gpus []string
+	for _, entry := range entries {
+		device := sysfs.Path("bus/pci/devices", entry.Name())
+		// 0x0300 VGA and 0x0302 3D controllers.
+		class, err := readSysfsString(filepath.Join(device, "class"))
+		if err != nil || (!strings.HasPrefix(class, "0x0300") && !strings.HasPrefix(class, "0x0302")) {
+			continue
+		}
+		vendor, err := readSysfsString(filepath.Join(device, "vendor"))
+		if err != nil || (vendor != pciVendorNVIDIA && vendor != pciVendorMetaX) {
+			continue
+		}
+		gpus = append(gpus, entry.Name())
+	}
+
+	nics := make(map[string]string)
+	ibs, err := os.ReadDir(sysfs.Path("class/infiniband"))
+	if err != nil && !os.IsNotExist(err) {
+		return nil, nil, err
+	}
+	for _, ib := range ibs {
+		// e.g. ../../../0000:5e:00.0
+		target, err := os.Readlink(sysfs.Path("class/infiniband", ib.Name(), "device"))
+		if err != nil {
+			continue
+		}
+		nics[ib.Name()] = filepath.Base(target)
+	}
+
+	return gpus, nics, nil
+}
+
+// gpuPeerMemLoaded reports whether one of the peer memory modules, which
+// register the GPU memory to the RDMA stack, is loaded.
+func gpuPeerMemLoaded(modules []string) bool {
+	for _, module := range modules {
+		if _, err := os.Stat(sysfs.Path("module", module)); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+func iommuEnabled() bool {
+	entries, err := os.ReadDir(sysfs.Path("class/iommu"))
+	return err == nil && len(entries) > 0
+}
+
+// gpuDirectCheck checks the prerequisites of GPUDirect RDMA between a GPU
+// and a NIC: the peer memory module, the PCIe path and the ACS redirection
+// of its switch ports, and the IOMMU translating the peer addresses.
+func gpuDirectCheck(gpu, nic string, peerMem bool) *gpuDirectPair {
+	pair := &gpuDirectPair{GPU: gpu}
+	if !peerMem {
+		pair.Reasons = append(pair.Reasons, gpuDirectReasonPeerMem)
+	}
+
+	gpuRoot, gpuChain := pciDeviceChain(gpu)
+	nicRoot, nicChain := pciDeviceChain(nic)
+
+	common := 0
+	if gpuRoot == nicRoot {
+		for common < len(gpuChain)-1 && common < len(nicChain)-1 && gpuChain[common] == nicChain[common] {
+			common++
+		}
+	}
+
+	switch {
+	case common > 0:
+		// the ports below the common one route the peer requests.
+		below := len(gpuChain) - 1 - common + len(nicChain) - 1 - common
+		pair.Path = gpuDirectPathPXB
+		if below <= 2 {
+			pair.Path = gpuDirectPathPIX
+		}
+		for _, port := range append(gpuChain[common:len(gpuChain)-1], nicChain[common:len(nicChain)-1]...) {
+			if pciACSRedirects(port) {
+				pair.ACSPort = append(pair.ACSPort, port)
+			}
+		}
+		if len(pair.ACSPort) > 0 {
+			pair.Reasons = append(pair.Reasons, gpuDirectReasonACS)
+		}
+	case pciNUMANode(gpu) == pciNUMANode(nic):
+		pair.Path = gpuDirectPathPHB
+	default:
+		pair.Path = gpuDirectPathSYS
+		pair.Reasons = append(pair.Reasons, gpuDirectReasonCrossNUMA)
+	}
+
+	// a translating IOMMU needs the peer addresses mapped, passthrough
+	// (identity) domains do not.
+	if domain, err := readSysfsString(sysfs.Path("bus/pci/devices", gpu, "iommu_group/type")); err == nil && domain != "identity" {
+		pair.Reasons = append(pair.Reasons, gpuDirectReasonIOMMU)
+	}
+
+	return pair
+}
+
+// pciACSRedirects reports whether the ACS of a port redirects the peer to
+// peer requests. The extended config space is only readable by root.
+func pciACSRedirects(bdf string) bool {
+	config, err := os.ReadFile(sysfs.Path("bus/pci/devices", bdf, "config"))
+	if err != nil {
+		log.Debugf("gpudirect read config of %s: %v", bdf, err)
+		return false
+	}
+
+	ctrl, ok := pciACSControl(config)
+	return ok && ctrl&pciACSCtrlRedirects != 0
+}
+
+// pciACSControl walks the extended capabilities for the ACS control
+// register.
+func pciACSControl(config []byte) (uint16, bool) {
+	offset := pciExtCapStart
+	for range 512 {
+		if offset < pciExtCapStart || offset+8 > len(config) {
+			return 0, false
+		}
+
+		header := binary.LittleEndian.Uint32(config[offset:])
+		if header == 0 || header == 0xffffffff {
+			return 0, false
+		}
+		if header&0xffff == pciExtCapACS {
+			return binary.LittleEndian.Uint16(config[offset+pciACSCtrlOffset:]), true
+		}
+		offset = int(header>>20) & 0xffc
+	}
+	return 0, false
+}
@@ -0,0 +1,60 @@
+// Copyright 2026 The HuaTuo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"encoding/binary"
+	"testing"
+)
+
+// pciExtCap puts an extended capability header at offset, linked to next.
+func pciExtCap(config []byte, offset int, id uint16, next int) {
+	binary.LittleEndian.PutUint32(config[offset:], uint32(id)|1<<16|uint32(next)<<20)
+}
+
+func TestPCIACSControl(t *testing.T) {
+	// AER, then ACS with request and completion redirect.
+	config := make([]byte, 4096)
+	pciExtCap(config, 0x100, 0x0001, 0x148)
+	pciExtCap(config, 0x148, pciExtCapACS, 0)
+	binary.LittleEndian.PutUint16(config[0x148+pciACSCtrlOffset:], 0x001d)
+
+	ctrl, ok := pciACSControl(config)
+	if !ok || ctrl != 0x001d {
+		t.Fatalf("pciACSControl() = %#x, %v, want 0x1d, true", ctrl, ok)
+	}
+	if ctrl&pciACSCtrlRedirects == 0 {
+		t.Errorf("control %#x does not redirect", ctrl)
+	}
+
+	// no ACS in the list.
+	config = make([]byte, 4096)
+	pciExtCap(config, 0x100, 0x0001, 0)
+	if _, ok := pciACSControl(config); ok {
+		t.Error("pciACSControl() found ACS in a list without it")
+	}
+
+	// unprivileged readers only get the 256 bytes config space.
+	if _, ok := pciACSControl(make([]byte, 256)); ok {
+		t.Error("pciACSControl() found ACS in the legacy config space")
+	}
+
+	// a loop in the list ends.
+	config = make([]byte, 4096)
+	pciExtCap(config, 0x100, 0x0001, 0x100)
+	if _, ok := pciACSControl(config); ok {
+		t.Error("pciACSControl() found ACS in a looping list")
+	}
+}
@@ -0,0 +1,199 @@
+// Copyright 2026 The HuaTuo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package gpusim simulates the GPUs behind the SML and NVML libraries, the
+// simulated libraries of the sml and nvml packages read their devices here.
+// The GPU collectors then run in CI and the dashboards are demoed on nodes
+// without hardware.
+package gpusim
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+// Behavior is how a simulated GPU behaves over time.
+type Behavior string
+
+const (
+	// BehaviorIdle is a GPU no workload uses.
+	BehaviorIdle Behavior = "idle"
+	// BehaviorBusy is a GPU with a workload whose load varies in waves.
+	BehaviorBusy Behavior = "busy"
+	// BehaviorHot is a busy GPU overheating, its clocks throttled.
+	BehaviorHot Behavior = "hot"
+	// BehaviorECC is a busy GPU whose memory errors grow, an
+	// uncorrectable one every minute.
+	BehaviorECC Behavior = "ecc"
+	// BehaviorFailed is a GPU the driver reports unavailable.
+	BehaviorFailed Behavior = "failed"
+)
+
+// loadPeriod is the period of the load waves of the busy GPUs.
+const loadPeriod = 5 * time.Minute
+
+// Config is the simulated devices.
+type Config struct {
+	// GPUs is the count of devices.
+	GPUs int
+	// Behaviors are the behaviors of the GPUs by index, repeated when
+	// there are less of them than GPUs. Empty is all busy.
+	Behaviors []Behavior
+	// NotSupported are the library symbols returning not supported, e.g.
+	// "mxSmlGetPcieInfo" or "nvmlDeviceGetPowerUsage".
+	NotSupported []string
+	// Failures are the library symbols returning an error code.
+	Failures map[string]int32
+}
+
+// Sim is the state of the simulated devices, it is safe for concurrent use.
+type Sim struct {
+	behaviors    []Behavior
+	notSupported map[string]bool
+	failures     map[string]int32
+	start        time.Time
+	now          func() time.Time
+}
+
+// New returns the simulated devices of cfg.
+func New(cfg *Config) (*Sim, error) {
+	if cfg.GPUs <= 0 {
+		return nil, fmt.Errorf("gpu simulation needs one gpu at least, got %d", cfg.GPUs)
+	}
+
+	s := &Sim{
+		behaviors:    make([]Behavior, cfg.GPUs),
+		notSupported: make(map[string]bool, len(cfg.NotSupported)),
+		failures:     make(map[string]int32, len(cfg.Failures)),
+		start:        time.Now(),
+		now:          time.Now,
+	}
+
+	for i := range s.behaviors {
+		s.behaviors[i] = BehaviorBusy
+		if len(cfg.Behaviors) == 0 {
+			continue
+		}
+
+		behavior := cfg.Behaviors[i%len(cfg.Behaviors)]
+		switch behavior {
+		case BehaviorIdle, BehaviorBusy, BehaviorHot, BehaviorECC, BehaviorFailed:
+		default:
+			return nil, fmt.Errorf("invalid gpu simulation behavior %q", behavior)
+		}
+		s.behaviors[i] = behavior
+	}
+
+	for _, symbol := range cfg.NotSupported {
+		s.notSupported[symbol] = true
+	}
+	for symbol, code := range cfg.Failures {
+		if code == 0 {
+			return nil, fmt.Errorf("gpu simulation failure of %s: code 0 is the success", symbol)
+		}
+		s.failures[symbol] = code
+	}
+	return s, nil
+}
+
+// GPUs returns the count of devices.
+func (s *Sim) GPUs() int {
+	return len(s.behaviors)
+}
+
+// Behavior returns the behavior of the GPU.
+func (s *Sim) Behavior(gpu int) Behavior {
+	return s.behaviors[gpu]
+}
+
+// Return returns the code injected into symbol: the failure code, or
+// notSupported, the not supported code of the library. Zero is no
+// injection, the symbol reads the devices.
+func (s *Sim) Return(symbol string, notSupported int32) int32 {
+	if code, ok := s.failures[symbol]; ok {
+		return code
+	}
+	if s.notSupported[symbol] {
+		return notSupported
+	}
+	return 0
+}
+
+// Reading is the state of a GPU at a time.
+type Reading struct {
+	// Utilization and MemoryUtilization are in percent.
+	Utilization       float64
+	MemoryUtilization float64
+	// MemoryUsed and Power are fractions of the memory and of the power
+	// limit of the device.
+	MemoryUsed float64
+	Power      float64
+	// Temperature is in celsius.
+	Temperature float64
+	// Throttled is the clocks throttled on overheating.
+	Throttled bool
+	// CorrectableECC and UncorrectableECC are the memory errors since the
+	// simulation started.
+	CorrectableECC   uint64
+	UncorrectableECC uint64
+	// Traffic is the bytes moved over the links of the GPU since the
+	// simulation started, it grows with the load.
+	Traffic float64
+	// Unavailable is the GPU failed.
+	Unavailable bool
+}
+
+// Reading returns the current state of the GPU.
+func (s *Sim) Reading(gpu int) Reading {
+	elapsed := s.now().Sub(s.start)
+
+	// the GPUs are out of phase, they do not peak together.
+	omega := 2 * math.Pi / loadPeriod.Seconds()
+	offset := float64(gpu)
+	phase := omega*elapsed.Seconds() + offset
+	load := 70 + 25*math.Sin(phase)
+
+	switch s.behaviors[gpu] {
+	case BehaviorIdle:
+		return Reading{MemoryUsed: 0.01, Power: 0.1, Temperature: 35}
+	case BehaviorHot:
+		return Reading{
+			Utilization:       97,
+			MemoryUtilization: 60,
+			MemoryUsed:        0.9,
+			Power:             0.98,
+			Temperature:       95,
+			Throttled:         true,
+			Traffic:           1e6 * 97 * elapsed.Seconds(),
+		}
+	case BehaviorFailed:
+		return Reading{Unavailable: true}
+	}
+
+	r := Reading{
+		Utilization:       load,
+		MemoryUtilization: load * 0.6,
+		MemoryUsed:        0.5 + 0.3*math.Sin(phase),
+		Power:             0.3 + 0.6*load/100,
+		Temperature:       45 + load*0.3,
+		// 1MB/s per percent of load, integrated over the time.
+		Traffic: 1e6 * (70*elapsed.Seconds() - 25/omega*(math.Cos(phase)-math.Cos(offset))),
+	}
+	if s.behaviors[gpu] == BehaviorECC {
+		r.CorrectableECC = uint64(elapsed / (10 * time.Second))
+		r.UncorrectableECC = uint64(elapsed / time.Minute)
+	}
+	return r
+}
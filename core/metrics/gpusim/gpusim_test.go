@@ -0,0 +1,103 @@
+// Copyright 2026 The HuaTuo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gpusim
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNew(t *testing.T) {
+	for name, cfg := range map[string]Config{
+		"no gpu":           {},
+		"unknown behavior": {GPUs: 1, Behaviors: []Behavior{"melting"}},
+		"success failure":  {GPUs: 1, Failures: map[string]int32{"nvmlInit": 0}},
+	} {
+		if _, err := New(&cfg); err == nil {
+			t.Errorf("%s: New() error = nil", name)
+		}
+	}
+
+	sim, err := New(&Config{GPUs: 3, Behaviors: []Behavior{BehaviorIdle, BehaviorHot}})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	want := []Behavior{BehaviorIdle, BehaviorHot, BehaviorIdle}
+	for gpu, behavior := range want {
+		if got := sim.Behavior(gpu); got != behavior {
+			t.Errorf("Behavior(%d) = %s, want %s", gpu, got, behavior)
+		}
+	}
+
+	sim, _ = New(&Config{GPUs: 1})
+	if got := sim.Behavior(0); got != BehaviorBusy {
+		t.Errorf("default Behavior() = %s, want busy", got)
+	}
+}
+
+func TestReturn(t *testing.T) {
+	sim, err := New(&Config{
+		GPUs:         1,
+		NotSupported: []string{"nvmlDeviceGetPowerUsage", "nvmlInit"},
+		Failures:     map[string]int32{"nvmlInit": 999},
+	})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	for symbol, want := range map[string]int32{
+		"nvmlDeviceGetPowerUsage":  3,
+		"nvmlInit":                 999,
+		"nvmlDeviceGetTemperature": 0,
+	} {
+		if got := sim.Return(symbol, 3); got != want {
+			t.Errorf("Return(%s) = %d, want %d", symbol, got, want)
+		}
+	}
+}
+
+func TestReading(t *testing.T) {
+	sim, err := New(&Config{GPUs: 5, Behaviors: []Behavior{
+		BehaviorIdle, BehaviorBusy, BehaviorHot, BehaviorECC, BehaviorFailed,
+	}})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	now := sim.start
+	sim.now = func() time.Time { return now }
+
+	if r := sim.Reading(0); r.Utilization != 0 || r.Traffic != 0 {
+		t.Errorf("idle Reading() = %+v, want no load", r)
+	}
+	if r := sim.Reading(2); !r.Throttled || r.Temperature < 90 {
+		t.Errorf("hot Reading() = %+v, want throttled", r)
+	}
+	if r := sim.Reading(4); !r.Unavailable {
+		t.Errorf("failed Reading() = %+v, want unavailable", r)
+	}
+
+	busy := sim.Reading(1)
+	if busy.Utilization < 45 || busy.Utilization > 95 {
+		t.Errorf("busy Utilization = %.1f, want in [45, 95]", busy.Utilization)
+	}
+
+	now = now.Add(90 * time.Second)
+	if r := sim.Reading(1); r.Traffic <= busy.Traffic {
+		t.Errorf("busy Traffic = %.0f after %.0f, want it growing", r.Traffic, busy.Traffic)
+	}
+	if r := sim.Reading(3); r.CorrectableECC != 9 || r.UncorrectableECC != 1 {
+		t.Errorf("ecc Reading() errors = %d, %d, want 9, 1", r.CorrectableECC, r.UncorrectableECC)
+	}
+}
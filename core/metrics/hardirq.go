@@ -0,0 +1,340 @@
+// Copyright 2026 The HuaTuo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"huatuo-bamai/internal/log"
+	"huatuo-bamai/internal/procfs"
+	"huatuo-bamai/pkg/metric"
+	"huatuo-bamai/pkg/tracing"
+)
+
+// hardirq is a device interrupt of /proc/interrupts, the numbered lines,
+// and its count per cpu id.
+type hardirq struct {
+	irq    string
+	chip   string
+	device string
+	counts map[int]uint64
+}
+
+// hardirqAffinity is of /proc/irq/<irq>, the effective affinity is the cpus
+// the interrupt is really routed to, a subset of the affinity set.
+type hardirqAffinity struct {
+	affinity          string
+	effectiveAffinity string
+	node              string
+}
+
+// hardirqRate is the interrupts per second of an irq since the last
+// collection, per cpu id.
+type hardirqRate struct {
+	*hardirq
+	total float64
+	cpus  map[int]float64
+}
+
+// HardirqTracingData is stored when a cpu handles more than EventThreshold
+// percent of the device interrupts of the host.
+type HardirqTracingData struct {
+	CPU          int     `json:"cpu"`
+	SharePercent float64 `json:"share_percent"`
+	CPURate      float64 `json:"cpu_rate"`
+	TotalRate    float64 `json:"total_rate"`
+	CPUs         int     `json:"cpus"`
+	Interval     int64   `json:"interval_ms"`
+	// IRQs are the interrupts handled the most by the cpu.
+	IRQs []*HardirqIRQ `json:"irqs"`
+}
+
+type HardirqIRQ struct {
+	IRQ               string  `json:"irq"`
+	Chip              string  `json:"chip"`
+	Device            string  `json:"device"`
+	CPURate           float64 `json:"cpu_rate"`
+	TotalRate         float64 `json:"total_rate"`
+	Affinity          string  `json:"affinity"`
+	EffectiveAffinity string  `json:"effective_affinity,omitempty"`
+	Node              string  `json:"node,omitempty"`
+}
+
+type hardirqCollector struct {
+	mutex      sync.Mutex
+	last       map[string]*hardirq
+	lastUpdate time.Time
+	lastEvent  time.Time
+}
+
+func init() {
+	tracing.RegisterEventTracing("hardirq", newHardirq)
+	tracing.RegisterSchema[HardirqTracingData]("hardirq", "hardirq", 1)
+}
+
+func newHardirq() (*tracing.EventTracingAttr, error) {
+	return &tracing.EventTracingAttr{
+		TracingData: &hardirqCollector{},
+		Flag:        tracing.FlagMetric,
+	}, nil
+}
+
+// isHardirqHwField tells whether a field after the chip is the hardware irq
+// number or the trigger type, e.g. "524288-edge" or "30 Level", rather
+// than a device name.
+func isHardirqHwField(field string) bool {
+	if _, err := strconv.ParseUint(field, 10, 64); err == nil {
+		return true
+	}
+
+	switch strings.ToLower(field) {
+	case "edge", "level":
+		return true
+	}
+	return strings.HasSuffix(field, "-edge") || strings.HasSuffix(field, "-level") ||
+		strings.HasSuffix(field, "-fasteoi")
+}
+
+// readHardirqs returns the device interrupts of /proc/interrupts.
+func readHardirqs(r io.Reader) ([]*hardirq, error) {
+	lines, err := readInterrupts(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var irqs []*hardirq
+	for _, line := range lines {
+		// NMI, LOC and the other per cpu interrupts are not numbered.
+		if _, err := strconv.Atoi(line.name); err != nil {
+			continue
+		}
+
+		irq := &hardirq{irq: line.name, counts: line.counts}
+		rest := line.desc
+		if len(rest) > 0 {
+			irq.chip, rest = rest[0], rest[1:]
+		}
+		for len(rest) > 0 && isHardirqHwField(rest[0]) {
+			rest = rest[1:]
+		}
+		irq.device = strings.Join(rest, " ")
+
+		irqs = append(irqs, irq)
+	}
+	return irqs, nil
+}
+
+// hardirqRates returns the rates of the irqs since the last collection, the
+// irqs new or with a counter going backwards are left out.
+func hardirqRates(irqs []*hardirq, last map[string]*hardirq, interval time.Duration) []*hardirqRate {
+	if interval <= 0 {
+		return nil
+	}
+
+	var rates []*hardirqRate
+	for _, irq := range irqs {
+		prev, ok := last[irq.irq]
+		if !ok {
+			continue
+		}
+
+		rate := &hardirqRate{hardirq: irq, cpus: make(map[int]float64, len(irq.counts))}
+		for cpu, count := range irq.counts {
+			before, ok := prev.counts[cpu]
+			if !ok || count < before {
+				continue
+			}
+			r := float64(count-before) / interval.Seconds()
+			rate.cpus[cpu] = r
+			rate.total += r
+		}
+		rates = append(rates, rate)
+	}
+	return rates
+}
+
+// hardirqBusiestCPU returns the cpu handling the most device interrupts,
+// its rate, the rate of all the cpus, and the cpus.
+func hardirqBusiestCPU(rates []*hardirqRate) (cpu int, cpuRate, total float64, cpus int) {
+	perCPU := make(map[int]float64)
+	for _, rate := range rates {
+		for c, r := range rate.cpus {
+			perCPU[c] += r
+			total += r
+		}
+	}
+
+	cpu = -1
+	for c, r := range perCPU {
+		if r > cpuRate || (r == cpuRate && c < cpu) {
+			cpu, cpuRate = c, r
+		}
+	}
+	return cpu, cpuRate, total, len(perCPU)
+}
+
+// shouldReport tells whether the share of the busiest cpu is saved as an
+// event: at least EventThreshold percent of the device interrupts of
+// EventMinRate per second or more, and more than twice the share of an
+// even spread, at most one per EventInterval.
+func (c *hardirqCollector) shouldReport(cpuRate, total float64, cpus int, now time.Time) bool {
+	threshold := cfg.Hardirq.EventThreshold
+	if threshold <= 0 || cpus < 2 || total <= 0 || total < float64(cfg.Hardirq.EventMinRate) {
+		return false
+	}
+
+	share := cpuRate * 100 / total
+	if share < float64(threshold) || share <= 200/float64(cpus) {
+		return false
+	}
+	if now.Sub(c.lastEvent) < time.Duration(cfg.Hardirq.EventInterval)*time.Second {
+		return false
+	}
+
+	c.lastEvent = now
+	return true
+}
+
+func readHardirqAffinity(irq string) hardirqAffinity {
+	read := func(name string) string {
+		raw, err := os.ReadFile(procfs.Path("irq", irq, name))
+		if err != nil {
+			return ""
+		}
+		return strings.TrimSpace(string(raw))
+	}
+
+	return hardirqAffinity{
+		affinity:          read("smp_affinity_list"),
+		effectiveAffinity: read("effective_affinity_list"),
+		node:              read("node"),
+	}
+}
+
+func (c *hardirqCollector) Update() ([]*metric.Data, error) {
+	f, err := os.Open(procfs.Path("interrupts"))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	irqs, err := readHardirqs(f)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	c.mutex.Lock()
+	last := make(map[string]*hardirq, len(irqs))
+	for _, irq := range irqs {
+		last[irq.irq] = irq
+	}
+	interval := now.Sub(c.lastUpdate)
+	rates := hardirqRates(irqs, c.last, interval)
+	c.last, c.lastUpdate = last, now
+
+	cpu, cpuRate, total, cpus := hardirqBusiestCPU(rates)
+	report := len(rates) > 0 && c.shouldReport(cpuRate, total, cpus, now)
+	c.mutex.Unlock()
+
+	data := make([]*metric.Data, 0, len(irqs)+len(rates))
+	for _, irq := range irqs {
+		affinity := readHardirqAffinity(irq.irq)
+		data = append(data, metric.NewGaugeData("info", 1, "the chip, the device, the affinity and the numa node of the irq",
+			map[string]string{
+				"irq":                irq.irq,
+				"chip":               irq.chip,
+				"device":             irq.device,
+				"affinity":           affinity.affinity,
+				"effective_affinity": affinity.effectiveAffinity,
+				"node":               affinity.node,
+			}))
+	}
+	data = append(data, hardirqRateData(rates)...)
+
+	if report {
+		c.save(cpu, cpuRate, total, cpus, interval, rates, now)
+	}
+	return data, nil
+}
+
+func hardirqRateData(rates []*hardirqRate) []*metric.Data {
+	perCPU := make(map[int]float64)
+	data := make([]*metric.Data, 0, len(rates))
+	for _, rate := range rates {
+		data = append(data, metric.NewGaugeData("rate", rate.total, "interrupts per second of the irq",
+			map[string]string{"irq": rate.irq, "chip": rate.chip, "device": rate.device}))
+		for cpu, r := range rate.cpus {
+			perCPU[cpu] += r
+		}
+	}
+
+	for cpu, r := range perCPU {
+		data = append(data, metric.NewGaugeData("cpu_rate", r, "device interrupts per second handled by the cpu",
+			map[string]string{"cpu": strconv.Itoa(cpu)}))
+	}
+	return data
+}
+
+func (c *hardirqCollector) save(cpu int, cpuRate, total float64, cpus int, interval time.Duration, rates []*hardirqRate, now time.Time) {
+	top := make([]*hardirqRate, 0, len(rates))
+	for _, rate := range rates {
+		if rate.cpus[cpu] > 0 {
+			top = append(top, rate)
+		}
+	}
+	sort.Slice(top, func(i, j int) bool {
+		return top[i].cpus[cpu] > top[j].cpus[cpu]
+	})
+	if len(top) > cfg.Hardirq.TopIRQs {
+		top = top[:cfg.Hardirq.TopIRQs]
+	}
+
+	data := &HardirqTracingData{
+		CPU:          cpu,
+		SharePercent: cpuRate * 100 / total,
+		CPURate:      cpuRate,
+		TotalRate:    total,
+		CPUs:         cpus,
+		Interval:     interval.Milliseconds(),
+	}
+	for _, rate := range top {
+		affinity := readHardirqAffinity(rate.irq)
+		data.IRQs = append(data.IRQs, &HardirqIRQ{
+			IRQ:               rate.irq,
+			Chip:              rate.chip,
+			Device:            rate.device,
+			CPURate:           rate.cpus[cpu],
+			TotalRate:         rate.total,
+			Affinity:          affinity.affinity,
+			EffectiveAffinity: affinity.effectiveAffinity,
+			Node:              affinity.node,
+		})
+	}
+
+	if err := tracing.Save(&tracing.WriteRequest{
+		TracerName: "hardirq",
+		TracerTime: now,
+		TracerData: data,
+	}); err != nil {
+		log.Warnf("failed to save tracing data: %v", err)
+	}
+}
@@ -0,0 +1,123 @@
+// Copyright 2026 The HuaTuo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+const testInterrupts = `           CPU0       CPU1       CPU3
+  0:         36          0          0   IO-APIC   2-edge      timer
+  8:          0          0          0   IO-APIC   8-edge      rtc0
+ 24:       1000         10          0   IR-PCI-MSI 524288-edge      nvme0q0
+ 45:       5000        100        200   IR-PCI-MSI 1572864-edge      eth0-TxRx-0
+ 11:          4          1          2   GICv3  30 Level     arch_timer
+NMI:          0          0          0   Non-maskable interrupts
+LOC:     123456     234567     345678   Local timer interrupts
+ERR:          0
+`
+
+func TestReadHardirqs(t *testing.T) {
+	irqs, err := readHardirqs(strings.NewReader(testInterrupts))
+	if err != nil {
+		t.Fatalf("readHardirqs() error = %v", err)
+	}
+	if len(irqs) != 5 {
+		t.Fatalf("readHardirqs() returned %d irqs, want 5", len(irqs))
+	}
+
+	for i, want := range []struct{ irq, chip, device string }{
+		{"0", "IO-APIC", "timer"},
+		{"8", "IO-APIC", "rtc0"},
+		{"24", "IR-PCI-MSI", "nvme0q0"},
+		{"45", "IR-PCI-MSI", "eth0-TxRx-0"},
+		{"11", "GICv3", "arch_timer"},
+	} {
+		if irqs[i].irq != want.irq || irqs[i].chip != want.chip || irqs[i].device != want.device {
+			t.Errorf("readHardirqs()[%d] = %+v, want %+v", i, irqs[i], want)
+		}
+	}
+	if irqs[3].counts[3] != 200 {
+		t.Errorf("counts of irq 45 on cpu 3 = %d, want 200", irqs[3].counts[3])
+	}
+
+	if _, err := readHardirqs(strings.NewReader("")); err == nil {
+		t.Error("readHardirqs() of an empty file succeeded")
+	}
+}
+
+func TestHardirqRates(t *testing.T) {
+	last := map[string]*hardirq{
+		"24": {irq: "24", counts: map[int]uint64{0: 1000, 1: 10}},
+		"45": {irq: "45", counts: map[int]uint64{0: 5000, 1: 100}},
+	}
+	irqs := []*hardirq{
+		{irq: "24", counts: map[int]uint64{0: 1100, 1: 20}},
+		{irq: "45", counts: map[int]uint64{0: 95000, 1: 1100}},
+		// new irq.
+		{irq: "46", counts: map[int]uint64{0: 100}},
+	}
+
+	rates := hardirqRates(irqs, last, 10*time.Second)
+	if len(rates) != 2 {
+		t.Fatalf("hardirqRates() returned %d rates, want 2", len(rates))
+	}
+	if rates[0].total != 11 || rates[1].cpus[0] != 9000 || rates[1].total != 9100 {
+		t.Errorf("hardirqRates() = %+v, %+v", rates[0], rates[1])
+	}
+
+	cpu, cpuRate, total, cpus := hardirqBusiestCPU(rates)
+	if cpu != 0 || cpuRate != 9010 || total != 9111 || cpus != 2 {
+		t.Errorf("hardirqBusiestCPU() = %d, %v, %v, %d", cpu, cpuRate, total, cpus)
+	}
+
+	if rates := hardirqRates(irqs, nil, 0); rates != nil {
+		t.Errorf("hardirqRates() of the first collection = %v, want nil", rates)
+	}
+}
+
+func TestHardirqShouldReport(t *testing.T) {
+	orig := cfg
+	t.Cleanup(func() { cfg = orig })
+	cfg = &Config{}
+	cfg.Hardirq.EventThreshold = 50
+	cfg.Hardirq.EventMinRate = 5000
+	cfg.Hardirq.EventInterval = 600
+
+	var c hardirqCollector
+	now := time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC)
+
+	if c.shouldReport(3000, 4000, 8, now) {
+		t.Error("shouldReport() = true below the minimum rate")
+	}
+	if c.shouldReport(4000, 10000, 8, now) {
+		t.Error("shouldReport() = true below the threshold")
+	}
+	// 60% of 2 cpus is not more than twice an even spread.
+	if c.shouldReport(6000, 10000, 2, now) {
+		t.Error("shouldReport() = true for an even spread")
+	}
+	if !c.shouldReport(9000, 10000, 8, now) {
+		t.Error("shouldReport() = false for a cpu handling 90%")
+	}
+	if c.shouldReport(9000, 10000, 8, now.Add(time.Minute)) {
+		t.Error("shouldReport() = true within the event interval")
+	}
+	if !c.shouldReport(9000, 10000, 8, now.Add(11*time.Minute)) {
+		t.Error("shouldReport() = false after the event interval")
+	}
+}
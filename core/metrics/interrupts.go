@@ -0,0 +1,75 @@
+// Copyright 2026 The HuaTuo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// interrupt is a line of /proc/interrupts: its name without the colon, the
+// irq number of a device interrupt or e.g. "LOC", the count per cpu id,
+// and the fields after the counts.
+type interrupt struct {
+	name   string
+	counts map[int]uint64
+	desc   []string
+}
+
+// readInterrupts returns the lines of /proc/interrupts with a count per
+// cpu, "ERR" and "MIS" have a single one. Columns follow the header, since
+// offline cpus are left out.
+func readInterrupts(r io.Reader) ([]*interrupt, error) {
+	scanner := bufio.NewScanner(r)
+	if !scanner.Scan() {
+		return nil, fmt.Errorf("interrupts: empty")
+	}
+
+	var cpus []int
+	for _, name := range strings.Fields(scanner.Text()) {
+		cpu, err := strconv.Atoi(strings.TrimPrefix(name, "CPU"))
+		if err != nil {
+			return nil, fmt.Errorf("interrupts: invalid header %q", name)
+		}
+		cpus = append(cpus, cpu)
+	}
+
+	var irqs []*interrupt
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < len(cpus)+1 {
+			continue
+		}
+
+		irq := &interrupt{
+			name:   strings.TrimSuffix(fields[0], ":"),
+			counts: make(map[int]uint64, len(cpus)),
+			desc:   fields[len(cpus)+1:],
+		}
+		for i, cpu := range cpus {
+			v, err := strconv.ParseUint(fields[i+1], 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("interrupts: irq %s: %w", irq.name, err)
+			}
+			irq.counts[cpu] = v
+		}
+		irqs = append(irqs, irq)
+	}
+
+	return irqs, scanner.Err()
+}
@@ -0,0 +1,58 @@
+// Copyright 2026 The HuaTuo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestReadInterrupts(t *testing.T) {
+	irqs, err := readInterrupts(strings.NewReader(testInterrupts))
+	if err != nil {
+		t.Fatalf("readInterrupts() error = %v", err)
+	}
+	// ERR has a single count.
+	if len(irqs) != 7 {
+		t.Fatalf("readInterrupts() returned %d lines, want 7", len(irqs))
+	}
+
+	loc := irqs[6]
+	if loc.name != "LOC" || loc.counts[3] != 345678 || strings.Join(loc.desc, " ") != "Local timer interrupts" {
+		t.Errorf("readInterrupts()[6] = %+v", loc)
+	}
+
+	if _, err := readInterrupts(strings.NewReader("CPU0 CPU1\n 0: 1 x IO-APIC\n")); err == nil {
+		t.Error("readInterrupts() of an invalid count succeeded")
+	}
+}
+
+func TestTimerInterrupts(t *testing.T) {
+	counts, err := timerInterrupts(strings.NewReader(testInterrupts))
+	if err != nil {
+		t.Fatalf("timerInterrupts() error = %v", err)
+	}
+
+	// LOC and arch_timer.
+	want := map[int]uint64{0: 123460, 1: 234568, 3: 345680}
+	if len(counts) != len(want) {
+		t.Fatalf("timerInterrupts() = %v, want %v", counts, want)
+	}
+	for cpu, v := range want {
+		if counts[cpu] != v {
+			t.Errorf("timerInterrupts()[%d] = %d, want %d", cpu, counts[cpu], v)
+		}
+	}
+}
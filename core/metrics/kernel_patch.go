@@ -0,0 +1,283 @@
+// Copyright 2026 The HuaTuo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"bufio"
+	"bytes"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"huatuo-bamai/internal/log"
+	"huatuo-bamai/internal/procfs"
+	"huatuo-bamai/internal/procfs/sysfs"
+	"huatuo-bamai/pkg/metric"
+	"huatuo-bamai/pkg/tracing"
+)
+
+func init() {
+	tracing.RegisterEventTracing("kernel_patch", newKernelPatch)
+}
+
+const (
+	kernelPatchLivepatch = "livepatch"
+	kernelPatchKpatch    = "kpatch"
+
+	// TAINT_LIVEPATCH of /proc/sys/kernel/tainted.
+	kernelTaintLivepatch = 1 << 15
+)
+
+// livePatch is a patch module loaded in the running kernel.
+type livePatch struct {
+	Module     string
+	Type       string
+	Enabled    string
+	Transition string
+}
+
+type kernelPatch struct {
+	bootKernel  string
+	refreshTime time.Time
+}
+
+func newKernelPatch() (*tracing.EventTracingAttr, error) {
+	return &tracing.EventTracingAttr{
+		TracingData: &kernelPatch{},
+		Flag:        tracing.FlagMetric,
+	}, nil
+}
+
+func (k *kernelPatch) Update() ([]*metric.Data, error) {
+	var data []*metric.Data
+
+	for _, p := range livePatches() {
+		data = append(data, metric.NewGaugeData("livepatch_info", 1, "Live patch module loaded in the running kernel.", map[string]string{
+			"module":     p.Module,
+			"type":       p.Type,
+			"enabled":    p.Enabled,
+			"transition": p.Transition,
+		}))
+	}
+
+	if tainted, err := readKernelTaint(); err == nil {
+		value := 0.0
+		if tainted&kernelTaintLivepatch != 0 {
+			value = 1
+		}
+		data = append(data, metric.NewGaugeData("livepatch_tainted", value, "Whether the kernel was ever live patched since boot.", nil))
+	}
+
+	running := readTrimmed(procfs.Path("sys/kernel/osrelease"))
+
+	// the default only changes with a kernel package install or grub tooling.
+	interval := time.Duration(cfg.KernelPatch.Interval) * time.Second
+	if k.refreshTime.IsZero() || time.Since(k.refreshTime) >= interval {
+		boot, err := defaultBootKernel(procfs.Path("1/root/boot"))
+		if err != nil {
+			log.Debugf("kernel patch default boot kernel: %v", err)
+		}
+		k.bootKernel = boot
+		k.refreshTime = time.Now()
+	}
+
+	if running != "" && k.bootKernel != "" {
+		value := 0.0
+		if running != k.bootKernel {
+			value = 1
+		}
+		data = append(data, metric.NewGaugeData("boot_kernel_mismatch", value, "Whether the running kernel differs from the default boot kernel, 1 means differs.", map[string]string{
+			"running": running,
+			"default": k.bootKernel,
+		}))
+	}
+
+	return data, nil
+}
+
+// livePatches lists the patches of the kernel livepatch framework and of
+// the legacy kpatch core module.
+func livePatches() []livePatch {
+	var patches []livePatch
+
+	for _, source := range []struct {
+		typ string
+		dir string
+	}{
+		{kernelPatchLivepatch, sysfs.Path("kernel/livepatch")},
+		{kernelPatchKpatch, sysfs.Path("kernel/kpatch/patches")},
+	} {
+		entries, err := os.ReadDir(source.dir)
+		if err != nil {
+			continue
+		}
+
+		for _, entry := range entries {
+			dir := filepath.Join(source.dir, entry.Name())
+			patches = append(patches, livePatch{
+				Module:     entry.Name(),
+				Type:       source.typ,
+				Enabled:    readTrimmed(filepath.Join(dir, "enabled")),
+				Transition: readTrimmed(filepath.Join(dir, "transition")),
+			})
+		}
+	}
+
+	return patches
+}
+
+func readKernelTaint() (uint64, error) {
+	raw, err := os.ReadFile(procfs.Path("sys/kernel/tainted"))
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseUint(strings.TrimSpace(string(raw)), 10, 64)
+}
+
+// defaultBootKernel returns the release of the kernel grub boots by
+// default. The saved_entry of grubenv names a BLS entry, or a menu entry
+// containing the release. Without one, grub boots the first menu entry,
+// which grub-mkconfig makes the newest installed kernel.
+func defaultBootKernel(bootDir string) (string, error) {
+	releases, err := installedKernels(bootDir)
+	if err != nil {
+		return "", err
+	}
+	if len(releases) == 0 {
+		return "", nil
+	}
+
+	entry := grubSavedEntry(bootDir)
+	if entry != "" {
+		if release := blsEntryKernel(filepath.Join(bootDir, "loader/entries", entry+".conf")); release != "" {
+			return release, nil
+		}
+
+		// the longest one, 5.10.0-1 must not match an entry of 5.10.0-10.
+		found := ""
+		for _, release := range releases {
+			if strings.Contains(entry, release) && len(release) > len(found) {
+				found = release
+			}
+		}
+		if found != "" {
+			return found, nil
+		}
+
+		// a menu index other than the first one cannot be resolved.
+		if entry != "0" {
+			return "", nil
+		}
+	}
+
+	slices.SortFunc(releases, compareKernelRelease)
+	return releases[len(releases)-1], nil
+}
+
+// installedKernels returns the releases of the /boot/vmlinuz-<release>.
+func installedKernels(bootDir string) ([]string, error) {
+	images, err := filepath.Glob(filepath.Join(bootDir, "vmlinuz-*"))
+	if err != nil {
+		return nil, err
+	}
+
+	var releases []string
+	for _, image := range images {
+		release := strings.TrimPrefix(filepath.Base(image), "vmlinuz-")
+		// rescue images of dracut.
+		if strings.HasPrefix(release, "0-rescue-") {
+			continue
+		}
+		releases = append(releases, release)
+	}
+	return releases, nil
+}
+
+func grubSavedEntry(bootDir string) string {
+	for _, env := range []string{"grub2/grubenv", "grub/grubenv"} {
+		raw, err := os.ReadFile(filepath.Join(bootDir, env))
+		if err != nil {
+			continue
+		}
+
+		scanner := bufio.NewScanner(bytes.NewReader(raw))
+		for scanner.Scan() {
+			if value, ok := strings.CutPrefix(scanner.Text(), "saved_entry="); ok {
+				return strings.TrimSpace(value)
+			}
+		}
+		return ""
+	}
+	return ""
+}
+
+// blsEntryKernel returns the release of the "linux /vmlinuz-<release>" line
+// of a boot loader specification entry.
+func blsEntryKernel(path string) string {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(raw))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 || fields[0] != "linux" {
+			continue
+		}
+		if _, release, ok := strings.Cut(filepath.Base(fields[1]), "vmlinuz-"); ok {
+			return release
+		}
+	}
+	return ""
+}
+
+// compareKernelRelease compares the releases as version numbers, e.g.
+// 5.15.0-105 is newer than 5.15.0-99.
+func compareKernelRelease(a, b string) int {
+	for a != "" && b != "" {
+		var ca, cb string
+		ca, a = nextReleaseChunk(a)
+		cb, b = nextReleaseChunk(b)
+
+		na, errA := strconv.ParseUint(ca, 10, 64)
+		nb, errB := strconv.ParseUint(cb, 10, 64)
+		switch {
+		case errA == nil && errB == nil:
+			if na != nb {
+				if na < nb {
+					return -1
+				}
+				return 1
+			}
+		case ca != cb:
+			return strings.Compare(ca, cb)
+		}
+	}
+	return strings.Compare(a, b)
+}
+
+// nextReleaseChunk splits the leading run of digits or of other runes.
+func nextReleaseChunk(s string) (chunk, rest string) {
+	isDigit := s[0] >= '0' && s[0] <= '9'
+	i := 1
+	for i < len(s) && (s[i] >= '0' && s[i] <= '9') == isDigit {
+		i++
+	}
+	return s[:i], s[i:]
+}
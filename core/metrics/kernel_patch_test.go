@@ -0,0 +1,116 @@
+// Copyright 2026 The HuaTuo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"huatuo-bamai/internal/procfs"
+)
+
+func TestDefaultBootKernel(t *testing.T) {
+	kernels := []string{"5.15.0-99-generic", "5.15.0-105-generic", "5.15.0-10-generic", "0-rescue-abc"}
+
+	cases := []struct {
+		name  string
+		files map[string]string
+		want  string
+	}{
+		{
+			name: "bls entry",
+			files: map[string]string{
+				"grub2/grubenv": "# GRUB Environment Block\nsaved_entry=abc-5.15.0-10-generic\n",
+				"loader/entries/abc-5.15.0-10-generic.conf": "title Linux\nlinux /vmlinuz-5.15.0-10-generic\n",
+			},
+			want: "5.15.0-10-generic",
+		},
+		{
+			name:  "menu entry title",
+			files: map[string]string{"grub/grubenv": "saved_entry=gnulinux-advanced-x>gnulinux-5.15.0-105-generic-advanced-x\n"},
+			want:  "5.15.0-105-generic",
+		},
+		{
+			name:  "first menu entry",
+			files: map[string]string{"grub/grubenv": "saved_entry=0\n"},
+			want:  "5.15.0-105-generic",
+		},
+		{
+			name: "no grubenv",
+			want: "5.15.0-105-generic",
+		},
+		{
+			name:  "other menu index",
+			files: map[string]string{"grub/grubenv": "saved_entry=2\n"},
+			want:  "",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			boot := t.TempDir()
+			for _, kernel := range kernels {
+				writeTestFile(t, filepath.Join(boot, "vmlinuz-"+kernel), "")
+			}
+			for path, content := range tc.files {
+				writeTestFile(t, filepath.Join(boot, path), content)
+			}
+
+			got, err := defaultBootKernel(boot)
+			if err != nil {
+				t.Fatalf("defaultBootKernel() error = %v", err)
+			}
+			if got != tc.want {
+				t.Errorf("defaultBootKernel() = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestCompareKernelRelease(t *testing.T) {
+	cases := []struct {
+		a, b string
+		want int
+	}{
+		{"5.15.0-105", "5.15.0-99", 1},
+		{"4.19.90", "5.4.0", -1},
+		{"5.10.0-1.el8", "5.10.0-1.el8", 0},
+		{"4.18.0-513.el8", "4.18.0-513.el7", 1},
+	}
+	for _, tc := range cases {
+		if got := compareKernelRelease(tc.a, tc.b); got != tc.want {
+			t.Errorf("compareKernelRelease(%q, %q) = %d, want %d", tc.a, tc.b, got, tc.want)
+		}
+	}
+}
+
+func TestLivePatches(t *testing.T) {
+	root := t.TempDir()
+	procfs.RootPrefix(root)
+	t.Cleanup(func() { procfs.RootPrefix("/") })
+
+	writeTestFile(t, filepath.Join(root, "sys/kernel/livepatch/livepatch_cve_2026/enabled"), "1\n")
+	writeTestFile(t, filepath.Join(root, "sys/kernel/livepatch/livepatch_cve_2026/transition"), "0\n")
+	writeTestFile(t, filepath.Join(root, "sys/kernel/kpatch/patches/kpatch_fix/enabled"), "1\n")
+
+	want := []livePatch{
+		{Module: "livepatch_cve_2026", Type: kernelPatchLivepatch, Enabled: "1", Transition: "0"},
+		{Module: "kpatch_fix", Type: kernelPatchKpatch, Enabled: "1"},
+	}
+	if got := livePatches(); !reflect.DeepEqual(got, want) {
+		t.Errorf("livePatches() = %+v, want %+v", got, want)
+	}
+}
@@ -57,13 +57,13 @@ func (c *memEventsCollector) Update() ([]*metric.Data, error) {
 		return nil, fmt.Errorf("get normal container: %w", err)
 	}
 
-	metrics := []*metric.Data{}
-	for _, container := range containers {
+	metrics, err := scanContainers("memory_events", containers, func(container *pod.Container) ([]*metric.Data, error) {
 		raw, err := c.cgroup.MemoryEventRaw(container.CgroupPath)
 		if err != nil {
 			return nil, err
 		}
 
+		var metrics []*metric.Data
 		for key, value := range raw {
 			if !f.Match(key) {
 				continue
@@ -72,6 +72,10 @@ func (c *memEventsCollector) Update() ([]*metric.Data, error) {
 			metrics = append(metrics,
 				metric.NewContainerGaugeData(container, key, float64(value), fmt.Sprintf("memory events %s", key), nil))
 		}
+		return metrics, nil
+	})
+	if err != nil {
+		return nil, err
 	}
 
 	return metrics, nil
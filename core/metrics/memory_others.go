@@ -104,9 +104,8 @@ func (c *memOthersCollector) Update() ([]*metric.Data, error) {
 		return nil, fmt.Errorf("Can't get normal container: %w", err)
 	}
 
-	metrics := []*metric.Data{}
-
-	for _, container := range containers {
+	return scanContainers("memory_others", containers, func(container *pod.Container) ([]*metric.Data, error) {
+		var metrics []*metric.Data
 		for _, spec := range didiMemcgMetricSpecs {
 			value, err := parseValueWithKey(container.CgroupPath, spec.path, spec.key)
 			if err != nil {
@@ -117,7 +116,6 @@ func (c *memOthersCollector) Update() ([]*metric.Data, error) {
 			metrics = append(metrics,
 				metric.NewContainerGaugeData(container, spec.name, float64(value), fmt.Sprintf("memory cgroup %s", spec.name), nil))
 		}
-	}
-
-	return metrics, nil
+		return metrics, nil
+	})
 }
@@ -0,0 +1,150 @@
+// Copyright 2026 The HuaTuo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"time"
+
+	"huatuo-bamai/internal/cgroups"
+	"huatuo-bamai/internal/log"
+	"huatuo-bamai/internal/pod"
+	"huatuo-bamai/pkg/metric"
+	"huatuo-bamai/pkg/tracing"
+)
+
+// memoryUnlimited is above the limits of the containers, cgroup v1 reports
+// no limit as the page aligned LONG_MAX and v2 as "max".
+const memoryUnlimited = 1 << 62
+
+// memoryUsage is the memory of a container, normalized from the memory.stat
+// of cgroup v1 (total_* keys, hierarchical) and v2.
+type memoryUsage struct {
+	usage      uint64
+	limit      uint64
+	workingSet uint64
+	anon       uint64
+	file       uint64
+	// slab, pgscan and pgsteal are of cgroup v2 only.
+	slab            uint64
+	pgscan, pgsteal uint64
+	hasSlab         bool
+	hasReclaim      bool
+}
+
+type memoryUsageCollector struct {
+	cgroup cgroups.Cgroup
+	// counters are pgscan and pgsteal, for their rates.
+	counters *cgroupCounters
+}
+
+func init() {
+	tracing.RegisterEventTracing("memory_usage", newMemoryUsage)
+}
+
+func newMemoryUsage() (*tracing.EventTracingAttr, error) {
+	cgroup, err := cgroups.NewManager()
+	if err != nil {
+		return nil, err
+	}
+
+	return &tracing.EventTracingAttr{
+		TracingData: &memoryUsageCollector{
+			cgroup:   cgroup,
+			counters: newCgroupCounters(),
+		},
+		Flag: tracing.FlagMetric,
+	}, nil
+}
+
+// readMemoryUsage normalizes memory.stat. The working set is the usage but
+// the inactive file pages, the first to be reclaimed, as kubelet evicts on.
+func readMemoryUsage(raw map[string]uint64, usage, limit uint64) *memoryUsage {
+	m := &memoryUsage{usage: usage, limit: limit}
+
+	inactiveFile := raw["inactive_file"]
+	if _, ok := raw["total_rss"]; ok {
+		m.anon, m.file = raw["total_rss"], raw["total_cache"]
+		inactiveFile = raw["total_inactive_file"]
+	} else {
+		m.anon, m.file = raw["anon"], raw["file"]
+		m.slab, m.hasSlab = raw["slab"]
+		m.pgscan, m.hasReclaim = raw["pgscan"]
+		m.pgsteal = raw["pgsteal"]
+	}
+
+	if usage > inactiveFile {
+		m.workingSet = usage - inactiveFile
+	}
+	return m
+}
+
+// memoryReclaimRates returns the pages scanned and reclaimed per second of
+// the delta of pgscan and pgsteal.
+func memoryReclaimRates(delta *cgroupCounterDelta) (pgscan, pgsteal float64) {
+	if delta == nil {
+		return 0, 0
+	}
+
+	elapsed := delta.interval.Seconds()
+	return float64(delta.values[0]) / elapsed, float64(delta.values[1]) / elapsed
+}
+
+func (c *memoryUsageCollector) Update() ([]*metric.Data, error) {
+	containers, err := pod.ContainersByType(pod.ContainerTypeNormal | pod.ContainerTypeSidecar)
+	if err != nil {
+		return nil, err
+	}
+	c.counters.retain(containers)
+
+	return scanContainers("memory_usage", containers, func(container *pod.Container) ([]*metric.Data, error) {
+		usage, err := c.cgroup.MemoryUsage(container.CgroupPath)
+		if err != nil {
+			log.Infof("failed to get memory usage of %s, %v", container, err)
+			return nil, nil
+		}
+
+		raw, err := c.cgroup.MemoryStatRaw(container.CgroupPath)
+		if err != nil {
+			log.Infof("failed to get memory stat of %s, %v", container, err)
+			return nil, nil
+		}
+
+		m := readMemoryUsage(raw, usage.Usage, usage.MaxLimited)
+		data := []*metric.Data{
+			metric.NewContainerGaugeData(container, "usage_bytes", float64(m.usage), "memory used, the page cache included", nil),
+			metric.NewContainerGaugeData(container, "working_set_bytes", float64(m.workingSet), "memory used but the inactive file pages", nil),
+			metric.NewContainerGaugeData(container, "anon_bytes", float64(m.anon), "anonymous memory", nil),
+			metric.NewContainerGaugeData(container, "file_bytes", float64(m.file), "page cache memory", nil),
+		}
+		if m.limit < memoryUnlimited {
+			data = append(data, metric.NewContainerGaugeData(container, "limit_bytes", float64(m.limit), "memory limit", nil))
+		}
+		if m.hasSlab {
+			data = append(data, metric.NewContainerGaugeData(container, "slab_bytes", float64(m.slab), "kernel slab memory", nil))
+		}
+		if !m.hasReclaim {
+			return data, nil
+		}
+
+		pgscanRate, pgstealRate := memoryReclaimRates(c.counters.delta(container.CgroupPath, time.Now(), m.pgscan, m.pgsteal))
+
+		return append(data,
+			metric.NewContainerCounterData(container, "pgscan_total", float64(m.pgscan), "pages scanned by the reclaim", nil),
+			metric.NewContainerCounterData(container, "pgsteal_total", float64(m.pgsteal), "pages reclaimed", nil),
+			metric.NewContainerGaugeData(container, "pgscan_rate", pgscanRate, "pages scanned by the reclaim per second since the last collection", nil),
+			metric.NewContainerGaugeData(container, "pgsteal_rate", pgstealRate, "pages reclaimed per second since the last collection", nil),
+		), nil
+	})
+}
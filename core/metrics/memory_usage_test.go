@@ -0,0 +1,67 @@
+// Copyright 2026 The HuaTuo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestReadMemoryUsage(t *testing.T) {
+	// cgroup v1, the total_* keys include the children.
+	v1 := readMemoryUsage(map[string]uint64{
+		"rss":                 1,
+		"total_rss":           600,
+		"total_cache":         400,
+		"inactive_file":       1,
+		"total_inactive_file": 300,
+	}, 1000, 9223372036854771712)
+	if v1.anon != 600 || v1.file != 400 || v1.workingSet != 700 || v1.hasSlab || v1.hasReclaim {
+		t.Errorf("cgroup v1 = %+v", v1)
+	}
+	if v1.limit < memoryUnlimited {
+		t.Errorf("cgroup v1 unlimited limit = %d", v1.limit)
+	}
+
+	v2 := readMemoryUsage(map[string]uint64{
+		"anon":          500,
+		"file":          450,
+		"slab":          50,
+		"inactive_file": 200,
+		"pgscan":        30,
+		"pgsteal":       20,
+	}, 1000, 2048)
+	if v2.anon != 500 || v2.file != 450 || v2.workingSet != 800 || v2.limit != 2048 ||
+		!v2.hasSlab || v2.slab != 50 || !v2.hasReclaim || v2.pgscan != 30 || v2.pgsteal != 20 {
+		t.Errorf("cgroup v2 = %+v", v2)
+	}
+
+	// the inactive file pages above the usage, read at another time.
+	if m := readMemoryUsage(map[string]uint64{"inactive_file": 2000}, 1000, math.MaxUint64); m.workingSet != 0 {
+		t.Errorf("working set = %d, want 0", m.workingSet)
+	}
+}
+
+func TestMemoryReclaimRates(t *testing.T) {
+	if pgscan, pgsteal := memoryReclaimRates(nil); pgscan != 0 || pgsteal != 0 {
+		t.Errorf("first rates = %v, %v, want 0", pgscan, pgsteal)
+	}
+
+	pgscan, pgsteal := memoryReclaimRates(&cgroupCounterDelta{values: []uint64{200, 100}, interval: 10 * time.Second})
+	if pgscan != 20 || pgsteal != 10 {
+		t.Errorf("rates = %v, %v, want 20, 10", pgscan, pgsteal)
+	}
+}
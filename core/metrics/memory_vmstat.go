@@ -75,14 +75,14 @@ func (c *memoryVmStat) containerVmstat() ([]*metric.Data, error) {
 		return nil, err
 	}
 
-	var metrics []*metric.Data
-	for _, container := range containers {
+	return scanContainers("memory_vmstat", containers, func(container *pod.Container) ([]*metric.Data, error) {
 		raw, err := c.cgroup.MemoryStatRaw(container.CgroupPath)
 		if err != nil {
 			log.Infof("parse %s memory.stat %v", container.CgroupPath, err)
-			continue
+			return nil, nil
 		}
 
+		var metrics []*metric.Data
 		for m, v := range raw {
 			if !f.Match(m) {
 				log.Debugf("Ignoring the cgroup memory.stat: %s", m)
@@ -91,9 +91,8 @@ func (c *memoryVmStat) containerVmstat() ([]*metric.Data, error) {
 
 			metrics = append(metrics, metric.NewContainerGaugeData(container, m, float64(v), fmt.Sprintf("cgroup memory.stat %s", m), nil))
 		}
-	}
-
-	return metrics, nil
+		return metrics, nil
+	})
 }
 
 func (c *memoryVmStat) hostVmstat() ([]*metric.Data, error) {
@@ -16,6 +16,7 @@
 package sml
 
 import (
+	"errors"
 	"runtime"
 	"sync"
 
@@ -45,13 +46,18 @@ type library struct {
 var libsml = newLibrary()
 
 func newLibrary() *library {
-	path := defaultSmlLibraryPath()
 	return &library{
-		dl: dl.New(path, purego.RTLD_NOW|purego.RTLD_GLOBAL),
+		dl: newDynamicLibrary(DefaultLibraryPath()),
 	}
 }
 
-func defaultSmlLibraryPath() string {
+func newDynamicLibrary(path string) dynamicLibrary {
+	return dl.New(path, purego.RTLD_NOW|purego.RTLD_GLOBAL)
+}
+
+// DefaultLibraryPath returns the SML library path of the driver default
+// install.
+func DefaultLibraryPath() string {
 	switch runtime.GOOS {
 	case "linux":
 		return "/opt/mxdriver/lib/libmxsml.so"
@@ -60,6 +66,24 @@ func defaultSmlLibraryPath() string {
 	}
 }
 
+// SetLibraryPath sets the path of the SML library loaded by Init, it fails
+// while the library is loaded.
+func SetLibraryPath(path string) error {
+	return libsml.setPath(path)
+}
+
+func (l *library) setPath(path string) error {
+	l.Lock()
+	defer l.Unlock()
+
+	if l.refcount > 0 {
+		return errors.New("sml library is already loaded")
+	}
+
+	l.dl = newDynamicLibrary(path)
+	return nil
+}
+
 // load initializes the shared library and registers all required symbols.
 // Multiple calls are reference-counted and idempotent.
 func (l *library) load() (rerr error) {
@@ -75,8 +99,13 @@ func (l *library) load() (rerr error) {
 		return err
 	}
 
-	// Register all symbols after successful loading.
-	l.registerSmlLibSymbols(l.dl.Handle())
+	// Register all symbols after successful loading, a simulated library
+	// provides them itself.
+	if sim, ok := l.dl.(*simulatedLibrary); ok {
+		sim.registerSymbols()
+	} else {
+		l.registerSmlLibSymbols(l.dl.Handle())
+	}
 
 	return nil
 }
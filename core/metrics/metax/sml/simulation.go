@@ -0,0 +1,292 @@
+// Copyright 2026 The HuaTuo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sml
+
+import (
+	"errors"
+	"fmt"
+	"unsafe"
+
+	"huatuo-bamai/core/metrics/gpusim"
+	"huatuo-bamai/core/metrics/metax/sml/device"
+	"huatuo-bamai/core/metrics/metax/sml/gpu"
+)
+
+// the simulated devices, sized like a MetaX C500.
+const (
+	simDeviceName   = "MXC500"
+	simVramTotalKB  = 64 * 1024 * 1024
+	simBoardWays    = 2
+	simBoardVoltage = 12000 // mV
+	simPowerLimitMW = 350000
+	simPcieSpeed    = 32 // GT/s
+	simPcieWidth    = 16
+)
+
+// simulatedLibrary stands in for libmxsml.so, its symbols read the devices
+// of a gpusim.Sim.
+type simulatedLibrary struct {
+	sim *gpusim.Sim
+}
+
+func (*simulatedLibrary) Open() error     { return nil }
+func (*simulatedLibrary) Close() error    { return nil }
+func (*simulatedLibrary) Handle() uintptr { return 0 }
+
+// Simulate replaces the SML library by the simulated devices of sim, before
+// Init is called.
+func Simulate(sim *gpusim.Sim) error {
+	libsml.Lock()
+	defer libsml.Unlock()
+
+	if libsml.refcount > 0 {
+		return errors.New("sml library is already loaded")
+	}
+
+	libsml.dl = &simulatedLibrary{sim: sim}
+	return nil
+}
+
+// writeCString copies s NUL-terminated into the buffer of size bytes at dst.
+func writeCString(dst *byte, size uint32, s string) {
+	if size == 0 {
+		return
+	}
+	buf := unsafe.Slice(dst, size)
+	n := copy(buf[:size-1], s)
+	buf[n] = 0
+}
+
+// registerSymbols sets the symbols of the library to the simulated ones.
+func (l *simulatedLibrary) registerSymbols() {
+	sim := l.sim
+
+	ret := func(symbol string) Return {
+		return Return(sim.Return(symbol, int32(ErrorNotSupported)))
+	}
+	// reading returns the state of the GPU, false when it does not exist.
+	reading := func(gpuId uint32) (gpusim.Reading, bool) {
+		if int(gpuId) >= sim.GPUs() {
+			return gpusim.Reading{}, false
+		}
+		return sim.Reading(int(gpuId)), true
+	}
+	// call runs fn on the GPU unless a code is injected into symbol.
+	call := func(symbol string, gpuId uint32, fn func(gpusim.Reading)) Return {
+		if code := ret(symbol); code != Success {
+			return code
+		}
+		r, ok := reading(gpuId)
+		if !ok {
+			return ErrorNotSupported
+		}
+		fn(r)
+		return Success
+	}
+
+	mxSmlInit = func() Return { return ret("mxSmlInit") }
+	mxSmlGetErrorString = func(code Return) string {
+		switch code {
+		case Success:
+			return "success"
+		case ErrorNotSupported:
+			return "operation not supported"
+		default:
+			return fmt.Sprintf("simulated error %d", code)
+		}
+	}
+
+	mxSmlGetMacaVersion = func(buf *byte, size *uint32) Return {
+		if code := ret("mxSmlGetMacaVersion"); code != Success {
+			return code
+		}
+		writeCString(buf, *size, "2.32.0.6-sim")
+		return Success
+	}
+
+	mxSmlGetDeviceCount = func() uint32 { return uint32(sim.GPUs()) }
+	mxSmlGetPfDeviceCount = func() uint32 { return 0 }
+
+	mxSmlGetDeviceInfo = func(gpuId uint32, info *device.Info) Return {
+		return call("mxSmlGetDeviceInfo", gpuId, func(gpusim.Reading) {
+			*info = device.Info{
+				DeviceId: 0x4081,
+				GpuId:    gpuId,
+				Brand:    device.BrandC,
+				Mode:     device.VirtualizationModeNone,
+			}
+			writeCString(&info.BDFId[0], uint32(len(info.BDFId)), fmt.Sprintf("0000:%02x:00.0", 0x10+gpuId))
+			writeCString(&info.UUID[0], uint32(len(info.UUID)), fmt.Sprintf("GPU-5a5a5a5a-0000-4000-8000-%012d", gpuId))
+			writeCString(&info.DeviceName[0], uint32(len(info.DeviceName)), simDeviceName)
+		})
+	}
+	mxSmlGetDeviceDieCount = func(gpuId uint32, count *uint32) Return {
+		return call("mxSmlGetDeviceDieCount", gpuId, func(gpusim.Reading) { *count = 1 })
+	}
+	mxSmlGetDeviceVersion = func(gpuId uint32, unit device.DeviceVersionUnit, buf *byte, size *uint32) Return {
+		return call("mxSmlGetDeviceVersion", gpuId, func(gpusim.Reading) {
+			version := "2.14.6-sim"
+			if unit == device.DeviceVersionUnitBios {
+				version = "1.20.0.0-sim"
+			}
+			writeCString(buf, *size, version)
+		})
+	}
+
+	mxSmlGetBoardPowerInfo = func(gpuId uint32, size *uint32, first *BoardWayElectricInfo) Return {
+		return call("mxSmlGetBoardPowerInfo", gpuId, func(r gpusim.Reading) {
+			ways := unsafe.Slice(first, *size)
+			*size = min(*size, simBoardWays)
+			for i := range ways[:*size] {
+				power := uint32(r.Power * simPowerLimitMW / simBoardWays)
+				ways[i] = BoardWayElectricInfo{
+					Voltage: simBoardVoltage,
+					Current: power * 1000 / simBoardVoltage,
+					Power:   power,
+				}
+			}
+		})
+	}
+
+	mxSmlGetPcieInfo = func(gpuId uint32, info *PcieInfo) Return {
+		return call("mxSmlGetPcieInfo", gpuId, func(gpusim.Reading) {
+			*info = PcieInfo{Speed: simPcieSpeed, Width: simPcieWidth}
+		})
+	}
+	mxSmlGetPcieThroughput = func(gpuId uint32, info *PcieThroughput) Return {
+		return call("mxSmlGetPcieThroughput", gpuId, func(r gpusim.Reading) {
+			*info = PcieThroughput{ReceiveRate: int32(r.Utilization * 200), TransmitRate: int32(r.Utilization * 100)}
+		})
+	}
+
+	// the GPUs are linked to each other by one MetaXLink.
+	links := func(size *uint32) uint32 {
+		*size = min(*size, uint32(min(sim.GPUs()-1, device.MetaXLinkMaxNumber)))
+		return *size
+	}
+	mxSmlGetMetaXLinkInfo_v2 = func(gpuId uint32, size *uint32, first *SingleMetaXLinkInfo) Return {
+		return call("mxSmlGetMetaXLinkInfo_v2", gpuId, func(gpusim.Reading) {
+			infos := unsafe.Slice(first, *size)
+			for i := range infos[:links(size)] {
+				infos[i] = SingleMetaXLinkInfo{Speed: simPcieSpeed, Width: simPcieWidth}
+			}
+		})
+	}
+	mxSmlGetMetaXLinkBandwidth = func(gpuId uint32, _ device.MetaXLinkType, size *uint32, first *MetaXLinkBandwidth) Return {
+		return call("mxSmlGetMetaXLinkBandwidth", gpuId, func(r gpusim.Reading) {
+			infos := unsafe.Slice(first, *size)
+			for i := range infos[:links(size)] {
+				infos[i] = MetaXLinkBandwidth{RequestBandwidth: int32(r.Utilization * 50)}
+			}
+		})
+	}
+	mxSmlGetMetaXLinkTrafficStat = func(gpuId uint32, _ device.MetaXLinkType, size *uint32, first *MetaXLinkTrafficStat) Return {
+		return call("mxSmlGetMetaXLinkTrafficStat", gpuId, func(r gpusim.Reading) {
+			infos := unsafe.Slice(first, *size)
+			for i := range infos[:links(size)] {
+				infos[i] = MetaXLinkTrafficStat{RequestTrafficStat: int64(r.Traffic)}
+			}
+		})
+	}
+	mxSmlGetMetaXLinkAer = func(gpuId uint32, size *uint32, first *MetaXLinkAer) Return {
+		return call("mxSmlGetMetaXLinkAer", gpuId, func(r gpusim.Reading) {
+			infos := unsafe.Slice(first, *size)
+			for i := range infos[:links(size)] {
+				infos[i] = MetaXLinkAer{CorrectableErrorsCount: int32(r.CorrectableECC / 10)}
+			}
+		})
+	}
+
+	mxSmlGetDieUnavailableReason = func(gpuId, _ uint32, info *DeviceUnavailableReasonInfo) Return {
+		return call("mxSmlGetDieUnavailableReason", gpuId, func(r gpusim.Reading) {
+			info.unavailableCode = 0
+			if r.Unavailable {
+				info.unavailableCode = 1
+			}
+		})
+	}
+	mxSmlGetDieTemperatureInfo = func(gpuId, _ uint32, _ gpu.TemperatureSensor, value *int32) Return {
+		return call("mxSmlGetDieTemperatureInfo", gpuId, func(r gpusim.Reading) {
+			// in hundredths of celsius.
+			*value = int32(r.Temperature * 100)
+		})
+	}
+	mxSmlGetDieIpUsage = func(gpuId, _ uint32, ip gpu.UsageIp, value *int32) Return {
+		return call("mxSmlGetDieIpUsage", gpuId, func(r gpusim.Reading) {
+			switch ip {
+			case gpu.UsageIpXcore:
+				*value = int32(r.Utilization)
+			case gpu.UsageIpVpue, gpu.UsageIpVpud:
+				*value = int32(r.Utilization / 10)
+			default:
+				*value = 0
+			}
+		})
+	}
+	mxSmlGetDieMemoryInfo = func(gpuId, _ uint32, info *MemoryInfo) Return {
+		return call("mxSmlGetDieMemoryInfo", gpuId, func(r gpusim.Reading) {
+			*info = MemoryInfo{vramTotal: simVramTotalKB, vramUse: int64(r.MemoryUsed * simVramTotalKB)}
+		})
+	}
+	mxSmlGetDieClocks = func(gpuId, _ uint32, ip gpu.ClockIp, size *uint32, first *uint32) Return {
+		return call("mxSmlGetDieClocks", gpuId, func(r gpusim.Reading) {
+			mhz := uint32(1000)
+			switch ip {
+			case gpu.ClockIpXcore:
+				mhz = 1600
+				if r.Throttled {
+					mhz = 800
+				}
+			case gpu.ClockIpMc, gpu.ClockIpMc0, gpu.ClockIpMc1:
+				mhz = 1800
+			}
+			if *size > 0 {
+				unsafe.Slice(first, *size)[0] = mhz
+				*size = 1
+			}
+		})
+	}
+	mxSmlGetDieCurrentClocksThrottleReason = func(gpuId, _ uint32, value *uint64) Return {
+		return call("mxSmlGetDieCurrentClocksThrottleReason", gpuId, func(r gpusim.Reading) {
+			// the bits of gpu.ClocksThrottleBitReasonMap, from 1.
+			switch {
+			case r.Throttled:
+				*value = 1 << (4 - 1) // chip_overheated
+			case r.Utilization == 0:
+				*value = 1 << (1 - 1) // idle
+			default:
+				*value = 0
+			}
+		})
+	}
+	mxSmlGetCurrentDieDpmIpPerfLevel = func(gpuId, _ uint32, _ gpu.DpmIp, value *uint32) Return {
+		return call("mxSmlGetCurrentDieDpmIpPerfLevel", gpuId, func(r gpusim.Reading) {
+			*value = uint32(r.Utilization * 7 / 100)
+			if r.Throttled {
+				*value = 2
+			}
+		})
+	}
+	mxSmlGetDieTotalEccErrors = func(gpuId, _ uint32, info *EccErrorCount) Return {
+		return call("mxSmlGetDieTotalEccErrors", gpuId, func(r gpusim.Reading) {
+			*info = EccErrorCount{
+				SramCorrectableErrorsCount:   uint32(r.CorrectableECC / 2),
+				DramCorrectableErrorsCount:   uint32(r.CorrectableECC - r.CorrectableECC/2),
+				DramUncorrectableErrorsCount: uint32(r.UncorrectableECC),
+				RetiredPagesCount:            uint32(r.UncorrectableECC),
+			}
+		})
+	}
+}
@@ -19,10 +19,11 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"os"
 	"strconv"
+	"strings"
 	"sync"
-
-	"golang.org/x/sync/errgroup"
+	"time"
 
 	"huatuo-bamai/core/metrics/metax/sml"
 	"huatuo-bamai/core/metrics/metax/sml/device"
@@ -37,23 +38,97 @@ func init() {
 	tracing.RegisterEventTracing("metax_gpu", newMetaxGpuCollector)
 }
 
-type metaxGpuCollector struct{}
+// metaxSmlLibraryEnv overrides the configured SML library path.
+const metaxSmlLibraryEnv = "HUATUO_METAX_SML_LIBRARY"
+
+// metaxSmlSearchPaths are the SML library paths of the driver and of the
+// MACA SDK installs, used when none is configured.
+var metaxSmlSearchPaths = []string{
+	sml.DefaultLibraryPath(),
+	"/opt/maca/lib/libmxsml.so",
+}
+
+type metaxGpuCollector struct {
+	// full is the last full metric set, exported again on idle cycles.
+	full     []*metric.Data
+	fullTime time.Time
+	// pool bounds the goroutines calling into SML. A wedged driver call
+	// keeps its slot, so the later scrapes time out instead of piling up.
+	pool chan struct{}
+}
 
 func newMetaxGpuCollector() (*tracing.EventTracingAttr, error) {
+	simulated, err := gpuSimulate(gpuVendorMetax)
+	if err != nil {
+		return nil, err
+	}
+
+	path := "simulation"
+	if !simulated {
+		path = metaxSmlLibraryPath()
+		if path == "" {
+			return nil, types.ErrNotSupported
+		}
+		if err := sml.SetLibraryPath(path); err != nil {
+			return nil, err
+		}
+	}
+
 	// Init MetaX SML lib
 	if err := sml.Init(); err != nil {
+		log.Debugf("metax gpu init sml %s: %v", path, err)
 		return nil, types.ErrNotSupported
 	}
 
+	if cfg.MetaxGpu.FaultInterval < 0 || cfg.MetaxGpu.FaultEccThreshold < 0 {
+		return nil, fmt.Errorf("metax gpu fault interval and ecc threshold must be non-negative")
+	}
+	if cfg.MetaxGpu.Concurrency <= 0 || cfg.MetaxGpu.Timeout <= 0 {
+		return nil, fmt.Errorf("metax gpu concurrency and timeout must be positive, got %d and %d",
+			cfg.MetaxGpu.Concurrency, cfg.MetaxGpu.Timeout)
+	}
+
+	// the faults are watched by the tracing role.
+	flag := tracing.FlagMetric
+	if cfg.MetaxGpu.FaultInterval > 0 {
+		flag |= tracing.FlagTracing
+	}
+
 	return &tracing.EventTracingAttr{
-		TracingData: &metaxGpuCollector{},
-		Flag:        tracing.FlagMetric,
+		TracingData: &metaxGpuCollector{pool: make(chan struct{}, cfg.MetaxGpu.Concurrency)},
+		Interval:    10,
+		Flag:        flag,
 	}, nil
 }
 
+// metaxSmlLibraryPath returns the SML library to load: the environment
+// override, the configured path, or the first existing one of the search
+// paths. It is empty when no library is found.
+func metaxSmlLibraryPath() string {
+	if path := os.Getenv(metaxSmlLibraryEnv); path != "" {
+		return path
+	}
+	if cfg.MetaxGpu.LibraryPath != "" {
+		return cfg.MetaxGpu.LibraryPath
+	}
+
+	searchPaths := cfg.MetaxGpu.SearchPaths
+	if len(searchPaths) == 0 {
+		searchPaths = metaxSmlSearchPaths
+	}
+	for _, path := range searchPaths {
+		if _, err := os.Stat(path); err == nil {
+			return path
+		}
+	}
+	return ""
+}
+
 func (m *metaxGpuCollector) Update() ([]*metric.Data, error) {
-	ctx := context.Background()
-	metrics, err := metaxCollectMetrics(ctx)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(cfg.MetaxGpu.Timeout)*time.Second)
+	defer cancel()
+
+	metrics, err := m.collect(ctx)
 	if err != nil {
 		var smlError *sml.Error
 		if errors.As(err, &smlError) {
@@ -62,7 +137,7 @@ func (m *metaxGpuCollector) Update() ([]*metric.Data, error) {
 			if err := sml.Init(); err != nil {
 				return nil, fmt.Errorf("failed to re-init sml: %w", err)
 			}
-			return metaxCollectMetrics(ctx)
+			return m.collect(ctx)
 		}
 
 		return nil, err
@@ -71,26 +146,93 @@ func (m *metaxGpuCollector) Update() ([]*metric.Data, error) {
 	return metrics, nil
 }
 
-func metaxCollectMetrics(ctx context.Context) ([]*metric.Data, error) {
-	var metrics []*metric.Data
+// collect runs the full metric set only when a workload is using the GPUs,
+// idle nodes refresh it every MetaxGpu.IdleFullInterval seconds and export
+// the cached set in between. When ctx expires, the metrics of the GPUs done
+// are returned with scrape_error set.
+func (m *metaxGpuCollector) collect(ctx context.Context) ([]*metric.Data, error) {
+	gpus := metaxListGpus()
+
+	present, err := metaxDo(ctx, m.pool, func(ctx context.Context) (bool, error) {
+		return metaxWorkloadPresent(ctx, gpus)
+	})
+	if errors.Is(err, context.DeadlineExceeded) {
+		log.Warnf("metax gpu presence check exceeded the %ds budget", cfg.MetaxGpu.Timeout)
+		return []*metric.Data{metaxScrapeErrorData(true)}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
 
-	// SDK version
-	operationGetSdkVersion := "get sdk version"
-	sdkVersion, err := sml.GetSDKVersion(ctx)
+	var presentValue float64
+	if present {
+		presentValue = 1
+	}
+	presentData := metric.NewGaugeData("workload_present", presentValue, "Whether any GPU die is busy, 1 means busy.", nil)
+
+	interval := time.Duration(cfg.MetaxGpu.IdleFullInterval) * time.Second
+	if !present && interval > 0 && m.full != nil && time.Since(m.fullTime) < interval {
+		metrics := make([]*metric.Data, 0, len(m.full)+2)
+		metrics = append(metrics, m.full...)
+		return append(metrics, presentData, metaxScrapeErrorData(false)), nil
+	}
+
+	metrics, err := metaxCollectMetrics(ctx, m.pool, gpus)
+	if errors.Is(err, context.DeadlineExceeded) {
+		// the partial set is not cached, the next cycle collects again.
+		log.Warnf("metax gpu collection exceeded the %ds budget: %v", cfg.MetaxGpu.Timeout, err)
+		return append(metrics, presentData, metaxScrapeErrorData(true)), nil
+	}
 	if err != nil {
-		if !sml.IsNotSupported(err) {
-			return nil, fmt.Errorf("failed to %s: %w", operationGetSdkVersion, err)
-		}
-		log.Debugf("operation %s not supported", operationGetSdkVersion)
-	} else {
-		metrics = append(
-			metrics,
-			metric.NewGaugeData("sdk_info", 1, "GPU SDK info.", map[string]string{
-				"version": sdkVersion,
-			}),
-		)
+		return nil, err
+	}
+
+	m.full = metrics
+	m.fullTime = time.Now()
+	return append(metrics[:len(metrics):len(metrics)], presentData, metaxScrapeErrorData(false)), nil
+}
+
+func metaxScrapeErrorData(exceeded bool) *metric.Data {
+	var value float64
+	if exceeded {
+		value = 1
 	}
+	return metric.NewGaugeData("scrape_error", value, "Whether the collection exceeded the timeout budget, 1 means partial metrics.", nil)
+}
+
+// metaxDo runs fn in a slot of pool. It returns ctx.Err() as soon as ctx
+// expires, fn is left running and releases its slot when the SML call
+// returns.
+func metaxDo[T any](ctx context.Context, pool chan struct{}, fn func(context.Context) (T, error)) (T, error) {
+	var zero T
+
+	select {
+	case pool <- struct{}{}:
+	case <-ctx.Done():
+		return zero, ctx.Err()
+	}
+
+	type result struct {
+		value T
+		err   error
+	}
+	done := make(chan result, 1)
+	go func() {
+		defer func() { <-pool }()
+		value, err := fn(ctx)
+		done <- result{value, err}
+	}()
+
+	select {
+	case r := <-done:
+		return r.value, r.err
+	case <-ctx.Done():
+		return zero, ctx.Err()
+	}
+}
 
+// metaxListGpus returns the native, VF and PF GPU indexes.
+func metaxListGpus() []uint32 {
 	var gpus []uint32
 
 	// Native and VF GPUs
@@ -106,6 +248,100 @@ func metaxCollectMetrics(ctx context.Context) ([]*metric.Data, error) {
 		gpus = append(gpus, i)
 	}
 
+	return gpus
+}
+
+// metaxWorkloadPresent is the cheap presence check, only the xcore
+// utilization of each die is read. Dies which cannot report it are counted
+// as busy so that they keep the full metric set.
+func metaxWorkloadPresent(ctx context.Context, gpus []uint32) (bool, error) {
+	for _, gpuId := range gpus {
+		gpuInfo, err := sml.GetGPUInfo(ctx, gpuId)
+		if err != nil {
+			return false, fmt.Errorf("failed to get gpu info: %w", err)
+		}
+
+		for dieId := uint32(0); dieId < gpuInfo.DieCount; dieId++ {
+			value, err := sml.GetDieUtilization(ctx, gpuId, dieId, gpu.UsageIpXcore)
+			if err != nil {
+				if !sml.IsNotSupported(err) {
+					return false, fmt.Errorf("failed to get xcore utilization: %w", err)
+				}
+				return true, nil
+			}
+			if value > 0 {
+				return true, nil
+			}
+		}
+	}
+
+	return false, nil
+}
+
+// metaxCollectMetrics collects the GPUs in the pool. When ctx expires, the
+// metrics of the GPUs done are returned with an error wrapping
+// context.DeadlineExceeded.
+func metaxCollectMetrics(ctx context.Context, pool chan struct{}, gpus []uint32) ([]*metric.Data, error) {
+	metrics, err := metaxDo(ctx, pool, func(ctx context.Context) ([]*metric.Data, error) {
+		return metaxCollectVersionMetrics(ctx, gpus)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// GPU
+	results := make([][]*metric.Data, len(gpus))
+	errs := make([]error, len(gpus))
+	var wg sync.WaitGroup
+	for i, gpuId := range gpus {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i], errs[i] = metaxDo(ctx, pool, func(ctx context.Context) ([]*metric.Data, error) {
+				return metaxCollectGpuMetrics(ctx, gpuId)
+			})
+		}()
+	}
+	wg.Wait()
+
+	var exceeded []string
+	for i, gpuId := range gpus {
+		switch {
+		case errors.Is(errs[i], context.DeadlineExceeded):
+			exceeded = append(exceeded, strconv.Itoa(int(gpuId)))
+		case errs[i] != nil:
+			return nil, fmt.Errorf("failed to collect gpu %d metrics: %w", gpuId, errs[i])
+		default:
+			metrics = append(metrics, results[i]...)
+		}
+	}
+
+	if len(exceeded) > 0 {
+		return metrics, fmt.Errorf("gpu %s not collected: %w", strings.Join(exceeded, ","), context.DeadlineExceeded)
+	}
+	return metrics, nil
+}
+
+func metaxCollectVersionMetrics(ctx context.Context, gpus []uint32) ([]*metric.Data, error) {
+	var metrics []*metric.Data
+
+	// SDK version
+	operationGetSdkVersion := "get sdk version"
+	sdkVersion, err := sml.GetSDKVersion(ctx)
+	if err != nil {
+		if !sml.IsNotSupported(err) {
+			return nil, fmt.Errorf("failed to %s: %w", operationGetSdkVersion, err)
+		}
+		log.Debugf("operation %s not supported", operationGetSdkVersion)
+	} else {
+		metrics = append(
+			metrics,
+			metric.NewGaugeData("sdk_info", 1, "GPU SDK info.", map[string]string{
+				"version": sdkVersion,
+			}),
+		)
+	}
+
 	// Driver version
 	if len(gpus) > 0 {
 		operationGetDriverVersion := "get driver version"
@@ -125,28 +361,6 @@ func metaxCollectMetrics(ctx context.Context) ([]*metric.Data, error) {
 		}
 	}
 
-	// GPU
-	eg, subCtx := errgroup.WithContext(ctx)
-	var mu sync.Mutex
-	for _, gpu := range gpus {
-		// Since Go 1.22, loop variables are scoped per iteration,
-		// so closures capture the correct gpu value without rebinding.
-		eg.Go(func() error {
-			gpuMetrics, err := metaxCollectGpuMetrics(subCtx, gpu)
-			if err != nil {
-				return fmt.Errorf("failed to collect gpu %d metrics: %w", gpu, err)
-			}
-			mu.Lock()
-			metrics = append(metrics, gpuMetrics...)
-			mu.Unlock()
-			return nil
-		})
-	}
-
-	if err := eg.Wait(); err != nil {
-		return nil, err
-	}
-
 	return metrics, nil
 }
 
@@ -172,6 +386,13 @@ func metaxCollectGpuMetrics(ctx context.Context, gpuId uint32) ([]*metric.Data,
 		}),
 	)
 
+	// Topology, SML does not report the MetaXLink peers.
+	metrics = append(
+		metrics,
+		metric.NewGaugeData("topology_info", 1, "GPU topology info.",
+			gpuTopologyLabels(strconv.Itoa(int(gpuId)), gpuInfo.BDF)),
+	)
+
 	// Board electric
 	operationListBoardWayElectricInfos := "list board way electric infos"
 	boardWayElectricInfos, err := sml.ListGPUBoardWayElectricInfos(ctx, gpuId)
@@ -332,26 +553,13 @@ func metaxCollectGpuMetrics(ctx context.Context, gpuId uint32) ([]*metric.Data,
 		}
 	}
 
-	// Die
-	eg, subCtx := errgroup.WithContext(ctx)
-	var mu sync.Mutex
+	// Die, in the slot of the GPU so that the pool bounds the SML callers.
 	for die := uint32(0); die < gpuInfo.DieCount; die++ {
-		// Since Go 1.22, loop variables are scoped per iteration,
-		// so closures capture the correct gpu value without rebinding.
-		eg.Go(func() error {
-			dieMetrics, err := metaxCollectDieMetrics(subCtx, gpuId, die, gpuInfo.Series)
-			if err != nil {
-				return fmt.Errorf("failed to collect die %d metrics: %w", die, err)
-			}
-			mu.Lock()
-			metrics = append(metrics, dieMetrics...)
-			mu.Unlock()
-			return nil
-		})
-	}
-
-	if err := eg.Wait(); err != nil {
-		return nil, err
+		dieMetrics, err := metaxCollectDieMetrics(ctx, gpuId, die, gpuInfo.Series)
+		if err != nil {
+			return nil, fmt.Errorf("failed to collect die %d metrics: %w", die, err)
+		}
+		metrics = append(metrics, dieMetrics...)
 	}
 
 	return metrics, nil
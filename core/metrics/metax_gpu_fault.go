@@ -0,0 +1,386 @@
+// Copyright 2026 The HuaTuo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"huatuo-bamai/core/metrics/metax/sml"
+	"huatuo-bamai/core/metrics/metax/sml/device"
+	"huatuo-bamai/core/metrics/metax/sml/gpu"
+	"huatuo-bamai/internal/log"
+	"huatuo-bamai/internal/pod"
+	"huatuo-bamai/internal/procfs"
+	"huatuo-bamai/internal/procfs/sysfs"
+	"huatuo-bamai/pkg/tracing"
+	"huatuo-bamai/pkg/types"
+)
+
+// gpuFaultTracerName is the tracer name of the GPU fault documents.
+const gpuFaultTracerName = "gpu_fault"
+
+// Kinds of the GPU faults.
+const (
+	gpuFaultDieStatus        = "die_status"
+	gpuFaultEccUncorrectable = "ecc_ue"
+	gpuFaultClocksThrottling = "clocks_throttling"
+	gpuFaultLinkDegraded     = "link_degraded"
+)
+
+// metaxBenignThrottleReasons are the throttle reasons of a GPU doing its job.
+var metaxBenignThrottleReasons = []string{"idle", "application_limit", "low_usage"}
+
+// MetaxGpuFaultData is a fault of a GPU, with the raw SML readings before
+// and when it was seen.
+type MetaxGpuFaultData struct {
+	GPU        uint32             `json:"gpu"`
+	Model      string             `json:"model"`
+	UUID       string             `json:"uuid"`
+	BDF        string             `json:"bdf"`
+	Faults     []*metaxGpuFault   `json:"faults"`
+	Previous   *metaxGpuReading   `json:"previous,omitempty"`
+	Current    *metaxGpuReading   `json:"current"`
+	Containers []*gpuFaultProcess `json:"containers"`
+}
+
+type metaxGpuFault struct {
+	Kind string `json:"kind"`
+	// Die is empty for the faults of the GPU links.
+	Die    string `json:"die,omitempty"`
+	Detail string `json:"detail"`
+}
+
+// gpuFaultProcess is a container with the GPU open.
+type gpuFaultProcess struct {
+	ContainerID string  `json:"container_id"`
+	Hostname    string  `json:"container_hostname"`
+	Pids        []int32 `json:"pids"`
+}
+
+// metaxGpuReading are the fault related SML readings of a GPU.
+type metaxGpuReading struct {
+	Time      time.Time                  `json:"time"`
+	Pcie      *device.PcieLinkInfo       `json:"pcie,omitempty"`
+	MetaXLink []device.MetaXLinkLinkInfo `json:"metaxlink,omitempty"`
+	Dies      []*metaxDieReading         `json:"dies"`
+}
+
+type metaxDieReading struct {
+	Die    uint32 `json:"die"`
+	Status *int32 `json:"status,omitempty"`
+	// Ecc is nil when ECC is not supported.
+	Ecc             *device.DieEccMemoryInfo `json:"ecc,omitempty"`
+	ThrottleReasons []string                 `json:"throttle_reasons,omitempty"`
+	Temperature     *float64                 `json:"temperature,omitempty"`
+}
+
+func (m *metaxGpuCollector) Start(ctx context.Context) error {
+	// the reading of every GPU at the previous poll.
+	readings := make(map[uint32]*metaxGpuReading)
+
+	ticker := time.NewTicker(time.Duration(cfg.MetaxGpu.FaultInterval) * time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return types.ErrExitByCancelCtx
+		case <-ticker.C:
+		}
+
+		for _, gpuId := range metaxListGpus() {
+			if err := m.checkGpuFaults(ctx, readings, gpuId); err != nil {
+				log.Debugf("metax gpu %d faults: %v", gpuId, err)
+			}
+		}
+	}
+}
+
+func (m *metaxGpuCollector) checkGpuFaults(ctx context.Context, readings map[uint32]*metaxGpuReading, gpuId uint32) error {
+	info, err := sml.GetGPUInfo(ctx, gpuId)
+	if err != nil {
+		return err
+	}
+
+	current, err := metaxReadGpu(ctx, gpuId, info.DieCount)
+	if err != nil {
+		return err
+	}
+
+	previous := readings[gpuId]
+	readings[gpuId] = current
+
+	faults := metaxGpuFaults(previous, current, uint32(cfg.MetaxGpu.FaultEccThreshold))
+	if len(faults) == 0 {
+		return nil
+	}
+
+	containers, err := gpuFaultContainers(metaxGpuDeviceNodes(info.BDF))
+	if err != nil {
+		log.Debugf("metax gpu %d containers: %v", gpuId, err)
+	}
+
+	log.Warnf("metax gpu %d faults: %d, first %s", gpuId, len(faults), faults[0].Kind)
+
+	return tracing.Save(&tracing.WriteRequest{
+		TracerName: gpuFaultTracerName,
+		TracerTime: current.Time,
+		TracerData: &MetaxGpuFaultData{
+			GPU:        gpuId,
+			Model:      info.Model,
+			UUID:       info.UUID,
+			BDF:        info.BDF,
+			Faults:     faults,
+			Previous:   previous,
+			Current:    current,
+			Containers: containers,
+		},
+		TracerRunType: tracing.TracerRunTypeAutotracing,
+	})
+}
+
+// metaxReadGpu reads the GPU, the readings not supported are left empty.
+func metaxReadGpu(ctx context.Context, gpuId, dieCount uint32) (*metaxGpuReading, error) {
+	reading := &metaxGpuReading{Time: time.Now()}
+
+	pcie, err := sml.GetGPUPcieLinkInfo(ctx, gpuId)
+	if err == nil {
+		reading.Pcie = &pcie
+	} else if !sml.IsNotSupported(err) {
+		return nil, fmt.Errorf("failed to get pcie link info: %w", err)
+	}
+
+	links, err := sml.ListGPUMetaXLinkLinkInfos(ctx, gpuId)
+	if err == nil {
+		reading.MetaXLink = links
+	} else if !sml.IsNotSupported(err) {
+		return nil, fmt.Errorf("failed to list metaxlink link infos: %w", err)
+	}
+
+	for dieId := uint32(0); dieId < dieCount; dieId++ {
+		die := &metaxDieReading{Die: dieId}
+
+		status, err := sml.GetDieStatus(ctx, gpuId, dieId)
+		if err == nil {
+			die.Status = &status
+		} else if !sml.IsNotSupported(err) {
+			return nil, fmt.Errorf("failed to get die %d status: %w", dieId, err)
+		}
+
+		ecc, err := sml.GetDieECCMemoryInfo(ctx, gpuId, dieId)
+		if err == nil {
+			die.Ecc = &ecc
+		} else if !sml.IsNotSupported(err) {
+			return nil, fmt.Errorf("failed to get die %d ecc memory info: %w", dieId, err)
+		}
+
+		throttle, err := sml.GetDieClocksThrottleStatus(ctx, gpuId, dieId)
+		if err == nil {
+			die.ThrottleReasons = metaxThrottleReasons(throttle)
+		} else if !sml.IsNotSupported(err) {
+			return nil, fmt.Errorf("failed to get die %d clocks throttle status: %w", dieId, err)
+		}
+
+		temperature, err := sml.GetDieTemperature(ctx, gpuId, dieId, gpu.TemperatureSensorHotspot)
+		if err == nil {
+			die.Temperature = &temperature
+		}
+
+		reading.Dies = append(reading.Dies, die)
+	}
+
+	return reading, nil
+}
+
+// metaxThrottleReasons returns the known reasons set in the throttle status.
+func metaxThrottleReasons(status uint64) []string {
+	var reasons []string
+	for i, v := range getBitsFromLsbToMsb(status) {
+		if reason, ok := gpu.ClocksThrottleBitReasonMap[i+1]; ok && v == 1 {
+			reasons = append(reasons, reason)
+		}
+	}
+	return reasons
+}
+
+// metaxGpuFaults compares the readings of two polls. Without previous, only
+// the abnormal die status is a fault, the counters and links need a base.
+func metaxGpuFaults(previous, current *metaxGpuReading, eccThreshold uint32) []*metaxGpuFault {
+	var faults []*metaxGpuFault
+
+	for i, die := range current.Dies {
+		dieLabel := strconv.Itoa(int(die.Die))
+
+		var prev *metaxDieReading
+		if previous != nil && i < len(previous.Dies) {
+			prev = previous.Dies[i]
+		}
+
+		// a status fault is reported when it changes.
+		if die.Status != nil && *die.Status != 0 && (prev == nil || prev.Status == nil || *prev.Status != *die.Status) {
+			faults = append(faults, &metaxGpuFault{
+				Kind:   gpuFaultDieStatus,
+				Die:    dieLabel,
+				Detail: fmt.Sprintf("status %d", *die.Status),
+			})
+		}
+
+		if prev == nil {
+			continue
+		}
+
+		if die.Ecc != nil && prev.Ecc != nil {
+			sram := die.Ecc.SramUncorrectableErrorsCount - min(prev.Ecc.SramUncorrectableErrorsCount, die.Ecc.SramUncorrectableErrorsCount)
+			dram := die.Ecc.DramUncorrectableErrorsCount - min(prev.Ecc.DramUncorrectableErrorsCount, die.Ecc.DramUncorrectableErrorsCount)
+			if eccThreshold > 0 && sram+dram >= eccThreshold {
+				faults = append(faults, &metaxGpuFault{
+					Kind:   gpuFaultEccUncorrectable,
+					Die:    dieLabel,
+					Detail: fmt.Sprintf("%d sram and %d dram uncorrectable errors since the previous poll", sram, dram),
+				})
+			}
+		}
+
+		var started []string
+		for _, reason := range die.ThrottleReasons {
+			if !slices.Contains(metaxBenignThrottleReasons, reason) && !slices.Contains(prev.ThrottleReasons, reason) {
+				started = append(started, reason)
+			}
+		}
+		if len(started) > 0 {
+			faults = append(faults, &metaxGpuFault{
+				Kind:   gpuFaultClocksThrottling,
+				Die:    dieLabel,
+				Detail: "throttling started: " + strings.Join(started, ","),
+			})
+		}
+	}
+
+	if previous == nil {
+		return faults
+	}
+
+	// a link is reported when it degrades, not at every poll.
+	if current.Pcie != nil && previous.Pcie != nil && metaxLinkBelow(current.Pcie.Speed, current.Pcie.Width, previous.Pcie.Speed, previous.Pcie.Width) {
+		faults = append(faults, &metaxGpuFault{
+			Kind: gpuFaultLinkDegraded,
+			Detail: fmt.Sprintf("pcie from %gGT/s x%d to %gGT/s x%d",
+				previous.Pcie.Speed, previous.Pcie.Width, current.Pcie.Speed, current.Pcie.Width),
+		})
+	}
+	for i, link := range current.MetaXLink {
+		if i >= len(previous.MetaXLink) {
+			break
+		}
+		prev := previous.MetaXLink[i]
+		if metaxLinkBelow(link.Speed, link.Width, prev.Speed, prev.Width) {
+			faults = append(faults, &metaxGpuFault{
+				Kind: gpuFaultLinkDegraded,
+				Detail: fmt.Sprintf("metaxlink %d from %gGT/s x%d to %gGT/s x%d",
+					i+1, prev.Speed, prev.Width, link.Speed, link.Width),
+			})
+		}
+	}
+
+	return faults
+}
+
+// metaxLinkBelow reports whether a link trained below the previous speed or
+// width.
+func metaxLinkBelow(speed float32, width uint32, prevSpeed float32, prevWidth uint32) bool {
+	return speed < prevSpeed || width < prevWidth
+}
+
+// metaxGpuDeviceNodes returns the DRM device nodes of the GPU at bdf.
+func metaxGpuDeviceNodes(bdf string) []string {
+	entries, err := os.ReadDir(sysfs.Path("bus/pci/devices", strings.ToLower(bdf), "drm"))
+	if err != nil {
+		return nil
+	}
+
+	var nodes []string
+	for _, entry := range entries {
+		nodes = append(nodes, filepath.Join("/dev/dri", entry.Name()))
+	}
+	return nodes
+}
+
+// gpuDevicePids returns the processes holding one of the device nodes open.
+func gpuDevicePids(nodes []string) ([]int32, error) {
+	if len(nodes) == 0 {
+		return nil, nil
+	}
+
+	procs, err := os.ReadDir(procfs.Path())
+	if err != nil {
+		return nil, err
+	}
+
+	var pids []int32
+	for _, proc := range procs {
+		pid, err := strconv.ParseInt(proc.Name(), 10, 32)
+		if err != nil {
+			continue
+		}
+
+		fdDir := procfs.Path(proc.Name(), "fd")
+		fds, err := os.ReadDir(fdDir)
+		if err != nil {
+			continue
+		}
+		for _, fd := range fds {
+			target, err := os.Readlink(filepath.Join(fdDir, fd.Name()))
+			if err == nil && slices.Contains(nodes, target) {
+				pids = append(pids, int32(pid))
+				break
+			}
+		}
+	}
+	return pids, nil
+}
+
+// gpuFaultContainers returns the containers of the processes holding the
+// device nodes open.
+func gpuFaultContainers(nodes []string) ([]*gpuFaultProcess, error) {
+	pids, err := gpuDevicePids(nodes)
+	if err != nil {
+		return nil, err
+	}
+
+	byID := make(map[string]*gpuFaultProcess)
+	var containers []*gpuFaultProcess
+	for _, pid := range pids {
+		container, err := pod.ContainerByPid(int(pid))
+		if err != nil || container == nil {
+			continue
+		}
+
+		p, ok := byID[container.ID]
+		if !ok {
+			p = &gpuFaultProcess{ContainerID: container.ID, Hostname: container.Hostname}
+			byID[container.ID] = p
+			containers = append(containers, p)
+		}
+		p.Pids = append(p.Pids, pid)
+	}
+	return containers, nil
+}
@@ -0,0 +1,151 @@
+// Copyright 2026 The HuaTuo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"huatuo-bamai/core/metrics/metax/sml/device"
+	"huatuo-bamai/internal/procfs"
+)
+
+func testMetaxReading(status int32, ue uint32, throttle []string, speed float32) *metaxGpuReading {
+	return &metaxGpuReading{
+		Pcie:      &device.PcieLinkInfo{Speed: speed, Width: 16},
+		MetaXLink: []device.MetaXLinkLinkInfo{{Speed: speed, Width: 8}},
+		Dies: []*metaxDieReading{{
+			Status:          &status,
+			Ecc:             &device.DieEccMemoryInfo{DramUncorrectableErrorsCount: ue},
+			ThrottleReasons: throttle,
+		}},
+	}
+}
+
+func metaxFaultKinds(faults []*metaxGpuFault) []string {
+	var kinds []string
+	for _, fault := range faults {
+		kinds = append(kinds, fault.Kind)
+	}
+	return kinds
+}
+
+func TestMetaxGpuFaults(t *testing.T) {
+	tests := []struct {
+		name     string
+		previous *metaxGpuReading
+		current  *metaxGpuReading
+		want     []string
+	}{
+		{
+			name:    "first poll healthy",
+			current: testMetaxReading(0, 5, nil, 32),
+		},
+		{
+			name:    "first poll abnormal status",
+			current: testMetaxReading(2, 5, []string{"over_power"}, 16),
+			want:    []string{gpuFaultDieStatus},
+		},
+		{
+			name:     "status unchanged",
+			previous: testMetaxReading(2, 0, nil, 32),
+			current:  testMetaxReading(2, 0, nil, 32),
+		},
+		{
+			name:     "ecc burst",
+			previous: testMetaxReading(0, 3, nil, 32),
+			current:  testMetaxReading(0, 4, nil, 32),
+			want:     []string{gpuFaultEccUncorrectable},
+		},
+		{
+			name:     "benign throttling",
+			previous: testMetaxReading(0, 0, nil, 32),
+			current:  testMetaxReading(0, 0, []string{"idle", "low_usage"}, 32),
+		},
+		{
+			name:     "throttling started",
+			previous: testMetaxReading(0, 0, []string{"over_power"}, 32),
+			current:  testMetaxReading(0, 0, []string{"over_power", "chip_overheated"}, 32),
+			want:     []string{gpuFaultClocksThrottling},
+		},
+		{
+			name:     "links degraded",
+			previous: testMetaxReading(0, 0, nil, 32),
+			current:  testMetaxReading(0, 0, nil, 16),
+			want:     []string{gpuFaultLinkDegraded, gpuFaultLinkDegraded},
+		},
+		{
+			name:     "links recovered",
+			previous: testMetaxReading(0, 0, nil, 16),
+			current:  testMetaxReading(0, 0, nil, 32),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := metaxFaultKinds(metaxGpuFaults(tt.previous, tt.current, 1))
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("metaxGpuFaults() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	// the threshold 0 disables the ecc faults.
+	if got := metaxGpuFaults(testMetaxReading(0, 0, nil, 32), testMetaxReading(0, 9, nil, 32), 0); len(got) != 0 {
+		t.Errorf("metaxGpuFaults() threshold 0 = %v", metaxFaultKinds(got))
+	}
+}
+
+func TestMetaxThrottleReasons(t *testing.T) {
+	// bit 0 is idle, bit 3 is chip_overheated.
+	if got, want := metaxThrottleReasons(0b1001), []string{"idle", "chip_overheated"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("metaxThrottleReasons() = %v, want %v", got, want)
+	}
+	if got := metaxThrottleReasons(0); got != nil {
+		t.Errorf("metaxThrottleReasons(0) = %v", got)
+	}
+}
+
+func TestGpuDevicePids(t *testing.T) {
+	root := t.TempDir()
+	procfs.RootPrefix(root)
+	t.Cleanup(func() { procfs.RootPrefix("/") })
+
+	fds := map[string]string{
+		"100/fd/3":  "/dev/dri/renderD128",
+		"200/fd/4":  "/dev/dri/renderD129",
+		"300/fd/0":  "/dev/null",
+		"self/fd/0": "/dev/dri/renderD128",
+	}
+	for fd, target := range fds {
+		path := filepath.Join(root, "proc", fd)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Symlink(target, path); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	pids, err := gpuDevicePids([]string{"/dev/dri/card0", "/dev/dri/renderD128"})
+	if err != nil {
+		t.Fatalf("gpuDevicePids() error = %v", err)
+	}
+	if want := []int32{100}; !reflect.DeepEqual(pids, want) {
+		t.Errorf("gpuDevicePids() = %v, want %v", pids, want)
+	}
+}
@@ -0,0 +1,159 @@
+// Copyright 2026 The HuaTuo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"huatuo-bamai/core/metrics/metax/sml"
+	"huatuo-bamai/pkg/metric"
+)
+
+func TestMetaxSmlLibraryPath(t *testing.T) {
+	orig := cfg
+	t.Cleanup(func() { cfg = orig })
+	cfg = &Config{}
+
+	dir := t.TempDir()
+	installed := filepath.Join(dir, "lib/libmxsml.so")
+	writeTestFile(t, installed, "")
+	cfg.MetaxGpu.SearchPaths = []string{filepath.Join(dir, "missing/libmxsml.so"), installed}
+
+	t.Setenv(metaxSmlLibraryEnv, "")
+	if got := metaxSmlLibraryPath(); got != installed {
+		t.Errorf("search paths: metaxSmlLibraryPath() = %q, want %q", got, installed)
+	}
+
+	cfg.MetaxGpu.LibraryPath = "/custom/libmxsml.so"
+	if got := metaxSmlLibraryPath(); got != "/custom/libmxsml.so" {
+		t.Errorf("config: metaxSmlLibraryPath() = %q", got)
+	}
+
+	t.Setenv(metaxSmlLibraryEnv, "/env/libmxsml.so")
+	if got := metaxSmlLibraryPath(); got != "/env/libmxsml.so" {
+		t.Errorf("env: metaxSmlLibraryPath() = %q", got)
+	}
+
+	t.Setenv(metaxSmlLibraryEnv, "")
+	cfg.MetaxGpu.LibraryPath = ""
+	cfg.MetaxGpu.SearchPaths = []string{filepath.Join(dir, "missing/libmxsml.so")}
+	if got := metaxSmlLibraryPath(); got != "" {
+		t.Errorf("no library: metaxSmlLibraryPath() = %q, want empty", got)
+	}
+}
+
+func TestMetaxDo(t *testing.T) {
+	pool := make(chan struct{}, 1)
+
+	got, err := metaxDo(context.Background(), pool, func(context.Context) (int, error) { return 1, nil })
+	if got != 1 || err != nil {
+		t.Fatalf("metaxDo() = %d, %v, want 1, nil", got, err)
+	}
+
+	// a wedged call returns at the deadline and keeps its slot.
+	release := make(chan struct{})
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := metaxDo(ctx, pool, func(context.Context) (int, error) {
+		<-release
+		return 1, nil
+	}); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("wedged metaxDo() error = %v, want deadline exceeded", err)
+	}
+
+	ctx2, cancel2 := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel2()
+	if _, err := metaxDo(ctx2, pool, func(context.Context) (int, error) {
+		t.Error("fn ran without a free slot")
+		return 0, nil
+	}); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("full pool metaxDo() error = %v, want deadline exceeded", err)
+	}
+
+	close(release)
+	if _, err := metaxDo(context.Background(), pool, func(context.Context) (int, error) { return 2, nil }); err != nil {
+		t.Fatalf("released pool metaxDo() error = %v", err)
+	}
+}
+
+// metaxSimulate loads the SML library simulating GPUs of the behavior.
+func metaxSimulate(t *testing.T, behavior string) {
+	t.Helper()
+
+	cfg.GPUSimulation.Behaviors = []string{behavior}
+	if _, err := gpuSimulate(gpuVendorMetax); err != nil {
+		t.Fatalf("gpuSimulate() error = %v", err)
+	}
+	if err := sml.Init(); err != nil {
+		t.Fatalf("sml.Init() error = %v", err)
+	}
+}
+
+func TestMetaxIdleFullInterval(t *testing.T) {
+	simulateGpus(t, gpuVendorMetax, nil, nil)
+	cfg.MetaxGpu.IdleFullInterval = 60
+	cfg.MetaxGpu.Timeout = 5
+	metaxSimulate(t, "idle")
+	t.Cleanup(func() { _ = sml.Shutdown() })
+
+	m := &metaxGpuCollector{pool: make(chan struct{}, 1)}
+	ctx := context.Background()
+
+	// workload_present and scrape_error follow the GPU metrics.
+	present := func(data []*metric.Data) float64 { return data[len(data)-2].Value }
+
+	data, err := m.collect(ctx)
+	if err != nil || m.full == nil {
+		t.Fatalf("first collect() = %v, want a full collection", err)
+	}
+	if present(data) != 0 {
+		t.Errorf("idle workload_present = %v, want 0", present(data))
+	}
+
+	// idle within the interval, the cached set is exported again.
+	full, fullTime := m.full, m.fullTime
+	data, err = m.collect(ctx)
+	if err != nil || m.fullTime != fullTime || data[0] != full[0] {
+		t.Errorf("idle collect() within the interval ran a full collection, err %v", err)
+	}
+
+	// the interval expired, the set is refreshed.
+	m.fullTime = m.fullTime.Add(-time.Minute)
+	if data, err = m.collect(ctx); err != nil || data[0] == full[0] {
+		t.Errorf("idle collect() after the interval reused the cache, err %v", err)
+	}
+
+	// a workload starts, the full collection resumes at once.
+	if err := sml.Shutdown(); err != nil {
+		t.Fatalf("sml.Shutdown() error = %v", err)
+	}
+	metaxSimulate(t, "busy")
+	full = m.full
+	if data, err = m.collect(ctx); err != nil || data[0] == full[0] {
+		t.Errorf("busy collect() reused the cache, err %v", err)
+	}
+	if present(data) != 1 {
+		t.Errorf("busy workload_present = %v, want 1", present(data))
+	}
+
+	full = m.full
+	if data, err = m.collect(ctx); err != nil || data[0] == full[0] {
+		t.Errorf("busy collect() reused the cache, err %v", err)
+	}
+}
@@ -61,14 +61,20 @@ func (c *arpCollector) Update() ([]*metric.Data, error) {
 		return nil, err
 	}
 
-	for _, container := range containers {
+	scanned, err := scanContainers("arp", containers, func(container *pod.Container) ([]*metric.Data, error) {
 		count, err := CountLines(procfs.Path(strconv.Itoa(container.InitPid), "net/arp"))
 		if err != nil {
-			// return data collected
-			return data, err
+			return nil, err
 		}
 
-		data = append(data, metric.NewContainerGaugeData(container, "entries", float64(count-1), "arp entries in container netns", nil))
+		return []*metric.Data{
+			metric.NewContainerGaugeData(container, "entries", float64(count-1), "arp entries in container netns", nil),
+		}, nil
+	})
+	data = append(data, scanned...)
+	if err != nil {
+		// return data collected
+		return data, err
 	}
 
 	entries, err := nodeArpCacheEntries()
@@ -0,0 +1,147 @@
+// Copyright 2026 The HuaTuo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"os"
+	"strings"
+
+	"huatuo-bamai/internal/procfs"
+	"huatuo-bamai/internal/procfs/sysfs"
+	"huatuo-bamai/pkg/metric"
+	"huatuo-bamai/pkg/tracing"
+)
+
+// Dataplanes and the sources of their connection tracking.
+const (
+	netDataplaneCilium     = "cilium"
+	netDataplaneCalicoEbpf = "calico_ebpf"
+	netDataplaneNetfilter  = "netfilter"
+	netDataplaneUnknown    = "unknown"
+
+	netConntrackEbpf      = "ebpf"
+	netConntrackNetfilter = "netfilter"
+	netConntrackNone      = "none"
+)
+
+type netDataplaneCollector struct{}
+
+func init() {
+	tracing.RegisterEventTracing("net_dataplane", newNetDataplane)
+}
+
+func newNetDataplane() (*tracing.EventTracingAttr, error) {
+	return &tracing.EventTracingAttr{
+		TracingData: &netDataplaneCollector{},
+		Flag:        tracing.FlagMetric,
+	}, nil
+}
+
+// netDataplane is the packet dataplane of the node. The eBPF dataplanes
+// track connections in their own maps, nf_conntrack and iptables are empty
+// or missing there, the network collectors read them accordingly.
+type netDataplane struct {
+	Name string
+	// Conntrack is where the connections are tracked.
+	Conntrack string
+	// ServiceLB reports whether the services are load balanced in eBPF,
+	// i.e. kube-proxy is replaced.
+	ServiceLB bool
+}
+
+// detectNetDataplane detects the dataplane by its devices and the maps it
+// pins to the bpf filesystem, which must be mounted for the eBPF ones to
+// report their conntrack.
+func detectNetDataplane() *netDataplane {
+	maps := netPinnedBpfMaps()
+	netfilter := netFileExists(procfs.Path("sys/net/netfilter/nf_conntrack_count"))
+
+	switch {
+	case netFileExists(sysfs.Path("class/net/cilium_host")):
+		dp := &netDataplane{Name: netDataplaneCilium, Conntrack: netConntrackNone}
+		if hasPinnedMap(maps, "cilium_ct4_global", "cilium_ct6_global", "cilium_ct_any4_global", "cilium_ct_any6_global") {
+			dp.Conntrack = netConntrackEbpf
+		} else if netfilter {
+			dp.Conntrack = netConntrackNetfilter
+		}
+		dp.ServiceLB = hasPinnedMap(maps, "cilium_lb4_services_v2", "cilium_lb6_services_v2")
+		return dp
+	case netFileExists(sysfs.Path("class/net/bpfin.cali")) || hasPinnedMapPrefix(maps, "cali_v4_ct", "cali_v6_ct"):
+		// calico eBPF mode replaces kube-proxy and conntrack together.
+		return &netDataplane{Name: netDataplaneCalicoEbpf, Conntrack: netConntrackEbpf, ServiceLB: true}
+	case netfilter:
+		return &netDataplane{Name: netDataplaneNetfilter, Conntrack: netConntrackNetfilter}
+	default:
+		return &netDataplane{Name: netDataplaneUnknown, Conntrack: netConntrackNone}
+	}
+}
+
+// netPinnedBpfMaps returns the names pinned by the tc programs of the CNI.
+func netPinnedBpfMaps() []string {
+	entries, err := os.ReadDir(sysfs.Path("fs/bpf/tc/globals"))
+	if err != nil {
+		return nil
+	}
+
+	names := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	return names
+}
+
+func hasPinnedMap(maps []string, names ...string) bool {
+	for _, m := range maps {
+		for _, name := range names {
+			if m == name {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func hasPinnedMapPrefix(maps []string, prefixes ...string) bool {
+	for _, m := range maps {
+		for _, prefix := range prefixes {
+			if strings.HasPrefix(m, prefix) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func netFileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+func (c *netDataplaneCollector) Update() ([]*metric.Data, error) {
+	dp := detectNetDataplane()
+
+	serviceLB := "kube-proxy"
+	if dp.ServiceLB {
+		serviceLB = "ebpf"
+	}
+
+	return []*metric.Data{
+		metric.NewGaugeData("info", 1, "packet dataplane of the node", map[string]string{
+			"dataplane":  dp.Name,
+			"conntrack":  dp.Conntrack,
+			"service_lb": serviceLB,
+		}),
+	}, nil
+}
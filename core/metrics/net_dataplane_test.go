@@ -0,0 +1,80 @@
+// Copyright 2026 The HuaTuo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"huatuo-bamai/internal/procfs"
+)
+
+func TestDetectNetDataplane(t *testing.T) {
+	tests := []struct {
+		name  string
+		files []string
+		want  netDataplane
+	}{
+		{
+			name: "cilium",
+			files: []string{
+				"sys/class/net/cilium_host/ifindex",
+				"sys/fs/bpf/tc/globals/cilium_ct4_global",
+				"sys/fs/bpf/tc/globals/cilium_lb4_services_v2",
+				"proc/sys/net/netfilter/nf_conntrack_count",
+			},
+			want: netDataplane{Name: netDataplaneCilium, Conntrack: netConntrackEbpf, ServiceLB: true},
+		},
+		{
+			name: "cilium without bpffs",
+			files: []string{
+				"sys/class/net/cilium_host/ifindex",
+				"proc/sys/net/netfilter/nf_conntrack_count",
+			},
+			want: netDataplane{Name: netDataplaneCilium, Conntrack: netConntrackNetfilter},
+		},
+		{
+			name:  "calico ebpf",
+			files: []string{"sys/fs/bpf/tc/globals/cali_v4_ct3"},
+			want:  netDataplane{Name: netDataplaneCalicoEbpf, Conntrack: netConntrackEbpf, ServiceLB: true},
+		},
+		{
+			name:  "netfilter",
+			files: []string{"proc/sys/net/netfilter/nf_conntrack_count"},
+			want:  netDataplane{Name: netDataplaneNetfilter, Conntrack: netConntrackNetfilter},
+		},
+		{
+			name: "unknown",
+			want: netDataplane{Name: netDataplaneUnknown, Conntrack: netConntrackNone},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := t.TempDir()
+			procfs.RootPrefix(root)
+			t.Cleanup(func() { procfs.RootPrefix("/") })
+
+			for _, file := range tt.files {
+				writeTestFile(t, filepath.Join(root, file), "")
+			}
+
+			if got := detectNetDataplane(); !reflect.DeepEqual(*got, tt.want) {
+				t.Errorf("detectNetDataplane() = %+v, want %+v", *got, tt.want)
+			}
+		})
+	}
+}
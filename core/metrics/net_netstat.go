@@ -64,15 +64,14 @@ func (c *netstatCollector) Update() ([]*metric.Data, error) {
 		return nil, fmt.Errorf("netstat filter: %w", err)
 	}
 
-	var metrics []*metric.Data
-	for _, container := range containers {
+	metrics, _ := scanContainers("netstat", containers, func(container *pod.Container) ([]*metric.Data, error) {
 		m, err := buildNetAndSnmpStat(container, f)
 		if err != nil {
 			log.Errorf("netstat/snmp metrics for container %v: %v", container, err)
-			continue
+			return nil, nil
 		}
-		metrics = append(metrics, m...)
-	}
+		return m, nil
+	})
 	log.Debugf("Updated netstat metrics by filter %v: %v", f, metrics)
 	return metrics, nil
 }
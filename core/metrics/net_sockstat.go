@@ -59,13 +59,15 @@ func (c *sockstatCollector) Update() ([]*metric.Data, error) {
 	// append host into containers
 	containers[""] = nil
 
-	var metrics []*metric.Data
-	for _, container := range containers {
+	metrics, err := scanContainers("sockstat", containers, func(container *pod.Container) ([]*metric.Data, error) {
 		m, err := c.procStatMetrics(container)
 		if err != nil {
 			return nil, fmt.Errorf("couldn't get sockstat metrics for container %v: %w", container, err)
 		}
-		metrics = append(metrics, m...)
+		return m, nil
+	})
+	if err != nil {
+		return nil, err
 	}
 
 	log.Debugf("Updated sockstat metrics: %v", metrics)
@@ -0,0 +1,89 @@
+// Copyright 2026 The HuaTuo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"huatuo-bamai/pkg/metric"
+	"huatuo-bamai/pkg/tracing"
+
+	"github.com/vishvananda/netlink"
+)
+
+type bondingCollector struct{}
+
+func init() {
+	tracing.RegisterEventTracing("netdev_bonding", newBonding)
+}
+
+func newBonding() (*tracing.EventTracingAttr, error) {
+	return &tracing.EventTracingAttr{
+		TracingData: &bondingCollector{},
+		Flag:        tracing.FlagMetric,
+	}, nil
+}
+
+func (c *bondingCollector) Update() ([]*metric.Data, error) {
+	links, err := netlink.LinkList()
+	if err != nil {
+		return nil, err
+	}
+
+	return bondingData(links), nil
+}
+
+// bondingData returns the slaves of the bonds and their state. The link
+// failures of a slave are counted by the bonding driver, a failover of an
+// active-backup bond is a link failure of its active slave.
+func bondingData(links []netlink.Link) []*metric.Data {
+	bonds := make(map[int]*netlink.Bond)
+	for _, link := range links {
+		if bond, ok := link.(*netlink.Bond); ok {
+			bonds[bond.Attrs().Index] = bond
+		}
+	}
+	if len(bonds) == 0 {
+		return nil
+	}
+
+	slaves := make(map[int]int, len(bonds))
+	data := []*metric.Data{}
+	for _, link := range links {
+		attrs := link.Attrs()
+		bond, ok := bonds[attrs.MasterIndex]
+		if !ok {
+			continue
+		}
+		slave, ok := attrs.Slave.(*netlink.BondSlave)
+		if !ok {
+			continue
+		}
+
+		slaves[bond.Index]++
+		label := map[string]string{"bond": bond.Name, "slave": attrs.Name}
+		data = append(data,
+			metric.NewGaugeData("slave_active", boolFloat(slave.State == netlink.BondStateActive),
+				"whether the slave is active", label),
+			metric.NewGaugeData("slave_mii_up", boolFloat(slave.MiiStatus == netlink.BondLinkUp),
+				"whether the mii status of the slave is up", label),
+			metric.NewCounterData("slave_link_failures_total", float64(slave.LinkFailureCount),
+				"link failures of the slave", label))
+	}
+
+	for index, bond := range bonds {
+		data = append(data, metric.NewGaugeData("slaves", float64(slaves[index]), "slaves of the bond",
+			map[string]string{"bond": bond.Name, "mode": bond.Mode.String()}))
+	}
+	return data
+}
@@ -0,0 +1,79 @@
+// Copyright 2026 The HuaTuo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"testing"
+
+	"github.com/vishvananda/netlink"
+)
+
+func TestBondingData(t *testing.T) {
+	bond := netlink.NewLinkBond(netlink.LinkAttrs{Name: "bond0", Index: 10})
+	bond.Mode = netlink.BOND_MODE_ACTIVE_BACKUP
+
+	links := []netlink.Link{
+		bond,
+		&netlink.Device{LinkAttrs: netlink.LinkAttrs{Name: "eth0", Index: 2, MasterIndex: 10,
+			Slave: &netlink.BondSlave{State: netlink.BondStateActive, MiiStatus: netlink.BondLinkUp, LinkFailureCount: 1}}},
+		&netlink.Device{LinkAttrs: netlink.LinkAttrs{Name: "eth1", Index: 3, MasterIndex: 10,
+			Slave: &netlink.BondSlave{State: netlink.BondStateBackup, MiiStatus: netlink.BondLinkDown, LinkFailureCount: 3}}},
+		// not a bond slave.
+		&netlink.Device{LinkAttrs: netlink.LinkAttrs{Name: "eth2", Index: 4}},
+	}
+
+	data := bondingData(links)
+	want := []float64{
+		1, 1, 1, // eth0 active, mii up, link failures
+		0, 0, 3, // eth1
+		2, // slaves of bond0
+	}
+	if len(data) != len(want) {
+		t.Fatalf("bondingData() returned %d metrics, want %d", len(data), len(want))
+	}
+	for i, v := range want {
+		if data[i].Value != v {
+			t.Errorf("bondingData()[%d] = %v, want %v", i, data[i].Value, v)
+		}
+	}
+
+	if data := bondingData(links[1:]); data != nil {
+		t.Errorf("bondingData() without bonds = %d metrics, want nil", len(data))
+	}
+}
+
+func TestSriovData(t *testing.T) {
+	links := []netlink.Link{
+		&netlink.Device{LinkAttrs: netlink.LinkAttrs{Name: "eth0", Vfs: []netlink.VfInfo{
+			{ID: 0, Spoofchk: true, RxPackets: 10, TxPackets: 20, RxDropped: 1},
+			{ID: 1, Trust: 1},
+		}}},
+		&netlink.Device{LinkAttrs: netlink.LinkAttrs{Name: "eth1"}},
+	}
+
+	data := sriovData(links)
+	if len(data) != 2*11 {
+		t.Fatalf("sriovData() returned %d metrics, want 22", len(data))
+	}
+	// info, spoofcheck, trust, rx and tx packets, of vf 0.
+	for i, v := range []float64{1, 1, 0, 10, 20} {
+		if data[i].Value != v {
+			t.Errorf("sriovData()[%d] = %v, want %v", i, data[i].Value, v)
+		}
+	}
+	if data[11+2].Value != 1 {
+		t.Errorf("trust of vf 1 = %v, want 1", data[11+2].Value)
+	}
+}
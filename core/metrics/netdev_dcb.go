@@ -53,14 +53,29 @@ func newDcb() (*tracing.EventTracingAttr, error) {
 const (
 	DCB_CMD_IEEE_GET       = 21
 	DCB_ATTR_IFNAME        = 1
+	DCB_ATTR_IEEE_ETS      = 1
 	DCB_ATTR_IEEE_PFC      = 2
+	DCB_ATTR_IEEE_PEER_ETS = 4
 	DCB_ATTR_IEEE_PEER_PFC = 5
 	DCB_ATTR_IEEE          = 13
 
 	/* IEEE 802.1Qaz std supported values */
 	IEEE_8021QAZ_MAX_TCS = 8
+
+	/* transmission selection algorithms of the traffic classes */
+	IEEE_8021QAZ_TSA_STRICT    = 0
+	IEEE_8021QAZ_TSA_CB_SHAPER = 1
+	IEEE_8021QAZ_TSA_ETS       = 2
+	IEEE_8021QAZ_TSA_VENDOR    = 255
 )
 
+var ieeeTsaNames = map[uint8]string{
+	IEEE_8021QAZ_TSA_STRICT:    "strict",
+	IEEE_8021QAZ_TSA_CB_SHAPER: "cbs",
+	IEEE_8021QAZ_TSA_ETS:       "ets",
+	IEEE_8021QAZ_TSA_VENDOR:    "vendor",
+}
+
 const (
 	sizeofDcbmsg = 4
 )
@@ -88,6 +103,30 @@ type ieeePfc struct {
 	Indications [IEEE_8021QAZ_MAX_TCS]uint64 // count of the received pfc frames
 }
 
+// ieeeEts is the ETS (Enhanced Transmission Selection) configuration,
+// the bandwidth of the traffic classes and the priorities mapped to them.
+type ieeeEts struct {
+	Willing    uint8
+	ETSCap     uint8
+	CBS        uint8
+	TcTxBw     [IEEE_8021QAZ_MAX_TCS]uint8 // percent of the bandwidth of the ets classes
+	TcRxBw     [IEEE_8021QAZ_MAX_TCS]uint8
+	TcTsa      [IEEE_8021QAZ_MAX_TCS]uint8
+	PrioTc     [IEEE_8021QAZ_MAX_TCS]uint8 // traffic class of the priorities
+	TcRecoBw   [IEEE_8021QAZ_MAX_TCS]uint8
+	TcRecoTsa  [IEEE_8021QAZ_MAX_TCS]uint8
+	RecoPrioTc [IEEE_8021QAZ_MAX_TCS]uint8
+}
+
+// ieeeDcb is the local and the peer configuration of a device, the peer
+// ones are learned by the lldp agent of the driver, nil if not supported.
+type ieeeDcb struct {
+	pfc     *ieeePfc
+	peerPfc *ieeePfc
+	ets     *ieeeEts
+	peerEts *ieeeEts
+}
+
 func deserializeIEEEPfc(b []byte) (*ieeePfc, error) {
 	size := int(unsafe.Sizeof(ieeePfc{}))
 	if len(b) < size {
@@ -97,6 +136,15 @@ func deserializeIEEEPfc(b []byte) (*ieeePfc, error) {
 	return (*ieeePfc)(unsafe.Pointer(&b[0])), nil
 }
 
+func deserializeIEEEEts(b []byte) (*ieeeEts, error) {
+	size := int(unsafe.Sizeof(ieeeEts{}))
+	if len(b) < size {
+		return nil, fmt.Errorf("ieee ets attr too short: got %d, want at least %d", len(b), size)
+	}
+
+	return (*ieeeEts)(unsafe.Pointer(&b[0])), nil
+}
+
 func doDcbRequest(ifname string) ([][]byte, error) {
 	req := nl.NewNetlinkRequest(unix.RTM_GETDCB, 0)
 	req.AddData(&dcbMsg{
@@ -108,7 +156,8 @@ func doDcbRequest(ifname string) ([][]byte, error) {
 	return req.Execute(unix.NETLINK_ROUTE, 0)
 }
 
-func parseAttributes(attrs []syscall.NetlinkRouteAttr) (*ieeePfc, error) {
+func parseAttributes(attrs []syscall.NetlinkRouteAttr) (*ieeeDcb, error) {
+	dcb := &ieeeDcb{}
 	for _, a := range attrs {
 		switch a.Attr.Type {
 		case DCB_ATTR_IFNAME:
@@ -120,14 +169,59 @@ func parseAttributes(attrs []syscall.NetlinkRouteAttr) (*ieeePfc, error) {
 			for _, s := range subattrs {
 				switch s.Attr.Type {
 				case DCB_ATTR_IEEE_PFC:
-					return deserializeIEEEPfc(s.Value)
+					dcb.pfc, err = deserializeIEEEPfc(s.Value)
 				case DCB_ATTR_IEEE_PEER_PFC:
+					dcb.peerPfc, err = deserializeIEEEPfc(s.Value)
+				case DCB_ATTR_IEEE_ETS:
+					dcb.ets, err = deserializeIEEEEts(s.Value)
+				case DCB_ATTR_IEEE_PEER_ETS:
+					dcb.peerEts, err = deserializeIEEEEts(s.Value)
+				}
+				if err != nil {
+					return nil, err
 				}
 			}
 		}
 	}
 
-	return nil, fmt.Errorf("no attr")
+	if dcb.pfc == nil && dcb.ets == nil {
+		return nil, fmt.Errorf("no attr")
+	}
+	return dcb, nil
+}
+
+// pfcEnabledData returns whether pfc is enabled on the priorities, by the
+// device or by its peer with the "peer_" prefix.
+func pfcEnabledData(prefix, ifname string, pfc *ieeePfc) []*metric.Data {
+	data := make([]*metric.Data, 0, IEEE_8021QAZ_MAX_TCS)
+	for i := 0; i < IEEE_8021QAZ_MAX_TCS; i++ {
+		data = append(data, metric.NewGaugeData(prefix+"pfc_enabled", float64((pfc.PFCEn>>i)&1),
+			"whether pfc is enabled on the priority",
+			map[string]string{"device": ifname, "prio": strconv.Itoa(i)}))
+	}
+	return data
+}
+
+// etsData returns the ets configuration of the device, or of its peer with
+// the "peer_" prefix.
+func etsData(prefix, ifname string, ets *ieeeEts) []*metric.Data {
+	data := make([]*metric.Data, 0, 3*IEEE_8021QAZ_MAX_TCS)
+	for i := 0; i < IEEE_8021QAZ_MAX_TCS; i++ {
+		tsa, ok := ieeeTsaNames[ets.TcTsa[i]]
+		if !ok {
+			tsa = strconv.Itoa(int(ets.TcTsa[i]))
+		}
+
+		tc := strconv.Itoa(i)
+		data = append(data,
+			metric.NewGaugeData(prefix+"ets_tc_bandwidth_percent", float64(ets.TcTxBw[i]),
+				"percent of the bandwidth of the traffic class",
+				map[string]string{"device": ifname, "tc": tc, "tsa": tsa}),
+			metric.NewGaugeData(prefix+"ets_prio_tc", float64(ets.PrioTc[i]),
+				"traffic class of the priority",
+				map[string]string{"device": ifname, "prio": strconv.Itoa(i)}))
+	}
+	return data
 }
 
 func (dcb *dcbCollector) Update() ([]*metric.Data, error) {
@@ -154,11 +248,27 @@ func (dcb *dcbCollector) Update() ([]*metric.Data, error) {
 				return nil, err
 			}
 
-			pfc, err := parseAttributes(attrs)
+			dcb, err := parseAttributes(attrs)
 			if err != nil {
 				return nil, err
 			}
 
+			if dcb.ets != nil {
+				data = append(data, etsData("", ifname, dcb.ets)...)
+			}
+			if dcb.peerEts != nil {
+				data = append(data, etsData("peer_", ifname, dcb.peerEts)...)
+			}
+			if dcb.peerPfc != nil {
+				data = append(data, pfcEnabledData("peer_", ifname, dcb.peerPfc)...)
+			}
+
+			pfc := dcb.pfc
+			if pfc == nil {
+				continue
+			}
+
+			data = append(data, pfcEnabledData("", ifname, pfc)...)
 			for i, cnt := range pfc.Requests {
 				data = append(data, metric.NewCounterData("pfc_send_total", float64(cnt),
 					"count of the sent pfc frames",
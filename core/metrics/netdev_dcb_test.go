@@ -0,0 +1,96 @@
+// Copyright 2026 The HuaTuo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"testing"
+	"unsafe"
+
+	"github.com/vishvananda/netlink/nl"
+)
+
+func dcbTestAttrs(t *testing.T, ieee *nl.RtAttr) []byte {
+	t.Helper()
+
+	b := nl.NewRtAttr(DCB_ATTR_IFNAME, nl.ZeroTerminated("eth0")).Serialize()
+	return append(b, ieee.Serialize()...)
+}
+
+func TestParseDcbAttributes(t *testing.T) {
+	pfc := ieeePfc{PFCCap: 8, PFCEn: 0x08}
+	pfc.Requests[3] = 10
+	pfc.Indications[3] = 20
+	peerPfc := ieeePfc{PFCEn: 0x18}
+	ets := ieeeEts{
+		TcTxBw: [IEEE_8021QAZ_MAX_TCS]uint8{50, 50},
+		TcTsa:  [IEEE_8021QAZ_MAX_TCS]uint8{IEEE_8021QAZ_TSA_ETS, IEEE_8021QAZ_TSA_ETS, IEEE_8021QAZ_TSA_STRICT},
+		PrioTc: [IEEE_8021QAZ_MAX_TCS]uint8{0, 0, 0, 1},
+	}
+
+	ieee := nl.NewRtAttr(DCB_ATTR_IEEE, nil)
+	ieee.AddRtAttr(DCB_ATTR_IEEE_ETS, (*(*[unsafe.Sizeof(ets)]byte)(unsafe.Pointer(&ets)))[:])
+	ieee.AddRtAttr(DCB_ATTR_IEEE_PFC, (*(*[unsafe.Sizeof(pfc)]byte)(unsafe.Pointer(&pfc)))[:])
+	ieee.AddRtAttr(DCB_ATTR_IEEE_PEER_PFC, (*(*[unsafe.Sizeof(peerPfc)]byte)(unsafe.Pointer(&peerPfc)))[:])
+
+	attrs, err := nl.ParseRouteAttr(dcbTestAttrs(t, ieee))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dcb, err := parseAttributes(attrs)
+	if err != nil {
+		t.Fatalf("parseAttributes() error: %v", err)
+	}
+	if dcb.pfc == nil || dcb.pfc.PFCEn != 0x08 || dcb.pfc.Requests[3] != 10 || dcb.pfc.Indications[3] != 20 {
+		t.Errorf("pfc = %+v", dcb.pfc)
+	}
+	if dcb.peerPfc == nil || dcb.peerPfc.PFCEn != 0x18 {
+		t.Errorf("peer pfc = %+v", dcb.peerPfc)
+	}
+	if dcb.ets == nil || dcb.ets.TcTxBw[1] != 50 || dcb.ets.PrioTc[3] != 1 {
+		t.Errorf("ets = %+v", dcb.ets)
+	}
+	if dcb.peerEts != nil {
+		t.Errorf("peer ets = %+v, want nil", dcb.peerEts)
+	}
+
+	data := etsData("peer_", "eth0", dcb.ets)
+	if len(data) != 2*IEEE_8021QAZ_MAX_TCS {
+		t.Errorf("etsData() returned %d metrics", len(data))
+	}
+	if data := pfcEnabledData("", "eth0", dcb.pfc); data[3].Value != 1 || data[4].Value != 0 {
+		t.Errorf("pfcEnabledData() = %v, %v", data[3].Value, data[4].Value)
+	}
+
+	// a device without pfc and ets.
+	attrs, err = nl.ParseRouteAttr(dcbTestAttrs(t, nl.NewRtAttr(DCB_ATTR_IEEE, nil)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := parseAttributes(attrs); err == nil {
+		t.Error("parseAttributes() without pfc and ets, error = nil")
+	}
+
+	// a truncated attribute.
+	ieee = nl.NewRtAttr(DCB_ATTR_IEEE, nil)
+	ieee.AddRtAttr(DCB_ATTR_IEEE_PEER_ETS, []byte{1, 2, 3})
+	attrs, err = nl.ParseRouteAttr(dcbTestAttrs(t, ieee))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := parseAttributes(attrs); err == nil {
+		t.Error("parseAttributes() of a truncated ets, error = nil")
+	}
+}
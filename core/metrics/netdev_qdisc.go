@@ -19,12 +19,16 @@ package collector
 
 import (
 	"fmt"
+	"sync"
+	"time"
 
+	"huatuo-bamai/internal/log"
 	"huatuo-bamai/internal/matcher"
 	"huatuo-bamai/pkg/metric"
 	"huatuo-bamai/pkg/tracing"
 
 	"github.com/ema/qdisc"
+	"github.com/vishvananda/netlink"
 )
 
 type qdiscStats struct {
@@ -41,19 +45,108 @@ type qdiscStats struct {
 
 const tcHMajMask = 0xFFFF0000
 
-type qdiscCollector struct{}
+// qdiscDropState is the drops of the root qdisc of a device at the last
+// collection.
+type qdiscDropState struct {
+	drops      uint64
+	lastUpdate time.Time
+	// rate is the drops per second since the collection before.
+	rate      float64
+	lastEvent time.Time
+}
+
+// qdiscDropDelta is the drops of the root qdisc of a device between two
+// collections.
+type qdiscDropDelta struct {
+	drops    uint64
+	rate     float64
+	prevRate float64
+	interval time.Duration
+}
+
+// QdiscSnapshot is a qdisc of the device when its drops accelerate.
+type QdiscSnapshot struct {
+	Handle     string `json:"handle"`
+	Parent     string `json:"parent"`
+	Kind       string `json:"kind"`
+	Drops      uint32 `json:"drops"`
+	Overlimits uint32 `json:"overlimits"`
+	Requeues   uint32 `json:"requeues"`
+	Qlen       uint32 `json:"qlen"`
+	Backlog    uint32 `json:"backlog"`
+}
+
+// QdiscTracingData is stored when the drops of the root qdisc of a device
+// reach EventDropRate per second and at least doubled since the last
+// collection. The classes show the token bucket of the htb ones.
+type QdiscTracingData struct {
+	Device       string               `json:"device"`
+	Kind         string               `json:"kind"`
+	Drops        uint64               `json:"drops"`
+	DropRate     float64              `json:"drop_rate"`
+	PrevDropRate float64              `json:"prev_drop_rate"`
+	Threshold    int                  `json:"threshold"`
+	Interval     int64                `json:"interval_ms"`
+	Qdiscs       []QdiscSnapshot      `json:"qdiscs"`
+	Classes      []QdiscClassSnapshot `json:"classes,omitempty"`
+}
+
+type qdiscCollector struct {
+	mutex sync.Mutex
+	// drops of the root qdisc per device.
+	drops map[string]*qdiscDropState
+}
 
 func init() {
 	tracing.RegisterEventTracing("netdev_qdisc", newQdiscCollector)
+	tracing.RegisterSchema[QdiscTracingData]("netdev_qdisc", "netdev_qdisc", 1)
 }
 
 func newQdiscCollector() (*tracing.EventTracingAttr, error) {
 	return &tracing.EventTracingAttr{
-		TracingData: &qdiscCollector{},
-		Flag:        tracing.FlagMetric,
+		TracingData: &qdiscCollector{
+			drops: make(map[string]*qdiscDropState),
+		},
+		Flag: tracing.FlagMetric,
 	}, nil
 }
 
+// update caches the drops of the root qdisc and returns the drops since the
+// last collection, nil on the first one or when the qdisc was replaced.
+func (s *qdiscDropState) update(drops uint64, now time.Time) *qdiscDropDelta {
+	prev := *s
+
+	s.drops, s.lastUpdate, s.rate = drops, now, 0
+	if prev.lastUpdate.IsZero() || drops < prev.drops || !now.After(prev.lastUpdate) {
+		return nil
+	}
+
+	interval := now.Sub(prev.lastUpdate)
+	s.rate = float64(drops-prev.drops) / interval.Seconds()
+	return &qdiscDropDelta{
+		drops:    drops - prev.drops,
+		rate:     s.rate,
+		prevRate: prev.rate,
+		interval: interval,
+	}
+}
+
+// shouldReport tells whether the drops accelerate: at least EventDropRate
+// per second and twice the rate of the collection before, at most one event
+// per EventInterval of a device.
+func (s *qdiscDropState) shouldReport(delta *qdiscDropDelta, now time.Time) bool {
+	threshold := cfg.Qdisc.EventDropRate
+	if threshold <= 0 || delta.rate < float64(threshold) || delta.rate < 2*delta.prevRate {
+		return false
+	}
+	if now.Sub(s.lastEvent) < time.Duration(cfg.Qdisc.EventInterval)*time.Second {
+		return false
+	}
+
+	s.lastEvent = now
+	return true
+}
+
 // sum of same level(parent major) for a device, example:
 // <device0> (1+2, 3)
 // 1: qidsc <kind> handle0 parent0
@@ -76,11 +169,16 @@ func (c *qdiscCollector) Update() ([]*metric.Data, error) {
 	}
 
 	allQdiscMap := make(map[string]map[uint32]*qdiscStats)
+	rootQdiscs := make(map[string]qdisc.QdiscInfo)
 	for _, q := range allQdisc {
 		if !f.Match(q.IfaceName) || q.Kind == "noqueue" {
 			continue
 		}
 
+		if q.Parent == netlink.HANDLE_ROOT {
+			rootQdiscs[q.IfaceName] = q
+		}
+
 		parentMaj := (q.Parent & tcHMajMask) >> 16
 		if _, ok := allQdiscMap[q.IfaceName]; !ok {
 			allQdiscMap[q.IfaceName] = make(map[uint32]*qdiscStats)
@@ -126,5 +224,84 @@ func (c *qdiscCollector) Update() ([]*metric.Data, error) {
 		}
 	}
 
-	return metrics, nil
+	c.checkDrops(rootQdiscs, allQdisc)
+
+	classData, err := qdiscClassData(cfg.Qdisc.ClassDeviceList)
+	if err != nil {
+		return nil, err
+	}
+	return append(metrics, classData...), nil
+}
+
+// checkDrops saves an event of the devices whose root qdisc drops
+// accelerate, and forgets the devices gone.
+func (c *qdiscCollector) checkDrops(rootQdiscs map[string]qdisc.QdiscInfo, allQdisc []qdisc.QdiscInfo) {
+	now := time.Now()
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	for device := range c.drops {
+		if _, ok := rootQdiscs[device]; !ok {
+			delete(c.drops, device)
+		}
+	}
+
+	for device, root := range rootQdiscs {
+		state, ok := c.drops[device]
+		if !ok {
+			state = &qdiscDropState{}
+			c.drops[device] = state
+		}
+
+		delta := state.update(uint64(root.Drops), now)
+		if delta == nil || !state.shouldReport(delta, now) {
+			continue
+		}
+
+		c.save(device, root.Kind, delta, allQdisc, now)
+	}
+}
+
+func (c *qdiscCollector) save(device, kind string, delta *qdiscDropDelta, allQdisc []qdisc.QdiscInfo, now time.Time) {
+	tracerData := &QdiscTracingData{
+		Device:       device,
+		Kind:         kind,
+		Drops:        delta.drops,
+		DropRate:     delta.rate,
+		PrevDropRate: delta.prevRate,
+		Threshold:    cfg.Qdisc.EventDropRate,
+		Interval:     delta.interval.Milliseconds(),
+	}
+	for _, q := range allQdisc {
+		if q.IfaceName != device {
+			continue
+		}
+		tracerData.Qdiscs = append(tracerData.Qdiscs, QdiscSnapshot{
+			Handle:     netlink.HandleStr(q.Handle),
+			Parent:     netlink.HandleStr(q.Parent),
+			Kind:       q.Kind,
+			Drops:      q.Drops,
+			Overlimits: q.Overlimits,
+			Requeues:   q.Requeues,
+			Qlen:       q.Qlen,
+			Backlog:    q.Backlog,
+		})
+	}
+
+	classes, err := qdiscClasses(device)
+	if err != nil {
+		log.Infof("failed to list the classes of %s: %v", device, err)
+	}
+	for _, class := range classes {
+		tracerData.Classes = append(tracerData.Classes, newQdiscClassSnapshot(class))
+	}
+
+	if err := tracing.Save(&tracing.WriteRequest{
+		TracerName: "netdev_qdisc",
+		TracerTime: now,
+		TracerData: tracerData,
+	}); err != nil {
+		log.Warnf("failed to save tracing data: %v", err)
+	}
 }
@@ -0,0 +1,145 @@
+// Copyright 2026 The HuaTuo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"errors"
+	"fmt"
+
+	"huatuo-bamai/internal/matcher"
+	"huatuo-bamai/internal/procfs/sysfs"
+	"huatuo-bamai/pkg/metric"
+
+	"github.com/vishvananda/netlink"
+)
+
+// QdiscClassSnapshot is a class of the device when its drops accelerate.
+// RateLimit and Ceil are the token bucket of an htb class, in bytes per
+// second.
+type QdiscClassSnapshot struct {
+	Class      string `json:"class"`
+	Parent     string `json:"parent"`
+	Kind       string `json:"kind"`
+	Bytes      uint64 `json:"bytes"`
+	Packets    uint32 `json:"packets"`
+	Drops      uint32 `json:"drops"`
+	Overlimits uint32 `json:"overlimits"`
+	Backlog    uint32 `json:"backlog"`
+	Rate       uint32 `json:"rate_bytes"`
+	RateLimit  uint64 `json:"rate_limit_bytes,omitempty"`
+	Ceil       uint64 `json:"ceil_bytes,omitempty"`
+}
+
+func newQdiscClassSnapshot(class netlink.Class) QdiscClassSnapshot {
+	attrs := class.Attrs()
+	stats := qdiscClassStatistics(class)
+	snapshot := QdiscClassSnapshot{
+		Class:      netlink.HandleStr(attrs.Handle),
+		Parent:     netlink.HandleStr(attrs.Parent),
+		Kind:       class.Type(),
+		Bytes:      stats.Basic.Bytes,
+		Packets:    stats.Basic.Packets,
+		Drops:      stats.Queue.Drops,
+		Overlimits: stats.Queue.Overlimits,
+		Backlog:    stats.Queue.Backlog,
+		Rate:       stats.RateEst.Bps,
+	}
+	if htb, ok := class.(*netlink.HtbClass); ok {
+		snapshot.RateLimit, snapshot.Ceil = htb.Rate, htb.Ceil
+	}
+	return snapshot
+}
+
+// qdiscClassStatistics returns the statistics of the class, zero the ones
+// not reported.
+func qdiscClassStatistics(class netlink.Class) *netlink.ClassStatistics {
+	stats := netlink.NewClassStatistics()
+	if s := class.Attrs().Statistics; s != nil {
+		if s.Basic != nil {
+			stats.Basic = s.Basic
+		}
+		if s.Queue != nil {
+			stats.Queue = s.Queue
+		}
+		if s.RateEst != nil {
+			stats.RateEst = s.RateEst
+		}
+	}
+	return stats
+}
+
+func qdiscClasses(device string) ([]netlink.Class, error) {
+	link, err := netlink.LinkByName(device)
+	if err != nil {
+		return nil, err
+	}
+
+	return netlink.ClassList(link, netlink.HANDLE_NONE)
+}
+
+// qdiscClassData returns the classes of the devices of the list. The rate
+// is of the rate estimator of the class, 0 without one.
+func qdiscClassData(deviceList []string) ([]*metric.Data, error) {
+	if len(deviceList) == 0 {
+		return nil, nil
+	}
+
+	deviceMatcher, err := matcher.NewListMatcher(deviceList)
+	if err != nil {
+		return nil, fmt.Errorf("qdisc class device list: %w", err)
+	}
+
+	ifaces, err := sysfs.DefaultNetClassDevices()
+	if err != nil {
+		return nil, err
+	}
+
+	var data []*metric.Data
+	for _, device := range deviceMatcher.Filter(ifaces) {
+		classes, err := qdiscClasses(device)
+		if err != nil {
+			var notFound netlink.LinkNotFoundError
+			if errors.As(err, &notFound) {
+				continue
+			}
+			return nil, err
+		}
+
+		for _, class := range classes {
+			data = append(data, qdiscClassMetrics(device, class)...)
+		}
+	}
+	return data, nil
+}
+
+func qdiscClassMetrics(device string, class netlink.Class) []*metric.Data {
+	s := newQdiscClassSnapshot(class)
+	tags := map[string]string{"device": device, "class": s.Class, "parent": s.Parent, "kind": s.Kind}
+
+	data := []*metric.Data{
+		metric.NewCounterData("class_bytes_total", float64(s.Bytes), "number of bytes sent by the class.", tags),
+		metric.NewCounterData("class_packets_total", float64(s.Packets), "number of packets sent by the class.", tags),
+		metric.NewCounterData("class_drops_total", float64(s.Drops), "number of packet drops of the class.", tags),
+		metric.NewCounterData("class_overlimits_total", float64(s.Overlimits), "number of packet overlimits of the class.", tags),
+		metric.NewGaugeData("class_backlog", float64(s.Backlog), "number of bytes currently in queue of the class.", tags),
+		metric.NewGaugeData("class_rate_bytes", float64(s.Rate), "bytes per second sent by the class, by its rate estimator.", tags),
+	}
+	if _, ok := class.(*netlink.HtbClass); ok {
+		data = append(data,
+			metric.NewGaugeData("class_rate_limit_bytes", float64(s.RateLimit), "guaranteed bytes per second of the htb class.", tags),
+			metric.NewGaugeData("class_ceil_bytes", float64(s.Ceil), "maximum bytes per second of the htb class.", tags))
+	}
+	return data
+}
@@ -0,0 +1,110 @@
+// Copyright 2026 The HuaTuo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"testing"
+	"time"
+
+	"github.com/vishvananda/netlink"
+)
+
+func TestQdiscDropState(t *testing.T) {
+	orig := cfg
+	t.Cleanup(func() { cfg = orig })
+	cfg = &Config{}
+	cfg.Qdisc.EventDropRate = 100
+	cfg.Qdisc.EventInterval = 300
+
+	start := time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC)
+	var state qdiscDropState
+
+	if delta := state.update(1000, start); delta != nil {
+		t.Fatalf("first update() = %+v, want nil", delta)
+	}
+
+	// 50/s, below the threshold.
+	now := start.Add(10 * time.Second)
+	delta := state.update(1500, now)
+	if delta == nil || delta.drops != 500 || delta.rate != 50 || delta.prevRate != 0 {
+		t.Fatalf("update() = %+v", delta)
+	}
+	if state.shouldReport(delta, now) {
+		t.Error("shouldReport() = true below the threshold")
+	}
+
+	// 500/s, ten times the rate before.
+	now = now.Add(10 * time.Second)
+	delta = state.update(6500, now)
+	if delta == nil || delta.rate != 500 || delta.prevRate != 50 {
+		t.Fatalf("update() = %+v", delta)
+	}
+	if !state.shouldReport(delta, now) {
+		t.Error("shouldReport() = false for accelerating drops")
+	}
+
+	// 2000/s, accelerating again within the event interval.
+	now = now.Add(10 * time.Second)
+	delta = state.update(26500, now)
+	if state.shouldReport(delta, now) {
+		t.Error("shouldReport() = true within the event interval")
+	}
+
+	// steady drops after the event interval do not accelerate.
+	now = now.Add(10 * time.Minute)
+	delta = state.update(26500+1200000, now)
+	if delta.rate != 2000 || state.shouldReport(delta, now) {
+		t.Errorf("update() = %+v, steady drops reported", delta)
+	}
+
+	// the qdisc was replaced.
+	if delta := state.update(10, now.Add(10*time.Second)); delta != nil {
+		t.Errorf("update() after a reset = %+v, want nil", delta)
+	}
+}
+
+func TestQdiscClassSnapshot(t *testing.T) {
+	htb := netlink.NewHtbClass(netlink.ClassAttrs{
+		Handle: netlink.MakeHandle(1, 0x10),
+		Parent: netlink.MakeHandle(1, 1),
+	}, netlink.HtbClassAttrs{Rate: 8000000, Ceil: 16000000})
+	htb.Statistics = netlink.NewClassStatistics()
+	htb.Statistics.Basic.Bytes = 4096
+	htb.Statistics.Queue.Drops = 7
+
+	s := newQdiscClassSnapshot(htb)
+	want := QdiscClassSnapshot{
+		Class: "1:10", Parent: "1:1", Kind: "htb",
+		Bytes: 4096, Drops: 7, RateLimit: 1000000, Ceil: 2000000,
+	}
+	if s != want {
+		t.Errorf("newQdiscClassSnapshot() = %+v, want %+v", s, want)
+	}
+	if data := qdiscClassMetrics("eth0", htb); len(data) != 8 {
+		t.Errorf("qdiscClassMetrics() returned %d metrics, want 8", len(data))
+	}
+
+	// no statistics reported.
+	generic := &netlink.GenericClass{
+		ClassAttrs: netlink.ClassAttrs{Handle: netlink.MakeHandle(0, 1), Parent: netlink.HANDLE_ROOT},
+		ClassType:  "mq",
+	}
+	if s := newQdiscClassSnapshot(generic); s.Bytes != 0 || s.Kind != "mq" || s.Parent != "root" {
+		t.Errorf("newQdiscClassSnapshot() = %+v", s)
+	}
+	if data := qdiscClassMetrics("eth0", generic); len(data) != 6 {
+		t.Errorf("qdiscClassMetrics() returned %d metrics, want 6", len(data))
+	}
+}
@@ -0,0 +1,91 @@
+// Copyright 2026 The HuaTuo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"strconv"
+
+	"huatuo-bamai/pkg/metric"
+	"huatuo-bamai/pkg/tracing"
+
+	"github.com/vishvananda/netlink"
+	"github.com/vishvananda/netlink/nl"
+)
+
+// the link state of a vf set on its pf, auto follows the pf.
+var vfLinkStates = map[uint32]string{
+	nl.IFLA_VF_LINK_STATE_AUTO:    "auto",
+	nl.IFLA_VF_LINK_STATE_ENABLE:  "enable",
+	nl.IFLA_VF_LINK_STATE_DISABLE: "disable",
+}
+
+type sriovCollector struct{}
+
+func init() {
+	tracing.RegisterEventTracing("netdev_sriov", newSriov)
+}
+
+func newSriov() (*tracing.EventTracingAttr, error) {
+	return &tracing.EventTracingAttr{
+		TracingData: &sriovCollector{},
+		Flag:        tracing.FlagMetric,
+	}, nil
+}
+
+func (c *sriovCollector) Update() ([]*metric.Data, error) {
+	links, err := netlink.LinkList()
+	if err != nil {
+		return nil, err
+	}
+
+	return sriovData(links), nil
+}
+
+// sriovData returns the vfs of the pfs as reported by the pf, the vf
+// netdevs may be moved into the containers or passed through to the vms.
+func sriovData(links []netlink.Link) []*metric.Data {
+	data := []*metric.Data{}
+	for _, link := range links {
+		attrs := link.Attrs()
+		for _, vf := range attrs.Vfs {
+			state, ok := vfLinkStates[vf.LinkState]
+			if !ok {
+				state = strconv.FormatUint(uint64(vf.LinkState), 10)
+			}
+
+			label := map[string]string{"device": attrs.Name, "vf": strconv.Itoa(vf.ID)}
+			data = append(data,
+				metric.NewGaugeData("vf_info", 1, "the mac, vlan and link state of the vf",
+					map[string]string{
+						"device":     attrs.Name,
+						"vf":         strconv.Itoa(vf.ID),
+						"mac":        vf.Mac.String(),
+						"vlan":       strconv.Itoa(vf.Vlan),
+						"link_state": state,
+					}),
+				metric.NewGaugeData("vf_spoofcheck", boolFloat(vf.Spoofchk), "whether spoof checking is enabled on the vf", label),
+				metric.NewGaugeData("vf_trust", boolFloat(vf.Trust != 0), "whether the vf is trusted", label),
+				metric.NewCounterData("vf_rx_packets_total", float64(vf.RxPackets), "packets received by the vf", label),
+				metric.NewCounterData("vf_tx_packets_total", float64(vf.TxPackets), "packets sent by the vf", label),
+				metric.NewCounterData("vf_rx_bytes_total", float64(vf.RxBytes), "bytes received by the vf", label),
+				metric.NewCounterData("vf_tx_bytes_total", float64(vf.TxBytes), "bytes sent by the vf", label),
+				metric.NewCounterData("vf_rx_dropped_total", float64(vf.RxDropped), "packets received and dropped by the vf", label),
+				metric.NewCounterData("vf_tx_dropped_total", float64(vf.TxDropped), "packets to send dropped by the vf", label),
+				metric.NewCounterData("vf_rx_multicast_total", float64(vf.Multicast), "multicast packets received by the vf", label),
+				metric.NewCounterData("vf_rx_broadcast_total", float64(vf.Broadcast), "broadcast packets received by the vf", label))
+		}
+	}
+	return data
+}
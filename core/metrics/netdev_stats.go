@@ -65,13 +65,13 @@ func (c *netdevCollector) Update() ([]*metric.Data, error) {
 	// append host into containers
 	containers[""] = nil
 
-	var metrics []*metric.Data
-	for _, container := range containers {
+	metrics, err := scanContainers("netdev", containers, func(container *pod.Container) ([]*metric.Data, error) {
 		devStats, err := c.getStats(container)
 		if err != nil {
 			return nil, fmt.Errorf("couldn't get netdev statistic for container %v: %w", container, err)
 		}
 
+		var metrics []*metric.Data
 		for dev, stats := range devStats {
 			for key, val := range stats {
 				tags := map[string]string{"device": dev}
@@ -84,6 +84,10 @@ func (c *netdevCollector) Update() ([]*metric.Data, error) {
 				}
 			}
 		}
+		return metrics, nil
+	})
+	if err != nil {
+		return nil, err
 	}
 
 	return metrics, nil
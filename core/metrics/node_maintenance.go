@@ -0,0 +1,263 @@
+// Copyright 2026 The HuaTuo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"encoding/binary"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"huatuo-bamai/internal/log"
+	"huatuo-bamai/internal/procfs"
+	"huatuo-bamai/pkg/metric"
+	"huatuo-bamai/pkg/tracing"
+)
+
+func init() {
+	tracing.RegisterEventTracing("node_maintenance", newNodeMaintenance)
+}
+
+const (
+	// intelUcodeHeaderSize is the header of a microcode update in the
+	// intel-ucode files, a 0 total size means the legacy 2048 bytes.
+	intelUcodeHeaderSize     = 48
+	intelUcodeLegacyDataSize = 2048
+)
+
+// nodeMaintenance is the state of the node the maintenance planning needs.
+// The host files are read through /proc/1/root every Interval.
+type nodeMaintenance struct {
+	refreshTime time.Time
+
+	rebootRequired   bool
+	rebootPackages   int
+	microcode        *microcodeState
+	crashes          int
+	crashesSupported bool
+}
+
+// microcodeState is the microcode revision cpu0 runs, and the newest one in
+// the firmware files the next boot or late load applies.
+type microcodeState struct {
+	Running   uint32
+	Available uint32
+}
+
+func newNodeMaintenance() (*tracing.EventTracingAttr, error) {
+	return &tracing.EventTracingAttr{
+		TracingData: &nodeMaintenance{},
+		Flag:        tracing.FlagMetric,
+	}, nil
+}
+
+func (n *nodeMaintenance) Update() ([]*metric.Data, error) {
+	interval := time.Duration(cfg.NodeMaintenance.Interval) * time.Second
+	if n.refreshTime.IsZero() || time.Since(n.refreshTime) >= interval {
+		n.refresh()
+		n.refreshTime = time.Now()
+	}
+
+	data := []*metric.Data{
+		metric.NewGaugeData("reboot_required", boolFloat(n.rebootRequired), "Whether /var/run/reboot-required exists, 1 means a reboot is required.", nil),
+		metric.NewGaugeData("reboot_required_packages", float64(n.rebootPackages), "Packages listed in /var/run/reboot-required.pkgs.", nil),
+	}
+
+	if n.microcode != nil {
+		data = append(data, metric.NewGaugeData("microcode_pending", boolFloat(n.microcode.Available > n.microcode.Running),
+			"Whether a newer microcode is installed than cpu0 runs, 1 means pending.", map[string]string{
+				"running":   fmt.Sprintf("0x%x", n.microcode.Running),
+				"available": fmt.Sprintf("0x%x", n.microcode.Available),
+			}))
+	}
+
+	if n.crashesSupported {
+		data = append(data, metric.NewGaugeData("kernel_crashes", float64(n.crashes), "Kernel crash dumps kept in the crash directory.", nil))
+	}
+
+	uptime, err := nodeUptime()
+	if err != nil {
+		return data, err
+	}
+	data = append(data, metric.NewGaugeData("uptime_seconds", uptime.Seconds(), "Time since the node booted.", nil))
+
+	if days := cfg.NodeMaintenance.MaxUptimeDays; days > 0 {
+		exceeded := uptime > time.Duration(days)*24*time.Hour
+		data = append(data, metric.NewGaugeData("uptime_exceeded", boolFloat(exceeded), "Whether the uptime exceeds the fleet policy, 1 means exceeded.", nil))
+	}
+
+	return data, nil
+}
+
+func (n *nodeMaintenance) refresh() {
+	root := procfs.Path("1/root")
+
+	_, err := os.Stat(filepath.Join(root, "var/run/reboot-required"))
+	n.rebootRequired = err == nil
+	n.rebootPackages, _ = countNonEmptyLines(filepath.Join(root, "var/run/reboot-required.pkgs"))
+
+	microcode, err := intelMicrocodeState(filepath.Join(root, "lib/firmware/intel-ucode"))
+	if err != nil {
+		log.Debugf("node maintenance microcode: %v", err)
+	}
+	n.microcode = microcode
+
+	n.crashes, err = countKernelCrashes(filepath.Join(root, cfg.NodeMaintenance.CrashDir))
+	n.crashesSupported = err == nil
+	if err != nil {
+		log.Debugf("node maintenance crash dumps: %v", err)
+	}
+}
+
+func boolFloat(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+func countNonEmptyLines(path string) (int, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+	for _, line := range strings.Split(string(raw), "\n") {
+		if strings.TrimSpace(line) != "" {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// countKernelCrashes counts the dumps kdump saved, one directory with a
+// vmcore per crash. The crash reports of the applications, e.g. *.crash of
+// apport, are not kernel crashes.
+func countKernelCrashes(dir string) (int, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		for _, name := range []string{"vmcore", "vmcore-dmesg.txt", "vmcore.flat", "dmesg"} {
+			if _, err := os.Stat(filepath.Join(dir, entry.Name(), name)); err == nil {
+				count++
+				break
+			}
+		}
+	}
+	return count, nil
+}
+
+func nodeUptime() (time.Duration, error) {
+	raw, err := os.ReadFile(procfs.Path("uptime"))
+	if err != nil {
+		return 0, err
+	}
+
+	fields := strings.Fields(string(raw))
+	if len(fields) == 0 {
+		return 0, fmt.Errorf("invalid uptime %q", raw)
+	}
+	seconds, err := strconv.ParseFloat(fields[0], 64)
+	if err != nil {
+		return 0, err
+	}
+	return time.Duration(seconds * float64(time.Second)), nil
+}
+
+// intelMicrocodeState compares the microcode of cpu0 with the updates of
+// its signature in the intel-ucode files. It is nil on other vendors.
+func intelMicrocodeState(ucodeDir string) (*microcodeState, error) {
+	fs, err := procfs.NewDefaultFS()
+	if err != nil {
+		return nil, err
+	}
+	cpus, err := fs.CPUInfo()
+	if err != nil {
+		return nil, err
+	}
+	if len(cpus) == 0 || cpus[0].VendorID != "GenuineIntel" {
+		return nil, nil
+	}
+
+	cpu := cpus[0]
+	running, err := strconv.ParseUint(strings.TrimPrefix(cpu.Microcode, "0x"), 16, 32)
+	if err != nil {
+		return nil, fmt.Errorf("invalid microcode %q: %w", cpu.Microcode, err)
+	}
+
+	family, err1 := strconv.ParseUint(cpu.CPUFamily, 10, 32)
+	model, err2 := strconv.ParseUint(cpu.Model, 10, 32)
+	stepping, err3 := strconv.ParseUint(cpu.Stepping, 10, 32)
+	if err1 != nil || err2 != nil || err3 != nil {
+		return nil, fmt.Errorf("invalid cpu family %q model %q stepping %q", cpu.CPUFamily, cpu.Model, cpu.Stepping)
+	}
+
+	raw, err := os.ReadFile(filepath.Join(ucodeDir, fmt.Sprintf("%02x-%02x-%02x", family, model, stepping)))
+	if err != nil {
+		if os.IsNotExist(err) {
+			// no update for the cpu, the running one is the newest.
+			return &microcodeState{Running: uint32(running), Available: uint32(running)}, nil
+		}
+		return nil, err
+	}
+
+	available := intelMicrocodeRevision(raw, intelCPUSignature(uint32(family), uint32(model), uint32(stepping)))
+	return &microcodeState{Running: uint32(running), Available: max(available, uint32(running))}, nil
+}
+
+// intelCPUSignature returns the CPUID(1).EAX of the family, model and
+// stepping /proc/cpuinfo displays.
+func intelCPUSignature(family, model, stepping uint32) uint32 {
+	baseFamily, extFamily := family, uint32(0)
+	if family >= 0xf {
+		baseFamily, extFamily = 0xf, family-0xf
+	}
+	return extFamily<<20 | (model>>4)<<16 | baseFamily<<8 | (model&0xf)<<4 | stepping
+}
+
+// intelMicrocodeRevision returns the newest revision of the updates of the
+// signature in an intel-ucode file, 0 when there is none.
+func intelMicrocodeRevision(raw []byte, signature uint32) uint32 {
+	var newest uint32
+	for len(raw) >= intelUcodeHeaderSize {
+		revision := binary.LittleEndian.Uint32(raw[4:8])
+		sig := binary.LittleEndian.Uint32(raw[12:16])
+		totalSize := binary.LittleEndian.Uint32(raw[32:36])
+		if totalSize == 0 {
+			totalSize = intelUcodeHeaderSize + intelUcodeLegacyDataSize
+		}
+
+		if sig == signature && revision > newest {
+			newest = revision
+		}
+
+		if totalSize < intelUcodeHeaderSize || int(totalSize) > len(raw) {
+			break
+		}
+		raw = raw[totalSize:]
+	}
+	return newest
+}
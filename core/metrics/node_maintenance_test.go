@@ -0,0 +1,91 @@
+// Copyright 2026 The HuaTuo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"encoding/binary"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"huatuo-bamai/internal/procfs"
+)
+
+func TestIntelCPUSignature(t *testing.T) {
+	// Skylake-SP and a family 15 Pentium 4.
+	if got := intelCPUSignature(6, 85, 7); got != 0x50657 {
+		t.Errorf("intelCPUSignature(6, 85, 7) = %#x, want 0x50657", got)
+	}
+	if got := intelCPUSignature(15, 6, 5); got != 0xf65 {
+		t.Errorf("intelCPUSignature(15, 6, 5) = %#x, want 0xf65", got)
+	}
+}
+
+func intelUcodeUpdate(revision, signature, totalSize uint32) []byte {
+	size := totalSize
+	if size == 0 {
+		size = intelUcodeHeaderSize + intelUcodeLegacyDataSize
+	}
+	update := make([]byte, size)
+	binary.LittleEndian.PutUint32(update[0:4], 1)
+	binary.LittleEndian.PutUint32(update[4:8], revision)
+	binary.LittleEndian.PutUint32(update[12:16], signature)
+	binary.LittleEndian.PutUint32(update[32:36], totalSize)
+	return update
+}
+
+func TestIntelMicrocodeRevision(t *testing.T) {
+	var raw []byte
+	raw = append(raw, intelUcodeUpdate(0x2006e05, 0x50657, 1024)...)
+	raw = append(raw, intelUcodeUpdate(0x3000010, 0x50656, 0)...)
+	raw = append(raw, intelUcodeUpdate(0x2007006, 0x50657, 512)...)
+
+	if got := intelMicrocodeRevision(raw, 0x50657); got != 0x2007006 {
+		t.Errorf("intelMicrocodeRevision() = %#x, want 0x2007006", got)
+	}
+	if got := intelMicrocodeRevision(raw, 0x906ea); got != 0 {
+		t.Errorf("intelMicrocodeRevision() other cpu = %#x, want 0", got)
+	}
+	// a truncated file keeps the updates before it.
+	if got := intelMicrocodeRevision(raw[:1024+100], 0x50657); got != 0x2006e05 {
+		t.Errorf("intelMicrocodeRevision() truncated = %#x, want 0x2006e05", got)
+	}
+}
+
+func TestCountKernelCrashes(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFile(t, filepath.Join(dir, "127.0.0.1-2026-01-02-03:04:05/vmcore"), "")
+	writeTestFile(t, filepath.Join(dir, "202601020304/dmesg"), "")
+	writeTestFile(t, filepath.Join(dir, "empty/readme"), "")
+	writeTestFile(t, filepath.Join(dir, "_usr_bin_python3.0.crash"), "")
+
+	if got, err := countKernelCrashes(dir); got != 2 || err != nil {
+		t.Errorf("countKernelCrashes() = %d, %v, want 2", got, err)
+	}
+	if _, err := countKernelCrashes(filepath.Join(dir, "missing")); err == nil {
+		t.Errorf("countKernelCrashes() missing dir error = nil")
+	}
+}
+
+func TestNodeUptime(t *testing.T) {
+	root := t.TempDir()
+	procfs.RootPrefix(root)
+	t.Cleanup(func() { procfs.RootPrefix("/") })
+
+	writeTestFile(t, filepath.Join(root, "proc/uptime"), "86400.50 170000.00\n")
+	if got, err := nodeUptime(); got != 86400*time.Second+500*time.Millisecond || err != nil {
+		t.Errorf("nodeUptime() = %v, %v", got, err)
+	}
+}
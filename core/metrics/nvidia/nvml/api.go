@@ -0,0 +1,34 @@
+// Copyright 2026 The HuaTuo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nvml
+
+// API provides the public interface for NVIDIA GPU monitoring
+var (
+	GetDriverVersion      = libnvml.getDriverVersion
+	GetCudaVersion        = libnvml.getCudaVersion
+	ListDevices           = libnvml.listDevices
+	GetInfo               = libnvml.getInfo
+	GetUtilization        = libnvml.getUtilization
+	GetMemory             = libnvml.getMemory
+	GetPower              = libnvml.getPower
+	GetTemperature        = libnvml.getTemperature
+	GetEccErrors          = libnvml.getEccErrors
+	GetPcieThroughput     = libnvml.getPcieThroughput
+	ListNvLinkThroughputs = libnvml.listNvLinkThroughputs
+	ListNvLinkRemotes     = libnvml.listNvLinkRemotes
+	ListProcesses         = libnvml.listProcesses
+	ListProcessSamples    = libnvml.listProcessSamples
+	WatchXidEvents        = libnvml.watchXidEvents
+)
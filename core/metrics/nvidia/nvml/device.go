@@ -0,0 +1,360 @@
+// Copyright 2026 The HuaTuo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nvml
+
+import (
+	"context"
+	"fmt"
+)
+
+// getDriverVersion returns the version of the kernel driver.
+func (l *library) getDriverVersion() (string, error) {
+	buf := make([]byte, driverVersionBufferSize)
+	if err := checkReturnCode("nvmlSystemGetDriverVersion", nvmlSystemGetDriverVersion(&buf[0], uint32(len(buf)))); err != nil {
+		return "", err
+	}
+	return cString(buf), nil
+}
+
+// getCudaVersion returns the CUDA version supported by the driver, e.g. 12.4.
+func (l *library) getCudaVersion() (string, error) {
+	var version int32
+	if err := checkReturnCode("nvmlSystemGetCudaDriverVersion", nvmlSystemGetCudaDriverVersion(&version)); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%d.%d", version/1000, version%1000/10), nil
+}
+
+// listDevices returns the handles of the GPUs, by index.
+func (l *library) listDevices() ([]Device, error) {
+	var count uint32
+	if err := checkReturnCode("nvmlDeviceGetCount", nvmlDeviceGetCount(&count)); err != nil {
+		return nil, err
+	}
+
+	devices := make([]Device, count)
+	for i := uint32(0); i < count; i++ {
+		if err := checkReturnCode("nvmlDeviceGetHandleByIndex", nvmlDeviceGetHandleByIndex(i, &devices[i])); err != nil {
+			return nil, err
+		}
+	}
+	return devices, nil
+}
+
+// getInfo returns the identity of the GPU, the vbios version is left empty
+// when not supported.
+func (l *library) getInfo(ctx context.Context, dev Device) (Info, error) {
+	select {
+	case <-ctx.Done():
+		return Info{}, ctx.Err()
+	default:
+	}
+
+	name := make([]byte, deviceNameBufferSize)
+	if err := checkReturnCode("nvmlDeviceGetName", nvmlDeviceGetName(dev, &name[0], uint32(len(name)))); err != nil {
+		return Info{}, err
+	}
+
+	uuid := make([]byte, deviceUUIDBufferSize)
+	if err := checkReturnCode("nvmlDeviceGetUUID", nvmlDeviceGetUUID(dev, &uuid[0], uint32(len(uuid)))); err != nil {
+		return Info{}, err
+	}
+
+	var pci PciInfo
+	if err := checkReturnCode("nvmlDeviceGetPciInfo", nvmlDeviceGetPciInfo(dev, &pci)); err != nil {
+		return Info{}, err
+	}
+
+	vbios := make([]byte, vbiosVersionBufferSize)
+	if err := checkReturnCode("nvmlDeviceGetVbiosVersion", nvmlDeviceGetVbiosVersion(dev, &vbios[0], uint32(len(vbios)))); err != nil && !IsNotSupported(err) {
+		return Info{}, err
+	}
+
+	return Info{
+		Name:         cString(name),
+		UUID:         cString(uuid),
+		BiosVersion:  cString(vbios),
+		BDF:          cString(pci.BusID[:]),
+		PciDeviceID:  pci.PciDeviceID,
+		PciSubSystem: pci.PciSubSystemID,
+	}, nil
+}
+
+// getUtilization returns the gpu and memory utilization in percent.
+func (l *library) getUtilization(ctx context.Context, dev Device) (Utilization, error) {
+	select {
+	case <-ctx.Done():
+		return Utilization{}, ctx.Err()
+	default:
+	}
+
+	var obj Utilization
+	if err := checkReturnCode("nvmlDeviceGetUtilizationRates", nvmlDeviceGetUtilizationRates(dev, &obj)); err != nil {
+		return Utilization{}, err
+	}
+	return obj, nil
+}
+
+// getMemory returns the framebuffer memory usage.
+func (l *library) getMemory(ctx context.Context, dev Device) (Memory, error) {
+	select {
+	case <-ctx.Done():
+		return Memory{}, ctx.Err()
+	default:
+	}
+
+	var obj Memory
+	if err := checkReturnCode("nvmlDeviceGetMemoryInfo", nvmlDeviceGetMemoryInfo(dev, &obj)); err != nil {
+		return Memory{}, err
+	}
+	return obj, nil
+}
+
+// getPcieThroughput returns the PCIe traffic of the GPU.
+func (l *library) getPcieThroughput(ctx context.Context, dev Device) (PcieThroughput, error) {
+	select {
+	case <-ctx.Done():
+		return PcieThroughput{}, ctx.Err()
+	default:
+	}
+
+	var tx, rx uint32
+	if err := checkReturnCode("nvmlDeviceGetPcieThroughput", nvmlDeviceGetPcieThroughput(dev, pcieUtilTxBytes, &tx)); err != nil {
+		return PcieThroughput{}, err
+	}
+	if err := checkReturnCode("nvmlDeviceGetPcieThroughput", nvmlDeviceGetPcieThroughput(dev, pcieUtilRxBytes, &rx)); err != nil {
+		return PcieThroughput{}, err
+	}
+	// the driver reports KB/s.
+	return PcieThroughput{Transmit: uint64(tx) * 1024, Receive: uint64(rx) * 1024}, nil
+}
+
+// getPower returns the board power draw in milliwatts.
+func (l *library) getPower(ctx context.Context, dev Device) (uint32, error) {
+	select {
+	case <-ctx.Done():
+		return 0, ctx.Err()
+	default:
+	}
+
+	var mw uint32
+	if err := checkReturnCode("nvmlDeviceGetPowerUsage", nvmlDeviceGetPowerUsage(dev, &mw)); err != nil {
+		return 0, err
+	}
+	return mw, nil
+}
+
+// getTemperature returns the GPU die temperature in celsius.
+func (l *library) getTemperature(ctx context.Context, dev Device) (uint32, error) {
+	select {
+	case <-ctx.Done():
+		return 0, ctx.Err()
+	default:
+	}
+
+	var celsius uint32
+	if err := checkReturnCode("nvmlDeviceGetTemperature", nvmlDeviceGetTemperature(dev, temperatureGpu, &celsius)); err != nil {
+		return 0, err
+	}
+	return celsius, nil
+}
+
+// getEccErrors returns the ECC errors of the type since the GPU was
+// installed.
+func (l *library) getEccErrors(ctx context.Context, dev Device, errorType MemoryErrorType) (uint64, error) {
+	select {
+	case <-ctx.Done():
+		return 0, ctx.Err()
+	default:
+	}
+
+	var count uint64
+	if err := checkReturnCode("nvmlDeviceGetTotalEccErrors", nvmlDeviceGetTotalEccErrors(dev, errorType, eccCounterAggregate, &count)); err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+// listNvLinkThroughputs returns the data counters of the active NVLinks.
+func (l *library) listNvLinkThroughputs(ctx context.Context, dev Device) ([]NvLinkThroughput, error) {
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	default:
+	}
+
+	var links []NvLinkThroughput
+	for link := uint32(0); link < NvLinkMaxLinks; link++ {
+		var active uint32
+		err := checkReturnCode("nvmlDeviceGetNvLinkState", nvmlDeviceGetNvLinkState(dev, link, &active))
+		if isReturn(err, errorInvalidArgument) {
+			// past the last link of the device.
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if active == 0 {
+			continue
+		}
+
+		values := []fieldValue{
+			{fieldID: fieldNvLinkThroughputDataRx, scopeID: link},
+			{fieldID: fieldNvLinkThroughputDataTx, scopeID: link},
+		}
+		if err := checkReturnCode("nvmlDeviceGetFieldValues", nvmlDeviceGetFieldValues(dev, int32(len(values)), &values[0])); err != nil {
+			return nil, err
+		}
+		for i := range values {
+			if err := checkReturnCode("nvmlDeviceGetFieldValues", values[i].nvmlReturn); err != nil {
+				return nil, err
+			}
+		}
+
+		links = append(links, NvLinkThroughput{
+			Link:     link,
+			Receive:  values[0].value * 1024,
+			Transmit: values[1].value * 1024,
+		})
+	}
+	return links, nil
+}
+
+// listNvLinkRemotes returns the devices at the other end of the active
+// NVLinks.
+func (l *library) listNvLinkRemotes(ctx context.Context, dev Device) ([]NvLinkRemote, error) {
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	default:
+	}
+
+	var remotes []NvLinkRemote
+	for link := uint32(0); link < NvLinkMaxLinks; link++ {
+		var active uint32
+		err := checkReturnCode("nvmlDeviceGetNvLinkState", nvmlDeviceGetNvLinkState(dev, link, &active))
+		if isReturn(err, errorInvalidArgument) {
+			// past the last link of the device.
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if active == 0 {
+			continue
+		}
+
+		var pci PciInfo
+		if err := checkReturnCode("nvmlDeviceGetNvLinkRemotePciInfo", nvmlDeviceGetNvLinkRemotePciInfo(dev, link, &pci)); err != nil {
+			return nil, err
+		}
+		remotes = append(remotes, NvLinkRemote{Link: link, BDF: cString(pci.BusID[:])})
+	}
+	return remotes, nil
+}
+
+// listProcesses returns the compute and graphics processes on the GPU, a
+// process doing both is listed once.
+func (l *library) listProcesses(ctx context.Context, dev Device) ([]ProcessInfo, error) {
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	default:
+	}
+
+	compute, err := runningProcesses("nvmlDeviceGetComputeRunningProcesses", nvmlDeviceGetComputeRunningProcesses, dev)
+	if err != nil {
+		return nil, err
+	}
+	graphics, err := runningProcesses("nvmlDeviceGetGraphicsRunningProcesses", nvmlDeviceGetGraphicsRunningProcesses, dev)
+	if err != nil {
+		return nil, err
+	}
+
+	seen := make(map[uint32]bool, len(compute))
+	processes := make([]ProcessInfo, 0, len(compute)+len(graphics))
+	for _, process := range append(compute, graphics...) {
+		if seen[process.Pid] {
+			continue
+		}
+		seen[process.Pid] = true
+		processes = append(processes, process)
+	}
+	return processes, nil
+}
+
+func runningProcesses(symbol string, fn func(Device, *uint32, *ProcessInfo) Return, dev Device) ([]ProcessInfo, error) {
+	infos := make([]ProcessInfo, 32)
+	for {
+		count := uint32(len(infos))
+		err := checkReturnCode(symbol, fn(dev, &count, &infos[0]))
+		if isReturn(err, errorInsufficientSize) {
+			// processes may start before the next call.
+			infos = make([]ProcessInfo, count+8)
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		return infos[:count], nil
+	}
+}
+
+// listProcessSamples returns the utilization samples of the processes taken
+// after lastSeen, a timestamp in microseconds, 0 for all the buffered ones.
+func (l *library) listProcessSamples(ctx context.Context, dev Device, lastSeen uint64) ([]ProcessUtilizationSample, error) {
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	default:
+	}
+
+	for {
+		var count uint32
+		err := checkReturnCode("nvmlDeviceGetProcessUtilization", nvmlDeviceGetProcessUtilization(dev, nil, &count, lastSeen))
+		if isReturn(err, errorNotFound) {
+			return nil, nil
+		}
+		if err != nil && !isReturn(err, errorInsufficientSize) {
+			return nil, err
+		}
+		if count == 0 {
+			return nil, nil
+		}
+
+		samples := make([]ProcessUtilizationSample, count)
+		err = checkReturnCode("nvmlDeviceGetProcessUtilization", nvmlDeviceGetProcessUtilization(dev, &samples[0], &count, lastSeen))
+		switch {
+		case isReturn(err, errorInsufficientSize):
+			// new samples between the calls.
+			continue
+		case isReturn(err, errorNotFound):
+			return nil, nil
+		case err != nil:
+			return nil, err
+		}
+		return samples[:count], nil
+	}
+}
+
+// cString converts a NUL-terminated byte slice to a Go string.
+func cString(bs []byte) string {
+	for i, b := range bs {
+		if b == 0 {
+			return string(bs[:i])
+		}
+	}
+	return string(bs)
+}
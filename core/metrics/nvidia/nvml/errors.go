@@ -0,0 +1,80 @@
+// Copyright 2026 The HuaTuo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nvml
+
+import (
+	"errors"
+	"fmt"
+)
+
+// NVML return codes used by the callers, see nvmlReturn_t.
+//
+//nolint:errname
+const (
+	Success           Return = 0
+	ErrorNotSupported Return = 3
+	ErrorTimeout      Return = 10
+)
+
+// invalid argument, returned for the NVLinks a device does not have.
+const errorInvalidArgument Return = 2
+
+// the process queries return the count needed when the buffer is too
+// small, and not found when no sample is newer than the timestamp.
+const (
+	errorNotFound         Return = 6
+	errorInsufficientSize Return = 7
+)
+
+// String returns the description of the return code by NVML.
+func (r Return) String() string {
+	return nvmlErrorString(r)
+}
+
+type Error struct {
+	symbol string
+	code   Return
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s failed: %s", e.symbol, e.code.String())
+}
+
+func isReturn(err error, code Return) bool {
+	var nvmlErr *Error
+	return errors.As(err, &nvmlErr) && nvmlErr.code == code
+}
+
+// IsNotSupported reports whether err represents an unsupported operation.
+func IsNotSupported(err error) bool {
+	return isReturn(err, ErrorNotSupported)
+}
+
+// IsTimeout reports whether err is the timeout of waiting for events.
+func IsTimeout(err error) bool {
+	return isReturn(err, ErrorTimeout)
+}
+
+// checkReturnCode converts a return code to an error.
+func checkReturnCode(symbol string, code Return) error {
+	if code == Success {
+		return nil
+	}
+
+	return &Error{
+		symbol: symbol,
+		code:   code,
+	}
+}
@@ -0,0 +1,63 @@
+// Copyright 2026 The HuaTuo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nvml
+
+import "context"
+
+// XidEvent is a critical Xid error reported by the driver.
+type XidEvent struct {
+	Device Device
+	Xid    uint64
+}
+
+// watchXidEvents calls fn with every Xid error of the devices until ctx is
+// done. Devices without Xid events are skipped.
+func (l *library) watchXidEvents(ctx context.Context, devices []Device, fn func(XidEvent)) error {
+	var set EventSet
+	if err := checkReturnCode("nvmlEventSetCreate", nvmlEventSetCreate(&set)); err != nil {
+		return err
+	}
+	defer nvmlEventSetFree(set)
+
+	for _, dev := range devices {
+		err := checkReturnCode("nvmlDeviceRegisterEvents", nvmlDeviceRegisterEvents(dev, EventTypeXidCriticalError, set))
+		if err != nil && !IsNotSupported(err) {
+			return err
+		}
+	}
+
+	// the wait is bounded to notice ctx.
+	const waitTimeoutMs = 1000
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		default:
+		}
+
+		var data EventData
+		err := checkReturnCode("nvmlEventSetWait", nvmlEventSetWait(set, &data, waitTimeoutMs))
+		if IsTimeout(err) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		if data.EventType == EventTypeXidCriticalError {
+			fn(XidEvent{Device: data.Device, Xid: data.EventData})
+		}
+	}
+}
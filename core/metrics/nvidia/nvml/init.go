@@ -0,0 +1,31 @@
+// Copyright 2026 The HuaTuo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nvml
+
+// Init loads and initializes the NVML library.
+func Init() error {
+	if err := libnvml.load(); err != nil {
+		return err
+	}
+	return checkReturnCode("nvmlInit", nvmlInit())
+}
+
+// Shutdown shuts down the NVML library.
+func Shutdown() error {
+	if err := checkReturnCode("nvmlShutdown", nvmlShutdown()); err != nil {
+		return err
+	}
+	return libnvml.close()
+}
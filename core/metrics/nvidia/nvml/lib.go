@@ -0,0 +1,124 @@
+// Copyright 2026 The HuaTuo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nvml
+
+import (
+	"sync"
+
+	"huatuo-bamai/core/metrics/metax/dl"
+
+	"github.com/ebitengine/purego"
+)
+
+// dynamicLibrary abstracts a dynamically loaded shared library.
+type dynamicLibrary interface {
+	Open() error
+	Close() error
+	Handle() uintptr
+}
+
+// library represents the NVML shared library, loaded once and shared by
+// reference counting.
+type library struct {
+	sync.Mutex
+	refcount int32
+	dl       dynamicLibrary
+}
+
+// the soname installed by the NVIDIA driver, found by the dynamic linker.
+const nvmlLibraryPath = "libnvidia-ml.so.1"
+
+// global singleton instance
+var libnvml = &library{
+	dl: dl.New(nvmlLibraryPath, purego.RTLD_NOW|purego.RTLD_GLOBAL),
+}
+
+// load opens the shared library and registers all required symbols.
+// Multiple calls are reference-counted and idempotent.
+func (l *library) load() error {
+	l.Lock()
+	defer l.Unlock()
+
+	if l.refcount > 0 {
+		l.refcount++
+		return nil
+	}
+
+	if err := l.dl.Open(); err != nil {
+		return err
+	}
+
+	if sim, ok := l.dl.(*simulatedLibrary); ok {
+		sim.registerSymbols()
+	} else {
+		l.registerNvmlLibSymbols(l.dl.Handle())
+	}
+	l.refcount++
+	return nil
+}
+
+// close decrements the reference count and unloads the library when the
+// last reference is released.
+func (l *library) close() error {
+	l.Lock()
+	defer l.Unlock()
+
+	if l.refcount == 0 {
+		return nil
+	}
+	if l.refcount > 1 {
+		l.refcount--
+		return nil
+	}
+
+	if err := l.dl.Close(); err != nil {
+		return err
+	}
+	l.refcount--
+	return nil
+}
+
+// registerNvmlLibSymbols registers all required NVML symbols from the loaded
+// shared library. The versioned symbols are the ones nvml.h maps the plain
+// names to.
+func (l *library) registerNvmlLibSymbols(handle uintptr) {
+	purego.RegisterLibFunc(&nvmlInit, handle, "nvmlInit_v2")
+	purego.RegisterLibFunc(&nvmlShutdown, handle, "nvmlShutdown")
+	purego.RegisterLibFunc(&nvmlErrorString, handle, "nvmlErrorString")
+	purego.RegisterLibFunc(&nvmlSystemGetDriverVersion, handle, "nvmlSystemGetDriverVersion")
+	purego.RegisterLibFunc(&nvmlSystemGetCudaDriverVersion, handle, "nvmlSystemGetCudaDriverVersion_v2")
+	purego.RegisterLibFunc(&nvmlDeviceGetCount, handle, "nvmlDeviceGetCount_v2")
+	purego.RegisterLibFunc(&nvmlDeviceGetHandleByIndex, handle, "nvmlDeviceGetHandleByIndex_v2")
+	purego.RegisterLibFunc(&nvmlDeviceGetName, handle, "nvmlDeviceGetName")
+	purego.RegisterLibFunc(&nvmlDeviceGetUUID, handle, "nvmlDeviceGetUUID")
+	purego.RegisterLibFunc(&nvmlDeviceGetVbiosVersion, handle, "nvmlDeviceGetVbiosVersion")
+	purego.RegisterLibFunc(&nvmlDeviceGetPciInfo, handle, "nvmlDeviceGetPciInfo_v3")
+	purego.RegisterLibFunc(&nvmlDeviceGetUtilizationRates, handle, "nvmlDeviceGetUtilizationRates")
+	purego.RegisterLibFunc(&nvmlDeviceGetMemoryInfo, handle, "nvmlDeviceGetMemoryInfo")
+	purego.RegisterLibFunc(&nvmlDeviceGetPowerUsage, handle, "nvmlDeviceGetPowerUsage")
+	purego.RegisterLibFunc(&nvmlDeviceGetTemperature, handle, "nvmlDeviceGetTemperature")
+	purego.RegisterLibFunc(&nvmlDeviceGetTotalEccErrors, handle, "nvmlDeviceGetTotalEccErrors")
+	purego.RegisterLibFunc(&nvmlDeviceGetPcieThroughput, handle, "nvmlDeviceGetPcieThroughput")
+	purego.RegisterLibFunc(&nvmlDeviceGetComputeRunningProcesses, handle, "nvmlDeviceGetComputeRunningProcesses_v3")
+	purego.RegisterLibFunc(&nvmlDeviceGetGraphicsRunningProcesses, handle, "nvmlDeviceGetGraphicsRunningProcesses_v3")
+	purego.RegisterLibFunc(&nvmlDeviceGetProcessUtilization, handle, "nvmlDeviceGetProcessUtilization")
+	purego.RegisterLibFunc(&nvmlDeviceGetNvLinkState, handle, "nvmlDeviceGetNvLinkState")
+	purego.RegisterLibFunc(&nvmlDeviceGetNvLinkRemotePciInfo, handle, "nvmlDeviceGetNvLinkRemotePciInfo_v2")
+	purego.RegisterLibFunc(&nvmlDeviceGetFieldValues, handle, "nvmlDeviceGetFieldValues")
+	purego.RegisterLibFunc(&nvmlEventSetCreate, handle, "nvmlEventSetCreate")
+	purego.RegisterLibFunc(&nvmlDeviceRegisterEvents, handle, "nvmlDeviceRegisterEvents")
+	purego.RegisterLibFunc(&nvmlEventSetWait, handle, "nvmlEventSetWait_v2")
+	purego.RegisterLibFunc(&nvmlEventSetFree, handle, "nvmlEventSetFree")
+}
@@ -0,0 +1,282 @@
+// Copyright 2026 The HuaTuo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nvml
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+	"unsafe"
+
+	"huatuo-bamai/core/metrics/gpusim"
+)
+
+// the simulated devices, sized like an H100 SXM.
+const (
+	simDeviceName    = "NVIDIA H100 80GB HBM3"
+	simMemoryTotal   = 80 << 30
+	simPowerLimitMW  = 700000
+	simPciDeviceID   = 0x233010de
+	simPciSubSystem  = 0x16c110de
+	simCudaVersion   = 12040
+	simDriverVersion = "550.54.15-sim"
+
+	// simXidDoubleBitEcc is the Xid of an uncorrectable ECC error.
+	simXidDoubleBitEcc = 48
+)
+
+// simulatedLibrary stands in for libnvidia-ml.so, its symbols read the
+// devices of a gpusim.Sim.
+type simulatedLibrary struct {
+	sim *gpusim.Sim
+
+	// the uncorrectable errors already sent as Xid events, by GPU.
+	xidMu   sync.Mutex
+	xidSeen []uint64
+}
+
+func (*simulatedLibrary) Open() error     { return nil }
+func (*simulatedLibrary) Close() error    { return nil }
+func (*simulatedLibrary) Handle() uintptr { return 0 }
+
+// Simulate replaces the NVML library by the simulated devices of sim,
+// before Init is called.
+func Simulate(sim *gpusim.Sim) error {
+	libnvml.Lock()
+	defer libnvml.Unlock()
+
+	if libnvml.refcount > 0 {
+		return errors.New("nvml library is already loaded")
+	}
+
+	libnvml.dl = &simulatedLibrary{sim: sim, xidSeen: make([]uint64, sim.GPUs())}
+	return nil
+}
+
+// writeCString copies s NUL-terminated into the buffer of size bytes at dst.
+func writeCString(dst *byte, size uint32, s string) {
+	if size == 0 {
+		return
+	}
+	buf := unsafe.Slice(dst, size)
+	n := copy(buf[:size-1], s)
+	buf[n] = 0
+}
+
+// simPciInfo is the PCI identity of the GPU, with the bus id format of NVML.
+func simPciInfo(gpu int) PciInfo {
+	bus := uint32(0x18 + gpu)
+	pci := PciInfo{Bus: bus, PciDeviceID: simPciDeviceID, PciSubSystemID: simPciSubSystem}
+	writeCString(&pci.BusID[0], uint32(len(pci.BusID)), fmt.Sprintf("00000000:%02X:00.0", bus))
+	writeCString(&pci.BusIDLegacy[0], uint32(len(pci.BusIDLegacy)), fmt.Sprintf("0000:%02X:00.0", bus))
+	return pci
+}
+
+// registerSymbols sets the symbols of the library to the simulated ones.
+func (l *simulatedLibrary) registerSymbols() {
+	sim := l.sim
+
+	ret := func(symbol string) Return {
+		return Return(sim.Return(symbol, int32(ErrorNotSupported)))
+	}
+	// the handles are the GPU indexes from 1, 0 is no device.
+	call := func(symbol string, dev Device, fn func(gpu int, r gpusim.Reading)) Return {
+		if code := ret(symbol); code != Success {
+			return code
+		}
+		gpu := int(dev) - 1
+		if gpu < 0 || gpu >= sim.GPUs() {
+			return errorInvalidArgument
+		}
+		fn(gpu, sim.Reading(gpu))
+		return Success
+	}
+	// the GPUs are linked to each other by one NVLink.
+	links := min(sim.GPUs()-1, NvLinkMaxLinks)
+
+	nvmlInit = func() Return { return ret("nvmlInit") }
+	nvmlShutdown = func() Return { return ret("nvmlShutdown") }
+	nvmlErrorString = func(code Return) string {
+		switch code {
+		case Success:
+			return "Success"
+		case ErrorNotSupported:
+			return "Not Supported"
+		case ErrorTimeout:
+			return "Timeout"
+		default:
+			return fmt.Sprintf("Simulated Error %d", code)
+		}
+	}
+
+	nvmlSystemGetDriverVersion = func(buf *byte, size uint32) Return {
+		if code := ret("nvmlSystemGetDriverVersion"); code != Success {
+			return code
+		}
+		writeCString(buf, size, simDriverVersion)
+		return Success
+	}
+	nvmlSystemGetCudaDriverVersion = func(version *int32) Return {
+		if code := ret("nvmlSystemGetCudaDriverVersion"); code != Success {
+			return code
+		}
+		*version = simCudaVersion
+		return Success
+	}
+
+	nvmlDeviceGetCount = func(count *uint32) Return {
+		if code := ret("nvmlDeviceGetCount"); code != Success {
+			return code
+		}
+		*count = uint32(sim.GPUs())
+		return Success
+	}
+	nvmlDeviceGetHandleByIndex = func(index uint32, dev *Device) Return {
+		if code := ret("nvmlDeviceGetHandleByIndex"); code != Success {
+			return code
+		}
+		if int(index) >= sim.GPUs() {
+			return errorInvalidArgument
+		}
+		*dev = Device(index + 1)
+		return Success
+	}
+	nvmlDeviceGetName = func(dev Device, buf *byte, size uint32) Return {
+		return call("nvmlDeviceGetName", dev, func(int, gpusim.Reading) { writeCString(buf, size, simDeviceName) })
+	}
+	nvmlDeviceGetUUID = func(dev Device, buf *byte, size uint32) Return {
+		return call("nvmlDeviceGetUUID", dev, func(gpu int, _ gpusim.Reading) {
+			writeCString(buf, size, fmt.Sprintf("GPU-5a5a5a5a-0000-4000-8000-%012d", gpu))
+		})
+	}
+	nvmlDeviceGetVbiosVersion = func(dev Device, buf *byte, size uint32) Return {
+		return call("nvmlDeviceGetVbiosVersion", dev, func(int, gpusim.Reading) { writeCString(buf, size, "96.00.99.00.01") })
+	}
+	nvmlDeviceGetPciInfo = func(dev Device, pci *PciInfo) Return {
+		return call("nvmlDeviceGetPciInfo", dev, func(gpu int, _ gpusim.Reading) { *pci = simPciInfo(gpu) })
+	}
+
+	nvmlDeviceGetUtilizationRates = func(dev Device, util *Utilization) Return {
+		return call("nvmlDeviceGetUtilizationRates", dev, func(_ int, r gpusim.Reading) {
+			*util = Utilization{Gpu: uint32(r.Utilization), Memory: uint32(r.MemoryUtilization)}
+		})
+	}
+	nvmlDeviceGetMemoryInfo = func(dev Device, memory *Memory) Return {
+		return call("nvmlDeviceGetMemoryInfo", dev, func(_ int, r gpusim.Reading) {
+			used := uint64(r.MemoryUsed * simMemoryTotal)
+			*memory = Memory{Total: simMemoryTotal, Used: used, Free: simMemoryTotal - used}
+		})
+	}
+	nvmlDeviceGetPowerUsage = func(dev Device, mw *uint32) Return {
+		return call("nvmlDeviceGetPowerUsage", dev, func(_ int, r gpusim.Reading) { *mw = uint32(r.Power * simPowerLimitMW) })
+	}
+	nvmlDeviceGetTemperature = func(dev Device, _ uint32, celsius *uint32) Return {
+		return call("nvmlDeviceGetTemperature", dev, func(_ int, r gpusim.Reading) { *celsius = uint32(r.Temperature) })
+	}
+	nvmlDeviceGetTotalEccErrors = func(dev Device, errorType MemoryErrorType, _ uint32, count *uint64) Return {
+		return call("nvmlDeviceGetTotalEccErrors", dev, func(_ int, r gpusim.Reading) {
+			*count = r.CorrectableECC
+			if errorType == MemoryErrorTypeUncorrected {
+				*count = r.UncorrectableECC
+			}
+		})
+	}
+	nvmlDeviceGetPcieThroughput = func(dev Device, counter uint32, kbps *uint32) Return {
+		return call("nvmlDeviceGetPcieThroughput", dev, func(_ int, r gpusim.Reading) {
+			// KB/s, the host sends more than it reads back.
+			*kbps = uint32(r.Utilization * 200 * 1024)
+			if counter == pcieUtilTxBytes {
+				*kbps /= 2
+			}
+		})
+	}
+
+	// the simulated processes are not on this node, none is listed.
+	noProcesses := func(symbol string) func(Device, *uint32, *ProcessInfo) Return {
+		return func(dev Device, count *uint32, _ *ProcessInfo) Return {
+			return call(symbol, dev, func(int, gpusim.Reading) { *count = 0 })
+		}
+	}
+	nvmlDeviceGetComputeRunningProcesses = noProcesses("nvmlDeviceGetComputeRunningProcesses")
+	nvmlDeviceGetGraphicsRunningProcesses = noProcesses("nvmlDeviceGetGraphicsRunningProcesses")
+	nvmlDeviceGetProcessUtilization = func(dev Device, _ *ProcessUtilizationSample, count *uint32, _ uint64) Return {
+		if code := call("nvmlDeviceGetProcessUtilization", dev, func(int, gpusim.Reading) { *count = 0 }); code != Success {
+			return code
+		}
+		return errorNotFound
+	}
+
+	nvmlDeviceGetNvLinkState = func(dev Device, link uint32, active *uint32) Return {
+		code := call("nvmlDeviceGetNvLinkState", dev, func(int, gpusim.Reading) { *active = 1 })
+		if code == Success && int(link) >= links {
+			return errorInvalidArgument
+		}
+		return code
+	}
+	nvmlDeviceGetNvLinkRemotePciInfo = func(dev Device, link uint32, pci *PciInfo) Return {
+		return call("nvmlDeviceGetNvLinkRemotePciInfo", dev, func(gpu int, _ gpusim.Reading) {
+			*pci = simPciInfo((gpu + 1 + int(link)) % sim.GPUs())
+		})
+	}
+	nvmlDeviceGetFieldValues = func(dev Device, n int32, first *fieldValue) Return {
+		return call("nvmlDeviceGetFieldValues", dev, func(_ int, r gpusim.Reading) {
+			values := unsafe.Slice(first, n)
+			for i := range values {
+				// the throughput counters are in KiB.
+				values[i].value = uint64(r.Traffic / 1024)
+				values[i].nvmlReturn = Success
+			}
+		})
+	}
+
+	nvmlEventSetCreate = func(set *EventSet) Return {
+		if code := ret("nvmlEventSetCreate"); code != Success {
+			return code
+		}
+		*set = 1
+		return Success
+	}
+	nvmlDeviceRegisterEvents = func(dev Device, _ uint64, _ EventSet) Return {
+		return call("nvmlDeviceRegisterEvents", dev, func(int, gpusim.Reading) {})
+	}
+	nvmlEventSetWait = func(_ EventSet, data *EventData, timeoutMs uint32) Return {
+		if code := ret("nvmlEventSetWait"); code != Success {
+			return code
+		}
+		if dev, ok := l.nextXid(); ok {
+			*data = EventData{Device: dev, EventType: EventTypeXidCriticalError, EventData: simXidDoubleBitEcc}
+			return Success
+		}
+		time.Sleep(time.Duration(timeoutMs) * time.Millisecond)
+		return ErrorTimeout
+	}
+	nvmlEventSetFree = func(EventSet) Return { return ret("nvmlEventSetFree") }
+}
+
+// nextXid returns a GPU with an uncorrectable error not yet sent as an Xid
+// event.
+func (l *simulatedLibrary) nextXid() (Device, bool) {
+	l.xidMu.Lock()
+	defer l.xidMu.Unlock()
+
+	for gpu := range l.xidSeen {
+		if ue := l.sim.Reading(gpu).UncorrectableECC; ue > l.xidSeen[gpu] {
+			l.xidSeen[gpu]++
+			return Device(gpu + 1), true
+		}
+	}
+	return 0, false
+}
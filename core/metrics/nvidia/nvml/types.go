@@ -0,0 +1,204 @@
+// Copyright 2026 The HuaTuo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nvml
+
+// Return is nvmlReturn_t.
+type Return int32
+
+// Device is the nvmlDevice_t handle of a GPU.
+type Device uintptr
+
+// EventSet is the nvmlEventSet_t handle.
+type EventSet uintptr
+
+// MemoryErrorType is nvmlMemoryErrorType_t.
+type MemoryErrorType uint32
+
+const (
+	MemoryErrorTypeCorrected   MemoryErrorType = 0
+	MemoryErrorTypeUncorrected MemoryErrorType = 1
+)
+
+// the counters survive driver reloads, NVML_AGGREGATE_ECC.
+const eccCounterAggregate uint32 = 1
+
+// NVML_TEMPERATURE_GPU, the GPU die sensor.
+const temperatureGpu uint32 = 0
+
+// NVML_NVLINK_MAX_LINKS of recent drivers.
+const NvLinkMaxLinks = 18
+
+// NVLink data throughput counters in KiB, scoped to a link.
+const (
+	fieldNvLinkThroughputDataTx uint32 = 138
+	fieldNvLinkThroughputDataRx uint32 = 139
+)
+
+// EventTypeXidCriticalError is nvmlEventTypeXidCriticalError.
+const EventTypeXidCriticalError uint64 = 0x0000000000000008
+
+// buffer sizes of nvml.h.
+const (
+	deviceNameBufferSize    = 96
+	deviceUUIDBufferSize    = 96
+	vbiosVersionBufferSize  = 32
+	driverVersionBufferSize = 80
+)
+
+// PciInfo is nvmlPciInfo_t.
+type PciInfo struct {
+	BusIDLegacy    [16]byte
+	Domain         uint32
+	Bus            uint32
+	Device         uint32
+	PciDeviceID    uint32
+	PciSubSystemID uint32
+	BusID          [32]byte
+}
+
+// Utilization is nvmlUtilization_t, in percent of the last sample period.
+type Utilization struct {
+	Gpu    uint32
+	Memory uint32
+}
+
+// Memory is nvmlMemory_t in bytes.
+type Memory struct {
+	Total uint64
+	Free  uint64
+	Used  uint64
+}
+
+// fieldValue is nvmlFieldValue_t, value is only read as unsigned long long.
+type fieldValue struct {
+	fieldID    uint32
+	scopeID    uint32
+	_          int64  // timestamp, not used yet.
+	_          int64  // latencyUsec, not used yet.
+	_          uint32 // valueType, unsigned long long for the fields read.
+	nvmlReturn Return
+	value      uint64
+}
+
+// EventData is nvmlEventData_t.
+type EventData struct {
+	Device            Device
+	EventType         uint64
+	EventData         uint64
+	GpuInstanceID     uint32
+	ComputeInstanceID uint32
+}
+
+// Info is the identity of a GPU.
+type Info struct {
+	Name         string
+	UUID         string
+	BiosVersion  string
+	BDF          string
+	PciDeviceID  uint32
+	PciSubSystem uint32
+}
+
+// ValueNotAvailable is NVML_VALUE_NOT_AVAILABLE, e.g. the memory of a process
+// the driver cannot account.
+const ValueNotAvailable = ^uint64(0)
+
+// ProcessInfo is nvmlProcessInfo_t, a process running on the GPU.
+type ProcessInfo struct {
+	Pid               uint32
+	UsedGpuMemory     uint64
+	GpuInstanceID     uint32
+	ComputeInstanceID uint32
+}
+
+// ProcessUtilizationSample is nvmlProcessUtilizationSample_t, the
+// utilization of a process in percent, TimeStamp in microseconds.
+type ProcessUtilizationSample struct {
+	Pid       uint32
+	TimeStamp uint64
+	SmUtil    uint32
+	MemUtil   uint32
+	EncUtil   uint32
+	DecUtil   uint32
+}
+
+// PcieThroughput is the PCIe traffic of the GPU in bytes per second,
+// sampled over 20ms by the driver.
+type PcieThroughput struct {
+	Transmit uint64
+	Receive  uint64
+}
+
+// nvmlPcieUtilCounter_t
+const (
+	pcieUtilTxBytes uint32 = 0
+	pcieUtilRxBytes uint32 = 1
+)
+
+// NvLinkThroughput is the data transferred over an NVLink in bytes.
+type NvLinkThroughput struct {
+	Link     uint32
+	Receive  uint64
+	Transmit uint64
+}
+
+// NvLinkRemote is the PCI bus id of the device at the other end of an
+// NVLink, a GPU or an NVSwitch.
+type NvLinkRemote struct {
+	Link uint32
+	BDF  string
+}
+
+// NVML API RAW SYMBOLS
+var (
+	// Error and initialization symbols
+	nvmlInit        func() Return
+	nvmlShutdown    func() Return
+	nvmlErrorString func(Return) string
+
+	// System symbols
+	nvmlSystemGetDriverVersion     func(*byte, uint32) Return
+	nvmlSystemGetCudaDriverVersion func(*int32) Return
+
+	// Device symbols
+	nvmlDeviceGetCount            func(*uint32) Return
+	nvmlDeviceGetHandleByIndex    func(uint32, *Device) Return
+	nvmlDeviceGetName             func(Device, *byte, uint32) Return
+	nvmlDeviceGetUUID             func(Device, *byte, uint32) Return
+	nvmlDeviceGetVbiosVersion     func(Device, *byte, uint32) Return
+	nvmlDeviceGetPciInfo          func(Device, *PciInfo) Return
+	nvmlDeviceGetUtilizationRates func(Device, *Utilization) Return
+	nvmlDeviceGetMemoryInfo       func(Device, *Memory) Return
+	nvmlDeviceGetPowerUsage       func(Device, *uint32) Return
+	nvmlDeviceGetTemperature      func(Device, uint32, *uint32) Return
+	nvmlDeviceGetTotalEccErrors   func(Device, MemoryErrorType, uint32, *uint64) Return
+	nvmlDeviceGetPcieThroughput   func(Device, uint32, *uint32) Return
+
+	// Process symbols
+	nvmlDeviceGetComputeRunningProcesses  func(Device, *uint32, *ProcessInfo) Return
+	nvmlDeviceGetGraphicsRunningProcesses func(Device, *uint32, *ProcessInfo) Return
+	nvmlDeviceGetProcessUtilization       func(Device, *ProcessUtilizationSample, *uint32, uint64) Return
+
+	// NVLink symbols
+	nvmlDeviceGetNvLinkState         func(Device, uint32, *uint32) Return
+	nvmlDeviceGetNvLinkRemotePciInfo func(Device, uint32, *PciInfo) Return
+	nvmlDeviceGetFieldValues         func(Device, int32, *fieldValue) Return
+
+	// Event symbols
+	nvmlEventSetCreate       func(*EventSet) Return
+	nvmlDeviceRegisterEvents func(Device, uint64, EventSet) Return
+	nvmlEventSetWait         func(EventSet, *EventData, uint32) Return
+	nvmlEventSetFree         func(EventSet) Return
+)
@@ -0,0 +1,488 @@
+// Copyright 2026 The HuaTuo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"huatuo-bamai/core/metrics/nvidia/nvml"
+	"huatuo-bamai/internal/log"
+	"huatuo-bamai/internal/pod"
+	"huatuo-bamai/pkg/metric"
+	"huatuo-bamai/pkg/tracing"
+	"huatuo-bamai/pkg/types"
+)
+
+func init() {
+	tracing.RegisterEventTracing("nvidia_gpu", newNvidiaGpuCollector)
+}
+
+type nvidiaXidKey struct {
+	gpu int
+	xid uint64
+}
+
+type nvidiaGpuCollector struct {
+	devices []nvml.Device
+
+	// the Xid errors only come as events, counted since the start.
+	xidOnce sync.Once
+	xidMu   sync.Mutex
+	xids    map[nvidiaXidKey]uint64
+
+	// the newest process sample accounted, by gpu.
+	samplesMu   sync.Mutex
+	lastSamples []uint64
+}
+
+// nvidiaContainerUsage is the usage of a GPU by the processes of a container.
+type nvidiaContainerUsage struct {
+	memory  uint64
+	smUtil  float64
+	memUtil float64
+}
+
+func newNvidiaGpuCollector() (*tracing.EventTracingAttr, error) {
+	if _, err := gpuSimulate(gpuVendorNvidia); err != nil {
+		return nil, err
+	}
+
+	// Init NVML lib, absent without the NVIDIA driver.
+	if err := nvml.Init(); err != nil {
+		return nil, types.ErrNotSupported
+	}
+
+	devices, err := nvml.ListDevices()
+	if err != nil || len(devices) == 0 {
+		_ = nvml.Shutdown()
+		return nil, types.ErrNotSupported
+	}
+
+	return &tracing.EventTracingAttr{
+		TracingData: &nvidiaGpuCollector{
+			devices:     devices,
+			xids:        make(map[nvidiaXidKey]uint64),
+			lastSamples: make([]uint64, len(devices)),
+		},
+		Flag: tracing.FlagMetric,
+	}, nil
+}
+
+func (n *nvidiaGpuCollector) Update() ([]*metric.Data, error) {
+	n.xidOnce.Do(func() { go n.watchXid() })
+
+	ctx := context.Background()
+	var metrics []*metric.Data
+
+	// Driver and CUDA version
+	operationGetDriverVersion := "get driver version"
+	driverVersion, err := nvml.GetDriverVersion()
+	if err != nil {
+		if !nvml.IsNotSupported(err) {
+			return nil, fmt.Errorf("failed to %s: %w", operationGetDriverVersion, err)
+		}
+		log.Debugf("operation %s not supported", operationGetDriverVersion)
+	} else {
+		metrics = append(metrics, metric.NewGaugeData("driver_info", 1, "GPU driver info.", map[string]string{
+			"version": driverVersion,
+		}))
+	}
+
+	operationGetCudaVersion := "get cuda version"
+	cudaVersion, err := nvml.GetCudaVersion()
+	if err != nil {
+		if !nvml.IsNotSupported(err) {
+			return nil, fmt.Errorf("failed to %s: %w", operationGetCudaVersion, err)
+		}
+		log.Debugf("operation %s not supported", operationGetCudaVersion)
+	} else {
+		metrics = append(metrics, metric.NewGaugeData("sdk_info", 1, "GPU SDK info.", map[string]string{
+			"version": cudaVersion,
+		}))
+	}
+
+	// GPU
+	for i, dev := range n.devices {
+		gpuMetrics, err := nvidiaCollectGpuMetrics(ctx, i, dev)
+		if err != nil {
+			return nil, fmt.Errorf("failed to collect gpu %d metrics: %w", i, err)
+		}
+		metrics = append(metrics, gpuMetrics...)
+	}
+
+	// Topology
+	topologyMetrics, err := nvidiaCollectTopologyMetrics(ctx, n.devices)
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect topology metrics: %w", err)
+	}
+	metrics = append(metrics, topologyMetrics...)
+
+	// Containers
+	containerMetrics, err := n.collectContainerMetrics(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect container metrics: %w", err)
+	}
+	metrics = append(metrics, containerMetrics...)
+
+	// Xid errors
+	n.xidMu.Lock()
+	for key, count := range n.xids {
+		metrics = append(metrics, metric.NewCounterData("xid_errors_total", float64(count), "GPU Xid errors count.", map[string]string{
+			"gpu": strconv.Itoa(key.gpu),
+			"xid": strconv.FormatUint(key.xid, 10),
+		}))
+	}
+	n.xidMu.Unlock()
+
+	return metrics, nil
+}
+
+// watchXid counts the Xid errors for the lifetime of the agent.
+func (n *nvidiaGpuCollector) watchXid() {
+	index := make(map[nvml.Device]int, len(n.devices))
+	for i, dev := range n.devices {
+		index[dev] = i
+	}
+
+	err := nvml.WatchXidEvents(context.Background(), n.devices, func(event nvml.XidEvent) {
+		gpu, ok := index[event.Device]
+		if !ok {
+			return
+		}
+
+		log.Warnf("nvidia gpu %d reported xid %d", gpu, event.Xid)
+
+		n.xidMu.Lock()
+		n.xids[nvidiaXidKey{gpu: gpu, xid: event.Xid}]++
+		n.xidMu.Unlock()
+	})
+	if err != nil {
+		log.Warnf("nvidia gpu xid events: %v", err)
+	}
+}
+
+// collectContainerMetrics attributes the memory and utilization of the GPU
+// processes to their containers, the host processes are left out.
+func (n *nvidiaGpuCollector) collectContainerMetrics(ctx context.Context) ([]*metric.Data, error) {
+	n.samplesMu.Lock()
+	defer n.samplesMu.Unlock()
+
+	owners := make(map[uint32]*pod.Container)
+	owner := func(pid uint32) *pod.Container {
+		container, ok := owners[pid]
+		if !ok {
+			// the process may be gone already.
+			container, _ = pod.ContainerByPid(int(pid))
+			owners[pid] = container
+		}
+		return container
+	}
+
+	var metrics []*metric.Data
+	for i, dev := range n.devices {
+		gpuLabel := strconv.Itoa(i)
+
+		operationListProcesses := "list processes"
+		processes, err := nvml.ListProcesses(ctx, dev)
+		if err != nil {
+			if !nvml.IsNotSupported(err) {
+				return nil, fmt.Errorf("failed to %s on gpu %d: %w", operationListProcesses, i, err)
+			}
+			log.Debugf("operation %s not supported on gpu %d", operationListProcesses, i)
+			continue
+		}
+
+		operationListProcessSamples := "list process samples"
+		samples, err := nvml.ListProcessSamples(ctx, dev, n.lastSamples[i])
+		if err != nil {
+			if !nvml.IsNotSupported(err) {
+				return nil, fmt.Errorf("failed to %s on gpu %d: %w", operationListProcessSamples, i, err)
+			}
+			log.Debugf("operation %s not supported on gpu %d", operationListProcessSamples, i)
+		}
+		for j := range samples {
+			n.lastSamples[i] = max(n.lastSamples[i], samples[j].TimeStamp)
+		}
+
+		for container, usage := range nvidiaContainerUsages(processes, samples, owner) {
+			metrics = append(
+				metrics,
+				metric.NewContainerGaugeData(container, "gpu_memory_used_bytes", float64(usage.memory), "Used vram by the container processes.", map[string]string{
+					"gpu": gpuLabel,
+				}),
+				metric.NewContainerGaugeData(container, "gpu_utilization_percent", min(usage.smUtil, 100), "GPU utilization by the container processes, ranging from 0 to 100.", map[string]string{
+					"gpu": gpuLabel,
+					"ip":  "gpu",
+				}),
+				metric.NewContainerGaugeData(container, "gpu_utilization_percent", min(usage.memUtil, 100), "GPU utilization by the container processes, ranging from 0 to 100.", map[string]string{
+					"gpu": gpuLabel,
+					"ip":  "memory",
+				}),
+			)
+		}
+	}
+
+	return metrics, nil
+}
+
+// nvidiaContainerUsages sums the usage of the processes by container. The
+// utilization of a process is the average of its samples since the last
+// collection.
+func nvidiaContainerUsages(processes []nvml.ProcessInfo, samples []nvml.ProcessUtilizationSample, owner func(pid uint32) *pod.Container) map[*pod.Container]*nvidiaContainerUsage {
+	usages := make(map[*pod.Container]*nvidiaContainerUsage)
+	usageOf := func(pid uint32) *nvidiaContainerUsage {
+		container := owner(pid)
+		if container == nil {
+			return nil
+		}
+		usage, ok := usages[container]
+		if !ok {
+			usage = &nvidiaContainerUsage{}
+			usages[container] = usage
+		}
+		return usage
+	}
+
+	for _, process := range processes {
+		usage := usageOf(process.Pid)
+		if usage == nil {
+			continue
+		}
+		if process.UsedGpuMemory != nvml.ValueNotAvailable {
+			usage.memory += process.UsedGpuMemory
+		}
+	}
+
+	type processSamples struct {
+		smUtil, memUtil uint64
+		count           uint64
+	}
+	byPid := make(map[uint32]*processSamples)
+	for _, sample := range samples {
+		s, ok := byPid[sample.Pid]
+		if !ok {
+			s = &processSamples{}
+			byPid[sample.Pid] = s
+		}
+		s.smUtil += uint64(sample.SmUtil)
+		s.memUtil += uint64(sample.MemUtil)
+		s.count++
+	}
+	for pid, s := range byPid {
+		usage := usageOf(pid)
+		if usage == nil {
+			continue
+		}
+		usage.smUtil += float64(s.smUtil) / float64(s.count)
+		usage.memUtil += float64(s.memUtil) / float64(s.count)
+	}
+
+	return usages
+}
+
+// nvidiaCollectGpuMetrics gathers raw GPU metrics for a single GPU.
+// nvidiaCollectTopologyMetrics exports the NUMA node, PCIe root complex and
+// NVLink peers of every GPU.
+func nvidiaCollectTopologyMetrics(ctx context.Context, devices []nvml.Device) ([]*metric.Data, error) {
+	bdfs := make([]string, len(devices))
+	gpuByBDF := make(map[string]int, len(devices))
+	for i, dev := range devices {
+		info, err := nvml.GetInfo(ctx, dev)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get gpu %d info: %w", i, err)
+		}
+		bdfs[i] = info.BDF
+		gpuByBDF[gpuSysfsBDF(info.BDF)] = i
+	}
+
+	var metrics []*metric.Data
+	for i, dev := range devices {
+		remotes, err := nvml.ListNvLinkRemotes(ctx, dev)
+		if err != nil && !nvml.IsNotSupported(err) {
+			return nil, fmt.Errorf("failed to list gpu %d nvlink remotes: %w", i, err)
+		}
+
+		peers, switchLinks := nvidiaNvLinkPeers(remotes, gpuByBDF)
+		labels := gpuTopologyLabels(strconv.Itoa(i), bdfs[i])
+		labels["nvlink_peers"] = peers
+		labels["nvswitch_links"] = strconv.Itoa(switchLinks)
+		metrics = append(metrics, metric.NewGaugeData("topology_info", 1, "GPU topology info.", labels))
+	}
+	return metrics, nil
+}
+
+// nvidiaNvLinkPeers returns the sorted indexes of the GPUs at the other end
+// of the links, and the links to devices which are not GPUs, the NVSwitches.
+func nvidiaNvLinkPeers(remotes []nvml.NvLinkRemote, gpuByBDF map[string]int) (string, int) {
+	var peers []int
+	switchLinks := 0
+	for _, remote := range remotes {
+		gpu, ok := gpuByBDF[gpuSysfsBDF(remote.BDF)]
+		if !ok {
+			switchLinks++
+			continue
+		}
+		if !slices.Contains(peers, gpu) {
+			peers = append(peers, gpu)
+		}
+	}
+	slices.Sort(peers)
+
+	labels := make([]string, 0, len(peers))
+	for _, gpu := range peers {
+		labels = append(labels, strconv.Itoa(gpu))
+	}
+	return strings.Join(labels, ","), switchLinks
+}
+
+func nvidiaCollectGpuMetrics(ctx context.Context, gpuId int, dev nvml.Device) ([]*metric.Data, error) {
+	var metrics []*metric.Data
+	gpuLabel := strconv.Itoa(gpuId)
+
+	// GPU info
+	info, err := nvml.GetInfo(ctx, dev)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get gpu info: %w", err)
+	}
+	metrics = append(metrics, metric.NewGaugeData("info", 1, "GPU info.", map[string]string{
+		"gpu":          gpuLabel,
+		"model":        info.Name,
+		"uuid":         info.UUID,
+		"bios_version": info.BiosVersion,
+		"bdf":          info.BDF,
+	}))
+
+	// Utilization
+	operationGetUtilization := "get utilization"
+	utilization, err := nvml.GetUtilization(ctx, dev)
+	if err != nil {
+		if !nvml.IsNotSupported(err) {
+			return nil, fmt.Errorf("failed to %s: %w", operationGetUtilization, err)
+		}
+		log.Debugf("operation %s not supported on gpu %d", operationGetUtilization, gpuId)
+	} else {
+		metrics = append(
+			metrics,
+			metric.NewGaugeData("utilization_percent", float64(utilization.Gpu), "GPU utilization, ranging from 0 to 100.", map[string]string{
+				"gpu": gpuLabel,
+				"ip":  "gpu",
+			}),
+			metric.NewGaugeData("utilization_percent", float64(utilization.Memory), "GPU utilization, ranging from 0 to 100.", map[string]string{
+				"gpu": gpuLabel,
+				"ip":  "memory",
+			}),
+		)
+	}
+
+	// Memory
+	operationGetMemory := "get memory info"
+	memory, err := nvml.GetMemory(ctx, dev)
+	if err != nil {
+		if !nvml.IsNotSupported(err) {
+			return nil, fmt.Errorf("failed to %s: %w", operationGetMemory, err)
+		}
+		log.Debugf("operation %s not supported on gpu %d", operationGetMemory, gpuId)
+	} else {
+		metrics = append(
+			metrics,
+			metric.NewGaugeData("memory_total_bytes", float64(memory.Total), "Total vram.", map[string]string{
+				"gpu": gpuLabel,
+			}),
+			metric.NewGaugeData("memory_used_bytes", float64(memory.Used), "Used vram.", map[string]string{
+				"gpu": gpuLabel,
+			}),
+		)
+	}
+
+	// Board power
+	operationGetPower := "get power usage"
+	power, err := nvml.GetPower(ctx, dev)
+	if err != nil {
+		if !nvml.IsNotSupported(err) {
+			return nil, fmt.Errorf("failed to %s: %w", operationGetPower, err)
+		}
+		log.Debugf("operation %s not supported on gpu %d", operationGetPower, gpuId)
+	} else {
+		metrics = append(metrics, metric.NewGaugeData("board_power_watts", float64(power)/1000, "GPU board power.", map[string]string{
+			"gpu": gpuLabel,
+		}))
+	}
+
+	// Temperature
+	operationGetTemperature := "get temperature"
+	temperature, err := nvml.GetTemperature(ctx, dev)
+	if err != nil {
+		if !nvml.IsNotSupported(err) {
+			return nil, fmt.Errorf("failed to %s: %w", operationGetTemperature, err)
+		}
+		log.Debugf("operation %s not supported on gpu %d", operationGetTemperature, gpuId)
+	} else {
+		metrics = append(metrics, metric.NewGaugeData("temperature_celsius", float64(temperature), "GPU temperature.", map[string]string{
+			"gpu": gpuLabel,
+		}))
+	}
+
+	// Ecc memory, not supported when ECC is disabled.
+	for errorType, errorTypeC := range map[string]nvml.MemoryErrorType{
+		"ce": nvml.MemoryErrorTypeCorrected,
+		"ue": nvml.MemoryErrorTypeUncorrected,
+	} {
+		operationGetEccErrors := fmt.Sprintf("get %s ecc errors", errorType)
+		count, err := nvml.GetEccErrors(ctx, dev, errorTypeC)
+		if err != nil {
+			if !nvml.IsNotSupported(err) {
+				return nil, fmt.Errorf("failed to %s: %w", operationGetEccErrors, err)
+			}
+			log.Debugf("operation %s not supported on gpu %d", operationGetEccErrors, gpuId)
+			continue
+		}
+
+		metrics = append(metrics, metric.NewCounterData("ecc_memory_errors_total", float64(count), "GPU ECC memory errors count.", map[string]string{
+			"gpu":        gpuLabel,
+			"error_type": errorType,
+		}))
+	}
+
+	// NVLink throughput
+	operationListNvLinkThroughputs := "list nvlink throughputs"
+	links, err := nvml.ListNvLinkThroughputs(ctx, dev)
+	if err != nil {
+		if !nvml.IsNotSupported(err) {
+			return nil, fmt.Errorf("failed to %s: %w", operationListNvLinkThroughputs, err)
+		}
+		log.Debugf("operation %s not supported on gpu %d", operationListNvLinkThroughputs, gpuId)
+	} else {
+		for _, link := range links {
+			metrics = append(
+				metrics,
+				metric.NewCounterData("nvlink_receive_bytes_total", float64(link.Receive), "GPU NVLink receive data size.", map[string]string{
+					"gpu":    gpuLabel,
+					"nvlink": strconv.Itoa(int(link.Link)),
+				}),
+				metric.NewCounterData("nvlink_transmit_bytes_total", float64(link.Transmit), "GPU NVLink transmit data size.", map[string]string{
+					"gpu":    gpuLabel,
+					"nvlink": strconv.Itoa(int(link.Link)),
+				}),
+			)
+		}
+	}
+
+	return metrics, nil
+}
@@ -0,0 +1,146 @@
+// Copyright 2026 The HuaTuo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"huatuo-bamai/core/metrics/nvidia/nvml"
+	"huatuo-bamai/internal/pod"
+)
+
+// fakeNvml replaces the NVML API by fixed readings, restored at the end of
+// the test.
+func fakeNvml(t *testing.T) {
+	getInfo, getUtilization, getMemory := nvml.GetInfo, nvml.GetUtilization, nvml.GetMemory
+	getPower, getTemperature, getEccErrors := nvml.GetPower, nvml.GetTemperature, nvml.GetEccErrors
+	listNvLinkThroughputs, watchXidEvents := nvml.ListNvLinkThroughputs, nvml.WatchXidEvents
+	t.Cleanup(func() {
+		nvml.GetInfo, nvml.GetUtilization, nvml.GetMemory = getInfo, getUtilization, getMemory
+		nvml.GetPower, nvml.GetTemperature, nvml.GetEccErrors = getPower, getTemperature, getEccErrors
+		nvml.ListNvLinkThroughputs, nvml.WatchXidEvents = listNvLinkThroughputs, watchXidEvents
+	})
+
+	nvml.GetInfo = func(context.Context, nvml.Device) (nvml.Info, error) {
+		return nvml.Info{Name: "NVIDIA H100", UUID: "GPU-0", BDF: "0000:18:00.0"}, nil
+	}
+	nvml.GetUtilization = func(context.Context, nvml.Device) (nvml.Utilization, error) {
+		return nvml.Utilization{Gpu: 80, Memory: 30}, nil
+	}
+	nvml.GetMemory = func(context.Context, nvml.Device) (nvml.Memory, error) {
+		return nvml.Memory{Total: 80 << 30, Used: 20 << 30}, nil
+	}
+	nvml.GetPower = func(context.Context, nvml.Device) (uint32, error) { return 350_000, nil }
+	nvml.GetTemperature = func(context.Context, nvml.Device) (uint32, error) { return 65, nil }
+	nvml.GetEccErrors = func(_ context.Context, _ nvml.Device, errorType nvml.MemoryErrorType) (uint64, error) {
+		if errorType == nvml.MemoryErrorTypeCorrected {
+			return 7, nil
+		}
+		return 2, nil
+	}
+	nvml.ListNvLinkThroughputs = func(context.Context, nvml.Device) ([]nvml.NvLinkThroughput, error) {
+		return []nvml.NvLinkThroughput{{Link: 0, Receive: 1000, Transmit: 3000}}, nil
+	}
+}
+
+func TestNvidiaCollectGpuMetrics(t *testing.T) {
+	fakeNvml(t)
+
+	data, err := nvidiaCollectGpuMetrics(context.Background(), 0, 1)
+	if err != nil {
+		t.Fatalf("nvidiaCollectGpuMetrics() error = %v", err)
+	}
+
+	values := make(map[float64]bool, len(data))
+	for _, d := range data {
+		values[d.Value] = true
+	}
+	// utilization, vram, power in watts, temperature, ecc and nvlink.
+	for _, want := range []float64{80, 30, 80 << 30, 20 << 30, 350, 65, 7, 2, 1000, 3000} {
+		if !values[want] {
+			t.Errorf("nvidiaCollectGpuMetrics() has no metric of value %v", want)
+		}
+	}
+
+	// an unexpected error of the library fails the collection.
+	nvml.GetTemperature = func(context.Context, nvml.Device) (uint32, error) {
+		return 0, errors.New("nvmlDeviceGetTemperature failed")
+	}
+	if _, err := nvidiaCollectGpuMetrics(context.Background(), 0, 1); err == nil {
+		t.Error("nvidiaCollectGpuMetrics() with a failing temperature error = nil")
+	}
+}
+
+func TestNvidiaWatchXid(t *testing.T) {
+	fakeNvml(t)
+
+	nvml.WatchXidEvents = func(_ context.Context, _ []nvml.Device, fn func(nvml.XidEvent)) error {
+		fn(nvml.XidEvent{Device: 11, Xid: 79})
+		fn(nvml.XidEvent{Device: 11, Xid: 79})
+		fn(nvml.XidEvent{Device: 10, Xid: 48})
+		// a device not collected.
+		fn(nvml.XidEvent{Device: 12, Xid: 79})
+		return nil
+	}
+
+	n := &nvidiaGpuCollector{devices: []nvml.Device{10, 11}, xids: make(map[nvidiaXidKey]uint64)}
+	n.watchXid()
+
+	want := map[nvidiaXidKey]uint64{{gpu: 1, xid: 79}: 2, {gpu: 0, xid: 48}: 1}
+	if len(n.xids) != len(want) {
+		t.Errorf("xids = %v, want %v", n.xids, want)
+	}
+	for key, count := range want {
+		if n.xids[key] != count {
+			t.Errorf("xids[%+v] = %d, want %d", key, n.xids[key], count)
+		}
+	}
+}
+
+func TestNvidiaContainerUsages(t *testing.T) {
+	train := &pod.Container{ID: "train"}
+	serve := &pod.Container{ID: "serve"}
+	owners := map[uint32]*pod.Container{10: train, 11: train, 20: serve}
+	owner := func(pid uint32) *pod.Container { return owners[pid] }
+
+	processes := []nvml.ProcessInfo{
+		{Pid: 10, UsedGpuMemory: 1 << 30},
+		{Pid: 11, UsedGpuMemory: 2 << 30},
+		{Pid: 20, UsedGpuMemory: nvml.ValueNotAvailable},
+		// a host process.
+		{Pid: 1, UsedGpuMemory: 4 << 30},
+	}
+	samples := []nvml.ProcessUtilizationSample{
+		{Pid: 10, SmUtil: 40, MemUtil: 10},
+		{Pid: 10, SmUtil: 60, MemUtil: 30},
+		{Pid: 11, SmUtil: 20, MemUtil: 5},
+		{Pid: 20, SmUtil: 7, MemUtil: 3},
+		{Pid: 1, SmUtil: 90, MemUtil: 90},
+	}
+
+	usages := nvidiaContainerUsages(processes, samples, owner)
+	if len(usages) != 2 {
+		t.Fatalf("usages = %d containers, want 2", len(usages))
+	}
+
+	if u := usages[train]; u.memory != 3<<30 || u.smUtil != 70 || u.memUtil != 25 {
+		t.Errorf("train usage = %+v, want 3GiB, sm 70, mem 25", u)
+	}
+	if u := usages[serve]; u.memory != 0 || u.smUtil != 7 || u.memUtil != 3 {
+		t.Errorf("serve usage = %+v, want 0, sm 7, mem 3", u)
+	}
+}
@@ -0,0 +1,125 @@
+// Copyright 2026 The HuaTuo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"fmt"
+
+	"huatuo-bamai/internal/cgroups"
+	"huatuo-bamai/internal/cgroups/stats"
+	"huatuo-bamai/internal/log"
+	"huatuo-bamai/internal/pod"
+	"huatuo-bamai/pkg/metric"
+	"huatuo-bamai/pkg/tracing"
+	"huatuo-bamai/pkg/types"
+)
+
+// pressureResources are the resources of the pressure stall information.
+var pressureResources = []string{"cpu", "memory", "io"}
+
+type pressureCollector struct {
+	cgroup cgroups.Cgroup
+}
+
+func init() {
+	tracing.RegisterEventTracing("pressure", newPressure)
+}
+
+func newPressure() (*tracing.EventTracingAttr, error) {
+	if !stats.PSISupported() {
+		return nil, types.ErrNotSupported
+	}
+
+	cgroup, err := cgroups.NewManager()
+	if err != nil {
+		return nil, err
+	}
+
+	return &tracing.EventTracingAttr{
+		TracingData: &pressureCollector{
+			cgroup: cgroup,
+		},
+		Flag: tracing.FlagMetric,
+	}, nil
+}
+
+// pressureData returns the avg10, avg60 and total of the some and full
+// lines of psi, labelled by resource and kind.
+func pressureData(resource string, psi *stats.PSIStats,
+	gauge, counter func(name string, value float64, help string, label map[string]string) *metric.Data,
+) []*metric.Data {
+	var data []*metric.Data
+	for _, kind := range []struct {
+		name string
+		line *stats.PSILine
+	}{{"some", psi.Some}, {"full", psi.Full}} {
+		line := kind.line
+		if line == nil {
+			continue
+		}
+
+		label := map[string]string{"resource": resource, "kind": kind.name}
+		data = append(data,
+			gauge("avg10", line.Avg10, "percent of the time stalled over the last 10 seconds", label),
+			gauge("avg60", line.Avg60, "percent of the time stalled over the last 60 seconds", label),
+			counter("stall_seconds_total", float64(line.Total)/1e6, "time stalled", label))
+	}
+	return data
+}
+
+func (c *pressureCollector) Update() ([]*metric.Data, error) {
+	var data []*metric.Data
+	for _, resource := range pressureResources {
+		psi, err := stats.ReadHostPSI(resource)
+		if err != nil {
+			return nil, fmt.Errorf("read %s pressure: %w", resource, err)
+		}
+		data = append(data, pressureData(resource, psi, metric.NewGaugeData, metric.NewCounterData)...)
+	}
+
+	containers, err := pod.ContainersByType(pod.ContainerTypeNormal | pod.ContainerTypeSidecar)
+	if err != nil {
+		return nil, err
+	}
+
+	containerData, err := scanContainers("pressure", containers, func(container *pod.Container) ([]*metric.Data, error) {
+		gauge := func(name string, value float64, help string, label map[string]string) *metric.Data {
+			return metric.NewContainerGaugeData(container, name, value, help, label)
+		}
+		counter := func(name string, value float64, help string, label map[string]string) *metric.Data {
+			return metric.NewContainerCounterData(container, name, value, help, label)
+		}
+
+		var data []*metric.Data
+		for _, resource := range pressureResources {
+			psi, err := c.cgroup.Pressure(container.CgroupPath, resource)
+			if err != nil {
+				log.Infof("failed to get %s pressure of %s, %v", resource, container, err)
+				return nil, nil
+			}
+			// the cgroup v1 without psi.
+			if psi == nil {
+				continue
+			}
+			data = append(data, pressureData(resource, psi, gauge, counter)...)
+		}
+		return data, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return append(data, containerData...), nil
+}
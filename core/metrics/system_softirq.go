@@ -19,10 +19,16 @@ import (
 	"context"
 	"encoding/binary"
 	"fmt"
+	"sort"
 	"strconv"
 	"sync/atomic"
+	"time"
 
 	"huatuo-bamai/internal/bpf"
+	"huatuo-bamai/internal/log"
+	"huatuo-bamai/internal/procfs"
+	"huatuo-bamai/internal/symbol"
+	"huatuo-bamai/internal/utils/bytesutil"
 	"huatuo-bamai/pkg/metric"
 	"huatuo-bamai/pkg/tracing"
 
@@ -31,6 +37,7 @@ import (
 
 func init() {
 	tracing.RegisterEventTracing("softirq", newSoftirq)
+	tracing.RegisterSchema[SoftirqTracingData]("softirq", "softirq", 1)
 }
 
 func newSoftirq() (*tracing.EventTracingAttr, error) {
@@ -64,10 +71,57 @@ type softirqLatency struct {
 	cpuOnline   int
 }
 
+// softirqLatencyData is of bpf/system_softirq.c, the latency from the raise
+// to the entry of a softirq and its runtime from the entry to the exit.
 type softirqLatencyData struct {
 	Enable       uint64
 	Timestamp    uint64
 	TotalLatency [4]uint64
+	Entry        uint64
+	TotalRuntime [4]uint64
+}
+
+const (
+	softirqNetRxSlowMap   = "softirq_net_rx_slow"
+	softirqNetRxStacksMap = "softirq_net_rx_stacks"
+
+	softirqCheckInterval = 10 * time.Second
+)
+
+// softirqStackKey is the task running the slow NET_RX softirqs and its
+// kernel stack.
+type softirqStackKey struct {
+	pid     uint32
+	stackID int32
+	comm    string
+}
+
+type softirqStackValue struct {
+	count      uint64
+	maxRuntime uint64
+}
+
+// SoftirqTracingData is stored when the NET_RX softirqs run longer than
+// the threshold, of the slow runs since the last event.
+type SoftirqTracingData struct {
+	Threshold  uint64 `json:"threshold"`
+	SlowRuns   uint64 `json:"slow_runs"`
+	MaxRuntime uint64 `json:"max_runtime"`
+	// TimeSqueezed is the times the NET_RX softirqs of all the cpus ran
+	// out of their budget or time with packets still to process.
+	TimeSqueezed uint64  `json:"time_squeezed"`
+	DurationSecs float64 `json:"duration_secs"`
+	// Stacks are the tasks running the slow runs, ksoftirqd when the
+	// softirqs were deferred to it, and their stacks, the most first.
+	Stacks []*SoftirqStack `json:"stacks"`
+}
+
+type SoftirqStack struct {
+	Comm       string `json:"comm"`
+	Pid        uint32 `json:"pid"`
+	SlowRuns   uint64 `json:"slow_runs"`
+	MaxRuntime uint64 `json:"max_runtime"`
+	Stack      string `json:"stack"`
 }
 
 const (
@@ -130,7 +184,6 @@ func (s *softirqLatency) Update() ([]*metric.Data, error) {
 		return nil, fmt.Errorf("dump map: %w", err)
 	}
 
-	labels := make(map[string]string)
 	metricData := []*metric.Data{}
 
 	// IRQ: 0 ... NR_SOFTIRQS_MAX
@@ -150,25 +203,225 @@ func (s *softirqLatency) Update() ([]*metric.Data, error) {
 			return nil, fmt.Errorf("read map value: %w", err)
 		}
 
-		labels["type"] = irqTypeName(int(irqVector))
+		metricData = append(metricData, softirqLatencyMetrics(int(irqVector), latencyOnAllCPU, s.cpuOnline)...)
+	}
+
+	stats, err := softnetStat()
+	if err != nil {
+		return nil, err
+	}
+
+	return append(metricData, timeSqueezeData(stats)...), nil
+}
 
-		for cpuid, lat := range latencyOnAllCPU {
-			if cpuid >= s.cpuOnline {
-				break
-			}
-			labels["cpuid"] = strconv.Itoa(cpuid)
-			for zoneid, zone := range lat.TotalLatency {
-				labels["zone"] = strconv.Itoa(zoneid)
-				metricData = append(metricData, metric.NewGaugeData("latency", float64(zone), "softirq latency", labels))
-			}
+// softirqLatencyMetrics returns the latency and runtime histograms of the
+// vector on the online cpus.
+func softirqLatencyMetrics(irqVector int, latencyOnAllCPU []softirqLatencyData, cpuOnline int) []*metric.Data {
+	labels := map[string]string{"type": irqTypeName(irqVector)}
+	metricData := []*metric.Data{}
+
+	for cpuid, lat := range latencyOnAllCPU {
+		if cpuid >= cpuOnline {
+			break
+		}
+		labels["cpuid"] = strconv.Itoa(cpuid)
+		for zoneid, zone := range lat.TotalLatency {
+			labels["zone"] = strconv.Itoa(zoneid)
+			metricData = append(metricData, metric.NewGaugeData("latency", float64(zone), "softirq latency", labels))
+		}
+		for zoneid, zone := range lat.TotalRuntime {
+			labels["zone"] = strconv.Itoa(zoneid)
+			metricData = append(metricData, metric.NewGaugeData("runtime", float64(zone), "softirq runtime", labels))
 		}
 	}
 
-	return metricData, nil
+	return metricData
+}
+
+func softnetStat() ([]procfs.SoftnetStat, error) {
+	fs, err := procfs.NewDefaultFS()
+	if err != nil {
+		return nil, err
+	}
+
+	return fs.NetSoftnetStat()
+}
+
+// timeSqueezeData returns the time_squeeze of /proc/net/softnet_stat, the
+// times the NET_RX softirq of the cpu ran out of its netdev_budget or
+// netdev_budget_usecs with packets still to process. The kernels before
+// 5.14 have no cpu column, and list the online cpus in order.
+func timeSqueezeData(stats []procfs.SoftnetStat) []*metric.Data {
+	data := make([]*metric.Data, 0, len(stats))
+	for i, stat := range stats {
+		cpuid := i
+		if stat.Width >= 13 {
+			cpuid = int(stat.Index)
+		}
+		data = append(data, metric.NewCounterData("time_squeeze_total", float64(stat.TimeSqueezed),
+			"times the NET_RX softirq ran out of budget or time with work remaining",
+			map[string]string{"cpuid": strconv.Itoa(cpuid)}))
+	}
+	return data
+}
+
+func timeSqueezed(stats []procfs.SoftnetStat) uint64 {
+	var total uint64
+	for _, stat := range stats {
+		total += uint64(stat.TimeSqueezed)
+	}
+	return total
+}
+
+// netRxSlow returns the tasks and stacks of the slow NET_RX softirqs since
+// the last event.
+func (s *softirqLatency) netRxSlow() (map[softirqStackKey]softirqStackValue, error) {
+	items, err := s.bpf.DumpMapByName(softirqNetRxSlowMap)
+	if err != nil {
+		return nil, err
+	}
+
+	return softirqStacks(items), nil
+}
+
+func softirqStacks(items []bpf.MapItem) map[softirqStackKey]softirqStackValue {
+	stacks := make(map[softirqStackKey]softirqStackValue, len(items))
+	for _, item := range items {
+		if len(item.Key) < 8+bpf.TaskCommLen || len(item.Value) < 16 {
+			continue
+		}
+
+		key := softirqStackKey{
+			pid:     binary.LittleEndian.Uint32(item.Key),
+			stackID: int32(binary.LittleEndian.Uint32(item.Key[4:])),
+			comm:    bytesutil.ToStr(item.Key[8 : 8+bpf.TaskCommLen]),
+		}
+		stacks[key] = softirqStackValue{
+			count:      binary.LittleEndian.Uint64(item.Value),
+			maxRuntime: binary.LittleEndian.Uint64(item.Value[8:]),
+		}
+	}
+	return stacks
+}
+
+// topSoftirqStacks returns the topN stacks by their slow runs.
+func topSoftirqStacks(stacks map[softirqStackKey]softirqStackValue, topN int) []softirqStackKey {
+	keys := make([]softirqStackKey, 0, len(stacks))
+	for key := range stacks {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if stacks[keys[i]].count != stacks[keys[j]].count {
+			return stacks[keys[i]].count > stacks[keys[j]].count
+		}
+		return keys[i].pid < keys[j].pid
+	})
+
+	if len(keys) > topN {
+		keys = keys[:topN]
+	}
+	return keys
+}
+
+// checkNetRxSlow saves an event of the slow NET_RX softirqs every
+// EventInterval seconds at most, and clears them for the next one.
+func (s *softirqLatency) checkNetRxSlow(ctx context.Context) {
+	ticker := time.NewTicker(softirqCheckInterval)
+	defer ticker.Stop()
+
+	lastReport := time.Now()
+	var lastSqueezed uint64
+	if stats, err := softnetStat(); err == nil {
+		lastSqueezed = timeSqueezed(stats)
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		now := time.Now()
+		if now.Sub(lastReport) < time.Duration(cfg.Softirq.EventInterval)*time.Second {
+			continue
+		}
+
+		stacks, err := s.netRxSlow()
+		if err != nil {
+			log.Warnf("softirq dump %s: %v", softirqNetRxSlowMap, err)
+			continue
+		}
+		if len(stacks) == 0 {
+			continue
+		}
+
+		var squeezed uint64
+		if stats, err := softnetStat(); err == nil {
+			squeezed = timeSqueezed(stats)
+		}
+
+		data := &SoftirqTracingData{
+			Threshold:    cfg.Softirq.NetRxThreshold,
+			DurationSecs: now.Sub(lastReport).Seconds(),
+		}
+		if squeezed >= lastSqueezed {
+			data.TimeSqueezed = squeezed - lastSqueezed
+		}
+		for _, value := range stacks {
+			data.SlowRuns += value.count
+			data.MaxRuntime = max(data.MaxRuntime, value.maxRuntime)
+		}
+
+		s.report(data, stacks, now)
+		lastReport, lastSqueezed = now, squeezed
+	}
+}
+
+func (s *softirqLatency) report(data *SoftirqTracingData, stacks map[softirqStackKey]softirqStackValue, now time.Time) {
+	for _, key := range topSoftirqStacks(stacks, cfg.Softirq.TopStacks) {
+		data.Stacks = append(data.Stacks, &SoftirqStack{
+			Comm:       key.comm,
+			Pid:        key.pid,
+			SlowRuns:   stacks[key].count,
+			MaxRuntime: stacks[key].maxRuntime,
+			Stack:      symbol.KsymStackOfMap(s.bpf, softirqNetRxStacksMap, key.stackID),
+		})
+	}
+
+	if err := tracing.Save(&tracing.WriteRequest{
+		TracerName: "softirq",
+		TracerTime: now,
+		TracerData: data,
+	}); err != nil {
+		log.Warnf("failed to save tracing data: %v", err)
+	}
+
+	// the runs and stacks of the next event, the ones recorded meanwhile
+	// are lost.
+	keys := make([][]byte, 0, len(stacks))
+	stackIDs := make([][]byte, 0, len(stacks))
+	for key := range stacks {
+		k := make([]byte, 8+bpf.TaskCommLen)
+		binary.LittleEndian.PutUint32(k, key.pid)
+		binary.LittleEndian.PutUint32(k[4:], uint32(key.stackID))
+		copy(k[8:], key.comm)
+		keys = append(keys, k)
+
+		if key.stackID >= 0 {
+			stackIDs = append(stackIDs, binary.LittleEndian.AppendUint32(nil, uint32(key.stackID)))
+		}
+	}
+	if err := s.bpf.DeleteMapItems(s.bpf.MapIDByName(softirqNetRxSlowMap), keys); err != nil {
+		log.Warnf("softirq clear %s: %v", softirqNetRxSlowMap, err)
+	}
+	if err := s.bpf.DeleteMapItems(s.bpf.MapIDByName(softirqNetRxStacksMap), stackIDs); err != nil {
+		log.Warnf("softirq clear %s: %v", softirqNetRxStacksMap, err)
+	}
 }
 
 func (s *softirqLatency) Start(ctx context.Context) error {
-	b, err := bpf.LoadBpf(bpf.ThisBpfOBJ(), nil)
+	b, err := bpf.LoadBpf(bpf.ThisBpfOBJ(), map[string]any{"net_rx_thresh": cfg.Softirq.NetRxThreshold})
 	if err != nil {
 		return err
 	}
@@ -186,6 +439,10 @@ func (s *softirqLatency) Start(ctx context.Context) error {
 
 	b.WaitDetachByBreaker(childCtx, cancel)
 
+	if cfg.Softirq.NetRxThreshold > 0 {
+		go s.checkNetRxSlow(childCtx)
+	}
+
 	<-childCtx.Done()
 
 	s.running.Store(false)
@@ -0,0 +1,95 @@
+// Copyright 2026 The HuaTuo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"encoding/binary"
+	"testing"
+
+	"huatuo-bamai/internal/bpf"
+	"huatuo-bamai/internal/procfs"
+)
+
+func TestSoftirqLatencyMetrics(t *testing.T) {
+	lats := []softirqLatencyData{
+		{TotalLatency: [4]uint64{10, 2, 0, 0}, TotalRuntime: [4]uint64{8, 3, 1, 0}},
+		{TotalLatency: [4]uint64{5, 0, 0, 0}, TotalRuntime: [4]uint64{5, 0, 0, 1}},
+		// a possible cpu not online.
+		{TotalLatency: [4]uint64{1, 1, 1, 1}},
+	}
+
+	data := softirqLatencyMetrics(softirqNetRx, lats, 2)
+	want := []float64{
+		10, 2, 0, 0, 8, 3, 1, 0, // cpu 0, latency and runtime
+		5, 0, 0, 0, 5, 0, 0, 1, // cpu 1
+	}
+	if len(data) != len(want) {
+		t.Fatalf("softirqLatencyMetrics() returned %d metrics, want %d", len(data), len(want))
+	}
+	for i, v := range want {
+		if data[i].Value != v {
+			t.Errorf("softirqLatencyMetrics()[%d] = %v, want %v", i, data[i].Value, v)
+		}
+	}
+}
+
+func TestTimeSqueezeData(t *testing.T) {
+	stats := []procfs.SoftnetStat{
+		{TimeSqueezed: 3, Index: 0, Width: 13},
+		{TimeSqueezed: 7, Index: 2, Width: 13},
+	}
+
+	data := timeSqueezeData(stats)
+	if len(data) != 2 || data[0].Value != 3 || data[1].Value != 7 {
+		t.Fatalf("timeSqueezeData() = %v", data)
+	}
+	if total := timeSqueezed(stats); total != 10 {
+		t.Errorf("timeSqueezed() = %d, want 10", total)
+	}
+}
+
+func softirqStackItem(pid uint32, stackID int32, comm string, count, maxRuntime uint64) bpf.MapItem {
+	key := make([]byte, 8+bpf.TaskCommLen)
+	binary.LittleEndian.PutUint32(key, pid)
+	binary.LittleEndian.PutUint32(key[4:], uint32(stackID))
+	copy(key[8:], comm)
+
+	value := binary.LittleEndian.AppendUint64(nil, count)
+	value = binary.LittleEndian.AppendUint64(value, maxRuntime)
+	return bpf.MapItem{Key: key, Value: value}
+}
+
+func TestSoftirqStacks(t *testing.T) {
+	stacks := softirqStacks([]bpf.MapItem{
+		softirqStackItem(16, 3, "ksoftirqd/1", 5, 4000000),
+		softirqStackItem(1200, -17, "nginx", 9, 2500000),
+		softirqStackItem(22, 3, "ksoftirqd/2", 1, 3000000),
+		// truncated.
+		{Key: []byte{1, 2, 3}, Value: []byte{1}},
+	})
+	if len(stacks) != 3 {
+		t.Fatalf("softirqStacks() returned %d stacks, want 3", len(stacks))
+	}
+
+	ksoftirqd := softirqStackKey{pid: 16, stackID: 3, comm: "ksoftirqd/1"}
+	if v := stacks[ksoftirqd]; v.count != 5 || v.maxRuntime != 4000000 {
+		t.Errorf("stacks[%+v] = %+v", ksoftirqd, v)
+	}
+
+	top := topSoftirqStacks(stacks, 2)
+	if len(top) != 2 || top[0].comm != "nginx" || top[0].stackID != -17 || top[1] != ksoftirqd {
+		t.Errorf("topSoftirqStacks() = %+v", top)
+	}
+}
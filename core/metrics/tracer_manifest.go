@@ -0,0 +1,546 @@
+// Copyright 2026 The HuaTuo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"text/template"
+	"time"
+
+	"sigs.k8s.io/yaml"
+
+	"huatuo-bamai/internal/bpf"
+	"huatuo-bamai/internal/cgroups/subsystem"
+	"huatuo-bamai/internal/log"
+	"huatuo-bamai/internal/pod"
+	"huatuo-bamai/pkg/metric"
+	"huatuo-bamai/pkg/tracing"
+	"huatuo-bamai/pkg/types"
+)
+
+func init() {
+	tracing.RegisterEventTracing("tracer_manifest", newTracerManifest)
+}
+
+//go:generate $BPF_COMPILE $BPF_INCLUDE -s $BPF_DIR/tracer_manifest.c -o $BPF_DIR/tracer_manifest.o
+
+const (
+	// keep in sync with bpf/tracer_manifest.c
+	manifestMaxFilters  = 4
+	manifestProgramName = "tracepoint_manifest_probe"
+	manifestMapName     = "manifest_counts"
+
+	manifestAggregationCount = "count"
+	manifestDefaultTemplate  = "{{.Manifest}}: {{.Count}} events in {{.Container}}, {{printf \"%.1f\" .Rate}}/s"
+)
+
+var (
+	manifestNameRegexp = regexp.MustCompile(`^[a-z0-9][a-z0-9_]*$`)
+	// filter operators, the value is the operator index in the bpf program.
+	manifestFilterOps = map[string]uint32{"eq": 0, "ne": 1, "gt": 2, "ge": 3, "lt": 4, "le": 5}
+	tracefsEventDirs  = []string{"/sys/kernel/tracing/events", "/sys/kernel/debug/tracing/events"}
+)
+
+// tracerManifest is a tracer defined in yaml instead of Go: count the hits
+// of a tracepoint per container and save an event when the rate of a
+// container crosses the threshold.
+type tracerManifest struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Probe       struct {
+		// Tracepoint is <system>/<event>.
+		Tracepoint string `json:"tracepoint"`
+	} `json:"probe"`
+	Filters     []manifestFilter `json:"filters"`
+	Aggregation string           `json:"aggregation"`
+	Threshold   struct {
+		// Rate is events per second of a single container, 0 never fires.
+		Rate float64 `json:"rate"`
+	} `json:"threshold"`
+	Event struct {
+		Template string `json:"template"`
+	} `json:"event"`
+}
+
+// manifestFilter compares an integer field of the tracepoint record, the
+// comparison is unsigned.
+type manifestFilter struct {
+	Field string `json:"field"`
+	Op    string `json:"op"`
+	Value int64  `json:"value"`
+}
+
+// tracepointField is a field of the tracepoint format file.
+type tracepointField struct {
+	Offset uint32
+	Size   uint32
+	Signed bool
+}
+
+// TracerManifestEvent is saved when a container crosses the rate threshold
+// of a manifest.
+type TracerManifestEvent struct {
+	Manifest   string  `json:"manifest"`
+	Tracepoint string  `json:"tracepoint"`
+	Count      uint64  `json:"count"`
+	Rate       float64 `json:"rate"`
+	Threshold  float64 `json:"threshold"`
+	Message    string  `json:"message"`
+}
+
+// manifestTemplateData is what the event template is rendered with.
+type manifestTemplateData struct {
+	Manifest  string
+	Container string
+	Count     uint64
+	Rate      float64
+	Threshold float64
+}
+
+type manifestProbe struct {
+	manifest *tracerManifest
+	tmpl     *template.Template
+	consts   map[string]any
+
+	bpf      bpf.BPF
+	last     map[uint64]uint64
+	lastTime time.Time
+}
+
+type tracerManifestTracer struct {
+	probes  []*manifestProbe
+	running atomic.Bool
+}
+
+func newTracerManifest() (*tracing.EventTracingAttr, error) {
+	if cfg.TracerManifest.Dir == "" {
+		return nil, types.ErrNotSupported
+	}
+
+	probes, err := loadManifestProbes(cfg.TracerManifest.Dir)
+	if err != nil {
+		return nil, err
+	}
+	if len(probes) == 0 {
+		return nil, types.ErrNotSupported
+	}
+
+	return &tracing.EventTracingAttr{
+		TracingData: &tracerManifestTracer{probes: probes},
+		Interval:    10,
+		Flag:        tracing.FlagTracing | tracing.FlagMetric,
+	}, nil
+}
+
+// loadManifestProbes loads every *.yaml manifest of dir. Invalid manifests
+// and tracepoints the kernel does not have are skipped.
+func loadManifestProbes(dir string) ([]*manifestProbe, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.yaml"))
+	if err != nil {
+		return nil, err
+	}
+
+	var probes []*manifestProbe
+	names := map[string]string{}
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+
+		manifest, err := parseTracerManifest(data)
+		if err != nil {
+			log.Warnf("tracer manifest %s: %v", file, err)
+			continue
+		}
+		if other, ok := names[manifest.Name]; ok {
+			log.Warnf("tracer manifest %s: name %q already used by %s", file, manifest.Name, other)
+			continue
+		}
+
+		probe, err := newManifestProbe(manifest)
+		if err != nil {
+			log.Warnf("tracer manifest %s: %v", file, err)
+			continue
+		}
+
+		names[manifest.Name] = file
+		probes = append(probes, probe)
+	}
+
+	return probes, nil
+}
+
+func parseTracerManifest(data []byte) (*tracerManifest, error) {
+	var m tracerManifest
+	if err := yaml.UnmarshalStrict(data, &m); err != nil {
+		return nil, err
+	}
+
+	if !manifestNameRegexp.MatchString(m.Name) {
+		return nil, fmt.Errorf("name %q must match %s", m.Name, manifestNameRegexp)
+	}
+	if parts := strings.Split(m.Probe.Tracepoint, "/"); len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return nil, fmt.Errorf("probe.tracepoint %q must be <system>/<event>", m.Probe.Tracepoint)
+	}
+	if len(m.Filters) > manifestMaxFilters {
+		return nil, fmt.Errorf("at most %d filters are supported", manifestMaxFilters)
+	}
+	for _, f := range m.Filters {
+		if _, ok := manifestFilterOps[f.Op]; !ok {
+			return nil, fmt.Errorf("filter %s: unknown op %q", f.Field, f.Op)
+		}
+	}
+
+	if m.Aggregation == "" {
+		m.Aggregation = manifestAggregationCount
+	}
+	if m.Aggregation != manifestAggregationCount {
+		return nil, fmt.Errorf("aggregation %q is not supported", m.Aggregation)
+	}
+	if m.Threshold.Rate < 0 {
+		return nil, fmt.Errorf("threshold.rate must not be negative")
+	}
+	if m.Event.Template == "" {
+		m.Event.Template = manifestDefaultTemplate
+	}
+
+	return &m, nil
+}
+
+func newManifestProbe(m *tracerManifest) (*manifestProbe, error) {
+	tmpl, err := template.New(m.Name).Parse(m.Event.Template)
+	if err != nil {
+		return nil, fmt.Errorf("event.template: %w", err)
+	}
+
+	fields, err := readTracepointFields(m.Probe.Tracepoint)
+	if err != nil {
+		return nil, err
+	}
+
+	consts, err := manifestFilterConsts(m.Filters, fields)
+	if err != nil {
+		return nil, err
+	}
+
+	return &manifestProbe{manifest: m, tmpl: tmpl, consts: consts}, nil
+}
+
+// manifestFilterConsts resolves the filters to the constants of the bpf
+// program.
+func manifestFilterConsts(filters []manifestFilter, fields map[string]tracepointField) (map[string]any, error) {
+	var (
+		offsets [manifestMaxFilters]uint32
+		masks   [manifestMaxFilters]uint64
+		ops     [manifestMaxFilters]uint32
+		values  [manifestMaxFilters]uint64
+	)
+
+	for i, f := range filters {
+		field, ok := fields[f.Field]
+		if !ok {
+			return nil, fmt.Errorf("filter: tracepoint has no field %q", f.Field)
+		}
+		switch field.Size {
+		case 1, 2, 4:
+			masks[i] = 1<<(field.Size*8) - 1
+		case 8:
+			masks[i] = ^uint64(0)
+		default:
+			return nil, fmt.Errorf("filter: field %q of %d bytes is not an integer", f.Field, field.Size)
+		}
+
+		offsets[i] = field.Offset
+		ops[i] = manifestFilterOps[f.Op]
+		values[i] = uint64(f.Value) & masks[i]
+	}
+
+	return map[string]any{
+		"filter_count":  uint32(len(filters)),
+		"filter_offset": offsets,
+		"filter_mask":   masks,
+		"filter_op":     ops,
+		"filter_value":  values,
+	}, nil
+}
+
+func readTracepointFields(tracepoint string) (map[string]tracepointField, error) {
+	for _, dir := range tracefsEventDirs {
+		f, err := os.Open(filepath.Join(dir, tracepoint, "format"))
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				continue
+			}
+			return nil, err
+		}
+		defer f.Close()
+
+		return parseTracepointFormat(f)
+	}
+
+	return nil, fmt.Errorf("tracepoint %s not found", tracepoint)
+}
+
+// parseTracepointFormat parses the fields of a tracefs format file:
+//
+//	field:pid_t child_pid;	offset:44;	size:4;	signed:1;
+func parseTracepointFormat(r io.Reader) (map[string]tracepointField, error) {
+	fields := map[string]tracepointField{}
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if !strings.HasPrefix(line, "field:") {
+			continue
+		}
+
+		var (
+			name  string
+			field tracepointField
+		)
+		for _, part := range strings.Split(line, ";") {
+			key, value, ok := strings.Cut(strings.TrimSpace(part), ":")
+			if !ok {
+				continue
+			}
+
+			switch key {
+			case "field":
+				decl := strings.Fields(value)
+				if len(decl) == 0 {
+					continue
+				}
+				name = decl[len(decl)-1]
+			case "offset", "size", "signed":
+				n, err := strconv.ParseUint(value, 10, 32)
+				if err != nil {
+					return nil, fmt.Errorf("parse %q: %w", line, err)
+				}
+				switch key {
+				case "offset":
+					field.Offset = uint32(n)
+				case "size":
+					field.Size = uint32(n)
+				case "signed":
+					field.Signed = n == 1
+				}
+			}
+		}
+
+		// arrays such as char comm[16] cannot be filtered on.
+		if name == "" || strings.Contains(name, "[") {
+			continue
+		}
+		fields[name] = field
+	}
+
+	return fields, scanner.Err()
+}
+
+func (t *tracerManifestTracer) Start(ctx context.Context) error {
+	childCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	for _, p := range t.probes {
+		obj, err := bpf.LoadBpf(bpf.ThisBpfOBJ(), p.consts)
+		if err != nil {
+			return fmt.Errorf("manifest %s: %w", p.manifest.Name, err)
+		}
+		defer obj.Close()
+
+		if err := obj.AttachWithOptions([]bpf.AttachOption{
+			{ProgramName: manifestProgramName, Symbol: p.manifest.Probe.Tracepoint},
+		}); err != nil {
+			return fmt.Errorf("manifest %s: %w", p.manifest.Name, err)
+		}
+
+		obj.WaitDetachByBreaker(childCtx, cancel)
+		p.bpf = obj
+		p.last = nil
+	}
+
+	t.running.Store(true)
+	defer t.running.Store(false)
+
+	interval := time.Duration(cfg.TracerManifest.Interval) * time.Second
+	if interval <= 0 {
+		interval = 10 * time.Second
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-childCtx.Done():
+			return nil
+		case <-ticker.C:
+			for _, p := range t.probes {
+				if err := p.checkThreshold(); err != nil {
+					log.Warnf("manifest %s: %v", p.manifest.Name, err)
+				}
+			}
+		}
+	}
+}
+
+func (p *manifestProbe) counts() (map[uint64]uint64, error) {
+	items, err := p.bpf.DumpMapByName(manifestMapName)
+	if err != nil {
+		return nil, err
+	}
+
+	counts := make(map[uint64]uint64, len(items))
+	for _, item := range items {
+		if len(item.Key) < 8 || len(item.Value) < 8 {
+			continue
+		}
+		counts[binary.LittleEndian.Uint64(item.Key)] = binary.LittleEndian.Uint64(item.Value)
+	}
+	return counts, nil
+}
+
+// checkThreshold saves an event for every container whose rate since the
+// last check is above the threshold.
+func (p *manifestProbe) checkThreshold() error {
+	counts, err := p.counts()
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	last, lastTime := p.last, p.lastTime
+	p.last, p.lastTime = counts, now
+
+	if last == nil || p.manifest.Threshold.Rate <= 0 {
+		return nil
+	}
+	elapsed := now.Sub(lastTime).Seconds()
+	if elapsed <= 0 {
+		return nil
+	}
+
+	var containers map[uint64]*pod.Container
+	for css, count := range counts {
+		// entries evicted from the lru map restart from 1.
+		delta := count
+		if prev, ok := last[css]; ok && prev <= count {
+			delta = count - prev
+		}
+
+		rate := float64(delta) / elapsed
+		if rate < p.manifest.Threshold.Rate {
+			continue
+		}
+
+		if containers == nil {
+			all, err := pod.NormalContainers()
+			if err != nil {
+				return err
+			}
+			containers = pod.BuildCssContainers(all, subsystem.SubsystemCPU)
+		}
+		p.saveEvent(containers[css], delta, rate, now)
+	}
+
+	return nil
+}
+
+func (p *manifestProbe) saveEvent(container *pod.Container, count uint64, rate float64, now time.Time) {
+	data := manifestTemplateData{
+		Manifest:  p.manifest.Name,
+		Container: "host",
+		Count:     count,
+		Rate:      rate,
+		Threshold: p.manifest.Threshold.Rate,
+	}
+	containerID := ""
+	if container != nil {
+		data.Container = container.Name
+		containerID = container.ID
+	}
+
+	var msg bytes.Buffer
+	if err := p.tmpl.Execute(&msg, &data); err != nil {
+		log.Warnf("manifest %s: event template: %v", p.manifest.Name, err)
+	}
+
+	if err := tracing.Save(&tracing.WriteRequest{
+		TracerName:  "tracer_manifest",
+		TracerTime:  now,
+		ContainerID: containerID,
+		TracerData: &TracerManifestEvent{
+			Manifest:   p.manifest.Name,
+			Tracepoint: p.manifest.Probe.Tracepoint,
+			Count:      count,
+			Rate:       rate,
+			Threshold:  p.manifest.Threshold.Rate,
+			Message:    msg.String(),
+		},
+	}); err != nil {
+		log.Warnf("failed to save tracing data: %v", err)
+	}
+}
+
+func (t *tracerManifestTracer) Update() ([]*metric.Data, error) {
+	if !t.running.Load() {
+		return nil, nil
+	}
+
+	containers, err := pod.NormalContainers()
+	if err != nil {
+		return nil, err
+	}
+	cssContainers := pod.BuildCssContainers(containers, subsystem.SubsystemCPU)
+
+	var data []*metric.Data
+	for _, p := range t.probes {
+		counts, err := p.counts()
+		if err != nil {
+			return nil, err
+		}
+
+		labels := map[string]string{"manifest": p.manifest.Name}
+
+		var host uint64
+		for css, count := range counts {
+			container, ok := cssContainers[css]
+			if !ok {
+				host += count
+				continue
+			}
+			data = append(data, metric.NewContainerCounterData(container, "events_total", float64(count),
+				"Tracepoint hits of a tracer manifest in a container.", labels))
+		}
+
+		// host processes and containers not known (yet).
+		data = append(data, metric.NewCounterData("host_events_total", float64(host),
+			"Tracepoint hits of a tracer manifest outside of containers.", labels))
+	}
+
+	return data, nil
+}
@@ -0,0 +1,158 @@
+// Copyright 2026 The HuaTuo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"strings"
+	"testing"
+)
+
+const sampleForkFormat = `name: sched_process_fork
+ID: 318
+format:
+	field:unsigned short common_type;	offset:0;	size:2;	signed:0;
+	field:unsigned char common_flags;	offset:2;	size:1;	signed:0;
+	field:unsigned char common_preempt_count;	offset:3;	size:1;	signed:0;
+	field:int common_pid;	offset:4;	size:4;	signed:1;
+
+	field:char parent_comm[16];	offset:8;	size:16;	signed:0;
+	field:pid_t parent_pid;	offset:24;	size:4;	signed:1;
+	field:char child_comm[16];	offset:28;	size:16;	signed:0;
+	field:pid_t child_pid;	offset:44;	size:4;	signed:1;
+
+print fmt: "comm=%s pid=%d child_comm=%s child_pid=%d", REC->parent_comm, REC->parent_pid, REC->child_comm, REC->child_pid
+`
+
+func TestParseTracepointFormat(t *testing.T) {
+	fields, err := parseTracepointFormat(strings.NewReader(sampleForkFormat))
+	if err != nil {
+		t.Fatalf("parseTracepointFormat() error = %v", err)
+	}
+
+	if got, want := fields["child_pid"], (tracepointField{Offset: 44, Size: 4, Signed: true}); got != want {
+		t.Errorf("child_pid = %+v, want %+v", got, want)
+	}
+	if got, want := fields["common_type"], (tracepointField{Offset: 0, Size: 2}); got != want {
+		t.Errorf("common_type = %+v, want %+v", got, want)
+	}
+	if _, ok := fields["parent_comm[16]"]; ok {
+		t.Errorf("array field parent_comm must be skipped")
+	}
+	if len(fields) != 6 {
+		t.Errorf("fields = %d, want 6", len(fields))
+	}
+}
+
+func TestParseTracerManifest(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		wantErr string
+	}{
+		{
+			name: "valid",
+			data: `
+name: fork
+probe:
+  tracepoint: sched/sched_process_fork
+filters:
+  - field: child_pid
+    op: gt
+    value: 1
+threshold:
+  rate: 100
+`,
+		},
+		{
+			name:    "unknown field",
+			data:    "name: fork\nprobe:\n  tracepoint: sched/sched_process_fork\nthreshhold:\n  rate: 1\n",
+			wantErr: "unknown field",
+		},
+		{
+			name:    "bad name",
+			data:    "name: Fork-1\nprobe:\n  tracepoint: sched/sched_process_fork\n",
+			wantErr: "name",
+		},
+		{
+			name:    "bad tracepoint",
+			data:    "name: fork\nprobe:\n  tracepoint: sched_process_fork\n",
+			wantErr: "probe.tracepoint",
+		},
+		{
+			name:    "bad op",
+			data:    "name: fork\nprobe:\n  tracepoint: sched/sched_process_fork\nfilters:\n  - field: child_pid\n    op: like\n",
+			wantErr: "unknown op",
+		},
+		{
+			name:    "bad aggregation",
+			data:    "name: fork\nprobe:\n  tracepoint: sched/sched_process_fork\naggregation: histogram\n",
+			wantErr: "aggregation",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := parseTracerManifest([]byte(tt.data))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("parseTracerManifest() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseTracerManifest() error = %v", err)
+			}
+			if m.Aggregation != manifestAggregationCount || m.Event.Template != manifestDefaultTemplate {
+				t.Errorf("defaults not applied: %+v", m)
+			}
+		})
+	}
+}
+
+func TestManifestFilterConsts(t *testing.T) {
+	fields, err := parseTracepointFormat(strings.NewReader(sampleForkFormat))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	consts, err := manifestFilterConsts([]manifestFilter{
+		{Field: "child_pid", Op: "gt", Value: 1},
+		{Field: "common_pid", Op: "ne", Value: -1},
+	}, fields)
+	if err != nil {
+		t.Fatalf("manifestFilterConsts() error = %v", err)
+	}
+
+	if got := consts["filter_count"].(uint32); got != 2 {
+		t.Errorf("filter_count = %d, want 2", got)
+	}
+	if got := consts["filter_offset"].([manifestMaxFilters]uint32); got[0] != 44 || got[1] != 4 {
+		t.Errorf("filter_offset = %v", got)
+	}
+	if got := consts["filter_op"].([manifestMaxFilters]uint32); got[0] != 2 || got[1] != 1 {
+		t.Errorf("filter_op = %v", got)
+	}
+	// negative values are truncated to the field size.
+	if got := consts["filter_value"].([manifestMaxFilters]uint64); got[0] != 1 || got[1] != 0xffffffff {
+		t.Errorf("filter_value = %#v", got)
+	}
+
+	if _, err := manifestFilterConsts([]manifestFilter{{Field: "parent_comm", Op: "eq"}}, fields); err == nil {
+		t.Errorf("filter on array field must fail")
+	}
+	if _, err := manifestFilterConsts([]manifestFilter{{Field: "nope", Op: "eq"}}, fields); err == nil {
+		t.Errorf("filter on unknown field must fail")
+	}
+}
@@ -87,3 +87,15 @@ BlackList = ["metax_gpu", "ascend_npu", "softlockup", "ethtool", "netstat_hw", "
     Path = "${HUATUO_BAMAI_TEST_TMPDIR}/events"
 EOF
 }
+
+# write_cgroup_workload_config resolves the cgroups of the workloads of
+# test_cgroup_workload.sh as containers.
+write_cgroup_workload_config() {
+	cat > "${HUATUO_BAMAI_TEST_TMPDIR}/bamai.conf" << 'EOF'
+BlackList = ["metax_gpu", "ascend_npu", "softlockup", "ethtool", "netstat_hw", "iolatency", "memory_free", "memory_reclaim", "reschedipi", "softirq", "iotracing"]
+
+[[Pod.CgroupPaths]]
+    Root = "/huatuo-vmtest"
+    Pattern = '(?P<name>workload-\d+)'
+EOF
+}
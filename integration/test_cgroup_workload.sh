@@ -0,0 +1,105 @@
+#!/usr/bin/env bash
+
+# Copyright 2026 The HuaTuo Authors.
+#
+# Licensed under the Apache License, Version 2.0 (the "License");
+# you may not use this file except in compliance with the License.
+# You may obtain a copy of the License at
+#
+#     http://www.apache.org/licenses/LICENSE-2.0
+#
+# Unless required by applicable law or agreed to in writing, software
+# distributed under the License is distributed on an "AS IS" BASIS,
+# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+# See the License for the specific language governing permissions and
+# limitations under the License.
+
+# Verify the container metrics of a workload cgroup on the real kernel and
+# cgroup hierarchy. It only runs in the test VMs of integration/vm, which
+# boot the kernels of the matrix with cgroup v1 or v2.
+
+set -euo pipefail
+
+source "${ROOT_DIR}/integration/lib.sh"
+source "${ROOT_DIR}/integration/config.sh"
+
+[[ -n "${HUATUO_BAMAI_VM:-}" ]] || skip "runs in the test VMs only, see integration/vm"
+
+readonly WORKLOAD_CGROUP="huatuo-vmtest/workload-1"
+
+workload_pid=""
+
+cgroup_unified() {
+	[[ -f /sys/fs/cgroup/cgroup.controllers ]]
+}
+
+# cgroup_dirs lists the directories of the workload cgroup, one per v1
+# hierarchy the collectors read.
+cgroup_dirs() {
+	if cgroup_unified; then
+		echo "/sys/fs/cgroup/${WORKLOAD_CGROUP}"
+		return 0
+	fi
+
+	local hierarchy
+	for hierarchy in cpu,cpuacct memory; do
+		[[ -d "/sys/fs/cgroup/${hierarchy}" ]] && echo "/sys/fs/cgroup/${hierarchy}/${WORKLOAD_CGROUP}"
+	done
+}
+
+workload_cleanup() {
+	[[ -n "${workload_pid}" ]] && stop_by_pid "${workload_pid}"
+
+	local dir
+	for dir in $(cgroup_dirs); do
+		rmdir "${dir}" "$(dirname "${dir}")" 2> /dev/null || true
+	done
+}
+trap workload_cleanup EXIT
+
+workload_start() {
+	local dir controller parent
+	for dir in $(cgroup_dirs); do
+		mkdir -p "${dir}"
+	done
+
+	# the v2 parent passes its controllers down to the workload.
+	if cgroup_unified; then
+		parent="/sys/fs/cgroup/$(dirname "${WORKLOAD_CGROUP}")"
+		for controller in $(< "${parent}/cgroup.controllers"); do
+			echo "+${controller}" > "${parent}/cgroup.subtree_control" || true
+		done
+	fi
+
+	sleep infinity &
+	workload_pid=$!
+	for dir in $(cgroup_dirs); do
+		echo "${workload_pid}" > "${dir}/cgroup.procs"
+	done
+	log_info "workload pid ${workload_pid} in $(cgroup_dirs | tr '\n' ' ')"
+}
+
+workload_metrics_ready() {
+	huatuo_bamai_collect_metrics || return 1
+	grep -q "^huatuo_bamai_cpu_util_container_total{.*container_name=\"workload-1\"" \
+		"${HUATUO_BAMAI_TEST_TMPDIR}/metrics.txt"
+}
+
+workload_start
+
+integration_huatuo_bamai_start write_cgroup_workload_config \
+	"--region" "dev" \
+	"--disable-storage" \
+	"--disable-kubelet" \
+	"--log-debug"
+
+wait_until "${WAIT_HUATUO_BAMAI_TIMEOUT}" "${WAIT_HUATUO_BAMAI_INTERVAL}" workload_metrics_ready \
+	|| fatal "no container metrics of the workload on $(uname -r)"
+
+for metric in cpu_util_container_total cpu_util_container_usr cpu_util_container_sys; do
+	grep -q "^huatuo_bamai_${metric}{.*container_hostnamespace=\"huatuo-vmtest\"" \
+		"${HUATUO_BAMAI_TEST_TMPDIR}/metrics.txt" || fatal "missing ${metric} of the workload"
+	log_info "workload metric ok: huatuo_bamai_${metric}"
+done
+
+huatuo_bamai_log_check || fatal "found error/panic keywords in huatuo.log"
@@ -0,0 +1,109 @@
+#!/usr/bin/env bash
+
+# Copyright 2026 The HuaTuo Authors.
+#
+# Licensed under the Apache License, Version 2.0 (the "License");
+# you may not use this file except in compliance with the License.
+# You may obtain a copy of the License at
+#
+#     http://www.apache.org/licenses/LICENSE-2.0
+#
+# Unless required by applicable law or agreed to in writing, software
+# distributed under the License is distributed on an "AS IS" BASIS,
+# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+# See the License for the specific language governing permissions and
+# limitations under the License.
+
+# The init of the test VMs: the root filesystem is the one of the host,
+# read-only, the repository and the result directory are shared read-write.
+# It mounts the kernel filesystems and the cgroup hierarchy of the huatuo.*
+# kernel options, runs the run.sh of the result directory and powers off.
+
+set -uo pipefail
+
+export PATH=/usr/local/sbin:/usr/local/bin:/usr/sbin:/usr/bin:/sbin:/bin
+export HOME=/root
+
+cmdline_opt() {
+	local name=$1 opt
+	for opt in $(< /proc/cmdline); do
+		if [[ "${opt}" == "${name}="* ]]; then
+			echo "${opt#*=}"
+			return 0
+		fi
+	done
+	return 1
+}
+
+poweroff_now() {
+	sync
+	echo o > /proc/sysrq-trigger
+	sleep 10
+}
+
+mount -t proc proc /proc
+mount -t sysfs sysfs /sys
+mount -t devtmpfs devtmpfs /dev 2> /dev/null || true
+mkdir -p /dev/pts /dev/shm
+mount -t devpts devpts /dev/pts
+mount -t tmpfs tmpfs /dev/shm
+for dir in /tmp /run /var/tmp /var/log /var/run /root; do
+	mount -t tmpfs tmpfs "${dir}" 2> /dev/null || true
+done
+mount -t debugfs debugfs /sys/kernel/debug 2> /dev/null || true
+mount -t tracefs tracefs /sys/kernel/tracing 2> /dev/null || true
+mount -t bpf bpf /sys/fs/bpf 2> /dev/null || true
+
+root_dir=$(cmdline_opt huatuo.root) || {
+	echo "huatuo.root is missing" >&2
+	poweroff_now
+}
+result_dir=$(cmdline_opt huatuo.result) || {
+	echo "huatuo.result is missing" >&2
+	poweroff_now
+}
+cgroup_mode=$(cmdline_opt huatuo.cgroup || echo v2)
+
+# over the read-only root, so the paths of the host work in the guest.
+mount -t 9p -o trans=virtio,version=9p2000.L,msize=1048576 huatuo "${root_dir}"
+mount -t 9p -o trans=virtio,version=9p2000.L,msize=1048576 result "${result_dir}"
+
+mount -t tmpfs cgroup_root /sys/fs/cgroup
+if [[ "${cgroup_mode}" == "v1" ]]; then
+	while read -r controller _ _ enabled; do
+		[[ "${controller}" == \#* || "${enabled}" != "1" ]] && continue
+		case "${controller}" in
+		cpu | cpuacct)
+			dir=cpu,cpuacct
+			;;
+		net_cls | net_prio)
+			dir=net_cls,net_prio
+			;;
+		*)
+			dir=${controller}
+			;;
+		esac
+		mountpoint -q "/sys/fs/cgroup/${dir}" && continue
+		mkdir -p "/sys/fs/cgroup/${dir}"
+		mount -t cgroup -o "${dir}" cgroup "/sys/fs/cgroup/${dir}"
+	done < /proc/cgroups
+	ln -sf cpu,cpuacct /sys/fs/cgroup/cpu
+	ln -sf cpu,cpuacct /sys/fs/cgroup/cpuacct
+else
+	umount /sys/fs/cgroup
+	mount -t cgroup2 cgroup2 /sys/fs/cgroup
+	# the workloads of the tests get every controller.
+	for controller in $(< /sys/fs/cgroup/cgroup.controllers); do
+		echo "+${controller}" > /sys/fs/cgroup/cgroup.subtree_control 2> /dev/null || true
+	done
+fi
+
+ip link set lo up
+hostname huatuo-vm
+
+echo "[VM] kernel $(uname -r), cgroup ${cgroup_mode}"
+cd "${root_dir}" || poweroff_now
+bash "${result_dir}/run.sh"
+echo $? > "${result_dir}/exit_code"
+
+poweroff_now
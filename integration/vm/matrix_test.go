@@ -0,0 +1,109 @@
+// Copyright 2026 The HuaTuo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build vmtest
+
+package vm
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// consoleTailLines of the console are logged when a VM fails.
+const consoleTailLines = 100
+
+// TestKernelMatrix runs the integration tests on every kernel of
+// HUATUO_VM_KERNELS with every cgroup mode of HUATUO_VM_CGROUPS, "v1,v2"
+// by default. HUATUO_VM_TEST narrows it down to one test_*.sh and
+// HUATUO_VM_QEMU overrides the qemu binary.
+func TestKernelMatrix(t *testing.T) {
+	kernelsDir := os.Getenv("HUATUO_VM_KERNELS")
+	if kernelsDir == "" {
+		t.Skip("HUATUO_VM_KERNELS is not set")
+	}
+
+	kernels, err := Kernels(kernelsDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cgroups := os.Getenv("HUATUO_VM_CGROUPS")
+	if cgroups == "" {
+		cgroups = "v1,v2"
+	}
+	modes, err := ParseCgroupModes(cgroups)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rootDir, err := filepath.Abs("../..")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(rootDir, "_output/bin/huatuo-bamai")); err != nil {
+		t.Fatalf("huatuo-bamai is not built, run make all first: %v", err)
+	}
+
+	script := fmt.Sprintf("export HUATUO_BAMAI_VM=1\nbash integration/run.sh %s\n", os.Getenv("HUATUO_VM_TEST"))
+
+	for _, kernel := range kernels {
+		for _, mode := range modes {
+			vm := &VM{
+				Kernel:    kernel,
+				Cgroup:    mode,
+				RootDir:   rootDir,
+				ResultDir: filepath.Join(rootDir, "_output/vm", kernel.Name+"-"+string(mode)),
+				QEMU:      os.Getenv("HUATUO_VM_QEMU"),
+			}
+
+			t.Run(kernel.Name+"/"+string(mode), func(t *testing.T) {
+				t.Parallel()
+
+				start := time.Now()
+				result, err := vm.Run(context.Background(), script)
+				if err != nil {
+					logConsoleTail(t, vm.ResultDir)
+					t.Fatal(err)
+				}
+				if result.ExitCode != 0 {
+					logConsoleTail(t, vm.ResultDir)
+					t.Fatalf("integration tests exited with %d on %s/%s, see %s",
+						result.ExitCode, kernel.Name, mode, result.Console)
+				}
+				t.Logf("integration tests passed on %s/%s in %s", kernel.Name, mode, time.Since(start).Round(time.Second))
+			})
+		}
+	}
+}
+
+func logConsoleTail(t *testing.T, resultDir string) {
+	t.Helper()
+
+	data, err := os.ReadFile(filepath.Join(resultDir, consoleFile))
+	if err != nil {
+		return
+	}
+
+	lines := strings.Split(strings.TrimRight(string(data), "\n"), "\n")
+	if len(lines) > consoleTailLines {
+		lines = lines[len(lines)-consoleTailLines:]
+	}
+	t.Logf("console:\n%s", strings.Join(lines, "\n"))
+}
@@ -0,0 +1,271 @@
+// Copyright 2026 The HuaTuo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package vm boots lightweight qemu VMs on the kernels of the test matrix
+// and runs the integration tests in them, so the kernel compatibility of
+// the tracers and collectors is tested end-to-end before a release.
+//
+// The guest shares the root filesystem of the host read-only over 9p, no
+// image is built: the kernels need 9p, virtio-pci and the serial console
+// built in.
+package vm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CgroupMode is the cgroup hierarchy mounted in the guest.
+type CgroupMode string
+
+const (
+	CgroupV1 CgroupMode = "v1"
+	CgroupV2 CgroupMode = "v2"
+)
+
+const (
+	defaultMemory  = "2G"
+	defaultCPUs    = 2
+	defaultTimeout = 20 * time.Minute
+
+	// the file init.sh writes the exit code of the script in.
+	exitCodeFile = "exit_code"
+	consoleFile  = "console.log"
+	scriptFile   = "run.sh"
+)
+
+// kernelImagePrefixes are the file names of the kernel images, followed by
+// the kernel name, e.g. vmlinuz-5.10 or bzImage-6.6-rc1.
+var kernelImagePrefixes = []string{"vmlinuz-", "bzImage-", "Image-"}
+
+// Kernel is a kernel image of the test matrix.
+type Kernel struct {
+	Name  string
+	Image string
+}
+
+// Kernels returns the kernel images in dir, sorted by name.
+func Kernels(dir string) ([]Kernel, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var kernels []Kernel
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		for _, prefix := range kernelImagePrefixes {
+			name, ok := strings.CutPrefix(entry.Name(), prefix)
+			if !ok || name == "" {
+				continue
+			}
+			kernels = append(kernels, Kernel{Name: name, Image: filepath.Join(dir, entry.Name())})
+			break
+		}
+	}
+	if len(kernels) == 0 {
+		return nil, fmt.Errorf("no kernel image in %s", dir)
+	}
+
+	sort.Slice(kernels, func(i, j int) bool { return kernels[i].Name < kernels[j].Name })
+	return kernels, nil
+}
+
+// ParseCgroupModes parses the comma separated cgroup modes, e.g. "v1,v2".
+func ParseCgroupModes(s string) ([]CgroupMode, error) {
+	var modes []CgroupMode
+	for _, mode := range strings.Split(s, ",") {
+		switch m := CgroupMode(strings.TrimSpace(mode)); m {
+		case CgroupV1, CgroupV2:
+			modes = append(modes, m)
+		case "":
+		default:
+			return nil, fmt.Errorf("invalid cgroup mode %q", mode)
+		}
+	}
+	if len(modes) == 0 {
+		return nil, errors.New("no cgroup mode")
+	}
+	return modes, nil
+}
+
+// VM is a guest booting a kernel of the matrix.
+type VM struct {
+	Kernel Kernel
+	Cgroup CgroupMode
+
+	// RootDir is the repository, shared read-write at the same path so the
+	// test scripts and the built binaries work unchanged.
+	RootDir string
+	// ResultDir is where the script, the console log and the exit code
+	// are written, it is shared with the guest too.
+	ResultDir string
+
+	// QEMU is the qemu-system binary, the one of the host arch by default.
+	QEMU    string
+	Memory  string
+	CPUs    int
+	Timeout time.Duration
+}
+
+// Result is the outcome of a script run in the guest.
+type Result struct {
+	ExitCode int
+	// Console is the path of the serial console log.
+	Console string
+}
+
+func (vm *VM) qemu() string {
+	if vm.QEMU != "" {
+		return vm.QEMU
+	}
+
+	switch runtime.GOARCH {
+	case "arm64":
+		return "qemu-system-aarch64"
+	default:
+		return "qemu-system-x86_64"
+	}
+}
+
+// cmdline is the kernel command line, init.sh reads the huatuo.* options.
+func (vm *VM) cmdline() string {
+	opts := []string{
+		"console=ttyS0",
+		"root=/dev/root",
+		"rootfstype=9p",
+		"rootflags=trans=virtio,version=9p2000.L,cache=loose",
+		"ro",
+		"panic=-1",
+		"oops=panic",
+		"init=" + filepath.Join(vm.RootDir, "integration/vm/init.sh"),
+		"huatuo.root=" + vm.RootDir,
+		"huatuo.result=" + vm.ResultDir,
+		"huatuo.cgroup=" + string(vm.Cgroup),
+	}
+
+	// init.sh mounts the hierarchy, the v1 controllers are disabled so
+	// that none is missing from v2 on the kernels supporting both.
+	if vm.Cgroup == CgroupV2 {
+		opts = append(opts, "cgroup_no_v1=all")
+	}
+	if runtime.GOARCH == "arm64" {
+		opts[0] = "console=ttyAMA0"
+	}
+	return strings.Join(opts, " ")
+}
+
+// args are the qemu arguments to boot the VM.
+func (vm *VM) args(kvm bool) []string {
+	memory := vm.Memory
+	if memory == "" {
+		memory = defaultMemory
+	}
+	cpus := vm.CPUs
+	if cpus <= 0 {
+		cpus = defaultCPUs
+	}
+
+	args := []string{
+		"-nodefaults",
+		"-no-reboot",
+		"-display", "none",
+		"-m", memory,
+		"-smp", strconv.Itoa(cpus),
+		"-kernel", vm.Kernel.Image,
+		"-append", vm.cmdline(),
+		"-serial", "file:" + filepath.Join(vm.ResultDir, consoleFile),
+		"-fsdev", "local,id=root,path=/,security_model=none,readonly=on",
+		"-device", "virtio-9p-pci,fsdev=root,mount_tag=/dev/root",
+		"-fsdev", "local,id=huatuo,path=" + vm.RootDir + ",security_model=none",
+		"-device", "virtio-9p-pci,fsdev=huatuo,mount_tag=huatuo",
+		"-fsdev", "local,id=result,path=" + vm.ResultDir + ",security_model=none",
+		"-device", "virtio-9p-pci,fsdev=result,mount_tag=result",
+		"-nic", "user,model=virtio-net-pci",
+	}
+
+	if runtime.GOARCH == "arm64" {
+		args = append(args, "-machine", "virt")
+	}
+	if kvm {
+		args = append(args, "-enable-kvm", "-cpu", "host")
+	} else {
+		args = append(args, "-cpu", "max")
+	}
+	return args
+}
+
+func kvmAvailable() bool {
+	f, err := os.OpenFile("/dev/kvm", os.O_RDWR, 0)
+	if err != nil {
+		return false
+	}
+	f.Close()
+	return true
+}
+
+// Run boots the VM, runs the bash script in the guest as root and powers
+// the VM off. The error is about the VM, the script failing is reported by
+// the exit code of the Result.
+func (vm *VM) Run(ctx context.Context, script string) (*Result, error) {
+	if err := os.MkdirAll(vm.ResultDir, 0o755); err != nil {
+		return nil, err
+	}
+	_ = os.Remove(filepath.Join(vm.ResultDir, exitCodeFile))
+
+	if err := os.WriteFile(filepath.Join(vm.ResultDir, scriptFile), []byte(script), 0o755); err != nil {
+		return nil, err
+	}
+
+	timeout := vm.Timeout
+	if timeout <= 0 {
+		timeout = defaultTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	result := &Result{ExitCode: -1, Console: filepath.Join(vm.ResultDir, consoleFile)}
+
+	cmd := exec.CommandContext(ctx, vm.qemu(), vm.args(kvmAvailable())...)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		if ctx.Err() != nil {
+			return result, fmt.Errorf("vm %s/%s timed out after %s", vm.Kernel.Name, vm.Cgroup, timeout)
+		}
+		return result, fmt.Errorf("vm %s/%s: %w: %s", vm.Kernel.Name, vm.Cgroup, err, out)
+	}
+
+	// init.sh has not written it when the guest panicked.
+	data, err := os.ReadFile(filepath.Join(vm.ResultDir, exitCodeFile))
+	if err != nil {
+		return result, fmt.Errorf("vm %s/%s exited without the exit code, see %s", vm.Kernel.Name, vm.Cgroup, result.Console)
+	}
+	code, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil {
+		return result, fmt.Errorf("vm %s/%s exit code %q: %w", vm.Kernel.Name, vm.Cgroup, data, err)
+	}
+
+	result.ExitCode = code
+	return result, nil
+}
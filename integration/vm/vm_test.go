@@ -0,0 +1,108 @@
+// Copyright 2026 The HuaTuo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vm
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestKernels(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"vmlinuz-6.6", "bzImage-5.10", "vmlinuz-", "config-6.6", "System.map-6.6"} {
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Mkdir(filepath.Join(dir, "vmlinuz-dir"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	kernels, err := Kernels(dir)
+	if err != nil {
+		t.Fatalf("Kernels() error = %v", err)
+	}
+	want := []Kernel{
+		{Name: "5.10", Image: filepath.Join(dir, "bzImage-5.10")},
+		{Name: "6.6", Image: filepath.Join(dir, "vmlinuz-6.6")},
+	}
+	if !slices.Equal(kernels, want) {
+		t.Errorf("Kernels() = %+v, want %+v", kernels, want)
+	}
+
+	if _, err := Kernels(t.TempDir()); err == nil {
+		t.Error("Kernels() of an empty dir succeeded")
+	}
+}
+
+func TestParseCgroupModes(t *testing.T) {
+	modes, err := ParseCgroupModes("v1, v2,")
+	if err != nil || !slices.Equal(modes, []CgroupMode{CgroupV1, CgroupV2}) {
+		t.Errorf("ParseCgroupModes() = %v, %v, want [v1 v2]", modes, err)
+	}
+
+	for _, s := range []string{"", "v3", " , "} {
+		if _, err := ParseCgroupModes(s); err == nil {
+			t.Errorf("ParseCgroupModes(%q) succeeded", s)
+		}
+	}
+}
+
+func TestVMArgs(t *testing.T) {
+	vm := &VM{
+		Kernel:    Kernel{Name: "6.6", Image: "/kernels/vmlinuz-6.6"},
+		Cgroup:    CgroupV2,
+		RootDir:   "/src/huatuo",
+		ResultDir: "/src/huatuo/_output/vm/6.6-v2",
+	}
+
+	args := strings.Join(vm.args(true), " ")
+	for _, want := range []string{
+		"-kernel /kernels/vmlinuz-6.6",
+		"-m 2G",
+		"-smp 2",
+		"-serial file:/src/huatuo/_output/vm/6.6-v2/console.log",
+		"path=/src/huatuo,security_model=none",
+		"-enable-kvm -cpu host",
+	} {
+		if !strings.Contains(args, want) {
+			t.Errorf("args() = %s, want %s", args, want)
+		}
+	}
+
+	cmdline := vm.cmdline()
+	for _, want := range []string{
+		"init=/src/huatuo/integration/vm/init.sh",
+		"huatuo.root=/src/huatuo",
+		"huatuo.result=/src/huatuo/_output/vm/6.6-v2",
+		"huatuo.cgroup=v2",
+		"cgroup_no_v1=all",
+	} {
+		if !strings.Contains(cmdline, want) {
+			t.Errorf("cmdline() = %s, want %s", cmdline, want)
+		}
+	}
+
+	vm.Cgroup = CgroupV1
+	if cmdline := vm.cmdline(); strings.Contains(cmdline, "cgroup_no_v1") || !strings.Contains(cmdline, "huatuo.cgroup=v1") {
+		t.Errorf("cmdline() = %s, want the v1 controllers", cmdline)
+	}
+	if args := strings.Join(vm.args(false), " "); strings.Contains(args, "-enable-kvm") {
+		t.Errorf("args() = %s, want no kvm", args)
+	}
+}
@@ -0,0 +1,270 @@
+// Copyright 2026 The HuaTuo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package affinity pins the threads of the agent to the housekeeping
+// CPUs, so the agent causes no jitter on the CPUs isolated for the
+// latency critical workloads.
+//
+// The threads of the process are pinned once at startup, before the BPF
+// readers start, and the threads the go runtime creates later inherit the
+// mask of the thread creating them. A watch pins again any thread found
+// with another mask, e.g. after a setns restoring failed.
+package affinity
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"huatuo-bamai/internal/log"
+	"huatuo-bamai/internal/procfs/sysfs"
+	"huatuo-bamai/internal/utils/parseutil"
+
+	"golang.org/x/sys/unix"
+)
+
+// watchInterval is the interval the threads are checked at.
+const watchInterval = time.Minute
+
+// Config is the housekeeping CPUs, a cpu list, or all the CPUs of a NUMA
+// node. NUMANode < 0 is no node.
+type Config struct {
+	CPUs     string
+	NUMANode int
+}
+
+// tasksPath is the threads of the agent, overridden by the tests.
+var tasksPath = "/proc/self/task"
+
+var (
+	lock sync.Mutex
+	// applied is the mask the threads are pinned to, nil when unpinned.
+	applied *unix.CPUSet
+	// appliedList is the cpu list of applied.
+	appliedList string
+	stopWatch   chan struct{}
+)
+
+// CPUs returns the housekeeping CPUs of the config, nil when none is
+// configured.
+func CPUs(cfg Config) ([]int, error) {
+	cpuList := strings.TrimSpace(cfg.CPUs)
+	if cpuList != "" && cfg.NUMANode >= 0 {
+		return nil, fmt.Errorf("both cpus %q and numa node %d", cpuList, cfg.NUMANode)
+	}
+
+	if cfg.NUMANode >= 0 {
+		path := sysfs.Path("devices/system/node", "node"+strconv.Itoa(cfg.NUMANode), "cpulist")
+		raw, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("numa node %d: %w", cfg.NUMANode, err)
+		}
+		cpuList = strings.TrimSpace(string(raw))
+		if cpuList == "" {
+			return nil, fmt.Errorf("numa node %d has no cpu", cfg.NUMANode)
+		}
+	}
+
+	if cpuList == "" {
+		return nil, nil
+	}
+
+	cpus, err := parseutil.CPUList(cpuList)
+	if err != nil {
+		return nil, err
+	}
+	if len(cpus) == 0 {
+		return nil, fmt.Errorf("empty cpu list %q", cpuList)
+	}
+	return cpus, nil
+}
+
+// Start pins the threads of the agent to the CPUs, which must be allowed
+// to the agent, e.g. by its cpuset, and starts the watch.
+func Start(cpus []int) error {
+	set, err := cpuSet(cpus)
+	if err != nil {
+		return err
+	}
+
+	n, err := pin(set)
+	if err != nil {
+		return err
+	}
+
+	lock.Lock()
+	defer lock.Unlock()
+
+	applied = set
+	appliedList = FormatCPUList(cpus)
+	if stopWatch == nil {
+		stopWatch = make(chan struct{})
+		go watch(stopWatch)
+	}
+
+	log.Infof("pinned %d threads to the cpus %s", n, appliedList)
+	return nil
+}
+
+// Stop stops the watch, the threads remain pinned.
+func Stop() {
+	lock.Lock()
+	defer lock.Unlock()
+
+	if stopWatch != nil {
+		close(stopWatch)
+		stopWatch = nil
+	}
+	applied = nil
+	appliedList = ""
+}
+
+// cpuSet returns the mask of the CPUs, if all of them are allowed to the
+// agent. The kernel would silently drop the others.
+func cpuSet(cpus []int) (*unix.CPUSet, error) {
+	var allowed unix.CPUSet
+	if err := unix.SchedGetaffinity(0, &allowed); err != nil {
+		return nil, fmt.Errorf("sched_getaffinity: %w", err)
+	}
+
+	var set unix.CPUSet
+	for _, cpu := range cpus {
+		if cpu < 0 || cpu >= len(set)*64 {
+			return nil, fmt.Errorf("invalid cpu %d", cpu)
+		}
+		if !allowed.IsSet(cpu) {
+			return nil, fmt.Errorf("cpu %d is not allowed to the agent", cpu)
+		}
+		set.Set(cpu)
+	}
+	return &set, nil
+}
+
+// pin sets the mask of every thread having another one and returns the
+// threads set. The threads are listed again until no new one shows up,
+// those created meanwhile may have inherited the former mask.
+func pin(set *unix.CPUSet) (int, error) {
+	var (
+		seen = make(map[int]struct{})
+		n    int
+	)
+
+	for {
+		tids, err := threadIDs()
+		if err != nil {
+			return n, err
+		}
+
+		found := false
+		for _, tid := range tids {
+			if _, ok := seen[tid]; ok {
+				continue
+			}
+			seen[tid] = struct{}{}
+			found = true
+
+			var cur unix.CPUSet
+			if err := unix.SchedGetaffinity(tid, &cur); err == nil && cur == *set {
+				continue
+			}
+			if err := unix.SchedSetaffinity(tid, set); err != nil {
+				if errors.Is(err, unix.ESRCH) {
+					// exited meanwhile.
+					continue
+				}
+				return n, fmt.Errorf("sched_setaffinity of thread %d: %w", tid, err)
+			}
+			n++
+		}
+
+		if !found {
+			return n, nil
+		}
+	}
+}
+
+func watch(stop chan struct{}) {
+	ticker := time.NewTicker(watchInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+
+		lock.Lock()
+		set := applied
+		lock.Unlock()
+		if set == nil {
+			continue
+		}
+
+		n, err := pin(set)
+		if err != nil {
+			log.Warnf("pin the threads: %v", err)
+			continue
+		}
+		if n > 0 {
+			log.Infof("pinned again %d threads", n)
+		}
+	}
+}
+
+// threadIDs returns the threads of the agent.
+func threadIDs() ([]int, error) {
+	entries, err := os.ReadDir(tasksPath)
+	if err != nil {
+		return nil, err
+	}
+
+	tids := make([]int, 0, len(entries))
+	for _, e := range entries {
+		if tid, err := strconv.Atoi(e.Name()); err == nil {
+			tids = append(tids, tid)
+		}
+	}
+	return tids, nil
+}
+
+// FormatCPUList returns the kernel cpu list of the CPUs, e.g. "0-3,8".
+func FormatCPUList(cpus []int) string {
+	sorted := slices.Clone(cpus)
+	slices.Sort(sorted)
+	sorted = slices.Compact(sorted)
+
+	var b strings.Builder
+	for i := 0; i < len(sorted); {
+		j := i
+		for j+1 < len(sorted) && sorted[j+1] == sorted[j]+1 {
+			j++
+		}
+		if b.Len() > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(strconv.Itoa(sorted[i]))
+		if j > i {
+			b.WriteByte('-')
+			b.WriteString(strconv.Itoa(sorted[j]))
+		}
+		i = j + 1
+	}
+	return b.String()
+}
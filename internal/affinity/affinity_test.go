@@ -0,0 +1,151 @@
+// Copyright 2026 The HuaTuo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package affinity
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"huatuo-bamai/internal/procfs"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"golang.org/x/sys/unix"
+)
+
+func TestCPUs(t *testing.T) {
+	root := t.TempDir()
+	node := filepath.Join(root, "sys/devices/system/node/node1")
+	if err := os.MkdirAll(node, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(node, "cpulist"), []byte("4-5,8\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	procfs.RootPrefix(root)
+	t.Cleanup(func() { procfs.RootPrefix("/") })
+
+	tests := []struct {
+		name    string
+		cfg     Config
+		want    []int
+		wantErr bool
+	}{
+		{name: "none", cfg: Config{NUMANode: -1}},
+		{name: "cpus", cfg: Config{CPUs: "0-1,3", NUMANode: -1}, want: []int{0, 1, 3}},
+		{name: "numa node", cfg: Config{NUMANode: 1}, want: []int{4, 5, 8}},
+		{name: "both", cfg: Config{CPUs: "0", NUMANode: 1}, wantErr: true},
+		{name: "missing numa node", cfg: Config{NUMANode: 2}, wantErr: true},
+		{name: "invalid cpus", cfg: Config{CPUs: "3-1", NUMANode: -1}, wantErr: true},
+		{name: "empty cpus", cfg: Config{CPUs: ",", NUMANode: -1}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := CPUs(tt.cfg)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("CPUs() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("CPUs() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFormatCPUList(t *testing.T) {
+	tests := []struct {
+		cpus []int
+		want string
+	}{
+		{nil, ""},
+		{[]int{3}, "3"},
+		{[]int{0, 1, 2, 3}, "0-3"},
+		{[]int{8, 0, 1, 10, 9, 1}, "0-1,8-10"},
+	}
+	for _, tt := range tests {
+		if got := FormatCPUList(tt.cpus); got != tt.want {
+			t.Errorf("FormatCPUList(%v) = %q, want %q", tt.cpus, got, tt.want)
+		}
+	}
+}
+
+// allowedCPUs returns the CPUs the test may pin to, so it changes nothing.
+func allowedCPUs(t *testing.T) []int {
+	t.Helper()
+
+	var allowed unix.CPUSet
+	if err := unix.SchedGetaffinity(0, &allowed); err != nil {
+		t.Skipf("sched_getaffinity: %v", err)
+	}
+
+	var cpus []int
+	for cpu := range len(allowed) * 64 {
+		if allowed.IsSet(cpu) {
+			cpus = append(cpus, cpu)
+		}
+	}
+	return cpus
+}
+
+func TestStart(t *testing.T) {
+	cpus := allowedCPUs(t)
+
+	if err := Start(append(slices.Clone(cpus), len(unix.CPUSet{})*64-1)); err == nil {
+		t.Errorf("Start() with a cpu not allowed, want error")
+	}
+
+	if err := Start(cpus); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	t.Cleanup(Stop)
+
+	reg := prometheus.NewRegistry()
+	RegisterMetrics(reg)
+	families, err := reg.Gather()
+	if err != nil {
+		t.Fatalf("Gather() error = %v", err)
+	}
+
+	values := make(map[string]float64)
+	for _, mf := range families {
+		for _, m := range mf.GetMetric() {
+			key := mf.GetName()
+			for _, l := range m.GetLabel() {
+				key += "," + l.GetName() + "=" + l.GetValue()
+			}
+			values[key] = m.GetGauge().GetValue()
+		}
+	}
+
+	if got := values["huatuo_affinity_cpus,cpus="+FormatCPUList(cpus)]; got != float64(len(cpus)) {
+		t.Errorf("huatuo_affinity_cpus = %v, want %d", got, len(cpus))
+	}
+	if got := values["huatuo_affinity_threads,pinned=true"]; got < 1 {
+		t.Errorf("huatuo_affinity_threads{pinned=true} = %v, want >= 1", got)
+	}
+	if got := values["huatuo_affinity_threads,pinned=false"]; got != 0 {
+		t.Errorf("huatuo_affinity_threads{pinned=false} = %v, want 0", got)
+	}
+
+	Stop()
+	families, err = reg.Gather()
+	if err != nil {
+		t.Fatalf("Gather() error = %v", err)
+	}
+	if len(families) != 0 {
+		t.Errorf("Gather() after Stop() = %d families, want none", len(families))
+	}
+}
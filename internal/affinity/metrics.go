@@ -0,0 +1,75 @@
+// Copyright 2026 The HuaTuo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package affinity
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"golang.org/x/sys/unix"
+)
+
+var (
+	cpusDesc = prometheus.NewDesc("huatuo_affinity_cpus",
+		"CPUs the agent threads are pinned to, by cpu list.", []string{"cpus"}, nil)
+	threadsDesc = prometheus.NewDesc("huatuo_affinity_threads",
+		"Agent threads by whether their mask is the pinned one.", []string{"pinned"}, nil)
+)
+
+// affinityCollector reads the masks of the threads at scrape time, so the
+// metrics confirm what the kernel applied.
+type affinityCollector struct{}
+
+// RegisterMetrics registers huatuo_affinity_cpus and huatuo_affinity_threads,
+// exported only while the threads are pinned.
+func RegisterMetrics(reg prometheus.Registerer) {
+	reg.MustRegister(affinityCollector{})
+}
+
+func (affinityCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- cpusDesc
+	ch <- threadsDesc
+}
+
+func (affinityCollector) Collect(ch chan<- prometheus.Metric) {
+	lock.Lock()
+	set, cpuList := applied, appliedList
+	lock.Unlock()
+	if set == nil {
+		return
+	}
+
+	tids, err := threadIDs()
+	if err != nil {
+		ch <- prometheus.NewInvalidMetric(threadsDesc, err)
+		return
+	}
+
+	var pinned, unpinned int
+	for _, tid := range tids {
+		var cur unix.CPUSet
+		if err := unix.SchedGetaffinity(tid, &cur); err != nil {
+			// exited meanwhile.
+			continue
+		}
+		if cur == *set {
+			pinned++
+		} else {
+			unpinned++
+		}
+	}
+
+	ch <- prometheus.MustNewConstMetric(cpusDesc, prometheus.GaugeValue, float64(set.Count()), cpuList)
+	ch <- prometheus.MustNewConstMetric(threadsDesc, prometheus.GaugeValue, float64(pinned), "true")
+	ch <- prometheus.MustNewConstMetric(threadsDesc, prometheus.GaugeValue, float64(unpinned), "false")
+}
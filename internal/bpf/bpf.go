@@ -1,4 +1,4 @@
-// Copyright 2025 The HuaTuo Authors
+// Copyright 2025, 2026 The HuaTuo Authors
 //
 // Licensed under the Apache License, Version 2.0 (the "License");
 // you may not use this file except in compliance with the License.
@@ -20,6 +20,8 @@ import (
 
 type Option struct {
 	KeepaliveTimeout int
+	// OnLoadFailure is called for every BPF object the kernel refuses to load.
+	OnLoadFailure func(*LoadFailure)
 }
 
 // AttachOption is an option for attaching a program.
@@ -1,4 +1,4 @@
-// Copyright 2025 The HuaTuo Authors
+// Copyright 2025, 2026 The HuaTuo Authors
 //
 // Licensed under the Apache License, Version 2.0 (the "License");
 // you may not use this file except in compliance with the License.
@@ -41,6 +41,10 @@ var DefaultObjDir = "bpf"
 
 // NewManager initializes the bpf manager.
 func NewManager(opt *Option) error {
+	if opt != nil {
+		setLoadFailureNotify(opt.OnLoadFailure)
+	}
+
 	return unix.Setrlimit(unix.RLIMIT_MEMLOCK, &unix.Rlimit{
 		Cur: unix.RLIM_INFINITY,
 		Max: unix.RLIM_INFINITY,
@@ -79,6 +83,8 @@ type defaultBPF struct {
 	programName2IDs map[string]uint32
 	innerPerfEvent  *perfEventAttach
 	closed          atomic.Bool
+	// object reports the object in Objects until Close.
+	object *loadedObject
 }
 
 // _ is a type assertion
@@ -139,6 +145,7 @@ func loadBpfFromCollectionSpec(bpfName string, specs *ebpf.CollectionSpec, const
 	// loads Maps and Programs into the kernel.
 	coll, err := ebpf.NewCollection(specs)
 	if err != nil {
+		recordLoadFailure(bpfName, err)
 		return nil, fmt.Errorf("create BPF collection: %w", err)
 	}
 	defer coll.Close()
@@ -223,6 +230,9 @@ func loadBpfFromCollectionSpec(bpfName string, specs *ebpf.CollectionSpec, const
 
 	log.Debugf("loaded bpf: %s", b)
 
+	info, _ := b.Info()
+	b.object = registerObject(bpfName, info)
+
 	// auto clean
 	runtime.SetFinalizer(b, (*defaultBPF).Close)
 	return b, nil
@@ -281,6 +291,7 @@ func (b *defaultBPF) Close() error {
 	if b.closed.Swap(true) {
 		return nil
 	}
+	b.object.unregister()
 
 	var closeErrs []error
 
@@ -369,6 +380,12 @@ func (b *defaultBPF) AttachWithOptions(opts []AttachOption) error {
 			}); err != nil {
 				return fmt.Errorf("attach perf event: %w", err)
 			}
+			b.object.attached(spec.name, "perf_event")
+		case ebpf.LSM:
+			// section: lsm/<hook>, the hook is resolved when loading.
+			if err = b.attachLSM(progID); err != nil {
+				return fmt.Errorf("attach lsm: %w", err)
+			}
 		default:
 			return fmt.Errorf("bpf %s: unsupported program type: %q", b, spec.specType)
 		}
@@ -422,6 +439,10 @@ func (b *defaultBPF) Attach() error {
 			if err = b.attachRawTracepoint(progID, symbols[1]); err != nil {
 				return fmt.Errorf("attach raw tracepoint: %w", err)
 			}
+		case ebpf.LSM:
+			if err = b.attachLSM(progID); err != nil {
+				return fmt.Errorf("attach lsm: %w", err)
+			}
 		default:
 			return fmt.Errorf("bpf %s: unsupported program type: %q", b, spec.specType)
 		}
@@ -465,6 +486,7 @@ func (b *defaultBPF) attachKprobe(progID uint32, symbol string, isRetprobe bool)
 		}
 
 		spec.links[linkKey] = l
+		b.object.attached(spec.name, "kprobe/"+symbol)
 		log.Debugf("attach kprobe %s, links: %d", symbol, len(spec.links))
 	} else { // kretprobe
 		linkKey := symbol
@@ -478,6 +500,7 @@ func (b *defaultBPF) attachKprobe(progID uint32, symbol string, isRetprobe bool)
 		}
 
 		spec.links[linkKey] = l
+		b.object.attached(spec.name, "kretprobe/"+symbol)
 		log.Debugf("attach kretprobe %s, links: %d", symbol, len(spec.links))
 	}
 
@@ -498,6 +521,7 @@ func (b *defaultBPF) attachTracepoint(progID uint32, system, symbol string) erro
 	}
 
 	spec.links[linkKey] = l
+	b.object.attached(spec.name, "tracepoint/"+linkKey)
 	log.Debugf("attach tracepoint %s/%s, links: %d", system, symbol, len(spec.links))
 	return nil
 }
@@ -519,10 +543,30 @@ func (b *defaultBPF) attachRawTracepoint(progID uint32, symbol string) error {
 	}
 
 	spec.links[linkKey] = l
+	b.object.attached(spec.name, "raw_tracepoint/"+symbol)
 	log.Debugf("attach raw tracepoint %s, links: %d", symbol, len(spec.links))
 	return nil
 }
 
+func (b *defaultBPF) attachLSM(progID uint32) error {
+	spec := b.programSpecs[progID]
+
+	linkKey := spec.sectionName
+	if _, ok := spec.links[linkKey]; ok {
+		return fmt.Errorf("bpf %s: duplicate section: %q", b, spec.sectionName)
+	}
+
+	l, err := link.AttachLSM(link.LSMOptions{Program: spec.cloned})
+	if err != nil {
+		return fmt.Errorf("attach lsm %q: %w", spec.sectionName, err)
+	}
+
+	spec.links[linkKey] = l
+	b.object.attached(spec.name, spec.sectionName)
+	log.Debugf("attach lsm %s, links: %d", spec.sectionName, len(spec.links))
+	return nil
+}
+
 func (b *defaultBPF) attachPerfEvent(opt *perfEventOption) error {
 	if b.innerPerfEvent != nil {
 		return fmt.Errorf("bpf %s: duplicate perf event attach", b)
@@ -570,6 +614,7 @@ func (b *defaultBPF) Detach() error {
 		}
 		b.innerPerfEvent = nil
 	}
+	b.object.detached()
 
 	return errors.Join(detachErrs...)
 }
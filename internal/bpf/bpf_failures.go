@@ -0,0 +1,109 @@
+// Copyright 2026 The HuaTuo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bpf
+
+import (
+	"errors"
+	"os"
+	"sync"
+	"time"
+
+	"huatuo-bamai/internal/utils/bytesutil"
+
+	"github.com/cilium/ebpf"
+	"golang.org/x/sys/unix"
+)
+
+// loadFailuresMax is the number of load failures kept in memory.
+const loadFailuresMax = 16
+
+const btfVmlinuxPath = "/sys/kernel/btf/vmlinux"
+
+// LoadFailure is a BPF object which the kernel refused to load.
+type LoadFailure struct {
+	Name          string    `json:"name"`
+	Time          time.Time `json:"time"`
+	KernelVersion string    `json:"kernel_version"`
+	BTF           bool      `json:"btf"`
+	Error         string    `json:"error"`
+	// VerifierLog is the full verifier output, empty when the failure
+	// happened before the verifier ran, e.g. a missing map type.
+	VerifierLog []string `json:"verifier_log,omitempty"`
+}
+
+var loadFailures struct {
+	sync.Mutex
+	list   []LoadFailure
+	notify func(*LoadFailure)
+}
+
+func setLoadFailureNotify(fn func(*LoadFailure)) {
+	loadFailures.Lock()
+	defer loadFailures.Unlock()
+
+	loadFailures.notify = fn
+}
+
+func newLoadFailure(name string, err error) *LoadFailure {
+	f := &LoadFailure{
+		Name:  name,
+		Time:  time.Now(),
+		Error: err.Error(),
+	}
+
+	var uts unix.Utsname
+	if err := unix.Uname(&uts); err == nil {
+		f.KernelVersion = bytesutil.ToStr(uts.Release[:])
+	}
+
+	_, statErr := os.Stat(btfVmlinuxPath)
+	f.BTF = statErr == nil
+
+	var verr *ebpf.VerifierError
+	if errors.As(err, &verr) {
+		f.VerifierLog = verr.Log
+	}
+
+	return f
+}
+
+func recordLoadFailure(name string, err error) {
+	f := newLoadFailure(name, err)
+
+	loadFailures.Lock()
+	if len(loadFailures.list) == loadFailuresMax {
+		loadFailures.list = loadFailures.list[1:]
+	}
+	loadFailures.list = append(loadFailures.list, *f)
+	notify := loadFailures.notify
+	loadFailures.Unlock()
+
+	if notify != nil {
+		notify(f)
+	}
+}
+
+// LoadFailures returns at most the n most recent load failures, oldest
+// first. n <= 0 returns all of them.
+func LoadFailures(n int) []LoadFailure {
+	loadFailures.Lock()
+	defer loadFailures.Unlock()
+
+	list := loadFailures.list
+	if n > 0 && n < len(list) {
+		list = list[len(list)-n:]
+	}
+	return append([]LoadFailure{}, list...)
+}
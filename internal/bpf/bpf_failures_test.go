@@ -0,0 +1,58 @@
+// Copyright 2026 The HuaTuo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bpf
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/cilium/ebpf"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRecordLoadFailure(t *testing.T) {
+	loadFailures.list = nil
+	t.Cleanup(func() {
+		loadFailures.list = nil
+		setLoadFailureNotify(nil)
+	})
+
+	var notified []string
+	setLoadFailureNotify(func(f *LoadFailure) { notified = append(notified, f.Name) })
+
+	verr := &ebpf.VerifierError{
+		Cause: errors.New("permission denied"),
+		Log:   []string{"0: (b7) r0 = 0", "R0 !read_ok"},
+	}
+	recordLoadFailure("verifier", fmt.Errorf("program foo: %w", verr))
+	for i := range loadFailuresMax {
+		recordLoadFailure(fmt.Sprintf("obj%d", i), errors.New("map create: invalid argument"))
+	}
+
+	all := LoadFailures(0)
+	assert.Len(t, all, loadFailuresMax)
+	assert.Equal(t, "obj0", all[0].Name)
+	assert.Len(t, notified, loadFailuresMax+1)
+	assert.Equal(t, "verifier", notified[0])
+
+	last := LoadFailures(2)
+	assert.Equal(t, []string{"obj14", "obj15"}, []string{last[0].Name, last[1].Name})
+	assert.Empty(t, last[1].VerifierLog)
+
+	f := newLoadFailure("verifier", fmt.Errorf("program foo: %w", verr))
+	assert.Equal(t, verr.Log, f.VerifierLog)
+	assert.NotEmpty(t, f.KernelVersion)
+}
@@ -0,0 +1,136 @@
+// Copyright 2026 The HuaTuo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bpf
+
+import (
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// ObjectState is a BPF object loaded in the kernel, with its programs and
+// where they are attached.
+type ObjectState struct {
+	Name     string         `json:"name"`
+	Maps     int            `json:"maps"`
+	Programs []ProgramState `json:"programs"`
+}
+
+// ProgramState is a program of a loaded object, Attached lists its attach
+// points, e.g. "sched/sched_switch", empty if it is not attached.
+type ProgramState struct {
+	Name     string   `json:"name"`
+	Section  string   `json:"section"`
+	Attached []string `json:"attached,omitempty"`
+}
+
+// loadedObject tracks a loaded object. It holds no reference to the
+// object, so the objects dropped without Close are still finalized.
+type loadedObject struct {
+	mu    sync.Mutex
+	state ObjectState
+}
+
+var (
+	loadedObjectsMu sync.Mutex
+	loadedObjects   = map[*loadedObject]struct{}{}
+)
+
+func registerObject(name string, info *Info) *loadedObject {
+	obj := &loadedObject{state: ObjectState{Name: name, Maps: len(info.MapsInfo)}}
+	for _, p := range info.ProgramsInfo {
+		obj.state.Programs = append(obj.state.Programs, ProgramState{Name: p.Name, Section: p.SectionName})
+	}
+	sort.Slice(obj.state.Programs, func(i, j int) bool {
+		return obj.state.Programs[i].Name < obj.state.Programs[j].Name
+	})
+
+	loadedObjectsMu.Lock()
+	loadedObjects[obj] = struct{}{}
+	loadedObjectsMu.Unlock()
+	return obj
+}
+
+func (o *loadedObject) unregister() {
+	if o == nil {
+		return
+	}
+	loadedObjectsMu.Lock()
+	delete(loadedObjects, o)
+	loadedObjectsMu.Unlock()
+}
+
+// attached records an attach point of the program, a nil object is not
+// tracked.
+func (o *loadedObject) attached(program, point string) {
+	if o == nil {
+		return
+	}
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	for i := range o.state.Programs {
+		if o.state.Programs[i].Name == program {
+			o.state.Programs[i].Attached = append(o.state.Programs[i].Attached, point)
+			return
+		}
+	}
+}
+
+// detached forgets the attach points of every program.
+func (o *loadedObject) detached() {
+	if o == nil {
+		return
+	}
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	for i := range o.state.Programs {
+		o.state.Programs[i].Attached = nil
+	}
+}
+
+func (o *loadedObject) snapshot() ObjectState {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	state := o.state
+	state.Programs = make([]ProgramState, len(o.state.Programs))
+	for i, p := range o.state.Programs {
+		p.Attached = slices.Clone(p.Attached)
+		state.Programs[i] = p
+	}
+	return state
+}
+
+// Objects returns the BPF objects loaded and not closed, sorted by name.
+func Objects() []ObjectState {
+	loadedObjectsMu.Lock()
+	objects := make([]*loadedObject, 0, len(loadedObjects))
+	for obj := range loadedObjects {
+		objects = append(objects, obj)
+	}
+	loadedObjectsMu.Unlock()
+
+	states := make([]ObjectState, 0, len(objects))
+	for _, obj := range objects {
+		states = append(states, obj.snapshot())
+	}
+	sort.SliceStable(states, func(i, j int) bool {
+		return strings.Compare(states[i].Name, states[j].Name) < 0
+	})
+	return states
+}
@@ -0,0 +1,87 @@
+// Copyright 2026 The HuaTuo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bpf
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// testObjects returns the objects registered by the tests, the other tests
+// may leave objects loaded.
+func testObjects() []ObjectState {
+	var states []ObjectState
+	for _, state := range Objects() {
+		if strings.HasPrefix(state.Name, "test_") {
+			states = append(states, state)
+		}
+	}
+	return states
+}
+
+func TestObjects(t *testing.T) {
+	netrx := registerObject("test_netrecvlat.o", &Info{
+		MapsInfo: []MapInfo{{ID: 1, Name: "events"}},
+		ProgramsInfo: []ProgramInfo{
+			{ID: 3, Name: "tcp_v4_rcv", SectionName: "kprobe/tcp_v4_rcv"},
+			{ID: 2, Name: "netif_receive_skb", SectionName: "tracepoint/net/netif_receive_skb"},
+		},
+	})
+	oom := registerObject("test_oom.o", &Info{
+		ProgramsInfo: []ProgramInfo{{ID: 1, Name: "oom_kill_process", SectionName: "kprobe/oom_kill_process"}},
+	})
+	t.Cleanup(func() {
+		netrx.unregister()
+		oom.unregister()
+	})
+
+	netrx.attached("tcp_v4_rcv", "kprobe/tcp_v4_rcv")
+	netrx.attached("unknown", "kprobe/unknown")
+
+	assert.Equal(t, []ObjectState{
+		{
+			Name: "test_netrecvlat.o",
+			Maps: 1,
+			Programs: []ProgramState{
+				{Name: "netif_receive_skb", Section: "tracepoint/net/netif_receive_skb"},
+				{Name: "tcp_v4_rcv", Section: "kprobe/tcp_v4_rcv", Attached: []string{"kprobe/tcp_v4_rcv"}},
+			},
+		},
+		{
+			Name:     "test_oom.o",
+			Programs: []ProgramState{{Name: "oom_kill_process", Section: "kprobe/oom_kill_process"}},
+		},
+	}, testObjects())
+
+	// the snapshots are copies.
+	testObjects()[0].Programs[1].Attached[0] = "changed"
+	assert.Equal(t, "kprobe/tcp_v4_rcv", testObjects()[0].Programs[1].Attached[0])
+
+	netrx.detached()
+	assert.Empty(t, testObjects()[0].Programs[1].Attached)
+
+	netrx.unregister()
+	states := testObjects()
+	assert.Len(t, states, 1)
+	assert.Equal(t, "test_oom.o", states[0].Name)
+
+	// the objects not tracked are ignored.
+	var untracked *loadedObject
+	untracked.attached("oom_kill_process", "kprobe/oom_kill_process")
+	untracked.detached()
+	untracked.unregister()
+}
@@ -0,0 +1,93 @@
+// Copyright 2026 The HuaTuo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package bugreport bundles agent state and host environment into a single
+// tarball to attach to issues.
+package bugreport
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"time"
+)
+
+// Source is one file of the bundle.
+type Source struct {
+	Name    string
+	Collect func(ctx context.Context) ([]byte, error)
+}
+
+// Write writes every source as a file under dir of a gzipped tarball. A
+// failing source leaves its error in <name>.err instead of failing the
+// whole bundle, a partial report is still worth filing.
+func Write(ctx context.Context, w io.Writer, dir string, sources []Source) error {
+	gw := gzip.NewWriter(w)
+	tw := tar.NewWriter(gw)
+
+	now := time.Now()
+	for _, src := range sources {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		name := src.Name
+		data, err := src.Collect(ctx)
+		if err != nil {
+			name += ".err"
+			data = []byte(err.Error() + "\n")
+		}
+
+		if err := tw.WriteHeader(&tar.Header{
+			Name:    path.Join(dir, name),
+			Mode:    0o644,
+			Size:    int64(len(data)),
+			ModTime: now,
+		}); err != nil {
+			return err
+		}
+		if _, err := tw.Write(data); err != nil {
+			return err
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gw.Close()
+}
+
+// TailFile returns at most the last n bytes of the file at path.
+func TailFile(path string, n int64) ([]byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	fi, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	offset := max(fi.Size()-n, 0)
+	if _, err := f.Seek(offset, io.SeekStart); err != nil {
+		return nil, fmt.Errorf("seek %s: %w", path, err)
+	}
+	return io.ReadAll(f)
+}
@@ -0,0 +1,74 @@
+// Copyright 2026 The HuaTuo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bugreport
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWrite(t *testing.T) {
+	sources := []Source{
+		{Name: "ok.txt", Collect: func(context.Context) ([]byte, error) { return []byte("hello"), nil }},
+		{Name: "bad.txt", Collect: func(context.Context) ([]byte, error) { return nil, errors.New("boom") }},
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, Write(context.Background(), &buf, "report", sources))
+
+	gr, err := gzip.NewReader(&buf)
+	require.NoError(t, err)
+
+	files := map[string]string{}
+	tr := tar.NewReader(gr)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+
+		data, err := io.ReadAll(tr)
+		require.NoError(t, err)
+		files[hdr.Name] = string(data)
+	}
+
+	require.Equal(t, map[string]string{
+		"report/ok.txt":      "hello",
+		"report/bad.txt.err": "boom\n",
+	}, files)
+}
+
+func TestTailFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "agent.log")
+	require.NoError(t, os.WriteFile(path, []byte("0123456789"), 0o600))
+
+	tail, err := TailFile(path, 4)
+	require.NoError(t, err)
+	require.Equal(t, "6789", string(tail))
+
+	all, err := TailFile(path, 100)
+	require.NoError(t, err)
+	require.Equal(t, "0123456789", string(all))
+}
@@ -0,0 +1,132 @@
+// Copyright 2026 The HuaTuo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bugreport
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"huatuo-bamai/internal/cgroups"
+	"huatuo-bamai/internal/procfs"
+	"huatuo-bamai/internal/utils/bytesutil"
+
+	"golang.org/x/sys/unix"
+)
+
+const (
+	btfVmlinuxPath = "/sys/kernel/btf/vmlinux"
+	osReleasePath  = "/etc/os-release"
+
+	kubeletVersionTimeout = 5 * time.Second
+)
+
+var cgroupModeNames = map[cgroups.Mode]string{
+	cgroups.Unavailable: "unavailable",
+	cgroups.Legacy:      "legacy (v1)",
+	cgroups.Hybrid:      "hybrid",
+	cgroups.Unified:     "unified (v2)",
+}
+
+// Environment describes the host: kernel, distribution, boot command line,
+// BTF, cgroup layout and kubelet version.
+func Environment(ctx context.Context) ([]byte, error) {
+	var b bytes.Buffer
+
+	var uts unix.Utsname
+	if err := unix.Uname(&uts); err == nil {
+		fmt.Fprintf(&b, "kernel: %s %s %s %s\n",
+			bytesutil.ToStr(uts.Sysname[:]), bytesutil.ToStr(uts.Release[:]),
+			bytesutil.ToStr(uts.Version[:]), bytesutil.ToStr(uts.Machine[:]))
+		fmt.Fprintf(&b, "hostname: %s\n", bytesutil.ToStr(uts.Nodename[:]))
+	}
+
+	if raw, err := os.ReadFile(procfs.Path("cmdline")); err == nil {
+		fmt.Fprintf(&b, "cmdline: %s\n", strings.TrimSpace(string(raw)))
+	}
+
+	_, err := os.Stat(btfVmlinuxPath)
+	fmt.Fprintf(&b, "btf: %v\n", err == nil)
+
+	fmt.Fprintf(&b, "cgroup mode: %s\n", cgroupModeNames[cgroups.CgroupMode()])
+	fmt.Fprintf(&b, "kubelet: %s\n", kubeletVersion(ctx))
+
+	b.WriteString("\ncgroup mounts:\n")
+	b.WriteString(cgroupMounts())
+
+	if raw, err := os.ReadFile(osReleasePath); err == nil {
+		b.WriteString("\nos-release:\n")
+		b.Write(raw)
+	}
+
+	return b.Bytes(), nil
+}
+
+// cgroupMounts returns the cgroup and cgroup2 lines of mountinfo.
+func cgroupMounts() string {
+	f, err := os.Open(procfs.Path("self", "mountinfo"))
+	if err != nil {
+		return err.Error() + "\n"
+	}
+	defer f.Close()
+
+	var b strings.Builder
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := scanner.Text()
+		// optional fields end with " - ", followed by the fs type.
+		_, fs, ok := strings.Cut(line, " - ")
+		if ok && (strings.HasPrefix(fs, "cgroup ") || strings.HasPrefix(fs, "cgroup2 ")) {
+			b.WriteString(line + "\n")
+		}
+	}
+	return b.String()
+}
+
+// kubeletVersion asks the running kubelet binary for its version.
+func kubeletVersion(ctx context.Context) string {
+	fs, err := procfs.NewDefaultFS()
+	if err != nil {
+		return err.Error()
+	}
+
+	procs, err := fs.AllProcs()
+	if err != nil {
+		return err.Error()
+	}
+
+	for _, p := range procs {
+		comm, err := p.Comm()
+		if err != nil || comm != "kubelet" {
+			continue
+		}
+
+		ctx, cancel := context.WithTimeout(ctx, kubeletVersionTimeout)
+		defer cancel()
+
+		out, err := exec.CommandContext(ctx, procfs.Path(fmt.Sprint(p.PID), "exe"), "--version").Output()
+		if err != nil {
+			return fmt.Sprintf("pid %d: %v", p.PID, err)
+		}
+		return strings.TrimSpace(string(out))
+	}
+
+	return "not running"
+}
@@ -1,4 +1,4 @@
-// Copyright 2025 The HuaTuo Authors
+// Copyright 2025, 2026 The HuaTuo Authors
 //
 // Licensed under the Apache License, Version 2.0 (the "License");
 // you may not use this file except in compliance with the License.
@@ -71,6 +71,11 @@ type Cgroup interface {
 	// memory.usage_in_bytes,memory.limit_in_bytes in cgroup1
 	// memory.current,memory.max in cgroup2
 	MemoryUsage(path string) (*stats.MemoryUsage, error)
+	// pids.current,pids.max
+	PidsUsage(path string) (*stats.PidsUsage, error)
+	// Pressure returns <resource>.pressure of cpu, memory or io, nil
+	// if the cgroup has none.
+	Pressure(path, resource string) (*stats.PSIStats, error)
 }
 
 func NewManager() (Cgroup, error) {
@@ -0,0 +1,115 @@
+// Copyright 2026 The HuaTuo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package stats
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"huatuo-bamai/internal/procfs"
+)
+
+// PSISupported tells whether the kernel reports the pressure stall
+// information, not without CONFIG_PSI or booted with psi=0.
+func PSISupported() bool {
+	_, err := os.Stat(procfs.Path("pressure"))
+	return err == nil
+}
+
+// ReadHostPSI reads /proc/pressure/<resource>.
+func ReadHostPSI(resource string) (*PSIStats, error) {
+	return ReadPSI(procfs.Path("pressure", resource))
+}
+
+// ReadPSI reads a pressure file, /proc/pressure/<resource> or the
+// <resource>.pressure of a cgroup.
+func ReadPSI(path string) (*PSIStats, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	psi, err := ParsePSI(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return psi, nil
+}
+
+// ParsePSI parses the "some" and "full" lines of a pressure file:
+//
+//	some avg10=0.00 avg60=0.00 avg300=0.00 total=0
+//	full avg10=0.00 avg60=0.00 avg300=0.00 total=0
+func ParsePSI(r io.Reader) (*PSIStats, error) {
+	psi := &PSIStats{}
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+
+		var line **PSILine
+		switch fields[0] {
+		case "some":
+			line = &psi.Some
+		case "full":
+			line = &psi.Full
+		default:
+			// the kinds added by the later kernels.
+			continue
+		}
+
+		l, err := parsePSILine(fields[1:])
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", fields[0], err)
+		}
+		*line = l
+	}
+
+	return psi, scanner.Err()
+}
+
+func parsePSILine(fields []string) (*PSILine, error) {
+	l := &PSILine{}
+	for _, field := range fields {
+		key, value, ok := strings.Cut(field, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid field %q", field)
+		}
+
+		var err error
+		switch key {
+		case "avg10":
+			l.Avg10, err = strconv.ParseFloat(value, 64)
+		case "avg60":
+			l.Avg60, err = strconv.ParseFloat(value, 64)
+		case "avg300":
+			l.Avg300, err = strconv.ParseFloat(value, 64)
+		case "total":
+			l.Total, err = strconv.ParseUint(value, 10, 64)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid field %q: %w", field, err)
+		}
+	}
+	return l, nil
+}
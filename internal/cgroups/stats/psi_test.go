@@ -0,0 +1,48 @@
+// Copyright 2026 The HuaTuo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package stats
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParsePSI(t *testing.T) {
+	psi, err := ParsePSI(strings.NewReader(
+		"some avg10=12.50 avg60=3.10 avg300=0.72 total=123456\n" +
+			"full avg10=1.00 avg60=0.00 avg300=0.00 total=42\n"))
+	if err != nil {
+		t.Fatalf("ParsePSI() error = %v", err)
+	}
+	if psi.Some == nil || *psi.Some != (PSILine{Avg10: 12.5, Avg60: 3.1, Avg300: 0.72, Total: 123456}) {
+		t.Errorf("some = %+v", psi.Some)
+	}
+	if psi.Full == nil || *psi.Full != (PSILine{Avg10: 1, Total: 42}) {
+		t.Errorf("full = %+v", psi.Full)
+	}
+
+	// the cpu pressure of the kernels before 5.13 has no full line.
+	psi, err = ParsePSI(strings.NewReader("some avg10=0.00 avg60=0.00 avg300=0.00 total=7\n"))
+	if err != nil {
+		t.Fatalf("ParsePSI() error = %v", err)
+	}
+	if psi.Some == nil || psi.Full != nil {
+		t.Errorf("psi = %+v, want some only", psi)
+	}
+
+	if _, err := ParsePSI(strings.NewReader("some avg10=x avg60=0.00 avg300=0.00 total=7\n")); err == nil {
+		t.Error("ParsePSI() of an invalid avg10 succeeded")
+	}
+}
@@ -1,4 +1,4 @@
-// Copyright 2025 The HuaTuo Authors
+// Copyright 2025, 2026 The HuaTuo Authors
 //
 // Licensed under the Apache License, Version 2.0 (the "License");
 // you may not use this file except in compliance with the License.
@@ -24,9 +24,34 @@ type CpuUsage struct {
 type CpuQuota struct {
 	Quota  uint64
 	Period uint64
+	// Burst is 0 when unset or unsupported by the kernel (< 5.14).
+	Burst uint64
 }
 
 type MemoryUsage struct {
 	Usage      uint64
 	MaxLimited uint64
 }
+
+type PidsUsage struct {
+	Current uint64
+	// Max is math.MaxUint64 when unlimited.
+	Max uint64
+}
+
+// PSILine is a line of a pressure file, the avgs are the percent of the
+// time stalled over 10, 60 and 300 seconds, Total the time stalled in
+// microseconds.
+type PSILine struct {
+	Avg10  float64
+	Avg60  float64
+	Avg300 float64
+	Total  uint64
+}
+
+// PSIStats is the pressure stall information of a resource, Some of the
+// time at least a task stalled and Full all of them, nil when missing.
+type PSIStats struct {
+	Some *PSILine
+	Full *PSILine
+}
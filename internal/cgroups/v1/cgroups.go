@@ -1,4 +1,4 @@
-// Copyright 2025 The HuaTuo Authors
+// Copyright 2025, 2026 The HuaTuo Authors
 //
 // Licensed under the Apache License, Version 2.0 (the "License");
 // you may not use this file except in compliance with the License.
@@ -17,6 +17,7 @@ package v1
 import (
 	"errors"
 	"math"
+	"os"
 	"syscall"
 
 	"huatuo-bamai/internal/cgroups/paths"
@@ -127,16 +128,24 @@ func (c *CgroupV1) CpuQuotaAndPeriod(path string) (*stats.CpuQuota, error) {
 		return nil, err
 	}
 
+	burstPath := paths.Path(subsystem.SubsystemCPU, path, "cpu.cfs_burst_us")
+	burst, err := parseutil.ReadUint(burstPath)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+
 	if quota == -1 {
 		return &stats.CpuQuota{
 			Quota:  math.MaxUint64,
 			Period: period,
+			Burst:  burst,
 		}, nil
 	}
 
 	return &stats.CpuQuota{
 		Quota:  uint64(quota),
 		Period: period,
+		Burst:  burst,
 	}, nil
 }
 
@@ -170,3 +179,30 @@ func (c *CgroupV1) MemoryUsage(path string) (*stats.MemoryUsage, error) {
 
 	return &stats.MemoryUsage{Usage: usage, MaxLimited: maxLimited}, nil
 }
+
+func (c *CgroupV1) PidsUsage(path string) (*stats.PidsUsage, error) {
+	current, err := parseutil.ReadUint(paths.Path(subsystem.SubsystemPids,
+		path, "pids.current"))
+	if err != nil {
+		return nil, err
+	}
+
+	maxLimited, err := parseutil.ReadUint(paths.Path(subsystem.SubsystemPids,
+		path, "pids.max"))
+	if err != nil {
+		return nil, err
+	}
+
+	return &stats.PidsUsage{Current: current, Max: maxLimited}, nil
+}
+
+// Pressure is missing from the upstream cgroup v1, the kernels exposing it
+// with the psi_v1 boot option put the files into cpuacct.
+func (c *CgroupV1) Pressure(path, resource string) (*stats.PSIStats, error) {
+	psi, err := stats.ReadPSI(paths.Path(subsystem.SubsystemCPUAcct, path, resource+".pressure"))
+	if err != nil && errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+
+	return psi, err
+}
@@ -1,4 +1,4 @@
-// Copyright 2025 The HuaTuo Authors
+// Copyright 2025, 2026 The HuaTuo Authors
 //
 // Licensed under the Apache License, Version 2.0 (the "License");
 // you may not use this file except in compliance with the License.
@@ -16,8 +16,10 @@ package v2
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"math"
+	"os"
 	"strconv"
 
 	"huatuo-bamai/internal/cgroups/paths"
@@ -169,8 +171,13 @@ func (c *CgroupV2) CpuQuotaAndPeriod(path string) (*stats.CpuQuota, error) {
 		return nil, err
 	}
 
+	burst, err := parseutil.ReadUint(paths.Path(path, "cpu.max.burst"))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+
 	if maxQuota == "max" {
-		return &stats.CpuQuota{Quota: math.MaxUint64, Period: period}, nil
+		return &stats.CpuQuota{Quota: math.MaxUint64, Period: period, Burst: burst}, nil
 	}
 
 	quota, err := strconv.ParseUint(maxQuota, 10, 64)
@@ -178,7 +185,7 @@ func (c *CgroupV2) CpuQuotaAndPeriod(path string) (*stats.CpuQuota, error) {
 		return nil, err
 	}
 
-	return &stats.CpuQuota{Quota: quota, Period: period}, nil
+	return &stats.CpuQuota{Quota: quota, Period: period, Burst: burst}, nil
 }
 
 func (c *CgroupV2) MemoryStatRaw(path string) (map[string]uint64, error) {
@@ -202,3 +209,27 @@ func (c *CgroupV2) MemoryUsage(path string) (*stats.MemoryUsage, error) {
 
 	return &stats.MemoryUsage{Usage: usage, MaxLimited: maxLimited}, nil
 }
+
+func (c *CgroupV2) PidsUsage(path string) (*stats.PidsUsage, error) {
+	current, err := parseutil.ReadUint(paths.Path(path, "pids.current"))
+	if err != nil {
+		return nil, err
+	}
+
+	maxLimited, err := parseutil.ReadUint(paths.Path(path, "pids.max"))
+	if err != nil {
+		return nil, err
+	}
+
+	return &stats.PidsUsage{Current: current, Max: maxLimited}, nil
+}
+
+func (c *CgroupV2) Pressure(path, resource string) (*stats.PSIStats, error) {
+	psi, err := stats.ReadPSI(paths.Path(path, resource+".pressure"))
+	if err != nil && errors.Is(err, os.ErrNotExist) {
+		// the kernel booted with psi=0.
+		return nil, nil
+	}
+
+	return psi, err
+}
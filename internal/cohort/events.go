@@ -0,0 +1,104 @@
+// Copyright 2026 The HuaTuo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cohort
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"huatuo-bamai/internal/storage"
+	"huatuo-bamai/internal/storage/driver"
+)
+
+// eventCollection is the collection of the tracing documents, the
+// DocumentCollection of pkg/tracing.
+const eventCollection = "tracing_documents"
+
+// the keyword fields of the dynamic mapping of the event documents.
+const (
+	eventFieldHostname     = "hostname.keyword"
+	eventFieldTracerName   = "tracer_name.keyword"
+	eventFieldUploadedTime = "uploaded_time"
+)
+
+// eventDocument is the part of the event documents the report reads.
+type eventDocument struct {
+	Hostname   string `json:"hostname"`
+	TracerName string `json:"tracer_name"`
+}
+
+type eventDocumentMapper struct{}
+
+func (eventDocumentMapper) ID(*eventDocument) string { return "" }
+
+func (eventDocumentMapper) Encode(doc *eventDocument) ([]byte, error) {
+	return json.Marshal(doc)
+}
+
+func (eventDocumentMapper) Decode(data []byte) (*eventDocument, error) {
+	var doc eventDocument
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	return &doc, nil
+}
+
+func (eventDocumentMapper) Fields(doc *eventDocument) (map[string]any, error) {
+	return map[string]any{
+		"hostname":    doc.Hostname,
+		"tracer_name": doc.TracerName,
+	}, nil
+}
+
+func (eventDocumentMapper) Indexes() []driver.Index {
+	return []driver.Index{
+		{Field: eventFieldHostname},
+		{Field: eventFieldTracerName},
+		{Field: eventFieldUploadedTime},
+	}
+}
+
+// Events counts the event documents of the storage.
+type Events struct {
+	store *storage.Store[*eventDocument]
+}
+
+// NewEvents returns the EventSource of the storage of cfg.
+func NewEvents(ctx context.Context, cfg *driver.Config) (*Events, error) {
+	store, err := storage.NewFromConfig[*eventDocument](ctx, cfg, eventCollection, eventDocumentMapper{})
+	if err != nil {
+		return nil, err
+	}
+	return &Events{store: store}, nil
+}
+
+// Count returns the events of the tracer the host uploaded within start
+// and end.
+func (e *Events) Count(ctx context.Context, host, tracer string, start, end time.Time) (int64, error) {
+	return e.store.Count(ctx, driver.Query{
+		Filters: []driver.Filter{
+			{Field: eventFieldHostname, Op: driver.OpEq, Value: host},
+			{Field: eventFieldTracerName, Op: driver.OpEq, Value: tracer},
+			{Field: eventFieldUploadedTime, Op: driver.OpGte, Value: start.UTC().Format(time.RFC3339Nano)},
+			{Field: eventFieldUploadedTime, Op: driver.OpLt, Value: end.UTC().Format(time.RFC3339Nano)},
+		},
+	})
+}
+
+// Close releases the storage.
+func (e *Events) Close(ctx context.Context) error {
+	return e.store.Close(ctx)
+}
@@ -0,0 +1,79 @@
+// Copyright 2026 The HuaTuo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cohort
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"time"
+)
+
+// WriteMarkdown writes the report as markdown tables, for the upgrade
+// reviews.
+func (r *Report) WriteMarkdown(w io.Writer) error {
+	b := bufio.NewWriter(w)
+
+	fmt.Fprintf(b, "# Cohort report: %s vs %s\n\n", r.A.Name, r.B.Name)
+	fmt.Fprintf(b, "Window: %s to %s, significance level %g.\n\n",
+		r.Start.Format(time.RFC3339), r.End.Format(time.RFC3339), r.Alpha)
+	fmt.Fprintf(b, "- %s: %d nodes %s\n", r.A.Name, len(r.A.Hosts), r.A.Selector)
+	fmt.Fprintf(b, "- %s: %d nodes %s\n", r.B.Name, len(r.B.Hosts), r.B.Selector)
+	if len(r.Overlap) > 0 {
+		fmt.Fprintf(b, "- in both, left out: %d nodes\n", len(r.Overlap))
+	}
+
+	fmt.Fprintf(b, "\n## Metrics\n\n")
+	fmt.Fprintf(b, "| metric | nodes | mean %[1]s | mean %[2]s | p50 %[1]s | p50 %[2]s | p90 %[1]s | p90 %[2]s | change | p-value |\n",
+		r.A.Name, r.B.Name)
+	fmt.Fprintf(b, "|---|---|---|---|---|---|---|---|---|---|\n")
+	for i := range r.Metrics {
+		m := &r.Metrics[i]
+		fmt.Fprintf(b, "| %s | %d/%d | %.4g | %.4g | %.4g | %.4g | %.4g | %.4g | %s |\n",
+			m.Name, m.A.Nodes, m.B.Nodes, m.A.Mean, m.B.Mean, m.A.P50, m.B.P50, m.A.P90, m.B.P90,
+			markdownComparison(m.Comparison))
+	}
+
+	fmt.Fprintf(b, "\n## Events per node\n\n")
+	fmt.Fprintf(b, "| tracer | total %[1]s | total %[2]s | mean %[1]s | mean %[2]s | max %[1]s | max %[2]s | change | p-value |\n",
+		r.A.Name, r.B.Name)
+	fmt.Fprintf(b, "|---|---|---|---|---|---|---|---|---|\n")
+	for i := range r.Events {
+		e := &r.Events[i]
+		fmt.Fprintf(b, "| %s | %d | %d | %.4g | %.4g | %.4g | %.4g | %s |\n",
+			e.Tracer, e.TotalA, e.TotalB, e.A.Mean, e.B.Mean, e.A.Max, e.B.Max,
+			markdownComparison(e.Comparison))
+	}
+
+	return b.Flush()
+}
+
+// markdownComparison returns the change and p-value cells, the significant
+// differences in bold.
+func markdownComparison(c *Comparison) string {
+	if c == nil {
+		return "- | -"
+	}
+
+	change := fmt.Sprintf("%+.4g", c.MeanDelta)
+	if c.MeanChangePercent != nil {
+		change = fmt.Sprintf("%+.1f%%", *c.MeanChangePercent)
+	}
+	pvalue := fmt.Sprintf("%.3g", c.PValue)
+	if c.Significant {
+		return fmt.Sprintf("**%s** | **%s**", change, pvalue)
+	}
+	return change + " | " + pvalue
+}
@@ -0,0 +1,127 @@
+// Copyright 2026 The HuaTuo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cohort
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// hostLabel is the label of the node in the huatuo-bamai metrics.
+const hostLabel = "host"
+
+// Prometheus reads the metrics from the HTTP API of Prometheus, or of a
+// compatible store such as Thanos or VictoriaMetrics.
+type Prometheus struct {
+	address string
+	client  *http.Client
+}
+
+// NewPrometheus returns the MetricSource of the API at address.
+func NewPrometheus(address string, timeout time.Duration) *Prometheus {
+	return &Prometheus{
+		address: strings.TrimSuffix(address, "/"),
+		client:  &http.Client{Timeout: timeout},
+	}
+}
+
+type promResponse struct {
+	Status string `json:"status"`
+	Error  string `json:"error"`
+	Data   struct {
+		ResultType string `json:"resultType"`
+		Result     []struct {
+			Metric map[string]string `json:"metric"`
+			// Value is [unix time, "value"].
+			Value [2]any `json:"value"`
+		} `json:"result"`
+	} `json:"data"`
+}
+
+// Hosts returns the hosts of the series matching selector within the window.
+func (p *Prometheus) Hosts(ctx context.Context, selector string, window time.Duration, end time.Time) ([]string, error) {
+	query := fmt.Sprintf("count by (%s) (count_over_time((%s)[%s]))", hostLabel, selector, promDuration(window))
+	values, err := p.Values(ctx, query, end)
+	if err != nil {
+		return nil, err
+	}
+
+	hosts := make([]string, 0, len(values))
+	for host := range values {
+		hosts = append(hosts, host)
+	}
+	return hosts, nil
+}
+
+// Values returns the value of the instant query by host, the series
+// without a host or with a NaN value are left out.
+func (p *Prometheus) Values(ctx context.Context, query string, end time.Time) (map[string]float64, error) {
+	params := url.Values{}
+	params.Set("query", query)
+	params.Set("time", strconv.FormatInt(end.Unix(), 10))
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.address+"/api/v1/query",
+		strings.NewReader(params.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	res, err := p.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("prometheus query: %w", err)
+	}
+	defer res.Body.Close()
+
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, fmt.Errorf("prometheus query: %w", err)
+	}
+
+	var resp promResponse
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return nil, fmt.Errorf("prometheus query: status %d: %s", res.StatusCode, strings.TrimSpace(string(body)))
+	}
+	if resp.Status != "success" {
+		return nil, fmt.Errorf("prometheus query %q: %s", query, resp.Error)
+	}
+	if resp.Data.ResultType != "vector" {
+		return nil, fmt.Errorf("prometheus query %q: %s result, want vector", query, resp.Data.ResultType)
+	}
+
+	values := make(map[string]float64, len(resp.Data.Result))
+	for _, sample := range resp.Data.Result {
+		host := sample.Metric[hostLabel]
+		raw, ok := sample.Value[1].(string)
+		if host == "" || !ok {
+			continue
+		}
+		v, err := strconv.ParseFloat(raw, 64)
+		if err != nil || math.IsNaN(v) || math.IsInf(v, 0) {
+			continue
+		}
+		values[host] = v
+	}
+
+	return values, nil
+}
@@ -0,0 +1,274 @@
+// Copyright 2026 The HuaTuo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package cohort compares the per-node metrics and events of two cohorts
+// of nodes over a window, e.g. the nodes of kernel A and of kernel B after
+// an upgrade.
+package cohort
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"golang.org/x/sync/errgroup"
+)
+
+// Cohort is a set of nodes, given by their hostnames or by the hosts of
+// the series matching a Prometheus selector within the window.
+type Cohort struct {
+	Name     string
+	Selector string
+	Hosts    []string
+}
+
+// Metric is a per-node value. Query is a PromQL expression, $window is
+// replaced by the window, and it is averaged by host.
+type Metric struct {
+	Name  string
+	Query string
+}
+
+// DefaultMetrics are compared when none is configured.
+var DefaultMetrics = []Metric{
+	{Name: "cpu_util_total", Query: "avg_over_time(huatuo_bamai_cpu_util_total[$window])"},
+	{Name: "cpu_util_sys", Query: "avg_over_time(huatuo_bamai_cpu_util_sys[$window])"},
+	{Name: "load1", Query: "avg_over_time(huatuo_bamai_loadavg_load1[$window])"},
+}
+
+// DefaultTracers are the events compared when none is configured.
+var DefaultTracers = []string{"softlockup", "hungtask", "oom", "ras", "softirq_tracing", "kernel_log"}
+
+// Spec is what a report compares.
+type Spec struct {
+	A, B    Cohort
+	End     time.Time
+	Window  time.Duration
+	Metrics []Metric
+	Tracers []string
+	// Alpha is the significance level of the tests.
+	Alpha float64
+	// Concurrency bounds the queries in flight.
+	Concurrency int
+}
+
+// MetricSource reads the per-node metrics.
+type MetricSource interface {
+	// Hosts returns the hosts of the series matching selector within the
+	// window ending at end.
+	Hosts(ctx context.Context, selector string, window time.Duration, end time.Time) ([]string, error)
+	// Values returns the value of query by host at end.
+	Values(ctx context.Context, query string, end time.Time) (map[string]float64, error)
+}
+
+// EventSource counts the events of a node.
+type EventSource interface {
+	Count(ctx context.Context, host, tracer string, start, end time.Time) (int64, error)
+}
+
+// Report is the statistical summary document of the comparison.
+type Report struct {
+	GeneratedAt time.Time    `json:"generated_at"`
+	Start       time.Time    `json:"start"`
+	End         time.Time    `json:"end"`
+	Alpha       float64      `json:"alpha"`
+	A           CohortResult `json:"a"`
+	B           CohortResult `json:"b"`
+	// Overlap are the hosts in both cohorts within the window, e.g. the
+	// nodes upgraded meanwhile, left out of both.
+	Overlap []string           `json:"overlap,omitempty"`
+	Metrics []MetricComparison `json:"metrics"`
+	Events  []EventComparison  `json:"events"`
+}
+
+// CohortResult is a cohort as resolved for the report.
+type CohortResult struct {
+	Name     string   `json:"name"`
+	Selector string   `json:"selector,omitempty"`
+	Hosts    []string `json:"hosts"`
+}
+
+// MetricComparison compares a metric, the nodes without a value are left
+// out of the summaries.
+type MetricComparison struct {
+	Name       string      `json:"name"`
+	Query      string      `json:"query"`
+	A          Summary     `json:"a"`
+	B          Summary     `json:"b"`
+	Comparison *Comparison `json:"comparison,omitempty"`
+}
+
+// EventComparison compares the events of a tracer per node.
+type EventComparison struct {
+	Tracer     string      `json:"tracer"`
+	TotalA     int64       `json:"total_a"`
+	TotalB     int64       `json:"total_b"`
+	A          Summary     `json:"a"`
+	B          Summary     `json:"b"`
+	Comparison *Comparison `json:"comparison,omitempty"`
+}
+
+// Generate builds the report of spec.
+func Generate(ctx context.Context, spec *Spec, metrics MetricSource, events EventSource) (*Report, error) {
+	if spec.Window <= 0 {
+		return nil, errors.New("cohort report window must be positive")
+	}
+	if spec.A.Name == spec.B.Name {
+		return nil, fmt.Errorf("cohorts have the same name %q", spec.A.Name)
+	}
+
+	report := &Report{
+		GeneratedAt: time.Now().UTC(),
+		Start:       spec.End.Add(-spec.Window).UTC(),
+		End:         spec.End.UTC(),
+		Alpha:       spec.Alpha,
+		Metrics:     []MetricComparison{},
+		Events:      []EventComparison{},
+	}
+
+	hostsA, err := resolveHosts(ctx, &spec.A, spec, metrics)
+	if err != nil {
+		return nil, err
+	}
+	hostsB, err := resolveHosts(ctx, &spec.B, spec, metrics)
+	if err != nil {
+		return nil, err
+	}
+	hostsA, hostsB, report.Overlap = splitOverlap(hostsA, hostsB)
+	if len(hostsA) == 0 || len(hostsB) == 0 {
+		return nil, fmt.Errorf("cohort %s has %d hosts and %s %d, both need one at least",
+			spec.A.Name, len(hostsA), spec.B.Name, len(hostsB))
+	}
+	report.A = CohortResult{Name: spec.A.Name, Selector: spec.A.Selector, Hosts: hostsA}
+	report.B = CohortResult{Name: spec.B.Name, Selector: spec.B.Selector, Hosts: hostsB}
+
+	for _, m := range spec.Metrics {
+		query := "avg by (host) (" + strings.ReplaceAll(m.Query, "$window", promDuration(spec.Window)) + ")"
+		values, err := metrics.Values(ctx, query, spec.End)
+		if err != nil {
+			return nil, fmt.Errorf("metric %s: %w", m.Name, err)
+		}
+
+		a, b := pick(values, hostsA), pick(values, hostsB)
+		report.Metrics = append(report.Metrics, MetricComparison{
+			Name:       m.Name,
+			Query:      query,
+			A:          summarize(a),
+			B:          summarize(b),
+			Comparison: compare(a, b, spec.Alpha),
+		})
+	}
+
+	for _, tracer := range spec.Tracers {
+		a, totalA, err := countEvents(ctx, events, hostsA, tracer, report.Start, report.End, spec.Concurrency)
+		if err != nil {
+			return nil, err
+		}
+		b, totalB, err := countEvents(ctx, events, hostsB, tracer, report.Start, report.End, spec.Concurrency)
+		if err != nil {
+			return nil, err
+		}
+
+		report.Events = append(report.Events, EventComparison{
+			Tracer:     tracer,
+			TotalA:     totalA,
+			TotalB:     totalB,
+			A:          summarize(a),
+			B:          summarize(b),
+			Comparison: compare(a, b, spec.Alpha),
+		})
+	}
+
+	return report, nil
+}
+
+func resolveHosts(ctx context.Context, c *Cohort, spec *Spec, metrics MetricSource) ([]string, error) {
+	hosts := slices.Clone(c.Hosts)
+	if c.Selector != "" {
+		selected, err := metrics.Hosts(ctx, c.Selector, spec.Window, spec.End)
+		if err != nil {
+			return nil, fmt.Errorf("cohort %s: %w", c.Name, err)
+		}
+		hosts = append(hosts, selected...)
+	}
+
+	slices.Sort(hosts)
+	return slices.Compact(hosts), nil
+}
+
+// splitOverlap removes the hosts of both a and b, both sorted.
+func splitOverlap(a, b []string) ([]string, []string, []string) {
+	var overlap []string
+	for _, host := range a {
+		if _, found := slices.BinarySearch(b, host); found {
+			overlap = append(overlap, host)
+		}
+	}
+
+	without := func(hosts []string) []string {
+		return slices.DeleteFunc(slices.Clone(hosts), func(host string) bool {
+			_, found := slices.BinarySearch(overlap, host)
+			return found
+		})
+	}
+	return without(a), without(b), overlap
+}
+
+func pick(values map[string]float64, hosts []string) []float64 {
+	picked := make([]float64, 0, len(hosts))
+	for _, host := range hosts {
+		if v, ok := values[host]; ok {
+			picked = append(picked, v)
+		}
+	}
+	return picked
+}
+
+// countEvents returns the events of the tracer by node, zero for the nodes
+// without any.
+func countEvents(ctx context.Context, events EventSource, hosts []string, tracer string,
+	start, end time.Time, concurrency int,
+) ([]float64, int64, error) {
+	counts := make([]float64, len(hosts))
+
+	group, ctx := errgroup.WithContext(ctx)
+	group.SetLimit(max(concurrency, 1))
+	for i, host := range hosts {
+		group.Go(func() error {
+			n, err := events.Count(ctx, host, tracer, start, end)
+			if err != nil {
+				return fmt.Errorf("count %s events of %s: %w", tracer, host, err)
+			}
+			counts[i] = float64(n)
+			return nil
+		})
+	}
+	if err := group.Wait(); err != nil {
+		return nil, 0, err
+	}
+
+	var total int64
+	for _, n := range counts {
+		total += int64(n)
+	}
+	return counts, total, nil
+}
+
+// promDuration formats d in whole seconds, a PromQL range.
+func promDuration(d time.Duration) string {
+	return fmt.Sprintf("%ds", int64(max(d, time.Second)/time.Second))
+}
@@ -0,0 +1,157 @@
+// Copyright 2026 The HuaTuo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cohort
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newMockPrometheus answers the queries containing a key of results with
+// its host values.
+func newMockPrometheus(t *testing.T, results map[string]map[string]string) *httptest.Server {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query := r.FormValue("query")
+		if strings.Contains(query, "invalid") {
+			fmt.Fprint(w, `{"status":"error","errorType":"bad_data","error":"parse error"}`)
+			return
+		}
+
+		var samples []string
+		for key, hosts := range results {
+			if !strings.Contains(query, key) {
+				continue
+			}
+			for host, value := range hosts {
+				samples = append(samples, fmt.Sprintf(`{"metric":{"host":%q},"value":[1700000000,%q]}`, host, value))
+			}
+		}
+		fmt.Fprintf(w, `{"status":"success","data":{"resultType":"vector","result":[%s]}}`, strings.Join(samples, ","))
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+type fakeEvents map[string]int64
+
+func (f fakeEvents) Count(_ context.Context, host, tracer string, _, _ time.Time) (int64, error) {
+	return f[host+"/"+tracer], nil
+}
+
+func TestPrometheusValues(t *testing.T) {
+	server := newMockPrometheus(t, map[string]map[string]string{
+		"cpu": {"node-a": "1.5", "node-b": "NaN", "": "3"},
+	})
+	prom := NewPrometheus(server.URL+"/", time.Second)
+
+	values, err := prom.Values(t.Context(), "cpu", time.Now())
+	if err != nil {
+		t.Fatalf("Values() error = %v", err)
+	}
+	if want := map[string]float64{"node-a": 1.5}; !reflect.DeepEqual(values, want) {
+		t.Errorf("Values() = %v, want %v", values, want)
+	}
+
+	if _, err := prom.Values(t.Context(), "invalid", time.Now()); err == nil {
+		t.Error("Values() of an invalid query error = nil")
+	}
+}
+
+func TestGenerate(t *testing.T) {
+	server := newMockPrometheus(t, map[string]map[string]string{
+		`running="5.10"`: {"a1": "1", "a2": "1", "a3": "1", "both": "1"},
+		`running="6.6"`:  {"b1": "1", "b2": "1", "both": "1"},
+		"cpu_util_sys":   {"a1": "10", "a2": "12", "a3": "11", "b1": "20", "b2": "22", "both": "50"},
+	})
+
+	spec := &Spec{
+		A:           Cohort{Name: "5.10", Selector: `huatuo_bamai_kernel_patch_boot_kernel_mismatch{running="5.10"}`},
+		B:           Cohort{Name: "6.6", Selector: `huatuo_bamai_kernel_patch_boot_kernel_mismatch{running="6.6"}`, Hosts: []string{"b3"}},
+		End:         time.Date(2026, 5, 1, 0, 0, 0, 0, time.UTC),
+		Window:      24 * time.Hour,
+		Metrics:     []Metric{{Name: "sys", Query: "avg_over_time(huatuo_bamai_cpu_util_sys[$window])"}},
+		Tracers:     []string{"softlockup"},
+		Alpha:       0.05,
+		Concurrency: 2,
+	}
+	events := fakeEvents{"b1/softlockup": 2, "b3/softlockup": 1, "both/softlockup": 9}
+
+	report, err := Generate(t.Context(), spec, NewPrometheus(server.URL, time.Second), events)
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+
+	if !reflect.DeepEqual(report.A.Hosts, []string{"a1", "a2", "a3"}) ||
+		!reflect.DeepEqual(report.B.Hosts, []string{"b1", "b2", "b3"}) ||
+		!reflect.DeepEqual(report.Overlap, []string{"both"}) {
+		t.Errorf("hosts = %v, %v, overlap %v", report.A.Hosts, report.B.Hosts, report.Overlap)
+	}
+	if !report.Start.Equal(spec.End.Add(-24 * time.Hour)) {
+		t.Errorf("Start = %v, want a day before End", report.Start)
+	}
+
+	m := report.Metrics[0]
+	if m.Query != "avg by (host) (avg_over_time(huatuo_bamai_cpu_util_sys[86400s]))" {
+		t.Errorf("metric query = %s", m.Query)
+	}
+	// b3 has no metric.
+	if m.A.Nodes != 3 || m.B.Nodes != 2 || m.Comparison == nil || m.Comparison.MeanDelta != 10 {
+		t.Errorf("metric comparison = %+v", m)
+	}
+
+	e := report.Events[0]
+	if e.TotalA != 0 || e.TotalB != 3 || e.B.Nodes != 3 || e.B.Max != 2 {
+		t.Errorf("event comparison = %+v", e)
+	}
+
+	var md bytes.Buffer
+	if err := report.WriteMarkdown(&md); err != nil {
+		t.Fatalf("WriteMarkdown() error = %v", err)
+	}
+	for _, want := range []string{"# Cohort report: 5.10 vs 6.6", "| sys | 3/2 |", "| softlockup | 0 | 3 |"} {
+		if !strings.Contains(md.String(), want) {
+			t.Errorf("markdown misses %q:\n%s", want, md.String())
+		}
+	}
+}
+
+func TestGenerateInvalid(t *testing.T) {
+	server := newMockPrometheus(t, map[string]map[string]string{
+		"5.10": {"a1": "1"},
+	})
+	prom := NewPrometheus(server.URL, time.Second)
+
+	for name, spec := range map[string]Spec{
+		"no window":    {A: Cohort{Name: "a", Hosts: []string{"a1"}}, B: Cohort{Name: "b", Hosts: []string{"b1"}}},
+		"same name":    {A: Cohort{Name: "a"}, B: Cohort{Name: "a"}, Window: time.Hour},
+		"empty cohort": {A: Cohort{Name: "a", Selector: "5.10"}, B: Cohort{Name: "b", Selector: "6.6"}, Window: time.Hour},
+		"invalid selector": {
+			A: Cohort{Name: "a", Selector: "invalid"}, B: Cohort{Name: "b", Hosts: []string{"b1"}}, Window: time.Hour,
+		},
+	} {
+		if _, err := Generate(t.Context(), &spec, prom, fakeEvents{}); err == nil {
+			t.Errorf("%s: Generate() error = nil", name)
+		}
+	}
+}
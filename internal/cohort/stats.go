@@ -0,0 +1,168 @@
+// Copyright 2026 The HuaTuo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cohort
+
+import (
+	"math"
+	"slices"
+)
+
+// Summary describes the per-node values of a cohort.
+type Summary struct {
+	Nodes  int     `json:"nodes"`
+	Mean   float64 `json:"mean"`
+	Stddev float64 `json:"stddev"`
+	Min    float64 `json:"min"`
+	P50    float64 `json:"p50"`
+	P90    float64 `json:"p90"`
+	P99    float64 `json:"p99"`
+	Max    float64 `json:"max"`
+}
+
+// Comparison is the difference of cohort B from cohort A.
+type Comparison struct {
+	MeanDelta float64 `json:"mean_delta"`
+	// MeanChangePercent is the mean delta in percent of the mean of A,
+	// absent when the mean of A is zero.
+	MeanChangePercent *float64 `json:"mean_change_percent,omitempty"`
+	P50Delta          float64  `json:"p50_delta"`
+	// U and PValue are the Mann-Whitney U test of the two samples, which
+	// does not assume the per-node values are normally distributed.
+	U           float64 `json:"u"`
+	PValue      float64 `json:"p_value"`
+	Significant bool    `json:"significant"`
+}
+
+func summarize(values []float64) Summary {
+	if len(values) == 0 {
+		return Summary{}
+	}
+
+	sorted := slices.Clone(values)
+	slices.Sort(sorted)
+
+	var sum float64
+	for _, v := range sorted {
+		sum += v
+	}
+	mean := sum / float64(len(sorted))
+
+	var variance float64
+	if len(sorted) > 1 {
+		for _, v := range sorted {
+			variance += (v - mean) * (v - mean)
+		}
+		variance /= float64(len(sorted) - 1)
+	}
+
+	return Summary{
+		Nodes:  len(sorted),
+		Mean:   mean,
+		Stddev: math.Sqrt(variance),
+		Min:    sorted[0],
+		P50:    quantile(sorted, 0.5),
+		P90:    quantile(sorted, 0.9),
+		P99:    quantile(sorted, 0.99),
+		Max:    sorted[len(sorted)-1],
+	}
+}
+
+// quantile interpolates linearly between the closest ranks of sorted.
+func quantile(sorted []float64, q float64) float64 {
+	pos := q * float64(len(sorted)-1)
+	lower := int(math.Floor(pos))
+	if lower+1 >= len(sorted) {
+		return sorted[lower]
+	}
+	return sorted[lower] + (pos-float64(lower))*(sorted[lower+1]-sorted[lower])
+}
+
+// compare returns nil when either cohort has no value.
+func compare(a, b []float64, alpha float64) *Comparison {
+	if len(a) == 0 || len(b) == 0 {
+		return nil
+	}
+
+	sa, sb := summarize(a), summarize(b)
+	c := &Comparison{
+		MeanDelta: sb.Mean - sa.Mean,
+		P50Delta:  sb.P50 - sa.P50,
+	}
+	if sa.Mean != 0 {
+		change := c.MeanDelta / math.Abs(sa.Mean) * 100
+		c.MeanChangePercent = &change
+	}
+
+	c.U, c.PValue = mannWhitney(a, b)
+	c.Significant = c.PValue < alpha
+	return c
+}
+
+// mannWhitney returns the U statistic of a and the two-sided p-value of
+// the normal approximation, with the tie and continuity corrections.
+func mannWhitney(a, b []float64) (float64, float64) {
+	type sample struct {
+		value float64
+		first bool
+	}
+
+	samples := make([]sample, 0, len(a)+len(b))
+	for _, v := range a {
+		samples = append(samples, sample{value: v, first: true})
+	}
+	for _, v := range b {
+		samples = append(samples, sample{value: v})
+	}
+	slices.SortFunc(samples, func(x, y sample) int {
+		switch {
+		case x.value < y.value:
+			return -1
+		case x.value > y.value:
+			return 1
+		}
+		return 0
+	})
+
+	var rankSum, ties float64
+	for i := 0; i < len(samples); {
+		j := i
+		for j < len(samples) && samples[j].value == samples[i].value {
+			j++
+		}
+		// the tied samples share the average of their ranks.
+		rank := float64(i+j+1) / 2
+		for k := i; k < j; k++ {
+			if samples[k].first {
+				rankSum += rank
+			}
+		}
+		t := float64(j - i)
+		ties += t*t*t - t
+		i = j
+	}
+
+	n1, n2 := float64(len(a)), float64(len(b))
+	n := n1 + n2
+	u := rankSum - n1*(n1+1)/2
+
+	variance := n1 * n2 / 12 * ((n + 1) - ties/(n*(n-1)))
+	if variance <= 0 {
+		// every value is equal.
+		return u, 1
+	}
+
+	z := math.Max(math.Abs(u-n1*n2/2)-0.5, 0) / math.Sqrt(variance)
+	return u, math.Erfc(z / math.Sqrt2)
+}
@@ -0,0 +1,93 @@
+// Copyright 2026 The HuaTuo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cohort
+
+import (
+	"math"
+	"testing"
+)
+
+func near(a, b float64) bool {
+	return math.Abs(a-b) < 1e-3
+}
+
+func TestSummarize(t *testing.T) {
+	s := summarize([]float64{4, 1, 3, 2, 5})
+	want := Summary{Nodes: 5, Mean: 3, Stddev: math.Sqrt(2.5), Min: 1, P50: 3, P90: 4.6, P99: 4.96, Max: 5}
+	if s.Nodes != want.Nodes || !near(s.Mean, want.Mean) || !near(s.Stddev, want.Stddev) ||
+		s.Min != want.Min || !near(s.P50, want.P50) || !near(s.P90, want.P90) || !near(s.P99, want.P99) || s.Max != want.Max {
+		t.Errorf("summarize() = %+v, want %+v", s, want)
+	}
+
+	if s := summarize([]float64{7}); s.Nodes != 1 || s.Stddev != 0 || s.P99 != 7 {
+		t.Errorf("summarize() of one value = %+v", s)
+	}
+	if s := summarize(nil); s != (Summary{}) {
+		t.Errorf("summarize(nil) = %+v, want zero", s)
+	}
+}
+
+func TestMannWhitney(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b []float64
+		u, p float64
+	}{
+		{
+			name: "separated",
+			a:    []float64{1, 2, 3, 4, 5},
+			b:    []float64{6, 7, 8, 9, 10},
+			u:    0,
+			p:    0.01219,
+		},
+		{
+			name: "ties",
+			a:    []float64{1, 2, 2, 3},
+			b:    []float64{2, 3, 3, 4},
+			u:    3,
+			p:    0.1720,
+		},
+		{
+			name: "equal",
+			a:    []float64{0, 0, 0},
+			b:    []float64{0, 0},
+			u:    3,
+			p:    1,
+		},
+	}
+
+	for i := range tests {
+		t.Run(tests[i].name, func(t *testing.T) {
+			u, p := mannWhitney(tests[i].a, tests[i].b)
+			if !near(u, tests[i].u) || !near(p, tests[i].p) {
+				t.Errorf("mannWhitney() = %g, %g, want %g, %g", u, p, tests[i].u, tests[i].p)
+			}
+		})
+	}
+}
+
+func TestCompare(t *testing.T) {
+	c := compare([]float64{1, 2, 3, 4, 5}, []float64{6, 7, 8, 9, 10}, 0.05)
+	if c == nil || c.MeanDelta != 5 || c.MeanChangePercent == nil || !near(*c.MeanChangePercent, 500.0/3) || !c.Significant {
+		t.Errorf("compare() = %+v, want a significant +5", c)
+	}
+
+	if c := compare([]float64{0, 0}, []float64{1, 1}, 0.05); c == nil || c.MeanChangePercent != nil {
+		t.Errorf("compare() from a zero mean = %+v, want no change percent", c)
+	}
+	if c := compare(nil, []float64{1}, 0.05); c != nil {
+		t.Errorf("compare() of an empty cohort = %+v, want nil", c)
+	}
+}
@@ -41,7 +41,8 @@ func init() {
 	}
 }
 
-// Load decodes a toml file into dst using strict mode.
+// Load decodes a toml file into dst using strict mode, then checks the
+// fields against their min, max and enum tags.
 func Load(path string, dst any) error {
 	f, err := os.Open(path)
 	if err != nil {
@@ -49,7 +50,10 @@ func Load(path string, dst any) error {
 	}
 	defer f.Close()
 
-	return toml.NewDecoder(f).Strict(true).Decode(dst)
+	if err := toml.NewDecoder(f).Strict(true).Decode(dst); err != nil {
+		return err
+	}
+	return Validate(dst)
 }
 
 // Sync encodes src as toml and writes it to path.
@@ -0,0 +1,97 @@
+// Copyright 2026 The HuaTuo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+)
+
+// Difference is a key whose value differs between two configs, A or B is
+// nil when the key is not in that config.
+type Difference struct {
+	Path string `json:"path"`
+	A    any    `json:"a"`
+	B    any    `json:"b"`
+}
+
+// Diff compares the configs a and b key by key, they are structs or their
+// JSON objects, e.g. a config struct and the config of the API. The
+// differences are sorted by path, e.g. Storage.Routing[1].Backends[0]. The
+// empty lists and tables are the same as the missing ones.
+func Diff(a, b any) ([]Difference, error) {
+	flatA, err := flatten(a)
+	if err != nil {
+		return nil, err
+	}
+	flatB, err := flatten(b)
+	if err != nil {
+		return nil, err
+	}
+
+	var diffs []Difference
+	for path, va := range flatA {
+		if vb, ok := flatB[path]; !ok || !reflect.DeepEqual(va, vb) {
+			diffs = append(diffs, Difference{Path: path, A: va, B: flatB[path]})
+		}
+	}
+	for path, vb := range flatB {
+		if _, ok := flatA[path]; !ok {
+			diffs = append(diffs, Difference{Path: path, B: vb})
+		}
+	}
+
+	sort.Slice(diffs, func(i, j int) bool { return diffs[i].Path < diffs[j].Path })
+	return diffs, nil
+}
+
+// flatten maps the paths of the leaves of v to their values, the numbers
+// are json.Number so that both sides compare the same.
+func flatten(v any) (map[string]any, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("encode config: %w", err)
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var generic any
+	if err := dec.Decode(&generic); err != nil {
+		return nil, fmt.Errorf("decode config: %w", err)
+	}
+
+	flat := map[string]any{}
+	flattenValue(generic, "", flat)
+	return flat, nil
+}
+
+func flattenValue(v any, path string, flat map[string]any) {
+	switch v := v.(type) {
+	case map[string]any:
+		for key, elem := range v {
+			flattenValue(elem, joinPath(path, key), flat)
+		}
+	case []any:
+		for i, elem := range v {
+			flattenValue(elem, fmt.Sprintf("%s[%d]", path, i), flat)
+		}
+	case nil:
+	default:
+		flat[path] = v
+	}
+}
@@ -0,0 +1,70 @@
+// Copyright 2026 The HuaTuo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestDiff(t *testing.T) {
+	running := boundedConfig{Port: 8080, Compression: "gzip", Ratio: 0.5, Modules: []string{"a", "b"}}
+	running.Routes = append(running.Routes, struct {
+		Backends []string `enum:"es,loki"`
+		Weight   int      `min:"0"`
+	}{Backends: []string{"es"}, Weight: 1})
+
+	file := running
+	file.Port = 9090
+	file.Modules = []string{"a"}
+	file.Labels = map[string]string{"zone": "a"}
+	file.Routes = nil
+
+	// the running config comes from the API, as JSON.
+	data, err := json.Marshal(running)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var runningJSON map[string]any
+	if err := json.Unmarshal(data, &runningJSON); err != nil {
+		t.Fatal(err)
+	}
+
+	diffs, err := Diff(runningJSON, &file)
+	if err != nil {
+		t.Fatalf("Diff() error = %v", err)
+	}
+	want := []Difference{
+		{Path: "Labels.zone", B: "a"},
+		{Path: "Modules[1]", A: "b"},
+		{Path: "Port", A: json.Number("8080"), B: json.Number("9090")},
+		{Path: "Routes[0].Backends[0]", A: "es"},
+		{Path: "Routes[0].Weight", A: json.Number("1")},
+	}
+	if !reflect.DeepEqual(diffs, want) {
+		t.Errorf("Diff() = %+v, want %+v", diffs, want)
+	}
+
+	if diffs, err := Diff(&running, runningJSON); err != nil || len(diffs) != 0 {
+		t.Errorf("Diff() of the same config = %+v, %v, want none", diffs, err)
+	}
+
+	// empty tables and lists are the missing ones.
+	empty := boundedConfig{Labels: map[string]string{}, Modules: []string{}}
+	if diffs, err := Diff(&boundedConfig{}, &empty); err != nil || len(diffs) != 0 {
+		t.Errorf("Diff() of empty and missing = %+v, %v, want none", diffs, err)
+	}
+}
@@ -0,0 +1,93 @@
+// Copyright 2026 The HuaTuo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"reflect"
+)
+
+// MaskedValue replaces the values of the secret fields in the configs
+// returned by Mask.
+const MaskedValue = "******"
+
+// Mask returns a copy of the config cfg points to with the fields tagged
+// `secret:"true"` masked: a non-empty string, and every value of a map of
+// strings, e.g. the headers of a request. The maps and the slices of the
+// copy holding secrets are copied, they are not shared with cfg.
+func Mask[T any](cfg *T) *T {
+	masked := *cfg
+	maskValue(reflect.ValueOf(&masked).Elem())
+	return &masked
+}
+
+func maskValue(v reflect.Value) {
+	switch v.Kind() {
+	case reflect.Struct:
+		t := v.Type()
+		for i := 0; i < v.NumField(); i++ {
+			if !t.Field(i).IsExported() {
+				continue
+			}
+			if t.Field(i).Tag.Get("secret") == "true" {
+				maskSecret(v.Field(i))
+				continue
+			}
+			maskValue(v.Field(i))
+		}
+	case reflect.Slice:
+		if v.IsNil() || !hasSecret(v.Type().Elem()) {
+			return
+		}
+		elems := reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+		reflect.Copy(elems, v)
+		for i := 0; i < elems.Len(); i++ {
+			maskValue(elems.Index(i))
+		}
+		v.Set(elems)
+	}
+}
+
+func maskSecret(v reflect.Value) {
+	switch v.Kind() {
+	case reflect.String:
+		if v.Len() > 0 {
+			v.SetString(MaskedValue)
+		}
+	case reflect.Map:
+		if v.IsNil() || v.Type().Elem().Kind() != reflect.String {
+			return
+		}
+		masked := reflect.MakeMapWithSize(v.Type(), v.Len())
+		for _, key := range v.MapKeys() {
+			masked.SetMapIndex(key, reflect.ValueOf(MaskedValue).Convert(v.Type().Elem()))
+		}
+		v.Set(masked)
+	}
+}
+
+// hasSecret tells whether a value of the type may hold a secret field.
+func hasSecret(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Struct:
+		for i := 0; i < t.NumField(); i++ {
+			if t.Field(i).Tag.Get("secret") == "true" || hasSecret(t.Field(i).Type) {
+				return true
+			}
+		}
+	case reflect.Slice:
+		return hasSecret(t.Elem())
+	}
+	return false
+}
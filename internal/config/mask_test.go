@@ -0,0 +1,64 @@
+// Copyright 2026 The HuaTuo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"testing"
+)
+
+type maskTestConfig struct {
+	Address  string
+	Password string `secret:"true"`
+	Token    string `secret:"true"`
+	Backend  struct {
+		Username string
+		Password string            `secret:"true"`
+		Headers  map[string]string `secret:"true"`
+	}
+	Routes []struct {
+		Name   string
+		APIKey string `secret:"true"`
+	}
+	Tracers []string
+}
+
+func TestMask(t *testing.T) {
+	cfg := &maskTestConfig{Address: "http://127.0.0.1", Password: "secret"}
+	cfg.Backend.Username = "user"
+	cfg.Backend.Password = "secret"
+	cfg.Backend.Headers = map[string]string{"Authorization": "Bearer token"}
+	cfg.Routes = append(cfg.Routes, struct {
+		Name   string
+		APIKey string `secret:"true"`
+	}{Name: "oom", APIKey: "key"})
+	cfg.Tracers = []string{"oom"}
+
+	masked := Mask(cfg)
+	if masked.Address != cfg.Address || masked.Backend.Username != "user" || masked.Routes[0].Name != "oom" {
+		t.Errorf("Mask() changed the fields not secret: %+v", masked)
+	}
+	if masked.Password != MaskedValue || masked.Backend.Password != MaskedValue ||
+		masked.Backend.Headers["Authorization"] != MaskedValue || masked.Routes[0].APIKey != MaskedValue {
+		t.Errorf("Mask() left secrets: %+v", masked)
+	}
+	// an empty secret is not set.
+	if masked.Token != "" {
+		t.Errorf("Mask() Token = %q, want empty", masked.Token)
+	}
+
+	if cfg.Password != "secret" || cfg.Backend.Headers["Authorization"] != "Bearer token" || cfg.Routes[0].APIKey != "key" {
+		t.Errorf("Mask() changed the config: %+v", cfg)
+	}
+}
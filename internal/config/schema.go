@@ -0,0 +1,200 @@
+// Copyright 2026 The HuaTuo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// SchemaDraft is the JSON Schema dialect of Schema.
+const SchemaDraft = "https://json-schema.org/draft/2020-12/schema"
+
+// JSONSchema is the JSON Schema of a config value.
+type JSONSchema struct {
+	Schema               string                 `json:"$schema,omitempty"`
+	Title                string                 `json:"title,omitempty"`
+	Type                 string                 `json:"type,omitempty"`
+	Properties           map[string]*JSONSchema `json:"properties,omitempty"`
+	AdditionalProperties any                    `json:"additionalProperties,omitempty"`
+	Items                *JSONSchema            `json:"items,omitempty"`
+	Default              any                    `json:"default,omitempty"`
+	Enum                 []any                  `json:"enum,omitempty"`
+	Minimum              *float64               `json:"minimum,omitempty"`
+	Maximum              *float64               `json:"maximum,omitempty"`
+}
+
+// Schema returns the JSON Schema of the toml files decoded into v, a
+// struct or a pointer to one. The unknown keys are rejected as Load does
+// in strict mode.
+func Schema(v any, title string) (*JSONSchema, error) {
+	typ := reflect.TypeOf(v)
+	for typ != nil && typ.Kind() == reflect.Pointer {
+		typ = typ.Elem()
+	}
+	if typ == nil || typ.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("schema of %T: not a struct", v)
+	}
+
+	schema, err := typeSchema(typ, "")
+	if err != nil {
+		return nil, err
+	}
+	schema.Schema = SchemaDraft
+	schema.Title = title
+	return schema, nil
+}
+
+func typeSchema(typ reflect.Type, path string) (*JSONSchema, error) {
+	for typ.Kind() == reflect.Pointer {
+		typ = typ.Elem()
+	}
+
+	switch typ.Kind() {
+	case reflect.Struct:
+		schema := &JSONSchema{
+			Type:                 "object",
+			Properties:           map[string]*JSONSchema{},
+			AdditionalProperties: false,
+		}
+		for i := range typ.NumField() {
+			field := typ.Field(i)
+			name, ok := fieldKey(&field)
+			if !ok {
+				continue
+			}
+			fieldPath := joinPath(path, name)
+			fieldSchema, err := typeSchema(field.Type, fieldPath)
+			if err != nil {
+				return nil, err
+			}
+			if err := fieldTags(fieldSchema, &field, fieldPath); err != nil {
+				return nil, err
+			}
+			schema.Properties[name] = fieldSchema
+		}
+		return schema, nil
+	case reflect.Slice, reflect.Array:
+		items, err := typeSchema(typ.Elem(), path+"[]")
+		if err != nil {
+			return nil, err
+		}
+		return &JSONSchema{Type: "array", Items: items}, nil
+	case reflect.Map:
+		if typ.Key().Kind() != reflect.String {
+			return nil, fmt.Errorf("%s: map keys must be strings", path)
+		}
+		values, err := typeSchema(typ.Elem(), path+"{}")
+		if err != nil {
+			return nil, err
+		}
+		return &JSONSchema{Type: "object", AdditionalProperties: values}, nil
+	case reflect.String:
+		return &JSONSchema{Type: "string"}, nil
+	case reflect.Bool:
+		return &JSONSchema{Type: "boolean"}, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return &JSONSchema{Type: "integer"}, nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		zero := 0.0
+		return &JSONSchema{Type: "integer", Minimum: &zero}, nil
+	case reflect.Float32, reflect.Float64:
+		return &JSONSchema{Type: "number"}, nil
+	case reflect.Interface:
+		return &JSONSchema{}, nil
+	default:
+		return nil, fmt.Errorf("%s: unsupported type %s", path, typ)
+	}
+}
+
+// fieldTags adds the default and the bounds of a field to its schema,
+// those of a slice go to its items.
+func fieldTags(schema *JSONSchema, field *reflect.StructField, path string) error {
+	if value, ok := field.Tag.Lookup(tagDefault); ok {
+		def, err := defaultValue(field.Type, value)
+		if err != nil {
+			return fmt.Errorf("%s: default %q: %w", path, value, err)
+		}
+		schema.Default = def
+	}
+
+	target := schema
+	if schema.Items != nil {
+		target = schema.Items
+	}
+	bounds, err := parseBounds(field)
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	if bounds.min != nil {
+		target.Minimum = bounds.min
+	}
+	if bounds.max != nil {
+		target.Maximum = bounds.max
+	}
+	for _, value := range bounds.enum {
+		target.Enum = append(target.Enum, value)
+	}
+	return nil
+}
+
+// defaultValue converts a default tag to the JSON value of the field. The
+// toml decoder fails on the defaults of the other kinds, e.g. slices.
+func defaultValue(typ reflect.Type, value string) (any, error) {
+	switch typ.Kind() {
+	case reflect.String:
+		return value, nil
+	case reflect.Bool:
+		return strconv.ParseBool(value)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		// time.Duration defaults may carry a unit.
+		if n, err := strconv.ParseInt(value, 10, 64); err == nil {
+			return n, nil
+		}
+		return value, nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.ParseUint(value, 10, 64)
+	case reflect.Float32, reflect.Float64:
+		return strconv.ParseFloat(value, 64)
+	default:
+		return nil, fmt.Errorf("unsupported type %s", typ)
+	}
+}
+
+// fieldKey is the toml key of a field, false for the fields never
+// decoded.
+func fieldKey(field *reflect.StructField) (string, bool) {
+	if !field.IsExported() {
+		return "", false
+	}
+	name, _, _ := strings.Cut(field.Tag.Get(tagFieldName), ",")
+	name = strings.TrimSpace(name)
+	switch name {
+	case "-":
+		return "", false
+	case "":
+		return field.Name, true
+	}
+	return name, true
+}
+
+func joinPath(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
@@ -0,0 +1,129 @@
+// Copyright 2026 The HuaTuo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type boundedConfig struct {
+	Port        uint32 `default:"8080" min:"1" max:"65535"`
+	Compression string `enum:",gzip,zstd"`
+	Ratio       float64
+	Routes      []struct {
+		Backends []string `enum:"es,loki"`
+		Weight   int      `min:"0"`
+	} `toml:"Routes,omitempty"`
+	Modules  []string
+	Labels   map[string]string `toml:"Labels,omitempty"`
+	Internal string            `toml:"-"`
+}
+
+func TestSchema(t *testing.T) {
+	schema, err := Schema(&boundedConfig{}, "bounded")
+	if err != nil {
+		t.Fatalf("Schema() error = %v", err)
+	}
+	raw, err := json.Marshal(schema)
+	if err != nil {
+		t.Fatalf("marshal schema: %v", err)
+	}
+
+	var got map[string]any
+	if err := json.Unmarshal(raw, &got); err != nil {
+		t.Fatalf("unmarshal schema: %v", err)
+	}
+	if got["$schema"] != SchemaDraft || got["title"] != "bounded" || got["additionalProperties"] != false {
+		t.Errorf("schema header = %v", got)
+	}
+
+	props := got["properties"].(map[string]any)
+	if _, ok := props["Internal"]; ok {
+		t.Error("schema has the ignored field Internal")
+	}
+	want := map[string]any{
+		"type": "integer", "default": 8080.0, "minimum": 1.0, "maximum": 65535.0,
+	}
+	if !reflect.DeepEqual(props["Port"], want) {
+		t.Errorf("Port = %v, want %v", props["Port"], want)
+	}
+	want = map[string]any{"type": "string", "enum": []any{"", "gzip", "zstd"}}
+	if !reflect.DeepEqual(props["Compression"], want) {
+		t.Errorf("Compression = %v, want %v", props["Compression"], want)
+	}
+	want = map[string]any{"type": "array", "items": map[string]any{"type": "string"}}
+	if !reflect.DeepEqual(props["Modules"], want) {
+		t.Errorf("Modules = %v, want %v", props["Modules"], want)
+	}
+	want = map[string]any{"type": "object", "additionalProperties": map[string]any{"type": "string"}}
+	if !reflect.DeepEqual(props["Labels"], want) {
+		t.Errorf("Labels = %v, want %v", props["Labels"], want)
+	}
+
+	backends := props["Routes"].(map[string]any)["items"].(map[string]any)["properties"].(map[string]any)["Backends"]
+	want = map[string]any{"type": "array", "items": map[string]any{"type": "string", "enum": []any{"es", "loki"}}}
+	if !reflect.DeepEqual(backends, want) {
+		t.Errorf("Routes[].Backends = %v, want %v", backends, want)
+	}
+
+	if _, err := Schema(42, ""); err == nil {
+		t.Error("Schema(42) error = nil")
+	}
+	// the toml decoder fails on the slice defaults.
+	if _, err := Schema(&struct {
+		Modules []string `default:"[\"a\"]"`
+	}{}, ""); err == nil {
+		t.Error("Schema() of a slice default error = nil")
+	}
+}
+
+func TestLoadValidate(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	path := writeConfigFile(t, tmpDir, "valid.toml", `
+Compression = "zstd"
+
+[[Routes]]
+Backends = ["es"]
+`)
+	cfg := &boundedConfig{}
+	if err := Load(path, cfg); err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if cfg.Port != 8080 {
+		t.Errorf("Port = %d, want the default 8080", cfg.Port)
+	}
+
+	path = writeConfigFile(t, tmpDir, "invalid.toml", `
+Port = 70000
+Compression = "lz4"
+
+[[Routes]]
+Backends = ["es", "kafka"]
+Weight = -1
+`)
+	err := Load(path, &boundedConfig{})
+	if err == nil {
+		t.Fatal("Load() error = nil")
+	}
+	for _, field := range []string{"Port:", "Compression:", "Routes[0].Backends[1]:", "Routes[0].Weight:"} {
+		if !strings.Contains(err.Error(), field) {
+			t.Errorf("Load() error = %v, want a %s error", err, field)
+		}
+	}
+}
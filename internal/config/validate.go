@@ -0,0 +1,170 @@
+// Copyright 2026 The HuaTuo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// The struct tags of the config fields:
+//
+//	toml:"Name"       the key of the field, its Go name by default
+//	default:"10"      the value of the field missing from the file
+//	min:"1"           the lowest number allowed
+//	max:"65535"       the highest number allowed
+//	enum:",gzip,zstd" the strings allowed, comma separated
+//
+// The bounds of a slice apply to its elements. Load rejects the values out
+// of bounds and Schema exports them.
+const (
+	tagFieldName = "toml"
+	tagDefault   = "default"
+	tagMin       = "min"
+	tagMax       = "max"
+	tagEnum      = "enum"
+)
+
+type bounds struct {
+	min, max *float64
+	enum     []string
+}
+
+func parseBounds(field *reflect.StructField) (*bounds, error) {
+	b := &bounds{}
+	for _, tag := range []struct {
+		name  string
+		value **float64
+	}{
+		{name: tagMin, value: &b.min},
+		{name: tagMax, value: &b.max},
+	} {
+		value, ok := field.Tag.Lookup(tag.name)
+		if !ok {
+			continue
+		}
+		n, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s tag %q: %w", tag.name, value, err)
+		}
+		*tag.value = &n
+	}
+	if value, ok := field.Tag.Lookup(tagEnum); ok {
+		b.enum = strings.Split(value, ",")
+	}
+	return b, nil
+}
+
+// check returns why value, a number or a string, is out of bounds.
+func (b *bounds) check(value reflect.Value) error {
+	var n float64
+	switch value.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n = float64(value.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n = float64(value.Uint())
+	case reflect.Float32, reflect.Float64:
+		n = value.Float()
+	case reflect.String:
+		if b.enum != nil && !slices.Contains(b.enum, value.String()) {
+			return fmt.Errorf("%q is not one of %q", value.String(), b.enum)
+		}
+		return nil
+	default:
+		return nil
+	}
+
+	if b.min != nil && n < *b.min {
+		return fmt.Errorf("%v is lower than the minimum %v", value, *b.min)
+	}
+	if b.max != nil && n > *b.max {
+		return fmt.Errorf("%v is higher than the maximum %v", value, *b.max)
+	}
+	return nil
+}
+
+// Validate checks the fields of v, a struct or a pointer to one, against
+// their min, max and enum tags. The errors name the fields by their toml
+// path, e.g. Storage.Routing[1].Backends[0].
+func Validate(v any) error {
+	return validateValue(reflect.ValueOf(v), "")
+}
+
+func validateValue(value reflect.Value, path string) error {
+	for value.Kind() == reflect.Pointer || value.Kind() == reflect.Interface {
+		if value.IsNil() {
+			return nil
+		}
+		value = value.Elem()
+	}
+
+	switch value.Kind() {
+	case reflect.Struct:
+		var errs []error
+		typ := value.Type()
+		for i := range typ.NumField() {
+			field := typ.Field(i)
+			name, ok := fieldKey(&field)
+			if !ok {
+				continue
+			}
+			errs = append(errs, validateField(value.Field(i), &field, joinPath(path, name)))
+		}
+		return errors.Join(errs...)
+	case reflect.Slice, reflect.Array:
+		var errs []error
+		for i := range value.Len() {
+			errs = append(errs, validateValue(value.Index(i), fmt.Sprintf("%s[%d]", path, i)))
+		}
+		return errors.Join(errs...)
+	case reflect.Map:
+		var errs []error
+		iter := value.MapRange()
+		for iter.Next() {
+			errs = append(errs, validateValue(iter.Value(), fmt.Sprintf("%s.%v", path, iter.Key())))
+		}
+		return errors.Join(errs...)
+	}
+	return nil
+}
+
+func validateField(value reflect.Value, field *reflect.StructField, path string) error {
+	b, err := parseBounds(field)
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	if b.min == nil && b.max == nil && b.enum == nil {
+		return validateValue(value, path)
+	}
+
+	if value.Kind() != reflect.Slice && value.Kind() != reflect.Array {
+		if err := b.check(value); err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		return nil
+	}
+
+	var errs []error
+	for i := range value.Len() {
+		if err := b.check(value.Index(i)); err != nil {
+			errs = append(errs, fmt.Errorf("%s[%d]: %w", path, i, err))
+		}
+	}
+	return errors.Join(errs...)
+}
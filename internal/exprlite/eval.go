@@ -0,0 +1,575 @@
+// Copyright 2026 The HuaTuo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package exprlite
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+type node interface {
+	eval(vars map[string]any) (any, error)
+}
+
+type literalNode struct {
+	value any
+}
+
+func (n *literalNode) eval(map[string]any) (any, error) {
+	return n.value, nil
+}
+
+type identNode struct {
+	name string
+}
+
+func (n *identNode) eval(vars map[string]any) (any, error) {
+	v, ok := vars[n.name]
+	if !ok {
+		return nil, fmt.Errorf("no such attribute %q", n.name)
+	}
+	return normalize(v), nil
+}
+
+type listNode struct {
+	elems []node
+}
+
+func (n *listNode) eval(vars map[string]any) (any, error) {
+	list := make([]any, 0, len(n.elems))
+	for _, elem := range n.elems {
+		v, err := elem.eval(vars)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, v)
+	}
+	return list, nil
+}
+
+type selectNode struct {
+	operand node
+	field   string
+}
+
+func (n *selectNode) eval(vars map[string]any) (any, error) {
+	v, err := n.operand.eval(vars)
+	if err != nil {
+		return nil, err
+	}
+	m, ok := v.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("cannot select field %q from %s", n.field, typeName(v))
+	}
+	field, ok := m[n.field]
+	if !ok {
+		return nil, fmt.Errorf("no such key: %s", n.field)
+	}
+	return normalize(field), nil
+}
+
+type hasNode struct {
+	sel *selectNode
+}
+
+func (n *hasNode) eval(vars map[string]any) (any, error) {
+	v, err := n.sel.operand.eval(vars)
+	if err != nil {
+		return nil, err
+	}
+	m, ok := v.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("has() cannot test field %q of %s", n.sel.field, typeName(v))
+	}
+	_, ok = m[n.sel.field]
+	return ok, nil
+}
+
+type indexNode struct {
+	operand node
+	index   node
+}
+
+func (n *indexNode) eval(vars map[string]any) (any, error) {
+	v, err := n.operand.eval(vars)
+	if err != nil {
+		return nil, err
+	}
+	index, err := n.index.eval(vars)
+	if err != nil {
+		return nil, err
+	}
+
+	switch v := v.(type) {
+	case map[string]any:
+		key, ok := index.(string)
+		if !ok {
+			return nil, fmt.Errorf("map index must be a string, got %s", typeName(index))
+		}
+		field, ok := v[key]
+		if !ok {
+			return nil, fmt.Errorf("no such key: %s", key)
+		}
+		return normalize(field), nil
+	case []any:
+		i, ok := toInt(index)
+		if !ok {
+			return nil, fmt.Errorf("list index must be an int, got %s", typeName(index))
+		}
+		if i < 0 || i >= int64(len(v)) {
+			return nil, fmt.Errorf("index %d out of range [0, %d)", i, len(v))
+		}
+		return normalize(v[i]), nil
+	}
+	return nil, fmt.Errorf("cannot index %s", typeName(v))
+}
+
+type unaryNode struct {
+	op      string
+	operand node
+}
+
+func (n *unaryNode) eval(vars map[string]any) (any, error) {
+	v, err := n.operand.eval(vars)
+	if err != nil {
+		return nil, err
+	}
+
+	switch n.op {
+	case "!":
+		b, ok := v.(bool)
+		if !ok {
+			return nil, noOverload("!", v)
+		}
+		return !b, nil
+	default:
+		switch v := v.(type) {
+		case int64:
+			if v == math.MinInt64 {
+				return nil, errIntOverflow
+			}
+			return -v, nil
+		case float64:
+			return -v, nil
+		}
+		return nil, noOverload("-", v)
+	}
+}
+
+// logicalNode follows the CEL semantics for && and ||: an error on one side
+// is absorbed when the other side alone decides the result.
+type logicalNode struct {
+	and         bool
+	left, right node
+}
+
+func (n *logicalNode) eval(vars map[string]any) (any, error) {
+	decisive := !n.and
+
+	l, lerr := evalBool(n.left, vars)
+	if lerr == nil && l == decisive {
+		return decisive, nil
+	}
+	r, rerr := evalBool(n.right, vars)
+	if rerr == nil && r == decisive {
+		return decisive, nil
+	}
+	if lerr != nil {
+		return nil, lerr
+	}
+	if rerr != nil {
+		return nil, rerr
+	}
+	return !decisive, nil
+}
+
+func evalBool(n node, vars map[string]any) (bool, error) {
+	v, err := n.eval(vars)
+	if err != nil {
+		return false, err
+	}
+	b, ok := v.(bool)
+	if !ok {
+		return false, fmt.Errorf("expected bool, got %s", typeName(v))
+	}
+	return b, nil
+}
+
+type conditionalNode struct {
+	cond, then, els node
+}
+
+func (n *conditionalNode) eval(vars map[string]any) (any, error) {
+	cond, err := evalBool(n.cond, vars)
+	if err != nil {
+		return nil, err
+	}
+	if cond {
+		return n.then.eval(vars)
+	}
+	return n.els.eval(vars)
+}
+
+type binaryNode struct {
+	op          string
+	left, right node
+}
+
+func (n *binaryNode) eval(vars map[string]any) (any, error) {
+	l, err := n.left.eval(vars)
+	if err != nil {
+		return nil, err
+	}
+	r, err := n.right.eval(vars)
+	if err != nil {
+		return nil, err
+	}
+
+	switch n.op {
+	case "==":
+		return equal(l, r), nil
+	case "!=":
+		return !equal(l, r), nil
+	case "<", "<=", ">", ">=":
+		c, err := compare(n.op, l, r)
+		if err != nil {
+			return nil, err
+		}
+		switch n.op {
+		case "<":
+			return c < 0, nil
+		case "<=":
+			return c <= 0, nil
+		case ">":
+			return c > 0, nil
+		default:
+			return c >= 0, nil
+		}
+	case "in":
+		switch r := r.(type) {
+		case []any:
+			for _, elem := range r {
+				if equal(l, normalize(elem)) {
+					return true, nil
+				}
+			}
+			return false, nil
+		case map[string]any:
+			key, ok := l.(string)
+			if !ok {
+				return nil, noOverload("in", l, r)
+			}
+			_, ok = r[key]
+			return ok, nil
+		}
+		return nil, noOverload("in", l, r)
+	}
+
+	return arithmetic(n.op, l, r)
+}
+
+func arithmetic(op string, l, r any) (any, error) {
+	if op == "+" {
+		switch l := l.(type) {
+		case string:
+			if r, ok := r.(string); ok {
+				return l + r, nil
+			}
+		case []any:
+			if r, ok := r.([]any); ok {
+				return append(append(make([]any, 0, len(l)+len(r)), l...), r...), nil
+			}
+		}
+	}
+
+	li, lInt := l.(int64)
+	ri, rInt := r.(int64)
+	if lInt && rInt {
+		return intArithmetic(op, li, ri)
+	}
+
+	lf, lok := toFloat(l)
+	rf, rok := toFloat(r)
+	if !lok || !rok {
+		return nil, noOverload(op, l, r)
+	}
+	switch op {
+	case "+":
+		return lf + rf, nil
+	case "-":
+		return lf - rf, nil
+	case "*":
+		return lf * rf, nil
+	case "/":
+		return lf / rf, nil
+	}
+	return nil, noOverload(op, l, r)
+}
+
+var errIntOverflow = errors.New("int overflow")
+
+// intArithmetic returns an error instead of wrapping around on overflow.
+func intArithmetic(op string, l, r int64) (any, error) {
+	switch op {
+	case "+":
+		v := l + r
+		if (l > 0 && r > 0 && v < 0) || (l < 0 && r < 0 && v >= 0) {
+			return nil, errIntOverflow
+		}
+		return v, nil
+	case "-":
+		v := l - r
+		if (l >= 0 && r < 0 && v < 0) || (l < 0 && r > 0 && v >= 0) {
+			return nil, errIntOverflow
+		}
+		return v, nil
+	case "*":
+		v := l * r
+		if l != 0 && (v/l != r || (l == -1 && r == math.MinInt64) || (r == -1 && l == math.MinInt64)) {
+			return nil, errIntOverflow
+		}
+		return v, nil
+	case "/", "%":
+		if r == 0 {
+			return nil, fmt.Errorf("division by zero")
+		}
+		if op == "%" {
+			return l % r, nil
+		}
+		if l == math.MinInt64 && r == -1 {
+			return nil, errIntOverflow
+		}
+		return l / r, nil
+	}
+	return nil, noOverload(op, l, r)
+}
+
+func equal(l, r any) bool {
+	if lf, ok := toFloat(l); ok {
+		rf, ok := toFloat(r)
+		return ok && lf == rf
+	}
+	return reflect.DeepEqual(l, r)
+}
+
+func compare(op string, l, r any) (int, error) {
+	if lf, ok := toFloat(l); ok {
+		if rf, ok := toFloat(r); ok {
+			switch {
+			case lf < rf:
+				return -1, nil
+			case lf > rf:
+				return 1, nil
+			}
+			return 0, nil
+		}
+	}
+	if ls, ok := l.(string); ok {
+		if rs, ok := r.(string); ok {
+			return strings.Compare(ls, rs), nil
+		}
+	}
+	if lb, ok := l.(bool); ok {
+		if rb, ok := r.(bool); ok {
+			switch {
+			case lb == rb:
+				return 0, nil
+			case rb:
+				return -1, nil
+			}
+			return 1, nil
+		}
+	}
+	return 0, noOverload(op, l, r)
+}
+
+type callNode struct {
+	name string
+	args []node
+	// re is the pattern of matches() compiled ahead when it is a literal.
+	re *regexp.Regexp
+}
+
+func (n *callNode) eval(vars map[string]any) (any, error) {
+	args := make([]any, len(n.args))
+	for i, arg := range n.args {
+		v, err := arg.eval(vars)
+		if err != nil {
+			return nil, err
+		}
+		args[i] = v
+	}
+
+	switch n.name {
+	case "size":
+		switch v := args[0].(type) {
+		case string:
+			return int64(utf8.RuneCountInString(v)), nil
+		case []any:
+			return int64(len(v)), nil
+		case map[string]any:
+			return int64(len(v)), nil
+		}
+	case "int":
+		switch v := args[0].(type) {
+		case int64:
+			return v, nil
+		case float64:
+			if math.IsNaN(v) || v < math.MinInt64 || v >= math.MaxInt64 {
+				return nil, fmt.Errorf("int() range error: %v", v)
+			}
+			return int64(v), nil
+		case string:
+			i, err := strconv.ParseInt(v, 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("int(): %w", err)
+			}
+			return i, nil
+		}
+	case "double":
+		switch v := args[0].(type) {
+		case int64:
+			return float64(v), nil
+		case float64:
+			return v, nil
+		case string:
+			f, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				return nil, fmt.Errorf("double(): %w", err)
+			}
+			return f, nil
+		}
+	case "string":
+		switch v := args[0].(type) {
+		case string:
+			return v, nil
+		case int64:
+			return strconv.FormatInt(v, 10), nil
+		case float64:
+			return strconv.FormatFloat(v, 'g', -1, 64), nil
+		case bool:
+			return strconv.FormatBool(v), nil
+		}
+	default:
+		s, sok := args[0].(string)
+		arg, aok := args[1].(string)
+		if !sok || !aok {
+			break
+		}
+		switch n.name {
+		case "startsWith":
+			return strings.HasPrefix(s, arg), nil
+		case "endsWith":
+			return strings.HasSuffix(s, arg), nil
+		case "contains":
+			return strings.Contains(s, arg), nil
+		case "matches":
+			re := n.re
+			if re == nil {
+				var err error
+				if re, err = regexp.Compile(arg); err != nil {
+					return nil, fmt.Errorf("matches(): %w", err)
+				}
+			}
+			return re.MatchString(s), nil
+		}
+	}
+
+	return nil, noOverload(n.name+"()", args...)
+}
+
+// normalize maps the numbers of Go values, e.g. from encoding/json or
+// struct fields, to int64 and float64.
+func normalize(v any) any {
+	switch v := v.(type) {
+	case int:
+		return int64(v)
+	case int8:
+		return int64(v)
+	case int16:
+		return int64(v)
+	case int32:
+		return int64(v)
+	case uint8:
+		return int64(v)
+	case uint16:
+		return int64(v)
+	case uint32:
+		return int64(v)
+	case uint:
+		return float64(v)
+	case uint64:
+		return float64(v)
+	case float32:
+		return float64(v)
+	}
+	return v
+}
+
+func toInt(v any) (int64, bool) {
+	switch v := v.(type) {
+	case int64:
+		return v, true
+	case float64:
+		if v == math.Trunc(v) {
+			return int64(v), true
+		}
+	}
+	return 0, false
+}
+
+func toFloat(v any) (float64, bool) {
+	switch v := v.(type) {
+	case int64:
+		return float64(v), true
+	case float64:
+		return v, true
+	}
+	return 0, false
+}
+
+func typeName(v any) string {
+	switch v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "bool"
+	case int64:
+		return "int"
+	case float64:
+		return "double"
+	case string:
+		return "string"
+	case []any:
+		return "list"
+	case map[string]any:
+		return "map"
+	}
+	return fmt.Sprintf("%T", v)
+}
+
+func noOverload(op string, args ...any) error {
+	types := make([]string, len(args))
+	for i, arg := range args {
+		types[i] = typeName(arg)
+	}
+	return fmt.Errorf("no such overload: %s(%s)", op, strings.Join(types, ", "))
+}
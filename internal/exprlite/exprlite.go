@@ -0,0 +1,97 @@
+// Copyright 2026 The HuaTuo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package exprlite evaluates a small expression language over JSON-like
+// values. Its syntax borrows from the Common Expression Language (CEL), but
+// it is not CEL: it has no type checker, no macros and none of the CEL
+// library beyond the functions below.
+//
+// The grammar, from the lowest precedence:
+//
+//	expr           = or ["?" expr ":" expr]
+//	or             = and {"||" and}
+//	and            = relation {"&&" relation}
+//	relation       = additive {("==" | "!=" | "<" | "<=" | ">" | ">=" | "in") additive}
+//	additive       = multiplicative {("+" | "-") multiplicative}
+//	multiplicative = unary {("*" | "/" | "%") unary}
+//	unary          = ("!" | "-") unary | member
+//	member         = primary {"." ident ["(" [args] ")"] | "[" expr "]"}
+//	primary        = int | double | string | "true" | "false" | "null"
+//	               | ident ["(" [args] ")"] | "(" expr ")" | "[" [args] "]"
+//	args           = expr {"," expr}
+//
+// Ints are decimal or 0x hex int64, doubles need a digit after the dot or
+// an exponent, strings are single or double quoted with \n \t \r \\ and
+// quote escapes. The functions are has(), size(), int(), double(),
+// string(), startsWith(), endsWith(), contains() and matches(), all but
+// has() also callable as methods, e.g. s.startsWith("x").
+//
+// Values are int64, float64, string, bool, nil, []any and map[string]any.
+// Go ints up to 32 bits become int64, uint and uint64 become float64.
+// Numbers compare across int and double, && and || absorb an error of one
+// side when the other decides the result.
+//
+// Int arithmetic never wraps: +, -, *, / and unary - return an "int
+// overflow" error when the result does not fit in an int64, and int() of
+// a double out of the int64 range is an error. Double arithmetic follows
+// IEEE 754, overflowing to ±Inf.
+package exprlite
+
+import (
+	"fmt"
+)
+
+// Program is a compiled expression, safe for concurrent use.
+type Program struct {
+	expr string
+	root node
+}
+
+// Compile parses expr. Identifiers other than vars are rejected.
+func Compile(expr string, vars ...string) (*Program, error) {
+	tokens, err := lex(expr)
+	if err != nil {
+		return nil, fmt.Errorf("compile %q: %w", expr, err)
+	}
+
+	p := &parser{tokens: tokens, vars: make(map[string]bool, len(vars))}
+	for _, v := range vars {
+		p.vars[v] = true
+	}
+
+	root, err := p.expr()
+	if err != nil {
+		return nil, fmt.Errorf("compile %q: %w", expr, err)
+	}
+	if p.peek().kind != tokEOF {
+		return nil, fmt.Errorf("compile %q: %w", expr, p.errorf("unexpected token"))
+	}
+
+	return &Program{expr: expr, root: root}, nil
+}
+
+// Eval evaluates the program against vars.
+func (p *Program) Eval(vars map[string]any) (any, error) {
+	return p.root.eval(vars)
+}
+
+// EvalBool evaluates the program and requires a bool result.
+func (p *Program) EvalBool(vars map[string]any) (bool, error) {
+	return evalBool(p.root, vars)
+}
+
+// String returns the source expression.
+func (p *Program) String() string {
+	return p.expr
+}
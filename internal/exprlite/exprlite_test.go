@@ -0,0 +1,179 @@
+// Copyright 2026 The HuaTuo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package exprlite
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const testEvent = `{
+	"tracer_name": "oom",
+	"container_qos": "besteffort",
+	"tracer_data": {
+		"pid": 1234,
+		"comm": "java",
+		"usage": 2.5e9,
+		"tags": ["a", "b"],
+		"nested": {"level": 3}
+	}
+}`
+
+func testVars(t *testing.T) map[string]any {
+	t.Helper()
+
+	var event map[string]any
+	if err := json.Unmarshal([]byte(testEvent), &event); err != nil {
+		t.Fatal(err)
+	}
+	return map[string]any{"event": event}
+}
+
+func TestEval(t *testing.T) {
+	vars := testVars(t)
+
+	tests := []struct {
+		expr string
+		want any
+	}{
+		{`1 + 2 * 3`, int64(7)},
+		{`(1 + 2) * 3`, int64(9)},
+		{`7 / 2`, int64(3)},
+		{`7 % 4`, int64(3)},
+		{`7.0 / 2`, 3.5},
+		{`-event.tracer_data.pid`, -1234.0},
+		{`0x10`, int64(16)},
+		{`1e3`, 1000.0},
+		{`"a" + 'b'`, "ab"},
+		{`event.tracer_name == "oom"`, true},
+		{`event.tracer_data.pid == 1234`, true},
+		{`event.tracer_data.pid > 1000 && event.tracer_data.comm != "bash"`, true},
+		{`event.tracer_data["comm"]`, "java"},
+		{`event.tracer_data.tags[1]`, "b"},
+		{`event.tracer_data.nested.level >= 3`, true},
+		{`"a" in event.tracer_data.tags`, true},
+		{`"pid" in event.tracer_data`, true},
+		{`event.container_qos in ["guaranteed", "burstable"]`, false},
+		{`has(event.tracer_data.comm)`, true},
+		{`has(event.tracer_data.missing)`, false},
+		{`size(event.tracer_data.tags) == 2`, true},
+		{`event.tracer_data.comm.size()`, int64(4)},
+		{`event.tracer_data.comm.startsWith("ja")`, true},
+		{`event.tracer_data.comm.endsWith("va")`, true},
+		{`event.tracer_data.comm.contains("av")`, true},
+		{`event.tracer_data.comm.matches("^j.*a$")`, true},
+		{`matches(event.tracer_data.comm, "^b")`, false},
+		{`event.tracer_data.usage > 2e9 ? "critical" : "warning"`, "critical"},
+		{`int(event.tracer_data.usage / 1e9)`, int64(2)},
+		{`string(event.tracer_data.nested.level)`, "3"},
+		{`double("1.5") + 1`, 2.5},
+		{`!(1 < 2)`, false},
+		{`[1, 2] + [3]`, []any{int64(1), int64(2), int64(3)}},
+		{`null == null`, true},
+		// an error on one side is absorbed when the other side decides.
+		{`event.tracer_data.missing == 1 || true`, true},
+		{`false && event.tracer_data.missing == 1`, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			p, err := Compile(tt.expr, "event")
+			if err != nil {
+				t.Fatalf("Compile() error = %v", err)
+			}
+			got, err := p.Eval(vars)
+			if err != nil {
+				t.Fatalf("Eval() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Eval() = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEvalError(t *testing.T) {
+	vars := testVars(t)
+
+	tests := []struct {
+		expr    string
+		wantErr string
+	}{
+		{`event.tracer_data.missing`, "no such key"},
+		{`event.tracer_data.tags[5]`, "out of range"},
+		{`1 / 0`, "division by zero"},
+		{`"a" - 1`, "no such overload"},
+		{`event.tracer_data.missing == 1 && true`, "no such key"},
+		{`1 ? 2 : 3`, "expected bool"},
+		{`int("x")`, "int()"},
+		{`9223372036854775807 + 1`, "int overflow"},
+		{`-9223372036854775807 - 2`, "int overflow"},
+		{`4611686018427387904 * 2`, "int overflow"},
+		{`-(-9223372036854775807 - 1)`, "int overflow"},
+		{`(-9223372036854775807 - 1) / -1`, "int overflow"},
+		{`int(1e19)`, "range error"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			p, err := Compile(tt.expr, "event")
+			if err != nil {
+				t.Fatalf("Compile() error = %v", err)
+			}
+			if _, err := p.Eval(vars); err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Eval() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCompileError(t *testing.T) {
+	tests := []struct {
+		expr    string
+		wantErr string
+	}{
+		{`other.field`, "undeclared reference"},
+		{`event.`, "expected field name"},
+		{`(1 + 2`, `expected ")"`},
+		{`1 +`, "unexpected token"},
+		{`1 2`, "unexpected token"},
+		{`"abc`, "unterminated string"},
+		{`a = 1`, "unexpected character"},
+		{`nope(1)`, "unknown function"},
+		{`has(event)`, "field selection"},
+		{`event.name.matches("(")`, "matches()"},
+		{`size(1, 2)`, "takes 1 argument"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			if _, err := Compile(tt.expr, "event"); err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Compile() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestEvalBool(t *testing.T) {
+	p, err := Compile(`event.tracer_name`, "event")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := p.EvalBool(testVars(t)); err == nil {
+		t.Errorf("EvalBool() on a string must fail")
+	}
+}
@@ -0,0 +1,521 @@
+// Copyright 2026 The HuaTuo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package exprlite
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokIdent
+	tokInt
+	tokDouble
+	tokString
+	tokOp
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	pos  int
+}
+
+// twoCharOps must be matched before the single character ones.
+var twoCharOps = []string{"==", "!=", "<=", ">=", "&&", "||"}
+
+const singleCharOps = "()[].,?:!-+*/%<>"
+
+func lex(src string) ([]token, error) {
+	var tokens []token
+
+	for i := 0; i < len(src); {
+		c := src[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case isIdentStart(c):
+			start := i
+			for i < len(src) && isIdentPart(src[i]) {
+				i++
+			}
+			tokens = append(tokens, token{kind: tokIdent, text: src[start:i], pos: start})
+		case isDigit(c):
+			start := i
+			kind := tokInt
+			if strings.HasPrefix(src[i:], "0x") || strings.HasPrefix(src[i:], "0X") {
+				i += 2
+				for i < len(src) && isHex(src[i]) {
+					i++
+				}
+			} else {
+				for i < len(src) && isDigit(src[i]) {
+					i++
+				}
+				// 1.size() is not a double, a digit must follow the dot.
+				if i+1 < len(src) && src[i] == '.' && isDigit(src[i+1]) {
+					kind = tokDouble
+					for i++; i < len(src) && isDigit(src[i]); i++ {
+					}
+				}
+				if i < len(src) && (src[i] == 'e' || src[i] == 'E') {
+					j := i + 1
+					if j < len(src) && (src[j] == '+' || src[j] == '-') {
+						j++
+					}
+					if j < len(src) && isDigit(src[j]) {
+						kind = tokDouble
+						for i = j; i < len(src) && isDigit(src[i]); i++ {
+						}
+					}
+				}
+			}
+			tokens = append(tokens, token{kind: kind, text: src[start:i], pos: start})
+		case c == '"' || c == '\'':
+			s, n, err := lexString(src[i:])
+			if err != nil {
+				return nil, fmt.Errorf("position %d: %w", i, err)
+			}
+			tokens = append(tokens, token{kind: tokString, text: s, pos: i})
+			i += n
+		default:
+			op := ""
+			for _, two := range twoCharOps {
+				if strings.HasPrefix(src[i:], two) {
+					op = two
+					break
+				}
+			}
+			if op == "" && strings.IndexByte(singleCharOps, c) >= 0 {
+				op = string(c)
+			}
+			if op == "" {
+				return nil, fmt.Errorf("position %d: unexpected character %q", i, c)
+			}
+			tokens = append(tokens, token{kind: tokOp, text: op, pos: i})
+			i += len(op)
+		}
+	}
+
+	return append(tokens, token{kind: tokEOF, pos: len(src)}), nil
+}
+
+// lexString reads a quoted string and returns its value and the number of
+// bytes consumed.
+func lexString(src string) (string, int, error) {
+	quote := src[0]
+
+	var b strings.Builder
+	for i := 1; i < len(src); i++ {
+		c := src[i]
+		switch c {
+		case quote:
+			return b.String(), i + 1, nil
+		case '\\':
+			i++
+			if i >= len(src) {
+				return "", 0, fmt.Errorf("unterminated string")
+			}
+			switch src[i] {
+			case 'n':
+				b.WriteByte('\n')
+			case 't':
+				b.WriteByte('\t')
+			case 'r':
+				b.WriteByte('\r')
+			case '\\', '\'', '"':
+				b.WriteByte(src[i])
+			default:
+				return "", 0, fmt.Errorf("unsupported escape \\%c", src[i])
+			}
+		default:
+			b.WriteByte(c)
+		}
+	}
+
+	return "", 0, fmt.Errorf("unterminated string")
+}
+
+func isIdentStart(c byte) bool {
+	return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+func isIdentPart(c byte) bool {
+	return isIdentStart(c) || isDigit(c)
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isHex(c byte) bool {
+	return isDigit(c) || c >= 'a' && c <= 'f' || c >= 'A' && c <= 'F'
+}
+
+type parser struct {
+	tokens []token
+	pos    int
+	vars   map[string]bool
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.pos]
+	if t.kind != tokEOF {
+		p.pos++
+	}
+	return t
+}
+
+func (p *parser) isOp(op string) bool {
+	t := p.peek()
+	return t.kind == tokOp && t.text == op
+}
+
+func (p *parser) accept(op string) bool {
+	if p.isOp(op) {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *parser) expect(op string) error {
+	if !p.accept(op) {
+		return p.errorf("expected %q", op)
+	}
+	return nil
+}
+
+func (p *parser) errorf(format string, args ...any) error {
+	t := p.peek()
+	found := t.text
+	if t.kind == tokEOF {
+		found = "end of expression"
+	}
+	return fmt.Errorf("position %d near %q: %s", t.pos, found, fmt.Sprintf(format, args...))
+}
+
+// expr = or ["?" expr ":" expr]
+func (p *parser) expr() (node, error) {
+	cond, err := p.or()
+	if err != nil {
+		return nil, err
+	}
+	if !p.accept("?") {
+		return cond, nil
+	}
+
+	then, err := p.expr()
+	if err != nil {
+		return nil, err
+	}
+	if err := p.expect(":"); err != nil {
+		return nil, err
+	}
+	els, err := p.expr()
+	if err != nil {
+		return nil, err
+	}
+	return &conditionalNode{cond: cond, then: then, els: els}, nil
+}
+
+func (p *parser) or() (node, error) {
+	left, err := p.and()
+	if err != nil {
+		return nil, err
+	}
+	for p.accept("||") {
+		right, err := p.and()
+		if err != nil {
+			return nil, err
+		}
+		left = &logicalNode{and: false, left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) and() (node, error) {
+	left, err := p.relation()
+	if err != nil {
+		return nil, err
+	}
+	for p.accept("&&") {
+		right, err := p.relation()
+		if err != nil {
+			return nil, err
+		}
+		left = &logicalNode{and: true, left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) relation() (node, error) {
+	left, err := p.additive()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		t := p.peek()
+		op := ""
+		switch {
+		case t.kind == tokOp && (t.text == "==" || t.text == "!=" || t.text == "<" ||
+			t.text == "<=" || t.text == ">" || t.text == ">="):
+			op = t.text
+		case t.kind == tokIdent && t.text == "in":
+			op = "in"
+		default:
+			return left, nil
+		}
+		p.next()
+
+		right, err := p.additive()
+		if err != nil {
+			return nil, err
+		}
+		left = &binaryNode{op: op, left: left, right: right}
+	}
+}
+
+func (p *parser) additive() (node, error) {
+	left, err := p.multiplicative()
+	if err != nil {
+		return nil, err
+	}
+	for p.isOp("+") || p.isOp("-") {
+		op := p.next().text
+		right, err := p.multiplicative()
+		if err != nil {
+			return nil, err
+		}
+		left = &binaryNode{op: op, left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) multiplicative() (node, error) {
+	left, err := p.unary()
+	if err != nil {
+		return nil, err
+	}
+	for p.isOp("*") || p.isOp("/") || p.isOp("%") {
+		op := p.next().text
+		right, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		left = &binaryNode{op: op, left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) unary() (node, error) {
+	if p.isOp("!") || p.isOp("-") {
+		op := p.next().text
+		operand, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		return &unaryNode{op: op, operand: operand}, nil
+	}
+	return p.member()
+}
+
+func (p *parser) member() (node, error) {
+	n, err := p.primary()
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		switch {
+		case p.accept("."):
+			t := p.next()
+			if t.kind != tokIdent {
+				return nil, p.errorf("expected field name")
+			}
+			if p.accept("(") {
+				args, err := p.args()
+				if err != nil {
+					return nil, err
+				}
+				n, err = newCall(t.text, n, args)
+				if err != nil {
+					return nil, err
+				}
+				continue
+			}
+			n = &selectNode{operand: n, field: t.text}
+		case p.accept("["):
+			index, err := p.expr()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expect("]"); err != nil {
+				return nil, err
+			}
+			n = &indexNode{operand: n, index: index}
+		default:
+			return n, nil
+		}
+	}
+}
+
+// args parses call arguments after the opening parenthesis.
+func (p *parser) args() ([]node, error) {
+	var args []node
+	if p.accept(")") {
+		return args, nil
+	}
+	for {
+		arg, err := p.expr()
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, arg)
+		if p.accept(")") {
+			return args, nil
+		}
+		if err := p.expect(","); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (p *parser) primary() (node, error) {
+	t := p.next()
+	switch t.kind {
+	case tokInt:
+		v, err := strconv.ParseInt(t.text, 0, 64)
+		if err != nil {
+			return nil, fmt.Errorf("position %d: invalid int %q", t.pos, t.text)
+		}
+		return &literalNode{value: v}, nil
+	case tokDouble:
+		v, err := strconv.ParseFloat(t.text, 64)
+		if err != nil {
+			return nil, fmt.Errorf("position %d: invalid double %q", t.pos, t.text)
+		}
+		return &literalNode{value: v}, nil
+	case tokString:
+		return &literalNode{value: t.text}, nil
+	case tokIdent:
+		switch t.text {
+		case "true":
+			return &literalNode{value: true}, nil
+		case "false":
+			return &literalNode{value: false}, nil
+		case "null":
+			return &literalNode{value: nil}, nil
+		}
+		if p.accept("(") {
+			args, err := p.args()
+			if err != nil {
+				return nil, err
+			}
+			return newCall(t.text, nil, args)
+		}
+		if !p.vars[t.text] {
+			return nil, fmt.Errorf("position %d: undeclared reference to %q", t.pos, t.text)
+		}
+		return &identNode{name: t.text}, nil
+	case tokOp:
+		switch t.text {
+		case "(":
+			n, err := p.expr()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expect(")"); err != nil {
+				return nil, err
+			}
+			return n, nil
+		case "[":
+			var elems []node
+			if p.accept("]") {
+				return &listNode{}, nil
+			}
+			for {
+				elem, err := p.expr()
+				if err != nil {
+					return nil, err
+				}
+				elems = append(elems, elem)
+				if p.accept("]") {
+					return &listNode{elems: elems}, nil
+				}
+				if err := p.expect(","); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+
+	p.pos--
+	return nil, p.errorf("unexpected token")
+}
+
+// newCall builds a global (target == nil) or member function call.
+func newCall(name string, target node, args []node) (node, error) {
+	want := -1
+	switch name {
+	case "has":
+		if target != nil || len(args) != 1 {
+			return nil, fmt.Errorf("has() takes a single field selection")
+		}
+		sel, ok := args[0].(*selectNode)
+		if !ok {
+			return nil, fmt.Errorf("has() argument must be a field selection")
+		}
+		return &hasNode{sel: sel}, nil
+	case "size", "int", "double", "string":
+		want = 1
+	case "startsWith", "endsWith", "contains", "matches":
+		want = 2
+	default:
+		return nil, fmt.Errorf("unknown function %q", name)
+	}
+
+	if target != nil {
+		args = append([]node{target}, args...)
+	}
+	if len(args) != want {
+		return nil, fmt.Errorf("%s() takes %d argument(s), got %d", name, want, len(args))
+	}
+
+	call := &callNode{name: name, args: args}
+	if name == "matches" {
+		if lit, ok := args[1].(*literalNode); ok {
+			pattern, ok := lit.value.(string)
+			if !ok {
+				return nil, fmt.Errorf("matches() pattern must be a string")
+			}
+			re, err := regexp.Compile(pattern)
+			if err != nil {
+				return nil, fmt.Errorf("matches(): %w", err)
+			}
+			call.re = re
+		}
+	}
+	return call, nil
+}
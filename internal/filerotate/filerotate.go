@@ -15,11 +15,61 @@
 package filerotate
 
 import (
+	"fmt"
 	"io"
+	"os"
+	"sync"
+	"time"
 
 	"gopkg.in/natefinch/lumberjack.v2"
 )
 
+const (
+	// CompressionNone, CompressionGzip and CompressionZstd are the
+	// compressions of the rotated files.
+	CompressionNone = ""
+	CompressionGzip = "gzip"
+	CompressionZstd = "zstd"
+
+	// defaultRotationSize is the lumberjack default, in megabytes.
+	defaultRotationSize = 100
+	megabyte            = 1024 * 1024
+
+	// the age retention runs on rotations and at least this often.
+	millInterval = time.Hour
+)
+
+// Options are the rotation, retention and durability settings of a file.
+type Options struct {
+	// RotationSize is the size in megabytes the file is rotated at, 100 by
+	// default.
+	RotationSize int
+	// MaxRotation is the number of rotated files kept, 0 keeps them all.
+	MaxRotation int
+	// MaxAgeDays removes the files rotated more than MaxAgeDays ago, 0
+	// keeps them regardless of their age.
+	MaxAgeDays int
+	// Compression compresses the rotated files, gzip or zstd.
+	Compression string
+	// SyncInterval fsyncs the written data within SyncInterval, 0 leaves
+	// the writeback to the kernel.
+	SyncInterval time.Duration
+}
+
+// Validate checks the options.
+func (o *Options) Validate() error {
+	switch o.Compression {
+	case CompressionNone, CompressionGzip, CompressionZstd:
+	default:
+		return fmt.Errorf("invalid compression %q, want gzip or zstd", o.Compression)
+	}
+
+	if o.RotationSize < 0 || o.MaxRotation < 0 || o.MaxAgeDays < 0 || o.SyncInterval < 0 {
+		return fmt.Errorf("invalid negative rotation options %+v", *o)
+	}
+	return nil
+}
+
 type fileRotator struct {
 	// Filename is the file to write logs to.  Backup log files will be retained
 	// in the same directory.  It uses <processname>-lumberjack.log in
@@ -59,6 +109,19 @@ type fileRotator struct {
 	// using gzip. The default is not to perform compression.
 	// Compress bool `json:"compress" yaml:"compress"`
 	logger *lumberjack.Logger
+
+	// the rotation is driven here and the retention and compression of
+	// the rotated files done by mill, lumberjack only renames the files.
+	path string
+	opts Options
+
+	lock      sync.Mutex
+	size      int64 // -1 until the size of an existing file is known
+	milledAt  time.Time
+	milling   bool
+	millAgain bool
+	millWG    sync.WaitGroup
+	syncTimer *time.Timer
 }
 
 // Ensure fileRotator implements io.WriteCloser at compile time.
@@ -66,21 +129,156 @@ var _ io.WriteCloser = (*fileRotator)(nil)
 
 // NewFileRotator create a rotatable logger
 func NewFileRotator(path string, maxRotation, rotationSize int) io.WriteCloser {
+	return newFileRotator(path, Options{
+		RotationSize: rotationSize,
+		MaxRotation:  maxRotation,
+	})
+}
+
+// NewFileRotatorWithOptions creates a rotatable file which also compresses,
+// expires and fsyncs its rotated files as the options say.
+func NewFileRotatorWithOptions(path string, opts Options) (io.WriteCloser, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+	return newFileRotator(path, opts), nil
+}
+
+func newFileRotator(path string, opts Options) *fileRotator {
+	if opts.RotationSize == 0 {
+		opts.RotationSize = defaultRotationSize
+	}
+
 	return &fileRotator{
-		&lumberjack.Logger{
-			Filename:   path,
-			MaxSize:    rotationSize,
-			MaxBackups: maxRotation,
-			LocalTime:  true,
-			Compress:   false,
+		logger: &lumberjack.Logger{
+			Filename:  path,
+			MaxSize:   opts.RotationSize,
+			LocalTime: true,
+			Compress:  false,
 		},
+		path: path,
+		opts: opts,
+		size: -1,
 	}
 }
 
 func (r *fileRotator) Write(data []byte) (n int, err error) {
-	return r.logger.Write(data)
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	// lumberjack opens the file on the first write, rotating it when it is
+	// full already.
+	opening := r.size < 0
+	if opening {
+		r.size = 0
+		if info, err := os.Stat(r.path); err == nil {
+			r.size = info.Size()
+		}
+		r.millLocked()
+	}
+
+	// the conditions of lumberjack, so it never rotates by itself.
+	writeLen := int64(len(data))
+	full := r.size+writeLen > r.max()
+	if opening {
+		full = r.size+writeLen >= r.max()
+	}
+	if r.size > 0 && writeLen <= r.max() && full {
+		if r.opts.SyncInterval > 0 {
+			_ = syncFile(r.path)
+		}
+		if err := r.logger.Rotate(); err != nil {
+			return 0, err
+		}
+		r.size = 0
+		r.millLocked()
+	} else if time.Since(r.milledAt) > millInterval {
+		r.millLocked()
+	}
+
+	n, err = r.logger.Write(data)
+	r.size += int64(n)
+
+	if n > 0 && r.opts.SyncInterval > 0 && r.syncTimer == nil {
+		r.syncTimer = time.AfterFunc(r.opts.SyncInterval, r.syncNow)
+	}
+	return n, err
 }
 
+// Close syncs and closes the file, after the running retention. The file
+// can still be written, it is reopened.
 func (r *fileRotator) Close() error {
+	r.lock.Lock()
+	synced := r.syncTimer != nil
+	if synced {
+		r.syncTimer.Stop()
+		r.syncTimer = nil
+	}
+	r.lock.Unlock()
+
+	r.millWG.Wait()
+
+	if synced {
+		_ = syncFile(r.path)
+	}
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	r.size = -1
 	return r.logger.Close()
 }
+
+func (r *fileRotator) max() int64 {
+	return int64(r.opts.RotationSize) * megabyte
+}
+
+func (r *fileRotator) syncNow() {
+	r.lock.Lock()
+	r.syncTimer = nil
+	r.lock.Unlock()
+
+	_ = syncFile(r.path)
+}
+
+// millLocked runs the retention and compression of the rotated files in the
+// background, once more if it is already running.
+func (r *fileRotator) millLocked() {
+	r.milledAt = time.Now()
+	if r.milling {
+		r.millAgain = true
+		return
+	}
+
+	r.milling = true
+	r.millWG.Add(1)
+	go func() {
+		defer r.millWG.Done()
+
+		for {
+			// best effort, as the removal of the old backups of lumberjack.
+			_ = millRunOnce(r.path, &r.opts, time.Now())
+
+			r.lock.Lock()
+			if !r.millAgain {
+				r.milling = false
+				r.lock.Unlock()
+				return
+			}
+			r.millAgain = false
+			r.lock.Unlock()
+		}
+	}()
+}
+
+// syncFile flushes the written data of the file to the disk, any file
+// descriptor of it does.
+func syncFile(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	return f.Sync()
+}
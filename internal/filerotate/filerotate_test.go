@@ -245,3 +245,114 @@ func TestFileRotator_FailsOnInvalidPath(t *testing.T) {
 		t.Error("expected error with invalid path, but got none")
 	}
 }
+
+func TestFileRotator_Compression(t *testing.T) {
+	for _, compression := range []string{CompressionGzip, CompressionZstd} {
+		t.Run(compression, func(t *testing.T) {
+			dir := t.TempDir()
+			path := filepath.Join(dir, "oom")
+
+			r, err := NewFileRotatorWithOptions(path, Options{RotationSize: 1, MaxRotation: 2, Compression: compression})
+			if err != nil {
+				t.Fatalf("NewFileRotatorWithOptions() error = %v", err)
+			}
+
+			data := bytes.Repeat([]byte("a"), 1024*1024/4)
+			for i := range 16 {
+				if _, err := r.Write(data); err != nil {
+					t.Fatalf("write %d failed: %v", i, err)
+				}
+				time.Sleep(time.Millisecond)
+			}
+			if err := r.Close(); err != nil {
+				t.Fatalf("Close() error = %v", err)
+			}
+
+			files, err := backups(path)
+			if err != nil {
+				t.Fatalf("backups() error = %v", err)
+			}
+			if len(files) != 2 {
+				t.Fatalf("backups() = %+v, want 2", files)
+			}
+			suffix := map[string]string{CompressionGzip: ".gz", CompressionZstd: ".zst"}[compression]
+			for _, file := range files {
+				info, err := os.Stat(file.path)
+				if err != nil || !file.compressed || !strings.HasSuffix(file.path, suffix) || info.Size() >= int64(len(data)) {
+					t.Errorf("backup %s = %+v, %v, want compressed with %s", file.path, info, err, compression)
+				}
+			}
+		})
+	}
+
+	if _, err := NewFileRotatorWithOptions("oom", Options{Compression: "lz4"}); err == nil {
+		t.Error("NewFileRotatorWithOptions() with lz4 succeeded")
+	}
+}
+
+func TestFileRotator_MaxAge(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "oom")
+
+	now := time.Now()
+	names := []string{
+		"oom-" + now.Add(-time.Hour).Format(backupTimeFormat),
+		"oom-" + now.Add(-49*time.Hour).Format(backupTimeFormat) + ".gz",
+		"oom-" + now.Add(-72*time.Hour).Format(backupTimeFormat),
+		// not a backup of oom.
+		"oom-killer",
+	}
+	for _, name := range names {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("{}\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := millRunOnce(path, &Options{MaxAgeDays: 2}, now); err != nil {
+		t.Fatalf("millRunOnce() error = %v", err)
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, entry := range entries {
+		got = append(got, entry.Name())
+	}
+	want := []string{names[0], "oom-killer"}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("files = %v, want %v", got, want)
+	}
+}
+
+func TestFileRotator_SyncInterval(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "oom")
+	w, err := NewFileRotatorWithOptions(path, Options{SyncInterval: 10 * time.Millisecond})
+	if err != nil {
+		t.Fatalf("NewFileRotatorWithOptions() error = %v", err)
+	}
+	r := w.(*fileRotator)
+
+	if _, err := r.Write([]byte("{}\n")); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	r.lock.Lock()
+	scheduled := r.syncTimer != nil
+	r.lock.Unlock()
+	if !scheduled {
+		t.Error("Write() scheduled no sync")
+	}
+
+	time.Sleep(50 * time.Millisecond)
+	r.lock.Lock()
+	pending := r.syncTimer != nil
+	r.lock.Unlock()
+	if pending {
+		t.Error("sync still pending after the interval")
+	}
+
+	if err := r.Close(); err != nil {
+		t.Errorf("Close() error = %v", err)
+	}
+}
@@ -0,0 +1,178 @@
+// Copyright 2026 The HuaTuo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filerotate
+
+import (
+	"compress/gzip"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+const (
+	// backupTimeFormat is the timestamp lumberjack names the rotated files
+	// with, the time of the rotation.
+	backupTimeFormat = "2006-01-02T15-04-05.000"
+
+	gzipSuffix = ".gz"
+	zstdSuffix = ".zst"
+)
+
+// backup is a rotated file, compressed or not.
+type backup struct {
+	path       string
+	rotatedAt  time.Time
+	compressed bool
+}
+
+// backups returns the rotated files of path, the newest first.
+func backups(path string) ([]backup, error) {
+	dir := filepath.Dir(path)
+	filename := filepath.Base(path)
+	ext := filepath.Ext(filename)
+	prefix := filename[:len(filename)-len(ext)] + "-"
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var files []backup
+	for _, entry := range entries {
+		if !entry.Type().IsRegular() {
+			continue
+		}
+
+		name := entry.Name()
+		compressed := false
+		for _, suffix := range []string{gzipSuffix, zstdSuffix} {
+			if trimmed, ok := strings.CutSuffix(name, suffix); ok {
+				name, compressed = trimmed, true
+				break
+			}
+		}
+
+		if !strings.HasPrefix(name, prefix) || !strings.HasSuffix(name, ext) {
+			continue
+		}
+		// the files of other tracers sharing the prefix are not backups.
+		rotatedAt, err := time.ParseInLocation(backupTimeFormat, name[len(prefix):len(name)-len(ext)], time.Local)
+		if err != nil {
+			continue
+		}
+
+		files = append(files, backup{
+			path:       filepath.Join(dir, entry.Name()),
+			rotatedAt:  rotatedAt,
+			compressed: compressed,
+		})
+	}
+
+	sort.Slice(files, func(i, j int) bool { return files[i].rotatedAt.After(files[j].rotatedAt) })
+	return files, nil
+}
+
+// millRunOnce removes the rotated files of path beyond MaxRotation or older
+// than MaxAgeDays and compresses the remaining ones.
+func millRunOnce(path string, opts *Options, now time.Time) error {
+	files, err := backups(path)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	kept := 0
+	for _, file := range files {
+		expired := opts.MaxAgeDays > 0 && now.Sub(file.rotatedAt) > time.Duration(opts.MaxAgeDays)*24*time.Hour
+		if expired || (opts.MaxRotation > 0 && kept >= opts.MaxRotation) {
+			if err := os.Remove(file.path); err != nil && !os.IsNotExist(err) {
+				errs = append(errs, err)
+			}
+			continue
+		}
+		kept++
+
+		if opts.Compression != CompressionNone && !file.compressed {
+			if err := compressFile(file.path, opts.Compression); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// compressFile compresses src next to it and removes it, the compressed
+// file is renamed in place once complete.
+func compressFile(src, compression string) (err error) {
+	dst := src + gzipSuffix
+	if compression == CompressionZstd {
+		dst = src + zstdSuffix
+	}
+
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	info, err := in.Stat()
+	if err != nil {
+		return err
+	}
+
+	tmp := dst + ".tmp"
+	out, err := os.OpenFile(tmp, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, info.Mode())
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			_ = out.Close()
+			_ = os.Remove(tmp)
+		}
+	}()
+
+	var w io.WriteCloser
+	if compression == CompressionZstd {
+		if w, err = zstd.NewWriter(out); err != nil {
+			return err
+		}
+	} else {
+		w = gzip.NewWriter(out)
+	}
+
+	if _, err = io.Copy(w, in); err != nil {
+		return err
+	}
+	if err = w.Close(); err != nil {
+		return err
+	}
+	if err = out.Sync(); err != nil {
+		return err
+	}
+	if err = out.Close(); err != nil {
+		return err
+	}
+	if err = os.Rename(tmp, dst); err != nil {
+		return err
+	}
+	return os.Remove(src)
+}
@@ -0,0 +1,275 @@
+// Copyright 2026 The HuaTuo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package hostinfo resolves the identity of the host, the hostname, region
+// and kubernetes node name which label the metrics and events.
+//
+// Each of hostname and region is resolved from an ordered list of sources,
+// the first one that yields a value wins. The identity is re-evaluated
+// periodically, so a renamed host or a migrated instance is picked up
+// without a restart.
+package hostinfo
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"huatuo-bamai/internal/log"
+)
+
+// Sources of the hostname and region.
+const (
+	// SourceOS is the kernel hostname, for hostname only.
+	SourceOS = "os"
+	// SourceDNS is the fully qualified name of the kernel hostname, for
+	// hostname only.
+	SourceDNS = "dns"
+	// SourceNode is the kubernetes node name, for hostname only.
+	SourceNode = "node"
+	// SourceCloud is the instance metadata service of the cloud.
+	SourceCloud = "cloud"
+	// SourceStatic is the region given by the command line, for region
+	// only.
+	SourceStatic = "static"
+)
+
+// Options configures the resolution.
+type Options struct {
+	// HostnameSources default to os.
+	HostnameSources []string
+	// RegionSources default to static.
+	RegionSources []string
+	// Region is the static region.
+	Region string
+	// NodeNameEnv is the environment variable holding the node name,
+	// usually populated by the downward API from spec.nodeName.
+	NodeNameEnv string
+	// Cloud is the provider of the instance metadata service.
+	Cloud string
+	// RefreshInterval is how often the identity is re-evaluated, zero
+	// disables it.
+	RefreshInterval time.Duration
+}
+
+type identity struct {
+	hostname string
+	region   string
+	nodeName string
+}
+
+var (
+	current  atomic.Pointer[identity]
+	resolver *hostResolver
+	stop     context.CancelFunc
+)
+
+type hostResolver struct {
+	opts  Options
+	cloud *cloudProvider
+}
+
+func newResolver(opts Options) (*hostResolver, error) {
+	if len(opts.HostnameSources) == 0 {
+		opts.HostnameSources = []string{SourceOS}
+	}
+	if len(opts.RegionSources) == 0 {
+		opts.RegionSources = []string{SourceStatic}
+	}
+
+	r := &hostResolver{opts: opts}
+
+	for _, source := range opts.HostnameSources {
+		switch source {
+		case SourceOS, SourceDNS, SourceNode, SourceCloud:
+		default:
+			return nil, fmt.Errorf("unknown hostname source %q", source)
+		}
+	}
+	for _, source := range opts.RegionSources {
+		switch source {
+		case SourceStatic, SourceCloud:
+		default:
+			return nil, fmt.Errorf("unknown region source %q", source)
+		}
+	}
+
+	if opts.Cloud != "" {
+		provider, ok := cloudProviders[opts.Cloud]
+		if !ok {
+			return nil, fmt.Errorf("unknown cloud provider %q", opts.Cloud)
+		}
+		r.cloud = provider
+	}
+
+	return r, nil
+}
+
+func (r *hostResolver) nodeName() string {
+	if r.opts.NodeNameEnv == "" {
+		return ""
+	}
+	return strings.TrimSpace(os.Getenv(r.opts.NodeNameEnv))
+}
+
+func (r *hostResolver) hostnameFrom(ctx context.Context, source string) (string, error) {
+	switch source {
+	case SourceOS:
+		return os.Hostname()
+	case SourceDNS:
+		return fqdn(ctx)
+	case SourceNode:
+		return r.nodeName(), nil
+	default:
+		return r.fromCloud(ctx, func(p *cloudProvider) string { return p.hostnamePath })
+	}
+}
+
+func (r *hostResolver) regionFrom(ctx context.Context, source string) (string, error) {
+	if source == SourceStatic {
+		return r.opts.Region, nil
+	}
+	return r.fromCloud(ctx, func(p *cloudProvider) string { return p.regionPath })
+}
+
+func (r *hostResolver) fromCloud(ctx context.Context, path func(*cloudProvider) string) (string, error) {
+	if r.cloud == nil {
+		return "", fmt.Errorf("no cloud provider configured")
+	}
+	return r.cloud.get(ctx, path(r.cloud))
+}
+
+func first(ctx context.Context, kind string, sources []string, resolve func(context.Context, string) (string, error)) string {
+	for _, source := range sources {
+		value, err := resolve(ctx, source)
+		if err != nil {
+			log.Debugf("resolve %s from %s: %v", kind, source, err)
+			continue
+		}
+		if value = strings.TrimSpace(value); value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+func (r *hostResolver) resolve(ctx context.Context) *identity {
+	return &identity{
+		hostname: first(ctx, "hostname", r.opts.HostnameSources, r.hostnameFrom),
+		region:   first(ctx, "region", r.opts.RegionSources, r.regionFrom),
+		nodeName: r.nodeName(),
+	}
+}
+
+// refresh re-evaluates the identity, a source failing at the moment does
+// not wipe the value resolved before.
+func (r *hostResolver) refresh(ctx context.Context) {
+	id := r.resolve(ctx)
+
+	old := current.Load()
+	if old != nil {
+		if id.hostname == "" {
+			id.hostname = old.hostname
+		}
+		if id.region == "" {
+			id.region = old.region
+		}
+		if *id == *old {
+			return
+		}
+		log.Infof("host identity changed from %+v to %+v", *old, *id)
+	}
+
+	current.Store(id)
+}
+
+// Init resolves the identity, it fails when no region is resolved.
+func Init(opts Options) error {
+	r, err := newResolver(opts)
+	if err != nil {
+		return err
+	}
+
+	id := r.resolve(context.Background())
+	if id.region == "" {
+		return fmt.Errorf("region is unresolved from sources %v", r.opts.RegionSources)
+	}
+	current.Store(id)
+
+	log.Infof("host identity: hostname %q, region %q, node %q", id.hostname, id.region, id.nodeName)
+	resolver = r
+	return nil
+}
+
+// Start re-evaluates the identity every RefreshInterval until Stop.
+func Start() {
+	if resolver == nil || resolver.opts.RefreshInterval <= 0 {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stop = cancel
+
+	go func(r *hostResolver) {
+		ticker := time.NewTicker(r.opts.RefreshInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				r.refresh(ctx)
+			}
+		}
+	}(resolver)
+}
+
+// Stop stops the re-evaluation.
+func Stop() {
+	if stop != nil {
+		stop()
+		stop = nil
+	}
+}
+
+// Hostname returns the resolved hostname, or the kernel hostname before
+// Init.
+func Hostname() string {
+	if id := current.Load(); id != nil && id.hostname != "" {
+		return id.hostname
+	}
+
+	hostname, _ := os.Hostname()
+	return hostname
+}
+
+// Region returns the resolved region, empty before Init.
+func Region() string {
+	if id := current.Load(); id != nil {
+		return id.region
+	}
+	return ""
+}
+
+// NodeName returns the kubernetes node name, empty if unknown.
+func NodeName() string {
+	if id := current.Load(); id != nil {
+		return id.nodeName
+	}
+	return ""
+}
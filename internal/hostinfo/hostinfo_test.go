@@ -0,0 +1,177 @@
+// Copyright 2026 The HuaTuo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hostinfo
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+)
+
+func resetIdentity(t *testing.T) {
+	t.Helper()
+
+	current.Store(nil)
+	t.Cleanup(func() {
+		Stop()
+		current.Store(nil)
+		resolver = nil
+	})
+}
+
+// fakeCloud serves the AWS style metadata, the token is mandatory.
+func fakeCloud(t *testing.T, region *string) {
+	t.Helper()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/latest/api/token", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPut || r.Header.Get("X-aws-ec2-metadata-token-ttl-seconds") == "" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		_, _ = w.Write([]byte("token"))
+	})
+	mux.HandleFunc("/latest/meta-data/", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-aws-ec2-metadata-token") != "token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch r.URL.Path {
+		case "/latest/meta-data/local-hostname":
+			_, _ = w.Write([]byte("ip-10-0-0-1.ec2.internal\n"))
+		case "/latest/meta-data/placement/region":
+			if *region == "" {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			_, _ = w.Write([]byte(*region))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+
+	orig := cloudProviders["aws"]
+	provider := *orig
+	provider.endpoint = srv.URL
+	cloudProviders["aws"] = &provider
+	t.Cleanup(func() { cloudProviders["aws"] = orig })
+}
+
+func TestInitSources(t *testing.T) {
+	resetIdentity(t)
+	region := "us-east-1"
+	fakeCloud(t, &region)
+	t.Setenv("HUATUO_TEST_NODE", "node-1")
+
+	if err := Init(Options{
+		HostnameSources: []string{SourceCloud, SourceOS},
+		RegionSources:   []string{SourceCloud, SourceStatic},
+		Region:          "static-region",
+		NodeNameEnv:     "HUATUO_TEST_NODE",
+		Cloud:           "aws",
+	}); err != nil {
+		t.Fatalf("Init() error = %v", err)
+	}
+
+	if got := Hostname(); got != "ip-10-0-0-1.ec2.internal" {
+		t.Errorf("Hostname() = %q, want the cloud hostname", got)
+	}
+	if got := Region(); got != "us-east-1" {
+		t.Errorf("Region() = %q, want us-east-1", got)
+	}
+	if got := NodeName(); got != "node-1" {
+		t.Errorf("NodeName() = %q, want node-1", got)
+	}
+
+	// the instance migrated to another region.
+	region = "us-west-2"
+	resolver.refresh(context.Background())
+	if got := Region(); got != "us-west-2" {
+		t.Errorf("Region() after refresh = %q, want us-west-2", got)
+	}
+
+	// a failing source falls back to the next one.
+	region = ""
+	resolver.refresh(context.Background())
+	if got := Region(); got != "static-region" {
+		t.Errorf("Region() after cloud failure = %q, want static-region", got)
+	}
+}
+
+func TestRefreshKeepsResolved(t *testing.T) {
+	resetIdentity(t)
+	region := "cn-beijing"
+	fakeCloud(t, &region)
+
+	if err := Init(Options{RegionSources: []string{SourceCloud}, Cloud: "aws"}); err != nil {
+		t.Fatalf("Init() error = %v", err)
+	}
+
+	region = ""
+	resolver.refresh(context.Background())
+	if got := Region(); got != "cn-beijing" {
+		t.Errorf("Region() = %q, want the last resolved cn-beijing", got)
+	}
+}
+
+func TestInitErrors(t *testing.T) {
+	resetIdentity(t)
+
+	tests := []struct {
+		name string
+		opts Options
+	}{
+		{"unknown hostname source", Options{HostnameSources: []string{"nope"}, Region: "r"}},
+		{"region source for hostname", Options{HostnameSources: []string{SourceStatic}, Region: "r"}},
+		{"unknown region source", Options{RegionSources: []string{SourceOS}, Region: "r"}},
+		{"unknown cloud", Options{Cloud: "nope", Region: "r"}},
+		{"no region", Options{}},
+		{"cloud without provider", Options{RegionSources: []string{SourceCloud}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := Init(tt.opts); err == nil {
+				t.Errorf("Init(%+v) error = nil, want error", tt.opts)
+			}
+		})
+	}
+}
+
+func TestDefaults(t *testing.T) {
+	resetIdentity(t)
+
+	hostname, _ := os.Hostname()
+	if got := Hostname(); got != hostname {
+		t.Errorf("Hostname() before Init = %q, want %q", got, hostname)
+	}
+	if Region() != "" || NodeName() != "" {
+		t.Errorf("Region() = %q, NodeName() = %q before Init, want empty", Region(), NodeName())
+	}
+
+	if err := Init(Options{Region: "r"}); err != nil {
+		t.Fatalf("Init() error = %v", err)
+	}
+	if got := Hostname(); got != hostname {
+		t.Errorf("Hostname() = %q, want %q", got, hostname)
+	}
+	if NodeName() != "" {
+		t.Errorf("NodeName() = %q, want empty without NodeNameEnv", NodeName())
+	}
+}
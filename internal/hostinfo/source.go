@@ -0,0 +1,131 @@
+// Copyright 2026 The HuaTuo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hostinfo
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+const (
+	metadataTimeout  = 2 * time.Second
+	metadataMaxBytes = 4096
+)
+
+// cloudProvider describes the instance metadata service of a cloud.
+type cloudProvider struct {
+	endpoint     string
+	hostnamePath string
+	regionPath   string
+	// tokenPath, when set, issues the session token required by the
+	// service, e.g. AWS IMDSv2.
+	tokenPath   string
+	tokenHeader string
+	tokenTTL    [2]string
+}
+
+var cloudProviders = map[string]*cloudProvider{
+	"aliyun": {
+		endpoint:     "http://100.100.100.200",
+		hostnamePath: "/latest/meta-data/hostname",
+		regionPath:   "/latest/meta-data/region-id",
+	},
+	"aws": {
+		endpoint:     "http://169.254.169.254",
+		hostnamePath: "/latest/meta-data/local-hostname",
+		regionPath:   "/latest/meta-data/placement/region",
+		tokenPath:    "/latest/api/token",
+		tokenHeader:  "X-aws-ec2-metadata-token",
+		tokenTTL:     [2]string{"X-aws-ec2-metadata-token-ttl-seconds", "60"},
+	},
+}
+
+var metadataClient = &http.Client{Timeout: metadataTimeout}
+
+func metadataRequest(ctx context.Context, method, url string, header map[string]string) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, method, url, http.NoBody)
+	if err != nil {
+		return "", err
+	}
+	for k, v := range header {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := metadataClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("%s %s: %s", method, url, resp.Status)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, metadataMaxBytes))
+	if err != nil {
+		return "", fmt.Errorf("%s %s: %w", method, url, err)
+	}
+	return strings.TrimSpace(string(body)), nil
+}
+
+func (p *cloudProvider) get(ctx context.Context, path string) (string, error) {
+	header := map[string]string{}
+	if p.tokenPath != "" {
+		token, err := metadataRequest(ctx, http.MethodPut, p.endpoint+p.tokenPath,
+			map[string]string{p.tokenTTL[0]: p.tokenTTL[1]})
+		if err != nil {
+			return "", err
+		}
+		header[p.tokenHeader] = token
+	}
+
+	return metadataRequest(ctx, http.MethodGet, p.endpoint+path, header)
+}
+
+// fqdn resolves the fully qualified name of the kernel hostname, by the
+// reverse lookup of its addresses.
+func fqdn(ctx context.Context) (string, error) {
+	hostname, err := os.Hostname()
+	if err != nil {
+		return "", err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, metadataTimeout)
+	defer cancel()
+
+	addrs, err := net.DefaultResolver.LookupHost(ctx, hostname)
+	if err != nil {
+		return "", err
+	}
+
+	for _, addr := range addrs {
+		names, err := net.DefaultResolver.LookupAddr(ctx, addr)
+		if err != nil {
+			continue
+		}
+		for _, name := range names {
+			if name = strings.TrimSuffix(name, "."); strings.Contains(name, ".") {
+				return name, nil
+			}
+		}
+	}
+	return "", fmt.Errorf("no fully qualified name of %s", hostname)
+}
@@ -0,0 +1,291 @@
+// Copyright 2026 The HuaTuo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package instance keeps a single agent per node: the instance holds a lock
+// on its state directory and on the resources it writes, and a new instance
+// may take them over from the running one, e.g. during an upgrade.
+package instance
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+)
+
+const (
+	lockFileName = "instance.lock"
+	ownerSuffix  = ".owner"
+
+	defaultTakeoverTimeout = 30 * time.Second
+	pollInterval           = 100 * time.Millisecond
+)
+
+// ErrLocked is returned when another instance holds the state directory or
+// a resource.
+var ErrLocked = errors.New("held by another instance")
+
+// Owner identifies the instance holding a lock, it is the content of the
+// lock files.
+type Owner struct {
+	PID     int       `json:"pid"`
+	Started time.Time `json:"started"`
+	Version string    `json:"version,omitempty"`
+	Addr    string    `json:"addr,omitempty"`
+}
+
+func (o *Owner) String() string {
+	if o == nil {
+		return "unknown instance"
+	}
+	if o.Version == "" {
+		return fmt.Sprintf("pid %d", o.PID)
+	}
+	return fmt.Sprintf("pid %d (version %s)", o.PID, o.Version)
+}
+
+// Options configure Acquire.
+type Options struct {
+	// Dir is the state directory, one instance runs per directory.
+	Dir string
+	// Version and Addr, the API address, are recorded in the locks.
+	Version string
+	Addr    string
+	// Takeover terminates the instance holding the state directory or a
+	// resource and waits Timeout at most for it to release them, instead
+	// of failing with ErrLocked.
+	Takeover bool
+	Timeout  time.Duration
+	// PidFile is the pid file of the versions without a state directory,
+	// the instance holding it is taken over too.
+	PidFile string
+}
+
+// Instance holds the lock of the state directory and the ownership markers
+// of the resources claimed, until Release.
+type Instance struct {
+	opts  Options
+	owner Owner
+
+	// Previous is the instance taken over, nil if none.
+	Previous *Owner
+
+	mu    sync.Mutex
+	locks []*os.File
+}
+
+// Acquire locks the state directory for the calling process.
+func Acquire(opts *Options) (*Instance, error) {
+	if err := os.MkdirAll(opts.Dir, 0o755); err != nil {
+		return nil, fmt.Errorf("state dir %s: %w", opts.Dir, err)
+	}
+
+	inst := &Instance{
+		opts: *opts,
+		owner: Owner{
+			PID:     os.Getpid(),
+			Started: time.Now(),
+			Version: opts.Version,
+			Addr:    opts.Addr,
+		},
+	}
+	if inst.opts.Timeout <= 0 {
+		inst.opts.Timeout = defaultTakeoverTimeout
+	}
+
+	prev, err := inst.lock(filepath.Join(opts.Dir, lockFileName))
+	if err != nil {
+		return nil, err
+	}
+	inst.Previous = prev
+
+	if opts.Takeover && opts.PidFile != "" {
+		legacy, err := inst.takeoverPidFile(opts.PidFile)
+		if err != nil {
+			inst.Release()
+			return nil, err
+		}
+		if inst.Previous == nil {
+			inst.Previous = legacy
+		}
+	}
+	return inst, nil
+}
+
+// Claim marks path, a file or a directory, as written by this instance with
+// a lock file next to it, path+".owner". The claims are held until Release,
+// so two instances with different state directories still never write the
+// same resource. It returns the instance taken over, if any.
+func (i *Instance) Claim(path string) (*Owner, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, fmt.Errorf("claim %s: %w", path, err)
+	}
+	return i.lock(path + ownerSuffix)
+}
+
+// Release releases the claims and the state directory. The lock files are
+// emptied but kept, removing them would let two instances lock different
+// files of the same path.
+func (i *Instance) Release() {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	for j := len(i.locks) - 1; j >= 0; j-- {
+		f := i.locks[j]
+		_ = f.Truncate(0)
+		_ = syscall.Flock(int(f.Fd()), syscall.LOCK_UN)
+		_ = f.Close()
+	}
+	i.locks = nil
+}
+
+func (i *Instance) lock(path string) (*Owner, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0o644)
+	if err != nil {
+		return nil, err
+	}
+
+	var prev *Owner
+	err = tryLock(f)
+	if errors.Is(err, syscall.EWOULDBLOCK) {
+		prev = readOwner(f)
+		if !i.opts.Takeover {
+			_ = f.Close()
+			return nil, fmt.Errorf("%s %w: %s", path, ErrLocked, prev)
+		}
+		err = i.takeover(f, prev)
+	}
+	if err != nil {
+		_ = f.Close()
+		return nil, fmt.Errorf("lock %s: %w", path, err)
+	}
+
+	if err := writeOwner(f, &i.owner); err != nil {
+		_ = syscall.Flock(int(f.Fd()), syscall.LOCK_UN)
+		_ = f.Close()
+		return nil, fmt.Errorf("lock %s: %w", path, err)
+	}
+
+	i.mu.Lock()
+	i.locks = append(i.locks, f)
+	i.mu.Unlock()
+	return prev, nil
+}
+
+// takeover terminates the owner of f and waits for it to unlock f.
+func (i *Instance) takeover(f *os.File, prev *Owner) error {
+	if prev == nil || prev.PID <= 0 || prev.PID == os.Getpid() {
+		return fmt.Errorf("%w, no pid to take over", ErrLocked)
+	}
+
+	if err := syscall.Kill(prev.PID, syscall.SIGTERM); err != nil && !errors.Is(err, syscall.ESRCH) {
+		return fmt.Errorf("terminate %s: %w", prev, err)
+	}
+
+	deadline := time.Now().Add(i.opts.Timeout)
+	for {
+		err := tryLock(f)
+		if !errors.Is(err, syscall.EWOULDBLOCK) {
+			return err
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("take over %s: still running after %s", prev, i.opts.Timeout)
+		}
+		time.Sleep(pollInterval)
+	}
+}
+
+// takeoverPidFile takes the pid file over from the instance holding it, it
+// is left to the pid file lock of the new instance.
+func (i *Instance) takeoverPidFile(path string) (*Owner, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer f.Close()
+
+	err = tryLock(f)
+	if err == nil {
+		return nil, syscall.Flock(int(f.Fd()), syscall.LOCK_UN)
+	}
+	if !errors.Is(err, syscall.EWOULDBLOCK) {
+		return nil, err
+	}
+
+	var prev *Owner
+	if data, err := os.ReadFile(path); err == nil {
+		if pid, err := strconv.Atoi(string(bytes.TrimSpace(data))); err == nil {
+			prev = &Owner{PID: pid}
+		}
+	}
+	if err := i.takeover(f, prev); err != nil {
+		return nil, fmt.Errorf("pid file %s: %w", path, err)
+	}
+	return prev, syscall.Flock(int(f.Fd()), syscall.LOCK_UN)
+}
+
+func tryLock(f *os.File) error {
+	return syscall.Flock(int(f.Fd()), syscall.LOCK_EX|syscall.LOCK_NB)
+}
+
+func readOwner(f *os.File) *Owner {
+	data, err := os.ReadFile(f.Name())
+	if err != nil {
+		return nil
+	}
+
+	var owner Owner
+	if err := json.Unmarshal(data, &owner); err != nil {
+		return nil
+	}
+	return &owner
+}
+
+func writeOwner(f *os.File, owner *Owner) error {
+	data, err := json.Marshal(owner)
+	if err != nil {
+		return err
+	}
+	if err := f.Truncate(0); err != nil {
+		return err
+	}
+	_, err = f.WriteAt(data, 0)
+	return err
+}
+
+// WaitPort waits timeout at most for the TCP address addr to be free to
+// listen on, e.g. for the instance taken over to close it.
+func WaitPort(addr string, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	for {
+		listener, err := net.Listen("tcp", addr)
+		if err == nil {
+			return listener.Close()
+		}
+		if !errors.Is(err, syscall.EADDRINUSE) || time.Now().After(deadline) {
+			return err
+		}
+		time.Sleep(pollInterval)
+	}
+}
@@ -0,0 +1,158 @@
+// Copyright 2026 The HuaTuo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package instance
+
+import (
+	"bufio"
+	"errors"
+	"net"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+)
+
+const helperDirEnv = "HUATUO_INSTANCE_HELPER_DIR"
+
+// TestHelperInstance is the running instance of TestAcquireTakeover, in a
+// process of its own.
+func TestHelperInstance(t *testing.T) {
+	dir := os.Getenv(helperDirEnv)
+	if dir == "" {
+		t.Skip("helper process")
+	}
+
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, syscall.SIGTERM)
+
+	inst, err := Acquire(&Options{Dir: dir, Version: "old"})
+	if err != nil {
+		t.Fatalf("Acquire() error = %v", err)
+	}
+	if _, err := inst.Claim(filepath.Join(dir, "events.db")); err != nil {
+		t.Fatalf("Claim() error = %v", err)
+	}
+	os.Stdout.WriteString("ready\n")
+
+	<-sig
+	inst.Release()
+	os.Exit(0)
+}
+
+func startHelperInstance(t *testing.T, dir string) *exec.Cmd {
+	t.Helper()
+
+	cmd := exec.Command(os.Args[0], "-test.run=^TestHelperInstance$")
+	cmd.Env = append(os.Environ(), helperDirEnv+"="+dir)
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_ = cmd.Process.Kill()
+		_ = cmd.Wait()
+	})
+
+	scanner := bufio.NewScanner(stdout)
+	for scanner.Scan() {
+		if scanner.Text() == "ready" {
+			return cmd
+		}
+	}
+	t.Fatalf("helper instance did not start: %v", scanner.Err())
+	return nil
+}
+
+func TestAcquire(t *testing.T) {
+	dir := t.TempDir()
+
+	inst, err := Acquire(&Options{Dir: dir, Version: "v1", Addr: ":19704"})
+	if err != nil {
+		t.Fatalf("Acquire() error = %v", err)
+	}
+	if inst.Previous != nil {
+		t.Errorf("Previous = %v, want nil", inst.Previous)
+	}
+
+	if _, err := Acquire(&Options{Dir: dir}); !errors.Is(err, ErrLocked) {
+		t.Errorf("second Acquire() error = %v, want ErrLocked", err)
+	}
+
+	resource := filepath.Join(t.TempDir(), "huatuo-local")
+	if _, err := inst.Claim(resource); err != nil {
+		t.Fatalf("Claim() error = %v", err)
+	}
+	other, err := Acquire(&Options{Dir: t.TempDir()})
+	if err != nil {
+		t.Fatalf("Acquire() of another dir error = %v", err)
+	}
+	defer other.Release()
+	if _, err := other.Claim(resource); !errors.Is(err, ErrLocked) {
+		t.Errorf("Claim() of a claimed resource error = %v, want ErrLocked", err)
+	}
+
+	inst.Release()
+	again, err := Acquire(&Options{Dir: dir})
+	if err != nil {
+		t.Fatalf("Acquire() after Release() error = %v", err)
+	}
+	again.Release()
+}
+
+func TestAcquireTakeover(t *testing.T) {
+	dir := t.TempDir()
+	cmd := startHelperInstance(t, dir)
+
+	if _, err := Acquire(&Options{Dir: dir}); !errors.Is(err, ErrLocked) {
+		t.Fatalf("Acquire() error = %v, want ErrLocked", err)
+	}
+
+	inst, err := Acquire(&Options{Dir: dir, Version: "new", Takeover: true, Timeout: 10 * time.Second})
+	if err != nil {
+		t.Fatalf("Acquire() with takeover error = %v", err)
+	}
+	defer inst.Release()
+
+	if inst.Previous == nil || inst.Previous.PID != cmd.Process.Pid || inst.Previous.Version != "old" {
+		t.Errorf("Previous = %v, want pid %d (version old)", inst.Previous, cmd.Process.Pid)
+	}
+	// the claims of the instance taken over are released with it.
+	if _, err := inst.Claim(filepath.Join(dir, "events.db")); err != nil {
+		t.Errorf("Claim() error = %v", err)
+	}
+}
+
+func TestWaitPort(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := listener.Addr().String()
+
+	if err := WaitPort(addr, 0); !errors.Is(err, syscall.EADDRINUSE) {
+		t.Errorf("WaitPort() error = %v, want EADDRINUSE", err)
+	}
+
+	time.AfterFunc(200*time.Millisecond, func() { _ = listener.Close() })
+	if err := WaitPort(addr, 5*time.Second); err != nil {
+		t.Errorf("WaitPort() error = %v, want the port released", err)
+	}
+}
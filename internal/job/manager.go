@@ -358,7 +358,7 @@ func (m *Manager) createContext(ctx context.Context, req *CreateJobRequest, idAt
 	}
 
 	agentTask := job.AgentTask
-	agentTaskID, err := m.startTask(ctx, job.Hostname, job.ContainerID, &agentTask)
+	agentTaskID, err := m.startTask(ctx, job, &agentTask)
 	if err != nil {
 		if errors.Is(err, ErrAgentDispatchUncertain) {
 			log.WithError(err).WithField("job_id", job.ID).
@@ -386,7 +386,7 @@ func (m *Manager) createContext(ctx context.Context, req *CreateJobRequest, idAt
 	m.mu.Unlock()
 	if err := m.storage.Save(ctx, runningSnapshot); err != nil {
 		m.persistenceFailures.Add(1)
-		stopErr := m.stopTask(ctx, job.Hostname, agentTaskID, true)
+		stopErr := m.stopTask(ctx, job, agentTaskID, true)
 		finishErr := m.finishJob(ctx, job, JobStatusFailed, "failed to persist running job", nil)
 		if finishErr == nil {
 			m.monitorWG.Done()
@@ -451,10 +451,10 @@ func (m *Manager) StopContext(ctx context.Context, jobID string, force bool) err
 		return nil
 	}
 	m.stopping[jobID] = struct{}{}
-	host, agentTaskID := job.Hostname, job.AgentTaskID
+	agentTaskID := job.AgentTaskID
 	m.mu.Unlock()
 
-	err := m.stopTask(ctx, host, agentTaskID, force)
+	err := m.stopTask(ctx, job, agentTaskID, force)
 	if err != nil {
 		m.mu.Lock()
 		delete(m.stopping, jobID)
@@ -723,7 +723,7 @@ func (m *Manager) jobIsActive(jobID string) bool {
 }
 
 func (m *Manager) stopAgent(ctx context.Context, job *Job, force bool) error {
-	if err := m.stopTask(ctx, job.Hostname, job.AgentTaskID, force); err != nil {
+	if err := m.stopTask(ctx, job, job.AgentTaskID, force); err != nil {
 		return fmt.Errorf("stop task %s: %w", job.ID, err)
 	}
 	return nil
@@ -734,7 +734,7 @@ func (m *Manager) checkAndUpdateJobStatus(ctx context.Context, job *Job) (string
 	m.mu.RLock()
 	jobSnapshot := cloneJob(job)
 	m.mu.RUnlock()
-	agentStatus, results, err := m.getTaskStatus(ctx, jobSnapshot.Hostname, jobSnapshot.AgentTaskID)
+	agentStatus, results, err := m.getTaskStatus(ctx, jobSnapshot)
 	if err != nil {
 		return agentStatus, err
 	}
@@ -775,7 +775,7 @@ func (m *Manager) checkAndUpdateJobStatus(ctx context.Context, job *Job) (string
 func (m *Manager) restartPendingJob(ctx context.Context, job *Job) (string, error) {
 	task := job.AgentTask
 	task.RequestID = job.ID
-	taskID, err := m.startTask(ctx, job.Hostname, job.ContainerID, &task)
+	taskID, err := m.startTask(ctx, job, &task)
 	if err != nil {
 		return AgentStatusNotExist, fmt.Errorf("restart pending task: %w", err)
 	}
@@ -974,14 +974,17 @@ func hasJobType(jobType JobType, expectedTypes []JobType) bool {
 	return false
 }
 
-func (m *Manager) startTask(ctx context.Context, host, container string, req *AgentTaskRequest) (string, error) {
-	return m.nodeAgent.StartTaskContext(ctx, host, container, req)
+// startTask starts the task of the job on its agent. The agent requests are
+// made for the user of the job, the operator recorded by the audit of the
+// agent.
+func (m *Manager) startTask(ctx context.Context, job *Job, req *AgentTaskRequest) (string, error) {
+	return m.nodeAgent.StartTaskContext(WithOperator(ctx, job.UserID), job.Hostname, job.ContainerID, req)
 }
 
-func (m *Manager) stopTask(ctx context.Context, host, taskID string, force bool) error {
-	return m.nodeAgent.StopTaskContext(ctx, host, taskID, force)
+func (m *Manager) stopTask(ctx context.Context, job *Job, taskID string, force bool) error {
+	return m.nodeAgent.StopTaskContext(WithOperator(ctx, job.UserID), job.Hostname, taskID, force)
 }
 
-func (m *Manager) getTaskStatus(ctx context.Context, host, taskID string) (string, *Result, error) {
-	return m.nodeAgent.GetTaskStatusContext(ctx, host, taskID)
+func (m *Manager) getTaskStatus(ctx context.Context, job *Job) (string, *Result, error) {
+	return m.nodeAgent.GetTaskStatusContext(WithOperator(ctx, job.UserID), job.Hostname, job.AgentTaskID)
 }
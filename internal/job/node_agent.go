@@ -27,6 +27,7 @@ import (
 	"time"
 
 	"huatuo-bamai/internal/log"
+	"huatuo-bamai/internal/server"
 )
 
 // HTTPNodeAgent implements NodeAgent interface using HTTP
@@ -36,6 +37,7 @@ type HTTPNodeAgent struct {
 	statusRetryAttempts int
 	statusRetryBackoff  time.Duration
 	observe             AgentRequestObserver
+	signingKey          []byte
 }
 
 // AgentRequestObserver records one completed Agent request.
@@ -53,6 +55,17 @@ type HTTPNodeAgentConfig struct {
 	StatusRetryAttempts int
 	StatusRetryBackoff  time.Duration
 	Observe             AgentRequestObserver
+	// SigningKey signs the requests, for the agents requiring them signed
+	// by the key they share.
+	SigningKey []byte
+}
+
+type operatorKey struct{}
+
+// WithOperator returns a context making the agent requests for the
+// operator, the user the agents audit the tasks of.
+func WithOperator(ctx context.Context, operator string) context.Context {
+	return context.WithValue(ctx, operatorKey{}, operator)
 }
 
 type startTaskRequest struct {
@@ -99,6 +112,7 @@ func NewHTTPNodeAgent(configs ...HTTPNodeAgentConfig) *HTTPNodeAgent {
 		statusRetryAttempts: config.StatusRetryAttempts,
 		statusRetryBackoff:  config.StatusRetryBackoff,
 		observe:             config.Observe,
+		signingKey:          config.SigningKey,
 	}
 }
 
@@ -137,6 +151,7 @@ func (c *HTTPNodeAgent) StartTaskContext(
 		return "", fmt.Errorf("failed to create request: %w", err)
 	}
 	req.Header.Set("Content-Type", "application/json")
+	c.sign(req, requestBodyBytes)
 
 	resp, err := c.client.Do(req)
 	if err != nil {
@@ -194,6 +209,7 @@ func (c *HTTPNodeAgent) StopTaskContext(
 	if err != nil {
 		return fmt.Errorf("failed to create request: %w", err)
 	}
+	c.sign(req, nil)
 
 	resp, err := c.client.Do(req)
 	if err != nil {
@@ -234,6 +250,7 @@ func (c *HTTPNodeAgent) GetTaskStatusContext(
 		if err != nil {
 			return "", nil, fmt.Errorf("failed to create request: %w", err)
 		}
+		c.sign(req, nil)
 
 		resp, err := c.client.Do(req)
 		if err != nil {
@@ -297,6 +314,16 @@ func (c *HTTPNodeAgent) observeRequest(operation string, startedAt time.Time, er
 	}
 }
 
+// sign signs the request of the body for the operator of its context, if
+// a signing key is configured.
+func (c *HTTPNodeAgent) sign(req *http.Request, body []byte) {
+	if len(c.signingKey) == 0 {
+		return
+	}
+	operator, _ := req.Context().Value(operatorKey{}).(string)
+	server.SignRequest(req, body, c.signingKey, operator, time.Now())
+}
+
 func (c *HTTPNodeAgent) endpoint(host, path string) string {
 	return "http://" + net.JoinHostPort(host, strconv.Itoa(c.port)) + path
 }
@@ -15,6 +15,7 @@
 package job
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -23,6 +24,8 @@ import (
 	"strings"
 	"testing"
 	"time"
+
+	"huatuo-bamai/internal/server"
 )
 
 type roundTripFunc func(req *http.Request) (*http.Response, error)
@@ -319,6 +322,48 @@ func TestHTTPNodeAgentStopTask(t *testing.T) {
 	})
 }
 
+func TestHTTPNodeAgentSignsRequests(t *testing.T) {
+	key := []byte("signing-key-2026")
+	agent := NewHTTPNodeAgent(HTTPNodeAgentConfig{SigningKey: key})
+	agent.client.Transport = roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		body, err := io.ReadAll(req.Body)
+		if err != nil {
+			t.Errorf("ReadAll(req.Body) error=%v, want nil", err)
+		}
+		operator, err := server.VerifyRequest(req, body, key, time.Minute, time.Now())
+		if err != nil {
+			t.Errorf("%s %s VerifyRequest() error=%v, want nil", req.Method, req.URL.Path, err)
+		}
+		if operator != "alice" {
+			t.Errorf("%s %s operator=%q, want %q", req.Method, req.URL.Path, operator, "alice")
+		}
+
+		switch req.Method {
+		case http.MethodPost:
+			return newHTTPResponse(http.StatusOK, `{"code":0,"data":{"task_id":"agent-task-2026"}}`), nil
+		case http.MethodDelete:
+			return newHTTPResponse(http.StatusNoContent, ""), nil
+		default:
+			return newHTTPResponse(http.StatusOK, `{"code":0,"data":{"status":"running"}}`), nil
+		}
+	})
+
+	ctx := WithOperator(context.Background(), "alice")
+	if _, err := agent.StartTaskContext(ctx, "huatuo-dev", "", &AgentTaskRequest{
+		TracerName:   "oncpu",
+		TraceTimeout: 60,
+		DataType:     "flamegraph",
+	}); err != nil {
+		t.Errorf("StartTaskContext() error=%v, want nil", err)
+	}
+	if _, _, err := agent.GetTaskStatusContext(ctx, "huatuo-dev", "agent-task-2026"); err != nil {
+		t.Errorf("GetTaskStatusContext() error=%v, want nil", err)
+	}
+	if err := agent.StopTaskContext(ctx, "huatuo-dev", "agent-task-2026", true); err != nil {
+		t.Errorf("StopTaskContext() error=%v, want nil", err)
+	}
+}
+
 // TestHTTPNodeAgentGetTaskStatus tests HTTPNodeAgent.GetTaskStatus status query logic, including successful nested response parsing, retry success after timeout, failure after 3 consecutive timeouts, and immediate return without retry on non-timeout errors.
 func TestHTTPNodeAgentGetTaskStatus(t *testing.T) {
 	cases := []struct {
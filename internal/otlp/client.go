@@ -0,0 +1,223 @@
+// Copyright 2026 The HuaTuo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package otlp exports to an OpenTelemetry collector over OTLP/gRPC: the
+// tracing events as log records, through the otlp storage backend, and the
+// metrics of a prometheus gatherer.
+package otlp
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+	"time"
+
+	"huatuo-bamai/internal/log"
+
+	collogspb "go.opentelemetry.io/proto/otlp/collector/logs/v1"
+	colmetricspb "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
+	metricspb "go.opentelemetry.io/proto/otlp/metrics/v1"
+	resourcepb "go.opentelemetry.io/proto/otlp/resource/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	defaultTimeout = 10 * time.Second
+
+	// ServiceName is the service.name of the exported resources.
+	ServiceName = "huatuo-bamai"
+	// ScopeName is the instrumentation scope of the exported data.
+	ScopeName = "huatuo-bamai"
+)
+
+// Config contains the collector endpoint and how to reach it.
+type Config struct {
+	// Endpoint is the host:port of the OTLP/gRPC receiver.
+	Endpoint string
+	// Headers are sent with every export, e.g. an authorization token.
+	Headers map[string]string
+	// Insecure disables TLS.
+	Insecure bool
+	// CAFile verifies the collector instead of the system roots, CertFile
+	// and KeyFile are the client certificate of mutual TLS.
+	CAFile             string
+	CertFile           string
+	KeyFile            string
+	InsecureSkipVerify bool
+	// Timeout bounds an export.
+	Timeout time.Duration
+}
+
+// Client exports logs and metrics to one collector.
+type Client struct {
+	conn    *grpc.ClientConn
+	logs    collogspb.LogsServiceClient
+	metrics colmetricspb.MetricsServiceClient
+	headers metadata.MD
+	timeout time.Duration
+}
+
+// NewClient creates a client, it connects on the first export.
+func NewClient(cfg *Config) (*Client, error) {
+	if cfg.Endpoint == "" {
+		return nil, fmt.Errorf("otlp: endpoint is empty")
+	}
+
+	creds, err := transportCredentials(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	conn, err := grpc.NewClient(cfg.Endpoint, grpc.WithTransportCredentials(creds))
+	if err != nil {
+		return nil, fmt.Errorf("otlp: endpoint %q: %w", cfg.Endpoint, err)
+	}
+
+	c := &Client{
+		conn:    conn,
+		logs:    collogspb.NewLogsServiceClient(conn),
+		metrics: colmetricspb.NewMetricsServiceClient(conn),
+		headers: metadata.New(cfg.Headers),
+		timeout: cfg.Timeout,
+	}
+	if c.timeout <= 0 {
+		c.timeout = defaultTimeout
+	}
+	return c, nil
+}
+
+func transportCredentials(cfg *Config) (credentials.TransportCredentials, error) {
+	if cfg.Insecure {
+		return insecure.NewCredentials(), nil
+	}
+
+	tlsConfig := &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		InsecureSkipVerify: cfg.InsecureSkipVerify, // #nosec G402
+	}
+	if cfg.CAFile != "" {
+		raw, err := os.ReadFile(cfg.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("otlp: read ca file: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(raw) {
+			return nil, fmt.Errorf("otlp: no certificate in ca file %s", cfg.CAFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+	if cfg.CertFile != "" || cfg.KeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(cfg.CertFile, cfg.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("otlp: load client certificate: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+	return credentials.NewTLS(tlsConfig), nil
+}
+
+func (c *Client) exportContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	if len(c.headers) > 0 {
+		ctx = metadata.NewOutgoingContext(ctx, c.headers)
+	}
+	return ctx, cancel
+}
+
+// ExportLogs sends the log records. The records the collector rejected in
+// a partial success are logged, not returned.
+func (c *Client) ExportLogs(ctx context.Context, logs []*logspb.ResourceLogs) error {
+	ctx, cancel := c.exportContext(ctx)
+	defer cancel()
+
+	res, err := c.logs.Export(ctx, &collogspb.ExportLogsServiceRequest{ResourceLogs: logs})
+	if err != nil {
+		return err
+	}
+	if partial := res.GetPartialSuccess(); partial.GetRejectedLogRecords() > 0 {
+		log.Warnf("otlp collector rejected %d log records: %s", partial.GetRejectedLogRecords(), partial.GetErrorMessage())
+	}
+	return nil
+}
+
+// ExportMetrics sends the metrics, see ExportLogs for the partial success.
+func (c *Client) ExportMetrics(ctx context.Context, metrics []*metricspb.ResourceMetrics) error {
+	ctx, cancel := c.exportContext(ctx)
+	defer cancel()
+
+	res, err := c.metrics.Export(ctx, &colmetricspb.ExportMetricsServiceRequest{ResourceMetrics: metrics})
+	if err != nil {
+		return err
+	}
+	if partial := res.GetPartialSuccess(); partial.GetRejectedDataPoints() > 0 {
+		log.Warnf("otlp collector rejected %d data points: %s", partial.GetRejectedDataPoints(), partial.GetErrorMessage())
+	}
+	return nil
+}
+
+// Close closes the connection to the collector.
+func (c *Client) Close() error {
+	return c.conn.Close()
+}
+
+// IsRetryable reports whether an export error is worth a retry, the codes
+// retryable by the OTLP specification.
+func IsRetryable(err error) bool {
+	switch status.Code(err) {
+	case codes.Canceled,
+		codes.DeadlineExceeded,
+		codes.Aborted,
+		codes.OutOfRange,
+		codes.Unavailable,
+		codes.DataLoss,
+		codes.ResourceExhausted:
+		return true
+	default:
+		return false
+	}
+}
+
+// Resource describes the node the data comes from.
+func Resource(hostname, region string) *resourcepb.Resource {
+	attrs := []*commonpb.KeyValue{
+		StringAttribute("service.name", ServiceName),
+		StringAttribute("host.name", hostname),
+	}
+	if region != "" {
+		attrs = append(attrs, StringAttribute("cloud.region", region))
+	}
+	return &resourcepb.Resource{Attributes: attrs}
+}
+
+// Scope is the instrumentation scope of the agent.
+func Scope() *commonpb.InstrumentationScope {
+	return &commonpb.InstrumentationScope{Name: ScopeName}
+}
+
+// StringAttribute returns a string attribute.
+func StringAttribute(key, value string) *commonpb.KeyValue {
+	return &commonpb.KeyValue{
+		Key:   key,
+		Value: &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: value}},
+	}
+}
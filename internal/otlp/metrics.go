@@ -0,0 +1,213 @@
+// Copyright 2026 The HuaTuo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package otlp
+
+import (
+	"context"
+	"math"
+	"time"
+
+	"huatuo-bamai/internal/log"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	metricspb "go.opentelemetry.io/proto/otlp/metrics/v1"
+	resourcepb "go.opentelemetry.io/proto/otlp/resource/v1"
+)
+
+const defaultMetricsInterval = time.Minute
+
+// MetricsExporter pushes the metrics of a gatherer to the collector on an
+// interval, the same metrics as a scrape of /metrics.
+type MetricsExporter struct {
+	client   *Client
+	gatherer prometheus.Gatherer
+	resource *resourcepb.Resource
+	interval time.Duration
+	// start is the start time of the cumulative sums, the counters of the
+	// agent count from its start.
+	start time.Time
+}
+
+// NewMetricsExporter creates an exporter of the gatherer.
+func NewMetricsExporter(client *Client, gatherer prometheus.Gatherer, resource *resourcepb.Resource, interval time.Duration) *MetricsExporter {
+	if interval <= 0 {
+		interval = defaultMetricsInterval
+	}
+	return &MetricsExporter{
+		client:   client,
+		gatherer: gatherer,
+		resource: resource,
+		interval: interval,
+		start:    time.Now(),
+	}
+}
+
+// Run exports the metrics every interval until ctx is done. A failed export
+// is not retried, the next one carries the cumulative values.
+func (e *MetricsExporter) Run(ctx context.Context) {
+	ticker := time.NewTicker(e.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if err := e.Export(ctx); err != nil {
+			log.Warnf("otlp export metrics: %v", err)
+		}
+	}
+}
+
+// Export gathers and exports the metrics once.
+func (e *MetricsExporter) Export(ctx context.Context) error {
+	families, err := e.gatherer.Gather()
+	if err != nil {
+		// the families gathered are still exported, as a scrape does.
+		log.Debugf("otlp gather metrics: %v", err)
+	}
+	if len(families) == 0 {
+		return nil
+	}
+
+	return e.client.ExportMetrics(ctx, []*metricspb.ResourceMetrics{{
+		Resource: e.resource,
+		ScopeMetrics: []*metricspb.ScopeMetrics{{
+			Scope:   Scope(),
+			Metrics: convertMetrics(families, e.start, time.Now()),
+		}},
+	}})
+}
+
+// convertMetrics converts the metric families: counters to monotonic
+// cumulative sums, gauges and untyped to gauges, histograms and summaries
+// to their OTLP equivalents. The labels are the attributes.
+func convertMetrics(families []*dto.MetricFamily, start, now time.Time) []*metricspb.Metric {
+	startNano, nowNano := uint64(start.UnixNano()), uint64(now.UnixNano())
+
+	metrics := make([]*metricspb.Metric, 0, len(families))
+	for _, family := range families {
+		m := &metricspb.Metric{Name: family.GetName(), Description: family.GetHelp()}
+
+		switch family.GetType() {
+		case dto.MetricType_COUNTER:
+			points := make([]*metricspb.NumberDataPoint, 0, len(family.GetMetric()))
+			for _, sample := range family.GetMetric() {
+				points = append(points, numberPoint(sample, sample.GetCounter().GetValue(), startNano, nowNano))
+			}
+			m.Data = &metricspb.Metric_Sum{Sum: &metricspb.Sum{
+				AggregationTemporality: metricspb.AggregationTemporality_AGGREGATION_TEMPORALITY_CUMULATIVE,
+				IsMonotonic:            true,
+				DataPoints:             points,
+			}}
+		case dto.MetricType_GAUGE, dto.MetricType_UNTYPED:
+			points := make([]*metricspb.NumberDataPoint, 0, len(family.GetMetric()))
+			for _, sample := range family.GetMetric() {
+				value := sample.GetGauge().GetValue()
+				if family.GetType() == dto.MetricType_UNTYPED {
+					value = sample.GetUntyped().GetValue()
+				}
+				points = append(points, numberPoint(sample, value, 0, nowNano))
+			}
+			m.Data = &metricspb.Metric_Gauge{Gauge: &metricspb.Gauge{DataPoints: points}}
+		case dto.MetricType_HISTOGRAM:
+			points := make([]*metricspb.HistogramDataPoint, 0, len(family.GetMetric()))
+			for _, sample := range family.GetMetric() {
+				points = append(points, histogramPoint(sample, startNano, nowNano))
+			}
+			m.Data = &metricspb.Metric_Histogram{Histogram: &metricspb.Histogram{
+				AggregationTemporality: metricspb.AggregationTemporality_AGGREGATION_TEMPORALITY_CUMULATIVE,
+				DataPoints:             points,
+			}}
+		case dto.MetricType_SUMMARY:
+			points := make([]*metricspb.SummaryDataPoint, 0, len(family.GetMetric()))
+			for _, sample := range family.GetMetric() {
+				points = append(points, summaryPoint(sample, startNano, nowNano))
+			}
+			m.Data = &metricspb.Metric_Summary{Summary: &metricspb.Summary{DataPoints: points}}
+		default:
+			continue
+		}
+		metrics = append(metrics, m)
+	}
+	return metrics
+}
+
+func attributes(sample *dto.Metric) []*commonpb.KeyValue {
+	attrs := make([]*commonpb.KeyValue, 0, len(sample.GetLabel()))
+	for _, label := range sample.GetLabel() {
+		attrs = append(attrs, StringAttribute(label.GetName(), label.GetValue()))
+	}
+	return attrs
+}
+
+func numberPoint(sample *dto.Metric, value float64, startNano, nowNano uint64) *metricspb.NumberDataPoint {
+	return &metricspb.NumberDataPoint{
+		Attributes:        attributes(sample),
+		StartTimeUnixNano: startNano,
+		TimeUnixNano:      nowNano,
+		Value:             &metricspb.NumberDataPoint_AsDouble{AsDouble: value},
+	}
+}
+
+// histogramPoint converts the cumulative buckets of prometheus to the
+// counts per bucket of OTLP, the +Inf bucket is implicit in both.
+func histogramPoint(sample *dto.Metric, startNano, nowNano uint64) *metricspb.HistogramDataPoint {
+	h := sample.GetHistogram()
+	sum := h.GetSampleSum()
+
+	point := &metricspb.HistogramDataPoint{
+		Attributes:        attributes(sample),
+		StartTimeUnixNano: startNano,
+		TimeUnixNano:      nowNano,
+		Count:             h.GetSampleCount(),
+		Sum:               &sum,
+	}
+
+	var previous uint64
+	for _, bucket := range h.GetBucket() {
+		if math.IsInf(bucket.GetUpperBound(), 1) {
+			continue
+		}
+		point.ExplicitBounds = append(point.ExplicitBounds, bucket.GetUpperBound())
+		point.BucketCounts = append(point.BucketCounts, bucket.GetCumulativeCount()-previous)
+		previous = bucket.GetCumulativeCount()
+	}
+	point.BucketCounts = append(point.BucketCounts, h.GetSampleCount()-previous)
+	return point
+}
+
+func summaryPoint(sample *dto.Metric, startNano, nowNano uint64) *metricspb.SummaryDataPoint {
+	s := sample.GetSummary()
+
+	point := &metricspb.SummaryDataPoint{
+		Attributes:        attributes(sample),
+		StartTimeUnixNano: startNano,
+		TimeUnixNano:      nowNano,
+		Count:             s.GetSampleCount(),
+		Sum:               s.GetSampleSum(),
+	}
+	for _, q := range s.GetQuantile() {
+		point.QuantileValues = append(point.QuantileValues, &metricspb.SummaryDataPoint_ValueAtQuantile{
+			Quantile: q.GetQuantile(),
+			Value:    q.GetValue(),
+		})
+	}
+	return point
+}
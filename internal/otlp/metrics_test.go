@@ -0,0 +1,85 @@
+// Copyright 2026 The HuaTuo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package otlp
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func TestConvertMetrics(t *testing.T) {
+	registry := prometheus.NewRegistry()
+
+	counter := prometheus.NewCounterVec(prometheus.CounterOpts{Name: "huatuo_events_total", Help: "events"}, []string{"tracer"})
+	counter.WithLabelValues("oom").Add(3)
+	gauge := prometheus.NewGauge(prometheus.GaugeOpts{Name: "huatuo_backlog", Help: "backlog"})
+	gauge.Set(7)
+	histogram := prometheus.NewHistogram(prometheus.HistogramOpts{
+		Name:    "huatuo_latency_seconds",
+		Help:    "latency",
+		Buckets: []float64{0.1, 1},
+	})
+	for _, v := range []float64{0.05, 0.5, 0.7, 5} {
+		histogram.Observe(v)
+	}
+	registry.MustRegister(counter, gauge, histogram)
+
+	families, err := registry.Gather()
+	if err != nil {
+		t.Fatalf("Gather() error = %v", err)
+	}
+
+	start := time.Unix(100, 0)
+	now := time.Unix(160, 0)
+	metrics := convertMetrics(families, start, now)
+	if len(metrics) != 3 {
+		t.Fatalf("convertMetrics() = %d metrics, want 3", len(metrics))
+	}
+
+	byName := make(map[string]int)
+	for i, m := range metrics {
+		byName[m.GetName()] = i
+	}
+
+	sum := metrics[byName["huatuo_events_total"]].GetSum()
+	if sum == nil || !sum.GetIsMonotonic() || len(sum.GetDataPoints()) != 1 {
+		t.Fatalf("counter = %v, want a monotonic sum of one point", sum)
+	}
+	point := sum.GetDataPoints()[0]
+	if point.GetAsDouble() != 3 || point.GetStartTimeUnixNano() != uint64(start.UnixNano()) ||
+		point.GetAttributes()[0].GetKey() != "tracer" || point.GetAttributes()[0].GetValue().GetStringValue() != "oom" {
+		t.Errorf("counter point = %v, want 3 since start with tracer=oom", point)
+	}
+
+	g := metrics[byName["huatuo_backlog"]].GetGauge()
+	if g == nil || g.GetDataPoints()[0].GetAsDouble() != 7 || g.GetDataPoints()[0].GetTimeUnixNano() != uint64(now.UnixNano()) {
+		t.Errorf("gauge = %v, want 7 at now", g)
+	}
+
+	h := metrics[byName["huatuo_latency_seconds"]].GetHistogram()
+	if h == nil || len(h.GetDataPoints()) != 1 {
+		t.Fatalf("histogram = %v, want one point", h)
+	}
+	hp := h.GetDataPoints()[0]
+	if hp.GetCount() != 4 || hp.GetSum() != 6.25 {
+		t.Errorf("histogram count, sum = %d, %v, want 4, 6.25", hp.GetCount(), hp.GetSum())
+	}
+	if !slices.Equal(hp.GetExplicitBounds(), []float64{0.1, 1}) || !slices.Equal(hp.GetBucketCounts(), []uint64{1, 2, 1}) {
+		t.Errorf("histogram bounds, counts = %v, %v, want [0.1 1], [1 2 1]", hp.GetExplicitBounds(), hp.GetBucketCounts())
+	}
+}
@@ -0,0 +1,54 @@
+// Copyright 2026 The HuaTuo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package packet
+
+import (
+	"strings"
+
+	"github.com/cilium/ebpf/btf"
+)
+
+// DropReasonConsumed is the reason of the packets freed but not dropped.
+const DropReasonConsumed = "CONSUMED"
+
+// DropReasons maps the values of enum skb_drop_reason of the running kernel
+// to their names as the kfree_skb tracepoint prints them, e.g.
+// NOT_SPECIFIED. The kernels before 5.17 have no drop reasons.
+func DropReasons() (map[uint32]string, error) {
+	spec, err := btf.LoadKernelSpec()
+	if err != nil {
+		return nil, err
+	}
+
+	var enum *btf.Enum
+	if err := spec.TypeByName("skb_drop_reason", &enum); err != nil {
+		return nil, err
+	}
+	return dropReasonNames(enum), nil
+}
+
+func dropReasonNames(enum *btf.Enum) map[uint32]string {
+	names := make(map[uint32]string, len(enum.Values))
+	for _, v := range enum.Values {
+		name := strings.TrimPrefix(v.Name, "SKB_DROP_REASON_")
+		// SKB_CONSUMED and SKB_NOT_DROPPED_YET.
+		name = strings.TrimPrefix(name, "SKB_")
+		if name == "MAX" {
+			continue
+		}
+		names[uint32(v.Value)] = name
+	}
+	return names
+}
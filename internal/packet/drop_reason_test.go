@@ -0,0 +1,45 @@
+// Copyright 2026 The HuaTuo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package packet
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/cilium/ebpf/btf"
+)
+
+func TestDropReasonNames(t *testing.T) {
+	got := dropReasonNames(&btf.Enum{
+		Name: "skb_drop_reason",
+		Values: []btf.EnumValue{
+			{Name: "SKB_NOT_DROPPED_YET", Value: 0},
+			{Name: "SKB_CONSUMED", Value: 1},
+			{Name: "SKB_DROP_REASON_NOT_SPECIFIED", Value: 2},
+			{Name: "SKB_DROP_REASON_NO_SOCKET", Value: 3},
+			{Name: "SKB_DROP_REASON_MAX", Value: 4},
+		},
+	})
+
+	want := map[uint32]string{
+		0: "NOT_DROPPED_YET",
+		1: DropReasonConsumed,
+		2: "NOT_SPECIFIED",
+		3: "NO_SOCKET",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("names = %v, want %v", got, want)
+	}
+}
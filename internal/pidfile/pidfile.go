@@ -29,6 +29,11 @@ func path(name string) string {
 	return fmt.Sprintf("%s/%s.pid", defaultDirPath, name)
 }
 
+// Path returns the path of the pid file for name.
+func Path(name string) string {
+	return path(name)
+}
+
 // Handle owns an acquired pid file: an open fd holding the exclusive
 // flock, plus the on-disk path to remove on Unlock. The fd must stay
 // reachable for the whole lifetime of the lock — Linux flock is bound
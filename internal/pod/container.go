@@ -1,4 +1,4 @@
-// Copyright 2025 The HuaTuo Authors
+// Copyright 2025, 2026 The HuaTuo Authors
 //
 // Licensed under the Apache License, Version 2.0 (the "License");
 // you may not use this file except in compliance with the License.
@@ -18,10 +18,12 @@ import (
 	"errors"
 	"fmt"
 	"regexp"
+	"strings"
 	"sync"
 	"syscall"
 	"time"
 
+	"huatuo-bamai/internal/cgroups"
 	"huatuo-bamai/internal/log"
 )
 
@@ -53,8 +55,14 @@ type Container struct {
 	CgroupCss          map[string]uint64 `json:"cgroup_css"` // map for: subSysName -> structAddress
 	StartedAt          time.Time         `json:"started_at"`
 	SyncedAt           time.Time         `json:"synced_at"`
-	Labels             map[string]any    `json:"labels"` // custom labels
+	ExitedAt           time.Time         `json:"exited_at,omitzero"`     // set on the containers gone, see tombstoneBy
+	Labels             map[string]any    `json:"labels"`                 // custom labels
+	Thresholds         map[string]uint64 `json:"thresholds,omitempty"`   // tracer threshold overrides by pod annotations
+	PodMetadata        map[string]string `json:"pod_metadata,omitempty"` // pod labels and annotations configured, see SetPodMetadata
+	Resolver           string            `json:"resolver,omitempty"`     // resolver of a workload not from kubelet
 	lifeResources      map[string]any
+	eventRegisteredAt  time.Time // registered from its CRI event, before kubelet reports it
+	utsNamespaceInode  uint64    // the key of its hostname in utsHostnames
 }
 
 func (c *Container) String() string {
@@ -87,6 +95,7 @@ func containersByTypeQos(typeMask ContainerType, minLevel ContainerQos) (map[str
 	res := make(map[string]*Container)
 
 	if time.Since(lastUpdatedAt) > updatedStep {
+		resolverSyncContainers()
 		if err := kubeletSyncContainers(); err != nil {
 			if errors.Is(err, syscall.ECONNREFUSED) { // ignore error of no connections
 				log.Debugf("failed to sync containers by ECONNREFUSED, err: %v", err)
@@ -129,7 +138,8 @@ func ValidateContainerID(id string) error {
 	return nil
 }
 
-// ContainerByID returns the special container by id.
+// ContainerByID returns the special container by id, or the container gone
+// within the last minute, with ExitedAt set.
 func ContainerByID(id string) (*Container, error) {
 	all, err := Containers()
 	if err != nil {
@@ -139,7 +149,7 @@ func ContainerByID(id string) (*Container, error) {
 	if c, ok := all[id]; ok {
 		return c, nil
 	}
-	return nil, nil
+	return tombstoneBy(ContainerTypeAll, func(c *Container) string { return c.ID }, id), nil
 }
 
 // NormalContainers returns the normal containers.
@@ -158,7 +168,9 @@ func Containers() (map[string]*Container, error) {
 }
 
 // containerBy searches normal containers and returns the first one for which
-// selector returns val. Returns nil, nil when no container matches.
+// selector returns val, then the normal containers gone within the last
+// minute, so the events read late are still attributed. Returns nil, nil
+// when no container matches.
 func containerBy[T comparable](selector func(*Container) T, val T) (*Container, error) {
 	all, err := NormalContainers()
 	if err != nil {
@@ -171,7 +183,7 @@ func containerBy[T comparable](selector func(*Container) T, val T) (*Container,
 		}
 	}
 
-	return nil, nil
+	return tombstoneBy(ContainerTypeNormal, selector, val), nil
 }
 
 // ContainerByNetInode returns the container whose net namespace inode matches.
@@ -219,6 +231,37 @@ func ContainerByCSS(css uint64, subsys string) (*Container, error) {
 	return containerBy(func(c *Container) uint64 { return c.CgroupCss[subsys] }, css)
 }
 
+// ContainerByPid returns the container that process pid belongs to, matched by
+// the container ID embedded in its cgroup path. Host processes yield nil, nil.
+func ContainerByPid(pid int) (*Container, error) {
+	paths, err := cgroups.PathsForPID(pid)
+	if err != nil {
+		return nil, err
+	}
+
+	cgroupPath, err := paths.PathForProcesses()
+	if err != nil {
+		return nil, err
+	}
+
+	all, err := Containers()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, c := range all {
+		if strings.Contains(cgroupPath, c.ID) {
+			return c, nil
+		}
+		// the IDs of the resolved workloads are not in their cgroup path.
+		if c.Resolver != "" && (cgroupPath == c.CgroupPath || strings.HasPrefix(cgroupPath, c.CgroupPath+"/")) {
+			return c, nil
+		}
+	}
+
+	return nil, nil
+}
+
 // ContainerByNetCookie returns the container whose net namespace cookie matches cookie.
 func ContainerByNetCookie(cookie uint64) (*Container, error) {
 	if cookie == 0 {
@@ -111,6 +111,8 @@ func containerScopeName(containerID string) (string, error) {
 		return "docker-" + containerID + ".scope", nil
 	case containerProviderContainerd:
 		return "cri-containerd-" + containerID + ".scope", nil
+	case containerProviderCRIO:
+		return "crio-" + containerID + ".scope", nil
 	default:
 		return "", fmt.Errorf("container provider not initialized")
 	}
@@ -147,6 +149,10 @@ func containerCgroupPath(containerID string, pod *corev1.Pod) (cgroupPath, error
 		return cgroupPath{slices: paths, scope: scope}, nil
 	}
 
+	// cri-o prefixes the cgroup of the container on cgroupfs too.
+	if currContainerProvider == containerProviderCRIO {
+		return cgroupPath{slices: append(paths, "crio-"+containerID)}, nil
+	}
 	paths = append(paths, containerID)
 	return cgroupPath{slices: paths}, nil
 }
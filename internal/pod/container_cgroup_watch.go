@@ -0,0 +1,286 @@
+// Copyright 2026 The HuaTuo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pod
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+	"unsafe"
+
+	"huatuo-bamai/internal/cgroups"
+	"huatuo-bamai/internal/cgroups/subsystem"
+	"huatuo-bamai/internal/log"
+
+	"golang.org/x/sys/unix"
+)
+
+const (
+	// events of one container start come in a burst, sync once.
+	cgroupWatchDebounce = 100 * time.Millisecond
+	// kubelet reports the container id after the runtime creates the
+	// cgroup, retry the sync until the new container shows up.
+	cgroupWatchRetryInterval = time.Second
+	cgroupWatchRetries       = 5
+	// kubepods/<qos>/<pod>/<container>, the container level is not watched.
+	cgroupWatchMaxDepth = 2
+
+	cgroupWatchMask = unix.IN_CREATE | unix.IN_DELETE | unix.IN_ONLYDIR
+)
+
+var cgroupWatchCancel context.CancelFunc
+
+// cgroupWatcher watches the kubepods cgroup hierarchy by inotify, which is
+// not recursive, so that every qos and pod directory gets its own watch.
+type cgroupWatcher struct {
+	file *os.File
+	root string
+	// watches maps the watch descriptor to the watched directory.
+	watches map[int32]string
+	// changed is signaled on every directory created or removed, and
+	// created collects the container ids of the new directories.
+	changed chan struct{}
+	created chan string
+}
+
+func newCgroupWatcher(root string) (*cgroupWatcher, error) {
+	// nonblocking, so that closing the file wakes up the reader.
+	fd, err := unix.InotifyInit1(unix.IN_NONBLOCK | unix.IN_CLOEXEC)
+	if err != nil {
+		return nil, fmt.Errorf("inotify init: %w", err)
+	}
+
+	w := &cgroupWatcher{
+		file:    os.NewFile(uintptr(fd), "inotify"),
+		root:    root,
+		watches: map[int32]string{},
+		changed: make(chan struct{}, 1),
+		created: make(chan string, 64),
+	}
+
+	if err := w.addTree(root); err != nil {
+		w.file.Close()
+		return nil, err
+	}
+	return w, nil
+}
+
+func (w *cgroupWatcher) depth(path string) int {
+	rel, err := filepath.Rel(w.root, path)
+	if err != nil || rel == "." {
+		return 0
+	}
+	return strings.Count(rel, string(filepath.Separator)) + 1
+}
+
+// addTree watches path and its sub-directories up to the pod level.
+func (w *cgroupWatcher) addTree(path string) error {
+	wd, err := unix.InotifyAddWatch(int(w.file.Fd()), path, cgroupWatchMask)
+	if err != nil {
+		return fmt.Errorf("inotify watch %s: %w", path, err)
+	}
+	w.watches[int32(wd)] = path
+
+	if w.depth(path) >= cgroupWatchMaxDepth {
+		return nil
+	}
+
+	// directories created before the watch was added.
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		return nil
+	}
+	for _, entry := range entries {
+		if entry.IsDir() && extractContainerID(entry.Name()) == "" {
+			_ = w.addTree(filepath.Join(path, entry.Name()))
+		}
+	}
+	return nil
+}
+
+func (w *cgroupWatcher) notify() {
+	select {
+	case w.changed <- struct{}{}:
+	default:
+	}
+}
+
+// run reads the inotify events until the watcher is closed.
+func (w *cgroupWatcher) run() {
+	buf := make([]byte, 64*(unix.SizeofInotifyEvent+unix.NAME_MAX+1))
+
+	for {
+		n, err := w.file.Read(buf)
+		if err != nil {
+			if !errors.Is(err, os.ErrClosed) {
+				log.Warnf("cgroup watch read: %v", err)
+			}
+			return
+		}
+
+		for offset := 0; offset+unix.SizeofInotifyEvent <= n; {
+			event := (*unix.InotifyEvent)(unsafe.Pointer(&buf[offset]))
+			nameBytes := buf[offset+unix.SizeofInotifyEvent : offset+unix.SizeofInotifyEvent+int(event.Len)]
+			offset += unix.SizeofInotifyEvent + int(event.Len)
+
+			w.handle(event.Wd, event.Mask, strings.TrimRight(string(nameBytes), "\x00"))
+		}
+	}
+}
+
+func (w *cgroupWatcher) handle(wd int32, mask uint32, name string) {
+	switch {
+	case mask&unix.IN_Q_OVERFLOW != 0:
+		// events lost, a full sync still catches up.
+		w.notify()
+		return
+	case mask&unix.IN_IGNORED != 0:
+		delete(w.watches, wd)
+		return
+	case mask&unix.IN_ISDIR == 0:
+		return
+	}
+
+	parent, ok := w.watches[wd]
+	if !ok {
+		return
+	}
+
+	if mask&unix.IN_CREATE != 0 {
+		path := filepath.Join(parent, name)
+		if id := extractContainerID(name); id != "" {
+			select {
+			case w.created <- id:
+			default:
+			}
+		} else if w.depth(path) <= cgroupWatchMaxDepth {
+			_ = w.addTree(path)
+		}
+	}
+
+	w.notify()
+}
+
+func (w *cgroupWatcher) close() {
+	w.file.Close()
+}
+
+// syncLoop calls sync once the events settle, and again while the created
+// containers are still unknown to sync.
+func (w *cgroupWatcher) syncLoop(ctx context.Context, sync func(pending map[string]int)) {
+	pending := map[string]int{}
+	retry := time.NewTimer(0)
+	<-retry.C
+
+	for {
+		select {
+		case <-ctx.Done():
+			retry.Stop()
+			return
+		case <-w.changed:
+			time.Sleep(cgroupWatchDebounce)
+		case <-retry.C:
+		}
+
+		for drained := false; !drained; {
+			select {
+			case id := <-w.created:
+				pending[id] = 0
+			case <-w.changed:
+			default:
+				drained = true
+			}
+		}
+
+		sync(pending)
+
+		for id, retries := range pending {
+			if retries >= cgroupWatchRetries {
+				delete(pending, id)
+				continue
+			}
+			pending[id] = retries + 1
+		}
+		if len(pending) > 0 {
+			retry.Reset(cgroupWatchRetryInterval)
+		}
+	}
+}
+
+// kubepodsCgroupRoot returns the top cgroup directory of the pods.
+func kubepodsCgroupRoot() string {
+	root := cgroups.RootfsDefaultPath()
+	if cgroups.CgroupMode() != cgroups.Unified {
+		root = cgroups.RootFsFilePath(subsystem.SubsystemCPU)
+	}
+
+	if kubeletPodCgroupDriver == "systemd" {
+		return filepath.Join(root, defaultNodeCgroupName+defaultSystemdSuffix)
+	}
+	return filepath.Join(root, defaultNodeCgroupName)
+}
+
+// containerCgroupWatchSync syncs the containers from kubelet, and drops
+// the pending container ids which are known now.
+func containerCgroupWatchSync(pending map[string]int) {
+	containersMapLock.Lock()
+	defer containersMapLock.Unlock()
+
+	if err := kubeletSyncContainers(); err != nil {
+		log.Debugf("cgroup watch sync containers: %v", err)
+		return
+	}
+	lastUpdatedAt = time.Now()
+
+	for id := range pending {
+		if _, ok := containers[id]; ok {
+			delete(pending, id)
+		}
+	}
+}
+
+// containerCgroupWatchInit syncs the containers as soon as their cgroups
+// are created or removed, the periodic sync remains as the fallback.
+func containerCgroupWatchInit() {
+	root := kubepodsCgroupRoot()
+
+	w, err := newCgroupWatcher(root)
+	if err != nil {
+		log.Infof("cgroup watch disabled, fallback to periodic sync: %v", err)
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cgroupWatchCancel = func() {
+		cancel()
+		w.close()
+	}
+
+	go w.run()
+	go w.syncLoop(ctx, containerCgroupWatchSync)
+
+	log.Infof("cgroup watch started on %s", root)
+}
+
+func containerCgroupWatchRelease() {
+	if cgroupWatchCancel != nil {
+		cgroupWatchCancel()
+		cgroupWatchCancel = nil
+	}
+}
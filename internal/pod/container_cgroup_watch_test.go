@@ -0,0 +1,122 @@
+// Copyright 2026 The HuaTuo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pod
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCgroupWatcher(t *testing.T) {
+	root := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(root, "burstable"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	w, err := newCgroupWatcher(root)
+	if err != nil {
+		t.Fatalf("newCgroupWatcher() error = %v", err)
+	}
+	t.Cleanup(w.close)
+
+	synced := make(chan map[string]int, 16)
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	go w.run()
+	go w.syncLoop(ctx, func(pending map[string]int) {
+		cp := make(map[string]int, len(pending))
+		for id, retries := range pending {
+			cp[id] = retries
+		}
+		synced <- cp
+		// the container is known after the first sync.
+		clear(pending)
+	})
+
+	waitSync := func() map[string]int {
+		t.Helper()
+		select {
+		case pending := <-synced:
+			return pending
+		case <-time.After(5 * time.Second):
+			t.Fatal("no sync after cgroup change")
+		}
+		return nil
+	}
+
+	// the pod directory created under the existing qos directory.
+	pod := filepath.Join(root, "burstable", "pod1234")
+	if err := os.Mkdir(pod, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	waitSync()
+
+	// the container directory created in the new pod directory.
+	id := strings.Repeat("ab", 32)
+	container := filepath.Join(pod, "cri-containerd-"+id+".scope")
+	if err := os.Mkdir(container, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if pending := waitSync(); pending[id] != 0 || len(pending) != 1 {
+		t.Errorf("pending = %v, want %s", pending, id)
+	}
+
+	if err := os.Remove(container); err != nil {
+		t.Fatal(err)
+	}
+	if pending := waitSync(); len(pending) != 0 {
+		t.Errorf("pending = %v, want empty", pending)
+	}
+}
+
+func TestCgroupWatcherDepth(t *testing.T) {
+	root := t.TempDir()
+	id := strings.Repeat("cd", 32)
+	for _, dir := range []string{
+		"pod1/" + id,
+		"besteffort/pod2/" + id,
+	} {
+		if err := os.MkdirAll(filepath.Join(root, dir), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	w, err := newCgroupWatcher(root)
+	if err != nil {
+		t.Fatalf("newCgroupWatcher() error = %v", err)
+	}
+	defer w.close()
+
+	got := map[string]bool{}
+	for _, path := range w.watches {
+		rel, _ := filepath.Rel(root, path)
+		got[rel] = true
+	}
+
+	// container directories are not watched.
+	for _, want := range []string{".", "pod1", "besteffort", "besteffort/pod2"} {
+		if !got[want] {
+			t.Errorf("%s is not watched, watches = %v", want, got)
+		}
+	}
+	if len(got) != 4 {
+		t.Errorf("watches = %v, want 4", got)
+	}
+}
@@ -0,0 +1,209 @@
+// Copyright 2026 The HuaTuo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pod
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"huatuo-bamai/internal/log"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	runtimeapi "k8s.io/cri-api/pkg/apis/runtime/v1"
+	k8sremote "k8s.io/cri-client/pkg"
+)
+
+const (
+	// criEventsRetryInterval is the wait before the stream is opened
+	// again, the cgroup watch syncs the containers meanwhile.
+	criEventsRetryInterval = 10 * time.Second
+	// criEventsGrace is how long a container registered by its event is
+	// kept while kubelet does not report it yet, kubelet updates the pod
+	// status after its next relist.
+	criEventsGrace = 30 * time.Second
+)
+
+var criEventsCancel context.CancelFunc
+
+// criEventsAPI is the part of the CRI runtime service streaming the events.
+type criEventsAPI interface {
+	GetContainerEvents(ctx context.Context, ch chan *runtimeapi.ContainerEventResponse,
+		connected func(runtimeapi.RuntimeService_GetContainerEventsClient)) error
+}
+
+// criEventsWatch registers the containers from the CRI event stream as
+// soon as they start, and buries them as soon as they stop. The cgroup
+// watch takes over while the stream is down, e.g. on a runtime without
+// GetContainerEvents: docker, containerd before 1.7 or cri-o before 1.26.
+func criEventsWatch(ctx context.Context, api criEventsAPI, fallback func()) {
+	var fallbackOnce sync.Once
+
+	for {
+		ch := make(chan *runtimeapi.ContainerEventResponse, 64)
+		done := make(chan error, 1)
+		go func() {
+			done <- api.GetContainerEvents(ctx, ch, func(runtimeapi.RuntimeService_GetContainerEventsClient) {
+				log.Infof("cri events watch started on %s", kubeletRuntimeEndpoint)
+			})
+		}()
+
+		err := criEventsConsume(ctx, ch, done)
+		if ctx.Err() != nil {
+			return
+		}
+
+		log.Infof("cri events watch stopped, fallback to cgroup watch: %v", err)
+		fallbackOnce.Do(fallback)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(criEventsRetryInterval):
+		}
+	}
+}
+
+// criEventsConsume handles the events until the stream ends.
+func criEventsConsume(ctx context.Context, ch chan *runtimeapi.ContainerEventResponse, done chan error) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case err := <-done:
+			return err
+		case event := <-ch:
+			criEventHandle(event)
+		}
+	}
+}
+
+func criEventHandle(event *runtimeapi.ContainerEventResponse) {
+	switch event.GetContainerEventType() {
+	case runtimeapi.ContainerEventType_CONTAINER_STARTED_EVENT:
+		if err := criEventRegister(event); err != nil {
+			// the cgroup watch or the periodic sync catches up.
+			log.Debugf("cri event of container %s: %v", event.GetContainerId(), err)
+		}
+	case runtimeapi.ContainerEventType_CONTAINER_STOPPED_EVENT, runtimeapi.ContainerEventType_CONTAINER_DELETED_EVENT:
+		containersMapLock.Lock()
+		buryContainer(event.GetContainerId(), time.Now())
+		containersMapLock.Unlock()
+	}
+}
+
+// criEventRegister registers the container started, its pod and spec from
+// kubelet, which knows the pod before its status reports the container.
+func criEventRegister(event *runtimeapi.ContainerEventResponse) error {
+	id := event.GetContainerId()
+	sandbox := event.GetPodSandboxStatus()
+	if sandbox == nil {
+		return fmt.Errorf("no pod sandbox")
+	}
+
+	var status *runtimeapi.ContainerStatus
+	for _, s := range event.GetContainersStatuses() {
+		if s.GetId() == id {
+			status = s
+			break
+		}
+	}
+	if status == nil {
+		return fmt.Errorf("no container status")
+	}
+
+	containersMapLock.Lock()
+	defer containersMapLock.Unlock()
+
+	if _, ok := containers[id]; ok {
+		return nil
+	}
+
+	podList, err := kubeletGetPodList()
+	if err != nil {
+		return err
+	}
+
+	pod, container := criEventPodContainer(&podList, sandbox.GetMetadata().GetUid(), status.GetMetadata().GetName())
+	if pod != nil && !podTracked(pod.Namespace, pod.Name) {
+		return nil
+	}
+	if container == nil {
+		return fmt.Errorf("no container %s in pod %s/%s", status.GetMetadata().GetName(),
+			sandbox.GetMetadata().GetNamespace(), sandbox.GetMetadata().GetName())
+	}
+	if pod.Status.PodIP == "" {
+		// kubelet reports the ip of a new pod after its sandbox is up.
+		pod = pod.DeepCopy()
+		pod.Status.PodIP = sandbox.GetNetwork().GetIp()
+	}
+
+	containerStatus := &corev1.ContainerStatus{
+		Name: container.Name,
+		State: corev1.ContainerState{
+			Running: &corev1.ContainerStateRunning{StartedAt: metav1.NewTime(time.Unix(0, status.GetStartedAt()))},
+		},
+	}
+	if err := kubeletUpdateContainer(id, container, containerStatus, pod); err != nil {
+		return err
+	}
+	containers[id].eventRegisteredAt = time.Now()
+
+	log.Debugf("cri event registered container %s of pod %s/%s", id, pod.Namespace, pod.Name)
+	return nil
+}
+
+func criEventPodContainer(podList *corev1.PodList, uid, name string) (*corev1.Pod, *corev1.Container) {
+	for i := range podList.Items {
+		pod := &podList.Items[i]
+		if string(pod.UID) != uid {
+			continue
+		}
+		for j := range pod.Spec.Containers {
+			if pod.Spec.Containers[j].Name == name {
+				return pod, &pod.Spec.Containers[j]
+			}
+		}
+		return pod, nil
+	}
+	return nil, nil
+}
+
+// containerWatchInit syncs the containers as soon as they start or stop,
+// from the CRI events or else the cgroups, the periodic sync remains as
+// the fallback.
+func containerWatchInit() {
+	ctx, cancel := context.WithCancel(context.Background())
+	criEventsCancel = cancel
+
+	client, err := k8sremote.NewRemoteRuntimeService(kubeletRuntimeEndpoint, criRequestTimeout, nil, nil)
+	if err != nil {
+		log.Infof("cri events watch disabled: %v", err)
+		containerCgroupWatchInit()
+		return
+	}
+
+	go criEventsWatch(ctx, client, containerCgroupWatchInit)
+}
+
+func containerWatchRelease() {
+	if criEventsCancel != nil {
+		criEventsCancel()
+		criEventsCancel = nil
+	}
+	containerCgroupWatchRelease()
+}
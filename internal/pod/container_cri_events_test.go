@@ -0,0 +1,121 @@
+// Copyright 2026 The HuaTuo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pod
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	runtimeapi "k8s.io/cri-api/pkg/apis/runtime/v1"
+)
+
+// fakeCRIEvents sends its events, then ends the stream with err.
+type fakeCRIEvents struct {
+	events []*runtimeapi.ContainerEventResponse
+	err    error
+	calls  int
+}
+
+func (f *fakeCRIEvents) GetContainerEvents(ctx context.Context, ch chan *runtimeapi.ContainerEventResponse,
+	connected func(runtimeapi.RuntimeService_GetContainerEventsClient),
+) error {
+	f.calls++
+	connected(nil)
+	for _, e := range f.events {
+		ch <- e
+	}
+	return f.err
+}
+
+func TestCRIEventsWatch(t *testing.T) {
+	savedContainers, savedTombstones := containers, tombstones
+	t.Cleanup(func() { containers, tombstones = savedContainers, savedTombstones })
+
+	containers = map[string]*Container{
+		"stopped": {ID: "stopped", Type: ContainerTypeNormal},
+		"running": {ID: "running", Type: ContainerTypeNormal},
+	}
+	tombstones = nil
+
+	api := &fakeCRIEvents{
+		events: []*runtimeapi.ContainerEventResponse{
+			{ContainerId: "stopped", ContainerEventType: runtimeapi.ContainerEventType_CONTAINER_STOPPED_EVENT},
+			// no sandbox, left to the periodic sync.
+			{ContainerId: "new", ContainerEventType: runtimeapi.ContainerEventType_CONTAINER_STARTED_EVENT},
+		},
+		err: errors.New("unimplemented"),
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	fallbacks := 0
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		criEventsWatch(ctx, api, func() {
+			fallbacks++
+			cancel()
+		})
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("watch not stopped")
+	}
+
+	if api.calls != 1 || fallbacks != 1 {
+		t.Errorf("calls = %d, fallbacks = %d, want 1 and 1", api.calls, fallbacks)
+	}
+	if _, ok := containers["stopped"]; ok || len(tombstones) != 1 || tombstones[0].ID != "stopped" {
+		t.Errorf("containers = %v, tombstones = %v, want stopped buried", containers, tombstones)
+	}
+	if _, ok := containers["new"]; ok {
+		t.Error("container registered without its sandbox")
+	}
+}
+
+func TestKubeletSyncContainersEventGrace(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, `{"items":[]}`)
+	}))
+	t.Cleanup(srv.Close)
+
+	savedContainers, savedTombstones := containers, tombstones
+	savedEnabled, savedClient, savedURL := kubeletPodListRunningEnabled, kubeletPodListClient, kubeletPodListURL
+	t.Cleanup(func() {
+		containers, tombstones = savedContainers, savedTombstones
+		kubeletPodListRunningEnabled, kubeletPodListClient, kubeletPodListURL = savedEnabled, savedClient, savedURL
+	})
+	kubeletPodListRunningEnabled, kubeletPodListClient, kubeletPodListURL = true, srv.Client(), srv.URL
+
+	containers = map[string]*Container{
+		"new":   {ID: "new", eventRegisteredAt: time.Now()},
+		"stale": {ID: "stale", eventRegisteredAt: time.Now().Add(-criEventsGrace)},
+		"gone":  {ID: "gone"},
+	}
+	tombstones = nil
+
+	if err := kubeletSyncContainers(); err != nil {
+		t.Fatal(err)
+	}
+	if len(containers) != 1 || containers["new"] == nil {
+		t.Errorf("containers = %v, want only the new one in its grace", containers)
+	}
+}
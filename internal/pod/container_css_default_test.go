@@ -42,6 +42,14 @@ func TestExtractContainerID(t *testing.T) {
 			input:    "cri-containerd-bd23762346b2af6261d285e8c2bdf82f9abeb427338c086cca27da98fee4dfa5.scope",
 			expected: "bd23762346b2af6261d285e8c2bdf82f9abeb427338c086cca27da98fee4dfa5",
 		},
+		{ // cri-o container cgroup name
+			input:    "crio-bd23762346b2af6261d285e8c2bdf82f9abeb427338c086cca27da98fee4dfa5.scope",
+			expected: "bd23762346b2af6261d285e8c2bdf82f9abeb427338c086cca27da98fee4dfa5",
+		},
+		{ // cri-o monitor cgroup name
+			input:    "crio-conmon-bd23762346b2af6261d285e8c2bdf82f9abeb427338c086cca27da98fee4dfa5.scope",
+			expected: "",
+		},
 	} {
 		actual := extractContainerID(tc.input)
 		if actual != tc.expected {
@@ -0,0 +1,94 @@
+// Copyright 2026 The HuaTuo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pod
+
+import (
+	"strings"
+	"sync"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// podReasonEvicted is the status reason kubelet sets on the pods it evicts
+// for node resource pressure.
+const podReasonEvicted = "Evicted"
+
+// ContainerEviction is a container of a pod evicted by kubelet. kubelet
+// sets the pod status before killing the containers, the container may
+// still be terminating.
+type ContainerEviction struct {
+	Container *Container
+	PodName   string
+	Namespace string
+	// Message is the kubelet explanation, e.g. "The node was low on
+	// resource: memory. ..."
+	Message string
+}
+
+var (
+	evictionHandlersLock sync.RWMutex
+	evictionHandlers     []func(*ContainerEviction)
+)
+
+// RegisterEvictionHandler calls fn once for every known container of an
+// evicted pod. fn is called while the containers are synced, it must not
+// block nor call back into this package.
+func RegisterEvictionHandler(fn func(*ContainerEviction)) {
+	evictionHandlersLock.Lock()
+	defer evictionHandlersLock.Unlock()
+
+	evictionHandlers = append(evictionHandlers, fn)
+}
+
+func isEvictedPod(pod *corev1.Pod) bool {
+	return pod.Status.Phase == corev1.PodFailed && pod.Status.Reason == podReasonEvicted
+}
+
+// notifyEvictedContainers reports the known containers of the evicted pods,
+// before the sync forgets them.
+func notifyEvictedContainers(podList *corev1.PodList) {
+	evictionHandlersLock.RLock()
+	defer evictionHandlersLock.RUnlock()
+
+	if len(evictionHandlers) == 0 {
+		return
+	}
+
+	for i := range podList.Items {
+		pod := &podList.Items[i]
+		if !isEvictedPod(pod) {
+			continue
+		}
+
+		for j := range pod.Status.ContainerStatuses {
+			// only the known containers matter, the runtime is set up already.
+			_, containerID, _ := strings.Cut(pod.Status.ContainerStatuses[j].ContainerID, "://")
+			container, ok := containers[containerID]
+			if !ok {
+				continue
+			}
+
+			eviction := &ContainerEviction{
+				Container: container,
+				PodName:   pod.Name,
+				Namespace: pod.Namespace,
+				Message:   pod.Status.Message,
+			}
+			for _, fn := range evictionHandlers {
+				fn(eviction)
+			}
+		}
+	}
+}
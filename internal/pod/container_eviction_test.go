@@ -0,0 +1,61 @@
+// Copyright 2026 The HuaTuo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pod
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestNotifyEvictedContainers(t *testing.T) {
+	origContainers, origHandlers := containers, evictionHandlers
+	t.Cleanup(func() { containers, evictionHandlers = origContainers, origHandlers })
+
+	containers = map[string]*Container{
+		"aaaa": {ID: "aaaa"},
+		"bbbb": {ID: "bbbb"},
+	}
+	evictionHandlers = nil
+
+	var got []*ContainerEviction
+	RegisterEvictionHandler(func(e *ContainerEviction) { got = append(got, e) })
+
+	newPod := func(name string, phase corev1.PodPhase, reason, id string) corev1.Pod {
+		return corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+			Status: corev1.PodStatus{
+				Phase:             phase,
+				Reason:            reason,
+				Message:           "The node was low on resource: memory.",
+				ContainerStatuses: []corev1.ContainerStatus{{ContainerID: "containerd://" + id}},
+			},
+		}
+	}
+	notifyEvictedContainers(&corev1.PodList{Items: []corev1.Pod{
+		newPod("evicted", corev1.PodFailed, podReasonEvicted, "aaaa"),
+		newPod("running", corev1.PodRunning, "", "bbbb"),
+		newPod("failed", corev1.PodFailed, "Error", "bbbb"),
+		newPod("unknown", corev1.PodFailed, podReasonEvicted, "cccc"),
+	}})
+
+	if len(got) != 1 {
+		t.Fatalf("evictions = %d, want 1", len(got))
+	}
+	if e := got[0]; e.Container.ID != "aaaa" || e.PodName != "evicted" || e.Namespace != "default" || e.Message == "" {
+		t.Errorf("eviction = %+v", e)
+	}
+}
@@ -39,6 +39,8 @@ func containerInitPid(containerID string) (int, error) {
 		return containerInitPIDInDockerRoot(dockerRoot, containerID)
 	case containerProviderContainerd:
 		return containerInitPIDInContainerdState(containerdState, containerID)
+	case containerProviderCRIO:
+		return containerInitPIDInCRIO(crioSocket, containerID)
 	default:
 		return -1, fmt.Errorf("container provider not initialized")
 	}
@@ -100,6 +102,8 @@ func containerInitPIDByID(containerID string) (int, error) {
 		return containerInitPIDInDockerRoot(dockerRoot, containerID)
 	case containerProviderContainerd:
 		return containerInitPIDInContainerdState(containerdState, containerID)
+	case containerProviderCRIO:
+		return containerInitPIDInCRIO(crioSocket, containerID)
 	}
 
 	dockerPID, dockerErr := containerInitPIDInDockerRoot(defaultDockerRootDir, containerID)
@@ -110,11 +114,15 @@ func containerInitPIDByID(containerID string) (int, error) {
 	if containerdErr == nil {
 		return containerdPID, nil
 	}
+	crioPID, crioErr := containerInitPIDInCRIO(crioSocket, containerID)
+	if crioErr == nil {
+		return crioPID, nil
+	}
 
 	return -1, fmt.Errorf(
 		"resolve container %q init PID from local runtime state: %w",
 		containerID,
-		errors.Join(dockerErr, containerdErr),
+		errors.Join(dockerErr, containerdErr, crioErr),
 	)
 }
 
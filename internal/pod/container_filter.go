@@ -0,0 +1,61 @@
+// Copyright 2026 The HuaTuo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pod
+
+import (
+	"fmt"
+	"path"
+)
+
+// trackFilter selects the containers tracked by the globs of their pod
+// namespace and name, an empty namespaceWhitelist tracks every namespace.
+var trackFilter struct {
+	namespaceWhitelist []string
+	podNameBlacklist   []string
+}
+
+// SetTrackFilter sets the globs, e.g. "kube-*", of the pod namespaces
+// tracked and of the pod names not tracked. The containers of the other
+// pods have no container metrics, and their events no container. It is
+// called before the containers are synced.
+func SetTrackFilter(namespaceWhitelist, podNameBlacklist []string) error {
+	for _, pattern := range append(namespaceWhitelist, podNameBlacklist...) {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid pattern %q: %w", pattern, err)
+		}
+	}
+
+	trackFilter.namespaceWhitelist = namespaceWhitelist
+	trackFilter.podNameBlacklist = podNameBlacklist
+	return nil
+}
+
+// podTracked returns whether the containers of the pod are tracked, name
+// is the workload name for the resolvers.
+func podTracked(namespace, name string) bool {
+	if len(trackFilter.namespaceWhitelist) > 0 && !matchAny(trackFilter.namespaceWhitelist, namespace) {
+		return false
+	}
+	return !matchAny(trackFilter.podNameBlacklist, name)
+}
+
+func matchAny(patterns []string, name string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
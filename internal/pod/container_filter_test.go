@@ -0,0 +1,99 @@
+// Copyright 2026 The HuaTuo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pod
+
+import (
+	"testing"
+)
+
+func setTrackFilter(t *testing.T, namespaceWhitelist, podNameBlacklist []string) {
+	t.Helper()
+
+	if err := SetTrackFilter(namespaceWhitelist, podNameBlacklist); err != nil {
+		t.Fatalf("SetTrackFilter() error = %v", err)
+	}
+	t.Cleanup(func() { _ = SetTrackFilter(nil, nil) })
+}
+
+func TestPodTracked(t *testing.T) {
+	if err := SetTrackFilter([]string{"prod-["}, nil); err == nil {
+		t.Error("SetTrackFilter() with an invalid glob succeeded")
+	}
+
+	tests := []struct {
+		name               string
+		namespaceWhitelist []string
+		podNameBlacklist   []string
+		namespace, pod     string
+		want               bool
+	}{
+		{name: "no filter", namespace: "default", pod: "web", want: true},
+		{name: "whitelisted", namespaceWhitelist: []string{"prod-*", "kube-system"}, namespace: "prod-a", pod: "web", want: true},
+		{name: "not whitelisted", namespaceWhitelist: []string{"prod-*"}, namespace: "dev", pod: "web", want: false},
+		{name: "blacklisted", podNameBlacklist: []string{"*-canary-*"}, namespace: "prod", pod: "web-canary-1", want: false},
+		{
+			name:               "whitelisted and blacklisted",
+			namespaceWhitelist: []string{"prod"},
+			podNameBlacklist:   []string{"web-*"},
+			namespace:          "prod",
+			pod:                "web-1",
+			want:               false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setTrackFilter(t, tt.namespaceWhitelist, tt.podNameBlacklist)
+			if got := podTracked(tt.namespace, tt.pod); got != tt.want {
+				t.Errorf("podTracked(%q, %q) = %v, want %v", tt.namespace, tt.pod, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResolverSyncContainersFiltered(t *testing.T) {
+	setupResolverRoot(t, []string{
+		"system.slice/nginx.service",
+		"system.slice/backup.service",
+	}, "system.slice/nginx.service", "system.slice/backup.service")
+	setTrackFilter(t, []string{"system"}, []string{"backup.*"})
+
+	r, err := NewSystemdResolver(nil, nil, "")
+	if err != nil {
+		t.Fatalf("NewSystemdResolver() error = %v", err)
+	}
+	if err := RegisterResolver(r); err != nil {
+		t.Fatalf("RegisterResolver() error = %v", err)
+	}
+	t.Cleanup(resolversRelease)
+
+	containersMapLock.Lock()
+	defer containersMapLock.Unlock()
+
+	saved := containers
+	containers = map[string]*Container{}
+	t.Cleanup(func() { containers = saved })
+
+	resolverSyncContainers()
+	if len(containers) != 1 || containers[workloadContainerID("systemd", "/system.slice/nginx.service")] == nil {
+		t.Fatalf("containers = %v, want nginx.service only", containers)
+	}
+
+	// the containers of a namespace no longer tracked are removed.
+	setTrackFilter(t, []string{"kube-*"}, nil)
+	resolverSyncContainers()
+	if len(containers) != 0 {
+		t.Errorf("containers = %v, want none", containers)
+	}
+}
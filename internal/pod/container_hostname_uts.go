@@ -0,0 +1,105 @@
+// Copyright 2026 The HuaTuo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pod
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+
+	"huatuo-bamai/internal/procfs"
+	"huatuo-bamai/internal/utils/executil"
+)
+
+// utsHostnames caches the hostnames by the inode of their UTS namespace,
+// the containers of a pod share it.
+var utsHostnames = struct {
+	sync.Mutex
+	m map[uint64]string
+}{m: make(map[uint64]string)}
+
+// utsHostnameByPid returns the hostname of the UTS namespace of pid.
+// Overridden by the tests.
+var utsHostnameByPid = func(pid int) (string, error) {
+	return executil.HostnameByPid(uint32(pid))
+}
+
+// utsNamespaceInode returns the inode of the UTS namespace of pid.
+func utsNamespaceInode(pid int) (uint64, error) {
+	st, err := os.Stat(procfs.Path(strconv.Itoa(pid), "ns/uts"))
+	if err != nil {
+		return 0, err
+	}
+	return st.Sys().(*syscall.Stat_t).Ino, nil
+}
+
+// containerUTSHostname returns the hostname the container of the init pid
+// sees, of its UTS namespace, and the inode of the namespace. Without the
+// privilege to enter the namespace, the /etc/hostname of the container
+// written by the runtime is read instead.
+func containerUTSHostname(pid int) (string, uint64, error) {
+	inode, err := utsNamespaceInode(pid)
+	if err != nil {
+		return "", 0, err
+	}
+
+	utsHostnames.Lock()
+	hostname, ok := utsHostnames.m[inode]
+	utsHostnames.Unlock()
+	if ok {
+		return hostname, inode, nil
+	}
+
+	hostname, err = utsHostnameByPid(pid)
+	if err != nil || hostname == "" {
+		data, ferr := os.ReadFile(procfs.Path(strconv.Itoa(pid), "root/etc/hostname"))
+		if ferr != nil {
+			return "", 0, fmt.Errorf("uts namespace: %v, /etc/hostname: %w", err, ferr)
+		}
+		hostname = strings.TrimSpace(string(data))
+	}
+	if hostname == "" {
+		return "", 0, fmt.Errorf("empty hostname of pid %d", pid)
+	}
+
+	utsHostnames.Lock()
+	utsHostnames.m[inode] = hostname
+	utsHostnames.Unlock()
+	return hostname, inode, nil
+}
+
+// pruneUTSHostnames drops the hostnames of the UTS namespaces no container
+// is in anymore, their inodes may be reused, with the containers lock held.
+func pruneUTSHostnames() {
+	utsHostnames.Lock()
+	defer utsHostnames.Unlock()
+
+	if len(utsHostnames.m) == 0 {
+		return
+	}
+
+	used := make(map[uint64]struct{}, len(containers))
+	for _, c := range containers {
+		used[c.utsNamespaceInode] = struct{}{}
+	}
+	for inode := range utsHostnames.m {
+		if _, ok := used[inode]; !ok {
+			delete(utsHostnames.m, inode)
+		}
+	}
+}
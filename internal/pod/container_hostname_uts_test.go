@@ -0,0 +1,103 @@
+// Copyright 2026 The HuaTuo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pod
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"huatuo-bamai/internal/procfs"
+)
+
+// fakeUTSProc creates the uts namespace of the pids, and the /etc/hostname
+// of their root if not empty, under a fake procfs.
+func fakeUTSProc(t *testing.T, hostnames map[string]string) {
+	t.Helper()
+
+	root := t.TempDir()
+	procfs.RootPrefix(root)
+	t.Cleanup(func() { procfs.RootPrefix("") })
+
+	for pid, hostname := range hostnames {
+		dir := procfs.Path(pid)
+		if err := os.MkdirAll(filepath.Join(dir, "ns"), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, "ns", "uts"), nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if hostname == "" {
+			continue
+		}
+		if err := os.MkdirAll(filepath.Join(dir, "root", "etc"), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, "root", "etc", "hostname"), []byte(hostname+"\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestContainerUTSHostname(t *testing.T) {
+	savedByPid, savedCache := utsHostnameByPid, utsHostnames.m
+	t.Cleanup(func() { utsHostnameByPid, utsHostnames.m = savedByPid, savedCache })
+	utsHostnames.m = make(map[uint64]string)
+
+	fakeUTSProc(t, map[string]string{"10": "", "20": "from-file", "30": ""})
+
+	calls := 0
+	utsHostnameByPid = func(pid int) (string, error) {
+		calls++
+		if pid == 10 {
+			return "web-0", nil
+		}
+		return "", errors.New("operation not permitted")
+	}
+
+	for range 2 {
+		hostname, inode, err := containerUTSHostname(10)
+		if err != nil || hostname != "web-0" || inode == 0 {
+			t.Fatalf("containerUTSHostname(10) = %q, %d, %v, want web-0", hostname, inode, err)
+		}
+	}
+	if calls != 1 {
+		t.Errorf("namespace entered %d times, want once then cached", calls)
+	}
+
+	if hostname, _, err := containerUTSHostname(20); err != nil || hostname != "from-file" {
+		t.Errorf("containerUTSHostname(20) = %q, %v, want the /etc/hostname", hostname, err)
+	}
+	if _, _, err := containerUTSHostname(30); err == nil {
+		t.Error("containerUTSHostname(30) error = nil, want no hostname")
+	}
+	if _, _, err := containerUTSHostname(40); err == nil {
+		t.Error("containerUTSHostname(40) error = nil, want no process")
+	}
+}
+
+func TestPruneUTSHostnames(t *testing.T) {
+	savedContainers, savedCache := containers, utsHostnames.m
+	t.Cleanup(func() { containers, utsHostnames.m = savedContainers, savedCache })
+
+	containers = map[string]*Container{"a": {ID: "a", utsNamespaceInode: 1}}
+	utsHostnames.m = map[uint64]string{1: "alive", 2: "gone"}
+
+	pruneUTSHostnames()
+	if len(utsHostnames.m) != 1 || utsHostnames.m[1] != "alive" {
+		t.Errorf("hostnames = %v, want only the alive one", utsHostnames.m)
+	}
+}
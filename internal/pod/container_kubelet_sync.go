@@ -1,4 +1,4 @@
-// Copyright 2025 The HuaTuo Authors
+// Copyright 2025, 2026 The HuaTuo Authors
 //
 // Licensed under the Apache License, Version 2.0 (the "License");
 // you may not use this file except in compliance with the License.
@@ -17,6 +17,7 @@ package pod
 import (
 	"context"
 	"crypto/tls"
+	"crypto/x509"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -73,7 +74,30 @@ type ManagerCtx struct {
 	PodReadOnlyPort   uint32
 	PodAuthorizedPort uint32
 	PodClientCertPath string
-	DockerAPIVersion  string
+	// PodTokenPath is the bearer token of the authorized port, e.g. the
+	// service account token, read at every request as it is rotated.
+	PodTokenPath string
+	// PodCAPath is the CA verifying the serving certificate of kubelet
+	// for PodServerName, the hostname if empty; not verified if empty.
+	PodCAPath        string
+	PodServerName    string
+	DockerAPIVersion string
+	// ContainerRuntime is the runtime of the containers, "docker",
+	// "containerd" or "cri-o", detected from the pods if empty.
+	ContainerRuntime string
+	// CRIOSocket is the socket of cri-o, defaultCRIOSocket if empty.
+	CRIOSocket string
+	// CgroupDriver is the cgroup driver of kubelet, "systemd" or
+	// "cgroupfs", read from the kubelet config if empty.
+	CgroupDriver string
+	// Mode is ModeKubelet, ModeStandalone or ModeAuto.
+	Mode string
+	// CRIEndpoint is the CRI runtime of the standalone mode, the
+	// containerd or cri-o socket found if empty.
+	CRIEndpoint string
+	// StandaloneNamespace is the namespace of the standalone containers
+	// not in a pod.
+	StandaloneNamespace string
 
 	// this is used internally.
 	podClientCertPath string
@@ -102,26 +126,86 @@ func kubeletPodListHttpRequest(ctx *ManagerCtx) (*http.Client, error) {
 }
 
 func kubeletPodListAuthorizationRequest(ctx *ManagerCtx) (*http.Client, error) {
-	cert, err := tls.LoadX509KeyPair(ctx.podClientCertPath, ctx.podClientCertKey)
+	client, err := kubeletAuthorizedClient(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("loading client key pair [%s,%s]: %w",
-			ctx.podClientCertPath, ctx.podClientCertKey, err)
-	}
-
-	client := &http.Client{
-		Timeout: kubeletReqTimeout,
-		Transport: &http.Transport{
-			TLSClientConfig: &tls.Config{
-				Certificates:       []tls.Certificate{cert},
-				InsecureSkipVerify: true, // #nosec G402
-			},
-		},
+		return nil, err
 	}
 
 	_, err = kubeletPodListDoRequest(client, kubeletPodListAuthorizedURL(ctx.PodAuthorizedPort))
 	return client, err
 }
 
+// kubeletAuthorizedClient returns the client of the authorized port,
+// authenticated by the client certificate, the bearer token or both.
+func kubeletAuthorizedClient(ctx *ManagerCtx) (*http.Client, error) {
+	tlsConfig := &tls.Config{
+		InsecureSkipVerify: true, // #nosec G402
+	}
+
+	if ctx.podClientCertPath != "" {
+		cert, err := tls.LoadX509KeyPair(ctx.podClientCertPath, ctx.podClientCertKey)
+		if err != nil {
+			return nil, fmt.Errorf("loading client key pair [%s,%s]: %w",
+				ctx.podClientCertPath, ctx.podClientCertKey, err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	if ctx.PodCAPath != "" {
+		data, err := os.ReadFile(ctx.PodCAPath)
+		if err != nil {
+			return nil, fmt.Errorf("loading kubelet ca: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(data) {
+			return nil, fmt.Errorf("loading kubelet ca %s: no certificate", ctx.PodCAPath)
+		}
+
+		serverName := ctx.PodServerName
+		if serverName == "" {
+			// the serving certificate of kubelet is for the node name.
+			if serverName, err = os.Hostname(); err != nil {
+				return nil, fmt.Errorf("kubelet server name: %w", err)
+			}
+		}
+		tlsConfig.RootCAs = pool
+		tlsConfig.ServerName = serverName
+		tlsConfig.InsecureSkipVerify = false
+	}
+
+	var transport http.RoundTripper = &http.Transport{TLSClientConfig: tlsConfig}
+	if ctx.PodTokenPath != "" {
+		transport = &kubeletTokenTransport{path: ctx.PodTokenPath, base: transport}
+	}
+
+	return &http.Client{
+		Timeout:   kubeletReqTimeout,
+		Transport: transport,
+	}, nil
+}
+
+// kubeletTokenTransport authenticates the requests by the bearer token in
+// path, read at every request as the projected tokens are rotated.
+type kubeletTokenTransport struct {
+	path string
+	base http.RoundTripper
+}
+
+func (t *kubeletTokenTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	data, err := os.ReadFile(t.path)
+	if err != nil {
+		return nil, fmt.Errorf("loading kubelet token: %w", err)
+	}
+	token := strings.TrimSpace(string(data))
+	if token == "" {
+		return nil, fmt.Errorf("loading kubelet token %s: empty", t.path)
+	}
+
+	req = req.Clone(req.Context())
+	req.Header.Set("Authorization", "Bearer "+token)
+	return t.base.RoundTrip(req)
+}
+
 func kubeletPodListPortCacheUpdate(ctx *ManagerCtx) error {
 	if client, err := kubeletPodListHttpRequest(ctx); err == nil {
 		kubeletPodListURL = kubeletPodListReadOnlyURL(ctx.PodReadOnlyPort)
@@ -145,40 +229,70 @@ func kubeletPodListPortCacheUpdate(ctx *ManagerCtx) error {
 
 func InitManager(ctx *ManagerCtx) error {
 	dockerAPIVersion = ctx.DockerAPIVersion
+	if ctx.CRIOSocket != "" {
+		crioSocket = ctx.CRIOSocket
+	}
+	if ctx.CgroupDriver != "" {
+		kubeletPodCgroupDriver = ctx.CgroupDriver
+	}
+
+	if ctx.ContainerRuntime != "" {
+		provider, err := containerProviderFrom(ctx.ContainerRuntime)
+		if err != nil {
+			return err
+		}
+		if err := initContainerProviderEnv(provider, dockerAPIVersion); err != nil {
+			return fmt.Errorf("init container runtime %s: %w", provider, err)
+		}
+	}
+
+	if ctx.Mode == ModeStandalone {
+		log.Infof("pod sync in standalone mode, the containers are not synced from kubelet")
+		standaloneStart(ctx)
+		setManagerState(ManagerStateStandalone)
+		return nil
+	}
 
 	if ctx.PodReadOnlyPort == 0 && ctx.PodAuthorizedPort == 0 {
 		log.Warnf("pod sync is not working, we manually turned off this, readonlyport == 0, and authorizedport == 0")
 		return nil
 	}
 
-	// if user enable the only authorized port, the cert path must be not
-	// empty.
-	if ctx.PodReadOnlyPort == 0 && ctx.PodAuthorizedPort != 0 && ctx.PodClientCertPath == "" {
-		log.Errorf("when you enable only the authorized port, you should populate cert path.")
+	// if user enable the only authorized port, the cert path or the token
+	// path must be not empty.
+	if ctx.PodReadOnlyPort == 0 && ctx.PodAuthorizedPort != 0 && ctx.PodClientCertPath == "" && ctx.PodTokenPath == "" {
+		log.Errorf("when you enable only the authorized port, you should populate cert path or token path.")
 		return nil
 	}
 
-	s := strings.Split(ctx.PodClientCertPath, ",")
-	cert := strings.TrimSpace(s[0])
-	if len(s) == 1 {
-		ctx.podClientCertPath, ctx.podClientCertKey = cert, cert
-	} else if len(s) >= 2 {
-		ctx.podClientCertPath, ctx.podClientCertKey = cert, strings.TrimSpace(s[1])
+	if ctx.PodClientCertPath != "" {
+		s := strings.Split(ctx.PodClientCertPath, ",")
+		cert := strings.TrimSpace(s[0])
+		if len(s) == 1 {
+			ctx.podClientCertPath, ctx.podClientCertKey = cert, cert
+		} else if len(s) >= 2 {
+			ctx.podClientCertPath, ctx.podClientCertKey = cert, strings.TrimSpace(s[1])
+		}
 	}
 
 	err := kubeletPodListPortCacheUpdate(ctx)
-	if !errors.Is(err, syscall.ECONNREFUSED) {
-		// success or other error codes except connect refused
+	if err == nil {
 		// only init css metadata collect when kubelet available.
-		if err == nil {
-			_ = kubeletConfigCacheUpdate(ctx)
-			return containerCgroupCssInit()
-		}
+		return kubeletContainersStart(ctx)
+	}
 
+	switch {
+	case ctx.Mode == ModeAuto:
+		log.Warnf("kubelet is unreachable, the containers are resolved in standalone mode until it is: %v", err)
+		standaloneStart(ctx)
+		setManagerState(ManagerStateStandalone)
+	case !errors.Is(err, syscall.ECONNREFUSED):
 		return err
+	default:
+		setManagerState(ManagerStateHostOnly)
 	}
 
-	// syscall.ECONNREFUSED:
+	// kubelet unreachable:
 	// I hope k8s will be available in the future. :)
 	doneCtx, cancel := context.WithCancel(context.Background())
 
@@ -190,8 +304,10 @@ func InitManager(ctx *ManagerCtx) error {
 			case <-t.C:
 				if err := kubeletPodListPortCacheUpdate(ctx); err == nil {
 					log.Infof("kubelet is running now")
-					_ = kubeletConfigCacheUpdate(ctx)
-					_ = containerCgroupCssInit()
+					standaloneStop()
+					if err := kubeletContainersStart(ctx); err != nil {
+						log.Errorf("kubelet containers: %v", err)
+					}
 					t.Stop()
 					return
 				}
@@ -214,8 +330,13 @@ func ReleaseManager() {
 		kubeletDoneCancel()
 		kubeletDoneCancel = nil
 	}
+	kubeletDetectStop()
 
+	containerWatchRelease()
 	containerCgroupCssRelease()
+	resolversRelease()
+	standaloneResolvers = nil
+	setManagerState(ManagerStateDisabled)
 }
 
 func kubeletSyncContainers() error {
@@ -225,6 +346,8 @@ func kubeletSyncContainers() error {
 		return nil
 	}
 
+	notifyEvictedContainers(&podList)
+
 	type containerInfo struct {
 		container       *corev1.Container
 		containerStatus *corev1.ContainerStatus
@@ -236,7 +359,7 @@ func kubeletSyncContainers() error {
 	for i := range podList.Items {
 		pod := &podList.Items[i]
 
-		if !isRuningPod(pod) {
+		if !isRuningPod(pod) || !podTracked(pod.Namespace, pod.Name) {
 			continue
 		}
 
@@ -269,14 +392,25 @@ func kubeletSyncContainers() error {
 		}
 	}
 
-	for k := range containers {
+	now := time.Now()
+	for k, c := range containers {
+		// the workloads of the resolvers are synced on their own.
+		if c.Resolver != "" {
+			continue
+		}
+
 		// clear old containers which do not exist in newContainers.
 		if _, ok := newContainers[k]; !ok {
-			delete(containers, k)
+			// kubelet reports the containers started after its relist.
+			if now.Sub(c.eventRegisteredAt) < criEventsGrace {
+				continue
+			}
+			buryContainer(k, now)
 			continue
 		}
 
 		// skip the existing containers
+		c.eventRegisteredAt = time.Time{}
 		delete(newContainers, k)
 	}
 
@@ -292,6 +426,9 @@ func kubeletSyncContainers() error {
 		}
 	}
 
+	// after the updates, so the events find the containers created.
+	notifyContainerLifecycle(&podList, now)
+	pruneUTSHostnames()
 	return nil
 }
 
@@ -385,6 +522,15 @@ func kubeletUpdateContainer(containerID string, container *corev1.Container, con
 		return fmt.Errorf("failed to get InitPid: %w", err)
 	}
 
+	// the hostname the container sees, the pod spec may not tell it, e.g.
+	// of the pods in the host UTS namespace.
+	utsInode := uint64(0)
+	if utsHostname, inode, err := containerUTSHostname(initPid); err == nil {
+		hostname, utsInode = utsHostname, inode
+	} else {
+		log.Debugf("failed to get the uts hostname of container %s: %v", containerID, err)
+	}
+
 	// net namespace
 	nsInode, err := netutil.NetNSInodeByPid(initPid)
 	if err != nil {
@@ -433,6 +579,9 @@ func kubeletUpdateContainer(containerID string, container *corev1.Container, con
 		SyncedAt:           time.Now(),
 		lifeResources:      make(map[string]any),
 		Labels:             labels,
+		Thresholds:         parseContainerThresholds(pod),
+		PodMetadata:        parseContainerPodMetadata(pod),
+		utsNamespaceInode:  utsInode,
 	}
 
 	// create container life resources
@@ -447,6 +596,7 @@ func parseContainerIDInPodStatus(data string) (string, error) {
 	//
 	// "containerID": "docker://06ae8891e7e9b80f353e07116980f93a357fb3f239c09894de73b2e74121c94f",
 	// "containerID": "containerd://0ac95a0f051b5094551a02b584414773dc24f5b2f1e4ea768460a787f762e279"
+	// "containerID": "cri-o://5d1c1b1b5b0f7a0c1d3e3f4a5b6c7d8e9f0a1b2c3d4e5f6a7b8c9d0e1f2a3b4c"
 	parts := strings.Split(strings.Trim(data, "\""), "://")
 	if len(parts) != 2 {
 		return "", fmt.Errorf("invalid container id: %s", data)
@@ -509,8 +659,11 @@ func kubeletConfigFileDefault() (kubeletConfiguration, error) {
 
 // kubeletConfigCacheUpdate try to update the cache var:
 //
-// CgroupDriver
+// CgroupDriver, unless set in the ManagerCtx
 // ContainerRuntimeEndpoint
+//
+// The cgroup driver is detected from the cgroups when there is no kubelet
+// config, it fails when it cannot be.
 func kubeletConfigCacheUpdate(ctx *ManagerCtx) error {
 	var (
 		config kubeletConfiguration
@@ -518,7 +671,7 @@ func kubeletConfigCacheUpdate(ctx *ManagerCtx) error {
 	)
 
 	defer func() {
-		if config.CgroupDriver != "" {
+		if config.CgroupDriver != "" && ctx.CgroupDriver == "" {
 			kubeletPodCgroupDriver = config.CgroupDriver
 		}
 		if config.ContainerRuntimeEndpoint != "" {
@@ -537,12 +690,18 @@ func kubeletConfigCacheUpdate(ctx *ManagerCtx) error {
 	log.Debugf("kubelet config port is not available, try to read config files: %v", kubeletDefaultConfigPath)
 
 	config, err = kubeletConfigFileDefault()
-	if err != nil {
-		panic(fmt.Sprintf(
-			"we cannot find any cgroup driver of kubelet after requesting configz and default files: %v",
-			err,
-		))
+	if err == nil || ctx.CgroupDriver != "" {
+		return nil
+	}
+
+	// e.g. the managed nodes blocking configz.
+	config.CgroupDriver = detectCgroupDriver()
+	if config.CgroupDriver == "" {
+		return fmt.Errorf("cannot find the cgroup driver of kubelet in configz, %v or the cgroups, set Pod.CgroupDriver: %w",
+			kubeletDefaultConfigPath, err)
 	}
 
+	log.Warnf("no kubelet config in configz and %v, cgroup driver %q detected from the cgroups",
+		kubeletDefaultConfigPath, config.CgroupDriver)
 	return nil
 }
@@ -15,14 +15,18 @@
 package pod
 
 import (
+	"encoding/pem"
 	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 	"testing/iotest"
+	"time"
 )
 
 // TestHTTPDoRequestPropagatesBodyReadError reproduces issue #258: when a kubelet
@@ -105,3 +109,99 @@ func (t bodyReadErrorTransport) RoundTrip(_ *http.Request) (*http.Response, erro
 		)),
 	}, nil
 }
+
+func TestKubeletConfigCacheUpdateCgroupDriver(t *testing.T) {
+	savedPaths, savedClient, savedDriver := kubeletDefaultConfigPath, kubeletPodListClient, kubeletPodCgroupDriver
+	t.Cleanup(func() {
+		kubeletDefaultConfigPath, kubeletPodListClient, kubeletPodCgroupDriver = savedPaths, savedClient, savedDriver
+	})
+
+	// configz is blocked and there is no config file.
+	kubeletDefaultConfigPath = []string{t.TempDir() + "/config.yaml"}
+	kubeletPodListClient = &http.Client{Timeout: time.Second}
+
+	tests := []struct {
+		name     string
+		dir      string
+		override string
+		want     string
+		wantErr  bool
+	}{
+		{name: "systemd detected", dir: "kubepods.slice", want: "systemd"},
+		{name: "cgroupfs detected", dir: "kubepods", want: "cgroupfs"},
+		{name: "override", dir: "kubepods.slice", override: "cgroupfs", want: "cgroupfs"},
+		{name: "override without pods", dir: "system.slice", override: "systemd", want: "systemd"},
+		{name: "not found", dir: "system.slice", want: "cgroupfs", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setupResolverRoot(t, []string{tt.dir})
+			kubeletPodCgroupDriver = "cgroupfs"
+			if tt.override != "" {
+				kubeletPodCgroupDriver = tt.override
+			}
+
+			err := kubeletConfigCacheUpdate(&ManagerCtx{PodAuthorizedPort: 1, CgroupDriver: tt.override})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("kubeletConfigCacheUpdate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil && !strings.Contains(err.Error(), "Pod.CgroupDriver") {
+				t.Errorf("kubeletConfigCacheUpdate() error = %v, want a hint to set Pod.CgroupDriver", err)
+			}
+			if kubeletPodCgroupDriver != tt.want {
+				t.Errorf("cgroup driver = %q, want %q", kubeletPodCgroupDriver, tt.want)
+			}
+		})
+	}
+}
+
+func TestKubeletAuthorizedClient(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer token-2" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		_, _ = w.Write([]byte(`{"items": []}`))
+	}))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	caPath := filepath.Join(dir, "ca.crt")
+	tokenPath := filepath.Join(dir, "token")
+	ca := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: srv.Certificate().Raw})
+	if err := os.WriteFile(caPath, ca, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(tokenPath, []byte("token-1\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	// the certificate of httptest is for example.com.
+	client, err := kubeletAuthorizedClient(&ManagerCtx{PodTokenPath: tokenPath, PodCAPath: caPath, PodServerName: "example.com"})
+	if err != nil {
+		t.Fatalf("kubeletAuthorizedClient() error = %v", err)
+	}
+	if _, err := kubeletPodListDoRequest(client, srv.URL); err == nil || !strings.Contains(err.Error(), "401") {
+		t.Errorf("kubeletPodListDoRequest() error = %v, want unauthorized by the old token", err)
+	}
+
+	// the rotated token is read by the next request.
+	if err := os.WriteFile(tokenPath, []byte("token-2\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := kubeletPodListDoRequest(client, srv.URL); err != nil {
+		t.Errorf("kubeletPodListDoRequest() error = %v", err)
+	}
+
+	client, err = kubeletAuthorizedClient(&ManagerCtx{PodTokenPath: tokenPath, PodCAPath: caPath, PodServerName: "kubelet.invalid"})
+	if err != nil {
+		t.Fatalf("kubeletAuthorizedClient() error = %v", err)
+	}
+	if _, err := kubeletPodListDoRequest(client, srv.URL); err == nil {
+		t.Error("kubeletPodListDoRequest() succeeded, want the certificate not valid for the server name")
+	}
+
+	if _, err := kubeletAuthorizedClient(&ManagerCtx{PodCAPath: tokenPath}); err == nil {
+		t.Error("kubeletAuthorizedClient() succeeded with a CA without certificate")
+	}
+}
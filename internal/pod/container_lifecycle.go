@@ -0,0 +1,216 @@
+// Copyright 2026 The HuaTuo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pod
+
+import (
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// The container lifecycle events.
+const (
+	ContainerCreated   = "created"
+	ContainerStarted   = "started"
+	ContainerOOMKilled = "oom_killed"
+	ContainerExited    = "exited"
+)
+
+const (
+	// containerReasonOOMKilled is the termination reason of the containers
+	// killed by the oom killer.
+	containerReasonOOMKilled = "OOMKilled"
+	// containerReasonRemoved is the reason of the containers gone from the
+	// pods without their termination seen, e.g. of a pod deleted.
+	containerReasonRemoved = "Removed"
+)
+
+// ContainerLifecycle is a state transition of a container, derived from the
+// pod status of kubelet.
+type ContainerLifecycle struct {
+	Event         string
+	ContainerID   string
+	ContainerName string
+	PodName       string
+	Namespace     string
+	// Time is when the transition happened, the sync noticing it if kubelet
+	// does not tell.
+	Time time.Time
+	// ExitCode, Reason and Duration, the time the container ran, are of
+	// the exits. ExitCode is nil when unknown.
+	ExitCode     *int32
+	Reason       string
+	RestartCount int32
+	Duration     time.Duration
+}
+
+type containerLifecycleState struct {
+	base      ContainerLifecycle
+	startedAt time.Time
+	started   bool
+	exited    bool
+}
+
+var (
+	lifecycleHandlersLock sync.RWMutex
+	lifecycleHandlers     []func(*ContainerLifecycle)
+	// lifecycleStates are the containers of the last sync by id, nil until
+	// the first sync, whose containers are not reported.
+	lifecycleStates map[string]*containerLifecycleState
+)
+
+// RegisterLifecycleHandler calls fn for every lifecycle transition of the
+// containers of the tracked pods, synced from kubelet. fn is called while
+// the containers are synced, it must not block nor call back into this
+// package.
+func RegisterLifecycleHandler(fn func(*ContainerLifecycle)) {
+	lifecycleHandlersLock.Lock()
+	defer lifecycleHandlersLock.Unlock()
+
+	lifecycleHandlers = append(lifecycleHandlers, fn)
+}
+
+// notifyContainerLifecycle reports the transitions of the containers since
+// the last sync. The pods of every phase count, a container crash looping
+// keeps its pod out of the running ones.
+func notifyContainerLifecycle(podList *corev1.PodList, now time.Time) {
+	lifecycleHandlersLock.RLock()
+	defer lifecycleHandlersLock.RUnlock()
+
+	if len(lifecycleHandlers) == 0 {
+		lifecycleStates = nil
+		return
+	}
+
+	seeding := lifecycleStates == nil
+	notify := func(e ContainerLifecycle) {
+		if seeding {
+			return
+		}
+		for _, fn := range lifecycleHandlers {
+			fn(&e)
+		}
+	}
+
+	states := make(map[string]*containerLifecycleState)
+	stateOf := func(id string, base ContainerLifecycle) (*containerLifecycleState, bool) {
+		if state, ok := states[id]; ok {
+			return state, true
+		}
+		state, ok := lifecycleStates[id]
+		if !ok {
+			base.ContainerID = id
+			state = &containerLifecycleState{base: base}
+		}
+		states[id] = state
+		return state, ok
+	}
+
+	for i := range podList.Items {
+		pod := &podList.Items[i]
+		if !podTracked(pod.Namespace, pod.Name) {
+			continue
+		}
+
+		for _, status := range slices.Concat(pod.Status.InitContainerStatuses, pod.Status.ContainerStatuses) {
+			base := ContainerLifecycle{
+				ContainerName: status.Name,
+				PodName:       pod.Name,
+				Namespace:     pod.Namespace,
+				RestartCount:  status.RestartCount,
+			}
+
+			// the previous container of a restarted one.
+			if terminated := status.LastTerminationState.Terminated; terminated != nil {
+				if id := lifecycleContainerID(terminated.ContainerID); id != "" {
+					state, _ := stateOf(id, base)
+					state.terminated(terminated, base.RestartCount, notify)
+				}
+			}
+
+			id := lifecycleContainerID(status.ContainerID)
+			if id == "" {
+				continue
+			}
+
+			state, known := stateOf(id, base)
+			state.base.RestartCount = status.RestartCount
+			if !known {
+				notify(state.event(ContainerCreated, now))
+			}
+			if running := status.State.Running; running != nil && !state.started {
+				state.started, state.startedAt = true, running.StartedAt.Time
+				notify(state.event(ContainerStarted, running.StartedAt.Time))
+			}
+			if terminated := status.State.Terminated; terminated != nil {
+				state.terminated(terminated, status.RestartCount, notify)
+			}
+		}
+	}
+
+	for id, state := range lifecycleStates {
+		if _, ok := states[id]; ok || !state.started || state.exited {
+			continue
+		}
+
+		e := state.event(ContainerExited, now)
+		e.Reason = containerReasonRemoved
+		if !state.startedAt.IsZero() {
+			e.Duration = now.Sub(state.startedAt)
+		}
+		notify(e)
+	}
+
+	lifecycleStates = states
+}
+
+func (s *containerLifecycleState) event(event string, at time.Time) ContainerLifecycle {
+	e := s.base
+	e.Event, e.Time = event, at
+	return e
+}
+
+// terminated reports the exit of the container, once.
+func (s *containerLifecycleState) terminated(terminated *corev1.ContainerStateTerminated, restartCount int32, notify func(ContainerLifecycle)) {
+	if s.exited {
+		return
+	}
+	s.started, s.exited = true, true
+
+	event := ContainerExited
+	if terminated.Reason == containerReasonOOMKilled {
+		event = ContainerOOMKilled
+	}
+
+	e := s.event(event, terminated.FinishedAt.Time)
+	exitCode := terminated.ExitCode
+	e.ExitCode = &exitCode
+	e.Reason = terminated.Reason
+	e.RestartCount = restartCount
+	if !terminated.StartedAt.IsZero() && !terminated.FinishedAt.IsZero() {
+		e.Duration = terminated.FinishedAt.Sub(terminated.StartedAt.Time)
+	}
+	notify(e)
+}
+
+// lifecycleContainerID returns the id of the "<runtime>://<id>" container
+// id of the pod status, empty if not created yet.
+func lifecycleContainerID(data string) string {
+	_, id, _ := strings.Cut(data, "://")
+	return id
+}
@@ -0,0 +1,126 @@
+// Copyright 2026 The HuaTuo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pod
+
+import (
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestNotifyContainerLifecycle(t *testing.T) {
+	origHandlers, origStates := lifecycleHandlers, lifecycleStates
+	t.Cleanup(func() { lifecycleHandlers, lifecycleStates = origHandlers, origStates })
+	lifecycleHandlers, lifecycleStates = nil, nil
+
+	var got []*ContainerLifecycle
+	RegisterLifecycleHandler(func(e *ContainerLifecycle) { got = append(got, e) })
+
+	start := time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC)
+	newPod := func(statuses ...corev1.ContainerStatus) *corev1.PodList {
+		return &corev1.PodList{Items: []corev1.Pod{{
+			ObjectMeta: metav1.ObjectMeta{Name: "web-0", Namespace: "default"},
+			Status:     corev1.PodStatus{Phase: corev1.PodRunning, ContainerStatuses: statuses},
+		}}}
+	}
+	running := func(id string, restarts int32, startedAt time.Time) corev1.ContainerStatus {
+		return corev1.ContainerStatus{
+			Name:         "app",
+			ContainerID:  "containerd://" + id,
+			RestartCount: restarts,
+			State:        corev1.ContainerState{Running: &corev1.ContainerStateRunning{StartedAt: metav1.NewTime(startedAt)}},
+		}
+	}
+	events := func() []string {
+		var names []string
+		for _, e := range got {
+			names = append(names, e.ContainerID+":"+e.Event)
+		}
+		got = nil
+		return names
+	}
+	expect := func(step string, want ...string) {
+		t.Helper()
+		names := events()
+		if len(names) != len(want) {
+			t.Fatalf("%s: events = %v, want %v", step, names, want)
+		}
+		for i := range want {
+			if names[i] != want[i] {
+				t.Fatalf("%s: events = %v, want %v", step, names, want)
+			}
+		}
+	}
+
+	// the containers of the first sync are not reported.
+	notifyContainerLifecycle(newPod(running("aaaa", 0, start)), start)
+	expect("seed")
+
+	notifyContainerLifecycle(newPod(running("aaaa", 0, start)), start.Add(time.Minute))
+	expect("unchanged")
+
+	// aaaa is oom killed and restarted as bbbb.
+	restarted := running("bbbb", 1, start.Add(3*time.Minute))
+	restarted.LastTerminationState.Terminated = &corev1.ContainerStateTerminated{
+		ContainerID: "containerd://aaaa",
+		ExitCode:    137,
+		Reason:      "OOMKilled",
+		StartedAt:   metav1.NewTime(start),
+		FinishedAt:  metav1.NewTime(start.Add(2 * time.Minute)),
+	}
+	notifyContainerLifecycle(newPod(restarted), start.Add(3*time.Minute))
+	oom := got[0]
+	expect("restart", "aaaa:oom_killed", "bbbb:created", "bbbb:started")
+	if oom.ExitCode == nil || *oom.ExitCode != 137 || oom.Duration != 2*time.Minute ||
+		oom.RestartCount != 1 || oom.PodName != "web-0" || oom.ContainerName != "app" {
+		t.Errorf("oom killed = %+v", oom)
+	}
+
+	// the last termination is reported once.
+	notifyContainerLifecycle(newPod(restarted), start.Add(4*time.Minute))
+	expect("restarted")
+
+	// bbbb exits, crash looping.
+	exited := corev1.ContainerStatus{
+		Name:         "app",
+		ContainerID:  "containerd://bbbb",
+		RestartCount: 1,
+		State: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{
+			ContainerID: "containerd://bbbb",
+			ExitCode:    1,
+			Reason:      "Error",
+			StartedAt:   metav1.NewTime(start.Add(3 * time.Minute)),
+			FinishedAt:  metav1.NewTime(start.Add(5 * time.Minute)),
+		}},
+	}
+	notifyContainerLifecycle(newPod(exited), start.Add(5*time.Minute))
+	exit := got[0]
+	expect("exit", "bbbb:exited")
+	if exit.ExitCode == nil || *exit.ExitCode != 1 || exit.Reason != "Error" || exit.Duration != 2*time.Minute {
+		t.Errorf("exited = %+v", exit)
+	}
+
+	// cccc is gone with its pod, its exit unknown.
+	notifyContainerLifecycle(newPod(running("cccc", 0, start.Add(6*time.Minute))), start.Add(6*time.Minute))
+	expect("new", "cccc:created", "cccc:started")
+	notifyContainerLifecycle(&corev1.PodList{}, start.Add(8*time.Minute))
+	removed := got[0]
+	expect("removed", "cccc:exited")
+	if removed.ExitCode != nil || removed.Reason != containerReasonRemoved || removed.Duration != 2*time.Minute {
+		t.Errorf("removed = %+v", removed)
+	}
+}
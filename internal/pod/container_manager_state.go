@@ -0,0 +1,197 @@
+// Copyright 2026 The HuaTuo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pod
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"huatuo-bamai/internal/log"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// The states of the pod manager.
+const (
+	// ManagerStateDisabled syncs no containers from kubelet.
+	ManagerStateDisabled = "disabled"
+	// ManagerStateStandalone resolves the containers from the runtimes.
+	ManagerStateStandalone = "standalone"
+	// ManagerStateHostOnly has no containers until kubelet and its cgroup
+	// driver are found, the host metrics and events go on.
+	ManagerStateHostOnly = "host_only"
+	// ManagerStateReady syncs the containers from kubelet.
+	ManagerStateReady = "ready"
+)
+
+var managerStates = []string{
+	ManagerStateDisabled,
+	ManagerStateStandalone,
+	ManagerStateHostOnly,
+	ManagerStateReady,
+}
+
+var managerState atomic.Value
+
+func init() {
+	managerState.Store(ManagerStateDisabled)
+}
+
+// ManagerState returns the state of the pod manager.
+func ManagerState() string {
+	return managerState.Load().(string)
+}
+
+func setManagerState(state string) {
+	if prev := managerState.Swap(state); prev != state {
+		log.Infof("pod manager state %s -> %s", prev, state)
+	}
+}
+
+// The detection of kubelet is retried with a backoff doubling from min to
+// max, vars for the tests.
+var (
+	kubeletDetectBackoffMin = 5 * time.Second
+	kubeletDetectBackoffMax = 5 * time.Minute
+)
+
+// kubeletDetectLock guards kubeletDetectCancel, the detection being
+// started again by the ticker of InitManager while ReleaseManager stops it.
+var (
+	kubeletDetectLock   sync.Mutex
+	kubeletDetectCancel context.CancelFunc
+	kubeletDetectWG     sync.WaitGroup
+)
+
+// kubeletContainersStart turns the containers of kubelet on. Until the
+// cgroup driver of kubelet is found, the manager is host only and the
+// detection retried in the background.
+func kubeletContainersStart(ctx *ManagerCtx) error {
+	err := kubeletConfigCacheUpdate(ctx)
+	if err == nil {
+		return kubeletContainersEnable()
+	}
+
+	log.Warnf("pod manager is host only until kubelet is detected, retrying: %v", err)
+	// the cgroups of the containers need the cgroup driver of kubelet.
+	kubeletPodListEnable(false)
+	setManagerState(ManagerStateHostOnly)
+	kubeletDetectStart(func() error {
+		if err := kubeletConfigCacheUpdate(ctx); err != nil {
+			return err
+		}
+		if err := kubeletContainersEnable(); err != nil {
+			log.Errorf("kubelet detected, but the containers cannot be enabled: %v", err)
+		}
+		return nil
+	})
+	return nil
+}
+
+// kubeletContainersEnable syncs the containers of kubelet, its cgroup
+// driver known.
+func kubeletContainersEnable() error {
+	if err := containerCgroupCssInit(); err != nil {
+		return err
+	}
+	containerWatchInit()
+	kubeletPodListEnable(true)
+	setManagerState(ManagerStateReady)
+	return nil
+}
+
+// kubeletPodListEnable turns the sync of the containers from kubelet on or
+// off, the detection flipping it while the containers are synced.
+func kubeletPodListEnable(enabled bool) {
+	containersMapLock.Lock()
+	defer containersMapLock.Unlock()
+
+	kubeletPodListRunningEnabled = enabled
+}
+
+// kubeletDetectStart calls detect in the background until it succeeds or
+// kubeletDetectStop is called. A detection still running is cancelled.
+func kubeletDetectStart(detect func() error) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	kubeletDetectLock.Lock()
+	if kubeletDetectCancel != nil {
+		kubeletDetectCancel()
+	}
+	kubeletDetectCancel = cancel
+	kubeletDetectWG.Add(1)
+	kubeletDetectLock.Unlock()
+
+	go func() {
+		defer kubeletDetectWG.Done()
+
+		backoff := kubeletDetectBackoffMin
+		for {
+			timer := time.NewTimer(backoff)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return
+			case <-timer.C:
+			}
+
+			err := detect()
+			if err == nil {
+				return
+			}
+			backoff = min(2*backoff, kubeletDetectBackoffMax)
+			log.Debugf("kubelet detection failed, retry in %s: %v", backoff, err)
+		}
+	}()
+}
+
+// kubeletDetectStop stops the detection and waits for it.
+func kubeletDetectStop() {
+	kubeletDetectLock.Lock()
+	if kubeletDetectCancel != nil {
+		kubeletDetectCancel()
+		kubeletDetectCancel = nil
+	}
+	kubeletDetectLock.Unlock()
+
+	kubeletDetectWG.Wait()
+}
+
+var managerStateDesc = prometheus.NewDesc("huatuo_pod_mgr_state",
+	"State of the pod manager, 1 for the current one.", []string{"state"}, nil)
+
+type managerStateCollector struct{}
+
+// RegisterMetrics registers huatuo_pod_mgr_state.
+func RegisterMetrics(reg prometheus.Registerer) {
+	reg.MustRegister(managerStateCollector{})
+}
+
+func (managerStateCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- managerStateDesc
+}
+
+func (managerStateCollector) Collect(ch chan<- prometheus.Metric) {
+	current := ManagerState()
+	for _, state := range managerStates {
+		value := 0.0
+		if state == current {
+			value = 1
+		}
+		ch <- prometheus.MustNewConstMetric(managerStateDesc, prometheus.GaugeValue, value, state)
+	}
+}
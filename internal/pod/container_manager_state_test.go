@@ -0,0 +1,159 @@
+// Copyright 2026 The HuaTuo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pod
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func TestKubeletDetectStart(t *testing.T) {
+	origMin, origMax := kubeletDetectBackoffMin, kubeletDetectBackoffMax
+	t.Cleanup(func() { kubeletDetectBackoffMin, kubeletDetectBackoffMax = origMin, origMax })
+	kubeletDetectBackoffMin, kubeletDetectBackoffMax = time.Millisecond, 4*time.Millisecond
+
+	calls := 0
+	done := make(chan struct{})
+	kubeletDetectStart(func() error {
+		calls++
+		if calls < 3 {
+			return errors.New("no cgroup driver")
+		}
+		close(done)
+		return nil
+	})
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("detection did not succeed")
+	}
+	kubeletDetectStop()
+	if calls != 3 {
+		t.Errorf("detect calls = %d, want 3", calls)
+	}
+
+	// the detection failing forever is stopped.
+	kubeletDetectStart(func() error { return errors.New("no cgroup driver") })
+	stopped := make(chan struct{})
+	go func() {
+		kubeletDetectStop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-time.After(5 * time.Second):
+		t.Fatal("kubeletDetectStop() did not return")
+	}
+}
+
+func TestKubeletDetectRestart(t *testing.T) {
+	origMin, origMax := kubeletDetectBackoffMin, kubeletDetectBackoffMax
+	t.Cleanup(func() { kubeletDetectBackoffMin, kubeletDetectBackoffMax = origMin, origMax })
+	kubeletDetectBackoffMin, kubeletDetectBackoffMax = time.Millisecond, time.Millisecond
+
+	var first, second atomic.Int64
+	kubeletDetectStart(func() error {
+		first.Add(1)
+		return errors.New("no cgroup driver")
+	})
+	kubeletDetectStart(func() error {
+		second.Add(1)
+		return errors.New("no cgroup driver")
+	})
+	t.Cleanup(kubeletDetectStop)
+
+	// the first detection is cancelled by the second one.
+	time.Sleep(20 * time.Millisecond)
+	calls := first.Load()
+	time.Sleep(20 * time.Millisecond)
+	if first.Load() != calls {
+		t.Errorf("first detection still running, calls %d -> %d", calls, first.Load())
+	}
+	if second.Load() == 0 {
+		t.Error("second detection not running")
+	}
+}
+
+func TestKubeletContainersStartHostOnly(t *testing.T) {
+	srv := httptest.NewTLSServer(http.NotFoundHandler())
+	t.Cleanup(srv.Close)
+	u, err := url.Parse(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	port, err := strconv.Atoi(u.Port())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	origEnabled, origClient := kubeletPodListRunningEnabled, kubeletPodListClient
+	origPaths, origRoot := kubeletDefaultConfigPath, resolverCgroupRoot
+	origMin := kubeletDetectBackoffMin
+	t.Cleanup(func() {
+		kubeletDetectStop()
+		kubeletPodListRunningEnabled, kubeletPodListClient = origEnabled, origClient
+		kubeletDefaultConfigPath, resolverCgroupRoot = origPaths, origRoot
+		kubeletDetectBackoffMin = origMin
+		setManagerState(ManagerStateDisabled)
+	})
+	root := t.TempDir()
+	kubeletPodListRunningEnabled, kubeletPodListClient = true, srv.Client()
+	kubeletDefaultConfigPath = []string{filepath.Join(root, "config.yaml")}
+	resolverCgroupRoot = func() string { return root }
+	kubeletDetectBackoffMin = time.Hour
+
+	// no configz, config file nor pod cgroups.
+	if err := kubeletContainersStart(&ManagerCtx{PodAuthorizedPort: uint32(port)}); err != nil {
+		t.Fatalf("kubeletContainersStart() error = %v, want host only", err)
+	}
+	if state := ManagerState(); state != ManagerStateHostOnly {
+		t.Errorf("ManagerState() = %s, want %s", state, ManagerStateHostOnly)
+	}
+	if kubeletPodListRunningEnabled {
+		t.Error("containers synced from kubelet in host only mode")
+	}
+
+	reg := prometheus.NewRegistry()
+	RegisterMetrics(reg)
+	families, err := reg.Gather()
+	if err != nil {
+		t.Fatalf("Gather() error = %v", err)
+	}
+	states := make(map[string]float64)
+	for _, mf := range families {
+		for _, m := range mf.GetMetric() {
+			states[m.GetLabel()[0].GetValue()] = m.GetGauge().GetValue()
+		}
+	}
+	for _, state := range managerStates {
+		want := 0.0
+		if state == ManagerStateHostOnly {
+			want = 1
+		}
+		if states[state] != want {
+			t.Errorf("huatuo_pod_mgr_state{state=%q} = %v, want %v", state, states[state], want)
+		}
+	}
+}
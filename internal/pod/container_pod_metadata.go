@@ -0,0 +1,119 @@
+// Copyright 2026 The HuaTuo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pod
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// The pod labels and annotations are named pod_label_<key> and
+// pod_annotation_<key>, the key sanitized as a prometheus label name,
+// e.g. pod_label_app_kubernetes_io_name.
+const (
+	podMetadataLabelPrefix      = "pod_label_"
+	podMetadataAnnotationPrefix = "pod_annotation_"
+)
+
+type podMetadataKey struct {
+	name       string
+	key        string
+	annotation bool
+}
+
+// podMetadataKeys are the pod labels and annotations of the containers,
+// sorted by name.
+var podMetadataKeys []podMetadataKey
+
+// SetPodMetadata sets the pod labels and annotations attached to the
+// containers, as metric labels and tracer document fields. It is called
+// before the containers are synced.
+func SetPodMetadata(labels, annotations []string) error {
+	var keys []podMetadataKey
+
+	add := func(prefix, key string, annotation bool) error {
+		key = strings.TrimSpace(key)
+		if key == "" {
+			return fmt.Errorf("empty pod metadata key")
+		}
+
+		name := prefix + podMetadataSanitize(key)
+		for _, k := range keys {
+			if k.name == name {
+				return fmt.Errorf("pod metadata %q and %q are both named %s", k.key, key, name)
+			}
+		}
+		keys = append(keys, podMetadataKey{name: name, key: key, annotation: annotation})
+		return nil
+	}
+
+	for _, key := range labels {
+		if err := add(podMetadataLabelPrefix, key, false); err != nil {
+			return err
+		}
+	}
+	for _, key := range annotations {
+		if err := add(podMetadataAnnotationPrefix, key, true); err != nil {
+			return err
+		}
+	}
+
+	slices.SortFunc(keys, func(a, b podMetadataKey) int { return strings.Compare(a.name, b.name) })
+	podMetadataKeys = keys
+	return nil
+}
+
+// PodMetadataNames returns the names of the pod labels and annotations of
+// the containers, sorted. Every container has them all, empty when its
+// pod has not, so the container metrics keep the same labels.
+func PodMetadataNames() []string {
+	names := make([]string, 0, len(podMetadataKeys))
+	for _, k := range podMetadataKeys {
+		names = append(names, k.name)
+	}
+	return names
+}
+
+// parseContainerPodMetadata returns the pod labels and annotations of the
+// pod by name, nil when none is configured.
+func parseContainerPodMetadata(pod *corev1.Pod) map[string]string {
+	if len(podMetadataKeys) == 0 {
+		return nil
+	}
+
+	metadata := make(map[string]string, len(podMetadataKeys))
+	for _, k := range podMetadataKeys {
+		if k.annotation {
+			metadata[k.name] = pod.Annotations[k.key]
+		} else {
+			metadata[k.name] = pod.Labels[k.key]
+		}
+	}
+	return metadata
+}
+
+// podMetadataSanitize returns the key as a prometheus label name suffix,
+// every character but [a-zA-Z0-9_] replaced by '_'.
+func podMetadataSanitize(key string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '_' || (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') {
+			return r
+		}
+		return '_'
+	}, key)
+}
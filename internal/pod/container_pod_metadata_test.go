@@ -0,0 +1,72 @@
+// Copyright 2026 The HuaTuo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pod
+
+import (
+	"reflect"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestParseContainerPodMetadata(t *testing.T) {
+	if err := SetPodMetadata([]string{"team", "app.kubernetes.io/name"}, []string{" owner "}); err != nil {
+		t.Fatalf("SetPodMetadata() error = %v", err)
+	}
+	t.Cleanup(func() { podMetadataKeys = nil })
+
+	wantNames := []string{"pod_annotation_owner", "pod_label_app_kubernetes_io_name", "pod_label_team"}
+	if got := PodMetadataNames(); !reflect.DeepEqual(got, wantNames) {
+		t.Errorf("PodMetadataNames() = %v, want %v", got, wantNames)
+	}
+
+	pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{
+		Labels:      map[string]string{"app.kubernetes.io/name": "web", "tier": "frontend"},
+		Annotations: map[string]string{"owner": "sre", "team": "ignored"},
+	}}
+	want := map[string]string{
+		"pod_annotation_owner":             "sre",
+		"pod_label_app_kubernetes_io_name": "web",
+		"pod_label_team":                   "",
+	}
+	if got := parseContainerPodMetadata(pod); !reflect.DeepEqual(got, want) {
+		t.Errorf("parseContainerPodMetadata() = %v, want %v", got, want)
+	}
+}
+
+func TestSetPodMetadataInvalid(t *testing.T) {
+	t.Cleanup(func() { podMetadataKeys = nil })
+
+	for _, tt := range []struct {
+		name        string
+		labels      []string
+		annotations []string
+	}{
+		{name: "empty key", labels: []string{" "}},
+		{name: "same name", labels: []string{"app.name", "app/name"}},
+	} {
+		if err := SetPodMetadata(tt.labels, tt.annotations); err == nil {
+			t.Errorf("%s: SetPodMetadata() error = nil, want error", tt.name)
+		}
+	}
+
+	if err := SetPodMetadata(nil, nil); err != nil {
+		t.Fatalf("SetPodMetadata() error = %v", err)
+	}
+	if got := parseContainerPodMetadata(&corev1.Pod{}); got != nil {
+		t.Errorf("parseContainerPodMetadata() without keys = %v, want nil", got)
+	}
+}
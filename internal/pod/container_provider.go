@@ -33,6 +33,7 @@ const (
 	containerProviderNoop       containerProvider = ""
 	containerProviderDocker     containerProvider = "docker"
 	containerProviderContainerd containerProvider = "containerd"
+	containerProviderCRIO       containerProvider = "cri-o"
 )
 
 var (
@@ -44,11 +45,11 @@ var (
 	dockerAPIVersion   string
 )
 
-// containerProviderFrom parses the runtime prefix from a container ID (e.g. "docker", "containerd").
+// containerProviderFrom parses the runtime prefix from a container ID (e.g. "docker", "containerd", "cri-o").
 func containerProviderFrom(s string) (containerProvider, error) {
 	p := containerProvider(s)
 	switch p {
-	case containerProviderDocker, containerProviderContainerd:
+	case containerProviderDocker, containerProviderContainerd, containerProviderCRIO:
 		return p, nil
 	default:
 		return containerProviderNoop, fmt.Errorf("invalid container provider: %s", s)
@@ -74,6 +75,8 @@ func initContainerProviderEnv(provider containerProvider, apiVersion string) err
 		return initDockerProviderEnv(apiVersion)
 	case containerProviderContainerd:
 		return initContainerdProviderEnv()
+	case containerProviderCRIO:
+		return initCRIOProviderEnv()
 	default:
 		return fmt.Errorf("invalid container provider: %q", provider)
 	}
@@ -0,0 +1,103 @@
+// Copyright 2026 The HuaTuo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pod
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"time"
+
+	k8sremote "k8s.io/cri-client/pkg"
+)
+
+const (
+	defaultCRIOSocket = "/var/run/crio/crio.sock"
+	crioRuntimeName   = "cri-o"
+	crioReqTimeout    = 5 * time.Second
+)
+
+// crioSocket serves both the CRI gRPC API and the HTTP API of crio, the
+// latter has the init pid of the containers.
+var crioSocket = defaultCRIOSocket
+
+func initCRIOProviderEnv() error {
+	client, err := k8sremote.NewRemoteRuntimeService("unix://"+crioSocket, crioReqTimeout, nil, nil)
+	if err != nil {
+		return fmt.Errorf("create cri-o client: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), crioReqTimeout)
+	defer cancel()
+
+	version, err := client.Version(ctx, "")
+	if err != nil {
+		return fmt.Errorf("get cri-o version: %w", err)
+	}
+	if version.RuntimeName != crioRuntimeName {
+		return fmt.Errorf("runtime at %s is %q, not cri-o", crioSocket, version.RuntimeName)
+	}
+
+	currContainerProvider = containerProviderCRIO
+	return nil
+}
+
+// crioContainerInfo is the part of GET /containers/<id> of the crio HTTP
+// API used here.
+type crioContainerInfo struct {
+	Pid int `json:"pid"`
+}
+
+func crioGet(socket, uri string, v any) error {
+	client := &http.Client{
+		Timeout: crioReqTimeout,
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				var dialer net.Dialer
+				return dialer.DialContext(ctx, "unix", socket)
+			},
+		},
+	}
+	defer client.CloseIdleConnections()
+
+	resp, err := client.Get("http://crio" + uri)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("crio %s: %s: %s", uri, resp.Status, body)
+	}
+	return json.Unmarshal(body, v)
+}
+
+func containerInitPIDInCRIO(socket, containerID string) (int, error) {
+	var info crioContainerInfo
+	if err := crioGet(socket, "/containers/"+containerID, &info); err != nil {
+		return -1, fmt.Errorf("get cri-o container %q: %w", containerID, err)
+	}
+	if info.Pid <= 0 {
+		return -1, fmt.Errorf("cri-o container %q has no running init PID", containerID)
+	}
+	return info.Pid, nil
+}
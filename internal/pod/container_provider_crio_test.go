@@ -0,0 +1,75 @@
+// Copyright 2026 The HuaTuo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pod
+
+import (
+	"net"
+	"net/http"
+	"path/filepath"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+const crioTestContainerID = "bd23762346b2af6261d285e8c2bdf82f9abeb427338c086cca27da98fee4dfa5"
+
+func TestContainerInitPIDInCRIO(t *testing.T) {
+	socket := filepath.Join(t.TempDir(), "crio.sock")
+	listener, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/containers/"+crioTestContainerID, func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte(`{"name":"k8s_app","pid":4242}`))
+	})
+	server := &http.Server{Handler: mux}
+	go func() { _ = server.Serve(listener) }()
+	t.Cleanup(func() { _ = server.Close() })
+
+	pid, err := containerInitPIDInCRIO(socket, crioTestContainerID)
+	if err != nil || pid != 4242 {
+		t.Errorf("containerInitPIDInCRIO() = %d, %v, want 4242", pid, err)
+	}
+
+	if _, err := containerInitPIDInCRIO(socket, "0123456789ab"); err == nil {
+		t.Error("containerInitPIDInCRIO() of an unknown container succeeded")
+	}
+}
+
+func TestContainerCgroupPathCRIO(t *testing.T) {
+	savedProvider, savedDriver := currContainerProvider, kubeletPodCgroupDriver
+	currContainerProvider = containerProviderCRIO
+	t.Cleanup(func() { currContainerProvider, kubeletPodCgroupDriver = savedProvider, savedDriver })
+
+	pod := &corev1.Pod{}
+	pod.UID = "44e9d203-d0d2-4d44-a5da-702190080eb4"
+	pod.Status.QOSClass = corev1.PodQOSBurstable
+
+	kubeletPodCgroupDriver = "systemd"
+	got, err := containerCgroupSuffix(crioTestContainerID, pod)
+	want := "/kubepods.slice/kubepods-burstable.slice/kubepods-burstable-pod44e9d203_d0d2_4d44_a5da_702190080eb4.slice/crio-" + crioTestContainerID + ".scope"
+	if err != nil || got != want {
+		t.Errorf("systemd containerCgroupSuffix() = %q, %v, want %q", got, err, want)
+	}
+
+	kubeletPodCgroupDriver = "cgroupfs"
+	got, err = containerCgroupSuffix(crioTestContainerID, pod)
+	want = "/kubepods/burstable/pod44e9d203-d0d2-4d44-a5da-702190080eb4/crio-" + crioTestContainerID
+	if err != nil || got != want {
+		t.Errorf("cgroupfs containerCgroupSuffix() = %q, %v, want %q", got, err, want)
+	}
+}
@@ -0,0 +1,263 @@
+// Copyright 2026 The HuaTuo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pod
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"huatuo-bamai/internal/cgroups"
+	"huatuo-bamai/internal/cgroups/subsystem"
+	"huatuo-bamai/internal/log"
+	"huatuo-bamai/internal/procfs"
+	"huatuo-bamai/internal/utils/netutil"
+)
+
+// Workload is a unit of an orchestrator other than kubelet, e.g. a systemd
+// service or a yarn container, running in its own cgroup.
+type Workload struct {
+	// ID is the runtime ID of the workload, if any, it is derived from
+	// the CgroupPath otherwise.
+	ID string
+	// Name is the container name and hostname of the workload.
+	Name string
+	// CgroupPath is relative to the cgroup root, as Container.CgroupPath.
+	CgroupPath string
+	// Namespace is the HostNamespace label.
+	Namespace string
+	// Labels are added to the container labels.
+	Labels map[string]string
+}
+
+// Resolver resolves the workloads of the node to containers, so the
+// container metrics and events work beyond kubelet. The containers are
+// synced with the kubelet ones, a Resolver is called with the containers
+// lock held and must not call back into this package.
+type Resolver interface {
+	// Name identifies the resolver, it must be unique.
+	Name() string
+	// Resolve returns the workloads running on the node.
+	Resolve() ([]Workload, error)
+}
+
+var (
+	resolversLock sync.Mutex
+	resolvers     []Resolver
+
+	// resolverCgroupRoot is the hierarchy the workloads are listed in.
+	resolverCgroupRoot = defaultResolverCgroupRoot
+)
+
+// RegisterResolver adds a resolver, its workloads are containers from the
+// next sync on.
+func RegisterResolver(r Resolver) error {
+	resolversLock.Lock()
+	defer resolversLock.Unlock()
+
+	for _, registered := range resolvers {
+		if registered.Name() == r.Name() {
+			return fmt.Errorf("resolver %s already registered", r.Name())
+		}
+	}
+	resolvers = append(resolvers, r)
+	return nil
+}
+
+// unregisterResolvers removes the resolvers by name, their containers are
+// dropped at the next sync.
+func unregisterResolvers(names []string) {
+	resolversLock.Lock()
+	defer resolversLock.Unlock()
+
+	resolvers = slices.DeleteFunc(resolvers, func(r Resolver) bool {
+		return slices.Contains(names, r.Name())
+	})
+}
+
+func resolversRelease() {
+	resolversLock.Lock()
+	defer resolversLock.Unlock()
+
+	resolvers = nil
+}
+
+// defaultResolverCgroupRoot lists the cpu hierarchy on cgroup v1, the
+// workloads without one have no cpu metrics either.
+func defaultResolverCgroupRoot() string {
+	if cgroups.CgroupMode() == cgroups.Unified {
+		return cgroups.RootfsDefaultPath()
+	}
+	return cgroups.RootFsFilePath(subsystem.SubsystemCPU)
+}
+
+// workloadContainerID derives a stable container ID from the cgroup of the
+// workload, in the hex format of the runtime IDs.
+func workloadContainerID(resolver, cgroupPath string) string {
+	sum := sha256.Sum256([]byte(resolver + ":" + cgroupPath))
+	return hex.EncodeToString(sum[:])
+}
+
+// resolverSyncContainers syncs the containers of the resolvers, with the
+// containers lock held. The containers of a failing resolver are kept.
+func resolverSyncContainers() {
+	resolversLock.Lock()
+	registered := slices.Clone(resolvers)
+	resolversLock.Unlock()
+
+	synced := make(map[string]struct{})
+	for _, r := range registered {
+		workloads, err := r.Resolve()
+		if err != nil {
+			log.Infof("failed to resolve workloads by %s: %v", r.Name(), err)
+			for id, c := range containers {
+				if c.Resolver == r.Name() {
+					synced[id] = struct{}{}
+				}
+			}
+			continue
+		}
+
+		for i := range workloads {
+			workload := &workloads[i]
+			if !podTracked(workload.Namespace, workload.Name) {
+				continue
+			}
+
+			id := workload.ID
+			if id == "" {
+				id = workloadContainerID(r.Name(), workload.CgroupPath)
+			}
+
+			// the same workload, unless its init process is gone.
+			if c, ok := containers[id]; ok && pidExists(c.InitPid) {
+				synced[id] = struct{}{}
+				continue
+			}
+
+			c, err := resolverNewContainer(id, r.Name(), workload)
+			if err != nil {
+				log.Debugf("failed to update workload %s by %s: %v", workload.CgroupPath, r.Name(), err)
+				continue
+			}
+
+			containers[id] = c
+			synced[id] = struct{}{}
+			createContainerLifeResources(c)
+			log.Debugf("update workload container %#v", c)
+		}
+	}
+
+	now := time.Now()
+	for id, c := range containers {
+		if _, ok := synced[id]; c.Resolver != "" && !ok {
+			buryContainer(id, now)
+		}
+	}
+}
+
+func resolverNewContainer(id, resolver string, workload *Workload) (*Container, error) {
+	dir := filepath.Join(resolverCgroupRoot(), workload.CgroupPath)
+
+	initPid, err := cgroupInitPid(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	nsInode, err := netutil.NetNSInodeByPid(initPid)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get net namespace inode by pid: %w", err)
+	}
+
+	netCookie, err := netutil.NetNSCookieByPid(initPid)
+	if err != nil {
+		log.Debugf("failed to get net namespace cookie for pid %d: %v", initPid, err)
+	}
+
+	startedAt := time.Now()
+	if info, err := os.Stat(dir); err == nil {
+		startedAt = info.ModTime()
+	}
+
+	labels := make(map[string]any, len(workload.Labels)+1)
+	for k, v := range workload.Labels {
+		labels[k] = v
+	}
+	labels[labelHostNamespace] = workload.Namespace
+
+	return &Container{
+		ID:                 id,
+		Name:               workload.Name,
+		Hostname:           workload.Name,
+		Type:               ContainerTypeNormal,
+		Qos:                containerQosUnknown,
+		NetNamespaceInode:  nsInode,
+		NetNamespaceCookie: netCookie,
+		InitPid:            initPid,
+		CgroupPath:         workload.CgroupPath,
+		CgroupCss:          map[string]uint64{},
+		StartedAt:          startedAt,
+		SyncedAt:           time.Now(),
+		Labels:             labels,
+		Resolver:           resolver,
+		lifeResources:      make(map[string]any),
+	}, nil
+}
+
+// cgroupInitPid returns the lowest pid of the cgroup, the first one started
+// in most cases.
+func cgroupInitPid(dir string) (int, error) {
+	f, err := os.Open(filepath.Join(dir, "cgroup.procs"))
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	initPid := 0
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		pid, err := strconv.Atoi(strings.TrimSpace(scanner.Text()))
+		if err != nil {
+			continue
+		}
+		if initPid == 0 || pid < initPid {
+			initPid = pid
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return 0, err
+	}
+
+	if initPid == 0 {
+		return 0, fmt.Errorf("no process in %s", dir)
+	}
+	return initPid, nil
+}
+
+func pidExists(pid int) bool {
+	if pid <= 0 {
+		return false
+	}
+	_, err := os.Stat(procfs.Path(strconv.Itoa(pid)))
+	return err == nil
+}
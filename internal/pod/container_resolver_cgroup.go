@@ -0,0 +1,103 @@
+// Copyright 2026 The HuaTuo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pod
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+)
+
+const cgroupResolverPrefix = "cgroup:"
+
+// cgroupPathResolver resolves the child cgroups of a root, e.g. the yarn
+// containers of /hadoop-yarn or the mesos executors of /mesos.
+type cgroupPathResolver struct {
+	root      string
+	pattern   *regexp.Regexp
+	namespace string
+}
+
+// NewCgroupPathResolver returns a resolver of the child cgroups of root
+// whose name matches pattern. The named group "name" of the pattern is the
+// workload name, the whole cgroup name without it, and the other named
+// groups are labels. The namespace defaults to the base name of root.
+func NewCgroupPathResolver(root, pattern, namespace string) (Resolver, error) {
+	if !path.IsAbs(root) {
+		return nil, fmt.Errorf("cgroup root %q is not absolute", root)
+	}
+	root = path.Clean(root)
+
+	if pattern == "" {
+		pattern = ".*"
+	}
+	re, err := regexp.Compile("^(?:" + pattern + ")$")
+	if err != nil {
+		return nil, fmt.Errorf("invalid cgroup pattern %q: %w", pattern, err)
+	}
+
+	if namespace == "" {
+		namespace = path.Base(root)
+	}
+	return &cgroupPathResolver{root: root, pattern: re, namespace: namespace}, nil
+}
+
+func (r *cgroupPathResolver) Name() string {
+	return cgroupResolverPrefix + r.root
+}
+
+func (r *cgroupPathResolver) Resolve() ([]Workload, error) {
+	entries, err := os.ReadDir(filepath.Join(resolverCgroupRoot(), r.root))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var workloads []Workload
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+
+		match := r.pattern.FindStringSubmatch(entry.Name())
+		if match == nil {
+			continue
+		}
+
+		workload := Workload{
+			Name:       entry.Name(),
+			CgroupPath: path.Join(r.root, entry.Name()),
+			Namespace:  r.namespace,
+		}
+		for i, group := range r.pattern.SubexpNames() {
+			switch group {
+			case "":
+			case "name":
+				workload.Name = match[i]
+			default:
+				if workload.Labels == nil {
+					workload.Labels = make(map[string]string)
+				}
+				workload.Labels[group] = match[i]
+			}
+		}
+		workloads = append(workloads, workload)
+	}
+	return workloads, nil
+}
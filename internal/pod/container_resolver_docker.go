@@ -0,0 +1,198 @@
+// Copyright 2026 The HuaTuo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pod
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	dockertypes "github.com/docker/docker/api/types"
+	dockercontainer "github.com/docker/docker/api/types/container"
+	dockerclient "github.com/docker/docker/client"
+
+	"huatuo-bamai/internal/cgroups"
+	"huatuo-bamai/internal/log"
+)
+
+const (
+	dockerResolverName       = "docker"
+	defaultDockerNamespace   = "docker"
+	defaultDockerCgroupfsDir = "/docker"
+	defaultDockerSystemdDir  = "system.slice"
+
+	// dockerLabelPodUID marks the containers of kubelet, they are synced
+	// from kubelet itself.
+	dockerLabelPodUID = "io.kubernetes.pod.uid"
+
+	dockerRequestTimeout = 5 * time.Second
+)
+
+// dockerAPI is the part of the Docker Engine API the resolver uses.
+type dockerAPI interface {
+	ContainerList(ctx context.Context, options dockercontainer.ListOptions) ([]dockertypes.Container, error)
+	ContainerInspect(ctx context.Context, containerID string) (dockertypes.ContainerJSON, error)
+}
+
+// dockerContainer is a running container inspected once.
+type dockerContainer struct {
+	name       string
+	cgroupPath string
+}
+
+// dockerResolver resolves the running containers of the Docker Engine, those
+// of a standalone Docker host or, besides kubelet, those not in a pod.
+type dockerResolver struct {
+	client       dockerAPI
+	cgroupDriver string
+	namespace    string
+	// inspected are the running containers by ID.
+	inspected map[string]dockerContainer
+}
+
+// NewDockerResolver returns a resolver of the containers of the Docker Engine
+// at host, e.g. "unix:///var/run/docker.sock", DOCKER_HOST or the default
+// socket if empty. The namespace of the containers is "docker", unless
+// namespace is set.
+func NewDockerResolver(host, apiVersion, namespace string) (Resolver, error) {
+	opts := []dockerclient.Opt{dockerclient.FromEnv}
+	if host != "" {
+		opts = append(opts, dockerclient.WithHost(host))
+	}
+	if apiVersion != "" {
+		opts = append(opts, dockerclient.WithVersion(apiVersion))
+	}
+	client, err := dockerclient.NewClientWithOpts(opts...)
+	if err != nil {
+		return nil, fmt.Errorf("create docker client: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), dockerRequestTimeout)
+	defer cancel()
+
+	info, err := client.Info(ctx)
+	if err != nil {
+		_ = client.Close()
+		return nil, fmt.Errorf("get docker info: %w", err)
+	}
+
+	return newDockerResolver(client, info.CgroupDriver, namespace), nil
+}
+
+func newDockerResolver(client dockerAPI, cgroupDriver, namespace string) *dockerResolver {
+	if namespace == "" {
+		namespace = defaultDockerNamespace
+	}
+	return &dockerResolver{
+		client:       client,
+		cgroupDriver: cgroupDriver,
+		namespace:    namespace,
+		inspected:    map[string]dockerContainer{},
+	}
+}
+
+func (r *dockerResolver) Name() string {
+	return dockerResolverName
+}
+
+func (r *dockerResolver) Resolve() ([]Workload, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), dockerRequestTimeout)
+	defer cancel()
+
+	list, err := r.client.ContainerList(ctx, dockercontainer.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("list docker containers: %w", err)
+	}
+
+	running := make(map[string]dockerContainer, len(list))
+	workloads := make([]Workload, 0, len(list))
+	for i := range list {
+		summary := &list[i]
+		if _, ok := summary.Labels[dockerLabelPodUID]; ok {
+			continue
+		}
+
+		c, ok := r.inspected[summary.ID]
+		if !ok {
+			if c, err = r.inspect(ctx, summary.ID); err != nil {
+				log.Debugf("failed to inspect docker container %s: %v", summary.ID, err)
+				continue
+			}
+		}
+		running[summary.ID] = c
+
+		workloads = append(workloads, Workload{
+			ID:         summary.ID,
+			Name:       c.name,
+			CgroupPath: c.cgroupPath,
+			Namespace:  r.namespace,
+		})
+	}
+	// the stopped containers are inspected again if they restart.
+	r.inspected = running
+	return workloads, nil
+}
+
+func (r *dockerResolver) inspect(ctx context.Context, id string) (dockerContainer, error) {
+	info, err := r.client.ContainerInspect(ctx, id)
+	if err != nil {
+		return dockerContainer{}, err
+	}
+	if info.ContainerJSONBase == nil || info.State == nil || info.State.Pid <= 0 {
+		return dockerContainer{}, fmt.Errorf("no running init pid")
+	}
+
+	var parent string
+	if info.HostConfig != nil {
+		parent = info.HostConfig.CgroupParent
+	}
+	cgroupPath := dockerCgroupPath(r.cgroupDriver, parent, id)
+
+	// a cgroup parent set for the whole daemon is known to the kernel only.
+	if _, err := os.Stat(filepath.Join(resolverCgroupRoot(), cgroupPath)); err != nil {
+		paths, err := cgroups.PathsForPID(info.State.Pid)
+		if err != nil {
+			return dockerContainer{}, err
+		}
+		if cgroupPath, err = paths.PathForProcesses(); err != nil {
+			return dockerContainer{}, err
+		}
+	}
+
+	return dockerContainer{name: strings.TrimPrefix(info.Name, "/"), cgroupPath: cgroupPath}, nil
+}
+
+// dockerCgroupPath is the cgroup of a Docker container by the cgroup driver
+// of the daemon:
+//
+//	cgroupfs: /docker/<id>, or /<parent>/<id>
+//	systemd:  /system.slice/docker-<id>.scope, or the <parent> slice expanded
+func dockerCgroupPath(cgroupDriver, parent, id string) string {
+	if cgroupDriver == "systemd" {
+		if parent == "" {
+			parent = defaultDockerSystemdDir
+		}
+		return expandSystemdSlice(parent) + "/docker-" + id + ".scope"
+	}
+
+	if parent == "" {
+		parent = defaultDockerCgroupfsDir
+	}
+	return path.Join("/", parent, id)
+}
@@ -0,0 +1,108 @@
+// Copyright 2026 The HuaTuo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pod
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	dockertypes "github.com/docker/docker/api/types"
+	dockercontainer "github.com/docker/docker/api/types/container"
+)
+
+type fakeDockerAPI struct {
+	containers []dockertypes.Container
+	parents    map[string]string
+	inspects   int
+}
+
+func (f *fakeDockerAPI) ContainerList(context.Context, dockercontainer.ListOptions) ([]dockertypes.Container, error) {
+	return f.containers, nil
+}
+
+func (f *fakeDockerAPI) ContainerInspect(_ context.Context, id string) (dockertypes.ContainerJSON, error) {
+	f.inspects++
+	for _, c := range f.containers {
+		if c.ID != id {
+			continue
+		}
+		return dockertypes.ContainerJSON{ContainerJSONBase: &dockertypes.ContainerJSONBase{
+			Name:       c.Names[0],
+			State:      &dockertypes.ContainerState{Pid: 1},
+			HostConfig: &dockercontainer.HostConfig{Resources: dockercontainer.Resources{CgroupParent: f.parents[id]}},
+		}}, nil
+	}
+	return dockertypes.ContainerJSON{}, fmt.Errorf("no such container %s", id)
+}
+
+func TestDockerCgroupPath(t *testing.T) {
+	tests := []struct {
+		driver, parent, want string
+	}{
+		{"cgroupfs", "", "/docker/abc"},
+		{"cgroupfs", "/custom/parent", "/custom/parent/abc"},
+		{"systemd", "", "/system.slice/docker-abc.scope"},
+		{"systemd", "app-web.slice", "/app.slice/app-web.slice/docker-abc.scope"},
+	}
+	for _, tt := range tests {
+		if got := dockerCgroupPath(tt.driver, tt.parent, "abc"); got != tt.want {
+			t.Errorf("dockerCgroupPath(%q, %q) = %q, want %q", tt.driver, tt.parent, got, tt.want)
+		}
+	}
+}
+
+func TestDockerResolver(t *testing.T) {
+	setupResolverRoot(t, []string{
+		"system.slice/docker-aaa.scope",
+		"app.slice/app-web.slice/docker-bbb.scope",
+	})
+
+	client := &fakeDockerAPI{
+		containers: []dockertypes.Container{
+			{ID: "aaa", Names: []string{"/nginx"}},
+			{ID: "bbb", Names: []string{"/web"}},
+			{ID: "ccc", Names: []string{"/k8s_app"}, Labels: map[string]string{dockerLabelPodUID: "uid"}},
+		},
+		parents: map[string]string{"bbb": "app-web.slice"},
+	}
+	r := newDockerResolver(client, "systemd", "")
+
+	workloads, err := r.Resolve()
+	if err != nil {
+		t.Fatalf("Resolve() error = %v", err)
+	}
+	want := []Workload{
+		{ID: "aaa", Name: "nginx", CgroupPath: "/system.slice/docker-aaa.scope", Namespace: "docker"},
+		{ID: "bbb", Name: "web", CgroupPath: "/app.slice/app-web.slice/docker-bbb.scope", Namespace: "docker"},
+	}
+	if len(workloads) != len(want) {
+		t.Fatalf("Resolve() = %+v, want %+v", workloads, want)
+	}
+	for i := range want {
+		if workloads[i].ID != want[i].ID || workloads[i].Name != want[i].Name ||
+			workloads[i].CgroupPath != want[i].CgroupPath || workloads[i].Namespace != want[i].Namespace {
+			t.Errorf("Resolve()[%d] = %+v, want %+v", i, workloads[i], want[i])
+		}
+	}
+
+	// the running containers are inspected once.
+	if _, err := r.Resolve(); err != nil {
+		t.Fatalf("Resolve() again error = %v", err)
+	}
+	if client.inspects != 2 {
+		t.Errorf("inspects = %d, want 2", client.inspects)
+	}
+}
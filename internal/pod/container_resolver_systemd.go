@@ -0,0 +1,107 @@
+// Copyright 2026 The HuaTuo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pod
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	systemdResolverName = "systemd"
+	defaultSystemdSlice = "system.slice"
+	defaultSystemdUnit  = "*.service"
+)
+
+// systemdResolver resolves the units of systemd slices, e.g. the services
+// of system.slice.
+type systemdResolver struct {
+	slices    []string
+	units     []string
+	namespace string
+}
+
+// NewSystemdResolver returns a resolver of the units of the slices matching
+// the unit globs, "system.slice" and "*.service" if empty. The namespace of
+// a unit is its slice name without ".slice", unless namespace is set.
+func NewSystemdResolver(slices, units []string, namespace string) (Resolver, error) {
+	if len(slices) == 0 {
+		slices = []string{defaultSystemdSlice}
+	}
+	if len(units) == 0 {
+		units = []string{defaultSystemdUnit}
+	}
+
+	for _, slice := range slices {
+		if !strings.HasSuffix(slice, defaultSystemdSuffix) || strings.Contains(slice, "/") {
+			return nil, fmt.Errorf("invalid systemd slice %q", slice)
+		}
+	}
+	for _, unit := range units {
+		if _, err := path.Match(unit, ""); err != nil {
+			return nil, fmt.Errorf("invalid systemd unit pattern %q: %w", unit, err)
+		}
+	}
+
+	return &systemdResolver{slices: slices, units: units, namespace: namespace}, nil
+}
+
+func (r *systemdResolver) Name() string {
+	return systemdResolverName
+}
+
+func (r *systemdResolver) Resolve() ([]Workload, error) {
+	var workloads []Workload
+	for _, slice := range r.slices {
+		slicePath := expandSystemdSlice(slice)
+
+		entries, err := os.ReadDir(filepath.Join(resolverCgroupRoot(), slicePath))
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return nil, err
+		}
+
+		namespace := r.namespace
+		if namespace == "" {
+			namespace = strings.TrimSuffix(slice, defaultSystemdSuffix)
+		}
+
+		for _, entry := range entries {
+			if !entry.IsDir() || !r.matchUnit(entry.Name()) {
+				continue
+			}
+			workloads = append(workloads, Workload{
+				Name:       entry.Name(),
+				CgroupPath: path.Join(slicePath, entry.Name()),
+				Namespace:  namespace,
+			})
+		}
+	}
+	return workloads, nil
+}
+
+func (r *systemdResolver) matchUnit(name string) bool {
+	for _, unit := range r.units {
+		if ok, _ := path.Match(unit, name); ok {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,161 @@
+// Copyright 2026 The HuaTuo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pod
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+)
+
+// setupResolverRoot creates the cgroups under a temporary root, those of
+// procs with the pid of the test as their process.
+func setupResolverRoot(t *testing.T, dirs []string, procs ...string) {
+	t.Helper()
+
+	root := t.TempDir()
+	for _, dir := range dirs {
+		if err := os.MkdirAll(filepath.Join(root, dir), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, dir := range procs {
+		pid := []byte(strconv.Itoa(os.Getpid()) + "\n")
+		if err := os.WriteFile(filepath.Join(root, dir, "cgroup.procs"), pid, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	saved := resolverCgroupRoot
+	resolverCgroupRoot = func() string { return root }
+	t.Cleanup(func() { resolverCgroupRoot = saved })
+}
+
+func TestSystemdResolver(t *testing.T) {
+	setupResolverRoot(t, []string{
+		"system.slice/nginx.service",
+		"system.slice/sshd.socket",
+		"user.slice/user-1000.slice/session-1.scope",
+	})
+
+	r, err := NewSystemdResolver([]string{"system.slice", "user-1000.slice"}, []string{"*.service", "session-*.scope"}, "")
+	if err != nil {
+		t.Fatalf("NewSystemdResolver() error = %v", err)
+	}
+
+	workloads, err := r.Resolve()
+	if err != nil {
+		t.Fatalf("Resolve() error = %v", err)
+	}
+	want := []Workload{
+		{Name: "nginx.service", CgroupPath: "/system.slice/nginx.service", Namespace: "system"},
+		{Name: "session-1.scope", CgroupPath: "/user.slice/user-1000.slice/session-1.scope", Namespace: "user-1000"},
+	}
+	if len(workloads) != len(want) {
+		t.Fatalf("Resolve() = %+v, want %+v", workloads, want)
+	}
+	for i := range want {
+		if workloads[i].Name != want[i].Name || workloads[i].CgroupPath != want[i].CgroupPath || workloads[i].Namespace != want[i].Namespace {
+			t.Errorf("Resolve()[%d] = %+v, want %+v", i, workloads[i], want[i])
+		}
+	}
+
+	if _, err := NewSystemdResolver([]string{"system"}, nil, ""); err == nil {
+		t.Error("NewSystemdResolver() with a slice without .slice succeeded")
+	}
+}
+
+func TestCgroupPathResolver(t *testing.T) {
+	setupResolverRoot(t, []string{
+		"hadoop-yarn/container_e01_1700000000000_0001_01_000002",
+		"hadoop-yarn/other",
+	})
+
+	r, err := NewCgroupPathResolver("/hadoop-yarn", `container_e\d+_(?P<application>\d+_\d+)_\d+_(?P<name>\d+)`, "")
+	if err != nil {
+		t.Fatalf("NewCgroupPathResolver() error = %v", err)
+	}
+	if r.Name() != "cgroup:/hadoop-yarn" {
+		t.Errorf("Name() = %q, want cgroup:/hadoop-yarn", r.Name())
+	}
+
+	workloads, err := r.Resolve()
+	if err != nil {
+		t.Fatalf("Resolve() error = %v", err)
+	}
+	if len(workloads) != 1 {
+		t.Fatalf("Resolve() = %+v, want one workload", workloads)
+	}
+	w := workloads[0]
+	if w.Name != "000002" || w.Namespace != "hadoop-yarn" || w.Labels["application"] != "1700000000000_0001" ||
+		w.CgroupPath != "/hadoop-yarn/container_e01_1700000000000_0001_01_000002" {
+		t.Errorf("Resolve() = %+v, want the container 000002 of application 1700000000000_0001", w)
+	}
+
+	if _, err := NewCgroupPathResolver("hadoop-yarn", "", ""); err == nil {
+		t.Error("NewCgroupPathResolver() with a relative root succeeded")
+	}
+}
+
+func TestResolverSyncContainers(t *testing.T) {
+	setupResolverRoot(t, []string{
+		"system.slice/nginx.service",
+		"system.slice/empty.service",
+	}, "system.slice/nginx.service")
+
+	r, err := NewSystemdResolver(nil, nil, "")
+	if err != nil {
+		t.Fatalf("NewSystemdResolver() error = %v", err)
+	}
+	if err := RegisterResolver(r); err != nil {
+		t.Fatalf("RegisterResolver() error = %v", err)
+	}
+	t.Cleanup(resolversRelease)
+	if err := RegisterResolver(r); err == nil {
+		t.Error("RegisterResolver() twice succeeded")
+	}
+
+	containersMapLock.Lock()
+	defer containersMapLock.Unlock()
+
+	saved := containers
+	containers = map[string]*Container{}
+	t.Cleanup(func() { containers = saved })
+
+	resolverSyncContainers()
+
+	// the empty cgroup has no process to be a container of.
+	if len(containers) != 1 {
+		t.Fatalf("containers = %v, want nginx.service only", containers)
+	}
+	id := workloadContainerID("systemd", "/system.slice/nginx.service")
+	c := containers[id]
+	if c == nil || ValidateContainerID(c.ID) != nil {
+		t.Fatalf("containers = %v, want a valid ID %s", containers, id)
+	}
+	if c.Name != "nginx.service" || c.LabelHostNamespace() != "system" || c.InitPid != os.Getpid() ||
+		c.Type != ContainerTypeNormal || c.Resolver != "systemd" {
+		t.Errorf("container = %+v, want nginx.service in system", c)
+	}
+
+	// a removed workload is removed, the kubelet containers are kept.
+	containers["kubelet"] = &Container{ID: "kubelet"}
+	resolversRelease()
+	resolverSyncContainers()
+	if _, ok := containers[id]; ok || containers["kubelet"] == nil {
+		t.Errorf("containers = %v, want the kubelet container only", containers)
+	}
+}
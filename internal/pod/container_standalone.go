@@ -0,0 +1,302 @@
+// Copyright 2026 The HuaTuo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pod
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"huatuo-bamai/internal/cgroups"
+	"huatuo-bamai/internal/log"
+
+	runtimeapi "k8s.io/cri-api/pkg/apis/runtime/v1"
+	k8sremote "k8s.io/cri-client/pkg"
+)
+
+// The modes of the pod manager, ModeAuto falls back to the standalone mode
+// when kubelet is unreachable.
+const (
+	ModeAuto       = ""
+	ModeKubelet    = "kubelet"
+	ModeStandalone = "standalone"
+)
+
+const (
+	criResolverName            = "cri"
+	cgroupScanResolverName     = "cgroup-scan"
+	defaultStandaloneNamespace = "standalone"
+
+	criLabelPodName      = "io.kubernetes.pod.name"
+	criLabelPodNamespace = "io.kubernetes.pod.namespace"
+	criRequestTimeout    = 5 * time.Second
+
+	// cgroupScanMaxDepth bounds the walk of the cgroup hierarchy, the
+	// deepest containers are those of the burstable kubelet pods.
+	cgroupScanMaxDepth = 5
+)
+
+// cgroupScanIDRegexp matches the cgroups of the containers of docker,
+// containerd and cri-o, on cgroupfs and systemd.
+var cgroupScanIDRegexp = regexp.MustCompile(`^(?:cri-containerd-|crio-|docker-)?([0-9a-f]{64})(?:\.scope)?$`)
+
+// standaloneResolvers are the resolvers registered by the standalone mode,
+// unregistered when kubelet comes back.
+var standaloneResolvers []string
+
+// criAPI is the part of the CRI runtime service the resolver uses.
+type criAPI interface {
+	ListContainers(ctx context.Context, filter *runtimeapi.ContainerFilter) ([]*runtimeapi.Container, error)
+	ContainerStatus(ctx context.Context, containerID string, verbose bool) (*runtimeapi.ContainerStatusResponse, error)
+}
+
+// criContainer is a running container inspected once.
+type criContainer struct {
+	name       string
+	namespace  string
+	cgroupPath string
+}
+
+// criResolver resolves the running containers of a CRI runtime, containerd
+// or cri-o, when kubelet is not there to list the pods.
+type criResolver struct {
+	client    criAPI
+	namespace string
+	// inspected are the running containers by ID.
+	inspected map[string]criContainer
+}
+
+// NewCRIResolver returns a resolver of the containers of the CRI runtime at
+// endpoint, e.g. "unix:///run/containerd/containerd.sock". The namespace of
+// the containers not in a pod is "standalone", unless namespace is set.
+func NewCRIResolver(endpoint, namespace string) (Resolver, error) {
+	client, err := k8sremote.NewRemoteRuntimeService(endpoint, criRequestTimeout, nil, nil)
+	if err != nil {
+		return nil, fmt.Errorf("create cri client: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), criRequestTimeout)
+	defer cancel()
+
+	if _, err := client.Version(ctx, ""); err != nil {
+		return nil, fmt.Errorf("get cri version at %s: %w", endpoint, err)
+	}
+
+	return newCRIResolver(client, namespace), nil
+}
+
+func newCRIResolver(client criAPI, namespace string) *criResolver {
+	if namespace == "" {
+		namespace = defaultStandaloneNamespace
+	}
+	return &criResolver{client: client, namespace: namespace, inspected: map[string]criContainer{}}
+}
+
+func (r *criResolver) Name() string {
+	return criResolverName
+}
+
+func (r *criResolver) Resolve() ([]Workload, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), criRequestTimeout)
+	defer cancel()
+
+	list, err := r.client.ListContainers(ctx, &runtimeapi.ContainerFilter{
+		State: &runtimeapi.ContainerStateValue{State: runtimeapi.ContainerState_CONTAINER_RUNNING},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("list cri containers: %w", err)
+	}
+
+	running := make(map[string]criContainer, len(list))
+	workloads := make([]Workload, 0, len(list))
+	for _, summary := range list {
+		c, ok := r.inspected[summary.Id]
+		if !ok {
+			if c, err = r.inspect(ctx, summary); err != nil {
+				log.Debugf("failed to inspect cri container %s: %v", summary.Id, err)
+				continue
+			}
+		}
+		running[summary.Id] = c
+
+		workloads = append(workloads, Workload{
+			ID:         summary.Id,
+			Name:       c.name,
+			CgroupPath: c.cgroupPath,
+			Namespace:  c.namespace,
+		})
+	}
+	// the stopped containers are inspected again if they restart.
+	r.inspected = running
+	return workloads, nil
+}
+
+func (r *criResolver) inspect(ctx context.Context, summary *runtimeapi.Container) (criContainer, error) {
+	status, err := r.client.ContainerStatus(ctx, summary.Id, true)
+	if err != nil {
+		return criContainer{}, err
+	}
+
+	// the verbose info of containerd and cri-o has the init pid.
+	info := struct {
+		Pid int `json:"pid"`
+	}{}
+	if err := json.Unmarshal([]byte(status.GetInfo()["info"]), &info); err != nil {
+		return criContainer{}, fmt.Errorf("unmarshal info: %w", err)
+	}
+	if info.Pid <= 0 {
+		return criContainer{}, fmt.Errorf("no running init pid")
+	}
+
+	paths, err := cgroups.PathsForPID(info.Pid)
+	if err != nil {
+		return criContainer{}, err
+	}
+	cgroupPath, err := paths.PathForProcesses()
+	if err != nil {
+		return criContainer{}, err
+	}
+
+	c := criContainer{name: summary.GetMetadata().GetName(), namespace: r.namespace, cgroupPath: cgroupPath}
+	if name := summary.Labels[criLabelPodName]; name != "" {
+		c.name = name
+	}
+	if namespace := summary.Labels[criLabelPodNamespace]; namespace != "" {
+		c.namespace = namespace
+	}
+	return c, nil
+}
+
+// cgroupScanResolver resolves the cgroups named after a container ID, the
+// containers of the runtimes without a socket to ask.
+type cgroupScanResolver struct {
+	namespace string
+}
+
+func newCgroupScanResolver(namespace string) *cgroupScanResolver {
+	if namespace == "" {
+		namespace = defaultStandaloneNamespace
+	}
+	return &cgroupScanResolver{namespace: namespace}
+}
+
+func (r *cgroupScanResolver) Name() string {
+	return cgroupScanResolverName
+}
+
+func (r *cgroupScanResolver) Resolve() ([]Workload, error) {
+	root := resolverCgroupRoot()
+
+	var workloads []Workload
+	err := filepath.WalkDir(root, func(dir string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			// the cgroups removed during the walk.
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if !entry.IsDir() || dir == root {
+			return nil
+		}
+
+		rel, _ := filepath.Rel(root, dir)
+		match := cgroupScanIDRegexp.FindStringSubmatch(entry.Name())
+		if match == nil {
+			if strings.Count(rel, string(filepath.Separator)) >= cgroupScanMaxDepth-1 {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		workloads = append(workloads, Workload{
+			ID:         match[1],
+			Name:       match[1][:12],
+			CgroupPath: "/" + filepath.ToSlash(rel),
+			Namespace:  r.namespace,
+		})
+		return filepath.SkipDir
+	})
+	if err != nil {
+		return nil, fmt.Errorf("scan cgroups: %w", err)
+	}
+	return workloads, nil
+}
+
+// standaloneStart registers the resolvers of the standalone mode: the CRI
+// runtime and the Docker Engine reachable, then the cgroup scan for the
+// containers of the others. The containers found by several of them are
+// the same.
+func standaloneStart(ctx *ManagerCtx) {
+	var registered []Resolver
+
+	endpoints := []string{ctx.CRIEndpoint}
+	if ctx.CRIEndpoint == "" {
+		endpoints = []string{kubeletRuntimeEndpoint, "unix://" + crioSocket}
+	}
+	for _, endpoint := range endpoints {
+		r, err := NewCRIResolver(endpoint, ctx.StandaloneNamespace)
+		if err != nil {
+			log.Debugf("standalone: no cri runtime at %s: %v", endpoint, err)
+			continue
+		}
+		registered = append(registered, r)
+		break
+	}
+
+	if r, err := NewDockerResolver("", dockerAPIVersion, ctx.StandaloneNamespace); err == nil {
+		registered = append(registered, r)
+	} else {
+		log.Debugf("standalone: no docker engine: %v", err)
+	}
+
+	registered = append(registered, newCgroupScanResolver(ctx.StandaloneNamespace))
+
+	for _, r := range registered {
+		// e.g. the docker resolver of the config.
+		if err := RegisterResolver(r); err != nil {
+			log.Debugf("standalone: %v", err)
+			continue
+		}
+		standaloneResolvers = append(standaloneResolvers, r.Name())
+		log.Infof("standalone: resolving the containers by %s", r.Name())
+	}
+}
+
+// standaloneStop unregisters the resolvers of the standalone mode, their
+// containers are dropped at the next sync.
+func standaloneStop() {
+	unregisterResolvers(standaloneResolvers)
+	standaloneResolvers = nil
+}
+
+// detectCgroupDriver guesses the cgroup driver of kubelet from the cgroup
+// of the pods, empty if there is none.
+func detectCgroupDriver() string {
+	root := resolverCgroupRoot()
+	if _, err := os.Stat(filepath.Join(root, defaultNodeCgroupName+defaultSystemdSuffix)); err == nil {
+		return "systemd"
+	}
+	if _, err := os.Stat(filepath.Join(root, defaultNodeCgroupName)); err == nil {
+		return "cgroupfs"
+	}
+	return ""
+}
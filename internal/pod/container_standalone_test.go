@@ -0,0 +1,148 @@
+// Copyright 2026 The HuaTuo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pod
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+
+	"huatuo-bamai/internal/cgroups"
+
+	runtimeapi "k8s.io/cri-api/pkg/apis/runtime/v1"
+)
+
+type fakeCRIAPI struct {
+	containers []*runtimeapi.Container
+	statuses   int
+}
+
+func (f *fakeCRIAPI) ListContainers(context.Context, *runtimeapi.ContainerFilter) ([]*runtimeapi.Container, error) {
+	return f.containers, nil
+}
+
+func (f *fakeCRIAPI) ContainerStatus(_ context.Context, id string, _ bool) (*runtimeapi.ContainerStatusResponse, error) {
+	f.statuses++
+	for _, c := range f.containers {
+		if c.Id == id {
+			info := fmt.Sprintf(`{"pid": %d}`, os.Getpid())
+			return &runtimeapi.ContainerStatusResponse{Info: map[string]string{"info": info}}, nil
+		}
+	}
+	return nil, fmt.Errorf("no such container %s", id)
+}
+
+func TestCRIResolver(t *testing.T) {
+	paths, err := cgroups.PathsForPID(os.Getpid())
+	if err != nil {
+		t.Skipf("no cgroup of the test: %v", err)
+	}
+	cgroupPath, err := paths.PathForProcesses()
+	if err != nil {
+		t.Skipf("no cgroup of the test: %v", err)
+	}
+
+	api := &fakeCRIAPI{containers: []*runtimeapi.Container{
+		{
+			Id:       "pod-container",
+			Metadata: &runtimeapi.ContainerMetadata{Name: "nginx"},
+			Labels:   map[string]string{criLabelPodName: "web-0", criLabelPodNamespace: "shop"},
+		},
+		{
+			Id:       "bare-container",
+			Metadata: &runtimeapi.ContainerMetadata{Name: "redis"},
+		},
+	}}
+	r := newCRIResolver(api, "")
+
+	for i := 0; i < 2; i++ {
+		workloads, err := r.Resolve()
+		if err != nil {
+			t.Fatalf("Resolve() error = %v", err)
+		}
+		want := []Workload{
+			{ID: "pod-container", Name: "web-0", CgroupPath: cgroupPath, Namespace: "shop"},
+			{ID: "bare-container", Name: "redis", CgroupPath: cgroupPath, Namespace: defaultStandaloneNamespace},
+		}
+		if len(workloads) != len(want) {
+			t.Fatalf("Resolve() = %+v, want %+v", workloads, want)
+		}
+		for i := range want {
+			if workloads[i].ID != want[i].ID || workloads[i].Name != want[i].Name ||
+				workloads[i].CgroupPath != want[i].CgroupPath || workloads[i].Namespace != want[i].Namespace {
+				t.Errorf("Resolve()[%d] = %+v, want %+v", i, workloads[i], want[i])
+			}
+		}
+	}
+	if api.statuses != 2 {
+		t.Errorf("containers inspected %d times, want once each", api.statuses)
+	}
+}
+
+func TestCgroupScanResolver(t *testing.T) {
+	id := func(c string) string { return strings.Repeat(c, 64) }
+	setupResolverRoot(t, []string{
+		"kubepods.slice/kubepods-burstable.slice/kubepods-burstable-pod1.slice/cri-containerd-" + id("a") + ".scope",
+		"docker/" + id("b"),
+		"system.slice/crio-conmon-" + id("c") + ".scope",
+		"system.slice/crio-" + id("d") + ".scope/container",
+		"system.slice/nginx.service",
+		"a/b/c/d/e/f/" + id("e"),
+	})
+
+	workloads, err := newCgroupScanResolver("").Resolve()
+	if err != nil {
+		t.Fatalf("Resolve() error = %v", err)
+	}
+	want := []Workload{
+		{ID: id("b"), Name: id("b")[:12], CgroupPath: "/docker/" + id("b")},
+		{ID: id("a"), Name: id("a")[:12], CgroupPath: "/kubepods.slice/kubepods-burstable.slice/kubepods-burstable-pod1.slice/cri-containerd-" + id("a") + ".scope"},
+		{ID: id("d"), Name: id("d")[:12], CgroupPath: "/system.slice/crio-" + id("d") + ".scope"},
+	}
+	if len(workloads) != len(want) {
+		t.Fatalf("Resolve() = %+v, want %+v", workloads, want)
+	}
+	for i := range want {
+		if workloads[i].ID != want[i].ID || workloads[i].Name != want[i].Name ||
+			workloads[i].CgroupPath != want[i].CgroupPath || workloads[i].Namespace != defaultStandaloneNamespace {
+			t.Errorf("Resolve()[%d] = %+v, want %+v", i, workloads[i], want[i])
+		}
+	}
+}
+
+func TestStandaloneStop(t *testing.T) {
+	t.Cleanup(func() {
+		resolversRelease()
+		standaloneResolvers = nil
+	})
+
+	systemd, err := NewSystemdResolver([]string{"system.slice"}, []string{"*.service"}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, r := range []Resolver{systemd, newCgroupScanResolver("")} {
+		if err := RegisterResolver(r); err != nil {
+			t.Fatal(err)
+		}
+	}
+	standaloneResolvers = []string{cgroupScanResolverName}
+
+	standaloneStop()
+	if len(resolvers) != 1 || resolvers[0] != systemd {
+		t.Errorf("resolvers after standaloneStop() = %v, want the systemd one", resolvers)
+	}
+}
@@ -0,0 +1,73 @@
+// Copyright 2026 The HuaTuo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pod
+
+import (
+	"strconv"
+	"strings"
+
+	"huatuo-bamai/internal/log"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// Pods override the tracer thresholds by annotations named
+// huatuo.io/<name>-threshold, e.g. huatuo.io/page-fault-rate-threshold: "100".
+const (
+	thresholdAnnotationPrefix = "huatuo.io/"
+	thresholdAnnotationSuffix = "-threshold"
+)
+
+// parseContainerThresholds returns the threshold overrides of the pod by
+// name, the invalid values are ignored.
+func parseContainerThresholds(pod *corev1.Pod) map[string]uint64 {
+	var thresholds map[string]uint64
+
+	for key, value := range pod.Annotations {
+		name, ok := strings.CutPrefix(key, thresholdAnnotationPrefix)
+		if !ok {
+			continue
+		}
+		name, ok = strings.CutSuffix(name, thresholdAnnotationSuffix)
+		if !ok || name == "" {
+			continue
+		}
+
+		threshold, err := strconv.ParseUint(strings.TrimSpace(value), 10, 64)
+		if err != nil {
+			log.Warnf("pod %s/%s annotation %s: invalid threshold %q", pod.Namespace, pod.Name, key, value)
+			continue
+		}
+
+		if thresholds == nil {
+			thresholds = make(map[string]uint64)
+		}
+		thresholds[name] = threshold
+	}
+
+	return thresholds
+}
+
+// Threshold returns the threshold name of the container, or def when its
+// pod does not override it or the container is nil.
+func (c *Container) Threshold(name string, def uint64) uint64 {
+	if c == nil {
+		return def
+	}
+	if threshold, ok := c.Thresholds[name]; ok {
+		return threshold
+	}
+	return def
+}
@@ -0,0 +1,63 @@
+// Copyright 2026 The HuaTuo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pod
+
+import (
+	"reflect"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestParseContainerThresholds(t *testing.T) {
+	pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{
+		Name:      "web",
+		Namespace: "default",
+		Annotations: map[string]string{
+			"huatuo.io/page-fault-rate-threshold": "100",
+			"huatuo.io/dload-threshold":           " 2 ",
+			"huatuo.io/zombie-unreaped-threshold": "-1",
+			"huatuo.io/-threshold":                "1",
+			"huatuo.io/owner":                     "sre",
+			"example.io/dload-threshold":          "3",
+		},
+	}}
+
+	want := map[string]uint64{"page-fault-rate": 100, "dload": 2}
+	if got := parseContainerThresholds(pod); !reflect.DeepEqual(got, want) {
+		t.Errorf("parseContainerThresholds() = %v, want %v", got, want)
+	}
+
+	if got := parseContainerThresholds(&corev1.Pod{}); got != nil {
+		t.Errorf("parseContainerThresholds() without annotations = %v, want nil", got)
+	}
+}
+
+func TestContainerThreshold(t *testing.T) {
+	c := &Container{Thresholds: map[string]uint64{"dload": 2}}
+
+	if got := c.Threshold("dload", 5); got != 2 {
+		t.Errorf("Threshold(dload) = %d, want 2", got)
+	}
+	if got := c.Threshold("page-fault-rate", 500); got != 500 {
+		t.Errorf("Threshold(page-fault-rate) = %d, want 500", got)
+	}
+
+	var none *Container
+	if got := none.Threshold("dload", 5); got != 5 {
+		t.Errorf("nil Threshold(dload) = %d, want 5", got)
+	}
+}
@@ -0,0 +1,76 @@
+// Copyright 2026 The HuaTuo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pod
+
+import (
+	"time"
+)
+
+const (
+	// containerTombstoneRetention is how long the containers gone are
+	// still found, for the events read from the buffers after the sync.
+	containerTombstoneRetention = time.Minute
+	// containerTombstoneMax bounds the tombstones, e.g. of a node
+	// restarting all of its pods, the oldest are dropped first.
+	containerTombstoneMax = 1024
+)
+
+// tombstones are the containers removed by the syncs, the oldest first,
+// with the containers lock held.
+var tombstones []*Container
+
+// buryContainer removes the container from the cache and keeps a copy of
+// it as a tombstone, with the containers lock held.
+func buryContainer(id string, now time.Time) {
+	c, ok := containers[id]
+	if !ok {
+		return
+	}
+	delete(containers, id)
+
+	dead := *c
+	dead.ExitedAt = now
+	tombstones = append(tombstones, &dead)
+	pruneTombstones(now)
+}
+
+// pruneTombstones drops the tombstones expired or beyond the max, with the
+// containers lock held.
+func pruneTombstones(now time.Time) {
+	expired := 0
+	for expired < len(tombstones) &&
+		(now.Sub(tombstones[expired].ExitedAt) > containerTombstoneRetention ||
+			len(tombstones)-expired > containerTombstoneMax) {
+		expired++
+	}
+	if expired > 0 {
+		tombstones = append(tombstones[:0], tombstones[expired:]...)
+	}
+}
+
+// tombstoneBy returns the latest container gone of typeMask for which
+// selector returns val, nil if none.
+func tombstoneBy[T comparable](typeMask ContainerType, selector func(*Container) T, val T) *Container {
+	containersMapLock.Lock()
+	defer containersMapLock.Unlock()
+
+	pruneTombstones(time.Now())
+	for i := len(tombstones) - 1; i >= 0; i-- {
+		if c := tombstones[i]; c.Type&typeMask != 0 && selector(c) == val {
+			return c
+		}
+	}
+	return nil
+}
@@ -0,0 +1,95 @@
+// Copyright 2026 The HuaTuo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pod
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestContainerTombstones(t *testing.T) {
+	savedContainers, savedTombstones, savedUpdatedAt := containers, tombstones, lastUpdatedAt
+	t.Cleanup(func() {
+		containers, tombstones, lastUpdatedAt = savedContainers, savedTombstones, savedUpdatedAt
+	})
+
+	containers = map[string]*Container{
+		"dead": {
+			ID: "dead", Type: ContainerTypeNormal, NetNamespaceCookie: 7, NetNamespaceInode: 8,
+			CgroupCss: map[string]uint64{"memory": 9}, Labels: map[string]any{"app": "web"},
+		},
+		"sidecar": {ID: "sidecar", Type: ContainerTypeSidecar, NetNamespaceCookie: 10},
+	}
+	tombstones = nil
+	// no sync during the test.
+	lastUpdatedAt = time.Now().Add(time.Hour)
+
+	now := time.Now()
+	containersMapLock.Lock()
+	buryContainer("dead", now)
+	buryContainer("sidecar", now)
+	buryContainer("unknown", now)
+	containersMapLock.Unlock()
+
+	if _, ok := containers["dead"]; ok || len(tombstones) != 2 {
+		t.Fatalf("containers = %v, tombstones = %v, want both buried", containers, tombstones)
+	}
+
+	lookups := map[string]func() (*Container, error){
+		"id":     func() (*Container, error) { return ContainerByID("dead") },
+		"cookie": func() (*Container, error) { return ContainerByNetCookie(7) },
+		"inode":  func() (*Container, error) { return ContainerByNetInode(8) },
+		"css":    func() (*Container, error) { return ContainerByCSS(9, "memory") },
+	}
+	for name, lookup := range lookups {
+		c, err := lookup()
+		if err != nil || c == nil || c.ID != "dead" || !c.ExitedAt.Equal(now) || c.Labels["app"] != "web" {
+			t.Errorf("%s: container = %+v, %v, want the dead one", name, c, err)
+		}
+	}
+
+	// the sidecars are not searched by the net namespace, as when running.
+	if c, _ := ContainerByNetCookie(10); c != nil {
+		t.Errorf("container = %+v, want no sidecar", c)
+	}
+	if c, _ := ContainerByID("sidecar"); c == nil {
+		t.Error("sidecar not found by ID")
+	}
+
+	// a running container wins.
+	containers["alive"] = &Container{ID: "alive", Type: ContainerTypeNormal, NetNamespaceCookie: 7}
+	if c, _ := ContainerByNetCookie(7); c == nil || c.ID != "alive" {
+		t.Errorf("container = %+v, want the running one", c)
+	}
+}
+
+func TestPruneTombstones(t *testing.T) {
+	saved := tombstones
+	t.Cleanup(func() { tombstones = saved })
+
+	now := time.Now()
+	tombstones = []*Container{
+		{ID: "expired", ExitedAt: now.Add(-containerTombstoneRetention - time.Second)},
+	}
+	for i := 0; i < containerTombstoneMax+1; i++ {
+		tombstones = append(tombstones, &Container{ID: fmt.Sprint(i), ExitedAt: now})
+	}
+
+	pruneTombstones(now)
+	if len(tombstones) != containerTombstoneMax || tombstones[0].ID != "1" {
+		t.Errorf("tombstones = %d from %s, want %d from 1", len(tombstones), tombstones[0].ID, containerTombstoneMax)
+	}
+}
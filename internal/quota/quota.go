@@ -0,0 +1,230 @@
+// Copyright 2026 The HuaTuo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package quota limits the events and metric series of each kubernetes
+// namespace, so the pods of one tenant cannot use up the budget of the
+// node. Host events and metrics, without namespace, are never limited.
+package quota
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Kinds of the exceeded quota.
+const (
+	KindEvents       = "events"
+	KindMetricSeries = "metric_series"
+)
+
+const (
+	eventWindow = time.Minute
+
+	// seriesIdle is how long a series not exported keeps its room, longer
+	// than the scrape intervals.
+	seriesIdle = 5 * time.Minute
+)
+
+// Limits of a namespace, zero is unlimited.
+type Limits struct {
+	// EventsPerMinute is the events stored per minute.
+	EventsPerMinute int
+	// MetricSeries is the series exported, by all the scrape endpoints.
+	MetricSeries int
+}
+
+// Config is the default limits and the overrides per namespace.
+type Config struct {
+	Default    Limits
+	Namespaces map[string]Limits
+}
+
+var exceeded = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Namespace: "huatuo",
+	Name:      "quota_exceeded_total",
+	Help:      "Events and metric series dropped by the namespace quotas.",
+}, []string{"namespace", "kind"})
+
+// RegisterMetrics registers huatuo_quota_exceeded_total.
+func RegisterMetrics(reg prometheus.Registerer) {
+	reg.MustRegister(exceeded)
+}
+
+type eventCount struct {
+	start time.Time
+	count int
+}
+
+type quota struct {
+	cfg Config
+
+	mu        sync.Mutex
+	events    map[string]*eventCount
+	lastSweep time.Time
+
+	seriesMu sync.Mutex
+	// series are when the series admitted of each namespace were last
+	// exported.
+	series          map[string]map[uint64]time.Time
+	lastSeriesSweep time.Time
+}
+
+var (
+	current atomic.Pointer[quota]
+	now     = time.Now
+)
+
+// Set installs the quotas, nothing is limited when no limit is set.
+func Set(cfg *Config) error {
+	enabled := cfg.Default != Limits{}
+	for namespace, limits := range cfg.Namespaces {
+		if namespace == "" {
+			return fmt.Errorf("quota: empty namespace")
+		}
+		if limits.EventsPerMinute < 0 || limits.MetricSeries < 0 {
+			return fmt.Errorf("quota: negative limit of namespace %s", namespace)
+		}
+		enabled = enabled || limits != Limits{}
+	}
+	if cfg.Default.EventsPerMinute < 0 || cfg.Default.MetricSeries < 0 {
+		return fmt.Errorf("quota: negative default limit")
+	}
+
+	if !enabled {
+		current.Store(nil)
+		return nil
+	}
+
+	current.Store(&quota{
+		cfg:    *cfg,
+		events: make(map[string]*eventCount),
+		series: make(map[string]map[uint64]time.Time),
+	})
+	return nil
+}
+
+func (q *quota) limits(namespace string) Limits {
+	if limits, ok := q.cfg.Namespaces[namespace]; ok {
+		return limits
+	}
+	return q.cfg.Default
+}
+
+// sweep forgets the namespaces idle for a whole window, e.g. deleted ones.
+func (q *quota) sweep(t time.Time) {
+	if t.Sub(q.lastSweep) < eventWindow {
+		return
+	}
+	for namespace, c := range q.events {
+		if t.Sub(c.start) >= eventWindow {
+			delete(q.events, namespace)
+		}
+	}
+	q.lastSweep = t
+}
+
+// AllowEvent reports whether an event of the namespace is stored, it
+// counts the events in windows of one minute.
+func AllowEvent(namespace string) bool {
+	q := current.Load()
+	if q == nil || namespace == "" {
+		return true
+	}
+
+	limit := q.limits(namespace).EventsPerMinute
+	if limit == 0 {
+		return true
+	}
+
+	t := now()
+
+	q.mu.Lock()
+	q.sweep(t)
+	c, ok := q.events[namespace]
+	if !ok || t.Sub(c.start) >= eventWindow {
+		c = &eventCount{start: t}
+		q.events[namespace] = c
+	}
+	allowed := c.count < limit
+	if allowed {
+		c.count++
+	}
+	q.mu.Unlock()
+
+	if !allowed {
+		exceeded.WithLabelValues(namespace, KindEvents).Inc()
+	}
+	return allowed
+}
+
+// sweepSeries forgets the series not exported for seriesIdle, e.g. of the
+// deleted containers, freeing their room.
+func (q *quota) sweepSeries(t time.Time) {
+	if t.Sub(q.lastSeriesSweep) < seriesIdle {
+		return
+	}
+	for namespace, series := range q.series {
+		for id, seen := range series {
+			if t.Sub(seen) >= seriesIdle {
+				delete(series, id)
+			}
+		}
+		if len(series) == 0 {
+			delete(q.series, namespace)
+		}
+	}
+	q.lastSeriesSweep = t
+}
+
+// AllowSeries reports whether the series id of the namespace is exported.
+// The series admitted keep their room while they are exported, whichever
+// collector or scrape endpoint exports them, so the same series are kept
+// from one scrape to the next. A new series is admitted when there is
+// room left.
+func AllowSeries(namespace string, id uint64) bool {
+	q := current.Load()
+	if q == nil || namespace == "" {
+		return true
+	}
+
+	limit := q.limits(namespace).MetricSeries
+	if limit == 0 {
+		return true
+	}
+
+	t := now()
+
+	q.seriesMu.Lock()
+	q.sweepSeries(t)
+	series, ok := q.series[namespace]
+	if !ok {
+		series = make(map[uint64]time.Time)
+		q.series[namespace] = series
+	}
+	_, allowed := series[id]
+	if allowed || len(series) < limit {
+		series[id] = t
+		allowed = true
+	}
+	q.seriesMu.Unlock()
+
+	if !allowed {
+		exceeded.WithLabelValues(namespace, KindMetricSeries).Inc()
+	}
+	return allowed
+}
@@ -0,0 +1,170 @@
+// Copyright 2026 The HuaTuo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package quota
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	dto "github.com/prometheus/client_model/go"
+)
+
+func exceededCount(t *testing.T, namespace, kind string) float64 {
+	t.Helper()
+
+	var m dto.Metric
+	if err := exceeded.WithLabelValues(namespace, kind).Write(&m); err != nil {
+		t.Fatalf("write metric: %v", err)
+	}
+	return m.GetCounter().GetValue()
+}
+
+func setQuota(t *testing.T, cfg *Config) {
+	t.Helper()
+
+	if err := Set(cfg); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+	t.Cleanup(func() {
+		current.Store(nil)
+		now = time.Now
+		exceeded.Reset()
+	})
+}
+
+func TestAllowEvent(t *testing.T) {
+	clock := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	setQuota(t, &Config{
+		Default:    Limits{EventsPerMinute: 2},
+		Namespaces: map[string]Limits{"system": {}},
+	})
+	now = func() time.Time { return clock }
+
+	for i, want := range []bool{true, true, false, false} {
+		if got := AllowEvent("tenant"); got != want {
+			t.Errorf("event %d AllowEvent(tenant) = %v, want %v", i, got, want)
+		}
+	}
+	for i := 0; i < 5; i++ {
+		if !AllowEvent("system") || !AllowEvent("") {
+			t.Fatal("unlimited namespace or host event dropped")
+		}
+	}
+	if got := exceededCount(t, "tenant", KindEvents); got != 2 {
+		t.Errorf("exceeded events of tenant = %v, want 2", got)
+	}
+
+	// the next window starts over.
+	clock = clock.Add(time.Minute)
+	if !AllowEvent("tenant") {
+		t.Error("AllowEvent(tenant) in a new window = false")
+	}
+}
+
+func TestAllowSeries(t *testing.T) {
+	if !AllowSeries("tenant", 1) {
+		t.Fatal("series must not be limited without quota")
+	}
+
+	clock := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	setQuota(t, &Config{Namespaces: map[string]Limits{"tenant": {MetricSeries: 3}}})
+	now = func() time.Time { return clock }
+
+	for id := uint64(1); id <= 5; id++ {
+		if got, want := AllowSeries("tenant", id), id <= 3; got != want {
+			t.Errorf("AllowSeries(tenant, %d) = %v, want %v", id, got, want)
+		}
+		if !AllowSeries("other", id) || !AllowSeries("", id) {
+			t.Fatal("namespace without limit or host series dropped")
+		}
+	}
+	if got := exceededCount(t, "tenant", KindMetricSeries); got != 2 {
+		t.Errorf("exceeded series of tenant = %v, want 2", got)
+	}
+
+	// the next scrape keeps the series admitted, a new one waits for the
+	// room of a series gone for seriesIdle.
+	clock = clock.Add(time.Minute)
+	if !AllowSeries("tenant", 2) || !AllowSeries("tenant", 3) || AllowSeries("tenant", 4) {
+		t.Error("the series admitted must be kept across scrapes")
+	}
+	clock = clock.Add(seriesIdle - time.Second)
+	if !AllowSeries("tenant", 4) || AllowSeries("tenant", 5) {
+		t.Error("the room of the series gone, and of it only, must be freed")
+	}
+}
+
+func TestAllowSeriesConcurrent(t *testing.T) {
+	setQuota(t, &Config{Default: Limits{MetricSeries: 10}})
+
+	// two collectors of 8 series each, in scrapes run concurrently as by
+	// the endpoints of the metric groups.
+	scrape := func() map[uint64]bool {
+		var mu sync.Mutex
+		var wg sync.WaitGroup
+		allowed := make(map[uint64]bool)
+		for c := uint64(0); c < 2; c++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for i := uint64(0); i < 8; i++ {
+					id := c*100 + i
+					if AllowSeries("tenant", id) {
+						mu.Lock()
+						allowed[id] = true
+						mu.Unlock()
+					}
+				}
+			}()
+		}
+		wg.Wait()
+		return allowed
+	}
+
+	first := scrape()
+	if len(first) != 10 {
+		t.Fatalf("first scrape allowed %d series, want 10", len(first))
+	}
+	for i := 0; i < 5; i++ {
+		next := scrape()
+		if len(next) != len(first) {
+			t.Fatalf("scrape %d allowed %d series, want %d", i, len(next), len(first))
+		}
+		for id := range first {
+			if !next[id] {
+				t.Fatalf("scrape %d dropped series %d admitted before", i, id)
+			}
+		}
+	}
+}
+
+func TestSet(t *testing.T) {
+	t.Cleanup(func() { current.Store(nil) })
+
+	if err := Set(&Config{Namespaces: map[string]Limits{"a": {}}}); err != nil || current.Load() != nil {
+		t.Errorf("config without limit must disable the quotas, err = %v", err)
+	}
+
+	for _, cfg := range []Config{
+		{Default: Limits{EventsPerMinute: -1}},
+		{Namespaces: map[string]Limits{"a": {MetricSeries: -1}}},
+		{Namespaces: map[string]Limits{"": {MetricSeries: 1}}},
+	} {
+		if err := Set(&cfg); err == nil {
+			t.Errorf("Set(%+v) error = nil", cfg)
+		}
+	}
+}
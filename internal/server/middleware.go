@@ -0,0 +1,204 @@
+// Copyright 2026 The HuaTuo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"runtime/debug"
+	"strconv"
+	"strings"
+	"time"
+
+	"huatuo-bamai/internal/log"
+	"huatuo-bamai/internal/server/response"
+
+	httpGin "github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// httpMetrics are the metrics of the requests, nil when the server has no
+// registry.
+type httpMetrics struct {
+	requests *prometheus.CounterVec
+	duration *prometheus.HistogramVec
+	inFlight prometheus.Gauge
+	slow     *prometheus.CounterVec
+	panics   *prometheus.CounterVec
+}
+
+func newHTTPMetrics(reg prometheus.Registerer) *httpMetrics {
+	m := &httpMetrics{
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "huatuo",
+			Subsystem: "http_server",
+			Name:      "requests_total",
+			Help:      "Total API requests by route, method, and status.",
+		}, []string{"route", "method", "status"}),
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: "huatuo",
+			Subsystem: "http_server",
+			Name:      "request_duration_seconds",
+			Help:      "API request duration by route and method.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"route", "method"}),
+		inFlight: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: "huatuo",
+			Subsystem: "http_server",
+			Name:      "requests_in_flight",
+			Help:      "API requests being served.",
+		}),
+		slow: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "huatuo",
+			Subsystem: "http_server",
+			Name:      "slow_requests_total",
+			Help:      "API requests slower than the slow request threshold by route and method.",
+		}, []string{"route", "method"}),
+		panics: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "huatuo",
+			Subsystem: "http_server",
+			Name:      "panics_total",
+			Help:      "API handler panics recovered by route.",
+		}, []string{"route"}),
+	}
+	reg.MustRegister(m.requests, m.duration, m.inFlight, m.slow, m.panics)
+	return m
+}
+
+// routeLabel is the route pattern of the request, never its raw path, so
+// the labels stay bounded.
+func routeLabel(ctx *httpGin.Context) string {
+	if route := ctx.FullPath(); route != "" {
+		return route
+	}
+	return "unmatched"
+}
+
+// isStreaming tells the responses which last as long as the client wants,
+// e.g. the events watch, they are never slow.
+func isStreaming(ctx *httpGin.Context) bool {
+	return strings.HasPrefix(ctx.Writer.Header().Get("Content-Type"), "text/event-stream")
+}
+
+// maxBodyBytesMiddleware rejects the requests announcing a body over limit
+// and caps the bodies sent without a length.
+func maxBodyBytesMiddleware(limit int64) httpGin.HandlerFunc {
+	return func(ctx *httpGin.Context) {
+		if ctx.Request.ContentLength > limit {
+			ctx.AbortWithStatusJSON(http.StatusRequestEntityTooLarge, response.Response{
+				Code:    http.StatusRequestEntityTooLarge,
+				Message: fmt.Sprintf("request body exceeds %d bytes", limit),
+			})
+			return
+		}
+		if ctx.Request.Body != nil {
+			ctx.Request.Body = http.MaxBytesReader(ctx.Writer, ctx.Request.Body, limit)
+		}
+		ctx.Next()
+	}
+}
+
+// accessLogMiddleware logs every request with its client, sizes and
+// latency. The slow requests and the server errors are logged as warnings
+// and the slow ones counted, the streaming responses are never slow.
+func accessLogMiddleware(slowThreshold time.Duration, metrics *httpMetrics) httpGin.HandlerFunc {
+	return func(ctx *httpGin.Context) {
+		startedAt := time.Now()
+		if metrics != nil {
+			metrics.inFlight.Inc()
+			defer metrics.inFlight.Dec()
+		}
+
+		ctx.Next()
+
+		latency := time.Since(startedAt)
+		status := ctx.Writer.Status()
+		entry := log.WithField("method", ctx.Request.Method).
+			WithField("route", routeLabel(ctx)).
+			WithField("path", ctx.Request.URL.Path).
+			WithField("status", status).
+			WithField("latency", latency).
+			WithField("client_ip", ctx.ClientIP()).
+			WithField("request_bytes", ctx.Request.ContentLength).
+			WithField("response_bytes", ctx.Writer.Size())
+		if user := internalContext(ctx).UserID; user != "" {
+			entry = entry.WithField("user", user)
+		}
+
+		slow := slowThreshold > 0 && latency >= slowThreshold && !isStreaming(ctx)
+		switch {
+		case slow:
+			if metrics != nil {
+				metrics.slow.WithLabelValues(routeLabel(ctx), ctx.Request.Method).Inc()
+			}
+			entry.Warn("slow http request")
+		case status >= http.StatusInternalServerError:
+			entry.Warn("http request failed")
+		default:
+			entry.Info("http request completed")
+		}
+	}
+}
+
+// metricsMiddleware counts the requests and observes their latency by
+// route.
+func metricsMiddleware(metrics *httpMetrics) httpGin.HandlerFunc {
+	return func(ctx *httpGin.Context) {
+		startedAt := time.Now()
+		ctx.Next()
+		route := routeLabel(ctx)
+		status := strconv.Itoa(ctx.Writer.Status())
+		metrics.requests.WithLabelValues(route, ctx.Request.Method, status).Inc()
+		metrics.duration.WithLabelValues(route, ctx.Request.Method).Observe(time.Since(startedAt).Seconds())
+	}
+}
+
+// recoveryMiddleware turns the panics of the handlers into 500 responses,
+// logged with their stack, so a bad request never takes the process down.
+// A response already started is cut short instead.
+func recoveryMiddleware(metrics *httpMetrics) httpGin.HandlerFunc {
+	return func(ctx *httpGin.Context) {
+		defer func() {
+			rec := recover()
+			if rec == nil {
+				return
+			}
+			// the client went away, net/http handles it silently.
+			if err, ok := rec.(error); ok && errors.Is(err, http.ErrAbortHandler) {
+				panic(rec)
+			}
+
+			if metrics != nil {
+				metrics.panics.WithLabelValues(routeLabel(ctx)).Inc()
+			}
+			log.WithField("method", ctx.Request.Method).
+				WithField("route", routeLabel(ctx)).
+				WithField("panic", fmt.Sprint(rec)).
+				WithField("stack", string(debug.Stack())).
+				Error("http handler panic recovered")
+
+			if ctx.Writer.Written() {
+				ctx.Abort()
+				return
+			}
+			ctx.AbortWithStatusJSON(http.StatusInternalServerError, response.Response{
+				Code:    response.ErrInternal.Code,
+				Message: "internal server error",
+			})
+		}()
+		ctx.Next()
+	}
+}
@@ -0,0 +1,138 @@
+// Copyright 2026 The HuaTuo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+// counterValue returns the sum of the samples of a metric family.
+func counterValue(t *testing.T, reg *prometheus.Registry, name string) float64 {
+	t.Helper()
+
+	families, err := reg.Gather()
+	if err != nil {
+		t.Fatalf("Gather() error = %v", err)
+	}
+	var sum float64
+	for _, family := range families {
+		if family.GetName() != name {
+			continue
+		}
+		for _, m := range family.GetMetric() {
+			switch family.GetType() {
+			case dto.MetricType_COUNTER:
+				sum += m.GetCounter().GetValue()
+			case dto.MetricType_GAUGE:
+				sum += m.GetGauge().GetValue()
+			}
+		}
+	}
+	return sum
+}
+
+func TestRecoveryMiddleware(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	s := NewServer(&Config{PromReg: reg})
+	s.MustRegisterRoutes("", []Handle{
+		{Typ: HttpGet, Uri: "/panic", Handle: func(*Context) error { panic("handler bug") }},
+	})
+
+	recorder := httptest.NewRecorder()
+	s.engine.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/panic", http.NoBody))
+
+	if recorder.Code != http.StatusInternalServerError {
+		t.Errorf("response status = %d, want %d", recorder.Code, http.StatusInternalServerError)
+	}
+	if !strings.Contains(recorder.Body.String(), "internal server error") {
+		t.Errorf("response body = %q, want the internal error", recorder.Body.String())
+	}
+	if got := counterValue(t, reg, "huatuo_http_server_panics_total"); got != 1 {
+		t.Errorf("panics = %g, want 1", got)
+	}
+	if got := counterValue(t, reg, "huatuo_http_server_requests_total"); got != 1 {
+		t.Errorf("requests = %g, want 1", got)
+	}
+
+	// the server still serves.
+	recorder = httptest.NewRecorder()
+	s.engine.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/healthz", http.NoBody))
+	if recorder.Code != http.StatusNoContent {
+		t.Errorf("healthz status = %d, want %d", recorder.Code, http.StatusNoContent)
+	}
+}
+
+func TestAccessLogMiddlewareSlowRequests(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	s := NewServer(&Config{PromReg: reg, SlowRequestThreshold: 10 * time.Millisecond})
+	s.MustRegisterRoutes("", []Handle{
+		{Typ: HttpGet, Uri: "/slow", Handle: func(ctx *Context) error {
+			time.Sleep(20 * time.Millisecond)
+			ctx.Status(http.StatusNoContent)
+			return nil
+		}},
+		{Typ: HttpGet, Uri: "/stream", Handle: func(ctx *Context) error {
+			ctx.Header("Content-Type", "text/event-stream")
+			time.Sleep(20 * time.Millisecond)
+			ctx.Status(http.StatusOK)
+			return nil
+		}},
+	})
+
+	for _, path := range []string{"/slow", "/stream", "/healthz"} {
+		s.engine.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, http.NoBody))
+	}
+
+	if got := counterValue(t, reg, "huatuo_http_server_slow_requests_total"); got != 1 {
+		t.Errorf("slow requests = %g, want 1", got)
+	}
+	if got := counterValue(t, reg, "huatuo_http_server_requests_in_flight"); got != 0 {
+		t.Errorf("requests in flight = %g, want 0", got)
+	}
+}
+
+func TestMaxBodyBytesMiddleware(t *testing.T) {
+	s := NewServer(&Config{MaxBodyBytes: 8})
+	s.MustRegisterRoutes("", []Handle{
+		{Typ: HttpPost, Uri: "/echo", Handle: func(ctx *Context) error {
+			var body map[string]any
+			if err := ctx.ShouldBindJSON(&body); err != nil {
+				ctx.Status(http.StatusBadRequest)
+				return nil
+			}
+			ctx.Status(http.StatusNoContent)
+			return nil
+		}},
+	})
+
+	recorder := httptest.NewRecorder()
+	s.engine.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/echo", strings.NewReader(`{"key":"a long value"}`)))
+	if recorder.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("response status = %d, want %d", recorder.Code, http.StatusRequestEntityTooLarge)
+	}
+
+	recorder = httptest.NewRecorder()
+	s.engine.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/echo", strings.NewReader(`{}`)))
+	if recorder.Code != http.StatusNoContent {
+		t.Errorf("response status = %d, want %d", recorder.Code, http.StatusNoContent)
+	}
+}
@@ -20,7 +20,6 @@ import (
 	"fmt"
 	"net"
 	"net/http"
-	"strconv"
 	"sync"
 	"syscall"
 	"time"
@@ -48,6 +47,7 @@ type Config struct {
 	PublicPaths       []string
 	AdminPaths        []string
 	PromReg           *prometheus.Registry
+	PromGroups        map[string]prometheus.Gatherer
 	Group             string
 	VersionInfo       *version.Info
 	ReadHeaderTimeout time.Duration
@@ -56,7 +56,14 @@ type Config struct {
 	IdleTimeout       time.Duration
 	MaxHeaderBytes    int
 	MaxBodyBytes      int64
-	Ready             func(context.Context) error
+	// SigningKey, when set, requires the SignedPaths requests be signed
+	// with it, signed within SignatureMaxAge. They need no bearer token.
+	SigningKey      []byte
+	SignedPaths     []string
+	SignatureMaxAge time.Duration
+	// SlowRequestThreshold logs the requests taking longer as slow.
+	SlowRequestThreshold time.Duration
+	Ready                func(context.Context) error
 }
 
 var defaultConfig = &Config{
@@ -73,6 +80,9 @@ var defaultConfig = &Config{
 	IdleTimeout:       120 * time.Second,
 	MaxHeaderBytes:    1 << 20,
 	MaxBodyBytes:      4 << 20,
+	SignatureMaxAge:   5 * time.Minute,
+
+	SlowRequestThreshold: time.Second,
 }
 
 // Server is an HTTP server instance.
@@ -209,19 +219,31 @@ func NewServer(cfg *Config) *server {
 		config:       *cfg,
 	}
 
+	var metrics *httpMetrics
+	if cfg.PromReg != nil {
+		metrics = newHTTPMetrics(cfg.PromReg)
+	}
 	middleWares := []httpGin.HandlerFunc{
 		middlewareContext(),
 		maxBodyBytesMiddleware(cfg.MaxBodyBytes),
-		requestLogMiddleware(),
-		httpGin.Recovery(),
+		accessLogMiddleware(cfg.SlowRequestThreshold, metrics),
 	}
-	if cfg.PromReg != nil {
-		middleWares = append(middleWares, newHTTPMetricsMiddleware(cfg.PromReg))
+	if metrics != nil {
+		middleWares = append(middleWares, metricsMiddleware(metrics))
+	}
+	middleWares = append(middleWares, recoveryMiddleware(metrics))
+
+	signed := len(cfg.SigningKey) > 0 && len(cfg.SignedPaths) > 0
+	if signed {
+		middleWares = append(middleWares, wrapHandler(NewSignatureMiddleware(cfg.SigningKey, cfg.SignatureMaxAge, cfg.SignedPaths)))
 	}
 
 	if cfg.RequireAuth || len(cfg.AuthUsers) > 0 {
 		svc := NewAuthService(cfg.AuthUsers)
 		publicPaths := append([]string{"/healthz", "/readyz", "/metrics", "/version"}, cfg.PublicPaths...)
+		if signed {
+			publicPaths = append(publicPaths, cfg.SignedPaths...)
+		}
 		adminPaths := append([]string{"/debug/pprof", "/debug/pprof/**"}, cfg.AdminPaths...)
 		middleWares = append(middleWares, wrapHandler(NewAuthMiddleware(svc, publicPaths, adminPaths)))
 	}
@@ -264,58 +286,14 @@ func normalizeServerConfig(cfg *Config) {
 	if cfg.MaxHeaderBytes <= 0 {
 		cfg.MaxHeaderBytes = defaultConfig.MaxHeaderBytes
 	}
+	if cfg.SignatureMaxAge <= 0 {
+		cfg.SignatureMaxAge = defaultConfig.SignatureMaxAge
+	}
 	if cfg.MaxBodyBytes <= 0 {
 		cfg.MaxBodyBytes = defaultConfig.MaxBodyBytes
 	}
-}
-
-func maxBodyBytesMiddleware(limit int64) httpGin.HandlerFunc {
-	return func(ctx *httpGin.Context) {
-		if ctx.Request.Body != nil {
-			ctx.Request.Body = http.MaxBytesReader(ctx.Writer, ctx.Request.Body, limit)
-		}
-		ctx.Next()
-	}
-}
-
-func requestLogMiddleware() httpGin.HandlerFunc {
-	return func(ctx *httpGin.Context) {
-		startedAt := time.Now()
-		ctx.Next()
-		log.WithField("method", ctx.Request.Method).
-			WithField("path", ctx.FullPath()).
-			WithField("status", ctx.Writer.Status()).
-			WithField("latency", time.Since(startedAt)).
-			Info("http request completed")
-	}
-}
-
-func newHTTPMetricsMiddleware(reg prometheus.Registerer) httpGin.HandlerFunc {
-	requests := prometheus.NewCounterVec(prometheus.CounterOpts{
-		Namespace: "huatuo",
-		Subsystem: "http_server",
-		Name:      "requests_total",
-		Help:      "Total API requests by route, method, and status.",
-	}, []string{"route", "method", "status"})
-	duration := prometheus.NewHistogramVec(prometheus.HistogramOpts{
-		Namespace: "huatuo",
-		Subsystem: "http_server",
-		Name:      "request_duration_seconds",
-		Help:      "API request duration by route and method.",
-		Buckets:   prometheus.DefBuckets,
-	}, []string{"route", "method"})
-	reg.MustRegister(requests, duration)
-
-	return func(ctx *httpGin.Context) {
-		startedAt := time.Now()
-		ctx.Next()
-		route := ctx.FullPath()
-		if route == "" {
-			route = "unmatched"
-		}
-		status := strconv.Itoa(ctx.Writer.Status())
-		requests.WithLabelValues(route, ctx.Request.Method, status).Inc()
-		duration.WithLabelValues(route, ctx.Request.Method).Observe(time.Since(startedAt).Seconds())
+	if cfg.SlowRequestThreshold <= 0 {
+		cfg.SlowRequestThreshold = defaultConfig.SlowRequestThreshold
 	}
 }
 
@@ -342,6 +320,8 @@ func (s *server) readyzHandler() ErrHandlerContextFunc {
 	}
 }
 
+// promServerHandler serves the registry, or one of the PromGroups selected by
+// the group parameter, e.g. /metrics?group=slow.
 func (s *server) promServerHandler() ErrHandlerContextFunc {
 	if s.promRegistry == nil {
 		return func(ctx *Context) error {
@@ -350,12 +330,29 @@ func (s *server) promServerHandler() ErrHandlerContextFunc {
 		}
 	}
 
-	h := promhttp.HandlerFor(s.promRegistry, promhttp.HandlerOpts{
+	opts := promhttp.HandlerOpts{
 		ErrorHandling: promhttp.ContinueOnError,
 		Timeout:       30 * time.Second,
-	})
+	}
+	h := promhttp.HandlerFor(s.promRegistry, opts)
+	groups := make(map[string]http.Handler, len(s.config.PromGroups))
+	for name, gatherer := range s.config.PromGroups {
+		groups[name] = promhttp.HandlerFor(gatherer, opts)
+	}
+
 	return func(ctx *Context) error {
-		h.ServeHTTP(ctx.Writer(), ctx.Request())
+		group := ctx.Request().URL.Query().Get("group")
+		if group == "" {
+			h.ServeHTTP(ctx.Writer(), ctx.Request())
+			return nil
+		}
+
+		gh, ok := groups[group]
+		if !ok {
+			ctx.JSON(http.StatusNotFound, map[string]any{"status": fmt.Sprintf("metric group %s not found", group)})
+			return nil
+		}
+		gh.ServeHTTP(ctx.Writer(), ctx.Request())
 		return nil
 	}
 }
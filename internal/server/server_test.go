@@ -191,6 +191,42 @@ func TestPromServerHandlerWithRegistry(t *testing.T) {
 	}
 }
 
+func TestPromServerHandlerGroups(t *testing.T) {
+	slow := prometheus.NewRegistry()
+	slow.MustRegister(prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Name: "slow_metric",
+		Help: "a metric of the slow group",
+	}, func() float64 { return 1 }))
+
+	s := &server{
+		promRegistry: prometheus.NewRegistry(),
+		config:       Config{PromGroups: map[string]prometheus.Gatherer{"slow": slow}},
+	}
+	handler := s.promServerHandler()
+
+	tests := []struct {
+		uri        string
+		wantStatus int
+		wantSlow   bool
+	}{
+		{"/metrics", http.StatusOK, false},
+		{"/metrics?group=slow", http.StatusOK, true},
+		{"/metrics?group=fast", http.StatusNotFound, false},
+	}
+	for _, tt := range tests {
+		ctx, recorder := newTestServerContext(http.MethodGet, tt.uri, "")
+		if err := handler(ctx); err != nil {
+			t.Fatalf("%s: promServerHandler() error = %v", tt.uri, err)
+		}
+		if recorder.Code != tt.wantStatus {
+			t.Errorf("%s: status = %d, want %d", tt.uri, recorder.Code, tt.wantStatus)
+		}
+		if got := strings.Contains(recorder.Body.String(), "slow_metric"); got != tt.wantSlow {
+			t.Errorf("%s: has slow_metric = %v, want %v", tt.uri, got, tt.wantSlow)
+		}
+	}
+}
+
 func TestNewRateLimitMiddleware(t *testing.T) {
 	httpGin.SetMode(httpGin.TestMode)
 
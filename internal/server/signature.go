@@ -0,0 +1,183 @@
+// Copyright 2026 The HuaTuo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"huatuo-bamai/internal/log"
+	"huatuo-bamai/internal/server/response"
+)
+
+// The headers of the signed requests, the central server signs the
+// commands it sends to the agents with the key they share.
+const (
+	HeaderSignature = "X-Huatuo-Signature"
+	HeaderTimestamp = "X-Huatuo-Timestamp"
+	// HeaderNonce is random per request, a signed request is accepted
+	// once.
+	HeaderNonce = "X-Huatuo-Nonce"
+	// HeaderOperator is the user of the central server the command is
+	// sent for, recorded by the audit of the agent.
+	HeaderOperator = "X-Huatuo-Operator"
+)
+
+// SignedActorPrefix prefixes the operator of a signed request in
+// Context.UserID.
+const SignedActorPrefix = "server:"
+
+// ErrInvalidSignature is returned for a request without a valid signature.
+var ErrInvalidSignature = errors.New("invalid request signature")
+
+// signaturePayload is what the signature covers: the request line, the
+// time it was signed at, the nonce, the operator and the hash of the body.
+func signaturePayload(req *http.Request, timestamp, nonce, operator string, body []byte) []byte {
+	sum := sha256.Sum256(body)
+	return []byte(strings.Join([]string{
+		req.Method,
+		req.URL.RequestURI(),
+		timestamp,
+		nonce,
+		operator,
+		hex.EncodeToString(sum[:]),
+	}, "\n"))
+}
+
+func signatureOf(key, payload []byte) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write(payload)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// SignRequest signs the request of the body for the operator at now, with
+// a new nonce.
+func SignRequest(req *http.Request, body, key []byte, operator string, now time.Time) {
+	timestamp := strconv.FormatInt(now.Unix(), 10)
+	nonce := rand.Text()
+	req.Header.Set(HeaderTimestamp, timestamp)
+	req.Header.Set(HeaderNonce, nonce)
+	if operator != "" {
+		req.Header.Set(HeaderOperator, operator)
+	}
+	req.Header.Set(HeaderSignature, signatureOf(key, signaturePayload(req, timestamp, nonce, operator, body)))
+}
+
+// VerifyRequest checks the signature of the request of the body, signed
+// within maxAge of now, and returns its operator. The nonce is not checked
+// against the requests already accepted, see NewSignatureMiddleware.
+func VerifyRequest(req *http.Request, body, key []byte, maxAge time.Duration, now time.Time) (string, error) {
+	timestamp := req.Header.Get(HeaderTimestamp)
+	nonce := req.Header.Get(HeaderNonce)
+	signature := req.Header.Get(HeaderSignature)
+	if timestamp == "" || nonce == "" || signature == "" {
+		return "", fmt.Errorf("%w: not signed", ErrInvalidSignature)
+	}
+
+	unix, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return "", fmt.Errorf("%w: timestamp %q", ErrInvalidSignature, timestamp)
+	}
+	if age := now.Sub(time.Unix(unix, 0)).Abs(); age > maxAge {
+		return "", fmt.Errorf("%w: signed %s away, more than %s", ErrInvalidSignature, age, maxAge)
+	}
+
+	operator := req.Header.Get(HeaderOperator)
+	want := signatureOf(key, signaturePayload(req, timestamp, nonce, operator, body))
+	if !hmac.Equal([]byte(signature), []byte(want)) {
+		return "", fmt.Errorf("%w: signature mismatch", ErrInvalidSignature)
+	}
+	return operator, nil
+}
+
+// signatureNonces are the nonces of the requests accepted, until their
+// signatures expire.
+type signatureNonces struct {
+	mu      sync.Mutex
+	expires map[string]time.Time
+}
+
+// add records the nonce until expires, it returns false for a nonce
+// already seen.
+func (n *signatureNonces) add(nonce string, expires, now time.Time) bool {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	for seen, at := range n.expires {
+		if !now.Before(at) {
+			delete(n.expires, seen)
+		}
+	}
+	if _, ok := n.expires[nonce]; ok {
+		return false
+	}
+	n.expires[nonce] = expires
+	return true
+}
+
+// NewSignatureMiddleware returns a HandlerContextFunc rejecting the requests
+// of the paths, matched as the auth paths e.g. "/tasks/**", without a valid
+// signature, or replayed within maxAge. The operator of a signed request is
+// set as the Context.UserID, prefixed by SignedActorPrefix.
+func NewSignatureMiddleware(key []byte, maxAge time.Duration, paths []string) HandlerContextFunc {
+	nonces := &signatureNonces{expires: make(map[string]time.Time)}
+
+	return func(ctx *Context) {
+		req := ctx.Request()
+		if !matchesAnyPath(&authService{}, paths, req.URL.Path) {
+			ctx.Next()
+			return
+		}
+
+		body, err := io.ReadAll(req.Body)
+		if err != nil {
+			response.ErrorWithCode(ctx, http.StatusBadRequest, response.ErrInvalidRequest.Code, "read body: "+err.Error())
+			ctx.Abort()
+			return
+		}
+		req.Body = io.NopCloser(bytes.NewReader(body))
+
+		now := time.Now()
+		operator, err := VerifyRequest(req, body, key, maxAge, now)
+		if err == nil {
+			// the timestamp is valid, the nonce is kept until the
+			// signature is rejected as expired.
+			signedAt, _ := strconv.ParseInt(req.Header.Get(HeaderTimestamp), 10, 64)
+			if !nonces.add(req.Header.Get(HeaderNonce), time.Unix(signedAt, 0).Add(maxAge+time.Second), now) {
+				err = fmt.Errorf("%w: nonce replayed", ErrInvalidSignature)
+			}
+		}
+		if err != nil {
+			log.Warnf("rejected %s %s from %s: %v", req.Method, req.URL.Path, ctx.ClientIP(), err)
+			response.ErrorWithCode(ctx, http.StatusUnauthorized, response.ErrUnauthorized.Code, err.Error())
+			ctx.Abort()
+			return
+		}
+
+		ctx.UserID = SignedActorPrefix + operator
+		ctx.Next()
+	}
+}
@@ -0,0 +1,222 @@
+// Copyright 2026 The HuaTuo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestVerifyRequest(t *testing.T) {
+	key := []byte("signing-key-2026")
+	body := []byte(`{"tracer_name":"perf"}`)
+	signedAt := time.Unix(1790000000, 0)
+
+	tests := []struct {
+		name     string
+		modify   func(req *http.Request) []byte
+		now      time.Time
+		operator string
+		wantErr  bool
+	}{
+		{
+			name:     "valid",
+			now:      signedAt.Add(time.Minute),
+			operator: "alice",
+		},
+		{
+			name: "valid without operator",
+			now:  signedAt,
+		},
+		{
+			name:    "expired",
+			now:     signedAt.Add(10 * time.Minute),
+			wantErr: true,
+		},
+		{
+			name:    "from the future",
+			now:     signedAt.Add(-10 * time.Minute),
+			wantErr: true,
+		},
+		{
+			name:    "not signed",
+			now:     signedAt,
+			modify:  func(req *http.Request) []byte { req.Header.Del(HeaderSignature); return body },
+			wantErr: true,
+		},
+		{
+			name:    "body modified",
+			now:     signedAt,
+			modify:  func(*http.Request) []byte { return []byte(`{"tracer_name":"sh"}`) },
+			wantErr: true,
+		},
+		{
+			name:    "path modified",
+			now:     signedAt,
+			modify:  func(req *http.Request) []byte { req.URL.Path = "/tasks/other"; return body },
+			wantErr: true,
+		},
+		{
+			name:    "no nonce",
+			now:     signedAt,
+			modify:  func(req *http.Request) []byte { req.Header.Del(HeaderNonce); return body },
+			wantErr: true,
+		},
+		{
+			name:    "nonce modified",
+			now:     signedAt,
+			modify:  func(req *http.Request) []byte { req.Header.Set(HeaderNonce, "other"); return body },
+			wantErr: true,
+		},
+		{
+			name: "operator modified",
+			now:  signedAt,
+			modify: func(req *http.Request) []byte {
+				req.Header.Set(HeaderOperator, "mallory")
+				return body
+			},
+			wantErr: true,
+		},
+		{
+			name: "signed with another key",
+			now:  signedAt,
+			modify: func(req *http.Request) []byte {
+				SignRequest(req, body, []byte("other-key"), "", signedAt)
+				return body
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/tasks", http.NoBody)
+			SignRequest(req, body, key, tt.operator, signedAt)
+
+			got := body
+			if tt.modify != nil {
+				got = tt.modify(req)
+			}
+
+			operator, err := VerifyRequest(req, got, key, 5*time.Minute, tt.now)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("VerifyRequest() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				if !errors.Is(err, ErrInvalidSignature) {
+					t.Errorf("VerifyRequest() error = %v, want ErrInvalidSignature", err)
+				}
+				return
+			}
+			if operator != tt.operator {
+				t.Errorf("VerifyRequest() operator = %q, want %q", operator, tt.operator)
+			}
+		})
+	}
+}
+
+func TestSignatureNonces(t *testing.T) {
+	nonces := &signatureNonces{expires: make(map[string]time.Time)}
+	now := time.Unix(1790000000, 0)
+
+	if !nonces.add("a", now.Add(5*time.Minute), now) {
+		t.Fatal("add() of a new nonce = false")
+	}
+	if nonces.add("a", now.Add(5*time.Minute), now.Add(time.Minute)) {
+		t.Error("add() of a replayed nonce = true")
+	}
+	if !nonces.add("b", now.Add(5*time.Minute), now.Add(time.Minute)) {
+		t.Error("add() of another nonce = false")
+	}
+
+	// the expired nonces are dropped.
+	if !nonces.add("c", now.Add(10*time.Minute), now.Add(5*time.Minute)) {
+		t.Error("add() of a new nonce = false")
+	}
+	if len(nonces.expires) != 1 {
+		t.Errorf("nonces = %v, want the unexpired one", nonces.expires)
+	}
+}
+
+func TestServerSignedPaths(t *testing.T) {
+	key := []byte("signing-key-2026")
+	srv := NewServer(&Config{
+		RequireAuth: true,
+		AuthUsers:   []UserConfig{{ID: "admin-2026", IsAdmin: true}},
+		SigningKey:  key,
+		SignedPaths: []string{"/tasks", "/tasks/**"},
+	})
+	srv.Group().POST("/tasks", func(ctx *Context) error {
+		body, err := io.ReadAll(ctx.Request().Body)
+		if err != nil {
+			return err
+		}
+		ctx.JSON(http.StatusOK, map[string]string{"user": ctx.UserID, "body": string(body)})
+		return nil
+	})
+	srv.Group().GET("/status", func(ctx *Context) error {
+		ctx.Status(http.StatusNoContent)
+		return nil
+	})
+
+	body := `{"tracer_name":"perf"}`
+
+	signed := httptest.NewRequest(http.MethodPost, "/tasks", strings.NewReader(body))
+	SignRequest(signed, []byte(body), key, "alice", time.Now())
+	recorder := httptest.NewRecorder()
+	srv.engine.ServeHTTP(recorder, signed)
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("signed status=%d, want %d: %s", recorder.Code, http.StatusOK, recorder.Body)
+	}
+	if got := recorder.Body.String(); !strings.Contains(got, `"user":"server:alice"`) ||
+		!strings.Contains(got, `"body":"{\"tracer_name\":\"perf\"}"`) {
+		t.Errorf("signed response = %s, want the operator and the body", got)
+	}
+
+	// a signed request is accepted once.
+	replayed := httptest.NewRequest(http.MethodPost, "/tasks", strings.NewReader(body))
+	replayed.Header = signed.Header.Clone()
+	recorder = httptest.NewRecorder()
+	srv.engine.ServeHTTP(recorder, replayed)
+	if recorder.Code != http.StatusUnauthorized {
+		t.Fatalf("replayed status=%d, want %d", recorder.Code, http.StatusUnauthorized)
+	}
+
+	// a bearer token does not replace the signature of the signed paths.
+	unsigned := httptest.NewRequest(http.MethodPost, "/tasks", strings.NewReader(body))
+	unsigned.Header.Set("Authorization", "Bearer admin-2026")
+	recorder = httptest.NewRecorder()
+	srv.engine.ServeHTTP(recorder, unsigned)
+	if recorder.Code != http.StatusUnauthorized {
+		t.Fatalf("unsigned status=%d, want %d", recorder.Code, http.StatusUnauthorized)
+	}
+
+	other := httptest.NewRequest(http.MethodGet, "/status", http.NoBody)
+	recorder = httptest.NewRecorder()
+	srv.engine.ServeHTTP(recorder, other)
+	if recorder.Code != http.StatusUnauthorized {
+		t.Fatalf("anonymous status=%d, want %d", recorder.Code, http.StatusUnauthorized)
+	}
+	other.Header.Set("Authorization", "Bearer admin-2026")
+	recorder = httptest.NewRecorder()
+	srv.engine.ServeHTTP(recorder, other)
+	if recorder.Code != http.StatusNoContent {
+		t.Fatalf("admin status=%d, want %d", recorder.Code, http.StatusNoContent)
+	}
+}
@@ -16,7 +16,10 @@ package storage
 
 import (
 	// Register all built-in storage backends.
+	_ "huatuo-bamai/internal/storage/clickhouse"
 	_ "huatuo-bamai/internal/storage/elasticsearch"
 	_ "huatuo-bamai/internal/storage/localfile"
+	_ "huatuo-bamai/internal/storage/loki"
+	_ "huatuo-bamai/internal/storage/otlp"
 	_ "huatuo-bamai/internal/storage/sqlite"
 )
@@ -0,0 +1,387 @@
+// Copyright 2026 The HuaTuo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package clickhouse implements a storage backend that persists records to
+// a ClickHouse table over the HTTP interface, with batched inserts.
+package clickhouse
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"huatuo-bamai/internal/storage/driver"
+)
+
+const (
+	defaultTable         = "huatuo_events"
+	defaultBatchSize     = 1000
+	defaultFlushInterval = time.Second
+
+	// insertRetries is the attempts of a batch, the rows are dropped after
+	// the last one. The waits between them grow from minBackoff.
+	insertRetries = 3
+	minBackoff    = 200 * time.Millisecond
+	maxBackoff    = 5 * time.Second
+
+	metricsBackend = "clickhouse"
+)
+
+// Config contains ClickHouse backend settings.
+type Config struct {
+	Address  string
+	Username string
+	Password string
+	Database string
+	Table    string
+	// CAFile verifies an https server instead of the system roots,
+	// InsecureSkipVerify does not verify it.
+	CAFile             string
+	InsecureSkipVerify bool
+	// BatchSize is the rows inserted at once, a batch is flushed earlier
+	// every FlushInterval.
+	BatchSize     int
+	FlushInterval time.Duration
+	// Retention is how long the rows are kept, zero keeps them forever.
+	Retention time.Duration
+}
+
+// Storage stores records in a ClickHouse table. The table is Config.Table,
+// the collection of Init is not used, every store of the events shares it.
+//
+// Save is asynchronous: the rows are queued and inserted in batches by
+// size, time, or Close. A nil error from Save means the row was buffered,
+// not that it landed in the table. Call Close on shutdown to flush the
+// pending rows.
+type Storage struct {
+	client    *client
+	table     string
+	retention time.Duration
+
+	batcher *driver.Batcher[pendingRow]
+
+	// FailureHandler passes the rows failing after the retries to the
+	// dead letter of the store.
+	driver.FailureHandler
+}
+
+type pendingRow struct {
+	queued time.Time
+	data   []byte
+	// rec is the ID and the Data of the record, for the dead letter.
+	rec driver.Record
+}
+
+func (r pendingRow) Queued() time.Time     { return r.queued }
+func (r pendingRow) Record() driver.Record { return r.rec }
+
+// row is a line of the JSONEachRow format of the inserts.
+type row struct {
+	ID           string `json:"id"`
+	TracerName   string `json:"tracer_name"`
+	TracerTime   string `json:"tracer_time"`
+	Hostname     string `json:"hostname"`
+	Region       string `json:"region"`
+	Fields       string `json:"fields"`
+	Payload      string `json:"payload"`
+	InsertedTime string `json:"inserted_time,omitempty"`
+}
+
+var (
+	_ driver.Backend         = (*Storage)(nil)
+	_ driver.Backlogger      = (*Storage)(nil)
+	_ driver.FailureNotifier = (*Storage)(nil)
+)
+
+func init() {
+	driver.RegisterBackend("clickhouse", func(cfg *driver.Config) (driver.Backend, error) {
+		return NewBackend(&Config{
+			Address:            cfg.ClickHouseAddress,
+			Username:           cfg.ClickHouseUsername,
+			Password:           cfg.ClickHousePassword,
+			Database:           cfg.ClickHouseDatabase,
+			Table:              cfg.ClickHouseTable,
+			CAFile:             cfg.ClickHouseCAFile,
+			InsecureSkipVerify: cfg.ClickHouseInsecureSkipVerify,
+			BatchSize:          cfg.ClickHouseBatchSize,
+			FlushInterval:      cfg.ClickHouseFlushInterval,
+			Retention:          cfg.ClickHouseRetention,
+		})
+	})
+}
+
+// NewBackend creates a ClickHouse backend and starts its flusher.
+func NewBackend(cfg *Config) (*Storage, error) {
+	table := cfg.Table
+	if table == "" {
+		table = defaultTable
+	}
+	if err := validateIdentifier(table); err != nil {
+		return nil, fmt.Errorf("clickhouse backend: table %q: %w", table, err)
+	}
+
+	client, err := newClient(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Storage{
+		client:    client,
+		table:     table,
+		retention: cfg.Retention,
+	}
+	batcher := &driver.BatcherConfig[pendingRow]{
+		Backend:       metricsBackend,
+		BatchSize:     cfg.BatchSize,
+		FlushInterval: cfg.FlushInterval,
+		Retries:       insertRetries,
+		MinBackoff:    minBackoff,
+		MaxBackoff:    maxBackoff,
+		Send:          s.insert,
+		Failure:       &s.FailureHandler,
+	}
+	if batcher.BatchSize <= 0 {
+		batcher.BatchSize = defaultBatchSize
+	}
+	if batcher.FlushInterval <= 0 {
+		batcher.FlushInterval = defaultFlushInterval
+	}
+	s.batcher = driver.NewBatcher(batcher)
+
+	return s, nil
+}
+
+// Init creates the table, adds the columns it misses and applies the
+// retention.
+func (s *Storage) Init(ctx context.Context, _ string, indexes []driver.Index) error {
+	for _, idx := range indexes {
+		if err := validateIdentifier(idx.Field); err != nil {
+			return err
+		}
+	}
+
+	ctx = driver.WithContext(ctx)
+	if err := s.client.exec(ctx, buildCreateTableSQL(s.table), nil, nil); err != nil {
+		return fmt.Errorf("clickhouse backend init table %s: %w", s.table, err)
+	}
+	for _, stmt := range buildAddColumnSQL(s.table) {
+		if err := s.client.exec(ctx, stmt, nil, nil); err != nil {
+			return fmt.Errorf("clickhouse backend init columns %s: %w", s.table, err)
+		}
+	}
+	if err := s.client.exec(ctx, buildTTLSQL(s.table, s.retention), nil, nil); err != nil {
+		return fmt.Errorf("clickhouse backend init ttl %s: %w", s.table, err)
+	}
+	return nil
+}
+
+func (s *Storage) Save(_ context.Context, rec driver.Record) error {
+	data, err := encodeRow(rec, time.Now())
+	if err != nil {
+		return err
+	}
+	return s.batcher.Save(pendingRow{queued: time.Now(), data: data, rec: driver.Record{ID: rec.ID, Data: rec.Data}})
+}
+
+func encodeRow(rec driver.Record, now time.Time) ([]byte, error) {
+	normalized := make(map[string]any, len(rec.Fields))
+	for k, v := range rec.Fields {
+		normalized[k] = driver.NormalizeValue(v)
+	}
+	fields, err := json.Marshal(normalized)
+	if err != nil {
+		return nil, fmt.Errorf("clickhouse backend marshal fields: %w", err)
+	}
+
+	tracerTime := now
+	if t, err := timeValue(rec.Fields[timeColumn]); err == nil {
+		tracerTime = t
+	}
+
+	data, err := json.Marshal(&row{
+		ID:           rec.ID,
+		TracerName:   driver.StringValue(rec.Fields["tracer_name"]),
+		TracerTime:   tracerTime.UTC().Format(timeLayout),
+		Hostname:     driver.StringValue(rec.Fields["hostname"]),
+		Region:       driver.StringValue(rec.Fields["region"]),
+		Fields:       string(fields),
+		Payload:      string(rec.Data),
+		InsertedTime: now.UTC().Format(timeLayout),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("clickhouse backend marshal row: %w", err)
+	}
+	return data, nil
+}
+
+// insert sends one batch of rows, every error is worth a retry.
+func (s *Storage) insert(ctx context.Context, batch []pendingRow) driver.BatchResult[pendingRow] {
+	var body bytes.Buffer
+	for _, r := range batch {
+		body.Write(r.data)
+		body.WriteByte('\n')
+	}
+
+	insertSQL := fmt.Sprintf("INSERT INTO %s FORMAT JSONEachRow", quoteIdentifier(s.table))
+	err := s.client.exec(ctx, insertSQL, nil, body.Bytes())
+	if err != nil {
+		err = fmt.Errorf("clickhouse insert into %s: %w", s.table, err)
+	}
+	return driver.BatchSent(batch, err, true)
+}
+
+// Backlog returns the rows queued and not yet inserted, it grows when the
+// server inserts slower than the node writes.
+func (s *Storage) Backlog() int64 {
+	return s.batcher.Backlog()
+}
+
+// Close stops the flusher and inserts the pending rows. The Storage must
+// not be reused.
+func (s *Storage) Close(ctx context.Context) error {
+	if s.batcher == nil {
+		return nil
+	}
+	s.batcher.Close(ctx)
+	return nil
+}
+
+func (s *Storage) Get(ctx context.Context, id string) (driver.Record, error) {
+	b := newSQLBuilder()
+	getSQL := fmt.Sprintf("SELECT id, payload, fields FROM %s WHERE id = %s ORDER BY inserted_time DESC LIMIT 1 FORMAT JSONEachRow",
+		quoteIdentifier(s.table), b.bind("String", id))
+
+	records, err := s.queryRecords(ctx, getSQL, b.params)
+	if err != nil {
+		return driver.Record{}, fmt.Errorf("clickhouse backend get from %s: %w", s.table, err)
+	}
+	if len(records) == 0 {
+		return driver.Record{}, driver.ErrNotFound
+	}
+	return records[0], nil
+}
+
+// Delete removes the rows of id with a lightweight delete.
+func (s *Storage) Delete(ctx context.Context, id string) error {
+	b := newSQLBuilder()
+	deleteSQL := fmt.Sprintf("DELETE FROM %s WHERE id = %s", quoteIdentifier(s.table), b.bind("String", id))
+	if err := s.client.exec(driver.WithContext(ctx), deleteSQL, b.params, nil); err != nil {
+		return fmt.Errorf("clickhouse backend delete from %s: %w", s.table, err)
+	}
+	return nil
+}
+
+func (s *Storage) Query(ctx context.Context, q driver.Query) ([]driver.Record, error) {
+	querySQL, params, err := buildSelectSQL(s.table, q)
+	if err != nil {
+		return nil, err
+	}
+
+	records, err := s.queryRecords(ctx, querySQL, params)
+	if err != nil {
+		return nil, fmt.Errorf("clickhouse backend query %s: %w", s.table, err)
+	}
+	return records, nil
+}
+
+func (s *Storage) queryRecords(ctx context.Context, query string, params map[string]string) ([]driver.Record, error) {
+	res, err := s.client.do(driver.WithContext(ctx), query, params, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Close()
+
+	records := make([]driver.Record, 0)
+	err = decodeRows(res, func(r *row) error {
+		fields := make(map[string]any)
+		if r.Fields != "" {
+			if err := json.Unmarshal([]byte(r.Fields), &fields); err != nil {
+				return fmt.Errorf("decode fields: %w", err)
+			}
+		}
+		records = append(records, driver.Record{ID: r.ID, Data: []byte(r.Payload), Fields: fields})
+		return nil
+	})
+	return records, err
+}
+
+func (s *Storage) Count(ctx context.Context, q driver.Query) (int64, error) {
+	countSQL, params, err := buildCountSQL(s.table, q)
+	if err != nil {
+		return 0, err
+	}
+
+	res, err := s.client.do(driver.WithContext(ctx), countSQL, params, nil)
+	if err != nil {
+		return 0, fmt.Errorf("clickhouse backend count %s: %w", s.table, err)
+	}
+	defer res.Close()
+
+	raw, err := io.ReadAll(res)
+	if err != nil {
+		return 0, fmt.Errorf("clickhouse backend count %s: %w", s.table, err)
+	}
+	count, err := strconv.ParseInt(strings.TrimSpace(string(raw)), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("clickhouse backend count %s: %w", s.table, err)
+	}
+	return count, nil
+}
+
+func (s *Storage) Values(ctx context.Context, field string, q driver.Query, size int) ([]string, error) {
+	valuesSQL, params, err := buildValuesSQL(s.table, field, q, size)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := s.client.do(driver.WithContext(ctx), valuesSQL, params, nil)
+	if err != nil {
+		return nil, fmt.Errorf("clickhouse backend values %s.%s: %w", s.table, field, err)
+	}
+	defer res.Close()
+
+	terms := make([]string, 0, size)
+	decoder := json.NewDecoder(res)
+	for decoder.More() {
+		var term struct {
+			Term any `json:"term"`
+		}
+		if err := decoder.Decode(&term); err != nil {
+			return nil, fmt.Errorf("clickhouse backend values %s.%s: %w: %w", s.table, field, driver.ErrDecodeFailed, err)
+		}
+		terms = append(terms, driver.StringValue(term.Term))
+	}
+	return terms, nil
+}
+
+// decodeRows reads the JSONEachRow lines of r. The payloads may be larger
+// than the default line limit of a scanner.
+func decodeRows(r io.Reader, fn func(*row) error) error {
+	decoder := json.NewDecoder(r)
+	for decoder.More() {
+		var line row
+		if err := decoder.Decode(&line); err != nil {
+			return fmt.Errorf("%w: %w", driver.ErrDecodeFailed, err)
+		}
+		if err := fn(&line); err != nil {
+			return err
+		}
+	}
+	return nil
+}
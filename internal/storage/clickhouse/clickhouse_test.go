@@ -0,0 +1,310 @@
+// Copyright 2026 The HuaTuo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clickhouse
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"huatuo-bamai/internal/storage/driver"
+)
+
+type testRequest struct {
+	query  string
+	params map[string]string
+	body   string
+	user   string
+}
+
+// testServer records the requests and answers them with respond.
+type testServer struct {
+	mu       sync.Mutex
+	requests []testRequest
+}
+
+func newTestServer(t *testing.T, respond func(query string) string) (*testServer, string) {
+	t.Helper()
+
+	ts := &testServer{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		req := testRequest{
+			query:  r.URL.Query().Get("query"),
+			params: make(map[string]string),
+			body:   string(body),
+			user:   r.Header.Get("X-ClickHouse-User"),
+		}
+		for name, values := range r.URL.Query() {
+			if param, ok := strings.CutPrefix(name, "param_"); ok {
+				req.params[param] = values[0]
+			}
+		}
+
+		ts.mu.Lock()
+		ts.requests = append(ts.requests, req)
+		ts.mu.Unlock()
+
+		if respond != nil {
+			_, _ = io.WriteString(w, respond(req.query))
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return ts, srv.URL
+}
+
+func (ts *testServer) inserts() []testRequest {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	var inserts []testRequest
+	for _, req := range ts.requests {
+		if strings.HasPrefix(req.query, "INSERT") {
+			inserts = append(inserts, req)
+		}
+	}
+	return inserts
+}
+
+func TestBackendInit(t *testing.T) {
+	ts, addr := newTestServer(t, nil)
+	backend, err := NewBackend(&Config{Address: addr, Username: "huatuo", Retention: 7 * 24 * time.Hour})
+	if err != nil {
+		t.Fatalf("NewBackend() error = %v", err)
+	}
+	t.Cleanup(func() { _ = backend.Close(t.Context()) })
+
+	if err := backend.Init(t.Context(), "tracing_documents", []driver.Index{{Field: "tracer_name"}}); err != nil {
+		t.Fatalf("Init() error = %v", err)
+	}
+
+	if len(ts.requests) != len(tableColumns)+2 {
+		t.Fatalf("Init() requests = %d, want %d", len(ts.requests), len(tableColumns)+2)
+	}
+	if q := ts.requests[0].query; !strings.HasPrefix(q, "CREATE TABLE IF NOT EXISTS `huatuo_events`") ||
+		!strings.Contains(q, "ReplacingMergeTree(inserted_time)") {
+		t.Errorf("Init() create = %q", q)
+	}
+	if q := ts.requests[len(ts.requests)-1].query; q != "ALTER TABLE `huatuo_events` MODIFY TTL toDateTime(tracer_time) + INTERVAL 7 DAY" {
+		t.Errorf("Init() ttl = %q", q)
+	}
+	if ts.requests[0].user != "huatuo" {
+		t.Errorf("Init() user = %q", ts.requests[0].user)
+	}
+
+	if err := backend.Init(t.Context(), "tracing_documents", []driver.Index{{Field: "bad-field"}}); !errors.Is(err, driver.ErrInvalidField) {
+		t.Errorf("Init() invalid index error = %v", err)
+	}
+}
+
+func TestBackendSaveBatches(t *testing.T) {
+	ts, addr := newTestServer(t, nil)
+	backend, err := NewBackend(&Config{Address: addr, BatchSize: 2, FlushInterval: time.Hour})
+	if err != nil {
+		t.Fatalf("NewBackend() error = %v", err)
+	}
+
+	tracerTime := time.Date(2026, 10, 16, 8, 0, 0, 0, time.UTC)
+	save := func(id string) {
+		err := backend.Save(t.Context(), driver.Record{
+			ID:   id,
+			Data: []byte(`{"tracer_data":{}}`),
+			Fields: map[string]any{
+				"tracer_name": "oom",
+				"tracer_time": tracerTime,
+				"hostname":    "node-1",
+				"region":      "r1",
+			},
+		})
+		if err != nil {
+			t.Fatalf("Save() error = %v", err)
+		}
+	}
+
+	// the full batch is flushed by the flusher, the rest by Close.
+	save("a")
+	save("b")
+	deadline := time.Now().Add(5 * time.Second)
+	for len(ts.inserts()) == 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	save("c")
+	if err := backend.Close(t.Context()); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	inserts := ts.inserts()
+	if len(inserts) != 2 {
+		t.Fatalf("inserts = %d, want 2", len(inserts))
+	}
+	if backlog := backend.Backlog(); backlog != 0 {
+		t.Errorf("Backlog() = %d, want 0", backlog)
+	}
+
+	lines := strings.Split(strings.TrimSpace(inserts[0].body+inserts[1].body), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("inserted rows = %d, want 3", len(lines))
+	}
+	var r row
+	if err := json.Unmarshal([]byte(lines[0]), &r); err != nil {
+		t.Fatal(err)
+	}
+	if r.ID != "a" || r.TracerName != "oom" || r.TracerTime != "2026-10-16 08:00:00.000" ||
+		r.Hostname != "node-1" || r.Region != "r1" || r.Payload != `{"tracer_data":{}}` {
+		t.Errorf("inserted row = %+v", r)
+	}
+}
+
+func TestBackendQuery(t *testing.T) {
+	ts, addr := newTestServer(t, func(query string) string {
+		switch {
+		case strings.HasPrefix(query, "SELECT count()"):
+			return "2\n"
+		case strings.HasPrefix(query, "SELECT DISTINCT"):
+			return "{\"term\":\"node-1\"}\n{\"term\":\"node-2\"}\n"
+		case strings.Contains(query, "WHERE id ="):
+			return ""
+		default:
+			return `{"id":"a","payload":"{\"x\":1}","fields":"{\"tracer_name\":\"oom\"}"}` + "\n"
+		}
+	})
+	backend, err := NewBackend(&Config{Address: addr})
+	if err != nil {
+		t.Fatalf("NewBackend() error = %v", err)
+	}
+	t.Cleanup(func() { _ = backend.Close(t.Context()) })
+
+	records, err := backend.Query(t.Context(), driver.Query{
+		Filters: []driver.Filter{{Field: "tracer_name", Op: driver.OpEq, Value: "oom"}},
+		Limit:   10,
+	})
+	if err != nil {
+		t.Fatalf("Query() error = %v", err)
+	}
+	if len(records) != 1 || records[0].ID != "a" || string(records[0].Data) != `{"x":1}` || records[0].Fields["tracer_name"] != "oom" {
+		t.Errorf("Query() = %+v", records)
+	}
+	if params := ts.requests[0].params; params["p0"] != "oom" || params["p1"] != "10" {
+		t.Errorf("Query() params = %v", params)
+	}
+
+	count, err := backend.Count(t.Context(), driver.Query{})
+	if err != nil || count != 2 {
+		t.Errorf("Count() = %d, %v", count, err)
+	}
+
+	values, err := backend.Values(t.Context(), "hostname", driver.Query{}, 10)
+	if err != nil || len(values) != 2 || values[1] != "node-2" {
+		t.Errorf("Values() = %v, %v", values, err)
+	}
+
+	if _, err := backend.Get(t.Context(), "missing"); !errors.Is(err, driver.ErrNotFound) {
+		t.Errorf("Get() error = %v, want ErrNotFound", err)
+	}
+}
+
+func TestBackendServerError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		http.Error(w, "Code: 60. DB::Exception: Table default.huatuo_events does not exist", http.StatusNotFound)
+	}))
+	t.Cleanup(srv.Close)
+
+	backend, err := NewBackend(&Config{Address: srv.URL})
+	if err != nil {
+		t.Fatalf("NewBackend() error = %v", err)
+	}
+	t.Cleanup(func() { _ = backend.Close(t.Context()) })
+
+	if _, err := backend.Count(t.Context(), driver.Query{}); err == nil || !strings.Contains(err.Error(), "does not exist") {
+		t.Errorf("Count() error = %v", err)
+	}
+}
+
+func TestClientVerifiesServer(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	t.Cleanup(srv.Close)
+
+	verifying, err := newClient(&Config{Address: srv.URL})
+	if err != nil {
+		t.Fatalf("newClient() error = %v", err)
+	}
+	if err := verifying.exec(t.Context(), "SELECT 1", nil, nil); err == nil {
+		t.Error("exec() on an unknown server certificate succeeded")
+	}
+
+	skipping, err := newClient(&Config{Address: srv.URL, InsecureSkipVerify: true})
+	if err != nil {
+		t.Fatalf("newClient() error = %v", err)
+	}
+	if err := skipping.exec(t.Context(), "SELECT 1", nil, nil); err != nil {
+		t.Errorf("exec() skipping the verification error = %v", err)
+	}
+
+	if _, err := newClient(&Config{Address: srv.URL, CAFile: "/nonexistent/ca.pem"}); err == nil {
+		t.Error("newClient() with a missing ca file succeeded")
+	}
+}
+
+func TestBuildSelectSQL(t *testing.T) {
+	since := time.Date(2026, 10, 16, 0, 0, 0, 0, time.UTC)
+	query, params, err := buildSelectSQL("huatuo_events", driver.Query{
+		Filters: []driver.Filter{
+			{Field: "tracer_time", Op: driver.OpGte, Value: since},
+			{Field: "container_id", Op: driver.OpIn, Value: []string{"c1", "c2"}},
+			{Field: "pid", Op: driver.OpGt, Value: 100},
+		},
+		Sorts:  []driver.Sort{{Field: "tracer_time", Desc: true}, {Field: "container_qos"}},
+		Limit:  5,
+		Offset: 10,
+	})
+	if err != nil {
+		t.Fatalf("buildSelectSQL() error = %v", err)
+	}
+
+	want := "SELECT id, payload, fields FROM `huatuo_events` WHERE `tracer_time` >= {p0:DateTime64(3)}" +
+		" AND JSONExtractString(fields, 'container_id') IN ({p1:String}, {p2:String})" +
+		" AND JSONExtractFloat(fields, 'pid') > {p3:Float64}" +
+		" ORDER BY `tracer_time` DESC, JSONExtractString(fields, 'container_qos') ASC" +
+		" LIMIT {p4:UInt64} OFFSET {p5:UInt64} FORMAT JSONEachRow"
+	if query != want {
+		t.Errorf("buildSelectSQL() = %q, want %q", query, want)
+	}
+	if params["p0"] != "2026-10-16 00:00:00.000" || params["p2"] != "c2" || params["p3"] != "100" {
+		t.Errorf("buildSelectSQL() params = %v", params)
+	}
+
+	if _, _, err := buildSelectSQL("huatuo_events", driver.Query{
+		Filters: []driver.Filter{{Field: "x'); DROP TABLE t; --", Op: driver.OpEq, Value: 1}},
+	}); !errors.Is(err, driver.ErrInvalidField) {
+		t.Errorf("buildSelectSQL() invalid field error = %v", err)
+	}
+	if _, _, err := buildSelectSQL("huatuo_events", driver.Query{
+		Filters: []driver.Filter{{Field: "tracer_time", Op: driver.OpEq, Value: "yesterday"}},
+	}); !errors.Is(err, driver.ErrInvalidQuery) {
+		t.Errorf("buildSelectSQL() invalid time error = %v", err)
+	}
+}
+
+func TestEscapeParam(t *testing.T) {
+	if got, want := escapeParam("a\tb\\c\n"), `a\tb\\c\n`; got != want {
+		t.Errorf("escapeParam() = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,124 @@
+// Copyright 2026 The HuaTuo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clickhouse
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"huatuo-bamai/internal/storage/driver"
+)
+
+// newTransport keeps a few idle connections to the server, the batches and
+// the queries of one agent are not concurrent enough to need more.
+func newTransport(tlsConfig *tls.Config) http.RoundTripper {
+	return &http.Transport{
+		MaxIdleConns:          16,
+		MaxIdleConnsPerHost:   8,
+		IdleConnTimeout:       50 * time.Second,
+		ResponseHeaderTimeout: 30 * time.Second,
+		DialContext: (&net.Dialer{
+			Timeout:   10 * time.Second,
+			KeepAlive: 30 * time.Second,
+		}).DialContext,
+		TLSClientConfig: tlsConfig,
+	}
+}
+
+// client talks to the ClickHouse HTTP interface. The statement is sent in
+// the query string, the body carries the rows of an INSERT, and the values
+// are bound as query parameters instead of being formatted into the SQL.
+type client struct {
+	http     *http.Client
+	address  string
+	username string
+	password string
+	database string
+}
+
+func newClient(cfg *Config) (*client, error) {
+	if cfg.Address == "" {
+		return nil, fmt.Errorf("clickhouse backend: address is empty")
+	}
+	if _, err := url.Parse(cfg.Address); err != nil {
+		return nil, fmt.Errorf("clickhouse backend: address %q: %w", cfg.Address, err)
+	}
+
+	tlsConfig, err := driver.TLSConfig(cfg.CAFile, cfg.InsecureSkipVerify)
+	if err != nil {
+		return nil, fmt.Errorf("clickhouse backend: %w", err)
+	}
+
+	return &client{
+		http:     &http.Client{Transport: newTransport(tlsConfig)},
+		address:  strings.TrimSuffix(cfg.Address, "/"),
+		username: cfg.Username,
+		password: cfg.Password,
+		database: cfg.Database,
+	}, nil
+}
+
+// do runs query with the parameters and returns the response body, which
+// the caller must close.
+func (c *client) do(ctx context.Context, query string, params map[string]string, body []byte) (io.ReadCloser, error) {
+	values := url.Values{}
+	values.Set("query", query)
+	if c.database != "" {
+		values.Set("database", c.database)
+	}
+	for name, value := range params {
+		values.Set("param_"+name, value)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.address+"/?"+values.Encode(), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	if c.username != "" {
+		req.Header.Set("X-ClickHouse-User", c.username)
+		req.Header.Set("X-ClickHouse-Key", c.password)
+	}
+
+	res, err := c.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if res.StatusCode != http.StatusOK {
+		defer res.Body.Close()
+		msg, _ := io.ReadAll(io.LimitReader(res.Body, 4096))
+		return nil, fmt.Errorf("status %d: %s", res.StatusCode, strings.TrimSpace(string(msg)))
+	}
+	return res.Body, nil
+}
+
+// exec runs a statement without a result.
+func (c *client) exec(ctx context.Context, query string, params map[string]string, body []byte) error {
+	res, err := c.do(ctx, query, params, body)
+	if err != nil {
+		return err
+	}
+	defer res.Close()
+
+	_, err = io.Copy(io.Discard, res)
+	return err
+}
@@ -0,0 +1,322 @@
+// Copyright 2026 The HuaTuo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clickhouse
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"huatuo-bamai/internal/storage/driver"
+)
+
+var safeIdentifierPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// binaryOpSQL maps comparison operators to their SQL string equivalents.
+var binaryOpSQL = map[driver.Op]string{
+	driver.OpEq:  "=",
+	driver.OpNe:  "!=",
+	driver.OpGt:  ">",
+	driver.OpGte: ">=",
+	driver.OpLt:  "<",
+	driver.OpLte: "<=",
+}
+
+const (
+	// timeColumn is the one column of type DateTime64, the others are
+	// strings.
+	timeColumn = "tracer_time"
+	timeLayout = "2006-01-02 15:04:05.000"
+)
+
+// columns are the fields stored in their own columns, the sort key and the
+// usual filters of the events. The other fields are read from the fields
+// JSON column.
+var columns = map[string]bool{
+	"tracer_name": true,
+	timeColumn:    true,
+	"hostname":    true,
+	"region":      true,
+}
+
+// tableColumns are the columns of the events table in order, a column added
+// here is added to the existing tables by Init.
+var tableColumns = []struct {
+	Name string
+	Type string
+}{
+	{"id", "String"},
+	{"tracer_name", "LowCardinality(String)"},
+	{timeColumn, "DateTime64(3, 'UTC')"},
+	{"hostname", "LowCardinality(String)"},
+	{"region", "LowCardinality(String)"},
+	{"fields", "String"},
+	{"payload", "String"},
+	{"inserted_time", "DateTime64(3, 'UTC')"},
+}
+
+// buildCreateTableSQL creates the events table. A record saved again with
+// the same id and tracer time replaces the older one at merge time.
+func buildCreateTableSQL(table string) string {
+	defs := make([]string, 0, len(tableColumns))
+	for _, col := range tableColumns {
+		defs = append(defs, fmt.Sprintf("\t%s %s", quoteIdentifier(col.Name), col.Type))
+	}
+
+	return fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+%s
+) ENGINE = ReplacingMergeTree(inserted_time)
+PARTITION BY toYYYYMM(%s)
+ORDER BY (tracer_name, %s, id)`,
+		quoteIdentifier(table), strings.Join(defs, ",\n"), timeColumn, timeColumn)
+}
+
+func buildAddColumnSQL(table string) []string {
+	stmts := make([]string, 0, len(tableColumns))
+	for _, col := range tableColumns {
+		stmts = append(stmts, fmt.Sprintf("ALTER TABLE %s ADD COLUMN IF NOT EXISTS %s %s",
+			quoteIdentifier(table), quoteIdentifier(col.Name), col.Type))
+	}
+	return stmts
+}
+
+func buildTTLSQL(table string, retention time.Duration) string {
+	if retention <= 0 {
+		return fmt.Sprintf("ALTER TABLE %s REMOVE TTL", quoteIdentifier(table))
+	}
+	days := max(int64(retention/(24*time.Hour)), 1)
+	return fmt.Sprintf("ALTER TABLE %s MODIFY TTL toDateTime(%s) + INTERVAL %d DAY",
+		quoteIdentifier(table), timeColumn, days)
+}
+
+// sqlBuilder binds the values as query parameters {pN:Type}.
+type sqlBuilder struct {
+	params map[string]string
+}
+
+func newSQLBuilder() *sqlBuilder {
+	return &sqlBuilder{params: make(map[string]string)}
+}
+
+func (b *sqlBuilder) bind(typ, value string) string {
+	name := "p" + strconv.Itoa(len(b.params))
+	b.params[name] = escapeParam(value)
+	return fmt.Sprintf("{%s:%s}", name, typ)
+}
+
+// bindValue binds a filter value for the field expression, a column or a
+// typed extraction from the fields JSON.
+func (b *sqlBuilder) bindValue(field string, value any) (string, string, error) {
+	if field == timeColumn {
+		t, err := timeValue(value)
+		if err != nil {
+			return "", "", err
+		}
+		return quoteIdentifier(field), b.bind("DateTime64(3)", t.UTC().Format(timeLayout)), nil
+	}
+	if columns[field] {
+		return quoteIdentifier(field), b.bind("String", driver.StringValue(value)), nil
+	}
+
+	switch typed := value.(type) {
+	case bool:
+		return jsonExtractExpr("JSONExtractBool", field), b.bind("Bool", strconv.FormatBool(typed)), nil
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64:
+		return jsonExtractExpr("JSONExtractFloat", field), b.bind("Float64", driver.StringValue(typed)), nil
+	default:
+		return jsonExtractExpr("JSONExtractString", field), b.bind("String", driver.StringValue(driver.NormalizeValue(value))), nil
+	}
+}
+
+func (b *sqlBuilder) where(filters []driver.Filter) (string, error) {
+	clauses := make([]string, 0, len(filters))
+	for _, filter := range filters {
+		if err := validateIdentifier(filter.Field); err != nil {
+			return "", err
+		}
+
+		if opStr, ok := binaryOpSQL[filter.Op]; ok {
+			expr, param, err := b.bindValue(filter.Field, filter.Value)
+			if err != nil {
+				return "", err
+			}
+			clauses = append(clauses, expr+" "+opStr+" "+param)
+		} else if filter.Op == driver.OpIn {
+			inValues, err := driver.FlattenInValues(filter.Value)
+			if err != nil {
+				return "", err
+			}
+			var expr string
+			params := make([]string, len(inValues))
+			for i, value := range inValues {
+				if expr, params[i], err = b.bindValue(filter.Field, value); err != nil {
+					return "", err
+				}
+			}
+			clauses = append(clauses, fmt.Sprintf("%s IN (%s)", expr, strings.Join(params, ", ")))
+		} else {
+			return "", driver.ErrUnsupportedOp
+		}
+	}
+	return strings.Join(clauses, " AND "), nil
+}
+
+func buildSelectSQL(table string, q driver.Query) (string, map[string]string, error) {
+	if q.Limit < 0 || q.Offset < 0 {
+		return "", nil, driver.ErrNegativePagination
+	}
+
+	b := newSQLBuilder()
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "SELECT id, payload, fields FROM %s", quoteIdentifier(table))
+
+	whereSQL, err := b.where(q.Filters)
+	if err != nil {
+		return "", nil, err
+	}
+	if whereSQL != "" {
+		sb.WriteString(" WHERE ")
+		sb.WriteString(whereSQL)
+	}
+
+	orderParts := make([]string, 0, len(q.Sorts))
+	for _, s := range q.Sorts {
+		if err := validateIdentifier(s.Field); err != nil {
+			return "", nil, err
+		}
+		direction := "ASC"
+		if s.Desc {
+			direction = "DESC"
+		}
+		orderParts = append(orderParts, fieldExpr(s.Field)+" "+direction)
+	}
+	if len(orderParts) > 0 {
+		sb.WriteString(" ORDER BY ")
+		sb.WriteString(strings.Join(orderParts, ", "))
+	}
+
+	if q.Limit > 0 {
+		sb.WriteString(" LIMIT ")
+		sb.WriteString(b.bind("UInt64", strconv.Itoa(q.Limit)))
+	}
+	if q.Offset > 0 {
+		sb.WriteString(" OFFSET ")
+		sb.WriteString(b.bind("UInt64", strconv.Itoa(q.Offset)))
+	}
+	sb.WriteString(" FORMAT JSONEachRow")
+	return sb.String(), b.params, nil
+}
+
+func buildCountSQL(table string, q driver.Query) (string, map[string]string, error) {
+	if q.Limit < 0 || q.Offset < 0 {
+		return "", nil, driver.ErrNegativePagination
+	}
+
+	b := newSQLBuilder()
+	whereSQL, err := b.where(q.Filters)
+	if err != nil {
+		return "", nil, err
+	}
+
+	countSQL := fmt.Sprintf("SELECT count() FROM %s", quoteIdentifier(table))
+	if whereSQL != "" {
+		countSQL += " WHERE " + whereSQL
+	}
+	return countSQL + " FORMAT TabSeparated", b.params, nil
+}
+
+func buildValuesSQL(table, field string, q driver.Query, size int) (string, map[string]string, error) {
+	if q.Limit < 0 || q.Offset < 0 {
+		return "", nil, driver.ErrNegativePagination
+	}
+	if size < 0 {
+		return "", nil, driver.ErrNegativeSize
+	}
+	if err := validateIdentifier(field); err != nil {
+		return "", nil, err
+	}
+
+	b := newSQLBuilder()
+	termExpr := fieldExpr(field)
+	if field == timeColumn {
+		termExpr = fmt.Sprintf("formatDateTime(%s, '%%F %%T')", quoteIdentifier(field))
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "SELECT DISTINCT %s AS term FROM %s WHERE term != ''", termExpr, quoteIdentifier(table))
+
+	whereSQL, err := b.where(q.Filters)
+	if err != nil {
+		return "", nil, err
+	}
+	if whereSQL != "" {
+		sb.WriteString(" AND ")
+		sb.WriteString(whereSQL)
+	}
+
+	sb.WriteString(" ORDER BY term ASC")
+	if size > 0 {
+		sb.WriteString(" LIMIT ")
+		sb.WriteString(b.bind("UInt64", strconv.Itoa(size)))
+	}
+	sb.WriteString(" FORMAT JSONEachRow")
+	return sb.String(), b.params, nil
+}
+
+// fieldExpr is the column of the field, or its string in the fields JSON.
+func fieldExpr(field string) string {
+	if columns[field] {
+		return quoteIdentifier(field)
+	}
+	return jsonExtractExpr("JSONExtractString", field)
+}
+
+func jsonExtractExpr(fn, field string) string {
+	return fmt.Sprintf("%s(fields, '%s')", fn, field)
+}
+
+// timeValue accepts a time.Time or its normalized storage string.
+func timeValue(value any) (time.Time, error) {
+	switch typed := value.(type) {
+	case time.Time:
+		return typed, nil
+	case string:
+		for _, layout := range []string{"2006-01-02 15:04:05.000 -0700", time.RFC3339Nano, timeLayout} {
+			if t, err := time.Parse(layout, typed); err == nil {
+				return t, nil
+			}
+		}
+	}
+	return time.Time{}, fmt.Errorf("%w: invalid %s value %v", driver.ErrInvalidQuery, timeColumn, value)
+}
+
+// escapeParam escapes a parameter value in the TabSeparated format the
+// server parses the parameters with.
+func escapeParam(value string) string {
+	return strings.NewReplacer(`\`, `\\`, "\t", `\t`, "\n", `\n`, "\r", `\r`).Replace(value)
+}
+
+func quoteIdentifier(name string) string {
+	return "`" + strings.ReplaceAll(name, "`", "``") + "`"
+}
+
+func validateIdentifier(name string) error {
+	if !safeIdentifierPattern.MatchString(name) {
+		return driver.ErrInvalidField
+	}
+	return nil
+}
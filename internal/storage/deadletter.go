@@ -0,0 +1,220 @@
+// Copyright 2026 The HuaTuo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"huatuo-bamai/internal/log"
+	"huatuo-bamai/internal/storage/driver"
+)
+
+const (
+	deadLetterPrefix = "deadletter-"
+	deadLetterSuffix = ".jsonl"
+
+	defaultRedriveInterval = time.Minute
+)
+
+// deadLetterRecord is a line of a dead letter segment. The JSON documents
+// are kept as is, the others base64 encoded in Data.
+type deadLetterRecord struct {
+	ID       string          `json:"id"`
+	Failed   time.Time       `json:"failed"`
+	Error    string          `json:"error,omitempty"`
+	Document json.RawMessage `json:"document,omitempty"`
+	Data     []byte          `json:"data,omitempty"`
+}
+
+// deadLetterCodec encodes the records of the dead letter as its lines.
+type deadLetterCodec struct{}
+
+func (deadLetterCodec) Encode(rec deadLetterRecord) ([]byte, error) {
+	return json.Marshal(&rec)
+}
+
+func (deadLetterCodec) Decode(line []byte) (deadLetterRecord, error) {
+	var rec deadLetterRecord
+	err := json.Unmarshal(line, &rec)
+	return rec, err
+}
+
+// deadLetter keeps the records an engine failed to write on disk, in
+// segments read back oldest first. The segments of the previous run are
+// read back first.
+type deadLetter struct {
+	*driver.Spool[deadLetterRecord]
+}
+
+func newDeadLetter(dir string, maxSize int64, engine string) (*deadLetter, error) {
+	spool, err := driver.NewSpool(&driver.SpoolConfig[deadLetterRecord]{
+		Dir:     dir,
+		Prefix:  deadLetterPrefix,
+		Suffix:  deadLetterSuffix,
+		MaxSize: maxSize,
+		Codec:   deadLetterCodec{},
+		Observe: func(records, bytes int64) {
+			driver.ObserveDeadLetter(engine, records, bytes)
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("dead letter: %w", err)
+	}
+	return &deadLetter{Spool: spool}, nil
+}
+
+// write appends the records failed for cause until the dead letter is
+// full, it returns the count of those written.
+func (d *deadLetter) write(recs []driver.Record, cause error, now time.Time) (int, error) {
+	lines := make([]deadLetterRecord, 0, len(recs))
+	for i := range recs {
+		line := deadLetterRecord{ID: recs[i].ID, Failed: now}
+		if cause != nil {
+			line.Error = cause.Error()
+		}
+		if json.Valid(recs[i].Data) {
+			line.Document = recs[i].Data
+		} else {
+			line.Data = recs[i].Data
+		}
+		lines = append(lines, line)
+	}
+	return d.Write(lines)
+}
+
+// next reads back the oldest segment, the current one when it is the only
+// one left. The segment is removed by done, once its records are written
+// or put back in the dead letter.
+func (d *deadLetter) next() ([]driver.Record, func(), error) {
+	lines, done, err := d.Next()
+	if err != nil || done == nil {
+		return nil, done, err
+	}
+
+	recs := make([]driver.Record, 0, len(lines))
+	for _, line := range lines {
+		rec := driver.Record{ID: line.ID, Data: line.Data}
+		if line.Document != nil {
+			rec.Data = line.Document
+		}
+		recs = append(recs, rec)
+	}
+	return recs, done, nil
+}
+
+// startDeadLetter keeps the records the backend fails to write in dir and
+// writes them again every interval.
+func (s *Store[T]) startDeadLetter(dir string, maxSize int64, interval time.Duration) error {
+	dl, err := newDeadLetter(dir, maxSize, s.Name)
+	if err != nil {
+		return err
+	}
+	if interval <= 0 {
+		interval = defaultRedriveInterval
+	}
+
+	s.deadLetter = dl
+	if notifier, ok := s.backend.(driver.FailureNotifier); ok {
+		notifier.OnWriteFailure(s.deadLetterWrite)
+	}
+
+	var ctx context.Context
+	ctx, s.cancel = context.WithCancel(context.Background())
+	s.done = make(chan struct{})
+	go s.redriveLoop(ctx, interval)
+	return nil
+}
+
+// deadLetterWrite keeps the records failed for cause, those the dead
+// letter has no room for are dropped.
+func (s *Store[T]) deadLetterWrite(recs []driver.Record, cause error) {
+	written, err := s.deadLetter.write(recs, cause, time.Now())
+	if err != nil {
+		log.Errorf("%s dead letter: %v", s.Name, err)
+	}
+	driver.ObserveDropped(s.Name, driver.DropDeadLetterFull, len(recs)-written)
+}
+
+func (s *Store[T]) redriveLoop(ctx context.Context, interval time.Duration) {
+	defer close(s.done)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		s.redrive(ctx)
+	}
+}
+
+// redrive writes the records of the dead letter again, the oldest first,
+// until the backend fails one. The fields are computed again from the
+// decoded records, the asynchronous backends report the records failing
+// again to the dead letter themselves.
+func (s *Store[T]) redrive(ctx context.Context) {
+	for ctx.Err() == nil {
+		recs, done, err := s.deadLetter.next()
+		if err != nil {
+			log.Errorf("%s dead letter: %v", s.Name, err)
+			return
+		}
+		if done == nil {
+			return
+		}
+
+		var saved int
+		for i := range recs {
+			err = s.resave(ctx, recs[i])
+			if err != nil {
+				// the rest goes back to the dead letter as is.
+				driver.ObserveWriteFailures(s.Name, 1)
+				s.deadLetterWrite(recs[i:], err)
+				break
+			}
+			saved++
+		}
+		done()
+		driver.ObserveRedriven(s.Name, saved)
+
+		if err != nil {
+			log.Warnf("%s dead letter: %d records written again, the next ones still fail: %v", s.Name, saved, err)
+			return
+		}
+		log.Infof("%s dead letter: %d records written again", s.Name, saved)
+	}
+}
+
+// resave saves a record of the dead letter, the records which cannot be
+// decoded any more are dropped.
+func (s *Store[T]) resave(ctx context.Context, failed driver.Record) error {
+	v, err := s.mapper.Decode(failed.Data)
+	if err == nil {
+		var rec driver.Record
+		if rec, err = s.record(v); err == nil {
+			return s.backend.Save(driver.WithContext(ctx), rec)
+		}
+	}
+
+	log.Errorf("%s dead letter: drop %s: %v", s.Name, failed.ID, err)
+	driver.ObserveDropped(s.Name, driver.DropRejected, 1)
+	return nil
+}
@@ -0,0 +1,180 @@
+// Copyright 2026 The HuaTuo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storage
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+	"time"
+
+	"huatuo-bamai/internal/storage/driver"
+)
+
+// notifyingBackend reports its failed writes as the asynchronous backends
+// do.
+type notifyingBackend struct {
+	testBackend
+	driver.FailureHandler
+}
+
+func newDeadLetterStore(t *testing.T, backend driver.Backend, dir string) *Store[testEntity] {
+	t.Helper()
+
+	store, err := NewStore[testEntity](context.Background(), "test", backend, "jobs", newTestMapper())
+	if err != nil {
+		t.Fatalf("NewStore() error = %v", err)
+	}
+	if err := store.startDeadLetter(dir, 0, time.Hour); err != nil {
+		t.Fatalf("startDeadLetter() error = %v", err)
+	}
+	t.Cleanup(func() { _ = store.Close(context.Background()) })
+	return store
+}
+
+func TestDeadLetterRedrive(t *testing.T) {
+	dir := t.TempDir()
+	backend := &testBackend{saveErr: errors.New("backend down")}
+	store := newDeadLetterStore(t, backend, dir)
+
+	entity := testEntity{ID: "job-1", UserID: "user-1", Status: "failed", Cost: 3}
+	if err := store.Save(context.Background(), entity); err == nil {
+		t.Fatal("Save() error = nil, want the backend error")
+	}
+	if got := store.deadLetter.Records(); got != 1 {
+		t.Fatalf("dead letter records = %d, want 1", got)
+	}
+
+	// the backend still fails, the record stays.
+	store.redrive(context.Background())
+	if got := store.deadLetter.Records(); got != 1 {
+		t.Fatalf("dead letter records after a failed redrive = %d, want 1", got)
+	}
+
+	backend.saveErr = nil
+	backend.savedRecord = driver.Record{}
+	store.redrive(context.Background())
+
+	if got := store.deadLetter.Records(); got != 0 {
+		t.Errorf("dead letter records after redrive = %d, want 0", got)
+	}
+	if backend.savedRecord.ID != "job-1" {
+		t.Errorf("redriven record id = %q, want %q", backend.savedRecord.ID, "job-1")
+	}
+	// the fields are computed again with their types.
+	if got := backend.savedRecord.Fields["cost"]; got != int64(3) {
+		t.Errorf("redriven record cost = %#v, want int64(3)", got)
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("ReadDir() error = %v", err)
+	}
+	if len(entries) != 0 {
+		t.Errorf("dead letter segments left = %d, want 0", len(entries))
+	}
+}
+
+func TestDeadLetterFailureNotifier(t *testing.T) {
+	backend := &notifyingBackend{}
+	store := newDeadLetterStore(t, backend, t.TempDir())
+
+	backend.WriteFailed("test", []driver.Record{
+		{ID: "job-1", Data: mustEncodeEntity(testEntity{ID: "job-1", Status: "queued"})},
+		{ID: "job-2", Data: mustEncodeEntity(testEntity{ID: "job-2", Status: "queued"})},
+	}, errors.New("flush failed"))
+
+	if got := store.deadLetter.Records(); got != 2 {
+		t.Fatalf("dead letter records = %d, want 2", got)
+	}
+
+	store.redrive(context.Background())
+	if backend.saveCalls != 2 {
+		t.Errorf("backend Save() call count = %d, want 2", backend.saveCalls)
+	}
+	if got := store.deadLetter.Records(); got != 0 {
+		t.Errorf("dead letter records after redrive = %d, want 0", got)
+	}
+}
+
+func TestDeadLetterMaxSize(t *testing.T) {
+	d, err := newDeadLetter(t.TempDir(), 150, "test")
+	if err != nil {
+		t.Fatalf("newDeadLetter() error = %v", err)
+	}
+	defer d.Close()
+
+	recs := []driver.Record{
+		{ID: "job-1", Data: []byte(`{"id":"job-1"}`)},
+		{ID: "job-2", Data: []byte(`{"id":"job-2"}`)},
+	}
+	written, err := d.write(recs, errors.New("backend down"), time.Now())
+	if err != nil {
+		t.Fatalf("write() error = %v", err)
+	}
+	if written != 1 {
+		t.Errorf("write() = %d, want 1", written)
+	}
+	if got := d.Records(); got != 1 {
+		t.Errorf("records = %d, want 1", got)
+	}
+}
+
+func TestDeadLetterReopen(t *testing.T) {
+	dir := t.TempDir()
+	d, err := newDeadLetter(dir, 0, "test")
+	if err != nil {
+		t.Fatalf("newDeadLetter() error = %v", err)
+	}
+
+	recs := []driver.Record{
+		{ID: "job-1", Data: []byte(`{"id":"job-1"}`)},
+		{ID: "job-2", Data: []byte("not json\n")},
+	}
+	if _, err := d.write(recs, nil, time.Now()); err != nil {
+		t.Fatalf("write() error = %v", err)
+	}
+	if err := d.Close(); err != nil {
+		t.Fatalf("close() error = %v", err)
+	}
+
+	d, err = newDeadLetter(dir, 0, "test")
+	if err != nil {
+		t.Fatalf("newDeadLetter() again error = %v", err)
+	}
+	defer d.Close()
+
+	if got := d.Records(); got != 2 {
+		t.Fatalf("records after reopen = %d, want 2", got)
+	}
+	got, done, err := d.next()
+	if err != nil || done == nil {
+		t.Fatalf("next() error = %v, want a segment", err)
+	}
+	done()
+
+	if len(got) != 2 {
+		t.Fatalf("next() records = %d, want 2", len(got))
+	}
+	for i := range recs {
+		if got[i].ID != recs[i].ID || string(got[i].Data) != string(recs[i].Data) {
+			t.Errorf("record %d = %s %q, want %s %q", i, got[i].ID, got[i].Data, recs[i].ID, recs[i].Data)
+		}
+	}
+	if _, done, _ := d.next(); done != nil {
+		t.Error("next() after the last segment returned one")
+	}
+}
@@ -0,0 +1,379 @@
+// Copyright 2026 The HuaTuo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package driver
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"huatuo-bamai/internal/log"
+
+	"github.com/cloudflare/backoff"
+)
+
+// ErrQueueFull is returned by Batcher.Save when the queue is full and the
+// record could not be spilled.
+var ErrQueueFull = errors.New("storage: queue full")
+
+// BatchItem is a record queued by a Batcher, encoded by the backend.
+type BatchItem interface {
+	// Queued returns when the record was saved.
+	Queued() time.Time
+	// Record returns the ID and the Data of the record, for the dead
+	// letter. BatcherConfig.BatchBytes counts the bytes of the Data.
+	Record() Record
+}
+
+// BatchResult is what became of the records of a batch sent, each of them
+// is in one of the slices.
+type BatchResult[T BatchItem] struct {
+	Delivered []T
+	// Retry are the records failing with Err worth a retry, e.g. the
+	// backend being overloaded or unavailable.
+	Retry []T
+	Err   error
+	// Rejected are the records refused for good with RejectErr, e.g. on a
+	// mapping error, sending them again would not help.
+	Rejected  []T
+	RejectErr error
+}
+
+// BatchSent returns the result of a batch sent as a whole, err being nil
+// or worth a retry or not.
+func BatchSent[T BatchItem](batch []T, err error, retryable bool) BatchResult[T] {
+	switch {
+	case err == nil:
+		return BatchResult[T]{Delivered: batch}
+	case retryable:
+		return BatchResult[T]{Retry: batch, Err: err}
+	default:
+		return BatchResult[T]{Rejected: batch, RejectErr: err}
+	}
+}
+
+// BatcherConfig contains the settings of a Batcher.
+type BatcherConfig[T BatchItem] struct {
+	// Backend labels the metrics and the logs.
+	Backend string
+	// BatchSize is the records sent at once, BatchBytes the bytes of
+	// their Data when not zero. A batch is sent earlier every
+	// FlushInterval.
+	BatchSize     int
+	BatchBytes    int
+	FlushInterval time.Duration
+	// QueueSize is the records queued at most, zero is unlimited. Save
+	// spills the records beyond it, or refuses them with ErrQueueFull.
+	QueueSize int
+	// Retries is the attempts of a batch, the waits between them grow from
+	// MinBackoff to MaxBackoff.
+	Retries    int
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+
+	// Send sends a batch, encoded by the backend.
+	Send func(ctx context.Context, batch []T) BatchResult[T]
+	// Spill keeps the records beyond the queue, or still failing after
+	// the last retry, e.g. on disk until the backend is back. It returns
+	// how many of them it took, the others are dropped. Nil drops them.
+	Spill func(items []T) int
+	// Unspill reads back the oldest records spilled, they are sent after
+	// a flush of the queue all taken. done removes them from the spool
+	// once sent or spilled again, it is nil when there are none.
+	Unspill func() (items []T, done func(), err error)
+	// Failure passes the records dropped after the last retry to the dead
+	// letter of the store.
+	Failure *FailureHandler
+}
+
+// Batcher queues the records of an asynchronous backend and sends them in
+// batches by size, time, or Close. A failed batch is retried with backoff,
+// then the records still failing are spilled, or dropped and passed to the
+// dead letter.
+type Batcher[T BatchItem] struct {
+	cfg BatcherConfig[T]
+
+	mu         sync.Mutex
+	queue      []T
+	queueBytes int
+
+	flush  chan struct{}
+	cancel context.CancelFunc
+	done   chan struct{}
+
+	// backlog is the records queued and not yet sent.
+	backlog atomic.Int64
+}
+
+// NewBatcher creates a Batcher and starts its flusher.
+func NewBatcher[T BatchItem](cfg *BatcherConfig[T]) *Batcher[T] {
+	b := &Batcher[T]{
+		cfg:   *cfg,
+		flush: make(chan struct{}, 1),
+		done:  make(chan struct{}),
+	}
+
+	var ctx context.Context
+	ctx, b.cancel = context.WithCancel(context.Background())
+	go b.flushLoop(ctx)
+
+	return b
+}
+
+// Save queues item. A nil error means the record was queued or spilled,
+// not that it was delivered.
+func (b *Batcher[T]) Save(item T) error {
+	b.mu.Lock()
+	if b.cfg.QueueSize > 0 && len(b.queue) >= b.cfg.QueueSize {
+		b.mu.Unlock()
+
+		if b.cfg.Spill == nil {
+			ObserveDropped(b.cfg.Backend, DropQueueFull, 1)
+			return ErrQueueFull
+		}
+		if b.cfg.Spill([]T{item}) != 1 {
+			ObserveDropped(b.cfg.Backend, DropSpoolFull, 1)
+			return ErrQueueFull
+		}
+		ObserveQueued(b.cfg.Backend)
+		ObserveSpilled(b.cfg.Backend, 1)
+		return nil
+	}
+
+	// counted ahead, a flush may complete before the lock is released.
+	b.backlog.Add(1)
+	ObserveQueued(b.cfg.Backend)
+
+	b.queue = append(b.queue, item)
+	if b.cfg.BatchBytes > 0 {
+		b.queueBytes += len(item.Record().Data)
+	}
+	full := len(b.queue) >= b.cfg.BatchSize || (b.cfg.BatchBytes > 0 && b.queueBytes >= b.cfg.BatchBytes)
+	b.mu.Unlock()
+
+	if full {
+		select {
+		case b.flush <- struct{}{}:
+		default:
+		}
+	}
+	return nil
+}
+
+// batchLen returns the records of items making a batch.
+func (b *Batcher[T]) batchLen(items []T) int {
+	if b.cfg.BatchBytes <= 0 {
+		return min(len(items), b.cfg.BatchSize)
+	}
+
+	n, size := 0, 0
+	for n < len(items) && n < b.cfg.BatchSize {
+		size += len(items[n].Record().Data)
+		if n > 0 && size > b.cfg.BatchBytes {
+			break
+		}
+		n++
+	}
+	return n
+}
+
+func (b *Batcher[T]) flushLoop(ctx context.Context) {
+	defer close(b.done)
+
+	ticker := time.NewTicker(b.cfg.FlushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-b.flush:
+		}
+
+		if b.flushQueue(ctx) && b.cfg.Unspill != nil {
+			b.replay(ctx)
+		}
+	}
+}
+
+// flushQueue sends the queued records in batches, it returns whether they
+// all were taken. The records queued during a retry are sent by the next
+// batches, not merged into a larger one.
+func (b *Batcher[T]) flushQueue(ctx context.Context) bool {
+	ok := true
+	for {
+		b.mu.Lock()
+		n := b.batchLen(b.queue)
+		batch := b.queue[:n:n]
+		b.queue = b.queue[n:]
+		if b.cfg.BatchBytes > 0 {
+			for i := range batch {
+				b.queueBytes -= len(batch[i].Record().Data)
+			}
+		}
+		b.mu.Unlock()
+
+		if n == 0 {
+			return ok
+		}
+		if err := b.push(ctx, batch); err != nil {
+			ok = false
+		}
+	}
+}
+
+// replay sends the spilled records back, the oldest first, while the
+// backend takes them.
+func (b *Batcher[T]) replay(ctx context.Context) {
+	for ctx.Err() == nil {
+		items, done, err := b.cfg.Unspill()
+		if err != nil {
+			log.Errorf("%s spool: %v", b.cfg.Backend, err)
+			return
+		}
+		if done == nil {
+			return
+		}
+
+		ok := b.resend(ctx, items)
+		done()
+
+		if !ok {
+			return
+		}
+	}
+}
+
+// resend sends the records read back from the spool in batches. After a
+// batch failing, the rest goes back to the spool as is. It returns whether
+// they all were taken.
+func (b *Batcher[T]) resend(ctx context.Context, items []T) bool {
+	b.backlog.Add(int64(len(items)))
+	ObserveUnspilled(b.cfg.Backend, len(items))
+
+	var err error
+	for len(items) > 0 {
+		n := b.batchLen(items)
+		if err == nil {
+			err = b.push(ctx, items[:n])
+		} else {
+			b.giveUp(items[:n], err)
+		}
+		items = items[n:]
+	}
+	return err == nil
+}
+
+// push sends one batch, backing off on the errors worth a retry, for the
+// whole batch or some records. The backoff is cut short when ctx is done,
+// the remaining attempts are then made at once. It returns the error of the
+// records given up.
+func (b *Batcher[T]) push(ctx context.Context, batch []T) error {
+	var err error
+
+	bo := backoff.New(b.cfg.MaxBackoff, b.cfg.MinBackoff)
+	for attempt := 0; attempt < b.cfg.Retries && len(batch) > 0; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-ctx.Done():
+			case <-time.After(bo.Duration()):
+			}
+		}
+
+		res := b.cfg.Send(context.WithoutCancel(ctx), batch)
+		b.delivered(res.Delivered)
+		b.reject(res.Rejected, res.RejectErr)
+		batch, err = res.Retry, res.Err
+	}
+
+	if len(batch) == 0 {
+		return nil
+	}
+	log.Errorf("%s send %d records: %v", b.cfg.Backend, len(batch), err)
+	b.giveUp(batch, err)
+	return err
+}
+
+func (b *Batcher[T]) delivered(items []T) {
+	if len(items) == 0 {
+		return
+	}
+
+	b.backlog.Add(-int64(len(items)))
+	ObserveFlushed(b.cfg.Backend, uint64(len(items)), 0)
+	for i := range items {
+		ObserveDelivered(b.cfg.Backend, items[i].Queued())
+	}
+}
+
+// reject drops the records refused for good, they would be refused again.
+func (b *Batcher[T]) reject(items []T, err error) {
+	if len(items) == 0 {
+		return
+	}
+	log.Errorf("%s rejected %d records: %v", b.cfg.Backend, len(items), err)
+
+	b.backlog.Add(-int64(len(items)))
+	ObserveDropped(b.cfg.Backend, DropRejected, len(items))
+	ObserveFlushed(b.cfg.Backend, 0, uint64(len(items)))
+	ObserveWriteFailures(b.cfg.Backend, len(items))
+}
+
+// giveUp spills the records still failing after the last retry, or drops
+// them. The records dropped go to the dead letter of the store, if any.
+func (b *Batcher[T]) giveUp(items []T, err error) {
+	b.backlog.Add(-int64(len(items)))
+
+	reason := DropRetries
+	if b.cfg.Spill != nil {
+		written := b.cfg.Spill(items)
+		ObserveSpilled(b.cfg.Backend, written)
+		items, reason = items[written:], DropSpoolFull
+	}
+	if len(items) == 0 {
+		return
+	}
+
+	ObserveDropped(b.cfg.Backend, reason, len(items))
+	ObserveFlushed(b.cfg.Backend, 0, uint64(len(items)))
+
+	failed := make([]Record, 0, len(items))
+	for i := range items {
+		failed = append(failed, items[i].Record())
+	}
+	if b.cfg.Failure != nil {
+		b.cfg.Failure.WriteFailed(b.cfg.Backend, failed, err)
+	} else {
+		ObserveWriteFailures(b.cfg.Backend, len(failed))
+	}
+}
+
+// Backlog returns the records queued and not yet sent, it grows when the
+// backend is slower than the node writes or unavailable.
+func (b *Batcher[T]) Backlog() int64 {
+	return b.backlog.Load()
+}
+
+// Close stops the flusher and sends the queued records. The Batcher must
+// not be reused.
+func (b *Batcher[T]) Close(ctx context.Context) {
+	b.cancel()
+	<-b.done
+
+	b.flushQueue(WithContext(ctx))
+}
@@ -0,0 +1,155 @@
+// Copyright 2026 The HuaTuo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package driver
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+type testBatchItem struct {
+	id   string
+	data []byte
+}
+
+func (item testBatchItem) Queued() time.Time { return time.Now() }
+func (item testBatchItem) Record() Record    { return Record{ID: item.id, Data: item.data} }
+
+// testSender takes the items but those listed, retried or rejected.
+type testSender struct {
+	mu      sync.Mutex
+	batches [][]string
+	retry   map[string]int
+	reject  map[string]bool
+}
+
+func (s *testSender) send(_ context.Context, batch []testBatchItem) BatchResult[testBatchItem] {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ids := make([]string, 0, len(batch))
+	var res BatchResult[testBatchItem]
+	for _, item := range batch {
+		ids = append(ids, item.id)
+		switch {
+		case s.reject[item.id]:
+			res.Rejected, res.RejectErr = append(res.Rejected, item), errors.New("rejected")
+		case s.retry[item.id] > 0:
+			s.retry[item.id]--
+			res.Retry, res.Err = append(res.Retry, item), errors.New("unavailable")
+		default:
+			res.Delivered = append(res.Delivered, item)
+		}
+	}
+	s.batches = append(s.batches, ids)
+	return res
+}
+
+func newTestBatcher(sender *testSender, cfg BatcherConfig[testBatchItem]) *Batcher[testBatchItem] {
+	cfg.Backend = "batcher_test"
+	cfg.FlushInterval = time.Hour
+	cfg.Retries = 3
+	cfg.MinBackoff = time.Millisecond
+	cfg.MaxBackoff = time.Millisecond
+	cfg.Send = sender.send
+	return NewBatcher(&cfg)
+}
+
+func TestBatcherBatches(t *testing.T) {
+	sender := &testSender{}
+	b := newTestBatcher(sender, BatcherConfig[testBatchItem]{BatchSize: 2, BatchBytes: 10})
+
+	for _, item := range []testBatchItem{
+		{"a", []byte("12345")}, {"b", []byte("12345678")}, {"c", []byte("1")},
+	} {
+		if err := b.Save(item); err != nil {
+			t.Fatalf("Save(%s) error = %v", item.id, err)
+		}
+	}
+	// a single item larger than BatchBytes is sent alone.
+	b.flushQueue(t.Context())
+	if len(sender.batches) != 2 || len(sender.batches[0]) != 1 || len(sender.batches[1]) != 2 {
+		t.Errorf("batches = %v, want [[a] [b c]]", sender.batches)
+	}
+	if backlog := b.Backlog(); backlog != 0 {
+		t.Errorf("Backlog() = %d, want 0", backlog)
+	}
+	b.Close(t.Context())
+}
+
+func TestBatcherRetries(t *testing.T) {
+	sender := &testSender{
+		retry:  map[string]int{"retried": 2, "failed": 3},
+		reject: map[string]bool{"rejected": true},
+	}
+	var handler FailureHandler
+	var failed []Record
+	handler.OnWriteFailure(func(recs []Record, _ error) { failed = append(failed, recs...) })
+
+	b := newTestBatcher(sender, BatcherConfig[testBatchItem]{BatchSize: 10, Failure: &handler})
+	for _, id := range []string{"delivered", "retried", "failed", "rejected"} {
+		_ = b.Save(testBatchItem{id: id})
+	}
+	b.Close(t.Context())
+
+	// the retries send the failing items only.
+	if len(sender.batches) != 3 || len(sender.batches[1]) != 2 || len(sender.batches[2]) != 2 {
+		t.Errorf("batches = %v", sender.batches)
+	}
+	// the rejected items are not passed to the dead letter.
+	if len(failed) != 1 || failed[0].ID != "failed" {
+		t.Errorf("failed records = %v, want [failed]", failed)
+	}
+	if backlog := b.Backlog(); backlog != 0 {
+		t.Errorf("Backlog() = %d, want 0", backlog)
+	}
+}
+
+func TestBatcherSpill(t *testing.T) {
+	sender := &testSender{retry: map[string]int{"b": 3}}
+	var spilled []string
+	room := 2
+	spill := func(items []testBatchItem) int {
+		n := min(len(items), room)
+		for _, item := range items[:n] {
+			spilled = append(spilled, item.id)
+		}
+		room -= n
+		return n
+	}
+
+	b := newTestBatcher(sender, BatcherConfig[testBatchItem]{BatchSize: 10, QueueSize: 1, Spill: spill})
+	if err := b.Save(testBatchItem{id: "a"}); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	if err := b.Save(testBatchItem{id: "spilled"}); err != nil {
+		t.Fatalf("Save() beyond the queue error = %v", err)
+	}
+
+	// the items still failing are spilled too.
+	if ok := b.resend(t.Context(), []testBatchItem{{id: "b"}}); ok {
+		t.Error("resend() = true with a failing item")
+	}
+	if len(spilled) != 2 || spilled[1] != "b" {
+		t.Errorf("spilled = %v, want [spilled b]", spilled)
+	}
+	if err := b.Save(testBatchItem{id: "full"}); !errors.Is(err, ErrQueueFull) {
+		t.Errorf("Save() with the spool full error = %v, want ErrQueueFull", err)
+	}
+	b.Close(t.Context())
+}
@@ -0,0 +1,207 @@
+// Copyright 2026 The HuaTuo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package driver
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Write path metrics shared by the store and the backends. For synchronous
+// backends a write is delivered when Save returns, asynchronous backends
+// report the queue and delivery metrics as well.
+var (
+	writeDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "huatuo",
+		Subsystem: "storage",
+		Name:      "write_duration_seconds",
+		Help:      "Time spent in Save by backend and collection.",
+		Buckets:   []float64{.0005, .001, .005, .01, .05, .1, .5, 1, 5},
+	}, []string{"backend", "collection"})
+	writeErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "huatuo",
+		Subsystem: "storage",
+		Name:      "write_errors_total",
+		Help:      "Failed Save calls by backend and collection.",
+	}, []string{"backend", "collection"})
+	queueDepth = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "huatuo",
+		Subsystem: "storage",
+		Name:      "queue_depth",
+		Help:      "Records accepted by Save but not yet delivered.",
+	}, []string{"backend"})
+	batchSize = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "huatuo",
+		Subsystem: "storage",
+		Name:      "batch_size",
+		Help:      "Records per flushed batch.",
+		Buckets:   prometheus.ExponentialBuckets(1, 4, 8),
+	}, []string{"backend"})
+	deliveryDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "huatuo",
+		Subsystem: "storage",
+		Name:      "delivery_duration_seconds",
+		Help:      "Time from Save to acknowledgement by the backend.",
+		Buckets:   []float64{.1, .5, 1, 2.5, 5, 10, 30, 60, 300},
+	}, []string{"backend"})
+	deliveryErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "huatuo",
+		Subsystem: "storage",
+		Name:      "delivery_errors_total",
+		Help:      "Records dropped after being accepted by Save.",
+	}, []string{"backend"})
+	dropped = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "huatuo",
+		Subsystem: "storage",
+		Name:      "dropped_total",
+		Help:      "Records dropped by backend and reason, accepted by Save or not.",
+	}, []string{"backend", "reason"})
+	spoolDepth = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "huatuo",
+		Subsystem: "storage",
+		Name:      "spool_depth",
+		Help:      "Records spilled to disk and not yet delivered.",
+	}, []string{"backend"})
+	writeFailures = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "huatuo",
+		Subsystem: "storage",
+		Name:      "write_failures_total",
+		Help:      "Records an engine failed to write, by Save or after the retries of its flusher.",
+	}, []string{"engine"})
+	deadLetterRecords = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "huatuo",
+		Subsystem: "storage",
+		Name:      "dead_letter_records",
+		Help:      "Records in the dead letter spool, waiting to be written again.",
+	}, []string{"engine"})
+	deadLetterBytes = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "huatuo",
+		Subsystem: "storage",
+		Name:      "dead_letter_bytes",
+		Help:      "Size of the dead letter spool on disk.",
+	}, []string{"engine"})
+	redriven = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "huatuo",
+		Subsystem: "storage",
+		Name:      "dead_letter_redriven_total",
+		Help:      "Records of the dead letter spool written again by the engine.",
+	}, []string{"engine"})
+)
+
+// The reasons of the dropped records.
+const (
+	// DropQueueFull is a record refused by Save, the queue being full.
+	DropQueueFull = "queue_full"
+	// DropRejected is a record the backend refused for good, e.g. a
+	// mapping error.
+	DropRejected = "rejected"
+	// DropRetries is a record still failing after the last retry.
+	DropRetries = "retries_exhausted"
+	// DropSpoolFull is a record the spool had no room for.
+	DropSpoolFull = "spool_full"
+	// DropDeadLetterFull is a failed record the dead letter spool had no
+	// room for, or failed to write.
+	DropDeadLetterFull = "dead_letter_full"
+)
+
+// Collectors returns the storage metrics to register.
+func Collectors() []prometheus.Collector {
+	return []prometheus.Collector{
+		writeDuration, writeErrors, queueDepth,
+		batchSize, deliveryDuration, deliveryErrors,
+		dropped, spoolDepth,
+		writeFailures, deadLetterRecords, deadLetterBytes, redriven,
+	}
+}
+
+// ObserveWrite records one Save call.
+func ObserveWrite(backend, collection string, start time.Time, err error) {
+	writeDuration.WithLabelValues(backend, collection).Observe(time.Since(start).Seconds())
+	if err != nil {
+		writeErrors.WithLabelValues(backend, collection).Inc()
+	}
+}
+
+// ObserveQueued records a record buffered by an asynchronous backend.
+func ObserveQueued(backend string) {
+	queueDepth.WithLabelValues(backend).Inc()
+}
+
+// ObserveFlushed records a flushed batch of buffered records, of which
+// failed were dropped.
+func ObserveFlushed(backend string, delivered, failed uint64) {
+	n := delivered + failed
+	if n == 0 {
+		return
+	}
+
+	queueDepth.WithLabelValues(backend).Sub(float64(n))
+	batchSize.WithLabelValues(backend).Observe(float64(n))
+	if failed > 0 {
+		deliveryErrors.WithLabelValues(backend).Add(float64(failed))
+	}
+}
+
+// ObserveDelivered records the delivery lag of a buffered record.
+func ObserveDelivered(backend string, queued time.Time) {
+	deliveryDuration.WithLabelValues(backend).Observe(time.Since(queued).Seconds())
+}
+
+// ObserveDropped records n records dropped for reason. The dropped records
+// accepted by Save are reported by ObserveFlushed as well.
+func ObserveDropped(backend, reason string, n int) {
+	if n > 0 {
+		dropped.WithLabelValues(backend, reason).Add(float64(n))
+	}
+}
+
+// ObserveSpilled records n queued records moved to the spool on disk.
+func ObserveSpilled(backend string, n int) {
+	queueDepth.WithLabelValues(backend).Sub(float64(n))
+	spoolDepth.WithLabelValues(backend).Add(float64(n))
+}
+
+// ObserveRestored records n records found in the spool of a previous run.
+func ObserveRestored(backend string, n int) {
+	spoolDepth.WithLabelValues(backend).Add(float64(n))
+}
+
+// ObserveUnspilled records n records read back from the spool, queued again.
+func ObserveUnspilled(backend string, n int) {
+	spoolDepth.WithLabelValues(backend).Sub(float64(n))
+	queueDepth.WithLabelValues(backend).Add(float64(n))
+}
+
+// ObserveWriteFailures records n records the engine failed to write.
+func ObserveWriteFailures(engine string, n int) {
+	if n > 0 {
+		writeFailures.WithLabelValues(engine).Add(float64(n))
+	}
+}
+
+// ObserveDeadLetter records the change of the dead letter spool of the
+// engine, in records and bytes.
+func ObserveDeadLetter(engine string, records, bytes int64) {
+	deadLetterRecords.WithLabelValues(engine).Add(float64(records))
+	deadLetterBytes.WithLabelValues(engine).Add(float64(bytes))
+}
+
+// ObserveRedriven records n dead letter records written again.
+func ObserveRedriven(engine string, n int) {
+	if n > 0 {
+		redriven.WithLabelValues(engine).Add(float64(n))
+	}
+}
@@ -0,0 +1,98 @@
+// Copyright 2026 The HuaTuo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package driver
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+// gatherMetric returns the metric name of the "test" backend, the other
+// tests of the package observe their own.
+func gatherMetric(t *testing.T, reg *prometheus.Registry, name string) *dto.Metric {
+	t.Helper()
+
+	families, err := reg.Gather()
+	if err != nil {
+		t.Fatalf("Gather() returned error: %v", err)
+	}
+	for _, mf := range families {
+		if mf.GetName() != name {
+			continue
+		}
+		for _, m := range mf.GetMetric() {
+			if m.GetLabel()[0].GetValue() == "test" {
+				return m
+			}
+		}
+	}
+	t.Fatalf("metric %s not found", name)
+	return nil
+}
+
+func TestStorageMetrics(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	reg.MustRegister(Collectors()...)
+
+	start := time.Now()
+	ObserveWrite("test", "events", start, nil)
+	ObserveWrite("test", "events", start, errors.New("disk full"))
+
+	if got := gatherMetric(t, reg, "huatuo_storage_write_duration_seconds").GetHistogram().GetSampleCount(); got != 2 {
+		t.Errorf("write_duration_seconds count = %d, want 2", got)
+	}
+	if got := gatherMetric(t, reg, "huatuo_storage_write_errors_total").GetCounter().GetValue(); got != 1 {
+		t.Errorf("write_errors_total = %v, want 1", got)
+	}
+
+	for range 5 {
+		ObserveQueued("test")
+	}
+	ObserveFlushed("test", 3, 1)
+	ObserveFlushed("test", 0, 0)
+
+	if got := gatherMetric(t, reg, "huatuo_storage_queue_depth").GetGauge().GetValue(); got != 1 {
+		t.Errorf("queue_depth = %v, want 1", got)
+	}
+	if got := gatherMetric(t, reg, "huatuo_storage_batch_size").GetHistogram().GetSampleSum(); got != 4 {
+		t.Errorf("batch_size sum = %v, want 4", got)
+	}
+	if got := gatherMetric(t, reg, "huatuo_storage_delivery_errors_total").GetCounter().GetValue(); got != 1 {
+		t.Errorf("delivery_errors_total = %v, want 1", got)
+	}
+
+	ObserveSpilled("test", 1)
+	if got := gatherMetric(t, reg, "huatuo_storage_spool_depth").GetGauge().GetValue(); got != 1 {
+		t.Errorf("spool_depth = %v, want 1", got)
+	}
+	ObserveRestored("test", 2)
+	if got := gatherMetric(t, reg, "huatuo_storage_spool_depth").GetGauge().GetValue(); got != 3 {
+		t.Errorf("spool_depth after restore = %v, want 3", got)
+	}
+	ObserveUnspilled("test", 1)
+	if got := gatherMetric(t, reg, "huatuo_storage_queue_depth").GetGauge().GetValue(); got != 1 {
+		t.Errorf("queue_depth after unspill = %v, want 1", got)
+	}
+
+	ObserveDropped("test", DropQueueFull, 2)
+	ObserveDropped("test", DropQueueFull, 0)
+	if got := gatherMetric(t, reg, "huatuo_storage_dropped_total").GetCounter().GetValue(); got != 2 {
+		t.Errorf("dropped_total = %v, want 2", got)
+	}
+}
@@ -0,0 +1,251 @@
+// Copyright 2026 The HuaTuo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package driver
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// spoolSegmentSize is the size a segment is closed at, the segments are
+// read back and removed whole.
+const spoolSegmentSize = 8 * 1024 * 1024
+
+// SpoolCodec encodes the records of a Spool, each as a JSON line.
+type SpoolCodec[T any] interface {
+	Encode(v T) ([]byte, error)
+	Decode(line []byte) (T, error)
+}
+
+// SpoolConfig contains the settings of a Spool.
+type SpoolConfig[T any] struct {
+	// Dir holds the segments, named Prefix<time>-<seq>Suffix.
+	Dir    string
+	Prefix string
+	Suffix string
+	// MaxSize is the bytes of the segments at most, zero is unlimited.
+	MaxSize int64
+	Codec   SpoolCodec[T]
+	// Observe is passed the change of the records and the bytes kept,
+	// the segments found by NewSpool included. It may be nil.
+	Observe func(records, bytes int64)
+}
+
+// Spool keeps records on disk as NDJSON, in segments read back oldest
+// first. It survives restarts, the segments of the previous run are read
+// back first.
+type Spool[T any] struct {
+	cfg SpoolConfig[T]
+
+	mu      sync.Mutex
+	size    int64
+	records int64
+	// segments are the closed segments, the oldest first.
+	segments []string
+	current  *os.File
+	curName  string
+	curSize  int64
+	seq      int64
+}
+
+// NewSpool creates the directory of a Spool, or opens the segments left in
+// it by the previous run.
+func NewSpool[T any](cfg *SpoolConfig[T]) (*Spool[T], error) {
+	if err := os.MkdirAll(cfg.Dir, 0o755); err != nil {
+		return nil, fmt.Errorf("spool %s: %w", cfg.Dir, err)
+	}
+
+	entries, err := os.ReadDir(cfg.Dir)
+	if err != nil {
+		return nil, fmt.Errorf("spool %s: %w", cfg.Dir, err)
+	}
+
+	s := &Spool[T]{cfg: *cfg}
+	for _, entry := range entries {
+		name := entry.Name()
+		if !entry.Type().IsRegular() || !strings.HasPrefix(name, cfg.Prefix) || !strings.HasSuffix(name, cfg.Suffix) {
+			continue
+		}
+		path := filepath.Join(cfg.Dir, name)
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		n, err := scanSegment(path, nil)
+		if err != nil {
+			return nil, fmt.Errorf("spool %s: %w", path, err)
+		}
+		s.segments = append(s.segments, path)
+		s.size += info.Size()
+		s.records += n
+	}
+	// the names sort by their creation time.
+	sort.Strings(s.segments)
+	s.observe(s.records, s.size)
+	return s, nil
+}
+
+// scanSegment passes the lines of the segment at path to fn, if not nil,
+// and returns how many there are.
+func scanSegment(path string, fn func(line []byte)) (int64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	var n int64
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(nil, spoolSegmentSize*2)
+	for scanner.Scan() {
+		n++
+		if fn != nil {
+			fn(scanner.Bytes())
+		}
+	}
+	return n, scanner.Err()
+}
+
+func (s *Spool[T]) observe(records, bytes int64) {
+	if s.cfg.Observe != nil && records != 0 {
+		s.cfg.Observe(records, bytes)
+	}
+}
+
+// Records returns the records spooled.
+func (s *Spool[T]) Records() int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.records
+}
+
+// Write appends the records until the spool is full, it returns the count
+// of those written.
+func (s *Spool[T]) Write(items []T) (written int, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var size int64
+	defer func() { s.observe(int64(written), size) }()
+
+	for i := range items {
+		line, err := s.cfg.Codec.Encode(items[i])
+		if err != nil {
+			return written, err
+		}
+		line = append(line, '\n')
+
+		if s.cfg.MaxSize > 0 && s.size+int64(len(line)) > s.cfg.MaxSize {
+			return written, nil
+		}
+		if err := s.appendLocked(line); err != nil {
+			return written, err
+		}
+		written++
+		size += int64(len(line))
+	}
+	return written, nil
+}
+
+func (s *Spool[T]) appendLocked(line []byte) error {
+	if s.current == nil {
+		// seq keeps the names unique on a coarse clock.
+		s.seq++
+		s.curName = filepath.Join(s.cfg.Dir, fmt.Sprintf("%s%d-%06d%s", s.cfg.Prefix, time.Now().UnixNano(), s.seq, s.cfg.Suffix))
+		f, err := os.OpenFile(s.curName, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o644)
+		if err != nil {
+			return err
+		}
+		s.current, s.curSize = f, 0
+	}
+
+	if _, err := s.current.Write(line); err != nil {
+		return err
+	}
+	s.curSize += int64(len(line))
+	s.size += int64(len(line))
+	s.records++
+
+	if s.curSize >= spoolSegmentSize {
+		return s.closeCurrentLocked()
+	}
+	return nil
+}
+
+func (s *Spool[T]) closeCurrentLocked() error {
+	if s.current == nil {
+		return nil
+	}
+
+	err := s.current.Close()
+	s.segments = append(s.segments, s.curName)
+	s.current = nil
+	return err
+}
+
+// Next reads back the oldest segment, the current one when it is the only
+// one left. The segment is removed by done, once its records are delivered
+// or spooled again. done is nil when the spool is empty.
+func (s *Spool[T]) Next() (items []T, done func(), err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.segments) == 0 {
+		if err := s.closeCurrentLocked(); err != nil {
+			return nil, nil, err
+		}
+	}
+	if len(s.segments) == 0 {
+		return nil, nil, nil
+	}
+
+	path := s.segments[0]
+	s.segments = s.segments[1:]
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	lines, err := scanSegment(path, func(line []byte) {
+		// a line torn by a crash is lost, the others are read.
+		if item, err := s.cfg.Codec.Decode(line); err == nil {
+			items = append(items, item)
+		}
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	s.size -= info.Size()
+	s.records -= lines
+	s.observe(-lines, -info.Size())
+
+	return items, func() { _ = os.Remove(path) }, nil
+}
+
+// Close closes the current segment, it is read back by the next run.
+func (s *Spool[T]) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.closeCurrentLocked()
+}
@@ -0,0 +1,94 @@
+// Copyright 2026 The HuaTuo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package driver
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+type testSpoolCodec struct{}
+
+func (testSpoolCodec) Encode(v string) ([]byte, error) { return json.Marshal(v) }
+
+func (testSpoolCodec) Decode(line []byte) (string, error) {
+	var v string
+	err := json.Unmarshal(line, &v)
+	return v, err
+}
+
+func TestSpool(t *testing.T) {
+	dir := t.TempDir()
+	var records, bytes int64
+	cfg := &SpoolConfig[string]{
+		Dir:     dir,
+		Prefix:  "test-",
+		Suffix:  ".ndjson",
+		MaxSize: 12,
+		Codec:   testSpoolCodec{},
+		Observe: func(r, b int64) { records, bytes = records+r, bytes+b },
+	}
+
+	s, err := NewSpool(cfg)
+	if err != nil {
+		t.Fatalf("NewSpool() error = %v", err)
+	}
+	// room for two lines of 5 bytes, the third is refused.
+	if n, err := s.Write([]string{"aa", "bb", "cc"}); n != 2 || err != nil {
+		t.Fatalf("Write() = %d, %v, want 2", n, err)
+	}
+	if err := s.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	// a line torn by a crash is skipped, the files of others are ignored.
+	segments, _ := filepath.Glob(filepath.Join(dir, "test-*"))
+	f, err := os.OpenFile(segments[0], os.O_APPEND|os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _ = f.WriteString(`"d`)
+	f.Close()
+	_ = os.WriteFile(filepath.Join(dir, "other.ndjson"), []byte("\"e\"\n"), 0o644)
+
+	records, bytes = 0, 0
+	if s, err = NewSpool(cfg); err != nil {
+		t.Fatalf("NewSpool() again error = %v", err)
+	}
+	if s.Records() != 3 || records != 3 || bytes != 12 {
+		t.Errorf("Records() = %d, observed %d records of %d bytes, want 3 of 12", s.Records(), records, bytes)
+	}
+
+	items, done, err := s.Next()
+	if err != nil || done == nil {
+		t.Fatalf("Next() error = %v, want a segment", err)
+	}
+	if len(items) != 2 || items[0] != "aa" || items[1] != "bb" {
+		t.Errorf("Next() = %v, want [aa bb]", items)
+	}
+	done()
+
+	if _, done, _ := s.Next(); done != nil {
+		t.Error("Next() on an empty spool returned a segment")
+	}
+	if s.Records() != 0 || records != 0 || bytes != 0 {
+		t.Errorf("Records() = %d, observed %d records of %d bytes, want 0", s.Records(), records, bytes)
+	}
+	if segments, _ := filepath.Glob(filepath.Join(dir, "test-*")); len(segments) != 0 {
+		t.Errorf("segments left = %v", segments)
+	}
+}
@@ -0,0 +1,71 @@
+// Copyright 2026 The HuaTuo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package driver
+
+import (
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestTLSConfig(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	caFile := filepath.Join(dir, "ca.pem")
+	ca := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: srv.Certificate().Raw})
+	if err := os.WriteFile(caFile, ca, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	emptyFile := filepath.Join(dir, "empty.pem")
+	if err := os.WriteFile(emptyFile, []byte("not a certificate"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	get := func(caFile string, insecureSkipVerify bool) error {
+		config, err := TLSConfig(caFile, insecureSkipVerify)
+		if err != nil {
+			t.Fatalf("TLSConfig(%q, %v) error = %v", caFile, insecureSkipVerify, err)
+		}
+		client := &http.Client{Transport: &http.Transport{TLSClientConfig: config}}
+		res, err := client.Get(srv.URL)
+		if err == nil {
+			res.Body.Close()
+		}
+		return err
+	}
+
+	// the system roots do not know the test server.
+	if err := get("", false); err == nil {
+		t.Error("unknown server certificate verified")
+	}
+	if err := get(caFile, false); err != nil {
+		t.Errorf("verify with the ca file: %v", err)
+	}
+	if err := get("", true); err != nil {
+		t.Errorf("skip verify: %v", err)
+	}
+
+	if _, err := TLSConfig(filepath.Join(dir, "missing.pem"), false); err == nil {
+		t.Error("TLSConfig() of a missing ca file succeeded")
+	}
+	if _, err := TLSConfig(emptyFile, false); err == nil {
+		t.Error("TLSConfig() of a ca file without certificate succeeded")
+	}
+}
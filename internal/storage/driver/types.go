@@ -20,6 +20,8 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"sync/atomic"
+	"time"
 )
 
 // Sentinel errors returned by storage operations.
@@ -48,15 +50,78 @@ type Config struct {
 	Driver string
 
 	SQLiteDSN string
+	// SQLiteRetention deletes the rows whose SQLiteRetentionField time is
+	// older than it, zero keeps them forever.
+	SQLiteRetention      time.Duration
+	SQLiteRetentionField string
 
 	LocalFilePath         string
 	LocalFileRotationSize int
 	LocalFileMaxRotation  int
+	LocalFileMaxAgeDays   int
+	LocalFileCompression  string
+	LocalFileSyncInterval time.Duration
+
+	ESAddresses    []string
+	ESUsername     string
+	ESPassword     string
+	ESIndex        string
+	ESRoutes       []ESRoute
+	ESQueueSize    int
+	ESSpillPath    string
+	ESSpillMaxSize int64
+	ESIndexPattern string
+	ESILMPolicy    string
+	ESRetention    time.Duration
+	ESRolloverSize int64
+	ESRolloverAge  time.Duration
+
+	ClickHouseAddress            string
+	ClickHouseUsername           string
+	ClickHousePassword           string
+	ClickHouseDatabase           string
+	ClickHouseTable              string
+	ClickHouseCAFile             string
+	ClickHouseInsecureSkipVerify bool
+	ClickHouseBatchSize          int
+	ClickHouseFlushInterval      time.Duration
+	ClickHouseRetention          time.Duration
+
+	LokiAddress            string
+	LokiUsername           string
+	LokiPassword           string
+	LokiTenantID           string
+	LokiCAFile             string
+	LokiInsecureSkipVerify bool
+	LokiBatchSize          int
+	LokiFlushInterval      time.Duration
+
+	OTLPEndpoint           string
+	OTLPHeaders            map[string]string
+	OTLPInsecure           bool
+	OTLPCAFile             string
+	OTLPCertFile           string
+	OTLPKeyFile            string
+	OTLPInsecureSkipVerify bool
+	OTLPTimeout            time.Duration
+	OTLPBatchSize          int
+	OTLPFlushInterval      time.Duration
+
+	// DeadLetterPath keeps the records failing to be written as JSON
+	// lines, written again every DeadLetterRedriveInterval, empty drops
+	// them. DeadLetterMaxSize is in bytes, zero is unlimited.
+	DeadLetterPath            string
+	DeadLetterMaxSize         int64
+	DeadLetterRedriveInterval time.Duration
+}
 
-	ESAddresses []string
-	ESUsername  string
-	ESPassword  string
-	ESIndex     string
+// ESRoute sends the records of some tracers to a dedicated index family
+// with its own retention.
+type ESRoute struct {
+	Name    string
+	Tracers []string
+	// Retention is how long the records are kept, zero keeps them forever.
+	Retention time.Duration
 }
 
 // Op is a storage query operator.
@@ -143,3 +208,35 @@ type Backend interface {
 type Creator interface {
 	Create(ctx context.Context, rec Record) error
 }
+
+// Backlogger is implemented by backends with an asynchronous write path.
+// Backlog is the number of records accepted by Save but not yet delivered.
+type Backlogger interface {
+	Backlog() int64
+}
+
+// FailureNotifier is implemented by backends with an asynchronous write
+// path. The records still failing after the retries of the flusher are
+// passed to fn rather than dropped, their ID and Data are set.
+type FailureNotifier interface {
+	OnWriteFailure(fn func(recs []Record, err error))
+}
+
+// FailureHandler implements FailureNotifier for the backends embedding it.
+type FailureHandler struct {
+	fn atomic.Pointer[func([]Record, error)]
+}
+
+// OnWriteFailure sets the function the failed records are passed to.
+func (h *FailureHandler) OnWriteFailure(fn func(recs []Record, err error)) {
+	h.fn.Store(&fn)
+}
+
+// WriteFailed counts the records the backend failed to write and passes
+// them to the function set by OnWriteFailure, if any.
+func (h *FailureHandler) WriteFailed(backend string, recs []Record, err error) {
+	ObserveWriteFailures(backend, len(recs))
+	if fn := h.fn.Load(); fn != nil && len(recs) > 0 {
+		(*fn)(recs, err)
+	}
+}
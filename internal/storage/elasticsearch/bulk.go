@@ -0,0 +1,166 @@
+// Copyright 2026 The HuaTuo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package elasticsearch
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/elastic/go-elasticsearch/v8/esapi"
+
+	"huatuo-bamai/internal/log"
+	"huatuo-bamai/internal/storage/driver"
+)
+
+const (
+	// A batch is flushed at bulkBatchSize documents or bulkBatchBytes,
+	// whichever comes first, or every bulkFlushInterval.
+	bulkBatchSize     = 1000
+	bulkBatchBytes    = 5 * 1024 * 1024
+	bulkFlushInterval = time.Second
+
+	// bulkRetries is the attempts of a batch, the documents still failing
+	// after the last one are spilled or dropped. The waits between them
+	// grow from minBackoff.
+	bulkRetries = 5
+	minBackoff  = 500 * time.Millisecond
+	maxBackoff  = 30 * time.Second
+)
+
+// bulkItem is a document queued for the _bulk API.
+type bulkItem struct {
+	index  string
+	id     string
+	body   []byte
+	queued time.Time
+}
+
+func (item bulkItem) Queued() time.Time     { return item.queued }
+func (item bulkItem) Record() driver.Record { return driver.Record{ID: item.id, Data: item.body} }
+
+type bulkAction struct {
+	Index bulkActionMeta `json:"index"`
+}
+
+type bulkActionMeta struct {
+	Index string `json:"_index"`
+	ID    string `json:"_id,omitempty"`
+}
+
+type bulkResponse struct {
+	Errors bool                          `json:"errors"`
+	Items  []map[string]bulkResponseItem `json:"items"`
+}
+
+type bulkResponseItem struct {
+	Status int `json:"status"`
+	Error  *struct {
+		Type   string `json:"type"`
+		Reason string `json:"reason"`
+	} `json:"error,omitempty"`
+}
+
+// retryableStatus is the statuses of an overloaded or unavailable cluster.
+func retryableStatus(status int) bool {
+	return status == http.StatusTooManyRequests || status >= http.StatusInternalServerError
+}
+
+func encodeBulk(items []bulkItem) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	for i := range items {
+		if err := enc.Encode(bulkAction{Index: bulkActionMeta{Index: items[i].index, ID: items[i].id}}); err != nil {
+			return nil, err
+		}
+		buf.Write(bytes.TrimSpace(items[i].body))
+		buf.WriteByte('\n')
+	}
+	return buf.Bytes(), nil
+}
+
+// spill writes the documents the queue or the cluster could not take to the
+// spool, it returns how many of them it took.
+func (s *Storage) spill(items []bulkItem) int {
+	written, err := s.spool.Write(items)
+	if err != nil {
+		log.Errorf("elasticsearch spool: %v", err)
+	}
+	return written
+}
+
+// send sends batch to the _bulk API. The documents are retried as a whole
+// while the cluster is overloaded or unavailable, or one by one on the
+// statuses of their items.
+func (s *Storage) send(ctx context.Context, batch []bulkItem) driver.BatchResult[bulkItem] {
+	if err := s.ensureLifecycle(ctx); err != nil {
+		return driver.BatchSent(batch, err, true)
+	}
+
+	body, err := encodeBulk(batch)
+	if err != nil {
+		return driver.BatchSent(batch, fmt.Errorf("elasticsearch bulk: %w", err), false)
+	}
+
+	req := esapi.BulkRequest{Body: bytes.NewReader(body)}
+	res, err := req.Do(ctx, s.transport)
+	if err != nil {
+		return driver.BatchSent(batch, fmt.Errorf("elasticsearch bulk: %w", err), true)
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		return driver.BatchSent(batch, responseError("bulk", s.index, res), retryableStatus(res.StatusCode))
+	}
+
+	var payload bulkResponse
+	if err := json.NewDecoder(res.Body).Decode(&payload); err != nil {
+		// the documents may be indexed already, those without an ID are
+		// duplicated by the retry.
+		return driver.BatchSent(batch, fmt.Errorf("elasticsearch bulk: decode: %w", err), true)
+	}
+	if len(payload.Items) != len(batch) {
+		return driver.BatchSent(batch, fmt.Errorf("elasticsearch bulk: %d items in response, want %d", len(payload.Items), len(batch)), true)
+	}
+
+	var result driver.BatchResult[bulkItem]
+	for i := range payload.Items {
+		var item bulkResponseItem
+		for _, v := range payload.Items[i] {
+			item = v
+		}
+
+		switch {
+		case item.Status < http.StatusMultipleChoices:
+			result.Delivered = append(result.Delivered, batch[i])
+		case retryableStatus(item.Status):
+			result.Err = fmt.Errorf("elasticsearch bulk %s/%s: status %d", batch[i].index, batch[i].id, item.Status)
+			result.Retry = append(result.Retry, batch[i])
+		default:
+			reason := ""
+			if item.Error != nil {
+				reason = item.Error.Type + ": " + item.Error.Reason
+			}
+			// every rejection is logged, the batch keeps the last one.
+			result.RejectErr = fmt.Errorf("elasticsearch bulk %s/%s: status %d %s", batch[i].index, batch[i].id, item.Status, reason)
+			log.Errorf("%v", result.RejectErr)
+			result.Rejected = append(result.Rejected, batch[i])
+		}
+	}
+	return result
+}
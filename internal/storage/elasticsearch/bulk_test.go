@@ -0,0 +1,165 @@
+// Copyright 2026 The HuaTuo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package elasticsearch
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"os"
+	"testing"
+	"time"
+
+	"huatuo-bamai/internal/storage/driver"
+)
+
+func bulkRecord(id string) driver.Record {
+	return driver.Record{ID: id, Data: []byte(`{"id":"` + id + `"}`)}
+}
+
+func (m *mockElasticsearchServer) indexed(index string) int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return len(m.indexes[index])
+}
+
+// closeNow closes the backend without the backoff, the retries are made
+// at once.
+func closeNow(t *testing.T, backend *Storage) {
+	t.Helper()
+
+	ctx, cancel := context.WithCancel(t.Context())
+	cancel()
+	if err := backend.Close(ctx); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+}
+
+// TestBulkRetry covers the retries: a whole batch failing with 503 and a
+// document with 429 are sent again, a document refused with 400 is dropped.
+func TestBulkRetry(t *testing.T) {
+	server := newMockElasticsearchServer()
+	defer server.Close()
+
+	backend := newBackendForTest(t, server)
+
+	server.mu.Lock()
+	// more than the client retries by itself.
+	server.bulkStatuses = []int{503, 503, 503, 503, 503}
+	tooMany := 1
+	server.itemStatus = func(id string) int {
+		switch {
+		case id == "b" && tooMany > 0:
+			tooMany--
+			return http.StatusTooManyRequests
+		case id == "c":
+			return http.StatusBadRequest
+		}
+		return 0
+	}
+	server.mu.Unlock()
+
+	for _, id := range []string{"a", "b", "c"} {
+		if err := backend.Save(t.Context(), bulkRecord(id)); err != nil {
+			t.Fatalf("Save(%s) error = %v", id, err)
+		}
+	}
+	flushBackend(t, backend)
+
+	for id, want := range map[string]bool{"a": true, "b": true, "c": false} {
+		server.mu.Lock()
+		_, got := server.indexes["huatuo_bamai"][id]
+		server.mu.Unlock()
+		if got != want {
+			t.Errorf("document %s indexed = %v, want %v", id, got, want)
+		}
+	}
+	if got := backend.Backlog(); got != 0 {
+		t.Errorf("Backlog() after Close = %d, want 0", got)
+	}
+}
+
+func TestBulkQueueFull(t *testing.T) {
+	server := newMockElasticsearchServer()
+	defer server.Close()
+
+	backend, err := NewBackend(&Config{Addresses: []string{server.URL()}, QueueSize: 2})
+	if err != nil {
+		t.Fatalf("NewBackend() error = %v", err)
+	}
+	defer flushBackend(t, backend)
+
+	for _, id := range []string{"a", "b"} {
+		if err := backend.Save(t.Context(), bulkRecord(id)); err != nil {
+			t.Fatalf("Save(%s) error = %v", id, err)
+		}
+	}
+	if err := backend.Save(t.Context(), bulkRecord("c")); !errors.Is(err, driver.ErrQueueFull) {
+		t.Errorf("Save() on a full queue error = %v, want driver.ErrQueueFull", err)
+	}
+}
+
+// TestBulkSpill covers the spool: the documents failing after the retries
+// spill to disk and are sent by the next backend once the cluster is back.
+func TestBulkSpill(t *testing.T) {
+	server := newMockElasticsearchServer()
+	defer server.Close()
+
+	server.mu.Lock()
+	server.itemStatus = func(string) int { return http.StatusTooManyRequests }
+	server.mu.Unlock()
+
+	dir := t.TempDir()
+	cfg := &Config{Addresses: []string{server.URL()}, SpillPath: dir}
+	backend, err := NewBackend(cfg)
+	if err != nil {
+		t.Fatalf("NewBackend() error = %v", err)
+	}
+	for _, id := range []string{"a", "b"} {
+		if err := backend.Save(t.Context(), bulkRecord(id)); err != nil {
+			t.Fatalf("Save(%s) error = %v", id, err)
+		}
+	}
+	closeNow(t, backend)
+
+	if got := server.indexed("huatuo_bamai"); got != 0 {
+		t.Fatalf("indexed = %d, want 0 while failing", got)
+	}
+	if got := backend.spool.Records(); got != 2 {
+		t.Fatalf("spooled = %d, want 2", got)
+	}
+
+	server.mu.Lock()
+	server.itemStatus = nil
+	server.mu.Unlock()
+
+	backend, err = NewBackend(cfg)
+	if err != nil {
+		t.Fatalf("NewBackend() error = %v", err)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for server.indexed("huatuo_bamai") < 2 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	flushBackend(t, backend)
+
+	if got := server.indexed("huatuo_bamai"); got != 2 {
+		t.Errorf("indexed after replay = %d, want 2", got)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+		t.Errorf("spool segments left = %d, want 0", len(entries))
+	}
+}
@@ -95,9 +95,9 @@ func newCompatClient(addresses []string, username, password string) (*elasticsea
 		Password:                password,
 		EnableCompatibilityMode: true,
 		Transport:               &productHeaderTransport{inner: defaultTransport},
-		// Whole-batch retry: covers transport failures and 429/5xx returned for
-		// the entire bulk request. Per-item failures inside a 200 response are
-		// surfaced through BulkIndexerItem.OnFailure instead.
+		// Quick retry of every request on transport failures and 429/5xx.
+		// The bulk writer backs off longer on top, for a whole batch or the
+		// per-item failures inside a 200 response.
 		RetryOnStatus: []int{429, 502, 503, 504},
 		MaxRetries:    3,
 		RetryBackoff: func(attempt int) time.Duration {
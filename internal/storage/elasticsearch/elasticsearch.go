@@ -20,14 +20,15 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/elastic/go-elasticsearch/v8/esapi"
-	"github.com/elastic/go-elasticsearch/v8/esutil"
 	escount "github.com/elastic/go-elasticsearch/v8/typedapi/core/count"
 	esget "github.com/elastic/go-elasticsearch/v8/typedapi/core/get"
 	essearch "github.com/elastic/go-elasticsearch/v8/typedapi/core/search"
@@ -40,12 +41,9 @@ const (
 	defaultIndex     = "huatuo_bamai"
 	defaultQuerySize = 10000
 
-	// Bulk indexer tuning. 5MB / 1s matches the upstream defaults and is a
-	// safe starting point for ES/OpenSearch single-node and small clusters.
-	// Adjust if write rate or per-event size drifts significantly.
-	bulkFlushBytes    = 5 * 1024 * 1024
-	bulkFlushInterval = time.Second
-	bulkNumWorkers    = 4
+	defaultQueueSize = 10000
+
+	metricsBackend = "elasticsearch"
 )
 
 // Config contains Elasticsearch backend settings.
@@ -54,71 +52,200 @@ type Config struct {
 	Username  string
 	Password  string
 	Index     string
+	// Routes send some tracers to their own daily indices under Index.
+	Routes []driver.ESRoute
+	// QueueSize is the documents queued in memory, Save fails with the
+	// queue full unless they spill to SpillPath.
+	QueueSize int
+	// SpillPath is the directory the documents spill to when the queue is
+	// full or the cluster keeps failing, empty disables spilling.
+	SpillPath string
+	// SpillMaxSize is the bytes the spill directory is capped at, zero is
+	// unlimited.
+	SpillMaxSize int64
+	// IndexPattern writes the records not routed to time-suffixed indices,
+	// e.g. "huatuo_bamai-%Y.%m.%d" in UTC, empty writes them to Index.
+	IndexPattern string
+	// ILMPolicy is the ILM policy installed at startup for the indices of
+	// IndexPattern or of the rollover, deleting them after Retention.
+	ILMPolicy string
+	Retention time.Duration
+	// RolloverSize in bytes of a primary shard and RolloverAge roll the
+	// indices behind the Index alias over, either is enough.
+	RolloverSize int64
+	RolloverAge  time.Duration
 }
 
+// ignoreUnavailable reads the concrete Index while it is missing, it may
+// never be written with IndexPattern.
+var ignoreUnavailable = true
+
 // Storage stores records in Elasticsearch, OpenSearch, or any compatible backend.
 //
-// Save is asynchronous: items are queued in bulk and flushed by size, time, or
-// Close. A nil error from Save means the item was buffered, not that it landed
-// in the index. Permanent per-item failures are logged via OnFailure; transient
-// whole-batch failures (429, 5xx, transport errors) are retried by the client.
-// Call Close on shutdown to flush any pending events.
+// Save is asynchronous: documents are queued in memory and sent to the _bulk
+// API by size, time, or Close. A nil error from Save means the document was
+// queued, not that it landed in the index. Overloaded or unavailable clusters
+// (429, 5xx, transport errors) are retried with backoff, as a whole batch or
+// per document; documents refused for good (parsing, mapping) are dropped.
+// Call Close on shutdown to flush any pending documents.
 type Storage struct {
 	transport esapi.Transport
-	bulk      esutil.BulkIndexer
 	index     string
+	routes    *routes
+
+	// lifecycle names and expires the indices of the records not routed,
+	// lifecycleReady is its setup done.
+	lifecycle      *lifecycle
+	lifecycleReady atomic.Bool
+
+	batcher *driver.Batcher[bulkItem]
+
+	// spool keeps the documents the queue or the cluster could not take,
+	// nil without SpillPath.
+	spool *driver.Spool[bulkItem]
+
+	cleanupCancel context.CancelFunc
+	cleanupDone   chan struct{}
+
+	// FailureHandler passes the documents failing after the retries, and
+	// not spilled, to the dead letter of the store.
+	driver.FailureHandler
 }
 
-var _ driver.Backend = (*Storage)(nil)
+var (
+	_ driver.Backend         = (*Storage)(nil)
+	_ driver.Backlogger      = (*Storage)(nil)
+	_ driver.FailureNotifier = (*Storage)(nil)
+)
 
 func init() {
 	factory := func(cfg *driver.Config) (driver.Backend, error) {
 		return NewBackend(&Config{
-			Addresses: cfg.ESAddresses,
-			Username:  cfg.ESUsername,
-			Password:  cfg.ESPassword,
-			Index:     cfg.ESIndex,
+			Addresses:    cfg.ESAddresses,
+			Username:     cfg.ESUsername,
+			Password:     cfg.ESPassword,
+			Index:        cfg.ESIndex,
+			Routes:       cfg.ESRoutes,
+			QueueSize:    cfg.ESQueueSize,
+			SpillPath:    cfg.ESSpillPath,
+			SpillMaxSize: cfg.ESSpillMaxSize,
+			IndexPattern: cfg.ESIndexPattern,
+			ILMPolicy:    cfg.ESILMPolicy,
+			Retention:    cfg.ESRetention,
+			RolloverSize: cfg.ESRolloverSize,
+			RolloverAge:  cfg.ESRolloverAge,
 		})
 	}
 	driver.RegisterBackend("elasticsearch", factory)
 	driver.RegisterBackend("opensearch", factory)
 }
 
-// NewBackend creates a backend that connects to Elasticsearch v7/v8 or OpenSearch.
+// NewBackend creates a backend that connects to Elasticsearch v7/v8 or
+// OpenSearch and starts its flusher.
 func NewBackend(cfg *Config) (*Storage, error) {
 	prefix := cfg.Index
 	if prefix == "" {
 		prefix = defaultIndex
 	}
+	routes, err := newRoutes(prefix, cfg.Routes)
+	if err != nil {
+		return nil, err
+	}
+	if routes.lifecycle, err = newLifecycle(prefix, cfg); err != nil {
+		return nil, err
+	}
+
 	client, err := newCompatClient(cfg.Addresses, cfg.Username, cfg.Password)
 	if err != nil {
 		return nil, err
 	}
 
-	bulk, err := esutil.NewBulkIndexer(esutil.BulkIndexerConfig{
-		Client:        client,
-		Index:         prefix,
-		NumWorkers:    bulkNumWorkers,
-		FlushBytes:    bulkFlushBytes,
+	s := &Storage{
+		transport: client,
+		index:     prefix,
+		routes:    routes,
+		lifecycle: routes.lifecycle,
+	}
+
+	if s.lifecycle.policy != "" || s.lifecycle.family != "" {
+		// the rollover setup is tried again before the first bulk.
+		err := s.setupLifecycle(context.Background())
+		switch {
+		case errors.Is(err, errIndexNotAlias):
+			return nil, err
+		case err != nil:
+			log.Warnf("elasticsearch lifecycle: %v", err)
+		default:
+			s.lifecycleReady.Store(true)
+		}
+	}
+
+	if cfg.SpillPath != "" {
+		if s.spool, err = newSpool(cfg.SpillPath, cfg.SpillMaxSize); err != nil {
+			return nil, err
+		}
+		driver.ObserveRestored(metricsBackend, int(s.spool.Records()))
+	}
+
+	if len(cfg.Routes) > 0 {
+		// a missing template only loses the index settings, keep writing.
+		if err := s.putTemplates(context.Background()); err != nil {
+			log.Warnf("elasticsearch routes: %v", err)
+		}
+
+		var ctx context.Context
+		ctx, s.cleanupCancel = context.WithCancel(context.Background())
+		s.cleanupDone = make(chan struct{})
+		go s.cleanupLoop(ctx)
+	}
+
+	batcher := &driver.BatcherConfig[bulkItem]{
+		Backend:       metricsBackend,
+		BatchSize:     bulkBatchSize,
+		BatchBytes:    bulkBatchBytes,
 		FlushInterval: bulkFlushInterval,
-		OnError: func(_ context.Context, err error) {
-			log.Errorf("elasticsearch bulk: %v", err)
-		},
-	})
-	if err != nil {
-		return nil, fmt.Errorf("elasticsearch bulk indexer: %w", err)
+		QueueSize:     cfg.QueueSize,
+		Retries:       bulkRetries,
+		MinBackoff:    minBackoff,
+		MaxBackoff:    maxBackoff,
+		Send:          s.send,
+		Failure:       &s.FailureHandler,
+	}
+	if batcher.QueueSize <= 0 {
+		batcher.QueueSize = defaultQueueSize
+	}
+	if s.spool != nil {
+		batcher.Spill = s.spill
+		batcher.Unspill = s.spool.Next
 	}
+	s.batcher = driver.NewBatcher(batcher)
 
-	return &Storage{transport: client, bulk: bulk, index: prefix}, nil
+	return s, nil
 }
 
-// Close flushes any pending bulk operations and stops the indexer workers.
-// After Close, Save will panic; the Storage must not be reused.
+// Backlog returns the documents queued in memory and not yet flushed, it
+// grows when the cluster indexes slower than the node writes.
+func (s *Storage) Backlog() int64 {
+	return s.batcher.Backlog()
+}
+
+// Close stops the flusher and sends the pending documents, those the
+// cluster does not take are left in the spool for the next run. The
+// Storage must not be reused.
 func (s *Storage) Close(ctx context.Context) error {
-	if s.bulk == nil {
+	if s.cleanupCancel != nil {
+		s.cleanupCancel()
+		<-s.cleanupDone
+	}
+	if s.batcher == nil {
 		return nil
 	}
-	return s.bulk.Close(ctx)
+	s.batcher.Close(ctx)
+
+	if s.spool != nil {
+		return s.spool.Close()
+	}
+	return nil
 }
 
 func (s *Storage) Init(_ context.Context, _ string, indexes []driver.Index) error {
@@ -130,32 +257,30 @@ func (s *Storage) Init(_ context.Context, _ string, indexes []driver.Index) erro
 	return nil
 }
 
-func (s *Storage) Save(ctx context.Context, rec driver.Record) error {
-	item := esutil.BulkIndexerItem{
-		Index:      s.index,
-		Action:     "index",
-		DocumentID: rec.ID,
-		Body:       bytes.NewReader(rec.Data),
-		OnFailure: func(_ context.Context, _ esutil.BulkIndexerItem, res esutil.BulkIndexerResponseItem, err error) {
-			// Reached only after client-level retries are exhausted, or the
-			// failure is per-item (parsing, mapping, version conflict). The
-			// item is dropped — caller does not learn about this synchronously.
-			if err != nil {
-				log.Errorf("elasticsearch bulk save %s/%s: %v", s.index, rec.ID, err)
-				return
-			}
-			log.Errorf("elasticsearch bulk save %s/%s: status=%d type=%s reason=%s",
-				s.index, rec.ID, res.Status, res.Error.Type, res.Error.Reason)
-		},
-	}
-	if err := s.bulk.Add(driver.WithContext(ctx), item); err != nil {
-		return fmt.Errorf("elasticsearch backend save %s: %w", s.index, err)
-	}
-	log.Debugf("elasticsearch bulk queued index=%s id=%s data=%s", s.index, rec.ID, rec.Data)
+func (s *Storage) Save(_ context.Context, rec driver.Record) error {
+	queued := time.Now()
+	item := bulkItem{index: s.routes.index(rec, queued), id: rec.ID, body: rec.Data, queued: queued}
+	if err := s.batcher.Save(item); err != nil {
+		return fmt.Errorf("elasticsearch backend save %s: %w", item.index, err)
+	}
+	log.Debugf("elasticsearch bulk queued index=%s id=%s data=%s", item.index, rec.ID, rec.Data)
 	return nil
 }
 
-func (s *Storage) Get(ctx context.Context, id string) (rec driver.Record, err error) {
+func (s *Storage) Get(ctx context.Context, id string) (driver.Record, error) {
+	indices := s.routes.readIndices()
+	// a get on the rollover alias fails once it has several indices.
+	if !s.lifecycle.rollover() {
+		rec, err := s.getDefault(ctx, id)
+		if !errors.Is(err, driver.ErrNotFound) || len(indices) == 1 {
+			return rec, err
+		}
+		indices = indices[1:]
+	}
+	return s.getSearch(ctx, indices, id)
+}
+
+func (s *Storage) getDefault(ctx context.Context, id string) (rec driver.Record, err error) {
 	req := esapi.GetRequest{Index: s.index, DocumentID: id}
 	res, err := req.Do(driver.WithContext(ctx), s.transport)
 	if err != nil {
@@ -184,7 +309,76 @@ func (s *Storage) Get(ctx context.Context, id string) (rec driver.Record, err er
 	return driver.Record{ID: recordID, Data: driver.CloneBytes(payload.Source_)}, nil
 }
 
+// getSearch looks id up in the routed daily indices and the indices of the
+// lifecycle, which a plain get cannot address by wildcard.
+func (s *Storage) getSearch(ctx context.Context, indices []string, id string) (driver.Record, error) {
+	body, err := idsQuery(id)
+	if err != nil {
+		return driver.Record{}, err
+	}
+
+	req := esapi.SearchRequest{Index: indices, Body: bytes.NewReader(body), IgnoreUnavailable: &ignoreUnavailable}
+	res, err := req.Do(driver.WithContext(ctx), s.transport)
+	if err != nil {
+		return driver.Record{}, fmt.Errorf("elasticsearch backend get %v/%s: %w", indices, id, err)
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		return driver.Record{}, responseError("get document", strings.Join(indices, ","), res)
+	}
+
+	var payload essearch.Response
+	if err := json.NewDecoder(res.Body).Decode(&payload); err != nil {
+		return driver.Record{}, fmt.Errorf("elasticsearch backend get %v/%s: decode: %w", indices, id, err)
+	}
+	if len(payload.Hits.Hits) == 0 {
+		return driver.Record{}, driver.ErrNotFound
+	}
+	return driver.Record{ID: id, Data: driver.CloneBytes(payload.Hits.Hits[0].Source_)}, nil
+}
+
+func idsQuery(id string) ([]byte, error) {
+	return json.Marshal(map[string]any{
+		"size":  1,
+		"query": map[string]any{"ids": map[string]any{"values": []string{id}}},
+	})
+}
+
 func (s *Storage) Delete(ctx context.Context, id string) error {
+	indices := s.routes.readIndices()
+	if !s.lifecycle.rollover() {
+		if err := s.deleteDefault(ctx, id); err != nil || len(indices) == 1 {
+			return err
+		}
+		indices = indices[1:]
+	}
+
+	body, err := idsQuery(id)
+	if err != nil {
+		return err
+	}
+
+	refresh := true
+	req := esapi.DeleteByQueryRequest{
+		Index:             indices,
+		Body:              bytes.NewReader(body),
+		Refresh:           &refresh,
+		IgnoreUnavailable: &ignoreUnavailable,
+	}
+	res, err := req.Do(driver.WithContext(ctx), s.transport)
+	if err != nil {
+		return fmt.Errorf("elasticsearch backend delete %v/%s: %w", indices, id, err)
+	}
+	defer res.Body.Close()
+
+	if res.IsError() && res.StatusCode != http.StatusNotFound {
+		return responseError("delete document", strings.Join(indices, ","), res)
+	}
+	return nil
+}
+
+func (s *Storage) deleteDefault(ctx context.Context, id string) error {
 	req := esapi.DeleteRequest{Index: s.index, DocumentID: id, Refresh: "true"}
 	res, err := req.Do(driver.WithContext(ctx), s.transport)
 	if err != nil {
@@ -207,7 +401,7 @@ func (s *Storage) Query(ctx context.Context, q driver.Query) ([]driver.Record, e
 		return nil, err
 	}
 
-	req := esapi.SearchRequest{Index: []string{s.index}, Body: bytes.NewReader(body)}
+	req := esapi.SearchRequest{Index: s.routes.readIndices(), Body: bytes.NewReader(body), IgnoreUnavailable: &ignoreUnavailable}
 	res, err := req.Do(driver.WithContext(ctx), s.transport)
 	if err != nil {
 		return nil, fmt.Errorf("elasticsearch backend query %s: %w", s.index, err)
@@ -240,7 +434,7 @@ func (s *Storage) Count(ctx context.Context, q driver.Query) (int64, error) {
 		return 0, err
 	}
 
-	req := esapi.CountRequest{Index: []string{s.index}, Body: bytes.NewReader(body)}
+	req := esapi.CountRequest{Index: s.routes.readIndices(), Body: bytes.NewReader(body), IgnoreUnavailable: &ignoreUnavailable}
 	res, err := req.Do(driver.WithContext(ctx), s.transport)
 	if err != nil {
 		return 0, fmt.Errorf("elasticsearch backend count %s: %w", s.index, err)
@@ -264,7 +458,7 @@ func (s *Storage) Values(ctx context.Context, field string, q driver.Query, size
 		return nil, err
 	}
 
-	req := esapi.SearchRequest{Index: []string{s.index}, Body: bytes.NewReader(body)}
+	req := esapi.SearchRequest{Index: s.routes.readIndices(), Body: bytes.NewReader(body), IgnoreUnavailable: &ignoreUnavailable}
 	res, err := req.Do(driver.WithContext(ctx), s.transport)
 	if err != nil {
 		return nil, fmt.Errorf("elasticsearch backend terms %s/%s: %w", s.index, field, err)
@@ -21,6 +21,7 @@ import (
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"path"
 	"sort"
 	"strings"
 	"sync"
@@ -43,11 +44,24 @@ type mockElasticsearchServer struct {
 	searchBodies      []map[string]any
 	countBodies       []map[string]any
 	server            *httptest.Server
+
+	// aliases are the write index of an alias, lifecycleBodies the ILM
+	// policies and index templates by path.
+	aliases         map[string]string
+	lifecycleBodies map[string]map[string]any
+
+	// bulkStatuses fail the next bulk requests as a whole, itemStatus the
+	// documents by ID, zero indexes them.
+	bulkStatuses []int
+	itemStatus   func(id string) int
+	bulkRequests int
 }
 
 func newMockElasticsearchServer() *mockElasticsearchServer {
 	mockServer := &mockElasticsearchServer{
-		indexes: make(map[string]map[string]mockElasticsearchDocument),
+		indexes:         make(map[string]map[string]mockElasticsearchDocument),
+		aliases:         make(map[string]string),
+		lifecycleBodies: make(map[string]map[string]any),
 	}
 
 	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -67,6 +81,10 @@ func newMockElasticsearchServer() *mockElasticsearchServer {
 			mockServer.handleBulk(w, r, "")
 		case len(parts) == 2 && parts[1] == "_bulk":
 			mockServer.handleBulk(w, r, parts[0])
+		case (parts[0] == "_ilm" || parts[0] == "_index_template") && r.Method == http.MethodPut:
+			mockServer.handlePutLifecycle(w, r, path)
+		case len(parts) == 2 && parts[0] == "_alias" && r.Method == http.MethodHead:
+			mockServer.handleAliasExists(w, parts[1])
 		case len(parts) == 1 && r.Method == http.MethodHead:
 			mockServer.handleIndexExists(w, parts[0])
 		case len(parts) == 1 && r.Method == http.MethodPut:
@@ -128,11 +146,39 @@ func (m *mockElasticsearchServer) handleCreateIndex(w http.ResponseWriter, r *ht
 		m.indexes[index] = make(map[string]mockElasticsearchDocument)
 	}
 	m.createIndexBodies = append(m.createIndexBodies, body)
+	if aliases, ok := body["aliases"].(map[string]any); ok {
+		for alias := range aliases {
+			m.aliases[alias] = index
+		}
+	}
 
 	w.WriteHeader(http.StatusOK)
 	_, _ = w.Write([]byte(`{"acknowledged":true}`))
 }
 
+func (m *mockElasticsearchServer) handlePutLifecycle(w http.ResponseWriter, r *http.Request, path string) {
+	var body map[string]any
+	_ = json.NewDecoder(r.Body).Decode(&body)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.lifecycleBodies[path] = body
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write([]byte(`{"acknowledged":true}`))
+}
+
+func (m *mockElasticsearchServer) handleAliasExists(w http.ResponseWriter, alias string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.aliases[alias]; ok {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	w.WriteHeader(http.StatusNotFound)
+}
+
 func (m *mockElasticsearchServer) handleSaveDocument(w http.ResponseWriter, r *http.Request, index, id string) {
 	var raw json.RawMessage
 	_ = json.NewDecoder(r.Body).Decode(&raw)
@@ -179,6 +225,16 @@ func (m *mockElasticsearchServer) handleBulk(w http.ResponseWriter, r *http.Requ
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
+	m.bulkRequests++
+	if len(m.bulkStatuses) > 0 {
+		status := m.bulkStatuses[0]
+		m.bulkStatuses = m.bulkStatuses[1:]
+		w.WriteHeader(status)
+		_, _ = w.Write([]byte(`{"error":"injected"}`))
+		return
+	}
+	failed := false
+
 	for i := 0; i+1 < len(lines); i += 2 {
 		var act bulkAction
 		if err := json.Unmarshal(lines[i], &act); err != nil {
@@ -189,8 +245,26 @@ func (m *mockElasticsearchServer) handleBulk(w http.ResponseWriter, r *http.Requ
 		if idx == "" {
 			idx = defaultIndex
 		}
+		if index, ok := m.aliases[idx]; ok {
+			idx = index
+		}
 		id := act.Index.ID
 
+		if m.itemStatus != nil {
+			if status := m.itemStatus(id); status != 0 {
+				failed = true
+				items = append(items, map[string]any{
+					"index": map[string]any{
+						"_index": idx,
+						"_id":    id,
+						"status": status,
+						"error":  map[string]any{"type": "injected", "reason": "injected failure"},
+					},
+				})
+				continue
+			}
+		}
+
 		source := cloneRawMessage(lines[i+1])
 		var fields map[string]any
 		_ = json.Unmarshal(source, &fields)
@@ -214,7 +288,7 @@ func (m *mockElasticsearchServer) handleBulk(w http.ResponseWriter, r *http.Requ
 	w.WriteHeader(http.StatusOK)
 	_ = json.NewEncoder(w).Encode(map[string]any{
 		"took":   1,
-		"errors": false,
+		"errors": failed,
 		"items":  items,
 	})
 }
@@ -400,17 +474,32 @@ func (m *mockElasticsearchServer) queryDocumentsLocked(index string, body map[st
 	return append([]mockElasticsearchDocument(nil), docs[from:end]...)
 }
 
-func (m *mockElasticsearchServer) matchDocumentsLocked(index string, rawQuery any) []mockElasticsearchDocument {
-	docsByID := m.indexes[index]
-	docs := make([]mockElasticsearchDocument, 0, len(docsByID))
-	for _, doc := range docsByID {
-		if matchesQuery(doc, rawQuery) {
-			docs = append(docs, doc)
+// matchDocumentsLocked searches the comma-separated indices, wildcards and
+// aliases, like the cluster.
+func (m *mockElasticsearchServer) matchDocumentsLocked(indices string, rawQuery any) []mockElasticsearchDocument {
+	var docs []mockElasticsearchDocument
+	for name, docsByID := range m.indexes {
+		if !mockIndexMatches(name, indices, m.aliases) {
+			continue
+		}
+		for _, doc := range docsByID {
+			if matchesQuery(doc, rawQuery) {
+				docs = append(docs, doc)
+			}
 		}
 	}
 	return docs
 }
 
+func mockIndexMatches(name, indices string, aliases map[string]string) bool {
+	for _, pattern := range strings.Split(indices, ",") {
+		if ok, _ := path.Match(pattern, name); ok || aliases[pattern] == name {
+			return true
+		}
+	}
+	return false
+}
+
 func matchesQuery(doc mockElasticsearchDocument, rawQuery any) bool {
 	queryMap, ok := rawQuery.(map[string]any)
 	if !ok || len(queryMap) == 0 {
@@ -697,9 +786,9 @@ func newBackendForTest(t *testing.T, server *mockElasticsearchServer) *Storage {
 	return backend
 }
 
-// flushBackend forces the bulk indexer to drain pending items. Save buffers
+// flushBackend forces the bulk queue to drain pending items. Save buffers
 // asynchronously, so tests must flush before issuing a read that depends on a
-// just-saved record. After flushing the indexer is closed; tests that need to
+// just-saved record. After flushing the backend is closed; tests that need to
 // keep saving must rebuild the backend.
 func flushBackend(t *testing.T, backend *Storage) {
 	t.Helper()
@@ -837,7 +926,13 @@ func TestElasticsearchBackendCRUD(t *testing.T) {
 	if err := backend.Save(t.Context(), record); err != nil {
 		t.Errorf("Save() returned error: %v", err)
 	}
+	if got := backend.Backlog(); got != 1 {
+		t.Errorf("Backlog() before flush = %d, want 1", got)
+	}
 	flushBackend(t, backend)
+	if got := backend.Backlog(); got != 0 {
+		t.Errorf("Backlog() after flush = %d, want 0", got)
+	}
 
 	gotRecord, err := backend.Get(t.Context(), "job-es-alpha")
 	if err != nil {
@@ -0,0 +1,305 @@
+// Copyright 2026 The HuaTuo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package elasticsearch
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/elastic/go-elasticsearch/v8/esapi"
+
+	"huatuo-bamai/internal/log"
+)
+
+// lifecycleTemplatePrio is below the templates of the routes, whose indices
+// are more specific.
+const lifecycleTemplatePrio = 100
+
+// errIndexNotAlias is a concrete index in place of the rollover alias, the
+// documents would never roll over.
+var errIndexNotAlias = errors.New("index exists in place of the rollover alias")
+
+// lifecycle is how the indices of the records not routed are named and
+// expire: a single Index, time-suffixed indices of IndexPattern, or the
+// indices an ILM policy rolls over behind the Index alias.
+type lifecycle struct {
+	index   string
+	pattern string
+	// family matches every index written, empty for the single Index.
+	family string
+
+	policy       string
+	rolloverSize int64
+	rolloverAge  time.Duration
+	retention    time.Duration
+}
+
+func newLifecycle(index string, cfg *Config) (*lifecycle, error) {
+	l := &lifecycle{
+		index:        index,
+		pattern:      cfg.IndexPattern,
+		policy:       cfg.ILMPolicy,
+		rolloverSize: cfg.RolloverSize,
+		rolloverAge:  cfg.RolloverAge,
+		retention:    cfg.Retention,
+	}
+
+	if l.policy == "" && (l.rollover() || l.retention > 0) {
+		return nil, errors.New("elasticsearch rollover and retention need an ILM policy")
+	}
+
+	switch {
+	case l.pattern != "":
+		if l.rollover() {
+			return nil, errors.New("elasticsearch rollover writes to the Index alias, not to IndexPattern")
+		}
+		static, _, _ := strings.Cut(l.pattern, "%")
+		if static == l.pattern {
+			return nil, fmt.Errorf("elasticsearch index pattern %q has no time", l.pattern)
+		}
+		if _, err := formatIndex(l.pattern, time.Time{}); err != nil {
+			return nil, err
+		}
+		l.family = static + "*"
+	case l.rollover():
+		l.family = index + "-*"
+	}
+	return l, nil
+}
+
+// rollover is whether the ILM policy rolls the indices over.
+func (l *lifecycle) rollover() bool {
+	return l.rolloverSize > 0 || l.rolloverAge > 0
+}
+
+// writeIndex returns the index written at now, the alias when rolling over.
+func (l *lifecycle) writeIndex(now time.Time) string {
+	if l.pattern == "" {
+		return l.index
+	}
+	// validated by newLifecycle.
+	index, _ := formatIndex(l.pattern, now)
+	return index
+}
+
+// formatIndex replaces the %Y, %m, %d and %H of pattern by the UTC time.
+func formatIndex(pattern string, now time.Time) (string, error) {
+	now = now.UTC()
+
+	var b strings.Builder
+	for i := 0; i < len(pattern); i++ {
+		if pattern[i] != '%' {
+			b.WriteByte(pattern[i])
+			continue
+		}
+
+		i++
+		if i == len(pattern) {
+			return "", fmt.Errorf("elasticsearch index pattern %q ends with %%", pattern)
+		}
+		switch pattern[i] {
+		case 'Y':
+			b.WriteString(strconv.Itoa(now.Year()))
+		case 'm':
+			fmt.Fprintf(&b, "%02d", int(now.Month()))
+		case 'd':
+			fmt.Fprintf(&b, "%02d", now.Day())
+		case 'H':
+			fmt.Fprintf(&b, "%02d", now.Hour())
+		case '%':
+			b.WriteByte('%')
+		default:
+			return "", fmt.Errorf("elasticsearch index pattern %q: unknown %%%c", pattern, pattern[i])
+		}
+	}
+	return b.String(), nil
+}
+
+// ilmAge is the age in the units of ILM, whole seconds at least.
+func ilmAge(d time.Duration) string {
+	return strconv.FormatInt(int64(max(d, time.Second)/time.Second), 10) + "s"
+}
+
+func (l *lifecycle) policyBody() ([]byte, error) {
+	phases := map[string]any{}
+	if l.rollover() {
+		rollover := map[string]any{}
+		if l.rolloverSize > 0 {
+			rollover["max_primary_shard_size"] = strconv.FormatInt(l.rolloverSize, 10) + "b"
+		}
+		if l.rolloverAge > 0 {
+			rollover["max_age"] = ilmAge(l.rolloverAge)
+		}
+		phases["hot"] = map[string]any{"actions": map[string]any{"rollover": rollover}}
+	}
+	if l.retention > 0 {
+		// from the rollover, or from the creation of a time-suffixed index.
+		phases["delete"] = map[string]any{
+			"min_age": ilmAge(l.retention),
+			"actions": map[string]any{"delete": map[string]any{}},
+		}
+	}
+
+	return json.Marshal(map[string]any{
+		"policy": map[string]any{
+			"phases": phases,
+			"_meta":  map[string]any{"managed_by": "huatuo-bamai"},
+		},
+	})
+}
+
+func (l *lifecycle) templateBody() ([]byte, error) {
+	settings := map[string]any{}
+	if l.policy != "" {
+		settings["index.lifecycle.name"] = l.policy
+	}
+	if l.rollover() {
+		settings["index.lifecycle.rollover_alias"] = l.index
+	}
+
+	return json.Marshal(map[string]any{
+		"index_patterns": []string{l.family},
+		"priority":       lifecycleTemplatePrio,
+		"template":       map[string]any{"settings": settings},
+		"_meta":          map[string]any{"managed_by": "huatuo-bamai"},
+	})
+}
+
+// setupLifecycle installs the ILM policy and the index template of the
+// indices, and bootstraps the rollover alias. Every agent does this at
+// startup, the requests are idempotent.
+func (s *Storage) setupLifecycle(ctx context.Context) error {
+	l := s.lifecycle
+	if l.policy != "" {
+		body, err := l.policyBody()
+		if err != nil {
+			return err
+		}
+		req := esapi.ILMPutLifecycleRequest{Policy: l.policy, Body: bytes.NewReader(body)}
+		if err := s.do(ctx, req, "put ilm policy", l.policy); err != nil {
+			return err
+		}
+	}
+
+	if l.family != "" {
+		body, err := l.templateBody()
+		if err != nil {
+			return err
+		}
+		req := esapi.IndicesPutIndexTemplateRequest{Name: l.index, Body: bytes.NewReader(body)}
+		if err := s.do(ctx, req, "put template", l.index); err != nil {
+			return err
+		}
+	}
+
+	if l.rollover() {
+		return s.bootstrapRollover(ctx)
+	}
+	return nil
+}
+
+// bootstrapRollover creates the first index behind the rollover alias,
+// unless the alias exists already.
+func (s *Storage) bootstrapRollover(ctx context.Context) error {
+	alias := s.lifecycle.index
+
+	exists, err := s.exists(ctx, esapi.IndicesExistsAliasRequest{Name: []string{alias}}, "alias", alias)
+	if err != nil || exists {
+		return err
+	}
+	// an index is auto-created by the writes made before the rollover.
+	exists, err = s.exists(ctx, esapi.IndicesExistsRequest{Index: []string{alias}}, "index", alias)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return fmt.Errorf("elasticsearch %s: %w, reindex or rename it", alias, errIndexNotAlias)
+	}
+
+	body, err := json.Marshal(map[string]any{
+		"aliases": map[string]any{alias: map[string]any{"is_write_index": true}},
+	})
+	if err != nil {
+		return err
+	}
+	first := alias + "-000001"
+	res, err := esapi.IndicesCreateRequest{Index: first, Body: bytes.NewReader(body)}.Do(ctx, s.transport)
+	if err != nil {
+		return fmt.Errorf("elasticsearch create index %s: %w", first, err)
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		msg, _ := io.ReadAll(res.Body)
+		// another agent may have created it first.
+		if bytes.Contains(msg, []byte("resource_already_exists_exception")) {
+			return nil
+		}
+		return fmt.Errorf("elasticsearch create index %s: status %d: %s", first, res.StatusCode, bytes.TrimSpace(msg))
+	}
+
+	log.Infof("elasticsearch rollover: created %s behind the alias %s", first, alias)
+	return nil
+}
+
+// ensureLifecycle bootstraps the rollover alias before the first documents
+// are sent, they would create a plain index of its name otherwise.
+func (s *Storage) ensureLifecycle(ctx context.Context) error {
+	if !s.lifecycle.rollover() || s.lifecycleReady.Load() {
+		return nil
+	}
+	if err := s.setupLifecycle(ctx); err != nil {
+		return err
+	}
+	s.lifecycleReady.Store(true)
+	return nil
+}
+
+func (s *Storage) do(ctx context.Context, req esapi.Request, action, target string) error {
+	res, err := req.Do(ctx, s.transport)
+	if err != nil {
+		return fmt.Errorf("elasticsearch %s %s: %w", action, target, err)
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		return responseError(action, target, res)
+	}
+	return nil
+}
+
+func (s *Storage) exists(ctx context.Context, req esapi.Request, kind, name string) (bool, error) {
+	res, err := req.Do(ctx, s.transport)
+	if err != nil {
+		return false, fmt.Errorf("elasticsearch %s %s exists: %w", kind, name, err)
+	}
+	defer res.Body.Close()
+
+	switch res.StatusCode {
+	case http.StatusOK:
+		return true, nil
+	case http.StatusNotFound:
+		return false, nil
+	}
+	return false, responseError(kind+" exists", name, res)
+}
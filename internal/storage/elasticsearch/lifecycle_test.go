@@ -0,0 +1,200 @@
+// Copyright 2026 The HuaTuo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package elasticsearch
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"huatuo-bamai/internal/storage/driver"
+)
+
+// TestFormatIndex covers the time suffixes: UTC fields and escaped percents.
+func TestFormatIndex(t *testing.T) {
+	now := time.Date(2026, 3, 5, 23, 30, 0, 0, time.FixedZone("CST", -2*3600))
+
+	cases := map[string]string{
+		"huatuo_bamai-%Y.%m.%d":    "huatuo_bamai-2026.03.06",
+		"huatuo_bamai-%Y.%m.%d-%H": "huatuo_bamai-2026.03.06-01",
+		"huatuo_bamai-%Y%%":        "huatuo_bamai-2026%",
+	}
+	for pattern, want := range cases {
+		if got, err := formatIndex(pattern, now); got != want || err != nil {
+			t.Errorf("formatIndex(%q) = %q, %v, want %q", pattern, got, err, want)
+		}
+	}
+
+	for _, pattern := range []string{"huatuo_bamai-%j", "huatuo_bamai-%"} {
+		if _, err := formatIndex(pattern, now); err == nil {
+			t.Errorf("formatIndex(%q) returned nil error", pattern)
+		}
+	}
+}
+
+// TestNewLifecycleInvalid covers the validation: rollover and retention need a policy, rollover excludes the pattern.
+func TestNewLifecycleInvalid(t *testing.T) {
+	cases := map[string]Config{
+		"pattern without time": {IndexPattern: "huatuo_bamai-daily"},
+		"unknown field":        {IndexPattern: "huatuo_bamai-%Y.%j"},
+		"retention no policy":  {Retention: time.Hour},
+		"rollover no policy":   {RolloverAge: time.Hour},
+		"rollover and pattern": {IndexPattern: "huatuo_bamai-%Y", ILMPolicy: "huatuo", RolloverAge: time.Hour},
+	}
+	for name, cfg := range cases {
+		if _, err := newLifecycle("huatuo_bamai", &cfg); err == nil {
+			t.Errorf("%s: newLifecycle() returned nil error", name)
+		}
+	}
+}
+
+// TestLifecycleBodies covers the ILM policy and the index template of a rollover.
+func TestLifecycleBodies(t *testing.T) {
+	l, err := newLifecycle("huatuo_bamai", &Config{
+		ILMPolicy:    "huatuo",
+		Retention:    7 * 24 * time.Hour,
+		RolloverSize: 50 << 30,
+		RolloverAge:  24 * time.Hour,
+	})
+	if err != nil {
+		t.Fatalf("newLifecycle() returned error: %v", err)
+	}
+
+	body, err := l.policyBody()
+	if err != nil {
+		t.Fatalf("policyBody() returned error: %v", err)
+	}
+	phases := decodeJSONMap(t, body)["policy"].(map[string]any)["phases"].(map[string]any)
+	rollover := phases["hot"].(map[string]any)["actions"].(map[string]any)["rollover"]
+	want := map[string]any{"max_age": "86400s", "max_primary_shard_size": "53687091200b"}
+	if !reflect.DeepEqual(rollover, want) {
+		t.Errorf("rollover = %v, want %v", rollover, want)
+	}
+	if got := phases["delete"].(map[string]any)["min_age"]; got != "604800s" {
+		t.Errorf("delete min_age = %v, want 604800s", got)
+	}
+
+	body, err = l.templateBody()
+	if err != nil {
+		t.Fatalf("templateBody() returned error: %v", err)
+	}
+	template := decodeJSONMap(t, body)
+	if got := template["index_patterns"]; !reflect.DeepEqual(got, []any{"huatuo_bamai-*"}) {
+		t.Errorf("index_patterns = %v, want [huatuo_bamai-*]", got)
+	}
+	settings := template["template"].(map[string]any)["settings"]
+	wantSettings := map[string]any{"index.lifecycle.name": "huatuo", "index.lifecycle.rollover_alias": "huatuo_bamai"}
+	if !reflect.DeepEqual(settings, wantSettings) {
+		t.Errorf("settings = %v, want %v", settings, wantSettings)
+	}
+}
+
+// TestElasticsearchBackendIndexPattern covers the time-suffixed indices: the setup at startup, the writes and the reads by id.
+func TestElasticsearchBackendIndexPattern(t *testing.T) {
+	server := newMockElasticsearchServer()
+	defer server.Close()
+
+	cfg := &Config{
+		Addresses:    []string{server.URL()},
+		Index:        "huatuo_bamai",
+		IndexPattern: "huatuo_bamai-%Y.%m.%d",
+		ILMPolicy:    "huatuo",
+		Retention:    7 * 24 * time.Hour,
+	}
+	backend, err := NewBackend(cfg)
+	if err != nil {
+		t.Fatalf("NewBackend() returned error: %v", err)
+	}
+
+	server.mu.Lock()
+	_, policy := server.lifecycleBodies["_ilm/policy/huatuo"]
+	_, template := server.lifecycleBodies["_index_template/huatuo_bamai"]
+	server.mu.Unlock()
+	if !policy || !template {
+		t.Errorf("ilm policy installed = %v, index template = %v, want both", policy, template)
+	}
+
+	if err := backend.Save(t.Context(), driver.Record{ID: "a", Data: []byte(`{"id":"a"}`)}); err != nil {
+		t.Fatalf("Save() returned error: %v", err)
+	}
+	flushBackend(t, backend)
+
+	index := "huatuo_bamai-" + time.Now().UTC().Format("2006.01.02")
+	if got := server.indexed(index); got != 1 {
+		t.Fatalf("documents in %s = %d, want 1", index, got)
+	}
+
+	backend, err = NewBackend(cfg)
+	if err != nil {
+		t.Fatalf("NewBackend() returned error: %v", err)
+	}
+	defer flushBackend(t, backend)
+
+	rec, err := backend.Get(t.Context(), "a")
+	if err != nil || string(rec.Data) != `{"id":"a"}` {
+		t.Errorf("Get() = %s, %v, want the document", rec.Data, err)
+	}
+}
+
+// TestElasticsearchBackendRollover covers the rollover: the alias bootstrap, the writes through it, and a plain index in its place.
+func TestElasticsearchBackendRollover(t *testing.T) {
+	server := newMockElasticsearchServer()
+	defer server.Close()
+
+	cfg := &Config{
+		Addresses:   []string{server.URL()},
+		Index:       "huatuo_bamai",
+		ILMPolicy:   "huatuo",
+		RolloverAge: 24 * time.Hour,
+	}
+	backend, err := NewBackend(cfg)
+	if err != nil {
+		t.Fatalf("NewBackend() returned error: %v", err)
+	}
+
+	server.mu.Lock()
+	write := server.aliases["huatuo_bamai"]
+	server.mu.Unlock()
+	if write != "huatuo_bamai-000001" {
+		t.Fatalf("write index of the alias = %q, want huatuo_bamai-000001", write)
+	}
+
+	if err := backend.Save(t.Context(), driver.Record{ID: "a", Data: []byte(`{"id":"a"}`)}); err != nil {
+		t.Fatalf("Save() returned error: %v", err)
+	}
+	flushBackend(t, backend)
+	if got := server.indexed(write); got != 1 {
+		t.Fatalf("documents in %s = %d, want 1", write, got)
+	}
+
+	backend, err = NewBackend(cfg)
+	if err != nil {
+		t.Fatalf("NewBackend() again returned error: %v", err)
+	}
+	defer flushBackend(t, backend)
+	if _, err := backend.Get(t.Context(), "a"); err != nil {
+		t.Errorf("Get() returned error: %v", err)
+	}
+
+	other := newMockElasticsearchServer()
+	defer other.Close()
+	other.indexes["huatuo_bamai"] = map[string]mockElasticsearchDocument{}
+
+	cfg.Addresses = []string{other.URL()}
+	if _, err := NewBackend(cfg); !errors.Is(err, errIndexNotAlias) {
+		t.Errorf("NewBackend() over a plain index error = %v, want errIndexNotAlias", err)
+	}
+}
@@ -0,0 +1,238 @@
+// Copyright 2026 The HuaTuo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package elasticsearch
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/elastic/go-elasticsearch/v8/esapi"
+
+	"huatuo-bamai/internal/log"
+	"huatuo-bamai/internal/storage/driver"
+)
+
+const (
+	// routeField is the record field which routes match tracers against.
+	routeField = "tracer_name"
+	// routed records go to daily indices, so that retention drops whole
+	// indices instead of running delete-by-query on every host.
+	routeDateLayout      = "2006.01.02"
+	routeCleanupInterval = time.Hour
+	routeTemplatePrio    = 200
+)
+
+var routeNameRegexp = regexp.MustCompile(`^[a-z0-9][a-z0-9_]*$`)
+
+type routes struct {
+	prefix   string
+	list     []driver.ESRoute
+	byTracer map[string]int
+	// lifecycle names the indices of the records not routed, nil is the
+	// prefix alone.
+	lifecycle *lifecycle
+}
+
+func newRoutes(prefix string, list []driver.ESRoute) (*routes, error) {
+	r := &routes{prefix: prefix, list: list, byTracer: map[string]int{}}
+	for i, route := range list {
+		if !routeNameRegexp.MatchString(route.Name) {
+			return nil, fmt.Errorf("elasticsearch route %q: name must match %s", route.Name, routeNameRegexp)
+		}
+		for _, tracer := range route.Tracers {
+			if _, ok := r.byTracer[tracer]; ok {
+				return nil, fmt.Errorf("elasticsearch route %q: tracer %q already routed", route.Name, tracer)
+			}
+			r.byTracer[tracer] = i
+		}
+	}
+	return r, nil
+}
+
+func (r *routes) base(route *driver.ESRoute) string {
+	return r.prefix + "_" + route.Name + "-"
+}
+
+// index returns the index for a record, the default index when its tracer
+// is not routed.
+func (r *routes) index(rec driver.Record, now time.Time) string {
+	tracer, _ := rec.Fields[routeField].(string)
+	i, ok := r.byTracer[tracer]
+	if !ok {
+		if r.lifecycle != nil {
+			return r.lifecycle.writeIndex(now)
+		}
+		return r.prefix
+	}
+	return r.base(&r.list[i]) + now.UTC().Format(routeDateLayout)
+}
+
+// readIndices covers the default index, the indices of its lifecycle and
+// every routed index.
+func (r *routes) readIndices() []string {
+	indices := []string{r.prefix}
+	if r.lifecycle != nil && r.lifecycle.family != "" {
+		indices = append(indices, r.lifecycle.family)
+	}
+	for i := range r.list {
+		indices = append(indices, r.base(&r.list[i])+"*")
+	}
+	return indices
+}
+
+// expired returns the indices of route whose day ended before the retention.
+func (r *routes) expired(route *driver.ESRoute, indices []string, now time.Time) []string {
+	if route.Retention <= 0 {
+		return nil
+	}
+
+	base := r.base(route)
+	cutoff := now.UTC().Add(-route.Retention)
+
+	var expired []string
+	for _, index := range indices {
+		day, ok := strings.CutPrefix(index, base)
+		if !ok {
+			continue
+		}
+		t, err := time.Parse(routeDateLayout, day)
+		if err != nil {
+			continue
+		}
+		if t.AddDate(0, 0, 1).Before(cutoff) {
+			expired = append(expired, index)
+		}
+	}
+	return expired
+}
+
+func (r *routes) template(route *driver.ESRoute) ([]byte, error) {
+	return json.Marshal(map[string]any{
+		"index_patterns": []string{r.base(route) + "*"},
+		"priority":       routeTemplatePrio,
+		"template": map[string]any{
+			// daily indices of a single tracer are small.
+			"settings": map[string]any{"number_of_shards": 1},
+		},
+		"_meta": map[string]any{
+			"managed_by":        "huatuo-bamai",
+			"retention_seconds": int64(route.Retention.Seconds()),
+		},
+	})
+}
+
+// putTemplates installs an index template per route. Every agent does this
+// at startup, the request is idempotent.
+func (s *Storage) putTemplates(ctx context.Context) error {
+	for i := range s.routes.list {
+		route := &s.routes.list[i]
+		body, err := s.routes.template(route)
+		if err != nil {
+			return err
+		}
+
+		name := s.routes.prefix + "_" + route.Name
+		req := esapi.IndicesPutIndexTemplateRequest{Name: name, Body: bytes.NewReader(body)}
+		res, err := req.Do(ctx, s.transport)
+		if err != nil {
+			return fmt.Errorf("elasticsearch put template %s: %w", name, err)
+		}
+		if res.IsError() {
+			err = responseError("put template", name, res)
+		}
+		res.Body.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *Storage) cleanupLoop(ctx context.Context) {
+	defer close(s.cleanupDone)
+
+	ticker := time.NewTicker(routeCleanupInterval)
+	defer ticker.Stop()
+
+	for {
+		for i := range s.routes.list {
+			if err := s.cleanupRoute(ctx, &s.routes.list[i]); err != nil {
+				log.Warnf("elasticsearch route %s retention: %v", s.routes.list[i].Name, err)
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *Storage) cleanupRoute(ctx context.Context, route *driver.ESRoute) error {
+	if route.Retention <= 0 {
+		return nil
+	}
+
+	pattern := s.routes.base(route) + "*"
+	req := esapi.CatIndicesRequest{Index: []string{pattern}, Format: "json", H: []string{"index"}}
+	res, err := req.Do(ctx, s.transport)
+	if err != nil {
+		return fmt.Errorf("list %s: %w", pattern, err)
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		return responseError("list indices", pattern, res)
+	}
+
+	var rows []struct {
+		Index string `json:"index"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&rows); err != nil {
+		return fmt.Errorf("list %s: decode: %w", pattern, err)
+	}
+
+	indices := make([]string, 0, len(rows))
+	for _, row := range rows {
+		indices = append(indices, row.Index)
+	}
+
+	expired := s.routes.expired(route, indices, time.Now())
+	if len(expired) == 0 {
+		return nil
+	}
+
+	delReq := esapi.IndicesDeleteRequest{Index: expired}
+	delRes, err := delReq.Do(ctx, s.transport)
+	if err != nil {
+		return fmt.Errorf("delete %v: %w", expired, err)
+	}
+	defer delRes.Body.Close()
+
+	// another agent may have deleted them first.
+	if delRes.IsError() && delRes.StatusCode != http.StatusNotFound {
+		return responseError("delete indices", strings.Join(expired, ","), delRes)
+	}
+
+	log.Infof("elasticsearch route %s: deleted expired indices %v", route.Name, expired)
+	return nil
+}
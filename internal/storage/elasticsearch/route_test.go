@@ -0,0 +1,132 @@
+// Copyright 2026 The HuaTuo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package elasticsearch
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"huatuo-bamai/internal/storage/driver"
+)
+
+func testRoutes(t *testing.T) *routes {
+	t.Helper()
+
+	r, err := newRoutes("huatuo_bamai", []driver.ESRoute{
+		{Name: "profiler", Tracers: []string{"profiler"}, Retention: 3 * 24 * time.Hour},
+		{Name: "oom", Tracers: []string{"oom", "oom_group"}},
+	})
+	if err != nil {
+		t.Fatalf("newRoutes() returned error: %v", err)
+	}
+	return r
+}
+
+// TestRoutesIndex covers write routing: routed tracers go to their daily index, the rest to the default one.
+func TestRoutesIndex(t *testing.T) {
+	r := testRoutes(t)
+	now := time.Date(2026, 10, 16, 23, 30, 0, 0, time.FixedZone("CST", 8*3600))
+
+	cases := map[string]string{
+		"profiler":  "huatuo_bamai_profiler-2026.10.16",
+		"oom_group": "huatuo_bamai_oom-2026.10.16",
+		"softirq":   "huatuo_bamai",
+		"":          "huatuo_bamai",
+	}
+	for tracer, want := range cases {
+		rec := driver.Record{Fields: map[string]any{routeField: tracer}}
+		if got := r.index(rec, now); got != want {
+			t.Errorf("index(%q) = %q, want %q", tracer, got, want)
+		}
+	}
+
+	want := []string{"huatuo_bamai", "huatuo_bamai_profiler-*", "huatuo_bamai_oom-*"}
+	if got := r.readIndices(); !reflect.DeepEqual(got, want) {
+		t.Errorf("readIndices() = %v, want %v", got, want)
+	}
+}
+
+// TestRoutesExpired covers retention: only whole days older than the retention of the route are expired.
+func TestRoutesExpired(t *testing.T) {
+	r := testRoutes(t)
+	now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+
+	indices := []string{
+		"huatuo_bamai_profiler-2026.10.12",
+		"huatuo_bamai_profiler-2026.10.13",
+		"huatuo_bamai_profiler-2026.10.16",
+		"huatuo_bamai_profiler-latest",
+		"huatuo_bamai_oom-2020.01.01",
+	}
+
+	want := []string{"huatuo_bamai_profiler-2026.10.12"}
+	if got := r.expired(&r.list[0], indices, now); !reflect.DeepEqual(got, want) {
+		t.Errorf("expired(profiler) = %v, want %v", got, want)
+	}
+	if got := r.expired(&r.list[1], indices, now); got != nil {
+		t.Errorf("expired(oom) = %v, want nil for zero retention", got)
+	}
+}
+
+// TestNewRoutesInvalid covers route validation: bad index names and tracers routed twice are rejected.
+func TestNewRoutesInvalid(t *testing.T) {
+	cases := map[string][]driver.ESRoute{
+		"uppercase name": {{Name: "OOM", Tracers: []string{"oom"}}},
+		"empty name":     {{Name: "", Tracers: []string{"oom"}}},
+		"duplicate tracer": {
+			{Name: "a", Tracers: []string{"oom"}},
+			{Name: "b", Tracers: []string{"oom"}},
+		},
+	}
+	for name, list := range cases {
+		if _, err := newRoutes("huatuo_bamai", list); err == nil {
+			t.Errorf("%s: newRoutes() returned nil error", name)
+		}
+	}
+}
+
+// TestElasticsearchBackendRoutes covers the write path: a routed record is bulk indexed into its daily index.
+func TestElasticsearchBackendRoutes(t *testing.T) {
+	server := newMockElasticsearchServer()
+	defer server.Close()
+
+	backend, err := NewBackend(&Config{
+		Addresses: []string{server.URL()},
+		Index:     "huatuo_bamai",
+		Routes:    []driver.ESRoute{{Name: "oom", Tracers: []string{"oom"}}},
+	})
+	if err != nil {
+		t.Fatalf("NewBackend() returned error: %v", err)
+	}
+
+	rec := driver.Record{
+		ID:     "oom-1",
+		Data:   []byte(`{"tracer_name":"oom"}`),
+		Fields: map[string]any{routeField: "oom"},
+	}
+	if err := backend.Save(t.Context(), rec); err != nil {
+		t.Fatalf("Save() returned error: %v", err)
+	}
+	flushBackend(t, backend)
+
+	index := "huatuo_bamai_oom-" + time.Now().UTC().Format(routeDateLayout)
+	server.mu.Lock()
+	_, ok := server.indexes[index]["oom-1"]
+	server.mu.Unlock()
+	if !ok {
+		t.Errorf("record not found in %s", index)
+	}
+}
@@ -0,0 +1,67 @@
+// Copyright 2026 The HuaTuo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package elasticsearch
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"huatuo-bamai/internal/storage/driver"
+)
+
+const (
+	spoolPrefix = "spool-"
+	spoolSuffix = ".ndjson"
+)
+
+// spoolRecord is a line of a spool segment.
+type spoolRecord struct {
+	Index  string          `json:"index"`
+	ID     string          `json:"id,omitempty"`
+	Queued time.Time       `json:"queued"`
+	Body   json.RawMessage `json:"body"`
+}
+
+// spoolCodec encodes the bulk items as the lines of the spool.
+type spoolCodec struct{}
+
+func (spoolCodec) Encode(item bulkItem) ([]byte, error) {
+	return json.Marshal(spoolRecord{Index: item.index, ID: item.id, Queued: item.queued, Body: item.body})
+}
+
+func (spoolCodec) Decode(line []byte) (bulkItem, error) {
+	var rec spoolRecord
+	if err := json.Unmarshal(line, &rec); err != nil {
+		return bulkItem{}, err
+	}
+	return bulkItem{index: rec.Index, id: rec.ID, queued: rec.Queued, body: rec.Body}, nil
+}
+
+// newSpool opens the spool keeping the items the cluster did not take on
+// disk, read back by the flusher once the cluster takes the queue again.
+func newSpool(dir string, maxSize int64) (*driver.Spool[bulkItem], error) {
+	s, err := driver.NewSpool(&driver.SpoolConfig[bulkItem]{
+		Dir:     dir,
+		Prefix:  spoolPrefix,
+		Suffix:  spoolSuffix,
+		MaxSize: maxSize,
+		Codec:   spoolCodec{},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("elasticsearch %w", err)
+	}
+	return s, nil
+}
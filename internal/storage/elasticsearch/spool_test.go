@@ -0,0 +1,109 @@
+// Copyright 2026 The HuaTuo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package elasticsearch
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func spoolItems(ids ...string) []bulkItem {
+	items := make([]bulkItem, 0, len(ids))
+	for _, id := range ids {
+		items = append(items, bulkItem{
+			index:  "huatuo_bamai",
+			id:     id,
+			body:   []byte(`{"id":"` + id + `"}`),
+			queued: time.Unix(1700000000, 0).UTC(),
+		})
+	}
+	return items
+}
+
+func TestSpoolRestart(t *testing.T) {
+	dir := t.TempDir()
+
+	s, err := newSpool(dir, 0)
+	if err != nil {
+		t.Fatalf("newSpool() error = %v", err)
+	}
+	if n, err := s.Write(spoolItems("a", "b")); n != 2 || err != nil {
+		t.Fatalf("Write() = %d, %v, want 2", n, err)
+	}
+	if err := s.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	// a line torn by a crash is skipped.
+	segments, _ := filepath.Glob(filepath.Join(dir, spoolPrefix+"*"))
+	f, err := os.OpenFile(segments[0], os.O_APPEND|os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _ = f.WriteString(`{"index":"huatuo_bamai","id":"c"`)
+	f.Close()
+
+	s, err = newSpool(dir, 0)
+	if err != nil {
+		t.Fatalf("newSpool() error = %v", err)
+	}
+	items, done, err := s.Next()
+	if err != nil || done == nil {
+		t.Fatalf("Next() error = %v, want a segment", err)
+	}
+	if len(items) != 2 || items[0].id != "a" || items[1].id != "b" || string(items[1].body) != `{"id":"b"}` {
+		t.Errorf("Next() items = %+v, want a and b", items)
+	}
+	if !items[0].queued.Equal(time.Unix(1700000000, 0)) {
+		t.Errorf("queued = %v, want the time of the first queueing", items[0].queued)
+	}
+	done()
+
+	if _, done, _ := s.Next(); done != nil {
+		t.Error("Next() on an empty spool returned a segment")
+	}
+	if n := s.Records(); n != 0 {
+		t.Errorf("Records() = %d, want 0", n)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+		t.Errorf("segments left = %d, want 0", len(entries))
+	}
+}
+
+func TestSpoolMaxSize(t *testing.T) {
+	item := spoolItems("a")[0]
+	line, err := json.Marshal(spoolRecord{Index: item.index, ID: item.id, Queued: item.queued, Body: item.body})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// room for two lines, the third is refused.
+	s, err := newSpool(t.TempDir(), int64(2*(len(line)+1)))
+	if err != nil {
+		t.Fatalf("newSpool() error = %v", err)
+	}
+	defer s.Close()
+
+	n, err := s.Write(spoolItems("a", "b", "c"))
+	if n != 2 || err != nil {
+		t.Errorf("Write() = %d, %v, want 2", n, err)
+	}
+	if got := s.Records(); got != 2 {
+		t.Errorf("Records() = %d, want 2", got)
+	}
+}
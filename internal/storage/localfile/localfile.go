@@ -13,13 +13,15 @@
 // limitations under the License.
 
 // Package localfile implements a storage backend that appends records to local
-// files with rotation support.
+// files with rotation, compression and retention support.
 package localfile
 
 import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"io"
 	"os"
 	"path"
@@ -32,12 +34,11 @@ import (
 
 // Storage appends records to local files. It is bound to one collection by Init.
 type Storage struct {
-	lock         sync.Mutex
-	files        map[string]io.Writer
-	writerCache  sync.Map
-	path         string
-	rotationSize int
-	maxRotation  int
+	lock        sync.Mutex
+	files       map[string]io.Writer
+	writerCache sync.Map
+	path        string
+	opts        filerotate.Options
 }
 
 var _ driver.Backend = (*Storage)(nil)
@@ -46,18 +47,28 @@ var _ driver.Backend = (*Storage)(nil)
 // side-effect import.
 func init() {
 	driver.RegisterBackend("localfile", func(cfg *driver.Config) (driver.Backend, error) {
-		return NewBackend(cfg.LocalFilePath, cfg.LocalFileRotationSize, cfg.LocalFileMaxRotation), nil
+		return NewBackend(cfg.LocalFilePath, filerotate.Options{
+			RotationSize: cfg.LocalFileRotationSize,
+			MaxRotation:  cfg.LocalFileMaxRotation,
+			MaxAgeDays:   cfg.LocalFileMaxAgeDays,
+			Compression:  cfg.LocalFileCompression,
+			SyncInterval: cfg.LocalFileSyncInterval,
+		})
 	})
 }
 
-// NewBackend creates a local file backend.
-func NewBackend(path string, rotationSize, maxRotation int) *Storage {
-	return &Storage{
-		path:         path,
-		rotationSize: rotationSize,
-		maxRotation:  maxRotation,
-		files:        make(map[string]io.Writer),
+// NewBackend creates a local file backend, the files of which are rotated,
+// compressed and expired as opts say.
+func NewBackend(path string, opts filerotate.Options) (*Storage, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, fmt.Errorf("localfile: %w", err)
 	}
+
+	return &Storage{
+		path:  path,
+		opts:  opts,
+		files: make(map[string]io.Writer),
+	}, nil
 }
 
 func (b *Storage) Init(_ context.Context, _ string, _ []driver.Index) error {
@@ -103,10 +114,18 @@ func (b *Storage) Values(context.Context, string, driver.Query, int) ([]string,
 	return nil, driver.ErrUnsupported
 }
 
-// Close is a no-op: the file rotator flushes on each Write, so there is
-// nothing buffered to drain at shutdown.
+// Close syncs and closes the files. The file rotator writes through on each
+// Write, there is nothing buffered to drain, but the pending fsync and the
+// compression of the rotated files complete first.
 func (b *Storage) Close(_ context.Context) error {
-	return nil
+	var errs []error
+	b.writerCache.Range(func(_, fileWriter any) bool {
+		if err := fileWriter.(io.Closer).Close(); err != nil {
+			errs = append(errs, err)
+		}
+		return true
+	})
+	return errors.Join(errs...)
 }
 
 func (b *Storage) newFileWriter(filename string) io.Writer {
@@ -114,7 +133,8 @@ func (b *Storage) newFileWriter(filename string) io.Writer {
 
 	fileWriter, ok := b.writerCache.Load(fp)
 	if !ok {
-		fileWriter = filerotate.NewFileRotator(fp, b.maxRotation, b.rotationSize)
+		// the options are validated by NewBackend.
+		fileWriter, _ = filerotate.NewFileRotatorWithOptions(fp, b.opts)
 		b.writerCache.Store(fp, fileWriter)
 	}
 
@@ -20,15 +20,28 @@ import (
 	"os"
 	"path/filepath"
 	"runtime"
+	"strings"
 	"testing"
+	"time"
 
+	"huatuo-bamai/internal/filerotate"
 	"huatuo-bamai/internal/storage/driver"
 )
 
+func newTestBackend(t *testing.T, dir string) *Storage {
+	t.Helper()
+
+	backend, err := NewBackend(dir, filerotate.Options{RotationSize: 1024, MaxRotation: 3})
+	if err != nil {
+		t.Fatalf("NewBackend() error = %v", err)
+	}
+	return backend
+}
+
 // TestBackendSave covers the localfile backend save behavior: verifies that fields.tracer_name is used as the filename and JSON content is pretty-printed before writing.
 func TestBackendSave(t *testing.T) {
 	dir := t.TempDir()
-	backend := NewBackend(dir, 1024, 3)
+	backend := newTestBackend(t, dir)
 
 	err := backend.Save(t.Context(), driver.Record{
 		ID:   "trace-20260424",
@@ -78,7 +91,7 @@ func TestBackendSaveMkdirAllError(t *testing.T) {
 
 	// Use a subdirectory under the read-only dir that doesn't exist.
 	// MkdirAll will fail because the parent is read-only.
-	backend := NewBackend(filepath.Join(readOnlyDir, "nested", "data"), 1024, 3)
+	backend := newTestBackend(t, filepath.Join(readOnlyDir, "nested", "data"))
 
 	err := backend.Save(t.Context(), driver.Record{
 		ID:   "trace-permtest",
@@ -96,7 +109,7 @@ func TestBackendSaveMkdirAllError(t *testing.T) {
 // fails, Save falls back to writing raw data and logs a warning.
 func TestBackendSaveInvalidJSONFallback(t *testing.T) {
 	dir := t.TempDir()
-	backend := NewBackend(dir, 1024, 3)
+	backend := newTestBackend(t, dir)
 
 	const tracerName = "badjson_test"
 	want := []byte("not valid json {")
@@ -125,7 +138,7 @@ func TestBackendSaveInvalidJSONFallback(t *testing.T) {
 // TestBackendUnsupportedOperations covers operations not supported by the localfile backend: Get, Delete, Query, Count, and Terms all return ErrUnsupported.
 func TestBackendUnsupportedOperations(t *testing.T) {
 	dir := t.TempDir()
-	backend := NewBackend(dir, 1024, 3)
+	backend := newTestBackend(t, dir)
 
 	if _, err := backend.Get(t.Context(), "trace-20260424"); !errors.Is(err, driver.ErrUnsupported) {
 		t.Errorf("Backend.Get() error = %v, want ErrUnsupported", err)
@@ -143,3 +156,57 @@ func TestBackendUnsupportedOperations(t *testing.T) {
 		t.Errorf("Backend.Terms() error = %v, want ErrUnsupported", err)
 	}
 }
+
+// TestBackendCompressionRetention verifies the rotated files are compressed
+// and kept up to MaxRotation, and Close waits for the compression.
+func TestBackendCompressionRetention(t *testing.T) {
+	dir := t.TempDir()
+	backend, err := NewBackend(dir, filerotate.Options{
+		RotationSize: 1,
+		MaxRotation:  2,
+		MaxAgeDays:   7,
+		Compression:  filerotate.CompressionZstd,
+		SyncInterval: time.Second,
+	})
+	if err != nil {
+		t.Fatalf("NewBackend() error = %v", err)
+	}
+
+	data := []byte(`{"tracer_name":"oom","data":"` + strings.Repeat("a", 256*1024) + `"}`)
+	for i := range 12 {
+		if err := backend.Save(t.Context(), driver.Record{
+			Data:   data,
+			Fields: map[string]any{"tracer_name": "oom"},
+		}); err != nil {
+			t.Fatalf("Save() %d error = %v", i, err)
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if err := backend.Close(t.Context()); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var current bool
+	var compressed []string
+	for _, entry := range entries {
+		switch name := entry.Name(); {
+		case name == "oom":
+			current = true
+		case strings.HasPrefix(name, "oom-") && strings.HasSuffix(name, ".zst"):
+			compressed = append(compressed, name)
+		default:
+			t.Errorf("unexpected file %s", name)
+		}
+	}
+	if !current || len(compressed) != 2 {
+		t.Errorf("files = %v, want oom and 2 compressed rotations", entries)
+	}
+
+	if _, err := NewBackend(dir, filerotate.Options{Compression: "lz4"}); err == nil {
+		t.Error("NewBackend() with lz4 succeeded")
+	}
+}
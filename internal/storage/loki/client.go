@@ -0,0 +1,118 @@
+// Copyright 2026 The HuaTuo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package loki
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"huatuo-bamai/internal/storage/driver"
+)
+
+const pushPath = "/loki/api/v1/push"
+
+// errRetryable marks the push errors worth a retry: the network, the rate
+// limit and the server errors. The other client errors, e.g. an entry too
+// old, fail again.
+var errRetryable = errors.New("retryable")
+
+// newTransport keeps a few idle connections to the server, the pushes of
+// one agent are not concurrent.
+func newTransport(tlsConfig *tls.Config) http.RoundTripper {
+	return &http.Transport{
+		MaxIdleConns:          4,
+		MaxIdleConnsPerHost:   2,
+		IdleConnTimeout:       50 * time.Second,
+		ResponseHeaderTimeout: 30 * time.Second,
+		DialContext: (&net.Dialer{
+			Timeout:   10 * time.Second,
+			KeepAlive: 30 * time.Second,
+		}).DialContext,
+		TLSClientConfig: tlsConfig,
+	}
+}
+
+// client talks to the Loki push API.
+type client struct {
+	http     *http.Client
+	url      string
+	username string
+	password string
+	tenantID string
+}
+
+func newClient(cfg *Config) (*client, error) {
+	if cfg.Address == "" {
+		return nil, fmt.Errorf("loki backend: address is empty")
+	}
+	if _, err := url.Parse(cfg.Address); err != nil {
+		return nil, fmt.Errorf("loki backend: address %q: %w", cfg.Address, err)
+	}
+
+	tlsConfig, err := driver.TLSConfig(cfg.CAFile, cfg.InsecureSkipVerify)
+	if err != nil {
+		return nil, fmt.Errorf("loki backend: %w", err)
+	}
+
+	return &client{
+		http:     &http.Client{Transport: newTransport(tlsConfig)},
+		url:      strings.TrimSuffix(cfg.Address, "/") + pushPath,
+		username: cfg.Username,
+		password: cfg.Password,
+		tenantID: cfg.TenantID,
+	}, nil
+}
+
+// push sends the JSON body of a push request.
+func (c *client) push(ctx context.Context, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if c.username != "" {
+		req.SetBasicAuth(c.username, c.password)
+	}
+	if c.tenantID != "" {
+		req.Header.Set("X-Scope-OrgID", c.tenantID)
+	}
+
+	res, err := c.http.Do(req)
+	if err != nil {
+		return fmt.Errorf("%w: %w", errRetryable, err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode/100 == 2 {
+		_, err = io.Copy(io.Discard, res.Body)
+		return err
+	}
+
+	msg, _ := io.ReadAll(io.LimitReader(res.Body, 4096))
+	err = fmt.Errorf("status %d: %s", res.StatusCode, strings.TrimSpace(string(msg)))
+	if res.StatusCode == http.StatusTooManyRequests || res.StatusCode >= 500 {
+		return fmt.Errorf("%w: %w", errRetryable, err)
+	}
+	return err
+}
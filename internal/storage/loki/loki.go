@@ -0,0 +1,259 @@
+// Copyright 2026 The HuaTuo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package loki implements a write only storage backend that pushes records
+// as log lines to Loki, so the events are queried next to the pod logs.
+package loki
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"time"
+
+	"huatuo-bamai/internal/storage/driver"
+)
+
+const (
+	defaultBatchSize     = 1000
+	defaultFlushInterval = time.Second
+
+	// pushRetries is the attempts of a batch, the entries are dropped after
+	// the last one. The waits between them grow from minBackoff.
+	pushRetries = 5
+	minBackoff  = 500 * time.Millisecond
+	maxBackoff  = 30 * time.Second
+
+	metricsBackend = "loki"
+)
+
+// streamLabels are the fields the streams are labelled with, kept to the
+// few of a low cardinality. The other fields are in the line.
+var streamLabels = []string{"hostname", "region", "tracer_name"}
+
+// Config contains Loki backend settings.
+type Config struct {
+	Address  string
+	Username string
+	Password string
+	// TenantID is sent as X-Scope-OrgID to a multi tenant Loki.
+	TenantID string
+	// CAFile verifies an https server instead of the system roots,
+	// InsecureSkipVerify does not verify it.
+	CAFile             string
+	InsecureSkipVerify bool
+	// BatchSize is the entries pushed at once, a batch is flushed earlier
+	// every FlushInterval.
+	BatchSize     int
+	FlushInterval time.Duration
+}
+
+// Storage pushes records to Loki, one stream per hostname, region and
+// tracer_name, with the record payload as the line at the tracer_time.
+//
+// Save is asynchronous: the entries are queued and pushed in batches by
+// size, time, or Close. Loki is not read back, Get, Delete, Query, Count
+// and Values return driver.ErrUnsupported.
+type Storage struct {
+	client  *client
+	batcher *driver.Batcher[entry]
+
+	// FailureHandler passes the entries failing after the retries to the
+	// dead letter of the store.
+	driver.FailureHandler
+}
+
+type entry struct {
+	id     string
+	queued time.Time
+	labels map[string]string
+	time   time.Time
+	line   string
+}
+
+func (e entry) Queued() time.Time     { return e.queued }
+func (e entry) Record() driver.Record { return driver.Record{ID: e.id, Data: []byte(e.line)} }
+
+// pushRequest is the JSON body of the push API.
+type pushRequest struct {
+	Streams []pushStream `json:"streams"`
+}
+
+type pushStream struct {
+	Stream map[string]string `json:"stream"`
+	// Values are the [unix nanoseconds, line] pairs of the stream.
+	Values [][2]string `json:"values"`
+}
+
+var (
+	_ driver.Backend         = (*Storage)(nil)
+	_ driver.Backlogger      = (*Storage)(nil)
+	_ driver.FailureNotifier = (*Storage)(nil)
+)
+
+func init() {
+	driver.RegisterBackend("loki", func(cfg *driver.Config) (driver.Backend, error) {
+		return NewBackend(&Config{
+			Address:            cfg.LokiAddress,
+			Username:           cfg.LokiUsername,
+			Password:           cfg.LokiPassword,
+			TenantID:           cfg.LokiTenantID,
+			CAFile:             cfg.LokiCAFile,
+			InsecureSkipVerify: cfg.LokiInsecureSkipVerify,
+			BatchSize:          cfg.LokiBatchSize,
+			FlushInterval:      cfg.LokiFlushInterval,
+		})
+	})
+}
+
+// NewBackend creates a Loki backend and starts its flusher.
+func NewBackend(cfg *Config) (*Storage, error) {
+	client, err := newClient(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Storage{client: client}
+	batcher := &driver.BatcherConfig[entry]{
+		Backend:       metricsBackend,
+		BatchSize:     cfg.BatchSize,
+		FlushInterval: cfg.FlushInterval,
+		Retries:       pushRetries,
+		MinBackoff:    minBackoff,
+		MaxBackoff:    maxBackoff,
+		Send:          s.push,
+		Failure:       &s.FailureHandler,
+	}
+	if batcher.BatchSize <= 0 {
+		batcher.BatchSize = defaultBatchSize
+	}
+	if batcher.FlushInterval <= 0 {
+		batcher.FlushInterval = defaultFlushInterval
+	}
+	s.batcher = driver.NewBatcher(batcher)
+
+	return s, nil
+}
+
+// Init is a no-op, the streams are created by the first push.
+func (s *Storage) Init(context.Context, string, []driver.Index) error {
+	return nil
+}
+
+func (s *Storage) Save(_ context.Context, rec driver.Record) error {
+	return s.batcher.Save(newEntry(rec, time.Now()))
+}
+
+func newEntry(rec driver.Record, now time.Time) entry {
+	labels := make(map[string]string, len(streamLabels))
+	for _, name := range streamLabels {
+		if value := driver.StringValue(rec.Fields[name]); value != "" {
+			labels[name] = value
+		}
+	}
+	// a stream needs one label at least.
+	if len(labels) == 0 {
+		labels["tracer_name"] = "unknown"
+	}
+
+	t := now
+	if tracerTime, ok := rec.Fields["tracer_time"].(time.Time); ok && !tracerTime.IsZero() {
+		t = tracerTime
+	}
+
+	return entry{id: rec.ID, queued: now, labels: labels, time: t, line: string(rec.Data)}
+}
+
+// encodePush groups the entries by stream. The entries are sorted by time,
+// older Loki rejects those out of order in a stream.
+func encodePush(entries []entry) ([]byte, error) {
+	sorted := slices.Clone(entries)
+	slices.SortStableFunc(sorted, func(a, b entry) int { return a.time.Compare(b.time) })
+
+	req := pushRequest{}
+	streams := make(map[string]int)
+	for _, e := range sorted {
+		key := streamKey(e.labels)
+		i, ok := streams[key]
+		if !ok {
+			i = len(req.Streams)
+			streams[key] = i
+			req.Streams = append(req.Streams, pushStream{Stream: e.labels})
+		}
+		req.Streams[i].Values = append(req.Streams[i].Values, [2]string{strconv.FormatInt(e.time.UnixNano(), 10), e.line})
+	}
+
+	data, err := json.Marshal(&req)
+	if err != nil {
+		return nil, fmt.Errorf("loki backend marshal push: %w", err)
+	}
+	return data, nil
+}
+
+func streamKey(labels map[string]string) string {
+	key := ""
+	for _, name := range streamLabels {
+		key += labels[name] + "\xff"
+	}
+	return key
+}
+
+// push sends one batch, the entries refused for good with a 4xx would be
+// refused again.
+func (s *Storage) push(ctx context.Context, batch []entry) driver.BatchResult[entry] {
+	body, err := encodePush(batch)
+	if err == nil {
+		err = s.client.push(ctx, body)
+	}
+	return driver.BatchSent(batch, err, errors.Is(err, errRetryable))
+}
+
+// Backlog returns the entries queued and not yet pushed, it grows when Loki
+// is slower than the node writes or unavailable.
+func (s *Storage) Backlog() int64 {
+	return s.batcher.Backlog()
+}
+
+// Close stops the flusher and pushes the pending entries. The Storage must
+// not be reused.
+func (s *Storage) Close(ctx context.Context) error {
+	if s.batcher == nil {
+		return nil
+	}
+	s.batcher.Close(ctx)
+	return nil
+}
+
+func (s *Storage) Get(context.Context, string) (driver.Record, error) {
+	return driver.Record{}, driver.ErrUnsupported
+}
+
+func (s *Storage) Delete(context.Context, string) error {
+	return driver.ErrUnsupported
+}
+
+func (s *Storage) Query(context.Context, driver.Query) ([]driver.Record, error) {
+	return nil, driver.ErrUnsupported
+}
+
+func (s *Storage) Count(context.Context, driver.Query) (int64, error) {
+	return 0, driver.ErrUnsupported
+}
+
+func (s *Storage) Values(context.Context, string, driver.Query, int) ([]string, error) {
+	return nil, driver.ErrUnsupported
+}
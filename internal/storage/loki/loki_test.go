@@ -0,0 +1,237 @@
+// Copyright 2026 The HuaTuo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package loki
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"huatuo-bamai/internal/storage/driver"
+)
+
+// testServer records the pushes and answers them with the statuses, 204
+// once they are used up.
+type testServer struct {
+	mu       sync.Mutex
+	pushes   []pushRequest
+	tenants  []string
+	statuses []int
+}
+
+func newTestServer(t *testing.T, statuses ...int) (*testServer, string) {
+	t.Helper()
+
+	ts := &testServer{statuses: statuses}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != pushPath {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		var push pushRequest
+		if err := json.NewDecoder(r.Body).Decode(&push); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		ts.mu.Lock()
+		defer ts.mu.Unlock()
+		ts.pushes = append(ts.pushes, push)
+		ts.tenants = append(ts.tenants, r.Header.Get("X-Scope-OrgID"))
+
+		status := http.StatusNoContent
+		if len(ts.statuses) > 0 {
+			status, ts.statuses = ts.statuses[0], ts.statuses[1:]
+		}
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(srv.Close)
+	return ts, srv.URL
+}
+
+func (ts *testServer) requests() []pushRequest {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	return append([]pushRequest(nil), ts.pushes...)
+}
+
+func testRecord(id, tracer string, t time.Time) driver.Record {
+	return driver.Record{
+		ID:   id,
+		Data: []byte(`{"tracer_id":"` + id + `"}`),
+		Fields: map[string]any{
+			"hostname":     "node-1",
+			"region":       "dev",
+			"tracer_name":  tracer,
+			"tracer_time":  t,
+			"container_id": "c1",
+		},
+	}
+}
+
+func TestBackendSaveStreams(t *testing.T) {
+	ts, addr := newTestServer(t)
+	backend, err := NewBackend(&Config{Address: addr + "/", TenantID: "sre", FlushInterval: time.Hour})
+	if err != nil {
+		t.Fatalf("NewBackend() error = %v", err)
+	}
+
+	base := time.Date(2026, 10, 16, 8, 0, 0, 0, time.UTC)
+	records := []driver.Record{
+		testRecord("a", "oom", base.Add(2*time.Second)),
+		testRecord("b", "softlockup", base),
+		testRecord("c", "oom", base.Add(time.Second)),
+	}
+	for _, rec := range records {
+		if err := backend.Save(t.Context(), rec); err != nil {
+			t.Fatalf("Save() error = %v", err)
+		}
+	}
+	if backlog := backend.Backlog(); backlog != 3 {
+		t.Errorf("Backlog() = %d, want 3", backlog)
+	}
+	if err := backend.Close(t.Context()); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	pushes := ts.requests()
+	if len(pushes) != 1 || len(pushes[0].Streams) != 2 {
+		t.Fatalf("pushes = %+v, want one push of 2 streams", pushes)
+	}
+	if ts.tenants[0] != "sre" {
+		t.Errorf("X-Scope-OrgID = %q, want sre", ts.tenants[0])
+	}
+
+	// the streams in the order of their oldest entry.
+	softlockup, oom := pushes[0].Streams[0], pushes[0].Streams[1]
+	if softlockup.Stream["tracer_name"] != "softlockup" || len(softlockup.Stream) != 3 {
+		t.Errorf("stream = %v, want the hostname, region and tracer_name labels", softlockup.Stream)
+	}
+	if len(oom.Values) != 2 ||
+		oom.Values[0][0] != strconv.FormatInt(base.Add(time.Second).UnixNano(), 10) ||
+		oom.Values[0][1] != `{"tracer_id":"c"}` || oom.Values[1][1] != `{"tracer_id":"a"}` {
+		t.Errorf("oom values = %v, want c then a", oom.Values)
+	}
+	if backlog := backend.Backlog(); backlog != 0 {
+		t.Errorf("Backlog() after Close = %d, want 0", backlog)
+	}
+}
+
+func TestBackendSaveBatches(t *testing.T) {
+	ts, addr := newTestServer(t)
+	backend, err := NewBackend(&Config{Address: addr, BatchSize: 2, FlushInterval: time.Hour})
+	if err != nil {
+		t.Fatalf("NewBackend() error = %v", err)
+	}
+
+	now := time.Now()
+	for _, id := range []string{"a", "b", "c"} {
+		_ = backend.Save(t.Context(), testRecord(id, "oom", now))
+	}
+
+	// the full batch is pushed without waiting for the interval.
+	deadline := time.Now().Add(5 * time.Second)
+	for len(ts.requests()) == 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	_ = backend.Close(t.Context())
+
+	pushes := ts.requests()
+	if len(pushes) != 2 || len(pushes[0].Streams[0].Values) != 2 || len(pushes[1].Streams[0].Values) != 1 {
+		t.Errorf("pushes = %+v, want a batch of 2 then 1", pushes)
+	}
+}
+
+func TestBackendPushRetry(t *testing.T) {
+	ts, addr := newTestServer(t, http.StatusServiceUnavailable, http.StatusBadRequest)
+	backend, err := NewBackend(&Config{Address: addr, FlushInterval: time.Hour})
+	if err != nil {
+		t.Fatalf("NewBackend() error = %v", err)
+	}
+
+	// 503 is retried, 400 drops the batch.
+	_ = backend.Save(t.Context(), testRecord("a", "oom", time.Now()))
+	_ = backend.Close(t.Context())
+	if n := len(ts.requests()); n != 2 {
+		t.Errorf("pushes = %d, want 2", n)
+	}
+	if backlog := backend.Backlog(); backlog != 0 {
+		t.Errorf("Backlog() after a dropped batch = %d, want 0", backlog)
+	}
+}
+
+func TestNewBackendEmptyAddress(t *testing.T) {
+	if _, err := NewBackend(&Config{}); err == nil {
+		t.Error("NewBackend() without address succeeded")
+	}
+}
+
+func TestClientVerifiesServer(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	t.Cleanup(srv.Close)
+
+	verifying, err := newClient(&Config{Address: srv.URL})
+	if err != nil {
+		t.Fatalf("newClient() error = %v", err)
+	}
+	if err := verifying.push(t.Context(), []byte(`{"streams":[]}`)); err == nil {
+		t.Error("push() to an unknown server certificate succeeded")
+	}
+
+	skipping, err := newClient(&Config{Address: srv.URL, InsecureSkipVerify: true})
+	if err != nil {
+		t.Fatalf("newClient() error = %v", err)
+	}
+	if err := skipping.push(t.Context(), []byte(`{"streams":[]}`)); err != nil {
+		t.Errorf("push() skipping the verification error = %v", err)
+	}
+
+	if _, err := newClient(&Config{Address: srv.URL, CAFile: "/nonexistent/ca.pem"}); err == nil {
+		t.Error("newClient() with a missing ca file succeeded")
+	}
+}
+
+func TestBackendUnsupportedOperations(t *testing.T) {
+	_, addr := newTestServer(t)
+	backend, err := NewBackend(&Config{Address: addr})
+	if err != nil {
+		t.Fatalf("NewBackend() error = %v", err)
+	}
+	t.Cleanup(func() { _ = backend.Close(t.Context()) })
+
+	if _, err := backend.Get(t.Context(), "a"); !errors.Is(err, driver.ErrUnsupported) {
+		t.Errorf("Get() error = %v, want ErrUnsupported", err)
+	}
+	if err := backend.Delete(t.Context(), "a"); !errors.Is(err, driver.ErrUnsupported) {
+		t.Errorf("Delete() error = %v, want ErrUnsupported", err)
+	}
+	if _, err := backend.Query(t.Context(), driver.Query{}); !errors.Is(err, driver.ErrUnsupported) {
+		t.Errorf("Query() error = %v, want ErrUnsupported", err)
+	}
+	if _, err := backend.Count(t.Context(), driver.Query{}); !errors.Is(err, driver.ErrUnsupported) {
+		t.Errorf("Count() error = %v, want ErrUnsupported", err)
+	}
+	if _, err := backend.Values(t.Context(), "tracer_name", driver.Query{}, 10); !errors.Is(err, driver.ErrUnsupported) {
+		t.Errorf("Values() error = %v, want ErrUnsupported", err)
+	}
+}
@@ -0,0 +1,240 @@
+// Copyright 2026 The HuaTuo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package otlp implements a write only storage backend that exports
+// records as OTLP log records to an OpenTelemetry collector.
+package otlp
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	otlpclient "huatuo-bamai/internal/otlp"
+	"huatuo-bamai/internal/storage/driver"
+
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
+)
+
+const (
+	defaultBatchSize     = 512
+	defaultFlushInterval = time.Second
+
+	// exportRetries is the attempts of a batch, the records are dropped
+	// after the last one. The waits between them grow from minBackoff.
+	exportRetries = 5
+	minBackoff    = 500 * time.Millisecond
+	maxBackoff    = 30 * time.Second
+
+	metricsBackend = "otlp"
+)
+
+// Config contains OTLP backend settings.
+type Config struct {
+	Client otlpclient.Config
+	// BatchSize is the records exported at once, a batch is flushed
+	// earlier every FlushInterval.
+	BatchSize     int
+	FlushInterval time.Duration
+}
+
+// Storage exports records as log records: the payload is the body, the
+// tracer_name the event name, the hostname and region the resource and the
+// other fields the attributes.
+//
+// Save is asynchronous: the records are queued and exported in batches by
+// size, time, or Close. The collector is not read back, Get, Delete, Query,
+// Count and Values return driver.ErrUnsupported.
+type Storage struct {
+	client  *otlpclient.Client
+	batcher *driver.Batcher[pendingRecord]
+
+	// FailureHandler passes the records failing after the retries to the
+	// dead letter of the store.
+	driver.FailureHandler
+}
+
+type pendingRecord struct {
+	queued   time.Time
+	hostname string
+	region   string
+	record   *logspb.LogRecord
+	// rec is the ID and the Data of the record, for the dead letter.
+	rec driver.Record
+}
+
+func (r pendingRecord) Queued() time.Time     { return r.queued }
+func (r pendingRecord) Record() driver.Record { return r.rec }
+
+var (
+	_ driver.Backend         = (*Storage)(nil)
+	_ driver.Backlogger      = (*Storage)(nil)
+	_ driver.FailureNotifier = (*Storage)(nil)
+)
+
+func init() {
+	driver.RegisterBackend("otlp", func(cfg *driver.Config) (driver.Backend, error) {
+		return NewBackend(&Config{
+			Client: otlpclient.Config{
+				Endpoint:           cfg.OTLPEndpoint,
+				Headers:            cfg.OTLPHeaders,
+				Insecure:           cfg.OTLPInsecure,
+				CAFile:             cfg.OTLPCAFile,
+				CertFile:           cfg.OTLPCertFile,
+				KeyFile:            cfg.OTLPKeyFile,
+				InsecureSkipVerify: cfg.OTLPInsecureSkipVerify,
+				Timeout:            cfg.OTLPTimeout,
+			},
+			BatchSize:     cfg.OTLPBatchSize,
+			FlushInterval: cfg.OTLPFlushInterval,
+		})
+	})
+}
+
+// NewBackend creates an OTLP backend and starts its flusher.
+func NewBackend(cfg *Config) (*Storage, error) {
+	client, err := otlpclient.NewClient(&cfg.Client)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Storage{client: client}
+	batcher := &driver.BatcherConfig[pendingRecord]{
+		Backend:       metricsBackend,
+		BatchSize:     cfg.BatchSize,
+		FlushInterval: cfg.FlushInterval,
+		Retries:       exportRetries,
+		MinBackoff:    minBackoff,
+		MaxBackoff:    maxBackoff,
+		Send:          s.export,
+		Failure:       &s.FailureHandler,
+	}
+	if batcher.BatchSize <= 0 {
+		batcher.BatchSize = defaultBatchSize
+	}
+	if batcher.FlushInterval <= 0 {
+		batcher.FlushInterval = defaultFlushInterval
+	}
+	s.batcher = driver.NewBatcher(batcher)
+
+	return s, nil
+}
+
+// Init is a no-op, the collector has no schema.
+func (s *Storage) Init(context.Context, string, []driver.Index) error {
+	return nil
+}
+
+func (s *Storage) Save(_ context.Context, rec driver.Record) error {
+	return s.batcher.Save(newPendingRecord(rec, time.Now()))
+}
+
+func newPendingRecord(rec driver.Record, now time.Time) pendingRecord {
+	t := now
+	if tracerTime, ok := rec.Fields["tracer_time"].(time.Time); ok && !tracerTime.IsZero() {
+		t = tracerTime
+	}
+
+	keys := make([]string, 0, len(rec.Fields))
+	for key := range rec.Fields {
+		if key != "hostname" && key != "region" {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	attrs := make([]*commonpb.KeyValue, 0, len(keys))
+	for _, key := range keys {
+		if value := driver.StringValue(driver.NormalizeValue(rec.Fields[key])); value != "" {
+			attrs = append(attrs, otlpclient.StringAttribute(key, value))
+		}
+	}
+
+	return pendingRecord{
+		queued:   now,
+		hostname: driver.StringValue(rec.Fields["hostname"]),
+		region:   driver.StringValue(rec.Fields["region"]),
+		rec:      driver.Record{ID: rec.ID, Data: rec.Data},
+		record: &logspb.LogRecord{
+			TimeUnixNano:         uint64(t.UnixNano()),
+			ObservedTimeUnixNano: uint64(now.UnixNano()),
+			EventName:            driver.StringValue(rec.Fields["tracer_name"]),
+			Body:                 &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: string(rec.Data)}},
+			Attributes:           attrs,
+		},
+	}
+}
+
+// resourceLogs groups the records by the node they come from.
+func resourceLogs(batch []pendingRecord) []*logspb.ResourceLogs {
+	var logs []*logspb.ResourceLogs
+	resources := make(map[[2]string]*logspb.ScopeLogs)
+	for _, pending := range batch {
+		key := [2]string{pending.hostname, pending.region}
+		scope, ok := resources[key]
+		if !ok {
+			scope = &logspb.ScopeLogs{Scope: otlpclient.Scope()}
+			resources[key] = scope
+			logs = append(logs, &logspb.ResourceLogs{
+				Resource:  otlpclient.Resource(pending.hostname, pending.region),
+				ScopeLogs: []*logspb.ScopeLogs{scope},
+			})
+		}
+		scope.LogRecords = append(scope.LogRecords, pending.record)
+	}
+	return logs
+}
+
+// export sends one batch, the records refused for good would be refused
+// again.
+func (s *Storage) export(ctx context.Context, batch []pendingRecord) driver.BatchResult[pendingRecord] {
+	err := s.client.ExportLogs(ctx, resourceLogs(batch))
+	return driver.BatchSent(batch, err, otlpclient.IsRetryable(err))
+}
+
+// Backlog returns the records queued and not yet exported.
+func (s *Storage) Backlog() int64 {
+	return s.batcher.Backlog()
+}
+
+// Close stops the flusher, exports the pending records and closes the
+// connection. The Storage must not be reused.
+func (s *Storage) Close(ctx context.Context) error {
+	if s.batcher == nil {
+		return nil
+	}
+	s.batcher.Close(ctx)
+	return s.client.Close()
+}
+
+func (s *Storage) Get(context.Context, string) (driver.Record, error) {
+	return driver.Record{}, driver.ErrUnsupported
+}
+
+func (s *Storage) Delete(context.Context, string) error {
+	return driver.ErrUnsupported
+}
+
+func (s *Storage) Query(context.Context, driver.Query) ([]driver.Record, error) {
+	return nil, driver.ErrUnsupported
+}
+
+func (s *Storage) Count(context.Context, driver.Query) (int64, error) {
+	return 0, driver.ErrUnsupported
+}
+
+func (s *Storage) Values(context.Context, string, driver.Query, int) ([]string, error) {
+	return nil, driver.ErrUnsupported
+}
@@ -0,0 +1,243 @@
+// Copyright 2026 The HuaTuo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package otlp
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	otlpclient "huatuo-bamai/internal/otlp"
+	"huatuo-bamai/internal/storage/driver"
+
+	collogspb "go.opentelemetry.io/proto/otlp/collector/logs/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// testCollector records the exports and answers them with the codes, OK
+// once they are used up.
+type testCollector struct {
+	collogspb.UnimplementedLogsServiceServer
+
+	mu       sync.Mutex
+	requests []*collogspb.ExportLogsServiceRequest
+	tokens   []string
+	codes    []codes.Code
+}
+
+func (c *testCollector) Export(ctx context.Context, req *collogspb.ExportLogsServiceRequest) (*collogspb.ExportLogsServiceResponse, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.requests = append(c.requests, req)
+	md, _ := metadata.FromIncomingContext(ctx)
+	c.tokens = append(c.tokens, md.Get("authorization")...)
+
+	if len(c.codes) > 0 {
+		code := c.codes[0]
+		c.codes = c.codes[1:]
+		return nil, status.Error(code, code.String())
+	}
+	return &collogspb.ExportLogsServiceResponse{}, nil
+}
+
+func (c *testCollector) exports() []*collogspb.ExportLogsServiceRequest {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]*collogspb.ExportLogsServiceRequest(nil), c.requests...)
+}
+
+func newTestCollector(t *testing.T, codes ...codes.Code) (*testCollector, string) {
+	t.Helper()
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+
+	collector := &testCollector{codes: codes}
+	srv := grpc.NewServer()
+	collogspb.RegisterLogsServiceServer(srv, collector)
+	go func() { _ = srv.Serve(lis) }()
+	t.Cleanup(srv.Stop)
+
+	return collector, lis.Addr().String()
+}
+
+func newTestBackend(t *testing.T, endpoint string, batchSize int) *Storage {
+	t.Helper()
+
+	backend, err := NewBackend(&Config{
+		Client: otlpclient.Config{
+			Endpoint: endpoint,
+			Headers:  map[string]string{"authorization": "Bearer token"},
+			Insecure: true,
+		},
+		BatchSize:     batchSize,
+		FlushInterval: time.Hour,
+	})
+	if err != nil {
+		t.Fatalf("NewBackend() error = %v", err)
+	}
+	return backend
+}
+
+func testRecord(id, hostname string, t time.Time) driver.Record {
+	return driver.Record{
+		ID:   id,
+		Data: []byte(`{"tracer_id":"` + id + `"}`),
+		Fields: map[string]any{
+			"hostname":     hostname,
+			"region":       "dev",
+			"tracer_name":  "oom",
+			"tracer_time":  t,
+			"tracer_id":    id,
+			"container_id": "c1",
+		},
+	}
+}
+
+func TestBackendSaveLogRecords(t *testing.T) {
+	collector, endpoint := newTestCollector(t)
+	backend := newTestBackend(t, endpoint, 0)
+
+	base := time.Date(2026, 10, 16, 8, 0, 0, 0, time.UTC)
+	for _, rec := range []driver.Record{
+		testRecord("a", "node-1", base),
+		testRecord("b", "node-2", base.Add(time.Second)),
+		testRecord("c", "node-1", base.Add(2*time.Second)),
+	} {
+		if err := backend.Save(t.Context(), rec); err != nil {
+			t.Fatalf("Save() error = %v", err)
+		}
+	}
+	if backlog := backend.Backlog(); backlog != 3 {
+		t.Errorf("Backlog() = %d, want 3", backlog)
+	}
+	if err := backend.Close(t.Context()); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	exports := collector.exports()
+	if len(exports) != 1 || len(exports[0].GetResourceLogs()) != 2 {
+		t.Fatalf("exports = %v, want one export of 2 resources", exports)
+	}
+	if collector.tokens[0] != "Bearer token" {
+		t.Errorf("authorization = %q, want the configured header", collector.tokens[0])
+	}
+
+	node1 := exports[0].GetResourceLogs()[0]
+	if host := node1.GetResource().GetAttributes()[1]; host.GetKey() != "host.name" || host.GetValue().GetStringValue() != "node-1" {
+		t.Errorf("resource = %v, want host.name node-1", node1.GetResource())
+	}
+	records := node1.GetScopeLogs()[0].GetLogRecords()
+	if len(records) != 2 {
+		t.Fatalf("node-1 records = %d, want 2", len(records))
+	}
+
+	record := records[0]
+	if record.GetEventName() != "oom" || record.GetBody().GetStringValue() != `{"tracer_id":"a"}` ||
+		record.GetTimeUnixNano() != uint64(base.UnixNano()) {
+		t.Errorf("record = %v, want the oom event a at the tracer time", record)
+	}
+
+	var keys []string
+	for _, attr := range record.GetAttributes() {
+		keys = append(keys, attr.GetKey())
+	}
+	want := []string{"container_id", "tracer_id", "tracer_name", "tracer_time"}
+	if len(keys) != len(want) {
+		t.Fatalf("attributes = %v, want %v", keys, want)
+	}
+	for i := range want {
+		if keys[i] != want[i] {
+			t.Errorf("attributes = %v, want %v", keys, want)
+			break
+		}
+	}
+}
+
+func TestBackendSaveBatches(t *testing.T) {
+	collector, endpoint := newTestCollector(t)
+	backend := newTestBackend(t, endpoint, 2)
+
+	now := time.Now()
+	for _, id := range []string{"a", "b", "c"} {
+		_ = backend.Save(t.Context(), testRecord(id, "node-1", now))
+	}
+
+	// the full batch is exported without waiting for the interval.
+	deadline := time.Now().Add(5 * time.Second)
+	for len(collector.exports()) == 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	_ = backend.Close(t.Context())
+
+	exports := collector.exports()
+	if len(exports) != 2 ||
+		len(exports[0].GetResourceLogs()[0].GetScopeLogs()[0].GetLogRecords()) != 2 ||
+		len(exports[1].GetResourceLogs()[0].GetScopeLogs()[0].GetLogRecords()) != 1 {
+		t.Errorf("exports = %d, want a batch of 2 then 1", len(exports))
+	}
+}
+
+func TestBackendExportRetry(t *testing.T) {
+	collector, endpoint := newTestCollector(t, codes.Unavailable, codes.InvalidArgument)
+	backend := newTestBackend(t, endpoint, 0)
+
+	// Unavailable is retried, InvalidArgument drops the batch.
+	_ = backend.Save(t.Context(), testRecord("a", "node-1", time.Now()))
+	_ = backend.Close(t.Context())
+	if n := len(collector.exports()); n != 2 {
+		t.Errorf("exports = %d, want 2", n)
+	}
+	if backlog := backend.Backlog(); backlog != 0 {
+		t.Errorf("Backlog() after a dropped batch = %d, want 0", backlog)
+	}
+}
+
+func TestNewBackendEmptyEndpoint(t *testing.T) {
+	if _, err := NewBackend(&Config{}); err == nil {
+		t.Error("NewBackend() without endpoint succeeded")
+	}
+}
+
+func TestBackendUnsupportedOperations(t *testing.T) {
+	_, endpoint := newTestCollector(t)
+	backend := newTestBackend(t, endpoint, 0)
+	t.Cleanup(func() { _ = backend.Close(t.Context()) })
+
+	if _, err := backend.Get(t.Context(), "a"); !errors.Is(err, driver.ErrUnsupported) {
+		t.Errorf("Get() error = %v, want ErrUnsupported", err)
+	}
+	if err := backend.Delete(t.Context(), "a"); !errors.Is(err, driver.ErrUnsupported) {
+		t.Errorf("Delete() error = %v, want ErrUnsupported", err)
+	}
+	if _, err := backend.Query(t.Context(), driver.Query{}); !errors.Is(err, driver.ErrUnsupported) {
+		t.Errorf("Query() error = %v, want ErrUnsupported", err)
+	}
+	if _, err := backend.Count(t.Context(), driver.Query{}); !errors.Is(err, driver.ErrUnsupported) {
+		t.Errorf("Count() error = %v, want ErrUnsupported", err)
+	}
+	if _, err := backend.Values(t.Context(), "tracer_name", driver.Query{}, 10); !errors.Is(err, driver.ErrUnsupported) {
+		t.Errorf("Values() error = %v, want ErrUnsupported", err)
+	}
+}
@@ -0,0 +1,66 @@
+// Copyright 2026 The HuaTuo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sqlite
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"huatuo-bamai/internal/storage/driver"
+)
+
+func TestRetention(t *testing.T) {
+	backend, err := driver.NewBackend(&driver.Config{
+		Driver:               "sqlite",
+		SQLiteDSN:            filepath.Join(t.TempDir(), "events.db"),
+		SQLiteRetention:      24 * time.Hour,
+		SQLiteRetentionField: "time",
+	})
+	if err != nil {
+		t.Fatalf("NewBackend() error = %v", err)
+	}
+	s := backend.(*Storage)
+	t.Cleanup(func() { _ = s.Close(t.Context()) })
+
+	now := time.Now()
+	if err := s.Init(t.Context(), "events", []driver.Index{{Field: "time"}}); err != nil {
+		t.Fatalf("Init() error = %v", err)
+	}
+	for id, at := range map[string]time.Time{
+		"old":    now.Add(-48 * time.Hour),
+		"recent": now.Add(-time.Hour),
+	} {
+		if err := s.Save(t.Context(), driver.Record{ID: id, Data: []byte("{}"), Fields: map[string]any{"time": at}}); err != nil {
+			t.Fatalf("Save(%s) error = %v", id, err)
+		}
+	}
+
+	purged, err := s.purge(t.Context(), now)
+	if err != nil || purged != 1 {
+		t.Fatalf("purge() = %d, %v, want 1 row", purged, err)
+	}
+	if _, err := s.Get(t.Context(), "old"); !errors.Is(err, driver.ErrNotFound) {
+		t.Errorf("Get(old) error = %v, want %v", err, driver.ErrNotFound)
+	}
+	if _, err := s.Get(t.Context(), "recent"); err != nil {
+		t.Errorf("Get(recent) error = %v", err)
+	}
+
+	if err := s.SetRetention("time')--", time.Hour); err == nil {
+		t.Error("SetRetention() of an invalid field error = nil")
+	}
+}
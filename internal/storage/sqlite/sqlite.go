@@ -22,21 +22,41 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"time"
 
+	"huatuo-bamai/internal/log"
 	"huatuo-bamai/internal/storage/driver"
 )
 
+// purgeInterval is how often the rows past the retention are deleted.
+const purgeInterval = time.Hour
+
 // Storage stores records in SQLite. It is bound to one table by Init.
 type Storage struct {
 	db    *sql.DB
 	table string
+
+	retention      time.Duration
+	retentionField string
+	cancel         context.CancelFunc
+	done           chan struct{}
 }
 
 var _ driver.Backend = (*Storage)(nil)
 
 func init() {
 	driver.RegisterBackend("sqlite", func(cfg *driver.Config) (driver.Backend, error) {
-		return NewBackend(cfg.SQLiteDSN)
+		s, err := NewBackend(cfg.SQLiteDSN)
+		if err != nil {
+			return nil, err
+		}
+		if cfg.SQLiteRetention > 0 {
+			if err := s.SetRetention(cfg.SQLiteRetentionField, cfg.SQLiteRetention); err != nil {
+				_ = s.Close(context.Background())
+				return nil, err
+			}
+		}
+		return s, nil
 	})
 }
 
@@ -53,11 +73,26 @@ func NewBackend(dsn string) (*Storage, error) {
 	return &Storage{db: db}, nil
 }
 
-// Close closes the SQLite database.
+// SetRetention deletes, from Init on, the rows whose time field is older
+// than retention. It must be called before Init.
+func (s *Storage) SetRetention(field string, retention time.Duration) error {
+	if err := validateIdentifier(field); err != nil {
+		return fmt.Errorf("sqlite backend retention field %q: %w", field, err)
+	}
+	s.retentionField = field
+	s.retention = retention
+	return nil
+}
+
+// Close stops the purge and closes the SQLite database.
 func (s *Storage) Close(_ context.Context) error {
 	if s == nil || s.db == nil {
 		return nil
 	}
+	if s.cancel != nil {
+		s.cancel()
+		<-s.done
+	}
 	return s.db.Close()
 }
 
@@ -94,9 +129,48 @@ CREATE TABLE IF NOT EXISTS %s (
 			return fmt.Errorf("sqlite backend init index %s.%s: %w", s.table, idx.Field, err)
 		}
 	}
+
+	if s.retention > 0 && s.cancel == nil {
+		if _, err := s.purge(ctx, time.Now()); err != nil {
+			return err
+		}
+		var purgeCtx context.Context
+		purgeCtx, s.cancel = context.WithCancel(context.Background())
+		s.done = make(chan struct{})
+		go s.purgeLoop(purgeCtx)
+	}
 	return nil
 }
 
+func (s *Storage) purgeLoop(ctx context.Context) {
+	defer close(s.done)
+
+	ticker := time.NewTicker(purgeInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			if _, err := s.purge(ctx, now); err != nil && ctx.Err() == nil {
+				log.Warnf("%v", err)
+			}
+		}
+	}
+}
+
+// purge deletes the rows older than the retention at now.
+func (s *Storage) purge(ctx context.Context, now time.Time) (int64, error) {
+	purgeSQL := fmt.Sprintf(`DELETE FROM %s WHERE %s < ?`,
+		quoteIdentifier(s.table), jsonExtractExpr(s.retentionField))
+	result, err := s.db.ExecContext(driver.WithContext(ctx), purgeSQL, driver.NormalizeValue(now.Add(-s.retention)))
+	if err != nil {
+		return 0, fmt.Errorf("sqlite backend purge %s: %w", s.table, err)
+	}
+	return result.RowsAffected()
+}
+
 func (s *Storage) Save(ctx context.Context, rec driver.Record) error {
 	fieldsJSON, err := normalizedFieldsJSON(rec.Fields)
 	if err != nil {
@@ -16,17 +16,33 @@ package storage
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"path/filepath"
+	"time"
 
 	"huatuo-bamai/internal/storage/driver"
+
+	"github.com/prometheus/client_golang/prometheus"
 )
 
 // Store is a generic, backend-agnostic CRUD abstraction; a Mapper[T] handles
 // encoding, decoding, and index declarations for the domain type T.
 type Store[T any] struct {
-	Name    string
-	backend driver.Backend
-	mapper  driver.Mapper[T]
+	Name       string
+	backend    driver.Backend
+	mapper     driver.Mapper[T]
+	collection string
+
+	// deadLetter keeps the records failing to be written, nil drops them.
+	deadLetter *deadLetter
+	cancel     context.CancelFunc
+	done       chan struct{}
+}
+
+// RegisterMetrics registers the huatuo_storage_* write path metrics.
+func RegisterMetrics(reg prometheus.Registerer) {
+	reg.MustRegister(driver.Collectors()...)
 }
 
 // NewFromConfig creates a Store from Config and Mapper.
@@ -36,7 +52,17 @@ func NewFromConfig[T any](ctx context.Context, cfg *driver.Config, collection st
 		return nil, err
 	}
 
-	return NewStore(ctx, cfg.Driver, backend, collection, mapper)
+	store, err := NewStore(ctx, cfg.Driver, backend, collection, mapper)
+	if err != nil || cfg.DeadLetterPath == "" {
+		return store, err
+	}
+
+	dir := filepath.Join(cfg.DeadLetterPath, cfg.Driver+"-"+collection)
+	if err := store.startDeadLetter(dir, cfg.DeadLetterMaxSize, cfg.DeadLetterRedriveInterval); err != nil {
+		_ = backend.Close(driver.WithContext(ctx))
+		return nil, err
+	}
+	return store, nil
 }
 
 // NewStore validates that backend and mapper are non-nil, verifies the collection
@@ -67,27 +93,49 @@ func NewStore[T any](ctx context.Context, name string, backend driver.Backend, c
 	}
 
 	return &Store[T]{
-		Name:    name,
-		backend: backend,
-		mapper:  mapper,
+		Name:       name,
+		backend:    backend,
+		mapper:     mapper,
+		collection: collection,
 	}, nil
 }
 
-// Save persists v; returns ErrInvalidField if the ID is empty.
-func (s *Store[T]) Save(ctx context.Context, v T) error {
+// Save persists v; returns ErrInvalidField if the ID is empty. A record the
+// backend fails to save goes to the dead letter, if any.
+func (s *Store[T]) Save(ctx context.Context, v T) (err error) {
+	start := time.Now()
+	defer func() { driver.ObserveWrite(s.Name, s.collection, start, err) }()
+
 	rec, err := s.record(v)
 	if err != nil {
 		return err
 	}
-	return s.backend.Save(driver.WithContext(ctx), rec)
+	if err = s.backend.Save(driver.WithContext(ctx), rec); err != nil {
+		driver.ObserveWriteFailures(s.Name, 1)
+		if s.deadLetter != nil {
+			s.deadLetterWrite([]driver.Record{rec}, err)
+		}
+	}
+	return err
 }
 
 // Create persists v only when its ID does not already exist.
-func (s *Store[T]) Create(ctx context.Context, v T) error {
+func (s *Store[T]) Create(ctx context.Context, v T) (err error) {
 	creator, ok := s.backend.(driver.Creator)
 	if !ok {
 		return driver.ErrUnsupportedOp
 	}
+
+	start := time.Now()
+	defer func() {
+		// an existing id is the expected outcome of a racing Create.
+		if errors.Is(err, driver.ErrAlreadyExists) {
+			driver.ObserveWrite(s.Name, s.collection, start, nil)
+			return
+		}
+		driver.ObserveWrite(s.Name, s.collection, start, err)
+	}()
+
 	rec, err := s.record(v)
 	if err != nil {
 		return err
@@ -117,6 +165,16 @@ func (s *Store[T]) record(v T) (driver.Record, error) {
 	return rec, nil
 }
 
+// Backlog returns the records saved but not yet delivered, false when the
+// backend writes synchronously.
+func (s *Store[T]) Backlog() (int64, bool) {
+	backlogger, ok := s.backend.(driver.Backlogger)
+	if !ok {
+		return 0, false
+	}
+	return backlogger.Backlog(), true
+}
+
 // Get retrieves the object with the given id; returns ErrNotFound when not found.
 func (s *Store[T]) Get(ctx context.Context, id string) (T, error) {
 	rec, err := s.backend.Get(driver.WithContext(ctx), id)
@@ -135,7 +193,17 @@ func (s *Store[T]) Delete(ctx context.Context, id string) error {
 // Close releases backend resources and flushes any pending writes. The store
 // must not be used after Close returns.
 func (s *Store[T]) Close(ctx context.Context) error {
-	return s.backend.Close(driver.WithContext(ctx))
+	if s.cancel != nil {
+		s.cancel()
+		<-s.done
+	}
+
+	err := s.backend.Close(driver.WithContext(ctx))
+	// the records failing in the last flush are written by the next run.
+	if s.deadLetter != nil {
+		err = errors.Join(err, s.deadLetter.Close())
+	}
+	return err
 }
 
 // Query returns objects matching q; all filter and sort fields must be registered indexes.
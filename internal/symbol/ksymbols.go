@@ -15,10 +15,13 @@
 package symbol
 
 import (
+	"encoding/binary"
 	"fmt"
 	"slices"
+	"strings"
 	"sync"
 
+	"huatuo-bamai/internal/bpf"
 	"huatuo-bamai/internal/log"
 	"huatuo-bamai/internal/procfs"
 )
@@ -50,6 +53,33 @@ func KsymStackStrs(kstack []uint64, kstackSize int) []string {
 	return dumpKernelBackTrace(kstack, kstackSize, outTypeString, false).strings
 }
 
+// KsymStackOfMap returns the kernel stack of the id in the stack trace map
+// of b, a frame per line, "" if the map was full, the id being negative.
+func KsymStackOfMap(b bpf.BPF, mapName string, stackID int32) string {
+	if stackID < 0 {
+		return ""
+	}
+
+	value, err := b.ReadMap(b.MapIDByName(mapName), binary.LittleEndian.AppendUint32(nil, uint32(stackID)))
+	if err != nil {
+		return ""
+	}
+	addrs := stackTraceAddrs(value)
+	if len(addrs) == 0 {
+		return ""
+	}
+	return strings.Join(KsymStackStrs(addrs, KsymStackMaxDepth), "\n")
+}
+
+// stackTraceAddrs decodes the addresses of a stack trace map value.
+func stackTraceAddrs(value []byte) []uint64 {
+	addrs := make([]uint64, len(value)/8)
+	for i := range addrs {
+		addrs[i] = binary.LittleEndian.Uint64(value[i*8:])
+	}
+	return addrs
+}
+
 // KsymStackBytesReversed resolves kernel stack addresses into byte frames (outermost first).
 func KsymStackBytesReversed(kstack []uint64, kstackSize int) [][]byte {
 	return dumpKernelBackTrace(kstack, kstackSize, outTypeBytes, true).bytes
@@ -15,6 +15,7 @@
 package symbol
 
 import (
+	"encoding/binary"
 	"path/filepath"
 	"strconv"
 	"strings"
@@ -176,3 +177,16 @@ func TestDumpKernelBackTraceKsymNotFound(t *testing.T) {
 		t.Errorf("dumpKernelBackTrace ksym-not-found: got %q, want %q", got[0], want)
 	}
 }
+
+func TestStackTraceAddrs(t *testing.T) {
+	value := binary.LittleEndian.AppendUint64(nil, 0xffffffff81000010)
+	value = binary.LittleEndian.AppendUint64(value, 0xffffffff81000020)
+
+	addrs := stackTraceAddrs(value)
+	if len(addrs) != 2 || addrs[0] != 0xffffffff81000010 || addrs[1] != 0xffffffff81000020 {
+		t.Errorf("stackTraceAddrs() = %x", addrs)
+	}
+	if addrs := stackTraceAddrs([]byte{1, 2, 3}); len(addrs) != 0 {
+		t.Errorf("stackTraceAddrs() of a truncated value = %x", addrs)
+	}
+}
@@ -34,21 +34,42 @@ func RunningDir() (string, error) {
 	return filepath.Dir(exePath), nil
 }
 
+// HostnameByPid returns the hostname of the UTS namespace of pid, entered
+// by the calling thread for the time of the call.
 func HostnameByPid(pid uint32) (string, error) {
-	var empty string
 	fd, err := os.Open(procfs.Path(fmt.Sprintf("%d", pid), "ns/uts"))
 	if err != nil {
-		return empty, err
+		return "", err
 	}
 	defer fd.Close()
 
 	runtime.LockOSThread()
-	defer runtime.UnlockOSThread()
+
+	self, err := os.Open(procfs.Path("thread-self", "ns/uts"))
+	if err != nil {
+		runtime.UnlockOSThread()
+		return "", err
+	}
+	defer self.Close()
 
 	if err := unix.Setns(int(fd.Fd()), unix.CLONE_NEWUTS); err != nil {
-		return empty, err
+		runtime.UnlockOSThread()
+		return "", err
+	}
+
+	var uts unix.Utsname
+	unameErr := unix.Uname(&uts)
+
+	// a thread not restored is left locked, the runtime terminates it.
+	if err := unix.Setns(int(self.Fd()), unix.CLONE_NEWUTS); err != nil {
+		return "", fmt.Errorf("restore uts namespace: %w", err)
+	}
+	runtime.UnlockOSThread()
+
+	if unameErr != nil {
+		return "", unameErr
 	}
-	return os.Hostname()
+	return unix.ByteSliceToString(uts.Nodename[:]), nil
 }
 
 func ProcNameByPid(pid uint32) (string, error) {
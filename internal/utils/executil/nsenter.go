@@ -0,0 +1,189 @@
+// Copyright 2026 The HuaTuo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package executil
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"syscall"
+	"time"
+)
+
+const (
+	nsenterDefaultTimeout   = 5 * time.Second
+	nsenterDefaultMaxOutput = 1 << 20
+	nsenterPath             = "/usr/sbin:/usr/bin:/sbin:/bin"
+)
+
+// Namespaces which Nsenter can enter, named as the nsenter(1) options. The
+// mount namespace is not one of them, the command would be run from the
+// filesystem of the container as the root of the host.
+const (
+	NamespaceNet    = "net"
+	NamespaceUTS    = "uts"
+	NamespaceIPC    = "ipc"
+	NamespacePID    = "pid"
+	NamespaceCgroup = "cgroup"
+)
+
+var (
+	// ErrNsenterNotAllowed is returned for a command outside the allowlist.
+	ErrNsenterNotAllowed = errors.New("command not allowed in nsenter")
+	// ErrNsenterOutputLimit is returned with the truncated output when the
+	// command writes more than NsenterOptions.MaxOutput bytes.
+	ErrNsenterOutputLimit = errors.New("nsenter output exceeds limit")
+)
+
+// nsenterAllowlist is the read-only tools tracers may run inside a
+// container. Only bare names are accepted, they are resolved to the
+// binaries of the host before entering the namespaces.
+var nsenterAllowlist = map[string]struct{}{
+	"ss":        {},
+	"ip":        {},
+	"tc":        {},
+	"ethtool":   {},
+	"nstat":     {},
+	"conntrack": {},
+	"hostname":  {},
+}
+
+var nsenterNamespaces = map[string]struct{}{
+	NamespaceNet:    {},
+	NamespaceUTS:    {},
+	NamespaceIPC:    {},
+	NamespacePID:    {},
+	NamespaceCgroup: {},
+}
+
+// NsenterOptions controls how Nsenter runs a command.
+type NsenterOptions struct {
+	// Namespaces to enter, defaults to the network namespace only.
+	Namespaces []string
+	// Timeout kills the command and its children, defaults to 5s.
+	Timeout time.Duration
+	// MaxOutput bytes of stdout are kept, defaults to 1MiB.
+	MaxOutput int
+}
+
+// Nsenter runs an allowlisted command in the namespaces of pid, usually the
+// init pid of a container, and returns its stdout.
+func Nsenter(ctx context.Context, pid int, opts *NsenterOptions, name string, args ...string) ([]byte, error) {
+	if _, ok := nsenterAllowlist[name]; !ok {
+		return nil, fmt.Errorf("%q: %w", name, ErrNsenterNotAllowed)
+	}
+
+	nsArgs, err := nsenterArgs(pid, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	nsenter, err := nsenterLookPath("nsenter")
+	if err != nil {
+		return nil, err
+	}
+	path, err := nsenterLookPath(name)
+	if err != nil {
+		return nil, err
+	}
+
+	timeout, maxOutput := nsenterDefaultTimeout, nsenterDefaultMaxOutput
+	if opts != nil && opts.Timeout > 0 {
+		timeout = opts.Timeout
+	}
+	if opts != nil && opts.MaxOutput > 0 {
+		maxOutput = opts.MaxOutput
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, nsenter, append(append(nsArgs, "--", path), args...)...)
+	cmd.Env = []string{"PATH=" + nsenterPath, "LC_ALL=C"}
+	// nsenter forks for the pid namespace, kill the whole group on timeout.
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	cmd.Cancel = func() error {
+		return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+	}
+	cmd.WaitDelay = time.Second
+
+	stdout := &limitedBuffer{limit: maxOutput}
+	stderr := &limitedBuffer{limit: 4096}
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("nsenter -t %d %s: %w: %s", pid, name, err, bytes.TrimSpace(stderr.buf.Bytes()))
+	}
+
+	if stdout.truncated {
+		return stdout.buf.Bytes(), fmt.Errorf("nsenter -t %d %s: %w (%d bytes)", pid, name, ErrNsenterOutputLimit, maxOutput)
+	}
+	return stdout.buf.Bytes(), nil
+}
+
+// nsenterLookPath returns the absolute path of the binary of the host in
+// nsenterPath, the agent PATH is not trusted.
+func nsenterLookPath(name string) (string, error) {
+	for _, dir := range filepath.SplitList(nsenterPath) {
+		path := filepath.Join(dir, name)
+		if fi, err := os.Stat(path); err == nil && fi.Mode().IsRegular() && fi.Mode()&0o111 != 0 {
+			return path, nil
+		}
+	}
+	return "", fmt.Errorf("%s: %w", name, exec.ErrNotFound)
+}
+
+func nsenterArgs(pid int, opts *NsenterOptions) ([]string, error) {
+	if pid <= 0 {
+		return nil, fmt.Errorf("invalid pid %d", pid)
+	}
+
+	namespaces := []string{NamespaceNet}
+	if opts != nil && len(opts.Namespaces) > 0 {
+		namespaces = opts.Namespaces
+	}
+
+	args := []string{"--target", strconv.Itoa(pid)}
+	for _, ns := range namespaces {
+		if _, ok := nsenterNamespaces[ns]; !ok {
+			return nil, fmt.Errorf("invalid namespace %q", ns)
+		}
+		args = append(args, "--"+ns)
+	}
+	return args, nil
+}
+
+// limitedBuffer keeps the first limit bytes and drops the rest, so that a
+// chatty command is not killed by a broken pipe.
+type limitedBuffer struct {
+	buf       bytes.Buffer
+	limit     int
+	truncated bool
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	if room := b.limit - b.buf.Len(); len(p) > room {
+		b.truncated = true
+		b.buf.Write(p[:max(room, 0)])
+		return len(p), nil
+	}
+	return b.buf.Write(p)
+}
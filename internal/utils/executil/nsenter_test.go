@@ -0,0 +1,94 @@
+// Copyright 2026 The HuaTuo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package executil
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNsenterRejects(t *testing.T) {
+	_, err := Nsenter(context.Background(), 1, nil, "sh", "-c", "id")
+	assert.ErrorIs(t, err, ErrNsenterNotAllowed)
+
+	_, err = Nsenter(context.Background(), 1, nil, "/usr/bin/ss")
+	assert.ErrorIs(t, err, ErrNsenterNotAllowed)
+
+	_, err = Nsenter(context.Background(), 0, nil, "ss")
+	assert.Error(t, err)
+
+	_, err = Nsenter(context.Background(), 1, &NsenterOptions{Namespaces: []string{"user"}}, "ss")
+	assert.Error(t, err)
+}
+
+func TestNsenterArgs(t *testing.T) {
+	args, err := nsenterArgs(42, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"--target", "42", "--net"}, args)
+
+	args, err = nsenterArgs(42, &NsenterOptions{Namespaces: []string{NamespaceUTS, NamespacePID}})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"--target", "42", "--uts", "--pid"}, args)
+
+	// the binaries of the container would be run as the root of the host.
+	_, err = nsenterArgs(42, &NsenterOptions{Namespaces: []string{"mount"}})
+	assert.Error(t, err)
+}
+
+func TestNsenterLookPath(t *testing.T) {
+	path, err := nsenterLookPath("sh")
+	if err != nil {
+		t.Skip("sh not installed in the nsenter path")
+	}
+	assert.True(t, filepath.IsAbs(path))
+
+	_, err = nsenterLookPath("huatuo-not-a-command")
+	assert.ErrorIs(t, err, exec.ErrNotFound)
+}
+
+func TestLimitedBuffer(t *testing.T) {
+	b := &limitedBuffer{limit: 4}
+
+	n, err := b.Write([]byte("ab"))
+	assert.NoError(t, err)
+	assert.Equal(t, 2, n)
+	assert.False(t, b.truncated)
+
+	n, err = b.Write([]byte("cdef"))
+	assert.NoError(t, err)
+	assert.Equal(t, 4, n)
+	assert.True(t, b.truncated)
+	assert.Equal(t, "abcd", b.buf.String())
+}
+
+func TestNsenterSelf(t *testing.T) {
+	if _, err := exec.LookPath("nsenter"); err != nil {
+		t.Skip("nsenter not installed")
+	}
+
+	out, err := Nsenter(context.Background(), os.Getpid(), &NsenterOptions{Namespaces: []string{NamespaceUTS}}, "hostname")
+	if err != nil {
+		t.Skipf("nsenter requires CAP_SYS_ADMIN: %v", err)
+	}
+
+	hostname, _ := os.Hostname()
+	assert.Equal(t, hostname, strings.TrimSpace(string(out)))
+}
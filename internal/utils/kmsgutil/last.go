@@ -0,0 +1,80 @@
+// Copyright 2026 The HuaTuo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kmsgutil
+
+import (
+	"errors"
+	"syscall"
+	"time"
+)
+
+// Last returns the last n records of /dev/kmsg, the oldest first.
+func Last(n int) ([]*Record, error) {
+	// not os.Open, the pollable file blocks at the end of the records.
+	fd, err := syscall.Open("/dev/kmsg", syscall.O_RDONLY|syscall.O_NONBLOCK|syscall.O_CLOEXEC, 0)
+	if err != nil {
+		return nil, err
+	}
+	defer syscall.Close(fd)
+
+	bootTime, err := getBootTime()
+	if err != nil {
+		return nil, err
+	}
+
+	return lastRecords(func(buf []byte) (int, error) {
+		return syscall.Read(fd, buf)
+	}, n, bootTime)
+}
+
+// lastRecords reads the records by read until EAGAIN, keeping the last n.
+func lastRecords(read func([]byte) (int, error), n int, bootTime time.Time) ([]*Record, error) {
+	if n <= 0 {
+		return nil, nil
+	}
+
+	ring := make([]string, n)
+	count := 0
+	buf := make([]byte, recordMaxSize)
+	for {
+		size, err := read(buf)
+		if err != nil {
+			// the ring buffer wrapped past the reader.
+			if errors.Is(err, syscall.EPIPE) {
+				continue
+			}
+			if errors.Is(err, syscall.EAGAIN) {
+				break
+			}
+			return nil, err
+		}
+		if size == 0 {
+			break
+		}
+
+		ring[count%n] = string(buf[:size])
+		count++
+	}
+
+	records := make([]*Record, 0, min(count, n))
+	for i := max(count-n, 0); i < count; i++ {
+		rec, err := ParseRecord(ring[i%n], bootTime)
+		if err != nil {
+			continue
+		}
+		records = append(records, rec)
+	}
+	return records, nil
+}
@@ -0,0 +1,74 @@
+// Copyright 2026 The HuaTuo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kmsgutil
+
+import (
+	"fmt"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestLastRecords(t *testing.T) {
+	bootTime := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	reader := func(raws ...any) func([]byte) (int, error) {
+		return func(buf []byte) (int, error) {
+			if len(raws) == 0 {
+				return 0, syscall.EAGAIN
+			}
+			raw := raws[0]
+			raws = raws[1:]
+			if err, ok := raw.(error); ok {
+				return 0, err
+			}
+			return copy(buf, raw.(string)), nil
+		}
+	}
+
+	var raws []any
+	for seq := 1; seq <= 5; seq++ {
+		raws = append(raws, fmt.Sprintf("6,%d,%d,-;message %d\n", seq, seq*1000000, seq))
+		if seq == 2 {
+			raws = append(raws, syscall.EPIPE)
+		}
+	}
+
+	records, err := lastRecords(reader(raws...), 3, bootTime)
+	if err != nil {
+		t.Fatalf("lastRecords() error = %v", err)
+	}
+	if len(records) != 3 {
+		t.Fatalf("lastRecords() = %d records, want 3", len(records))
+	}
+	for i, rec := range records {
+		if want := fmt.Sprintf("message %d", i+3); rec.Message != want {
+			t.Errorf("records[%d] = %q, want %q", i, rec.Message, want)
+		}
+	}
+	if !records[0].Time.Equal(bootTime.Add(3 * time.Second)) {
+		t.Errorf("records[0].Time = %v", records[0].Time)
+	}
+
+	// fewer records than asked.
+	records, err = lastRecords(reader("6,1,0,-;only\n"), 3, bootTime)
+	if err != nil || len(records) != 1 || records[0].Message != "only" {
+		t.Errorf("lastRecords() = %v, %v, want the only record", records, err)
+	}
+
+	if _, err := lastRecords(reader(syscall.EIO), 3, bootTime); err == nil {
+		t.Error("lastRecords() of a failing read succeeded")
+	}
+}
@@ -0,0 +1,238 @@
+// Copyright 2026 The HuaTuo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kmsgutil
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// recordMaxSize is the largest record the kernel returns by one read,
+// CONSOLE_EXT_LOG_MAX. A smaller buffer fails the read with EINVAL.
+const recordMaxSize = 8192
+
+// facilityNames are the syslog facilities, by number.
+var facilityNames = []string{
+	"kern", "user", "mail", "daemon", "auth", "syslog", "lpr", "news",
+	"uucp", "cron", "authpriv", "ftp", "ntp", "security", "console", "solaris-cron",
+	"local0", "local1", "local2", "local3", "local4", "local5", "local6", "local7",
+}
+
+// severityNames are the syslog severities, by level.
+var severityNames = []string{
+	"emerg", "alert", "crit", "err", "warning", "notice", "info", "debug",
+}
+
+// Severities returns the names of the severities, the most severe first.
+func Severities() []string {
+	return append([]string(nil), severityNames...)
+}
+
+// SeverityLevel returns the level of the severity name, e.g. 3 for "err".
+func SeverityLevel(name string) (int, error) {
+	for level, severity := range severityNames {
+		if severity == name {
+			return level, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown kmsg severity %q", name)
+}
+
+// Record is a record of /dev/kmsg.
+type Record struct {
+	Facility string
+	Severity string
+	// Level is the number of Severity, 0 is the most severe.
+	Level int
+	Seq   uint64
+	Time  time.Time
+	// Message is the text, without the dictionary.
+	Message string
+	// Fields are the dictionary of the record, e.g. SUBSYSTEM and DEVICE.
+	Fields map[string]string
+}
+
+// ParseRecord parses a record read from /dev/kmsg,
+// "prio,seq,usec,flags;message" and the " KEY=value" dictionary lines.
+// The time of the record is bootTime plus its usec.
+func ParseRecord(raw string, bootTime time.Time) (*Record, error) {
+	header, body, ok := strings.Cut(raw, ";")
+	if !ok {
+		return nil, fmt.Errorf("invalid kmsg record %q", raw)
+	}
+
+	fields := strings.Split(header, ",")
+	if len(fields) < 3 {
+		return nil, fmt.Errorf("invalid kmsg record header %q", header)
+	}
+	prio, err := strconv.ParseUint(fields[0], 10, 32)
+	if err != nil {
+		return nil, fmt.Errorf("invalid kmsg record prio %q", fields[0])
+	}
+	seq, err := strconv.ParseUint(fields[1], 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid kmsg record seq %q", fields[1])
+	}
+	usec, err := strconv.ParseInt(fields[2], 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid kmsg record timestamp %q", fields[2])
+	}
+
+	rec := &Record{
+		Facility: "unknown",
+		Severity: severityNames[prio&7],
+		Level:    int(prio & 7),
+		Seq:      seq,
+		Time:     bootTime.Add(time.Duration(usec) * time.Microsecond),
+	}
+	if facility := prio >> 3; facility < uint64(len(facilityNames)) {
+		rec.Facility = facilityNames[facility]
+	}
+
+	lines := strings.Split(strings.TrimSuffix(body, "\n"), "\n")
+	rec.Message = lines[0]
+	for _, line := range lines[1:] {
+		key, value, ok := strings.Cut(strings.TrimPrefix(line, " "), "=")
+		if !ok {
+			continue
+		}
+		if rec.Fields == nil {
+			rec.Fields = make(map[string]string)
+		}
+		rec.Fields[key] = value
+	}
+
+	return rec, nil
+}
+
+// Tail reads the records written to /dev/kmsg after it is opened. The
+// records above the rate are dropped before being parsed, so a storm of
+// kernel messages does not take the cpu of the readers.
+type Tail struct {
+	file     *os.File
+	bootTime time.Time
+	limiter  *rate.Limiter
+	buf      []byte
+
+	// lastSeq is the seq of the last record read, to count the records
+	// overwritten in the ring buffer before being read.
+	lastSeq uint64
+	dropped atomic.Uint64
+	lost    atomic.Uint64
+}
+
+// NewTail opens /dev/kmsg at its end. maxPerSecond limits the records
+// returned, 0 does not limit them.
+func NewTail(maxPerSecond int) (*Tail, error) {
+	// the file is pollable, Close wakes up a blocked Next.
+	file, err := os.Open("/dev/kmsg")
+	if err != nil {
+		return nil, err
+	}
+	if _, err := file.Seek(0, io.SeekEnd); err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	bootTime, err := getBootTime()
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	limiter := rate.NewLimiter(rate.Inf, 0)
+	if maxPerSecond > 0 {
+		limiter = rate.NewLimiter(rate.Limit(maxPerSecond), maxPerSecond)
+	}
+
+	return &Tail{
+		file:     file,
+		bootTime: bootTime,
+		limiter:  limiter,
+		buf:      make([]byte, recordMaxSize),
+	}, nil
+}
+
+// Next blocks until the next record within the rate, and returns
+// os.ErrClosed once the tail is closed.
+func (t *Tail) Next() (*Record, error) {
+	for {
+		n, err := t.file.Read(t.buf)
+		if err != nil {
+			// the ring buffer wrapped past the reader, which continues
+			// from the oldest record left.
+			if errors.Is(err, syscall.EPIPE) {
+				continue
+			}
+			return nil, err
+		}
+
+		raw := string(t.buf[:n])
+		if seq, ok := recordSeq(raw); ok {
+			if t.lastSeq != 0 && seq > t.lastSeq+1 {
+				t.lost.Add(seq - t.lastSeq - 1)
+			}
+			t.lastSeq = seq
+		}
+
+		if !t.limiter.Allow() {
+			t.dropped.Add(1)
+			continue
+		}
+
+		rec, err := ParseRecord(raw, t.bootTime)
+		if err != nil {
+			continue
+		}
+		return rec, nil
+	}
+}
+
+// Dropped returns the number of records dropped above the rate.
+func (t *Tail) Dropped() uint64 {
+	return t.dropped.Load()
+}
+
+// Lost returns the number of records overwritten before being read.
+func (t *Tail) Lost() uint64 {
+	return t.lost.Load()
+}
+
+// Close closes /dev/kmsg.
+func (t *Tail) Close() error {
+	return t.file.Close()
+}
+
+func recordSeq(raw string) (uint64, bool) {
+	_, rest, ok := strings.Cut(raw, ",")
+	if !ok {
+		return 0, false
+	}
+	seq, _, ok := strings.Cut(rest, ",")
+	if !ok {
+		return 0, false
+	}
+	n, err := strconv.ParseUint(seq, 10, 64)
+	return n, err == nil
+}
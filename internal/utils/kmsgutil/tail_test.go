@@ -0,0 +1,118 @@
+// Copyright 2026 The HuaTuo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kmsgutil
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseRecord(t *testing.T) {
+	bootTime := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	tests := []struct {
+		name    string
+		raw     string
+		want    *Record
+		wantErr bool
+	}{
+		{
+			name: "kernel error",
+			raw:  "3,1042,5000000,-;EXT4-fs error (device sda1): ext4_find_entry:1455: comm ls: reading directory lblock 0\n",
+			want: &Record{
+				Facility: "kern",
+				Severity: "err",
+				Level:    3,
+				Seq:      1042,
+				Time:     bootTime.Add(5 * time.Second),
+				Message:  "EXT4-fs error (device sda1): ext4_find_entry:1455: comm ls: reading directory lblock 0",
+			},
+		},
+		{
+			name: "dictionary",
+			raw:  "4,7,100,-;ixgbe 0000:3b:00.0 eth0: NIC Link is Down\n SUBSYSTEM=pci\n DEVICE=+pci:0000:3b:00.0\n",
+			want: &Record{
+				Facility: "kern",
+				Severity: "warning",
+				Level:    4,
+				Seq:      7,
+				Time:     bootTime.Add(100 * time.Microsecond),
+				Message:  "ixgbe 0000:3b:00.0 eth0: NIC Link is Down",
+				Fields:   map[string]string{"SUBSYSTEM": "pci", "DEVICE": "+pci:0000:3b:00.0"},
+			},
+		},
+		{
+			name: "user facility and caller",
+			raw:  "14,8,200,-,caller=T1;systemd[1]: Started session\n",
+			want: &Record{
+				Facility: "user",
+				Severity: "info",
+				Level:    6,
+				Seq:      8,
+				Time:     bootTime.Add(200 * time.Microsecond),
+				Message:  "systemd[1]: Started session",
+			},
+		},
+		{
+			name: "unknown facility",
+			raw:  "255,9,0,-;message",
+			want: &Record{
+				Facility: "unknown",
+				Severity: "debug",
+				Level:    7,
+				Seq:      9,
+				Time:     bootTime,
+				Message:  "message",
+			},
+		},
+		{name: "no message", raw: "6,1,0,-", wantErr: true},
+		{name: "short header", raw: "6,1;message", wantErr: true},
+		{name: "invalid prio", raw: "x,1,0,-;message", wantErr: true},
+		{name: "invalid timestamp", raw: "6,1,x,-;message", wantErr: true},
+	}
+
+	for i := range tests {
+		t.Run(tests[i].name, func(t *testing.T) {
+			got, err := ParseRecord(tests[i].raw, bootTime)
+			if (err != nil) != tests[i].wantErr {
+				t.Fatalf("ParseRecord() error=%v, wantErr=%v", err, tests[i].wantErr)
+			}
+			if !reflect.DeepEqual(got, tests[i].want) {
+				t.Errorf("ParseRecord()=%+v, want %+v", got, tests[i].want)
+			}
+		})
+	}
+}
+
+func TestSeverityLevel(t *testing.T) {
+	for level, name := range Severities() {
+		if got, err := SeverityLevel(name); got != level || err != nil {
+			t.Errorf("SeverityLevel(%q)=%d, %v, want %d", name, got, err, level)
+		}
+	}
+	if _, err := SeverityLevel("fatal"); err == nil {
+		t.Error("SeverityLevel(\"fatal\") error=nil")
+	}
+}
+
+func TestRecordSeq(t *testing.T) {
+	if seq, ok := recordSeq("6,1042,5000000,-;message"); seq != 1042 || !ok {
+		t.Errorf("recordSeq()=%d, %v, want 1042", seq, ok)
+	}
+	if _, ok := recordSeq("invalid"); ok {
+		t.Error("recordSeq(\"invalid\") ok=true")
+	}
+}
@@ -1,4 +1,4 @@
-// Copyright 2025 The HuaTuo Authors
+// Copyright 2025, 2026 The HuaTuo Authors
 //
 // Licensed under the Apache License, Version 2.0 (the "License");
 // you may not use this file except in compliance with the License.
@@ -110,3 +110,38 @@ func KV(path string) (string, uint64, error) {
 
 	return parseKV(scanner.Text())
 }
+
+// CPUList parses a kernel cpu list such as "0-3,8,10-11", as found in
+// cpuset.cpus or /sys/devices/system/cpu/isolated. An empty list is valid.
+func CPUList(s string) ([]int, error) {
+	var cpus []int
+
+	for _, part := range strings.Split(strings.TrimSpace(s), ",") {
+		if part == "" {
+			continue
+		}
+
+		first, last, isRange := strings.Cut(part, "-")
+		start, err := strconv.Atoi(first)
+		if err != nil {
+			return nil, fmt.Errorf("invalid cpu list %q: %w", s, err)
+		}
+
+		end := start
+		if isRange {
+			if end, err = strconv.Atoi(last); err != nil {
+				return nil, fmt.Errorf("invalid cpu list %q: %w", s, err)
+			}
+		}
+
+		if start > end {
+			return nil, fmt.Errorf("invalid cpu list %q: range %q", s, part)
+		}
+
+		for cpu := start; cpu <= end; cpu++ {
+			cpus = append(cpus, cpu)
+		}
+	}
+
+	return cpus, nil
+}
@@ -18,6 +18,7 @@ import (
 	"math"
 	"os"
 	"path/filepath"
+	"reflect"
 	"strconv"
 	"testing"
 )
@@ -217,3 +218,29 @@ func TestKV(t *testing.T) {
 		}
 	})
 }
+
+func TestCPUList(t *testing.T) {
+	tests := []struct {
+		name    string
+		list    string
+		want    []int
+		wantErr bool
+	}{
+		{"empty", "\n", nil, false},
+		{"single", "3", []int{3}, false},
+		{"mixed", "0-2,8,10-11\n", []int{0, 1, 2, 8, 10, 11}, false},
+		{"reversed range", "3-1", nil, true},
+		{"invalid", "a-b", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := CPUList(tt.list)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("CPUList() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("CPUList() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,139 @@
+// Copyright 2026 The HuaTuo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metric
+
+import (
+	"fmt"
+	"sort"
+	"sync/atomic"
+	"time"
+)
+
+// Alias exports a renamed metric under its deprecated name too, so the
+// dashboards keep working while they migrate. Both names are fully
+// qualified, e.g. huatuo_bamai_netdev_receive_bytes_total.
+type Alias struct {
+	Deprecated string
+	Name       string
+	// Until ends the transition, the deprecated name is no longer exported
+	// from then on. Zero never ends it.
+	Until time.Time
+}
+
+// AliasUsage reports an alias and the series exported under its deprecated
+// name.
+type AliasUsage struct {
+	Deprecated   string    `json:"deprecated"`
+	Name         string    `json:"name"`
+	Until        time.Time `json:"until"`
+	Expired      bool      `json:"expired"`
+	Series       uint64    `json:"series"`
+	LastExported time.Time `json:"last_exported"`
+}
+
+type aliasState struct {
+	Alias
+	series       atomic.Uint64
+	lastExported atomic.Int64
+}
+
+var (
+	// aliases are the aliases by the new name.
+	aliases atomic.Pointer[map[string]*aliasState]
+	now     = time.Now
+)
+
+// SetAliases installs the aliases, replacing the previous ones.
+func SetAliases(list []Alias) error {
+	states := make(map[string]*aliasState, len(list))
+	deprecated := make(map[string]bool, len(list))
+	for _, alias := range list {
+		if alias.Deprecated == "" || alias.Name == "" {
+			return fmt.Errorf("metric alias: empty name of %q -> %q", alias.Deprecated, alias.Name)
+		}
+		if alias.Deprecated == alias.Name {
+			return fmt.Errorf("metric alias: %s is aliased to itself", alias.Name)
+		}
+		if _, ok := states[alias.Name]; ok {
+			return fmt.Errorf("metric alias: %s has several deprecated names", alias.Name)
+		}
+		if deprecated[alias.Deprecated] {
+			return fmt.Errorf("metric alias: %s is deprecated by several names", alias.Deprecated)
+		}
+		states[alias.Name] = &aliasState{Alias: alias}
+		deprecated[alias.Deprecated] = true
+	}
+
+	// a deprecated name still in use would export the series twice.
+	for name := range states {
+		if deprecated[name] {
+			return fmt.Errorf("metric alias: %s is both deprecated and aliased", name)
+		}
+	}
+
+	if len(states) == 0 {
+		aliases.Store(nil)
+		return nil
+	}
+	aliases.Store(&states)
+	return nil
+}
+
+// lookupAlias returns the alias of a metric name, nil without an alias or
+// past its transition.
+func lookupAlias(name string) *aliasState {
+	states := aliases.Load()
+	if states == nil {
+		return nil
+	}
+
+	alias, ok := (*states)[name]
+	if !ok || (!alias.Until.IsZero() && !now().Before(alias.Until)) {
+		return nil
+	}
+	return alias
+}
+
+func (a *aliasState) exported() {
+	a.series.Add(1)
+	a.lastExported.Store(now().Unix())
+}
+
+// AliasReport returns the aliases sorted by the deprecated name, with the
+// series exported under it since they were set.
+func AliasReport() []AliasUsage {
+	states := aliases.Load()
+	if states == nil {
+		return []AliasUsage{}
+	}
+
+	t := now()
+	report := make([]AliasUsage, 0, len(*states))
+	for _, alias := range *states {
+		usage := AliasUsage{
+			Deprecated: alias.Deprecated,
+			Name:       alias.Name,
+			Until:      alias.Until,
+			Expired:    !alias.Until.IsZero() && !t.Before(alias.Until),
+			Series:     alias.series.Load(),
+		}
+		if last := alias.lastExported.Load(); last != 0 {
+			usage.LastExported = time.Unix(last, 0)
+		}
+		report = append(report, usage)
+	}
+	sort.Slice(report, func(i, j int) bool { return report[i].Deprecated < report[j].Deprecated })
+	return report
+}
@@ -0,0 +1,109 @@
+// Copyright 2026 The HuaTuo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metric
+
+import (
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func TestSetAliasesInvalid(t *testing.T) {
+	defer func() { _ = SetAliases(nil) }()
+
+	tests := []struct {
+		name    string
+		aliases []Alias
+	}{
+		{"empty name", []Alias{{Deprecated: "huatuo_bamai_cpu_old"}}},
+		{"itself", []Alias{{Deprecated: "huatuo_bamai_cpu_new", Name: "huatuo_bamai_cpu_new"}}},
+		{"several deprecated names", []Alias{
+			{Deprecated: "huatuo_bamai_cpu_a", Name: "huatuo_bamai_cpu_new"},
+			{Deprecated: "huatuo_bamai_cpu_b", Name: "huatuo_bamai_cpu_new"},
+		}},
+		{"chained", []Alias{
+			{Deprecated: "huatuo_bamai_cpu_a", Name: "huatuo_bamai_cpu_b"},
+			{Deprecated: "huatuo_bamai_cpu_b", Name: "huatuo_bamai_cpu_c"},
+		}},
+	}
+	for _, tt := range tests {
+		if err := SetAliases(tt.aliases); err == nil {
+			t.Errorf("%s: SetAliases() succeeded, want error", tt.name)
+		}
+	}
+}
+
+func TestCollectorManagerDoCollectAlias(t *testing.T) {
+	defaultRegion = "huatuo-region"
+
+	start := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+	now = func() time.Time { return start }
+	defer func() {
+		now = time.Now
+		_ = SetAliases(nil)
+	}()
+
+	if err := SetAliases([]Alias{{
+		Deprecated: "huatuo_bamai_cpu_usage_old",
+		Name:       "huatuo_bamai_cpu_usage",
+		Until:      start.AddDate(0, 1, 0),
+	}}); err != nil {
+		t.Fatalf("SetAliases() error: %v", err)
+	}
+
+	collect := func() []string {
+		mgr := newTestCollectorManager()
+		c := NewMockCollector(t)
+		c.On("Update").Return([]*Data{
+			NewGaugeData("usage", 1, "help", nil),
+			NewGaugeData("idle", 1, "help", nil),
+		}, nil).Once()
+
+		ch := make(chan prometheus.Metric, 16)
+		mgr.doCollect("cpu", &CollectorWrapper{collector: c, mu: sync.Mutex{}}, ch)
+		close(ch)
+
+		var names []string
+		for _, m := range readMetrics(ch) {
+			desc := m.Desc().String()
+			if strings.Contains(desc, "_usage") {
+				names = append(names, desc[strings.Index(desc, `"`)+1:strings.Index(desc, `", help`)])
+			}
+		}
+		return names
+	}
+
+	names := collect()
+	if len(names) != 2 || names[0] != "huatuo_bamai_cpu_usage" || names[1] != "huatuo_bamai_cpu_usage_old" {
+		t.Fatalf("collected %v, want the metric and its deprecated name", names)
+	}
+
+	report := AliasReport()
+	if len(report) != 1 || report[0].Series != 1 || report[0].Expired || !report[0].LastExported.Equal(start) {
+		t.Errorf("AliasReport() = %+v, want 1 series exported", report)
+	}
+
+	// past the transition only the new name is exported.
+	now = func() time.Time { return start.AddDate(0, 2, 0) }
+	if names := collect(); len(names) != 1 {
+		t.Errorf("collected %v after the transition, want the new name only", names)
+	}
+	if report := AliasReport(); !report[0].Expired || report[0].Series != 1 {
+		t.Errorf("AliasReport() = %+v, want expired", report)
+	}
+}
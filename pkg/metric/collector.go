@@ -18,16 +18,21 @@
 // Tracers implement the Collector interface and register with a
 // CollectorManager, which adapts them to prometheus.Collector and
 // publishes scrape duration and success metrics for each registered
-// collector.
+// collector. Counters which go backwards between scrapes are counted in
+// the <collector>_counter_resets_total companion metric and saved as a
+// metric_counter_reset event. A renamed metric is exported under its
+// deprecated name too during the transition of its Alias.
 package metric
 
 import (
 	"fmt"
 	"os"
+	"sort"
 	"sync"
 	"time"
 
 	"huatuo-bamai/internal/log"
+	"huatuo-bamai/internal/quota"
 	"huatuo-bamai/pkg/tracing"
 
 	"github.com/prometheus/client_golang/prometheus"
@@ -47,13 +52,12 @@ type Collector interface {
 type CollectorWrapper struct {
 	collector Collector
 	mu        sync.Mutex
+	resets    counterResets
 }
 
 // CollectorManager implements the prometheus.Collector interface.
 type CollectorManager struct {
 	collectors         map[string]*CollectorWrapper
-	hostname           string
-	region             string
 	scrapeDurationDesc *prometheus.Desc
 	scrapeSuccessDesc  *prometheus.Desc
 }
@@ -104,13 +108,42 @@ func NewCollectorManager(blackListed []string, region string) (*CollectorManager
 
 	return &CollectorManager{
 		collectors:         collectors,
-		hostname:           hostname,
-		region:             region,
 		scrapeDurationDesc: scrapeDurationDesc,
 		scrapeSuccessDesc:  scrapeSuccessDesc,
 	}, nil
 }
 
+// Subset returns a manager of the named collectors, sharing them with m so a
+// collector is never updated concurrently. Unknown or blacklisted names are
+// skipped.
+func (m *CollectorManager) Subset(names []string) *CollectorManager {
+	collectors := make(map[string]*CollectorWrapper, len(names))
+	for _, name := range names {
+		c, ok := m.collectors[name]
+		if !ok {
+			log.Warnf("metric collector %s is not registered, skipped", name)
+			continue
+		}
+		collectors[name] = c
+	}
+
+	return &CollectorManager{
+		collectors:         collectors,
+		scrapeDurationDesc: m.scrapeDurationDesc,
+		scrapeSuccessDesc:  m.scrapeSuccessDesc,
+	}
+}
+
+// Names returns the names of the collectors.
+func (m *CollectorManager) Names() []string {
+	names := make([]string, 0, len(m.collectors))
+	for name := range m.collectors {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
 // Describe implements the prometheus.Collector interface.
 func (m *CollectorManager) Describe(ch chan<- *prometheus.Desc) {
 	ch <- m.scrapeDurationDesc
@@ -145,6 +178,16 @@ func (m *CollectorManager) doCollect(collectorName string, c *CollectorWrapper,
 		defer c.mu.Unlock()
 
 		metrics, err = c.collector.Update()
+		if err != nil {
+			return
+		}
+
+		if resets := c.resets.observe(metrics); len(resets) > 0 {
+			saveCounterResets(collectorName, resets)
+		}
+		if companion := c.resets.companion(); companion != nil {
+			metrics = append(metrics[:len(metrics):len(metrics)], companion)
+		}
 	}()
 
 	duration := time.Since(begin)
@@ -158,12 +201,25 @@ func (m *CollectorManager) doCollect(collectorName string, c *CollectorWrapper,
 		success = 0
 	} else {
 		for _, data := range metrics {
+			name := data.fqName(collectorName)
+			namespace := data.hostNamespace()
+			if namespace != "" && !quota.AllowSeries(namespace, data.seriesID(name)) {
+				continue
+			}
 			ch <- data.prometheusMetric(collectorName)
+
+			// the deprecated name is one more series of the namespace.
+			alias := lookupAlias(name)
+			if alias != nil && (namespace == "" || quota.AllowSeries(namespace, data.seriesID(alias.Deprecated))) {
+				ch <- data.prometheusMetricNamed(alias.Deprecated, "Deprecated, renamed to "+alias.Name+". "+data.help)
+				alias.exported()
+			}
 		}
 		log.Debugf("collector %s succeeded, duration_seconds %f", collectorName, duration.Seconds())
 		success = 1
 	}
 
-	ch <- prometheus.MustNewConstMetric(m.scrapeDurationDesc, prometheus.GaugeValue, duration.Seconds(), m.hostname, m.region, collectorName)
-	ch <- prometheus.MustNewConstMetric(m.scrapeSuccessDesc, prometheus.GaugeValue, success, m.hostname, m.region, collectorName)
+	hostname, region := hostIdentity()
+	ch <- prometheus.MustNewConstMetric(m.scrapeDurationDesc, prometheus.GaugeValue, duration.Seconds(), hostname, region, collectorName)
+	ch <- prometheus.MustNewConstMetric(m.scrapeSuccessDesc, prometheus.GaugeValue, success, hostname, region, collectorName)
 }
@@ -29,8 +29,6 @@ import (
 func newTestCollectorManager() *CollectorManager {
 	return &CollectorManager{
 		collectors: make(map[string]*CollectorWrapper),
-		hostname:   "huatuo-dev",
-		region:     "huatuo-region",
 		scrapeDurationDesc: prometheus.NewDesc(
 			prometheus.BuildFQName(DefaultNamespace, "scrape", "collector_duration_seconds"),
 			"duration",
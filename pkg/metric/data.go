@@ -17,10 +17,12 @@ package metric
 import (
 	"errors"
 	"fmt"
-	"os"
+	"hash/maphash"
+	"slices"
 	"sort"
 	"sync"
 
+	"huatuo-bamai/internal/hostinfo"
 	"huatuo-bamai/internal/pod"
 
 	"github.com/prometheus/client_golang/prometheus"
@@ -50,6 +52,8 @@ const (
 	LabelHost = "host"
 	// LabelRegion indicates the data collected from.
 	LabelRegion = "region"
+	// LabelNodeName indicates the kubernetes node, only present when known.
+	LabelNodeName = "node_name"
 	// LabelContainerName indicates the container name.
 	LabelContainerName = "container_name"
 	// LabelContainerHost indicates the container host.
@@ -62,7 +66,10 @@ const (
 	LabelContainerHostNamespace = "container_hostnamespace"
 )
 
-var metricDescCache sync.Map
+var (
+	metricDescCache sync.Map
+	seriesSeed      = maphash.MakeSeed()
+)
 
 // ErrNoData indicates the collector found no data to collect, but had no other error.
 var ErrNoData = errors.New("collector returned no data")
@@ -90,15 +97,13 @@ func newData(name string, value float64, typ int, help string, label map[string]
 		help:      help,
 	}
 
-	hostname, err := os.Hostname()
-	if err != nil {
-		hostname = defaultHostname
-	}
+	hostname, region := hostIdentity()
 
 	data.labelKey = append(data.labelKey, LabelRegion, LabelHost)
 	data.labelValue = append(data.labelValue,
-		labelValue(label, LabelRegion, defaultRegion),
+		labelValue(label, LabelRegion, region),
 		labelValue(label, LabelHost, hostname))
+	data.addNodeNameLabel(label)
 
 	// sort the labelKey
 	selfLabelKeys := make([]string, 0, len(label))
@@ -170,10 +175,7 @@ func newContainerData(container *pod.Container, name string, value float64, typ
 		help:      help,
 	}
 
-	hostname, err := os.Hostname()
-	if err != nil {
-		hostname = defaultHostname
-	}
+	hostname, region := hostIdentity()
 
 	// default label
 	data.labelKey = append(data.labelKey,
@@ -185,13 +187,22 @@ func newContainerData(container *pod.Container, name string, value float64, typ
 		LabelContainerHostNamespace,
 		LabelHost)
 	data.labelValue = append(data.labelValue,
-		labelValue(label, LabelRegion, defaultRegion),
+		labelValue(label, LabelRegion, region),
 		labelValue(label, LabelContainerHost, container.Hostname),
 		labelValue(label, LabelContainerName, container.Name),
 		labelValue(label, LabelContainerType, container.Type.String()),
 		labelValue(label, LabelContainerLevel, container.Qos.String()),
 		labelValue(label, LabelContainerHostNamespace, container.LabelHostNamespace()),
 		labelValue(label, LabelHost, hostname))
+	data.addNodeNameLabel(label)
+
+	// the pod labels and annotations configured, every container has them
+	// all so the metric keeps the same labels.
+	podMetadataNames := pod.PodMetadataNames()
+	for _, name := range podMetadataNames {
+		data.labelKey = append(data.labelKey, name)
+		data.labelValue = append(data.labelValue, labelValue(label, name, container.PodMetadata[name]))
+	}
 
 	// sort the labelKey
 	selfLabelKeys := make([]string, 0, len(label))
@@ -202,7 +213,7 @@ func newContainerData(container *pod.Container, name string, value float64, typ
 
 	// add self label
 	for _, k := range selfLabelKeys {
-		if isDefaultContainerLabel(k) {
+		if isDefaultContainerLabel(k) || slices.Contains(podMetadataNames, k) {
 			continue
 		}
 		data.labelKey = append(data.labelKey, k)
@@ -212,8 +223,56 @@ func newContainerData(container *pod.Container, name string, value float64, typ
 	return data
 }
 
+// hostNamespace returns the kubernetes namespace of container data, empty
+// for the host.
+func (d *Data) hostNamespace() string {
+	for i, key := range d.labelKey {
+		if key == LabelContainerHostNamespace {
+			return d.labelValue[i]
+		}
+	}
+	return ""
+}
+
+// seriesID identifies the series of the metric name for the quotas.
+func (d *Data) seriesID(name string) uint64 {
+	var h maphash.Hash
+	h.SetSeed(seriesSeed)
+	_, _ = h.WriteString(name)
+	for _, value := range d.labelValue {
+		_ = h.WriteByte(0)
+		_, _ = h.WriteString(value)
+	}
+	return h.Sum64()
+}
+
+// hostIdentity returns the resolved hostname and region, falling back to
+// the defaults when unresolved.
+func hostIdentity() (hostname, region string) {
+	hostname, region = hostinfo.Hostname(), hostinfo.Region()
+	if hostname == "" {
+		hostname = defaultHostname
+	}
+	if region == "" {
+		region = defaultRegion
+	}
+	return hostname, region
+}
+
+// addNodeNameLabel adds the kubernetes node name next to the host, the node
+// name is fixed for the lifetime of the process.
+func (d *Data) addNodeNameLabel(label map[string]string) {
+	nodeName := labelValue(label, LabelNodeName, hostinfo.NodeName())
+	if nodeName == "" {
+		return
+	}
+
+	d.labelKey = append(d.labelKey, LabelNodeName)
+	d.labelValue = append(d.labelValue, nodeName)
+}
+
 func isDefaultHostLabel(key string) bool {
-	return key == LabelRegion || key == LabelHost
+	return key == LabelRegion || key == LabelHost || key == LabelNodeName
 }
 
 func isDefaultContainerLabel(key string) bool {
@@ -224,7 +283,8 @@ func isDefaultContainerLabel(key string) bool {
 		LabelContainerType,
 		LabelContainerLevel,
 		LabelContainerHostNamespace,
-		LabelHost:
+		LabelHost,
+		LabelNodeName:
 		return true
 	default:
 		return false
@@ -249,6 +309,15 @@ func NewContainerCounterData(container *pod.Container, name string, value float6
 
 // convert 'Data' to prometheus Metric
 func (d *Data) prometheusMetric(collector string) prometheus.Metric {
+	return d.prometheusMetricNamed(d.fqName(collector), d.help)
+}
+
+// fqName returns the fully qualified name of the metric.
+func (d *Data) fqName(collector string) string {
+	return prometheus.BuildFQName(DefaultNamespace, collector, d.name)
+}
+
+func (d *Data) prometheusMetricNamed(metricName, help string) prometheus.Metric {
 	var valueType prometheus.ValueType
 	switch d.valueType {
 	case MetricTypeGauge:
@@ -259,10 +328,9 @@ func (d *Data) prometheusMetric(collector string) prometheus.Metric {
 		return nil
 	}
 
-	metricName := prometheus.BuildFQName(DefaultNamespace, collector, d.name)
 	desc, ok := metricDescCache.Load(metricName)
 	if !ok {
-		desc = prometheus.NewDesc(metricName, d.help, d.labelKey, nil)
+		desc = prometheus.NewDesc(metricName, help, d.labelKey, nil)
 		metricDescCache.Store(metricName, desc)
 	}
 
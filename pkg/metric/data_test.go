@@ -263,6 +263,41 @@ func TestNewContainerGaugeData(t *testing.T) {
 	}
 }
 
+func TestNewContainerDataPodMetadata(t *testing.T) {
+	if err := pod.SetPodMetadata([]string{"app"}, []string{"team"}); err != nil {
+		t.Fatalf("SetPodMetadata() error = %v", err)
+	}
+	t.Cleanup(func() { _ = pod.SetPodMetadata(nil, nil) })
+
+	labels := func(d *Data) map[string]string {
+		m := make(map[string]string, len(d.labelKey))
+		for i, k := range d.labelKey {
+			m[k] = d.labelValue[i]
+		}
+		return m
+	}
+
+	container := &pod.Container{
+		Labels:      map[string]any{"HostNamespace": "ns"},
+		PodMetadata: map[string]string{"pod_label_app": "web", "pod_annotation_team": "sre"},
+	}
+	d := NewContainerGaugeData(container, "latency", 1, "", map[string]string{"k1": "v1"})
+	got := labels(d)
+	if got["pod_label_app"] != "web" || got["pod_annotation_team"] != "sre" || got["k1"] != "v1" {
+		t.Errorf("labels = %v, want pod_label_app=web, pod_annotation_team=sre, k1=v1", got)
+	}
+
+	// a container without the metadata keeps the same labels.
+	other := NewContainerGaugeData(&pod.Container{Labels: map[string]any{"HostNamespace": "ns"}},
+		"latency", 1, "", map[string]string{"k1": "v1"})
+	if len(other.labelKey) != len(d.labelKey) {
+		t.Errorf("label keys = %v, want %v", other.labelKey, d.labelKey)
+	}
+	if v, ok := labels(other)["pod_label_app"]; !ok || v != "" {
+		t.Errorf("pod_label_app = %q, %v, want empty", v, ok)
+	}
+}
+
 func TestPrometheusMetric(t *testing.T) {
 	defaultRegion = "huatuo-region"
 	metricDescCache = sync.Map{}
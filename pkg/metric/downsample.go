@@ -0,0 +1,513 @@
+// Copyright 2026 The HuaTuo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metric
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"huatuo-bamai/internal/log"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+const (
+	defaultDownsampleInterval  = 15 * time.Second
+	defaultDownsampleRetention = 7 * 24 * time.Hour
+
+	// downsampleResolution is the period of an aggregate.
+	downsampleResolution = time.Minute
+	// downsampleFileLayout names the file of the aggregates of a day, in UTC.
+	downsampleFileLayout = "2006-01-02"
+	downsampleFileExt    = ".jsonl"
+)
+
+// DownsampleConfig selects the metrics downsampled and where they are kept.
+type DownsampleConfig struct {
+	// Dir is the directory of the daily files of the aggregates.
+	Dir string
+	// Metrics are the fully qualified names of the metrics, or globs.
+	Metrics []string
+	// Interval is the period the metrics are sampled at.
+	Interval time.Duration
+	// Retention is the age the aggregates are deleted at, by whole days.
+	Retention time.Duration
+}
+
+// Aggregate is the summary of the samples of a series over one minute.
+// Counters and untyped metrics are of type "counter" and "untyped", their
+// Last is the value at the end of the minute.
+type Aggregate struct {
+	Time   time.Time         `json:"time"`
+	Name   string            `json:"name"`
+	Type   string            `json:"type"`
+	Labels map[string]string `json:"labels,omitempty"`
+	Count  int               `json:"count"`
+	Min    float64           `json:"min"`
+	Max    float64           `json:"max"`
+	Sum    float64           `json:"sum"`
+	Last   float64           `json:"last"`
+}
+
+// Value is the value the aggregate is exported with: the average of the
+// gauges, the last value of the counters.
+func (a *Aggregate) Value() float64 {
+	if a.Type == "gauge" && a.Count > 0 {
+		return a.Sum / float64(a.Count)
+	}
+	return a.Last
+}
+
+func (a *Aggregate) add(value float64) {
+	if a.Count == 0 {
+		a.Min, a.Max = value, value
+	}
+	a.Count++
+	a.Min = math.Min(a.Min, value)
+	a.Max = math.Max(a.Max, value)
+	a.Sum += value
+	a.Last = value
+}
+
+// DownsampleQuery selects the aggregates of a query. Name is a name or a
+// glob, the Labels are matched exactly, zero Since and Until are unbounded.
+type DownsampleQuery struct {
+	Name   string
+	Labels map[string]string
+	Since  time.Time
+	Until  time.Time
+	Limit  int
+}
+
+func (q *DownsampleQuery) match(a *Aggregate) bool {
+	if !q.Since.IsZero() && a.Time.Before(q.Since) {
+		return false
+	}
+	if !q.Until.IsZero() && !a.Time.Before(q.Until) {
+		return false
+	}
+	if q.Name != "" {
+		if ok, _ := path.Match(q.Name, a.Name); !ok {
+			return false
+		}
+	}
+	for k, v := range q.Labels {
+		if a.Labels[k] != v {
+			return false
+		}
+	}
+	return true
+}
+
+// Downsampler keeps the 1-minute aggregates of the selected metrics of a
+// gatherer on the local disk, for the nodes without Prometheus to scrape
+// them. The aggregates of a minute are written once it is over, those of
+// the minute in progress are lost if the agent is killed.
+type Downsampler struct {
+	cfg      DownsampleConfig
+	gatherer prometheus.Gatherer
+
+	// lock serializes the samples and the files, a query reads the files
+	// written.
+	lock   sync.Mutex
+	minute time.Time
+	series map[string]*Aggregate
+	// pruned is the day the expired files were last deleted.
+	pruned string
+}
+
+// NewDownsampler creates a downsampler of the gatherer.
+func NewDownsampler(cfg *DownsampleConfig, gatherer prometheus.Gatherer) (*Downsampler, error) {
+	if cfg.Dir == "" {
+		return nil, fmt.Errorf("downsample: no directory")
+	}
+	if len(cfg.Metrics) == 0 {
+		return nil, fmt.Errorf("downsample: no metrics")
+	}
+	for _, pattern := range cfg.Metrics {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("downsample: invalid metric %q: %w", pattern, err)
+		}
+	}
+	if err := os.MkdirAll(cfg.Dir, 0o755); err != nil {
+		return nil, fmt.Errorf("downsample: %w", err)
+	}
+
+	d := &Downsampler{cfg: *cfg, gatherer: gatherer, series: map[string]*Aggregate{}}
+	if d.cfg.Interval <= 0 {
+		d.cfg.Interval = defaultDownsampleInterval
+	}
+	if d.cfg.Retention <= 0 {
+		d.cfg.Retention = defaultDownsampleRetention
+	}
+	return d, nil
+}
+
+// Run samples the metrics every interval until ctx is done, the minute in
+// progress is written then.
+func (d *Downsampler) Run(ctx context.Context) {
+	ticker := time.NewTicker(d.cfg.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			if err := d.Flush(); err != nil {
+				log.Warnf("downsample metrics: %v", err)
+			}
+			return
+		case now := <-ticker.C:
+			if err := d.Sample(now); err != nil {
+				log.Warnf("downsample metrics: %v", err)
+			}
+		}
+	}
+}
+
+// Sample gathers the metrics once, at now. The aggregates of the previous
+// minute are written first when now is in a new one.
+func (d *Downsampler) Sample(now time.Time) error {
+	families, err := d.gatherer.Gather()
+	if err != nil {
+		// the families gathered are still sampled, as a scrape does.
+		log.Debugf("downsample gather metrics: %v", err)
+	}
+
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	var flushErr error
+	minute := now.UTC().Truncate(downsampleResolution)
+	if !minute.Equal(d.minute) {
+		flushErr = d.flushLocked()
+		d.minute = minute
+	}
+
+	for _, family := range families {
+		if !d.selected(family.GetName()) {
+			continue
+		}
+		for _, m := range family.GetMetric() {
+			typ, value, ok := sampleValue(family.GetType(), m)
+			if !ok || math.IsNaN(value) || math.IsInf(value, 0) {
+				continue
+			}
+
+			key := seriesKey(family.GetName(), m.GetLabel())
+			a, ok := d.series[key]
+			if !ok {
+				a = &Aggregate{Time: minute, Name: family.GetName(), Type: typ, Labels: labelMap(m.GetLabel())}
+				d.series[key] = a
+			}
+			a.add(value)
+		}
+	}
+	return flushErr
+}
+
+// Flush writes the aggregates of the minute in progress.
+func (d *Downsampler) Flush() error {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	return d.flushLocked()
+}
+
+func (d *Downsampler) flushLocked() error {
+	if len(d.series) == 0 {
+		return nil
+	}
+
+	aggregates := make([]*Aggregate, 0, len(d.series))
+	for _, a := range d.series {
+		aggregates = append(aggregates, a)
+	}
+	d.series = map[string]*Aggregate{}
+	sort.Slice(aggregates, func(i, j int) bool { return aggregates[i].Name < aggregates[j].Name })
+
+	day := d.minute.Format(downsampleFileLayout)
+	if err := d.appendFile(day, aggregates); err != nil {
+		return err
+	}
+
+	if day != d.pruned {
+		d.pruned = day
+		d.prune(d.minute)
+	}
+	return nil
+}
+
+func (d *Downsampler) appendFile(day string, aggregates []*Aggregate) error {
+	f, err := os.OpenFile(filepath.Join(d.cfg.Dir, day+downsampleFileExt), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return fmt.Errorf("write aggregates: %w", err)
+	}
+	defer f.Close()
+
+	w := bufio.NewWriter(f)
+	enc := json.NewEncoder(w)
+	for _, a := range aggregates {
+		if err := enc.Encode(a); err != nil {
+			return fmt.Errorf("write aggregates: %w", err)
+		}
+	}
+	if err := w.Flush(); err != nil {
+		return fmt.Errorf("write aggregates: %w", err)
+	}
+	return nil
+}
+
+// prune deletes the files of the days older than the retention.
+func (d *Downsampler) prune(now time.Time) {
+	days, err := d.days()
+	if err != nil {
+		log.Warnf("downsample prune: %v", err)
+		return
+	}
+
+	oldest := now.Add(-d.cfg.Retention).Truncate(24 * time.Hour)
+	for _, day := range days {
+		if !day.Before(oldest) {
+			break
+		}
+		name := filepath.Join(d.cfg.Dir, day.Format(downsampleFileLayout)+downsampleFileExt)
+		if err := os.Remove(name); err != nil {
+			log.Warnf("downsample prune: %v", err)
+		}
+	}
+}
+
+// days returns the days of the files, the oldest first.
+func (d *Downsampler) days() ([]time.Time, error) {
+	entries, err := os.ReadDir(d.cfg.Dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var days []time.Time
+	for _, entry := range entries {
+		name, ok := strings.CutSuffix(entry.Name(), downsampleFileExt)
+		if !ok || !entry.Type().IsRegular() {
+			continue
+		}
+		day, err := time.Parse(downsampleFileLayout, name)
+		if err != nil {
+			continue
+		}
+		days = append(days, day)
+	}
+	sort.Slice(days, func(i, j int) bool { return days[i].Before(days[j]) })
+	return days, nil
+}
+
+// Query returns the aggregates of the minutes written matching q, the
+// oldest first.
+func (d *Downsampler) Query(q *DownsampleQuery) ([]*Aggregate, error) {
+	var aggregates []*Aggregate
+	err := d.scan(q, func(a *Aggregate) bool {
+		aggregates = append(aggregates, a)
+		return q.Limit <= 0 || len(aggregates) < q.Limit
+	})
+	return aggregates, err
+}
+
+// Export writes the aggregates matching q in the OpenMetrics text format,
+// one sample a minute of the value of the aggregate, for promtool to
+// backfill a Prometheus with once the node is connected again:
+//
+//	promtool tsdb create-blocks-from openmetrics <file> <data dir>
+//
+// The counters are exported as unknown, their samples keep the name of
+// the family.
+func (d *Downsampler) Export(w io.Writer, q *DownsampleQuery) error {
+	type sample struct {
+		labels string
+		*Aggregate
+	}
+
+	families := map[string][]sample{}
+	err := d.scan(q, func(a *Aggregate) bool {
+		families[a.Name] = append(families[a.Name], sample{labels: openMetricsLabels(a.Labels), Aggregate: a})
+		return true
+	})
+	if err != nil {
+		return err
+	}
+
+	names := make([]string, 0, len(families))
+	for name := range families {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	bw := bufio.NewWriter(w)
+	for _, name := range names {
+		samples := families[name]
+		typ := "unknown"
+		if samples[0].Type == "gauge" {
+			typ = "gauge"
+		}
+		fmt.Fprintf(bw, "# TYPE %s %s\n", name, typ)
+
+		// the samples of a series are together, in order of time.
+		sort.SliceStable(samples, func(i, j int) bool { return samples[i].labels < samples[j].labels })
+		for _, s := range samples {
+			fmt.Fprintf(bw, "%s%s %s %d\n", name, s.labels, formatFloat(s.Value()), s.Time.Unix())
+		}
+	}
+	fmt.Fprintln(bw, "# EOF")
+	return bw.Flush()
+}
+
+// scan calls fn with the aggregates matching q, the oldest first, until it
+// returns false.
+func (d *Downsampler) scan(q *DownsampleQuery, fn func(*Aggregate) bool) error {
+	days, err := d.days()
+	if err != nil {
+		return fmt.Errorf("read aggregates: %w", err)
+	}
+
+	for _, day := range days {
+		if !q.Since.IsZero() && !day.Add(24*time.Hour).After(q.Since) {
+			continue
+		}
+		if !q.Until.IsZero() && !day.Before(q.Until) {
+			break
+		}
+
+		more, err := d.scanFile(filepath.Join(d.cfg.Dir, day.Format(downsampleFileLayout)+downsampleFileExt), q, fn)
+		if err != nil {
+			return err
+		}
+		if !more {
+			return nil
+		}
+	}
+	return nil
+}
+
+func (d *Downsampler) scanFile(name string, q *DownsampleQuery, fn func(*Aggregate) bool) (bool, error) {
+	// the file of the day is appended to by the samples.
+	d.lock.Lock()
+	data, err := os.ReadFile(name)
+	d.lock.Unlock()
+	if err != nil {
+		if os.IsNotExist(err) {
+			return true, nil
+		}
+		return false, fmt.Errorf("read aggregates: %w", err)
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	for {
+		a := &Aggregate{}
+		if err := dec.Decode(a); err != nil {
+			if err == io.EOF {
+				return true, nil
+			}
+			// e.g. the last line of an agent killed while writing it.
+			log.Debugf("downsample read %s: %v", name, err)
+			return true, nil
+		}
+		if q.match(a) && !fn(a) {
+			return false, nil
+		}
+	}
+}
+
+func (d *Downsampler) selected(name string) bool {
+	for _, pattern := range d.cfg.Metrics {
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// sampleValue returns the value of the counters, gauges and untyped
+// metrics, the histograms and summaries are not downsampled.
+func sampleValue(typ dto.MetricType, m *dto.Metric) (string, float64, bool) {
+	switch typ {
+	case dto.MetricType_COUNTER:
+		return "counter", m.GetCounter().GetValue(), true
+	case dto.MetricType_GAUGE:
+		return "gauge", m.GetGauge().GetValue(), true
+	case dto.MetricType_UNTYPED:
+		return "untyped", m.GetUntyped().GetValue(), true
+	}
+	return "", 0, false
+}
+
+func seriesKey(name string, pairs []*dto.LabelPair) string {
+	var b strings.Builder
+	b.WriteString(name)
+	for _, p := range pairs {
+		b.WriteByte(0)
+		b.WriteString(p.GetName())
+		b.WriteByte(0)
+		b.WriteString(p.GetValue())
+	}
+	return b.String()
+}
+
+func labelMap(pairs []*dto.LabelPair) map[string]string {
+	if len(pairs) == 0 {
+		return nil
+	}
+	labels := make(map[string]string, len(pairs))
+	for _, p := range pairs {
+		labels[p.GetName()] = p.GetValue()
+	}
+	return labels
+}
+
+var openMetricsEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// openMetricsLabels formats the labels sorted by name, empty without any.
+func openMetricsLabels(labels map[string]string) string {
+	if len(labels) == 0 {
+		return ""
+	}
+
+	names := make([]string, 0, len(labels))
+	for name := range labels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	b.WriteByte('{')
+	for i, name := range names {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		fmt.Fprintf(&b, `%s="%s"`, name, openMetricsEscaper.Replace(labels[name]))
+	}
+	b.WriteByte('}')
+	return b.String()
+}
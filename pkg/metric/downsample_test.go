@@ -0,0 +1,105 @@
+// Copyright 2026 The HuaTuo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metric
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDownsampler(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	load := prometheus.NewGaugeVec(prometheus.GaugeOpts{Name: "huatuo_bamai_load"}, []string{"cpu"})
+	drops := prometheus.NewCounter(prometheus.CounterOpts{Name: "huatuo_bamai_drops_total"})
+	ignored := prometheus.NewGauge(prometheus.GaugeOpts{Name: "huatuo_bamai_ignored"})
+	reg.MustRegister(load, drops, ignored)
+
+	dir := t.TempDir()
+	d, err := NewDownsampler(&DownsampleConfig{
+		Dir:       dir,
+		Metrics:   []string{"huatuo_bamai_load", "huatuo_bamai_drops_*"},
+		Retention: 2 * 24 * time.Hour,
+	}, reg)
+	require.NoError(t, err)
+
+	// an expired file, and one not of the downsampler.
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "2026-01-01.jsonl"), nil, 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "notes.txt"), nil, 0o644))
+
+	start := time.Date(2026, 1, 10, 23, 59, 0, 0, time.UTC)
+	for i, v := range []float64{1, 3, 8} {
+		load.WithLabelValues("0").Set(v)
+		load.WithLabelValues("1").Set(10 * v)
+		drops.Add(v)
+		ignored.Set(v)
+		require.NoError(t, d.Sample(start.Add(time.Duration(i)*20*time.Second)))
+	}
+	// the next minute, of the next day, writes the first.
+	load.WithLabelValues("0").Set(2)
+	require.NoError(t, d.Sample(start.Add(time.Minute)))
+	require.NoError(t, d.Flush())
+
+	_, err = os.Stat(filepath.Join(dir, "2026-01-01.jsonl"))
+	require.True(t, os.IsNotExist(err), "expired file kept")
+	_, err = os.Stat(filepath.Join(dir, "notes.txt"))
+	require.NoError(t, err)
+
+	all, err := d.Query(&DownsampleQuery{})
+	require.NoError(t, err)
+	require.Len(t, all, 6)
+
+	got, err := d.Query(&DownsampleQuery{Name: "huatuo_bamai_load", Labels: map[string]string{"cpu": "0"}})
+	require.NoError(t, err)
+	require.Len(t, got, 2)
+	require.Equal(t, Aggregate{
+		Time: start, Name: "huatuo_bamai_load", Type: "gauge", Labels: map[string]string{"cpu": "0"},
+		Count: 3, Min: 1, Max: 8, Sum: 12, Last: 8,
+	}, *got[0])
+	require.Equal(t, 4.0, got[0].Value())
+	require.Equal(t, start.Add(time.Minute), got[1].Time)
+
+	got, err = d.Query(&DownsampleQuery{Since: start.Add(time.Minute), Limit: 1})
+	require.NoError(t, err)
+	require.Len(t, got, 1)
+	require.Equal(t, start.Add(time.Minute), got[0].Time)
+
+	var b strings.Builder
+	require.NoError(t, d.Export(&b, &DownsampleQuery{Until: start.Add(time.Minute)}))
+	require.Equal(t, `# TYPE huatuo_bamai_drops_total unknown
+huatuo_bamai_drops_total 12 1768089540
+# TYPE huatuo_bamai_load gauge
+huatuo_bamai_load{cpu="0"} 4 1768089540
+huatuo_bamai_load{cpu="1"} 40 1768089540
+# EOF
+`, b.String())
+}
+
+func TestNewDownsamplerInvalid(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	for _, cfg := range []*DownsampleConfig{
+		{Metrics: []string{"huatuo_bamai_load"}},
+		{Dir: t.TempDir()},
+		{Dir: t.TempDir(), Metrics: []string{"huatuo_bamai_[load"}},
+	} {
+		_, err := NewDownsampler(cfg, reg)
+		require.Error(t, err, "%+v", cfg)
+	}
+}
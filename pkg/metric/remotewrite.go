@@ -0,0 +1,412 @@
+// Copyright 2026 The HuaTuo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metric
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"huatuo-bamai/internal/log"
+
+	"github.com/cloudflare/backoff"
+	"github.com/golang/snappy"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/model"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+const (
+	defaultRemoteWriteInterval  = 30 * time.Second
+	defaultRemoteWriteTimeout   = 10 * time.Second
+	defaultRemoteWriteBatchSize = 2000
+
+	// remoteWriteRetries is the attempts of a batch, the samples are
+	// dropped after the last one. The waits between them grow from
+	// remoteWriteMinBackoff.
+	remoteWriteRetries    = 3
+	remoteWriteMinBackoff = 500 * time.Millisecond
+	remoteWriteMaxBackoff = 5 * time.Second
+)
+
+// errRemoteWriteRetryable marks the errors worth a retry: the network, the
+// rate limit and the server errors. The other client errors, e.g. an
+// out of order sample, fail again.
+var errRemoteWriteRetryable = errors.New("retryable")
+
+// RemoteWriteConfig is the endpoint the metrics are pushed to.
+type RemoteWriteConfig struct {
+	URL                string
+	Username, Password string
+	BearerToken        string
+	Headers            map[string]string
+	// ExternalLabels are added to every series which does not have them.
+	ExternalLabels     map[string]string
+	CAFile             string
+	CertFile           string
+	KeyFile            string
+	InsecureSkipVerify bool
+	Timeout            time.Duration
+	Interval           time.Duration
+	// BatchSize is the samples sent in one request.
+	BatchSize int
+}
+
+// RemoteWriter pushes the metrics of a gatherer to a Prometheus remote
+// write endpoint on an interval, the same samples as a scrape of /metrics.
+// Nothing is buffered on disk, a batch failing after the retries is
+// dropped and the next push carries the current values.
+type RemoteWriter struct {
+	cfg      RemoteWriteConfig
+	gatherer prometheus.Gatherer
+	client   *http.Client
+	samples  *prometheus.CounterVec
+}
+
+// NewRemoteWriter creates a remote writer of the gatherer.
+func NewRemoteWriter(cfg *RemoteWriteConfig, gatherer prometheus.Gatherer) (*RemoteWriter, error) {
+	u, err := url.Parse(cfg.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("remote write: invalid url %q", cfg.URL)
+	}
+	if cfg.BearerToken != "" && cfg.Username != "" {
+		return nil, fmt.Errorf("remote write: basic auth and bearer token are exclusive")
+	}
+	for name := range cfg.ExternalLabels {
+		if !model.LabelName(name).IsValidLegacy() {
+			return nil, fmt.Errorf("remote write: invalid external label %q", name)
+		}
+	}
+
+	tlsConfig, err := remoteWriteTLSConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	w := &RemoteWriter{
+		cfg:      *cfg,
+		gatherer: gatherer,
+		client: &http.Client{Transport: &http.Transport{
+			MaxIdleConns:        4,
+			MaxIdleConnsPerHost: 2,
+			IdleConnTimeout:     90 * time.Second,
+			DialContext: (&net.Dialer{
+				Timeout:   10 * time.Second,
+				KeepAlive: 30 * time.Second,
+			}).DialContext,
+			TLSClientConfig: tlsConfig,
+		}},
+		samples: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: DefaultNamespace,
+			Name:      "remote_write_samples_total",
+			Help:      "The samples pushed to the remote write endpoint, by result.",
+		}, []string{"result"}),
+	}
+	if w.cfg.Timeout <= 0 {
+		w.cfg.Timeout = defaultRemoteWriteTimeout
+	}
+	if w.cfg.Interval <= 0 {
+		w.cfg.Interval = defaultRemoteWriteInterval
+	}
+	if w.cfg.BatchSize <= 0 {
+		w.cfg.BatchSize = defaultRemoteWriteBatchSize
+	}
+	return w, nil
+}
+
+func remoteWriteTLSConfig(cfg *RemoteWriteConfig) (*tls.Config, error) {
+	tlsConfig := &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		InsecureSkipVerify: cfg.InsecureSkipVerify, // #nosec G402
+	}
+	if cfg.CAFile != "" {
+		raw, err := os.ReadFile(cfg.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("remote write: read ca file: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(raw) {
+			return nil, fmt.Errorf("remote write: no certificate in ca file %s", cfg.CAFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+	if cfg.CertFile != "" || cfg.KeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(cfg.CertFile, cfg.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("remote write: load client certificate: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+	return tlsConfig, nil
+}
+
+// Describe implements prometheus.Collector, the writer exports the samples
+// it pushed.
+func (w *RemoteWriter) Describe(ch chan<- *prometheus.Desc) {
+	w.samples.Describe(ch)
+}
+
+// Collect implements prometheus.Collector.
+func (w *RemoteWriter) Collect(ch chan<- prometheus.Metric) {
+	w.samples.Collect(ch)
+}
+
+// Run pushes the metrics every interval until ctx is done.
+func (w *RemoteWriter) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.cfg.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if err := w.Push(ctx); err != nil {
+			log.Warnf("remote write metrics: %v", err)
+		}
+	}
+}
+
+// Push gathers and pushes the metrics once, in batches of BatchSize
+// samples. It returns the error of the last batch failing.
+func (w *RemoteWriter) Push(ctx context.Context) error {
+	families, err := w.gatherer.Gather()
+	if err != nil {
+		// the families gathered are still pushed, as a scrape does.
+		log.Debugf("remote write gather metrics: %v", err)
+	}
+
+	series := convertSeries(families, w.cfg.ExternalLabels, time.Now())
+	var lastErr error
+	for start := 0; start < len(series); start += w.cfg.BatchSize {
+		batch := series[start:min(start+w.cfg.BatchSize, len(series))]
+		if err := w.pushBatch(ctx, batch); err != nil {
+			w.samples.WithLabelValues("dropped").Add(float64(len(batch)))
+			lastErr = fmt.Errorf("push %d samples: %w", len(batch), err)
+			continue
+		}
+		w.samples.WithLabelValues("sent").Add(float64(len(batch)))
+	}
+	return lastErr
+}
+
+// pushBatch sends one batch, backing off on the errors worth a retry.
+func (w *RemoteWriter) pushBatch(ctx context.Context, batch []remoteSeries) error {
+	body := snappy.Encode(nil, encodeWriteRequest(batch))
+
+	var err error
+	b := backoff.New(remoteWriteMaxBackoff, remoteWriteMinBackoff)
+	for attempt := 0; attempt < remoteWriteRetries; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-ctx.Done():
+				return err
+			case <-time.After(b.Duration()):
+			}
+		}
+		if err = w.send(ctx, body); err == nil || !errors.Is(err, errRemoteWriteRetryable) {
+			return err
+		}
+	}
+	return err
+}
+
+func (w *RemoteWriter) send(ctx context.Context, body []byte) error {
+	ctx, cancel := context.WithTimeout(ctx, w.cfg.Timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.cfg.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	for k, v := range w.cfg.Headers {
+		req.Header.Set(k, v)
+	}
+	req.Header.Set("Content-Encoding", "snappy")
+	req.Header.Set("Content-Type", "application/x-protobuf")
+	req.Header.Set("User-Agent", "huatuo-bamai")
+	req.Header.Set("X-Prometheus-Remote-Write-Version", "0.1.0")
+	if w.cfg.Username != "" {
+		req.SetBasicAuth(w.cfg.Username, w.cfg.Password)
+	} else if w.cfg.BearerToken != "" {
+		req.Header.Set("Authorization", "Bearer "+w.cfg.BearerToken)
+	}
+
+	res, err := w.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("%w: %w", errRemoteWriteRetryable, err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode/100 == 2 {
+		_, err = io.Copy(io.Discard, res.Body)
+		return err
+	}
+
+	msg, _ := io.ReadAll(io.LimitReader(res.Body, 4096))
+	err = fmt.Errorf("status %d: %s", res.StatusCode, strings.TrimSpace(string(msg)))
+	if res.StatusCode == http.StatusTooManyRequests || res.StatusCode >= 500 {
+		return fmt.Errorf("%w: %w", errRemoteWriteRetryable, err)
+	}
+	return err
+}
+
+type remoteLabel struct {
+	name, value string
+}
+
+// remoteSeries is a series of one sample, its labels sorted by name.
+type remoteSeries struct {
+	labels    []remoteLabel
+	value     float64
+	timestamp int64
+}
+
+// convertSeries converts the metric families to series: counters, gauges
+// and untyped to one series, histograms to their _bucket, _sum and _count
+// series and summaries to their quantile, _sum and _count series, as
+// Prometheus does when it scrapes them.
+func convertSeries(families []*dto.MetricFamily, external map[string]string, now time.Time) []remoteSeries {
+	var series []remoteSeries
+	for _, family := range families {
+		name := family.GetName()
+		for _, m := range family.GetMetric() {
+			ts := now.UnixMilli()
+			if m.TimestampMs != nil {
+				ts = m.GetTimestampMs()
+			}
+			add := func(name string, value float64, extra ...remoteLabel) {
+				series = append(series, remoteSeries{
+					labels:    seriesLabels(name, m.GetLabel(), external, extra),
+					value:     value,
+					timestamp: ts,
+				})
+			}
+
+			switch family.GetType() {
+			case dto.MetricType_COUNTER:
+				add(name, m.GetCounter().GetValue())
+			case dto.MetricType_GAUGE:
+				add(name, m.GetGauge().GetValue())
+			case dto.MetricType_UNTYPED:
+				add(name, m.GetUntyped().GetValue())
+			case dto.MetricType_HISTOGRAM:
+				h := m.GetHistogram()
+				infSeen := false
+				for _, b := range h.GetBucket() {
+					if math.IsInf(b.GetUpperBound(), 1) {
+						infSeen = true
+					}
+					add(name+"_bucket", float64(b.GetCumulativeCount()),
+						remoteLabel{"le", formatFloat(b.GetUpperBound())})
+				}
+				if !infSeen {
+					add(name+"_bucket", float64(h.GetSampleCount()), remoteLabel{"le", "+Inf"})
+				}
+				add(name+"_sum", h.GetSampleSum())
+				add(name+"_count", float64(h.GetSampleCount()))
+			case dto.MetricType_SUMMARY:
+				s := m.GetSummary()
+				for _, q := range s.GetQuantile() {
+					add(name, q.GetValue(), remoteLabel{"quantile", formatFloat(q.GetQuantile())})
+				}
+				add(name+"_sum", s.GetSampleSum())
+				add(name+"_count", float64(s.GetSampleCount()))
+			}
+		}
+	}
+	return series
+}
+
+func seriesLabels(name string, pairs []*dto.LabelPair, external map[string]string, extra []remoteLabel) []remoteLabel {
+	labels := make([]remoteLabel, 0, 1+len(pairs)+len(extra)+len(external))
+	labels = append(labels, remoteLabel{"__name__", name})
+	seen := make(map[string]bool, len(pairs)+len(extra))
+	for _, p := range pairs {
+		labels = append(labels, remoteLabel{p.GetName(), p.GetValue()})
+		seen[p.GetName()] = true
+	}
+	for _, l := range extra {
+		labels = append(labels, l)
+		seen[l.name] = true
+	}
+	for k, v := range external {
+		if !seen[k] {
+			labels = append(labels, remoteLabel{k, v})
+		}
+	}
+
+	sort.Slice(labels, func(i, j int) bool { return labels[i].name < labels[j].name })
+	return labels
+}
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+// encodeWriteRequest encodes the series as a prometheus.WriteRequest of
+// the remote write 1.0 protocol:
+//
+//	WriteRequest { repeated TimeSeries timeseries = 1; }
+//	TimeSeries   { repeated Label labels = 1; repeated Sample samples = 2; }
+//	Label        { string name = 1; string value = 2; }
+//	Sample       { double value = 1; int64 timestamp = 2; }
+func encodeWriteRequest(series []remoteSeries) []byte {
+	var buf, ts, msg []byte
+	for i := range series {
+		ts = ts[:0]
+		for _, l := range series[i].labels {
+			msg = protowire.AppendTag(msg[:0], 1, protowire.BytesType)
+			msg = protowire.AppendString(msg, l.name)
+			msg = protowire.AppendTag(msg, 2, protowire.BytesType)
+			msg = protowire.AppendString(msg, l.value)
+			ts = protowire.AppendTag(ts, 1, protowire.BytesType)
+			ts = protowire.AppendBytes(ts, msg)
+		}
+
+		msg = protowire.AppendTag(msg[:0], 1, protowire.Fixed64Type)
+		msg = protowire.AppendFixed64(msg, math.Float64bits(series[i].value))
+		msg = protowire.AppendTag(msg, 2, protowire.VarintType)
+		msg = protowire.AppendVarint(msg, uint64(series[i].timestamp))
+		ts = protowire.AppendTag(ts, 2, protowire.BytesType)
+		ts = protowire.AppendBytes(ts, msg)
+
+		buf = protowire.AppendTag(buf, 1, protowire.BytesType)
+		buf = protowire.AppendBytes(buf, ts)
+	}
+	return buf
+}
@@ -0,0 +1,203 @@
+// Copyright 2026 The HuaTuo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metric
+
+import (
+	"io"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/golang/snappy"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// decodeWriteRequest decodes the series of a WriteRequest as
+// "labels value" lines, the labels as name=value joined by commas.
+func decodeWriteRequest(t *testing.T, body []byte) []string {
+	t.Helper()
+
+	fields := func(b []byte, visit func(num protowire.Number, typ protowire.Type, v []byte, n uint64)) {
+		for len(b) > 0 {
+			num, typ, n := protowire.ConsumeTag(b)
+			require.GreaterOrEqual(t, n, 0)
+			b = b[n:]
+			switch typ {
+			case protowire.BytesType:
+				v, n := protowire.ConsumeBytes(b)
+				require.GreaterOrEqual(t, n, 0)
+				visit(num, typ, v, 0)
+				b = b[n:]
+			case protowire.Fixed64Type:
+				v, n := protowire.ConsumeFixed64(b)
+				require.GreaterOrEqual(t, n, 0)
+				visit(num, typ, nil, v)
+				b = b[n:]
+			case protowire.VarintType:
+				v, n := protowire.ConsumeVarint(b)
+				require.GreaterOrEqual(t, n, 0)
+				visit(num, typ, nil, v)
+				b = b[n:]
+			default:
+				t.Fatalf("unexpected wire type %d", typ)
+			}
+		}
+	}
+
+	var lines []string
+	fields(body, func(_ protowire.Number, _ protowire.Type, ts []byte, _ uint64) {
+		var labels []string
+		var value float64
+		fields(ts, func(num protowire.Number, _ protowire.Type, msg []byte, _ uint64) {
+			if num == 1 {
+				var name, val string
+				fields(msg, func(num protowire.Number, _ protowire.Type, v []byte, _ uint64) {
+					if num == 1 {
+						name = string(v)
+					} else {
+						val = string(v)
+					}
+				})
+				labels = append(labels, name+"="+val)
+				return
+			}
+			fields(msg, func(num protowire.Number, _ protowire.Type, _ []byte, v uint64) {
+				if num == 1 {
+					value = math.Float64frombits(v)
+				}
+			})
+		})
+		lines = append(lines, strings.Join(labels, ",")+" "+formatFloat(value))
+	})
+	sort.Strings(lines)
+	return lines
+}
+
+func remoteWriteSamples(t *testing.T, writer *RemoteWriter, result string) float64 {
+	t.Helper()
+
+	var m dto.Metric
+	require.NoError(t, writer.samples.WithLabelValues(result).Write(&m))
+	return m.GetCounter().GetValue()
+}
+
+func newRemoteWriteRegistry() *prometheus.Registry {
+	reg := prometheus.NewRegistry()
+	counter := prometheus.NewCounterVec(prometheus.CounterOpts{Name: "events_total", Help: "h"}, []string{"host"})
+	counter.WithLabelValues("node-1").Add(3)
+	histogram := prometheus.NewHistogram(prometheus.HistogramOpts{Name: "latency_seconds", Help: "h", Buckets: []float64{0.5}})
+	histogram.Observe(0.1)
+	histogram.Observe(2)
+	reg.MustRegister(counter, histogram)
+	return reg
+}
+
+func TestRemoteWriterPush(t *testing.T) {
+	var (
+		mu     sync.Mutex
+		bodies [][]byte
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, pass, ok := r.BasicAuth()
+		if !ok || user != "u" || pass != "p" || r.Header.Get("Content-Encoding") != "snappy" ||
+			r.Header.Get("X-Prometheus-Remote-Write-Version") != "0.1.0" || r.Header.Get("X-Tenant") != "t1" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		raw, _ := io.ReadAll(r.Body)
+		body, err := snappy.Decode(nil, raw)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		mu.Lock()
+		bodies = append(bodies, body)
+		mu.Unlock()
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	writer, err := NewRemoteWriter(&RemoteWriteConfig{
+		URL:            server.URL + "/api/v1/write",
+		Username:       "u",
+		Password:       "p",
+		Headers:        map[string]string{"X-Tenant": "t1"},
+		ExternalLabels: map[string]string{"cluster": "c1", "host": "ignored"},
+		BatchSize:      4,
+	}, newRemoteWriteRegistry())
+	require.NoError(t, err)
+
+	require.NoError(t, writer.Push(t.Context()))
+
+	// 5 samples in batches of 4.
+	require.Len(t, bodies, 2)
+	var lines []string
+	for _, body := range bodies {
+		lines = append(lines, decodeWriteRequest(t, body)...)
+	}
+	sort.Strings(lines)
+	require.Equal(t, []string{
+		"__name__=events_total,cluster=c1,host=node-1 3",
+		"__name__=latency_seconds_bucket,cluster=c1,host=ignored,le=+Inf 2",
+		"__name__=latency_seconds_bucket,cluster=c1,host=ignored,le=0.5 1",
+		"__name__=latency_seconds_count,cluster=c1,host=ignored 2",
+		"__name__=latency_seconds_sum,cluster=c1,host=ignored 2.1",
+	}, lines)
+	require.Equal(t, 5.0, remoteWriteSamples(t, writer, "sent"))
+}
+
+func TestRemoteWriterRetry(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch requests.Add(1) {
+		case 1:
+			w.WriteHeader(http.StatusServiceUnavailable)
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	}))
+	defer server.Close()
+
+	writer, err := NewRemoteWriter(&RemoteWriteConfig{URL: server.URL, Timeout: time.Second}, newRemoteWriteRegistry())
+	require.NoError(t, err)
+
+	// the 503 is retried, the 400 is not.
+	require.Error(t, writer.Push(t.Context()))
+	require.Equal(t, int32(2), requests.Load())
+	require.Equal(t, 5.0, remoteWriteSamples(t, writer, "dropped"))
+}
+
+func TestNewRemoteWriterInvalid(t *testing.T) {
+	for name, cfg := range map[string]RemoteWriteConfig{
+		"no url":         {},
+		"no scheme":      {URL: "127.0.0.1:9090/api/v1/write"},
+		"auth conflict":  {URL: "http://127.0.0.1:9090", Username: "u", BearerToken: "t"},
+		"external label": {URL: "http://127.0.0.1:9090", ExternalLabels: map[string]string{"bad-name": "v"}},
+		"ca file":        {URL: "https://127.0.0.1:9090", CAFile: "/nonexistent/ca.pem"},
+	} {
+		if _, err := NewRemoteWriter(&cfg, prometheus.NewRegistry()); err == nil {
+			t.Errorf("%s: NewRemoteWriter() error = nil", name)
+		}
+	}
+}
@@ -0,0 +1,133 @@
+// Copyright 2026 The HuaTuo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metric
+
+import (
+	"time"
+
+	"huatuo-bamai/internal/log"
+	"huatuo-bamai/pkg/tracing"
+)
+
+const (
+	counterResetTracerName = "metric_counter_reset"
+	// a driver or nic reset restarts all of its counters at once, keep the
+	// event small.
+	counterResetMaxSeries = 16
+)
+
+// CounterReset is one counter series which went backwards.
+type CounterReset struct {
+	Metric   string            `json:"metric"`
+	Labels   map[string]string `json:"labels"`
+	Previous float64           `json:"previous"`
+	Current  float64           `json:"current"`
+}
+
+// CounterResetEvent is saved once per scrape of a collector whose counters
+// went backwards, it marks where rate() over these series is not reliable.
+type CounterResetEvent struct {
+	Collector string         `json:"collector"`
+	Count     int            `json:"count"`
+	Series    []CounterReset `json:"series"`
+}
+
+// counterResets detects counters which went backwards between two scrapes
+// of a collector, which means the driver or device behind them restarted.
+type counterResets struct {
+	// last is updated in place by the scrapes, keyed by the series id.
+	last map[uint64]counterSample
+	// generation is the number of the last scrape.
+	generation uint64
+	total      float64
+}
+
+// counterSample is the value of a counter series at the scrape generation.
+type counterSample struct {
+	value      float64
+	generation uint64
+}
+
+// observe records the counters of this scrape and returns the series which
+// regressed. Series missing from the scrape are forgotten.
+func (r *counterResets) observe(metrics []*Data) []CounterReset {
+	var resets []CounterReset
+
+	if r.last == nil {
+		r.last = make(map[uint64]counterSample)
+	}
+	r.generation++
+
+	for _, d := range metrics {
+		if d.valueType != MetricTypeCounter {
+			continue
+		}
+
+		id := d.seriesID(d.name)
+		if prev, ok := r.last[id]; ok && prev.generation != r.generation && d.Value < prev.value {
+			labels := make(map[string]string, len(d.labelKey))
+			for i, k := range d.labelKey {
+				labels[k] = d.labelValue[i]
+			}
+			resets = append(resets, CounterReset{
+				Metric:   d.name,
+				Labels:   labels,
+				Previous: prev.value,
+				Current:  d.Value,
+			})
+		}
+		r.last[id] = counterSample{value: d.Value, generation: r.generation}
+	}
+
+	for id, sample := range r.last {
+		if sample.generation != r.generation {
+			delete(r.last, id)
+		}
+	}
+
+	r.total += float64(len(resets))
+	return resets
+}
+
+// companion returns the resets counter of the collector, nil while the
+// collector has not exported any counter.
+func (r *counterResets) companion() *Data {
+	if len(r.last) == 0 && r.total == 0 {
+		return nil
+	}
+	return NewCounterData("counter_resets_total", r.total,
+		"Counter series of this collector which went backwards, e.g. after a driver or device reset.", nil)
+}
+
+func saveCounterResets(collector string, resets []CounterReset) {
+	event := &CounterResetEvent{
+		Collector: collector,
+		Count:     len(resets),
+		Series:    resets,
+	}
+	if len(event.Series) > counterResetMaxSeries {
+		event.Series = event.Series[:counterResetMaxSeries]
+	}
+
+	log.Infof("collector %s: %d counters went backwards", collector, len(resets))
+
+	if err := tracing.Save(&tracing.WriteRequest{
+		TracerName: counterResetTracerName,
+		TracerTime: time.Now(),
+		TracerData: event,
+	}); err != nil {
+		log.Warnf("failed to save tracing data: %v", err)
+	}
+}
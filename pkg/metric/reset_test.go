@@ -0,0 +1,73 @@
+// Copyright 2026 The HuaTuo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metric
+
+import "testing"
+
+func TestCounterResetsObserve(t *testing.T) {
+	defaultRegion = "huatuo-region"
+
+	scrape := func(rx, tx float64) []*Data {
+		return []*Data{
+			NewCounterData("bytes_total", rx, "help", map[string]string{"dir": "rx"}),
+			NewCounterData("bytes_total", tx, "help", map[string]string{"dir": "tx"}),
+			NewGaugeData("temperature", 1, "help", nil),
+		}
+	}
+
+	var r counterResets
+	if c := r.companion(); c != nil {
+		t.Fatalf("companion() before any counter = %v, want nil", c)
+	}
+
+	if resets := r.observe(scrape(100, 200)); len(resets) != 0 {
+		t.Fatalf("first scrape resets = %v, want none", resets)
+	}
+	if resets := r.observe(scrape(150, 250)); len(resets) != 0 {
+		t.Fatalf("increasing counters resets = %v, want none", resets)
+	}
+
+	resets := r.observe(scrape(10, 260))
+	if len(resets) != 1 {
+		t.Fatalf("resets = %v, want 1", resets)
+	}
+	if resets[0].Metric != "bytes_total" || resets[0].Labels["dir"] != "rx" ||
+		resets[0].Previous != 150 || resets[0].Current != 10 {
+		t.Errorf("reset = %+v, want bytes_total rx 150 -> 10", resets[0])
+	}
+
+	// a series which disappears is forgotten, it does not count as a reset
+	// when it comes back.
+	r.observe(scrape(20, 270)[:1])
+	if resets := r.observe(scrape(30, 5)); len(resets) != 0 {
+		t.Errorf("reappearing series resets = %v, want none", resets)
+	}
+
+	if len(r.last) != 2 {
+		t.Errorf("tracked series = %d, want 2", len(r.last))
+	}
+
+	c := r.companion()
+	if c == nil || c.name != "counter_resets_total" || c.Value != 1 {
+		t.Errorf("companion() = %+v, want counter_resets_total 1", c)
+	}
+
+	// the steady scrapes reuse the map of the series.
+	metrics := scrape(40, 10)
+	r.observe(metrics)
+	if allocs := testing.AllocsPerRun(100, func() { r.observe(metrics) }); allocs != 0 {
+		t.Errorf("observe() allocates %v times per scrape, want 0", allocs)
+	}
+}
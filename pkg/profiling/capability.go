@@ -46,6 +46,7 @@ const (
 	MemoryModeObjectAlloc   MemoryMode = "object_alloc"
 	MemoryModeObjectUsage   MemoryMode = "object_usage"
 	MemoryModeVirtualAlloc  MemoryMode = "virtual_alloc"
+	MemoryModeVirtualUsage  MemoryMode = "virtual_usage"
 	MemoryModePhysicalAlloc MemoryMode = "physical_alloc"
 	MemoryModePhysicalUsage MemoryMode = "physical_usage"
 )
@@ -91,6 +92,7 @@ func newNativeCapability(language Language) capability {
 		Types:          []Type{TypeCPU, TypeMemory},
 		MemoryModes: []MemoryMode{
 			MemoryModeVirtualAlloc,
+			MemoryModeVirtualUsage,
 			MemoryModePhysicalAlloc,
 			MemoryModePhysicalUsage,
 		},
@@ -24,6 +24,7 @@ import (
 func TestCapabilities(t *testing.T) {
 	nativeModes := []MemoryMode{
 		MemoryModeVirtualAlloc,
+		MemoryModeVirtualUsage,
 		MemoryModePhysicalAlloc,
 		MemoryModePhysicalUsage,
 	}
@@ -136,6 +137,7 @@ func allMemoryModes() []MemoryMode {
 		MemoryModeObjectAlloc,
 		MemoryModeObjectUsage,
 		MemoryModeVirtualAlloc,
+		MemoryModeVirtualUsage,
 		MemoryModePhysicalAlloc,
 		MemoryModePhysicalUsage,
 	}
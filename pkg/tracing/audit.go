@@ -0,0 +1,201 @@
+// Copyright 2026 The HuaTuo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tracing
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync/atomic"
+	"time"
+
+	"github.com/rs/xid"
+
+	"huatuo-bamai/internal/log"
+	"huatuo-bamai/internal/storage"
+	"huatuo-bamai/internal/storage/driver"
+)
+
+// AuditCollection is the storage collection name for the audit records.
+const AuditCollection = "tracing_audit"
+
+// Audit actions.
+const (
+	AuditActionStart  = "start"
+	AuditActionStop   = "stop"
+	AuditActionConfig = "config"
+	// AuditActionTaskStart and AuditActionTaskStop are the tasks run and
+	// stopped through the task API, the tracer is the task binary.
+	AuditActionTaskStart = "task_start"
+	AuditActionTaskStop  = "task_stop"
+)
+
+// Actors of the changes not requested through the API.
+const (
+	// ActorSystem is the agent itself, e.g. starting the tracers at boot.
+	ActorSystem = "system"
+	// ActorBackpressure pauses and resumes the tracers while the stores lag.
+	ActorBackpressure = "backpressure"
+)
+
+// AuditRecord is a change of the tracers, stored append-only.
+type AuditRecord struct {
+	ID     string    `json:"id"`
+	Time   time.Time `json:"time"`
+	Action string    `json:"action"`
+	// Tracer is empty for the changes of the agent config.
+	Tracer string `json:"tracer,omitempty"`
+	Actor  string `json:"actor"`
+	Detail string `json:"detail,omitempty"`
+	// Error is set when the change failed.
+	Error string `json:"error,omitempty"`
+}
+
+// AuditQuery selects audit records, newest first. Empty fields match all.
+type AuditQuery struct {
+	Tracer string
+	Action string
+	Actor  string
+	Since  time.Time
+	Limit  int
+	Offset int
+}
+
+// ErrAuditDisabled is returned when no audit store is configured.
+var ErrAuditDisabled = errors.New("tracing audit is disabled")
+
+type actorKey struct{}
+
+// WithActor returns a context recording actor as the author of the changes
+// made with it.
+func WithActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+func actorFromContext(ctx context.Context) string {
+	if actor, ok := ctx.Value(actorKey{}).(string); ok && actor != "" {
+		return actor
+	}
+	return ActorSystem
+}
+
+var auditStore atomic.Pointer[storage.Store[*AuditRecord]]
+
+// SetAuditStore configures the store of the audit records, nil disables the
+// audit.
+func SetAuditStore(store *storage.Store[*AuditRecord]) {
+	auditStore.Store(store)
+}
+
+// closeAuditStore releases the audit store.
+func closeAuditStore(ctx context.Context) error {
+	store := auditStore.Swap(nil)
+	if store == nil {
+		return nil
+	}
+	return store.Close(ctx)
+}
+
+// Audit records a change made by the actor of ctx. A record which fails to
+// be stored is logged, it never fails the change.
+func Audit(ctx context.Context, action, tracer, detail string, err error) {
+	store := auditStore.Load()
+	if store == nil {
+		return
+	}
+
+	record := &AuditRecord{
+		ID:     xid.New().String(),
+		Time:   time.Now(),
+		Action: action,
+		Tracer: tracer,
+		Actor:  actorFromContext(ctx),
+		Detail: detail,
+	}
+	if err != nil {
+		record.Error = err.Error()
+	}
+
+	// the record outlives a canceled request.
+	if err := store.Create(context.WithoutCancel(ctx), record); err != nil {
+		log.Warnf("tracing audit %s %s by %s: %v", action, tracer, record.Actor, err)
+	}
+}
+
+// QueryAudit returns the audit records matching q.
+func QueryAudit(ctx context.Context, q *AuditQuery) ([]*AuditRecord, error) {
+	store := auditStore.Load()
+	if store == nil {
+		return nil, ErrAuditDisabled
+	}
+
+	query := driver.Query{
+		Sorts:  []driver.Sort{{Field: "time", Desc: true}},
+		Limit:  q.Limit,
+		Offset: q.Offset,
+	}
+	for field, value := range map[string]string{
+		"tracer": q.Tracer,
+		"action": q.Action,
+		"actor":  q.Actor,
+	} {
+		if value != "" {
+			query.Filters = append(query.Filters, driver.Filter{Field: field, Op: driver.OpEq, Value: value})
+		}
+	}
+	if !q.Since.IsZero() {
+		query.Filters = append(query.Filters, driver.Filter{Field: "time", Op: driver.OpGte, Value: q.Since.UTC()})
+	}
+
+	return store.Query(ctx, query)
+}
+
+// AuditStoreMapper maps the audit records to storage records.
+type AuditStoreMapper struct{}
+
+func (AuditStoreMapper) ID(record *AuditRecord) string {
+	return record.ID
+}
+
+func (AuditStoreMapper) Encode(record *AuditRecord) ([]byte, error) {
+	return json.Marshal(record)
+}
+
+func (AuditStoreMapper) Decode(data []byte) (*AuditRecord, error) {
+	var record AuditRecord
+	if err := json.Unmarshal(data, &record); err != nil {
+		return nil, err
+	}
+
+	return &record, nil
+}
+
+func (AuditStoreMapper) Fields(record *AuditRecord) (map[string]any, error) {
+	return map[string]any{
+		"time":   record.Time.UTC(),
+		"action": record.Action,
+		"tracer": record.Tracer,
+		"actor":  record.Actor,
+	}, nil
+}
+
+func (AuditStoreMapper) Indexes() []driver.Index {
+	return []driver.Index{
+		{Field: "time"},
+		{Field: "action"},
+		{Field: "tracer"},
+		{Field: "actor"},
+	}
+}
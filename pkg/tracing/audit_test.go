@@ -0,0 +1,97 @@
+// Copyright 2026 The HuaTuo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tracing
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"huatuo-bamai/internal/storage"
+	"huatuo-bamai/internal/storage/driver"
+)
+
+func TestManagerAudit(t *testing.T) {
+	resetRegisterState()
+	t.Cleanup(resetRegisterState)
+
+	store, err := storage.NewFromConfig(context.Background(), &driver.Config{
+		Driver:    "sqlite",
+		SQLiteDSN: filepath.Join(t.TempDir(), "audit.db"),
+	}, AuditCollection, AuditStoreMapper{})
+	if err != nil {
+		t.Fatalf("new audit store: %v", err)
+	}
+	SetAuditStore(store)
+	t.Cleanup(func() { _ = closeAuditStore(context.Background()) })
+
+	RegisterEventTracing("oom", func() (*EventTracingAttr, error) {
+		return &EventTracingAttr{
+			Flag:     FlagTracing,
+			Interval: 1,
+			TracingData: &starterStub{
+				startFunc: func(ctx context.Context) error {
+					<-ctx.Done()
+					return nil
+				},
+			},
+		}, nil
+	})
+
+	manager, err := NewManager(nil)
+	if err != nil {
+		t.Fatalf("NewManager() error = %v", err)
+	}
+	t.Cleanup(func() { _ = manager.Close(context.Background()) })
+
+	ctx := WithActor(context.Background(), "api:10.0.0.1")
+	if err := manager.StartByName(ctx, "oom"); err != nil {
+		t.Fatalf("StartByName() error = %v", err)
+	}
+	if err := manager.StopByName(ctx, "oom"); err != nil {
+		t.Fatalf("StopByName() error = %v", err)
+	}
+	if err := manager.StopByName(context.Background(), "missing"); !errors.Is(err, ErrTracerNotFound) {
+		t.Fatalf("StopByName(missing) error = %v", err)
+	}
+
+	records, err := QueryAudit(context.Background(), &AuditQuery{Tracer: "oom"})
+	if err != nil {
+		t.Fatalf("QueryAudit() error = %v", err)
+	}
+	if len(records) != 2 {
+		t.Fatalf("QueryAudit() = %d records, want 2", len(records))
+	}
+	for _, record := range records {
+		if record.Actor != "api:10.0.0.1" || record.Error != "" {
+			t.Errorf("record = %+v", record)
+		}
+	}
+
+	records, err = QueryAudit(context.Background(), &AuditQuery{Tracer: "missing", Action: AuditActionStop})
+	if err != nil {
+		t.Fatalf("QueryAudit(missing) error = %v", err)
+	}
+	if len(records) != 1 || records[0].Actor != ActorSystem || records[0].Error == "" {
+		t.Errorf("QueryAudit(missing) = %+v, want a failed stop by the system", records)
+	}
+}
+
+func TestQueryAuditDisabled(t *testing.T) {
+	if _, err := QueryAudit(context.Background(), &AuditQuery{}); !errors.Is(err, ErrAuditDisabled) {
+		t.Errorf("QueryAudit() error = %v, want %v", err, ErrAuditDisabled)
+	}
+}
@@ -0,0 +1,271 @@
+// Copyright 2026 The HuaTuo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tracing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync/atomic"
+	"time"
+
+	"huatuo-bamai/internal/log"
+)
+
+// Backpressure levels, each one includes the previous.
+const (
+	backpressureNone int32 = iota
+	// backpressureSample keeps one of SampleRate events.
+	backpressureSample
+	// backpressurePause stops the tracers which have no metrics.
+	backpressurePause
+)
+
+var backpressureLevelNames = [...]string{"none", "sample", "pause"}
+
+// BackpressureConfig throttles the event tracers when the stores lag, the
+// backlog is the events saved and not yet delivered by the stores.
+type BackpressureConfig struct {
+	// Tracers limits the throttling to these tracers, empty for all.
+	Tracers []string
+	// SampleBacklog starts the sampling, zero disables it.
+	SampleBacklog int64
+	// SampleRate keeps one of SampleRate events while sampling.
+	SampleRate int
+	// PauseBacklog pauses the tracers, zero disables it.
+	PauseBacklog int64
+	// ResumeBacklog ends the throttling, below it all tracers run and
+	// every event is stored.
+	ResumeBacklog int64
+	// Interval is how often the backlog is checked.
+	Interval time.Duration
+}
+
+func (c *BackpressureConfig) enabled() bool {
+	return c.SampleBacklog > 0 || c.PauseBacklog > 0
+}
+
+func (c *BackpressureConfig) validate() error {
+	if c.Interval <= 0 {
+		return fmt.Errorf("backpressure: interval must be positive")
+	}
+	if c.SampleBacklog > 0 && c.SampleRate < 1 {
+		return fmt.Errorf("backpressure: sample rate must be positive")
+	}
+	if c.SampleBacklog > 0 && c.PauseBacklog > 0 && c.SampleBacklog > c.PauseBacklog {
+		return fmt.Errorf("backpressure: sample backlog %d is above pause backlog %d", c.SampleBacklog, c.PauseBacklog)
+	}
+	for _, threshold := range []int64{c.SampleBacklog, c.PauseBacklog} {
+		if threshold > 0 && c.ResumeBacklog >= threshold {
+			return fmt.Errorf("backpressure: resume backlog %d must be below %d", c.ResumeBacklog, threshold)
+		}
+	}
+	return nil
+}
+
+// everySampler keeps the first of every n events, n may change between
+// two events.
+type everySampler struct {
+	seq atomic.Uint64
+}
+
+func (s *everySampler) keep(n uint64) bool {
+	return n <= 1 || (s.seq.Add(1)-1)%n == 0
+}
+
+type backpressure struct {
+	cfg     BackpressureConfig
+	tracers map[string]bool
+	level   atomic.Int32
+	sampler everySampler
+	dropped atomic.Uint64
+	// paused are the tracers stopped by the pause level.
+	paused []string
+}
+
+var eventThrottle atomic.Pointer[backpressure]
+
+func newBackpressure(cfg *BackpressureConfig) *backpressure {
+	bp := &backpressure{cfg: *cfg, tracers: make(map[string]bool, len(cfg.Tracers))}
+	for _, tracer := range cfg.Tracers {
+		bp.tracers[tracer] = true
+	}
+	return bp
+}
+
+func (bp *backpressure) affects(tracer string) bool {
+	return len(bp.tracers) == 0 || bp.tracers[tracer]
+}
+
+// next is the level for the backlog. The throttling starts at the sample
+// and pause backlogs and only ends below the resume backlog, so a backlog
+// going up and down around a threshold does not flap the tracers.
+func (bp *backpressure) next(backlog int64) int32 {
+	cur := bp.level.Load()
+
+	switch {
+	case bp.cfg.PauseBacklog > 0 && backlog >= bp.cfg.PauseBacklog:
+		return backpressurePause
+	case bp.cfg.SampleBacklog > 0 && backlog >= bp.cfg.SampleBacklog:
+		return max(cur, backpressureSample)
+	case backlog < bp.cfg.ResumeBacklog:
+		return backpressureNone
+	}
+	return cur
+}
+
+// sampleRate returns the n of the 1 of n sampling of the tracer events, 1
+// when they are not sampled. A nil backpressure samples nothing.
+func (bp *backpressure) sampleRate(tracer string) uint64 {
+	if bp == nil || bp.level.Load() == backpressureNone || !bp.affects(tracer) || bp.cfg.SampleRate <= 1 {
+		return 1
+	}
+	return uint64(bp.cfg.SampleRate)
+}
+
+// admit reports whether the event is stored, the sampling keeps the first
+// of every SampleRate events.
+func (bp *backpressure) admit(tracer string) bool {
+	if bp.sampler.keep(bp.sampleRate(tracer)) {
+		return true
+	}
+
+	bp.dropped.Add(1)
+	return false
+}
+
+// tracingBacklog sums the backlog of the tracing stores.
+func tracingBacklog() int64 {
+	writer := tracingDataWriter
+	if writer == nil {
+		return 0
+	}
+
+	var total int64
+	for _, store := range writer.stores {
+		if store == nil {
+			continue
+		}
+		if backlog, ok := store.Backlog(); ok {
+			total += backlog
+		}
+	}
+	return total
+}
+
+// StartBackpressure checks the backlog of the tracing stores every
+// Interval, it samples the events and pauses the tracers of cfg while the
+// stores lag. The tracers with metrics are never paused, so the metrics
+// keep flowing. It stops on Close.
+func (m *Manager) StartBackpressure(cfg *BackpressureConfig) error {
+	if !cfg.enabled() {
+		return nil
+	}
+	if err := cfg.validate(); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.isClosed {
+		return ErrManagerClosed
+	}
+	if m.stopBackpressure != nil {
+		return fmt.Errorf("backpressure is already started")
+	}
+
+	bp := newBackpressure(cfg)
+	ctx, cancel := context.WithCancel(context.Background())
+	m.stopBackpressure = cancel
+	eventThrottle.Store(bp)
+
+	go func() {
+		ticker := time.NewTicker(bp.cfg.Interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				eventThrottle.CompareAndSwap(bp, nil)
+				return
+			case <-ticker.C:
+				m.applyBackpressure(WithActor(ctx, ActorBackpressure), bp, tracingBacklog())
+			}
+		}
+	}()
+
+	return nil
+}
+
+func (m *Manager) applyBackpressure(ctx context.Context, bp *backpressure, backlog int64) {
+	cur, next := bp.level.Load(), bp.next(backlog)
+	if cur == next {
+		return
+	}
+
+	log.Infof("tracing backpressure %s -> %s, backlog %d, dropped %d events",
+		backpressureLevelNames[cur], backpressureLevelNames[next], backlog, bp.dropped.Load())
+	bp.level.Store(next)
+
+	switch {
+	case next == backpressurePause:
+		bp.paused = m.pauseTracers(ctx, bp)
+	case cur == backpressurePause:
+		m.resumeTracers(bp.paused)
+		bp.paused = nil
+	}
+}
+
+// pauseTracers stops the running tracers without metrics, it returns the
+// stopped ones.
+func (m *Manager) pauseTracers(ctx context.Context, bp *backpressure) []string {
+	m.mu.RLock()
+	names := make([]string, 0, len(m.runners))
+	for name, runner := range m.runners {
+		if runner.roles&FlagMetric != 0 || !bp.affects(name) || !runner.snapshot().IsRunning {
+			continue
+		}
+		names = append(names, name)
+	}
+	m.mu.RUnlock()
+	sort.Strings(names)
+
+	paused := make([]string, 0, len(names))
+	for _, name := range names {
+		if err := m.StopByName(ctx, name); err != nil {
+			if !errors.Is(err, ErrTracerNotRunning) {
+				log.Warnf("backpressure pause tracer %s: %v", name, err)
+			}
+			continue
+		}
+		paused = append(paused, name)
+	}
+
+	log.Infof("tracing backpressure paused tracers %v", paused)
+	return paused
+}
+
+func (m *Manager) resumeTracers(names []string) {
+	for _, name := range names {
+		if err := m.StartByName(WithActor(context.Background(), ActorBackpressure), name); err != nil &&
+			!errors.Is(err, ErrTracerAlreadyRunning) {
+			log.Warnf("backpressure resume tracer %s: %v", name, err)
+		}
+	}
+
+	log.Infof("tracing backpressure resumed tracers %v", names)
+}
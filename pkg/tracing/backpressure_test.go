@@ -0,0 +1,172 @@
+// Copyright 2026 The HuaTuo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tracing
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestBackpressureLevels(t *testing.T) {
+	bp := newBackpressure(&BackpressureConfig{
+		SampleBacklog: 100,
+		SampleRate:    4,
+		PauseBacklog:  1000,
+		ResumeBacklog: 10,
+	})
+
+	steps := []struct {
+		backlog int64
+		want    int32
+	}{
+		{50, backpressureNone},
+		{100, backpressureSample},
+		{50, backpressureSample},
+		{1000, backpressurePause},
+		// the pause holds until the backlog drains below the resume one.
+		{500, backpressurePause},
+		{50, backpressurePause},
+		{9, backpressureNone},
+	}
+	for i, step := range steps {
+		got := bp.next(step.backlog)
+		if got != step.want {
+			t.Fatalf("step %d: next(%d) = %s, want %s", i, step.backlog,
+				backpressureLevelNames[got], backpressureLevelNames[step.want])
+		}
+		bp.level.Store(got)
+	}
+}
+
+func TestBackpressureAdmit(t *testing.T) {
+	bp := newBackpressure(&BackpressureConfig{Tracers: []string{"dropwatch"}, SampleRate: 4})
+
+	for i := 0; i < 8; i++ {
+		if !bp.admit("dropwatch") {
+			t.Fatal("events must not be sampled without backpressure")
+		}
+	}
+
+	bp.level.Store(backpressureSample)
+	admitted := 0
+	for i := 0; i < 8; i++ {
+		if bp.admit("dropwatch") {
+			admitted++
+		}
+		if !bp.admit("oom") {
+			t.Fatal("tracer not configured must not be sampled")
+		}
+	}
+	if admitted != 2 || bp.dropped.Load() != 6 {
+		t.Errorf("admitted %d, dropped %d of 8, want 2 and 6", admitted, bp.dropped.Load())
+	}
+
+	var none *backpressure
+	for _, tt := range []struct {
+		bp     *backpressure
+		tracer string
+		want   uint64
+	}{
+		{bp, "dropwatch", 4},
+		{bp, "oom", 1},
+		{none, "dropwatch", 1},
+	} {
+		if got := tt.bp.sampleRate(tt.tracer); got != tt.want {
+			t.Errorf("sampleRate(%s) = %d, want %d", tt.tracer, got, tt.want)
+		}
+	}
+}
+
+func TestBackpressurePauseResume(t *testing.T) {
+	blocking := &starterStub{startFunc: func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}}
+	m := &Manager{runners: map[string]*eventRunner{
+		"events":  newEventRunner("events", blocking, time.Second, FlagTracing),
+		"metrics": newEventRunner("metrics", blocking, time.Second, FlagTracing|FlagMetric),
+		"other":   newEventRunner("other", blocking, time.Second, FlagTracing),
+	}}
+	if err := m.Start(context.Background()); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	t.Cleanup(func() { _ = m.Close(context.Background()) })
+
+	running := func() map[string]bool {
+		states := make(map[string]bool)
+		for name, s := range m.Snapshots() {
+			states[name] = s.IsRunning
+		}
+		return states
+	}
+
+	bp := newBackpressure(&BackpressureConfig{
+		Tracers:       []string{"events", "metrics"},
+		PauseBacklog:  100,
+		ResumeBacklog: 10,
+	})
+	ctx := context.Background()
+
+	m.applyBackpressure(ctx, bp, 100)
+	if got := running(); got["events"] || !got["metrics"] || !got["other"] {
+		t.Fatalf("running after pause = %v, want only events stopped", got)
+	}
+
+	m.applyBackpressure(ctx, bp, 50)
+	if running()["events"] {
+		t.Fatal("events resumed above the resume backlog")
+	}
+
+	m.applyBackpressure(ctx, bp, 0)
+	if got := running(); !got["events"] || !got["metrics"] || !got["other"] {
+		t.Fatalf("running after resume = %v, want all", got)
+	}
+	if bp.level.Load() != backpressureNone || bp.paused != nil {
+		t.Errorf("level = %d, paused = %v after resume", bp.level.Load(), bp.paused)
+	}
+}
+
+func TestStartBackpressureConfig(t *testing.T) {
+	m := &Manager{runners: map[string]*eventRunner{}}
+	t.Cleanup(func() { _ = m.Close(context.Background()) })
+
+	if err := m.StartBackpressure(&BackpressureConfig{}); err != nil || m.stopBackpressure != nil {
+		t.Fatalf("disabled config must not start, err = %v", err)
+	}
+
+	invalid := []BackpressureConfig{
+		{SampleBacklog: 10, SampleRate: 2},
+		{SampleBacklog: 10, Interval: time.Second},
+		{SampleBacklog: 100, SampleRate: 2, PauseBacklog: 10, Interval: time.Second},
+		{PauseBacklog: 100, ResumeBacklog: 100, Interval: time.Second},
+	}
+	for _, c := range invalid {
+		if err := m.StartBackpressure(&c); err == nil {
+			t.Errorf("StartBackpressure(%+v) error = nil", c)
+		}
+	}
+
+	valid := &BackpressureConfig{SampleBacklog: 10, SampleRate: 2, Interval: time.Hour}
+	if err := m.StartBackpressure(valid); err != nil {
+		t.Fatalf("StartBackpressure() error = %v", err)
+	}
+	if eventThrottle.Load() == nil {
+		t.Error("events are not throttled after StartBackpressure")
+	}
+	if err := m.StartBackpressure(valid); err == nil {
+		t.Error("second StartBackpressure() error = nil")
+	}
+}
@@ -0,0 +1,188 @@
+// Copyright 2026 The HuaTuo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tracing
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+	"sync/atomic"
+
+	"golang.org/x/time/rate"
+
+	"huatuo-bamai/internal/cgroups"
+	"huatuo-bamai/internal/cgroups/paths"
+	"huatuo-bamai/internal/log"
+	"huatuo-bamai/internal/pod"
+	"huatuo-bamai/internal/procfs"
+	"huatuo-bamai/internal/procfs/sysfs"
+)
+
+const captureTruncated = "\n... truncated"
+
+// CaptureConfig is the context captured into the event documents, the
+// snapshot of the node when the tracer triggered.
+type CaptureConfig struct {
+	// Tracers limits the capture to these tracers, empty for all.
+	Tracers []string
+	// Files are host files under /proc or /sys, e.g. /proc/meminfo.
+	Files []string
+	// CgroupFiles are <subsystem>/<file> of the container of the event,
+	// e.g. memory/memory.stat. The subsystem is ignored on cgroup v2.
+	CgroupFiles []string
+	// Rate and Burst limit the captures per second over all tracers.
+	Rate  float64
+	Burst int
+	// MaxFileBytes truncates each captured file.
+	MaxFileBytes int
+}
+
+type capturer struct {
+	tracers      map[string]bool
+	files        []captureFile
+	cgroupFiles  []captureFile
+	limiter      *rate.Limiter
+	maxFileBytes int
+}
+
+// captureFile is a file to capture, key is its name in the document.
+type captureFile struct {
+	key    string
+	subsys string
+	name   string
+}
+
+var contextCapturer atomic.Pointer[capturer]
+
+// SetCaptureConfig installs the context capture, nothing is captured when
+// no file is configured.
+func SetCaptureConfig(c *CaptureConfig) error {
+	if len(c.Files) == 0 && len(c.CgroupFiles) == 0 {
+		contextCapturer.Store(nil)
+		return nil
+	}
+	if c.Rate <= 0 || c.Burst <= 0 || c.MaxFileBytes <= 0 {
+		return fmt.Errorf("context capture: rate, burst and max file bytes must be positive")
+	}
+
+	capt := &capturer{
+		tracers:      make(map[string]bool, len(c.Tracers)),
+		limiter:      rate.NewLimiter(rate.Limit(c.Rate), c.Burst),
+		maxFileBytes: c.MaxFileBytes,
+	}
+	for _, tracer := range c.Tracers {
+		capt.tracers[tracer] = true
+	}
+
+	for _, file := range c.Files {
+		clean := path.Clean(file)
+		if !strings.HasPrefix(clean, "/proc/") && !strings.HasPrefix(clean, "/sys/") {
+			return fmt.Errorf("context capture: %s is not under /proc or /sys", file)
+		}
+		capt.files = append(capt.files, captureFile{key: clean, name: clean})
+	}
+
+	for _, file := range c.CgroupFiles {
+		clean := path.Clean(file)
+		subsys, name, ok := strings.Cut(clean, "/")
+		if !ok || subsys == "" || name == "" || strings.Contains(clean, "..") {
+			return fmt.Errorf("context capture: cgroup file %s is not <subsystem>/<file>", file)
+		}
+		capt.cgroupFiles = append(capt.cgroupFiles, captureFile{key: "cgroup/" + subsys + "/" + name, subsys: subsys, name: name})
+	}
+
+	contextCapturer.Store(capt)
+	return nil
+}
+
+// hostPath maps /proc and /sys to the mount points of the host.
+func (f *captureFile) hostPath() string {
+	if rest, ok := strings.CutPrefix(f.name, "/proc/"); ok {
+		return procfs.Path(rest)
+	}
+	return sysfs.Path(strings.TrimPrefix(f.name, "/sys/"))
+}
+
+func (f *captureFile) cgroupPath(cgroupPath string) string {
+	if cgroups.CgroupMode() == cgroups.Unified {
+		return paths.Path(cgroupPath, f.name)
+	}
+	return paths.Path(f.subsys, cgroupPath, f.name)
+}
+
+func (c *capturer) read(file string) (string, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	data, err := io.ReadAll(io.LimitReader(f, int64(c.maxFileBytes)+1))
+	if err != nil {
+		return "", err
+	}
+	if len(data) > c.maxFileBytes {
+		return string(data[:c.maxFileBytes]) + captureTruncated, nil
+	}
+	return string(data), nil
+}
+
+// captureContext snapshots the configured files into the document. The
+// global rate limit drops the capture, never the document, under bursts.
+func captureContext(document *Document) {
+	c := contextCapturer.Load()
+	if c == nil {
+		return
+	}
+	if len(c.tracers) > 0 && !c.tracers[document.TracerName] {
+		return
+	}
+	if !c.limiter.Allow() {
+		log.Debugf("context capture of %s is rate limited", document.TracerName)
+		return
+	}
+
+	snapshot := make(map[string]string, len(c.files)+len(c.cgroupFiles))
+	for i := range c.files {
+		file := &c.files[i]
+		data, err := c.read(file.hostPath())
+		if err != nil {
+			log.Debugf("context capture %s: %v", file.name, err)
+			continue
+		}
+		snapshot[file.key] = data
+	}
+
+	if document.ContainerID != "" && len(c.cgroupFiles) > 0 {
+		container, err := pod.ContainerByID(document.ContainerID)
+		if err == nil && container != nil {
+			for i := range c.cgroupFiles {
+				file := &c.cgroupFiles[i]
+				data, err := c.read(file.cgroupPath(container.CgroupPath))
+				if err != nil {
+					log.Debugf("context capture %s of %s: %v", file.key, container.ID, err)
+					continue
+				}
+				snapshot[file.key] = data
+			}
+		}
+	}
+
+	if len(snapshot) > 0 {
+		document.Context = snapshot
+	}
+}
@@ -0,0 +1,112 @@
+// Copyright 2026 The HuaTuo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tracing
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"huatuo-bamai/internal/procfs"
+)
+
+func TestCaptureContext(t *testing.T) {
+	root := t.TempDir()
+	procfs.RootPrefix(root)
+	t.Cleanup(func() {
+		procfs.RootPrefix("/")
+		contextCapturer.Store(nil)
+	})
+
+	for name, content := range map[string]string{
+		"proc/meminfo": "MemTotal: 1024 kB\n",
+		"proc/vmstat":  strings.Repeat("x", 64),
+		"sys/kernel/mm/transparent_hugepage/enabled": "[always] madvise never\n",
+	} {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := SetCaptureConfig(&CaptureConfig{
+		Tracers:      []string{"oom"},
+		Files:        []string{"/proc/meminfo", "/proc/vmstat", "/proc/missing", "/sys/kernel/mm/transparent_hugepage/enabled"},
+		Rate:         0.001,
+		Burst:        1,
+		MaxFileBytes: 32,
+	}); err != nil {
+		t.Fatalf("SetCaptureConfig() error = %v", err)
+	}
+
+	other := &Document{TracerName: "hungtask"}
+	captureContext(other)
+	if other.Context != nil {
+		t.Errorf("tracer not configured captured %v", other.Context)
+	}
+
+	doc := &Document{TracerName: "oom"}
+	captureContext(doc)
+	if got := doc.Context["/proc/meminfo"]; got != "MemTotal: 1024 kB\n" {
+		t.Errorf("context /proc/meminfo = %q", got)
+	}
+	if got := doc.Context["/proc/vmstat"]; got != strings.Repeat("x", 32)+captureTruncated {
+		t.Errorf("context /proc/vmstat = %q, want truncated", got)
+	}
+	if got := doc.Context["/sys/kernel/mm/transparent_hugepage/enabled"]; got != "[always] madvise never\n" {
+		t.Errorf("context thp = %q", got)
+	}
+	if _, ok := doc.Context["/proc/missing"]; ok || len(doc.Context) != 3 {
+		t.Errorf("context = %v, want 3 files", doc.Context)
+	}
+
+	// the burst is used up, the next event is stored without context.
+	limited := &Document{TracerName: "oom"}
+	captureContext(limited)
+	if limited.Context != nil {
+		t.Errorf("rate limited capture got %v", limited.Context)
+	}
+}
+
+func TestSetCaptureConfig(t *testing.T) {
+	t.Cleanup(func() { contextCapturer.Store(nil) })
+
+	if err := SetCaptureConfig(&CaptureConfig{}); err != nil || contextCapturer.Load() != nil {
+		t.Errorf("empty config must disable the capture, err = %v", err)
+	}
+
+	valid := CaptureConfig{Rate: 1, Burst: 1, MaxFileBytes: 1}
+	tests := []CaptureConfig{
+		{Files: []string{"/etc/passwd"}, Rate: 1, Burst: 1, MaxFileBytes: 1},
+		{Files: []string{"/proc/../etc/passwd"}, Rate: 1, Burst: 1, MaxFileBytes: 1},
+		{CgroupFiles: []string{"memory.stat"}, Rate: 1, Burst: 1, MaxFileBytes: 1},
+		{CgroupFiles: []string{"memory/../../x"}, Rate: 1, Burst: 1, MaxFileBytes: 1},
+		{Files: []string{"/proc/meminfo"}},
+	}
+	for _, c := range tests {
+		if err := SetCaptureConfig(&c); err == nil {
+			t.Errorf("SetCaptureConfig(%+v) error = nil", c)
+		}
+	}
+
+	valid.CgroupFiles = []string{"memory/memory.stat"}
+	if err := SetCaptureConfig(&valid); err != nil {
+		t.Errorf("SetCaptureConfig(%+v) error = %v", valid, err)
+	}
+}
@@ -0,0 +1,247 @@
+// Copyright 2026 The HuaTuo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tracing
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/rs/xid"
+
+	"huatuo-bamai/internal/log"
+)
+
+const (
+	// IncidentTracerName is the tracer name of the incident documents.
+	IncidentTracerName = "incident"
+	// TracerRunTypeIncident is the run type of the incident documents.
+	TracerRunTypeIncident = "incident"
+
+	// incidentMaxTracerIDs bounds the events listed in an incident.
+	incidentMaxTracerIDs = 64
+)
+
+// CorrelationConfig groups the events of a node or of a container which
+// come within Window of each other into an incident.
+type CorrelationConfig struct {
+	// Tracers limits the correlation to these tracers, empty for all.
+	Tracers []string
+	// Window closes an incident when no event came during it, zero
+	// disables the correlation.
+	Window time.Duration
+	// MaxDuration closes an incident which lasts longer, so a steady
+	// stream of events is split into several incidents.
+	MaxDuration time.Duration
+	// MinEvents is the events an incident needs to be stored.
+	MinEvents int
+}
+
+// IncidentData is the tracer data of an incident document.
+type IncidentData struct {
+	StartTime string         `json:"start_time"`
+	EndTime   string         `json:"end_time"`
+	Events    int            `json:"events"`
+	Tracers   map[string]int `json:"tracers"`
+	// TracerIDs are the first events of the incident.
+	TracerIDs []string `json:"tracer_ids"`
+}
+
+type incident struct {
+	id    string
+	key   string
+	start time.Time
+	last  time.Time
+	timer *time.Timer
+	data  IncidentData
+	// first is the first event, the incident shares its container.
+	first Document
+}
+
+type correlator struct {
+	cfg     CorrelationConfig
+	tracers map[string]bool
+
+	mu sync.Mutex
+	// open are the incidents keyed by the container id, empty for the
+	// node events.
+	open map[string]*incident
+}
+
+var eventCorrelator atomic.Pointer[correlator]
+
+// SetCorrelationConfig installs the event correlation, replacing the
+// previous one and closing its incidents.
+func SetCorrelationConfig(c *CorrelationConfig) error {
+	if c.Window <= 0 {
+		closeCorrelator(eventCorrelator.Swap(nil))
+		return nil
+	}
+	if c.MaxDuration < c.Window {
+		return fmt.Errorf("correlation: max duration %s is below window %s", c.MaxDuration, c.Window)
+	}
+	if c.MinEvents < 1 {
+		return fmt.Errorf("correlation: min events must be positive")
+	}
+
+	corr := &correlator{
+		cfg:     *c,
+		tracers: make(map[string]bool, len(c.Tracers)),
+		open:    make(map[string]*incident),
+	}
+	for _, tracer := range c.Tracers {
+		corr.tracers[tracer] = true
+	}
+
+	closeCorrelator(eventCorrelator.Swap(corr))
+	return nil
+}
+
+func closeCorrelator(corr *correlator) {
+	if corr != nil {
+		corr.flush()
+	}
+}
+
+// correlateDocument sets the incident of an event document.
+func correlateDocument(document *Document) {
+	if corr := eventCorrelator.Load(); corr != nil {
+		corr.observe(document, time.Now())
+	}
+}
+
+func (c *correlator) observe(document *Document, now time.Time) {
+	if len(c.tracers) != 0 && !c.tracers[document.TracerName] {
+		return
+	}
+
+	c.mu.Lock()
+	closed := c.addLocked(document, now)
+	c.mu.Unlock()
+
+	// the stores are not called under the lock.
+	saveIncident(closed)
+}
+
+// addLocked adds the event to the open incident of its container, it
+// returns the incident closed for lasting longer than MaxDuration.
+func (c *correlator) addLocked(document *Document, now time.Time) *incident {
+	var closed *incident
+
+	key := document.ContainerID
+	inc := c.open[key]
+	if inc != nil && now.Sub(inc.start) >= c.cfg.MaxDuration {
+		closed = c.closeLocked(inc)
+		inc = nil
+	}
+
+	if inc == nil {
+		inc = &incident{
+			id:    xid.New().String(),
+			key:   key,
+			start: now,
+			first: *document,
+			data:  IncidentData{Tracers: make(map[string]int)},
+		}
+		inc.timer = time.AfterFunc(c.cfg.Window, func() { c.expire(inc) })
+		c.open[key] = inc
+	} else {
+		inc.timer.Reset(c.cfg.Window)
+	}
+
+	inc.last = now
+	inc.data.Events++
+	inc.data.Tracers[document.TracerName]++
+	if len(inc.data.TracerIDs) < incidentMaxTracerIDs {
+		inc.data.TracerIDs = append(inc.data.TracerIDs, document.TracerID)
+	}
+
+	document.IncidentID = inc.id
+	return closed
+}
+
+// expire closes the incident when no event came during the window, a
+// timer racing with a new event finds the incident renewed.
+func (c *correlator) expire(inc *incident) {
+	c.mu.Lock()
+	if c.open[inc.key] != inc || time.Since(inc.last) < c.cfg.Window {
+		c.mu.Unlock()
+		return
+	}
+	closed := c.closeLocked(inc)
+	c.mu.Unlock()
+
+	saveIncident(closed)
+}
+
+// flush closes all the open incidents.
+func (c *correlator) flush() {
+	c.mu.Lock()
+	var closed []*incident
+	for _, inc := range c.open {
+		closed = append(closed, c.closeLocked(inc))
+	}
+	c.mu.Unlock()
+
+	for _, inc := range closed {
+		saveIncident(inc)
+	}
+}
+
+// closeLocked removes the incident, it returns the incident to store, nil
+// when it has too few events.
+func (c *correlator) closeLocked(inc *incident) *incident {
+	inc.timer.Stop()
+	delete(c.open, inc.key)
+
+	if inc.data.Events < c.cfg.MinEvents {
+		return nil
+	}
+	return inc
+}
+
+func saveIncident(inc *incident) {
+	writer := tracingDataWriter
+	if inc == nil || writer == nil {
+		return
+	}
+
+	inc.data.StartTime = inc.start.Format(tracingDocumentTimeLayout)
+	inc.data.EndTime = inc.last.Format(tracingDocumentTimeLayout)
+
+	document := &Document{
+		Hostname:               inc.first.Hostname,
+		Region:                 inc.first.Region,
+		NodeName:               inc.first.NodeName,
+		UploadedTime:           time.Now(),
+		Time:                   inc.data.StartTime,
+		ContainerID:            inc.first.ContainerID,
+		ContainerHostname:      inc.first.ContainerHostname,
+		ContainerHostNamespace: inc.first.ContainerHostNamespace,
+		ContainerType:          inc.first.ContainerType,
+		ContainerQoS:           inc.first.ContainerQoS,
+		ContainerPodMetadata:   inc.first.ContainerPodMetadata,
+		TracerName:             IncidentTracerName,
+		TracerID:               inc.id,
+		TracerTime:             inc.data.StartTime,
+		TracerRunType:          TracerRunTypeIncident,
+		TracerData:             &inc.data,
+		IncidentID:             inc.id,
+	}
+	if err := writer.storeDocument(document); err != nil {
+		log.Warnf("save incident %s: %v", inc.id, err)
+	}
+}
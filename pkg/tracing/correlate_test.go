@@ -0,0 +1,107 @@
+// Copyright 2026 The HuaTuo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tracing
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCorrelateDocuments(t *testing.T) {
+	writer := tracingDataWriter
+	tracingDataWriter = newDocumentWriter(nil, DocumentOptions{})
+	t.Cleanup(func() {
+		closeCorrelator(eventCorrelator.Swap(nil))
+		tracingDataWriter = writer
+	})
+
+	if err := SetCorrelationConfig(&CorrelationConfig{
+		Tracers:     []string{"cpusys", "softirq", "dropwatch"},
+		Window:      time.Hour,
+		MaxDuration: 2 * time.Hour,
+		MinEvents:   2,
+	}); err != nil {
+		t.Fatalf("SetCorrelationConfig() error = %v", err)
+	}
+	corr := eventCorrelator.Load()
+
+	now := time.Now()
+	cpusys := &Document{TracerName: "cpusys", TracerID: "a"}
+	softirq := &Document{TracerName: "softirq", TracerID: "b"}
+	container := &Document{TracerName: "dropwatch", TracerID: "c", ContainerID: "ctr"}
+	other := &Document{TracerName: "oom", TracerID: "d"}
+	corr.observe(cpusys, now)
+	corr.observe(softirq, now.Add(time.Second))
+	corr.observe(container, now.Add(time.Second))
+	corr.observe(other, now.Add(time.Second))
+
+	if cpusys.IncidentID == "" || softirq.IncidentID != cpusys.IncidentID {
+		t.Errorf("node events incidents %q and %q, want the same", cpusys.IncidentID, softirq.IncidentID)
+	}
+	if container.IncidentID == "" || container.IncidentID == cpusys.IncidentID {
+		t.Errorf("container event incident %q, want its own", container.IncidentID)
+	}
+	if other.IncidentID != "" {
+		t.Errorf("tracer not configured got incident %q", other.IncidentID)
+	}
+
+	// a longer incident is split, the closed one is stored.
+	late := &Document{TracerName: "cpusys", TracerID: "e"}
+	corr.observe(late, now.Add(3*time.Hour))
+	if late.IncidentID == "" || late.IncidentID == cpusys.IncidentID {
+		t.Errorf("late event incident %q, want a new one", late.IncidentID)
+	}
+
+	incident := recentIncident(cpusys.IncidentID)
+	if incident == nil {
+		t.Fatalf("incident %s not stored", cpusys.IncidentID)
+	}
+	data := incident.TracerData.(*IncidentData)
+	if data.Events != 2 || data.Tracers["cpusys"] != 1 || data.Tracers["softirq"] != 1 {
+		t.Errorf("incident data = %+v", data)
+	}
+
+	// the single event incidents are not stored.
+	corr.flush()
+	if recentIncident(container.IncidentID) != nil || recentIncident(late.IncidentID) != nil {
+		t.Errorf("single event incidents stored")
+	}
+}
+
+func TestCorrelationConfig(t *testing.T) {
+	t.Cleanup(func() { closeCorrelator(eventCorrelator.Swap(nil)) })
+
+	for _, c := range []CorrelationConfig{
+		{Window: time.Minute, MaxDuration: time.Second, MinEvents: 2},
+		{Window: time.Minute, MaxDuration: time.Hour},
+	} {
+		if err := SetCorrelationConfig(&c); err == nil {
+			t.Errorf("SetCorrelationConfig(%+v) error = nil", c)
+		}
+	}
+
+	if err := SetCorrelationConfig(&CorrelationConfig{}); err != nil || eventCorrelator.Load() != nil {
+		t.Errorf("zero window did not disable the correlation, error = %v", err)
+	}
+}
+
+func recentIncident(id string) *Document {
+	for _, document := range RecentDocuments() {
+		if document.TracerName == IncidentTracerName && document.IncidentID == id {
+			return document
+		}
+	}
+	return nil
+}
@@ -77,6 +77,7 @@ func (DocumentStoreMapper) Fields(document *Document) (map[string]any, error) {
 		"record_id":                document.TracerID,
 		"hostname":                 document.Hostname,
 		"region":                   document.Region,
+		"node_name":                document.NodeName,
 		"uploaded_time":            document.UploadedTime,
 		"time":                     tracingDocumentTimeValue(document.Time, document.UploadedTime),
 		"container_id":             document.ContainerID,
@@ -88,6 +89,9 @@ func (DocumentStoreMapper) Fields(document *Document) (map[string]any, error) {
 		"tracer_id":                document.TracerID,
 		"tracer_time":              tracingDocumentTimeValue(document.TracerTime, document.UploadedTime),
 		"tracer_type":              document.TracerRunType,
+		"incident_id":              document.IncidentID,
+		"schema_name":              document.SchemaName,
+		"schema_version":           document.SchemaVersion,
 	}, nil
 }
 
@@ -96,6 +100,7 @@ func (DocumentStoreMapper) Indexes() []driver.Index {
 		{Field: "record_id"},
 		{Field: "hostname"},
 		{Field: "region"},
+		{Field: "node_name"},
 		{Field: "uploaded_time"},
 		{Field: "time"},
 		{Field: "container_id"},
@@ -107,5 +112,6 @@ func (DocumentStoreMapper) Indexes() []driver.Index {
 		{Field: "tracer_id"},
 		{Field: "tracer_time"},
 		{Field: "tracer_type"},
+		{Field: "incident_id"},
 	}
 }
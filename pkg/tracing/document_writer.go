@@ -19,12 +19,13 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
-	"os"
 	"time"
 
 	"github.com/rs/xid"
 
+	"huatuo-bamai/internal/hostinfo"
 	"huatuo-bamai/internal/pod"
+	"huatuo-bamai/internal/quota"
 	"huatuo-bamai/internal/storage"
 )
 
@@ -33,6 +34,9 @@ const defaultHostname = "huatuo-dev"
 type documentWriter struct {
 	stores  []*storage.Store[*Document]
 	options DocumentOptions
+	// events is the writer of the tracer events, which capture the
+	// context of the node and count in the namespace quotas.
+	events bool
 }
 
 func newDocumentWriter(
@@ -74,19 +78,58 @@ func (s *documentWriter) saveJSON(req *WriteRequest) error {
 }
 
 func (s *documentWriter) saveRaw(req *WriteRequest) error {
+	schema, err := schemaOf(req.TracerName, req.TracerData)
+	if err != nil {
+		return err
+	}
+
 	document, err := newBaseDocument(s.options, req)
 	if err != nil {
 		return err
 	}
+	if schema != nil {
+		document.SchemaName = schema.Name
+		document.SchemaVersion = schema.Version
+	}
 
 	return s.saveDocument(document)
 }
 
 func (s *documentWriter) saveDocument(document *Document) error {
+	// the enrichment is only paid for the events sampled and admitted.
+	if s.events {
+		if !sampleDocument(document) {
+			return nil
+		}
+		if !quota.AllowEvent(document.ContainerHostNamespace) {
+			return nil
+		}
+	}
+
+	if !enrichDocument(document) {
+		return nil
+	}
+
+	if s.events {
+		captureContext(document)
+		correlateDocument(document)
+	}
+
+	return s.storeDocument(document)
+}
+
+// storeDocument notifies the subscribers and saves the document to every
+// store, those of its route for the events.
+func (s *documentWriter) storeDocument(document *Document) error {
 	NotifySubscribers(document)
 
+	stores := s.stores
+	if s.events {
+		stores = routeStores(stores, document.TracerName)
+	}
+
 	var errs []error
-	for _, store := range s.stores {
+	for _, store := range stores {
 		if store == nil {
 			continue
 		}
@@ -102,7 +145,8 @@ func newBaseDocument(options DocumentOptions, req *WriteRequest) (*Document, err
 	formattedTime := req.TracerTime.Format(tracingDocumentTimeLayout)
 	document := Document{
 		Hostname:      setDocumentHostnameWithDefault(options.Hostname),
-		Region:        options.Region,
+		Region:        setDocumentRegionWithDefault(options.Region),
+		NodeName:      hostinfo.NodeName(),
 		UploadedTime:  time.Now(),
 		Time:          formattedTime,
 		TracerName:    req.TracerName,
@@ -132,6 +176,7 @@ func newBaseDocument(options DocumentOptions, req *WriteRequest) (*Document, err
 	document.ContainerHostNamespace = container.LabelHostNamespace()
 	document.ContainerType = container.Type.String()
 	document.ContainerQoS = container.Qos.String()
+	document.ContainerPodMetadata = container.PodMetadata
 	return &document, nil
 }
 
@@ -140,10 +185,19 @@ func setDocumentHostnameWithDefault(hostname string) string {
 		return hostname
 	}
 
-	detectedHostname, err := os.Hostname()
-	if err != nil {
-		return defaultHostname
+	if detectedHostname := hostinfo.Hostname(); detectedHostname != "" {
+		return detectedHostname
+	}
+
+	return defaultHostname
+}
+
+// setDocumentRegionWithDefault prefers the resolved region, which follows
+// the host when it is re-evaluated.
+func setDocumentRegionWithDefault(region string) string {
+	if resolved := hostinfo.Region(); resolved != "" {
+		return resolved
 	}
 
-	return detectedHostname
+	return region
 }
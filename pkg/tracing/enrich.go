@@ -0,0 +1,156 @@
+// Copyright 2026 The HuaTuo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tracing
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync/atomic"
+
+	"huatuo-bamai/internal/exprlite"
+	"huatuo-bamai/internal/log"
+)
+
+// enrichVar is the variable holding the document in enrichment expressions.
+const enrichVar = "event"
+
+// EnrichRule is the enrichment stage of a tracer, evaluated on each of its
+// documents before they are stored.
+type EnrichRule struct {
+	Tracer string
+	// Drop discards the document when it evaluates to true.
+	Drop string
+	// Fields are computed in order into the enrichment of the document.
+	Fields []EnrichField
+}
+
+// EnrichField is a derived field of a document.
+type EnrichField struct {
+	Name string
+	Expr string
+}
+
+type enrichProgram struct {
+	drop   *exprlite.Program
+	fields []enrichFieldProgram
+}
+
+type enrichFieldProgram struct {
+	name string
+	expr *exprlite.Program
+}
+
+var enrichPrograms atomic.Pointer[map[string][]*enrichProgram]
+
+// SetEnrichRules compiles and installs the enrichment rules, replacing the
+// previous ones. Nothing is installed when an expression does not compile.
+func SetEnrichRules(rules []EnrichRule) error {
+	programs := make(map[string][]*enrichProgram, len(rules))
+	for i := range rules {
+		rule := &rules[i]
+		if rule.Tracer == "" {
+			return fmt.Errorf("enrichment rule %d: tracer is empty", i)
+		}
+
+		program := &enrichProgram{}
+		if rule.Drop != "" {
+			drop, err := exprlite.Compile(rule.Drop, enrichVar)
+			if err != nil {
+				return fmt.Errorf("enrichment rule %s: drop: %w", rule.Tracer, err)
+			}
+			program.drop = drop
+		}
+
+		for _, field := range rule.Fields {
+			if field.Name == "" {
+				return fmt.Errorf("enrichment rule %s: field name is empty", rule.Tracer)
+			}
+			expr, err := exprlite.Compile(field.Expr, enrichVar)
+			if err != nil {
+				return fmt.Errorf("enrichment rule %s: field %s: %w", rule.Tracer, field.Name, err)
+			}
+			program.fields = append(program.fields, enrichFieldProgram{name: field.Name, expr: expr})
+		}
+
+		programs[rule.Tracer] = append(programs[rule.Tracer], program)
+	}
+
+	enrichPrograms.Store(&programs)
+	return nil
+}
+
+// enrichDocument runs the rules of the document tracer, it returns false
+// when the document is dropped. Expressions which fail to evaluate are
+// skipped, they never drop a document.
+func enrichDocument(document *Document) bool {
+	programs := enrichPrograms.Load()
+	if programs == nil {
+		return true
+	}
+	rules := (*programs)[document.TracerName]
+	if len(rules) == 0 {
+		return true
+	}
+
+	// expressions see the document as stored, with the json field names.
+	event, err := documentFields(document)
+	if err != nil {
+		log.Debugf("enrichment %s: %v", document.TracerName, err)
+		return true
+	}
+	vars := map[string]any{enrichVar: event}
+
+	for _, rule := range rules {
+		if rule.drop != nil {
+			drop, err := rule.drop.EvalBool(vars)
+			if err != nil {
+				log.Debugf("enrichment %s: drop %q: %v", document.TracerName, rule.drop, err)
+			} else if drop {
+				return false
+			}
+		}
+
+		for _, field := range rule.fields {
+			v, err := field.expr.Eval(vars)
+			if err != nil {
+				log.Debugf("enrichment %s: field %s %q: %v", document.TracerName, field.name, field.expr, err)
+				continue
+			}
+
+			if document.Enrichment == nil {
+				document.Enrichment = map[string]any{}
+			}
+			document.Enrichment[field.name] = v
+			// later fields and rules can use the fields computed so far.
+			event["enrichment"] = document.Enrichment
+		}
+	}
+
+	return true
+}
+
+// documentFields returns the document as stored, keyed by the json field
+// names.
+func documentFields(document *Document) (map[string]any, error) {
+	raw, err := json.Marshal(document)
+	if err != nil {
+		return nil, fmt.Errorf("encode document: %w", err)
+	}
+	var fields map[string]any
+	if err := json.Unmarshal(raw, &fields); err != nil {
+		return nil, fmt.Errorf("decode document: %w", err)
+	}
+	return fields, nil
+}
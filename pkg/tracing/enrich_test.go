@@ -0,0 +1,86 @@
+// Copyright 2026 The HuaTuo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tracing
+
+import (
+	"testing"
+)
+
+type enrichTestData struct {
+	Pid  int    `json:"pid"`
+	Comm string `json:"comm"`
+}
+
+func TestEnrichDocument(t *testing.T) {
+	t.Cleanup(func() { enrichPrograms.Store(nil) })
+
+	if err := SetEnrichRules([]EnrichRule{
+		{
+			Tracer: "oom",
+			Drop:   `event.tracer_data.comm == "stress"`,
+			Fields: []EnrichField{
+				{Name: "severity", Expr: `event.container_qos == "guaranteed" ? "critical" : "warning"`},
+				{Name: "page", Expr: `event.enrichment.severity == "critical"`},
+				{Name: "broken", Expr: `event.tracer_data.missing + 1`},
+			},
+		},
+	}); err != nil {
+		t.Fatalf("SetEnrichRules() error = %v", err)
+	}
+
+	doc := &Document{
+		TracerName:   "oom",
+		ContainerQoS: "guaranteed",
+		TracerData:   &enrichTestData{Pid: 1, Comm: "java"},
+	}
+	if !enrichDocument(doc) {
+		t.Fatalf("enrichDocument() dropped %+v", doc)
+	}
+	if doc.Enrichment["severity"] != "critical" || doc.Enrichment["page"] != true {
+		t.Errorf("Enrichment = %v, want severity critical and page true", doc.Enrichment)
+	}
+	if _, ok := doc.Enrichment["broken"]; ok {
+		t.Errorf("failed field must be skipped, got %v", doc.Enrichment)
+	}
+
+	dropped := &Document{TracerName: "oom", TracerData: &enrichTestData{Comm: "stress"}}
+	if enrichDocument(dropped) {
+		t.Errorf("enrichDocument() kept %+v, want dropped", dropped)
+	}
+
+	other := &Document{TracerName: "hungtask", TracerData: &enrichTestData{Comm: "stress"}}
+	if !enrichDocument(other) || other.Enrichment != nil {
+		t.Errorf("rules of another tracer must not apply: %+v", other)
+	}
+}
+
+func TestSetEnrichRulesError(t *testing.T) {
+	t.Cleanup(func() { enrichPrograms.Store(nil) })
+
+	tests := []EnrichRule{
+		{Drop: "true"},
+		{Tracer: "oom", Drop: "event.x =="},
+		{Tracer: "oom", Fields: []EnrichField{{Name: "", Expr: "1"}}},
+		{Tracer: "oom", Fields: []EnrichField{{Name: "x", Expr: "other.x"}}},
+	}
+	for _, rule := range tests {
+		if err := SetEnrichRules([]EnrichRule{rule}); err == nil {
+			t.Errorf("SetEnrichRules(%+v) error = nil", rule)
+		}
+	}
+	if enrichPrograms.Load() != nil {
+		t.Errorf("invalid rules must not be installed")
+	}
+}
@@ -30,6 +30,9 @@ var (
 	ErrInvalidTracer = errors.New("invalid tracer")
 	// ErrManagerClosed indicates that the manager no longer accepts starts.
 	ErrManagerClosed = errors.New("manager closed")
+	// ErrInvalidTracerData indicates that a tracer data does not match the
+	// schemas of its tracer.
+	ErrInvalidTracerData = errors.New("invalid tracer data")
 )
 
 func newTracerStateError(err error, name string) error {
@@ -28,6 +28,8 @@ type Manager struct {
 	mu       sync.RWMutex
 	runners  map[string]*eventRunner
 	isClosed bool
+	// stopBackpressure stops the backlog check of StartBackpressure.
+	stopBackpressure context.CancelFunc
 }
 
 // NewManager initializes all registered tracers that are not blacklisted.
@@ -80,8 +82,10 @@ func (m *Manager) Start(ctx context.Context) error {
 	}
 
 	var errs []error
-	for _, runner := range m.runners {
-		if err := runner.start(ctx); err != nil {
+	for name, runner := range m.runners {
+		err := runner.start(ctx)
+		Audit(ctx, AuditActionStart, name, "", err)
+		if err != nil {
 			errs = append(errs, err)
 		}
 	}
@@ -89,8 +93,15 @@ func (m *Manager) Start(ctx context.Context) error {
 	return errors.Join(errs...)
 }
 
-// StartByName starts a registered tracer.
+// StartByName starts a registered tracer, the start is audited with the
+// actor of ctx.
 func (m *Manager) StartByName(ctx context.Context, name string) error {
+	err := m.startByName(ctx, name)
+	Audit(ctx, AuditActionStart, name, "", err)
+	return err
+}
+
+func (m *Manager) startByName(ctx context.Context, name string) error {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
 
@@ -116,6 +127,10 @@ func (m *Manager) StartByName(ctx context.Context, name string) error {
 func (m *Manager) Close(ctx context.Context) error {
 	m.mu.Lock()
 	m.isClosed = true
+	if m.stopBackpressure != nil {
+		m.stopBackpressure()
+		m.stopBackpressure = nil
+	}
 
 	type pendingStop struct {
 		name string
@@ -137,7 +152,9 @@ func (m *Manager) Close(ctx context.Context) error {
 	m.mu.Unlock()
 
 	for _, runner := range pending {
-		if err := waitForRunner(ctx, runner.name, runner.done); err != nil {
+		err := waitForRunner(ctx, runner.name, runner.done)
+		Audit(ctx, AuditActionStop, runner.name, "manager closed", err)
+		if err != nil {
 			errs = append(errs, err)
 		}
 	}
@@ -145,16 +162,22 @@ func (m *Manager) Close(ctx context.Context) error {
 	return errors.Join(errs...)
 }
 
-// StopByName stops a registered tracer and waits for its goroutine to exit.
+// StopByName stops a registered tracer and waits for its goroutine to exit,
+// the stop is audited with the actor of ctx.
 func (m *Manager) StopByName(ctx context.Context, name string) error {
 	m.mu.RLock()
 	runner, ok := m.runners[name]
 	m.mu.RUnlock()
-	if !ok {
-		return newTracerStateError(ErrTracerNotFound, name)
+
+	var err error
+	if ok {
+		err = runner.stop(ctx)
+	} else {
+		err = newTracerStateError(ErrTracerNotFound, name)
 	}
 
-	return runner.stop(ctx)
+	Audit(ctx, AuditActionStop, name, "", err)
+	return err
 }
 
 // Snapshots returns lifecycle snapshots for all registered tracers.
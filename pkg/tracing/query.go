@@ -0,0 +1,78 @@
+// Copyright 2026 The HuaTuo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tracing
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"time"
+
+	"huatuo-bamai/internal/storage"
+	"huatuo-bamai/internal/storage/driver"
+)
+
+// EventQuery selects the stored events, newest first. Empty fields match
+// all.
+type EventQuery struct {
+	Tracers     []string
+	ContainerID string
+	// Since and Until bound the time of the events, Until excluded.
+	Since  time.Time
+	Until  time.Time
+	Limit  int
+	Offset int
+}
+
+// ErrEventQueryDisabled is returned when no queryable event store is
+// configured.
+var ErrEventQueryDisabled = errors.New("tracing event query is disabled")
+
+var eventQueryStore atomic.Pointer[storage.Store[*Document]]
+
+// SetEventQueryStore configures the store the events are queried from, nil
+// disables the queries. The store is one of the tracing stores, it is
+// closed with them.
+func SetEventQueryStore(store *storage.Store[*Document]) {
+	eventQueryStore.Store(store)
+}
+
+// QueryEvents returns the stored events matching q.
+func QueryEvents(ctx context.Context, q *EventQuery) ([]*Document, error) {
+	store := eventQueryStore.Load()
+	if store == nil {
+		return nil, ErrEventQueryDisabled
+	}
+
+	query := driver.Query{
+		Sorts:  []driver.Sort{{Field: "time", Desc: true}},
+		Limit:  q.Limit,
+		Offset: q.Offset,
+	}
+	if len(q.Tracers) > 0 {
+		query.Filters = append(query.Filters, driver.Filter{Field: "tracer_name", Op: driver.OpIn, Value: q.Tracers})
+	}
+	if q.ContainerID != "" {
+		query.Filters = append(query.Filters, driver.Filter{Field: "container_id", Op: driver.OpEq, Value: q.ContainerID})
+	}
+	if !q.Since.IsZero() {
+		query.Filters = append(query.Filters, driver.Filter{Field: "time", Op: driver.OpGte, Value: q.Since.UTC()})
+	}
+	if !q.Until.IsZero() {
+		query.Filters = append(query.Filters, driver.Filter{Field: "time", Op: driver.OpLt, Value: q.Until.UTC()})
+	}
+
+	return store.Query(ctx, query)
+}
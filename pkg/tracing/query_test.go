@@ -0,0 +1,85 @@
+// Copyright 2026 The HuaTuo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tracing
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"huatuo-bamai/internal/storage"
+	"huatuo-bamai/internal/storage/driver"
+)
+
+func TestQueryEvents(t *testing.T) {
+	store, err := storage.NewFromConfig(context.Background(), &driver.Config{
+		Driver:    "sqlite",
+		SQLiteDSN: filepath.Join(t.TempDir(), "events.db"),
+	}, DocumentCollection, DocumentStoreMapper{})
+	if err != nil {
+		t.Fatalf("new event store: %v", err)
+	}
+	SetEventQueryStore(store)
+	t.Cleanup(func() {
+		SetEventQueryStore(nil)
+		_ = store.Close(context.Background())
+	})
+
+	now := time.Now()
+	for _, doc := range []*Document{
+		{TracerID: "1", TracerName: "oom", Time: now.Add(-48 * time.Hour).Format(tracingDocumentTimeLayout)},
+		{TracerID: "2", TracerName: "oom", Time: now.Add(-2 * time.Hour).Format(tracingDocumentTimeLayout), ContainerID: "c1"},
+		{TracerID: "3", TracerName: "softirq", Time: now.Add(-time.Hour).Format(tracingDocumentTimeLayout)},
+		{TracerID: "4", TracerName: "netdev", Time: now.Add(-time.Hour).Format(tracingDocumentTimeLayout)},
+	} {
+		if err := store.Save(context.Background(), doc); err != nil {
+			t.Fatalf("Save(%s) error = %v", doc.TracerID, err)
+		}
+	}
+
+	ids := func(q *EventQuery) []string {
+		t.Helper()
+		docs, err := QueryEvents(context.Background(), q)
+		if err != nil {
+			t.Fatalf("QueryEvents(%+v) error = %v", q, err)
+		}
+		var ids []string
+		for _, doc := range docs {
+			ids = append(ids, doc.TracerID)
+		}
+		return ids
+	}
+
+	if got := ids(&EventQuery{Tracers: []string{"oom", "softirq"}, Since: now.Add(-24 * time.Hour)}); len(got) != 2 || got[0] != "3" || got[1] != "2" {
+		t.Errorf("QueryEvents(last day) = %v, want [3 2]", got)
+	}
+	if got := ids(&EventQuery{Until: now.Add(-24 * time.Hour)}); len(got) != 1 || got[0] != "1" {
+		t.Errorf("QueryEvents(until) = %v, want [1]", got)
+	}
+	if got := ids(&EventQuery{ContainerID: "c1"}); len(got) != 1 || got[0] != "2" {
+		t.Errorf("QueryEvents(container) = %v, want [2]", got)
+	}
+	if got := ids(&EventQuery{Limit: 2, Offset: 1}); len(got) != 2 || got[1] != "2" {
+		t.Errorf("QueryEvents(page) = %v, want 2 events ending with 2", got)
+	}
+}
+
+func TestQueryEventsDisabled(t *testing.T) {
+	if _, err := QueryEvents(context.Background(), &EventQuery{}); !errors.Is(err, ErrEventQueryDisabled) {
+		t.Errorf("QueryEvents() error = %v, want %v", err, ErrEventQueryDisabled)
+	}
+}
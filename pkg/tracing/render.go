@@ -0,0 +1,151 @@
+// Copyright 2026 The HuaTuo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tracing
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"text/template"
+
+	"huatuo-bamai/internal/log"
+)
+
+// RenderDefault is the tracer of the template rendering the tracers
+// without their own.
+const RenderDefault = "*"
+
+const (
+	defaultRenderTemplate = `{{.tracer_name}} on {{.hostname}}` +
+		`{{with .container_hostname}} in {{.}}{{with $.container_host_namespace}}/{{.}}{{end}}{{end}}` +
+		` at {{.tracer_time}}`
+	// renderMaxBytes bounds a summary, a template ranging over a large
+	// tracer data must not flood the notifications.
+	renderMaxBytes = 4096
+)
+
+// RenderTemplate is a Go text/template rendering the documents of a tracer
+// into a human readable summary. The template sees the document as stored,
+// e.g. {{.tracer_name}} or {{.tracer_data.pid}}.
+type RenderTemplate struct {
+	Tracer   string
+	Template string
+}
+
+var renderFuncs = template.FuncMap{
+	// default returns def when v is missing or empty.
+	"default": func(def, v any) any {
+		if v == nil || v == "" {
+			return def
+		}
+		return v
+	},
+	"upper": strings.ToUpper,
+	"lower": strings.ToLower,
+	"json": func(v any) (string, error) {
+		raw, err := json.Marshal(v)
+		return string(raw), err
+	},
+}
+
+type renderTemplates struct {
+	tracers map[string]*template.Template
+	def     *template.Template
+}
+
+var renderers atomic.Pointer[renderTemplates]
+
+func parseRenderTemplate(name, text string) (*template.Template, error) {
+	return template.New(name).Funcs(renderFuncs).Option("missingkey=zero").Parse(text)
+}
+
+var builtinRenderTemplate = template.Must(parseRenderTemplate(RenderDefault, defaultRenderTemplate))
+
+// SetRenderTemplates parses and installs the templates, replacing the
+// previous ones. Nothing is installed when a template does not parse.
+func SetRenderTemplates(templates []RenderTemplate) error {
+	r := &renderTemplates{
+		tracers: make(map[string]*template.Template, len(templates)),
+		def:     builtinRenderTemplate,
+	}
+
+	for i := range templates {
+		t := &templates[i]
+		if t.Tracer == "" {
+			return fmt.Errorf("render template %d: tracer is empty", i)
+		}
+		if _, ok := r.tracers[t.Tracer]; ok {
+			return fmt.Errorf("render template %s is duplicated", t.Tracer)
+		}
+
+		tmpl, err := parseRenderTemplate(t.Tracer, t.Template)
+		if err != nil {
+			return fmt.Errorf("render template %s: %w", t.Tracer, err)
+		}
+		r.tracers[t.Tracer] = tmpl
+	}
+	if def, ok := r.tracers[RenderDefault]; ok {
+		r.def = def
+		delete(r.tracers, RenderDefault)
+	}
+
+	renderers.Store(r)
+	return nil
+}
+
+func execRenderTemplate(tmpl *template.Template, fields map[string]any) (string, error) {
+	var b strings.Builder
+	if err := tmpl.Execute(&b, fields); err != nil {
+		return "", err
+	}
+
+	summary := strings.TrimSpace(b.String())
+	if len(summary) > renderMaxBytes {
+		summary = strings.ToValidUTF8(summary[:renderMaxBytes], "") + "..."
+	}
+	return summary, nil
+}
+
+// RenderDocument renders the document into a human readable summary, by
+// the template of its tracer or the default one. A template failing on the
+// document falls back to the built-in template.
+func RenderDocument(document *Document) string {
+	tmpl := builtinRenderTemplate
+	if r := renderers.Load(); r != nil {
+		tmpl = r.def
+		if t, ok := r.tracers[document.TracerName]; ok {
+			tmpl = t
+		}
+	}
+
+	fields, err := documentFields(document)
+	if err != nil {
+		log.Debugf("render %s: %v", document.TracerName, err)
+		return document.TracerName
+	}
+
+	summary, err := execRenderTemplate(tmpl, fields)
+	if err == nil {
+		return summary
+	}
+
+	log.Debugf("render %s: %v", document.TracerName, err)
+	summary, err = execRenderTemplate(builtinRenderTemplate, fields)
+	if err != nil {
+		return document.TracerName
+	}
+	return summary
+}
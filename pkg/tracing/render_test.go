@@ -0,0 +1,90 @@
+// Copyright 2026 The HuaTuo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tracing
+
+import (
+	"strings"
+	"testing"
+)
+
+type renderTestData struct {
+	Pid     int    `json:"pid"`
+	Comm    string `json:"comm"`
+	Message string `json:"message,omitempty"`
+}
+
+func TestRenderDocument(t *testing.T) {
+	t.Cleanup(func() { renderers.Store(nil) })
+
+	doc := &Document{
+		Hostname:               "node-1",
+		ContainerHostname:      "web-0",
+		ContainerHostNamespace: "shop",
+		TracerName:             "oom",
+		TracerTime:             "2026-01-01 00:00:00",
+		TracerData:             &renderTestData{Pid: 42, Comm: "java"},
+	}
+
+	if got, want := RenderDocument(doc), "oom on node-1 in web-0/shop at 2026-01-01 00:00:00"; got != want {
+		t.Errorf("RenderDocument() without templates = %q, want %q", got, want)
+	}
+
+	if err := SetRenderTemplates([]RenderTemplate{
+		{Tracer: "oom", Template: `{{upper .tracer_name}}: {{.tracer_data.comm}}({{.tracer_data.pid}}) {{default "-" .tracer_data.message}}`},
+		{Tracer: RenderDefault, Template: `[{{.tracer_name}}] {{.hostname}}`},
+		{Tracer: "broken", Template: `{{index .tracer_data 1}}`},
+	}); err != nil {
+		t.Fatalf("SetRenderTemplates() error = %v", err)
+	}
+
+	tests := []struct {
+		tracer string
+		want   string
+	}{
+		{"oom", "OOM: java(42) -"},
+		{"hungtask", "[hungtask] node-1"},
+		// the template fails on the document, the built-in one is used.
+		{"broken", "broken on node-1 in web-0/shop at 2026-01-01 00:00:00"},
+	}
+	for _, tt := range tests {
+		doc.TracerName = tt.tracer
+		if got := RenderDocument(doc); got != tt.want {
+			t.Errorf("RenderDocument(%s) = %q, want %q", tt.tracer, got, tt.want)
+		}
+	}
+
+	doc.TracerName = "oom"
+	doc.TracerData = &renderTestData{Comm: strings.Repeat("x", 2*renderMaxBytes)}
+	if got := RenderDocument(doc); len(got) != renderMaxBytes+len("...") {
+		t.Errorf("RenderDocument() of a large document has %d bytes, want truncated", len(got))
+	}
+}
+
+func TestSetRenderTemplates(t *testing.T) {
+	t.Cleanup(func() { renderers.Store(nil) })
+
+	for _, templates := range [][]RenderTemplate{
+		{{Tracer: "", Template: "x"}},
+		{{Tracer: "oom", Template: "{{.x"}},
+		{{Tracer: "oom", Template: "a"}, {Tracer: "oom", Template: "b"}},
+	} {
+		if err := SetRenderTemplates(templates); err == nil {
+			t.Errorf("SetRenderTemplates(%+v) error = nil", templates)
+		}
+	}
+	if renderers.Load() != nil {
+		t.Error("invalid templates must not be installed")
+	}
+}
@@ -0,0 +1,94 @@
+// Copyright 2026 The HuaTuo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tracing
+
+import (
+	"fmt"
+	"path"
+	"slices"
+	"sync/atomic"
+
+	"huatuo-bamai/internal/storage"
+)
+
+// StoreRoute sends the events of some tracers to some of the tracing stores
+// only, e.g. the high volume tracers to the local files and not to the
+// expensive backends.
+type StoreRoute struct {
+	// Tracers are the tracer names or globs, e.g. "oom*".
+	Tracers []string
+	// Backends are the names of the stores, e.g. "elasticsearch" or
+	// "localfile". The events of a route without backend are not stored.
+	Backends []string
+}
+
+var storeRoutes atomic.Pointer[[]StoreRoute]
+
+// SetStoreRoutes installs the routes of the events, replacing the previous
+// ones. The first route matching the tracer of an event wins, the events of
+// the tracers not routed go to every store.
+func SetStoreRoutes(routes []StoreRoute) error {
+	if len(routes) == 0 {
+		storeRoutes.Store(nil)
+		return nil
+	}
+
+	for i := range routes {
+		if len(routes[i].Tracers) == 0 {
+			return fmt.Errorf("store route %d: tracers are empty", i)
+		}
+		for _, tracer := range routes[i].Tracers {
+			if _, err := path.Match(tracer, ""); err != nil {
+				return fmt.Errorf("store route %d: tracer %q: %w", i, tracer, err)
+			}
+		}
+	}
+
+	routes = slices.Clone(routes)
+	storeRoutes.Store(&routes)
+	return nil
+}
+
+// routeStores returns the stores of the tracer events.
+func routeStores(stores []*storage.Store[*Document], tracer string) []*storage.Store[*Document] {
+	routes := storeRoutes.Load()
+	if routes == nil {
+		return stores
+	}
+
+	for _, route := range *routes {
+		if !matchTracer(route.Tracers, tracer) {
+			continue
+		}
+
+		routed := make([]*storage.Store[*Document], 0, len(route.Backends))
+		for _, store := range stores {
+			if store != nil && slices.Contains(route.Backends, store.Name) {
+				routed = append(routed, store)
+			}
+		}
+		return routed
+	}
+	return stores
+}
+
+func matchTracer(patterns []string, tracer string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, tracer); ok {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,88 @@
+// Copyright 2026 The HuaTuo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tracing
+
+import (
+	"testing"
+
+	"huatuo-bamai/internal/storage"
+)
+
+func storeNames(stores []*storage.Store[*Document]) []string {
+	names := make([]string, 0, len(stores))
+	for _, store := range stores {
+		names = append(names, store.Name)
+	}
+	return names
+}
+
+func TestRouteStores(t *testing.T) {
+	t.Cleanup(func() { storeRoutes.Store(nil) })
+
+	stores := []*storage.Store[*Document]{
+		{Name: "elasticsearch"},
+		{Name: "localfile"},
+		{Name: "loki"},
+	}
+
+	if got := routeStores(stores, "oom"); len(got) != 3 {
+		t.Errorf("routeStores() without routes = %v, want every store", storeNames(got))
+	}
+
+	if err := SetStoreRoutes([]StoreRoute{
+		{Tracers: []string{"softirq"}, Backends: []string{"localfile"}},
+		{Tracers: []string{"oom*", "dload"}, Backends: []string{"elasticsearch", "loki", "kafka"}},
+		{Tracers: []string{"softirq", "netrecvlat"}},
+	}); err != nil {
+		t.Fatalf("SetStoreRoutes() error = %v", err)
+	}
+
+	for tracer, want := range map[string][]string{
+		"softirq":    {"localfile"},
+		"oom_group":  {"elasticsearch", "loki"},
+		"dload":      {"elasticsearch", "loki"},
+		"netrecvlat": {},
+		"hungtask":   {"elasticsearch", "localfile", "loki"},
+	} {
+		got := storeNames(routeStores(stores, tracer))
+		if len(got) != len(want) {
+			t.Errorf("routeStores(%s) = %v, want %v", tracer, got, want)
+			continue
+		}
+		for i := range want {
+			if got[i] != want[i] {
+				t.Errorf("routeStores(%s) = %v, want %v", tracer, got, want)
+				break
+			}
+		}
+	}
+}
+
+func TestSetStoreRoutesError(t *testing.T) {
+	t.Cleanup(func() { storeRoutes.Store(nil) })
+
+	for name, routes := range map[string][]StoreRoute{
+		"no tracer":   {{Backends: []string{"localfile"}}},
+		"bad pattern": {{Tracers: []string{"oom["}, Backends: []string{"localfile"}}},
+	} {
+		if err := SetStoreRoutes(routes); err == nil {
+			t.Errorf("SetStoreRoutes(%s) succeeded", name)
+		}
+	}
+
+	if err := SetStoreRoutes(nil); err != nil || storeRoutes.Load() != nil {
+		t.Errorf("SetStoreRoutes(nil) must remove the routes, err = %v", err)
+	}
+}
@@ -0,0 +1,254 @@
+// Copyright 2026 The HuaTuo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tracing
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"path"
+	"sync"
+	"sync/atomic"
+
+	"golang.org/x/time/rate"
+
+	"huatuo-bamai/internal/exprlite"
+	"huatuo-bamai/internal/log"
+)
+
+// SamplingPolicy samples the events of the high volume tracers before they
+// are stored, e.g. the packet drops. A policy sets one of Every,
+// Probability or Rate, each tracer it matches is sampled on its own.
+type SamplingPolicy struct {
+	// Tracers are the tracer names or globs, e.g. "net*".
+	Tracers []string
+	// Every keeps the first of every Every events.
+	Every int
+	// Probability keeps each event with this probability, in (0, 1].
+	Probability float64
+	// Rate and Burst keep Rate events per second, token bucket.
+	Rate  float64
+	Burst int
+	// Always keeps the events it evaluates to true whatever the sampling,
+	// e.g. the fatal severities. It sees the document as the enrichment
+	// expressions do, before they run.
+	Always string
+}
+
+type samplingPolicy struct {
+	tracers     []string
+	every       uint64
+	probability float64
+	rate        float64
+	burst       int
+	always      *exprlite.Program
+}
+
+// sampler is the sampling state of a tracer.
+type sampler struct {
+	policy  *samplingPolicy
+	every   everySampler
+	limiter *rate.Limiter
+	// skipped are the events dropped by the limiter since the last kept.
+	skipped atomic.Uint64
+}
+
+type samplers struct {
+	policies []*samplingPolicy
+
+	mu sync.Mutex
+	// tracers caches the sampler of each tracer seen, nil if no policy
+	// matches it.
+	tracers map[string]*sampler
+}
+
+var eventSamplers atomic.Pointer[samplers]
+
+// SetSamplingPolicies compiles and installs the sampling policies,
+// replacing the previous ones and their state. The first policy matching
+// the tracer of an event wins, the events of the other tracers are all
+// kept.
+func SetSamplingPolicies(policies []SamplingPolicy) error {
+	if len(policies) == 0 {
+		eventSamplers.Store(nil)
+		return nil
+	}
+
+	compiled := make([]*samplingPolicy, 0, len(policies))
+	for i := range policies {
+		policy, err := compileSamplingPolicy(&policies[i])
+		if err != nil {
+			return fmt.Errorf("sampling policy %d: %w", i, err)
+		}
+		compiled = append(compiled, policy)
+	}
+
+	eventSamplers.Store(&samplers{policies: compiled, tracers: map[string]*sampler{}})
+	return nil
+}
+
+func compileSamplingPolicy(p *SamplingPolicy) (*samplingPolicy, error) {
+	if len(p.Tracers) == 0 {
+		return nil, fmt.Errorf("tracers are empty")
+	}
+	for _, tracer := range p.Tracers {
+		if _, err := path.Match(tracer, ""); err != nil {
+			return nil, fmt.Errorf("tracer %q: %w", tracer, err)
+		}
+	}
+
+	modes := 0
+	for _, set := range []bool{p.Every > 0, p.Probability > 0, p.Rate > 0} {
+		if set {
+			modes++
+		}
+	}
+	if modes != 1 {
+		return nil, fmt.Errorf("one of every, probability and rate must be set")
+	}
+	if p.Every < 0 || p.Probability < 0 || p.Probability > 1 || p.Rate < 0 {
+		return nil, fmt.Errorf("every, probability and rate must be positive, probability at most 1")
+	}
+	if p.Rate > 0 && p.Burst < 1 {
+		return nil, fmt.Errorf("burst must be positive")
+	}
+
+	policy := &samplingPolicy{
+		tracers:     p.Tracers,
+		every:       uint64(p.Every),
+		probability: p.Probability,
+		rate:        p.Rate,
+		burst:       p.Burst,
+	}
+	if p.Always != "" {
+		always, err := exprlite.Compile(p.Always, enrichVar)
+		if err != nil {
+			return nil, fmt.Errorf("always: %w", err)
+		}
+		policy.always = always
+	}
+	return policy, nil
+}
+
+// sampler returns the sampler of the tracer, nil if it is not sampled.
+func (s *samplers) sampler(tracer string) *sampler {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if smp, ok := s.tracers[tracer]; ok {
+		return smp
+	}
+
+	var smp *sampler
+	for _, policy := range s.policies {
+		if !matchTracer(policy.tracers, tracer) {
+			continue
+		}
+		smp = &sampler{policy: policy}
+		if policy.rate > 0 {
+			smp.limiter = rate.NewLimiter(rate.Limit(policy.rate), policy.burst)
+		}
+		break
+	}
+	s.tracers[tracer] = smp
+	return smp
+}
+
+// sampleDocument reports whether the document is kept, and records in it
+// the events it stands for. The policy of the tracer is the base rate and
+// the backpressure raises it, both by the same 1 of N sampler.
+func sampleDocument(document *Document) bool {
+	bp := eventThrottle.Load()
+	bpRate := bp.sampleRate(document.TracerName)
+
+	var smp *sampler
+	if s := eventSamplers.Load(); s != nil {
+		smp = s.sampler(document.TracerName)
+	}
+	if smp == nil {
+		if bpRate == 1 {
+			return true
+		}
+		if !bp.admit(document.TracerName) {
+			return false
+		}
+		document.SampleRate = float64(bpRate)
+		return true
+	}
+
+	keep := smp.sample(document, bpRate)
+	if !keep && bpRate > 1 {
+		bp.dropped.Add(1)
+	}
+	return keep
+}
+
+// sample applies the policy of the sampler raised by the 1 of bpRate
+// sampling of the backpressure.
+func (smp *sampler) sample(document *Document, bpRate uint64) bool {
+	if smp.always(document) {
+		if !smp.every.keep(bpRate) {
+			return false
+		}
+		document.SampleRate = float64(bpRate)
+		return true
+	}
+
+	policy := smp.policy
+	switch {
+	case policy.every > 0:
+		every := policy.every * bpRate
+		if !smp.every.keep(every) {
+			return false
+		}
+		document.SampleRate = float64(every)
+	case policy.probability > 0:
+		probability := policy.probability / float64(bpRate)
+		if rand.Float64() >= probability {
+			return false
+		}
+		document.SampleRate = 1 / probability
+	default:
+		// the tokens are only taken by the events the backpressure keeps.
+		if !smp.every.keep(bpRate) {
+			return false
+		}
+		if !smp.limiter.Allow() {
+			smp.skipped.Add(1)
+			return false
+		}
+		document.SampleRate = float64((smp.skipped.Swap(0) + 1) * bpRate)
+	}
+	return true
+}
+
+// always evaluates the Always expression, the documents it fails on are
+// sampled.
+func (smp *sampler) always(document *Document) bool {
+	if smp.policy.always == nil {
+		return false
+	}
+
+	event, err := documentFields(document)
+	if err != nil {
+		log.Debugf("sampling %s: %v", document.TracerName, err)
+		return false
+	}
+	keep, err := smp.policy.always.EvalBool(map[string]any{enrichVar: event})
+	if err != nil {
+		log.Debugf("sampling %s: always %q: %v", document.TracerName, smp.policy.always, err)
+		return false
+	}
+	return keep
+}
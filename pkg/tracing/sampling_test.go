@@ -0,0 +1,202 @@
+// Copyright 2026 The HuaTuo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tracing
+
+import (
+	"testing"
+
+	"golang.org/x/time/rate"
+)
+
+// sampleN samples n documents of the tracer, it returns the kept ones.
+func sampleN(tracer string, n int, data func(i int) any) []*Document {
+	var kept []*Document
+	for i := 0; i < n; i++ {
+		document := &Document{TracerName: tracer}
+		if data != nil {
+			document.TracerData = data(i)
+		}
+		if sampleDocument(document) {
+			kept = append(kept, document)
+		}
+	}
+	return kept
+}
+
+func TestSampleDocumentEvery(t *testing.T) {
+	t.Cleanup(func() { eventSamplers.Store(nil) })
+
+	if err := SetSamplingPolicies([]SamplingPolicy{
+		{Tracers: []string{"netrecvlat", "drop*"}, Every: 10},
+	}); err != nil {
+		t.Fatalf("SetSamplingPolicies() error = %v", err)
+	}
+
+	for _, tracer := range []string{"netrecvlat", "dropwatch"} {
+		kept := sampleN(tracer, 100, nil)
+		if len(kept) != 10 {
+			t.Errorf("%s kept %d of 100 events, want 10", tracer, len(kept))
+		}
+		for _, document := range kept {
+			if document.SampleRate != 10 {
+				t.Errorf("%s SampleRate = %v, want 10", tracer, document.SampleRate)
+				break
+			}
+		}
+	}
+
+	for _, document := range sampleN("oom", 5, nil) {
+		if document.SampleRate != 0 {
+			t.Errorf("oom SampleRate = %v, want unsampled", document.SampleRate)
+		}
+	}
+	if kept := sampleN("oom", 5, nil); len(kept) != 5 {
+		t.Errorf("oom kept %d of 5 events, want 5", len(kept))
+	}
+}
+
+func TestSampleDocumentProbability(t *testing.T) {
+	t.Cleanup(func() { eventSamplers.Store(nil) })
+
+	if err := SetSamplingPolicies([]SamplingPolicy{
+		{Tracers: []string{"softirq"}, Probability: 0.25},
+	}); err != nil {
+		t.Fatalf("SetSamplingPolicies() error = %v", err)
+	}
+
+	kept := sampleN("softirq", 10000, nil)
+	if len(kept) < 2000 || len(kept) > 3000 {
+		t.Errorf("kept %d of 10000 events, want about 2500", len(kept))
+	}
+	if len(kept) > 0 && kept[0].SampleRate != 4 {
+		t.Errorf("SampleRate = %v, want 4", kept[0].SampleRate)
+	}
+}
+
+func TestSampleDocumentRate(t *testing.T) {
+	t.Cleanup(func() { eventSamplers.Store(nil) })
+
+	if err := SetSamplingPolicies([]SamplingPolicy{
+		{Tracers: []string{"dropwatch"}, Rate: 0.001, Burst: 3},
+	}); err != nil {
+		t.Fatalf("SetSamplingPolicies() error = %v", err)
+	}
+
+	kept := sampleN("dropwatch", 50, nil)
+	if len(kept) != 3 {
+		t.Fatalf("kept %d of 50 events, want the burst of 3", len(kept))
+	}
+	for _, document := range kept {
+		if document.SampleRate != 1 {
+			t.Errorf("SampleRate of the burst = %v, want 1", document.SampleRate)
+		}
+	}
+
+	// the next document kept stands for the events dropped before it.
+	eventSamplers.Load().sampler("dropwatch").limiter.SetLimit(rate.Inf)
+	kept = sampleN("dropwatch", 1, nil)
+	if len(kept) != 1 || kept[0].SampleRate != 48 {
+		t.Errorf("kept %v, want 1 document standing for 48 events", kept)
+	}
+}
+
+func TestSampleDocumentAlways(t *testing.T) {
+	t.Cleanup(func() { eventSamplers.Store(nil) })
+
+	if err := SetSamplingPolicies([]SamplingPolicy{
+		{Tracers: []string{"ras"}, Every: 1000, Always: `event.tracer_data.severity == "fatal"`},
+	}); err != nil {
+		t.Fatalf("SetSamplingPolicies() error = %v", err)
+	}
+
+	kept := sampleN("ras", 10, func(i int) any {
+		if i%2 == 1 {
+			return map[string]any{"severity": "fatal"}
+		}
+		return map[string]any{"severity": "corrected"}
+	})
+	// the first corrected event and the 5 fatal ones.
+	if len(kept) != 6 {
+		t.Fatalf("kept %d of 10 events, want 6", len(kept))
+	}
+	if kept[0].SampleRate != 1000 {
+		t.Errorf("SampleRate of the sampled event = %v, want 1000", kept[0].SampleRate)
+	}
+	for _, document := range kept[1:] {
+		if document.SampleRate != 1 {
+			t.Errorf("SampleRate of an always kept event = %v, want 1", document.SampleRate)
+		}
+	}
+}
+
+func TestSampleDocumentBackpressure(t *testing.T) {
+	t.Cleanup(func() {
+		eventSamplers.Store(nil)
+		eventThrottle.Store(nil)
+	})
+
+	if err := SetSamplingPolicies([]SamplingPolicy{
+		{Tracers: []string{"dropwatch"}, Every: 10},
+		{Tracers: []string{"softirq"}, Probability: 0.5},
+	}); err != nil {
+		t.Fatalf("SetSamplingPolicies() error = %v", err)
+	}
+	bp := newBackpressure(&BackpressureConfig{SampleRate: 4})
+	bp.level.Store(backpressureSample)
+	eventThrottle.Store(bp)
+
+	// the policy rate is raised by the backpressure.
+	kept := sampleN("dropwatch", 400, nil)
+	if len(kept) != 10 || kept[0].SampleRate != 40 {
+		t.Errorf("dropwatch kept %d of 400 events, want 10 at 40", len(kept))
+	}
+	kept = sampleN("softirq", 10000, nil)
+	if len(kept) < 1000 || len(kept) > 1500 || kept[0].SampleRate != 8 {
+		t.Errorf("softirq kept %d of 10000 events, want about 1250 at 8", len(kept))
+	}
+
+	// the tracers without a policy are sampled by the backpressure alone.
+	kept = sampleN("oom", 8, nil)
+	if len(kept) != 2 || kept[0].SampleRate != 4 {
+		t.Errorf("oom kept %d of 8 events, want 2 at 4", len(kept))
+	}
+
+	// the policies alone once the backlog recovers.
+	bp.level.Store(backpressureNone)
+	if kept := sampleN("dropwatch", 100, nil); len(kept) != 10 || kept[0].SampleRate != 10 {
+		t.Errorf("dropwatch kept %d of 100 events without backpressure, want 10", len(kept))
+	}
+	if kept := sampleN("oom", 8, nil); len(kept) != 8 || kept[0].SampleRate != 0 {
+		t.Errorf("oom kept %d of 8 events without backpressure, want all unsampled", len(kept))
+	}
+}
+
+func TestSetSamplingPoliciesError(t *testing.T) {
+	t.Cleanup(func() { eventSamplers.Store(nil) })
+
+	for name, policy := range map[string]SamplingPolicy{
+		"no tracer":       {Every: 2},
+		"bad pattern":     {Tracers: []string{"net["}, Every: 2},
+		"no mode":         {Tracers: []string{"net"}},
+		"two modes":       {Tracers: []string{"net"}, Every: 2, Probability: 0.5},
+		"probability > 1": {Tracers: []string{"net"}, Probability: 2},
+		"no burst":        {Tracers: []string{"net"}, Rate: 10},
+		"bad always":      {Tracers: []string{"net"}, Every: 2, Always: "event.("},
+	} {
+		if err := SetSamplingPolicies([]SamplingPolicy{policy}); err == nil {
+			t.Errorf("SetSamplingPolicies(%s) error = nil", name)
+		}
+	}
+}
@@ -0,0 +1,208 @@
+// Copyright 2026 The HuaTuo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tracing
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/go-playground/validator/v10"
+)
+
+// Schema is the typed tracer data of the documents of a tracer. Its name
+// and version are stored with every document, the consumers parse the
+// tracer data by them, and a tracer bumps the version when a field
+// changes or goes away.
+type Schema struct {
+	Tracer  string        `json:"tracer"`
+	Name    string        `json:"name"`
+	Version int           `json:"version"`
+	Fields  []SchemaField `json:"fields"`
+
+	typ reflect.Type
+}
+
+// SchemaField is a JSON field of the tracer data.
+type SchemaField struct {
+	Name     string `json:"name"`
+	Type     string `json:"type"`
+	Required bool   `json:"required,omitempty"`
+}
+
+// SchemaValidator is implemented by the tracer data checking more than
+// the validate tags of their fields.
+type SchemaValidator interface {
+	Validate() error
+}
+
+var (
+	schemasLock sync.RWMutex
+	// map: tracer -> schemas of its tracer data
+	schemas = make(map[string][]*Schema)
+
+	schemaValidate = validator.New(validator.WithRequiredStructEnabled())
+)
+
+// RegisterSchema registers the struct T, or a pointer to it, as tracer
+// data of the tracer, checked by the validate tags of its fields, see
+// github.com/go-playground/validator, and SchemaValidator. Once a tracer
+// has a schema, the tracer data of another type are rejected.
+func RegisterSchema[T any](tracer, name string, version int) {
+	typ := reflect.TypeFor[T]()
+	for typ.Kind() == reflect.Pointer {
+		typ = typ.Elem()
+	}
+	if typ.Kind() != reflect.Struct {
+		panic(fmt.Sprintf("tracing: schema %s of %s: %s is not a struct", name, tracer, typ))
+	}
+	if name == "" || version < 1 {
+		panic(fmt.Sprintf("tracing: schema of %s: invalid name %q or version %d", tracer, name, version))
+	}
+
+	schemasLock.Lock()
+	defer schemasLock.Unlock()
+
+	for _, s := range schemas[tracer] {
+		if s.typ == typ || s.Name == name {
+			panic(fmt.Sprintf("tracing: schema %s of %s registered twice", name, tracer))
+		}
+	}
+	schemas[tracer] = append(schemas[tracer], &Schema{
+		Tracer:  tracer,
+		Name:    name,
+		Version: version,
+		Fields:  schemaFields(typ),
+		typ:     typ,
+	})
+}
+
+// Schemas returns the schemas registered, by tracer and name.
+func Schemas() []Schema {
+	schemasLock.RLock()
+	defer schemasLock.RUnlock()
+
+	all := make([]Schema, 0, len(schemas))
+	for _, tracerSchemas := range schemas {
+		for _, s := range tracerSchemas {
+			all = append(all, *s)
+		}
+	}
+	slices.SortFunc(all, func(a, b Schema) int {
+		if c := strings.Compare(a.Tracer, b.Tracer); c != 0 {
+			return c
+		}
+		return strings.Compare(a.Name, b.Name)
+	})
+	return all
+}
+
+// schemaOf returns the schema of the tracer data, nil for a tracer
+// without schema, or ErrInvalidTracerData.
+func schemaOf(tracer string, data any) (*Schema, error) {
+	schemasLock.RLock()
+	tracerSchemas := schemas[tracer]
+	schemasLock.RUnlock()
+
+	if len(tracerSchemas) == 0 {
+		return nil, nil
+	}
+
+	v := reflect.ValueOf(data)
+	for v.Kind() == reflect.Pointer && !v.IsNil() {
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("%w: %s: %T is not a registered schema", ErrInvalidTracerData, tracer, data)
+	}
+
+	idx := slices.IndexFunc(tracerSchemas, func(s *Schema) bool { return s.typ == v.Type() })
+	if idx < 0 {
+		return nil, fmt.Errorf("%w: %s: %T is not a registered schema", ErrInvalidTracerData, tracer, data)
+	}
+	schema := tracerSchemas[idx]
+
+	if err := schemaValidate.Struct(data); err != nil {
+		return nil, fmt.Errorf("%w: %s %s/v%d: %w", ErrInvalidTracerData, tracer, schema.Name, schema.Version, err)
+	}
+	if check, ok := data.(SchemaValidator); ok {
+		if err := check.Validate(); err != nil {
+			return nil, fmt.Errorf("%w: %s %s/v%d: %w", ErrInvalidTracerData, tracer, schema.Name, schema.Version, err)
+		}
+	}
+
+	return schema, nil
+}
+
+// schemaFields returns the JSON fields of the struct, those of the
+// embedded structs inlined as encoding/json does.
+func schemaFields(typ reflect.Type) []SchemaField {
+	var fields []SchemaField
+	for i := range typ.NumField() {
+		f := typ.Field(i)
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "-" || (!f.IsExported() && !f.Anonymous) {
+			continue
+		}
+
+		ft := f.Type
+		for ft.Kind() == reflect.Pointer {
+			ft = ft.Elem()
+		}
+		if f.Anonymous && name == "" && ft.Kind() == reflect.Struct {
+			fields = append(fields, schemaFields(ft)...)
+			continue
+		}
+		if !f.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+
+		required := slices.Contains(strings.Split(f.Tag.Get("validate"), ","), "required")
+		fields = append(fields, SchemaField{Name: name, Type: schemaFieldType(ft), Required: required})
+	}
+	return fields
+}
+
+// schemaFieldType returns the JSON type of the field.
+func schemaFieldType(typ reflect.Type) string {
+	if typ.PkgPath() == "time" && typ.Name() == "Time" {
+		return "string"
+	}
+
+	switch typ.Kind() {
+	case reflect.Bool:
+		return "boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return "integer"
+	case reflect.Float32, reflect.Float64:
+		return "number"
+	case reflect.String:
+		return "string"
+	case reflect.Slice, reflect.Array:
+		if typ.Elem().Kind() == reflect.Uint8 {
+			// encoding/json encodes the bytes in base64.
+			return "string"
+		}
+		return "array"
+	default:
+		return "object"
+	}
+}
@@ -0,0 +1,179 @@
+// Copyright 2026 The HuaTuo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tracing
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"huatuo-bamai/internal/storage"
+	"huatuo-bamai/internal/storage/driver"
+)
+
+type schemaTestBase struct {
+	Comm string `json:"comm" validate:"required"`
+}
+
+type schemaTestData struct {
+	schemaTestBase
+	Pid     int       `json:"pid" validate:"gte=1"`
+	Latency float64   `json:"latency_ms,omitempty"`
+	Stack   []string  `json:"stack"`
+	Raw     []byte    `json:"raw"`
+	Time    time.Time `json:"time"`
+	Ignored string    `json:"-"`
+}
+
+func (d *schemaTestData) Validate() error {
+	if d.Comm == "forbidden" {
+		return errors.New("forbidden comm")
+	}
+	return nil
+}
+
+type schemaTestOther struct {
+	Reason string `json:"reason"`
+}
+
+func resetSchemas(t *testing.T) {
+	saved := schemas
+	schemas = make(map[string][]*Schema)
+	t.Cleanup(func() { schemas = saved })
+}
+
+func TestRegisterSchema(t *testing.T) {
+	resetSchemas(t)
+
+	RegisterSchema[*schemaTestData]("test", "test", 2)
+	RegisterSchema[schemaTestOther]("test", "test_other", 1)
+	RegisterSchema[schemaTestOther]("another", "another", 1)
+
+	all := Schemas()
+	if len(all) != 3 || all[0].Tracer != "another" || all[1].Name != "test" || all[2].Name != "test_other" {
+		t.Fatalf("Schemas() = %+v, want sorted by tracer and name", all)
+	}
+
+	want := []SchemaField{
+		{Name: "comm", Type: "string", Required: true},
+		{Name: "pid", Type: "integer"},
+		{Name: "latency_ms", Type: "number"},
+		{Name: "stack", Type: "array"},
+		{Name: "raw", Type: "string"},
+		{Name: "time", Type: "string"},
+	}
+	if !reflect.DeepEqual(all[1].Fields, want) {
+		t.Errorf("Fields = %+v, want %+v", all[1].Fields, want)
+	}
+
+	for name, register := range map[string]func(){
+		"twice":      func() { RegisterSchema[schemaTestData]("test", "again", 1) },
+		"same name":  func() { RegisterSchema[schemaTestBase]("test", "test", 1) },
+		"not struct": func() { RegisterSchema[map[string]any]("test", "map", 1) },
+		"no version": func() { RegisterSchema[schemaTestBase]("test", "base", 0) },
+	} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("%s: RegisterSchema() did not panic", name)
+				}
+			}()
+			register()
+		}()
+	}
+}
+
+func TestSchemaOf(t *testing.T) {
+	resetSchemas(t)
+	RegisterSchema[schemaTestData]("test", "test", 2)
+
+	valid := &schemaTestData{schemaTestBase: schemaTestBase{Comm: "stress"}, Pid: 1}
+	if s, err := schemaOf("test", valid); err != nil || s == nil || s.Name != "test" || s.Version != 2 {
+		t.Errorf("schemaOf(valid) = %+v, %v, want test/v2", s, err)
+	}
+	if s, err := schemaOf("test", *valid); err != nil || s == nil {
+		t.Errorf("schemaOf(value) = %+v, %v, want test/v2", s, err)
+	}
+	if s, err := schemaOf("unknown", map[string]any{}); err != nil || s != nil {
+		t.Errorf("schemaOf(no schema) = %+v, %v, want none", s, err)
+	}
+
+	for name, data := range map[string]any{
+		"other type": &schemaTestOther{},
+		"map":        map[string]any{"comm": "stress"},
+		"nil":        (*schemaTestData)(nil),
+		"required":   &schemaTestData{Pid: 1},
+		"tag":        &schemaTestData{schemaTestBase: schemaTestBase{Comm: "stress"}},
+		"validate":   &schemaTestData{schemaTestBase: schemaTestBase{Comm: "forbidden"}, Pid: 1},
+	} {
+		if _, err := schemaOf("test", data); !errors.Is(err, ErrInvalidTracerData) {
+			t.Errorf("schemaOf(%s) error = %v, want %v", name, err, ErrInvalidTracerData)
+		}
+	}
+}
+
+func TestSaveRawSchema(t *testing.T) {
+	resetSchemas(t)
+	RegisterSchema[schemaTestData]("test", "test", 2)
+
+	store, err := storage.NewFromConfig(context.Background(), &driver.Config{
+		Driver:    "sqlite",
+		SQLiteDSN: filepath.Join(t.TempDir(), "events.db"),
+	}, DocumentCollection, DocumentStoreMapper{})
+	if err != nil {
+		t.Fatalf("new event store: %v", err)
+	}
+	SetEventQueryStore(store)
+	t.Cleanup(func() {
+		SetEventQueryStore(nil)
+		_ = store.Close(context.Background())
+	})
+
+	w := newDocumentWriter([]*storage.Store[*Document]{store}, DocumentOptions{Hostname: "node"})
+	for _, req := range []*WriteRequest{
+		{TracerName: "test", TracerID: "valid", TracerTime: time.Now(), TracerData: &schemaTestData{
+			schemaTestBase: schemaTestBase{Comm: "stress"}, Pid: 1,
+		}},
+		{TracerName: "unregistered", TracerID: "free", TracerTime: time.Now(), TracerData: map[string]any{"any": 1}},
+	} {
+		if err := w.saveRaw(req); err != nil {
+			t.Fatalf("saveRaw(%s) error = %v", req.TracerID, err)
+		}
+	}
+	if err := w.saveRaw(&WriteRequest{TracerName: "test", TracerID: "invalid", TracerData: &schemaTestData{}}); !errors.Is(err, ErrInvalidTracerData) {
+		t.Errorf("saveRaw(invalid) error = %v, want %v", err, ErrInvalidTracerData)
+	}
+
+	docs, err := QueryEvents(context.Background(), &EventQuery{})
+	if err != nil {
+		t.Fatalf("QueryEvents() error = %v", err)
+	}
+	got := make(map[string]*Document)
+	for _, doc := range docs {
+		got[doc.TracerID] = doc
+	}
+	if len(got) != 2 {
+		t.Fatalf("documents = %v, want valid and free", got)
+	}
+	if doc := got["valid"]; doc.SchemaName != "test" || doc.SchemaVersion != 2 {
+		t.Errorf("valid schema = %s/v%d, want test/v2", doc.SchemaName, doc.SchemaVersion)
+	}
+	if doc := got["free"]; doc.SchemaName != "" || doc.SchemaVersion != 0 {
+		t.Errorf("free schema = %s/v%d, want none", doc.SchemaName, doc.SchemaVersion)
+	}
+}
@@ -0,0 +1,151 @@
+// Copyright 2026 The HuaTuo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tracing
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"huatuo-bamai/internal/storage"
+	"huatuo-bamai/internal/storage/driver"
+)
+
+// StateCollection is the storage collection name for the tracer states.
+const StateCollection = "tracing_state"
+
+// StateRecord is the checkpoint of a piece of state of a tracer, e.g. its
+// counters, baselines or rate limiter.
+type StateRecord struct {
+	Tracer string          `json:"tracer"`
+	Key    string          `json:"key"`
+	Time   time.Time       `json:"time"`
+	Data   json.RawMessage `json:"data"`
+}
+
+type stateStoreConfig struct {
+	store  *storage.Store[*StateRecord]
+	maxAge time.Duration
+}
+
+var stateStore atomic.Pointer[stateStoreConfig]
+
+// SetStateStore configures the store of the tracer states, nil disables the
+// persistence. The states older than maxAge are not restored, zero restores
+// them all.
+func SetStateStore(store *storage.Store[*StateRecord], maxAge time.Duration) {
+	if store == nil {
+		stateStore.Store(nil)
+		return
+	}
+	stateStore.Store(&stateStoreConfig{store: store, maxAge: maxAge})
+}
+
+// closeStateStore releases the state store.
+func closeStateStore(ctx context.Context) error {
+	cfg := stateStore.Swap(nil)
+	if cfg == nil {
+		return nil
+	}
+	return cfg.store.Close(ctx)
+}
+
+// SaveState checkpoints v, encoded as JSON, as the state of the tracer under
+// key. It is a no-op when the persistence is disabled.
+func SaveState(ctx context.Context, tracer, key string, v any) error {
+	cfg := stateStore.Load()
+	if cfg == nil {
+		return nil
+	}
+
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("tracing state %s/%s: %w", tracer, key, err)
+	}
+
+	return cfg.store.Save(context.WithoutCancel(ctx), &StateRecord{
+		Tracer: tracer,
+		Key:    key,
+		Time:   time.Now(),
+		Data:   data,
+	})
+}
+
+// LoadState restores into v the state of the tracer saved under key. It
+// returns false, leaving v untouched, when there is none, it is too old or
+// the persistence is disabled.
+func LoadState(ctx context.Context, tracer, key string, v any) (bool, error) {
+	cfg := stateStore.Load()
+	if cfg == nil {
+		return false, nil
+	}
+
+	record, err := cfg.store.Get(ctx, stateID(tracer, key))
+	if err != nil {
+		if errors.Is(err, driver.ErrNotFound) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	if cfg.maxAge > 0 && time.Since(record.Time) > cfg.maxAge {
+		return false, nil
+	}
+
+	if err := json.Unmarshal(record.Data, v); err != nil {
+		return false, fmt.Errorf("tracing state %s/%s: %w", tracer, key, err)
+	}
+	return true, nil
+}
+
+func stateID(tracer, key string) string {
+	return tracer + "/" + key
+}
+
+// StateStoreMapper maps the tracer states to storage records.
+type StateStoreMapper struct{}
+
+func (StateStoreMapper) ID(record *StateRecord) string {
+	return stateID(record.Tracer, record.Key)
+}
+
+func (StateStoreMapper) Encode(record *StateRecord) ([]byte, error) {
+	return json.Marshal(record)
+}
+
+func (StateStoreMapper) Decode(data []byte) (*StateRecord, error) {
+	var record StateRecord
+	if err := json.Unmarshal(data, &record); err != nil {
+		return nil, err
+	}
+
+	return &record, nil
+}
+
+func (StateStoreMapper) Fields(record *StateRecord) (map[string]any, error) {
+	return map[string]any{
+		"tracer": record.Tracer,
+		"time":   record.Time.UTC(),
+	}, nil
+}
+
+func (StateStoreMapper) Indexes() []driver.Index {
+	return []driver.Index{
+		{Field: "tracer"},
+	}
+}
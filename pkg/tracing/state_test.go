@@ -0,0 +1,105 @@
+// Copyright 2026 The HuaTuo Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tracing
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"huatuo-bamai/internal/storage"
+	"huatuo-bamai/internal/storage/driver"
+)
+
+type testState struct {
+	Counter  int64     `json:"counter"`
+	Baseline float64   `json:"baseline"`
+	Until    time.Time `json:"until"`
+}
+
+func newTestStateStore(t *testing.T, path string, maxAge time.Duration) {
+	t.Helper()
+
+	store, err := storage.NewFromConfig(context.Background(), &driver.Config{
+		Driver:    "sqlite",
+		SQLiteDSN: path,
+	}, StateCollection, StateStoreMapper{})
+	if err != nil {
+		t.Fatalf("new state store: %v", err)
+	}
+	SetStateStore(store, maxAge)
+}
+
+func TestStateRestart(t *testing.T) {
+	t.Cleanup(func() { _ = closeStateStore(context.Background()) })
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "state.db")
+
+	newTestStateStore(t, path, time.Hour)
+	want := testState{Counter: 42, Baseline: 1.5, Until: time.Now().Add(time.Minute).UTC().Round(time.Millisecond)}
+	if err := SaveState(ctx, "hungtask", "limiter", &want); err != nil {
+		t.Fatalf("SaveState() error = %v", err)
+	}
+	want.Counter = 43
+	if err := SaveState(ctx, "hungtask", "limiter", &want); err != nil {
+		t.Fatalf("SaveState() again error = %v", err)
+	}
+	if err := closeStateStore(ctx); err != nil {
+		t.Fatalf("closeStateStore() error = %v", err)
+	}
+
+	// the agent restarts on the same file.
+	newTestStateStore(t, path, time.Hour)
+
+	var got testState
+	ok, err := LoadState(ctx, "hungtask", "limiter", &got)
+	if err != nil || !ok {
+		t.Fatalf("LoadState() = %v, %v, want the saved state", ok, err)
+	}
+	if got.Counter != 43 || got.Baseline != 1.5 || !got.Until.Equal(want.Until) {
+		t.Errorf("LoadState() = %+v, want %+v", got, want)
+	}
+
+	if ok, err := LoadState(ctx, "hungtask", "other", &got); ok || err != nil {
+		t.Errorf("LoadState() of an unknown key = %v, %v, want false", ok, err)
+	}
+}
+
+func TestStateMaxAge(t *testing.T) {
+	t.Cleanup(func() { _ = closeStateStore(context.Background()) })
+	ctx := context.Background()
+
+	newTestStateStore(t, filepath.Join(t.TempDir(), "state.db"), time.Nanosecond)
+	if err := SaveState(ctx, "hungtask", "limiter", &testState{Counter: 1}); err != nil {
+		t.Fatalf("SaveState() error = %v", err)
+	}
+	time.Sleep(time.Millisecond)
+
+	got := testState{Counter: 7}
+	if ok, err := LoadState(ctx, "hungtask", "limiter", &got); ok || err != nil || got.Counter != 7 {
+		t.Errorf("LoadState() of a stale state = %v, %v, %+v, want it ignored", ok, err, got)
+	}
+}
+
+func TestStateDisabled(t *testing.T) {
+	ctx := context.Background()
+	if err := SaveState(ctx, "hungtask", "limiter", &testState{}); err != nil {
+		t.Errorf("SaveState() without store error = %v", err)
+	}
+	if ok, err := LoadState(ctx, "hungtask", "limiter", &testState{}); ok || err != nil {
+		t.Errorf("LoadState() without store = %v, %v, want false", ok, err)
+	}
+}
@@ -60,6 +60,7 @@ func SetTracingStore(stores []*storage.Store[*Document], options DocumentOptions
 	}
 
 	tracingDataWriter = newDocumentWriter(stores, options)
+	tracingDataWriter.events = true
 }
 
 // Save writes tracing data when a tracing document store is configured.
@@ -128,11 +129,56 @@ func SaveTaskOutputJSON(req *WriteRequest) error {
 	return taskDataWriter.saveJSON(req)
 }
 
-// CloseStores flushes and releases every configured tracing/task store. The
-// same Store may be registered under both writers; close it only once. All
-// close errors are joined and returned so the caller can observe every
-// failure.
+// StoreState is a store the documents are written to.
+type StoreState struct {
+	// Writer is the documents of the store: "events", "tasks", "profiles",
+	// "audit" or "state".
+	Writer  string `json:"writer"`
+	Backend string `json:"backend"`
+	// Backlog is the documents saved and not yet delivered, nil when the
+	// backend writes synchronously.
+	Backlog *int64 `json:"backlog,omitempty"`
+}
+
+// StoreStates returns the stores configured.
+func StoreStates() []StoreState {
+	var states []StoreState
+	add := func(writer string, w *documentWriter) {
+		if w == nil {
+			return
+		}
+		for _, store := range w.stores {
+			if store == nil {
+				continue
+			}
+			state := StoreState{Writer: writer, Backend: store.Name}
+			if backlog, ok := store.Backlog(); ok {
+				state.Backlog = &backlog
+			}
+			states = append(states, state)
+		}
+	}
+
+	add("events", tracingDataWriter)
+	add("tasks", taskDataWriter)
+	add("profiles", profileDataWriter)
+	if store := auditStore.Load(); store != nil {
+		states = append(states, StoreState{Writer: "audit", Backend: store.Name})
+	}
+	if cfg := stateStore.Load(); cfg != nil {
+		states = append(states, StoreState{Writer: "state", Backend: cfg.store.Name})
+	}
+	return states
+}
+
+// CloseStores flushes and releases every configured tracing/task, audit and
+// state store. The same Store may be registered under both writers; close it only
+// once. All close errors are joined and returned so the caller can observe
+// every failure.
 func CloseStores(ctx context.Context) error {
+	// the open incidents are stored before the stores flush.
+	closeCorrelator(eventCorrelator.Swap(nil))
+
 	seen := make(map[*storage.Store[*Document]]struct{})
 	var errs []error
 
@@ -160,5 +206,11 @@ func CloseStores(ctx context.Context) error {
 	if profileDataWriter != nil {
 		closeAll(profileDataWriter.stores)
 	}
+	if err := closeAuditStore(ctx); err != nil {
+		errs = append(errs, err)
+	}
+	if err := closeStateStore(ctx); err != nil {
+		errs = append(errs, err)
+	}
 	return errors.Join(errs...)
 }
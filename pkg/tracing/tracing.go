@@ -19,6 +19,7 @@ import (
 	"context"
 	"errors"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"huatuo-bamai/internal/log"
@@ -39,6 +40,21 @@ type eventRunner struct {
 	cancel   context.CancelFunc
 	done     <-chan struct{}
 	runCount int
+	// config is the config of the tracer when it last started.
+	config any
+}
+
+var tracerConfigFunc atomic.Pointer[func(tracer string) any]
+
+// SetTracerConfigFunc sets the function returning the config of a tracer,
+// nil if it has none. The config is recorded in the snapshots every time
+// the tracer starts, the tracers read their config when they start.
+func SetTracerConfigFunc(fn func(tracer string) any) {
+	if fn == nil {
+		tracerConfigFunc.Store(nil)
+		return
+	}
+	tracerConfigFunc.Store(&fn)
 }
 
 func newEventRunner(
@@ -82,6 +98,7 @@ func (r *eventRunner) run(ctx context.Context, done chan<- struct{}) {
 	defer r.finish(done)
 
 	for {
+		r.recordConfig()
 		err := r.starter.Start(ctx)
 		r.incrementRunCount()
 
@@ -118,6 +135,18 @@ func (r *eventRunner) finish(done chan<- struct{}) {
 	log.WithField("tracer", r.name).Info("tracer stopped")
 }
 
+func (r *eventRunner) recordConfig() {
+	fn := tracerConfigFunc.Load()
+	if fn == nil {
+		return
+	}
+
+	config := (*fn)(r.name)
+	r.mu.Lock()
+	r.config = config
+	r.mu.Unlock()
+}
+
 func (r *eventRunner) incrementRunCount() {
 	r.mu.Lock()
 	r.runCount++
@@ -170,6 +199,8 @@ type LifecycleSnapshot struct {
 	RunCount        int    `json:"hit"`
 	RestartInterval int    `json:"restart_interval"`
 	Roles           uint32 `json:"flag"`
+	// Config is the config the tracer last started with.
+	Config any `json:"config,omitempty"`
 }
 
 func (r *eventRunner) snapshot() LifecycleSnapshot {
@@ -182,5 +213,6 @@ func (r *eventRunner) snapshot() LifecycleSnapshot {
 		RunCount:        r.runCount,
 		RestartInterval: int(r.restartInterval / time.Second),
 		Roles:           r.roles,
+		Config:          r.config,
 	}
 }
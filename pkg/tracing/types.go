@@ -20,6 +20,7 @@ import "time"
 type Document struct {
 	Hostname     string    `json:"hostname"`
 	Region       string    `json:"region"`
+	NodeName     string    `json:"node_name,omitempty"`
 	UploadedTime time.Time `json:"uploaded_time"`
 	Time         string    `json:"time"`
 
@@ -28,10 +29,32 @@ type Document struct {
 	ContainerHostNamespace string `json:"container_host_namespace,omitempty"`
 	ContainerType          string `json:"container_type,omitempty"`
 	ContainerQoS           string `json:"container_qos,omitempty"`
+	// ContainerPodMetadata holds the pod labels and annotations
+	// configured, e.g. pod_label_app.
+	ContainerPodMetadata map[string]string `json:"container_pod_metadata,omitempty"`
 
 	TracerName    string `json:"tracer_name,omitempty"`
 	TracerID      string `json:"tracer_id,omitempty"`
 	TracerTime    string `json:"tracer_time"`
 	TracerRunType string `json:"tracer_type,omitempty"`
 	TracerData    any    `json:"tracer_data,omitempty"`
+
+	// SchemaName and SchemaVersion identify the schema of the tracer
+	// data, set for the tracers registering theirs.
+	SchemaName    string `json:"schema_name,omitempty"`
+	SchemaVersion int    `json:"schema_version,omitempty"`
+
+	// Enrichment holds the fields computed by the enrichment rules.
+	Enrichment map[string]any `json:"enrichment,omitempty"`
+
+	// Context holds the host and cgroup files captured when the tracer
+	// triggered.
+	Context map[string]string `json:"context,omitempty"`
+
+	// IncidentID groups the correlated events with their incident.
+	IncidentID string `json:"incident_id,omitempty"`
+
+	// SampleRate is the number of events the document stands for, set
+	// when the tracer is sampled.
+	SampleRate float64 `json:"sample_rate,omitempty"`
 }
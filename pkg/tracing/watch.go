@@ -14,10 +14,22 @@
 
 package tracing
 
-import "huatuo-bamai/internal/watch"
+import (
+	"sync"
+
+	"huatuo-bamai/internal/watch"
+)
+
+// recentDocumentsMax is the number of documents kept for RecentDocuments.
+const recentDocumentsMax = 100
 
 var documentHub = watch.NewHub[*Document]()
 
+var recentDocuments struct {
+	sync.Mutex
+	docs []*Document
+}
+
 // Subscribe registers a new document subscriber. The returned channel receives
 // documents as they are saved. Call cancel to unsubscribe.
 func Subscribe() (<-chan *Document, func()) {
@@ -26,5 +38,20 @@ func Subscribe() (<-chan *Document, func()) {
 
 // NotifySubscribers fans out doc to all registered document subscribers.
 func NotifySubscribers(doc *Document) {
+	recentDocuments.Lock()
+	if len(recentDocuments.docs) == recentDocumentsMax {
+		recentDocuments.docs = recentDocuments.docs[1:]
+	}
+	recentDocuments.docs = append(recentDocuments.docs, doc)
+	recentDocuments.Unlock()
+
 	documentHub.Notify(doc)
 }
+
+// RecentDocuments returns the last saved documents, oldest first.
+func RecentDocuments() []*Document {
+	recentDocuments.Lock()
+	defer recentDocuments.Unlock()
+
+	return append([]*Document(nil), recentDocuments.docs...)
+}
@@ -29,7 +29,8 @@ type WatchEvent struct {
 
 // WatchEventData is the stable public payload carried inside a WatchEvent.
 // It exposes a curated subset of internal Document fields so that internal
-// storage changes do not affect the public API contract.
+// storage changes do not affect the public API contract. Summary is the
+// event rendered by the template of its tracer.
 type WatchEventData struct {
 	Hostname               string `json:"hostname"`
 	Region                 string `json:"region"`
@@ -39,7 +40,10 @@ type WatchEventData struct {
 	ContainerHostNamespace string `json:"container_host_namespace,omitempty"`
 	ContainerType          string `json:"container_type,omitempty"`
 	ContainerQos           string `json:"container_qos,omitempty"`
-	TracerName             string `json:"tracer_name,omitempty"`
-	TracerID               string `json:"tracer_id,omitempty"`
-	TracerRunType          string `json:"tracer_run_type,omitempty"`
+	// ContainerPodMetadata is the pod labels and annotations configured.
+	ContainerPodMetadata map[string]string `json:"container_pod_metadata,omitempty"`
+	TracerName           string            `json:"tracer_name,omitempty"`
+	TracerID             string            `json:"tracer_id,omitempty"`
+	TracerRunType        string            `json:"tracer_run_type,omitempty"`
+	Summary              string            `json:"summary,omitempty"`
 }